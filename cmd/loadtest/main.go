@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"runtime"
+	"runtime/pprof"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/loadtest"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func main() {
+	rate := flag.Int("rps", 500, "notifications offered per second")
+	duration := flag.Duration("duration", 10*time.Second, "how long traffic is offered")
+	workers := flag.Int("workers", 32, "queue workers sending concurrently")
+	queueSize := flag.Int("queue", 10000, "queue capacity; offers to a full queue are dropped")
+	mix := flag.String("mix", "email=6,sms=2,push=2", "relative weight of each channel")
+	seed := flag.Int64("seed", 1, "seed for traffic and provider simulation; 0 seeds from the clock")
+	latency := flag.Duration("latency", 20*time.Millisecond, "mean simulated provider latency")
+	failureRate := flag.Float64("failure-rate", 0, "fraction of sends the providers fail")
+	logLevel := flag.String("log-level", "error", "log level: debug, info, warn or error")
+	jsonOutput := flag.Bool("json", false, "print the report as JSON")
+	cpuProfile := flag.String("cpuprofile", "", "write a CPU profile to this file")
+	memProfile := flag.String("memprofile", "", "write an allocation profile to this file")
+	flag.Parse()
+
+	channels, err := loadtest.ParseMix(*mix)
+	if err != nil {
+		log.Fatalf("Invalid mix: %v", err)
+	}
+
+	logger := utils.NewSimpleLogger(*logLevel)
+
+	// Providers take between half and one and a half times the mean latency
+	sim := providers.Simulation{
+		Seed:         *seed,
+		LatencyMin:   *latency / 2,
+		LatencyMax:   *latency * 3 / 2,
+		DeliveryRate: 0.9,
+	}
+	if *failureRate > 0 {
+		sim.Rules = []providers.FailureRule{{Code: errors.ErrorCodeProviderUnavailable, Rate: *failureRate}}
+	}
+
+	dispatcher, err := loadtest.NewDispatcher(sim, logger)
+	if err != nil {
+		log.Fatalf("Failed to create dispatcher: %v", err)
+	}
+
+	runner, err := loadtest.NewRunner(dispatcher, loadtest.Config{
+		Rate:      *rate,
+		Duration:  *duration,
+		Workers:   *workers,
+		QueueSize: *queueSize,
+		Mix:       channels,
+		Seed:      *seed,
+	}, logger)
+	if err != nil {
+		log.Fatalf("Invalid load test: %v", err)
+	}
+
+	if *cpuProfile != "" {
+		file, err := os.Create(*cpuProfile)
+		if err != nil {
+			log.Fatalf("Failed to create CPU profile: %v", err)
+		}
+		defer file.Close()
+		if err := pprof.StartCPUProfile(file); err != nil {
+			log.Fatalf("Failed to start CPU profile: %v", err)
+		}
+		defer pprof.StopCPUProfile()
+	}
+
+	// Interrupting the run still reports what was sent so far
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Printf("🚀 Offering %d/s for %s (%s) to %d workers\n", *rate, *duration, *mix, *workers)
+	report, err := runner.Run(ctx)
+	if err != nil {
+		fmt.Printf("⚠️  Run stopped early: %v\n", err)
+	}
+
+	if *memProfile != "" {
+		file, err := os.Create(*memProfile)
+		if err != nil {
+			log.Fatalf("Failed to create allocation profile: %v", err)
+		}
+		defer file.Close()
+		runtime.GC()
+		if err := pprof.Lookup("allocs").WriteTo(file, 0); err != nil {
+			log.Fatalf("Failed to write allocation profile: %v", err)
+		}
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Fatalf("Failed to encode report: %v", err)
+		}
+		return
+	}
+	fmt.Print(report)
+}
@@ -0,0 +1,222 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/audit"
+	"github.com/nareshkumar-microsoft/notificationService/internal/branding"
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/events"
+	"github.com/nareshkumar-microsoft/notificationService/internal/layout"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/ratelimit"
+	"github.com/nareshkumar-microsoft/notificationService/internal/rbac"
+	"github.com/nareshkumar-microsoft/notificationService/internal/repository"
+	"github.com/nareshkumar-microsoft/notificationService/internal/services"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/internal/webhookauth"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+func TestServer_AccessControl(t *testing.T) {
+	server := createTestServer(t)
+	server.SetBranding(branding.NewService(layout.NewLibrary(), nil, config.BrandingConfig{}))
+	authorizer, err := rbac.NewAuthorizerFromConfig(config.AuthConfig{APIKeys: []config.APIKeyConfig{
+		{Subject: "billing", Roles: []string{"sender"}, Key: "sender-0123456789"},
+		{Subject: "design", Roles: []string{"template-editor"}, Key: "editor-0123456789"},
+		{Subject: "dashboard", Roles: []string{"viewer"}, Key: "viewer-0123456789"},
+	}}, utils.NewSimpleLogger("error"))
+	require.NoError(t, err)
+	server.SetAccessControl(authorizer)
+
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer webhook.Close()
+	send := []byte(`{"type": "chat", "priority": "normal", "recipient": "` + webhook.URL + `", "body": "Deploy finished"}`)
+	branded := []byte(`{"name": "Acme"}`)
+	tests := []struct {
+		name   string
+		key    string
+		method string
+		path   string
+		body   []byte
+		status int
+	}{
+		{"health is public", "", http.MethodGet, "/v1/health", nil, http.StatusOK},
+		{"docs are public", "", http.MethodGet, "/openapi.json", nil, http.StatusOK},
+		{"no key", "", http.MethodPost, "/v1/notifications", send, http.StatusUnauthorized},
+		{"unknown key", "unknown-0123456789", http.MethodPost, "/v1/notifications", send, http.StatusUnauthorized},
+		{"sender sends", "sender-0123456789", http.MethodPost, "/v1/notifications", send, http.StatusAccepted},
+		{"viewer cannot send", "viewer-0123456789", http.MethodPost, "/v1/notifications", send, http.StatusForbidden},
+		{"editor cannot send", "editor-0123456789", http.MethodPost, "/v1/notifications", send, http.StatusForbidden},
+		{"editor edits branding", "editor-0123456789", http.MethodPatch, "/v1/tenants/acme/branding", branded, http.StatusOK},
+		{"sender cannot edit branding", "sender-0123456789", http.MethodPatch, "/v1/tenants/acme/branding", branded, http.StatusForbidden},
+		{"viewer reads branding", "viewer-0123456789", http.MethodGet, "/v1/tenants/acme/branding", nil, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(tt.method, tt.path, bytes.NewReader(tt.body))
+			if tt.key != "" {
+				request.Header.Set(rbac.APIKeyHeader, tt.key)
+			}
+			recorder := httptest.NewRecorder()
+			server.ServeHTTP(recorder, request)
+			assert.Equal(t, tt.status, recorder.Code, recorder.Body.String())
+		})
+	}
+}
+
+func TestServer_AccessControlTenant(t *testing.T) {
+	server := createTestServer(t)
+	server.SetBranding(branding.NewService(layout.NewLibrary(), nil, config.BrandingConfig{}))
+	server.SetAccessControl(rbac.NewAuthorizer(utils.NewSimpleLogger("error"), &tenantAuthenticator{
+		identity: &rbac.Identity{Subject: "ana", Method: "oidc", Roles: []rbac.Role{rbac.RoleTemplateEditor}, TenantID: "acme"},
+	}))
+
+	assert.Equal(t, http.StatusOK, serve(server, http.MethodGet, "/v1/tenants/acme/branding", nil).Code)
+	recorder := serve(server, http.MethodGet, "/v1/tenants/globex/branding", nil)
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "FORBIDDEN")
+}
+
+func TestServer_AccessControlNotificationTenant(t *testing.T) {
+	server, repo := createTestServerWithRepository(t)
+	server.SetAccessControl(rbac.NewAuthorizer(utils.NewSimpleLogger("error"), &tenantAuthenticator{
+		identity: &rbac.Identity{Subject: "acme-app", Method: "api_key", Roles: []rbac.Role{rbac.RoleSender, rbac.RoleViewer}, TenantID: "acme"},
+	}))
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer webhook.Close()
+
+	// The request names another tenant, but is sent as the caller's
+	send := []byte(`{"type": "chat", "priority": "normal", "recipient": "` + webhook.URL + `", "body": "Deploy finished", "metadata": {"tenant_id": "globex"}}`)
+	recorder := serve(server, http.MethodPost, "/v1/notifications", send)
+	require.Equal(t, http.StatusAccepted, recorder.Code, recorder.Body.String())
+	var response models.NotificationResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	stored, err := repo.GetByID(context.Background(), response.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, "acme", stored.Metadata[ratelimit.MetadataTenantID])
+	assert.Equal(t, http.StatusOK, serve(server, http.MethodGet, "/v1/notifications/"+response.ID.String(), nil).Code)
+
+	// Another tenant's notifications, and those of no tenant, are off limits
+	for _, metadata := range []map[string]string{{ratelimit.MetadataTenantID: "globex"}, nil} {
+		other := &models.Notification{ID: uuid.New(), Type: models.NotificationTypeChat, Status: models.StatusSent, Recipient: webhook.URL, Body: "Hi", Metadata: metadata}
+		require.NoError(t, repo.Save(context.Background(), other))
+		assert.Equal(t, http.StatusForbidden, serve(server, http.MethodGet, "/v1/notifications/"+other.ID.String(), nil).Code)
+		assert.Equal(t, http.StatusForbidden, serve(server, http.MethodPost, "/v1/notifications/"+other.ID.String()+"/resend", nil).Code)
+		assert.Equal(t, http.StatusForbidden, serve(server, http.MethodPost, "/v1/notifications/"+other.ID.String()+"/ack", nil).Code)
+	}
+}
+
+func TestServer_AccessControlAuditActor(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	dispatcher, err := services.NewDispatcherFromConfig(config.ProvidersConfig{
+		Chat: config.ChatProviderConfig{Provider: "slack", Enabled: true, AllowedHosts: []string{"127.0.0.1"}, AllowPrivateWebhooks: true},
+	}, repo, utils.NewSimpleLogger("error"))
+	require.NoError(t, err)
+	trail := repository.NewMemoryAuditRepository()
+	bus := events.NewBus(utils.NewSimpleLogger("error"))
+	bus.Subscribe(audit.NewRecorder(trail, utils.NewSimpleLogger("error")))
+	dispatcher.SetEventPublisher(bus)
+
+	server := NewServer(dispatcher, utils.NewSimpleLogger("error"))
+	server.SetAccessControl(rbac.NewAuthorizer(utils.NewSimpleLogger("error"), &tenantAuthenticator{
+		identity: &rbac.Identity{Subject: "billing-app", Method: "api_key", Roles: []rbac.Role{rbac.RoleSender}},
+	}))
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer webhook.Close()
+
+	send := []byte(`{"type": "chat", "priority": "normal", "recipient": "` + webhook.URL + `", "body": "Invoice paid"}`)
+	recorder := serve(server, http.MethodPost, "/v1/notifications", send)
+	require.Equal(t, http.StatusAccepted, recorder.Code, recorder.Body.String())
+	var response models.NotificationResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+
+	entries, err := trail.Query(context.Background(), interfaces.AuditFilters{NotificationID: response.ID.String()})
+	require.NoError(t, err)
+	require.NotEmpty(t, entries)
+	for _, entry := range entries {
+		assert.Equal(t, "billing-app", entry.Actor, entry.Action)
+	}
+}
+
+func TestRoutePermission(t *testing.T) {
+	assert.Equal(t, rbac.PermissionNotificationsWrite, routePermission(route{method: http.MethodPost, tag: "notifications"}))
+	assert.Equal(t, rbac.PermissionTemplatesRead, routePermission(route{method: http.MethodGet, tag: "branding"}))
+	assert.Equal(t, rbac.PermissionCampaignsLaunch, routePermission(route{method: http.MethodPost, tag: "campaigns"}))
+	assert.Equal(t, rbac.PermissionSettingsWrite, routePermission(route{method: http.MethodPut, tag: "pauses"}))
+	assert.Equal(t, rbac.PermissionSettingsRead, routePermission(route{method: http.MethodPost, tag: "routing", permission: rbac.PermissionSettingsRead}))
+}
+
+func TestServer_OpenAPIWithAccessControl(t *testing.T) {
+	server := createTestServer(t)
+	authorizer, err := rbac.NewAuthorizerFromConfig(config.AuthConfig{APIKeys: []config.APIKeyConfig{
+		{Subject: "ops", Roles: []string{"admin"}, Key: "admin-0123456789"},
+	}}, utils.NewSimpleLogger("error"))
+	require.NoError(t, err)
+	server.SetAccessControl(authorizer)
+
+	doc := server.OpenAPI()
+	assert.Contains(t, doc.Components.SecuritySchemes, "apiKey")
+	send := (*doc.Paths["/v1/notifications"])["post"]
+	assert.Equal(t, "notifications:write", send.Permission)
+	assert.Contains(t, send.Responses, "403")
+	assert.Empty(t, (*doc.Paths["/v1/health"])["get"].Security)
+}
+
+func TestServer_WebhookGuard(t *testing.T) {
+	server, _ := createTestKeywordServer(t)
+	server.SetWebhookGuard(webhookauth.NewGuard(config.WebhookAuthConfig{
+		Twilio: config.WebhookSourceConfig{AllowedIPs: []string{"54.172.60.0/30"}},
+		SES:    config.WebhookSourceConfig{AllowedIPs: []string{"not-an-ip"}},
+	}))
+
+	post := func(remoteAddr string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/v1/inbound/sms", strings.NewReader("From=%2B14155550123&Body=HELP"))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		request.RemoteAddr = remoteAddr
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+		return recorder
+	}
+	assert.Equal(t, http.StatusOK, post("54.172.60.1:443").Code)
+	recorder := post("203.0.113.9:443")
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "FORBIDDEN")
+
+	// The misconfigured allowlist degrades health
+	recorder = serve(server, http.MethodGet, "/v1/health", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	var health HealthResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &health))
+	assert.Equal(t, "degraded", health.Status)
+	assert.Equal(t, "ip allowlist", health.Webhooks["twilio"])
+	assert.Equal(t, "unprotected", health.Webhooks["sendgrid"])
+	assert.Contains(t, health.Webhooks["ses"], "not-an-ip")
+}
+
+// Helper functions
+
+type tenantAuthenticator struct {
+	identity *rbac.Identity
+}
+
+func (a *tenantAuthenticator) Authenticate(context.Context, rbac.Credentials) (*rbac.Identity, error) {
+	return a.identity, nil
+}
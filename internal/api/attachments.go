@@ -0,0 +1,54 @@
+package api
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/attachlink"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// SetAttachmentLinks adds the route the download links of offloaded email
+// attachments point at. The offloader's base URL must be the server's
+// public URL.
+func (s *Server) SetAttachmentLinks(offloader *attachlink.Offloader) {
+	s.routes = append(s.routes, route{
+		method:      http.MethodGet,
+		path:        attachlink.DownloadPath + "{key}",
+		operationID: "downloadAttachment",
+		summary:     "Download an email attachment offloaded to object storage, with a signed link from the email",
+		tag:         "attachments",
+		status:      http.StatusOK,
+		errors:      []int{http.StatusUnauthorized, http.StatusNotFound},
+		handler:     s.handleDownloadAttachment(offloader),
+		public:      true,
+	})
+}
+
+// handleDownloadAttachment serves an offloaded attachment
+func (s *Server) handleDownloadAttachment(offloader *attachlink.Offloader) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		query := r.URL.Query()
+		object, err := offloader.Open(r.Context(), params["key"], query.Get("expires"), query.Get("signature"))
+		if err != nil {
+			errors.WriteProblem(w, r, err)
+			return
+		}
+
+		contentType := object.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(object.Content)))
+		if object.Filename != "" {
+			w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": object.Filename}))
+		}
+		// Links are personal to the email's recipients
+		w.Header().Set("Cache-Control", "private, no-store")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusOK)
+		w.Write(object.Content)
+	}
+}
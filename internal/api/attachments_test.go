@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/attachlink"
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+)
+
+func TestServer_DownloadAttachment(t *testing.T) {
+	server := createTestServer(t)
+	offloader, err := attachlink.NewOffloader(attachlink.NewMemoryStore(time.Hour), config.AttachLinkConfig{
+		Threshold:  10,
+		BaseURL:    "https://notify.example.com",
+		SigningKey: "0123456789abcdef0123456789abcdef",
+	})
+	require.NoError(t, err)
+	server.SetAttachmentLinks(offloader)
+
+	_, links, err := offloader.Offload(context.Background(), &models.EmailNotification{
+		TextBody: "See the attached report.",
+		Attachments: []models.EmailAttachment{
+			{Filename: "report.pdf", ContentType: "application/pdf", Content: []byte("%PDF-1.4 quarterly report")},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	link, err := url.Parse(links[0].URL)
+	require.NoError(t, err)
+
+	recorder := serve(server, http.MethodGet, link.RequestURI(), nil)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	assert.Equal(t, "%PDF-1.4 quarterly report", recorder.Body.String())
+	assert.Equal(t, "application/pdf", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename=report.pdf`, recorder.Header().Get("Content-Disposition"))
+	assert.Equal(t, "private, no-store", recorder.Header().Get("Cache-Control"))
+
+	tampered := strings.Replace(link.RequestURI(), "signature=", "signature=0", 1)
+	recorder = serve(server, http.MethodGet, tampered, nil)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	doc := server.OpenAPI()
+	assert.Contains(t, doc.Paths, "/attachments/{key}")
+}
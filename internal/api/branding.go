@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/branding"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// maxBrandingAssetUpload bounds reading an asset; the service enforces the
+// configured limit
+const maxBrandingAssetUpload = 10 << 20
+
+// SetBranding adds the routes that read and update tenant branding and
+// upload branding assets
+func (s *Server) SetBranding(service *branding.Service) {
+	s.routes = append(s.routes,
+		route{
+			method:      http.MethodGet,
+			path:        "/v1/tenants/{tenant}/branding",
+			operationID: "getBranding",
+			summary:     "Get the branding variables a tenant's templates get as {{brand.<key>}}, defaults included",
+			tag:         "branding",
+			response:    branding.Branding{},
+			status:      http.StatusOK,
+			errors:      []int{http.StatusBadRequest},
+			handler:     s.handleGetBranding(service),
+		},
+		route{
+			method:      http.MethodPatch,
+			path:        "/v1/tenants/{tenant}/branding",
+			operationID: "updateBranding",
+			summary:     "Merge variables into a tenant's branding; an empty value removes the tenant's own variable",
+			tag:         "branding",
+			request:     map[string]string{},
+			response:    branding.Branding{},
+			status:      http.StatusOK,
+			errors:      []int{http.StatusBadRequest},
+			handler:     s.handleUpdateBranding(service),
+		},
+		route{
+			method:      http.MethodPut,
+			path:        "/v1/tenants/{tenant}/assets/{name}",
+			operationID: "uploadBrandingAsset",
+			summary:     "Upload the image in the request body and set the tenant's <name>_url branding variable to its URL",
+			tag:         "branding",
+			response:    branding.Asset{},
+			status:      http.StatusCreated,
+			errors:      []int{http.StatusBadRequest, http.StatusServiceUnavailable},
+			handler:     s.handleUploadBrandingAsset(service),
+		},
+	)
+}
+
+// handleGetBranding returns a tenant's branding
+func (s *Server) handleGetBranding(service *branding.Service) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		tenant, err := service.Get(params["tenant"])
+		if err != nil {
+			errors.WriteProblem(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, tenant)
+	}
+}
+
+// handleUpdateBranding merges variables into a tenant's branding
+func (s *Server) handleUpdateBranding(service *branding.Service) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		var vars map[string]string
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&vars); err != nil {
+			errors.WriteProblem(w, r, errors.NewNotificationErrorWithDetails(
+				errors.ErrorCodeInvalidRequest, "request body is not a map of branding variables", err.Error()))
+			return
+		}
+
+		tenant, err := service.Update(params["tenant"], vars)
+		if err != nil {
+			errors.WriteProblem(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, tenant)
+	}
+}
+
+// handleUploadBrandingAsset uploads a branding asset
+func (s *Server) handleUploadBrandingAsset(service *branding.Service) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBrandingAssetUpload))
+		if err != nil {
+			errors.WriteProblem(w, r, errors.NewNotificationErrorWithDetails(
+				errors.ErrorCodeInvalidRequest, "request body could not be read", err.Error()))
+			return
+		}
+
+		asset, err := service.UploadAsset(r.Context(), params["tenant"], params["name"], data)
+		if err != nil {
+			s.logger.Errorf("Branding asset upload failed: %v", err)
+			errors.WriteProblem(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, asset)
+	}
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/branding"
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/layout"
+)
+
+func TestServer_Branding(t *testing.T) {
+	server := createTestServer(t)
+	library := layout.NewLibrary()
+	store := &memoryImageStore{objects: make(map[string][]byte)}
+	server.SetBranding(branding.NewService(library, store, config.BrandingConfig{}))
+
+	recorder := serve(server, http.MethodPatch, "/v1/tenants/acme/branding", []byte(`{"name": "Acme", "primary_color": "#ff6600"}`))
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	var tenant branding.Branding
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &tenant))
+	assert.Equal(t, "Acme", tenant.Branding["name"])
+	assert.Equal(t, map[string]string{"name": "Acme", "primary_color": "#ff6600"}, tenant.Custom)
+
+	// A 1x1 GIF
+	gif := []byte("GIF89a\x01\x00\x01\x00\x80\x00\x00\x00\x00\x00\xff\xff\xff!\xf9\x04\x01\x00\x00\x00\x00,\x00\x00\x00\x00\x01\x00\x01\x00\x00\x02\x02D\x01\x00;")
+	recorder = serve(server, http.MethodPut, "/v1/tenants/acme/assets/logo", gif)
+	require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+	var asset branding.Asset
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &asset))
+	assert.Equal(t, "logo_url", asset.Variable)
+
+	recorder = serve(server, http.MethodGet, "/v1/tenants/acme/branding", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &tenant))
+	assert.Equal(t, asset.URL, tenant.Branding["logo_url"])
+
+	assert.Equal(t, http.StatusBadRequest, serve(server, http.MethodPatch, "/v1/tenants/acme/branding", []byte(`{"primary_color": "orange"}`)).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(server, http.MethodPatch, "/v1/tenants/acme/branding", []byte(`not json`)).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(server, http.MethodPut, "/v1/tenants/acme/assets/logo", []byte("not an image")).Code)
+
+	doc := server.OpenAPI()
+	assert.Contains(t, doc.Paths, "/v1/tenants/{tenant}/branding")
+	assert.Contains(t, doc.Paths, "/v1/tenants/{tenant}/assets/{name}")
+}
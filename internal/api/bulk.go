@@ -0,0 +1,281 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/queue"
+	"github.com/nareshkumar-microsoft/notificationService/internal/requestlog"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/internal/validation"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// NDJSONContentType is the media type of newline-delimited JSON streams
+const NDJSONContentType = "application/x-ndjson"
+
+// Job metadata keys set on notifications submitted through a bulk stream
+const (
+	MetadataBulkJobID = "bulk_job_id"
+	MetadataBulkLine  = "bulk_line"
+)
+
+// DefaultBulkChunkSize is how many recipients are acknowledged at a time
+const DefaultBulkChunkSize = 1000
+
+// bulkRetryInterval is how often a full queue is retried
+const bulkRetryInterval = 50 * time.Millisecond
+
+// BulkRecipient is a recipient line of a bulk stream
+type BulkRecipient struct {
+	Recipient    string            `json:"recipient" validate:"required"`
+	TemplateData map[string]string `json:"template_data,omitempty"` // merged over the header's data
+	Metadata     map[string]string `json:"metadata,omitempty"`      // merged over the header's metadata
+}
+
+// BulkLineError is a recipient line that was rejected
+type BulkLineError struct {
+	Line    int              `json:"line"`
+	Code    errors.ErrorCode `json:"code"`
+	Message string           `json:"message"`
+}
+
+// BulkAck acknowledges a chunk of a bulk stream. Counts are cumulative;
+// errors list the lines rejected since the previous acknowledgement.
+type BulkAck struct {
+	JobID    string          `json:"job_id"`
+	Accepted int             `json:"accepted"`
+	Rejected int             `json:"rejected"`
+	Errors   []BulkLineError `json:"errors,omitempty"`
+	Done     bool            `json:"done,omitempty"`  // the whole stream was read
+	Error    string          `json:"error,omitempty"` // why the stream stopped early
+}
+
+// SetBulkQueue adds the route that streams bulk submissions into a queue.
+// The request body is NDJSON: a notification request used as the template
+// for every recipient, then one BulkRecipient per line. Recipients are
+// validated and enqueued as they arrive, and acknowledged every chunkSize
+// lines, so campaigns of any size never need one large request body. A
+// chunkSize of zero or less uses DefaultBulkChunkSize.
+func (s *Server) SetBulkQueue(q *queue.MemoryQueue, chunkSize int) {
+	if chunkSize <= 0 {
+		chunkSize = DefaultBulkChunkSize
+	}
+
+	s.routes = append(s.routes, route{
+		method:      http.MethodPost,
+		path:        "/v1/bulk",
+		operationID: "streamBulk",
+		summary:     "Stream recipients for a bulk send: a template request line, then one recipient per line; acknowledgements are streamed back",
+		tag:         "notifications",
+		request:     BulkRecipient{},
+		response:    BulkAck{},
+		status:      http.StatusOK,
+		errors:      []int{http.StatusBadRequest, http.StatusTooManyRequests, http.StatusServiceUnavailable},
+		handler:     s.handleBulk(q, chunkSize),
+		mediaType:   NDJSONContentType,
+	})
+}
+
+// handleBulk reads a bulk stream, enqueueing each valid recipient. Errors in
+// the header line are answered with a problem; once acknowledgements start,
+// errors are reported in them.
+func (s *Server) handleBulk(q *queue.MemoryQueue, chunkSize int) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		scanner := bufio.NewScanner(r.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxRequestBody)
+
+		header, err := readBulkHeader(scanner)
+		if err != nil {
+			errors.WriteProblem(w, r, err)
+			return
+		}
+		if header.CorrelationID == "" {
+			// Queued sends run outside the request, so the ID travels with them
+			header.CorrelationID = requestlog.CorrelationIDFromContext(r.Context())
+		}
+
+		if s.shedder != nil {
+			if err := s.shedder.Check(header.Priority); err != nil {
+				errors.WriteProblem(w, r, err)
+				return
+			}
+		}
+
+		// Acknowledgements are written while the body is still being read
+		_ = http.NewResponseController(w).EnableFullDuplex()
+		w.Header().Set("Content-Type", NDJSONContentType)
+		w.WriteHeader(http.StatusOK)
+
+		encoder := json.NewEncoder(w)
+		ack := BulkAck{JobID: uuid.New().String()}
+		flush := func() {
+			encoder.Encode(ack)
+			if flusher, ok := w.(http.Flusher); ok {
+				flusher.Flush()
+			}
+			ack.Errors = nil
+		}
+
+		s.logger.Infof("Bulk job %s started", ack.JobID)
+
+		line, pending := 1, 0
+		for scanner.Scan() {
+			line++
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+
+			request, err := bulkRequest(header, scanner.Bytes())
+			if err == nil {
+				scopeToTenant(r, request)
+				err = enqueueBulk(r.Context(), q, &queue.Job{
+					ID:      fmt.Sprintf("%s-%d", ack.JobID, line),
+					Request: request,
+					Metadata: map[string]string{
+						MetadataBulkJobID: ack.JobID,
+						MetadataBulkLine:  strconv.Itoa(line),
+					},
+				})
+				if err != nil && r.Context().Err() != nil {
+					break
+				}
+			}
+
+			if err != nil {
+				ack.Rejected++
+				ack.Errors = append(ack.Errors, bulkLineError(line, err))
+			} else {
+				ack.Accepted++
+			}
+
+			if pending++; pending == chunkSize {
+				flush()
+				pending = 0
+			}
+		}
+
+		switch {
+		case r.Context().Err() != nil:
+			ack.Error = "stream cancelled"
+		case scanner.Err() != nil:
+			ack.Error = scanner.Err().Error()
+		default:
+			ack.Done = true
+		}
+		flush()
+
+		s.logger.Infof("Bulk job %s finished: %d accepted, %d rejected", ack.JobID, ack.Accepted, ack.Rejected)
+	}
+}
+
+// enqueueBulk enqueues a job, waiting while the queue is full so a fast
+// stream is slowed to the rate the workers drain the queue
+func enqueueBulk(ctx context.Context, q *queue.MemoryQueue, job *queue.Job) error {
+	for {
+		err := q.Enqueue(job)
+		if err != errors.ErrQueueFull {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(bulkRetryInterval):
+		}
+	}
+}
+
+// readBulkHeader reads and validates the template request on the first line
+// of a bulk stream. Its recipient is ignored, and channel data may not name
+// a recipient, since each line supplies its own.
+func readBulkHeader(scanner *bufio.Scanner) (*models.NotificationRequest, error) {
+	if !scanner.Scan() {
+		details := "the stream is empty"
+		if scanner.Err() != nil {
+			details = scanner.Err().Error()
+		}
+		return nil, errors.NewNotificationErrorWithDetails(errors.ErrorCodeInvalidRequest, "bulk stream has no header line", details)
+	}
+
+	var header models.NotificationRequest
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return nil, errors.NewNotificationErrorWithDetails(
+			errors.ErrorCodeInvalidRequest, "bulk stream header is not a valid notification request", err.Error())
+	}
+
+	switch {
+	case header.EmailData != nil && len(header.EmailData.To) > 0:
+		return nil, errors.NewValidationError("email_data.to", "recipients are given per line in a bulk stream")
+	case header.SMSData != nil && header.SMSData.PhoneNumber != "",
+		header.VoiceData != nil && header.VoiceData.PhoneNumber != "":
+		return nil, errors.NewValidationError("phone_number", "recipients are given per line in a bulk stream")
+	case header.PushData != nil && header.PushData.DeviceToken != "":
+		return nil, errors.NewValidationError("push_data.device_token", "recipients are given per line in a bulk stream")
+	case header.ChatData != nil && header.ChatData.WebhookURL != "":
+		return nil, errors.NewValidationError("chat_data.webhook_url", "recipients are given per line in a bulk stream")
+	}
+
+	// Validate the template with a placeholder recipient; recipients are
+	// validated line by line
+	check := header
+	check.Recipient = "bulk"
+	if err := validation.Struct(&check); err != nil {
+		return nil, err
+	}
+
+	return &header, nil
+}
+
+// bulkRequest builds the notification request for a recipient line
+func bulkRequest(header *models.NotificationRequest, line []byte) (*models.NotificationRequest, error) {
+	var recipient BulkRecipient
+	if err := json.Unmarshal(line, &recipient); err != nil {
+		return nil, errors.NewNotificationErrorWithDetails(errors.ErrorCodeInvalidRequest, "line is not a valid bulk recipient", err.Error())
+	}
+	if err := validation.Struct(&recipient); err != nil {
+		return nil, err
+	}
+
+	request := *header
+	request.Recipient = recipient.Recipient
+	request.TemplateData = mergeStrings(header.TemplateData, recipient.TemplateData)
+	request.Metadata = mergeStrings(header.Metadata, recipient.Metadata)
+
+	if err := utils.ValidateNotificationRequest(&request); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// bulkLineError describes why a recipient line was rejected
+func bulkLineError(line int, err error) BulkLineError {
+	lineErr := BulkLineError{Line: line, Code: errors.ErrorCodeInternal, Message: err.Error()}
+	if notifErr, ok := errors.AsNotificationError(err); ok {
+		lineErr.Code = notifErr.Code
+	}
+	return lineErr
+}
+
+// mergeStrings returns base with overrides applied, without modifying either
+func mergeStrings(base, overrides map[string]string) map[string]string {
+	if len(overrides) == 0 {
+		return base
+	}
+
+	merged := make(map[string]string, len(base)+len(overrides))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		merged[key] = value
+	}
+	return merged
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/queue"
+	"github.com/nareshkumar-microsoft/notificationService/internal/requestlog"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestServer_StreamBulk(t *testing.T) {
+	server := createTestServer(t)
+	q := queue.NewMemoryQueue(0)
+	server.SetBulkQueue(q, 2)
+
+	body := createTestBulkStream(t, models.NotificationRequest{
+		Type:         models.NotificationTypeEmail,
+		Priority:     models.PriorityLow,
+		Subject:      "Spring sale",
+		Body:         "Hi {{name}}, everything is {{discount}} off",
+		TemplateData: map[string]string{"discount": "20%"},
+		Metadata:     map[string]string{"campaign": "spring"},
+	},
+		BulkRecipient{Recipient: "ana@example.com", TemplateData: map[string]string{"name": "Ana"}},
+		BulkRecipient{Recipient: "not-an-email"},
+		BulkRecipient{Recipient: "bo@example.com", Metadata: map[string]string{"segment": "vip"}},
+	)
+	body = append(body, "{broken\n"...)
+
+	recorder := serve(server, http.MethodPost, "/v1/bulk", body)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, NDJSONContentType, recorder.Header().Get("Content-Type"))
+
+	acks := readTestAcks(t, recorder.Body.Bytes())
+	require.Len(t, acks, 3)
+	assert.Equal(t, 1, acks[0].Accepted)
+	assert.Equal(t, 1, acks[0].Rejected)
+	require.Len(t, acks[0].Errors, 1)
+	assert.Equal(t, 3, acks[0].Errors[0].Line)
+	assert.Equal(t, errors.ErrorCodeValidationFailed, acks[0].Errors[0].Code)
+
+	final := acks[2]
+	assert.True(t, final.Done)
+	assert.Equal(t, 2, final.Accepted)
+	assert.Equal(t, 2, final.Rejected)
+	assert.Empty(t, final.Errors)
+	require.Len(t, acks[1].Errors, 1)
+	assert.Equal(t, errors.ErrorCodeInvalidRequest, acks[1].Errors[0].Code)
+	for _, ack := range acks {
+		assert.Equal(t, acks[0].JobID, ack.JobID)
+	}
+
+	require.Equal(t, 2, q.Len())
+	job, err := q.TryDequeue()
+	require.NoError(t, err)
+	assert.Equal(t, "ana@example.com", job.Request.Recipient)
+	assert.Equal(t, map[string]string{"discount": "20%", "name": "Ana"}, job.Request.TemplateData)
+	assert.Equal(t, acks[0].JobID, job.Metadata[MetadataBulkJobID])
+	assert.Equal(t, "2", job.Metadata[MetadataBulkLine])
+
+	job, err = q.TryDequeue()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"campaign": "spring", "segment": "vip"}, job.Request.Metadata)
+}
+
+func TestServer_StreamBulk_InvalidHeader(t *testing.T) {
+	server := createTestServer(t)
+	q := queue.NewMemoryQueue(0)
+	server.SetBulkQueue(q, 0)
+
+	tests := []struct {
+		name string
+		body []byte
+	}{
+		{"empty stream", nil},
+		{"malformed header", []byte("{broken\n")},
+		{"missing body", createTestBulkStream(t, models.NotificationRequest{Type: models.NotificationTypeEmail, Priority: models.PriorityLow})},
+		{"recipient in channel data", createTestBulkStream(t, models.NotificationRequest{
+			Type:      models.NotificationTypeEmail,
+			Priority:  models.PriorityLow,
+			Body:      "Hello",
+			EmailData: &models.EmailData{To: []string{"ana@example.com"}},
+		})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := serve(server, http.MethodPost, "/v1/bulk", tt.body)
+			assert.Equal(t, http.StatusBadRequest, recorder.Code)
+			assert.Equal(t, errors.ProblemContentType, recorder.Header().Get("Content-Type"))
+		})
+	}
+	assert.Equal(t, 0, q.Len())
+}
+
+func TestServer_StreamBulk_WaitsForQueueSpace(t *testing.T) {
+	server := createTestServer(t)
+	q := queue.NewMemoryQueue(1)
+	server.SetBulkQueue(q, 0)
+
+	recipients := make([]BulkRecipient, 3)
+	for i := range recipients {
+		recipients[i] = BulkRecipient{Recipient: "ana@example.com"}
+	}
+	body := createTestBulkStream(t, models.NotificationRequest{Type: models.NotificationTypeEmail, Priority: models.PriorityLow, Body: "Hello"}, recipients...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	drained := make(chan int)
+	go func() {
+		count := 0
+		for count < len(recipients) {
+			if _, err := q.Dequeue(ctx); err != nil {
+				break
+			}
+			count++
+		}
+		drained <- count
+	}()
+
+	recorder := serve(server, http.MethodPost, "/v1/bulk", body)
+	acks := readTestAcks(t, recorder.Body.Bytes())
+	require.Len(t, acks, 1)
+	assert.True(t, acks[0].Done)
+	assert.Equal(t, 3, acks[0].Accepted)
+
+	select {
+	case count := <-drained:
+		assert.Equal(t, 3, count)
+	case <-time.After(time.Second):
+		t.Fatal("queue was not drained")
+	}
+}
+
+func TestServer_StreamBulk_CorrelationID(t *testing.T) {
+	server := createTestServer(t)
+	q := queue.NewMemoryQueue(0)
+	server.SetBulkQueue(q, 0)
+	header := models.NotificationRequest{Type: models.NotificationTypeEmail, Priority: models.PriorityLow, Body: "Hello"}
+
+	// Jobs carry the ID of the request that queued them
+	request := httptest.NewRequest(http.MethodPost, "/v1/bulk", bytes.NewReader(createTestBulkStream(t, header, BulkRecipient{Recipient: "ana@example.com"})))
+	server.ServeHTTP(httptest.NewRecorder(), request.WithContext(requestlog.WithCorrelationID(request.Context(), "req-1")))
+
+	job, err := q.TryDequeue()
+	require.NoError(t, err)
+	assert.Equal(t, "req-1", job.Request.CorrelationID)
+
+	// unless the header sets its own
+	header.CorrelationID = "trace-9"
+	request = httptest.NewRequest(http.MethodPost, "/v1/bulk", bytes.NewReader(createTestBulkStream(t, header, BulkRecipient{Recipient: "ana@example.com"})))
+	server.ServeHTTP(httptest.NewRecorder(), request.WithContext(requestlog.WithCorrelationID(request.Context(), "req-2")))
+
+	job, err = q.TryDequeue()
+	require.NoError(t, err)
+	assert.Equal(t, "trace-9", job.Request.CorrelationID)
+}
+
+func TestServer_StreamBulk_OpenAPI(t *testing.T) {
+	server := createTestServer(t)
+	server.SetBulkQueue(queue.NewMemoryQueue(0), 0)
+
+	item := server.OpenAPI().Paths["/v1/bulk"]
+	require.NotNil(t, item)
+	op := (*item)["post"]
+	require.NotNil(t, op)
+	assert.Contains(t, op.RequestBody.Content, NDJSONContentType)
+	assert.Contains(t, op.Responses["200"].Content, NDJSONContentType)
+}
+
+// Helper functions
+
+func createTestBulkStream(t *testing.T, header models.NotificationRequest, recipients ...BulkRecipient) []byte {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	require.NoError(t, encoder.Encode(header))
+	for _, recipient := range recipients {
+		require.NoError(t, encoder.Encode(recipient))
+	}
+	return buf.Bytes()
+}
+
+func readTestAcks(t *testing.T, body []byte) []BulkAck {
+	var acks []BulkAck
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		var ack BulkAck
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &ack))
+		acks = append(acks, ack)
+	}
+	return acks
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/compat"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// SetTemplateCompatibility adds the routes that test email templates in
+// email clients and serve the reports of their versions
+func (s *Server) SetTemplateCompatibility(reports *compat.Service) {
+	s.routes = append(s.routes,
+		route{
+			method:      http.MethodPost,
+			path:        "/v1/templates/email/{id}/compatibility",
+			operationID: "testTemplateCompatibility",
+			summary:     "Test how the current version of an email template renders in email clients such as Gmail, Outlook and Apple Mail",
+			tag:         "templates",
+			request:     compat.TestRequest{},
+			response:    compat.Report{},
+			status:      http.StatusCreated,
+			errors:      []int{http.StatusBadRequest, http.StatusNotFound},
+			handler:     s.handleTestCompatibility(reports),
+		},
+		route{
+			method:      http.MethodGet,
+			path:        "/v1/templates/email/{id}/compatibility",
+			operationID: "listTemplateCompatibility",
+			summary:     "List the compatibility reports of an email template's versions, newest first",
+			tag:         "templates",
+			response:    []compat.Report{},
+			status:      http.StatusOK,
+			handler:     s.handleListCompatibility(reports),
+		},
+		route{
+			method:      http.MethodGet,
+			path:        "/v1/templates/email/{id}/compatibility/{version}",
+			operationID: "getTemplateCompatibility",
+			summary:     "Get the compatibility report of a template version, or of the current version when the version is \"current\"",
+			tag:         "templates",
+			response:    compat.Report{},
+			status:      http.StatusOK,
+			errors:      []int{http.StatusNotFound},
+			handler:     s.handleGetCompatibility(reports),
+		},
+	)
+}
+
+// handleTestCompatibility tests a template. The request body is optional.
+func (s *Server) handleTestCompatibility(reports *compat.Service) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		var request compat.TestRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&request); err != nil && err != io.EOF {
+			errors.WriteProblem(w, r, errors.NewNotificationErrorWithDetails(
+				errors.ErrorCodeInvalidRequest, "request body is not a valid compatibility test request", err.Error()))
+			return
+		}
+
+		report, err := reports.Test(r.Context(), params["id"], request)
+		if err != nil {
+			s.logger.Errorf("Compatibility test of template %s failed: %v", params["id"], err)
+			errors.WriteProblem(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, report)
+	}
+}
+
+// handleListCompatibility lists a template's reports
+func (s *Server) handleListCompatibility(reports *compat.Service) handlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+		writeJSON(w, http.StatusOK, reports.List(params["id"]))
+	}
+}
+
+// handleGetCompatibility serves the report of a template version
+func (s *Server) handleGetCompatibility(reports *compat.Service) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		var report *compat.Report
+		var err error
+		if params["version"] == "current" {
+			report, err = reports.Current(params["id"])
+		} else {
+			report, err = reports.Get(params["id"], params["version"])
+		}
+		if err != nil {
+			errors.WriteProblem(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, report)
+	}
+}
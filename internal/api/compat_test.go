@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/compat"
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/internal/repository"
+	"github.com/nareshkumar-microsoft/notificationService/internal/services"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+)
+
+func TestServer_TemplateCompatibility(t *testing.T) {
+	server, email := createTestCompatServer(t)
+
+	recorder := serve(server, http.MethodPost, "/v1/templates/email/welcome/compatibility", []byte(`{"template_data":{"user_name":"Jane"},"clients":["gmail","outlook"]}`))
+	require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+	var first compat.Report
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &first))
+	assert.Equal(t, compat.StatusPass, first.Status)
+	require.Len(t, first.Clients, 2)
+	assert.NotEmpty(t, first.Version)
+
+	recorder = serve(server, http.MethodGet, "/v1/templates/email/welcome/compatibility/current", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var current compat.Report
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &current))
+	assert.Equal(t, first.Version, current.Version)
+
+	// An edited template has no report for its current version
+	template, err := email.GetTemplate("welcome")
+	require.NoError(t, err)
+	changed := *template
+	changed.HTMLBody += "<p>Changed</p>"
+	require.NoError(t, email.AddTemplate(&changed))
+	recorder = serve(server, http.MethodGet, "/v1/templates/email/welcome/compatibility/current", nil)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	recorder = serve(server, http.MethodGet, "/v1/templates/email/welcome/compatibility/"+first.Version, nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = serve(server, http.MethodPost, "/v1/templates/email/welcome/compatibility", nil)
+	require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+	recorder = serve(server, http.MethodGet, "/v1/templates/email/welcome/compatibility", nil)
+	var listed []compat.Report
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &listed))
+	require.Len(t, listed, 2)
+	assert.Len(t, listed[0].Clients, len(compat.DefaultClients))
+
+	recorder = serve(server, http.MethodPost, "/v1/templates/email/missing/compatibility", nil)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	recorder = serve(server, http.MethodPost, "/v1/templates/email/welcome/compatibility", []byte(`{"clients":[""]}`))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+// Helper functions
+
+// passingTester reports that every client renders the message as intended
+type passingTester struct{}
+
+func (passingTester) Test(_ context.Context, _ compat.Message, clients []compat.Client) ([]compat.ClientResult, error) {
+	results := make([]compat.ClientResult, len(clients))
+	for i, client := range clients {
+		results[i] = compat.ClientResult{Client: client, Status: compat.StatusPass}
+	}
+	return results, nil
+}
+
+func createTestCompatServer(t *testing.T) (*Server, *providers.MockEmailProvider) {
+	dispatcher, err := services.NewDispatcherFromConfig(config.ProvidersConfig{}, repository.NewMemoryRepository(), utils.NewSimpleLogger("error"))
+	require.NoError(t, err)
+	server := NewServer(dispatcher, utils.NewSimpleLogger("error"))
+
+	email := providers.NewMockEmailProvider(config.EmailProviderConfig{Provider: "mock"})
+	server.SetTemplateCompatibility(compat.NewService(email, passingTester{}, utils.NewSimpleLogger("error")))
+	return server, email
+}
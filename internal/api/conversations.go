@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/conversation"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// SetConversations adds the routes that fetch two-way SMS conversations
+func (s *Server) SetConversations(store *conversation.Store) {
+	s.routes = append(s.routes,
+		route{
+			method:      http.MethodGet,
+			path:        "/v1/conversations",
+			operationID: "listConversations",
+			summary:     "List the SMS conversations with a phone number, given by the phone query parameter, most recent first",
+			tag:         "conversations",
+			response:    []conversation.Conversation{},
+			status:      http.StatusOK,
+			errors:      []int{http.StatusBadRequest},
+			handler:     s.handleListConversations(store),
+		},
+		route{
+			method:      http.MethodGet,
+			path:        "/v1/conversations/{id}",
+			operationID: "getConversation",
+			summary:     "Get an SMS conversation with its messages",
+			tag:         "conversations",
+			response:    conversation.Conversation{},
+			status:      http.StatusOK,
+			errors:      []int{http.StatusNotFound},
+			handler:     s.handleGetConversation(store),
+		},
+	)
+}
+
+// handleListConversations lists a phone number's conversations
+func (s *Server) handleListConversations(store *conversation.Store) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		phone := r.URL.Query().Get("phone")
+		if phone == "" {
+			errors.WriteProblem(w, r, errors.NewValidationError("phone", "phone query parameter is required"))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, store.ForParticipant(phone))
+	}
+}
+
+// handleGetConversation returns a conversation
+func (s *Server) handleGetConversation(store *conversation.Store) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		conv, err := store.Get(params["id"])
+		if err != nil {
+			errors.WriteProblem(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, conv)
+	}
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/conversation"
+)
+
+func TestServer_Conversations(t *testing.T) {
+	server := createTestServer(t)
+	store := conversation.NewStore(config.ConversationConfig{ServiceNumber: "+14155550100"})
+	server.SetConversations(store)
+
+	sent := store.RecordOutbound(uuid.New(), "", "+14155550123", "Your code is 123456")
+	store.RecordInbound("+14155550123", "+14155550100", "RESEND", "SM1")
+
+	recorder := serve(server, http.MethodGet, "/v1/conversations?phone=%2B14155550123", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var listed []conversation.Conversation
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &listed))
+	require.Len(t, listed, 1)
+	assert.Equal(t, sent.ID, listed[0].ID)
+
+	recorder = serve(server, http.MethodGet, "/v1/conversations/"+sent.ID, nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var fetched conversation.Conversation
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &fetched))
+	require.Len(t, fetched.Messages, 2)
+	assert.Equal(t, "RESEND", fetched.Messages[1].Body)
+
+	assert.Equal(t, http.StatusNotFound, serve(server, http.MethodGet, "/v1/conversations/missing", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(server, http.MethodGet, "/v1/conversations", nil).Code)
+
+	doc := server.OpenAPI()
+	assert.Contains(t, doc.Paths, "/v1/conversations/{id}")
+}
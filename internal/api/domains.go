@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/domainauth"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// SetDomainDiagnostics adds the route that diagnoses the SPF, DKIM and
+// DMARC setup of a sending domain
+func (s *Server) SetDomainDiagnostics(diagnoser *domainauth.Diagnoser) {
+	s.routes = append(s.routes, route{
+		method:      http.MethodGet,
+		path:        "/v1/email/domains/{domain}/diagnostics",
+		operationID: "diagnoseSendingDomain",
+		summary:     "Check a sending domain's SPF, DKIM and DMARC records and their alignment with the From domain; the from_domain query parameter overrides the configured From domain and selectors adds comma-separated DKIM selectors",
+		tag:         "email",
+		response:    domainauth.Report{},
+		status:      http.StatusOK,
+		errors:      []int{http.StatusBadRequest, http.StatusServiceUnavailable},
+		handler:     s.handleDiagnoseDomain(diagnoser),
+	})
+}
+
+// handleDiagnoseDomain diagnoses a sending domain
+func (s *Server) handleDiagnoseDomain(diagnoser *domainauth.Diagnoser) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		query := r.URL.Query()
+		var selectors []string
+		if list := query.Get("selectors"); list != "" {
+			selectors = strings.Split(list, ",")
+		}
+
+		report, err := diagnoser.DiagnoseSendingDomain(r.Context(), params["domain"], query.Get("from_domain"), selectors...)
+		if err != nil {
+			errors.WriteProblem(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, report)
+	}
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/domainauth"
+	"github.com/nareshkumar-microsoft/notificationService/internal/repository"
+	"github.com/nareshkumar-microsoft/notificationService/internal/services"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+)
+
+func TestServer_DomainDiagnostics(t *testing.T) {
+	server := createTestDomainServer(t)
+
+	recorder := serve(server, http.MethodGet, "/v1/email/domains/example.com/diagnostics?selectors=sg", nil)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	var report domainauth.Report
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+	assert.Equal(t, "example.com", report.Domain)
+	require.Len(t, report.DKIM, 1)
+	assert.Equal(t, "sg", report.DKIM[0].Selector)
+	assert.True(t, report.Aligned)
+
+	recorder = serve(server, http.MethodGet, "/v1/email/domains/example.com/diagnostics?from_domain=other.net", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+	assert.False(t, report.Aligned)
+
+	recorder = serve(server, http.MethodGet, "/v1/email/domains/localhost/diagnostics", nil)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+// Helper functions
+
+// txtResolver answers TXT lookups from a table; other names do not exist
+type txtResolver map[string][]string
+
+func (r txtResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	records, exists := r[name]
+	if !exists {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return records, nil
+}
+
+func createTestDomainServer(t *testing.T) *Server {
+	dispatcher, err := services.NewDispatcherFromConfig(config.ProvidersConfig{}, repository.NewMemoryRepository(), utils.NewSimpleLogger("error"))
+	require.NoError(t, err)
+	server := NewServer(dispatcher, utils.NewSimpleLogger("error"))
+
+	server.SetDomainDiagnostics(domainauth.NewDiagnoser(domainauth.Options{Resolver: txtResolver{
+		"example.com":               {"v=spf1 -all"},
+		"sg._domainkey.example.com": {"v=DKIM1; k=ed25519; p=11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo="},
+		"_dmarc.example.com":        {"v=DMARC1; p=reject; rua=mailto:dmarc@example.com"},
+	}}))
+	return server
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/inbound"
+	"github.com/nareshkumar-microsoft/notificationService/internal/webhookauth"
+	"github.com/nareshkumar-microsoft/notificationService/internal/webhookdedup"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// SetInbound adds the inbound parse webhooks replies to notifications are
+// posted to. They are called by the email provider rather than clients, so
+// they are left out of the OpenAPI document.
+func (s *Server) SetInbound(router *inbound.Router) {
+	s.routes = append(s.routes,
+		route{method: http.MethodPost, path: "/v1/inbound/sendgrid", handler: s.handleSendGridInbound(router), internal: true, public: true, webhook: webhookauth.SourceSendGrid},
+		route{method: http.MethodPost, path: "/v1/inbound/ses", handler: s.handleSESInbound(router), internal: true, public: true, webhook: webhookauth.SourceSES},
+	)
+}
+
+// SetWebhookDedup makes inbound webhooks process each provider event once.
+// Retries of a processed event are acknowledged without handling them
+// again; see webhookdedup.Deduplicator.
+func (s *Server) SetWebhookDedup(dedup *webhookdedup.Deduplicator) {
+	s.dedup = dedup
+}
+
+// processOnce calls handle unless the event was processed before, reporting
+// a duplicate
+func (s *Server) processOnce(r *http.Request, source, eventID string, handle func() error) (bool, error) {
+	if s.dedup == nil {
+		return false, handle()
+	}
+	return s.dedup.Process(r.Context(), source, eventID, handle)
+}
+
+// handleSendGridInbound handles a SendGrid Inbound Parse post
+func (s *Server) handleSendGridInbound(router *inbound.Router) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		message, err := inbound.ParseSendGrid(r)
+		if err != nil {
+			errors.WriteProblem(w, r, err)
+			return
+		}
+		s.handleInbound(w, r, router, message)
+	}
+}
+
+// handleSESInbound handles an SES receipt notification delivered by SNS
+func (s *Server) handleSESInbound(router *inbound.Router) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+		if err != nil {
+			errors.WriteProblem(w, r, errors.NewNotificationErrorWithDetails(
+				errors.ErrorCodeInvalidRequest, "request body could not be read", err.Error()))
+			return
+		}
+
+		message, subscribeURL, err := inbound.ParseSES(body)
+		if err != nil {
+			errors.WriteProblem(w, r, err)
+			return
+		}
+		if message == nil {
+			// Subscriptions are confirmed by an operator, not fetched automatically
+			s.logger.Infof("SES inbound SNS subscription awaiting confirmation: %s", subscribeURL)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		s.handleInbound(w, r, router, *message)
+	}
+}
+
+// handleInbound routes a parsed inbound email
+func (s *Server) handleInbound(w http.ResponseWriter, r *http.Request, router *inbound.Router, message inbound.Message) {
+	var result inbound.Result
+	duplicate, err := s.processOnce(r, message.Provider, message.MessageID, func() error {
+		var err error
+		result, err = router.Handle(r.Context(), message)
+		return err
+	})
+	if err != nil {
+		s.logger.Errorf("Inbound email from %s failed: %v", message.From, err)
+		errors.WriteProblem(w, r, err)
+		return
+	}
+	if duplicate {
+		result = inbound.Result{Status: "ignored", Reason: "duplicate of a processed event"}
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
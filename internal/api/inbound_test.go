@@ -0,0 +1,159 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/events"
+	"github.com/nareshkumar-microsoft/notificationService/internal/inbound"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/internal/repository"
+	"github.com/nareshkumar-microsoft/notificationService/internal/services"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/internal/webhookdedup"
+)
+
+func TestServer_InboundSendGridReply(t *testing.T) {
+	server, dispatcher, replies := createTestInboundServer(t)
+
+	response, err := dispatcher.SendNotification(context.Background(), &models.NotificationRequest{
+		Type:      models.NotificationTypeEmail,
+		Priority:  models.PriorityNormal,
+		Recipient: "customer@example.com",
+		Subject:   "Your order shipped",
+		Body:      "Reply to this email with any questions",
+		EmailData: &models.EmailData{},
+	})
+	require.NoError(t, err)
+
+	provider, err := dispatcher.GetProvider(models.NotificationTypeEmail)
+	require.NoError(t, err)
+	sent := provider.(*providers.MockEmailProvider).GetSentEmails()
+	require.Len(t, sent, 1)
+	replyTo := sent[0].ReplyTo
+	require.NotEmpty(t, replyTo)
+
+	form := url.Values{
+		"from":     {"customer@example.com"},
+		"subject":  {"Re: Your order shipped"},
+		"text":     {"Where is it now?"},
+		"envelope": {`{"from":"customer@example.com","to":["` + replyTo + `"]}`},
+	}
+	request := httptest.NewRequest(http.MethodPost, "/v1/inbound/sendgrid", strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+	var result inbound.Result
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+	assert.Equal(t, "replied", result.Status)
+	assert.Equal(t, response.ID.String(), result.NotificationID)
+
+	require.Len(t, *replies, 1)
+	assert.Equal(t, response.ID, (*replies)[0].NotificationID)
+	assert.Equal(t, "Where is it now?", (*replies)[0].Metadata[inbound.MetadataReplyText])
+}
+
+func TestServer_InboundSES(t *testing.T) {
+	server, _, replies := createTestInboundServer(t)
+
+	recorder := serve(server, http.MethodPost, "/v1/inbound/ses", []byte(`{"Type":"SubscriptionConfirmation","SubscribeURL":"https://sns.example.com/confirm"}`))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	// Mail to other addresses on the domain is acknowledged and ignored
+	recorder = serve(server, http.MethodPost, "/v1/inbound/ses", []byte(`{"notificationType":"Received","mail":{"source":"a@example.com","destination":["sales@replies.example.com"]}}`))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var result inbound.Result
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+	assert.Equal(t, "ignored", result.Status)
+
+	recorder = serve(server, http.MethodPost, "/v1/inbound/ses", []byte(`not json`))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	assert.Empty(t, *replies)
+}
+
+func TestServer_InboundDuplicateProcessedOnce(t *testing.T) {
+	server, dispatcher, replies := createTestInboundServer(t)
+	server.SetWebhookDedup(webhookdedup.NewDeduplicator(config.WebhookDedupConfig{}, webhookdedup.NewMemoryStore(), utils.NewSimpleLogger("error")))
+
+	_, err := dispatcher.SendNotification(context.Background(), &models.NotificationRequest{
+		Type:      models.NotificationTypeEmail,
+		Priority:  models.PriorityNormal,
+		Recipient: "customer@example.com",
+		Subject:   "Your order shipped",
+		Body:      "Reply to this email with any questions",
+		EmailData: &models.EmailData{},
+	})
+	require.NoError(t, err)
+	provider, err := dispatcher.GetProvider(models.NotificationTypeEmail)
+	require.NoError(t, err)
+	replyTo := provider.(*providers.MockEmailProvider).GetSentEmails()[0].ReplyTo
+
+	form := url.Values{
+		"from":     {"customer@example.com"},
+		"text":     {"Where is it now?"},
+		"envelope": {`{"from":"customer@example.com","to":["` + replyTo + `"]}`},
+		"headers":  {"Message-ID: <reply-1@example.com>"},
+	}
+	var statuses []string
+	for i := 0; i < 2; i++ {
+		request := httptest.NewRequest(http.MethodPost, "/v1/inbound/sendgrid", strings.NewReader(form.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+		var result inbound.Result
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+		statuses = append(statuses, result.Status)
+	}
+
+	// The retried post is acknowledged without firing the reply again
+	assert.Equal(t, []string{"replied", "ignored"}, statuses)
+	assert.Len(t, *replies, 1)
+}
+
+func TestServer_InboundRoutesNotInOpenAPI(t *testing.T) {
+	server, _, _ := createTestInboundServer(t)
+
+	doc := server.OpenAPI()
+	assert.NotContains(t, doc.Paths, "/v1/inbound/sendgrid")
+	assert.NotContains(t, doc.Paths, "/v1/inbound/ses")
+}
+
+// Helper functions
+
+func createTestInboundServer(t *testing.T) (*Server, *services.Dispatcher, *[]events.Event) {
+	repo := repository.NewMemoryRepository()
+	dispatcher, err := services.NewDispatcherFromConfig(config.ProvidersConfig{
+		Email: config.EmailProviderConfig{Provider: "mock", Enabled: true},
+	}, repo, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+
+	bus := events.NewBus(utils.NewSimpleLogger("info"))
+	var replies []events.Event
+	bus.Subscribe(events.SubscriberFunc(func(ctx context.Context, event events.Event) error {
+		replies = append(replies, event)
+		return nil
+	}), events.EventNotificationReplied)
+
+	router, err := inbound.NewRouter(config.InboundConfig{ReplyDomain: "replies.example.com", SigningKey: "secret"}, repo, bus, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	dispatcher.SetReplyRouter(router)
+
+	server := NewServer(dispatcher, utils.NewSimpleLogger("info"))
+	server.SetInbound(router)
+	return server, dispatcher, &replies
+}
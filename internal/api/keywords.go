@@ -0,0 +1,60 @@
+package api
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/keywords"
+	"github.com/nareshkumar-microsoft/notificationService/internal/webhookauth"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// emptyTwiML tells Twilio not to reply; confirmations are sent separately
+const emptyTwiML = `<?xml version="1.0" encoding="UTF-8"?><Response></Response>`
+
+// SetSMSKeywords adds the inbound SMS webhook that handles STOP, START and
+// HELP keywords. It is called by the SMS provider rather than clients, so it
+// is left out of the OpenAPI document.
+func (s *Server) SetSMSKeywords(handler *keywords.Handler) {
+	s.routes = append(s.routes,
+		route{method: http.MethodPost, path: "/v1/inbound/sms", handler: s.handleInboundSMS(handler), internal: true, public: true, webhook: webhookauth.SourceTwilio},
+	)
+}
+
+// handleInboundSMS handles an inbound SMS webhook post
+func (s *Server) handleInboundSMS(handler *keywords.Handler) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		message, err := keywords.ParseRequest(w, r)
+		if err != nil {
+			errors.WriteProblem(w, r, err)
+			return
+		}
+
+		source := message.Provider
+		if source == "" {
+			source = "sms"
+		}
+		var result keywords.Result
+		duplicate, err := s.processOnce(r, source, message.MessageID, func() error {
+			var err error
+			result, err = handler.Handle(r.Context(), message)
+			return err
+		})
+		if err != nil {
+			s.logger.Errorf("Inbound SMS from %s failed: %v", message.From, err)
+			errors.WriteProblem(w, r, err)
+			return
+		}
+		if duplicate {
+			result = keywords.Result{Action: keywords.ActionIgnored}
+		}
+
+		if message.IsTwilio() {
+			w.Header().Set("Content-Type", "text/xml")
+			w.WriteHeader(http.StatusOK)
+			_, _ = io.WriteString(w, emptyTwiML)
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+	}
+}
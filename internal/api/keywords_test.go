@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/keywords"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/preferences"
+	"github.com/nareshkumar-microsoft/notificationService/internal/repository"
+	"github.com/nareshkumar-microsoft/notificationService/internal/services"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+func TestServer_InboundSMSStop(t *testing.T) {
+	server, dispatcher := createTestKeywordServer(t)
+	request := &models.NotificationRequest{
+		Type:      models.NotificationTypeSMS,
+		Priority:  models.PriorityNormal,
+		Category:  models.CategoryMarketing,
+		Recipient: "+14155550123",
+		Body:      "20% off this weekend",
+	}
+	_, err := dispatcher.SendNotification(context.Background(), request)
+	require.NoError(t, err)
+
+	form := url.Values{"From": {"+14155550123"}, "To": {"+14155550100"}, "Body": {"STOP"}, "MessageSid": {"SM123"}}
+	post := httptest.NewRequest(http.MethodPost, "/v1/inbound/sms", strings.NewReader(form.Encode()))
+	post.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, post)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	assert.Equal(t, "text/xml", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, emptyTwiML, recorder.Body.String())
+
+	// Further marketing SMS are suppressed
+	_, err = dispatcher.SendNotification(context.Background(), request)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeRecipientOptedOut, notifErr.Code)
+
+	recorder = serve(server, http.MethodPost, "/v1/inbound/sms", []byte(`{"to":"+14155550100","body":"STOP"}`))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+// Helper functions
+
+func createTestKeywordServer(t *testing.T) (*Server, *services.Dispatcher) {
+	dispatcher, err := services.NewDispatcherFromConfig(config.ProvidersConfig{
+		SMS: config.SMSProviderConfig{Provider: "mock", Enabled: true},
+	}, repository.NewMemoryRepository(), utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+
+	store := preferences.NewStore()
+	require.NoError(t, dispatcher.SetPreferences(preferences.NewEnforcer(store, utils.NewSimpleLogger("info"))))
+
+	provider, err := dispatcher.GetProvider(models.NotificationTypeSMS)
+	require.NoError(t, err)
+	handler, err := keywords.NewHandler(config.KeywordsConfig{}, store, provider.(interfaces.SMSProvider), utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+
+	server := NewServer(dispatcher, utils.NewSimpleLogger("info"))
+	server.SetSMSKeywords(handler)
+	return server, dispatcher
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/loadshed"
+)
+
+// SetLoadShedder sheds sends according to the shedder's policy and adds the
+// route reporting load shedding metrics. Shed sends are answered with 429 or
+// 503 and a Retry-After header.
+func (s *Server) SetLoadShedder(shedder *loadshed.Shedder) {
+	s.shedder = shedder
+
+	for i := range s.routes {
+		if s.routes[i].operationID == "sendNotification" {
+			s.routes[i].errors = append(s.routes[i].errors, http.StatusTooManyRequests)
+		}
+	}
+
+	s.routes = append(s.routes, route{
+		method:      http.MethodGet,
+		path:        "/v1/load",
+		operationID: "getLoad",
+		summary:     "Get the load level and load shedding metrics",
+		tag:         "health",
+		response:    loadshed.Stats{},
+		status:      http.StatusOK,
+		handler:     s.handleLoad,
+	})
+}
+
+// handleLoad reports the load level and shedding metrics
+func (s *Server) handleLoad(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+	writeJSON(w, http.StatusOK, s.shedder.Stats())
+}
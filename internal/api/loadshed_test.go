@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/loadshed"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestServer_LoadShedding(t *testing.T) {
+	server := createTestServer(t)
+	shedder, err := loadshed.NewShedder(config.LoadShedConfig{
+		QueueDepthElevated: 10,
+		ShedElevated:       []string{"low"},
+		RetryAfter:         15 * time.Second,
+	}, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	depth := 50
+	shedder.SetQueueDepth(func() int { return depth })
+	server.SetLoadShedder(shedder)
+
+	recorder := serve(server, http.MethodPost, "/v1/notifications", createTestSendBody(t, models.PriorityLow))
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, "15", recorder.Header().Get("Retry-After"))
+	problem, err := errors.ParseProblem(recorder.Body.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, errors.ErrorCodeRateLimited, problem.Code)
+
+	// Urgent notifications are still accepted; the send fails later at the
+	// unreachable webhook, not at the shedder
+	recorder = serve(server, http.MethodPost, "/v1/notifications", createTestSendBody(t, models.PriorityUrgent))
+	assert.NotEqual(t, http.StatusTooManyRequests, recorder.Code)
+
+	recorder = serve(server, http.MethodGet, "/v1/load", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var stats loadshed.Stats
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &stats))
+	assert.Equal(t, loadshed.LevelElevated, stats.Level)
+	assert.Equal(t, int64(1), stats.Shed[models.PriorityLow])
+	assert.Equal(t, int64(1), stats.Accepted)
+
+	doc := server.OpenAPI()
+	require.Contains(t, doc.Paths, "/v1/load")
+	assert.Contains(t, (*doc.Paths["/v1/notifications"])["post"].Responses, "429")
+}
+
+// Helper functions
+
+func createTestSendBody(t *testing.T, priority models.Priority) []byte {
+	body, err := json.Marshal(models.NotificationRequest{
+		Type:      models.NotificationTypeChat,
+		Priority:  priority,
+		Recipient: "http://127.0.0.1:1/hook",
+		Body:      "Deploy finished",
+	})
+	require.NoError(t, err)
+	return body
+}
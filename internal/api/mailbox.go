@@ -0,0 +1,250 @@
+package api
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/internal/smtpsink"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// Mailbox holds the messages the mock providers sent, and the mail the SMTP
+// sink captured, newest first
+type Mailbox struct {
+	Emails []providers.SentEmail `json:"emails"`
+	SMS    []providers.SentSMS   `json:"sms"`
+	Push   []providers.SentPush  `json:"push"`
+	SMTP   []smtpsink.Message    `json:"smtp,omitempty"` // only with an SMTP sink
+}
+
+// EnableMailbox adds the development mailbox: a page at /dev/mailbox and
+// JSON at /dev/mailbox/messages listing what the mock providers sent, with
+// full rendered content, searchable by recipient. It shows message content
+// to anyone who can reach the server, so enable it for local development
+// only. Channels without a mock provider are left out.
+func (s *Server) EnableMailbox() {
+	s.routes = append(s.routes,
+		route{method: http.MethodGet, path: "/dev/mailbox", handler: s.handleMailboxPage, internal: true},
+		route{method: http.MethodGet, path: "/dev/mailbox/messages", handler: s.handleMailbox, internal: true},
+		route{method: http.MethodDelete, path: "/dev/mailbox/messages", handler: s.handleClearMailbox, internal: true},
+	)
+}
+
+// SetSMTPSink adds the mail an SMTP sink captured to the mailbox, as email
+func (s *Server) SetSMTPSink(sink *smtpsink.Server) {
+	s.smtpSink = sink
+}
+
+// handleMailbox lists the mock providers' messages. The "recipient" query
+// parameter keeps those with a recipient containing it, and "channel"
+// keeps one channel's.
+func (s *Server) handleMailbox(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	query := r.URL.Query()
+	writeJSON(w, http.StatusOK, s.mailbox(query.Get("recipient"), models.NotificationType(query.Get("channel"))))
+}
+
+// handleClearMailbox removes the mock providers' messages
+func (s *Server) handleClearMailbox(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+	for _, provider := range s.service.ListProviders() {
+		switch mock := mockProvider(provider).(type) {
+		case *providers.MockEmailProvider:
+			mock.ClearSentEmails()
+		case *providers.MockSMSProvider:
+			mock.ClearSentSMS()
+		case *providers.MockPushProvider:
+			mock.ClearSentPush()
+		}
+	}
+	if s.smtpSink != nil {
+		s.smtpSink.Clear()
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleMailboxPage renders the mailbox as HTML
+func (s *Server) handleMailboxPage(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	query := r.URL.Query()
+	page := mailboxPageData{
+		Recipient: query.Get("recipient"),
+		Channel:   query.Get("channel"),
+		Sink:      s.smtpSink != nil,
+	}
+	page.Mailbox = s.mailbox(page.Recipient, models.NotificationType(page.Channel))
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if err := mailboxPage.Execute(w, page); err != nil {
+		s.logger.Errorf("Failed to render the mailbox: %v", err)
+	}
+}
+
+// mailbox collects the mock providers' messages to recipients containing a
+// query, of one channel or of all when the channel is empty
+func (s *Server) mailbox(recipient string, channel models.NotificationType) Mailbox {
+	mailbox := Mailbox{Emails: []providers.SentEmail{}, SMS: []providers.SentSMS{}, Push: []providers.SentPush{}}
+	for notificationType, provider := range s.service.ListProviders() {
+		if channel != "" && !strings.EqualFold(string(channel), string(notificationType)) {
+			continue
+		}
+		switch mock := mockProvider(provider).(type) {
+		case *providers.MockEmailProvider:
+			mailbox.Emails = newestFirst(mock.SentEmails().Search(recipient))
+		case *providers.MockSMSProvider:
+			mailbox.SMS = newestFirst(mock.SentMessages().Search(recipient))
+		case *providers.MockPushProvider:
+			mailbox.Push = newestFirst(mock.SentPushes().Search(recipient))
+		}
+	}
+	if s.smtpSink != nil && (channel == "" || strings.EqualFold(string(channel), string(models.NotificationTypeEmail))) {
+		mailbox.SMTP = newestFirst(s.smtpSink.Search(recipient))
+	}
+	return mailbox
+}
+
+// mockProvider returns the provider a wrapper, such as per-domain email
+// throttling or attachment scanning, sends through
+func mockProvider(provider interfaces.NotificationProvider) interfaces.NotificationProvider {
+	for {
+		switch wrapper := provider.(type) {
+		case *providers.ThrottledEmailProvider:
+			provider = wrapper.Unwrap()
+		case *providers.ScanningEmailProvider:
+			provider = wrapper.Unwrap()
+		case *providers.OffloadingEmailProvider:
+			provider = wrapper.Unwrap()
+		case *providers.CountryRoutedSMSProvider:
+			provider = wrapper.Unwrap()
+		default:
+			return provider
+		}
+	}
+}
+
+// newestFirst reverses messages kept oldest first
+func newestFirst[T any](messages []T) []T {
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages
+}
+
+// mailboxPageData is what the mailbox page renders
+type mailboxPageData struct {
+	Mailbox
+	Recipient string
+	Channel   string
+	Sink      bool // an SMTP sink is capturing mail
+}
+
+// mailboxPage lists the mailbox's messages. Email HTML renders in a
+// sandboxed frame so its scripts and styles cannot reach the page.
+var mailboxPage = template.Must(template.New("mailbox").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Mailbox - Notification Service</title>
+  <style>
+    body { font-family: sans-serif; margin: 2em; color: #222; }
+    form { margin-bottom: 1.5em; }
+    section { margin-bottom: 2em; }
+    article { border: 1px solid #ddd; border-radius: 4px; padding: 0.75em 1em; margin-bottom: 1em; }
+    dl { display: grid; grid-template-columns: max-content auto; gap: 0.25em 1em; margin: 0 0 0.5em; }
+    dt { color: #666; }
+    dd { margin: 0; }
+    pre { white-space: pre-wrap; background: #f6f6f6; padding: 0.5em; }
+    iframe { width: 100%; height: 320px; border: 1px solid #eee; }
+  </style>
+</head>
+<body>
+  <h1>Mailbox</h1>
+  <form method="get" action="/dev/mailbox">
+    <input type="search" name="recipient" value="{{.Recipient}}" placeholder="Recipient">
+    <select name="channel">
+      <option value="" {{if eq .Channel ""}}selected{{end}}>All channels</option>
+      <option value="email" {{if eq .Channel "email"}}selected{{end}}>Email</option>
+      <option value="sms" {{if eq .Channel "sms"}}selected{{end}}>SMS</option>
+      <option value="push" {{if eq .Channel "push"}}selected{{end}}>Push</option>
+    </select>
+    <button type="submit">Search</button>
+  </form>
+
+  <section>
+    <h2>Email ({{len .Emails}})</h2>
+    {{range .Emails}}
+    <article>
+      <dl>
+        <dt>To</dt><dd>{{range $i, $to := .To}}{{if $i}}, {{end}}{{$to}}{{end}}</dd>
+        {{if .CC}}<dt>CC</dt><dd>{{range $i, $cc := .CC}}{{if $i}}, {{end}}{{$cc}}{{end}}</dd>{{end}}
+        <dt>From</dt><dd>{{.From}}</dd>
+        <dt>Subject</dt><dd>{{.Subject}}</dd>
+        <dt>Sent</dt><dd>{{.SentAt.Format "2006-01-02 15:04:05"}} ({{.Status}})</dd>
+        {{if .Attachments}}<dt>Attachments</dt><dd>{{range $i, $a := .Attachments}}{{if $i}}, {{end}}{{$a.Filename}}{{end}}</dd>{{end}}
+      </dl>
+      {{if .HTMLBody}}<iframe sandbox srcdoc="{{.HTMLBody}}" title="{{.Subject}}"></iframe>{{end}}
+      {{if .TextBody}}<pre>{{.TextBody}}</pre>{{end}}
+    </article>
+    {{else}}
+    <p>No email.</p>
+    {{end}}
+  </section>
+
+  {{if .Sink}}
+  <section>
+    <h2>Captured by the SMTP sink ({{len .SMTP}})</h2>
+    {{range .SMTP}}
+    <article>
+      <dl>
+        <dt>To</dt><dd>{{range $i, $to := .Recipients}}{{if $i}}, {{end}}{{$to}}{{end}}</dd>
+        <dt>From</dt><dd>{{.From}}</dd>
+        <dt>Subject</dt><dd>{{.Subject}}</dd>
+        <dt>Received</dt><dd>{{.ReceivedAt.Format "2006-01-02 15:04:05"}} ({{.Size}} bytes)</dd>
+        {{if .Attachments}}<dt>Attachments</dt><dd>{{range $i, $a := .Attachments}}{{if $i}}, {{end}}{{$a.Filename}}{{end}}</dd>{{end}}
+      </dl>
+      {{if .HTMLBody}}<iframe sandbox srcdoc="{{.HTMLBody}}" title="{{.Subject}}"></iframe>{{end}}
+      {{if .TextBody}}<pre>{{.TextBody}}</pre>{{end}}
+    </article>
+    {{else}}
+    <p>No captured mail.</p>
+    {{end}}
+  </section>
+  {{end}}
+
+  <section>
+    <h2>SMS ({{len .SMS}})</h2>
+    {{range .SMS}}
+    <article>
+      <dl>
+        <dt>To</dt><dd>{{.PhoneNumber}}{{if .CountryCode}} ({{.CountryCode}}){{end}}</dd>
+        {{if .SenderID}}<dt>From</dt><dd>{{.SenderID}}</dd>{{end}}
+        <dt>Sent</dt><dd>{{.SentAt.Format "2006-01-02 15:04:05"}} ({{.Status}}, {{.Segments}} segments)</dd>
+      </dl>
+      <pre>{{.Message}}</pre>
+    </article>
+    {{else}}
+    <p>No SMS.</p>
+    {{end}}
+  </section>
+
+  <section>
+    <h2>Push ({{len .Push}})</h2>
+    {{range .Push}}
+    <article>
+      <dl>
+        <dt>To</dt><dd>{{.DeviceToken}} ({{.Platform}})</dd>
+        <dt>Title</dt><dd>{{.Title}}</dd>
+        <dt>Sent</dt><dd>{{.SentAt.Format "2006-01-02 15:04:05"}} ({{.Status}})</dd>
+      </dl>
+      <pre>{{.Message}}</pre>
+      {{if .Data}}<pre>{{range $key, $value := .Data}}{{$key}}: {{$value}}
+{{end}}</pre>{{end}}
+    </article>
+    {{else}}
+    <p>No push notifications.</p>
+    {{end}}
+  </section>
+</body>
+</html>
+`))
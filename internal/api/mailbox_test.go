@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/smtp"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/internal/repository"
+	"github.com/nareshkumar-microsoft/notificationService/internal/services"
+	"github.com/nareshkumar-microsoft/notificationService/internal/smtpsink"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+)
+
+func TestServer_Mailbox(t *testing.T) {
+	server, email, sms := createTestMailboxServer(t)
+	for _, to := range []string{"ana@example.com", "bo@example.org"} {
+		_, err := email.SendEmail(context.Background(), &models.EmailNotification{
+			Notification: models.Notification{ID: uuid.New(), Type: models.NotificationTypeEmail, Subject: "Welcome"},
+			To:           []string{to},
+			HTMLBody:     `<p>Hello <b>there</b></p>`,
+		})
+		require.NoError(t, err)
+	}
+	_, err := sms.SendSMS(context.Background(), &models.SMSNotification{
+		Notification: models.Notification{ID: uuid.New(), Type: models.NotificationTypeSMS},
+		PhoneNumber:  "+14155550100",
+		Message:      "Your code is 123456",
+	})
+	require.NoError(t, err)
+
+	recorder := serve(server, http.MethodGet, "/dev/mailbox/messages", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var mailbox Mailbox
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &mailbox))
+	require.Len(t, mailbox.Emails, 2)
+	assert.Equal(t, "bo@example.org", mailbox.Emails[0].To[0])
+	require.Len(t, mailbox.SMS, 1)
+	assert.Equal(t, "Your code is 123456", mailbox.SMS[0].Message)
+	assert.Empty(t, mailbox.Push)
+
+	recorder = serve(server, http.MethodGet, "/dev/mailbox/messages?recipient=EXAMPLE.COM&channel=email", nil)
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &mailbox))
+	require.Len(t, mailbox.Emails, 1)
+	assert.Equal(t, "ana@example.com", mailbox.Emails[0].To[0])
+	assert.Empty(t, mailbox.SMS)
+
+	recorder = serve(server, http.MethodGet, "/dev/mailbox?recipient=ana", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Header().Get("Content-Type"), "text/html")
+	page := recorder.Body.String()
+	assert.Contains(t, page, "ana@example.com")
+	assert.NotContains(t, page, "bo@example.org")
+	assert.Contains(t, page, `srcdoc="&lt;p&gt;Hello &lt;b&gt;there&lt;/b&gt;&lt;/p&gt;"`)
+
+	recorder = serve(server, http.MethodDelete, "/dev/mailbox/messages", nil)
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Empty(t, email.GetSentEmails())
+	assert.Empty(t, sms.GetSentSMS())
+
+	// The mailbox is not part of the published API
+	assert.NotContains(t, server.OpenAPI().Paths, "/dev/mailbox")
+}
+
+func TestServer_MailboxSMTPSink(t *testing.T) {
+	server, _, _ := createTestMailboxServer(t)
+	sink := smtpsink.NewServer(config.SMTPSinkConfig{Addr: "127.0.0.1:0"}, utils.NewSimpleLogger("error"))
+	require.NoError(t, sink.Start(context.Background()))
+	t.Cleanup(sink.Stop)
+	server.SetSMTPSink(sink)
+
+	message := "Subject: Captured\r\nContent-Type: text/html\r\n\r\n<p>Hi</p>\r\n"
+	require.NoError(t, smtp.SendMail(sink.Addr(), nil, "alerts@example.com", []string{"ana@example.com"}, []byte(message)))
+
+	recorder := serve(server, http.MethodGet, "/dev/mailbox/messages?channel=email", nil)
+	var mailbox Mailbox
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &mailbox))
+	require.Len(t, mailbox.SMTP, 1)
+	assert.Equal(t, "Captured", mailbox.SMTP[0].Subject)
+	assert.Equal(t, "<p>Hi</p>\n", mailbox.SMTP[0].HTMLBody)
+
+	// Captured mail is email
+	recorder = serve(server, http.MethodGet, "/dev/mailbox/messages?channel=sms", nil)
+	assert.NotContains(t, recorder.Body.String(), `"smtp"`)
+
+	page := serve(server, http.MethodGet, "/dev/mailbox", nil).Body.String()
+	assert.Contains(t, page, "Captured by the SMTP sink (1)")
+	assert.Contains(t, page, `srcdoc="&lt;p&gt;Hi&lt;/p&gt;`)
+
+	serve(server, http.MethodDelete, "/dev/mailbox/messages", nil)
+	assert.Empty(t, sink.Messages())
+}
+
+func TestServer_MailboxDisabled(t *testing.T) {
+	server := createTestServer(t)
+	recorder := serve(server, http.MethodGet, "/dev/mailbox", nil)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+// Helper functions
+
+func createTestMailboxServer(t *testing.T) (*Server, *providers.MockEmailProvider, *providers.MockSMSProvider) {
+	dispatcher := services.NewDispatcher(repository.NewMemoryRepository(), utils.NewSimpleLogger("error"))
+	email := providers.NewMockEmailProvider(config.EmailProviderConfig{Provider: "mock", Enabled: true})
+	email.SetSimulation(providers.Simulation{})
+	sms := providers.NewMockSMSProvider(config.SMSProviderConfig{Provider: "mock", Enabled: true})
+	sms.SetSimulation(providers.Simulation{})
+	require.NoError(t, dispatcher.RegisterProvider(providers.NewThrottledEmailProvider(email, config.EmailProviderConfig{})))
+	require.NoError(t, dispatcher.RegisterProvider(sms))
+
+	server := NewServer(dispatcher, utils.NewSimpleLogger("error"))
+	server.EnableMailbox()
+	return server, email, sms
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/numberlookup"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// SetNumberLookup adds the route that looks up a phone number's carrier,
+// line type and portability
+func (s *Server) SetNumberLookup(lookup *numberlookup.Service) {
+	s.routes = append(s.routes, route{
+		method:      http.MethodGet,
+		path:        "/v1/phone-numbers/{number}",
+		operationID: "lookupPhoneNumber",
+		summary:     "Look up the carrier, line type (mobile, landline or VoIP) and portability of a phone number in international format",
+		tag:         "sms",
+		response:    numberlookup.Result{},
+		status:      http.StatusOK,
+		errors:      []int{http.StatusBadRequest, http.StatusServiceUnavailable},
+		handler:     s.handleLookupPhoneNumber(lookup),
+	})
+}
+
+// handleLookupPhoneNumber looks up a phone number
+func (s *Server) handleLookupPhoneNumber(lookup *numberlookup.Service) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		result, err := lookup.LookupPhoneNumber(r.Context(), params["number"])
+		if err != nil {
+			errors.WriteProblem(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, result)
+	}
+}
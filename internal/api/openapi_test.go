@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+)
+
+func TestSchemaGenerator_Struct(t *testing.T) {
+	generator := NewSchemaGenerator()
+	generator.Enum(models.Priority(""), "low", "normal", "high", "urgent")
+
+	ref := generator.Schema(models.NotificationRequest{})
+	assert.Equal(t, "#/components/schemas/NotificationRequest", ref.Ref)
+
+	schema := generator.Components()["NotificationRequest"]
+	require.NotNil(t, schema)
+	assert.Equal(t, "object", schema.Type)
+	assert.Equal(t, []string{"body", "priority", "recipient", "type"}, schema.Required)
+
+	// validate:"oneof=..." and registered enums both become enums
+	assert.Equal(t, []string{"email", "sms", "push", "chat", "voice"}, schema.Properties["type"].Enum)
+	assert.Equal(t, []string{"low", "normal", "high", "urgent"}, schema.Properties["priority"].Enum)
+
+	assert.Equal(t, &Schema{Type: "string", Format: "date-time"}, schema.Properties["scheduled_at"])
+	assert.Equal(t, "string", schema.Properties["metadata"].AdditionalProperties.Type)
+	assert.Equal(t, "#/components/schemas/EmailData", schema.Properties["email_data"].Ref)
+	assert.Contains(t, generator.Components(), "EmailData")
+}
+
+func TestSchemaGenerator_EmbeddedAndSpecialTypes(t *testing.T) {
+	generator := NewSchemaGenerator()
+
+	generator.Schema(models.EmailNotification{})
+	schema := generator.Components()["EmailNotification"]
+	require.NotNil(t, schema)
+
+	// Embedded Notification fields are flattened into the parent
+	assert.Equal(t, &Schema{Type: "string", Format: "uuid"}, schema.Properties["id"])
+	assert.Equal(t, "array", schema.Properties["to"].Type)
+	assert.NotContains(t, generator.Components(), "Notification")
+
+	attachment := generator.Components()["EmailAttachment"]
+	require.NotNil(t, attachment)
+	assert.Equal(t, &Schema{Type: "string", Format: "byte"}, attachment.Properties["content"])
+	assert.Equal(t, &Schema{Type: "integer", Format: "int64"}, attachment.Properties["size"])
+}
+
+func TestSchemaGenerator_SkipsIgnoredAndUnexportedFields(t *testing.T) {
+	type sample struct {
+		Name    string        `json:"name"`
+		Secret  string        `json:"-"`
+		Any     interface{}   `json:"any"`
+		Timeout time.Duration `json:"timeout"`
+		hidden  string
+	}
+
+	generator := NewSchemaGenerator()
+	generator.Schema(sample{})
+
+	schema := generator.Components()["sample"]
+	require.NotNil(t, schema)
+	assert.Len(t, schema.Properties, 3)
+	assert.Equal(t, &Schema{}, schema.Properties["any"])
+	assert.Equal(t, "integer", schema.Properties["timeout"].Type)
+}
+
+func TestServer_OpenAPI(t *testing.T) {
+	doc := createTestServer(t).OpenAPI()
+
+	assert.Equal(t, OpenAPIVersion, doc.OpenAPI)
+	assert.Equal(t, APIVersion, doc.Info.Version)
+	assert.NotContains(t, doc.Paths, "/openapi.json")
+	assert.NotContains(t, doc.Paths, "/docs")
+
+	send := (*doc.Paths["/v1/notifications"])["post"]
+	require.NotNil(t, send)
+	assert.Equal(t, "sendNotification", send.OperationID)
+	assert.Equal(t, "#/components/schemas/NotificationRequest", send.RequestBody.Content["application/json"].Schema.Ref)
+	assert.Equal(t, "#/components/schemas/NotificationResponse", send.Responses["202"].Content["application/json"].Schema.Ref)
+	assert.Equal(t, "#/components/schemas/Problem", send.Responses["400"].Content["application/problem+json"].Schema.Ref)
+	assert.Contains(t, doc.Components.Schemas["Problem"].Properties["code"].Enum, "VALIDATION_FAILED")
+
+	get := (*doc.Paths["/v1/notifications/{id}"])["get"]
+	require.NotNil(t, get)
+	require.Len(t, get.Parameters, 1)
+	assert.Equal(t, Parameter{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string"}}, get.Parameters[0])
+
+	resend := (*doc.Paths["/v1/notifications/{id}/resend"])["post"]
+	require.NotNil(t, resend)
+	assert.Equal(t, "#/components/schemas/ResendOptions", resend.RequestBody.Content["application/json"].Schema.Ref)
+
+	status := doc.Components.Schemas["Notification"].Properties["status"]
+	assert.Equal(t, []string{"pending", "sent", "delivered", "failed", "retrying"}, status.Enum)
+
+	// The document must serialize to valid JSON with $ref keys
+	data, err := json.Marshal(doc)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"$ref":"#/components/schemas/NotificationRequest"`)
+}
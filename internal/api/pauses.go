@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pause"
+	"github.com/nareshkumar-microsoft/notificationService/internal/validation"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// PauseRequest is the body of a pause request
+type PauseRequest struct {
+	Reason string `json:"reason,omitempty" validate:"max=500"`
+}
+
+// SetPauseController adds the routes that list, pause and resume channels
+// and providers
+func (s *Server) SetPauseController(controller *pause.Controller) {
+	s.routes = append(s.routes,
+		route{
+			method:      http.MethodGet,
+			path:        "/v1/pauses",
+			operationID: "listPauses",
+			summary:     "List paused channels and providers",
+			tag:         "pauses",
+			response:    []pause.Pause{},
+			status:      http.StatusOK,
+			handler:     s.handleListPauses(controller),
+		},
+		route{
+			method:      http.MethodPut,
+			path:        "/v1/pauses/{scope}/{target}",
+			operationID: "pause",
+			summary:     "Pause sending on a channel or through a provider; queued notifications are held",
+			tag:         "pauses",
+			request:     PauseRequest{},
+			response:    pause.Pause{},
+			status:      http.StatusOK,
+			errors:      []int{http.StatusBadRequest},
+			handler:     s.handlePause(controller),
+		},
+		route{
+			method:      http.MethodDelete,
+			path:        "/v1/pauses/{scope}/{target}",
+			operationID: "resume",
+			summary:     "Resume sending on a channel or through a provider; held notifications drain at the configured rate",
+			tag:         "pauses",
+			status:      http.StatusNoContent,
+			errors:      []int{http.StatusBadRequest, http.StatusNotFound},
+			handler:     s.handleResume(controller),
+		},
+	)
+}
+
+// handleListPauses lists the active pauses
+func (s *Server) handleListPauses(controller *pause.Controller) handlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+		writeJSON(w, http.StatusOK, controller.Paused())
+	}
+}
+
+// handlePause pauses a channel or provider. The request body is optional.
+func (s *Server) handlePause(controller *pause.Controller) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		var request PauseRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&request); err != nil && err != io.EOF {
+			errors.WriteProblem(w, r, errors.NewNotificationErrorWithDetails(
+				errors.ErrorCodeInvalidRequest, "request body is not a valid pause request", err.Error()))
+			return
+		}
+
+		if err := validation.Struct(&request); err != nil {
+			errors.WriteProblem(w, r, err)
+			return
+		}
+
+		scope, target := pause.Scope(params["scope"]), params["target"]
+		var paused pause.Pause
+		var err error
+		switch scope {
+		case pause.ScopeChannel:
+			paused, err = controller.PauseChannel(models.NotificationType(target), request.Reason)
+		case pause.ScopeProvider:
+			paused, err = controller.PauseProvider(target, request.Reason)
+		default:
+			err = unknownScope(scope)
+		}
+		if err != nil {
+			errors.WriteProblem(w, r, err)
+			return
+		}
+
+		s.logger.Infof("API paused %s %s", scope, target)
+		writeJSON(w, http.StatusOK, paused)
+	}
+}
+
+// handleResume resumes a paused channel or provider
+func (s *Server) handleResume(controller *pause.Controller) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		scope, target := pause.Scope(params["scope"]), params["target"]
+		var resumed bool
+		switch scope {
+		case pause.ScopeChannel:
+			resumed = controller.ResumeChannel(models.NotificationType(target))
+		case pause.ScopeProvider:
+			resumed = controller.ResumeProvider(target)
+		default:
+			errors.WriteProblem(w, r, unknownScope(scope))
+			return
+		}
+
+		if !resumed {
+			errors.WriteProblem(w, r, errors.NewNotificationError(errors.ErrorCodeNotFound, fmt.Sprintf("%s %s is not paused", scope, target)))
+			return
+		}
+
+		s.logger.Infof("API resumed %s %s", scope, target)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// unknownScope returns the error for a pause scope that is neither a channel nor a provider
+func unknownScope(scope pause.Scope) error {
+	return errors.NewValidationError("scope", fmt.Sprintf("unknown pause scope %q, expected %s or %s", scope, pause.ScopeChannel, pause.ScopeProvider))
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pause"
+	"github.com/nareshkumar-microsoft/notificationService/internal/services"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestServer_PauseAndResume(t *testing.T) {
+	server := createTestPauseServer(t)
+
+	recorder := serve(server, http.MethodPut, "/v1/pauses/channel/chat", []byte(`{"reason":"webhook outage"}`))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var paused pause.Pause
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &paused))
+	assert.Equal(t, pause.ScopeChannel, paused.Scope)
+	assert.Equal(t, "chat", paused.Target)
+	assert.Equal(t, "webhook outage", paused.Reason)
+
+	// The body is optional
+	recorder = serve(server, http.MethodPut, "/v1/pauses/provider/slack", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = serve(server, http.MethodGet, "/v1/pauses", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var pauses []pause.Pause
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &pauses))
+	assert.Len(t, pauses, 2)
+
+	// Sends on a paused channel are rejected as unavailable
+	body, err := json.Marshal(models.NotificationRequest{
+		Type:      models.NotificationTypeChat,
+		Priority:  models.PriorityNormal,
+		Recipient: "https://hooks.example.com/x",
+		Body:      "paused",
+	})
+	require.NoError(t, err)
+	recorder = serve(server, http.MethodPost, "/v1/notifications", body)
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+	recorder = serve(server, http.MethodDelete, "/v1/pauses/channel/chat", nil)
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	recorder = serve(server, http.MethodDelete, "/v1/pauses/provider/slack", nil)
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+
+	recorder = serve(server, http.MethodDelete, "/v1/pauses/channel/chat", nil)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestServer_PauseErrors(t *testing.T) {
+	server := createTestPauseServer(t)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		code   errors.ErrorCode
+	}{
+		{"unknown scope", http.MethodPut, "/v1/pauses/region/eu", errors.ErrorCodeValidationFailed},
+		{"unknown channel", http.MethodPut, "/v1/pauses/channel/fax", errors.ErrorCodeValidationFailed},
+		{"resume unknown scope", http.MethodDelete, "/v1/pauses/region/eu", errors.ErrorCodeValidationFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := serve(server, tt.method, tt.path, nil)
+			require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+			problem, err := errors.ParseProblem(recorder.Body.Bytes())
+			require.NoError(t, err)
+			assert.Equal(t, tt.code, problem.Code)
+		})
+	}
+
+	recorder := serve(server, http.MethodPut, "/v1/pauses/channel/sms", []byte(`{"reason":`))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestServer_PauseRoutesInOpenAPI(t *testing.T) {
+	doc := createTestPauseServer(t).OpenAPI()
+
+	require.Contains(t, doc.Paths, "/v1/pauses/{scope}/{target}")
+	item := *doc.Paths["/v1/pauses/{scope}/{target}"]
+	require.Contains(t, item, "delete")
+	assert.Empty(t, item["delete"].Responses["204"].Content)
+	assert.Len(t, item["put"].Parameters, 2)
+	assert.Contains(t, doc.Components.Schemas, "Pause")
+}
+
+// Helper functions
+
+func createTestPauseServer(t *testing.T) *Server {
+	server := createTestServer(t)
+
+	controller, err := pause.NewController(config.PauseConfig{}, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	server.service.(*services.Dispatcher).SetPauseController(controller)
+	server.SetPauseController(controller)
+
+	return server
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/pushgroup"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// SetPushGroups adds the route apps call when the user opens or clears a
+// group of push alerts, so the group's count starts over
+func (s *Server) SetPushGroups(grouper *pushgroup.Grouper) {
+	s.routes = append(s.routes, route{
+		method:      http.MethodDelete,
+		path:        "/v1/push/groups/{group}",
+		operationID: "resetPushGroup",
+		summary:     "Reset the alert count of a push group on the device given by the device_token query parameter",
+		tag:         "push",
+		status:      http.StatusNoContent,
+		errors:      []int{http.StatusBadRequest},
+		handler:     s.handleResetPushGroup(grouper),
+	})
+}
+
+// handleResetPushGroup resets a push group's alert count
+func (s *Server) handleResetPushGroup(grouper *pushgroup.Grouper) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		if err := grouper.Reset(r.URL.Query().Get("device_token"), params["group"]); err != nil {
+			errors.WriteProblem(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
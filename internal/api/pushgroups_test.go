@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pushgroup"
+)
+
+func TestServer_ResetPushGroup(t *testing.T) {
+	server := createTestServer(t)
+	grouper := pushgroup.NewGrouper(config.PushGroupConfig{})
+	server.SetPushGroups(grouper)
+
+	grouper.Add("device-1", "orders")
+	grouper.Add("device-1", "orders")
+
+	recorder := serve(server, http.MethodDelete, "/v1/push/groups/orders?device_token=device-1", nil)
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Equal(t, 0, grouper.Count("device-1", "orders"))
+
+	assert.Equal(t, http.StatusBadRequest, serve(server, http.MethodDelete, "/v1/push/groups/orders", nil).Code)
+
+	doc := server.OpenAPI()
+	assert.Contains(t, doc.Paths, "/v1/push/groups/{group}")
+}
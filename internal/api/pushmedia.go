@@ -0,0 +1,49 @@
+package api
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/pushmedia"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// maxPushImageUpload is the largest image any platform shows
+const maxPushImageUpload = 10 << 20
+
+// SetPushMedia adds the route that uploads push images to the configured
+// object store
+func (s *Server) SetPushMedia(checker *pushmedia.Checker) {
+	s.routes = append(s.routes, route{
+		method:      http.MethodPost,
+		path:        "/v1/push/media",
+		operationID: "uploadPushImage",
+		summary:     "Upload the image in the request body and get its public URL for image_url; the platform query parameter checks it against one platform's limits instead of every platform's",
+		tag:         "push",
+		response:    pushmedia.Media{},
+		status:      http.StatusCreated,
+		errors:      []int{http.StatusBadRequest, http.StatusServiceUnavailable},
+		handler:     s.handleUploadPushImage(checker),
+	})
+}
+
+// handleUploadPushImage uploads a push image
+func (s *Server) handleUploadPushImage(checker *pushmedia.Checker) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPushImageUpload))
+		if err != nil {
+			errors.WriteProblem(w, r, errors.NewNotificationErrorWithDetails(
+				errors.ErrorCodeInvalidRequest, "request body could not be read", err.Error()))
+			return
+		}
+
+		media, err := checker.Upload(r.Context(), data, r.URL.Query().Get("platform"))
+		if err != nil {
+			s.logger.Errorf("Push image upload failed: %v", err)
+			errors.WriteProblem(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, media)
+	}
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pushmedia"
+)
+
+func TestServer_UploadPushImage(t *testing.T) {
+	server := createTestServer(t)
+	store := &memoryImageStore{objects: make(map[string][]byte)}
+	server.SetPushMedia(pushmedia.NewChecker(config.PushMediaConfig{}, store))
+
+	// A 1x1 GIF
+	gif := []byte("GIF89a\x01\x00\x01\x00\x80\x00\x00\x00\x00\x00\xff\xff\xff!\xf9\x04\x01\x00\x00\x00\x00,\x00\x00\x00\x00\x01\x00\x01\x00\x00\x02\x02D\x01\x00;")
+
+	recorder := serve(server, http.MethodPost, "/v1/push/media?platform=ios", gif)
+	require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+	var media pushmedia.Media
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &media))
+	assert.Equal(t, "image/gif", media.ContentType)
+	assert.Contains(t, media.URL, "https://cdn.example.com/")
+	assert.Len(t, store.objects, 1)
+
+	// Android cannot show GIFs
+	assert.Equal(t, http.StatusBadRequest, serve(server, http.MethodPost, "/v1/push/media?platform=android", gif).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(server, http.MethodPost, "/v1/push/media", nil).Code)
+
+	doc := server.OpenAPI()
+	assert.Contains(t, doc.Paths, "/v1/push/media")
+}
+
+// Helper functions
+
+type memoryImageStore struct {
+	objects map[string][]byte
+}
+
+func (s *memoryImageStore) Put(ctx context.Context, key, contentType string, data []byte) (string, error) {
+	s.objects[key] = data
+	return "https://cdn.example.com/" + key, nil
+}
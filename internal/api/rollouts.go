@@ -0,0 +1,81 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/rollout"
+	"github.com/nareshkumar-microsoft/notificationService/internal/validation"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// RolloutRequest changes the share of a channel's sends going to its canary
+type RolloutRequest struct {
+	Percent *float64 `json:"percent" validate:"required,min=0,max=100"`
+}
+
+// SetRolloutController adds the routes that report provider rollouts and
+// ramp them up, down or back on after a rollback
+func (s *Server) SetRolloutController(controller *rollout.Controller) {
+	s.routes = append(s.routes,
+		route{
+			method:      http.MethodGet,
+			path:        "/v1/rollouts",
+			operationID: "listRollouts",
+			summary:     "List provider rollouts with the error rates and latencies of the canary and incumbent providers",
+			tag:         "rollouts",
+			response:    []rollout.Status{},
+			status:      http.StatusOK,
+			handler:     s.handleListRollouts(controller),
+		},
+		route{
+			method:      http.MethodPut,
+			path:        "/v1/rollouts/{channel}",
+			operationID: "setRolloutPercent",
+			summary:     "Set the percentage of a channel's sends going to its canary provider; a rolled back rollout resumes with fresh metrics",
+			tag:         "rollouts",
+			request:     RolloutRequest{},
+			response:    rollout.Status{},
+			status:      http.StatusOK,
+			errors:      []int{http.StatusBadRequest, http.StatusNotFound},
+			handler:     s.handleSetRolloutPercent(controller),
+		},
+	)
+}
+
+// handleListRollouts lists the rollouts
+func (s *Server) handleListRollouts(controller *rollout.Controller) handlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+		statuses := controller.Statuses()
+		if statuses == nil {
+			statuses = []rollout.Status{}
+		}
+		writeJSON(w, http.StatusOK, statuses)
+	}
+}
+
+// handleSetRolloutPercent ramps a channel's rollout
+func (s *Server) handleSetRolloutPercent(controller *rollout.Controller) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		var request RolloutRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&request); err != nil {
+			errors.WriteProblem(w, r, errors.NewNotificationErrorWithDetails(
+				errors.ErrorCodeInvalidRequest, "request body is not a valid rollout request", err.Error()))
+			return
+		}
+
+		if err := validation.Struct(&request); err != nil {
+			errors.WriteProblem(w, r, err)
+			return
+		}
+
+		status, err := controller.SetPercent(models.NotificationType(params["channel"]), *request.Percent)
+		if err != nil {
+			errors.WriteProblem(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, status)
+	}
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/rollout"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+)
+
+func TestServer_Rollouts(t *testing.T) {
+	server := createTestServer(t)
+	controller, err := rollout.NewController(config.RolloutConfig{
+		Rollouts: []config.ProviderRollout{{Channel: "sms", Provider: "twilio-v2", Percent: 5}},
+	}, utils.NewSimpleLogger("error"))
+	require.NoError(t, err)
+	server.SetRolloutController(controller)
+
+	recorder := serve(server, http.MethodGet, "/v1/rollouts", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var statuses []rollout.Status
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &statuses))
+	require.Len(t, statuses, 1)
+	assert.Equal(t, "twilio-v2", statuses[0].Provider)
+	assert.Equal(t, rollout.StateActive, statuses[0].State)
+
+	recorder = serve(server, http.MethodPut, "/v1/rollouts/sms", []byte(`{"percent":25}`))
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	var status rollout.Status
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+	assert.Equal(t, 25.0, status.Percent)
+
+	recorder = serve(server, http.MethodPut, "/v1/rollouts/sms", []byte(`{"percent":250}`))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	recorder = serve(server, http.MethodPut, "/v1/rollouts/sms", []byte(`{}`))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	recorder = serve(server, http.MethodPut, "/v1/rollouts/email", []byte(`{"percent":10}`))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/internal/rbac"
+	"github.com/nareshkumar-microsoft/notificationService/internal/routing"
+	"github.com/nareshkumar-microsoft/notificationService/internal/validation"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// OutcomeRequest reports a bounce or complaint of email sent through a route
+type OutcomeRequest struct {
+	Outcome routing.Outcome `json:"outcome" validate:"required,oneof=bounced complained"`
+}
+
+// SetEmailRouting adds the routes that show the reputation of email routes
+// and record their bounces and complaints
+func (s *Server) SetEmailRouting(router *routing.Router) {
+	s.routes = append(s.routes,
+		route{
+			method:      http.MethodGet,
+			path:        "/v1/email/routes",
+			operationID: "listEmailRoutes",
+			summary:     "List email routes with their sends, bounces, complaints and health over the reputation window",
+			tag:         "email",
+			response:    []routing.Reputation{},
+			status:      http.StatusOK,
+			handler: func(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+				writeJSON(w, http.StatusOK, router.Reputations())
+			},
+		},
+		route{
+			method:      http.MethodPost,
+			path:        "/v1/email/routes/{name}/outcomes",
+			operationID: "recordEmailRouteOutcome",
+			summary:     "Record a bounce or complaint of email sent through a route, as reported by the provider",
+			tag:         "email",
+			request:     OutcomeRequest{},
+			status:      http.StatusNoContent,
+			errors:      []int{http.StatusBadRequest, http.StatusNotFound},
+			handler:     s.handleRecordOutcome(router),
+		},
+	)
+}
+
+// handleRecordOutcome records a route's bounce or complaint
+func (s *Server) handleRecordOutcome(router *routing.Router) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		var request OutcomeRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&request); err != nil {
+			errors.WriteProblem(w, r, errors.NewNotificationErrorWithDetails(
+				errors.ErrorCodeInvalidRequest, "request body is not a valid outcome", err.Error()))
+			return
+		}
+
+		if err := validation.Struct(&request); err != nil {
+			errors.WriteProblem(w, r, err)
+			return
+		}
+
+		if err := router.Record(params["name"], request.Outcome); err != nil {
+			errors.WriteProblem(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// SetRoutingRules adds the routes that list the routing rules and show
+// which rule a send would match
+func (s *Server) SetRoutingRules(engine *routing.Engine) {
+	s.routes = append(s.routes,
+		route{
+			method:      http.MethodGet,
+			path:        "/v1/routing/rules",
+			operationID: "listRoutingRules",
+			summary:     "List the routing rules in evaluation order",
+			tag:         "routing",
+			response:    []config.RoutingRule{},
+			status:      http.StatusOK,
+			handler: func(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+				writeJSON(w, http.StatusOK, engine.Rules())
+			},
+		},
+		route{
+			method:      http.MethodPost,
+			path:        "/v1/routing/evaluate",
+			operationID: "evaluateRoutingRules",
+			summary:     "Show the routing rule a notification request would match and its provider, sender and rate limit, without sending it",
+			tag:         "routing",
+			request:     models.NotificationRequest{},
+			response:    routing.Decision{},
+			status:      http.StatusOK,
+			errors:      []int{http.StatusBadRequest, http.StatusNotFound},
+			handler:     s.handleEvaluateRules(engine),
+			permission:  rbac.PermissionSettingsRead, // a dry run
+		},
+	)
+}
+
+// handleEvaluateRules evaluates the routing rules against a request
+func (s *Server) handleEvaluateRules(engine *routing.Engine) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		var request models.NotificationRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&request); err != nil {
+			errors.WriteProblem(w, r, errors.NewNotificationErrorWithDetails(
+				errors.ErrorCodeInvalidRequest, "request body is not a valid notification request", err.Error()))
+			return
+		}
+
+		if err := validation.Struct(&request); err != nil {
+			errors.WriteProblem(w, r, err)
+			return
+		}
+
+		decision := engine.Evaluate(&request)
+		if decision == nil {
+			errors.WriteProblem(w, r, errors.NewNotificationError(errors.ErrorCodeNotFound, "no routing rule matches the request"))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, decision)
+	}
+}
+
+// SetSMSRoutes adds the route reporting the metrics of the per-country SMS
+// routes
+func (s *Server) SetSMSRoutes(provider *providers.CountryRoutedSMSProvider) {
+	s.routes = append(s.routes, route{
+		method:      http.MethodGet,
+		path:        "/v1/sms/routes",
+		operationID: "listSMSRouteStats",
+		summary:     "List the sends, failures, fallbacks, cost and latency of each provider on each country's SMS route",
+		tag:         "sms",
+		response:    []providers.SMSRouteStats{},
+		status:      http.StatusOK,
+		handler: func(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+			writeJSON(w, http.StatusOK, provider.RouteStats())
+		},
+	})
+}
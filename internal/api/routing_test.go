@@ -0,0 +1,107 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/internal/ratelimit"
+	"github.com/nareshkumar-microsoft/notificationService/internal/repository"
+	"github.com/nareshkumar-microsoft/notificationService/internal/routing"
+	"github.com/nareshkumar-microsoft/notificationService/internal/services"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+)
+
+func TestServer_EmailRouting(t *testing.T) {
+	dispatcher, err := services.NewDispatcherFromConfig(config.ProvidersConfig{}, repository.NewMemoryRepository(), utils.NewSimpleLogger("error"))
+	require.NoError(t, err)
+	server := NewServer(dispatcher, utils.NewSimpleLogger("error"))
+	router, err := routing.NewRouter(config.EmailRoutingConfig{Routes: []config.EmailRoute{
+		{Name: "otp", Categories: []string{"transactional"}},
+		{Name: "campaigns", Categories: []string{"marketing"}, Provider: "bulk"},
+	}}, utils.NewSimpleLogger("error"))
+	require.NoError(t, err)
+	server.SetEmailRouting(router)
+
+	recorder := serve(server, http.MethodPost, "/v1/email/routes/campaigns/outcomes", []byte(`{"outcome":"complained"}`))
+	require.Equal(t, http.StatusNoContent, recorder.Code, recorder.Body.String())
+
+	recorder = serve(server, http.MethodGet, "/v1/email/routes", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var reputations []routing.Reputation
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &reputations))
+	require.Len(t, reputations, 2)
+	assert.Equal(t, "campaigns", reputations[1].Route)
+	assert.Equal(t, "bulk", reputations[1].Provider)
+	assert.Equal(t, 1, reputations[1].Complained)
+
+	recorder = serve(server, http.MethodPost, "/v1/email/routes/campaigns/outcomes", []byte(`{"outcome":"opened"}`))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	recorder = serve(server, http.MethodPost, "/v1/email/routes/missing/outcomes", []byte(`{"outcome":"bounced"}`))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestServer_RoutingRules(t *testing.T) {
+	dispatcher, err := services.NewDispatcherFromConfig(config.ProvidersConfig{}, repository.NewMemoryRepository(), utils.NewSimpleLogger("error"))
+	require.NoError(t, err)
+	server := NewServer(dispatcher, utils.NewSimpleLogger("error"))
+	engine, err := routing.NewEngine([]config.RoutingRule{
+		{Name: "india-sms", Match: config.RuleMatch{Types: []string{"sms"}, Countries: []string{"IN"}}, Provider: "msg91", Sender: "NOTIFY"},
+	}, ratelimit.NewLocalStore(), utils.NewSimpleLogger("error"))
+	require.NoError(t, err)
+	server.SetRoutingRules(engine)
+
+	recorder := serve(server, http.MethodGet, "/v1/routing/rules", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var rules []config.RoutingRule
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rules))
+	require.Len(t, rules, 1)
+	assert.Equal(t, "india-sms", rules[0].Name)
+
+	recorder = serve(server, http.MethodPost, "/v1/routing/evaluate", []byte(`{"type":"sms","priority":"normal","recipient":"+919876543210","body":"Your code is 1234"}`))
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	var decision routing.Decision
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &decision))
+	assert.Equal(t, routing.Decision{Rule: "india-sms", Provider: "msg91", Sender: "NOTIFY"}, decision)
+
+	recorder = serve(server, http.MethodPost, "/v1/routing/evaluate", []byte(`{"type":"sms","priority":"normal","recipient":"+14155550100","body":"Your code is 1234"}`))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	recorder = serve(server, http.MethodPost, "/v1/routing/evaluate", []byte(`{"type":"sms"}`))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestServer_SMSRoutes(t *testing.T) {
+	provider, err := providers.NewSMSProvider(config.SMSProviderConfig{Provider: "mock", CountryRoutes: map[string][]string{"IN": {"mock"}}})
+	require.NoError(t, err)
+	routed := provider.(*providers.CountryRoutedSMSProvider)
+	routed.Unwrap().(*providers.MockSMSProvider).SetSimulation(providers.Simulation{})
+	_, err = routed.SendSMS(context.Background(), &models.SMSNotification{
+		Notification: models.Notification{ID: uuid.New(), Type: models.NotificationTypeSMS, Recipient: "+919876543210"},
+		PhoneNumber:  "+919876543210",
+		Message:      "Your code is 123456",
+	})
+	require.NoError(t, err)
+
+	dispatcher, err := services.NewDispatcherFromConfig(config.ProvidersConfig{}, repository.NewMemoryRepository(), utils.NewSimpleLogger("error"))
+	require.NoError(t, err)
+	server := NewServer(dispatcher, utils.NewSimpleLogger("error"))
+	server.SetSMSRoutes(routed)
+
+	recorder := serve(server, http.MethodGet, "/v1/sms/routes", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var stats []providers.SMSRouteStats
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &stats))
+	require.Len(t, stats, 1)
+	assert.Equal(t, "IN", stats[0].Country)
+	assert.Equal(t, int64(1), stats[0].Sent)
+}
@@ -0,0 +1,590 @@
+// Package api exposes the notification service over HTTP. Routes are declared
+// once in a table that drives both request routing and the OpenAPI document
+// served at /openapi.json, so the published contract cannot drift from the
+// handlers that implement it.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/audit"
+	"github.com/nareshkumar-microsoft/notificationService/internal/loadshed"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pause"
+	"github.com/nareshkumar-microsoft/notificationService/internal/rbac"
+	"github.com/nareshkumar-microsoft/notificationService/internal/services"
+	"github.com/nareshkumar-microsoft/notificationService/internal/smtpsink"
+	"github.com/nareshkumar-microsoft/notificationService/internal/validation"
+	"github.com/nareshkumar-microsoft/notificationService/internal/webhookauth"
+	"github.com/nareshkumar-microsoft/notificationService/internal/webhookdedup"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// APIVersion is the version of the HTTP API contract
+const APIVersion = "1.0.0"
+
+// maxRequestBody bounds the size of a request body
+const maxRequestBody = 1 << 20
+
+// HealthResponse reports the health of each registered provider
+type HealthResponse struct {
+	Status    string            `json:"status" validate:"required,oneof=ok degraded"`
+	Providers map[string]string `json:"providers"`
+	Webhooks  map[string]string `json:"webhooks,omitempty"` // each webhook's protection, or its misconfiguration
+}
+
+// handlerFunc handles a request with the path parameters of its route
+type handlerFunc func(w http.ResponseWriter, r *http.Request, params map[string]string)
+
+// route is an HTTP operation and its documentation
+type route struct {
+	method       string
+	path         string // segments in braces are parameters, e.g. /v1/notifications/{id}
+	operationID  string
+	summary      string
+	tag          string
+	request      interface{} // request body type, nil when there is none
+	response     interface{} // success response body type, nil when there is none
+	status       int
+	errors       []int // documented error statuses
+	handler      handlerFunc
+	internal     bool            // served but left out of the OpenAPI document
+	mediaType    string          // request and response media type, application/json when empty
+	public       bool            // served without credentials, e.g. to recipients and provider webhooks
+	permission   rbac.Permission // required when access control is set; derived from tag and method when empty
+	webhook      string          // provider whose webhook checks apply, e.g. webhookauth.SourceTwilio
+	notification bool            // the {id} param names a notification, which callers limited to a tenant may only use when it is their tenant's
+}
+
+// resender is implemented by services that can resend notifications, such as services.Dispatcher
+type resender interface {
+	ResendNotification(ctx context.Context, notificationID string, options *services.ResendOptions) (*models.NotificationResponse, error)
+}
+
+// acknowledger is implemented by services that record acknowledgements, such as services.Dispatcher
+type acknowledger interface {
+	AcknowledgeNotification(ctx context.Context, notificationID string, ack *models.Acknowledgement) (*models.Notification, error)
+}
+
+// batchSender is implemented by services that can send mixed-channel
+// batches, such as services.Dispatcher
+type batchSender interface {
+	SendBatch(ctx context.Context, requests []*models.NotificationRequest) (*services.BatchResponse, error)
+}
+
+// BatchRequest is the body of a batch send
+type BatchRequest struct {
+	Requests []*models.NotificationRequest `json:"requests"`
+}
+
+// Server serves the notification service HTTP API
+type Server struct {
+	service  interfaces.NotificationService
+	logger   interfaces.Logger
+	routes   []route
+	shedder  *loadshed.Shedder
+	smtpSink *smtpsink.Server
+	access   *rbac.Authorizer
+	webhooks *webhookauth.Guard
+	dedup    *webhookdedup.Deduplicator
+}
+
+// NewServer creates a new HTTP API server for a notification service
+func NewServer(service interfaces.NotificationService, logger interfaces.Logger) *Server {
+	s := &Server{
+		service: service,
+		logger:  logger,
+	}
+
+	s.routes = []route{
+		{
+			method:      http.MethodPost,
+			path:        "/v1/notifications",
+			operationID: "sendNotification",
+			summary:     "Send a notification",
+			tag:         "notifications",
+			request:     models.NotificationRequest{},
+			response:    models.NotificationResponse{},
+			status:      http.StatusAccepted,
+			errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusServiceUnavailable},
+			handler:     s.handleSend,
+		},
+		{
+			method:       http.MethodGet,
+			path:         "/v1/notifications/{id}",
+			operationID:  "getNotification",
+			summary:      "Get a notification and its delivery status",
+			tag:          "notifications",
+			response:     models.Notification{},
+			status:       http.StatusOK,
+			errors:       []int{http.StatusNotFound},
+			handler:      s.handleGet,
+			notification: true,
+		},
+		{
+			method:      http.MethodGet,
+			path:        "/v1/health",
+			operationID: "getHealth",
+			summary:     "Check the health of the notification providers",
+			tag:         "health",
+			response:    HealthResponse{},
+			status:      http.StatusOK,
+			errors:      []int{http.StatusServiceUnavailable},
+			handler:     s.handleHealth,
+			public:      true,
+		},
+		{method: http.MethodGet, path: "/openapi.json", handler: s.handleOpenAPI, internal: true, public: true},
+		{method: http.MethodGet, path: "/docs", handler: s.handleDocs, internal: true, public: true},
+	}
+
+	if resender, ok := service.(resender); ok {
+		s.routes = append(s.routes, route{
+			method:       http.MethodPost,
+			path:         "/v1/notifications/{id}/resend",
+			operationID:  "resendNotification",
+			summary:      "Resend a finished notification, optionally to a new recipient or with new template data",
+			tag:          "notifications",
+			request:      services.ResendOptions{},
+			response:     models.NotificationResponse{},
+			status:       http.StatusAccepted,
+			errors:       []int{http.StatusBadRequest, http.StatusNotFound, http.StatusServiceUnavailable},
+			handler:      s.handleResend(resender),
+			notification: true,
+		})
+	}
+
+	if acknowledger, ok := service.(acknowledger); ok {
+		s.routes = append(s.routes, route{
+			method:       http.MethodPost,
+			path:         "/v1/notifications/{id}/ack",
+			operationID:  "acknowledgeNotification",
+			summary:      "Record that a person saw a notification, e.g. when a push is opened or a message is viewed in the app",
+			tag:          "notifications",
+			request:      models.Acknowledgement{},
+			response:     models.Notification{},
+			status:       http.StatusOK,
+			errors:       []int{http.StatusBadRequest, http.StatusNotFound},
+			handler:      s.handleAcknowledge(acknowledger),
+			notification: true,
+		})
+	}
+
+	if batcher, ok := service.(batchSender); ok {
+		s.routes = append(s.routes, route{
+			method:      http.MethodPost,
+			path:        "/v1/notifications/batch",
+			operationID: "sendNotificationBatch",
+			summary:     "Send a batch of notifications that may mix channels; results are indexed by request",
+			tag:         "notifications",
+			request:     BatchRequest{},
+			response:    services.BatchResponse{},
+			status:      http.StatusOK,
+			errors:      []int{http.StatusBadRequest, http.StatusServiceUnavailable},
+			handler:     s.handleBatch(batcher),
+		})
+	}
+
+	return s
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var allowed []string
+	for _, rt := range s.routes {
+		params, ok := matchPath(rt.path, r.URL.Path)
+		if !ok {
+			continue
+		}
+		if rt.method != r.Method {
+			allowed = append(allowed, rt.method)
+			continue
+		}
+
+		if rt.webhook != "" && s.webhooks != nil {
+			if err := s.webhooks.Check(r, rt.webhook); err != nil {
+				s.logger.Warnf("Rejected %s webhook from %s: %v", rt.webhook, r.RemoteAddr, err)
+				errors.WriteProblem(w, r, err)
+				return
+			}
+		}
+
+		if !rt.public && s.access != nil {
+			identity, err := s.authorize(r, rt, params)
+			if err != nil {
+				errors.WriteProblem(w, r, err)
+				return
+			}
+			// The identity attributes the request's audit entries too
+			ctx := rbac.WithIdentity(r.Context(), identity)
+			r = r.WithContext(audit.WithActor(ctx, identity.Subject))
+		}
+
+		rt.handler(w, r, params)
+		return
+	}
+
+	if len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		notAllowed := errors.NewNotificationErrorWithDetails(errors.ErrorCodeInvalidRequest, "method not allowed", r.Method)
+		notAllowed.StatusCode = http.StatusMethodNotAllowed
+		errors.WriteProblem(w, r, notAllowed)
+		return
+	}
+
+	errors.WriteProblem(w, r, errors.NewNotificationError(errors.ErrorCodeNotFound, "route not found"))
+}
+
+// OpenAPI returns the OpenAPI document describing the server's routes
+func (s *Server) OpenAPI() *Document {
+	generator := NewSchemaGenerator()
+	generator.Enum(models.NotificationType(""), "email", "sms", "push", "chat", "voice")
+	generator.Enum(models.Priority(""), "low", "normal", "high", "urgent")
+	generator.Enum(models.NotificationStatus(""), "pending", "sent", "delivered", "failed", "retrying")
+	generator.Enum(models.Category(""), "transactional", "marketing", "security")
+	generator.Enum(pause.Scope(""), string(pause.ScopeChannel), string(pause.ScopeProvider))
+
+	codes := make([]string, 0, len(errors.Codes()))
+	for _, code := range errors.Codes() {
+		codes = append(codes, string(code))
+	}
+	generator.Enum(errors.ErrorCode(""), codes...)
+
+	doc := &Document{
+		OpenAPI: OpenAPIVersion,
+		Info: Info{
+			Title:       "Notification Service API",
+			Description: "Send email, SMS, push, chat and voice notifications and track their delivery.",
+			Version:     APIVersion,
+		},
+		Paths: make(map[string]*PathItem),
+	}
+
+	for _, rt := range s.routes {
+		if rt.internal {
+			continue
+		}
+
+		item, exists := doc.Paths[rt.path]
+		if !exists {
+			item = &PathItem{}
+			doc.Paths[rt.path] = item
+		}
+		(*item)[strings.ToLower(rt.method)] = s.operation(generator, rt)
+	}
+
+	doc.Components.Schemas = generator.Components()
+	if s.access != nil {
+		doc.Components.SecuritySchemes = map[string]*SecurityScheme{
+			"apiKey": {Type: "apiKey", In: "header", Name: rbac.APIKeyHeader},
+			"oidc":   {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+		}
+	}
+	return doc
+}
+
+// operation documents a route
+func (s *Server) operation(generator *SchemaGenerator, rt route) *Operation {
+	op := &Operation{
+		OperationID: rt.operationID,
+		Summary:     rt.summary,
+		Responses:   make(map[string]*Response),
+	}
+	if rt.tag != "" {
+		op.Tags = []string{rt.tag}
+	}
+
+	for _, segment := range strings.Split(rt.path, "/") {
+		if name, ok := pathParam(segment); ok {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "string"},
+			})
+		}
+	}
+
+	if rt.request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  content(rt.mediaType, generator.Schema(rt.request)),
+		}
+	}
+
+	success := &Response{Description: http.StatusText(rt.status)}
+	if rt.response != nil {
+		success.Content = content(rt.mediaType, generator.Schema(rt.response))
+	}
+	op.Responses[strconv.Itoa(rt.status)] = success
+
+	problemSchema := generator.Schema(errors.Problem{})
+	for _, status := range rt.errors {
+		op.Responses[strconv.Itoa(status)] = &Response{
+			Description: http.StatusText(status),
+			Content:     map[string]MediaType{errors.ProblemContentType: {Schema: problemSchema}},
+		}
+	}
+
+	if s.access != nil && !rt.public {
+		op.Security = []map[string][]string{{"apiKey": {}}, {"oidc": {}}}
+		op.Permission = string(routePermission(rt))
+		for _, status := range []int{http.StatusUnauthorized, http.StatusForbidden} {
+			op.Responses[strconv.Itoa(status)] = &Response{
+				Description: http.StatusText(status),
+				Content:     map[string]MediaType{errors.ProblemContentType: {Schema: problemSchema}},
+			}
+		}
+	}
+
+	return op
+}
+
+// handleSend sends a notification
+func (s *Server) handleSend(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	var request models.NotificationRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&request); err != nil {
+		errors.WriteProblem(w, r, errors.NewNotificationErrorWithDetails(
+			errors.ErrorCodeInvalidRequest, "request body is not a valid notification request", err.Error()))
+		return
+	}
+
+	if err := validation.Struct(&request); err != nil {
+		errors.WriteProblem(w, r, err)
+		return
+	}
+	scopeToTenant(r, &request)
+
+	if s.shedder != nil {
+		if err := s.shedder.Check(request.Priority); err != nil {
+			errors.WriteProblem(w, r, err)
+			return
+		}
+	}
+
+	started := time.Now()
+	response, err := s.service.SendNotification(r.Context(), &request)
+	if s.shedder != nil {
+		s.shedder.ObserveLatency(time.Since(started))
+	}
+	if err != nil {
+		s.logger.Errorf("API send failed: %v", err)
+		errors.WriteProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, response)
+}
+
+// handleResend resends a notification. The request body is optional.
+func (s *Server) handleResend(resender resender) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		var options services.ResendOptions
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&options); err != nil && err != io.EOF {
+			errors.WriteProblem(w, r, errors.NewNotificationErrorWithDetails(
+				errors.ErrorCodeInvalidRequest, "request body is not valid resend options", err.Error()))
+			return
+		}
+
+		response, err := resender.ResendNotification(r.Context(), params["id"], &options)
+		if err != nil {
+			s.logger.Errorf("API resend of %s failed: %v", params["id"], err)
+			errors.WriteProblem(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusAccepted, response)
+	}
+}
+
+// handleAcknowledge records an acknowledgement. The request body is optional.
+func (s *Server) handleAcknowledge(acknowledger acknowledger) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		var ack models.Acknowledgement
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&ack); err != nil && err != io.EOF {
+			errors.WriteProblem(w, r, errors.NewNotificationErrorWithDetails(
+				errors.ErrorCodeInvalidRequest, "request body is not a valid acknowledgement", err.Error()))
+			return
+		}
+		if err := validation.Struct(&ack); err != nil {
+			errors.WriteProblem(w, r, err)
+			return
+		}
+
+		notification, err := acknowledger.AcknowledgeNotification(r.Context(), params["id"], &ack)
+		if err != nil {
+			errors.WriteProblem(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, notification)
+	}
+}
+
+// handleBatch sends a batch of notifications. Failed requests are reported
+// in their results; the batch is rejected only when it is malformed or load
+// shedding would reject any of its requests.
+func (s *Server) handleBatch(batcher batchSender) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		var batch BatchRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&batch); err != nil {
+			errors.WriteProblem(w, r, errors.NewNotificationErrorWithDetails(
+				errors.ErrorCodeInvalidRequest, "request body is not a valid notification batch", err.Error()))
+			return
+		}
+		for _, request := range batch.Requests {
+			scopeToTenant(r, request)
+		}
+
+		if s.shedder != nil {
+			for _, request := range batch.Requests {
+				if request == nil {
+					continue
+				}
+				if err := s.shedder.Check(request.Priority); err != nil {
+					errors.WriteProblem(w, r, err)
+					return
+				}
+			}
+		}
+
+		started := time.Now()
+		response, err := batcher.SendBatch(r.Context(), batch.Requests)
+		if s.shedder != nil {
+			s.shedder.ObserveLatency(time.Since(started))
+		}
+		if err != nil {
+			errors.WriteProblem(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, response)
+	}
+}
+
+// handleGet returns a notification
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request, params map[string]string) {
+	notification, err := s.service.GetNotificationStatus(r.Context(), params["id"])
+	if err != nil {
+		errors.WriteProblem(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, notification)
+}
+
+// handleHealth reports provider health
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	health := HealthResponse{Status: "ok", Providers: make(map[string]string)}
+	for notificationType, err := range s.service.HealthCheck(r.Context()) {
+		if err != nil {
+			health.Status = "degraded"
+			health.Providers[string(notificationType)] = err.Error()
+			continue
+		}
+		health.Providers[string(notificationType)] = "healthy"
+	}
+	if s.webhooks != nil {
+		health.Webhooks = make(map[string]string)
+		for name, err := range s.webhooks.HealthCheck() {
+			if err != nil {
+				health.Status = "degraded"
+				health.Webhooks[name] = err.Error()
+				continue
+			}
+			health.Webhooks[name] = s.webhooks.Protection(name)
+		}
+	}
+
+	status := http.StatusOK
+	if health.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, health)
+}
+
+// handleOpenAPI serves the OpenAPI document
+func (s *Server) handleOpenAPI(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+	writeJSON(w, http.StatusOK, s.OpenAPI())
+}
+
+// handleDocs serves interactive API documentation for the OpenAPI document
+func (s *Server) handleDocs(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, docsPage)
+}
+
+// docsPage renders the OpenAPI document with Swagger UI
+const docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Notification Service API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.onload = function () {
+      SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+    };
+  </script>
+</body>
+</html>
+`
+
+// matchPath matches a request path against a route path, returning its parameters
+func matchPath(pattern, path string) (map[string]string, bool) {
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(patternSegments) != len(pathSegments) {
+		return nil, false
+	}
+
+	params := make(map[string]string)
+	for i, segment := range patternSegments {
+		if name, ok := pathParam(segment); ok {
+			if pathSegments[i] == "" {
+				return nil, false
+			}
+			params[name] = pathSegments[i]
+			continue
+		}
+		if segment != pathSegments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// pathParam returns the parameter name of a {name} path segment
+func pathParam(segment string) (string, bool) {
+	if len(segment) > 2 && strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+		return segment[1 : len(segment)-1], true
+	}
+	return "", false
+}
+
+// content wraps a schema as content of a media type, application/json when empty
+func content(mediaType string, schema *Schema) map[string]MediaType {
+	if mediaType == "" {
+		mediaType = "application/json"
+	}
+	return map[string]MediaType{mediaType: {Schema: schema}}
+}
+
+// writeJSON writes a JSON response body
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,341 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/repository"
+	"github.com/nareshkumar-microsoft/notificationService/internal/services"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+func TestServer_SendAndGetNotification(t *testing.T) {
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer webhook.Close()
+
+	server := createTestServer(t)
+
+	body, err := json.Marshal(models.NotificationRequest{
+		Type:      models.NotificationTypeChat,
+		Priority:  models.PriorityNormal,
+		Recipient: webhook.URL,
+		Body:      "Deploy finished",
+	})
+	require.NoError(t, err)
+
+	recorder := serve(server, http.MethodPost, "/v1/notifications", body)
+	require.Equal(t, http.StatusAccepted, recorder.Code)
+
+	var response models.NotificationResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, models.StatusSent, response.Status)
+	assert.Equal(t, "slack", response.ProviderMetadata["platform"])
+
+	recorder = serve(server, http.MethodGet, "/v1/notifications/"+response.ID.String(), nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var notification models.Notification
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &notification))
+	assert.Equal(t, response.ID, notification.ID)
+	assert.Equal(t, "Deploy finished", notification.Body)
+}
+
+func TestServer_SendStripsReservedMetadata(t *testing.T) {
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer webhook.Close()
+
+	server, repo := createTestServerWithRepository(t)
+
+	// A caller naming an unregistered provider would otherwise fail the send
+	body, err := json.Marshal(models.NotificationRequest{
+		Type:      models.NotificationTypeChat,
+		Priority:  models.PriorityNormal,
+		Recipient: webhook.URL,
+		Body:      "Deploy finished",
+		Metadata: map[string]string{
+			"provider":            "attacker",
+			"rollout_arm":         "canary",
+			"coalesced_count":     "3",
+			"outbox_id":           "42",
+			"compliance_decision": "allowed",
+			"frequency_capped":    "true",
+			"reconciled":          "true",
+			"order_id":            "42",
+		},
+	})
+	require.NoError(t, err)
+
+	recorder := serve(server, http.MethodPost, "/v1/notifications", body)
+	require.Equal(t, http.StatusAccepted, recorder.Code, recorder.Body.String())
+	var response models.NotificationResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+
+	stored, err := repo.GetByID(context.Background(), response.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"order_id": "42"}, stored.Metadata)
+}
+
+func TestServer_SendReturnsProviderDetails(t *testing.T) {
+	dispatcher, err := services.NewDispatcherFromConfig(config.ProvidersConfig{
+		SMS: config.SMSProviderConfig{Provider: "mock", Enabled: true},
+	}, repository.NewMemoryRepository(), utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	server := NewServer(dispatcher, utils.NewSimpleLogger("info"))
+
+	body, err := json.Marshal(models.NotificationRequest{
+		Type:      models.NotificationTypeSMS,
+		Priority:  models.PriorityNormal,
+		Recipient: "+15551234567",
+		Body:      "Your code is 1234",
+		SMSData:   &models.SMSData{CountryCode: "US"},
+	})
+	require.NoError(t, err)
+
+	recorder := serve(server, http.MethodPost, "/v1/notifications", body)
+	require.Equal(t, http.StatusAccepted, recorder.Code)
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, float64(1), response["segments"])
+	assert.Equal(t, 0.0075, response["cost"])
+	assert.Equal(t, "USD", response["currency"])
+
+	metadata, ok := response["provider_metadata"].(map[string]interface{})
+	require.True(t, ok)
+	assert.Equal(t, "mock-sms", metadata["provider"])
+	assert.Equal(t, response["provider_id"], metadata["message_id"])
+}
+
+func TestServer_ResendNotification(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer failing.Close()
+
+	working := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer working.Close()
+
+	server, repo := createTestServerWithRepository(t)
+
+	body, err := json.Marshal(models.NotificationRequest{
+		Type:      models.NotificationTypeChat,
+		Priority:  models.PriorityNormal,
+		Recipient: failing.URL,
+		Body:      "Deploy finished",
+	})
+	require.NoError(t, err)
+	recorder := serve(server, http.MethodPost, "/v1/notifications", body)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	failed := models.StatusFailed
+	stored, err := repo.List(context.Background(), interfaces.NotificationFilters{Status: &failed})
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+
+	recorder = serve(server, http.MethodPost, "/v1/notifications/"+stored[0].ID.String()+"/resend",
+		[]byte(`{"recipient":"`+working.URL+`"}`))
+	require.Equal(t, http.StatusAccepted, recorder.Code)
+
+	var response models.NotificationResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, models.StatusSent, response.Status)
+
+	// Resending a sent notification needs no body
+	recorder = serve(server, http.MethodPost, "/v1/notifications/"+response.ID.String()+"/resend", nil)
+	assert.Equal(t, http.StatusAccepted, recorder.Code)
+}
+
+func TestServer_AcknowledgeNotification(t *testing.T) {
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer webhook.Close()
+
+	server := createTestServer(t)
+
+	body, err := json.Marshal(models.NotificationRequest{
+		Type:      models.NotificationTypeChat,
+		Priority:  models.PriorityNormal,
+		Recipient: webhook.URL,
+		Body:      "Incident opened",
+	})
+	require.NoError(t, err)
+	recorder := serve(server, http.MethodPost, "/v1/notifications", body)
+	require.Equal(t, http.StatusAccepted, recorder.Code)
+	var response models.NotificationResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+
+	recorder = serve(server, http.MethodPost, "/v1/notifications/"+response.ID.String()+"/ack", []byte(`{"source":"push"}`))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var notification models.Notification
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &notification))
+	assert.Equal(t, models.AcknowledgeSourcePush, notification.AcknowledgedVia)
+	assert.NotNil(t, notification.AcknowledgedAt)
+
+	// The body is optional, but its source must be known
+	recorder = serve(server, http.MethodPost, "/v1/notifications/"+response.ID.String()+"/ack", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	recorder = serve(server, http.MethodPost, "/v1/notifications/"+response.ID.String()+"/ack", []byte(`{"source":"carrier_pigeon"}`))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestServer_SendBatch(t *testing.T) {
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer webhook.Close()
+
+	server := createTestServer(t)
+
+	body, err := json.Marshal(BatchRequest{Requests: []*models.NotificationRequest{
+		{Type: models.NotificationTypeChat, Priority: models.PriorityNormal, Recipient: webhook.URL, Body: "Deploy started"},
+		{Type: models.NotificationTypeSMS, Priority: models.PriorityNormal, Recipient: "+1234567890", Body: "no SMS provider"},
+	}})
+	require.NoError(t, err)
+	recorder := serve(server, http.MethodPost, "/v1/notifications/batch", body)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var response services.BatchResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, 1, response.SuccessCount)
+	require.Len(t, response.Results, 2)
+	assert.Equal(t, models.StatusSent, response.Results[0].Response.Status)
+	assert.Equal(t, errors.ErrorCodeProviderNotFound, response.Results[1].Code)
+
+	recorder = serve(server, http.MethodPost, "/v1/notifications/batch", []byte(`{"requests": []}`))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestServer_Errors(t *testing.T) {
+	server := createTestServer(t)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   []byte
+		status int
+		code   errors.ErrorCode
+	}{
+		{"malformed body", http.MethodPost, "/v1/notifications", []byte("{"), http.StatusBadRequest, errors.ErrorCodeInvalidRequest},
+		{"unknown notification", http.MethodGet, "/v1/notifications/missing", nil, http.StatusNotFound, errors.ErrorCodeNotFound},
+		{"unknown route", http.MethodGet, "/v1/unknown", nil, http.StatusNotFound, errors.ErrorCodeNotFound},
+		{"wrong method", http.MethodDelete, "/v1/notifications", nil, http.StatusMethodNotAllowed, errors.ErrorCodeInvalidRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := serve(server, tt.method, tt.path, tt.body)
+			assert.Equal(t, tt.status, recorder.Code)
+
+			assert.Equal(t, errors.ProblemContentType, recorder.Header().Get("Content-Type"))
+
+			var problem errors.Problem
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+			assert.Equal(t, tt.code, problem.Code)
+			assert.Equal(t, tt.status, problem.Status)
+			assert.Equal(t, tt.path, problem.Instance)
+		})
+	}
+
+	recorder := serve(server, http.MethodDelete, "/v1/notifications", nil)
+	assert.Equal(t, http.MethodPost, recorder.Header().Get("Allow"))
+}
+
+func TestServer_SendReturnsFieldErrors(t *testing.T) {
+	server := createTestServer(t)
+
+	recorder := serve(server, http.MethodPost, "/v1/notifications", []byte(`{"type":"fax","priority":"normal"}`))
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	var problem errors.Problem
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+	assert.Equal(t, errors.ErrorCodeValidationFailed, problem.Code)
+	assert.Equal(t, "urn:notification-service:problem:validation-failed", problem.Type)
+	assert.Equal(t, []errors.FieldError{
+		{Field: "type", Rule: "oneof", Message: "must be one of: email, sms, push, chat, voice"},
+		{Field: "recipient", Rule: "required", Message: "is required"},
+		{Field: "body", Rule: "required", Message: "is required"},
+	}, problem.Errors)
+}
+
+func TestServer_Health(t *testing.T) {
+	server := createTestServer(t)
+
+	recorder := serve(server, http.MethodGet, "/v1/health", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var health HealthResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &health))
+	assert.Equal(t, "ok", health.Status)
+	assert.Equal(t, "healthy", health.Providers["chat"])
+}
+
+func TestServer_ServesOpenAPIAndDocs(t *testing.T) {
+	server := createTestServer(t)
+
+	recorder := serve(server, http.MethodGet, "/openapi.json", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &doc))
+	assert.Equal(t, OpenAPIVersion, doc["openapi"])
+
+	recorder = serve(server, http.MethodGet, "/docs", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `url: "/openapi.json"`)
+}
+
+func TestMatchPath(t *testing.T) {
+	params, ok := matchPath("/v1/notifications/{id}", "/v1/notifications/abc")
+	require.True(t, ok)
+	assert.Equal(t, "abc", params["id"])
+
+	_, ok = matchPath("/v1/notifications/{id}", "/v1/notifications/")
+	assert.False(t, ok)
+
+	_, ok = matchPath("/v1/notifications/{id}", "/v1/notifications/abc/retry")
+	assert.False(t, ok)
+}
+
+// Helper functions
+
+func createTestServer(t *testing.T) *Server {
+	server, _ := createTestServerWithRepository(t)
+	return server
+}
+
+func createTestServerWithRepository(t *testing.T) (*Server, *repository.MemoryRepository) {
+	repo := repository.NewMemoryRepository()
+	dispatcher, err := services.NewDispatcherFromConfig(config.ProvidersConfig{
+		Chat: config.ChatProviderConfig{Provider: "slack", Enabled: true, AllowedHosts: []string{"127.0.0.1"}, AllowPrivateWebhooks: true},
+	}, repo, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+
+	return NewServer(dispatcher, utils.NewSimpleLogger("info")), repo
+}
+
+func serve(server *Server, method, path string, body []byte) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, bytes.NewReader(body))
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	return recorder
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/shortlink"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// SetShortLinks adds the route short links redirect through, which counts
+// clicks, and the route listing a notification's links with their clicks.
+// The service's base URL must point at the redirect route, e.g.
+// "https://sms.example.com/s/".
+func (s *Server) SetShortLinks(links *shortlink.Service) {
+	s.routes = append(s.routes,
+		route{
+			method:      http.MethodGet,
+			path:        "/s/{code}",
+			operationID: "followShortLink",
+			summary:     "Redirect a short link to its URL and count the click",
+			tag:         "links",
+			status:      http.StatusFound,
+			errors:      []int{http.StatusNotFound},
+			handler:     s.handleFollowShortLink(links),
+			public:      true,
+		},
+		route{
+			method:       http.MethodGet,
+			path:         "/v1/notifications/{id}/links",
+			operationID:  "listNotificationLinks",
+			summary:      "List the short links sent in a notification with their clicks",
+			tag:          "links",
+			response:     []shortlink.Link{},
+			status:       http.StatusOK,
+			handler:      s.handleListNotificationLinks(links),
+			notification: true,
+		},
+	)
+}
+
+// handleFollowShortLink counts a click and redirects to the link's URL
+func (s *Server) handleFollowShortLink(links *shortlink.Service) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		link, err := links.Click(params["code"])
+		if err != nil {
+			errors.WriteProblem(w, r, err)
+			return
+		}
+
+		// Caching the redirect would hide repeat clicks
+		w.Header().Set("Cache-Control", "no-store")
+		http.Redirect(w, r, link.URL, http.StatusFound)
+	}
+}
+
+// handleListNotificationLinks lists a notification's short links
+func (s *Server) handleListNotificationLinks(links *shortlink.Service) handlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+		writeJSON(w, http.StatusOK, links.Links(params["id"]))
+	}
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/repository"
+	"github.com/nareshkumar-microsoft/notificationService/internal/services"
+	"github.com/nareshkumar-microsoft/notificationService/internal/shortlink"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+)
+
+func TestServer_ShortLinkClicks(t *testing.T) {
+	server, links := createTestShortLinkServer(t)
+
+	longURL := "https://shop.example.com/orders/12345/tracking?utm_source=sms"
+	body, err := json.Marshal(models.NotificationRequest{
+		Type:      models.NotificationTypeSMS,
+		Priority:  models.PriorityNormal,
+		Recipient: "+14155550123",
+		Body:      "Track your order: " + longURL,
+	})
+	require.NoError(t, err)
+	recorder := serve(server, http.MethodPost, "/v1/notifications", body)
+	require.Equal(t, http.StatusAccepted, recorder.Code, recorder.Body.String())
+
+	var response models.NotificationResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	sent := links.Links(response.ID.String())
+	require.Len(t, sent, 1)
+
+	recorder = serve(server, http.MethodGet, "/s/"+sent[0].Code, nil)
+	require.Equal(t, http.StatusFound, recorder.Code)
+	assert.Equal(t, longURL, recorder.Header().Get("Location"))
+	assert.Equal(t, "no-store", recorder.Header().Get("Cache-Control"))
+
+	recorder = serve(server, http.MethodGet, "/v1/notifications/"+response.ID.String()+"/links", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var listed []shortlink.Link
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &listed))
+	require.Len(t, listed, 1)
+	assert.Equal(t, int64(1), listed[0].Clicks)
+	assert.NotNil(t, listed[0].LastClickedAt)
+
+	recorder = serve(server, http.MethodGet, "/s/unknown", nil)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestServer_ShortLinkRoutesInOpenAPI(t *testing.T) {
+	server, _ := createTestShortLinkServer(t)
+	doc := server.OpenAPI()
+
+	require.Contains(t, doc.Paths, "/s/{code}")
+	assert.Contains(t, (*doc.Paths["/s/{code}"])["get"].Responses, "302")
+	assert.Contains(t, doc.Paths, "/v1/notifications/{id}/links")
+	assert.Contains(t, doc.Components.Schemas, "Link")
+}
+
+// Helper functions
+
+func createTestShortLinkServer(t *testing.T) (*Server, *shortlink.Service) {
+	dispatcher, err := services.NewDispatcherFromConfig(config.ProvidersConfig{
+		SMS: config.SMSProviderConfig{Provider: "mock", Enabled: true},
+	}, repository.NewMemoryRepository(), utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	server := NewServer(dispatcher, utils.NewSimpleLogger("info"))
+
+	links, err := shortlink.NewService("https://sms.example.com/s/")
+	require.NoError(t, err)
+	require.NoError(t, dispatcher.SetShortLinks(links))
+	server.SetShortLinks(links)
+
+	return server, links
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/sla"
+)
+
+// SetSLAMonitor adds the routes reporting the success rate, latency and
+// degradation of each provider, now and in the monitor's rollups
+func (s *Server) SetSLAMonitor(monitor *sla.Monitor) {
+	s.routes = append(s.routes, route{
+		method:      http.MethodGet,
+		path:        "/v1/stats/providers",
+		operationID: "listProviderStats",
+		summary:     "List each provider's success rate and latency over the SLA window, and whether it is degraded",
+		tag:         "stats",
+		response:    []sla.ProviderStats{},
+		status:      http.StatusOK,
+		handler: func(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+			writeJSON(w, http.StatusOK, monitor.Stats())
+		},
+	}, route{
+		method:      http.MethodGet,
+		path:        "/v1/stats/providers/rollups",
+		operationID: "listProviderStatsRollups",
+		summary:     "List the rollups of each provider's success rate and latency, oldest first",
+		tag:         "stats",
+		response:    []sla.Rollup{},
+		status:      http.StatusOK,
+		handler: func(w http.ResponseWriter, _ *http.Request, _ map[string]string) {
+			writeJSON(w, http.StatusOK, monitor.Rollups())
+		},
+	})
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/sla"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+)
+
+func TestServer_ProviderStats(t *testing.T) {
+	server := createTestServer(t)
+	monitor := sla.NewMonitor(config.SLAConfig{}, utils.NewSimpleLogger("error"))
+	monitor.RecordSend(models.NotificationTypeSMS, "twilio", nil, 120*time.Millisecond)
+	server.SetSLAMonitor(monitor)
+
+	recorder := serve(server, http.MethodGet, "/v1/stats/providers", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var stats []sla.ProviderStats
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &stats))
+	require.Len(t, stats, 1)
+	assert.Equal(t, "twilio", stats[0].Provider)
+	assert.Equal(t, 1.0, stats[0].SuccessRate)
+	assert.False(t, stats[0].Degraded)
+
+	monitor.Rollup()
+	recorder = serve(server, http.MethodGet, "/v1/stats/providers/rollups", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var rollups []sla.Rollup
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rollups))
+	require.Len(t, rollups, 1)
+	assert.Equal(t, "twilio", rollups[0].Providers[0].Provider)
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/snapshot"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// SetTemplateSnapshots adds the routes that capture email template
+// snapshots, serve their images and diff two template versions
+func (s *Server) SetTemplateSnapshots(snapshots *snapshot.Service) {
+	s.routes = append(s.routes,
+		route{
+			method:      http.MethodPost,
+			path:        "/v1/templates/email/{id}/snapshots",
+			operationID: "captureTemplateSnapshot",
+			summary:     "Render the current version of an email template with sample data and store a screenshot of it",
+			tag:         "templates",
+			request:     snapshot.CaptureRequest{},
+			response:    snapshot.Snapshot{},
+			status:      http.StatusCreated,
+			errors:      []int{http.StatusBadRequest, http.StatusNotFound},
+			handler:     s.handleCaptureSnapshot(snapshots),
+		},
+		route{
+			method:      http.MethodGet,
+			path:        "/v1/templates/email/{id}/snapshots",
+			operationID: "listTemplateSnapshots",
+			summary:     "List the snapshots of an email template, newest first",
+			tag:         "templates",
+			response:    []snapshot.Snapshot{},
+			status:      http.StatusOK,
+			handler:     s.handleListSnapshots(snapshots),
+		},
+		route{
+			method:      http.MethodGet,
+			path:        "/v1/templates/email/{id}/snapshots/diff",
+			operationID: "diffTemplateSnapshots",
+			summary:     "Compare the snapshots of two template versions given by the from and to query parameters",
+			tag:         "templates",
+			response:    snapshot.Diff{},
+			status:      http.StatusOK,
+			errors:      []int{http.StatusBadRequest, http.StatusNotFound},
+			handler:     s.handleDiffSnapshots(snapshots),
+		},
+		route{
+			method:      http.MethodGet,
+			path:        "/v1/templates/email/{id}/snapshots/{version}/image",
+			operationID: "getTemplateSnapshotImage",
+			summary:     "Get the image of the newest snapshot of a template version",
+			tag:         "templates",
+			status:      http.StatusOK,
+			errors:      []int{http.StatusNotFound},
+			handler:     s.handleSnapshotImage(snapshots),
+		},
+	)
+}
+
+// handleCaptureSnapshot captures a snapshot. The request body is optional.
+func (s *Server) handleCaptureSnapshot(snapshots *snapshot.Service) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		var request snapshot.CaptureRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&request); err != nil && err != io.EOF {
+			errors.WriteProblem(w, r, errors.NewNotificationErrorWithDetails(
+				errors.ErrorCodeInvalidRequest, "request body is not a valid snapshot request", err.Error()))
+			return
+		}
+
+		captured, err := snapshots.Capture(r.Context(), params["id"], request)
+		if err != nil {
+			s.logger.Errorf("Snapshot of template %s failed: %v", params["id"], err)
+			errors.WriteProblem(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusCreated, captured)
+	}
+}
+
+// handleListSnapshots lists a template's snapshots
+func (s *Server) handleListSnapshots(snapshots *snapshot.Service) handlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request, params map[string]string) {
+		writeJSON(w, http.StatusOK, snapshots.List(params["id"]))
+	}
+}
+
+// handleDiffSnapshots diffs two template versions
+func (s *Server) handleDiffSnapshots(snapshots *snapshot.Service) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+		if from == "" || to == "" {
+			errors.WriteProblem(w, r, errors.NewValidationError("from", "from and to versions are required"))
+			return
+		}
+
+		diff, err := snapshots.Compare(params["id"], from, to)
+		if err != nil {
+			errors.WriteProblem(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, diff)
+	}
+}
+
+// handleSnapshotImage serves a snapshot's image
+func (s *Server) handleSnapshotImage(snapshots *snapshot.Service) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		found, err := snapshots.Get(params["id"], params["version"])
+		if err != nil {
+			errors.WriteProblem(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", found.ContentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(found.Image)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(found.Image)
+	}
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/internal/repository"
+	"github.com/nareshkumar-microsoft/notificationService/internal/services"
+	"github.com/nareshkumar-microsoft/notificationService/internal/snapshot"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+)
+
+func TestServer_TemplateSnapshots(t *testing.T) {
+	server, email := createTestSnapshotServer(t)
+
+	recorder := serve(server, http.MethodPost, "/v1/templates/email/welcome/snapshots", []byte(`{"template_data":{"user_name":"Jane"},"width":480}`))
+	require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+	var first snapshot.Snapshot
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &first))
+	assert.Equal(t, 480, first.Width)
+	assert.NotEmpty(t, first.Version)
+
+	recorder = serve(server, http.MethodGet, "/v1/templates/email/welcome/snapshots/"+first.Version+"/image", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "image/test", recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), "Jane")
+
+	template, err := email.GetTemplate("welcome")
+	require.NoError(t, err)
+	changed := *template
+	changed.HTMLBody += "<p>Changed</p>"
+	require.NoError(t, email.AddTemplate(&changed))
+
+	recorder = serve(server, http.MethodPost, "/v1/templates/email/welcome/snapshots", nil)
+	require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+	var second snapshot.Snapshot
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &second))
+
+	recorder = serve(server, http.MethodGet, "/v1/templates/email/welcome/snapshots", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var listed []snapshot.Snapshot
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &listed))
+	assert.Len(t, listed, 2)
+
+	recorder = serve(server, http.MethodGet, "/v1/templates/email/welcome/snapshots/diff?from="+first.Version+"&to="+second.Version, nil)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	var diff snapshot.Diff
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &diff))
+	assert.False(t, diff.Identical)
+
+	recorder = serve(server, http.MethodGet, "/v1/templates/email/welcome/snapshots/diff?from="+first.Version, nil)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	recorder = serve(server, http.MethodGet, "/v1/templates/email/welcome/snapshots/unknown/image", nil)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	recorder = serve(server, http.MethodPost, "/v1/templates/email/missing/snapshots", nil)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	recorder = serve(server, http.MethodPost, "/v1/templates/email/welcome/snapshots", []byte(`{"width":-1}`))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+// Helper functions
+
+// htmlRenderer "renders" a document as its own bytes
+type htmlRenderer struct{}
+
+func (htmlRenderer) Render(_ context.Context, html string, _ int) ([]byte, string, error) {
+	return []byte(html), "image/test", nil
+}
+
+func createTestSnapshotServer(t *testing.T) (*Server, *providers.MockEmailProvider) {
+	dispatcher, err := services.NewDispatcherFromConfig(config.ProvidersConfig{}, repository.NewMemoryRepository(), utils.NewSimpleLogger("error"))
+	require.NoError(t, err)
+	server := NewServer(dispatcher, utils.NewSimpleLogger("error"))
+
+	email := providers.NewMockEmailProvider(config.EmailProviderConfig{Provider: "mock"})
+	server.SetTemplateSnapshots(snapshot.NewService(email, htmlRenderer{}, utils.NewSimpleLogger("error")))
+	return server, email
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/rbac"
+	"github.com/nareshkumar-microsoft/notificationService/internal/spamcheck"
+	"github.com/nareshkumar-microsoft/notificationService/internal/validation"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// SetSpamCheck adds the route that scores an email for spam without sending it
+func (s *Server) SetSpamCheck(checker *spamcheck.Checker) {
+	s.routes = append(s.routes, route{
+		method:      http.MethodPost,
+		path:        "/v1/notifications/spam-check",
+		operationID: "checkSpam",
+		summary:     "Score an email notification request for spam, with the rules it hits, without sending it",
+		tag:         "notifications",
+		request:     models.NotificationRequest{},
+		response:    spamcheck.Result{},
+		status:      http.StatusOK,
+		errors:      []int{http.StatusBadRequest, http.StatusServiceUnavailable},
+		handler:     s.handleSpamCheck(checker),
+		permission:  rbac.PermissionNotificationsRead, // sends nothing
+	})
+}
+
+// handleSpamCheck scores an email request
+func (s *Server) handleSpamCheck(checker *spamcheck.Checker) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		var request models.NotificationRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(&request); err != nil {
+			errors.WriteProblem(w, r, errors.NewNotificationErrorWithDetails(
+				errors.ErrorCodeInvalidRequest, "request body is not a valid notification request", err.Error()))
+			return
+		}
+
+		if err := validation.Struct(&request); err != nil {
+			errors.WriteProblem(w, r, err)
+			return
+		}
+
+		result, err := checker.Preview(r.Context(), &request)
+		if err != nil {
+			errors.WriteProblem(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, result)
+	}
+}
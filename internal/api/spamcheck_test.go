@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/repository"
+	"github.com/nareshkumar-microsoft/notificationService/internal/services"
+	"github.com/nareshkumar-microsoft/notificationService/internal/spamcheck"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+)
+
+func TestServer_SpamCheck(t *testing.T) {
+	server := createTestSpamCheckServer(t)
+
+	body := `{"type":"email","priority":"normal","recipient":"jane@example.com","subject":"{{deal}} for you","body":"Shop now","template_data":{"deal":"FREE MONEY"}}`
+	recorder := serve(server, http.MethodPost, "/v1/notifications/spam-check", []byte(body))
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+	var result spamcheck.Result
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &result))
+	assert.True(t, result.Spam)
+	assert.Equal(t, 8.0, result.Score)
+	require.Len(t, result.Rules, 1)
+	assert.Equal(t, "MONEY_FREE", result.Rules[0].Name)
+
+	recorder = serve(server, http.MethodPost, "/v1/notifications/spam-check", []byte(`{"type":"sms","priority":"normal","recipient":"+14155550123","body":"Hi"}`))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	recorder = serve(server, http.MethodPost, "/v1/notifications/spam-check", []byte(`{"type":"email"}`))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+// Helper functions
+
+// subjectScorer scores messages whose subject mentions free money as spam
+type subjectScorer struct{}
+
+func (subjectScorer) Score(_ context.Context, message []byte) (*spamcheck.Result, error) {
+	if strings.Contains(string(message), "Subject: FREE MONEY") {
+		return &spamcheck.Result{Score: 8, Rules: []spamcheck.RuleHit{{Name: "MONEY_FREE", Score: 8}}}, nil
+	}
+	return &spamcheck.Result{}, nil
+}
+
+func createTestSpamCheckServer(t *testing.T) *Server {
+	dispatcher, err := services.NewDispatcherFromConfig(config.ProvidersConfig{}, repository.NewMemoryRepository(), utils.NewSimpleLogger("error"))
+	require.NoError(t, err)
+	server := NewServer(dispatcher, utils.NewSimpleLogger("error"))
+
+	server.SetSpamCheck(spamcheck.NewChecker(subjectScorer{}, config.SpamCheckConfig{}, utils.NewSimpleLogger("error")))
+	return server
+}
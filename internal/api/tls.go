@@ -0,0 +1,99 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// TLSConfig returns the TLS configuration of the server, or nil when TLS is
+// disabled. The certificate is reloaded when its file changes, so rotated
+// certificates are served without a restart. With ClientAuth "optional" or
+// "require", client certificates are verified against the client CA; a
+// rbac.CertificateAuthenticator then maps them to identities.
+func TLSConfig(cfg config.ServerConfig) (*tls.Config, error) {
+	if !cfg.EnableTLS {
+		if cfg.ClientAuth != "" && cfg.ClientAuth != "none" {
+			return nil, errors.NewValidationError("client_auth", "client certificates need TLS to be enabled")
+		}
+		return nil, nil
+	}
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, errors.NewValidationError("cert_file", "TLS needs a certificate and key file")
+	}
+
+	certificate := &certificateReloader{certFile: cfg.CertFile, keyFile: cfg.KeyFile}
+	if _, err := certificate.GetCertificate(nil); err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certificate.GetCertificate,
+	}
+
+	switch cfg.ClientAuth {
+	case "", "none":
+		return tlsConfig, nil
+	case "optional":
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	case "require":
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, errors.NewValidationError("client_auth", fmt.Sprintf("unknown client auth %q; use none, optional or require", cfg.ClientAuth))
+	}
+
+	if cfg.ClientCAFile == "" {
+		return nil, errors.NewValidationError("client_ca_file", "client certificates need a client CA file")
+	}
+	data, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return nil, errors.NewValidationError("client_ca_file", fmt.Sprintf("failed to read client CA file: %v", err))
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.NewValidationError("client_ca_file", "client CA file has no PEM certificates")
+	}
+	tlsConfig.ClientCAs = pool
+	return tlsConfig, nil
+}
+
+// certificateReloader loads a certificate and key pair, reloading them when
+// the certificate file's modification time changes
+type certificateReloader struct {
+	certFile string
+	keyFile  string
+
+	mu          sync.Mutex
+	certificate *tls.Certificate
+	modified    time.Time
+}
+
+// GetCertificate implements tls.Config.GetCertificate. When reloading fails,
+// e.g. while the files are being replaced, the loaded certificate is kept.
+func (c *certificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	info, err := os.Stat(c.certFile)
+	if err == nil && c.certificate != nil && info.ModTime().Equal(c.modified) {
+		return c.certificate, nil
+	}
+	if err == nil {
+		var certificate tls.Certificate
+		if certificate, err = tls.LoadX509KeyPair(c.certFile, c.keyFile); err == nil {
+			c.certificate = &certificate
+			c.modified = info.ModTime()
+			return c.certificate, nil
+		}
+	}
+	if c.certificate != nil {
+		return c.certificate, nil
+	}
+	return nil, errors.NewValidationError("cert_file", fmt.Sprintf("failed to load TLS certificate: %v", err))
+}
@@ -0,0 +1,234 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/branding"
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/layout"
+	"github.com/nareshkumar-microsoft/notificationService/internal/rbac"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+)
+
+func TestTLSConfig_MutualTLS(t *testing.T) {
+	pki := createTestPKI(t)
+	cfg := config.ServerConfig{
+		EnableTLS:    true,
+		CertFile:     pki.serverCert,
+		KeyFile:      pki.serverKey,
+		ClientCAFile: pki.caFile,
+		ClientAuth:   "require",
+		ClientIdentities: []config.ClientIdentityConfig{
+			{SAN: "spiffe://mesh.local/ns/acme/*", Tenant: "acme", Roles: []string{"template-editor"}},
+		},
+	}
+	tlsConfig, err := TLSConfig(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, tlsConfig.ClientAuth)
+
+	server := createTestServer(t)
+	server.SetBranding(branding.NewService(layout.NewLibrary(), nil, config.BrandingConfig{}))
+	certificates, err := rbac.NewCertificateAuthenticator(cfg.ClientIdentities)
+	require.NoError(t, err)
+	authorizer, err := rbac.NewAuthorizerFromConfig(config.AuthConfig{}, utils.NewSimpleLogger("error"), certificates)
+	require.NoError(t, err)
+	server.SetAccessControl(authorizer)
+
+	// httptest serves its own certificate unless one is set
+	certificate, err := tlsConfig.GetCertificate(nil)
+	require.NoError(t, err)
+	tlsConfig.Certificates = []tls.Certificate{*certificate}
+	listener := httptest.NewUnstartedServer(server)
+	listener.TLS = tlsConfig
+	listener.StartTLS()
+	defer listener.Close()
+
+	client := pki.client(t, "spiffe://mesh.local/ns/acme/sa/designer")
+	response, err := client.Get(listener.URL + "/v1/tenants/acme/branding")
+	require.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	// The SAN maps the caller to its tenant
+	response, err = client.Get(listener.URL + "/v1/tenants/globex/branding")
+	require.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusForbidden, response.StatusCode)
+
+	// A verified certificate without a mapped SAN is not an identity
+	response, err = pki.client(t, "spiffe://mesh.local/ns/globex/sa/designer").Get(listener.URL + "/v1/tenants/acme/branding")
+	require.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+
+	// Without a client certificate the handshake fails
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pki.pool}}}
+	_, err = anonymous.Get(listener.URL + "/v1/health")
+	assert.Error(t, err)
+}
+
+func TestTLSConfig_Invalid(t *testing.T) {
+	pki := createTestPKI(t)
+
+	tlsConfig, err := TLSConfig(config.ServerConfig{ClientAuth: "none"})
+	assert.NoError(t, err)
+	assert.Nil(t, tlsConfig)
+
+	tlsConfig, err = TLSConfig(config.ServerConfig{EnableTLS: true, CertFile: pki.serverCert, KeyFile: pki.serverKey, ClientAuth: "optional", ClientCAFile: pki.caFile})
+	require.NoError(t, err)
+	assert.Equal(t, tls.VerifyClientCertIfGiven, tlsConfig.ClientAuth)
+
+	tests := []struct {
+		name string
+		cfg  config.ServerConfig
+	}{
+		{"client auth without TLS", config.ServerConfig{ClientAuth: "require"}},
+		{"missing key", config.ServerConfig{EnableTLS: true, CertFile: pki.serverCert}},
+		{"unreadable certificate", config.ServerConfig{EnableTLS: true, CertFile: pki.caFile, KeyFile: pki.serverKey}},
+		{"unknown client auth", config.ServerConfig{EnableTLS: true, CertFile: pki.serverCert, KeyFile: pki.serverKey, ClientAuth: "always"}},
+		{"missing client CA", config.ServerConfig{EnableTLS: true, CertFile: pki.serverCert, KeyFile: pki.serverKey, ClientAuth: "require"}},
+		{"client CA without certificates", config.ServerConfig{EnableTLS: true, CertFile: pki.serverCert, KeyFile: pki.serverKey, ClientAuth: "require", ClientCAFile: pki.serverKey}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := TLSConfig(tt.cfg)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCertificateReloader(t *testing.T) {
+	pki := createTestPKI(t)
+	reloader := &certificateReloader{certFile: pki.serverCert, keyFile: pki.serverKey}
+	first, err := reloader.GetCertificate(nil)
+	require.NoError(t, err)
+
+	// A rotated certificate is picked up
+	other := createTestPKI(t)
+	data, err := os.ReadFile(other.serverCert)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(pki.serverCert, data, 0o600))
+	data, err = os.ReadFile(other.serverKey)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(pki.serverKey, data, 0o600))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(pki.serverCert, later, later))
+
+	second, err := reloader.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Certificate[0], second.Certificate[0])
+
+	// A broken replacement keeps the loaded certificate
+	require.NoError(t, os.WriteFile(pki.serverCert, []byte("broken"), 0o600))
+	require.NoError(t, os.Chtimes(pki.serverCert, later.Add(time.Minute), later.Add(time.Minute)))
+	third, err := reloader.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, second.Certificate[0], third.Certificate[0])
+}
+
+// Helper functions
+
+// testPKI is a CA with a server certificate for 127.0.0.1, written to files
+type testPKI struct {
+	caFile     string
+	serverCert string
+	serverKey  string
+	pool       *x509.CertPool
+	ca         *x509.Certificate
+	caKey      *ecdsa.PrivateKey
+}
+
+func createTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+	dir := t.TempDir()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pki := &testPKI{caFile: filepath.Join(dir, "ca.pem"), pool: x509.NewCertPool(), ca: ca, caKey: caKey}
+	pki.pool.AddCert(ca)
+	require.NoError(t, os.WriteFile(pki.caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+
+	server := pki.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "notification-service"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	pki.serverCert = filepath.Join(dir, "server.pem")
+	pki.serverKey = filepath.Join(dir, "server-key.pem")
+	require.NoError(t, os.WriteFile(pki.serverCert, server.certPEM, 0o600))
+	require.NoError(t, os.WriteFile(pki.serverKey, server.keyPEM, 0o600))
+	return pki
+}
+
+type testKeyPair struct {
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func (p *testPKI) issue(t *testing.T, template *x509.Certificate) testKeyPair {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	der, err := x509.CreateCertificate(rand.Reader, template, p.ca, &key.PublicKey, p.caKey)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return testKeyPair{
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// client returns an HTTP client presenting a certificate with a URI SAN
+func (p *testPKI) client(t *testing.T, uri string) *http.Client {
+	t.Helper()
+	parsed, err := url.Parse(uri)
+	require.NoError(t, err)
+	pair := p.issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "client"},
+		URIs:        []*url.URL{parsed},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	certificate, err := tls.X509KeyPair(pair.certPEM, pair.keyPEM)
+	require.NoError(t, err)
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      p.pool,
+		Certificates: []tls.Certificate{certificate},
+	}}}
+}
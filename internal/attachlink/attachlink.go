@@ -0,0 +1,248 @@
+// Package attachlink keeps emails under provider size limits by offloading
+// large attachments: they are uploaded to object storage and replaced in the
+// email by signed download links that expire. The service serves the
+// downloads, so the store need not be public.
+package attachlink
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// DownloadPath is the path downloads are served under, followed by the key
+const DownloadPath = "/attachments/"
+
+// Defaults applied to zero configuration fields
+const (
+	defaultThreshold = 10 << 20
+	defaultLinkTTL   = 7 * 24 * time.Hour
+)
+
+// minSigningKeyLength is the shortest signing key accepted, in bytes
+const minSigningKeyLength = 32
+
+// Object is an offloaded attachment
+type Object struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
+// Store keeps offloaded attachments
+type Store interface {
+	Put(ctx context.Context, key string, object *Object) error
+	Get(ctx context.Context, key string) (*Object, error)
+}
+
+// Link is the download link of an offloaded attachment
+type Link struct {
+	Filename  string    `json:"filename"`
+	Size      int64     `json:"size"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Offloader offloads large attachments and serves their downloads
+type Offloader struct {
+	store        Store
+	threshold    int64
+	maxTotalSize int64
+	ttl          time.Duration
+	baseURL      string
+	key          []byte
+	now          func() time.Time
+}
+
+// NewOffloader creates an offloader that keeps attachments in the store
+func NewOffloader(store Store, cfg config.AttachLinkConfig) (*Offloader, error) {
+	base, err := url.Parse(cfg.BaseURL)
+	if err != nil || base.Host == "" || (base.Scheme != "https" && base.Scheme != "http") {
+		return nil, errors.NewValidationError("base_url", "attachment link base URL must be an http or https URL")
+	}
+	if len(cfg.SigningKey) < minSigningKeyLength {
+		return nil, errors.NewValidationError("signing_key", fmt.Sprintf("attachment link signing key must be at least %d bytes", minSigningKeyLength))
+	}
+
+	threshold := cfg.Threshold
+	if threshold <= 0 {
+		threshold = defaultThreshold
+	}
+	ttl := cfg.LinkTTL
+	if ttl <= 0 {
+		ttl = defaultLinkTTL
+	}
+
+	return &Offloader{
+		store:        store,
+		threshold:    threshold,
+		maxTotalSize: cfg.MaxTotalSize,
+		ttl:          ttl,
+		baseURL:      strings.TrimRight(cfg.BaseURL, "/"),
+		key:          []byte(cfg.SigningKey),
+		now:          time.Now,
+	}, nil
+}
+
+// Offload uploads the email's attachments over the threshold, and the
+// largest others while all of them together exceed the maximum total size.
+// It returns a copy of the email with their download links in its bodies
+// instead, or the email itself when nothing is offloaded.
+func (o *Offloader) Offload(ctx context.Context, email *models.EmailNotification) (*models.EmailNotification, []Link, error) {
+	offload := o.selectAttachments(email.Attachments)
+	if len(offload) == 0 {
+		return email, nil, nil
+	}
+
+	expires := o.now().Add(o.ttl).Truncate(time.Second)
+	kept := make([]models.EmailAttachment, 0, len(email.Attachments)-len(offload))
+	links := make([]Link, 0, len(offload))
+	for i, attachment := range email.Attachments {
+		if !offload[i] {
+			kept = append(kept, attachment)
+			continue
+		}
+
+		key := uuid.New().String()
+		object := &Object{Filename: attachment.Filename, ContentType: attachment.ContentType, Content: attachment.Content}
+		if err := o.store.Put(ctx, key, object); err != nil {
+			return nil, nil, errors.NewNotificationErrorWithDetails(errors.ErrorCodeProviderUnavailable,
+				fmt.Sprintf("failed to offload attachment %s", attachment.Filename), err.Error()).WithCause(err)
+		}
+		links = append(links, Link{
+			Filename:  attachment.Filename,
+			Size:      int64(len(attachment.Content)),
+			URL:       o.link(key, expires),
+			ExpiresAt: expires,
+		})
+	}
+
+	offloaded := *email
+	offloaded.Attachments = kept
+	if email.HTMLBody != "" {
+		offloaded.HTMLBody = insertHTML(email.HTMLBody, linksHTML(links))
+	}
+	if email.TextBody != "" || email.HTMLBody == "" {
+		offloaded.TextBody = strings.TrimRight(email.TextBody, "\n") + "\n\n" + linksText(links)
+	}
+	return &offloaded, links, nil
+}
+
+// Open checks a download link's signature and expiry and returns its attachment
+func (o *Offloader) Open(ctx context.Context, key, expires, signature string) (*Object, error) {
+	if !hmac.Equal([]byte(signature), []byte(o.sign(key, expires))) {
+		return nil, errors.NewNotificationError(errors.ErrorCodeUnauthorized, "download link is not valid")
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || !o.now().Before(time.Unix(unix, 0)) {
+		return nil, errors.NewNotificationError(errors.ErrorCodeNotFound, "download link has expired")
+	}
+
+	object, err := o.store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+// selectAttachments returns the indexes of the attachments to offload
+func (o *Offloader) selectAttachments(attachments []models.EmailAttachment) map[int]bool {
+	offload := make(map[int]bool)
+	var total int64
+	var remaining []int
+	for i, attachment := range attachments {
+		size := int64(len(attachment.Content))
+		if size > o.threshold {
+			offload[i] = true
+			continue
+		}
+		total += size
+		remaining = append(remaining, i)
+	}
+
+	if o.maxTotalSize <= 0 || total <= o.maxTotalSize {
+		return offload
+	}
+	sort.SliceStable(remaining, func(a, b int) bool {
+		return len(attachments[remaining[a]].Content) > len(attachments[remaining[b]].Content)
+	})
+	for _, i := range remaining {
+		if total <= o.maxTotalSize {
+			break
+		}
+		offload[i] = true
+		total -= int64(len(attachments[i].Content))
+	}
+	return offload
+}
+
+// link returns the signed download link of a key
+func (o *Offloader) link(key string, expires time.Time) string {
+	unix := strconv.FormatInt(expires.Unix(), 10)
+	query := url.Values{"expires": {unix}, "signature": {o.sign(key, unix)}}
+	return o.baseURL + DownloadPath + key + "?" + query.Encode()
+}
+
+// sign returns the signature of a key and expiry
+func (o *Offloader) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, o.key)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// insertHTML inserts a block before the closing body tag, or at the end
+func insertHTML(body, block string) string {
+	if index := strings.LastIndex(strings.ToLower(body), "</body>"); index >= 0 {
+		return body[:index] + block + body[index:]
+	}
+	return body + block
+}
+
+// linksHTML lists download links as HTML
+func linksHTML(links []Link) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "<p>These attachments are too large to send by email. Download them until %s:</p><ul>",
+		html.EscapeString(links[0].ExpiresAt.UTC().Format("January 2, 2006")))
+	for _, link := range links {
+		fmt.Fprintf(&builder, `<li><a href="%s">%s</a> (%s)</li>`,
+			html.EscapeString(link.URL), html.EscapeString(link.Filename), formatSize(link.Size))
+	}
+	builder.WriteString("</ul>")
+	return builder.String()
+}
+
+// linksText lists download links as plain text
+func linksText(links []Link) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "These attachments are too large to send by email. Download them until %s:\n",
+		links[0].ExpiresAt.UTC().Format("January 2, 2006"))
+	for _, link := range links {
+		fmt.Fprintf(&builder, "- %s (%s): %s\n", link.Filename, formatSize(link.Size), link.URL)
+	}
+	return builder.String()
+}
+
+// formatSize formats a size in bytes for people
+func formatSize(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%d bytes", size)
+	}
+}
@@ -0,0 +1,181 @@
+package attachlink
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+const testSigningKey = "0123456789abcdef0123456789abcdef"
+
+func TestOffloader_Offload(t *testing.T) {
+	store := NewMemoryStore(time.Hour)
+	offloader := createTestOffloader(t, store, config.AttachLinkConfig{Threshold: 1000})
+	email := createTestEmail(createTestAttachment("report.pdf", 5000), createTestAttachment("logo.png", 100))
+
+	offloaded, links, err := offloader.Offload(context.Background(), email)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, "report.pdf", links[0].Filename)
+	assert.Equal(t, int64(5000), links[0].Size)
+	assert.True(t, strings.HasPrefix(links[0].URL, "https://notify.example.com/attachments/"))
+
+	require.Len(t, offloaded.Attachments, 1)
+	assert.Equal(t, "logo.png", offloaded.Attachments[0].Filename)
+	assert.Len(t, email.Attachments, 2) // the caller's email is not modified
+	assert.Contains(t, offloaded.HTMLBody, `<a href="`+strings.ReplaceAll(links[0].URL, "&", "&amp;")+`">report.pdf</a> (4.9 KB)</li></ul></body>`)
+	assert.Contains(t, offloaded.TextBody, "- report.pdf (4.9 KB): "+links[0].URL)
+
+	// The link opens the attachment
+	link, err := url.Parse(links[0].URL)
+	require.NoError(t, err)
+	object, err := offloader.Open(context.Background(), strings.TrimPrefix(link.Path, DownloadPath), link.Query().Get("expires"), link.Query().Get("signature"))
+	require.NoError(t, err)
+	assert.Equal(t, "report.pdf", object.Filename)
+	assert.Len(t, object.Content, 5000)
+
+	// Small attachments are left alone
+	small := createTestEmail(createTestAttachment("logo.png", 100))
+	unchanged, links, err := offloader.Offload(context.Background(), small)
+	require.NoError(t, err)
+	assert.Empty(t, links)
+	assert.Same(t, small, unchanged)
+}
+
+func TestOffloader_OffloadMaxTotalSize(t *testing.T) {
+	offloader := createTestOffloader(t, NewMemoryStore(time.Hour), config.AttachLinkConfig{Threshold: 1000, MaxTotalSize: 1000})
+	email := createTestEmail(createTestAttachment("a.pdf", 400), createTestAttachment("b.pdf", 700), createTestAttachment("c.pdf", 300))
+
+	offloaded, links, err := offloader.Offload(context.Background(), email)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, "b.pdf", links[0].Filename) // the largest goes first
+	assert.Len(t, offloaded.Attachments, 2)
+}
+
+func TestOffloader_Open(t *testing.T) {
+	store := NewMemoryStore(time.Hour)
+	offloader := createTestOffloader(t, store, config.AttachLinkConfig{LinkTTL: time.Minute})
+	now := time.Now()
+	offloader.now = func() time.Time { return now }
+	require.NoError(t, store.Put(context.Background(), "key", &Object{Filename: "a.pdf", Content: []byte("data")}))
+
+	link, err := url.Parse(offloader.link("key", now.Add(time.Minute)))
+	require.NoError(t, err)
+	expires, signature := link.Query().Get("expires"), link.Query().Get("signature")
+
+	_, err = offloader.Open(context.Background(), "key", expires, signature)
+	require.NoError(t, err)
+
+	_, err = offloader.Open(context.Background(), "other", expires, signature)
+	assertErrorCode(t, errors.ErrorCodeUnauthorized, err)
+	_, err = offloader.Open(context.Background(), "key", expires+"0", signature)
+	assertErrorCode(t, errors.ErrorCodeUnauthorized, err)
+
+	now = now.Add(2 * time.Minute)
+	_, err = offloader.Open(context.Background(), "key", expires, signature)
+	assertErrorCode(t, errors.ErrorCodeNotFound, err)
+}
+
+func TestNewOffloader_Validation(t *testing.T) {
+	_, err := NewOffloader(NewMemoryStore(0), config.AttachLinkConfig{BaseURL: "notify.example.com", SigningKey: testSigningKey})
+	assert.Error(t, err)
+	_, err = NewOffloader(NewMemoryStore(0), config.AttachLinkConfig{BaseURL: "https://notify.example.com", SigningKey: "short"})
+	assert.Error(t, err)
+}
+
+func TestHTTPStore(t *testing.T) {
+	var mu sync.Mutex
+	objects := make(map[string][]byte)
+	headers := make(map[string]http.Header)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			content, _ := io.ReadAll(r.Body)
+			objects[r.URL.Path] = content
+			headers[r.URL.Path] = r.Header.Clone()
+		case http.MethodGet:
+			content, exists := objects[r.URL.Path]
+			if !exists {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", headers[r.URL.Path].Get("Content-Type"))
+			w.Header().Set("Content-Disposition", headers[r.URL.Path].Get("Content-Disposition"))
+			w.Write(content)
+		}
+	}))
+	defer server.Close()
+
+	store, err := NewHTTPStore(config.AttachLinkConfig{StoreURL: server.URL + "/bucket/", StoreToken: "secret"})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, store.Put(ctx, "key", &Object{Filename: "quarterly report.pdf", ContentType: "application/pdf", Content: []byte("%PDF")}))
+	object, err := store.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, &Object{Filename: "quarterly report.pdf", ContentType: "application/pdf", Content: []byte("%PDF")}, object)
+
+	_, err = store.Get(ctx, "missing")
+	assertErrorCode(t, errors.ErrorCodeNotFound, err)
+
+	_, err = NewHTTPStore(config.AttachLinkConfig{})
+	assert.Error(t, err)
+}
+
+// Helper functions
+
+func createTestOffloader(t *testing.T, store Store, cfg config.AttachLinkConfig) *Offloader {
+	cfg.BaseURL = "https://notify.example.com/"
+	cfg.SigningKey = testSigningKey
+	offloader, err := NewOffloader(store, cfg)
+	require.NoError(t, err)
+	return offloader
+}
+
+func createTestEmail(attachments ...models.EmailAttachment) *models.EmailNotification {
+	return &models.EmailNotification{
+		Notification: models.Notification{ID: uuid.New(), Type: models.NotificationTypeEmail, Recipient: "user@example.com"},
+		To:           []string{"user@example.com"},
+		HTMLBody:     "<html><body><p>Your report is attached.</p></body></html>",
+		TextBody:     "Your report is attached.",
+		Attachments:  attachments,
+	}
+}
+
+func createTestAttachment(filename string, size int) models.EmailAttachment {
+	return models.EmailAttachment{
+		Filename:    filename,
+		ContentType: "application/octet-stream",
+		Content:     bytes.Repeat([]byte("x"), size),
+		Size:        int64(size),
+	}
+}
+
+func assertErrorCode(t *testing.T, code errors.ErrorCode, err error) {
+	t.Helper()
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok, "%v", err)
+	assert.Equal(t, code, notifErr.Code)
+}
@@ -0,0 +1,162 @@
+package attachlink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/httpclient"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// defaultStoreTimeout bounds an upload or download
+const defaultStoreTimeout = 60 * time.Second
+
+// maxObjectSize bounds an object read from the store
+const maxObjectSize = 1 << 30
+
+// MemoryStore keeps attachments in memory, for development and tests.
+// Attachments are dropped once their links have expired.
+type MemoryStore struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	objects map[string]memoryObject
+	now     func() time.Time
+}
+
+// memoryObject is a stored attachment
+type memoryObject struct {
+	object  *Object
+	expires time.Time
+}
+
+// NewMemoryStore creates a memory store keeping attachments for the link TTL
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	if ttl <= 0 {
+		ttl = defaultLinkTTL
+	}
+	return &MemoryStore{ttl: ttl, objects: make(map[string]memoryObject), now: time.Now}
+}
+
+// Put implements the Store interface
+func (s *MemoryStore) Put(ctx context.Context, key string, object *Object) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for existing, stored := range s.objects {
+		if !now.Before(stored.expires) {
+			delete(s.objects, existing)
+		}
+	}
+	s.objects[key] = memoryObject{object: object, expires: now.Add(s.ttl)}
+	return nil
+}
+
+// Get implements the Store interface
+func (s *MemoryStore) Get(ctx context.Context, key string) (*Object, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, exists := s.objects[key]
+	if !exists || !s.now().Before(stored.expires) {
+		return nil, errors.NewNotificationError(errors.ErrorCodeNotFound, "attachment not found")
+	}
+	return stored.object, nil
+}
+
+// HTTPStore keeps attachments with HTTP PUT and GET requests, as S3, GCS
+// and most object stores accept. The filename is kept in the
+// Content-Disposition header.
+type HTTPStore struct {
+	storeURL string
+	token    string
+	client   *http.Client
+}
+
+// NewHTTPStore creates a store for the configured store URL
+func NewHTTPStore(cfg config.AttachLinkConfig) (*HTTPStore, error) {
+	store, err := url.Parse(cfg.StoreURL)
+	if err != nil || store.Host == "" || (store.Scheme != "https" && store.Scheme != "http") {
+		return nil, errors.NewValidationError("store_url", "attachment store URL must be an http or https URL")
+	}
+
+	return &HTTPStore{
+		storeURL: strings.TrimRight(cfg.StoreURL, "/"),
+		token:    cfg.StoreToken,
+		client:   httpclient.Shared.Client(config.HTTPClientConfig{}, defaultStoreTimeout),
+	}, nil
+}
+
+// Put implements the Store interface
+func (s *HTTPStore) Put(ctx context.Context, key string, object *Object) error {
+	request, err := s.request(ctx, http.MethodPut, key, bytes.NewReader(object.Content))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", object.ContentType)
+	request.Header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": object.Filename}))
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("object store returned status %d", response.StatusCode)
+	}
+	return nil
+}
+
+// Get implements the Store interface
+func (s *HTTPStore) Get(ctx context.Context, key string) (*Object, error) {
+	request, err := s.request(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return nil, errors.NewNotificationErrorWithDetails(errors.ErrorCodeProviderUnavailable, "attachment store is unreachable", err.Error())
+	}
+	defer response.Body.Close()
+
+	switch {
+	case response.StatusCode == http.StatusNotFound:
+		return nil, errors.NewNotificationError(errors.ErrorCodeNotFound, "attachment not found")
+	case response.StatusCode < 200 || response.StatusCode >= 300:
+		return nil, errors.NewNotificationError(errors.ErrorCodeProviderUnavailable, fmt.Sprintf("attachment store returned status %d", response.StatusCode))
+	}
+
+	content, err := io.ReadAll(io.LimitReader(response.Body, maxObjectSize))
+	if err != nil {
+		return nil, errors.NewNotificationErrorWithDetails(errors.ErrorCodeProviderUnavailable, "failed to read the attachment", err.Error())
+	}
+	object := &Object{ContentType: response.Header.Get("Content-Type"), Content: content}
+	if _, params, err := mime.ParseMediaType(response.Header.Get("Content-Disposition")); err == nil {
+		object.Filename = params["filename"]
+	}
+	return object, nil
+}
+
+// request creates a request for a key
+func (s *HTTPStore) request(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	request, err := http.NewRequestWithContext(ctx, method, s.storeURL+"/"+url.PathEscape(key), body)
+	if err != nil {
+		return nil, err
+	}
+	if s.token != "" {
+		request.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	return request, nil
+}
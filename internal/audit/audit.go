@@ -0,0 +1,139 @@
+// Package audit records an append-only trail of send operations for
+// compliance investigations. The Recorder subscribes to the event bus and
+// attributes each entry to the actor carried in the request context.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nareshkumar-microsoft/notificationService/internal/events"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// actorKey is the context key for the acting API key or identity
+type actorKey struct{}
+
+// WithActor returns a context carrying the API key or identity making a request
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor carried by a context, if any
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// HashPayload returns the hex SHA-256 of a payload's JSON encoding, so the
+// audit trail can prove what was sent without storing message content
+func HashPayload(payload interface{}) string {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// eventOutcomes maps lifecycle events to audit outcomes
+var eventOutcomes = map[events.EventType]models.AuditOutcome{
+	events.EventNotificationQueued:       models.AuditOutcomeAccepted,
+	events.EventNotificationSent:         models.AuditOutcomeSent,
+	events.EventNotificationDelivered:    models.AuditOutcomeDelivered,
+	events.EventNotificationFailed:       models.AuditOutcomeFailed,
+	events.EventNotificationRetried:      models.AuditOutcomeRetried,
+	events.EventNotificationSuppressed:   models.AuditOutcomeSuppressed,
+	events.EventNotificationRejected:     models.AuditOutcomeRejected,
+	events.EventNotificationExpired:      models.AuditOutcomeExpired,
+	events.EventNotificationAcknowledged: models.AuditOutcomeAcknowledged,
+}
+
+// Recorder writes audit entries for send operations
+type Recorder struct {
+	repository interfaces.AuditRepository
+	logger     interfaces.Logger
+}
+
+// NewRecorder creates a new audit recorder
+func NewRecorder(repository interfaces.AuditRepository, logger interfaces.Logger) *Recorder {
+	return &Recorder{
+		repository: repository,
+		logger:     logger,
+	}
+}
+
+// HandleEvent implements events.Subscriber, recording every lifecycle event
+func (r *Recorder) HandleEvent(ctx context.Context, event events.Event) error {
+	outcome, exists := eventOutcomes[event.Type]
+	if !exists {
+		return nil
+	}
+
+	entry := &models.AuditEntry{
+		Action:           string(event.Type),
+		Outcome:          outcome,
+		NotificationType: event.NotificationType,
+		PayloadHash:      event.PayloadHash,
+		Reason:           event.Reason,
+		Timestamp:        event.OccurredAt,
+	}
+	if event.NotificationID != uuid.Nil {
+		entry.NotificationID = event.NotificationID.String()
+	}
+	if event.Error != "" && entry.Reason == "" {
+		entry.Reason = event.Error
+	}
+	if campaignID := event.Metadata["campaign_id"]; campaignID != "" {
+		entry.Metadata = map[string]string{"campaign_id": campaignID}
+	}
+
+	return r.Record(ctx, entry)
+}
+
+// Record appends a decision to the audit trail, such as a quota rejection
+// made outside the dispatcher. The actor is taken from the context when the
+// entry does not name one.
+func (r *Recorder) Record(ctx context.Context, entry *models.AuditEntry) error {
+	if entry == nil || entry.Action == "" || entry.Outcome == "" {
+		return errors.NewValidationError("entry", "audit entry requires an action and outcome")
+	}
+
+	if entry.ID == uuid.Nil {
+		entry.ID = uuid.New()
+	}
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	if entry.Actor == "" {
+		entry.Actor = ActorFromContext(ctx)
+	}
+
+	if err := r.repository.Append(ctx, entry); err != nil {
+		r.logger.Errorf("Failed to append audit entry for %s: %v", entry.Action, err)
+		return err
+	}
+	return nil
+}
+
+// ForNotification returns the audit trail of a notification, oldest first
+func (r *Recorder) ForNotification(ctx context.Context, notificationID string) ([]*models.AuditEntry, error) {
+	if notificationID == "" {
+		return nil, errors.NewValidationError("notification_id", "notification ID is required")
+	}
+	return r.repository.Query(ctx, interfaces.AuditFilters{NotificationID: notificationID})
+}
+
+// Query returns audit entries matching the filters, such as a time range
+func (r *Recorder) Query(ctx context.Context, filters interfaces.AuditFilters) ([]*models.AuditEntry, error) {
+	if filters.From != nil && filters.To != nil && filters.To.Before(*filters.From) {
+		return nil, errors.NewValidationError("to", "end of time range is before its start")
+	}
+	return r.repository.Query(ctx, filters)
+}
@@ -0,0 +1,115 @@
+package audit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/events"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/repository"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+func TestActorContext(t *testing.T) {
+	assert.Equal(t, "", ActorFromContext(context.Background()))
+	assert.Equal(t, "key-123", ActorFromContext(WithActor(context.Background(), "key-123")))
+}
+
+func TestHashPayload(t *testing.T) {
+	request := &models.NotificationRequest{Type: models.NotificationTypeEmail, Recipient: "a@example.com", Body: "Hello"}
+
+	hash := HashPayload(request)
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hash, HashPayload(request))
+
+	request.Body = "Hello!"
+	assert.NotEqual(t, hash, HashPayload(request))
+
+	// Values that cannot be encoded have no hash
+	assert.Equal(t, "", HashPayload(make(chan int)))
+}
+
+func TestRecorder_HandleEvent(t *testing.T) {
+	recorder := createTestRecorder()
+	ctx := WithActor(context.Background(), "key-123")
+	notificationID := uuid.New()
+
+	require.NoError(t, recorder.HandleEvent(ctx, events.Event{
+		Type:             events.EventNotificationQueued,
+		NotificationID:   notificationID,
+		NotificationType: models.NotificationTypeSMS,
+		Recipient:        "+14155550123",
+		PayloadHash:      "abc123",
+		OccurredAt:       time.Now(),
+		Metadata:         map[string]string{"campaign_id": "c-1", "order_id": "o-9"},
+	}))
+	require.NoError(t, recorder.HandleEvent(ctx, events.Event{
+		Type:           events.EventNotificationFailed,
+		NotificationID: notificationID,
+		Error:          "carrier rejected",
+	}))
+	require.NoError(t, recorder.HandleEvent(ctx, events.Event{
+		Type:   events.EventNotificationSuppressed,
+		Reason: "recipient unsubscribed",
+	}))
+
+	// Unknown event types are ignored
+	require.NoError(t, recorder.HandleEvent(ctx, events.Event{Type: "notification.unknown"}))
+
+	trail, err := recorder.ForNotification(ctx, notificationID.String())
+	require.NoError(t, err)
+	require.Len(t, trail, 2)
+
+	assert.Equal(t, models.AuditOutcomeAccepted, trail[0].Outcome)
+	assert.Equal(t, "key-123", trail[0].Actor)
+	assert.Equal(t, "abc123", trail[0].PayloadHash)
+	assert.Equal(t, map[string]string{"campaign_id": "c-1"}, trail[0].Metadata)
+	assert.Equal(t, models.AuditOutcomeFailed, trail[1].Outcome)
+	assert.Equal(t, "carrier rejected", trail[1].Reason)
+
+	suppressed := models.AuditOutcomeSuppressed
+	entries, err := recorder.Query(ctx, interfaces.AuditFilters{Outcome: &suppressed})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "recipient unsubscribed", entries[0].Reason)
+	assert.Empty(t, entries[0].NotificationID)
+}
+
+func TestRecorder_Record(t *testing.T) {
+	recorder := createTestRecorder()
+	ctx := WithActor(context.Background(), "key-123")
+
+	entry := &models.AuditEntry{Action: "quota.check", Outcome: models.AuditOutcomeRejected, Reason: "daily quota exceeded"}
+	require.NoError(t, recorder.Record(ctx, entry))
+	assert.NotEqual(t, uuid.Nil, entry.ID)
+	assert.Equal(t, "key-123", entry.Actor)
+	assert.False(t, entry.Timestamp.IsZero())
+
+	// An explicit actor wins over the context
+	require.NoError(t, recorder.Record(ctx, &models.AuditEntry{Action: "quota.check", Outcome: models.AuditOutcomeAccepted, Actor: "system"}))
+	entries, err := recorder.Query(ctx, interfaces.AuditFilters{Actor: "system"})
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	assert.Error(t, recorder.Record(ctx, &models.AuditEntry{Action: "missing outcome"}))
+	assert.Error(t, recorder.Record(ctx, nil))
+
+	_, err = recorder.ForNotification(ctx, "")
+	assert.Error(t, err)
+
+	from, to := time.Now(), time.Now().Add(-time.Hour)
+	_, err = recorder.Query(ctx, interfaces.AuditFilters{From: &from, To: &to})
+	assert.Error(t, err)
+}
+
+// Helper functions
+
+func createTestRecorder() *Recorder {
+	return NewRecorder(repository.NewMemoryAuditRepository(), utils.NewSimpleLogger("info"))
+}
@@ -0,0 +1,226 @@
+// Package branding manages tenant branding: the variables, such as the logo
+// URL, colors, footer address and support email, that are injected into
+// email and push templates as {{brand.<key>}}, and the assets, such as
+// logos, they point to. Assets are uploaded to an object store, so
+// templates reference {{brand.logo_url}} instead of hardcoded URLs.
+package branding
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/layout"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pushmedia"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// Limits of branding variables and assets
+const (
+	maxValueLength      = 1000
+	defaultMaxAssetSize = 1 << 20
+)
+
+var (
+	// tenantPattern matches tenant IDs; they become part of asset keys
+	tenantPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+	// keyPattern matches branding variable and asset names
+	keyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+	// colorPattern matches hex colors, e.g. "#ff6600" or "#f60"
+	colorPattern = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+)
+
+// assetTypes are the image types email clients and push platforms show
+var assetTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// Store stores uploaded assets and returns their public URLs
+type Store interface {
+	Put(ctx context.Context, key, contentType string, data []byte) (string, error)
+}
+
+// NewHTTPStore creates a store that uploads assets with HTTP PUT requests to
+// the configured upload URL and serves them from the public URL
+func NewHTTPStore(cfg config.BrandingConfig) (Store, error) {
+	store, err := pushmedia.NewHTTPStore(config.PushMediaConfig{
+		UploadURL:   cfg.AssetUploadURL,
+		PublicURL:   cfg.AssetPublicURL,
+		UploadToken: cfg.AssetUploadToken,
+	})
+	if err != nil {
+		return nil, errors.NewValidationError("branding", "branding asset upload URL must be an http or https URL and public URL an https URL")
+	}
+	return store, nil
+}
+
+// Branding is a tenant's branding
+type Branding struct {
+	TenantID string            `json:"tenant_id"`
+	Branding map[string]string `json:"branding"`         // the variables templates get, defaults included
+	Custom   map[string]string `json:"custom,omitempty"` // the variables the tenant set itself
+}
+
+// Asset is an uploaded branding asset
+type Asset struct {
+	Name        string `json:"name"`
+	Variable    string `json:"variable"` // the branding variable set to the URL, e.g. "logo_url"
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
+
+// Service sets tenant branding in a layout library and uploads branding
+// assets. It is safe for concurrent use.
+type Service struct {
+	library      *layout.Library
+	store        Store
+	maxAssetSize int64
+}
+
+// NewService creates a branding service. Uploads are disabled when store is nil.
+func NewService(library *layout.Library, store Store, cfg config.BrandingConfig) *Service {
+	maxAssetSize := cfg.MaxAssetSize
+	if maxAssetSize <= 0 {
+		maxAssetSize = defaultMaxAssetSize
+	}
+	return &Service{library: library, store: store, maxAssetSize: maxAssetSize}
+}
+
+// Get returns a tenant's branding
+func (s *Service) Get(tenantID string) (*Branding, error) {
+	if err := validateTenant(tenantID); err != nil {
+		return nil, err
+	}
+	custom, _ := s.library.TenantBranding(tenantID)
+	return &Branding{TenantID: tenantID, Branding: s.library.Branding(tenantID), Custom: custom}, nil
+}
+
+// Update merges variables into a tenant's branding. An empty value removes
+// the tenant's own variable.
+func (s *Service) Update(tenantID string, vars map[string]string) (*Branding, error) {
+	if err := validateTenant(tenantID); err != nil {
+		return nil, err
+	}
+	if err := Validate(vars); err != nil {
+		return nil, err
+	}
+	s.library.UpdateBranding(tenantID, vars)
+	return s.Get(tenantID)
+}
+
+// UploadAsset stores an image and sets the tenant's <name>_url variable to
+// its URL, e.g. an asset named "logo" sets {{brand.logo_url}}. Assets are
+// stored under a hash of their content, so uploading the same image again
+// returns the same URL.
+func (s *Service) UploadAsset(ctx context.Context, tenantID, name string, data []byte) (*Asset, error) {
+	if s.store == nil {
+		return nil, errors.NewNotificationError(errors.ErrorCodeProviderConfiguration, "branding asset uploads are not configured")
+	}
+	if err := validateTenant(tenantID); err != nil {
+		return nil, err
+	}
+	if !keyPattern.MatchString(name) || strings.HasSuffix(name, "_url") {
+		return nil, errors.NewValidationError("name", fmt.Sprintf("asset name %q must be lowercase letters, digits and underscores, not ending in _url", name))
+	}
+	if len(data) == 0 {
+		return nil, errors.NewValidationError("asset", "asset is required")
+	}
+	if int64(len(data)) > s.maxAssetSize {
+		return nil, errors.NewValidationError("asset", fmt.Sprintf("asset is %d bytes, over the limit of %d", len(data), s.maxAssetSize))
+	}
+
+	contentType := http.DetectContentType(data)
+	if !assetTypes[contentType] {
+		return nil, errors.NewValidationError("asset", fmt.Sprintf("asset must be a PNG, JPEG, GIF or WebP image, got %s", contentType))
+	}
+
+	sum := sha256.Sum256(data)
+	key := "branding/" + tenantID + "/" + name + "-" + hex.EncodeToString(sum[:])
+	if extensions, _ := mime.ExtensionsByType(contentType); len(extensions) > 0 {
+		key += extensions[0]
+	}
+
+	hosted, err := s.store.Put(ctx, key, contentType, data)
+	if err != nil {
+		return nil, errors.NewNotificationError(errors.ErrorCodeProviderUnavailable, "branding asset upload failed").WithCause(err)
+	}
+
+	asset := &Asset{Name: name, Variable: name + "_url", URL: hosted, ContentType: contentType, Size: int64(len(data))}
+	s.library.UpdateBranding(tenantID, map[string]string{asset.Variable: hosted})
+	return asset, nil
+}
+
+// Validate checks branding variables: names are lowercase, *_color values
+// are hex colors, *_url values are https URLs and *_email values are email
+// addresses. Empty values, which remove variables, are always valid.
+func Validate(vars map[string]string) error {
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var failures []errors.FieldError
+	for _, key := range keys {
+		value := vars[key]
+		field := "branding." + key
+		if !keyPattern.MatchString(key) {
+			failures = append(failures, errors.FieldError{Field: field, Rule: "name", Message: "must be lowercase letters, digits and underscores"})
+			continue
+		}
+		if message := checkValue(key, value); message != "" {
+			failures = append(failures, errors.FieldError{Field: field, Rule: "format", Message: message})
+		}
+	}
+
+	if len(failures) > 0 {
+		return errors.NewFieldValidationError(failures...)
+	}
+	return nil
+}
+
+// checkValue returns why a variable's value is invalid, or "" when it is valid
+func checkValue(key, value string) string {
+	switch {
+	case value == "":
+		return ""
+	case len(value) > maxValueLength:
+		return fmt.Sprintf("must be at most %d characters", maxValueLength)
+	case strings.HasSuffix(key, "_color"):
+		if !colorPattern.MatchString(value) {
+			return fmt.Sprintf("must be a hex color such as #ff6600, got %q", value)
+		}
+	case strings.HasSuffix(key, "_url"):
+		parsed, err := url.Parse(value)
+		if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+			return fmt.Sprintf("must be an https URL, got %q", value)
+		}
+	case strings.HasSuffix(key, "_email"):
+		if utils.ValidateEmailAddress(value) != nil {
+			return fmt.Sprintf("must be an email address, got %q", value)
+		}
+	}
+	return ""
+}
+
+// validateTenant checks a tenant ID. The default branding, with an empty
+// tenant ID, is set in configuration rather than through the service.
+func validateTenant(tenantID string) error {
+	if !tenantPattern.MatchString(tenantID) {
+		return errors.NewValidationError("tenant_id", fmt.Sprintf("invalid tenant ID %q", tenantID))
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package branding
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/layout"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// testPNG is the signature and header of a PNG image
+var testPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00")
+
+func TestService_Update(t *testing.T) {
+	library := layout.NewLibrary()
+	service := NewService(library, nil, config.BrandingConfig{})
+
+	tenant, err := service.Update("acme", map[string]string{
+		"name":           "Acme",
+		"primary_color":  "#ff6600",
+		"footer_address": "1 Main St, Springfield",
+		"support_email":  "help@acme.example",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Acme", tenant.Branding["name"])
+	assert.Equal(t, layout.DefaultBranding["secondary_color"], tenant.Branding["secondary_color"])
+	assert.Len(t, tenant.Custom, 4)
+	assert.Equal(t, "help@acme.example", library.Data("acme", nil)["brand.support_email"])
+
+	tenant, err = service.Update("acme", map[string]string{"name": ""})
+	require.NoError(t, err)
+	assert.Equal(t, layout.DefaultBranding["name"], tenant.Branding["name"])
+	assert.NotContains(t, tenant.Custom, "name")
+}
+
+func TestService_UpdateValidation(t *testing.T) {
+	service := NewService(layout.NewLibrary(), nil, config.BrandingConfig{})
+
+	_, err := service.Update("acme", map[string]string{
+		"primary_color": "orange",
+		"logo_url":      "http://acme.example/logo.png",
+		"support_email": "not-an-email",
+		"Name":          "Acme",
+	})
+	require.Error(t, err)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	fields := make([]string, len(notifErr.Fields))
+	for i, field := range notifErr.Fields {
+		fields[i] = field.Field
+	}
+	assert.Equal(t, []string{"branding.Name", "branding.logo_url", "branding.primary_color", "branding.support_email"}, fields)
+
+	_, err = service.Update("../acme", map[string]string{"name": "Acme"})
+	assert.Error(t, err)
+	_, err = service.Get("")
+	assert.Error(t, err)
+}
+
+func TestService_UploadAsset(t *testing.T) {
+	library := layout.NewLibrary()
+	store := &memoryStore{objects: make(map[string][]byte)}
+	service := NewService(library, store, config.BrandingConfig{MaxAssetSize: 1024})
+	ctx := context.Background()
+
+	asset, err := service.UploadAsset(ctx, "acme", "logo", testPNG)
+	require.NoError(t, err)
+	assert.Equal(t, "logo_url", asset.Variable)
+	assert.Equal(t, "image/png", asset.ContentType)
+	assert.Regexp(t, `^https://cdn\.example\.com/branding/acme/logo-[0-9a-f]{64}\.png$`, asset.URL)
+	assert.Equal(t, asset.URL, library.Branding("acme")["logo_url"])
+
+	// The same image gets the same URL
+	again, err := service.UploadAsset(ctx, "acme", "logo", testPNG)
+	require.NoError(t, err)
+	assert.Equal(t, asset.URL, again.URL)
+	assert.Len(t, store.objects, 1)
+
+	for name, data := range map[string][]byte{
+		"Logo":     testPNG,
+		"logo_url": testPNG,
+		"empty":    nil,
+		"text":     []byte("not an image"),
+		"large":    append(append([]byte(nil), testPNG...), make([]byte, 1024)...),
+	} {
+		_, err := service.UploadAsset(ctx, "acme", name, data)
+		assert.Error(t, err, name)
+	}
+
+	store.err = fmt.Errorf("connection refused")
+	_, err = service.UploadAsset(ctx, "acme", "icon", testPNG)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeProviderUnavailable, notifErr.Code)
+	assert.NotContains(t, library.Branding("acme"), "icon_url")
+}
+
+func TestService_UploadAssetNotConfigured(t *testing.T) {
+	service := NewService(layout.NewLibrary(), nil, config.BrandingConfig{})
+	_, err := service.UploadAsset(context.Background(), "acme", "logo", testPNG)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeProviderConfiguration, notifErr.Code)
+}
+
+func TestNewHTTPStore(t *testing.T) {
+	_, err := NewHTTPStore(config.BrandingConfig{AssetUploadURL: "https://storage.example.com/assets", AssetPublicURL: "https://cdn.example.com"})
+	assert.NoError(t, err)
+	_, err = NewHTTPStore(config.BrandingConfig{AssetUploadURL: "https://storage.example.com/assets"})
+	assert.Error(t, err)
+}
+
+// Helper functions
+
+type memoryStore struct {
+	objects map[string][]byte
+	err     error
+}
+
+func (s *memoryStore) Put(ctx context.Context, key, contentType string, data []byte) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	s.objects[key] = data
+	return "https://cdn.example.com/" + key, nil
+}
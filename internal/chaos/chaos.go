@@ -0,0 +1,241 @@
+// Package chaos injects faults into provider calls so retries, failover and
+// degradation detection can be tested end to end. A scenario file lists the
+// faults: calls are delayed, dropped or failed with an error code, at a
+// rate, for the channels and providers each fault matches.
+//
+// Chaos is for test environments only and is off unless enabled in the
+// configuration.
+package chaos
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// Action is what a fault does to a provider call
+type Action string
+
+// Fault actions
+const (
+	ActionDelay Action = "delay" // the call proceeds after the delay
+	ActionDrop  Action = "drop"  // the call never answers and times out
+	ActionError Action = "error" // the call fails with the fault's error code
+)
+
+// defaultDropTimeout bounds how long a dropped call waits when its fault
+// sets no delay and its context has no deadline
+const defaultDropTimeout = 30 * time.Second
+
+// Scenario is a named set of faults, as read from a scenario file
+type Scenario struct {
+	Name   string  `json:"name"`
+	Faults []Fault `json:"faults"`
+}
+
+// Fault describes the calls a fault applies to and what it does to them
+type Fault struct {
+	Name      string           `json:"name"`
+	Channels  []string         `json:"channels,omitempty"`  // e.g. "sms"; empty matches every channel
+	Providers []string         `json:"providers,omitempty"` // configured or named providers; empty matches every provider
+	Action    Action           `json:"action"`
+	Rate      float64          `json:"rate,omitempty"`  // probability a matching call is affected; 0 means every one
+	Times     int              `json:"times,omitempty"` // affect only the first N matching calls; 0 means no limit
+	Delay     string           `json:"delay,omitempty"` // e.g. "250ms"; how long a delay or drop holds the call
+	Code      errors.ErrorCode `json:"code,omitempty"`  // error returned by an error fault; PROVIDER_UNAVAILABLE by default
+}
+
+// fault is a validated fault with its count of affected calls
+type fault struct {
+	Fault
+	delay    time.Duration
+	injected int
+}
+
+// Injector applies a scenario's faults to provider calls. It is safe for
+// concurrent use.
+type Injector struct {
+	scenario string
+	logger   interfaces.Logger
+
+	mu     sync.Mutex
+	faults []*fault
+	rand   *rand.Rand
+}
+
+// LoadScenario reads a scenario file
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WrapError(err, "failed to read chaos scenario")
+	}
+
+	var scenario Scenario
+	if err := json.Unmarshal(data, &scenario); err != nil {
+		return nil, errors.NewNotificationErrorWithDetails(errors.ErrorCodeValidationFailed,
+			fmt.Sprintf("chaos scenario %s is not valid JSON", path), err.Error())
+	}
+	return &scenario, nil
+}
+
+// NewInjectorFromConfig creates an injector for the configured scenario
+// file. It returns nil when chaos is not enabled.
+func NewInjectorFromConfig(cfg config.ChaosConfig, logger interfaces.Logger) (*Injector, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if cfg.ScenarioFile == "" {
+		return nil, errors.NewValidationError("scenario_file", "chaos is enabled without a scenario file")
+	}
+
+	scenario, err := LoadScenario(cfg.ScenarioFile)
+	if err != nil {
+		return nil, err
+	}
+	return NewInjector(scenario, cfg.Seed, logger)
+}
+
+// NewInjector creates an injector for a scenario. With the same seed, the
+// same sequence of calls meets the same faults; 0 seeds from the clock.
+func NewInjector(scenario *Scenario, seed int64, logger interfaces.Logger) (*Injector, error) {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	i := &Injector{
+		scenario: scenario.Name,
+		logger:   logger,
+		rand:     rand.New(rand.NewSource(seed)),
+	}
+	for n, configured := range scenario.Faults {
+		field := fmt.Sprintf("faults[%d]", n)
+		f := &fault{Fault: configured}
+		if f.Name == "" {
+			f.Name = fmt.Sprintf("fault-%d", n+1)
+		}
+		if f.Rate < 0 || f.Rate > 1 {
+			return nil, errors.NewValidationError(field, fmt.Sprintf("rate must be between 0 and 1, got %g", f.Rate))
+		}
+		if f.Delay != "" {
+			delay, err := time.ParseDuration(f.Delay)
+			if err != nil || delay < 0 {
+				return nil, errors.NewValidationError(field, fmt.Sprintf("invalid delay: %s", f.Delay))
+			}
+			f.delay = delay
+		}
+
+		switch f.Action {
+		case ActionDelay:
+			if f.delay == 0 {
+				return nil, errors.NewValidationError(field, "delay faults need a delay")
+			}
+		case ActionDrop:
+			if f.delay == 0 {
+				f.delay = defaultDropTimeout
+			}
+		case ActionError:
+			if f.Code == "" {
+				f.Code = errors.ErrorCodeProviderUnavailable
+			}
+		default:
+			return nil, errors.NewValidationError(field, fmt.Sprintf("unknown action %q, expected delay, drop or error", f.Action))
+		}
+		i.faults = append(i.faults, f)
+	}
+	return i, nil
+}
+
+// Inject applies the faults matching a call to a provider before it is
+// made. Delays hold the call and let it proceed; drops hold it until its
+// context ends or the fault's delay passes and return ErrorCodeTimeout;
+// errors fail it at once. A nil error means the call should go ahead.
+func (i *Injector) Inject(ctx context.Context, channel models.NotificationType, provider string) error {
+	for _, f := range i.roll(channel, provider) {
+		switch f.Action {
+		case ActionDelay:
+			if err := hold(ctx, f.delay); err != nil {
+				return errors.NewNotificationError(errors.ErrorCodeTimeout,
+					fmt.Sprintf("chaos fault %s: call to %s cancelled during delay", f.Name, provider))
+			}
+		case ActionDrop:
+			hold(ctx, f.delay)
+			return errors.NewNotificationError(errors.ErrorCodeTimeout,
+				fmt.Sprintf("chaos fault %s: call to %s dropped", f.Name, provider))
+		case ActionError:
+			return errors.NewProviderError(provider, f.Code, fmt.Sprintf("chaos fault %s", f.Name))
+		}
+	}
+	return nil
+}
+
+// Injections returns how many calls each fault has affected, by fault name
+func (i *Injector) Injections() map[string]int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	injections := make(map[string]int, len(i.faults))
+	for _, f := range i.faults {
+		injections[f.Name] = f.injected
+	}
+	return injections
+}
+
+// roll returns the faults affecting a call, in scenario order
+func (i *Injector) roll(channel models.NotificationType, provider string) []*fault {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	var affected []*fault
+	for _, f := range i.faults {
+		if !matches(f.Channels, string(channel)) || !matches(f.Providers, provider) {
+			continue
+		}
+		if f.Times > 0 && f.injected >= f.Times {
+			continue
+		}
+		if f.Rate > 0 && i.rand.Float64() >= f.Rate {
+			continue
+		}
+		f.injected++
+		affected = append(affected, f)
+		i.logger.Debugf("Chaos scenario %s: %s %s call to %s", i.scenario, f.Action, channel, provider)
+	}
+	return affected
+}
+
+// matches reports whether a value is in a list, or the list is empty
+func matches(list []string, value string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// hold waits for a duration or until the context ends, returning the
+// context's error in that case
+func hold(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,120 @@
+package chaos
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestNewInjector_Validation(t *testing.T) {
+	tests := []struct {
+		name  string
+		fault Fault
+	}{
+		{"unknown action", Fault{Action: "explode"}},
+		{"delay without a delay", Fault{Action: ActionDelay}},
+		{"invalid delay", Fault{Action: ActionDelay, Delay: "soon"}},
+		{"rate over 1", Fault{Action: ActionError, Rate: 1.5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewInjector(&Scenario{Faults: []Fault{tt.fault}}, 1, utils.NewSimpleLogger("info"))
+			notifErr, ok := errors.AsNotificationError(err)
+			require.True(t, ok)
+			assert.Equal(t, errors.ErrorCodeValidationFailed, notifErr.Code)
+		})
+	}
+}
+
+func TestInjector_Error(t *testing.T) {
+	injector := createTestInjector(t, Fault{Name: "twilio-outage", Channels: []string{"SMS"}, Providers: []string{"twilio"}, Action: ActionError, Times: 2})
+
+	for i := 0; i < 2; i++ {
+		err := injector.Inject(context.Background(), models.NotificationTypeSMS, "twilio")
+		notifErr, ok := errors.AsNotificationError(err)
+		require.True(t, ok)
+		assert.Equal(t, errors.ErrorCodeProviderUnavailable, notifErr.Code)
+		assert.Contains(t, notifErr.Message, "twilio-outage")
+	}
+	assert.NoError(t, injector.Inject(context.Background(), models.NotificationTypeSMS, "twilio"))
+
+	// Other providers and channels are untouched
+	assert.NoError(t, injector.Inject(context.Background(), models.NotificationTypeSMS, "msg91"))
+	assert.NoError(t, injector.Inject(context.Background(), models.NotificationTypeVoice, "twilio"))
+	assert.Equal(t, map[string]int{"twilio-outage": 2}, injector.Injections())
+}
+
+func TestInjector_Rate(t *testing.T) {
+	injector := createTestInjector(t, Fault{Action: ActionError, Code: errors.ErrorCodeRateLimited, Rate: 0.25})
+
+	failed := 0
+	for i := 0; i < 1000; i++ {
+		if err := injector.Inject(context.Background(), models.NotificationTypeEmail, "ses"); err != nil {
+			failed++
+		}
+	}
+	assert.InDelta(t, 250, failed, 50)
+	assert.Equal(t, failed, injector.Injections()["fault-1"])
+}
+
+func TestInjector_DelayAndDrop(t *testing.T) {
+	injector := createTestInjector(t,
+		Fault{Name: "slow", Providers: []string{"ses"}, Action: ActionDelay, Delay: "20ms"},
+		Fault{Name: "lost", Providers: []string{"fcm"}, Action: ActionDrop, Delay: "10ms"},
+	)
+
+	start := time.Now()
+	require.NoError(t, injector.Inject(context.Background(), models.NotificationTypeEmail, "ses"))
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	err := injector.Inject(context.Background(), models.NotificationTypePush, "fcm")
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeTimeout, notifErr.Code)
+
+	// A cancelled call stops waiting
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = injector.Inject(ctx, models.NotificationTypeEmail, "ses")
+	notifErr, ok = errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeTimeout, notifErr.Code)
+}
+
+func TestNewInjectorFromConfig(t *testing.T) {
+	injector, err := NewInjectorFromConfig(config.ChaosConfig{ScenarioFile: "ignored.json"}, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	assert.Nil(t, injector)
+
+	_, err = NewInjectorFromConfig(config.ChaosConfig{Enabled: true}, utils.NewSimpleLogger("info"))
+	require.Error(t, err)
+
+	path := filepath.Join(t.TempDir(), "scenario.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"name":"sms-outage","faults":[{"name":"down","channels":["sms"],"action":"error","code":"PROVIDER_UNAVAILABLE"}]}`), 0o600))
+	injector, err = NewInjectorFromConfig(config.ChaosConfig{Enabled: true, ScenarioFile: path, Seed: 1}, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	assert.Error(t, injector.Inject(context.Background(), models.NotificationTypeSMS, "mock"))
+
+	require.NoError(t, os.WriteFile(path, []byte(`[]`), 0o600))
+	_, err = NewInjectorFromConfig(config.ChaosConfig{Enabled: true, ScenarioFile: path}, utils.NewSimpleLogger("info"))
+	require.Error(t, err)
+}
+
+// Helper functions
+
+func createTestInjector(t *testing.T, faults ...Fault) *Injector {
+	injector, err := NewInjector(&Scenario{Name: "test", Faults: faults}, 1, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	return injector
+}
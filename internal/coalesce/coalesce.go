@@ -0,0 +1,321 @@
+// Package coalesce folds alert storms into summaries. The first
+// notification with a dedup key is sent immediately; repeats of it sent to
+// the same recipient during the category's cool-down are held back and
+// reported by one "N more occurrences" follow-up when the cool-down ends.
+// Replicas sharing a Locker send the first notification of a key once
+// between them; each reports the repeats it held back.
+package coalesce
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pipeline"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// MiddlewareName is the name the coalescer's middleware is registered under
+const MiddlewareName = "coalesce"
+
+// Metadata keys read from requests and set on follow-ups
+const (
+	MetadataDedupKey       = "dedup_key"       // requests with the same key are coalesced
+	MetadataCoalescedCount = "coalesced_count" // set on a follow-up to the number of notifications it reports
+)
+
+// followUpKey is the context key marking a follow-up's send, so the
+// middleware lets it through. Callers cannot set it, unlike metadata.
+type followUpKey struct{}
+
+// lockKeyPrefix prefixes the lock a first notification claims its key with
+const lockKeyPrefix = "coalesce:"
+
+// burst is the notifications of a dedup key sent to a recipient within a cool-down
+type burst struct {
+	startedAt  time.Time
+	coolDown   time.Duration
+	suppressed int
+	latest     *models.NotificationRequest
+	timer      *time.Timer
+}
+
+// Coalescer coalesces repeated notifications per category. It is safe for
+// concurrent use.
+type Coalescer struct {
+	mu        sync.Mutex
+	coolDowns map[models.Category]time.Duration
+	bursts    map[string]*burst
+	sender    pipeline.Handler
+	locker    interfaces.Locker
+	owner     string
+	logger    interfaces.Logger
+	now       func() time.Time
+	stopped   bool
+}
+
+// NewCoalescer creates a coalescer for the configured cool-downs. Categories
+// without a cool-down are not coalesced.
+func NewCoalescer(cfg config.CoalesceConfig, logger interfaces.Logger) (*Coalescer, error) {
+	c := &Coalescer{
+		coolDowns: make(map[models.Category]time.Duration),
+		bursts:    make(map[string]*burst),
+		logger:    logger,
+		now:       time.Now,
+	}
+
+	for name, coolDown := range cfg.CoolDowns {
+		category := models.Category(name)
+		if !utils.IsValidCategory(category) {
+			return nil, errors.NewValidationError("cool_downs", fmt.Sprintf("unknown category: %q", name))
+		}
+		if coolDown < 0 {
+			return nil, errors.NewValidationError("cool_downs", fmt.Sprintf("cool-down of %s cannot be negative", name))
+		}
+		c.coolDowns[category] = coolDown
+	}
+
+	return c, nil
+}
+
+// SetSender sets the handler that sends follow-ups, typically the
+// dispatcher's SendNotification. Without a sender nothing is coalesced.
+func (c *Coalescer) SetSender(sender pipeline.Handler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sender = sender
+}
+
+// SetLocker makes the first notification of a key claim it as owner for the
+// cool-down. An instance that finds the key claimed by another holds the
+// notification back as a repeat, so replicas sharing the locker do not each
+// send it.
+func (c *Coalescer) SetLocker(locker interfaces.Locker, owner string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.locker = locker
+	c.owner = owner
+}
+
+// Middleware returns the send middleware coalescing repeats. Register it at
+// pipeline.StageDedup under MiddlewareName. Requests without a dedup key, in
+// a category without a cool-down, or that are follow-ups pass through.
+func (c *Coalescer) Middleware() pipeline.Middleware {
+	return func(next pipeline.Handler) pipeline.Handler {
+		return func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+			dedupKey := request.Metadata[MetadataDedupKey]
+			if followUp, _ := ctx.Value(followUpKey{}).(bool); dedupKey == "" || followUp {
+				return next(ctx, request)
+			}
+
+			key := string(request.Type) + "|" + request.Recipient + "|" + dedupKey
+			if response, held := c.hold(ctx, key, request); held {
+				return response, nil
+			}
+
+			response, err := next(ctx, request)
+			if err != nil {
+				c.forget(key)
+			}
+			return response, err
+		}
+	}
+}
+
+// Pending returns the number of follow-ups waiting to be sent
+func (c *Coalescer) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pending := 0
+	for _, b := range c.bursts {
+		if b.suppressed > 0 {
+			pending++
+		}
+	}
+	return pending
+}
+
+// Flush sends the follow-ups of every burst with repeats held back now,
+// rather than when their cool-downs end, as a digest of what was held back
+// so far. Each starts a new cool-down. It returns the number of follow-ups
+// sent.
+func (c *Coalescer) Flush() int {
+	c.mu.Lock()
+	due := make(map[string]*burst)
+	for key, b := range c.bursts {
+		if b.suppressed > 0 {
+			due[key] = b
+		}
+	}
+	c.mu.Unlock()
+
+	sent := 0
+	for key, b := range due {
+		if c.flush(key, b) {
+			sent++
+		}
+	}
+	return sent
+}
+
+// Stop cancels follow-ups that are still waiting
+func (c *Coalescer) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, b := range c.bursts {
+		b.timer.Stop()
+		delete(c.bursts, key)
+	}
+	c.stopped = true
+}
+
+// hold starts a burst for the first notification of a key, which is then
+// sent, or holds back a repeat within the burst's cool-down. A first
+// notification whose key another instance claimed is held back too.
+func (c *Coalescer) hold(ctx context.Context, key string, request *models.NotificationRequest) (*models.NotificationResponse, bool) {
+	category := request.Category
+	if category == "" {
+		category = models.CategoryTransactional
+	}
+
+	c.mu.Lock()
+	coolDown := c.coolDowns[category]
+	if coolDown <= 0 || c.sender == nil || c.stopped {
+		c.mu.Unlock()
+		return nil, false
+	}
+	_, exists := c.bursts[key]
+	locker, owner := c.locker, c.owner
+	c.mu.Unlock()
+
+	// Claiming may be a round trip to the locker, so it is done unlocked
+	claimed := true
+	if !exists && locker != nil {
+		claimed = c.claim(ctx, locker, key, owner, coolDown)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.sender == nil || c.stopped {
+		return nil, false
+	}
+
+	b, exists := c.bursts[key]
+	if !exists {
+		b = c.start(key, coolDown)
+		if claimed {
+			return nil, false
+		}
+	}
+
+	held := *request
+	b.latest = &held
+	b.suppressed++
+	followUpAt := b.startedAt.Add(coolDown)
+	return &models.NotificationResponse{
+		Status:  models.StatusPending,
+		Message: fmt.Sprintf("coalesced with %d repeats; summarized at %s", b.suppressed, followUpAt.Format(time.RFC3339)),
+	}, true
+}
+
+// claim takes the lock of a key for the cool-down, reporting false when
+// another instance holds it. When the locker fails the notification is sent
+// rather than risk losing it.
+func (c *Coalescer) claim(ctx context.Context, locker interfaces.Locker, key, owner string, coolDown time.Duration) bool {
+	acquired, err := locker.Acquire(ctx, lockKeyPrefix+key, owner, coolDown)
+	if err != nil {
+		c.logger.Warnf("Failed to claim coalescing key %s, sending: %v", key, err)
+		return true
+	}
+	return acquired
+}
+
+// start opens a burst for a key that ends after the cool-down. Callers must
+// hold the lock.
+func (c *Coalescer) start(key string, coolDown time.Duration) *burst {
+	b := &burst{startedAt: c.now(), coolDown: coolDown}
+	b.timer = time.AfterFunc(coolDown, func() { c.flush(key, b) })
+	c.bursts[key] = b
+	return b
+}
+
+// flush ends a burst. When repeats were held back, a follow-up reporting
+// them is sent and a new burst opens, so a storm that goes on is reported
+// once per cool-down. It reports whether a follow-up was sent.
+func (c *Coalescer) flush(key string, b *burst) bool {
+	c.mu.Lock()
+	if c.bursts[key] != b {
+		c.mu.Unlock()
+		return false
+	}
+	b.timer.Stop()
+	delete(c.bursts, key)
+	if b.suppressed == 0 || c.stopped {
+		c.mu.Unlock()
+		return false
+	}
+	c.start(key, b.coolDown)
+	sender := c.sender
+	c.mu.Unlock()
+
+	followUp := summarize(b)
+	if _, err := sender(context.WithValue(context.Background(), followUpKey{}, true), followUp); err != nil {
+		c.logger.Errorf("Coalesced %s follow-up to %s failed: %v", followUp.Type, followUp.Recipient, err)
+		return false
+	}
+	c.logger.Infof("Sent %s follow-up for %d coalesced notifications (%s)", followUp.Type, b.suppressed, followUp.Metadata[MetadataDedupKey])
+	return true
+}
+
+// forget drops the burst of a first notification that failed to send, so
+// the next one is sent rather than held back, here or on another instance
+func (c *Coalescer) forget(key string) {
+	c.mu.Lock()
+	b, exists := c.bursts[key]
+	if !exists || b.suppressed > 0 {
+		c.mu.Unlock()
+		return
+	}
+	b.timer.Stop()
+	delete(c.bursts, key)
+	locker, owner := c.locker, c.owner
+	c.mu.Unlock()
+
+	if locker != nil {
+		if err := locker.Release(context.Background(), lockKeyPrefix+key, owner); err != nil {
+			c.logger.Warnf("Failed to release coalescing key %s: %v", key, err)
+		}
+	}
+}
+
+// summarize builds the follow-up of a burst from its latest notification
+func summarize(b *burst) *models.NotificationRequest {
+	followUp := *b.latest
+	summary := fmt.Sprintf("%d more occurrences since %s", b.suppressed, b.startedAt.UTC().Format("15:04 MST"))
+	if b.suppressed == 1 {
+		summary = fmt.Sprintf("1 more occurrence since %s", b.startedAt.UTC().Format("15:04 MST"))
+	}
+
+	if followUp.Subject != "" {
+		followUp.Subject = fmt.Sprintf("%s (%s)", followUp.Subject, summary)
+	}
+	followUp.Body = fmt.Sprintf("%s. Latest: %s", summary, followUp.Body)
+
+	followUp.Metadata = make(map[string]string, len(b.latest.Metadata)+1)
+	for key, value := range b.latest.Metadata {
+		followUp.Metadata[key] = value
+	}
+	followUp.Metadata[MetadataCoalescedCount] = strconv.Itoa(b.suppressed)
+	return &followUp
+}
@@ -0,0 +1,209 @@
+package coalesce
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/lock"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pipeline"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+)
+
+func TestNewCoalescer_Validation(t *testing.T) {
+	_, err := NewCoalescer(config.CoalesceConfig{CoolDowns: map[string]time.Duration{"newsletter": time.Minute}}, utils.NewSimpleLogger("info"))
+	assert.Error(t, err)
+	_, err = NewCoalescer(config.CoalesceConfig{CoolDowns: map[string]time.Duration{"marketing": -time.Minute}}, utils.NewSimpleLogger("info"))
+	assert.Error(t, err)
+}
+
+func TestCoalescer_SummarizesBurst(t *testing.T) {
+	coalescer, sent := createTestCoalescer(t, map[string]time.Duration{"transactional": 50 * time.Millisecond})
+	defer coalescer.Stop()
+	send := coalescer.Middleware()(sent.handler)
+
+	response, err := send(context.Background(), createTestRequest("disk-full", "db-1 disk at 91%"))
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusSent, response.Status)
+
+	for _, body := range []string{"db-1 disk at 95%", "db-1 disk at 99%"} {
+		response, err = send(context.Background(), createTestRequest("disk-full", body))
+		require.NoError(t, err)
+		assert.Equal(t, models.StatusPending, response.Status)
+	}
+	assert.Equal(t, 1, coalescer.Pending())
+	assert.Len(t, sent.requests(), 1)
+
+	// Other keys are not held back
+	_, err = send(context.Background(), createTestRequest("cpu-high", "db-1 CPU at 100%"))
+	require.NoError(t, err)
+	assert.Len(t, sent.requests(), 2)
+
+	require.Eventually(t, func() bool { return len(sent.requests()) == 3 }, 2*time.Second, 5*time.Millisecond)
+	followUp := sent.requests()[2]
+	assert.Equal(t, "2", followUp.Metadata[MetadataCoalescedCount])
+	assert.True(t, strings.HasPrefix(followUp.Body, "2 more occurrences since "))
+	assert.True(t, strings.HasSuffix(followUp.Body, "Latest: db-1 disk at 99%"))
+	assert.Contains(t, followUp.Subject, "Disk alert (2 more occurrences")
+
+	// The follow-up opens a new cool-down, so a storm that goes on is held back again
+	response, err = send(context.Background(), createTestRequest("disk-full", "db-1 disk at 100%"))
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusPending, response.Status)
+}
+
+func TestCoalescer_CallerCannotMarkFollowUps(t *testing.T) {
+	coalescer, sent := createTestCoalescer(t, map[string]time.Duration{"transactional": time.Hour})
+	defer coalescer.Stop()
+	send := coalescer.Middleware()(sent.handler)
+
+	for _, body := range []string{"db-1 disk at 91%", "db-1 disk at 95%"} {
+		request := createTestRequest("disk-full", body)
+		request.Metadata[MetadataCoalescedCount] = "1"
+		_, err := send(context.Background(), request)
+		require.NoError(t, err)
+	}
+	assert.Len(t, sent.requests(), 1)
+	assert.Equal(t, 1, coalescer.Pending())
+}
+
+func TestCoalescer_Flush(t *testing.T) {
+	coalescer, sent := createTestCoalescer(t, map[string]time.Duration{"transactional": time.Hour})
+	defer coalescer.Stop()
+	send := coalescer.Middleware()(sent.handler)
+
+	for _, body := range []string{"db-1 disk at 91%", "db-1 disk at 95%", "db-1 disk at 99%"} {
+		_, err := send(context.Background(), createTestRequest("disk-full", body))
+		require.NoError(t, err)
+	}
+	_, err := send(context.Background(), createTestRequest("cpu-high", "db-1 CPU at 100%"))
+	require.NoError(t, err)
+
+	// Only bursts with repeats held back are summarized, well before their cool-down ends
+	assert.Equal(t, 1, coalescer.Flush())
+	require.Len(t, sent.requests(), 3)
+	assert.Equal(t, "2", sent.requests()[2].Metadata[MetadataCoalescedCount])
+	assert.Equal(t, 0, coalescer.Pending())
+	assert.Equal(t, 0, coalescer.Flush())
+
+	// The digest opens a new cool-down
+	response, err := send(context.Background(), createTestRequest("disk-full", "db-1 disk at 100%"))
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusPending, response.Status)
+}
+
+func TestCoalescer_PassesThrough(t *testing.T) {
+	coalescer, sent := createTestCoalescer(t, map[string]time.Duration{"transactional": time.Hour})
+	defer coalescer.Stop()
+	send := coalescer.Middleware()(sent.handler)
+
+	// Requests without a dedup key
+	for i := 0; i < 2; i++ {
+		_, err := send(context.Background(), createTestRequest("", "no key"))
+		require.NoError(t, err)
+	}
+
+	// Categories without a cool-down
+	for i := 0; i < 2; i++ {
+		request := createTestRequest("login", "Your code is 123456")
+		request.Category = models.CategorySecurity
+		_, err := send(context.Background(), request)
+		require.NoError(t, err)
+	}
+
+	// A first notification that fails to send is not a burst
+	sent.fail = true
+	_, err := send(context.Background(), createTestRequest("disk-full", "db-1 disk at 91%"))
+	require.Error(t, err)
+	sent.fail = false
+	response, err := send(context.Background(), createTestRequest("disk-full", "db-1 disk at 91%"))
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusSent, response.Status)
+
+	assert.Len(t, sent.requests(), 5)
+	assert.Equal(t, 0, coalescer.Pending())
+}
+
+func TestCoalescer_SharedLocker(t *testing.T) {
+	locker := lock.NewMemoryLocker()
+	coolDowns := map[string]time.Duration{"transactional": 50 * time.Millisecond}
+	first, firstSent := createTestCoalescer(t, coolDowns)
+	defer first.Stop()
+	first.SetLocker(locker, "instance-1")
+	second, secondSent := createTestCoalescer(t, coolDowns)
+	defer second.Stop()
+	second.SetLocker(locker, "instance-2")
+
+	// The storm reaches both replicas; only the first sends its first alert
+	response, err := first.Middleware()(firstSent.handler)(context.Background(), createTestRequest("disk-full", "db-1 disk at 91%"))
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusSent, response.Status)
+	response, err = second.Middleware()(secondSent.handler)(context.Background(), createTestRequest("disk-full", "db-1 disk at 95%"))
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusPending, response.Status)
+	assert.Empty(t, secondSent.requests())
+
+	// The second reports what it held back
+	require.Eventually(t, func() bool { return len(secondSent.requests()) == 1 }, 2*time.Second, 5*time.Millisecond)
+	assert.Equal(t, "1", secondSent.requests()[0].Metadata[MetadataCoalescedCount])
+	assert.Len(t, firstSent.requests(), 1)
+}
+
+// Helper functions
+
+// testSender records the requests it sends
+type testSender struct {
+	mu   sync.Mutex
+	sent []*models.NotificationRequest
+	fail bool
+}
+
+func (s *testSender) handler(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+	if s.fail {
+		return nil, assert.AnError
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sent = append(s.sent, request)
+	return &models.NotificationResponse{ID: uuid.New(), Status: models.StatusSent}, nil
+}
+
+func (s *testSender) requests() []*models.NotificationRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*models.NotificationRequest(nil), s.sent...)
+}
+
+func createTestCoalescer(t *testing.T, coolDowns map[string]time.Duration) (*Coalescer, *testSender) {
+	coalescer, err := NewCoalescer(config.CoalesceConfig{CoolDowns: coolDowns}, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+
+	sent := &testSender{}
+	// Follow-ups go through the middleware again, as they would through the dispatcher
+	coalescer.SetSender(coalescer.Middleware()(pipeline.Handler(sent.handler)))
+	return coalescer, sent
+}
+
+func createTestRequest(dedupKey, body string) *models.NotificationRequest {
+	request := &models.NotificationRequest{
+		Type:      models.NotificationTypeSMS,
+		Priority:  models.PriorityHigh,
+		Recipient: "+14155550100",
+		Subject:   "Disk alert",
+		Body:      body,
+	}
+	if dedupKey != "" {
+		request.Metadata = map[string]string{MetadataDedupKey: dedupKey}
+	}
+	return request
+}
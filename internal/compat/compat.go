@@ -0,0 +1,242 @@
+// Package compat reports how email templates render across email clients
+// such as Gmail, Outlook and Apple Mail. Reports come from a Tester,
+// typically a rendering-test service like Litmus or Email on Acid, and are
+// stored with the version of the template they cover, so a change that
+// breaks a client shows against the version that introduced it.
+package compat
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/snapshot"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// Client is an email client, e.g. "gmail" or "outlook"
+type Client string
+
+// Major email clients, tested when a request names none
+const (
+	ClientGmail     Client = "gmail"
+	ClientOutlook   Client = "outlook"
+	ClientAppleMail Client = "apple-mail"
+)
+
+// DefaultClients are the clients a template is tested in by default
+var DefaultClients = []Client{ClientGmail, ClientOutlook, ClientAppleMail}
+
+// Status is how well a template renders in a client
+type Status string
+
+// Statuses, from best to worst
+const (
+	StatusPass    Status = "pass"    // renders as intended
+	StatusWarning Status = "warning" // renders with minor differences
+	StatusFail    Status = "fail"    // renders broken or unreadable
+)
+
+// Limits of a test
+const (
+	maxClients = 50
+	maxReports = 50 // kept per template; older ones are dropped
+)
+
+// Tester renders a message in email clients. Implement it with a
+// rendering-test service.
+type Tester interface {
+	// Test renders a message in each client and reports the results
+	Test(ctx context.Context, message Message, clients []Client) ([]ClientResult, error)
+}
+
+// Message is a rendered template as sent to the tester
+type Message struct {
+	Subject  string `json:"subject"`
+	HTMLBody string `json:"html_body"`
+	TextBody string `json:"text_body,omitempty"`
+}
+
+// ClientResult is how a template renders in one client
+type ClientResult struct {
+	Client        Client   `json:"client"`
+	Status        Status   `json:"status"`
+	Issues        []string `json:"issues,omitempty"` // e.g. "background images are not supported"
+	ScreenshotURL string   `json:"screenshot_url,omitempty"`
+}
+
+// Report is the result of testing a template version in email clients
+type Report struct {
+	TemplateID   string            `json:"template_id"`
+	Version      string            `json:"version"` // see snapshot.Version
+	Status       Status            `json:"status"`  // the worst of the clients' statuses
+	Clients      []ClientResult    `json:"clients"`
+	TemplateData map[string]string `json:"template_data,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
+}
+
+// TestRequest is the body of a compatibility test request
+type TestRequest struct {
+	TemplateData map[string]string `json:"template_data,omitempty"`
+	Clients      []Client          `json:"clients,omitempty"` // DefaultClients when empty
+}
+
+// Service tests templates and keeps their reports. It is safe for
+// concurrent use.
+type Service struct {
+	templates snapshot.Templates
+	tester    Tester
+	logger    interfaces.Logger
+
+	mu      sync.RWMutex
+	reports map[string][]*Report // by template ID, oldest first
+	now     func() time.Time
+}
+
+// NewService creates a compatibility service
+func NewService(templates snapshot.Templates, tester Tester, logger interfaces.Logger) *Service {
+	return &Service{
+		templates: templates,
+		tester:    tester,
+		logger:    logger,
+		reports:   make(map[string][]*Report),
+		now:       time.Now,
+	}
+}
+
+// Test renders the current version of a template with the data, tests it in
+// the requested clients and stores the report, replacing an earlier report
+// of the same version
+func (s *Service) Test(ctx context.Context, templateID string, request TestRequest) (*Report, error) {
+	clients, err := normalizeClients(request.Clients)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := s.templates.GetTemplate(templateID)
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := s.templates.RenderTemplate(templateID, request.TemplateData)
+	if err != nil {
+		return nil, err
+	}
+	if rendered.HTMLBody == "" {
+		return nil, errors.NewValidationError("template", fmt.Sprintf("template %s has no HTML body", templateID))
+	}
+
+	results, err := s.tester.Test(ctx, Message{Subject: rendered.Subject, HTMLBody: rendered.HTMLBody, TextBody: rendered.TextBody}, clients)
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Sprintf("failed to test template %s in email clients", templateID), err)
+	}
+
+	report := &Report{
+		TemplateID:   templateID,
+		Version:      snapshot.Version(template),
+		Status:       StatusPass,
+		Clients:      results,
+		TemplateData: request.TemplateData,
+		CreatedAt:    s.now(),
+	}
+	for _, result := range results {
+		if severity(result.Status) > severity(report.Status) {
+			report.Status = statuses[severity(result.Status)]
+		}
+	}
+
+	s.mu.Lock()
+	kept := s.reports[templateID][:0:0]
+	for _, existing := range s.reports[templateID] {
+		if existing.Version != report.Version {
+			kept = append(kept, existing)
+		}
+	}
+	kept = append(kept, report)
+	if len(kept) > maxReports {
+		kept = kept[len(kept)-maxReports:]
+	}
+	s.reports[templateID] = kept
+	s.mu.Unlock()
+
+	s.logger.Infof("Tested template %s version %s in %d email clients: %s", templateID, report.Version, len(results), report.Status)
+	return report, nil
+}
+
+// List returns the reports of a template, newest first
+func (s *Service) List(templateID string) []*Report {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	reports := append([]*Report(nil), s.reports[templateID]...)
+	sort.SliceStable(reports, func(i, j int) bool { return reports[i].CreatedAt.After(reports[j].CreatedAt) })
+	return reports
+}
+
+// Get returns the report of a template version
+func (s *Service) Get(templateID, version string) (*Report, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, report := range s.reports[templateID] {
+		if report.Version == version {
+			return report, nil
+		}
+	}
+	return nil, errors.NewNotificationError(errors.ErrorCodeNotFound,
+		fmt.Sprintf("no compatibility report of template %s version %s", templateID, version))
+}
+
+// Current returns the report of a template's current version. A template
+// edited since its last test has none.
+func (s *Service) Current(templateID string) (*Report, error) {
+	template, err := s.templates.GetTemplate(templateID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(templateID, snapshot.Version(template))
+}
+
+// normalizeClients lowercases and deduplicates the requested clients, or
+// returns DefaultClients when none are requested
+func normalizeClients(requested []Client) ([]Client, error) {
+	if len(requested) == 0 {
+		return DefaultClients, nil
+	}
+	if len(requested) > maxClients {
+		return nil, errors.NewValidationError("clients", fmt.Sprintf("at most %d clients can be tested at once", maxClients))
+	}
+
+	clients := make([]Client, 0, len(requested))
+	seen := make(map[Client]bool, len(requested))
+	for _, client := range requested {
+		client = Client(strings.ToLower(strings.TrimSpace(string(client))))
+		if client == "" {
+			return nil, errors.NewValidationError("clients", "client names must not be empty")
+		}
+		if !seen[client] {
+			seen[client] = true
+			clients = append(clients, client)
+		}
+	}
+	return clients, nil
+}
+
+// statuses are the statuses by severity
+var statuses = []Status{StatusPass, StatusWarning, StatusFail}
+
+// severity orders statuses from best to worst. Statuses a tester invents
+// rank as warnings.
+func severity(status Status) int {
+	switch status {
+	case StatusPass:
+		return 0
+	case StatusFail:
+		return 2
+	default:
+		return 1
+	}
+}
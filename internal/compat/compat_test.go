@@ -0,0 +1,142 @@
+package compat
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/internal/snapshot"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestService_Test(t *testing.T) {
+	service, email, tester := createTestService(t)
+	ctx := context.Background()
+
+	first, err := service.Test(ctx, "promo", TestRequest{TemplateData: map[string]string{"name": "Jane"}})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultClients, tester.lastClients)
+	assert.Contains(t, tester.lastMessage.HTMLBody, "Hello Jane")
+	assert.Equal(t, StatusPass, first.Status)
+	require.Len(t, first.Clients, 3)
+
+	template, err := email.GetTemplate("promo")
+	require.NoError(t, err)
+	assert.Equal(t, snapshot.Version(template), first.Version)
+
+	// Testing the same version again replaces its report
+	_, err = service.Test(ctx, "promo", TestRequest{Clients: []Client{" Outlook ", "outlook", "gmail"}})
+	require.NoError(t, err)
+	assert.Equal(t, []Client{ClientOutlook, ClientGmail}, tester.lastClients)
+	require.Len(t, service.List("promo"), 1)
+
+	require.NoError(t, email.AddTemplate(&providers.EmailTemplate{
+		ID: "promo", Name: "Promo", Subject: "Hi", HTMLBody: `<div style="background-image:url(x.png)">Hello {{name}}</div>`,
+	}))
+	second, err := service.Test(ctx, "promo", TestRequest{})
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Version, second.Version)
+	assert.Equal(t, StatusFail, second.Status)
+
+	listed := service.List("promo")
+	require.Len(t, listed, 2)
+	assert.Equal(t, second.Version, listed[0].Version)
+
+	current, err := service.Current("promo")
+	require.NoError(t, err)
+	assert.Equal(t, second.Version, current.Version)
+	old, err := service.Get("promo", first.Version)
+	require.NoError(t, err)
+	assert.Equal(t, StatusPass, old.Status)
+}
+
+func TestService_TestErrors(t *testing.T) {
+	service, _, tester := createTestService(t)
+	ctx := context.Background()
+
+	_, err := service.Test(ctx, "promo", TestRequest{Clients: []Client{"gmail", " "}})
+	assertCode(t, err, errors.ErrorCodeValidationFailed)
+
+	_, err = service.Test(ctx, "missing", TestRequest{})
+	assertCode(t, err, errors.ErrorCodeTemplateNotFound)
+
+	tester.fail = true
+	_, err = service.Test(ctx, "promo", TestRequest{})
+	assertCode(t, err, errors.ErrorCodeInternal)
+	assert.Empty(t, service.List("promo"))
+
+	_, err = service.Current("promo")
+	assertCode(t, err, errors.ErrorCodeNotFound)
+}
+
+func TestSeverity(t *testing.T) {
+	service, _, tester := createTestService(t)
+	tester.status = "partial"
+
+	report, err := service.Test(context.Background(), "promo", TestRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, StatusWarning, report.Status)
+	assert.Equal(t, Status("partial"), report.Clients[0].Status)
+}
+
+// Helper functions
+
+// testTester fails Outlook on background images, like the real one, and
+// passes everything else
+type testTester struct {
+	lastMessage Message
+	lastClients []Client
+	status      Status // reported for passing clients; pass when empty
+	fail        bool
+}
+
+func (t *testTester) Test(_ context.Context, message Message, clients []Client) ([]ClientResult, error) {
+	if t.fail {
+		return nil, errors.NewNotificationError(errors.ErrorCodeProviderUnavailable, "rendering service is down")
+	}
+	t.lastMessage, t.lastClients = message, clients
+
+	results := make([]ClientResult, 0, len(clients))
+	for _, client := range clients {
+		result := ClientResult{Client: client, Status: StatusPass}
+		if t.status != "" {
+			result.Status = t.status
+		}
+		if client == ClientOutlook && strings.Contains(message.HTMLBody, "background-image") {
+			result.Status = StatusFail
+			result.Issues = []string{"background images are not supported"}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func assertCode(t *testing.T, err error, code errors.ErrorCode) {
+	t.Helper()
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok, "expected a notification error, got %v", err)
+	assert.Equal(t, code, notifErr.Code)
+}
+
+func createTestService(t *testing.T) (*Service, *providers.MockEmailProvider, *testTester) {
+	email := providers.NewMockEmailProvider(config.EmailProviderConfig{Provider: "mock"})
+	require.NoError(t, email.AddTemplate(&providers.EmailTemplate{
+		ID: "promo", Name: "Promo", Subject: "Hi", HTMLBody: "<p>Hello {{name}}</p>",
+	}))
+
+	tester := &testTester{}
+	service := NewService(email, tester, utils.NewSimpleLogger("error"))
+	now := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	service.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	return service, email, tester
+}
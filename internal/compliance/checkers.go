@@ -0,0 +1,198 @@
+package compliance
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+)
+
+// Registry is a do-not-disturb registry of one country, such as India's
+// NCPR or the US National Do Not Call Registry. It blocks sends in its
+// categories, marketing by default, to registered numbers of its country.
+type Registry struct {
+	name       string
+	country    string
+	categories map[models.Category]bool
+
+	mu      sync.RWMutex
+	numbers map[string]time.Time // number key to registration time
+}
+
+// NewRegistry creates an empty registry for a country. Without categories,
+// it blocks marketing sends.
+func NewRegistry(name, country string, categories ...models.Category) *Registry {
+	if len(categories) == 0 {
+		categories = []models.Category{models.CategoryMarketing}
+	}
+
+	registry := &Registry{
+		name:       name,
+		country:    strings.ToUpper(country),
+		categories: make(map[models.Category]bool, len(categories)),
+		numbers:    make(map[string]time.Time),
+	}
+	for _, category := range categories {
+		registry.categories[category] = true
+	}
+	return registry
+}
+
+// Name implements the Checker interface
+func (r *Registry) Name() string {
+	return r.name
+}
+
+// Register adds numbers to the registry
+func (r *Registry) Register(phoneNumbers ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, phoneNumber := range phoneNumbers {
+		r.numbers[numberKey(phoneNumber, r.country)] = now
+	}
+}
+
+// Remove removes numbers from the registry
+func (r *Registry) Remove(phoneNumbers ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, phoneNumber := range phoneNumbers {
+		delete(r.numbers, numberKey(phoneNumber, r.country))
+	}
+}
+
+// IsRegistered reports whether a number is in the registry
+func (r *Registry) IsRegistered(phoneNumber string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, registered := r.numbers[numberKey(phoneNumber, r.country)]
+	return registered
+}
+
+// Len returns the number of registered numbers
+func (r *Registry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.numbers)
+}
+
+// Check implements the Checker interface
+func (r *Registry) Check(ctx context.Context, subject Subject) (Decision, error) {
+	if subject.Country != r.country || !r.categories[subject.Category] {
+		return Decision{Allowed: true}, nil
+	}
+
+	if r.IsRegistered(subject.PhoneNumber) {
+		return Decision{Reason: fmt.Sprintf("number is registered in %s", r.name)}, nil
+	}
+	return Decision{Allowed: true}, nil
+}
+
+// Consent is a recipient's recorded consent to marketing SMS
+type Consent struct {
+	PhoneNumber string    `json:"phone_number"`
+	Source      string    `json:"source"` // where it was given, e.g. "signup-form"
+	GrantedAt   time.Time `json:"granted_at"`
+}
+
+// ConsentRecords holds the numbers that consented to marketing SMS, as the
+// TCPA requires prior express written consent. It blocks marketing sends to
+// numbers without consent, in the countries it covers or everywhere.
+type ConsentRecords struct {
+	countries map[string]bool
+
+	mu       sync.RWMutex
+	consents map[string]Consent // number key to consent
+}
+
+// NewConsentRecords creates empty consent records covering countries, or
+// every country when none are given
+func NewConsentRecords(countries ...string) *ConsentRecords {
+	records := &ConsentRecords{
+		countries: make(map[string]bool, len(countries)),
+		consents:  make(map[string]Consent),
+	}
+	for _, country := range countries {
+		records.countries[strings.ToUpper(country)] = true
+	}
+	return records
+}
+
+// Name implements the Checker interface
+func (c *ConsentRecords) Name() string {
+	return "consent"
+}
+
+// Grant records a number's consent
+func (c *ConsentRecords) Grant(phoneNumber, source string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.consents[consentKey(phoneNumber)] = Consent{PhoneNumber: phoneNumber, Source: source, GrantedAt: time.Now()}
+}
+
+// Revoke removes a number's consent
+func (c *ConsentRecords) Revoke(phoneNumber string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.consents, consentKey(phoneNumber))
+}
+
+// Consent returns a number's consent record
+func (c *ConsentRecords) Consent(phoneNumber string) (Consent, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	consent, exists := c.consents[consentKey(phoneNumber)]
+	return consent, exists
+}
+
+// Check implements the Checker interface
+func (c *ConsentRecords) Check(ctx context.Context, subject Subject) (Decision, error) {
+	if subject.Category != models.CategoryMarketing {
+		return Decision{Allowed: true}, nil
+	}
+	if len(c.countries) > 0 && !c.countries[subject.Country] {
+		return Decision{Allowed: true}, nil
+	}
+
+	if _, exists := c.Consent(subject.PhoneNumber); !exists {
+		return Decision{Reason: "no marketing consent on record"}, nil
+	}
+	return Decision{Allowed: true}, nil
+}
+
+// consentKey normalizes a number for consent lookups. Consent is given for
+// a number rather than a send, so the country is inferred from the number;
+// numbers in international format match however they are written.
+func consentKey(phoneNumber string) string {
+	country := utils.CountryFromPhoneNumber(phoneNumber)
+	return country + ":" + numberKey(phoneNumber, country)
+}
+
+// CheckerFunc adapts a function to the Checker interface, for example to
+// consult an external registry API
+type CheckerFunc struct {
+	CheckerName string
+	Func        func(ctx context.Context, subject Subject) (Decision, error)
+}
+
+// Name implements the Checker interface
+func (f CheckerFunc) Name() string {
+	return f.CheckerName
+}
+
+// Check implements the Checker interface
+func (f CheckerFunc) Check(ctx context.Context, subject Subject) (Decision, error) {
+	return f.Func(ctx, subject)
+}
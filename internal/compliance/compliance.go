@@ -0,0 +1,175 @@
+// Package compliance checks SMS sends against do-not-disturb registries and
+// consent records before they reach a provider, for rules such as India's
+// TRAI DND (NCPR) or the US TCPA. Checkers are pluggable; the guard runs
+// them in order and records its decision on the notification.
+package compliance
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pipeline"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// MiddlewareName is the name the guard's middleware is registered under
+const MiddlewareName = "compliance"
+
+// Metadata keys of the decision record attached to checked notifications
+const (
+	MetadataPrefix    = "compliance_"               // every decision record key starts with it
+	MetadataDecision  = MetadataPrefix + "decision" // "allowed" or "blocked"
+	MetadataCheckedBy = MetadataPrefix + "checked_by"
+	MetadataReason    = MetadataPrefix + "reason"
+	MetadataCheckedAt = MetadataPrefix + "checked_at"
+)
+
+// Subject is the send a checker decides on
+type Subject struct {
+	PhoneNumber string
+	Country     string // given or inferred from the number; empty when unknown
+	Category    models.Category
+}
+
+// Decision is a checker's verdict on a send
+type Decision struct {
+	Allowed   bool      `json:"allowed"`
+	CheckedBy string    `json:"checked_by"`
+	Reason    string    `json:"reason,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Checker decides whether an SMS may be sent. A checker that has no opinion,
+// for example a registry of another country, allows the send. An error means
+// the checker could not decide; the guard then blocks the send.
+type Checker interface {
+	Name() string
+	Check(ctx context.Context, subject Subject) (Decision, error)
+}
+
+// Guard runs checkers before SMS sends. Every checker must allow a send; the
+// first one blocking it decides. It is safe for concurrent use.
+type Guard struct {
+	mu       sync.RWMutex
+	checkers []Checker
+	logger   interfaces.Logger
+	now      func() time.Time
+}
+
+// NewGuard creates a guard running checkers in order
+func NewGuard(logger interfaces.Logger, checkers ...Checker) *Guard {
+	return &Guard{
+		checkers: checkers,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// AddChecker appends a checker
+func (g *Guard) AddChecker(checker Checker) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.checkers = append(g.checkers, checker)
+}
+
+// Check runs the checkers on a send and returns the decision
+func (g *Guard) Check(ctx context.Context, subject Subject) (Decision, error) {
+	g.mu.RLock()
+	checkers := append([]Checker(nil), g.checkers...)
+	g.mu.RUnlock()
+
+	if subject.Category == "" {
+		subject.Category = models.CategoryTransactional
+	}
+
+	names := make([]string, 0, len(checkers))
+	for _, checker := range checkers {
+		decision, err := checker.Check(ctx, subject)
+		if err != nil {
+			return Decision{}, errors.NewNotificationError(errors.ErrorCodeProviderUnavailable,
+				fmt.Sprintf("compliance check %s failed", checker.Name())).WithCause(err)
+		}
+		if !decision.Allowed {
+			decision.CheckedBy = checker.Name()
+			if decision.CheckedAt.IsZero() {
+				decision.CheckedAt = g.now()
+			}
+			return decision, nil
+		}
+		names = append(names, checker.Name())
+	}
+
+	return Decision{Allowed: true, CheckedBy: strings.Join(names, ","), CheckedAt: g.now()}, nil
+}
+
+// Middleware returns the send middleware checking SMS requests. Blocked
+// sends fail with RECIPIENT_OPTED_OUT; allowed ones carry the decision in
+// their metadata. Register it at pipeline.StagePreferences under MiddlewareName.
+func (g *Guard) Middleware() pipeline.Middleware {
+	return func(next pipeline.Handler) pipeline.Handler {
+		return func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+			if request.Type != models.NotificationTypeSMS {
+				return next(ctx, request)
+			}
+
+			subject := subjectOf(request)
+			decision, err := g.Check(ctx, subject)
+			if err != nil {
+				g.logger.Errorf("Compliance check failed for SMS to %s: %v", subject.Country, err)
+				return nil, err
+			}
+
+			if !decision.Allowed {
+				g.logger.Warnf("Blocked %s SMS by %s: %s", subject.Category, decision.CheckedBy, decision.Reason)
+				return nil, errors.NewNotificationError(errors.ErrorCodeRecipientOptedOut,
+					fmt.Sprintf("SMS blocked by compliance check %s: %s", decision.CheckedBy, decision.Reason)).
+					WithMetadata(MetadataDecision, "blocked").
+					WithMetadata(MetadataCheckedBy, decision.CheckedBy).
+					WithMetadata(MetadataReason, decision.Reason)
+			}
+
+			checked := *request
+			checked.Metadata = make(map[string]string, len(request.Metadata)+3)
+			for key, value := range request.Metadata {
+				checked.Metadata[key] = value
+			}
+			checked.Metadata[MetadataDecision] = "allowed"
+			checked.Metadata[MetadataCheckedBy] = decision.CheckedBy
+			checked.Metadata[MetadataCheckedAt] = decision.CheckedAt.UTC().Format(time.RFC3339)
+			return next(ctx, &checked)
+		}
+	}
+}
+
+// subjectOf returns the subject of an SMS request
+func subjectOf(request *models.NotificationRequest) Subject {
+	phoneNumber, countryCode := request.Recipient, ""
+	if request.SMSData != nil {
+		if request.SMSData.PhoneNumber != "" {
+			phoneNumber = request.SMSData.PhoneNumber
+		}
+		countryCode = request.SMSData.CountryCode
+	}
+	if countryCode == "" && request.Metadata != nil {
+		countryCode = request.Metadata["country_code"]
+	}
+
+	return Subject{
+		PhoneNumber: phoneNumber,
+		Country:     utils.ResolveCountry(phoneNumber, countryCode),
+		Category:    request.Category,
+	}
+}
+
+// numberKey normalizes a phone number for lookups: its national digits
+// without a trunk prefix, so "+44 7911 123456" and "07911 123456" match
+func numberKey(phoneNumber, country string) string {
+	return strings.TrimLeft(utils.NationalNumber(phoneNumber, country), "0")
+}
@@ -0,0 +1,158 @@
+package compliance
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestRegistry_BlocksMarketingToRegisteredNumbers(t *testing.T) {
+	registry := NewRegistry("TRAI NCPR", "IN")
+	registry.Register("98765 43210")
+	ctx := context.Background()
+
+	// Registered numbers match in national or international format
+	assert.True(t, registry.IsRegistered("+91 98765 43210"))
+
+	decision, err := registry.Check(ctx, Subject{PhoneNumber: "+919876543210", Country: "IN", Category: models.CategoryMarketing})
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Contains(t, decision.Reason, "TRAI NCPR")
+
+	// Transactional sends and other countries are not covered
+	decision, _ = registry.Check(ctx, Subject{PhoneNumber: "+919876543210", Country: "IN", Category: models.CategoryTransactional})
+	assert.True(t, decision.Allowed)
+	decision, _ = registry.Check(ctx, Subject{PhoneNumber: "9876543210", Country: "US", Category: models.CategoryMarketing})
+	assert.True(t, decision.Allowed)
+
+	registry.Remove("+919876543210")
+	assert.Equal(t, 0, registry.Len())
+}
+
+func TestConsentRecords(t *testing.T) {
+	records := NewConsentRecords("US")
+	records.Grant("+1 415 555 0123", "signup-form")
+	ctx := context.Background()
+
+	consent, exists := records.Consent("+14155550123")
+	require.True(t, exists)
+	assert.Equal(t, "signup-form", consent.Source)
+
+	decision, _ := records.Check(ctx, Subject{PhoneNumber: "+14155550123", Country: "US", Category: models.CategoryMarketing})
+	assert.True(t, decision.Allowed)
+
+	decision, _ = records.Check(ctx, Subject{PhoneNumber: "+14155550199", Country: "US", Category: models.CategoryMarketing})
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, "no marketing consent on record", decision.Reason)
+
+	// Only marketing sends in covered countries need consent
+	decision, _ = records.Check(ctx, Subject{PhoneNumber: "+14155550199", Country: "US", Category: models.CategorySecurity})
+	assert.True(t, decision.Allowed)
+	decision, _ = records.Check(ctx, Subject{PhoneNumber: "+447911123456", Country: "UK", Category: models.CategoryMarketing})
+	assert.True(t, decision.Allowed)
+
+	records.Revoke("+1 (415) 555-0123")
+	_, exists = records.Consent("+14155550123")
+	assert.False(t, exists)
+}
+
+func TestGuard_Check(t *testing.T) {
+	registry := NewRegistry("dnc", "US")
+	registry.Register("+14155550123")
+	guard := createTestGuard(NewConsentRecords(), registry)
+	ctx := context.Background()
+
+	// The first blocking checker decides
+	decision, err := guard.Check(ctx, Subject{PhoneNumber: "+14155550123", Country: "US", Category: models.CategoryMarketing})
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, "consent", decision.CheckedBy)
+	assert.False(t, decision.CheckedAt.IsZero())
+
+	// Sends without a category are transactional
+	decision, err = guard.Check(ctx, Subject{PhoneNumber: "+14155550123", Country: "US"})
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, "consent,dnc", decision.CheckedBy)
+
+	// A checker that cannot decide blocks the send
+	guard.AddChecker(CheckerFunc{CheckerName: "remote", Func: func(ctx context.Context, subject Subject) (Decision, error) {
+		return Decision{}, fmt.Errorf("registry unreachable")
+	}})
+	_, err = guard.Check(ctx, Subject{PhoneNumber: "+14155550123", Country: "US"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "remote")
+}
+
+func TestGuard_Middleware(t *testing.T) {
+	registry := NewRegistry("TRAI NCPR", "IN")
+	registry.Register("+919876543210")
+	guard := createTestGuard(registry)
+
+	var sent *models.NotificationRequest
+	handler := guard.Middleware()(func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+		sent = request
+		return &models.NotificationResponse{Status: models.StatusSent}, nil
+	})
+	ctx := context.Background()
+
+	request := &models.NotificationRequest{
+		Type:      models.NotificationTypeSMS,
+		Recipient: "+919876543210",
+		Body:      "50% off today",
+		Category:  models.CategoryMarketing,
+		Metadata:  map[string]string{"campaign": "sale"},
+	}
+	_, err := handler(ctx, request)
+	require.Error(t, err)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeRecipientOptedOut, notifErr.Code)
+	assert.Equal(t, "blocked", notifErr.Metadata[MetadataDecision])
+	assert.Equal(t, "TRAI NCPR", notifErr.Metadata[MetadataCheckedBy])
+	assert.Nil(t, sent)
+
+	// Allowed sends carry the decision record, without changing the caller's metadata
+	request.Category = models.CategoryTransactional
+	_, err = handler(ctx, request)
+	require.NoError(t, err)
+	assert.Equal(t, "allowed", sent.Metadata[MetadataDecision])
+	assert.Equal(t, "TRAI NCPR", sent.Metadata[MetadataCheckedBy])
+	assert.Equal(t, "2024-01-02T03:04:05Z", sent.Metadata[MetadataCheckedAt])
+	assert.Equal(t, "sale", sent.Metadata["campaign"])
+	assert.NotContains(t, request.Metadata, MetadataDecision)
+
+	// Other channels are not checked
+	_, err = handler(ctx, &models.NotificationRequest{Type: models.NotificationTypeEmail, Recipient: "a@example.com", Category: models.CategoryMarketing})
+	require.NoError(t, err)
+	assert.NotContains(t, sent.Metadata, MetadataDecision)
+}
+
+func TestSubjectOf(t *testing.T) {
+	subject := subjectOf(&models.NotificationRequest{
+		Type:      models.NotificationTypeSMS,
+		Recipient: "9876543210",
+		SMSData:   &models.SMSData{CountryCode: "in"},
+		Category:  models.CategoryMarketing,
+	})
+	assert.Equal(t, Subject{PhoneNumber: "9876543210", Country: "IN", Category: models.CategoryMarketing}, subject)
+
+	subject = subjectOf(&models.NotificationRequest{Type: models.NotificationTypeSMS, Recipient: "+447911123456"})
+	assert.Equal(t, "UK", subject.Country)
+}
+
+// Helper functions
+
+func createTestGuard(checkers ...Checker) *Guard {
+	guard := NewGuard(utils.NewSimpleLogger("error"), checkers...)
+	guard.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+	return guard
+}
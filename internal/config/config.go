@@ -11,11 +11,46 @@ import (
 
 // Config represents the main configuration for the notification service
 type Config struct {
-	Server    ServerConfig    `json:"server"`
-	Database  DatabaseConfig  `json:"database"`
-	Logger    LoggerConfig    `json:"logger"`
-	Queue     QueueConfig     `json:"queue"`
-	Providers ProvidersConfig `json:"providers"`
+	Server        ServerConfig       `json:"server"`
+	Database      DatabaseConfig     `json:"database"`
+	Logger        LoggerConfig       `json:"logger"`
+	Queue         QueueConfig        `json:"queue"`
+	Providers     ProvidersConfig    `json:"providers"`
+	Privacy       PrivacyConfig      `json:"privacy"`
+	Retention     RetentionConfig    `json:"retention"`
+	Pause         PauseConfig        `json:"pause"`
+	Frequency     FrequencyConfig    `json:"frequency"`
+	Warmup        WarmupConfig       `json:"warmup"`
+	Inbound       InboundConfig      `json:"inbound"`
+	Keywords      KeywordsConfig     `json:"keywords"`
+	Conversations ConversationConfig `json:"conversations"`
+	PushGroups    PushGroupConfig    `json:"push_groups"`
+	PushMedia     PushMediaConfig    `json:"push_media"`
+	Branding      BrandingConfig     `json:"branding"`
+	Outbox        OutboxConfig       `json:"outbox"`
+	Reconcile     ReconcileConfig    `json:"reconcile"`
+	LoadShed      LoadShedConfig     `json:"load_shed"`
+	Templates     TemplateConfig     `json:"templates"`
+	Coalesce      CoalesceConfig     `json:"coalesce"`
+	Jobs          JobsConfig         `json:"jobs"`
+	Lock          LockConfig         `json:"lock"`
+	RateLimit     RateLimitConfig    `json:"rate_limit"`
+	Credentials   CredentialsConfig  `json:"credentials"`
+	RequestLog    RequestLogConfig   `json:"request_log"`
+	SpamCheck     SpamCheckConfig    `json:"spam_check"`
+	VirusScan     VirusScanConfig    `json:"virus_scan"`
+	AttachLinks   AttachLinkConfig   `json:"attachment_links"`
+	NumberLookup  NumberLookupConfig `json:"number_lookup"`
+	EmailRouting  EmailRoutingConfig `json:"email_routing"`
+	Routing       RoutingConfig      `json:"routing"`
+	Rollout       RolloutConfig      `json:"rollout"`
+	SLA           SLAConfig          `json:"sla"`
+	Chaos         ChaosConfig        `json:"chaos"`
+	SMTPSink      SMTPSinkConfig     `json:"smtp_sink"`
+	Auth          AuthConfig         `json:"auth"`
+	Webhooks      WebhookAuthConfig  `json:"webhooks"`
+	WebhookDedup  WebhookDedupConfig `json:"webhook_dedup"`
+	Limits        LimitsConfig       `json:"limits"`
 }
 
 // ServerConfig represents HTTP server configuration
@@ -29,6 +64,19 @@ type ServerConfig struct {
 	EnableTLS    bool          `json:"enable_tls"`
 	CertFile     string        `json:"cert_file,omitempty"`
 	KeyFile      string        `json:"key_file,omitempty"`
+
+	// Mutual TLS: client certificates are verified against ClientCAFile
+	ClientCAFile     string                 `json:"client_ca_file,omitempty"`
+	ClientAuth       string                 `json:"client_auth"` // "none", "optional" or "require"
+	ClientIdentities []ClientIdentityConfig `json:"client_identities,omitempty"`
+}
+
+// ClientIdentityConfig maps client certificates to a tenant and roles by
+// their subject alternative names (SANs)
+type ClientIdentityConfig struct {
+	SAN    string   `json:"san"` // a URI, DNS name or email SAN; a trailing * matches any suffix
+	Tenant string   `json:"tenant,omitempty"`
+	Roles  []string `json:"roles"`
 }
 
 // DatabaseConfig represents database configuration
@@ -72,11 +120,481 @@ type QueueConfig struct {
 	RedisDB       int    `json:"redis_db,omitempty"`
 }
 
+// PrivacyConfig represents configuration for handling personal data
+type PrivacyConfig struct {
+	RedactionLevel    string `json:"redaction_level"` // "off", "partial" or "strict"
+	EncryptRecipients bool   `json:"encrypt_recipients"`
+	RecipientKey      string `json:"recipient_key,omitempty"` // base64 encoded 32-byte key
+
+	// Envelope encryption of notification content; keys are "id:base64key"
+	EncryptContent bool     `json:"encrypt_content"`
+	ContentKeyID   string   `json:"content_key_id,omitempty"` // primary key for new content
+	ContentKeys    []string `json:"content_keys,omitempty"`
+}
+
+// RequestLogConfig represents the logging of API requests
+type RequestLogConfig struct {
+	Enabled      bool    `json:"enabled"`
+	SampleRate   float64 `json:"sample_rate"`    // share of requests, 0 to 1, whose bodies are logged
+	MaxBodyBytes int     `json:"max_body_bytes"` // bodies are cut off after this many bytes
+}
+
+// SpamCheckConfig represents the spam scoring of marketing email
+type SpamCheckConfig struct {
+	Enabled   bool          `json:"enabled"`
+	Address   string        `json:"address"`   // host:port of a spamd server
+	Threshold float64       `json:"threshold"` // sends scoring this or more are spam
+	Block     bool          `json:"block"`     // reject spam instead of only logging it
+	Timeout   time.Duration `json:"timeout"`   // bounds one scoring request
+}
+
+// VirusScanConfig represents the virus scanning of email attachments
+type VirusScanConfig struct {
+	Enabled bool          `json:"enabled"`
+	Address string        `json:"address"` // host:port of a ClamAV clamd server
+	Action  string        `json:"action"`  // "reject" or "quarantine" infected attachments
+	Timeout time.Duration `json:"timeout"` // bounds the scan of one attachment
+}
+
+// AttachLinkConfig represents the offloading of large email attachments to
+// object storage, replaced in the email by signed download links
+type AttachLinkConfig struct {
+	Enabled      bool          `json:"enabled"`
+	Threshold    int64         `json:"threshold"`             // attachments larger than this many bytes are offloaded
+	MaxTotalSize int64         `json:"max_total_size"`        // the largest are offloaded until the rest fit; 0 means no limit
+	LinkTTL      time.Duration `json:"link_ttl"`              // how long a download link works
+	BaseURL      string        `json:"base_url"`              // public URL of the service, which serves the downloads
+	SigningKey   string        `json:"signing_key"`           // signs download links
+	StoreURL     string        `json:"store_url,omitempty"`   // attachments are PUT and fetched under this URL; kept in memory when empty
+	StoreToken   string        `json:"store_token,omitempty"` // sent as a bearer token to the store
+}
+
+// NumberLookupConfig represents phone number lookups of carrier and line type
+type NumberLookupConfig struct {
+	Enabled        bool          `json:"enabled"`
+	Provider       string        `json:"provider"`        // "twilio" or "mock"
+	BlockLandlines bool          `json:"block_landlines"` // reject SMS to landline numbers
+	CacheTTL       time.Duration `json:"cache_ttl"`       // how long a lookup is reused
+	Timeout        time.Duration `json:"timeout"`         // bounds one lookup
+
+	// Twilio specific
+	TwilioAccountSID string `json:"twilio_account_sid,omitempty"`
+	TwilioAuthToken  string `json:"twilio_auth_token,omitempty"`
+	TwilioBaseURL    string `json:"twilio_base_url,omitempty"`
+}
+
+// RetentionConfig represents how long notification data is kept
+type RetentionConfig struct {
+	Enabled         bool          `json:"enabled"`
+	BodyRetention   time.Duration `json:"body_retention"`   // bodies and template data are cleared after this; zero keeps them
+	RecordRetention time.Duration `json:"record_retention"` // notifications are deleted after this; zero keeps them
+	PurgeInterval   time.Duration `json:"purge_interval"`
+}
+
+// PauseConfig represents channels and providers paused at startup
+type PauseConfig struct {
+	Channels  []string `json:"channels"`   // e.g. "sms"
+	Providers []string `json:"providers"`  // provider names as configured, e.g. "twilio"
+	DrainRate int      `json:"drain_rate"` // held jobs released per second after a resume; zero releases them at once
+}
+
+// OutboxConfig represents how the transactional outbox is polled
+type OutboxConfig struct {
+	PollInterval time.Duration `json:"poll_interval"`
+	BatchSize    int           `json:"batch_size"` // entries claimed per poll
+	Lease        time.Duration `json:"lease"`      // how long a claim blocks other pollers
+}
+
+// ReconcileConfig represents how notifications left pending are reconciled with providers
+type ReconcileConfig struct {
+	Interval    time.Duration `json:"interval"`
+	GracePeriod time.Duration `json:"grace_period"` // notifications pending for less than this may still be sending
+	BatchSize   int           `json:"batch_size"`   // providers queried per run
+}
+
+// LoadShedConfig represents when the API sheds load. The service is
+// elevated when either signal crosses its elevated threshold and critical
+// when either crosses its critical threshold; zero thresholds are ignored.
+type LoadShedConfig struct {
+	Enabled            bool          `json:"enabled"`
+	QueueDepthElevated int           `json:"queue_depth_elevated"`
+	QueueDepthCritical int           `json:"queue_depth_critical"`
+	LatencyElevated    time.Duration `json:"latency_elevated"` // average send latency
+	LatencyCritical    time.Duration `json:"latency_critical"`
+	LatencyWindow      time.Duration `json:"latency_window"` // how long latency samples count
+	ShedElevated       []string      `json:"shed_elevated"`  // priorities rejected with 429 when elevated
+	ShedCritical       []string      `json:"shed_critical"`  // priorities rejected with 503 when critical
+	RetryAfter         time.Duration `json:"retry_after"`
+}
+
+// TemplateConfig represents where templates are synced from. When Dir is
+// set, the templates of every channel are replaced with the files in it on
+// each sync, so templates ship through code review instead of runtime edits.
+type TemplateConfig struct {
+	Dir          string        `json:"dir"`        // <channel>/<id>.json files; the checkout when GitURL is set
+	GitURL       string        `json:"git_url"`    // repository cloned into Dir; empty reads Dir as is
+	GitBranch    string        `json:"git_branch"` // branch to follow
+	GitPath      string        `json:"git_path"`   // templates directory within the repository
+	SyncInterval time.Duration `json:"sync_interval"`
+	Standard     bool          `json:"standard"` // load the standard template library; synced templates override it by ID
+}
+
+// CoalesceConfig represents the coalescing of repeated notifications that
+// share a dedup key
+type CoalesceConfig struct {
+	// CoolDowns maps a category ("transactional", "marketing" or "security")
+	// to how long repeats are held back before a summary is sent
+	CoolDowns map[string]time.Duration `json:"cool_downs"`
+}
+
+// JobsConfig represents the scheduler of internal maintenance jobs
+type JobsConfig struct {
+	Enabled bool `json:"enabled"`
+	// Schedules maps a job name to a cron expression, e.g. "0 3 * * *" or
+	// "@every 15m", overriding the job's default. "off" disables a job.
+	Schedules  map[string]string `json:"schedules"`
+	Timezone   string            `json:"timezone"`   // schedules are read in this zone; UTC when empty
+	LockLease  time.Duration     `json:"lock_lease"` // how long a run may hold its job's lock
+	InstanceID string            `json:"instance_id"`
+}
+
+// LockConfig represents the locks that keep replicas from running the same
+// scheduled work at once
+type LockConfig struct {
+	Type      string        `json:"type"`       // "memory", "redis" or "postgres"
+	KeyPrefix string        `json:"key_prefix"` // prepended to every lock key
+	Timeout   time.Duration `json:"timeout"`    // of each lock operation
+	// Redis specific; the URL is redis://[:password@]host:port[/db] or host:port
+	RedisURL      string `json:"redis_url,omitempty"`
+	RedisPassword string `json:"redis_password,omitempty"`
+	RedisDB       int    `json:"redis_db,omitempty"`
+}
+
+// RateLimitConfig represents the send limits of providers and tenants. With
+// the Redis store the limits hold across all instances.
+type RateLimitConfig struct {
+	Store     string               `json:"store"` // "memory" or "redis"
+	KeyPrefix string               `json:"key_prefix"`
+	Providers map[string]RateLimit `json:"providers"` // by provider name, e.g. "twilio"
+	// Tenants maps a tenant ID to its limit; "*" limits each tenant without one
+	Tenants map[string]RateLimit `json:"tenants"`
+	// FallbackCoolOff is how long limits use local buckets after the shared
+	// store fails before trying it again
+	FallbackCoolOff time.Duration `json:"fallback_cool_off"`
+	Timeout         time.Duration `json:"timeout"` // of each store operation
+	// Redis specific; the URL is redis://[:password@]host:port[/db] or host:port
+	RedisURL      string `json:"redis_url,omitempty"`
+	RedisPassword string `json:"redis_password,omitempty"`
+	RedisDB       int    `json:"redis_db,omitempty"`
+}
+
+// RateLimit is a token bucket: it refills at PerSecond tokens a second and
+// holds up to Burst tokens
+type RateLimit struct {
+	PerSecond float64 `json:"per_second"`
+	Burst     int     `json:"burst"` // PerSecond rounded up when zero
+}
+
+// CredentialsConfig represents how provider credentials are monitored
+type CredentialsConfig struct {
+	Enabled       bool          `json:"enabled"`
+	CheckInterval time.Duration `json:"check_interval"`
+	ExpiryWarning time.Duration `json:"expiry_warning"` // how long before expiry alerts start
+	// Expiries maps a channel, e.g. "push", to when its credentials expire,
+	// for credentials such as APNs keys whose expiry the provider cannot report
+	Expiries map[string]time.Time `json:"expiries,omitempty"`
+}
+
+// FrequencyConfig represents per-recipient send frequency caps
+type FrequencyConfig struct {
+	Caps []FrequencyCap `json:"caps"`
+}
+
+// FrequencyCap limits how many notifications of a category a recipient
+// receives on a channel within a period
+type FrequencyCap struct {
+	Category string        `json:"category"`          // "transactional", "marketing" or "security"
+	Channel  string        `json:"channel,omitempty"` // empty applies the cap to each channel
+	Limit    int           `json:"limit"`
+	Period   time.Duration `json:"period"`
+	Action   string        `json:"action,omitempty"` // "drop" (default) or "reschedule"
+}
+
+// WarmupConfig represents the warm-up of new email sending domains and IPs
+type WarmupConfig struct {
+	Domains []WarmupDomain `json:"domains"`
+}
+
+// WarmupDomain ramps up the daily email volume of a new sending domain or IP
+type WarmupDomain struct {
+	Domain      string    `json:"domain"`                 // sending domain, or an IP matched against the "sending_ip" metadata
+	Start       time.Time `json:"start"`                  // first day of the warm-up
+	DailyLimits []int     `json:"daily_limits,omitempty"` // daily limit of each week; the default ramp when empty
+}
+
+// EmailRoutingConfig represents the routing of email by category to
+// providers and sending domains, away from routes with a poor reputation
+type EmailRoutingConfig struct {
+	Routes           []EmailRoute  `json:"routes"`
+	Window           time.Duration `json:"window"`             // bounces and complaints are counted over this period
+	MinSends         int           `json:"min_sends"`          // a route's rates are trusted after this many sends in the window
+	MaxBounceRate    float64       `json:"max_bounce_rate"`    // share of sends; routes over it are avoided
+	MaxComplaintRate float64       `json:"max_complaint_rate"` // share of sends; routes over it are avoided
+}
+
+// EmailRoute sends email of some categories through a provider and sending domain
+type EmailRoute struct {
+	Name       string   `json:"name"`
+	Categories []string `json:"categories"`            // transactional, marketing or security
+	Provider   string   `json:"provider,omitempty"`    // a named email provider; the default one when empty
+	FromDomain string   `json:"from_domain,omitempty"` // replaces the domain of the From address
+	SendingIP  string   `json:"sending_ip,omitempty"`  // set as the "sending_ip" metadata
+}
+
+// RoutingConfig represents the rules choosing the provider, sender and rate
+// limit of each send
+type RoutingConfig struct {
+	RulesFile string        `json:"rules_file,omitempty"` // JSON array of rules, evaluated after Rules
+	Rules     []RoutingRule `json:"rules,omitempty"`
+}
+
+// RoutingRule applies to the sends its match selects; the first matching
+// rule wins
+type RoutingRule struct {
+	Name      string     `json:"name"`
+	Match     RuleMatch  `json:"match"`
+	Provider  string     `json:"provider,omitempty"`   // a named provider of the send's channel
+	Sender    string     `json:"sender,omitempty"`     // email From address or SMS sender ID
+	RateLimit *RateLimit `json:"rate_limit,omitempty"` // shared by every send the rule matches
+}
+
+// RuleMatch selects sends by their attributes. An empty list matches any
+// value; a send must match every non-empty list.
+type RuleMatch struct {
+	Types      []string `json:"types,omitempty"`
+	Tenants    []string `json:"tenants,omitempty"`
+	Countries  []string `json:"countries,omitempty"` // e.g. "US", "UK"; see utils.CountryFromPhoneNumber
+	Categories []string `json:"categories,omitempty"`
+	Priorities []string `json:"priorities,omitempty"`
+}
+
+// RolloutConfig represents the gradual rollout of new providers: a share
+// of each channel's sends goes to the new provider, which is rolled back
+// when its error rate exceeds the incumbent's by too much
+type RolloutConfig struct {
+	Rollouts             []ProviderRollout `json:"rollouts"`
+	MinSends             int               `json:"min_sends"`               // canary sends before its error rate is judged
+	MaxErrorRateIncrease float64           `json:"max_error_rate_increase"` // over the incumbent's, e.g. 0.05 for 5 points
+}
+
+// ProviderRollout sends a percentage of a channel's sends through a new
+// named provider
+type ProviderRollout struct {
+	Channel  string  `json:"channel"`  // email, sms, push, chat or voice
+	Provider string  `json:"provider"` // named provider being rolled out
+	Percent  float64 `json:"percent"`  // of the channel's sends, 0 to 100
+}
+
+// SLAConfig represents the service levels expected of providers. A
+// provider breaching its objectives over the window is marked degraded.
+type SLAConfig struct {
+	Window     time.Duration  `json:"window"`              // sends are judged over this sliding period
+	MinSamples int            `json:"min_samples"`         // sends in the window before a provider is judged
+	Objectives SLO            `json:"objectives"`          // of every provider without an override
+	Providers  map[string]SLO `json:"providers,omitempty"` // overrides by configured provider name, e.g. "twilio"
+}
+
+// SLO holds a provider's service level objectives. Zero fields are not checked.
+type SLO struct {
+	MinSuccessRate    float64       `json:"min_success_rate"`    // share of sends not failed by the provider
+	MaxAverageLatency time.Duration `json:"max_average_latency"` // of the provider's responses
+}
+
+// ChaosConfig represents fault injection into provider calls, for
+// resilience tests only
+type ChaosConfig struct {
+	Enabled      bool   `json:"enabled"`
+	ScenarioFile string `json:"scenario_file"` // JSON scenario of the faults to inject
+	Seed         int64  `json:"seed"`          // makes fault rolls repeatable; 0 seeds from the clock
+}
+
+// SMTPSinkConfig represents the local SMTP server that captures outbound
+// mail in development instead of delivering it
+type SMTPSinkConfig struct {
+	Enabled     bool   `json:"enabled"`
+	Addr        string `json:"addr"`         // listen address, e.g. "127.0.0.1:1025"
+	MaxMessages int    `json:"max_messages"` // captured messages kept; 0 uses the default
+}
+
+// AuthConfig represents the authentication of API callers, by API key or
+// OIDC token, and the roles that control what they may do
+type AuthConfig struct {
+	Enabled         bool                `json:"enabled"`
+	APIKeys         []APIKeyConfig      `json:"api_keys,omitempty"`
+	OIDCIssuer      string              `json:"oidc_issuer,omitempty"`       // tokens are accepted when set
+	OIDCAudience    string              `json:"oidc_audience,omitempty"`     // tokens must be issued for it; required with an issuer
+	OIDCJWKSURL     string              `json:"oidc_jwks_url,omitempty"`     // the issuer's signing keys; discovered when empty
+	OIDCRolesClaim  string              `json:"oidc_roles_claim"`            // the token claim listing roles, e.g. "groups" or "realm_access.roles"
+	OIDCTenantClaim string              `json:"oidc_tenant_claim"`           // the token claim naming the caller's tenant
+	OIDCRoleMapping map[string][]string `json:"oidc_role_mapping,omitempty"` // roles claim values, such as SSO groups, to roles
+}
+
+// APIKeyConfig is an API key and the roles of its holder
+type APIKeyConfig struct {
+	Subject string   `json:"subject"` // who holds the key, e.g. "billing-service"
+	Roles   []string `json:"roles"`
+	Key     string   `json:"-"`
+}
+
+// InboundConfig represents the handling of email replies to notifications
+type InboundConfig struct {
+	ReplyDomain string `json:"reply_domain"`         // domain whose mail the inbound parse webhook receives
+	LocalPart   string `json:"local_part,omitempty"` // of reply addresses; "reply" when empty
+	SigningKey  string `json:"signing_key"`          // signs the notification token of reply addresses
+}
+
+// WebhookAuthConfig represents the protection of provider webhooks, such
+// as inbound email and SMS, by IP allowlists and signatures
+type WebhookAuthConfig struct {
+	PublicURL      string              `json:"public_url,omitempty"`      // base URL providers post to, as signed by Twilio; the request's host when empty
+	TrustedProxies []string            `json:"trusted_proxies,omitempty"` // CIDRs whose X-Forwarded-For is trusted
+	SendGrid       WebhookSourceConfig `json:"sendgrid"`
+	SES            WebhookSourceConfig `json:"ses"`
+	Twilio         WebhookSourceConfig `json:"twilio"`
+}
+
+// WebhookSourceConfig represents the checks of one provider's webhooks
+type WebhookSourceConfig struct {
+	AllowedIPs []string `json:"allowed_ips,omitempty"` // addresses or CIDRs; any address when empty
+	SigningKey string   `json:"-"`                     // Twilio's auth token or SendGrid's verification key; unsigned when empty
+}
+
+// WebhookDedupConfig represents the deduplication of provider webhook
+// events, which providers retry. With the Redis store retries are recognized
+// across all instances.
+type WebhookDedupConfig struct {
+	Enabled   bool          `json:"enabled"`
+	Store     string        `json:"store"` // "memory" or "redis"
+	KeyPrefix string        `json:"key_prefix"`
+	TTL       time.Duration `json:"ttl"`     // how long processed events are remembered
+	Lease     time.Duration `json:"lease"`   // how long an event being processed holds off its retries
+	Timeout   time.Duration `json:"timeout"` // of each store operation
+	// Redis specific; the URL is redis://[:password@]host:port[/db] or host:port
+	RedisURL      string `json:"redis_url,omitempty"`
+	RedisPassword string `json:"redis_password,omitempty"`
+	RedisDB       int    `json:"redis_db,omitempty"`
+}
+
+// LimitsConfig represents the size and content limits notifications are
+// validated against. Zero values use the defaults.
+type LimitsConfig struct {
+	MaxEmailSize    int64 `json:"max_email_size"`     // bytes of the encoded message, attachments included
+	MaxAttachments  int   `json:"max_attachments"`    // per email
+	MaxSMSSegments  int   `json:"max_sms_segments"`   // per message
+	MaxPushDataKeys int   `json:"max_push_data_keys"` // custom data keys per push notification
+}
+
+// KeywordsConfig represents the handling of inbound SMS keywords such as
+// STOP and HELP. Empty replies use the defaults.
+type KeywordsConfig struct {
+	StopReply      string        `json:"stop_reply,omitempty"`
+	StartReply     string        `json:"start_reply,omitempty"`
+	HelpReply      string        `json:"help_reply,omitempty"`
+	ForwardURL     string        `json:"forward_url,omitempty"` // receives other inbound messages; dropped when empty
+	ForwardTimeout time.Duration `json:"forward_timeout,omitempty"`
+}
+
+// ConversationConfig represents the threading of outbound and inbound SMS
+// into conversations
+type ConversationConfig struct {
+	Window        time.Duration `json:"window"`                   // inactivity after which a new conversation starts
+	ServiceNumber string        `json:"service_number,omitempty"` // our number, for sends without a numeric sender
+}
+
+// PushGroupConfig represents the service-managed grouping of push alerts
+type PushGroupConfig struct {
+	Window time.Duration `json:"window"` // inactivity after which a group's count starts over
+}
+
+// PushMediaConfig represents the checking of push images and the object
+// store images are uploaded to
+type PushMediaConfig struct {
+	CheckTimeout time.Duration `json:"check_timeout"`          // bounds fetching an image to check it
+	CacheTTL     time.Duration `json:"cache_ttl"`              // how long a checked image is trusted
+	CacheSize    int           `json:"cache_size"`             // how many checked images are kept
+	UploadURL    string        `json:"upload_url,omitempty"`   // images are PUT under this URL; uploads are disabled when empty
+	PublicURL    string        `json:"public_url,omitempty"`   // the CDN URL uploaded images are served from
+	UploadToken  string        `json:"upload_token,omitempty"` // sent as a bearer token with uploads
+}
+
+// BrandingConfig represents the object store tenant branding assets, such
+// as logos, are uploaded to
+type BrandingConfig struct {
+	AssetUploadURL   string `json:"asset_upload_url,omitempty"`   // assets are PUT under this URL; uploads are disabled when empty
+	AssetPublicURL   string `json:"asset_public_url,omitempty"`   // the CDN URL uploaded assets are served from
+	AssetUploadToken string `json:"asset_upload_token,omitempty"` // sent as a bearer token with uploads
+	MaxAssetSize     int64  `json:"max_asset_size"`               // largest asset in bytes
+}
+
 // ProvidersConfig represents configuration for all notification providers
 type ProvidersConfig struct {
 	Email EmailProviderConfig `json:"email"`
 	SMS   SMSProviderConfig   `json:"sms"`
 	Push  PushProviderConfig  `json:"push"`
+	Chat  ChatProviderConfig  `json:"chat"`
+	Voice VoiceProviderConfig `json:"voice"`
+	HTTP  HTTPClientConfig    `json:"http"` // shared by providers calling HTTP APIs
+}
+
+// HTTPClientConfig represents connection pooling and timeouts of the HTTP
+// clients providers use. Zero fields take the client factory's defaults.
+type HTTPClientConfig struct {
+	MaxIdleConns          int           `json:"max_idle_conns,omitempty"`
+	MaxIdleConnsPerHost   int           `json:"max_idle_conns_per_host,omitempty"`
+	MaxConnsPerHost       int           `json:"max_conns_per_host,omitempty"` // zero is unlimited
+	IdleConnTimeout       time.Duration `json:"idle_conn_timeout,omitempty"`
+	DialTimeout           time.Duration `json:"dial_timeout,omitempty"`
+	KeepAlive             time.Duration `json:"keep_alive,omitempty"` // TCP keep-alive probe interval
+	TLSHandshakeTimeout   time.Duration `json:"tls_handshake_timeout,omitempty"`
+	ResponseHeaderTimeout time.Duration `json:"response_header_timeout,omitempty"`
+	DisableHTTP2          bool          `json:"disable_http2,omitempty"`
+	PublicOnly            bool          `json:"public_only,omitempty"` // refuse private, loopback and link-local addresses
+}
+
+// Merge returns the configuration with the non-zero fields of an override
+// applied, e.g. a provider's settings over the shared ones
+func (c HTTPClientConfig) Merge(override HTTPClientConfig) HTTPClientConfig {
+	if override.MaxIdleConns > 0 {
+		c.MaxIdleConns = override.MaxIdleConns
+	}
+	if override.MaxIdleConnsPerHost > 0 {
+		c.MaxIdleConnsPerHost = override.MaxIdleConnsPerHost
+	}
+	if override.MaxConnsPerHost > 0 {
+		c.MaxConnsPerHost = override.MaxConnsPerHost
+	}
+	if override.IdleConnTimeout > 0 {
+		c.IdleConnTimeout = override.IdleConnTimeout
+	}
+	if override.DialTimeout > 0 {
+		c.DialTimeout = override.DialTimeout
+	}
+	if override.KeepAlive > 0 {
+		c.KeepAlive = override.KeepAlive
+	}
+	if override.TLSHandshakeTimeout > 0 {
+		c.TLSHandshakeTimeout = override.TLSHandshakeTimeout
+	}
+	if override.ResponseHeaderTimeout > 0 {
+		c.ResponseHeaderTimeout = override.ResponseHeaderTimeout
+	}
+	if override.DisableHTTP2 {
+		c.DisableHTTP2 = true
+	}
+	if override.PublicOnly {
+		c.PublicOnly = true
+	}
+	return c
 }
 
 // EmailProviderConfig represents email provider configuration
@@ -99,6 +617,23 @@ type EmailProviderConfig struct {
 	SESRegion          string `json:"ses_region,omitempty"`
 	SESAccessKeyID     string `json:"ses_access_key_id,omitempty"`
 	SESSecretAccessKey string `json:"ses_secret_access_key,omitempty"`
+
+	// Per-recipient-domain throttling, keyed by domain or "*" for any other;
+	// sends are not throttled when empty
+	DomainLimits map[string]EmailDomainLimit `json:"domain_limits,omitempty"`
+	// Backoff after a domain defers mail (SMTP 421/450), doubled on each
+	// deferral up to the maximum; 30s and 15m when zero
+	DeferralBackoff    time.Duration `json:"deferral_backoff,omitempty"`
+	MaxDeferralBackoff time.Duration `json:"max_deferral_backoff,omitempty"`
+
+	// Mock specific
+	SentHistory int `json:"sent_history,omitempty"` // sends kept for inspection; 0 uses the default
+}
+
+// EmailDomainLimit limits sends to one recipient domain
+type EmailDomainLimit struct {
+	PerSecond   float64 `json:"per_second"`  // 0 means no rate limit
+	Concurrency int     `json:"concurrency"` // sends in flight at once; 0 means no limit
 }
 
 // SMSProviderConfig represents SMS provider configuration
@@ -107,6 +642,16 @@ type SMSProviderConfig struct {
 	Enabled  bool              `json:"enabled"`
 	Settings map[string]string `json:"settings"`
 
+	// Numeric sender used in countries that prohibit alphanumeric sender IDs
+	FromNumber string `json:"from_number,omitempty"`
+
+	// Per-country routing: the providers to try in order, keyed by country
+	// code as utils.CountryFromPhoneNumber returns it ("US", "UK", "IN") or
+	// "*" for any other. Every send uses Provider when empty. Route providers
+	// without an entry in RouteProviders are created from this configuration.
+	CountryRoutes  map[string][]string          `json:"country_routes,omitempty"`
+	RouteProviders map[string]SMSProviderConfig `json:"route_providers,omitempty"`
+
 	// Twilio specific
 	TwilioAccountSID string `json:"twilio_account_sid,omitempty"`
 	TwilioAuthToken  string `json:"twilio_auth_token,omitempty"`
@@ -116,6 +661,9 @@ type SMSProviderConfig struct {
 	NexmoAPIKey    string `json:"nexmo_api_key,omitempty"`
 	NexmoAPISecret string `json:"nexmo_api_secret,omitempty"`
 	NexmoFromName  string `json:"nexmo_from_name,omitempty"`
+
+	// Mock specific
+	SentHistory int `json:"sent_history,omitempty"` // sends kept for inspection; 0 uses the default
 }
 
 // PushProviderConfig represents push notification provider configuration
@@ -134,6 +682,56 @@ type PushProviderConfig struct {
 	APNSBundleID   string `json:"apns_bundle_id,omitempty"`
 	APNSKeyFile    string `json:"apns_key_file,omitempty"`
 	APNSProduction bool   `json:"apns_production,omitempty"`
+
+	// Mock specific
+	SentHistory int `json:"sent_history,omitempty"` // sends kept for inspection; 0 uses the default
+}
+
+// ChatProviderConfig represents chat webhook provider configuration
+type ChatProviderConfig struct {
+	Provider string            `json:"provider"` // "slack", "teams"
+	Enabled  bool              `json:"enabled"`
+	Settings map[string]string `json:"settings"`
+	Timeout  time.Duration     `json:"timeout"`
+	HTTP     HTTPClientConfig  `json:"http"` // overrides the shared HTTP client settings
+
+	// Default webhook used when a request does not specify one
+	WebhookURL string `json:"webhook_url,omitempty"`
+
+	// Hosts webhooks may be sent to; "*." matches any subdomain. Empty
+	// allows Slack's and Teams' webhook hosts.
+	AllowedHosts []string `json:"allowed_hosts,omitempty"`
+
+	// Allows webhooks over http and on private, loopback and link-local
+	// addresses, e.g. a self-hosted chat server. Only enable it when every
+	// API caller is trusted.
+	AllowPrivateWebhooks bool `json:"allow_private_webhooks,omitempty"`
+
+	// Slack specific
+	SlackUsername  string `json:"slack_username,omitempty"`
+	SlackIconEmoji string `json:"slack_icon_emoji,omitempty"`
+}
+
+// VoiceProviderConfig represents voice call provider configuration
+type VoiceProviderConfig struct {
+	Provider string            `json:"provider"` // "twilio"
+	Enabled  bool              `json:"enabled"`
+	Settings map[string]string `json:"settings"`
+	Timeout  time.Duration     `json:"timeout"`
+	HTTP     HTTPClientConfig  `json:"http"` // overrides the shared HTTP client settings
+
+	// Countries (ISO 3166 alpha-2) calls may be placed to; empty allows all supported countries
+	AllowedCountries []string `json:"allowed_countries,omitempty"`
+
+	// Text-to-speech defaults
+	DefaultVoice    string `json:"default_voice,omitempty"`
+	DefaultLanguage string `json:"default_language,omitempty"`
+
+	// Twilio specific
+	TwilioAccountSID string `json:"twilio_account_sid,omitempty"`
+	TwilioAuthToken  string `json:"twilio_auth_token,omitempty"`
+	TwilioFromNumber string `json:"twilio_from_number,omitempty"`
+	TwilioBaseURL    string `json:"twilio_base_url,omitempty"`
 }
 
 // LoadConfig loads configuration from environment variables and defaults
@@ -149,6 +747,10 @@ func LoadConfig() (*Config, error) {
 			EnableTLS:    getEnvBool("SERVER_ENABLE_TLS", false),
 			CertFile:     getEnv("SERVER_CERT_FILE", ""),
 			KeyFile:      getEnv("SERVER_KEY_FILE", ""),
+
+			ClientCAFile:     getEnv("SERVER_CLIENT_CA_FILE", ""),
+			ClientAuth:       getEnv("SERVER_CLIENT_AUTH", "none"),
+			ClientIdentities: getEnvClientIdentities("SERVER_CLIENT_IDENTITIES"),
 		},
 		Database: DatabaseConfig{
 			Type:         getEnv("DB_TYPE", "memory"),
@@ -198,17 +800,24 @@ func LoadConfig() (*Config, error) {
 				SESRegion:          getEnv("SES_REGION", ""),
 				SESAccessKeyID:     getEnv("SES_ACCESS_KEY_ID", ""),
 				SESSecretAccessKey: getEnv("SES_SECRET_ACCESS_KEY", ""),
+				DomainLimits:       getEnvEmailDomainLimits("EMAIL_DOMAIN_LIMITS"),
+				DeferralBackoff:    getEnvDuration("EMAIL_DEFERRAL_BACKOFF", 0),
+				MaxDeferralBackoff: getEnvDuration("EMAIL_MAX_DEFERRAL_BACKOFF", 0),
+				SentHistory:        getEnvInt("EMAIL_SENT_HISTORY", 0),
 			},
 			SMS: SMSProviderConfig{
 				Provider:         getEnv("SMS_PROVIDER", "mock"),
 				Enabled:          getEnvBool("SMS_ENABLED", true),
 				Settings:         make(map[string]string),
+				FromNumber:       getEnv("SMS_FROM_NUMBER", ""),
+				CountryRoutes:    getEnvCountryRoutes("SMS_COUNTRY_ROUTES"),
 				TwilioAccountSID: getEnv("TWILIO_ACCOUNT_SID", ""),
 				TwilioAuthToken:  getEnv("TWILIO_AUTH_TOKEN", ""),
 				TwilioFromNumber: getEnv("TWILIO_FROM_NUMBER", ""),
 				NexmoAPIKey:      getEnv("NEXMO_API_KEY", ""),
 				NexmoAPISecret:   getEnv("NEXMO_API_SECRET", ""),
 				NexmoFromName:    getEnv("NEXMO_FROM_NAME", ""),
+				SentHistory:      getEnvInt("SMS_SENT_HISTORY", 0),
 			},
 			Push: PushProviderConfig{
 				Provider:       getEnv("PUSH_PROVIDER", "mock"),
@@ -221,8 +830,278 @@ func LoadConfig() (*Config, error) {
 				APNSBundleID:   getEnv("APNS_BUNDLE_ID", ""),
 				APNSKeyFile:    getEnv("APNS_KEY_FILE", ""),
 				APNSProduction: getEnvBool("APNS_PRODUCTION", false),
+				SentHistory:    getEnvInt("PUSH_SENT_HISTORY", 0),
+			},
+			Chat: ChatProviderConfig{
+				Provider:             getEnv("CHAT_PROVIDER", "slack"),
+				Enabled:              getEnvBool("CHAT_ENABLED", false),
+				Settings:             make(map[string]string),
+				Timeout:              getEnvDuration("CHAT_TIMEOUT", 10*time.Second),
+				WebhookURL:           getEnv("CHAT_WEBHOOK_URL", ""),
+				AllowedHosts:         getEnvList("CHAT_WEBHOOK_ALLOWED_HOSTS", nil),
+				AllowPrivateWebhooks: getEnvBool("CHAT_ALLOW_PRIVATE_WEBHOOKS", false),
+				SlackUsername:        getEnv("SLACK_USERNAME", ""),
+				SlackIconEmoji:       getEnv("SLACK_ICON_EMOJI", ""),
+			},
+			Voice: VoiceProviderConfig{
+				Provider:         getEnv("VOICE_PROVIDER", "twilio"),
+				Enabled:          getEnvBool("VOICE_ENABLED", false),
+				Settings:         make(map[string]string),
+				Timeout:          getEnvDuration("VOICE_TIMEOUT", 15*time.Second),
+				AllowedCountries: getEnvList("VOICE_ALLOWED_COUNTRIES", []string{"US", "CA", "UK"}),
+				DefaultVoice:     getEnv("VOICE_DEFAULT_VOICE", "alice"),
+				DefaultLanguage:  getEnv("VOICE_DEFAULT_LANGUAGE", "en-US"),
+				TwilioAccountSID: getEnv("TWILIO_ACCOUNT_SID", ""),
+				TwilioAuthToken:  getEnv("TWILIO_AUTH_TOKEN", ""),
+				TwilioFromNumber: getEnv("TWILIO_VOICE_FROM_NUMBER", getEnv("TWILIO_FROM_NUMBER", "")),
+				TwilioBaseURL:    getEnv("TWILIO_BASE_URL", "https://api.twilio.com"),
+			},
+			HTTP: HTTPClientConfig{
+				MaxIdleConns:          getEnvInt("PROVIDER_HTTP_MAX_IDLE_CONNS", 100),
+				MaxIdleConnsPerHost:   getEnvInt("PROVIDER_HTTP_MAX_IDLE_CONNS_PER_HOST", 32),
+				MaxConnsPerHost:       getEnvInt("PROVIDER_HTTP_MAX_CONNS_PER_HOST", 0),
+				IdleConnTimeout:       getEnvDuration("PROVIDER_HTTP_IDLE_CONN_TIMEOUT", 90*time.Second),
+				DialTimeout:           getEnvDuration("PROVIDER_HTTP_DIAL_TIMEOUT", 5*time.Second),
+				KeepAlive:             getEnvDuration("PROVIDER_HTTP_KEEP_ALIVE", 30*time.Second),
+				TLSHandshakeTimeout:   getEnvDuration("PROVIDER_HTTP_TLS_HANDSHAKE_TIMEOUT", 5*time.Second),
+				ResponseHeaderTimeout: getEnvDuration("PROVIDER_HTTP_RESPONSE_HEADER_TIMEOUT", 0),
+				DisableHTTP2:          getEnvBool("PROVIDER_HTTP_DISABLE_HTTP2", false),
 			},
 		},
+		Privacy: PrivacyConfig{
+			RedactionLevel:    getEnv("PRIVACY_REDACTION_LEVEL", "partial"),
+			EncryptRecipients: getEnvBool("PRIVACY_ENCRYPT_RECIPIENTS", false),
+			RecipientKey:      getEnv("PRIVACY_RECIPIENT_KEY", ""),
+			EncryptContent:    getEnvBool("PRIVACY_ENCRYPT_CONTENT", false),
+			ContentKeyID:      getEnv("PRIVACY_CONTENT_KEY_ID", ""),
+			ContentKeys:       getEnvList("PRIVACY_CONTENT_KEYS", nil),
+		},
+		RequestLog: RequestLogConfig{
+			Enabled:      getEnvBool("REQUEST_LOG_ENABLED", true),
+			SampleRate:   getEnvFloat("REQUEST_LOG_SAMPLE_RATE", 0.01),
+			MaxBodyBytes: getEnvInt("REQUEST_LOG_MAX_BODY_BYTES", 4096),
+		},
+		SpamCheck: SpamCheckConfig{
+			Enabled:   getEnvBool("SPAM_CHECK_ENABLED", false),
+			Address:   getEnv("SPAM_CHECK_ADDRESS", "localhost:783"),
+			Threshold: getEnvFloat("SPAM_CHECK_THRESHOLD", 5.0),
+			Block:     getEnvBool("SPAM_CHECK_BLOCK", true),
+			Timeout:   getEnvDuration("SPAM_CHECK_TIMEOUT", 5*time.Second),
+		},
+		AttachLinks: AttachLinkConfig{
+			Enabled:      getEnvBool("ATTACHMENT_LINKS_ENABLED", false),
+			Threshold:    int64(getEnvInt("ATTACHMENT_LINKS_THRESHOLD", 10<<20)),
+			MaxTotalSize: int64(getEnvInt("ATTACHMENT_LINKS_MAX_TOTAL_SIZE", 0)),
+			LinkTTL:      getEnvDuration("ATTACHMENT_LINKS_TTL", 7*24*time.Hour),
+			BaseURL:      getEnv("ATTACHMENT_LINKS_BASE_URL", ""),
+			SigningKey:   getEnv("ATTACHMENT_LINKS_SIGNING_KEY", ""),
+			StoreURL:     getEnv("ATTACHMENT_LINKS_STORE_URL", ""),
+			StoreToken:   getEnv("ATTACHMENT_LINKS_STORE_TOKEN", ""),
+		},
+		NumberLookup: NumberLookupConfig{
+			Enabled:          getEnvBool("NUMBER_LOOKUP_ENABLED", false),
+			Provider:         getEnv("NUMBER_LOOKUP_PROVIDER", "mock"),
+			BlockLandlines:   getEnvBool("NUMBER_LOOKUP_BLOCK_LANDLINES", false),
+			CacheTTL:         getEnvDuration("NUMBER_LOOKUP_CACHE_TTL", 7*24*time.Hour),
+			Timeout:          getEnvDuration("NUMBER_LOOKUP_TIMEOUT", 5*time.Second),
+			TwilioAccountSID: getEnv("NUMBER_LOOKUP_TWILIO_ACCOUNT_SID", ""),
+			TwilioAuthToken:  getEnv("NUMBER_LOOKUP_TWILIO_AUTH_TOKEN", ""),
+		},
+		VirusScan: VirusScanConfig{
+			Enabled: getEnvBool("VIRUS_SCAN_ENABLED", false),
+			Address: getEnv("VIRUS_SCAN_ADDRESS", "localhost:3310"),
+			Action:  getEnv("VIRUS_SCAN_ACTION", "reject"),
+			Timeout: getEnvDuration("VIRUS_SCAN_TIMEOUT", 30*time.Second),
+		},
+		Retention: RetentionConfig{
+			Enabled:         getEnvBool("RETENTION_ENABLED", false),
+			BodyRetention:   getEnvDuration("RETENTION_BODY", 30*24*time.Hour),
+			RecordRetention: getEnvDuration("RETENTION_RECORD", 180*24*time.Hour),
+			PurgeInterval:   getEnvDuration("RETENTION_PURGE_INTERVAL", time.Hour),
+		},
+		Pause: PauseConfig{
+			Channels:  getEnvList("PAUSED_CHANNELS", nil),
+			Providers: getEnvList("PAUSED_PROVIDERS", nil),
+			DrainRate: getEnvInt("PAUSE_DRAIN_RATE", 10),
+		},
+		Frequency: FrequencyConfig{
+			Caps: getEnvFrequencyCaps("FREQUENCY_CAPS"),
+		},
+		Warmup: WarmupConfig{
+			Domains: getEnvWarmupDomains("WARMUP_DOMAINS", getEnvIntList("WARMUP_DAILY_LIMITS")),
+		},
+		EmailRouting: EmailRoutingConfig{
+			Routes:           getEnvEmailRoutes("EMAIL_ROUTES"),
+			Window:           getEnvDuration("EMAIL_ROUTE_WINDOW", 24*time.Hour),
+			MinSends:         getEnvInt("EMAIL_ROUTE_MIN_SENDS", 100),
+			MaxBounceRate:    getEnvFloat("EMAIL_ROUTE_MAX_BOUNCE_RATE", 0.05),
+			MaxComplaintRate: getEnvFloat("EMAIL_ROUTE_MAX_COMPLAINT_RATE", 0.003),
+		},
+		Routing: RoutingConfig{
+			RulesFile: getEnv("ROUTING_RULES_FILE", ""),
+		},
+		Rollout: RolloutConfig{
+			Rollouts:             getEnvProviderRollouts("PROVIDER_ROLLOUTS"),
+			MinSends:             getEnvInt("ROLLOUT_MIN_SENDS", 50),
+			MaxErrorRateIncrease: getEnvFloat("ROLLOUT_MAX_ERROR_RATE_INCREASE", 0.05),
+		},
+		SLA: SLAConfig{
+			Window:     getEnvDuration("SLA_WINDOW", 5*time.Minute),
+			MinSamples: getEnvInt("SLA_MIN_SAMPLES", 20),
+			Objectives: SLO{
+				MinSuccessRate:    getEnvFloat("SLA_MIN_SUCCESS_RATE", 0.95),
+				MaxAverageLatency: getEnvDuration("SLA_MAX_AVERAGE_LATENCY", 2*time.Second),
+			},
+		},
+		Chaos: ChaosConfig{
+			Enabled:      getEnvBool("CHAOS_ENABLED", false),
+			ScenarioFile: getEnv("CHAOS_SCENARIO_FILE", ""),
+			Seed:         int64(getEnvInt("CHAOS_SEED", 0)),
+		},
+		SMTPSink: SMTPSinkConfig{
+			Enabled:     getEnvBool("SMTP_SINK_ENABLED", false),
+			Addr:        getEnv("SMTP_SINK_ADDR", "127.0.0.1:1025"),
+			MaxMessages: getEnvInt("SMTP_SINK_MAX_MESSAGES", 1000),
+		},
+		Auth: AuthConfig{
+			Enabled:         getEnvBool("AUTH_ENABLED", false),
+			APIKeys:         getEnvAPIKeys("AUTH_API_KEYS"),
+			OIDCIssuer:      getEnv("AUTH_OIDC_ISSUER", ""),
+			OIDCAudience:    getEnv("AUTH_OIDC_AUDIENCE", ""),
+			OIDCJWKSURL:     getEnv("AUTH_OIDC_JWKS_URL", ""),
+			OIDCRolesClaim:  getEnv("AUTH_OIDC_ROLES_CLAIM", "roles"),
+			OIDCTenantClaim: getEnv("AUTH_OIDC_TENANT_CLAIM", "tenant_id"),
+			OIDCRoleMapping: getEnvRoleMapping("AUTH_OIDC_ROLE_MAPPING"),
+		},
+		Webhooks: WebhookAuthConfig{
+			PublicURL:      getEnv("WEBHOOK_PUBLIC_URL", ""),
+			TrustedProxies: getEnvList("WEBHOOK_TRUSTED_PROXIES", nil),
+			SendGrid: WebhookSourceConfig{
+				AllowedIPs: getEnvList("WEBHOOK_SENDGRID_ALLOWED_IPS", nil),
+				SigningKey: getEnv("WEBHOOK_SENDGRID_VERIFICATION_KEY", ""),
+			},
+			SES: WebhookSourceConfig{
+				AllowedIPs: getEnvList("WEBHOOK_SES_ALLOWED_IPS", nil),
+			},
+			Twilio: WebhookSourceConfig{
+				AllowedIPs: getEnvList("WEBHOOK_TWILIO_ALLOWED_IPS", nil),
+				SigningKey: getEnv("WEBHOOK_TWILIO_AUTH_TOKEN", ""),
+			},
+		},
+		WebhookDedup: WebhookDedupConfig{
+			Enabled:       getEnvBool("WEBHOOK_DEDUP_ENABLED", false),
+			Store:         getEnv("WEBHOOK_DEDUP_STORE", "memory"),
+			KeyPrefix:     getEnv("WEBHOOK_DEDUP_KEY_PREFIX", "notification-service:webhook:"),
+			TTL:           getEnvDuration("WEBHOOK_DEDUP_TTL", 72*time.Hour),
+			Lease:         getEnvDuration("WEBHOOK_DEDUP_LEASE", time.Minute),
+			Timeout:       getEnvDuration("WEBHOOK_DEDUP_TIMEOUT", 2*time.Second),
+			RedisURL:      getEnv("WEBHOOK_DEDUP_REDIS_URL", getEnv("REDIS_URL", "")),
+			RedisPassword: getEnv("WEBHOOK_DEDUP_REDIS_PASSWORD", getEnv("REDIS_PASSWORD", "")),
+			RedisDB:       getEnvInt("WEBHOOK_DEDUP_REDIS_DB", getEnvInt("REDIS_DB", 0)),
+		},
+		Limits: LimitsConfig{
+			MaxEmailSize:    int64(getEnvInt("LIMITS_MAX_EMAIL_SIZE", 25<<20)),
+			MaxAttachments:  getEnvInt("LIMITS_MAX_ATTACHMENTS", 20),
+			MaxSMSSegments:  getEnvInt("LIMITS_MAX_SMS_SEGMENTS", 10),
+			MaxPushDataKeys: getEnvInt("LIMITS_MAX_PUSH_DATA_KEYS", 64),
+		},
+		Inbound: InboundConfig{
+			ReplyDomain: getEnv("INBOUND_REPLY_DOMAIN", ""),
+			LocalPart:   getEnv("INBOUND_LOCAL_PART", "reply"),
+			SigningKey:  getEnv("INBOUND_SIGNING_KEY", ""),
+		},
+		Keywords: KeywordsConfig{
+			StopReply:      getEnv("SMS_STOP_REPLY", ""),
+			StartReply:     getEnv("SMS_START_REPLY", ""),
+			HelpReply:      getEnv("SMS_HELP_REPLY", ""),
+			ForwardURL:     getEnv("SMS_INBOUND_FORWARD_URL", ""),
+			ForwardTimeout: getEnvDuration("SMS_INBOUND_FORWARD_TIMEOUT", 10*time.Second),
+		},
+		Conversations: ConversationConfig{
+			Window:        getEnvDuration("SMS_CONVERSATION_WINDOW", 24*time.Hour),
+			ServiceNumber: getEnv("SMS_FROM_NUMBER", ""),
+		},
+		PushGroups: PushGroupConfig{
+			Window: getEnvDuration("PUSH_GROUP_WINDOW", 24*time.Hour),
+		},
+		PushMedia: PushMediaConfig{
+			CheckTimeout: getEnvDuration("PUSH_IMAGE_CHECK_TIMEOUT", 5*time.Second),
+			CacheTTL:     getEnvDuration("PUSH_IMAGE_CACHE_TTL", 10*time.Minute),
+			CacheSize:    getEnvInt("PUSH_IMAGE_CACHE_SIZE", 10000),
+			UploadURL:    getEnv("PUSH_MEDIA_UPLOAD_URL", ""),
+			PublicURL:    getEnv("PUSH_MEDIA_PUBLIC_URL", ""),
+			UploadToken:  getEnv("PUSH_MEDIA_UPLOAD_TOKEN", ""),
+		},
+		Branding: BrandingConfig{
+			AssetUploadURL:   getEnv("BRANDING_ASSET_UPLOAD_URL", ""),
+			AssetPublicURL:   getEnv("BRANDING_ASSET_PUBLIC_URL", ""),
+			AssetUploadToken: getEnv("BRANDING_ASSET_UPLOAD_TOKEN", ""),
+			MaxAssetSize:     int64(getEnvInt("BRANDING_MAX_ASSET_SIZE", 1<<20)),
+		},
+		Outbox: OutboxConfig{
+			PollInterval: getEnvDuration("OUTBOX_POLL_INTERVAL", time.Second),
+			BatchSize:    getEnvInt("OUTBOX_BATCH_SIZE", 100),
+			Lease:        getEnvDuration("OUTBOX_LEASE", 30*time.Second),
+		},
+		LoadShed: LoadShedConfig{
+			Enabled:            getEnvBool("LOAD_SHED_ENABLED", false),
+			QueueDepthElevated: getEnvInt("LOAD_SHED_QUEUE_ELEVATED", 1000),
+			QueueDepthCritical: getEnvInt("LOAD_SHED_QUEUE_CRITICAL", 5000),
+			LatencyElevated:    getEnvDuration("LOAD_SHED_LATENCY_ELEVATED", 2*time.Second),
+			LatencyCritical:    getEnvDuration("LOAD_SHED_LATENCY_CRITICAL", 5*time.Second),
+			LatencyWindow:      getEnvDuration("LOAD_SHED_LATENCY_WINDOW", 30*time.Second),
+			ShedElevated:       getEnvList("LOAD_SHED_ELEVATED_PRIORITIES", []string{"low"}),
+			ShedCritical:       getEnvList("LOAD_SHED_CRITICAL_PRIORITIES", []string{"low", "normal", "high"}),
+			RetryAfter:         getEnvDuration("LOAD_SHED_RETRY_AFTER", 10*time.Second),
+		},
+		Reconcile: ReconcileConfig{
+			Interval:    getEnvDuration("RECONCILE_INTERVAL", time.Minute),
+			GracePeriod: getEnvDuration("RECONCILE_GRACE_PERIOD", 5*time.Minute),
+			BatchSize:   getEnvInt("RECONCILE_BATCH_SIZE", 100),
+		},
+		Templates: TemplateConfig{
+			Dir:          getEnv("TEMPLATES_DIR", ""),
+			GitURL:       getEnv("TEMPLATES_GIT_URL", ""),
+			GitBranch:    getEnv("TEMPLATES_GIT_BRANCH", "main"),
+			GitPath:      getEnv("TEMPLATES_GIT_PATH", ""),
+			SyncInterval: getEnvDuration("TEMPLATES_SYNC_INTERVAL", 30*time.Second),
+			Standard:     getEnvBool("TEMPLATES_STANDARD", false),
+		},
+		Coalesce: CoalesceConfig{
+			CoolDowns: getEnvDurations("COALESCE_COOLDOWNS"),
+		},
+		Jobs: JobsConfig{
+			Enabled:    getEnvBool("JOBS_ENABLED", false),
+			Schedules:  getEnvSchedules("JOBS_SCHEDULES"),
+			Timezone:   getEnv("JOBS_TIMEZONE", "UTC"),
+			LockLease:  getEnvDuration("JOBS_LOCK_LEASE", 10*time.Minute),
+			InstanceID: getEnv("JOBS_INSTANCE_ID", ""),
+		},
+		Lock: LockConfig{
+			Type:          getEnv("LOCK_TYPE", "memory"),
+			KeyPrefix:     getEnv("LOCK_KEY_PREFIX", "notification-service:lock:"),
+			Timeout:       getEnvDuration("LOCK_TIMEOUT", 5*time.Second),
+			RedisURL:      getEnv("LOCK_REDIS_URL", getEnv("REDIS_URL", "")),
+			RedisPassword: getEnv("LOCK_REDIS_PASSWORD", getEnv("REDIS_PASSWORD", "")),
+			RedisDB:       getEnvInt("LOCK_REDIS_DB", getEnvInt("REDIS_DB", 0)),
+		},
+		RateLimit: RateLimitConfig{
+			Store:           getEnv("RATE_LIMIT_STORE", "memory"),
+			KeyPrefix:       getEnv("RATE_LIMIT_KEY_PREFIX", "notification-service:ratelimit:"),
+			Providers:       getEnvRateLimits("RATE_LIMIT_PROVIDERS"),
+			Tenants:         getEnvRateLimits("RATE_LIMIT_TENANTS"),
+			FallbackCoolOff: getEnvDuration("RATE_LIMIT_FALLBACK_COOL_OFF", 30*time.Second),
+			Timeout:         getEnvDuration("RATE_LIMIT_TIMEOUT", 250*time.Millisecond),
+			RedisURL:        getEnv("RATE_LIMIT_REDIS_URL", getEnv("REDIS_URL", "")),
+			RedisPassword:   getEnv("RATE_LIMIT_REDIS_PASSWORD", getEnv("REDIS_PASSWORD", "")),
+			RedisDB:         getEnvInt("RATE_LIMIT_REDIS_DB", getEnvInt("REDIS_DB", 0)),
+		},
+		Credentials: CredentialsConfig{
+			Enabled:       getEnvBool("CREDENTIALS_MONITOR_ENABLED", false),
+			CheckInterval: getEnvDuration("CREDENTIALS_CHECK_INTERVAL", 15*time.Minute),
+			ExpiryWarning: getEnvDuration("CREDENTIALS_EXPIRY_WARNING", 14*24*time.Hour),
+			Expiries:      getEnvExpiries("CREDENTIALS_EXPIRIES"),
+		},
 	}
 
 	return config, nil
@@ -274,6 +1153,15 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatVal
+		}
+	}
+	return defaultValue
+}
+
 func getEnvBool(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
 		return strings.ToLower(value) == "true" || value == "1"
@@ -289,3 +1177,333 @@ func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	}
 	return defaultValue
 }
+
+func getEnvList(key string, defaultValue []string) []string {
+	if value := os.Getenv(key); value != "" {
+		parts := strings.Split(value, ",")
+		result := make([]string, 0, len(parts))
+		for _, part := range parts {
+			if trimmed := strings.TrimSpace(part); trimmed != "" {
+				result = append(result, trimmed)
+			}
+		}
+		return result
+	}
+	return defaultValue
+}
+
+// getEnvFrequencyCaps parses caps written as category[/channel]=limit/period[:action],
+// e.g. "marketing/email=3/day:reschedule,marketing=10/week". Periods are
+// "day", "week" or a duration. Malformed entries are skipped.
+func getEnvFrequencyCaps(key string) []FrequencyCap {
+	var caps []FrequencyCap
+	for _, entry := range getEnvList(key, nil) {
+		scope, rule, found := strings.Cut(entry, "=")
+		if !found {
+			continue
+		}
+
+		var frequencyCap FrequencyCap
+		frequencyCap.Category, frequencyCap.Channel, _ = strings.Cut(scope, "/")
+		rule, frequencyCap.Action, _ = strings.Cut(rule, ":")
+
+		limit, period, found := strings.Cut(rule, "/")
+		if !found {
+			continue
+		}
+		var err error
+		if frequencyCap.Limit, err = strconv.Atoi(limit); err != nil {
+			continue
+		}
+		switch period {
+		case "day":
+			frequencyCap.Period = 24 * time.Hour
+		case "week":
+			frequencyCap.Period = 7 * 24 * time.Hour
+		default:
+			if frequencyCap.Period, err = time.ParseDuration(period); err != nil {
+				continue
+			}
+		}
+
+		caps = append(caps, frequencyCap)
+	}
+	return caps
+}
+
+// getEnvAPIKeys parses API keys written as subject:role+role:key, e.g.
+// "billing:sender:s3cret,ops:admin+viewer:t0ps3cret". Malformed entries are
+// skipped.
+func getEnvAPIKeys(key string) []APIKeyConfig {
+	var keys []APIKeyConfig
+	for _, entry := range getEnvList(key, nil) {
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			continue
+		}
+		keys = append(keys, APIKeyConfig{Subject: parts[0], Roles: strings.Split(parts[1], "+"), Key: parts[2]})
+	}
+	return keys
+}
+
+// getEnvClientIdentities parses identities written as san=tenant:role+role,
+// e.g. "spiffe://mesh.local/ns/acme/*=acme:sender,ops.internal=:admin".
+// The tenant may be empty. Malformed entries are skipped.
+func getEnvClientIdentities(key string) []ClientIdentityConfig {
+	var identities []ClientIdentityConfig
+	for _, entry := range getEnvList(key, nil) {
+		separator := strings.LastIndex(entry, "=")
+		if separator <= 0 {
+			continue
+		}
+		tenant, roles, found := strings.Cut(entry[separator+1:], ":")
+		if !found || roles == "" {
+			continue
+		}
+		identities = append(identities, ClientIdentityConfig{SAN: entry[:separator], Tenant: tenant, Roles: strings.Split(roles, "+")})
+	}
+	return identities
+}
+
+// getEnvRoleMapping parses a mapping written as value=role+role, e.g.
+// "notify-admins=admin,marketing=sender+viewer". Malformed entries are
+// skipped.
+func getEnvRoleMapping(key string) map[string][]string {
+	var mapping map[string][]string
+	for _, entry := range getEnvList(key, nil) {
+		value, roles, found := strings.Cut(entry, "=")
+		value = strings.TrimSpace(value)
+		if !found || value == "" || roles == "" {
+			continue
+		}
+		if mapping == nil {
+			mapping = make(map[string][]string)
+		}
+		mapping[value] = strings.Split(roles, "+")
+	}
+	return mapping
+}
+
+// getEnvDurations parses durations written as name=duration, e.g.
+// "transactional=5m,marketing=1h". Malformed entries are skipped.
+func getEnvDurations(key string) map[string]time.Duration {
+	var durations map[string]time.Duration
+	for _, entry := range getEnvList(key, nil) {
+		name, value, found := strings.Cut(entry, "=")
+		if !found {
+			continue
+		}
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			continue
+		}
+		if durations == nil {
+			durations = make(map[string]time.Duration)
+		}
+		durations[name] = duration
+	}
+	return durations
+}
+
+// getEnvSchedules parses schedules written as name=expression, separated by
+// semicolons since cron expressions contain commas, e.g.
+// "retention=0 3 * * *;token_cleanup=@daily". Malformed entries are skipped.
+func getEnvSchedules(key string) map[string]string {
+	var schedules map[string]string
+	for _, entry := range strings.Split(os.Getenv(key), ";") {
+		name, expression, found := strings.Cut(entry, "=")
+		name, expression = strings.TrimSpace(name), strings.TrimSpace(expression)
+		if !found || name == "" || expression == "" {
+			continue
+		}
+		if schedules == nil {
+			schedules = make(map[string]string)
+		}
+		schedules[name] = expression
+	}
+	return schedules
+}
+
+// getEnvRateLimits parses limits written as name=per-second[/burst], e.g.
+// "twilio=10/20,sendgrid=100". Malformed entries are skipped.
+func getEnvRateLimits(key string) map[string]RateLimit {
+	var limits map[string]RateLimit
+	for _, entry := range getEnvList(key, nil) {
+		name, rule, found := strings.Cut(entry, "=")
+		if !found {
+			continue
+		}
+		rate, burst, _ := strings.Cut(rule, "/")
+
+		var limit RateLimit
+		var err error
+		if limit.PerSecond, err = strconv.ParseFloat(rate, 64); err != nil {
+			continue
+		}
+		if burst != "" {
+			if limit.Burst, err = strconv.Atoi(burst); err != nil {
+				continue
+			}
+		}
+
+		if limits == nil {
+			limits = make(map[string]RateLimit)
+		}
+		limits[name] = limit
+	}
+	return limits
+}
+
+// getEnvExpiries parses dates written as name=date, e.g.
+// "push=2025-06-01,email=2025-09-30T12:00:00Z". Malformed entries are skipped.
+func getEnvExpiries(key string) map[string]time.Time {
+	var expiries map[string]time.Time
+	for _, entry := range getEnvList(key, nil) {
+		name, value, found := strings.Cut(entry, "=")
+		if !found {
+			continue
+		}
+		expiry, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			if expiry, err = time.Parse(time.DateOnly, value); err != nil {
+				continue
+			}
+		}
+		if expiries == nil {
+			expiries = make(map[string]time.Time)
+		}
+		expiries[name] = expiry
+	}
+	return expiries
+}
+
+// getEnvProviderRollouts parses rollouts written as channel=provider:percent,
+// e.g. "sms=twilio-v2:10,email=ses:5". Malformed entries are skipped.
+func getEnvProviderRollouts(key string) []ProviderRollout {
+	var rollouts []ProviderRollout
+	for _, entry := range getEnvList(key, nil) {
+		channel, rule, found := strings.Cut(entry, "=")
+		if !found {
+			continue
+		}
+		provider, percent, found := strings.Cut(rule, ":")
+		if !found || provider == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(percent, 64)
+		if err != nil {
+			continue
+		}
+		rollouts = append(rollouts, ProviderRollout{Channel: strings.ToLower(channel), Provider: provider, Percent: value})
+	}
+	return rollouts
+}
+
+// getEnvCountryRoutes parses routes written as country=provider+provider,
+// e.g. "IN=msg91+twilio,US=twilio,*=twilio". Malformed entries are skipped.
+func getEnvCountryRoutes(key string) map[string][]string {
+	var routes map[string][]string
+	for _, entry := range getEnvList(key, nil) {
+		country, names, found := strings.Cut(entry, "=")
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if !found || country == "" || names == "" {
+			continue
+		}
+		if routes == nil {
+			routes = make(map[string][]string)
+		}
+		routes[country] = strings.Split(names, "+")
+	}
+	return routes
+}
+
+// getEnvEmailDomainLimits parses limits written as domain=per-second[/concurrency],
+// e.g. "gmail.com=10/5,*=50". Malformed entries are skipped.
+func getEnvEmailDomainLimits(key string) map[string]EmailDomainLimit {
+	var limits map[string]EmailDomainLimit
+	for _, entry := range getEnvList(key, nil) {
+		domain, rule, found := strings.Cut(entry, "=")
+		if !found {
+			continue
+		}
+		rate, concurrency, _ := strings.Cut(rule, "/")
+
+		var limit EmailDomainLimit
+		var err error
+		if limit.PerSecond, err = strconv.ParseFloat(rate, 64); err != nil {
+			continue
+		}
+		if concurrency != "" {
+			if limit.Concurrency, err = strconv.Atoi(concurrency); err != nil {
+				continue
+			}
+		}
+
+		if limits == nil {
+			limits = make(map[string]EmailDomainLimit)
+		}
+		limits[domain] = limit
+	}
+	return limits
+}
+
+// getEnvWarmupDomains parses warm-ups written as domain=start-date, e.g.
+// "news.example.com=2024-06-01,203.0.113.7=2024-06-15". Every domain ramps
+// through dailyLimits. Malformed entries are skipped.
+func getEnvWarmupDomains(key string, dailyLimits []int) []WarmupDomain {
+	var domains []WarmupDomain
+	for _, entry := range getEnvList(key, nil) {
+		domain, start, found := strings.Cut(entry, "=")
+		if !found {
+			continue
+		}
+		startDate, err := time.Parse(time.DateOnly, start)
+		if err != nil {
+			continue
+		}
+
+		domains = append(domains, WarmupDomain{Domain: domain, Start: startDate, DailyLimits: dailyLimits})
+	}
+	return domains
+}
+
+// getEnvEmailRoutes parses routes written as
+// name=categories/provider/from-domain@sending-ip, with categories joined by
+// "+" and every part after the categories optional, e.g.
+// "otp=transactional+security/ses/auth.example.com,news=marketing//news.example.com@203.0.113.7".
+// Malformed entries are skipped.
+func getEnvEmailRoutes(key string) []EmailRoute {
+	var routes []EmailRoute
+	for _, entry := range getEnvList(key, nil) {
+		name, rule, found := strings.Cut(entry, "=")
+		if !found || name == "" {
+			continue
+		}
+		rule, sendingIP, _ := strings.Cut(rule, "@")
+		parts := strings.Split(rule, "/")
+		if parts[0] == "" || len(parts) > 3 {
+			continue
+		}
+
+		route := EmailRoute{Name: name, Categories: strings.Split(parts[0], "+"), SendingIP: sendingIP}
+		if len(parts) > 1 {
+			route.Provider = parts[1]
+		}
+		if len(parts) > 2 {
+			route.FromDomain = parts[2]
+		}
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+// getEnvIntList parses a comma-separated list of integers, skipping malformed ones
+func getEnvIntList(key string) []int {
+	var values []int
+	for _, entry := range getEnvList(key, nil) {
+		if value, err := strconv.Atoi(entry); err == nil {
+			values = append(values, value)
+		}
+	}
+	return values
+}
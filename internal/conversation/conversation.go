@@ -0,0 +1,252 @@
+// Package conversation threads outbound and inbound SMS into two-way
+// conversations. A conversation is the messages between one recipient and
+// one of our numbers; after a window without messages, the next message
+// starts a new conversation. Support and OTP-resend flows read the history
+// to see what a recipient was sent and what they replied.
+package conversation
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pipeline"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// MiddlewareName is the name the store's middleware is registered under
+const MiddlewareName = "conversation"
+
+// defaultWindow is the inactivity after which a new conversation starts
+const defaultWindow = 24 * time.Hour
+
+// Direction is whether a message was sent or received
+type Direction string
+
+const (
+	DirectionOutbound Direction = "outbound"
+	DirectionInbound  Direction = "inbound"
+)
+
+// Message is a message of a conversation
+type Message struct {
+	ID             string    `json:"id"`
+	Direction      Direction `json:"direction"`
+	Body           string    `json:"body"`
+	NotificationID string    `json:"notification_id,omitempty"` // outbound messages
+	ProviderID     string    `json:"provider_id,omitempty"`     // inbound messages, e.g. a Twilio MessageSid
+	At             time.Time `json:"at"`
+}
+
+// Conversation is the messages between a recipient and one of our numbers
+type Conversation struct {
+	ID            string    `json:"id"`
+	Participant   string    `json:"participant"`    // the recipient's phone number
+	ServiceNumber string    `json:"service_number"` // our number
+	StartedAt     time.Time `json:"started_at"`
+	LastMessageAt time.Time `json:"last_message_at"`
+	Messages      []Message `json:"messages"` // oldest first
+}
+
+// LastOutbound returns the most recent message sent in the conversation
+func (c Conversation) LastOutbound() (Message, bool) {
+	for i := len(c.Messages) - 1; i >= 0; i-- {
+		if c.Messages[i].Direction == DirectionOutbound {
+			return c.Messages[i], true
+		}
+	}
+	return Message{}, false
+}
+
+// Store is an in-memory store of conversations. It is safe for concurrent use.
+type Store struct {
+	window        time.Duration
+	serviceNumber string
+
+	mu            sync.RWMutex
+	conversations map[string]*Conversation
+	current       map[string]*Conversation // the latest conversation of each number pair
+	byParticipant map[string][]*Conversation
+	now           func() time.Time
+}
+
+// NewStore creates an empty conversation store
+func NewStore(cfg config.ConversationConfig) *Store {
+	window := cfg.Window
+	if window <= 0 {
+		window = defaultWindow
+	}
+
+	return &Store{
+		window:        window,
+		serviceNumber: normalizePhone(cfg.ServiceNumber),
+		conversations: make(map[string]*Conversation),
+		current:       make(map[string]*Conversation),
+		byParticipant: make(map[string][]*Conversation),
+		now:           time.Now,
+	}
+}
+
+// RecordOutbound records an SMS sent to a participant. Sends without a
+// numeric sender, such as from alphanumeric sender IDs, are recorded under
+// the configured service number.
+func (s *Store) RecordOutbound(notificationID uuid.UUID, serviceNumber, participant, body string) Conversation {
+	if !isPhoneNumber(serviceNumber) {
+		serviceNumber = s.serviceNumber
+	}
+
+	message := Message{Direction: DirectionOutbound, Body: body}
+	if notificationID != uuid.Nil {
+		message.NotificationID = notificationID.String()
+	}
+	return s.record(serviceNumber, participant, message)
+}
+
+// RecordInbound records an SMS received from a participant
+func (s *Store) RecordInbound(participant, serviceNumber, body, providerID string) Conversation {
+	if serviceNumber == "" {
+		serviceNumber = s.serviceNumber
+	}
+	return s.record(serviceNumber, participant, Message{Direction: DirectionInbound, Body: body, ProviderID: providerID})
+}
+
+// Get returns a conversation
+func (s *Store) Get(id string) (Conversation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	conversation, exists := s.conversations[id]
+	if !exists {
+		return Conversation{}, errors.NewNotificationError(errors.ErrorCodeNotFound, "conversation not found: "+id)
+	}
+	return copyConversation(conversation), nil
+}
+
+// ForParticipant returns a participant's conversations with any of our
+// numbers, most recent first
+func (s *Store) ForParticipant(participant string) []Conversation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored := s.byParticipant[normalizePhone(participant)]
+	conversations := make([]Conversation, len(stored))
+	for i, conversation := range stored {
+		conversations[i] = copyConversation(conversation)
+	}
+	sort.SliceStable(conversations, func(i, j int) bool {
+		return conversations[i].LastMessageAt.After(conversations[j].LastMessageAt)
+	})
+	return conversations
+}
+
+// Current returns the open conversation between a participant and one of
+// our numbers, if its window has not passed
+func (s *Store) Current(participant, serviceNumber string) (Conversation, bool) {
+	if serviceNumber == "" {
+		serviceNumber = s.serviceNumber
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	conversation, exists := s.current[pairKey(normalizePhone(participant), normalizePhone(serviceNumber))]
+	if !exists || s.now().Sub(conversation.LastMessageAt) > s.window {
+		return Conversation{}, false
+	}
+	return copyConversation(conversation), true
+}
+
+// Middleware returns the send middleware recording sent SMS. Register it at
+// pipeline.StageTemplate under MiddlewareName, so rendered bodies are recorded.
+func (s *Store) Middleware(logger interfaces.Logger) pipeline.Middleware {
+	return func(next pipeline.Handler) pipeline.Handler {
+		return func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+			response, err := next(ctx, request)
+			if err != nil || request.Type != models.NotificationTypeSMS {
+				return response, err
+			}
+
+			participant := request.Recipient
+			if request.SMSData != nil && request.SMSData.PhoneNumber != "" {
+				participant = request.SMSData.PhoneNumber
+			}
+			conversation := s.RecordOutbound(response.ID, "", participant, request.Body)
+			logger.Debugf("SMS %s recorded in conversation %s", response.ID, conversation.ID)
+			return response, nil
+		}
+	}
+}
+
+// record adds a message to the current conversation of a number pair,
+// starting a new one when there is none or its window has passed
+func (s *Store) record(serviceNumber, participant string, message Message) Conversation {
+	serviceNumber, participant = normalizePhone(serviceNumber), normalizePhone(participant)
+	key := pairKey(participant, serviceNumber)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	conversation, exists := s.current[key]
+	if !exists || now.Sub(conversation.LastMessageAt) > s.window {
+		conversation = &Conversation{
+			ID:            uuid.New().String(),
+			Participant:   participant,
+			ServiceNumber: serviceNumber,
+			StartedAt:     now,
+		}
+		s.conversations[conversation.ID] = conversation
+		s.current[key] = conversation
+		s.byParticipant[participant] = append(s.byParticipant[participant], conversation)
+	}
+
+	message.ID = uuid.New().String()
+	message.At = now
+	conversation.Messages = append(conversation.Messages, message)
+	conversation.LastMessageAt = now
+	return copyConversation(conversation)
+}
+
+// copyConversation returns a copy callers cannot use to change the store
+func copyConversation(conversation *Conversation) Conversation {
+	copied := *conversation
+	copied.Messages = append([]Message(nil), conversation.Messages...)
+	return copied
+}
+
+// pairKey identifies a number pair
+func pairKey(participant, serviceNumber string) string {
+	return participant + "|" + serviceNumber
+}
+
+// normalizePhone strips the formatting of a phone number, keeping a leading +
+func normalizePhone(phoneNumber string) string {
+	var normalized strings.Builder
+	for i, r := range strings.TrimSpace(phoneNumber) {
+		if r >= '0' && r <= '9' || r == '+' && i == 0 {
+			normalized.WriteRune(r)
+		}
+	}
+	return normalized.String()
+}
+
+// isPhoneNumber reports whether a sender is a number rather than an
+// alphanumeric sender ID
+func isPhoneNumber(sender string) bool {
+	sender = strings.TrimSpace(sender)
+	if sender == "" {
+		return false
+	}
+	for _, r := range sender {
+		if !(r >= '0' && r <= '9' || strings.ContainsRune("+-() ", r)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,116 @@
+package conversation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestStore_ThreadsByNumberPair(t *testing.T) {
+	store, _ := createTestStore()
+	notificationID := uuid.New()
+
+	sent := store.RecordOutbound(notificationID, "+1 (415) 555-0100", "+14155550123", "Your code is 123456")
+	reply := store.RecordInbound("+1 415-555-0123", "+14155550100", "Didn't get it", "SM1")
+	assert.Equal(t, sent.ID, reply.ID)
+
+	conversation, err := store.Get(sent.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "+14155550123", conversation.Participant)
+	assert.Equal(t, "+14155550100", conversation.ServiceNumber)
+	require.Len(t, conversation.Messages, 2)
+	assert.Equal(t, DirectionOutbound, conversation.Messages[0].Direction)
+	assert.Equal(t, notificationID.String(), conversation.Messages[0].NotificationID)
+	assert.Equal(t, DirectionInbound, conversation.Messages[1].Direction)
+	assert.Equal(t, "SM1", conversation.Messages[1].ProviderID)
+
+	// Another of our numbers is another conversation
+	other := store.RecordOutbound(uuid.New(), "+14155550199", "+14155550123", "Your order shipped")
+	assert.NotEqual(t, sent.ID, other.ID)
+}
+
+func TestStore_Window(t *testing.T) {
+	store, clock := createTestStore()
+
+	first := store.RecordOutbound(uuid.New(), "+14155550100", "+14155550123", "Your code is 123456")
+	*clock = clock.Add(59 * time.Minute)
+	same := store.RecordInbound("+14155550123", "+14155550100", "Thanks", "")
+	assert.Equal(t, first.ID, same.ID)
+
+	_, open := store.Current("+14155550123", "+14155550100")
+	assert.True(t, open)
+
+	*clock = clock.Add(61 * time.Minute)
+	_, open = store.Current("+14155550123", "+14155550100")
+	assert.False(t, open)
+
+	next := store.RecordInbound("+14155550123", "+14155550100", "Hello again", "")
+	assert.NotEqual(t, first.ID, next.ID)
+
+	conversations := store.ForParticipant("+14155550123")
+	require.Len(t, conversations, 2)
+	assert.Equal(t, next.ID, conversations[0].ID)
+	assert.Equal(t, first.ID, conversations[1].ID)
+}
+
+func TestStore_ServiceNumberFallback(t *testing.T) {
+	store, _ := createTestStore()
+
+	// Alphanumeric senders cannot be replied to; replies come to the service number
+	sent := store.RecordOutbound(uuid.New(), "ACME", "+14155550123", "Your code is 123456")
+	assert.Equal(t, "+14155550100", sent.ServiceNumber)
+
+	reply := store.RecordInbound("+14155550123", "", "Resend please", "")
+	assert.Equal(t, sent.ID, reply.ID)
+}
+
+func TestConversation_LastOutbound(t *testing.T) {
+	store, _ := createTestStore()
+
+	store.RecordOutbound(uuid.New(), "", "+14155550123", "Your code is 111111")
+	store.RecordOutbound(uuid.New(), "", "+14155550123", "Your code is 222222")
+	conversation := store.RecordInbound("+14155550123", "", "RESEND", "")
+
+	last, ok := conversation.LastOutbound()
+	require.True(t, ok)
+	assert.Equal(t, "Your code is 222222", last.Body)
+
+	_, ok = Conversation{}.LastOutbound()
+	assert.False(t, ok)
+}
+
+func TestStore_ReturnsCopies(t *testing.T) {
+	store, _ := createTestStore()
+
+	conversation := store.RecordOutbound(uuid.New(), "", "+14155550123", "Hello")
+	conversation.Messages[0].Body = "changed"
+
+	stored, err := store.Get(conversation.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", stored.Messages[0].Body)
+}
+
+func TestStore_GetNotFound(t *testing.T) {
+	store, _ := createTestStore()
+
+	_, err := store.Get("missing")
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeNotFound, notifErr.Code)
+	assert.Empty(t, store.ForParticipant("+14155550123"))
+}
+
+// Helper functions
+
+func createTestStore() (*Store, *time.Time) {
+	clock := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	store := NewStore(config.ConversationConfig{Window: time.Hour, ServiceNumber: "+1 415 555 0100"})
+	store.now = func() time.Time { return clock }
+	return store, &clock
+}
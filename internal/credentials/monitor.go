@@ -0,0 +1,315 @@
+// Package credentials monitors the credentials of notification providers. A
+// monitor validates them in the background, alerts when they are rejected
+// or about to expire, and rotates them without stopping sends.
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// AlertKind is the credential state an alert reports
+type AlertKind string
+
+// Alert kinds
+const (
+	AlertInvalid   AlertKind = "invalid"   // the provider rejected the credentials
+	AlertExpiring  AlertKind = "expiring"  // the credentials expire within the warning period
+	AlertExpired   AlertKind = "expired"   // the credentials are past their expiry
+	AlertRecovered AlertKind = "recovered" // the credentials are valid again
+)
+
+// Alert reports a change in the state of a channel's credentials
+type Alert struct {
+	Channel   models.NotificationType `json:"channel"`
+	Provider  string                  `json:"provider"`
+	Kind      AlertKind               `json:"kind"`
+	ExpiresAt *time.Time              `json:"expires_at,omitempty"`
+	Message   string                  `json:"message"`
+	At        time.Time               `json:"at"`
+}
+
+// Status is the latest check of a channel's credentials
+type Status struct {
+	Channel   models.NotificationType `json:"channel"`
+	Provider  string                  `json:"provider"`
+	Valid     bool                    `json:"valid"`
+	ExpiresAt *time.Time              `json:"expires_at,omitempty"`
+	Detail    string                  `json:"detail,omitempty"`
+	Error     string                  `json:"error,omitempty"` // why the check could not be made
+	CheckedAt time.Time               `json:"checked_at"`
+	RotatedAt *time.Time              `json:"rotated_at,omitempty"`
+}
+
+// ProviderLister lists the providers to monitor, typically the dispatcher
+type ProviderLister interface {
+	ListProviders() map[models.NotificationType]interfaces.NotificationProvider
+}
+
+// Monitor checks provider credentials periodically. Providers implementing
+// interfaces.CredentialChecker are checked against their API; others count
+// as valid while IsHealthy passes. Alerts fire only when a channel's state
+// changes, not on every check.
+type Monitor struct {
+	providers ProviderLister
+	config    config.CredentialsConfig
+	logger    interfaces.Logger
+
+	mu       sync.Mutex
+	expiries map[models.NotificationType]time.Time
+	statuses map[models.NotificationType]*Status
+	states   map[models.NotificationType]AlertKind
+	alert    func(Alert)
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	running  bool
+	now      func() time.Time
+}
+
+// NewMonitor creates a credential monitor
+func NewMonitor(providers ProviderLister, cfg config.CredentialsConfig, logger interfaces.Logger) *Monitor {
+	m := &Monitor{
+		providers: providers,
+		config:    cfg,
+		logger:    logger,
+		expiries:  make(map[models.NotificationType]time.Time, len(cfg.Expiries)),
+		statuses:  make(map[models.NotificationType]*Status),
+		states:    make(map[models.NotificationType]AlertKind),
+		now:       time.Now,
+	}
+	for channel, expiry := range cfg.Expiries {
+		m.expiries[models.NotificationType(channel)] = expiry
+	}
+	return m
+}
+
+// SetAlertHandler sets the function alerts are passed to. Without one
+// alerts are only logged. The handler must not block.
+func (m *Monitor) SetAlertHandler(handler func(Alert)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.alert = handler
+}
+
+// Start checks the credentials immediately and then every check interval
+// until Stop is called. Calling Start on a running monitor has no effect.
+func (m *Monitor) Start(ctx context.Context) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.running {
+		return
+	}
+
+	interval := m.config.CheckInterval
+	if interval <= 0 {
+		interval = 15 * time.Minute
+	}
+
+	ctx, m.cancel = context.WithCancel(ctx)
+	m.running = true
+
+	m.wg.Add(1)
+	go m.run(ctx, interval)
+
+	m.logger.Infof("Started credential monitor (every %s)", interval)
+}
+
+// Stop stops the monitor and waits for a check in progress to finish
+func (m *Monitor) Stop() {
+	m.mu.Lock()
+	if !m.running {
+		m.mu.Unlock()
+		return
+	}
+	m.cancel()
+	m.running = false
+	m.mu.Unlock()
+
+	m.wg.Wait()
+	m.logger.Infof("Stopped credential monitor")
+}
+
+// CheckOnce checks the credentials of every provider and returns their
+// statuses, sorted by channel
+func (m *Monitor) CheckOnce(ctx context.Context) []Status {
+	providers := m.providers.ListProviders()
+	for channel, provider := range providers {
+		m.check(ctx, channel, provider)
+	}
+	return m.Statuses()
+}
+
+// Statuses returns the latest status of each checked channel, sorted by channel
+func (m *Monitor) Statuses() []Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make([]Status, 0, len(m.statuses))
+	for _, status := range m.statuses {
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Channel < statuses[j].Channel })
+	return statuses
+}
+
+// Rotate switches a channel's provider to new credentials, which the
+// provider validates before using them, then checks the channel again.
+// expiresAt replaces the configured expiry of the old credentials; nil
+// means the new ones do not expire. This is the hook a configuration
+// reload calls with changed credentials.
+func (m *Monitor) Rotate(ctx context.Context, channel models.NotificationType, credentials map[string]string, expiresAt *time.Time) error {
+	provider, exists := m.providers.ListProviders()[channel]
+	if !exists {
+		return errors.NewNotificationError(errors.ErrorCodeProviderNotFound, fmt.Sprintf("no provider for channel: %s", channel))
+	}
+	rotator, ok := provider.(interfaces.CredentialRotator)
+	if !ok {
+		return errors.NewNotificationError(errors.ErrorCodeProviderConfiguration, fmt.Sprintf("provider of %s does not support credential rotation", channel))
+	}
+
+	if err := rotator.RotateCredentials(ctx, credentials); err != nil {
+		m.logger.Errorf("Rotating the %s credentials failed, keeping the current ones: %v", channel, err)
+		return err
+	}
+
+	m.mu.Lock()
+	if expiresAt != nil {
+		m.expiries[channel] = *expiresAt
+	} else {
+		delete(m.expiries, channel)
+	}
+	m.mu.Unlock()
+
+	m.logger.Infof("Rotated the %s credentials", channel)
+	status := m.check(ctx, channel, provider)
+
+	m.mu.Lock()
+	rotatedAt := status.CheckedAt
+	m.statuses[channel].RotatedAt = &rotatedAt
+	m.mu.Unlock()
+	return nil
+}
+
+// run checks on every tick until the context is cancelled
+func (m *Monitor) run(ctx context.Context, interval time.Duration) {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		m.CheckOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check checks one channel, records its status and alerts when its state changed
+func (m *Monitor) check(ctx context.Context, channel models.NotificationType, provider interfaces.NotificationProvider) Status {
+	status := Status{Channel: channel, Provider: provider.GetConfig().Name}
+
+	if checker, ok := provider.(interfaces.CredentialChecker); ok {
+		result, err := checker.CheckCredentials(ctx)
+		if err != nil {
+			status.Error = err.Error()
+		} else {
+			status.Valid = result.Valid
+			status.ExpiresAt = result.ExpiresAt
+			status.Detail = result.Detail
+		}
+	} else if err := provider.IsHealthy(ctx); err != nil {
+		status.Detail = err.Error()
+	} else {
+		status.Valid = true
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	status.CheckedAt = now
+	if status.ExpiresAt == nil {
+		if expiry, exists := m.expiries[channel]; exists {
+			status.ExpiresAt = &expiry
+		}
+	}
+	if previous, exists := m.statuses[channel]; exists {
+		status.RotatedAt = previous.RotatedAt
+	}
+	m.statuses[channel] = &status
+
+	// A check that could not be made says nothing about the credentials
+	if status.Error != "" {
+		m.logger.Warnf("Could not check the %s credentials: %s", channel, status.Error)
+		return status
+	}
+
+	state := m.state(status, now)
+	previous := m.states[channel]
+	if state == previous {
+		return status
+	}
+	m.states[channel] = state
+
+	if state == "" {
+		if previous != "" {
+			m.emit(Alert{Channel: channel, Provider: status.Provider, Kind: AlertRecovered, ExpiresAt: status.ExpiresAt,
+				Message: fmt.Sprintf("%s credentials are valid again", channel), At: now})
+		}
+		return status
+	}
+
+	alert := Alert{Channel: channel, Provider: status.Provider, Kind: state, ExpiresAt: status.ExpiresAt, At: now}
+	switch state {
+	case AlertInvalid:
+		alert.Message = fmt.Sprintf("%s credentials were rejected: %s", channel, status.Detail)
+	case AlertExpired:
+		alert.Message = fmt.Sprintf("%s credentials expired at %s", channel, status.ExpiresAt.Format(time.RFC3339))
+	case AlertExpiring:
+		alert.Message = fmt.Sprintf("%s credentials expire at %s", channel, status.ExpiresAt.Format(time.RFC3339))
+	}
+	m.emit(alert)
+	return status
+}
+
+// state returns the alert kind a status is in, or an empty kind when the
+// credentials are fine
+func (m *Monitor) state(status Status, now time.Time) AlertKind {
+	switch {
+	case !status.Valid:
+		return AlertInvalid
+	case status.ExpiresAt == nil:
+		return ""
+	case !now.Before(*status.ExpiresAt):
+		return AlertExpired
+	case status.ExpiresAt.Sub(now) <= m.config.ExpiryWarning:
+		return AlertExpiring
+	}
+	return ""
+}
+
+// emit logs an alert and passes it to the handler. Callers hold the lock.
+func (m *Monitor) emit(alert Alert) {
+	if alert.Kind == AlertRecovered {
+		m.logger.Infof("Credential alert: %s", alert.Message)
+	} else {
+		m.logger.Warnf("Credential alert: %s", alert.Message)
+	}
+	if m.alert != nil {
+		m.alert(alert)
+	}
+}
@@ -0,0 +1,237 @@
+package credentials
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+func TestMonitor_CheckOnce(t *testing.T) {
+	voice := &testProvider{token: "secret"}
+	email := providers.NewMockEmailProvider(config.EmailProviderConfig{Provider: "mock"})
+	monitor, alerts := createTestMonitor(config.CredentialsConfig{}, voice, email)
+
+	statuses := monitor.CheckOnce(context.Background())
+
+	require.Len(t, statuses, 2)
+	assert.Equal(t, models.NotificationTypeEmail, statuses[0].Channel)
+	assert.True(t, statuses[0].Valid)
+	assert.Equal(t, models.NotificationTypeVoice, statuses[1].Channel)
+	assert.True(t, statuses[1].Valid)
+	assert.Equal(t, "balance 5.00 USD", statuses[1].Detail)
+	assert.Empty(t, alerts.get())
+}
+
+func TestMonitor_AlertsOnStateChange(t *testing.T) {
+	voice := &testProvider{token: "secret"}
+	monitor, alerts := createTestMonitor(config.CredentialsConfig{}, voice)
+	ctx := context.Background()
+
+	voice.setToken("revoked")
+	monitor.CheckOnce(ctx)
+	monitor.CheckOnce(ctx)
+
+	// A failed check neither alerts nor clears the alert
+	voice.setFailing(true)
+	monitor.CheckOnce(ctx)
+	assert.NotEmpty(t, monitor.Statuses()[0].Error)
+	voice.setFailing(false)
+	monitor.CheckOnce(ctx)
+
+	voice.setToken("secret")
+	monitor.CheckOnce(ctx)
+
+	got := alerts.get()
+	require.Len(t, got, 2)
+	assert.Equal(t, AlertInvalid, got[0].Kind)
+	assert.Equal(t, "Test Voice Provider", got[0].Provider)
+	assert.Contains(t, got[0].Message, "rejected")
+	assert.Equal(t, AlertRecovered, got[1].Kind)
+}
+
+func TestMonitor_Expiry(t *testing.T) {
+	now := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	push := providers.NewMockPushProvider(config.PushProviderConfig{Provider: "mock"})
+	monitor, alerts := createTestMonitor(config.CredentialsConfig{
+		ExpiryWarning: 14 * 24 * time.Hour,
+		Expiries:      map[string]time.Time{"push": time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)},
+	}, push)
+	ctx := context.Background()
+
+	monitor.now = func() time.Time { return now }
+	monitor.CheckOnce(ctx)
+	assert.Empty(t, alerts.get())
+
+	now = time.Date(2025, 5, 20, 0, 0, 0, 0, time.UTC)
+	monitor.CheckOnce(ctx)
+	monitor.CheckOnce(ctx)
+	now = time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	monitor.CheckOnce(ctx)
+
+	got := alerts.get()
+	require.Len(t, got, 2)
+	assert.Equal(t, AlertExpiring, got[0].Kind)
+	assert.Equal(t, AlertExpired, got[1].Kind)
+	require.NotNil(t, got[1].ExpiresAt)
+}
+
+func TestMonitor_Rotate(t *testing.T) {
+	voice := &testProvider{token: "secret"}
+	email := providers.NewMockEmailProvider(config.EmailProviderConfig{Provider: "mock"})
+	expiry := time.Now().Add(time.Hour)
+	monitor, alerts := createTestMonitor(config.CredentialsConfig{
+		ExpiryWarning: 24 * time.Hour,
+		Expiries:      map[string]time.Time{"voice": expiry},
+	}, voice, email)
+	ctx := context.Background()
+
+	monitor.CheckOnce(ctx)
+	require.Len(t, alerts.get(), 1)
+
+	err := monitor.Rotate(ctx, models.NotificationTypeVoice, map[string]string{"auth_token": ""}, nil)
+	assert.Error(t, err)
+	assert.Equal(t, "secret", voice.currentToken())
+
+	require.NoError(t, monitor.Rotate(ctx, models.NotificationTypeVoice, map[string]string{"auth_token": "rotated"}, nil))
+	assert.Equal(t, "rotated", voice.currentToken())
+
+	status := monitor.Statuses()[1]
+	assert.True(t, status.Valid)
+	assert.Nil(t, status.ExpiresAt)
+	assert.NotNil(t, status.RotatedAt)
+	assert.Equal(t, AlertRecovered, alerts.get()[1].Kind)
+
+	err = monitor.Rotate(ctx, models.NotificationTypeEmail, map[string]string{"api_key": "new"}, nil)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeProviderConfiguration, notifErr.Code)
+
+	err = monitor.Rotate(ctx, models.NotificationTypeSMS, nil, nil)
+	notifErr, ok = errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeProviderNotFound, notifErr.Code)
+}
+
+func TestMonitor_StartStop(t *testing.T) {
+	voice := &testProvider{token: "secret"}
+	monitor, _ := createTestMonitor(config.CredentialsConfig{CheckInterval: time.Hour}, voice)
+
+	monitor.Start(context.Background())
+	monitor.Start(context.Background())
+
+	require.Eventually(t, func() bool { return len(monitor.Statuses()) == 1 }, time.Second, 5*time.Millisecond)
+
+	monitor.Stop()
+	monitor.Stop()
+}
+
+// Helper functions
+
+// testProvider is a voice provider whose credentials are a token, valid
+// when it is "secret" or "rotated"
+type testProvider struct {
+	mu      sync.Mutex
+	token   string
+	failing bool
+}
+
+func (p *testProvider) Send(context.Context, *models.Notification) (*models.NotificationResponse, error) {
+	return &models.NotificationResponse{Status: models.StatusSent}, nil
+}
+
+func (p *testProvider) GetType() models.NotificationType { return models.NotificationTypeVoice }
+
+func (p *testProvider) IsHealthy(context.Context) error { return nil }
+
+func (p *testProvider) GetConfig() interfaces.ProviderConfig {
+	return interfaces.ProviderConfig{Name: "Test Voice Provider", Type: models.NotificationTypeVoice}
+}
+
+func (p *testProvider) CheckCredentials(context.Context) (*interfaces.CredentialStatus, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.failing {
+		return nil, errors.NewNotificationError(errors.ErrorCodeProviderUnavailable, "provider unreachable")
+	}
+	if p.token != "secret" && p.token != "rotated" {
+		return &interfaces.CredentialStatus{Detail: "status 401"}, nil
+	}
+	return &interfaces.CredentialStatus{Valid: true, Detail: "balance 5.00 USD"}, nil
+}
+
+func (p *testProvider) RotateCredentials(_ context.Context, credentials map[string]string) error {
+	if credentials["auth_token"] == "" {
+		return errors.NewValidationError("auth_token", "auth token is required")
+	}
+	p.setToken(credentials["auth_token"])
+	return nil
+}
+
+func (p *testProvider) setToken(token string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.token = token
+}
+
+func (p *testProvider) currentToken() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.token
+}
+
+func (p *testProvider) setFailing(failing bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.failing = failing
+}
+
+// testProviders lists a fixed set of providers
+type testProviders map[models.NotificationType]interfaces.NotificationProvider
+
+func (p testProviders) ListProviders() map[models.NotificationType]interfaces.NotificationProvider {
+	return p
+}
+
+// testAlerts records the alerts a monitor emits
+type testAlerts struct {
+	mu     sync.Mutex
+	alerts []Alert
+}
+
+func (a *testAlerts) get() []Alert {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return append([]Alert(nil), a.alerts...)
+}
+
+func createTestMonitor(cfg config.CredentialsConfig, provs ...interfaces.NotificationProvider) (*Monitor, *testAlerts) {
+	listed := make(testProviders, len(provs))
+	for _, provider := range provs {
+		listed[provider.GetType()] = provider
+	}
+
+	monitor := NewMonitor(listed, cfg, utils.NewSimpleLogger("error"))
+	alerts := &testAlerts{}
+	monitor.SetAlertHandler(func(alert Alert) {
+		alerts.mu.Lock()
+		alerts.alerts = append(alerts.alerts, alert)
+		alerts.mu.Unlock()
+	})
+	return monitor, alerts
+}
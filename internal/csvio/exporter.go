@@ -0,0 +1,150 @@
+package csvio
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// exportPageSize is the number of notifications read from the repository per page
+const exportPageSize = 500
+
+// resultColumns is the header row written by ResultWriter
+var resultColumns = []string{"id", "recipient", "type", "status", "provider_id", "sent_at", "failed_at", "retry_count", "error"}
+
+// ResultWriter streams notification results to CSV
+type ResultWriter struct {
+	writer *csv.Writer
+	rows   int
+}
+
+// NewResultWriter creates a result writer and writes the header row
+func NewResultWriter(w io.Writer) (*ResultWriter, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(resultColumns); err != nil {
+		return nil, errors.NewInternalError("failed to write CSV header", err)
+	}
+
+	return &ResultWriter{writer: writer}, nil
+}
+
+// WriteNotification writes a stored notification
+func (w *ResultWriter) WriteNotification(notification *models.Notification) error {
+	return w.write([]string{
+		notification.ID.String(),
+		notification.Recipient,
+		string(notification.Type),
+		string(notification.Status),
+		notification.Metadata["provider_id"],
+		formatTime(notification.SentAt),
+		formatTime(notification.FailedAt),
+		strconv.Itoa(notification.RetryCount),
+		notification.ErrorMsg,
+	})
+}
+
+// WriteResponse writes the response of a bulk send for one recipient.
+// Responses carry no failure time or retry count, so those columns are left empty.
+func (w *ResultWriter) WriteResponse(recipient string, notificationType models.NotificationType, response *models.NotificationResponse) error {
+	return w.write([]string{
+		response.ID.String(),
+		recipient,
+		string(notificationType),
+		string(response.Status),
+		response.ProviderID,
+		formatTime(response.SentAt),
+		"",
+		"",
+		response.Error,
+	})
+}
+
+// Rows returns the number of result rows written, excluding the header
+func (w *ResultWriter) Rows() int {
+	return w.rows
+}
+
+// Flush writes any buffered rows to the underlying writer
+func (w *ResultWriter) Flush() error {
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		return errors.NewInternalError("failed to write CSV", err)
+	}
+	return nil
+}
+
+// write writes a row
+func (w *ResultWriter) write(row []string) error {
+	if err := w.writer.Write(row); err != nil {
+		return errors.NewInternalError("failed to write CSV row", err)
+	}
+	w.rows++
+	return nil
+}
+
+// ExportNotifications streams the notifications matching the filters to CSV,
+// reading the repository a page at a time. Filter offsets are honoured; the
+// limit caps the total number of rows exported. It returns the number of rows written.
+func ExportNotifications(ctx context.Context, w io.Writer, repository interfaces.NotificationRepository, filters interfaces.NotificationFilters) (int, error) {
+	writer, err := NewResultWriter(w)
+	if err != nil {
+		return 0, err
+	}
+
+	remaining := filters.Limit
+	page := filters
+	page.Limit = exportPageSize
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return writer.Rows(), err
+		}
+
+		if remaining > 0 && remaining < page.Limit {
+			page.Limit = remaining
+		}
+
+		notifications, err := repository.List(ctx, page)
+		if err != nil {
+			return writer.Rows(), err
+		}
+
+		for _, notification := range notifications {
+			if err := writer.WriteNotification(notification); err != nil {
+				return writer.Rows(), err
+			}
+		}
+
+		if remaining > 0 {
+			remaining -= len(notifications)
+			if remaining <= 0 {
+				break
+			}
+		}
+
+		if len(notifications) < page.Limit {
+			break
+		}
+		page.Offset += len(notifications)
+
+		if err := writer.Flush(); err != nil {
+			return writer.Rows(), err
+		}
+	}
+
+	return writer.Rows(), writer.Flush()
+}
+
+// formatTime formats an optional timestamp as RFC 3339
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
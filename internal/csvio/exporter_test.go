@@ -0,0 +1,84 @@
+package csvio
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/repository"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+func TestResultWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewResultWriter(&buf)
+	require.NoError(t, err)
+
+	sentAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, writer.WriteNotification(&models.Notification{
+		ID:        uuid.New(),
+		Type:      models.NotificationTypeEmail,
+		Status:    models.StatusSent,
+		Recipient: "a@example.com",
+		SentAt:    &sentAt,
+		Metadata:  map[string]string{"provider_id": "msg-1"},
+	}))
+	require.NoError(t, writer.WriteResponse("b@example.com", models.NotificationTypeEmail, &models.NotificationResponse{
+		ID:     uuid.New(),
+		Status: models.StatusFailed,
+		Error:  "mailbox full, try later",
+	}))
+	require.NoError(t, writer.Flush())
+	assert.Equal(t, 2, writer.Rows())
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, resultColumns, rows[0])
+	assert.Equal(t, []string{"a@example.com", "email", "sent", "msg-1", "2024-05-01T10:00:00Z"}, rows[1][1:6])
+	assert.Equal(t, "failed", rows[2][3])
+	assert.Equal(t, "mailbox full, try later", rows[2][8])
+}
+
+func TestExportNotifications(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	ctx := context.Background()
+
+	base := time.Now().Add(-time.Hour)
+	for i := 0; i < exportPageSize+20; i++ {
+		status := models.StatusSent
+		if i%10 == 0 {
+			status = models.StatusFailed
+		}
+		require.NoError(t, repo.Save(ctx, &models.Notification{
+			ID:        uuid.New(),
+			Type:      models.NotificationTypeSMS,
+			Status:    status,
+			Recipient: "+14155550123",
+			CreatedAt: base.Add(time.Duration(i) * time.Second),
+		}))
+	}
+
+	var buf bytes.Buffer
+	count, err := ExportNotifications(ctx, &buf, repo, interfaces.NotificationFilters{})
+	require.NoError(t, err)
+	assert.Equal(t, exportPageSize+20, count)
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	assert.Len(t, rows, exportPageSize+21)
+
+	// Filters and limits apply
+	failed := models.StatusFailed
+	buf.Reset()
+	count, err = ExportNotifications(ctx, &buf, repo, interfaces.NotificationFilters{Status: &failed, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 10, count)
+}
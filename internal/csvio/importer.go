@@ -0,0 +1,273 @@
+// Package csvio streams bulk recipients in from CSV and notification
+// results out to CSV.
+package csvio
+
+import (
+	"context"
+	"encoding/csv"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/queue"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// DefaultBatchSize is the number of recipients handed to a sink at a time
+const DefaultBatchSize = 500
+
+// recipientColumns are the header names recognised as the recipient column,
+// in order of preference, when ImportOptions.RecipientColumn is not set
+var recipientColumns = []string{"recipient", "email", "phone", "phone_number", "device_token", "webhook_url"}
+
+// RecipientSink receives batches of imported recipients
+type RecipientSink interface {
+	WriteRecipients(ctx context.Context, batch []models.CampaignRecipient) error
+}
+
+// SinkFunc adapts a function to the RecipientSink interface
+type SinkFunc func(ctx context.Context, batch []models.CampaignRecipient) error
+
+// WriteRecipients implements the RecipientSink interface
+func (f SinkFunc) WriteRecipients(ctx context.Context, batch []models.CampaignRecipient) error {
+	return f(ctx, batch)
+}
+
+// ImportOptions configures a recipient import
+type ImportOptions struct {
+	// Channel selects how recipients are validated: email addresses, phone
+	// numbers (sms, voice), device tokens (push) or webhook URLs (chat).
+	// Recipients are only checked for presence when empty.
+	Channel models.NotificationType
+
+	// RecipientColumn names the recipient column. When empty the first of
+	// recipient, email, phone, phone_number, device_token or webhook_url is used.
+	RecipientColumn string
+
+	// BatchSize is the number of recipients written to the sink at a time
+	BatchSize int
+
+	// MaxErrors stops the import once this many rows have failed. Zero means no limit.
+	MaxErrors int
+}
+
+// RowError describes a CSV row that could not be imported
+type RowError struct {
+	Row     int    `json:"row"` // line number in the file, the header being line 1
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// Error implements the error interface
+func (e RowError) Error() string {
+	if e.Column != "" {
+		return fmt.Sprintf("row %d, column %s: %s", e.Row, e.Column, e.Message)
+	}
+	return fmt.Sprintf("row %d: %s", e.Row, e.Message)
+}
+
+// ImportResult summarises a recipient import
+type ImportResult struct {
+	Rows     int        `json:"rows"`
+	Imported int        `json:"imported"`
+	Failed   int        `json:"failed"`
+	Errors   []RowError `json:"errors,omitempty"`
+	Aborted  bool       `json:"aborted"` // stopped early after MaxErrors failed rows
+}
+
+// ImportRecipients streams recipients from a CSV with a header row into a
+// sink. Every column other than the recipient column becomes template data.
+// Rows are validated and written in batches, so the file is never held in
+// memory. Invalid rows are reported in the result and do not stop the import.
+func ImportRecipients(ctx context.Context, r io.Reader, sink RecipientSink, opts ImportOptions) (*ImportResult, error) {
+	if sink == nil {
+		return nil, errors.NewValidationError("sink", "recipient sink is required")
+	}
+
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // field counts are checked per row
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.NewValidationError("csv", "CSV file is empty")
+	}
+	if err != nil {
+		return nil, errors.NewValidationError("csv", fmt.Sprintf("invalid CSV header: %v", err))
+	}
+
+	columns := make([]string, len(header))
+	for i, name := range header {
+		// Spreadsheet exports often start with a byte order mark
+		columns[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+	}
+
+	recipientIndex, err := findRecipientColumn(columns, opts.RecipientColumn)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{}
+	batch := make([]models.CampaignRecipient, 0, batchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := sink.WriteRecipients(ctx, batch); err != nil {
+			return err
+		}
+		result.Imported += len(batch)
+		batch = make([]models.CampaignRecipient, 0, batchSize)
+		return nil
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		var rowErr *RowError
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !stderrors.As(err, &parseErr) {
+				return result, errors.NewInternalError("failed to read CSV", err)
+			}
+			rowErr = &RowError{Row: parseErr.StartLine, Message: parseErr.Err.Error()}
+		} else {
+			line, _ := reader.FieldPos(0)
+			var recipient models.CampaignRecipient
+			recipient, rowErr = parseRow(line, record, columns, recipientIndex, opts.Channel)
+			if rowErr == nil {
+				batch = append(batch, recipient)
+			}
+		}
+
+		result.Rows++
+		if rowErr != nil {
+			result.Failed++
+			result.Errors = append(result.Errors, *rowErr)
+			if opts.MaxErrors > 0 && result.Failed >= opts.MaxErrors {
+				result.Aborted = true
+				break
+			}
+			continue
+		}
+
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// QueueSink enqueues a bulk job for each imported recipient. Build turns a
+// recipient into the notification request to send.
+type QueueSink struct {
+	Queue    *queue.MemoryQueue
+	Build    func(recipient models.CampaignRecipient) *models.NotificationRequest
+	Metadata map[string]string // copied onto every job, e.g. a bulk job ID
+}
+
+// WriteRecipients implements the RecipientSink interface
+func (s *QueueSink) WriteRecipients(ctx context.Context, batch []models.CampaignRecipient) error {
+	for _, recipient := range batch {
+		metadata := make(map[string]string, len(s.Metadata))
+		for key, value := range s.Metadata {
+			metadata[key] = value
+		}
+
+		job := &queue.Job{Request: s.Build(recipient), Metadata: metadata}
+		if err := s.Queue.Enqueue(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findRecipientColumn returns the index of the recipient column
+func findRecipientColumn(columns []string, name string) (int, error) {
+	candidates := recipientColumns
+	if name != "" {
+		candidates = []string{strings.ToLower(name)}
+	}
+
+	for _, candidate := range candidates {
+		for i, column := range columns {
+			if column == candidate {
+				return i, nil
+			}
+		}
+	}
+
+	return -1, errors.NewValidationError("csv", fmt.Sprintf("CSV header has no recipient column (expected one of: %s)", strings.Join(candidates, ", ")))
+}
+
+// parseRow converts a CSV record into a recipient, validating it for the channel
+func parseRow(line int, record, columns []string, recipientIndex int, channel models.NotificationType) (models.CampaignRecipient, *RowError) {
+	if len(record) != len(columns) {
+		return models.CampaignRecipient{}, &RowError{
+			Row:     line,
+			Message: fmt.Sprintf("expected %d fields, got %d", len(columns), len(record)),
+		}
+	}
+
+	data := make(map[string]string, len(columns)-1)
+	for i, value := range record {
+		if i != recipientIndex && columns[i] != "" {
+			data[columns[i]] = strings.TrimSpace(value)
+		}
+	}
+
+	recipient := strings.TrimSpace(record[recipientIndex])
+	if err := validateRecipient(recipient, channel, data); err != nil {
+		message := err.Error()
+		if notifErr, ok := errors.AsNotificationError(err); ok {
+			message = notifErr.Message
+		}
+		return models.CampaignRecipient{}, &RowError{Row: line, Column: columns[recipientIndex], Message: message}
+	}
+
+	return models.CampaignRecipient{Recipient: recipient, Data: data}, nil
+}
+
+// validateRecipient validates a recipient for the channel it will be sent on
+func validateRecipient(recipient string, channel models.NotificationType, data map[string]string) error {
+	if recipient == "" {
+		return errors.NewValidationError("recipient", "recipient is required")
+	}
+
+	switch channel {
+	case models.NotificationTypeEmail:
+		return utils.ValidateEmailAddress(recipient)
+	case models.NotificationTypeSMS, models.NotificationTypeVoice:
+		return utils.ValidatePhoneNumber(recipient, data["country_code"])
+	case models.NotificationTypePush:
+		return utils.ValidateDeviceToken(recipient, data["platform"])
+	case models.NotificationTypeChat:
+		return utils.ValidateWebhookURL(recipient)
+	}
+
+	return nil
+}
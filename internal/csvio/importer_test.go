@@ -0,0 +1,188 @@
+package csvio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/queue"
+)
+
+func TestImportRecipients(t *testing.T) {
+	input := "\ufeffEmail, name, plan\n" +
+		"asha@example.com,Asha,pro\n" +
+		"not-an-email,Bad,free\n" +
+		"sam@example.com,Sam\n" +
+		",Missing,free\n" +
+		"ravi@example.com,Ravi,\"team\"\n"
+
+	sink := &recordingSink{}
+	result, err := ImportRecipients(context.Background(), strings.NewReader(input), sink, ImportOptions{
+		Channel:   models.NotificationTypeEmail,
+		BatchSize: 1,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 5, result.Rows)
+	assert.Equal(t, 2, result.Imported)
+	assert.Equal(t, 3, result.Failed)
+	assert.False(t, result.Aborted)
+	assert.Equal(t, 2, sink.batches)
+
+	require.Len(t, sink.recipients, 2)
+	assert.Equal(t, "asha@example.com", sink.recipients[0].Recipient)
+	assert.Equal(t, map[string]string{"name": "Asha", "plan": "pro"}, sink.recipients[0].Data)
+	assert.Equal(t, "team", sink.recipients[1].Data["plan"])
+
+	require.Len(t, result.Errors, 3)
+	assert.Equal(t, 3, result.Errors[0].Row)
+	assert.Equal(t, "email", result.Errors[0].Column)
+	assert.Contains(t, result.Errors[0].Message, "invalid email address format")
+	assert.Equal(t, 4, result.Errors[1].Row)
+	assert.Contains(t, result.Errors[1].Message, "expected 3 fields")
+	assert.Equal(t, 5, result.Errors[2].Row)
+	assert.Contains(t, result.Errors[0].Error(), "row 3, column email: ")
+}
+
+func TestImportRecipients_ChannelValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel models.NotificationType
+		input   string
+		valid   int
+	}{
+		{"sms", models.NotificationTypeSMS, "phone,country_code\n+14155550123,US\n12,US\n", 1},
+		{"push", models.NotificationTypePush, "device_token,platform\n" + strings.Repeat("a", 64) + ",ios\nshort,ios\n", 1},
+		{"chat", models.NotificationTypeChat, "webhook_url\nhttps://hooks.example.com/x\nftp://x\n", 1},
+		{"any channel", "", "recipient\nanything\n \n", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ImportRecipients(context.Background(), strings.NewReader(tt.input), &recordingSink{}, ImportOptions{Channel: tt.channel})
+			require.NoError(t, err)
+			assert.Equal(t, tt.valid, result.Imported)
+			assert.Equal(t, 1, result.Failed)
+		})
+	}
+}
+
+func TestImportRecipients_MaxErrors(t *testing.T) {
+	input := "email\nbad1\nbad2\nok@example.com\n"
+
+	result, err := ImportRecipients(context.Background(), strings.NewReader(input), &recordingSink{}, ImportOptions{
+		Channel:   models.NotificationTypeEmail,
+		MaxErrors: 2,
+	})
+	require.NoError(t, err)
+	assert.True(t, result.Aborted)
+	assert.Equal(t, 0, result.Imported)
+	assert.Equal(t, 2, result.Rows)
+}
+
+func TestImportRecipients_ParseErrors(t *testing.T) {
+	input := "email,name\nbad\"quote@example.com,X\nok@example.com,Ok\n"
+
+	result, err := ImportRecipients(context.Background(), strings.NewReader(input), &recordingSink{}, ImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Imported)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, 2, result.Errors[0].Row)
+}
+
+func TestImportRecipients_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := ImportRecipients(ctx, strings.NewReader(""), &recordingSink{}, ImportOptions{})
+	assert.Error(t, err)
+
+	_, err = ImportRecipients(ctx, strings.NewReader("name,plan\nAsha,pro\n"), &recordingSink{}, ImportOptions{})
+	assert.Error(t, err)
+
+	_, err = ImportRecipients(ctx, strings.NewReader("email\n"), nil, ImportOptions{})
+	assert.Error(t, err)
+
+	// Custom recipient column
+	result, err := ImportRecipients(ctx, strings.NewReader("name,contact\nAsha,asha@example.com\n"), &recordingSink{}, ImportOptions{RecipientColumn: "Contact"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Imported)
+
+	// Sink failures stop the import
+	failing := SinkFunc(func(ctx context.Context, batch []models.CampaignRecipient) error {
+		return fmt.Errorf("storage unavailable")
+	})
+	_, err = ImportRecipients(ctx, strings.NewReader("email\na@example.com\n"), failing, ImportOptions{})
+	assert.Error(t, err)
+}
+
+func TestImportRecipients_Streaming(t *testing.T) {
+	// Rows are produced on demand, so a large file is never materialised
+	const rows = 10000
+	reader, writer := io.Pipe()
+	go func() {
+		fmt.Fprintln(writer, "email,index")
+		for i := 0; i < rows; i++ {
+			fmt.Fprintf(writer, "user%d@example.com,%d\n", i, i)
+		}
+		writer.Close()
+	}()
+
+	largest := 0
+	sink := SinkFunc(func(ctx context.Context, batch []models.CampaignRecipient) error {
+		if len(batch) > largest {
+			largest = len(batch)
+		}
+		return nil
+	})
+
+	result, err := ImportRecipients(context.Background(), reader, sink, ImportOptions{Channel: models.NotificationTypeEmail, BatchSize: 250})
+	require.NoError(t, err)
+	assert.Equal(t, rows, result.Imported)
+	assert.Equal(t, 250, largest)
+}
+
+func TestQueueSink(t *testing.T) {
+	jobs := queue.NewMemoryQueue(0)
+	sink := &QueueSink{
+		Queue: jobs,
+		Build: func(recipient models.CampaignRecipient) *models.NotificationRequest {
+			return &models.NotificationRequest{
+				Type:      models.NotificationTypeEmail,
+				Priority:  models.PriorityNormal,
+				Recipient: recipient.Recipient,
+				Body:      "Hi " + recipient.Data["name"],
+			}
+		},
+		Metadata: map[string]string{"bulk_job_id": "job-1"},
+	}
+
+	input := "email,name\na@example.com,A\nb@example.com,B\n"
+	result, err := ImportRecipients(context.Background(), strings.NewReader(input), sink, ImportOptions{Channel: models.NotificationTypeEmail})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Imported)
+	assert.Equal(t, 2, jobs.Len())
+
+	job, err := jobs.TryDequeue()
+	require.NoError(t, err)
+	assert.Equal(t, "Hi A", job.Request.Body)
+	assert.Equal(t, "job-1", job.Metadata["bulk_job_id"])
+}
+
+// Helper functions
+
+type recordingSink struct {
+	batches    int
+	recipients []models.CampaignRecipient
+}
+
+func (s *recordingSink) WriteRecipients(ctx context.Context, batch []models.CampaignRecipient) error {
+	s.batches++
+	s.recipients = append(s.recipients, batch...)
+	return nil
+}
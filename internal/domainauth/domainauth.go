@@ -0,0 +1,534 @@
+// Package domainauth diagnoses how a sending domain authenticates its email:
+// its SPF record, its DKIM keys and its DMARC policy, and whether they align
+// with the From domain so that DMARC passes. Each problem is reported as a
+// finding that says how to fix it.
+package domainauth
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	stderrors "errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// defaultLookupTimeout bounds each TXT lookup
+const defaultLookupTimeout = 3 * time.Second
+
+// maxSPFLookups is the number of DNS lookups an SPF check may cause (RFC 7208)
+const maxSPFLookups = 10
+
+// Resolver looks up TXT records; *net.Resolver implements it
+type Resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// Options configures a diagnoser
+type Options struct {
+	Resolver      Resolver      // net.DefaultResolver when nil
+	LookupTimeout time.Duration // 3s when zero
+	FromDomain    string        // the domain of the From address; the sending domain when empty
+	DKIMSelectors []string      // checked besides the common selectors
+}
+
+// Severity is how much a finding affects delivery
+type Severity string
+
+// Finding severities
+const (
+	SeverityError   Severity = "error"   // mail fails authentication or is likely rejected
+	SeverityWarning Severity = "warning" // mail authenticates but is weakly protected
+	SeverityInfo    Severity = "info"
+)
+
+// Finding is a problem found with a domain's authentication
+type Finding struct {
+	Check    string   `json:"check"` // spf, dkim, dmarc or alignment
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	Fix      string   `json:"fix,omitempty"`
+}
+
+// SPF is a domain's SPF record
+type SPF struct {
+	Record  string `json:"record,omitempty"`
+	All     string `json:"all,omitempty"` // the qualifier of the all mechanism, e.g. "-all"
+	Lookups int    `json:"lookups"`       // mechanisms causing DNS lookups, not counting nested includes
+}
+
+// DKIMKey is a DKIM public key published under a selector
+type DKIMKey struct {
+	Selector string `json:"selector"`
+	Record   string `json:"record"`
+	KeyType  string `json:"key_type"`
+	KeyBits  int    `json:"key_bits,omitempty"`
+	Revoked  bool   `json:"revoked"`
+}
+
+// DMARC is the DMARC policy of a From domain
+type DMARC struct {
+	Domain          string   `json:"domain"` // where the record was found, the From or organizational domain
+	Record          string   `json:"record"`
+	Policy          string   `json:"policy"`
+	SubdomainPolicy string   `json:"subdomain_policy,omitempty"`
+	Percent         int      `json:"percent"`
+	DKIMAlignment   string   `json:"dkim_alignment"` // r (relaxed) or s (strict)
+	SPFAlignment    string   `json:"spf_alignment"`
+	ReportAddresses []string `json:"report_addresses,omitempty"`
+}
+
+// Report is the diagnosis of a sending domain
+type Report struct {
+	Domain     string    `json:"domain"`
+	FromDomain string    `json:"from_domain"`
+	SPF        *SPF      `json:"spf,omitempty"`
+	DKIM       []DKIMKey `json:"dkim,omitempty"`
+	DMARC      *DMARC    `json:"dmarc,omitempty"`
+	Aligned    bool      `json:"aligned"` // the From domain aligns with the sending domain under the DMARC policy
+	Findings   []Finding `json:"findings"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// OK reports whether the diagnosis found no errors
+func (r *Report) OK() bool {
+	for _, finding := range r.Findings {
+		if finding.Severity == SeverityError {
+			return false
+		}
+	}
+	return true
+}
+
+// Diagnoser diagnoses sending domains
+type Diagnoser struct {
+	options Options
+	now     func() time.Time
+}
+
+// NewDiagnoser creates a diagnoser
+func NewDiagnoser(options Options) *Diagnoser {
+	if options.Resolver == nil {
+		options.Resolver = net.DefaultResolver
+	}
+	if options.LookupTimeout <= 0 {
+		options.LookupTimeout = defaultLookupTimeout
+	}
+
+	return &Diagnoser{options: options, now: time.Now}
+}
+
+// DiagnoseSendingDomain checks the SPF record and DKIM keys of a sending
+// domain and the DMARC policy of the From domain, the configured one unless
+// fromDomain is set. DKIM keys are looked up under the common selectors,
+// the configured ones and any given; DNS cannot list a domain's selectors.
+// The error is only set when a lookup fails; missing records are findings.
+func (d *Diagnoser) DiagnoseSendingDomain(ctx context.Context, domain, fromDomain string, selectors ...string) (*Report, error) {
+	domain = normalizeDomain(domain)
+	if !validDomain(domain) {
+		return nil, errors.NewValidationError("domain", fmt.Sprintf("%q is not a domain name", domain))
+	}
+	if fromDomain == "" {
+		fromDomain = d.options.FromDomain
+	}
+	if fromDomain = normalizeDomain(fromDomain); fromDomain == "" {
+		fromDomain = domain
+	}
+	if !validDomain(fromDomain) {
+		return nil, errors.NewValidationError("from_domain", fmt.Sprintf("%q is not a domain name", fromDomain))
+	}
+
+	report := &Report{Domain: domain, FromDomain: fromDomain, Findings: []Finding{}, CheckedAt: d.now()}
+	if err := d.checkSPF(ctx, report); err != nil {
+		return nil, err
+	}
+	if err := d.checkDKIM(ctx, report, selectors); err != nil {
+		return nil, err
+	}
+	if err := d.checkDMARC(ctx, report); err != nil {
+		return nil, err
+	}
+	d.checkAlignment(report)
+	return report, nil
+}
+
+// checkSPF checks the sending domain's SPF record
+func (d *Diagnoser) checkSPF(ctx context.Context, report *Report) error {
+	records, err := d.lookup(ctx, report.Domain, "v=spf1")
+	if err != nil {
+		return err
+	}
+
+	switch len(records) {
+	case 0:
+		report.add("spf", SeverityError, fmt.Sprintf("%s has no SPF record, so receivers cannot tell which servers may send its mail", report.Domain),
+			fmt.Sprintf(`Publish a TXT record at %s such as "v=spf1 include:<your email provider> -all"`, report.Domain))
+		return nil
+	case 1:
+	default:
+		report.add("spf", SeverityError, fmt.Sprintf("%s has %d SPF records; receivers treat this as a permanent error", report.Domain, len(records)),
+			"Merge them into a single v=spf1 TXT record")
+		return nil
+	}
+
+	spf := &SPF{Record: records[0]}
+	report.SPF = spf
+	for _, term := range strings.Fields(strings.ToLower(records[0]))[1:] {
+		qualifier, name := "+", term
+		if strings.ContainsAny(term[:1], "+-~?") {
+			qualifier, name = term[:1], term[1:]
+		}
+		if end := strings.IndexAny(name, ":/="); end >= 0 {
+			name = name[:end]
+		}
+
+		switch name {
+		case "all":
+			spf.All = qualifier + "all"
+		case "include", "a", "mx", "exists", "redirect":
+			spf.Lookups++
+		case "ptr":
+			spf.Lookups++
+			report.add("spf", SeverityWarning, "The SPF record uses the ptr mechanism, which is slow and ignored by some receivers",
+				"Replace ptr with ip4, ip6 or include mechanisms")
+		}
+	}
+
+	switch spf.All {
+	case "+all":
+		report.add("spf", SeverityError, "The SPF record ends in +all, which lets any server send as "+report.Domain,
+			"End the record with -all or ~all")
+	case "?all":
+		report.add("spf", SeverityWarning, "The SPF record ends in ?all, which gives no protection",
+			"End the record with -all or ~all")
+	case "":
+		if !strings.Contains(strings.ToLower(records[0]), "redirect=") {
+			report.add("spf", SeverityWarning, "The SPF record has no all mechanism, so mail from other servers is neutral",
+				"End the record with -all or ~all")
+		}
+	}
+	if spf.Lookups > maxSPFLookups {
+		report.add("spf", SeverityError, fmt.Sprintf("The SPF record needs %d DNS lookups; more than %d fail SPF", spf.Lookups, maxSPFLookups),
+			"Remove unused includes or replace them with ip4 and ip6 mechanisms")
+	}
+	return nil
+}
+
+// checkDKIM looks up DKIM keys under the common, configured and given selectors
+func (d *Diagnoser) checkDKIM(ctx context.Context, report *Report, selectors []string) error {
+	seen := make(map[string]bool)
+	var checked []string
+	for _, selector := range append(append(commonSelectors(), d.options.DKIMSelectors...), selectors...) {
+		selector = strings.ToLower(strings.TrimSpace(selector))
+		if selector == "" || seen[selector] {
+			continue
+		}
+		seen[selector] = true
+		checked = append(checked, selector)
+
+		records, err := d.lookup(ctx, selector+"._domainkey."+report.Domain, "")
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			tags := parseTags(record)
+			if _, hasKey := tags["p"]; !hasKey {
+				continue
+			}
+			report.DKIM = append(report.DKIM, dkimKey(selector, record, tags))
+			break
+		}
+	}
+
+	if len(report.DKIM) == 0 {
+		report.add("dkim", SeverityError, fmt.Sprintf("No DKIM key was found for %s under the selectors %s", report.Domain, strings.Join(checked, ", ")),
+			"Publish the key your email provider signs with, and add its selector to the configured selectors if it is not listed")
+		return nil
+	}
+
+	active := 0
+	for _, key := range report.DKIM {
+		switch {
+		case key.Revoked:
+			report.add("dkim", SeverityInfo, fmt.Sprintf("The DKIM key under selector %s is revoked", key.Selector), "")
+			continue
+		case key.KeyType == "rsa" && key.KeyBits == 0:
+			report.add("dkim", SeverityError, fmt.Sprintf("The DKIM key under selector %s is not a valid public key", key.Selector),
+				"Copy the public key from your email provider again")
+			continue
+		case key.KeyType == "rsa" && key.KeyBits < 1024:
+			report.add("dkim", SeverityError, fmt.Sprintf("The DKIM key under selector %s has %d bits; receivers reject keys under 1024 bits", key.Selector, key.KeyBits),
+				"Rotate to a 2048-bit key")
+		case key.KeyType == "rsa" && key.KeyBits < 2048:
+			report.add("dkim", SeverityWarning, fmt.Sprintf("The DKIM key under selector %s has %d bits", key.Selector, key.KeyBits),
+				"Rotate to a 2048-bit key")
+		}
+		active++
+	}
+	if active == 0 {
+		report.add("dkim", SeverityError, "Every DKIM key found is revoked or invalid", "Publish the key your email provider signs with")
+	}
+	return nil
+}
+
+// checkDMARC looks up the From domain's DMARC policy, falling back to its
+// organizational domain's
+func (d *Diagnoser) checkDMARC(ctx context.Context, report *Report) error {
+	domains := []string{report.FromDomain}
+	if org := organizationalDomain(report.FromDomain); org != report.FromDomain {
+		domains = append(domains, org)
+	}
+
+	for _, domain := range domains {
+		records, err := d.lookup(ctx, "_dmarc."+domain, "v=DMARC1")
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			continue
+		}
+		if len(records) > 1 {
+			report.add("dmarc", SeverityError, fmt.Sprintf("_dmarc.%s has %d DMARC records, so receivers ignore them", domain, len(records)),
+				"Keep a single v=DMARC1 TXT record")
+			return nil
+		}
+		report.DMARC = parseDMARC(domain, records[0])
+		break
+	}
+
+	dmarc := report.DMARC
+	if dmarc == nil {
+		report.add("dmarc", SeverityError, fmt.Sprintf("%s has no DMARC record; large mailbox providers require one from bulk senders", report.FromDomain),
+			fmt.Sprintf(`Publish a TXT record at _dmarc.%s such as "v=DMARC1; p=none; rua=mailto:dmarc@%s" and tighten the policy once reports look clean`, report.FromDomain, report.FromDomain))
+		return nil
+	}
+
+	usedPolicy := dmarc.Policy
+	if dmarc.Domain != report.FromDomain && dmarc.SubdomainPolicy != "" {
+		usedPolicy = dmarc.SubdomainPolicy
+	}
+	switch usedPolicy {
+	case "none":
+		report.add("dmarc", SeverityWarning, "The DMARC policy is p=none, which only monitors; spoofed mail is still delivered",
+			"Move to p=quarantine and then p=reject once reports show all legitimate mail passes")
+	case "quarantine", "reject":
+	default:
+		report.add("dmarc", SeverityError, fmt.Sprintf("The DMARC policy %q is not valid", usedPolicy), "Set p to none, quarantine or reject")
+	}
+	if dmarc.Percent < 100 {
+		report.add("dmarc", SeverityInfo, fmt.Sprintf("The DMARC policy applies to %d%% of failing mail", dmarc.Percent), "Raise pct to 100 when ready")
+	}
+	if len(dmarc.ReportAddresses) == 0 {
+		report.add("dmarc", SeverityWarning, "The DMARC record has no rua address, so you receive no aggregate reports",
+			fmt.Sprintf("Add rua=mailto:dmarc@%s to the record", dmarc.Domain))
+	}
+	return nil
+}
+
+// checkAlignment checks that the From domain aligns with the sending domain
+// under the DMARC policy's alignment modes: strict alignment needs the same
+// domain, relaxed the same organizational domain
+func (d *Diagnoser) checkAlignment(report *Report) {
+	dkimMode, spfMode := "r", "r"
+	if report.DMARC != nil {
+		dkimMode, spfMode = report.DMARC.DKIMAlignment, report.DMARC.SPFAlignment
+	}
+
+	dkimAligned := aligned(report.Domain, report.FromDomain, dkimMode)
+	spfAligned := aligned(report.Domain, report.FromDomain, spfMode)
+	report.Aligned = dkimAligned || spfAligned
+
+	switch {
+	case !report.Aligned:
+		report.add("alignment", SeverityError,
+			fmt.Sprintf("The From domain %s does not align with the sending domain %s, so DMARC fails even when SPF and DKIM pass", report.FromDomain, report.Domain),
+			fmt.Sprintf("Send from an address at %s, or authenticate %s with your email provider", report.Domain, report.FromDomain))
+	case !dkimAligned:
+		report.add("alignment", SeverityWarning, "Only SPF aligns with the From domain; forwarded mail will fail DMARC",
+			"Relax DKIM alignment (adkim=r) or sign with the From domain")
+	}
+}
+
+// lookup returns a name's TXT records starting with a prefix, compared
+// case-insensitively. A name that does not exist has none.
+func (d *Diagnoser) lookup(ctx context.Context, name, prefix string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.options.LookupTimeout)
+	defer cancel()
+
+	records, err := d.options.Resolver.LookupTXT(ctx, name)
+	var dnsErr *net.DNSError
+	if err != nil && !(stderrors.As(err, &dnsErr) && dnsErr.IsNotFound) {
+		return nil, errors.NewNotificationError(errors.ErrorCodeProviderUnavailable, "TXT lookup failed for "+name).WithCause(err)
+	}
+
+	var matching []string
+	for _, record := range records {
+		record = strings.TrimSpace(record)
+		if prefix == "" || hasTagPrefix(record, prefix) {
+			matching = append(matching, record)
+		}
+	}
+	return matching, nil
+}
+
+// add adds a finding to a report
+func (r *Report) add(check string, severity Severity, message, fix string) {
+	r.Findings = append(r.Findings, Finding{Check: check, Severity: severity, Message: message, Fix: fix})
+}
+
+// dkimKey describes the DKIM key in a record's tags
+func dkimKey(selector, record string, tags map[string]string) DKIMKey {
+	key := DKIMKey{Selector: selector, Record: record, KeyType: strings.ToLower(tags["k"])}
+	if key.KeyType == "" {
+		key.KeyType = "rsa"
+	}
+
+	encoded := strings.Join(strings.Fields(tags["p"]), "")
+	if encoded == "" {
+		key.Revoked = true
+		return key
+	}
+	if key.KeyType != "rsa" {
+		return key
+	}
+
+	der, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return key
+	}
+	parsed, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		// Some keys are published as a bare PKCS #1 RSA key
+		parsed, err = x509.ParsePKCS1PublicKey(der)
+	}
+	if err == nil {
+		if rsaKey, ok := parsed.(*rsa.PublicKey); ok {
+			key.KeyBits = rsaKey.N.BitLen()
+		}
+	}
+	return key
+}
+
+// parseDMARC reads a DMARC record, applying the defaults of RFC 7489
+func parseDMARC(domain, record string) *DMARC {
+	tags := parseTags(record)
+	dmarc := &DMARC{
+		Domain:          domain,
+		Record:          record,
+		Policy:          strings.ToLower(tags["p"]),
+		SubdomainPolicy: strings.ToLower(tags["sp"]),
+		Percent:         100,
+		DKIMAlignment:   "r",
+		SPFAlignment:    "r",
+	}
+	if percent, err := strconv.Atoi(tags["pct"]); err == nil && percent >= 0 && percent <= 100 {
+		dmarc.Percent = percent
+	}
+	if strings.EqualFold(tags["adkim"], "s") {
+		dmarc.DKIMAlignment = "s"
+	}
+	if strings.EqualFold(tags["aspf"], "s") {
+		dmarc.SPFAlignment = "s"
+	}
+	for _, address := range strings.Split(tags["rua"], ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			dmarc.ReportAddresses = append(dmarc.ReportAddresses, address)
+		}
+	}
+	return dmarc
+}
+
+// parseTags reads the tag=value list of a DKIM or DMARC record
+func parseTags(record string) map[string]string {
+	tags := make(map[string]string)
+	for _, part := range strings.Split(record, ";") {
+		name, value, found := strings.Cut(part, "=")
+		if found {
+			tags[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(value)
+		}
+	}
+	return tags
+}
+
+// hasTagPrefix reports whether a record starts with a version tag such as
+// "v=spf1", ignoring case and spaces around the equals sign
+func hasTagPrefix(record, prefix string) bool {
+	name, value, _ := strings.Cut(prefix, "=")
+	recordName, recordValue, found := strings.Cut(record, "=")
+	if !found || !strings.EqualFold(strings.TrimSpace(recordName), name) {
+		return false
+	}
+	recordValue = strings.TrimSpace(recordValue)
+	return len(recordValue) >= len(value) && strings.EqualFold(recordValue[:len(value)], value) &&
+		(len(recordValue) == len(value) || strings.ContainsAny(recordValue[len(value):len(value)+1], " ;"))
+}
+
+// aligned reports whether two domains align in a DMARC alignment mode
+func aligned(a, b, mode string) bool {
+	if mode == "s" {
+		return a == b
+	}
+	return organizationalDomain(a) == organizationalDomain(b)
+}
+
+// twoLevelSuffixes are common public suffixes of two labels. Without the
+// public suffix list, other suffixes are assumed to have one label.
+var twoLevelSuffixes = map[string]bool{
+	"co.uk": true, "org.uk": true, "ac.uk": true, "gov.uk": true,
+	"com.au": true, "net.au": true, "org.au": true,
+	"co.jp": true, "co.nz": true, "co.in": true, "co.za": true,
+	"com.br": true, "com.mx": true, "com.sg": true, "com.cn": true,
+}
+
+// organizationalDomain approximates a domain's organizational domain: its
+// registered domain, e.g. example.co.uk for mail.example.co.uk
+func organizationalDomain(domain string) string {
+	labels := strings.Split(domain, ".")
+	keep := 2
+	if len(labels) >= 3 && twoLevelSuffixes[strings.Join(labels[len(labels)-2:], ".")] {
+		keep = 3
+	}
+	if len(labels) <= keep {
+		return domain
+	}
+	return strings.Join(labels[len(labels)-keep:], ".")
+}
+
+// commonSelectors are the DKIM selectors of widely used email providers
+func commonSelectors() []string {
+	return []string{"default", "dkim", "google", "k1", "k2", "mail", "s1", "s2", "selector1", "selector2"}
+}
+
+// normalizeDomain lowercases a domain and removes a trailing dot, or takes
+// the domain of an email address
+func normalizeDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if at := strings.LastIndex(domain, "@"); at >= 0 {
+		domain = domain[at+1:]
+	}
+	return strings.TrimSuffix(domain, ".")
+}
+
+// validDomain reports whether a name is a plausible domain name
+func validDomain(domain string) bool {
+	if len(domain) > 253 || !strings.Contains(domain, ".") {
+		return false
+	}
+	for _, label := range strings.Split(domain, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
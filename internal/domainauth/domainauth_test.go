@@ -0,0 +1,193 @@
+package domainauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestDiagnoser_Healthy(t *testing.T) {
+	resolver := createTestResolver(t)
+	diagnoser := NewDiagnoser(Options{Resolver: resolver, FromDomain: "example.com"})
+
+	report, err := diagnoser.DiagnoseSendingDomain(context.Background(), "Mail.Example.com.", "")
+	require.NoError(t, err)
+
+	assert.Equal(t, "mail.example.com", report.Domain)
+	assert.Equal(t, "example.com", report.FromDomain)
+	require.NotNil(t, report.SPF)
+	assert.Equal(t, "-all", report.SPF.All)
+	assert.Equal(t, 2, report.SPF.Lookups)
+	require.Len(t, report.DKIM, 1)
+	assert.Equal(t, "s1", report.DKIM[0].Selector)
+	assert.Equal(t, 2048, report.DKIM[0].KeyBits)
+	require.NotNil(t, report.DMARC)
+	assert.Equal(t, "reject", report.DMARC.Policy)
+	assert.Equal(t, []string{"mailto:dmarc@example.com"}, report.DMARC.ReportAddresses)
+	assert.True(t, report.Aligned)
+	assert.Empty(t, report.Findings)
+	assert.True(t, report.OK())
+}
+
+func TestDiagnoser_Findings(t *testing.T) {
+	weakKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		records   map[string][]string
+		from      string
+		selectors []string
+		check     string
+		severity  Severity
+		contains  string
+	}{
+		{"no SPF", map[string][]string{"example.org": {"google-site-verification=abc"}}, "", nil, "spf", SeverityError, "no SPF record"},
+		{"two SPF records", map[string][]string{"example.org": {"v=spf1 -all", "v=spf1 mx -all"}}, "", nil, "spf", SeverityError, "2 SPF records"},
+		{"plus all", map[string][]string{"example.org": {"v=spf1 all"}}, "", nil, "spf", SeverityError, "+all"},
+		{"neutral all", map[string][]string{"example.org": {"v=spf1 mx ?all"}}, "", nil, "spf", SeverityWarning, "?all"},
+		{"too many lookups", map[string][]string{"example.org": {"v=spf1 a mx include:a.com include:b.com include:c.com include:d.com include:e.com include:f.com include:g.com include:h.com include:i.com -all"}}, "", nil, "spf", SeverityError, "11 DNS lookups"},
+		{"ptr", map[string][]string{"example.org": {"v=spf1 ptr -all"}}, "", nil, "spf", SeverityWarning, "ptr"},
+		{"no DKIM", map[string][]string{"s1._domainkey.example.org": nil}, "", nil, "dkim", SeverityError, "No DKIM key"},
+		{"revoked DKIM", map[string][]string{"s1._domainkey.example.org": {"v=DKIM1; p="}}, "", nil, "dkim", SeverityError, "revoked or invalid"},
+		{"weak DKIM", map[string][]string{"s1._domainkey.example.org": {"v=DKIM1; k=rsa; p=" + encodeKey(t, &weakKey.PublicKey)}}, "", nil, "dkim", SeverityWarning, "1024 bits"},
+		{"custom selector", map[string][]string{"sg._domainkey.example.org": {"v=DKIM1; p=bad"}}, "", []string{"sg"}, "dkim", SeverityError, "selector sg is not a valid public key"},
+		{"no DMARC", map[string][]string{"_dmarc.example.org": nil}, "", nil, "dmarc", SeverityError, "no DMARC record"},
+		{"monitoring DMARC", map[string][]string{"_dmarc.example.org": {"v=DMARC1; p=none; rua=mailto:d@example.org"}}, "", nil, "dmarc", SeverityWarning, "p=none"},
+		{"partial DMARC", map[string][]string{"_dmarc.example.org": {"v=DMARC1; p=reject; pct=25; rua=mailto:d@example.org"}}, "", nil, "dmarc", SeverityInfo, "25%"},
+		{"no DMARC reports", map[string][]string{"_dmarc.example.org": {"v=DMARC1; p=reject"}}, "", nil, "dmarc", SeverityWarning, "no rua"},
+		{"unaligned From", nil, "other.net", nil, "alignment", SeverityError, "does not align"},
+		{"strict SPF only", map[string][]string{"_dmarc.example.org": {"v=DMARC1; p=reject; adkim=s; rua=mailto:d@example.org"}}, "news.example.org", nil, "alignment", SeverityWarning, "Only SPF aligns"},
+	}
+
+	// Records set to nil are removed from the healthy defaults
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := &fakeResolver{records: map[string][]string{
+				"example.org":               {"v=spf1 include:_spf.mailer.example -all"},
+				"s1._domainkey.example.org": {"v=DKIM1; k=rsa; p=" + testKey(t)},
+				"_dmarc.example.org":        {"v=DMARC1; p=reject; rua=mailto:d@example.org"},
+				"_dmarc.other.net":          {"v=DMARC1; p=reject; rua=mailto:d@other.net"},
+			}}
+			for name, records := range tt.records {
+				if records == nil {
+					delete(resolver.records, name)
+				} else {
+					resolver.records[name] = records
+				}
+			}
+
+			report, err := NewDiagnoser(Options{Resolver: resolver}).DiagnoseSendingDomain(context.Background(), "example.org", tt.from, tt.selectors...)
+			require.NoError(t, err)
+
+			finding := findFinding(report, tt.check, tt.severity)
+			require.NotNil(t, finding, "findings: %+v", report.Findings)
+			assert.Contains(t, finding.Message, tt.contains)
+			if tt.severity != SeverityInfo {
+				assert.NotEmpty(t, finding.Fix)
+			}
+		})
+	}
+}
+
+func TestDiagnoser_OrganizationalDMARC(t *testing.T) {
+	resolver := createTestResolver(t)
+	resolver.records["_dmarc.example.com"] = []string{"v=DMARC1; p=reject; sp=none; rua=mailto:dmarc@example.com"}
+	diagnoser := NewDiagnoser(Options{Resolver: resolver})
+
+	report, err := diagnoser.DiagnoseSendingDomain(context.Background(), "mail.example.com", "")
+	require.NoError(t, err)
+
+	require.NotNil(t, report.DMARC)
+	assert.Equal(t, "example.com", report.DMARC.Domain)
+	// The subdomain policy applies to mail from a subdomain
+	assert.NotNil(t, findFinding(report, "dmarc", SeverityWarning))
+	assert.True(t, report.Aligned)
+}
+
+func TestDiagnoser_Errors(t *testing.T) {
+	diagnoser := NewDiagnoser(Options{Resolver: &fakeResolver{err: &net.DNSError{Err: "server misbehaving", Name: "example.com", IsTemporary: true}}})
+
+	_, err := diagnoser.DiagnoseSendingDomain(context.Background(), "example.com", "")
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeProviderUnavailable, notifErr.Code)
+
+	for _, domain := range []string{"", "localhost", "bad_domain.com", "-a.com"} {
+		_, err = diagnoser.DiagnoseSendingDomain(context.Background(), domain, "")
+		notifErr, ok = errors.AsNotificationError(err)
+		require.True(t, ok, domain)
+		assert.Equal(t, errors.ErrorCodeValidationFailed, notifErr.Code, domain)
+	}
+}
+
+func TestOrganizationalDomain(t *testing.T) {
+	assert.Equal(t, "example.com", organizationalDomain("a.b.example.com"))
+	assert.Equal(t, "example.co.uk", organizationalDomain("mail.example.co.uk"))
+	assert.Equal(t, "example.com", organizationalDomain("example.com"))
+	assert.True(t, aligned("mail.example.com", "example.com", "r"))
+	assert.False(t, aligned("mail.example.com", "example.com", "s"))
+}
+
+// Helper functions
+
+// fakeResolver answers TXT lookups from a table; other names do not exist
+type fakeResolver struct {
+	records map[string][]string
+	err     error
+}
+
+func (r *fakeResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	records, exists := r.records[name]
+	if !exists {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return records, nil
+}
+
+// testKeyEncoded is a 2048-bit public key, generated once per test run
+var testKeyEncoded string
+
+func testKey(t *testing.T) string {
+	if testKeyEncoded == "" {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		testKeyEncoded = encodeKey(t, &key.PublicKey)
+	}
+	return testKeyEncoded
+}
+
+func encodeKey(t *testing.T, key *rsa.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(der)
+}
+
+func findFinding(report *Report, check string, severity Severity) *Finding {
+	for i, finding := range report.Findings {
+		if finding.Check == check && finding.Severity == severity {
+			return &report.Findings[i]
+		}
+	}
+	return nil
+}
+
+func createTestResolver(t *testing.T) *fakeResolver {
+	return &fakeResolver{records: map[string][]string{
+		"mail.example.com":               {"v=spf1 ip4:192.0.2.0/24 include:_spf.mailer.example mx -all", "google-site-verification=abc"},
+		"s1._domainkey.mail.example.com": {"v=DKIM1; k=rsa; p=" + testKey(t)},
+		"_dmarc.example.com":             {"v=DMARC1; p=reject; rua=mailto:dmarc@example.com"},
+	}}
+}
@@ -0,0 +1,234 @@
+// Package emailverify checks email addresses beyond their format before
+// expensive sends: whether the domain accepts mail (MX records), whether it
+// is a disposable mailbox provider, and whether the address is a role
+// account such as noreply@ or admin@.
+package emailverify
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// defaultCacheTTL is how long MX lookups are cached, so bulk sends to the
+// same domain resolve it once
+const defaultCacheTTL = 10 * time.Minute
+
+// defaultLookupTimeout bounds each MX lookup
+const defaultLookupTimeout = 3 * time.Second
+
+// Resolver looks up MX records; *net.Resolver implements it
+type Resolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+}
+
+// Options configures a verifier. The zero value checks format, disposable
+// domains and role accounts without DNS lookups.
+type Options struct {
+	CheckMX           bool          // look up the domain's MX records
+	Resolver          Resolver      // net.DefaultResolver when nil
+	LookupTimeout     time.Duration // 3s when zero
+	CacheTTL          time.Duration // 10m when zero
+	DisposableDomains []string      // added to the built-in list
+	RoleAccounts      []string      // added to the built-in list
+
+	// Check rejects these besides invalid addresses
+	RejectDisposable   bool
+	RejectRoleAccounts bool
+}
+
+// VerificationResult describes an email address
+type VerificationResult struct {
+	Email       string   `json:"email"`
+	Domain      string   `json:"domain,omitempty"`
+	Valid       bool     `json:"valid"` // well formed and, when checked, the domain accepts mail
+	MXChecked   bool     `json:"mx_checked"`
+	MXHosts     []string `json:"mx_hosts,omitempty"`
+	Disposable  bool     `json:"disposable"`
+	RoleAccount bool     `json:"role_account"`
+	Reason      string   `json:"reason,omitempty"` // why the address is not valid
+}
+
+// Risky reports whether the address is valid but unlikely to reach a person
+func (r VerificationResult) Risky() bool {
+	return r.Disposable || r.RoleAccount
+}
+
+// Verifier verifies email addresses. It is safe for concurrent use.
+type Verifier struct {
+	options    Options
+	disposable map[string]bool
+	roles      map[string]bool
+
+	mu    sync.Mutex
+	cache map[string]mxEntry
+	now   func() time.Time
+}
+
+// mxEntry is a cached MX lookup
+type mxEntry struct {
+	hosts   []string
+	expires time.Time
+}
+
+// NewVerifier creates a verifier
+func NewVerifier(options Options) *Verifier {
+	if options.Resolver == nil {
+		options.Resolver = net.DefaultResolver
+	}
+	if options.LookupTimeout <= 0 {
+		options.LookupTimeout = defaultLookupTimeout
+	}
+	if options.CacheTTL <= 0 {
+		options.CacheTTL = defaultCacheTTL
+	}
+
+	verifier := &Verifier{
+		options:    options,
+		disposable: make(map[string]bool),
+		roles:      make(map[string]bool),
+		cache:      make(map[string]mxEntry),
+		now:        time.Now,
+	}
+	for _, domain := range append(defaultDisposableDomains(), options.DisposableDomains...) {
+		verifier.disposable[strings.ToLower(domain)] = true
+	}
+	for _, role := range append(defaultRoleAccounts(), options.RoleAccounts...) {
+		verifier.roles[strings.ToLower(role)] = true
+	}
+	return verifier
+}
+
+// VerifyEmail verifies an address. An address that fails a check is
+// reported in the result; the error is only set when a check could not be
+// completed, such as an MX lookup that timed out.
+func (v *Verifier) VerifyEmail(ctx context.Context, email string) (VerificationResult, error) {
+	result := VerificationResult{Email: email}
+	if err := utils.ValidateEmailAddress(email); err != nil {
+		result.Reason = "invalid email address format"
+		return result, nil
+	}
+
+	at := strings.LastIndex(email, "@")
+	local, domain := strings.ToLower(email[:at]), strings.ToLower(email[at+1:])
+	result.Domain = domain
+	result.Disposable = v.isDisposable(domain)
+	if tag := strings.Index(local, "+"); tag >= 0 {
+		local = local[:tag]
+	}
+	result.RoleAccount = v.roles[local]
+	result.Valid = true
+
+	if !v.options.CheckMX {
+		return result, nil
+	}
+
+	hosts, err := v.lookupMX(ctx, domain)
+	if err != nil {
+		return result, errors.NewNotificationError(errors.ErrorCodeProviderUnavailable, "MX lookup failed for "+domain).WithCause(err)
+	}
+	result.MXChecked = true
+	result.MXHosts = hosts
+	if len(hosts) == 0 {
+		result.Valid = false
+		result.Reason = "domain does not accept email"
+	}
+	return result, nil
+}
+
+// Check verifies an address and returns a validation error for the request
+// field it came from when the address is invalid or rejected by the options
+func (v *Verifier) Check(ctx context.Context, field, email string) error {
+	result, err := v.VerifyEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	var reason string
+	switch {
+	case !result.Valid:
+		reason = result.Reason
+	case result.Disposable && v.options.RejectDisposable:
+		reason = "disposable email addresses are not accepted"
+	case result.RoleAccount && v.options.RejectRoleAccounts:
+		reason = "role account email addresses are not accepted"
+	default:
+		return nil
+	}
+	return errors.NewValidationError(field, fmt.Sprintf("%s: %s", email, reason))
+}
+
+// isDisposable reports whether a domain, or a domain it is a subdomain of,
+// is a disposable mailbox provider
+func (v *Verifier) isDisposable(domain string) bool {
+	for {
+		if v.disposable[domain] {
+			return true
+		}
+		dot := strings.Index(domain, ".")
+		if dot < 0 {
+			return false
+		}
+		domain = domain[dot+1:]
+	}
+}
+
+// lookupMX returns the mail hosts of a domain, from the cache when possible.
+// A domain that does not exist, or publishes a null MX, has none.
+func (v *Verifier) lookupMX(ctx context.Context, domain string) ([]string, error) {
+	v.mu.Lock()
+	entry, cached := v.cache[domain]
+	v.mu.Unlock()
+	if cached && v.now().Before(entry.expires) {
+		return entry.hosts, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, v.options.LookupTimeout)
+	defer cancel()
+
+	records, err := v.options.Resolver.LookupMX(ctx, domain)
+	var dnsErr *net.DNSError
+	if err != nil && !(stderrors.As(err, &dnsErr) && dnsErr.IsNotFound) {
+		return nil, err
+	}
+
+	hosts := make([]string, 0, len(records))
+	for _, record := range records {
+		// A null MX (RFC 7505) declares that the domain accepts no mail
+		if host := strings.TrimSuffix(record.Host, "."); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+
+	v.mu.Lock()
+	v.cache[domain] = mxEntry{hosts: hosts, expires: v.now().Add(v.options.CacheTTL)}
+	v.mu.Unlock()
+	return hosts, nil
+}
+
+// defaultDisposableDomains returns well-known disposable mailbox providers
+func defaultDisposableDomains() []string {
+	return []string{
+		"10minutemail.com", "dispostable.com", "fakeinbox.com", "getnada.com",
+		"guerrillamail.com", "mailinator.com", "maildrop.cc", "mailnesia.com",
+		"mintemail.com", "sharklasers.com", "temp-mail.org", "tempmail.com",
+		"throwawaymail.com", "trashmail.com", "yopmail.com",
+	}
+}
+
+// defaultRoleAccounts returns local parts that address a function rather
+// than a person
+func defaultRoleAccounts() []string {
+	return []string{
+		"abuse", "admin", "administrator", "billing", "contact", "donotreply",
+		"do-not-reply", "hostmaster", "info", "no-reply", "noreply", "postmaster",
+		"root", "sales", "security", "support", "webmaster",
+	}
+}
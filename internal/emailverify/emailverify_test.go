@@ -0,0 +1,134 @@
+package emailverify
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestVerifier_VerifyEmail(t *testing.T) {
+	verifier := NewVerifier(Options{DisposableDomains: []string{"burner.example"}})
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		email       string
+		valid       bool
+		disposable  bool
+		roleAccount bool
+	}{
+		{"personal address", "jane.doe@example.com", true, false, false},
+		{"invalid format", "jane.doe@", false, false, false},
+		{"disposable domain", "jane@mailinator.com", true, true, false},
+		{"disposable subdomain", "jane@eu.mailinator.com", true, true, false},
+		{"custom disposable domain", "jane@Burner.example", true, true, false},
+		{"role account", "NoReply@example.com", true, false, true},
+		{"tagged role account", "admin+alerts@example.com", true, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := verifier.VerifyEmail(ctx, tt.email)
+			require.NoError(t, err)
+			assert.Equal(t, tt.valid, result.Valid)
+			assert.Equal(t, tt.disposable, result.Disposable)
+			assert.Equal(t, tt.roleAccount, result.RoleAccount)
+			assert.Equal(t, tt.disposable || tt.roleAccount, result.Risky())
+			assert.False(t, result.MXChecked)
+		})
+	}
+}
+
+func TestVerifier_MXLookup(t *testing.T) {
+	resolver := &fakeResolver{records: map[string][]*net.MX{
+		"example.com": {{Host: "mx1.example.com.", Pref: 10}},
+		"nomail.com":  {{Host: ".", Pref: 0}},
+	}}
+	verifier := createTestVerifier(Options{CheckMX: true, Resolver: resolver})
+	ctx := context.Background()
+
+	result, err := verifier.VerifyEmail(ctx, "jane@example.com")
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.True(t, result.MXChecked)
+	assert.Equal(t, []string{"mx1.example.com"}, result.MXHosts)
+
+	// Lookups are cached per domain
+	_, err = verifier.VerifyEmail(ctx, "john@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, 1, resolver.lookups["example.com"])
+
+	// A null MX or a domain that does not exist accepts no mail
+	for _, email := range []string{"jane@nomail.com", "jane@missing.example"} {
+		result, err = verifier.VerifyEmail(ctx, email)
+		require.NoError(t, err)
+		assert.False(t, result.Valid, email)
+		assert.Equal(t, "domain does not accept email", result.Reason)
+	}
+
+	// A lookup that fails is an error, not an invalid address
+	resolver.err = fmt.Errorf("connection refused")
+	_, err = verifier.VerifyEmail(ctx, "jane@other.example")
+	require.Error(t, err)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeProviderUnavailable, notifErr.Code)
+}
+
+func TestVerifier_Check(t *testing.T) {
+	ctx := context.Background()
+
+	verifier := NewVerifier(Options{})
+	assert.NoError(t, verifier.Check(ctx, "to", "noreply@mailinator.com"))
+	assert.Error(t, verifier.Check(ctx, "to", "not-an-email"))
+
+	verifier = NewVerifier(Options{RejectDisposable: true, RejectRoleAccounts: true})
+	err := verifier.Check(ctx, "cc", "jane@yopmail.com")
+	require.Error(t, err)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, "cc", notifErr.Metadata["field"])
+	assert.Contains(t, err.Error(), "disposable")
+
+	err = verifier.Check(ctx, "to", "support@example.com")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "role account")
+}
+
+// Helper functions
+
+func createTestVerifier(options Options) *Verifier {
+	verifier := NewVerifier(options)
+	verifier.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+	return verifier
+}
+
+// fakeResolver answers MX lookups from a table; other domains do not exist
+type fakeResolver struct {
+	records map[string][]*net.MX
+	err     error
+	lookups map[string]int
+}
+
+func (r *fakeResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	if r.lookups == nil {
+		r.lookups = make(map[string]int)
+	}
+	r.lookups[name]++
+
+	if r.err != nil {
+		return nil, r.err
+	}
+	records, exists := r.records[name]
+	if !exists {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return records, nil
+}
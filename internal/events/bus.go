@@ -0,0 +1,190 @@
+// Package events provides an in-process event bus for notification
+// lifecycle events. Features such as webhooks, metrics and audit logging
+// subscribe to the bus instead of being built into the services.
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// EventType identifies a notification lifecycle event
+type EventType string
+
+const (
+	EventNotificationQueued       EventType = "notification.queued"
+	EventNotificationSent         EventType = "notification.sent"
+	EventNotificationDelivered    EventType = "notification.delivered"
+	EventNotificationFailed       EventType = "notification.failed"
+	EventNotificationRetried      EventType = "notification.retried"
+	EventNotificationSuppressed   EventType = "notification.suppressed"
+	EventNotificationRejected     EventType = "notification.rejected"
+	EventNotificationReplied      EventType = "notification.replied"
+	EventNotificationExpired      EventType = "notification.expired"
+	EventNotificationAcknowledged EventType = "notification.acknowledged"
+)
+
+// Event represents a notification lifecycle event
+type Event struct {
+	ID               string                    `json:"id"`
+	Type             EventType                 `json:"type"`
+	NotificationID   uuid.UUID                 `json:"notification_id"`
+	NotificationType models.NotificationType   `json:"notification_type"`
+	Recipient        string                    `json:"recipient"`
+	Status           models.NotificationStatus `json:"status"`
+	Attempt          int                       `json:"attempt"`
+	Reason           string                    `json:"reason,omitempty"` // why a notification was suppressed or rejected
+	Error            string                    `json:"error,omitempty"`
+	PayloadHash      string                    `json:"payload_hash,omitempty"`
+	CorrelationID    string                    `json:"correlation_id,omitempty"`
+	Metadata         map[string]string         `json:"metadata,omitempty"`
+	OccurredAt       time.Time                 `json:"occurred_at"`
+}
+
+// NewNotificationEvent creates an event describing the current state of a notification
+func NewNotificationEvent(eventType EventType, notification *models.Notification) Event {
+	var metadata map[string]string
+	if notification.Metadata != nil {
+		metadata = make(map[string]string, len(notification.Metadata))
+		for key, value := range notification.Metadata {
+			metadata[key] = value
+		}
+	}
+
+	return Event{
+		ID:               uuid.New().String(),
+		Type:             eventType,
+		NotificationID:   notification.ID,
+		NotificationType: notification.Type,
+		Recipient:        notification.Recipient,
+		Status:           notification.Status,
+		Attempt:          notification.RetryCount + 1,
+		Error:            notification.ErrorMsg,
+		CorrelationID:    notification.CorrelationID,
+		Metadata:         metadata,
+		OccurredAt:       time.Now(),
+	}
+}
+
+// Subscriber receives published events
+type Subscriber interface {
+	HandleEvent(ctx context.Context, event Event) error
+}
+
+// SubscriberFunc adapts a function to the Subscriber interface
+type SubscriberFunc func(ctx context.Context, event Event) error
+
+// HandleEvent implements the Subscriber interface
+func (f SubscriberFunc) HandleEvent(ctx context.Context, event Event) error {
+	return f(ctx, event)
+}
+
+// Publisher publishes events
+type Publisher interface {
+	Publish(ctx context.Context, event Event)
+}
+
+// subscription is a subscriber and the event types it receives
+type subscription struct {
+	id         int
+	subscriber Subscriber
+	types      map[EventType]bool // empty means all types
+}
+
+// Bus delivers published events synchronously to subscribers, in the order
+// they subscribed. Subscriber errors and panics are logged and never reach
+// the publisher, so a faulty subscriber cannot fail a send.
+type Bus struct {
+	mu            sync.RWMutex
+	subscriptions []subscription
+	nextID        int
+	logger        interfaces.Logger
+}
+
+// NewBus creates a new event bus
+func NewBus(logger interfaces.Logger) *Bus {
+	return &Bus{logger: logger}
+}
+
+// Subscribe registers a subscriber for the given event types, or for all
+// events when no types are given. It returns a function that unsubscribes.
+func (b *Bus) Subscribe(subscriber Subscriber, types ...EventType) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	filter := make(map[EventType]bool, len(types))
+	for _, eventType := range types {
+		filter[eventType] = true
+	}
+
+	b.nextID++
+	id := b.nextID
+	b.subscriptions = append(b.subscriptions, subscription{id: id, subscriber: subscriber, types: filter})
+
+	return func() {
+		b.unsubscribe(id)
+	}
+}
+
+// Publish implements the Publisher interface
+func (b *Bus) Publish(ctx context.Context, event Event) {
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	b.mu.RLock()
+	subscriptions := make([]subscription, len(b.subscriptions))
+	copy(subscriptions, b.subscriptions)
+	b.mu.RUnlock()
+
+	for _, sub := range subscriptions {
+		if len(sub.types) > 0 && !sub.types[event.Type] {
+			continue
+		}
+
+		if err := b.deliver(ctx, sub.subscriber, event); err != nil {
+			b.logger.Errorf("Event subscriber failed to handle %s for notification %s: %v", event.Type, event.NotificationID, err)
+		}
+	}
+}
+
+// SubscriberCount returns the number of registered subscribers
+func (b *Bus) SubscriberCount() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return len(b.subscriptions)
+}
+
+// deliver calls a subscriber, converting a panic into an error
+func (b *Bus) deliver(ctx context.Context, subscriber Subscriber, event Event) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("subscriber panicked: %v", recovered)
+		}
+	}()
+
+	return subscriber.HandleEvent(ctx, event)
+}
+
+// unsubscribe removes a subscription by ID
+func (b *Bus) unsubscribe(id int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, sub := range b.subscriptions {
+		if sub.id == id {
+			b.subscriptions = append(b.subscriptions[:i:i], b.subscriptions[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+)
+
+func TestBus_PublishAndFilter(t *testing.T) {
+	bus := NewBus(utils.NewSimpleLogger("info"))
+	ctx := context.Background()
+
+	var all, failures []EventType
+	bus.Subscribe(SubscriberFunc(func(ctx context.Context, event Event) error {
+		all = append(all, event.Type)
+		return nil
+	}))
+	unsubscribe := bus.Subscribe(SubscriberFunc(func(ctx context.Context, event Event) error {
+		failures = append(failures, event.Type)
+		return nil
+	}), EventNotificationFailed, EventNotificationSuppressed)
+	assert.Equal(t, 2, bus.SubscriberCount())
+
+	bus.Publish(ctx, Event{Type: EventNotificationQueued})
+	bus.Publish(ctx, Event{Type: EventNotificationFailed})
+
+	unsubscribe()
+	unsubscribe() // no effect once removed
+	bus.Publish(ctx, Event{Type: EventNotificationSuppressed})
+
+	assert.Equal(t, []EventType{EventNotificationQueued, EventNotificationFailed, EventNotificationSuppressed}, all)
+	assert.Equal(t, []EventType{EventNotificationFailed}, failures)
+	assert.Equal(t, 1, bus.SubscriberCount())
+}
+
+func TestBus_SubscriberFailuresAreIsolated(t *testing.T) {
+	bus := NewBus(utils.NewSimpleLogger("info"))
+
+	bus.Subscribe(SubscriberFunc(func(ctx context.Context, event Event) error {
+		return fmt.Errorf("webhook endpoint down")
+	}))
+	bus.Subscribe(SubscriberFunc(func(ctx context.Context, event Event) error {
+		panic("subscriber bug")
+	}))
+
+	received := 0
+	bus.Subscribe(SubscriberFunc(func(ctx context.Context, event Event) error {
+		received++
+		assert.NotEmpty(t, event.ID)
+		assert.False(t, event.OccurredAt.IsZero())
+		return nil
+	}))
+
+	assert.NotPanics(t, func() {
+		bus.Publish(context.Background(), Event{Type: EventNotificationSent})
+	})
+	assert.Equal(t, 1, received)
+}
+
+func TestNewNotificationEvent(t *testing.T) {
+	notification := &models.Notification{
+		ID:         uuid.New(),
+		Type:       models.NotificationTypeSMS,
+		Status:     models.StatusFailed,
+		Recipient:  "+14155550123",
+		ErrorMsg:   "carrier rejected",
+		RetryCount: 1,
+		Metadata:   map[string]string{"campaign_id": "c-1"},
+
+		CorrelationID: "req-42",
+	}
+
+	event := NewNotificationEvent(EventNotificationFailed, notification)
+	assert.Equal(t, notification.ID, event.NotificationID)
+	assert.Equal(t, models.NotificationTypeSMS, event.NotificationType)
+	assert.Equal(t, 2, event.Attempt)
+	assert.Equal(t, "carrier rejected", event.Error)
+	assert.Equal(t, "req-42", event.CorrelationID)
+
+	// Metadata is copied
+	event.Metadata["campaign_id"] = "changed"
+	require.Equal(t, "c-1", notification.Metadata["campaign_id"])
+}
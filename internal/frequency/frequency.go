@@ -0,0 +1,244 @@
+// Package frequency caps how many notifications of a category, such as
+// marketing, each recipient receives per channel within a period. Caps are
+// enforced by a send middleware; a send over the cap is dropped or
+// rescheduled for when the recipient's window has room again.
+package frequency
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pipeline"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// MiddlewareName is the name the capper's middleware is registered under
+const MiddlewareName = "frequency-cap"
+
+// Action is what happens to a send over its cap
+type Action string
+
+const (
+	ActionDrop       Action = "drop"
+	ActionReschedule Action = "reschedule"
+)
+
+// MetadataCapped marks errors for dropped sends
+const MetadataCapped = "frequency_capped"
+
+// rule is a validated cap
+type rule struct {
+	category models.Category
+	channel  models.NotificationType // empty applies to each channel
+	limit    int
+	period   time.Duration
+	action   Action
+}
+
+// Capper enforces frequency caps. Sends are counted per cap, recipient and
+// channel over a sliding window.
+type Capper struct {
+	mu      sync.Mutex
+	rules   []rule
+	sends   map[string][]time.Time
+	timers  map[*time.Timer]bool
+	sender  pipeline.Handler
+	logger  interfaces.Logger
+	now     func() time.Time
+	stopped bool
+}
+
+// NewCapper creates a capper for the configured caps
+func NewCapper(cfg config.FrequencyConfig, logger interfaces.Logger) (*Capper, error) {
+	c := &Capper{
+		sends:  make(map[string][]time.Time),
+		timers: make(map[*time.Timer]bool),
+		logger: logger,
+		now:    time.Now,
+	}
+
+	for i, frequencyCap := range cfg.Caps {
+		field := fmt.Sprintf("caps[%d]", i)
+		category := models.Category(frequencyCap.Category)
+		if !utils.IsValidCategory(category) {
+			return nil, errors.NewValidationError(field, fmt.Sprintf("unknown cap category: %q", category))
+		}
+		channel := models.NotificationType(frequencyCap.Channel)
+		if channel != "" && !utils.IsValidNotificationType(channel) {
+			return nil, errors.NewValidationError(field, fmt.Sprintf("unknown channel: %s", channel))
+		}
+		if frequencyCap.Limit < 0 || frequencyCap.Period <= 0 {
+			return nil, errors.NewValidationError(field, "cap limit must not be negative and period must be positive")
+		}
+
+		action := Action(frequencyCap.Action)
+		switch action {
+		case "":
+			action = ActionDrop
+		case ActionDrop, ActionReschedule:
+		default:
+			return nil, errors.NewValidationError(field, fmt.Sprintf("unknown cap action: %s", action))
+		}
+
+		c.rules = append(c.rules, rule{
+			category: category,
+			channel:  channel,
+			limit:    frequencyCap.Limit,
+			period:   frequencyCap.Period,
+			action:   action,
+		})
+	}
+
+	return c, nil
+}
+
+// SetSender sets the handler that sends rescheduled notifications, typically
+// the dispatcher's SendNotification. Rescheduled sends pass the caps again.
+func (c *Capper) SetSender(sender pipeline.Handler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sender = sender
+}
+
+// Middleware returns the send middleware enforcing the caps. Register it at
+// pipeline.StageRateLimit under MiddlewareName.
+func (c *Capper) Middleware() pipeline.Middleware {
+	return func(next pipeline.Handler) pipeline.Handler {
+		return func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+			keys, capped, retryAt := c.reserve(request)
+			if capped != nil {
+				return c.reject(request, capped, retryAt)
+			}
+
+			response, err := next(ctx, request)
+			if err != nil {
+				c.release(keys)
+			}
+			return response, err
+		}
+	}
+}
+
+// Pending returns the number of rescheduled sends waiting to be sent
+func (c *Capper) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.timers)
+}
+
+// Stop cancels rescheduled sends that are still waiting
+func (c *Capper) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for timer := range c.timers {
+		timer.Stop()
+		delete(c.timers, timer)
+	}
+	c.stopped = true
+}
+
+// reserve counts a send against every cap that applies to it. When a cap is
+// full nothing is counted, and the cap and the time it has room again are returned.
+func (c *Capper) reserve(request *models.NotificationRequest) ([]string, *rule, time.Time) {
+	if request.Category == "" {
+		return nil, nil, time.Time{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	var keys []string
+	for i := range c.rules {
+		r := &c.rules[i]
+		if r.category != request.Category || (r.channel != "" && r.channel != request.Type) {
+			continue
+		}
+
+		key := fmt.Sprintf("%d|%s|%s", i, request.Type, request.Recipient)
+		sends := prune(c.sends[key], now.Add(-r.period))
+		c.sends[key] = sends
+		if len(sends) >= r.limit {
+			retryAt := now.Add(r.period)
+			if r.limit > 0 {
+				retryAt = sends[len(sends)-r.limit].Add(r.period)
+			}
+			return nil, r, retryAt
+		}
+		keys = append(keys, key)
+	}
+
+	for _, key := range keys {
+		c.sends[key] = append(c.sends[key], now)
+	}
+	return keys, nil, time.Time{}
+}
+
+// release uncounts a reserved send that failed
+func (c *Capper) release(keys []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		if sends := c.sends[key]; len(sends) > 0 {
+			c.sends[key] = sends[:len(sends)-1]
+		}
+	}
+}
+
+// reject drops or reschedules a send over a cap
+func (c *Capper) reject(request *models.NotificationRequest, capped *rule, retryAt time.Time) (*models.NotificationResponse, error) {
+	message := fmt.Sprintf("frequency cap of %d %s notifications per %s reached for recipient", capped.limit, capped.category, capped.period)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if capped.action == ActionDrop || c.sender == nil || c.stopped {
+		retryAfter := int(retryAt.Sub(c.now()).Seconds()) + 1
+		err := errors.NewNotificationError(errors.ErrorCodeRateLimited, message).
+			WithMetadata(MetadataCapped, string(ActionDrop)).
+			WithMetadata("retry_after", strconv.Itoa(retryAfter))
+		return nil, err
+	}
+
+	rescheduled := *request
+	rescheduled.ScheduledAt = &retryAt
+	sender := c.sender
+
+	var timer *time.Timer
+	timer = time.AfterFunc(retryAt.Sub(c.now()), func() {
+		c.mu.Lock()
+		delete(c.timers, timer)
+		c.mu.Unlock()
+
+		if _, err := sender(context.Background(), &rescheduled); err != nil {
+			c.logger.Errorf("Rescheduled %s notification failed: %v", rescheduled.Type, err)
+		}
+	})
+	c.timers[timer] = true
+
+	c.logger.Infof("%s; %s notification rescheduled for %s", message, request.Type, retryAt.Format(time.RFC3339))
+	return &models.NotificationResponse{
+		Status:  models.StatusPending,
+		Message: fmt.Sprintf("%s; rescheduled for %s", message, retryAt.Format(time.RFC3339)),
+	}, nil
+}
+
+// prune drops send times at or before the window start
+func prune(sends []time.Time, windowStart time.Time) []time.Time {
+	i := 0
+	for i < len(sends) && !sends[i].After(windowStart) {
+		i++
+	}
+	return sends[i:]
+}
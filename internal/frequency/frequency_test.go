@@ -0,0 +1,185 @@
+package frequency
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pipeline"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestNewCapper_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		cap  config.FrequencyCap
+	}{
+		{"missing category", config.FrequencyCap{Limit: 1, Period: time.Hour}},
+		{"unknown category", config.FrequencyCap{Category: "newsletter", Limit: 1, Period: time.Hour}},
+		{"unknown channel", config.FrequencyCap{Category: "marketing", Channel: "fax", Limit: 1, Period: time.Hour}},
+		{"negative limit", config.FrequencyCap{Category: "marketing", Limit: -1, Period: time.Hour}},
+		{"zero period", config.FrequencyCap{Category: "marketing", Limit: 1}},
+		{"unknown action", config.FrequencyCap{Category: "marketing", Limit: 1, Period: time.Hour, Action: "defer"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCapper(config.FrequencyConfig{Caps: []config.FrequencyCap{tt.cap}}, utils.NewSimpleLogger("info"))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCapper_DropsOverCap(t *testing.T) {
+	capper, clock := createTestCapper(t, config.FrequencyCap{Category: "marketing", Channel: "email", Limit: 2, Period: 24 * time.Hour})
+	send := capper.Middleware()(createTestHandler(nil))
+
+	for i := 0; i < 2; i++ {
+		_, err := send(context.Background(), createTestRequest(models.NotificationTypeEmail, "marketing"))
+		require.NoError(t, err)
+	}
+
+	_, err := send(context.Background(), createTestRequest(models.NotificationTypeEmail, "marketing"))
+	require.Error(t, err)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeRateLimited, notifErr.Code)
+	assert.Equal(t, string(ActionDrop), notifErr.Metadata[MetadataCapped])
+	assert.NotEmpty(t, notifErr.Metadata["retry_after"])
+
+	// Other categories, channels and recipients are not capped
+	_, err = send(context.Background(), createTestRequest(models.NotificationTypeEmail, "transactional"))
+	assert.NoError(t, err)
+	_, err = send(context.Background(), createTestRequest(models.NotificationTypeSMS, "marketing"))
+	assert.NoError(t, err)
+	other := createTestRequest(models.NotificationTypeEmail, "marketing")
+	other.Recipient = "other@example.com"
+	_, err = send(context.Background(), other)
+	assert.NoError(t, err)
+
+	// The window slides
+	*clock = clock.Add(25 * time.Hour)
+	_, err = send(context.Background(), createTestRequest(models.NotificationTypeEmail, "marketing"))
+	assert.NoError(t, err)
+}
+
+func TestCapper_CapAppliesPerChannel(t *testing.T) {
+	capper, _ := createTestCapper(t, config.FrequencyCap{Category: "marketing", Limit: 1, Period: time.Hour})
+	send := capper.Middleware()(createTestHandler(nil))
+
+	_, err := send(context.Background(), createTestRequest(models.NotificationTypeEmail, "marketing"))
+	require.NoError(t, err)
+	_, err = send(context.Background(), createTestRequest(models.NotificationTypeSMS, "marketing"))
+	require.NoError(t, err)
+	_, err = send(context.Background(), createTestRequest(models.NotificationTypeSMS, "marketing"))
+	assert.Error(t, err)
+}
+
+func TestCapper_FailedSendsAreNotCounted(t *testing.T) {
+	capper, _ := createTestCapper(t, config.FrequencyCap{Category: "marketing", Limit: 1, Period: time.Hour})
+
+	failing := capper.Middleware()(createTestHandler(errors.ErrProviderNotFound))
+	_, err := failing(context.Background(), createTestRequest(models.NotificationTypeEmail, "marketing"))
+	require.Error(t, err)
+
+	send := capper.Middleware()(createTestHandler(nil))
+	_, err = send(context.Background(), createTestRequest(models.NotificationTypeEmail, "marketing"))
+	assert.NoError(t, err)
+}
+
+func TestCapper_Reschedules(t *testing.T) {
+	capper, err := NewCapper(config.FrequencyConfig{Caps: []config.FrequencyCap{
+		{Category: "marketing", Limit: 1, Period: 50 * time.Millisecond, Action: "reschedule"},
+	}}, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	defer capper.Stop()
+
+	var mu sync.Mutex
+	var sent []*models.NotificationRequest
+	send := capper.Middleware()(func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+		mu.Lock()
+		sent = append(sent, request)
+		mu.Unlock()
+		return &models.NotificationResponse{ID: uuid.New(), Status: models.StatusSent}, nil
+	})
+	capper.SetSender(send)
+
+	_, err = send(context.Background(), createTestRequest(models.NotificationTypeEmail, "marketing"))
+	require.NoError(t, err)
+
+	response, err := send(context.Background(), createTestRequest(models.NotificationTypeEmail, "marketing"))
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusPending, response.Status)
+	assert.Contains(t, response.Message, "rescheduled")
+	assert.Equal(t, 1, capper.Pending())
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(sent) == 2
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 0, capper.Pending())
+
+	mu.Lock()
+	assert.NotNil(t, sent[1].ScheduledAt)
+	mu.Unlock()
+}
+
+func TestCapper_StopCancelsRescheduled(t *testing.T) {
+	capper, _ := createTestCapper(t, config.FrequencyCap{Category: "marketing", Limit: 1, Period: time.Hour, Action: "reschedule"})
+	send := capper.Middleware()(createTestHandler(nil))
+	capper.SetSender(send)
+
+	_, err := send(context.Background(), createTestRequest(models.NotificationTypeEmail, "marketing"))
+	require.NoError(t, err)
+	_, err = send(context.Background(), createTestRequest(models.NotificationTypeEmail, "marketing"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, capper.Pending())
+
+	capper.Stop()
+	assert.Equal(t, 0, capper.Pending())
+
+	// Once stopped, sends over the cap are dropped
+	_, err = send(context.Background(), createTestRequest(models.NotificationTypeEmail, "marketing"))
+	assert.Error(t, err)
+}
+
+// Helper functions
+
+// createTestCapper creates a capper whose clock only moves when the test moves it
+func createTestCapper(t *testing.T, caps ...config.FrequencyCap) (*Capper, *time.Time) {
+	capper, err := NewCapper(config.FrequencyConfig{Caps: caps}, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+
+	clock := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	capper.now = func() time.Time { return clock }
+	return capper, &clock
+}
+
+func createTestHandler(err error) pipeline.Handler {
+	return func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+		if err != nil {
+			return nil, err
+		}
+		return &models.NotificationResponse{ID: uuid.New(), Status: models.StatusSent}, nil
+	}
+}
+
+func createTestRequest(channel models.NotificationType, category models.Category) *models.NotificationRequest {
+	return &models.NotificationRequest{
+		Type:      channel,
+		Priority:  models.PriorityNormal,
+		Recipient: "user@example.com",
+		Subject:   "Spring sale",
+		Body:      "The sale is on",
+		Category:  category,
+	}
+}
@@ -0,0 +1,132 @@
+// Package httpclient creates the HTTP clients providers use to call external
+// APIs. Clients with the same transport settings share one pooled,
+// keep-alive transport, so sends reuse open connections instead of paying
+// for TCP and TLS setup each time.
+package httpclient
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+)
+
+// Defaults applied to zero fields of a configuration
+const (
+	DefaultMaxIdleConns        = 100
+	DefaultMaxIdleConnsPerHost = 32
+	DefaultIdleConnTimeout     = 90 * time.Second
+	DefaultDialTimeout         = 5 * time.Second
+	DefaultKeepAlive           = 30 * time.Second
+	DefaultTLSHandshakeTimeout = 5 * time.Second
+)
+
+// Shared is the factory used by the built-in providers
+var Shared = NewFactory()
+
+// Factory creates HTTP clients and pools their transports
+type Factory struct {
+	mu         sync.Mutex
+	transports map[config.HTTPClientConfig]*http.Transport
+}
+
+// NewFactory creates a factory with no transports
+func NewFactory() *Factory {
+	return &Factory{transports: make(map[config.HTTPClientConfig]*http.Transport)}
+}
+
+// Client returns a client with the given overall request timeout whose
+// transport is shared with every other client of the same settings
+func (f *Factory) Client(cfg config.HTTPClientConfig, timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: f.Transport(cfg)}
+}
+
+// Transport returns the pooled transport for the settings, creating it on first use
+func (f *Factory) Transport(cfg config.HTTPClientConfig) *http.Transport {
+	cfg = withDefaults(cfg)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if transport, exists := f.transports[cfg]; exists {
+		return transport
+	}
+
+	dialer := &net.Dialer{Timeout: cfg.DialTimeout, KeepAlive: cfg.KeepAlive}
+	proxy := http.ProxyFromEnvironment
+	if cfg.PublicOnly {
+		// Addresses are checked after DNS resolution, so a public name
+		// resolving to an internal address is refused too. A proxy would
+		// connect on the client's behalf, so none is used.
+		dialer.Control = refusePrivate
+		proxy = nil
+	}
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     !cfg.DisableHTTP2,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		TLSHandshakeTimeout:   cfg.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: cfg.ResponseHeaderTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+	if cfg.DisableHTTP2 {
+		// A non-nil empty map turns off the transport's HTTP/2 upgrade
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+
+	f.transports[cfg] = transport
+	return transport
+}
+
+// Transports returns how many distinct transports the factory has created
+func (f *Factory) Transports() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.transports)
+}
+
+// CloseIdleConnections closes the idle connections of every transport, e.g.
+// at shutdown
+func (f *Factory) CloseIdleConnections() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, transport := range f.transports {
+		transport.CloseIdleConnections()
+	}
+}
+
+// refusePrivate refuses connections to addresses that are not public
+func refusePrivate(network, address string, conn syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !utils.IsPublicIP(ip) {
+		return fmt.Errorf("connection to non-public address %s refused", host)
+	}
+	return nil
+}
+
+// withDefaults fills the zero fields of a configuration
+func withDefaults(cfg config.HTTPClientConfig) config.HTTPClientConfig {
+	return config.HTTPClientConfig{
+		MaxIdleConns:        DefaultMaxIdleConns,
+		MaxIdleConnsPerHost: DefaultMaxIdleConnsPerHost,
+		IdleConnTimeout:     DefaultIdleConnTimeout,
+		DialTimeout:         DefaultDialTimeout,
+		KeepAlive:           DefaultKeepAlive,
+		TLSHandshakeTimeout: DefaultTLSHandshakeTimeout,
+	}.Merge(cfg)
+}
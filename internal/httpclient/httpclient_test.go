@@ -0,0 +1,94 @@
+package httpclient
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+)
+
+func TestFactory_SharesTransports(t *testing.T) {
+	factory := NewFactory()
+
+	first := factory.Client(config.HTTPClientConfig{}, 10*time.Second)
+	second := factory.Client(config.HTTPClientConfig{MaxIdleConnsPerHost: DefaultMaxIdleConnsPerHost}, 15*time.Second)
+	assert.Same(t, first.Transport, second.Transport)
+	assert.Equal(t, 10*time.Second, first.Timeout)
+	assert.Equal(t, 15*time.Second, second.Timeout)
+
+	other := factory.Client(config.HTTPClientConfig{MaxIdleConnsPerHost: 4}, 10*time.Second)
+	assert.NotSame(t, first.Transport, other.Transport)
+	assert.Equal(t, 2, factory.Transports())
+}
+
+func TestFactory_Transport(t *testing.T) {
+	factory := NewFactory()
+
+	transport := factory.Transport(config.HTTPClientConfig{MaxConnsPerHost: 8, ResponseHeaderTimeout: 3 * time.Second})
+	assert.Equal(t, DefaultMaxIdleConns, transport.MaxIdleConns)
+	assert.Equal(t, DefaultMaxIdleConnsPerHost, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, 8, transport.MaxConnsPerHost)
+	assert.Equal(t, DefaultIdleConnTimeout, transport.IdleConnTimeout)
+	assert.Equal(t, DefaultTLSHandshakeTimeout, transport.TLSHandshakeTimeout)
+	assert.Equal(t, 3*time.Second, transport.ResponseHeaderTimeout)
+	assert.True(t, transport.ForceAttemptHTTP2)
+	assert.Nil(t, transport.TLSNextProto)
+
+	transport = factory.Transport(config.HTTPClientConfig{DisableHTTP2: true})
+	assert.False(t, transport.ForceAttemptHTTP2)
+	assert.NotNil(t, transport.TLSNextProto)
+}
+
+func TestFactory_ReusesConnections(t *testing.T) {
+	var connections int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	factory := NewFactory()
+	defer factory.CloseIdleConnections()
+
+	for i := 0; i < 5; i++ {
+		// Each send creates its client, as providers do, yet reuses the connection
+		response, err := factory.Client(config.HTTPClientConfig{}, time.Second).Get(server.URL)
+		require.NoError(t, err)
+		io.Copy(io.Discard, response.Body)
+		response.Body.Close()
+	}
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&connections))
+}
+
+func TestFactory_PublicOnly(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	factory := NewFactory()
+	transport := factory.Transport(config.HTTPClientConfig{PublicOnly: true})
+	assert.Nil(t, transport.Proxy)
+
+	// The test server listens on loopback, which public-only clients refuse
+	_, err := factory.Client(config.HTTPClientConfig{PublicOnly: true}, time.Second).Get(server.URL)
+	assert.ErrorContains(t, err, "non-public address")
+
+	response, err := factory.Client(config.HTTPClientConfig{}, time.Second).Get(server.URL)
+	require.NoError(t, err)
+	response.Body.Close()
+}
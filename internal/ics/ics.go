@@ -0,0 +1,243 @@
+// Package ics generates RFC 5545 calendar invites for meeting and appointment
+// notifications, following the iTIP (RFC 5546) REQUEST and CANCEL methods.
+package ics
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// Method is the iTIP method of an invite
+type Method string
+
+const (
+	MethodRequest Method = "REQUEST" // create or update an event
+	MethodCancel  Method = "CANCEL"  // cancel an event sent earlier with the same UID
+)
+
+// productID identifies the generator in PRODID
+const productID = "-//notificationService//Calendar Invites//EN"
+
+// maxLineOctets is the longest content line before folding
+const maxLineOctets = 75
+
+// dateTimeFormat is the UTC DATE-TIME form
+const dateTimeFormat = "20060102T150405Z"
+
+// Person is an organizer or attendee
+type Person struct {
+	Name     string `json:"name,omitempty"`
+	Email    string `json:"email"`
+	Optional bool   `json:"optional,omitempty"` // attendees only
+}
+
+// Event is a calendar invite. Updates and cancellations must reuse the UID of
+// the original invite with a higher Sequence.
+type Event struct {
+	UID         string    `json:"uid,omitempty"` // generated for new requests
+	Sequence    int       `json:"sequence"`
+	Method      Method    `json:"method,omitempty"` // defaults to REQUEST
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Location    string    `json:"location,omitempty"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Organizer   Person    `json:"organizer"`
+	Attendees   []Person  `json:"attendees"`
+	Stamp       time.Time `json:"stamp,omitempty"` // DTSTAMP; defaults to now
+}
+
+// Validate checks the event can be sent with its method
+func (e Event) Validate() error {
+	switch e.Method {
+	case "", MethodRequest:
+	case MethodCancel:
+		if e.UID == "" {
+			return errors.NewValidationError("uid", "cancellations must name the UID of the original invite")
+		}
+	default:
+		return errors.NewValidationError("method", fmt.Sprintf("unsupported method: %s", e.Method))
+	}
+
+	if strings.TrimSpace(e.Title) == "" {
+		return errors.NewValidationError("title", "title is required")
+	}
+	if e.Start.IsZero() || e.End.IsZero() {
+		return errors.NewValidationError("start", "start and end times are required")
+	}
+	if !e.End.After(e.Start) {
+		return errors.NewValidationError("end", "end must be after start")
+	}
+	if e.Sequence < 0 {
+		return errors.NewValidationError("sequence", "sequence cannot be negative")
+	}
+	if err := utils.ValidateEmailAddress(e.Organizer.Email); err != nil {
+		return errors.NewValidationError("organizer", fmt.Sprintf("invalid organizer email: %s", e.Organizer.Email))
+	}
+	if len(e.Attendees) == 0 {
+		return errors.NewValidationError("attendees", "at least one attendee is required")
+	}
+	for _, attendee := range e.Attendees {
+		if err := utils.ValidateEmailAddress(attendee.Email); err != nil {
+			return errors.NewValidationError("attendees", fmt.Sprintf("invalid attendee email: %s", attendee.Email))
+		}
+	}
+	return nil
+}
+
+// Generate returns the event as an iCalendar object. A UID is generated for
+// requests without one; read it back from the UID property to send updates.
+func Generate(event Event) ([]byte, error) {
+	if err := event.Validate(); err != nil {
+		return nil, err
+	}
+
+	method := event.Method
+	if method == "" {
+		method = MethodRequest
+	}
+	uid := event.UID
+	if uid == "" {
+		uid = uuid.New().String() + "@notificationservice"
+	}
+	stamp := event.Stamp
+	if stamp.IsZero() {
+		stamp = time.Now()
+	}
+	status := "CONFIRMED"
+	if method == MethodCancel {
+		status = "CANCELLED"
+	}
+
+	var buf bytes.Buffer
+	write := func(line string) {
+		buf.WriteString(fold(line))
+		buf.WriteString("\r\n")
+	}
+
+	write("BEGIN:VCALENDAR")
+	write("VERSION:2.0")
+	write("PRODID:" + productID)
+	write("CALSCALE:GREGORIAN")
+	write("METHOD:" + string(method))
+	write("BEGIN:VEVENT")
+	write("UID:" + escapeText(uid))
+	write(fmt.Sprintf("SEQUENCE:%d", event.Sequence))
+	write("DTSTAMP:" + stamp.UTC().Format(dateTimeFormat))
+	write("DTSTART:" + event.Start.UTC().Format(dateTimeFormat))
+	write("DTEND:" + event.End.UTC().Format(dateTimeFormat))
+	write("SUMMARY:" + escapeText(event.Title))
+	if event.Description != "" {
+		write("DESCRIPTION:" + escapeText(event.Description))
+	}
+	if event.Location != "" {
+		write("LOCATION:" + escapeText(event.Location))
+	}
+	write("STATUS:" + status)
+	write("ORGANIZER" + commonName(event.Organizer.Name) + ":mailto:" + event.Organizer.Email)
+	for _, attendee := range event.Attendees {
+		role := "REQ-PARTICIPANT"
+		if attendee.Optional {
+			role = "OPT-PARTICIPANT"
+		}
+		write(fmt.Sprintf("ATTENDEE%s;ROLE=%s;PARTSTAT=NEEDS-ACTION;RSVP=%s:mailto:%s",
+			commonName(attendee.Name), role, rsvp(method), attendee.Email))
+	}
+	write("END:VEVENT")
+	write("END:VCALENDAR")
+
+	return buf.Bytes(), nil
+}
+
+// Attachment generates the event as an email attachment with the content
+// type calendar clients expect for the method
+func Attachment(event Event) (models.EmailAttachment, error) {
+	content, err := Generate(event)
+	if err != nil {
+		return models.EmailAttachment{}, err
+	}
+
+	method := event.Method
+	if method == "" {
+		method = MethodRequest
+	}
+	filename := "invite.ics"
+	if method == MethodCancel {
+		filename = "cancel.ics"
+	}
+
+	return models.EmailAttachment{
+		Filename:    filename,
+		Content:     content,
+		ContentType: ContentType(method),
+		Size:        int64(len(content)),
+	}, nil
+}
+
+// ContentType returns the MIME type of an invite sent with a method
+func ContentType(method Method) string {
+	return fmt.Sprintf("text/calendar; charset=UTF-8; method=%s", method)
+}
+
+// rsvp returns whether attendees are asked to reply
+func rsvp(method Method) string {
+	if method == MethodCancel {
+		return "FALSE"
+	}
+	return "TRUE"
+}
+
+// commonName returns the CN parameter for a name, if any. Parameter values
+// cannot contain double quotes, so they are dropped.
+func commonName(name string) string {
+	name = strings.TrimSpace(strings.ReplaceAll(name, `"`, ""))
+	if name == "" {
+		return ""
+	}
+	if strings.ContainsAny(name, ";:,") {
+		return `;CN="` + name + `"`
+	}
+	return ";CN=" + name
+}
+
+// escapeText escapes a TEXT value
+func escapeText(text string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(text)
+}
+
+// fold splits a content line into lines of at most 75 octets, continuing
+// each with a leading space, without splitting UTF-8 sequences
+func fold(line string) string {
+	if len(line) <= maxLineOctets {
+		return line
+	}
+
+	var folded strings.Builder
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		folded.WriteString(line[:cut])
+		folded.WriteString("\r\n ")
+		line = line[cut:]
+		limit = maxLineOctets - 1 // continuation lines start with a space
+	}
+	folded.WriteString(line)
+	return folded.String()
+}
@@ -0,0 +1,130 @@
+package ics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate_Request(t *testing.T) {
+	content, err := Generate(createTestEvent())
+	require.NoError(t, err)
+
+	ics := strings.ReplaceAll(string(content), "\r\n ", "") // unfold
+	assert.True(t, strings.HasPrefix(ics, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(ics, "END:VEVENT\r\nEND:VCALENDAR\r\n"))
+	assert.Contains(t, ics, "METHOD:REQUEST\r\n")
+	assert.Contains(t, ics, "UID:appointment-42@example.com\r\n")
+	assert.Contains(t, ics, "DTSTAMP:20240301T080000Z\r\n")
+	assert.Contains(t, ics, "DTSTART:20240301T090000Z\r\n")
+	assert.Contains(t, ics, "DTEND:20240301T093000Z\r\n")
+	assert.Contains(t, ics, `SUMMARY:Checkup\, Dr. Smith\; room 4`+"\r\n")
+	assert.Contains(t, ics, `DESCRIPTION:Bring your card\nArrive early`+"\r\n")
+	assert.Contains(t, ics, "STATUS:CONFIRMED\r\n")
+	assert.Contains(t, ics, `ORGANIZER;CN="Clinic: Front Desk":mailto:desk@clinic.example`+"\r\n")
+	assert.Contains(t, ics, "ATTENDEE;CN=Jane Doe;ROLE=REQ-PARTICIPANT;PARTSTAT=NEEDS-ACTION;RSVP=TRUE:mailto:jane@example.com\r\n")
+	assert.Contains(t, ics, "ROLE=OPT-PARTICIPANT")
+}
+
+func TestGenerate_Cancel(t *testing.T) {
+	event := createTestEvent()
+	event.Method = MethodCancel
+	event.Sequence = 1
+
+	content, err := Generate(event)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "METHOD:CANCEL\r\n")
+	assert.Contains(t, string(content), "SEQUENCE:1\r\n")
+	assert.Contains(t, string(content), "STATUS:CANCELLED\r\n")
+	assert.Contains(t, string(content), "RSVP=FALSE")
+}
+
+func TestGenerate_GeneratesUID(t *testing.T) {
+	event := createTestEvent()
+	event.UID = ""
+
+	content, err := Generate(event)
+	require.NoError(t, err)
+	assert.Regexp(t, `UID:[0-9a-f-]{36}@notificationservice\r\n`, string(content))
+}
+
+func TestGenerate_FoldsLongLines(t *testing.T) {
+	event := createTestEvent()
+	event.Description = strings.Repeat("é", 100)
+
+	content, err := Generate(event)
+	require.NoError(t, err)
+
+	for _, line := range strings.Split(strings.TrimSuffix(string(content), "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75, line)
+		assert.True(t, utf8ValidLine(line), line)
+	}
+	unfolded := strings.ReplaceAll(string(content), "\r\n ", "")
+	assert.Contains(t, unfolded, "DESCRIPTION:"+strings.Repeat("é", 100)+"\r\n")
+}
+
+func TestEvent_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Event)
+	}{
+		{"missing title", func(e *Event) { e.Title = " " }},
+		{"missing start", func(e *Event) { e.Start = time.Time{} }},
+		{"end before start", func(e *Event) { e.End = e.Start.Add(-time.Minute) }},
+		{"invalid organizer", func(e *Event) { e.Organizer.Email = "desk" }},
+		{"no attendees", func(e *Event) { e.Attendees = nil }},
+		{"invalid attendee", func(e *Event) { e.Attendees[0].Email = "jane" }},
+		{"unknown method", func(e *Event) { e.Method = "PUBLISH" }},
+		{"cancel without uid", func(e *Event) { e.Method = MethodCancel; e.UID = "" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := createTestEvent()
+			tt.modify(&event)
+			_, err := Generate(event)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestAttachment(t *testing.T) {
+	attachment, err := Attachment(createTestEvent())
+	require.NoError(t, err)
+	assert.Equal(t, "invite.ics", attachment.Filename)
+	assert.Equal(t, "text/calendar; charset=UTF-8; method=REQUEST", attachment.ContentType)
+	assert.Equal(t, int64(len(attachment.Content)), attachment.Size)
+
+	event := createTestEvent()
+	event.Method = MethodCancel
+	attachment, err = Attachment(event)
+	require.NoError(t, err)
+	assert.Equal(t, "cancel.ics", attachment.Filename)
+	assert.Equal(t, "text/calendar; charset=UTF-8; method=CANCEL", attachment.ContentType)
+}
+
+// Helper functions
+
+func createTestEvent() Event {
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	return Event{
+		UID:         "appointment-42@example.com",
+		Title:       "Checkup, Dr. Smith; room 4",
+		Description: "Bring your card\nArrive early",
+		Start:       start,
+		End:         start.Add(30 * time.Minute),
+		Organizer:   Person{Name: "Clinic: Front Desk", Email: "desk@clinic.example"},
+		Attendees: []Person{
+			{Name: "Jane Doe", Email: "jane@example.com"},
+			{Email: "john@example.com", Optional: true},
+		},
+		Stamp: start.Add(-time.Hour),
+	}
+}
+
+func utf8ValidLine(line string) bool {
+	return strings.ToValidUTF8(line, "�") == line
+}
@@ -0,0 +1,180 @@
+// Package inbound maps email replies back to the notifications they answer.
+// Emails are sent with a plus address as their Reply-To, such as
+// reply+<token>@replies.example.com, whose token names the notification and
+// is signed so it cannot be forged. An inbound parse webhook (SendGrid Inbound
+// Parse or SES receiving) posts the replies, and each one is published as a
+// notification.replied event for the application to consume.
+package inbound
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/events"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// defaultLocalPart is the local part of reply addresses without one configured
+const defaultLocalPart = "reply"
+
+// signatureLength is the number of hex characters of a token's signature
+const signatureLength = 16
+
+// Reply event metadata keys
+const (
+	MetadataReplyFrom      = "reply_from"
+	MetadataReplySubject   = "reply_subject"
+	MetadataReplyText      = "reply_text"
+	MetadataReplyProvider  = "reply_provider"
+	MetadataReplyInReplyTo = "reply_in_reply_to" // Message-ID the reply answers
+)
+
+// Message is an inbound email as posted by an inbound parse webhook
+type Message struct {
+	From       string   `json:"from"`
+	Recipients []string `json:"recipients"` // envelope recipients, or To and Cc without an envelope
+	Subject    string   `json:"subject"`
+	Text       string   `json:"text,omitempty"`
+	HTML       string   `json:"html,omitempty"`
+	InReplyTo  string   `json:"in_reply_to,omitempty"`
+	MessageID  string   `json:"message_id,omitempty"` // the email's Message-ID, or SES's ID for it
+	Provider   string   `json:"provider"`             // "sendgrid" or "ses"
+}
+
+// Result is the outcome of handling an inbound email
+type Result struct {
+	Status         string `json:"status"` // "replied" or "ignored"
+	NotificationID string `json:"notification_id,omitempty"`
+	Reason         string `json:"reason,omitempty"` // why the email was ignored
+}
+
+// Router creates reply addresses for notifications and routes replies to
+// them back to the notifications
+type Router struct {
+	domain     string
+	localPart  string
+	key        []byte
+	repository interfaces.NotificationRepository
+	publisher  events.Publisher
+	logger     interfaces.Logger
+}
+
+// NewRouter creates a router. Replies are looked up in the repository and
+// published to the publisher.
+func NewRouter(cfg config.InboundConfig, repository interfaces.NotificationRepository, publisher events.Publisher, logger interfaces.Logger) (*Router, error) {
+	if cfg.ReplyDomain == "" {
+		return nil, errors.NewValidationError("reply_domain", "inbound reply domain is required")
+	}
+	if cfg.SigningKey == "" {
+		return nil, errors.NewValidationError("signing_key", "inbound signing key is required")
+	}
+
+	localPart := strings.ToLower(cfg.LocalPart)
+	if localPart == "" {
+		localPart = defaultLocalPart
+	}
+
+	return &Router{
+		domain:     strings.ToLower(cfg.ReplyDomain),
+		localPart:  localPart,
+		key:        []byte(cfg.SigningKey),
+		repository: repository,
+		publisher:  publisher,
+		logger:     logger,
+	}, nil
+}
+
+// ReplyAddress returns the address replies to a notification go to
+func (r *Router) ReplyAddress(notificationID uuid.UUID) string {
+	id := strings.ReplaceAll(notificationID.String(), "-", "")
+	return fmt.Sprintf("%s+%s.%s@%s", r.localPart, id, r.sign(id), r.domain)
+}
+
+// NotificationID returns the notification a reply address belongs to. It
+// reports false for other addresses and for tokens with a bad signature.
+func (r *Router) NotificationID(address string) (uuid.UUID, bool) {
+	address = strings.ToLower(strings.TrimSpace(address))
+	at := strings.LastIndex(address, "@")
+	if at < 0 || address[at+1:] != r.domain {
+		return uuid.Nil, false
+	}
+
+	local := address[:at]
+	if !strings.HasPrefix(local, r.localPart+"+") {
+		return uuid.Nil, false
+	}
+	token := local[len(r.localPart)+1:]
+
+	dot := strings.LastIndex(token, ".")
+	if dot < 0 {
+		return uuid.Nil, false
+	}
+	id, signature := token[:dot], token[dot+1:]
+	if !hmac.Equal([]byte(signature), []byte(r.sign(id))) {
+		return uuid.Nil, false
+	}
+
+	notificationID, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return notificationID, true
+}
+
+// Handle maps an inbound email to the notification it replies to and
+// publishes a notification.replied event. Emails that are not replies to a
+// known notification are ignored rather than failed, so the webhook does not
+// retry them.
+func (r *Router) Handle(ctx context.Context, message Message) (Result, error) {
+	notificationID, found := uuid.Nil, false
+	for _, recipient := range message.Recipients {
+		if notificationID, found = r.NotificationID(recipient); found {
+			break
+		}
+	}
+	if !found {
+		r.logger.Infof("Ignoring inbound email from %s: no reply address among its recipients", message.From)
+		return Result{Status: "ignored", Reason: "not addressed to a reply address"}, nil
+	}
+
+	notification, err := r.repository.GetByID(ctx, notificationID.String())
+	if err != nil {
+		if notifErr, ok := errors.AsNotificationError(err); ok && notifErr.Code == errors.ErrorCodeNotFound {
+			r.logger.Infof("Ignoring inbound email from %s: notification %s not found", message.From, notificationID)
+			return Result{Status: "ignored", Reason: "notification not found"}, nil
+		}
+		return Result{}, err
+	}
+
+	event := events.NewNotificationEvent(events.EventNotificationReplied, notification)
+	if event.Metadata == nil {
+		event.Metadata = make(map[string]string)
+	}
+	event.Metadata[MetadataReplyFrom] = message.From
+	event.Metadata[MetadataReplySubject] = message.Subject
+	event.Metadata[MetadataReplyText] = message.Text
+	event.Metadata[MetadataReplyProvider] = message.Provider
+	if message.InReplyTo != "" {
+		event.Metadata[MetadataReplyInReplyTo] = message.InReplyTo
+	}
+
+	if r.publisher != nil {
+		r.publisher.Publish(ctx, event)
+	}
+	r.logger.Infof("Reply from %s to notification %s", message.From, notification.ID)
+	return Result{Status: "replied", NotificationID: notification.ID.String()}, nil
+}
+
+// sign returns the signature of a notification token
+func (r *Router) sign(id string) string {
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(id))
+	return hex.EncodeToString(mac.Sum(nil))[:signatureLength]
+}
@@ -0,0 +1,252 @@
+package inbound
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/events"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/repository"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestNewRouter_RequiresDomainAndKey(t *testing.T) {
+	_, err := NewRouter(config.InboundConfig{SigningKey: "secret"}, repository.NewMemoryRepository(), nil, utils.NewSimpleLogger("info"))
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, "reply_domain", notifErr.Metadata["field"])
+
+	_, err = NewRouter(config.InboundConfig{ReplyDomain: "replies.example.com"}, repository.NewMemoryRepository(), nil, utils.NewSimpleLogger("info"))
+	notifErr, ok = errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, "signing_key", notifErr.Metadata["field"])
+}
+
+func TestRouter_ReplyAddress(t *testing.T) {
+	router, _, _ := createTestRouter(t)
+	id := uuid.New()
+
+	address := router.ReplyAddress(id)
+	assert.True(t, strings.HasPrefix(address, "reply+"))
+	assert.True(t, strings.HasSuffix(address, "@replies.example.com"))
+
+	parsed, ok := router.NotificationID(address)
+	require.True(t, ok)
+	assert.Equal(t, id, parsed)
+
+	// Mail servers may change the case of addresses
+	parsed, ok = router.NotificationID(strings.ToUpper(address))
+	require.True(t, ok)
+	assert.Equal(t, id, parsed)
+}
+
+func TestRouter_NotificationID_Rejects(t *testing.T) {
+	router, _, _ := createTestRouter(t)
+	address := router.ReplyAddress(uuid.New())
+	other, err := NewRouter(config.InboundConfig{ReplyDomain: "replies.example.com", SigningKey: "other"}, repository.NewMemoryRepository(), nil, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		address string
+	}{
+		{"other domain", strings.Replace(address, "replies.example.com", "example.com", 1)},
+		{"other local part", strings.Replace(address, "reply+", "support+", 1)},
+		{"no token", "reply@replies.example.com"},
+		{"forged signature", other.ReplyAddress(uuid.New())},
+		{"tampered id", "reply+" + strings.Repeat("0", 32) + address[strings.LastIndex(address, "."):]},
+		{"not an address", "reply"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ok := router.NotificationID(tt.address)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestRouter_Handle(t *testing.T) {
+	router, repo, replies := createTestRouter(t)
+	notification := createTestNotification(t, repo)
+
+	result, err := router.Handle(context.Background(), Message{
+		From:       "customer@example.com",
+		Recipients: []string{"support@example.com", router.ReplyAddress(notification.ID)},
+		Subject:    "Re: Your order",
+		Text:       "Can I change the delivery address?",
+		InReplyTo:  "<original@example.com>",
+		Provider:   "sendgrid",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "replied", result.Status)
+	assert.Equal(t, notification.ID.String(), result.NotificationID)
+
+	require.Len(t, *replies, 1)
+	event := (*replies)[0]
+	assert.Equal(t, events.EventNotificationReplied, event.Type)
+	assert.Equal(t, notification.ID, event.NotificationID)
+	assert.Equal(t, models.NotificationTypeEmail, event.NotificationType)
+	assert.Equal(t, "customer@example.com", event.Metadata[MetadataReplyFrom])
+	assert.Equal(t, "Re: Your order", event.Metadata[MetadataReplySubject])
+	assert.Equal(t, "Can I change the delivery address?", event.Metadata[MetadataReplyText])
+	assert.Equal(t, "sendgrid", event.Metadata[MetadataReplyProvider])
+	assert.Equal(t, "<original@example.com>", event.Metadata[MetadataReplyInReplyTo])
+	assert.Equal(t, "web", event.Metadata["source"])
+}
+
+func TestRouter_Handle_Ignored(t *testing.T) {
+	router, _, replies := createTestRouter(t)
+
+	result, err := router.Handle(context.Background(), Message{From: "customer@example.com", Recipients: []string{"support@example.com"}})
+	require.NoError(t, err)
+	assert.Equal(t, "ignored", result.Status)
+
+	// A validly signed address of a notification that no longer exists
+	result, err = router.Handle(context.Background(), Message{From: "customer@example.com", Recipients: []string{router.ReplyAddress(uuid.New())}})
+	require.NoError(t, err)
+	assert.Equal(t, "ignored", result.Status)
+	assert.Equal(t, "notification not found", result.Reason)
+
+	assert.Empty(t, *replies)
+}
+
+func TestParseSendGrid(t *testing.T) {
+	form := url.Values{
+		"from":     {"Jane Customer <customer@example.com>"},
+		"to":       {"reply+abc.def@replies.example.com"},
+		"subject":  {"Re: Your order"},
+		"text":     {"Thanks!"},
+		"html":     {"<p>Thanks!</p>"},
+		"envelope": {`{"from":"bounce@example.com","to":["reply+123.456@replies.example.com"]}`},
+		"headers":  {"Subject: Re: Your order\nIn-Reply-To: <original@example.com>\nMessage-ID: <reply-1@example.com>\nTo: reply+abc.def@replies.example.com"},
+	}
+	request := httptest.NewRequest(http.MethodPost, "/v1/inbound/sendgrid", strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	message, err := ParseSendGrid(request)
+	require.NoError(t, err)
+	assert.Equal(t, "customer@example.com", message.From)
+	assert.Equal(t, []string{"reply+123.456@replies.example.com"}, message.Recipients)
+	assert.Equal(t, "Re: Your order", message.Subject)
+	assert.Equal(t, "Thanks!", message.Text)
+	assert.Equal(t, "<p>Thanks!</p>", message.HTML)
+	assert.Equal(t, "<original@example.com>", message.InReplyTo)
+	assert.Equal(t, "<reply-1@example.com>", message.MessageID)
+	assert.Equal(t, "sendgrid", message.Provider)
+
+	// Without an envelope the To and Cc headers are used
+	form.Del("envelope")
+	form.Set("cc", "Team <team@example.com>")
+	request = httptest.NewRequest(http.MethodPost, "/v1/inbound/sendgrid", strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	message, err = ParseSendGrid(request)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"reply+abc.def@replies.example.com", "team@example.com"}, message.Recipients)
+}
+
+func TestParseSES(t *testing.T) {
+	content := strings.Join([]string{
+		"From: Jane Customer <customer@example.com>",
+		"To: reply+abc.def@replies.example.com",
+		"Subject: Re: Your order",
+		"In-Reply-To: <original@example.com>",
+		`Content-Type: multipart/alternative; boundary="b1"`,
+		"",
+		"--b1",
+		"Content-Type: text/plain; charset=utf-8",
+		"",
+		"Thanks!",
+		"--b1",
+		"Content-Type: text/html; charset=utf-8",
+		"",
+		"<p>Thanks!</p>",
+		"--b1--",
+		"",
+	}, "\r\n")
+	notification, err := json.Marshal(map[string]interface{}{
+		"notificationType": "Received",
+		"mail": map[string]interface{}{
+			"messageId":     "o3vrnil0e2ic28trm7dfhrc2v0clambda4nbp0g1",
+			"source":        "bounce@example.com",
+			"destination":   []string{"reply+abc.def@replies.example.com"},
+			"commonHeaders": map[string]interface{}{"from": []string{"Jane Customer <customer@example.com>"}, "subject": "Re: Your order"},
+		},
+		"content": content,
+	})
+	require.NoError(t, err)
+	body, err := json.Marshal(map[string]string{"Type": "Notification", "Message": string(notification)})
+	require.NoError(t, err)
+
+	message, subscribeURL, err := ParseSES(body)
+	require.NoError(t, err)
+	require.NotNil(t, message)
+	assert.Empty(t, subscribeURL)
+	assert.Equal(t, "customer@example.com", message.From)
+	assert.Equal(t, []string{"reply+abc.def@replies.example.com"}, message.Recipients)
+	assert.Equal(t, "Re: Your order", message.Subject)
+	assert.Equal(t, "Thanks!", message.Text)
+	assert.Equal(t, "<p>Thanks!</p>", message.HTML)
+	assert.Equal(t, "<original@example.com>", message.InReplyTo)
+	assert.Equal(t, "o3vrnil0e2ic28trm7dfhrc2v0clambda4nbp0g1", message.MessageID)
+	assert.Equal(t, "ses", message.Provider)
+
+	// Bare notifications are accepted too
+	message, _, err = ParseSES(notification)
+	require.NoError(t, err)
+	assert.Equal(t, "customer@example.com", message.From)
+}
+
+func TestParseSES_SubscriptionConfirmation(t *testing.T) {
+	message, subscribeURL, err := ParseSES([]byte(`{"Type":"SubscriptionConfirmation","SubscribeURL":"https://sns.example.com/confirm"}`))
+	require.NoError(t, err)
+	assert.Nil(t, message)
+	assert.Equal(t, "https://sns.example.com/confirm", subscribeURL)
+
+	_, _, err = ParseSES([]byte(`{"notificationType":"Bounce"}`))
+	assert.Error(t, err)
+
+	_, _, err = ParseSES([]byte(`not json`))
+	assert.Error(t, err)
+}
+
+// Helper functions
+
+func createTestRouter(t *testing.T) (*Router, *repository.MemoryRepository, *[]events.Event) {
+	repo := repository.NewMemoryRepository()
+	bus := events.NewBus(utils.NewSimpleLogger("info"))
+
+	var replies []events.Event
+	bus.Subscribe(events.SubscriberFunc(func(ctx context.Context, event events.Event) error {
+		replies = append(replies, event)
+		return nil
+	}), events.EventNotificationReplied)
+
+	router, err := NewRouter(config.InboundConfig{ReplyDomain: "Replies.Example.com", SigningKey: "secret"}, repo, bus, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	return router, repo, &replies
+}
+
+func createTestNotification(t *testing.T, repo *repository.MemoryRepository) *models.Notification {
+	notification := &models.Notification{
+		ID:        uuid.New(),
+		Type:      models.NotificationTypeEmail,
+		Status:    models.StatusSent,
+		Recipient: "customer@example.com",
+		Subject:   "Your order",
+		Metadata:  map[string]string{"source": "web"},
+	}
+	require.NoError(t, repo.Save(context.Background(), notification))
+	return notification
+}
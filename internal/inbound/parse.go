@@ -0,0 +1,202 @@
+package inbound
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/mail"
+	"net/textproto"
+	"strings"
+
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// maxMessageSize bounds the size of an inbound email
+const maxMessageSize = 10 << 20
+
+// ParseSendGrid parses a SendGrid Inbound Parse post. The envelope's
+// recipients are used when it is posted, otherwise the To and Cc headers.
+func ParseSendGrid(r *http.Request) (Message, error) {
+	if err := r.ParseMultipartForm(maxMessageSize); err != nil && err != http.ErrNotMultipart {
+		return Message{}, invalidMessage("inbound parse post is not valid form data", err)
+	}
+	if err := r.ParseForm(); err != nil {
+		return Message{}, invalidMessage("inbound parse post is not valid form data", err)
+	}
+
+	message := Message{
+		Subject:  r.FormValue("subject"),
+		Text:     r.FormValue("text"),
+		HTML:     r.FormValue("html"),
+		Provider: "sendgrid",
+	}
+
+	var envelope struct {
+		From string   `json:"from"`
+		To   []string `json:"to"`
+	}
+	if raw := r.FormValue("envelope"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &envelope); err != nil {
+			return Message{}, invalidMessage("inbound parse envelope is not valid JSON", err)
+		}
+	}
+
+	message.From = addressOf(r.FormValue("from"))
+	if message.From == "" {
+		message.From = envelope.From
+	}
+	message.Recipients = envelope.To
+	if len(message.Recipients) == 0 {
+		message.Recipients = append(addressesOf(r.FormValue("to")), addressesOf(r.FormValue("cc"))...)
+	}
+
+	if raw := r.FormValue("headers"); raw != "" {
+		header, err := textproto.NewReader(bufio.NewReader(strings.NewReader(raw + "\r\n\r\n"))).ReadMIMEHeader()
+		if err == nil || err == io.EOF {
+			message.InReplyTo = header.Get("In-Reply-To")
+			message.MessageID = header.Get("Message-ID")
+		}
+	}
+
+	return message, nil
+}
+
+// sesNotification is an SES receipt notification, the content of which is
+// included when the receipt rule's SNS action sends it
+type sesNotification struct {
+	NotificationType string `json:"notificationType"`
+	Mail             struct {
+		MessageID     string   `json:"messageId"`
+		Source        string   `json:"source"`
+		Destination   []string `json:"destination"`
+		CommonHeaders struct {
+			From    []string `json:"from"`
+			Subject string   `json:"subject"`
+		} `json:"commonHeaders"`
+	} `json:"mail"`
+	Content string `json:"content"`
+}
+
+// snsMessage is the SNS envelope SES notifications are delivered in
+type snsMessage struct {
+	Type         string `json:"Type"`
+	Message      string `json:"Message"`
+	SubscribeURL string `json:"SubscribeURL"`
+}
+
+// ParseSES parses an SES receipt notification, either bare or in its SNS
+// envelope. For an SNS subscription confirmation it returns no message and
+// the URL that confirms the subscription.
+func ParseSES(body []byte) (*Message, string, error) {
+	var envelope snsMessage
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, "", invalidMessage("SES notification is not valid JSON", err)
+	}
+	switch envelope.Type {
+	case "SubscriptionConfirmation":
+		return nil, envelope.SubscribeURL, nil
+	case "Notification":
+		body = []byte(envelope.Message)
+	}
+
+	var notification sesNotification
+	if err := json.Unmarshal(body, &notification); err != nil {
+		return nil, "", invalidMessage("SES notification is not valid JSON", err)
+	}
+	if notification.NotificationType != "Received" {
+		return nil, "", errors.NewNotificationError(errors.ErrorCodeInvalidRequest, "SES notification is not a received email")
+	}
+
+	message := &Message{
+		From:       notification.Mail.Source,
+		Recipients: notification.Mail.Destination,
+		Subject:    notification.Mail.CommonHeaders.Subject,
+		MessageID:  notification.Mail.MessageID,
+		Provider:   "ses",
+	}
+	if len(notification.Mail.CommonHeaders.From) > 0 {
+		if from := addressOf(notification.Mail.CommonHeaders.From[0]); from != "" {
+			message.From = from
+		}
+	}
+
+	if notification.Content != "" {
+		raw, err := mail.ReadMessage(strings.NewReader(notification.Content))
+		if err != nil {
+			return nil, "", invalidMessage("SES notification content is not a valid email", err)
+		}
+		message.InReplyTo = raw.Header.Get("In-Reply-To")
+		message.Text, message.HTML = readBodies(raw.Header.Get("Content-Type"), raw.Body)
+	}
+
+	return message, "", nil
+}
+
+// readBodies returns the text and HTML bodies of an email, looking inside
+// multipart bodies. Parts it cannot read are skipped.
+func readBodies(contentType string, body io.Reader) (string, string) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		var text, html string
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				return text, html
+			}
+			partText, partHTML := readBodies(part.Header.Get("Content-Type"), part)
+			if text == "" {
+				text = partText
+			}
+			if html == "" {
+				html = partHTML
+			}
+		}
+	}
+
+	content, err := io.ReadAll(io.LimitReader(body, maxMessageSize))
+	if err != nil {
+		return "", ""
+	}
+	switch mediaType {
+	case "text/plain":
+		return string(content), ""
+	case "text/html":
+		return "", string(content)
+	}
+	return "", ""
+}
+
+// addressOf returns the address of a From header, or "" if it has none
+func addressOf(header string) string {
+	address, err := mail.ParseAddress(header)
+	if err != nil {
+		return ""
+	}
+	return address.Address
+}
+
+// addressesOf returns the addresses of a To or Cc header
+func addressesOf(header string) []string {
+	list, err := mail.ParseAddressList(header)
+	if err != nil {
+		return nil
+	}
+	addresses := make([]string, len(list))
+	for i, address := range list {
+		addresses[i] = address.Address
+	}
+	return addresses
+}
+
+// invalidMessage is the error of an inbound post that cannot be parsed
+func invalidMessage(message string, cause error) error {
+	return errors.NewNotificationErrorWithDetails(errors.ErrorCodeInvalidRequest, message, cause.Error())
+}
@@ -0,0 +1,185 @@
+package jobs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// macros are the named schedules accepted in place of a cron expression
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronField is the range of values one field of a cron expression accepts
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7}, // 0 and 7 are both Sunday
+}
+
+// Schedule is a parsed cron expression. It is immutable and safe for
+// concurrent use.
+type Schedule struct {
+	expression string
+	every      time.Duration
+
+	minutes, hours, days, months, weekdays uint64 // bit sets of matching values
+	anyDay, anyWeekday                     bool   // the day field was not restricted
+}
+
+// ParseSchedule parses a standard five-field cron expression (minute, hour,
+// day of month, month, day of week) with *, lists, ranges and steps, e.g.
+// "*/15 * * * *" or "0 3 * * 1-5". It also accepts the macros @yearly,
+// @monthly, @weekly, @daily and @hourly, and "@every <duration>" for a fixed
+// interval. As in cron, when both day fields are restricted a time matching
+// either of them matches.
+func ParseSchedule(expression string) (*Schedule, error) {
+	expression = strings.TrimSpace(expression)
+	schedule := &Schedule{expression: expression}
+
+	if interval, found := strings.CutPrefix(expression, "@every "); found {
+		every, err := time.ParseDuration(strings.TrimSpace(interval))
+		if err != nil || every <= 0 {
+			return nil, errors.NewValidationError("schedule", fmt.Sprintf("invalid interval in %q", expression))
+		}
+		schedule.every = every
+		return schedule, nil
+	}
+
+	spec := expression
+	if strings.HasPrefix(spec, "@") {
+		var known bool
+		if spec, known = macros[spec]; !known {
+			return nil, errors.NewValidationError("schedule", fmt.Sprintf("unknown schedule macro %q", expression))
+		}
+	}
+
+	parts := strings.Fields(spec)
+	if len(parts) != len(cronFields) {
+		return nil, errors.NewValidationError("schedule", fmt.Sprintf("cron expression %q must have %d fields", expression, len(cronFields)))
+	}
+
+	sets := make([]uint64, len(cronFields))
+	for i, part := range parts {
+		set, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return nil, errors.NewValidationError("schedule", fmt.Sprintf("cron expression %q: %s", expression, err))
+		}
+		sets[i] = set
+	}
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1 << 0
+	}
+
+	schedule.minutes, schedule.hours, schedule.days, schedule.months, schedule.weekdays = sets[0], sets[1], sets[2], sets[3], sets[4]
+	schedule.anyDay = strings.HasPrefix(parts[2], "*")
+	schedule.anyWeekday = strings.HasPrefix(parts[4], "*")
+
+	reference := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if schedule.Next(reference).IsZero() {
+		return nil, errors.NewValidationError("schedule", fmt.Sprintf("cron expression %q never matches", expression))
+	}
+	return schedule, nil
+}
+
+// String returns the expression the schedule was parsed from
+func (s *Schedule) String() string {
+	return s.expression
+}
+
+// Next returns the first time after the given one that the schedule
+// matches, in the given time's location, or the zero time if it matches
+// none within five years. Intervals are aligned to multiples of the interval
+// since the zero time, so instances computing the next run agree on it.
+func (s *Schedule) Next(after time.Time) time.Time {
+	if s.every > 0 {
+		return after.Truncate(s.every).Add(s.every)
+	}
+
+	location := after.Location()
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.months&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, location)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, location)
+		case s.hours&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, location)
+		case s.minutes&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches checks a time's day against the day of month and day of week fields
+func (s *Schedule) dayMatches(t time.Time) bool {
+	day := s.days&(1<<uint(t.Day())) != 0
+	weekday := s.weekdays&(1<<uint(t.Weekday())) != 0
+	if s.anyDay || s.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
+
+// parseCronField parses one field of a cron expression into the bit set of
+// the values it matches
+func parseCronField(part string, field cronField) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(part, ",") {
+		span, stepText, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepText); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q in %s", stepText, field.name)
+			}
+		}
+
+		low, high := field.min, field.max
+		if span != "*" {
+			lowText, highText, isRange := strings.Cut(span, "-")
+			var err error
+			if low, err = strconv.Atoi(lowText); err != nil {
+				return 0, fmt.Errorf("invalid %s %q", field.name, span)
+			}
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(highText); err != nil {
+					return 0, fmt.Errorf("invalid %s %q", field.name, span)
+				}
+			} else if hasStep {
+				high = field.max // "5/15" counts from 5
+			}
+		}
+		if low < field.min || high > field.max || low > high {
+			return 0, fmt.Errorf("%s %q is outside %d-%d", field.name, span, field.min, field.max)
+		}
+
+		for value := low; value <= high; value += step {
+			set |= 1 << uint(value)
+		}
+	}
+	return set, nil
+}
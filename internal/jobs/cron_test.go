@@ -0,0 +1,79 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchedule_Next(t *testing.T) {
+	// A Friday
+	from := time.Date(2024, 3, 1, 9, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		expression string
+		next       time.Time
+	}{
+		{"* * * * *", time.Date(2024, 3, 1, 9, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 3, 1, 9, 15, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2024, 3, 2, 3, 0, 0, 0, time.UTC)},
+		{"30 8-10 * * *", time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)},
+		{"0 0 * * 1-5", time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC)},
+		{"0 8 1,15 * *", time.Date(2024, 3, 15, 8, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2024, 3, 1, 9, 25, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC)},
+		{"@every 90s", time.Date(2024, 3, 1, 9, 9, 0, 0, time.UTC)},
+		{"@every 20m", time.Date(2024, 3, 1, 9, 20, 0, 0, time.UTC)},
+		// Both day fields restricted: either matches
+		{"0 0 10 * 6", time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.expression)
+			require.NoError(t, err)
+			assert.Equal(t, tt.next, schedule.Next(from))
+			assert.Equal(t, tt.expression, schedule.String())
+		})
+	}
+}
+
+func TestParseSchedule_Location(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+
+	schedule, err := ParseSchedule("0 3 * * *")
+	require.NoError(t, err)
+
+	next := schedule.Next(time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC).In(paris))
+	assert.Equal(t, time.Date(2024, 3, 2, 2, 0, 0, 0, time.UTC), next.UTC())
+}
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	for _, expression := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"0 0 30 2 *",
+		"@sometimes",
+		"@every",
+		"@every -1m",
+		"@every soon",
+	} {
+		_, err := ParseSchedule(expression)
+		assert.Error(t, err, expression)
+	}
+}
@@ -0,0 +1,79 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/coalesce"
+	"github.com/nareshkumar-microsoft/notificationService/internal/reconcile"
+	"github.com/nareshkumar-microsoft/notificationService/internal/retention"
+	"github.com/nareshkumar-microsoft/notificationService/internal/services"
+	"github.com/nareshkumar-microsoft/notificationService/internal/sla"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// Names of the maintenance jobs this package provides
+const (
+	JobRetention    = "retention"
+	JobReconcile    = "reconcile"
+	JobTokenCleanup = "token_cleanup"
+	JobDigest       = "digest"
+	JobStatsRollup  = "stats_rollup"
+)
+
+// RetentionJob returns a job applying a retention purger's policy
+func RetentionJob(purger *retention.Purger) Func {
+	return func(ctx context.Context) error {
+		_, err := purger.PurgeOnce(ctx)
+		return err
+	}
+}
+
+// ReconcileJob returns a job reconciling notifications left pending
+func ReconcileJob(reconciler *reconcile.Reconciler) Func {
+	return func(ctx context.Context) error {
+		_, err := reconciler.ReconcileOnce(ctx)
+		return err
+	}
+}
+
+// TokenCleanupJob returns a job removing deactivated push devices and
+// devices not seen for maxIdle
+func TokenCleanupJob(devices *services.DeviceRegistry, maxIdle time.Duration, logger interfaces.Logger) Func {
+	return func(ctx context.Context) error {
+		if maxIdle <= 0 {
+			return errors.NewValidationError("max_idle", "device idle time must be positive")
+		}
+		pruned := devices.PruneStale(time.Now().Add(-maxIdle))
+		logger.Infof("Removed %d stale push device tokens", pruned)
+		return nil
+	}
+}
+
+// DigestJob returns a job sending the coalescer's follow-ups for the
+// repeats held back so far, as digests on the job's schedule rather than
+// only when each cool-down ends
+func DigestJob(coalescer *coalesce.Coalescer, logger interfaces.Logger) Func {
+	return func(ctx context.Context) error {
+		sent := coalescer.Flush()
+		logger.Infof("Sent %d coalesced notification digests", sent)
+		return nil
+	}
+}
+
+// StatsRollupJob returns a job recording the SLA monitor's provider stats as
+// a rollup. Scheduled once per SLA window, the rollups cover every send.
+func StatsRollupJob(monitor *sla.Monitor, logger interfaces.Logger) Func {
+	return func(ctx context.Context) error {
+		rollup := monitor.Rollup()
+		degraded := 0
+		for _, provider := range rollup.Providers {
+			if provider.Degraded {
+				degraded++
+			}
+		}
+		logger.Infof("Rolled up the stats of %d providers, %d degraded", len(rollup.Providers), degraded)
+		return nil
+	}
+}
@@ -0,0 +1,357 @@
+// Package jobs runs the service's internal maintenance tasks, such as
+// retention purges and reconciliation, on cron schedules. Each scheduled
+// run claims its tick and takes the job's lock first, so in a deployment of
+// several replicas sharing a Locker each tick runs on one instance, and a
+// job on one instance at a time.
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/lock"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// ScheduleOff disables a job when configured as its schedule
+const ScheduleOff = "off"
+
+// defaultLockLease is used when the configuration sets no lock lease
+const defaultLockLease = 10 * time.Minute
+
+// Func is the work of a job. The context is cancelled when the scheduler
+// stops or the job's lock lease runs out.
+type Func func(ctx context.Context) error
+
+// Stats holds a job's schedule and cumulative run metrics
+type Stats struct {
+	Name         string        `json:"name"`
+	Schedule     string        `json:"schedule,omitempty"` // empty when the job only runs on demand
+	Runs         int64         `json:"runs"`
+	Failures     int64         `json:"failures"`
+	Skipped      int64         `json:"skipped"` // runs skipped because the job was running elsewhere
+	LastRunAt    *time.Time    `json:"last_run_at,omitempty"`
+	LastDuration time.Duration `json:"last_duration,omitempty"`
+	LastError    string        `json:"last_error,omitempty"`
+	NextRunAt    *time.Time    `json:"next_run_at,omitempty"`
+}
+
+// job is a registered job
+type job struct {
+	name     string
+	schedule *Schedule
+	run      Func
+	active   bool // a run is in progress on this instance
+	stats    Stats
+}
+
+// Scheduler runs registered jobs on their schedules. It is safe for
+// concurrent use.
+type Scheduler struct {
+	config   config.JobsConfig
+	locker   interfaces.Locker
+	owner    string
+	location *time.Location
+	lease    time.Duration
+	logger   interfaces.Logger
+	now      func() time.Time
+
+	mu      sync.Mutex
+	jobs    map[string]*job
+	ctx     context.Context // of the running scheduler, for jobs registered after Start
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	running bool
+}
+
+// NewScheduler creates a job scheduler. A nil locker locks jobs within this
+// process only, which suits a single instance. Without an instance ID a
+// random one is used.
+func NewScheduler(cfg config.JobsConfig, locker interfaces.Locker, logger interfaces.Logger) (*Scheduler, error) {
+	timezone := cfg.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, errors.NewValidationError("timezone", fmt.Sprintf("unknown time zone: %q", cfg.Timezone))
+	}
+
+	for name, expression := range cfg.Schedules {
+		if expression == ScheduleOff {
+			continue
+		}
+		if _, err := ParseSchedule(expression); err != nil {
+			return nil, errors.NewValidationError("schedules", fmt.Sprintf("job %s: %s", name, err))
+		}
+	}
+
+	if locker == nil {
+		locker = lock.NewMemoryLocker()
+	}
+	owner := cfg.InstanceID
+	if owner == "" {
+		owner = uuid.New().String()
+	}
+	lease := cfg.LockLease
+	if lease <= 0 {
+		lease = defaultLockLease
+	}
+
+	return &Scheduler{
+		config:   cfg,
+		locker:   locker,
+		owner:    owner,
+		location: location,
+		lease:    lease,
+		logger:   logger,
+		now:      time.Now,
+		jobs:     make(map[string]*job),
+	}, nil
+}
+
+// Register adds a job. Its schedule is the one configured for its name, or
+// the default when none is. A job whose schedule is empty or "off" only
+// runs through RunNow. Jobs registered while the scheduler runs start at once.
+func (s *Scheduler) Register(name, defaultSchedule string, run Func) error {
+	if name == "" {
+		return errors.NewValidationError("name", "job name is required")
+	}
+	if run == nil {
+		return errors.NewValidationError("run", "job function is required")
+	}
+
+	expression := defaultSchedule
+	if configured, exists := s.config.Schedules[name]; exists {
+		expression = configured
+	}
+	var schedule *Schedule
+	if expression != "" && expression != ScheduleOff {
+		var err error
+		if schedule, err = ParseSchedule(expression); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.jobs[name]; exists {
+		return errors.NewNotificationError(errors.ErrorCodeInvalidRequest, fmt.Sprintf("job %s is already registered", name))
+	}
+	j := &job{name: name, schedule: schedule, run: run, stats: Stats{Name: name}}
+	if schedule != nil {
+		j.stats.Schedule = schedule.String()
+	}
+	s.jobs[name] = j
+
+	if s.running && schedule != nil {
+		s.wg.Add(1)
+		go s.loop(s.ctx, j)
+	}
+	return nil
+}
+
+// Start runs every scheduled job on its schedule until Stop is called.
+// Calling Start on a running scheduler has no effect.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return
+	}
+
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	s.running = true
+
+	scheduled := 0
+	for _, j := range s.jobs {
+		if j.schedule != nil {
+			s.wg.Add(1)
+			go s.loop(s.ctx, j)
+			scheduled++
+		}
+	}
+
+	s.logger.Infof("Started job scheduler as %s with %d scheduled jobs (%s)", s.owner, scheduled, s.location)
+}
+
+// Stop stops the scheduler and waits for runs in progress to finish
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.cancel()
+	s.running = false
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	s.logger.Infof("Stopped job scheduler")
+}
+
+// RunNow runs a job once, outside its schedule. It reports false without
+// running the job when the job is running, here or on another instance.
+func (s *Scheduler) RunNow(ctx context.Context, name string) (bool, error) {
+	s.mu.Lock()
+	j, exists := s.jobs[name]
+	s.mu.Unlock()
+
+	if !exists {
+		return false, errors.NewNotificationError(errors.ErrorCodeNotFound, fmt.Sprintf("job not found: %s", name))
+	}
+	return s.execute(ctx, j, time.Time{})
+}
+
+// Stats returns the metrics of every job, sorted by name
+func (s *Scheduler) Stats() []Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := make([]Stats, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		copied := j.stats
+		if j.stats.LastRunAt != nil {
+			lastRunAt := *j.stats.LastRunAt
+			copied.LastRunAt = &lastRunAt
+		}
+		if j.stats.NextRunAt != nil {
+			nextRunAt := *j.stats.NextRunAt
+			copied.NextRunAt = &nextRunAt
+		}
+		stats = append(stats, copied)
+	}
+	sort.Slice(stats, func(i, k int) bool { return stats[i].Name < stats[k].Name })
+	return stats
+}
+
+// loop runs a job each time its schedule comes due until the context is cancelled
+func (s *Scheduler) loop(ctx context.Context, j *job) {
+	defer s.wg.Done()
+
+	for {
+		next := j.schedule.Next(s.now().In(s.location))
+		if next.IsZero() {
+			s.logger.Warnf("Job %s has no further runs scheduled", j.name)
+			return
+		}
+
+		s.mu.Lock()
+		j.stats.NextRunAt = &next
+		s.mu.Unlock()
+
+		timer := time.NewTimer(next.Sub(s.now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		// Errors are logged and counted by execute; the next run retries
+		_, _ = s.execute(ctx, j, next)
+	}
+}
+
+// execute runs a job under its lock. The run's context ends with the lock
+// lease, so the job never runs unlocked. A scheduled run first claims its
+// tick, given by the time it was due; the claim is kept until its lease
+// expires, so a replica whose timer fires after the run has finished
+// skips the tick rather than running it again.
+func (s *Scheduler) execute(ctx context.Context, j *job, due time.Time) (bool, error) {
+	s.mu.Lock()
+	if j.active {
+		j.stats.Skipped++
+		s.mu.Unlock()
+		return false, nil
+	}
+	j.active = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		j.active = false
+		s.mu.Unlock()
+	}()
+
+	if !due.IsZero() {
+		claimed, err := s.locker.Acquire(ctx, tickKey(j.name, due), s.owner, s.lease)
+		if err != nil {
+			err = errors.NewInternalError(fmt.Sprintf("failed to claim the run of job %s", j.name), err)
+			s.record(j, s.now(), 0, err)
+			s.logger.Errorf("Job %s did not run: %v", j.name, err)
+			return false, err
+		}
+		if !claimed {
+			s.mu.Lock()
+			j.stats.Skipped++
+			s.mu.Unlock()
+			s.logger.Debugf("Job %s already ran for %s on another instance, skipped", j.name, due.Format(time.RFC3339))
+			return false, nil
+		}
+	}
+
+	acquired, err := s.locker.Acquire(ctx, j.name, s.owner, s.lease)
+	if err != nil {
+		err = errors.NewInternalError(fmt.Sprintf("failed to lock job %s", j.name), err)
+		s.record(j, s.now(), 0, err)
+		s.logger.Errorf("Job %s did not run: %v", j.name, err)
+		return false, err
+	}
+	if !acquired {
+		s.mu.Lock()
+		j.stats.Skipped++
+		s.mu.Unlock()
+		s.logger.Debugf("Job %s is running on another instance, skipped", j.name)
+		return false, nil
+	}
+	defer func() {
+		// The run's context may be done; releasing must not depend on it
+		if err := s.locker.Release(context.Background(), j.name, s.owner); err != nil {
+			s.logger.Warnf("Failed to release the lock of job %s: %v", j.name, err)
+		}
+	}()
+
+	runCtx, cancel := context.WithTimeout(ctx, s.lease)
+	defer cancel()
+
+	startedAt := s.now()
+	err = j.run(runCtx)
+	duration := s.now().Sub(startedAt)
+	s.record(j, startedAt, duration, err)
+
+	if err != nil {
+		s.logger.Errorf("Job %s failed after %s: %v", j.name, duration, err)
+		return true, err
+	}
+	s.logger.Infof("Job %s finished in %s", j.name, duration)
+	return true, nil
+}
+
+// tickKey is the lock key claiming the run of a job due at a time
+func tickKey(name string, due time.Time) string {
+	return fmt.Sprintf("%s@%d", name, due.UnixNano())
+}
+
+// record adds a run to a job's metrics
+func (s *Scheduler) record(j *job, startedAt time.Time, duration time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j.stats.Runs++
+	j.stats.LastRunAt = &startedAt
+	j.stats.LastDuration = duration
+	j.stats.LastError = ""
+	if err != nil {
+		j.stats.Failures++
+		j.stats.LastError = err.Error()
+	}
+}
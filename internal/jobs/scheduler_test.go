@@ -0,0 +1,267 @@
+package jobs
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/coalesce"
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/lock"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/repository"
+	"github.com/nareshkumar-microsoft/notificationService/internal/retention"
+	"github.com/nareshkumar-microsoft/notificationService/internal/services"
+	"github.com/nareshkumar-microsoft/notificationService/internal/sla"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+func TestNewScheduler_Validation(t *testing.T) {
+	logger := utils.NewSimpleLogger("error")
+
+	_, err := NewScheduler(config.JobsConfig{Timezone: "Europe/Atlantis"}, nil, logger)
+	assert.Error(t, err)
+
+	_, err = NewScheduler(config.JobsConfig{Schedules: map[string]string{JobRetention: "every day"}}, nil, logger)
+	assert.Error(t, err)
+
+	_, err = NewScheduler(config.JobsConfig{Schedules: map[string]string{JobRetention: ScheduleOff}}, nil, logger)
+	assert.NoError(t, err)
+}
+
+func TestScheduler_Register(t *testing.T) {
+	scheduler := createTestScheduler(t, config.JobsConfig{
+		Schedules: map[string]string{JobRetention: "0 3 * * *", JobReconcile: ScheduleOff},
+	}, nil)
+	noop := func(context.Context) error { return nil }
+
+	require.NoError(t, scheduler.Register(JobRetention, "@daily", noop))
+	require.NoError(t, scheduler.Register(JobReconcile, "@hourly", noop))
+	require.NoError(t, scheduler.Register(JobTokenCleanup, "@weekly", noop))
+
+	stats := scheduler.Stats()
+	require.Len(t, stats, 3)
+	assert.Equal(t, JobReconcile, stats[0].Name)
+	assert.Empty(t, stats[0].Schedule) // disabled by configuration
+	assert.Equal(t, "0 3 * * *", stats[1].Schedule)
+	assert.Equal(t, "@weekly", stats[2].Schedule)
+
+	assert.Error(t, scheduler.Register(JobRetention, "@daily", noop))
+	assert.Error(t, scheduler.Register("", "@daily", noop))
+	assert.Error(t, scheduler.Register("digest", "@daily", nil))
+	assert.Error(t, scheduler.Register("digest", "0 25 * * *", noop))
+}
+
+func TestScheduler_RunNow(t *testing.T) {
+	scheduler := createTestScheduler(t, config.JobsConfig{}, nil)
+	ctx := context.Background()
+
+	fail := true
+	require.NoError(t, scheduler.Register("flaky", "", func(context.Context) error {
+		if fail {
+			return errors.NewInternalError("store unavailable", nil)
+		}
+		return nil
+	}))
+
+	ran, err := scheduler.RunNow(ctx, "flaky")
+	assert.True(t, ran)
+	assert.Error(t, err)
+
+	stats := scheduler.Stats()[0]
+	assert.Equal(t, int64(1), stats.Runs)
+	assert.Equal(t, int64(1), stats.Failures)
+	assert.Contains(t, stats.LastError, "store unavailable")
+	assert.NotNil(t, stats.LastRunAt)
+	assert.Nil(t, stats.NextRunAt)
+
+	fail = false
+	ran, err = scheduler.RunNow(ctx, "flaky")
+	require.NoError(t, err)
+	assert.True(t, ran)
+	stats = scheduler.Stats()[0]
+	assert.Equal(t, int64(2), stats.Runs)
+	assert.Empty(t, stats.LastError)
+
+	_, err = scheduler.RunNow(ctx, "unknown")
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeNotFound, notifErr.Code)
+}
+
+func TestScheduler_RunsOnSchedule(t *testing.T) {
+	scheduler := createTestScheduler(t, config.JobsConfig{}, nil)
+
+	var runs atomic.Int64
+	require.NoError(t, scheduler.Register("tick", "@every 10ms", func(context.Context) error {
+		runs.Add(1)
+		return nil
+	}))
+
+	scheduler.Start(context.Background())
+	assert.Eventually(t, func() bool { return runs.Load() >= 3 }, time.Second, 5*time.Millisecond)
+
+	// Jobs registered while running are scheduled too
+	var late atomic.Int64
+	require.NoError(t, scheduler.Register("late", "@every 10ms", func(context.Context) error {
+		late.Add(1)
+		return nil
+	}))
+	assert.Eventually(t, func() bool { return late.Load() >= 1 }, time.Second, 5*time.Millisecond)
+
+	scheduler.Stop()
+	stopped := runs.Load()
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, stopped, runs.Load())
+	assert.NotNil(t, scheduler.Stats()[1].NextRunAt)
+}
+
+func TestScheduler_LockedAcrossInstances(t *testing.T) {
+	locker := lock.NewMemoryLocker()
+	first := createTestScheduler(t, config.JobsConfig{InstanceID: "instance-1"}, locker)
+	second := createTestScheduler(t, config.JobsConfig{InstanceID: "instance-2"}, locker)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	require.NoError(t, first.Register(JobRetention, "", func(context.Context) error {
+		close(started)
+		<-release
+		return nil
+	}))
+	require.NoError(t, second.Register(JobRetention, "", func(context.Context) error { return nil }))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		first.RunNow(context.Background(), JobRetention)
+	}()
+	<-started
+
+	// Held by the first instance, so the second skips the run, as does a
+	// second run on the first
+	ran, err := second.RunNow(context.Background(), JobRetention)
+	require.NoError(t, err)
+	assert.False(t, ran)
+	ran, _ = first.RunNow(context.Background(), JobRetention)
+	assert.False(t, ran)
+	assert.Equal(t, int64(1), second.Stats()[0].Skipped)
+
+	close(release)
+	<-done
+
+	ran, err = second.RunNow(context.Background(), JobRetention)
+	require.NoError(t, err)
+	assert.True(t, ran)
+}
+
+func TestScheduler_TickRunsOnce(t *testing.T) {
+	locker := lock.NewMemoryLocker()
+	first := createTestScheduler(t, config.JobsConfig{InstanceID: "instance-1"}, locker)
+	second := createTestScheduler(t, config.JobsConfig{InstanceID: "instance-2"}, locker)
+
+	var runs atomic.Int64
+	count := func(context.Context) error {
+		runs.Add(1)
+		return nil
+	}
+	require.NoError(t, first.Register(JobRetention, "@every 1m", count))
+	require.NoError(t, second.Register(JobRetention, "@every 1m", count))
+	ctx := context.Background()
+	due := time.Date(2024, 3, 1, 3, 0, 0, 0, time.UTC)
+
+	// The second instance's timer fires a few milliseconds after the first
+	// run finished and freed the job lock; the tick has still been run
+	ran, err := first.execute(ctx, first.jobs[JobRetention], due)
+	require.NoError(t, err)
+	assert.True(t, ran)
+	time.Sleep(5 * time.Millisecond)
+	ran, err = second.execute(ctx, second.jobs[JobRetention], due)
+	require.NoError(t, err)
+	assert.False(t, ran)
+	assert.Equal(t, int64(1), runs.Load())
+	assert.Equal(t, int64(1), second.Stats()[0].Skipped)
+
+	// The next tick is claimed afresh, and on-demand runs need no claim
+	ran, err = second.execute(ctx, second.jobs[JobRetention], due.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, ran)
+	ran, err = first.RunNow(ctx, JobRetention)
+	require.NoError(t, err)
+	assert.True(t, ran)
+	assert.Equal(t, int64(3), runs.Load())
+}
+
+func TestScheduler_RunEndsWithLease(t *testing.T) {
+	scheduler := createTestScheduler(t, config.JobsConfig{LockLease: 20 * time.Millisecond}, nil)
+	require.NoError(t, scheduler.Register("slow", "", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+
+	ran, err := scheduler.RunNow(context.Background(), "slow")
+	assert.True(t, ran)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestMaintenanceJobs(t *testing.T) {
+	ctx := context.Background()
+	logger := utils.NewSimpleLogger("error")
+
+	devices := services.NewDeviceRegistry()
+	_, err := devices.Register(&services.Device{UserID: "user-1", Token: testDeviceToken, Platform: "ios"})
+	require.NoError(t, err)
+
+	cleanup := TokenCleanupJob(devices, time.Hour, logger)
+	require.NoError(t, cleanup(ctx))
+	assert.Equal(t, 1, devices.Count())
+	require.NoError(t, devices.Deactivate(testDeviceToken))
+	require.NoError(t, cleanup(ctx))
+	assert.Equal(t, 0, devices.Count())
+	assert.Error(t, TokenCleanupJob(devices, 0, logger)(ctx))
+
+	purger := retention.NewPurger(repository.NewMemoryRepository(), config.RetentionConfig{BodyRetention: time.Hour}, logger)
+	require.NoError(t, RetentionJob(purger)(ctx))
+	assert.Equal(t, int64(1), purger.Stats().Runs)
+
+	coalescer, err := coalesce.NewCoalescer(config.CoalesceConfig{CoolDowns: map[string]time.Duration{"transactional": time.Hour}}, logger)
+	require.NoError(t, err)
+	defer coalescer.Stop()
+	var sent atomic.Int64
+	send := func(context.Context, *models.NotificationRequest) (*models.NotificationResponse, error) {
+		sent.Add(1)
+		return &models.NotificationResponse{Status: models.StatusSent}, nil
+	}
+	coalescer.SetSender(send)
+	alert := &models.NotificationRequest{Type: models.NotificationTypeSMS, Recipient: "+14155550100", Body: "disk full",
+		Metadata: map[string]string{coalesce.MetadataDedupKey: "disk-full"}}
+	for i := 0; i < 3; i++ {
+		_, err = coalescer.Middleware()(send)(ctx, alert)
+		require.NoError(t, err)
+	}
+	require.NoError(t, DigestJob(coalescer, logger)(ctx))
+	assert.Equal(t, int64(2), sent.Load()) // the first alert and the digest of its repeats
+	assert.Equal(t, 0, coalescer.Pending())
+
+	monitor := sla.NewMonitor(config.SLAConfig{}, logger)
+	monitor.RecordSend(models.NotificationTypeSMS, "twilio", nil, time.Millisecond)
+	require.NoError(t, StatsRollupJob(monitor, logger)(ctx))
+	require.Len(t, monitor.Rollups(), 1)
+	assert.Len(t, monitor.Rollups()[0].Providers, 1)
+}
+
+// Helper functions
+
+const testDeviceToken = "a1b2c3d4e5f6a1b2c3d4e5f6a1b2c3d4e5f6a1b2c3d4e5f6a1b2c3d4e5f6a1b2"
+
+func createTestScheduler(t *testing.T, cfg config.JobsConfig, locker interfaces.Locker) *Scheduler {
+	scheduler, err := NewScheduler(cfg, locker, utils.NewSimpleLogger("error"))
+	require.NoError(t, err)
+	t.Cleanup(scheduler.Stop)
+	return scheduler
+}
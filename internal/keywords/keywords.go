@@ -0,0 +1,280 @@
+// Package keywords handles inbound SMS keywords as carriers require: STOP
+// opts the sender out of SMS, START opts them back in and HELP replies with
+// support information, each confirmed with a reply. Other inbound messages can
+// be forwarded to an application callback.
+package keywords
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/conversation"
+	"github.com/nareshkumar-microsoft/notificationService/internal/httpclient"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/preferences"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// Default confirmation replies
+const (
+	DefaultStopReply  = "You have been unsubscribed and will receive no further messages. Reply START to resubscribe."
+	DefaultStartReply = "You have been resubscribed to messages. Reply STOP to unsubscribe or HELP for help."
+	DefaultHelpReply  = "Reply STOP to unsubscribe or START to resubscribe. Msg & data rates may apply."
+)
+
+// errNoReply is returned by reply when there is nothing to send
+var errNoReply = stderrors.New("no reply sent")
+
+// defaultForwardTimeout bounds a forwarded message's callback
+const defaultForwardTimeout = 10 * time.Second
+
+// Action is what a keyword does
+type Action string
+
+const (
+	ActionStop      Action = "stop"
+	ActionStart     Action = "start"
+	ActionHelp      Action = "help"
+	ActionForwarded Action = "forwarded" // not a keyword; sent to the callback
+	ActionIgnored   Action = "ignored"   // not a keyword and there is no callback
+)
+
+// DefaultKeywords returns the keywords carriers require, per the CTIA
+// messaging principles
+func DefaultKeywords() map[string]Action {
+	return map[string]Action{
+		"STOP":        ActionStop,
+		"STOPALL":     ActionStop,
+		"UNSUBSCRIBE": ActionStop,
+		"CANCEL":      ActionStop,
+		"END":         ActionStop,
+		"QUIT":        ActionStop,
+		"REVOKE":      ActionStop,
+		"OPTOUT":      ActionStop,
+		"START":       ActionStart,
+		"UNSTOP":      ActionStart,
+		"SUBSCRIBE":   ActionStart,
+		"HELP":        ActionHelp,
+		"INFO":        ActionHelp,
+	}
+}
+
+// Message is an inbound SMS
+type Message struct {
+	From      string `json:"from"` // the sender's phone number
+	To        string `json:"to"`   // the number the message was sent to
+	Body      string `json:"body"`
+	MessageID string `json:"message_id,omitempty"`
+	Provider  string `json:"provider,omitempty"`
+}
+
+// Result is the outcome of handling an inbound SMS
+type Result struct {
+	Action  Action `json:"action"`
+	Keyword string `json:"keyword,omitempty"`
+	Reply   string `json:"reply,omitempty"` // the confirmation sent back, if any
+}
+
+// Handler handles inbound SMS. It is safe for concurrent use.
+type Handler struct {
+	store      *preferences.Store
+	sender     interfaces.SMSProvider
+	replies    map[Action]string
+	forwardURL string
+	client     *http.Client
+	logger     interfaces.Logger
+
+	mu            sync.RWMutex
+	keywords      map[string]Action
+	conversations *conversation.Store
+}
+
+// NewHandler creates a handler with the default keywords. Opt-outs are
+// recorded in the store and confirmations are sent through the sender.
+func NewHandler(cfg config.KeywordsConfig, store *preferences.Store, sender interfaces.SMSProvider, logger interfaces.Logger) (*Handler, error) {
+	if cfg.ForwardURL != "" {
+		if err := utils.ValidateWebhookURL(cfg.ForwardURL); err != nil {
+			return nil, errors.NewValidationError("forward_url", "invalid inbound SMS forward URL")
+		}
+	}
+
+	timeout := cfg.ForwardTimeout
+	if timeout <= 0 {
+		timeout = defaultForwardTimeout
+	}
+
+	return &Handler{
+		store:  store,
+		sender: sender,
+		replies: map[Action]string{
+			ActionStop:  orDefault(cfg.StopReply, DefaultStopReply),
+			ActionStart: orDefault(cfg.StartReply, DefaultStartReply),
+			ActionHelp:  orDefault(cfg.HelpReply, DefaultHelpReply),
+		},
+		forwardURL: cfg.ForwardURL,
+		client:     httpclient.Shared.Client(config.HTTPClientConfig{}, timeout),
+		logger:     logger,
+		keywords:   DefaultKeywords(),
+	}, nil
+}
+
+// SetKeyword adds a keyword, or changes what it does
+func (h *Handler) SetKeyword(keyword string, action Action) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.keywords[normalize(keyword)] = action
+}
+
+// SetConversations records inbound messages, and the confirmations sent
+// back, in their two-way conversations
+func (h *Handler) SetConversations(store *conversation.Store) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.conversations = store
+}
+
+// Keyword returns the action of a message body, and false when the body is
+// not a keyword. Keywords must be the whole message; case, surrounding
+// spaces and trailing punctuation are ignored.
+func (h *Handler) Keyword(body string) (string, Action, bool) {
+	keyword := normalize(body)
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	action, exists := h.keywords[keyword]
+	return keyword, action, exists
+}
+
+// Handle handles an inbound SMS. Keywords update the sender's SMS opt-out
+// and are confirmed with a reply; other messages are forwarded to the
+// callback when one is configured.
+func (h *Handler) Handle(ctx context.Context, message Message) (Result, error) {
+	if strings.TrimSpace(message.From) == "" {
+		return Result{}, errors.NewValidationError("from", "sender phone number is required")
+	}
+
+	conversations := h.conversationStore()
+	if conversations != nil {
+		conversations.RecordInbound(message.From, message.To, message.Body, message.MessageID)
+	}
+
+	keyword, action, isKeyword := h.Keyword(message.Body)
+	if !isKeyword {
+		return h.forward(ctx, message)
+	}
+
+	switch action {
+	case ActionStop:
+		if err := h.store.OptOut(message.From, models.NotificationTypeSMS, ""); err != nil {
+			return Result{}, err
+		}
+		h.logger.Infof("%s opted out of SMS with %s", message.From, keyword)
+	case ActionStart:
+		h.store.OptIn(message.From, models.NotificationTypeSMS, "")
+		h.logger.Infof("%s opted back in to SMS with %s", message.From, keyword)
+	}
+
+	result := Result{Action: action, Keyword: keyword, Reply: h.replies[action]}
+	if err := h.reply(ctx, message, result.Reply); err == errNoReply {
+		result.Reply = ""
+	} else if err != nil {
+		// The opt-out is recorded even when its confirmation cannot be sent
+		h.logger.Errorf("Failed to confirm %s to %s: %v", keyword, message.From, err)
+		result.Reply = ""
+	}
+	if conversations != nil && result.Reply != "" {
+		conversations.RecordOutbound(uuid.Nil, message.To, message.From, result.Reply)
+	}
+	return result, nil
+}
+
+// conversationStore returns the conversation store, if one is set
+func (h *Handler) conversationStore() *conversation.Store {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.conversations
+}
+
+// reply sends a confirmation from the number the message was sent to. It
+// goes to the provider directly, since the sender may just have opted out.
+func (h *Handler) reply(ctx context.Context, message Message, text string) error {
+	if h.sender == nil || text == "" {
+		return errNoReply
+	}
+
+	now := time.Now()
+	_, err := h.sender.SendSMS(ctx, &models.SMSNotification{
+		Notification: models.Notification{
+			ID:        utils.GenerateNotificationID(),
+			Type:      models.NotificationTypeSMS,
+			Status:    models.StatusPending,
+			Priority:  models.PriorityHigh,
+			Category:  models.CategoryTransactional,
+			Recipient: message.From,
+			Body:      text,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		PhoneNumber: message.From,
+		Message:     text,
+		SenderID:    message.To,
+	})
+	return err
+}
+
+// forward posts a message that is not a keyword to the callback
+func (h *Handler) forward(ctx context.Context, message Message) (Result, error) {
+	if h.forwardURL == "" {
+		return Result{Action: ActionIgnored}, nil
+	}
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		return Result{}, err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, h.forwardURL, bytes.NewReader(body))
+	if err != nil {
+		return Result{}, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := h.client.Do(request)
+	if err != nil {
+		return Result{}, errors.NewNotificationError(errors.ErrorCodeProviderUnavailable, "inbound SMS callback failed").WithCause(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return Result{}, errors.NewNotificationError(errors.ErrorCodeProviderUnavailable,
+			fmt.Sprintf("inbound SMS callback returned status %d", response.StatusCode))
+	}
+	return Result{Action: ActionForwarded}, nil
+}
+
+// normalize returns a message body in the form keywords are matched in
+func normalize(body string) string {
+	return strings.ToUpper(strings.TrimRight(strings.TrimSpace(body), ".!?"))
+}
+
+// orDefault returns value, or fallback when it is empty
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
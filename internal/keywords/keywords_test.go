@@ -0,0 +1,209 @@
+package keywords
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/conversation"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/preferences"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestHandler_Keyword(t *testing.T) {
+	handler, _, _ := createTestHandler(t, config.KeywordsConfig{})
+
+	tests := []struct {
+		body    string
+		action  Action
+		keyword bool
+	}{
+		{"STOP", ActionStop, true},
+		{"  stop! ", ActionStop, true},
+		{"Unsubscribe.", ActionStop, true},
+		{"UNSTOP", ActionStart, true},
+		{"start", ActionStart, true},
+		{"Help?", ActionHelp, true},
+		{"please don't stop", "", false},
+		{"stop it", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			_, action, ok := handler.Keyword(tt.body)
+			assert.Equal(t, tt.keyword, ok)
+			assert.Equal(t, tt.action, action)
+		})
+	}
+
+	handler.SetKeyword("arrêt", ActionStop)
+	_, action, ok := handler.Keyword("Arrêt")
+	assert.True(t, ok)
+	assert.Equal(t, ActionStop, action)
+}
+
+func TestHandler_StopAndStart(t *testing.T) {
+	handler, store, sender := createTestHandler(t, config.KeywordsConfig{StopReply: "Unsubscribed from Acme alerts."})
+	ctx := context.Background()
+
+	result, err := handler.Handle(ctx, Message{From: "+14155550123", To: "+14155550100", Body: "Stop"})
+	require.NoError(t, err)
+	assert.Equal(t, ActionStop, result.Action)
+	assert.Equal(t, "STOP", result.Keyword)
+	assert.Equal(t, "Unsubscribed from Acme alerts.", result.Reply)
+	assert.True(t, store.IsOptedOut("+14155550123", models.NotificationTypeSMS, models.CategoryMarketing))
+	assert.False(t, store.IsOptedOut("+14155550123", models.NotificationTypeEmail, models.CategoryMarketing))
+
+	// The confirmation comes from the number the keyword was sent to
+	sent := sender.GetSentSMS()
+	require.Len(t, sent, 1)
+	assert.Equal(t, "+14155550123", sent[0].PhoneNumber)
+	assert.Equal(t, "+14155550100", sent[0].SenderID)
+	assert.Equal(t, "Unsubscribed from Acme alerts.", sent[0].Message)
+
+	result, err = handler.Handle(ctx, Message{From: "+14155550123", To: "+14155550100", Body: "UNSTOP"})
+	require.NoError(t, err)
+	assert.Equal(t, ActionStart, result.Action)
+	assert.Equal(t, DefaultStartReply, result.Reply)
+	assert.False(t, store.IsOptedOut("+14155550123", models.NotificationTypeSMS, models.CategoryMarketing))
+	assert.Len(t, sender.GetSentSMS(), 2)
+}
+
+func TestHandler_Help(t *testing.T) {
+	handler, store, sender := createTestHandler(t, config.KeywordsConfig{HelpReply: "Acme alerts: support@acme.example. Reply STOP to cancel."})
+
+	result, err := handler.Handle(context.Background(), Message{From: "+14155550123", Body: "help"})
+	require.NoError(t, err)
+	assert.Equal(t, ActionHelp, result.Action)
+	assert.Empty(t, store.OptOuts("+14155550123"))
+	require.Len(t, sender.GetSentSMS(), 1)
+	assert.Equal(t, "Acme alerts: support@acme.example. Reply STOP to cancel.", sender.GetSentSMS()[0].Message)
+}
+
+func TestHandler_StopRecordedWhenReplyFails(t *testing.T) {
+	handler, store, sender := createTestHandler(t, config.KeywordsConfig{})
+	sender.SetHealthy(false)
+
+	result, err := handler.Handle(context.Background(), Message{From: "+14155550123", Body: "STOP"})
+	require.NoError(t, err)
+	assert.Equal(t, ActionStop, result.Action)
+	assert.Empty(t, result.Reply)
+	assert.True(t, store.IsOptedOut("+14155550123", models.NotificationTypeSMS, models.CategoryTransactional))
+}
+
+func TestHandler_Forward(t *testing.T) {
+	var forwarded []Message
+	status := http.StatusOK
+	callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message Message
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&message))
+		forwarded = append(forwarded, message)
+		w.WriteHeader(status)
+	}))
+	defer callback.Close()
+
+	handler, _, sender := createTestHandler(t, config.KeywordsConfig{ForwardURL: callback.URL})
+	ctx := context.Background()
+
+	result, err := handler.Handle(ctx, Message{From: "+14155550123", To: "+14155550100", Body: "What time do you open?"})
+	require.NoError(t, err)
+	assert.Equal(t, ActionForwarded, result.Action)
+	require.Len(t, forwarded, 1)
+	assert.Equal(t, "What time do you open?", forwarded[0].Body)
+	assert.Empty(t, sender.GetSentSMS())
+
+	// Keywords are not forwarded
+	_, err = handler.Handle(ctx, Message{From: "+14155550123", Body: "HELP"})
+	require.NoError(t, err)
+	assert.Len(t, forwarded, 1)
+
+	status = http.StatusInternalServerError
+	_, err = handler.Handle(ctx, Message{From: "+14155550123", Body: "Hello?"})
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeProviderUnavailable, notifErr.Code)
+}
+
+func TestHandler_Ignored(t *testing.T) {
+	handler, _, _ := createTestHandler(t, config.KeywordsConfig{})
+
+	result, err := handler.Handle(context.Background(), Message{From: "+14155550123", Body: "Thanks"})
+	require.NoError(t, err)
+	assert.Equal(t, ActionIgnored, result.Action)
+
+	_, err = handler.Handle(context.Background(), Message{Body: "STOP"})
+	assert.Error(t, err)
+}
+
+func TestHandler_SetConversations(t *testing.T) {
+	handler, _, _ := createTestHandler(t, config.KeywordsConfig{})
+	store := conversation.NewStore(config.ConversationConfig{})
+	handler.SetConversations(store)
+	ctx := context.Background()
+
+	_, err := handler.Handle(ctx, Message{From: "+14155550123", To: "+14155550100", Body: "Where is my order?", MessageID: "SM1"})
+	require.NoError(t, err)
+	_, err = handler.Handle(ctx, Message{From: "+14155550123", To: "+14155550100", Body: "STOP", MessageID: "SM2"})
+	require.NoError(t, err)
+
+	conversations := store.ForParticipant("+14155550123")
+	require.Len(t, conversations, 1)
+	messages := conversations[0].Messages
+	require.Len(t, messages, 3)
+	assert.Equal(t, conversation.DirectionInbound, messages[0].Direction)
+	assert.Equal(t, "SM1", messages[0].ProviderID)
+	assert.Equal(t, "STOP", messages[1].Body)
+	assert.Equal(t, conversation.DirectionOutbound, messages[2].Direction)
+	assert.Equal(t, DefaultStopReply, messages[2].Body)
+}
+
+func TestNewHandler_InvalidForwardURL(t *testing.T) {
+	_, err := NewHandler(config.KeywordsConfig{ForwardURL: "ftp://example.com"}, preferences.NewStore(), nil, utils.NewSimpleLogger("info"))
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, "forward_url", notifErr.Metadata["field"])
+}
+
+func TestParseRequest(t *testing.T) {
+	form := url.Values{"From": {"+14155550123"}, "To": {"+14155550100"}, "Body": {"STOP"}, "MessageSid": {"SM123"}}
+	request := httptest.NewRequest(http.MethodPost, "/v1/inbound/sms", strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	message, err := ParseRequest(httptest.NewRecorder(), request)
+	require.NoError(t, err)
+	assert.Equal(t, Message{From: "+14155550123", To: "+14155550100", Body: "STOP", MessageID: "SM123", Provider: "twilio"}, message)
+	assert.True(t, message.IsTwilio())
+
+	request = httptest.NewRequest(http.MethodPost, "/v1/inbound/sms", strings.NewReader(`{"from":"+14155550123","body":"HELP","provider":"vonage"}`))
+	request.Header.Set("Content-Type", "application/json; charset=utf-8")
+	message, err = ParseRequest(httptest.NewRecorder(), request)
+	require.NoError(t, err)
+	assert.Equal(t, "HELP", message.Body)
+	assert.False(t, message.IsTwilio())
+
+	request = httptest.NewRequest(http.MethodPost, "/v1/inbound/sms", strings.NewReader(`{`))
+	request.Header.Set("Content-Type", "application/json")
+	_, err = ParseRequest(httptest.NewRecorder(), request)
+	assert.Error(t, err)
+}
+
+// Helper functions
+
+func createTestHandler(t *testing.T, cfg config.KeywordsConfig) (*Handler, *preferences.Store, *providers.MockSMSProvider) {
+	store := preferences.NewStore()
+	sender := providers.NewMockSMSProvider(config.SMSProviderConfig{Provider: "mock", Enabled: true})
+	handler, err := NewHandler(cfg, store, sender, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	return handler, store, sender
+}
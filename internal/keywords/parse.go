@@ -0,0 +1,44 @@
+package keywords
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// maxBodySize bounds the size of an inbound SMS post
+const maxBodySize = 64 << 10
+
+// ParseRequest parses an inbound SMS webhook post: a Twilio form post
+// (From, To, Body, MessageSid) or a JSON Message
+func ParseRequest(w http.ResponseWriter, r *http.Request) (Message, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		var message Message
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			return Message{}, errors.NewNotificationErrorWithDetails(errors.ErrorCodeInvalidRequest, "inbound SMS is not valid JSON", err.Error())
+		}
+		return message, nil
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return Message{}, errors.NewNotificationErrorWithDetails(errors.ErrorCodeInvalidRequest, "inbound SMS is not valid form data", err.Error())
+	}
+	return Message{
+		From:      r.PostForm.Get("From"),
+		To:        r.PostForm.Get("To"),
+		Body:      r.PostForm.Get("Body"),
+		MessageID: r.PostForm.Get("MessageSid"),
+		Provider:  "twilio",
+	}, nil
+}
+
+// IsTwilio reports whether a message was posted by Twilio, which expects a
+// TwiML response
+func (m Message) IsTwilio() bool {
+	return m.Provider == "twilio"
+}
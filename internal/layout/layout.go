@@ -0,0 +1,417 @@
+// Package layout composes email templates from shared layouts and partials
+// using Jinja-style tags:
+//
+//	{% extends "base" %}            inherit a layout; must come first
+//	{% block content %}...{% endblock %}  define or override a block
+//	{% include "footer" %}          insert a partial
+//
+// Composition resolves the tags only; {{variable}} placeholders are left for
+// the provider to replace. Per-tenant branding variables are exposed to
+// templates as {{brand.<key>}}.
+package layout
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// BrandingPrefix prefixes branding variables, e.g. {{brand.name}}
+const BrandingPrefix = "brand."
+
+// maxDepth bounds layout and partial nesting so cycles fail instead of recursing forever
+const maxDepth = 10
+
+// tagPattern matches {% tag %} and {% tag "name" %}
+var tagPattern = regexp.MustCompile(`\{%\s*(\w+)(?:\s+["']?([\w.-]+)["']?)?\s*%\}`)
+
+// DefaultLayouts are the layouts every library starts with
+var DefaultLayouts = map[string]string{
+	"base": `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{% block title %}{{brand.name}}{% endblock %}</title></head>
+<body style="margin: 0; font-family: Arial, sans-serif;">
+{% include "header" %}
+<div style="padding: 16px;">{% block content %}{% endblock %}</div>
+{% include "footer" %}
+</body>
+</html>`,
+	"base_text": `{% block content %}{% endblock %}
+
+--
+{{brand.name}}
+{{brand.footer_text}}`,
+}
+
+// DefaultPartials are the partials every library starts with
+var DefaultPartials = map[string]string{
+	"header": `<div style="background: {{brand.primary_color}}; color: #ffffff; padding: 16px;"><strong>{{brand.name}}</strong></div>`,
+	"footer": `<div style="color: #6b7280; font-size: 12px; padding: 16px;">{{brand.footer_text}}</div>`,
+}
+
+// DefaultBranding is the branding of tenants that do not set their own
+var DefaultBranding = map[string]string{
+	"name":            "Notification Service",
+	"primary_color":   "#2563eb",
+	"secondary_color": "#1e40af",
+	"footer_text":     "You are receiving this email because you have an account with us.",
+	"footer_address":  "",
+	"logo_url":        "",
+	"support_email":   "",
+}
+
+// Library holds named layouts and partials and the branding of each tenant
+type Library struct {
+	mu       sync.RWMutex
+	layouts  map[string]*document
+	partials map[string]*document
+	branding map[string]map[string]string
+}
+
+// NewLibrary creates a library with the default layouts, partials and branding
+func NewLibrary() *Library {
+	library := &Library{
+		layouts:  make(map[string]*document),
+		partials: make(map[string]*document),
+		branding: map[string]map[string]string{"": copyVars(DefaultBranding)},
+	}
+
+	for name, source := range DefaultLayouts {
+		if err := library.AddLayout(name, source); err != nil {
+			panic(fmt.Sprintf("invalid default layout %s: %v", name, err))
+		}
+	}
+	for name, source := range DefaultPartials {
+		if err := library.AddPartial(name, source); err != nil {
+			panic(fmt.Sprintf("invalid default partial %s: %v", name, err))
+		}
+	}
+
+	return library
+}
+
+// AddLayout adds or replaces a layout. Layouts may extend other layouts.
+func (l *Library) AddLayout(name, source string) error {
+	doc, err := parseNamed(name, source)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.layouts[name] = doc
+	return nil
+}
+
+// AddPartial adds or replaces a partial. Partials may include other partials
+// but cannot extend a layout.
+func (l *Library) AddPartial(name, source string) error {
+	doc, err := parseNamed(name, source)
+	if err != nil {
+		return err
+	}
+	if doc.extends != "" {
+		return errors.NewValidationError("source", fmt.Sprintf("partial %s cannot extend a layout", name))
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.partials[name] = doc
+	return nil
+}
+
+// Layouts returns the names of the layouts, sorted
+func (l *Library) Layouts() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return sortedKeys(l.layouts)
+}
+
+// Partials returns the names of the partials, sorted
+func (l *Library) Partials() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return sortedKeys(l.partials)
+}
+
+// SetBranding sets a tenant's branding variables. They are layered over the
+// default branding, which is set with an empty tenant ID.
+func (l *Library) SetBranding(tenantID string, vars map[string]string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.branding[tenantID] = copyVars(vars)
+}
+
+// UpdateBranding merges variables into a tenant's branding. An empty value
+// removes the tenant's own variable, so the default shows again.
+func (l *Library) UpdateBranding(tenantID string, vars map[string]string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	branding := copyVars(l.branding[tenantID])
+	for key, value := range vars {
+		if value == "" {
+			delete(branding, key)
+		} else {
+			branding[key] = value
+		}
+	}
+	if len(branding) == 0 && tenantID != "" {
+		delete(l.branding, tenantID)
+		return
+	}
+	l.branding[tenantID] = branding
+}
+
+// Branding returns a tenant's branding variables, including defaults it does not override
+func (l *Library) Branding(tenantID string) map[string]string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	branding := copyVars(l.branding[""])
+	for key, value := range l.branding[tenantID] {
+		branding[key] = value
+	}
+	return branding
+}
+
+// TenantBranding returns the branding variables a tenant set itself, without
+// defaults, and whether it set any
+func (l *Library) TenantBranding(tenantID string) (map[string]string, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	vars, exists := l.branding[tenantID]
+	if !exists {
+		return nil, false
+	}
+	return copyVars(vars), true
+}
+
+// Data returns template data with the tenant's branding injected as
+// brand.<key>. Keys in data take precedence over branding.
+func (l *Library) Data(tenantID string, data map[string]string) map[string]string {
+	branding := l.Branding(tenantID)
+	merged := make(map[string]string, len(branding)+len(data))
+	for key, value := range branding {
+		merged[BrandingPrefix+key] = value
+	}
+	for key, value := range data {
+		merged[key] = value
+	}
+	return merged
+}
+
+// Compose resolves the layout and partial tags in source
+func (l *Library) Compose(source string) (string, error) {
+	doc, err := parse(source)
+	if err != nil {
+		return "", err
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.render(doc, make(map[string][]node), 0)
+}
+
+// render renders doc, or the layout it extends, with the blocks overridden
+// by the templates that extend it
+func (l *Library) render(doc *document, overrides map[string][]node, depth int) (string, error) {
+	if depth > maxDepth {
+		return "", errors.NewValidationError("source", "layouts nest too deeply")
+	}
+
+	if doc.extends != "" {
+		// The most derived definition of a block wins
+		for name, nodes := range doc.blocks {
+			if _, exists := overrides[name]; !exists {
+				overrides[name] = nodes
+			}
+		}
+		parent, exists := l.layouts[doc.extends]
+		if !exists {
+			return "", errors.NewNotificationError(errors.ErrorCodeTemplateNotFound, fmt.Sprintf("layout not found: %s", doc.extends))
+		}
+		return l.render(parent, overrides, depth+1)
+	}
+
+	var out strings.Builder
+	if err := l.write(&out, doc.nodes, overrides, depth); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// write writes nodes, substituting overridden blocks and included partials
+func (l *Library) write(out *strings.Builder, nodes []node, overrides map[string][]node, depth int) error {
+	for _, n := range nodes {
+		switch n.kind {
+		case textNode:
+			out.WriteString(n.text)
+		case blockNode:
+			children := n.children
+			if override, exists := overrides[n.name]; exists {
+				children = override
+			}
+			if err := l.write(out, children, overrides, depth); err != nil {
+				return err
+			}
+		case includeNode:
+			partial, exists := l.partials[n.name]
+			if !exists {
+				return errors.NewNotificationError(errors.ErrorCodeTemplateNotFound, fmt.Sprintf("partial not found: %s", n.name))
+			}
+			if depth >= maxDepth {
+				return errors.NewValidationError("source", "partials nest too deeply")
+			}
+			if err := l.write(out, partial.nodes, overrides, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type nodeKind int
+
+const (
+	textNode nodeKind = iota
+	blockNode
+	includeNode
+)
+
+// node is a piece of a parsed template
+type node struct {
+	kind     nodeKind
+	text     string // textNode
+	name     string // blockNode and includeNode
+	children []node // blockNode
+}
+
+// document is a parsed template
+type document struct {
+	extends string
+	nodes   []node
+	blocks  map[string][]node // every block defined, including nested ones
+}
+
+// parseNamed parses a layout or partial source
+func parseNamed(name, source string) (*document, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, errors.NewValidationError("name", "name is required")
+	}
+	return parse(source)
+}
+
+// parse parses the tags in source
+func parse(source string) (*document, error) {
+	doc := &document{blocks: make(map[string][]node)}
+
+	// frames holds the open blocks; frames[0] is the top level
+	type frame struct {
+		name  string
+		nodes []node
+	}
+	frames := []*frame{{}}
+	seenContent := false
+
+	position := 0
+	for _, match := range tagPattern.FindAllStringSubmatchIndex(source, -1) {
+		top := frames[len(frames)-1]
+		if text := source[position:match[0]]; text != "" {
+			top.nodes = append(top.nodes, node{kind: textNode, text: text})
+			seenContent = seenContent || strings.TrimSpace(text) != ""
+		}
+		position = match[1]
+
+		tag := source[match[2]:match[3]]
+		name := ""
+		if match[4] >= 0 {
+			name = source[match[4]:match[5]]
+		}
+
+		switch tag {
+		case "extends":
+			if name == "" {
+				return nil, syntaxError("extends requires a layout name")
+			}
+			if seenContent || doc.extends != "" {
+				return nil, syntaxError("extends must be the first tag in a template")
+			}
+			doc.extends = name
+		case "block":
+			if name == "" {
+				return nil, syntaxError("block requires a name")
+			}
+			if _, exists := doc.blocks[name]; exists {
+				return nil, syntaxError(fmt.Sprintf("block %s is defined more than once", name))
+			}
+			doc.blocks[name] = nil
+			frames = append(frames, &frame{name: name})
+		case "endblock":
+			if len(frames) == 1 {
+				return nil, syntaxError("endblock without a block")
+			}
+			if name != "" && name != top.name {
+				return nil, syntaxError(fmt.Sprintf("endblock %s closes block %s", name, top.name))
+			}
+			frames = frames[:len(frames)-1]
+			doc.blocks[top.name] = top.nodes
+			parent := frames[len(frames)-1]
+			parent.nodes = append(parent.nodes, node{kind: blockNode, name: top.name, children: top.nodes})
+		case "include":
+			if name == "" {
+				return nil, syntaxError("include requires a partial name")
+			}
+			top.nodes = append(top.nodes, node{kind: includeNode, name: name})
+		default:
+			return nil, syntaxError(fmt.Sprintf("unknown tag: %s", tag))
+		}
+		if tag != "extends" {
+			seenContent = true
+		}
+	}
+
+	if len(frames) > 1 {
+		return nil, syntaxError(fmt.Sprintf("block %s is not closed", frames[len(frames)-1].name))
+	}
+	if text := source[position:]; text != "" {
+		frames[0].nodes = append(frames[0].nodes, node{kind: textNode, text: text})
+	}
+	doc.nodes = frames[0].nodes
+	return doc, nil
+}
+
+// syntaxError reports a malformed template
+func syntaxError(message string) error {
+	return errors.NewValidationError("source", message)
+}
+
+// copyVars copies a variable map
+func copyVars(vars map[string]string) map[string]string {
+	copied := make(map[string]string, len(vars))
+	for key, value := range vars {
+		copied[key] = value
+	}
+	return copied
+}
+
+// sortedKeys returns the names of documents, sorted
+func sortedKeys(documents map[string]*document) []string {
+	names := make([]string, 0, len(documents))
+	for name := range documents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,148 @@
+package layout
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestLibrary_ComposeExtends(t *testing.T) {
+	library := createTestLibrary(t)
+
+	composed, err := library.Compose(`{% extends "page" %}{% block content %}Hello {{user_name}}{% endblock %}`)
+	require.NoError(t, err)
+	assert.Equal(t, "<h1>Default title</h1><main>Hello {{user_name}}</main><footer>{{brand.name}}</footer>", composed)
+
+	// Text outside blocks of an extending template is ignored
+	composed, err = library.Compose("{% extends 'page' %}\nignored{% block title %}Welcome{% endblock %}")
+	require.NoError(t, err)
+	assert.Equal(t, "<h1>Welcome</h1><main></main><footer>{{brand.name}}</footer>", composed)
+}
+
+func TestLibrary_ComposeMultiLevel(t *testing.T) {
+	library := createTestLibrary(t)
+	require.NoError(t, library.AddLayout("marketing", `{% extends "page" %}{% block content %}<div class="promo">{% block promo %}{% endblock %}</div>{% endblock %}`))
+
+	composed, err := library.Compose(`{% extends "marketing" %}{% block promo %}Spring sale{% endblock %}{% block title %}Sale{% endblock %}`)
+	require.NoError(t, err)
+	assert.Equal(t, `<h1>Sale</h1><main><div class="promo">Spring sale</div></main><footer>{{brand.name}}</footer>`, composed)
+}
+
+func TestLibrary_ComposeWithoutLayout(t *testing.T) {
+	library := createTestLibrary(t)
+
+	composed, err := library.Compose(`Hi {{user_name}}{% include "signature" %}`)
+	require.NoError(t, err)
+	assert.Equal(t, "Hi {{user_name}}<footer>{{brand.name}}</footer>", composed)
+
+	composed, err = library.Compose("Plain {{user_name}}")
+	require.NoError(t, err)
+	assert.Equal(t, "Plain {{user_name}}", composed)
+}
+
+func TestLibrary_ComposeErrors(t *testing.T) {
+	library := createTestLibrary(t)
+	require.NoError(t, library.AddLayout("loop", `{% extends "loop" %}`))
+	require.NoError(t, library.AddPartial("recursive", `{% include "recursive" %}`))
+
+	tests := []struct {
+		name     string
+		source   string
+		notFound bool
+	}{
+		{"unknown layout", `{% extends "missing" %}`, true},
+		{"unknown partial", `{% include "missing" %}`, true},
+		{"extends not first", `Hi {% extends "page" %}`, false},
+		{"unclosed block", `{% block content %}`, false},
+		{"stray endblock", `{% endblock %}`, false},
+		{"mismatched endblock", `{% block a %}{% endblock b %}`, false},
+		{"duplicate block", `{% block a %}{% endblock %}{% block a %}{% endblock %}`, false},
+		{"unknown tag", `{% for x %}`, false},
+		{"layout cycle", `{% extends "loop" %}`, false},
+		{"partial cycle", `{% include "recursive" %}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := library.Compose(tt.source)
+			require.Error(t, err)
+			notifErr, ok := errors.AsNotificationError(err)
+			require.True(t, ok)
+			if tt.notFound {
+				assert.Equal(t, errors.ErrorCodeTemplateNotFound, notifErr.Code)
+			} else {
+				assert.Equal(t, errors.ErrorCodeValidationFailed, notifErr.Code)
+			}
+		})
+	}
+}
+
+func TestLibrary_AddPartialCannotExtend(t *testing.T) {
+	library := NewLibrary()
+
+	assert.Error(t, library.AddPartial("footer", `{% extends "base" %}`))
+	assert.Error(t, library.AddLayout("", "body"))
+	assert.Error(t, library.AddLayout("broken", `{% block content %}`))
+	assert.Equal(t, []string{"base", "base_text"}, library.Layouts())
+	assert.Equal(t, []string{"footer", "header"}, library.Partials())
+}
+
+func TestLibrary_Branding(t *testing.T) {
+	library := NewLibrary()
+	library.SetBranding("acme", map[string]string{"name": "Acme", "logo_url": "https://acme.example/logo.png"})
+
+	branding := library.Branding("acme")
+	assert.Equal(t, "Acme", branding["name"])
+	assert.Equal(t, DefaultBranding["primary_color"], branding["primary_color"])
+	assert.Equal(t, DefaultBranding["name"], library.Branding("unknown")["name"])
+
+	data := library.Data("acme", map[string]string{"user_name": "Jane", "brand.name": "Acme Europe"})
+	assert.Equal(t, "Jane", data["user_name"])
+	assert.Equal(t, "Acme Europe", data["brand.name"]) // explicit data wins
+	assert.Equal(t, "https://acme.example/logo.png", data["brand.logo_url"])
+
+	own, ok := library.TenantBranding("acme")
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{"name": "Acme", "logo_url": "https://acme.example/logo.png"}, own)
+	_, ok = library.TenantBranding("unknown")
+	assert.False(t, ok)
+}
+
+func TestLibrary_UpdateBranding(t *testing.T) {
+	library := NewLibrary()
+	library.SetBranding("acme", map[string]string{"name": "Acme"})
+
+	library.UpdateBranding("acme", map[string]string{"logo_url": "https://acme.example/logo.png"})
+	own, _ := library.TenantBranding("acme")
+	assert.Equal(t, map[string]string{"name": "Acme", "logo_url": "https://acme.example/logo.png"}, own)
+
+	// Empty values remove the tenant's own variables
+	library.UpdateBranding("acme", map[string]string{"name": ""})
+	assert.Equal(t, DefaultBranding["name"], library.Branding("acme")["name"])
+	library.UpdateBranding("acme", map[string]string{"logo_url": ""})
+	_, ok := library.TenantBranding("acme")
+	assert.False(t, ok)
+}
+
+func TestLibrary_DefaultLayouts(t *testing.T) {
+	library := NewLibrary()
+
+	composed, err := library.Compose(`{% extends "base" %}{% block content %}<p>Hi</p>{% endblock %}`)
+	require.NoError(t, err)
+	assert.Contains(t, composed, "<p>Hi</p>")
+	assert.Contains(t, composed, "{{brand.primary_color}}")
+	assert.Contains(t, composed, "{{brand.footer_text}}")
+	assert.NotContains(t, composed, "{%")
+}
+
+// Helper functions
+
+func createTestLibrary(t *testing.T) *Library {
+	library := NewLibrary()
+	require.NoError(t, library.AddPartial("signature", `<footer>{{brand.name}}</footer>`))
+	require.NoError(t, library.AddLayout("page", `<h1>{% block title %}Default title{% endblock %}</h1><main>{% block content %}{% endblock %}</main>{% include "signature" %}`))
+	return library
+}
@@ -0,0 +1,140 @@
+// Package limits enforces the configurable size and content limits of
+// notifications: the encoded size and attachment count of emails, the
+// segments of an SMS and the custom data keys of a push notification.
+// Requests over a limit fail with a PAYLOAD_TOO_LARGE error naming the
+// field, its size and the limit.
+package limits
+
+import (
+	"context"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pipeline"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// MiddlewareName is the name the limits middleware is registered under
+const MiddlewareName = "limits"
+
+// Defaults applied to zero configuration fields
+const (
+	defaultMaxEmailSize    = 25 << 20 // the common limit of mail providers
+	defaultMaxAttachments  = 20
+	defaultMaxSMSSegments  = 10
+	defaultMaxPushDataKeys = 64
+)
+
+// base64LineLength is the length of the lines of base64-encoded MIME parts
+const base64LineLength = 76
+
+// Limits checks notifications against size and content limits
+type Limits struct {
+	config config.LimitsConfig
+}
+
+// New creates limits from the configuration, using the defaults for zero fields
+func New(cfg config.LimitsConfig) *Limits {
+	if cfg.MaxEmailSize <= 0 {
+		cfg.MaxEmailSize = defaultMaxEmailSize
+	}
+	if cfg.MaxAttachments <= 0 {
+		cfg.MaxAttachments = defaultMaxAttachments
+	}
+	if cfg.MaxSMSSegments <= 0 {
+		cfg.MaxSMSSegments = defaultMaxSMSSegments
+	}
+	if cfg.MaxPushDataKeys <= 0 {
+		cfg.MaxPushDataKeys = defaultMaxPushDataKeys
+	}
+	return &Limits{config: cfg}
+}
+
+// Config returns the limits in effect
+func (l *Limits) Config() config.LimitsConfig {
+	return l.config
+}
+
+// CheckEmail checks an email's attachment count and encoded size
+func (l *Limits) CheckEmail(subject, htmlBody, textBody string, attachments []models.EmailAttachment) error {
+	if count := len(attachments); count > l.config.MaxAttachments {
+		return errors.NewPayloadTooLargeError("attachments", int64(count), int64(l.config.MaxAttachments), "attachments")
+	}
+	if size := EmailSize(subject, htmlBody, textBody, attachments); size > l.config.MaxEmailSize {
+		return errors.NewPayloadTooLargeError("email", size, l.config.MaxEmailSize, "bytes")
+	}
+	return nil
+}
+
+// CheckSMS checks the number of segments an SMS message takes
+func (l *Limits) CheckSMS(message string, unicode bool) error {
+	if segments := utils.CountSMSSegments(message, unicode); segments > l.config.MaxSMSSegments {
+		return errors.NewPayloadTooLargeError("message", int64(segments), int64(l.config.MaxSMSSegments), "segments")
+	}
+	return nil
+}
+
+// CheckPushData checks the number of custom data keys of a push notification
+func (l *Limits) CheckPushData(data map[string]string) error {
+	if keys := len(data); keys > l.config.MaxPushDataKeys {
+		return errors.NewPayloadTooLargeError("data", int64(keys), int64(l.config.MaxPushDataKeys), "keys")
+	}
+	return nil
+}
+
+// Check checks a request against the limits of its channel. Emails without
+// bodies in their email data are sent with the request body, as are SMS.
+func (l *Limits) Check(request *models.NotificationRequest) error {
+	switch request.Type {
+	case models.NotificationTypeEmail:
+		htmlBody, textBody := request.Body, ""
+		var attachments []models.EmailAttachment
+		if data := request.EmailData; data != nil {
+			if data.HTMLBody != "" || data.TextBody != "" {
+				htmlBody, textBody = data.HTMLBody, data.TextBody
+			}
+			attachments = data.Attachments
+		}
+		return l.CheckEmail(request.Subject, htmlBody, textBody, attachments)
+	case models.NotificationTypeSMS:
+		return l.CheckSMS(request.Body, request.SMSData != nil && request.SMSData.Unicode)
+	case models.NotificationTypePush:
+		if request.PushData != nil {
+			return l.CheckPushData(request.PushData.Data)
+		}
+	}
+	return nil
+}
+
+// Middleware returns a pipeline middleware rejecting requests over the
+// limits. It runs in the template stage so rendered bodies are measured;
+// register it after the other template-stage middleware.
+func (l *Limits) Middleware() pipeline.Middleware {
+	return func(next pipeline.Handler) pipeline.Handler {
+		return func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+			if err := l.Check(request); err != nil {
+				return nil, err
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// EmailSize returns the size in bytes of an email's subject, bodies and
+// base64-encoded attachments, which is what mail servers limit. Headers
+// other than the subject are not counted. Attachments without content, such
+// as those to be rendered or linked, count their declared size.
+func EmailSize(subject, htmlBody, textBody string, attachments []models.EmailAttachment) int64 {
+	size := int64(len(subject) + len(htmlBody) + len(textBody))
+	for _, attachment := range attachments {
+		raw := int64(len(attachment.Content))
+		if raw == 0 {
+			raw = attachment.Size
+		}
+		encoded := (raw + 2) / 3 * 4
+		lines := (encoded + base64LineLength - 1) / base64LineLength
+		size += encoded + 2*lines // each line ends in CRLF
+	}
+	return size
+}
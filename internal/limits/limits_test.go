@@ -0,0 +1,124 @@
+package limits
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestNew_Defaults(t *testing.T) {
+	cfg := New(config.LimitsConfig{MaxSMSSegments: 3}).Config()
+	assert.Equal(t, int64(defaultMaxEmailSize), cfg.MaxEmailSize)
+	assert.Equal(t, defaultMaxAttachments, cfg.MaxAttachments)
+	assert.Equal(t, 3, cfg.MaxSMSSegments)
+	assert.Equal(t, defaultMaxPushDataKeys, cfg.MaxPushDataKeys)
+}
+
+func TestLimits_CheckEmail(t *testing.T) {
+	limits := New(config.LimitsConfig{MaxEmailSize: 1000, MaxAttachments: 2})
+	attachment := models.EmailAttachment{Filename: "a.txt", Content: []byte(strings.Repeat("a", 300))}
+
+	assert.NoError(t, limits.CheckEmail("Hello", "<p>Hi</p>", "Hi", []models.EmailAttachment{attachment, attachment}))
+
+	err := limits.CheckEmail("Hello", "", "Hi", []models.EmailAttachment{attachment, attachment, attachment})
+	assertTooLarge(t, err, "attachments", "3", "2")
+
+	// Two 300 byte attachments fit, but not once base64 grows them to 824 bytes
+	err = limits.CheckEmail("Hello", strings.Repeat("x", 300), "", []models.EmailAttachment{attachment, attachment})
+	assertTooLarge(t, err, "email", "1129", "1000")
+}
+
+func TestEmailSize(t *testing.T) {
+	assert.Equal(t, int64(9), EmailSize("Hi", "<p>", "body", nil))
+
+	// 57 bytes encode to one 76 character line, 58 bytes to two
+	assert.Equal(t, int64(78), EmailSize("", "", "", []models.EmailAttachment{{Content: make([]byte, 57)}}))
+	assert.Equal(t, int64(84), EmailSize("", "", "", []models.EmailAttachment{{Content: make([]byte, 58)}}))
+
+	// Attachments without content count their declared size
+	assert.Equal(t, int64(78), EmailSize("", "", "", []models.EmailAttachment{{Size: 57}}))
+}
+
+func TestLimits_CheckSMS(t *testing.T) {
+	limits := New(config.LimitsConfig{MaxSMSSegments: 2})
+
+	tests := []struct {
+		name     string
+		message  string
+		unicode  bool
+		segments string
+	}{
+		{"single GSM segment", strings.Repeat("a", 160), false, ""},
+		{"two GSM segments", strings.Repeat("a", 306), false, ""},
+		{"three GSM segments", strings.Repeat("a", 307), false, "3"},
+		{"extension characters take two septets", strings.Repeat("€", 154), false, "3"},
+		{"accented GSM characters are one septet", strings.Repeat("é", 306), false, ""},
+		{"non-GSM character switches to UCS-2", strings.Repeat("a", 134) + "ł", false, "3"},
+		{"emoji take two UTF-16 units", strings.Repeat("😀", 68), false, "3"},
+		{"unicode flag forces UCS-2", strings.Repeat("a", 135), true, "3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := limits.CheckSMS(tt.message, tt.unicode)
+			if tt.segments == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assertTooLarge(t, err, "message", tt.segments, "2")
+		})
+	}
+}
+
+func TestLimits_CheckPushData(t *testing.T) {
+	limits := New(config.LimitsConfig{MaxPushDataKeys: 2})
+	assert.NoError(t, limits.CheckPushData(map[string]string{"a": "1", "b": "2"}))
+	assertTooLarge(t, limits.CheckPushData(map[string]string{"a": "1", "b": "2", "c": "3"}), "data", "3", "2")
+}
+
+func TestLimits_Middleware(t *testing.T) {
+	limits := New(config.LimitsConfig{MaxAttachments: 1, MaxSMSSegments: 1, MaxPushDataKeys: 1})
+	calls := 0
+	handler := limits.Middleware()(func(context.Context, *models.NotificationRequest) (*models.NotificationResponse, error) {
+		calls++
+		return &models.NotificationResponse{}, nil
+	})
+
+	requests := []*models.NotificationRequest{
+		{Type: models.NotificationTypeSMS, Body: strings.Repeat("a", 161)},
+		{Type: models.NotificationTypeEmail, Subject: "Hi", Body: "Hi", EmailData: &models.EmailData{
+			Attachments: []models.EmailAttachment{{Filename: "a.txt"}, {Filename: "b.txt"}},
+		}},
+		{Type: models.NotificationTypePush, Body: "Hi", PushData: &models.PushData{Data: map[string]string{"a": "1", "b": "2"}}},
+	}
+	for _, request := range requests {
+		_, err := handler(context.Background(), request)
+		notifErr, ok := errors.AsNotificationError(err)
+		require.True(t, ok, request.Type)
+		assert.Equal(t, errors.ErrorCodePayloadTooLarge, notifErr.Code, request.Type)
+	}
+	assert.Equal(t, 0, calls)
+
+	_, err := handler(context.Background(), &models.NotificationRequest{Type: models.NotificationTypeSMS, Body: "Hi"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+// Helper functions
+
+func assertTooLarge(t *testing.T, err error, field, size, limit string) {
+	t.Helper()
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok, "expected a notification error, got %v", err)
+	assert.Equal(t, errors.ErrorCodePayloadTooLarge, notifErr.Code)
+	assert.Equal(t, field, notifErr.Metadata["field"])
+	assert.Equal(t, size, notifErr.Metadata["size"])
+	assert.Equal(t, limit, notifErr.Metadata["limit"])
+}
@@ -0,0 +1,251 @@
+// Package loadshed protects the service under overload. A shedder watches
+// queue depth and send latency and, once they cross configured thresholds,
+// rejects lower-priority notifications with a Retry-After hint while urgent
+// notifications are still accepted.
+package loadshed
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// Level is how loaded the service is
+type Level string
+
+const (
+	LevelNormal   Level = "normal"
+	LevelElevated Level = "elevated" // priorities in ShedElevated are rejected with 429
+	LevelCritical Level = "critical" // priorities in ShedElevated and ShedCritical are rejected with 503
+)
+
+// maxSamples bounds the latency samples kept in the window
+const maxSamples = 1000
+
+// sample is a send latency observed at a time
+type sample struct {
+	at      time.Time
+	latency time.Duration
+}
+
+// Stats holds load shedding metrics
+type Stats struct {
+	Level      Level                     `json:"level"`
+	QueueDepth int                       `json:"queue_depth"`
+	Latency    time.Duration             `json:"latency"` // average over the latency window
+	Accepted   int64                     `json:"accepted"`
+	Shed       map[models.Priority]int64 `json:"shed"`
+	LastShedAt *time.Time                `json:"last_shed_at,omitempty"`
+}
+
+// Shedder decides whether to accept a notification given the current load
+type Shedder struct {
+	config   config.LoadShedConfig
+	elevated map[models.Priority]bool
+	critical map[models.Priority]bool
+	logger   interfaces.Logger
+
+	mu         sync.Mutex
+	queueDepth func() int
+	samples    []sample
+	accepted   int64
+	shed       map[models.Priority]int64
+	lastShedAt *time.Time
+	lastLevel  Level
+	now        func() time.Time
+}
+
+// NewShedder creates a shedder from a policy. Urgent notifications are never
+// shed, so the policy may not list them.
+func NewShedder(cfg config.LoadShedConfig, logger interfaces.Logger) (*Shedder, error) {
+	if cfg.LatencyWindow <= 0 {
+		cfg.LatencyWindow = 30 * time.Second
+	}
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = 10 * time.Second
+	}
+
+	elevated, err := priorities("shed_elevated", cfg.ShedElevated)
+	if err != nil {
+		return nil, err
+	}
+	critical, err := priorities("shed_critical", cfg.ShedCritical)
+	if err != nil {
+		return nil, err
+	}
+	// Whatever is shed when elevated is shed when critical too
+	for priority := range elevated {
+		critical[priority] = true
+	}
+
+	return &Shedder{
+		config:    cfg,
+		elevated:  elevated,
+		critical:  critical,
+		logger:    logger,
+		shed:      make(map[models.Priority]int64),
+		lastLevel: LevelNormal,
+		now:       time.Now,
+	}, nil
+}
+
+// SetQueueDepth sets the function reporting the send queue's depth, e.g.
+// MemoryQueue.Len
+func (s *Shedder) SetQueueDepth(depth func() int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queueDepth = depth
+}
+
+// ObserveLatency records how long a send took
+func (s *Shedder) ObserveLatency(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.samples = append(s.samples, sample{at: s.now(), latency: latency})
+	if len(s.samples) > maxSamples {
+		s.samples = s.samples[len(s.samples)-maxSamples:]
+	}
+}
+
+// Level returns the current load level
+func (s *Shedder) Level() Level {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	level, _, _ := s.levelLocked()
+	return level
+}
+
+// Check returns an error when a notification of the priority should be shed
+// at the current load level: RATE_LIMITED with status 429 when elevated and
+// 503 when critical, both with a retry_after hint in seconds
+func (s *Shedder) Check(priority models.Priority) error {
+	if priority == "" {
+		priority = models.PriorityNormal
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	level, depth, latency := s.levelLocked()
+	if level != s.lastLevel {
+		s.logger.Warnf("Load level changed from %s to %s (queue depth %d, latency %s)", s.lastLevel, level, depth, latency)
+		s.lastLevel = level
+	}
+
+	status := 0
+	switch {
+	case level == LevelCritical && s.critical[priority]:
+		status = http.StatusServiceUnavailable
+	case level == LevelElevated && s.elevated[priority]:
+		status = http.StatusTooManyRequests
+	default:
+		s.accepted++
+		return nil
+	}
+
+	now := s.now()
+	s.shed[priority]++
+	s.lastShedAt = &now
+
+	err := errors.NewRateLimitError(strconv.Itoa(int(math.Ceil(s.config.RetryAfter.Seconds()))))
+	err.Message = fmt.Sprintf("service is %s; %s priority notifications are shed", level, priority)
+	err.StatusCode = status
+	err.WithMetadata("load_level", string(level))
+	return err
+}
+
+// Stats returns the load shedding metrics
+func (s *Shedder) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	level, depth, latency := s.levelLocked()
+	stats := Stats{
+		Level:      level,
+		QueueDepth: depth,
+		Latency:    latency,
+		Accepted:   s.accepted,
+		Shed:       make(map[models.Priority]int64, len(s.shed)),
+	}
+	for priority, count := range s.shed {
+		stats.Shed[priority] = count
+	}
+	if s.lastShedAt != nil {
+		lastShedAt := *s.lastShedAt
+		stats.LastShedAt = &lastShedAt
+	}
+	return stats
+}
+
+// levelLocked computes the load level with the queue depth and average
+// latency it is based on. Expired latency samples are dropped, so the level
+// recovers when shed traffic stops producing samples.
+func (s *Shedder) levelLocked() (Level, int, time.Duration) {
+	depth := 0
+	if s.queueDepth != nil {
+		depth = s.queueDepth()
+	}
+
+	cutoff := s.now().Add(-s.config.LatencyWindow)
+	first := 0
+	for first < len(s.samples) && s.samples[first].at.Before(cutoff) {
+		first++
+	}
+	s.samples = s.samples[first:]
+
+	var latency time.Duration
+	if len(s.samples) > 0 {
+		var total time.Duration
+		for _, sample := range s.samples {
+			total += sample.latency
+		}
+		latency = total / time.Duration(len(s.samples))
+	}
+
+	switch {
+	case exceeds(depth, s.config.QueueDepthCritical) || exceedsLatency(latency, s.config.LatencyCritical):
+		return LevelCritical, depth, latency
+	case exceeds(depth, s.config.QueueDepthElevated) || exceedsLatency(latency, s.config.LatencyElevated):
+		return LevelElevated, depth, latency
+	default:
+		return LevelNormal, depth, latency
+	}
+}
+
+// exceeds reports whether a queue depth reaches a threshold; zero disables it
+func exceeds(depth, threshold int) bool {
+	return threshold > 0 && depth >= threshold
+}
+
+// exceedsLatency reports whether a latency reaches a threshold; zero disables it
+func exceedsLatency(latency, threshold time.Duration) bool {
+	return threshold > 0 && latency >= threshold
+}
+
+// priorities parses a policy's priority list
+func priorities(field string, names []string) (map[models.Priority]bool, error) {
+	result := make(map[models.Priority]bool, len(names))
+	for _, name := range names {
+		priority := models.Priority(name)
+		if !utils.IsValidPriority(priority) {
+			return nil, errors.NewValidationError(field, fmt.Sprintf("unknown priority: %q", name))
+		}
+		if priority == models.PriorityUrgent {
+			return nil, errors.NewValidationError(field, "urgent notifications are never shed")
+		}
+		result[priority] = true
+	}
+	return result, nil
+}
@@ -0,0 +1,101 @@
+package loadshed
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestShedder_QueueDepth(t *testing.T) {
+	shedder := createTestShedder(t)
+	depth := 0
+	shedder.SetQueueDepth(func() int { return depth })
+
+	assert.Equal(t, LevelNormal, shedder.Level())
+	assert.NoError(t, shedder.Check(models.PriorityLow))
+
+	depth = 100
+	assert.Equal(t, LevelElevated, shedder.Level())
+	assertShed(t, shedder.Check(models.PriorityLow), http.StatusTooManyRequests)
+	assert.NoError(t, shedder.Check(models.PriorityNormal))
+	assert.NoError(t, shedder.Check(models.PriorityUrgent))
+
+	depth = 500
+	assert.Equal(t, LevelCritical, shedder.Level())
+	assertShed(t, shedder.Check(models.PriorityLow), http.StatusServiceUnavailable)
+	assertShed(t, shedder.Check(""), http.StatusServiceUnavailable) // defaults to normal
+	assertShed(t, shedder.Check(models.PriorityHigh), http.StatusServiceUnavailable)
+	assert.NoError(t, shedder.Check(models.PriorityUrgent))
+
+	stats := shedder.Stats()
+	assert.Equal(t, LevelCritical, stats.Level)
+	assert.Equal(t, 500, stats.QueueDepth)
+	assert.Equal(t, int64(4), stats.Accepted)
+	assert.Equal(t, int64(2), stats.Shed[models.PriorityLow])
+	assert.Equal(t, int64(1), stats.Shed[models.PriorityNormal])
+	assert.NotNil(t, stats.LastShedAt)
+}
+
+func TestShedder_Latency(t *testing.T) {
+	shedder := createTestShedder(t)
+	clock := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	shedder.now = func() time.Time { return clock }
+
+	shedder.ObserveLatency(100 * time.Millisecond)
+	shedder.ObserveLatency(3 * time.Second)
+	assert.Equal(t, LevelElevated, shedder.Level())
+	assert.Equal(t, 1550*time.Millisecond, shedder.Stats().Latency)
+
+	shedder.ObserveLatency(10 * time.Second)
+	assert.Equal(t, LevelCritical, shedder.Level())
+
+	// Samples expire, so the level recovers without new traffic
+	clock = clock.Add(time.Minute)
+	assert.Equal(t, LevelNormal, shedder.Level())
+	assert.Zero(t, shedder.Stats().Latency)
+}
+
+func TestNewShedder_Errors(t *testing.T) {
+	logger := utils.NewSimpleLogger("info")
+
+	_, err := NewShedder(config.LoadShedConfig{ShedElevated: []string{"urgent"}}, logger)
+	assert.Error(t, err)
+	_, err = NewShedder(config.LoadShedConfig{ShedCritical: []string{"bulk"}}, logger)
+	assert.Error(t, err)
+}
+
+// Helper functions
+
+func assertShed(t *testing.T, err error, status int) {
+	t.Helper()
+	require.Error(t, err)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeRateLimited, notifErr.Code)
+	assert.Equal(t, status, notifErr.StatusCode)
+	assert.Equal(t, "5", notifErr.Metadata["retry_after"])
+}
+
+func createTestShedder(t *testing.T) *Shedder {
+	shedder, err := NewShedder(config.LoadShedConfig{
+		Enabled:            true,
+		QueueDepthElevated: 100,
+		QueueDepthCritical: 500,
+		LatencyElevated:    time.Second,
+		LatencyCritical:    4 * time.Second,
+		LatencyWindow:      30 * time.Second,
+		ShedElevated:       []string{"low"},
+		ShedCritical:       []string{"normal", "high"},
+		RetryAfter:         5 * time.Second,
+	}, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	return shedder
+}
@@ -0,0 +1,371 @@
+// Package loadtest drives a notification service with synthetic traffic at
+// a fixed rate and a configurable mix of channels through the send queue,
+// and reports throughput, latency percentiles and allocations, so
+// performance-oriented changes can be measured before and after.
+package loadtest
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/internal/queue"
+	"github.com/nareshkumar-microsoft/notificationService/internal/repository"
+	"github.com/nareshkumar-microsoft/notificationService/internal/services"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// Config describes a load test run
+type Config struct {
+	Rate      int                             // notifications offered per second
+	Duration  time.Duration                   // how long traffic is offered
+	Workers   int                             // queue workers sending concurrently
+	QueueSize int                             // queue capacity; offers to a full queue are dropped
+	Mix       map[models.NotificationType]int // relative weight of each channel
+	Seed      int64                           // seeds recipient and channel choice; 0 seeds from the clock
+}
+
+// DefaultMix is an email-heavy mix of the email, SMS and push channels
+var DefaultMix = map[models.NotificationType]int{
+	models.NotificationTypeEmail: 6,
+	models.NotificationTypeSMS:   2,
+	models.NotificationTypePush:  2,
+}
+
+// Report summarizes a load test run. Latency is measured from a request
+// being offered to its send finishing, so it includes time spent queued.
+type Report struct {
+	Offered    int                             `json:"offered"`
+	Sent       int                             `json:"sent"`
+	Failed     int                             `json:"failed"`
+	Dropped    int                             `json:"dropped"` // offered while the queue was full
+	ByType     map[models.NotificationType]int `json:"by_type"`
+	Elapsed    time.Duration                   `json:"elapsed"`
+	Throughput float64                         `json:"throughput"` // finished sends per second
+	P50        time.Duration                   `json:"p50"`
+	P95        time.Duration                   `json:"p95"`
+	P99        time.Duration                   `json:"p99"`
+	Max        time.Duration                   `json:"max"`
+	Allocs     uint64                          `json:"allocs"`      // heap allocations during the run
+	AllocBytes uint64                          `json:"alloc_bytes"` // bytes allocated during the run
+	GCCycles   uint32                          `json:"gc_cycles"`
+	Errors     map[string]int                  `json:"errors,omitempty"` // failures by error code
+}
+
+// AllocsPerSend returns the heap allocations per finished send
+func (r *Report) AllocsPerSend() float64 {
+	if finished := r.Sent + r.Failed; finished > 0 {
+		return float64(r.Allocs) / float64(finished)
+	}
+	return 0
+}
+
+// String formats the report for a terminal
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "offered %d, sent %d, failed %d, dropped %d in %s\n", r.Offered, r.Sent, r.Failed, r.Dropped, r.Elapsed.Round(time.Millisecond))
+	fmt.Fprintf(&b, "throughput %.1f/s\n", r.Throughput)
+	fmt.Fprintf(&b, "latency p50 %s, p95 %s, p99 %s, max %s\n", r.P50, r.P95, r.P99, r.Max)
+	fmt.Fprintf(&b, "allocations %d (%.0f per send), %d bytes, %d GC cycles\n", r.Allocs, r.AllocsPerSend(), r.AllocBytes, r.GCCycles)
+	codes := make([]string, 0, len(r.Errors))
+	for code := range r.Errors {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		fmt.Fprintf(&b, "error %s: %d\n", code, r.Errors[code])
+	}
+	return b.String()
+}
+
+// Runner offers synthetic traffic to a notification service
+type Runner struct {
+	service interfaces.NotificationService
+	config  Config
+	logger  interfaces.Logger
+	random  *rand.Rand
+	types   []models.NotificationType // channels, repeated by weight
+
+	pending sync.WaitGroup // enqueued requests not yet sent
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	report    Report
+}
+
+// NewRunner creates a runner for a configuration
+func NewRunner(service interfaces.NotificationService, cfg Config, logger interfaces.Logger) (*Runner, error) {
+	if cfg.Rate <= 0 {
+		return nil, errors.NewValidationError("rate", "rate must be positive")
+	}
+	if cfg.Duration <= 0 {
+		return nil, errors.NewValidationError("duration", "duration must be positive")
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 16
+	}
+	if cfg.Mix == nil {
+		cfg.Mix = DefaultMix
+	}
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	var types []models.NotificationType
+	for _, notificationType := range []models.NotificationType{
+		models.NotificationTypeEmail,
+		models.NotificationTypeSMS,
+		models.NotificationTypePush,
+	} {
+		for i := 0; i < cfg.Mix[notificationType]; i++ {
+			types = append(types, notificationType)
+		}
+	}
+	for notificationType, weight := range cfg.Mix {
+		if weight < 0 || (weight > 0 && !supported(notificationType)) {
+			return nil, errors.NewValidationError("mix", fmt.Sprintf("unsupported channel weight %s=%d", notificationType, weight))
+		}
+	}
+	if len(types) == 0 {
+		return nil, errors.NewValidationError("mix", "at least one channel must have a positive weight")
+	}
+
+	return &Runner{
+		service: service,
+		config:  cfg,
+		logger:  logger,
+		random:  rand.New(rand.NewSource(seed)),
+		types:   types,
+	}, nil
+}
+
+// NewDispatcher creates a dispatcher sending through the mock email, SMS and
+// push providers, each running the simulation
+func NewDispatcher(sim providers.Simulation, logger interfaces.Logger) (*services.Dispatcher, error) {
+	dispatcher, err := services.NewDispatcherFromConfig(config.ProvidersConfig{
+		Email: config.EmailProviderConfig{Provider: "mock", Enabled: true},
+		SMS:   config.SMSProviderConfig{Provider: "mock", Enabled: true},
+		Push:  config.PushProviderConfig{Provider: "mock", Enabled: true},
+	}, repository.NewMemoryRepository(), logger)
+	if err != nil {
+		return nil, err
+	}
+
+	for notificationType, provider := range dispatcher.ListProviders() {
+		simulated, ok := provider.(interface{ SetSimulation(providers.Simulation) })
+		if !ok {
+			return nil, errors.NewNotificationError(errors.ErrorCodeInternal, fmt.Sprintf("%s provider does not support simulation", notificationType))
+		}
+		simulated.SetSimulation(sim)
+	}
+	return dispatcher, nil
+}
+
+// ParseMix parses a channel mix such as "email=6,sms=2,push=2"
+func ParseMix(value string) (map[models.NotificationType]int, error) {
+	mix := make(map[models.NotificationType]int)
+	for _, part := range strings.Split(value, ",") {
+		name, weight, found := strings.Cut(strings.TrimSpace(part), "=")
+		var n int
+		if _, err := fmt.Sscanf(weight, "%d", &n); !found || err != nil || n < 0 {
+			return nil, errors.NewValidationError("mix", fmt.Sprintf("invalid channel weight %q, expected name=weight", part))
+		}
+		notificationType := models.NotificationType(strings.ToLower(name))
+		if !supported(notificationType) {
+			return nil, errors.NewValidationError("mix", fmt.Sprintf("unsupported channel %q", name))
+		}
+		mix[notificationType] = n
+	}
+	return mix, nil
+}
+
+// Run offers traffic for the configured duration, waits for the queue to
+// drain and reports the results. Cancelling the context stops the run early.
+func (r *Runner) Run(ctx context.Context) (*Report, error) {
+	q := queue.NewMemoryQueue(r.config.QueueSize)
+	workers := queue.NewWorkerPool(q, r.config.Workers, r.process, r.logger)
+
+	r.report = Report{ByType: make(map[models.NotificationType]int), Errors: make(map[string]int)}
+	r.latencies = make([]time.Duration, 0, r.config.Rate*int(r.config.Duration/time.Second+1))
+
+	runtime.GC()
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	started := time.Now()
+	workers.Start(ctx)
+	r.offer(ctx, q)
+
+	// Let the workers finish what was offered; stopping them earlier would
+	// cancel in-flight sends
+	drained := make(chan struct{})
+	go func() {
+		r.pending.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-ctx.Done():
+	}
+	workers.Stop()
+
+	// Requests left behind by a cancelled run are never sent
+	for {
+		if _, err := q.TryDequeue(); err != nil {
+			break
+		}
+		r.pending.Done()
+	}
+	elapsed := time.Since(started)
+
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := r.report
+	report.Elapsed = elapsed
+	report.Throughput = float64(report.Sent+report.Failed) / elapsed.Seconds()
+	report.Allocs = after.Mallocs - before.Mallocs
+	report.AllocBytes = after.TotalAlloc - before.TotalAlloc
+	report.GCCycles = after.NumGC - before.NumGC
+
+	sort.Slice(r.latencies, func(i, j int) bool { return r.latencies[i] < r.latencies[j] })
+	report.P50 = percentile(r.latencies, 0.50)
+	report.P95 = percentile(r.latencies, 0.95)
+	report.P99 = percentile(r.latencies, 0.99)
+	if len(r.latencies) > 0 {
+		report.Max = r.latencies[len(r.latencies)-1]
+	}
+
+	r.logger.Infof("Load test finished: %d sent, %d failed, %d dropped, %.1f/s, p99 %s",
+		report.Sent, report.Failed, report.Dropped, report.Throughput, report.P99)
+	return &report, ctx.Err()
+}
+
+// offer enqueues requests at the configured rate until the duration ends.
+// Requests due since the last tick are offered together, so rates above
+// the ticker's resolution are still met.
+func (r *Runner) offer(ctx context.Context, q *queue.MemoryQueue) {
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+
+	started := time.Now()
+	offered := 0
+	total := int(int64(r.config.Rate) * int64(r.config.Duration) / int64(time.Second))
+	for offered < total {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			due := int(int64(r.config.Rate) * int64(now.Sub(started)) / int64(time.Second))
+			if due > total {
+				due = total
+			}
+			for ; offered < due; offered++ {
+				r.enqueue(q, offered)
+			}
+		}
+	}
+}
+
+// enqueue offers one request, counting it as dropped when the queue is full
+func (r *Runner) enqueue(q *queue.MemoryQueue, n int) {
+	request := r.request(n)
+	job := &queue.Job{Request: request, EnqueuedAt: time.Now()}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.report.Offered++
+	r.report.ByType[request.Type]++
+	r.pending.Add(1)
+	if err := q.Enqueue(job); err != nil {
+		r.pending.Done()
+		r.report.Dropped++
+	}
+}
+
+// process sends a queued request and records its latency and outcome
+func (r *Runner) process(ctx context.Context, job *queue.Job) error {
+	defer r.pending.Done()
+
+	_, err := r.service.SendNotification(ctx, job.Request)
+	latency := time.Since(job.EnqueuedAt)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.latencies = append(r.latencies, latency)
+	if err != nil {
+		r.report.Failed++
+		code := "UNKNOWN"
+		if notifErr, ok := errors.AsNotificationError(err); ok {
+			code = string(notifErr.Code)
+		}
+		r.report.Errors[code]++
+		return nil
+	}
+	r.report.Sent++
+	return nil
+}
+
+// request builds the nth synthetic request. Callers must not run it concurrently.
+func (r *Runner) request(n int) *models.NotificationRequest {
+	notificationType := r.types[r.random.Intn(len(r.types))]
+	request := &models.NotificationRequest{
+		Type:     notificationType,
+		Priority: models.PriorityNormal,
+		Subject:  "Load test",
+		Body:     fmt.Sprintf("Load test notification %d", n),
+		Metadata: map[string]string{"load_test": "true"},
+	}
+
+	switch notificationType {
+	case models.NotificationTypeEmail:
+		request.Recipient = fmt.Sprintf("user%d@example.com", r.random.Intn(100000))
+	case models.NotificationTypeSMS:
+		request.Recipient = fmt.Sprintf("+1555%07d", r.random.Intn(10000000))
+		request.SMSData = &models.SMSData{CountryCode: "US"}
+	case models.NotificationTypePush:
+		request.Recipient = fmt.Sprintf("%064x", r.random.Uint64())
+		request.PushData = &models.PushData{Platform: "ios"}
+	}
+	return request
+}
+
+// supported reports whether synthetic traffic can be generated for a channel
+func supported(notificationType models.NotificationType) bool {
+	switch notificationType {
+	case models.NotificationTypeEmail, models.NotificationTypeSMS, models.NotificationTypePush:
+		return true
+	default:
+		return false
+	}
+}
+
+// percentile returns the pth percentile of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	index := int(float64(len(sorted))*p+0.5) - 1
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(sorted) {
+		index = len(sorted) - 1
+	}
+	return sorted[index]
+}
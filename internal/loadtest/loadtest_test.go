@@ -0,0 +1,136 @@
+package loadtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/internal/services"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestRunner_Run(t *testing.T) {
+	runner, err := NewRunner(createTestDispatcher(t, providers.Simulation{Seed: 1}), Config{
+		Rate:      500,
+		Duration:  200 * time.Millisecond,
+		Workers:   4,
+		QueueSize: 1000,
+		Seed:      1,
+	}, utils.NewSimpleLogger("error"))
+	require.NoError(t, err)
+
+	report, err := runner.Run(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 100, report.Offered)
+	assert.Equal(t, 100, report.Sent)
+	assert.Zero(t, report.Failed)
+	assert.Zero(t, report.Dropped)
+	assert.Greater(t, report.ByType[models.NotificationTypeEmail], report.ByType[models.NotificationTypePush])
+	assert.Positive(t, report.Throughput)
+	assert.LessOrEqual(t, report.P50, report.P99)
+	assert.LessOrEqual(t, report.P99, report.Max)
+	assert.Positive(t, report.AllocsPerSend())
+	assert.Contains(t, report.String(), "sent 100")
+}
+
+func TestRunner_Run_CountsFailuresAndDrops(t *testing.T) {
+	dispatcher := createTestDispatcher(t, providers.Simulation{
+		Seed:       1,
+		LatencyMin: 5 * time.Millisecond,
+		LatencyMax: 5 * time.Millisecond,
+		Rules:      []providers.FailureRule{{Code: errors.ErrorCodeRateLimited, Rate: 0.5}},
+	})
+	runner, err := NewRunner(dispatcher, Config{
+		Rate:      1000,
+		Duration:  100 * time.Millisecond,
+		Workers:   1,
+		QueueSize: 5,
+		Mix:       map[models.NotificationType]int{models.NotificationTypeSMS: 1},
+		Seed:      1,
+	}, utils.NewSimpleLogger("error"))
+	require.NoError(t, err)
+
+	report, err := runner.Run(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 100, report.Offered)
+	assert.Equal(t, report.ByType[models.NotificationTypeSMS], report.Offered)
+	assert.Positive(t, report.Dropped)
+	assert.Positive(t, report.Failed)
+	assert.Equal(t, report.Offered, report.Sent+report.Failed+report.Dropped)
+	assert.Equal(t, report.Failed, report.Errors[string(errors.ErrorCodeRateLimited)])
+}
+
+func TestNewRunner_Validation(t *testing.T) {
+	dispatcher := createTestDispatcher(t, providers.Simulation{})
+	logger := utils.NewSimpleLogger("error")
+
+	_, err := NewRunner(dispatcher, Config{Duration: time.Second}, logger)
+	assert.Error(t, err)
+
+	_, err = NewRunner(dispatcher, Config{Rate: 10}, logger)
+	assert.Error(t, err)
+
+	_, err = NewRunner(dispatcher, Config{Rate: 10, Duration: time.Second, Mix: map[models.NotificationType]int{models.NotificationTypeEmail: 0}}, logger)
+	assert.Error(t, err)
+
+	_, err = NewRunner(dispatcher, Config{Rate: 10, Duration: time.Second, Mix: map[models.NotificationType]int{models.NotificationTypeVoice: 1}}, logger)
+	assert.Error(t, err)
+}
+
+func TestParseMix(t *testing.T) {
+	mix, err := ParseMix("email=5, SMS=3,push=0")
+	require.NoError(t, err)
+	assert.Equal(t, map[models.NotificationType]int{
+		models.NotificationTypeEmail: 5,
+		models.NotificationTypeSMS:   3,
+		models.NotificationTypePush:  0,
+	}, mix)
+
+	for _, value := range []string{"email", "email=x", "email=-1", "fax=1"} {
+		_, err := ParseMix(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := make([]time.Duration, 100)
+	for i := range sorted {
+		sorted[i] = time.Duration(i+1) * time.Millisecond
+	}
+
+	assert.Equal(t, 50*time.Millisecond, percentile(sorted, 0.50))
+	assert.Equal(t, 99*time.Millisecond, percentile(sorted, 0.99))
+	assert.Equal(t, time.Duration(0), percentile(nil, 0.99))
+}
+
+func BenchmarkRunner_Run(b *testing.B) {
+	dispatcher, err := NewDispatcher(providers.Simulation{Seed: 1}, utils.NewSimpleLogger("error"))
+	require.NoError(b, err)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		runner, err := NewRunner(dispatcher, Config{Rate: 5000, Duration: 100 * time.Millisecond, Workers: 8, QueueSize: 10000, Seed: 1}, utils.NewSimpleLogger("error"))
+		require.NoError(b, err)
+
+		report, err := runner.Run(context.Background())
+		require.NoError(b, err)
+		b.ReportMetric(report.Throughput, "sends/s")
+		b.ReportMetric(float64(report.P99.Microseconds()), "p99-µs")
+	}
+}
+
+// Helper functions
+
+func createTestDispatcher(t *testing.T, sim providers.Simulation) *services.Dispatcher {
+	dispatcher, err := NewDispatcher(sim, utils.NewSimpleLogger("error"))
+	require.NoError(t, err)
+	return dispatcher
+}
@@ -0,0 +1,39 @@
+// Package lock provides the interfaces.Locker implementations replicas of
+// the service share, so scheduled work such as jobs and purges runs on one
+// instance at a time.
+package lock
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// Lock types accepted in config.LockConfig
+const (
+	TypeMemory   = "memory"
+	TypeRedis    = "redis"
+	TypePostgres = "postgres"
+)
+
+// NewLocker creates the locker the configuration selects. The Postgres
+// locker uses db, which the caller opens with its driver of choice; the
+// others ignore it.
+func NewLocker(cfg config.LockConfig, db *sql.DB) (interfaces.Locker, error) {
+	switch cfg.Type {
+	case "", TypeMemory:
+		return NewMemoryLocker(), nil
+	case TypeRedis:
+		return NewRedisLocker(cfg)
+	case TypePostgres:
+		if db == nil {
+			return nil, errors.NewValidationError("type", "postgres locks need a database connection")
+		}
+		return NewPostgresLocker(db, cfg.KeyPrefix), nil
+	default:
+		return nil, errors.NewValidationError("type", fmt.Sprintf("unknown lock type: %q", cfg.Type))
+	}
+}
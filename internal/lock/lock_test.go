@@ -0,0 +1,30 @@
+package lock
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+)
+
+func TestNewLocker(t *testing.T) {
+	locker, err := NewLocker(config.LockConfig{}, nil)
+	require.NoError(t, err)
+	assert.IsType(t, &MemoryLocker{}, locker)
+
+	locker, err = NewLocker(config.LockConfig{Type: TypeRedis, RedisURL: "cache:6379"}, nil)
+	require.NoError(t, err)
+	assert.IsType(t, &RedisLocker{}, locker)
+
+	_, err = NewLocker(config.LockConfig{Type: TypePostgres}, nil)
+	assert.Error(t, err)
+	locker, err = NewLocker(config.LockConfig{Type: TypePostgres}, &sql.DB{})
+	require.NoError(t, err)
+	assert.IsType(t, &PostgresLocker{}, locker)
+
+	_, err = NewLocker(config.LockConfig{Type: "zookeeper"}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,55 @@
+package lock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryLocker_AcquireAndRelease(t *testing.T) {
+	locker := NewMemoryLocker()
+	clock := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	locker.now = func() time.Time { return clock }
+	ctx := context.Background()
+
+	acquired, err := locker.Acquire(ctx, "retention", "instance-1", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	acquired, err = locker.Acquire(ctx, "retention", "instance-2", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	// Other jobs are locked separately, and the holder may extend its lease
+	acquired, _ = locker.Acquire(ctx, "reconcile", "instance-2", time.Minute)
+	assert.True(t, acquired)
+	acquired, _ = locker.Acquire(ctx, "retention", "instance-1", time.Minute)
+	assert.True(t, acquired)
+
+	// Only the holder releases a lock
+	require.NoError(t, locker.Release(ctx, "retention", "instance-2"))
+	acquired, _ = locker.Acquire(ctx, "retention", "instance-2", time.Minute)
+	assert.False(t, acquired)
+
+	require.NoError(t, locker.Release(ctx, "retention", "instance-1"))
+	acquired, _ = locker.Acquire(ctx, "retention", "instance-2", time.Minute)
+	assert.True(t, acquired)
+}
+
+func TestMemoryLocker_LeaseExpires(t *testing.T) {
+	locker := NewMemoryLocker()
+	clock := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	locker.now = func() time.Time { return clock }
+	ctx := context.Background()
+
+	acquired, _ := locker.Acquire(ctx, "retention", "instance-1", time.Minute)
+	require.True(t, acquired)
+
+	clock = clock.Add(time.Minute)
+	acquired, err := locker.Acquire(ctx, "retention", "instance-2", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+}
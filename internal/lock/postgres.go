@@ -0,0 +1,100 @@
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// PostgresSchema is the PostgreSQL definition of the lease table
+// PostgresLocker uses
+const PostgresSchema = `CREATE TABLE IF NOT EXISTS notification_locks (
+    key        TEXT PRIMARY KEY,
+    owner      TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS notification_locks_expires_at ON notification_locks (expires_at);`
+
+const (
+	// acquireQuery takes a free or expired lease, or renews the owner's.
+	// It affects no row when another owner holds an unexpired lease. Leases
+	// use the database clock so instances on different hosts agree on expiry.
+	acquireQuery = `INSERT INTO notification_locks (key, owner, expires_at)
+VALUES ($1, $2, now() + $3 * interval '1 millisecond')
+ON CONFLICT (key) DO UPDATE SET owner = EXCLUDED.owner, expires_at = EXCLUDED.expires_at
+WHERE notification_locks.owner = EXCLUDED.owner OR notification_locks.expires_at <= now()`
+
+	releaseQuery = `DELETE FROM notification_locks WHERE key = $1 AND owner = $2`
+
+	sweepQuery = `DELETE FROM notification_locks WHERE expires_at <= now()`
+)
+
+// sweepInterval is how often a locker deletes expired leases, which are
+// never released when their owners let them run out
+const sweepInterval = time.Minute
+
+// PostgresLocker implements the Locker interface with leases in the table
+// defined by PostgresSchema. Each call is one statement on a pooled
+// connection, so held locks pin no connections, and the leases of an
+// instance that dies run out on their own.
+type PostgresLocker struct {
+	db     *sql.DB
+	prefix string
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewPostgresLocker creates a Postgres locker on a database holding the
+// PostgresSchema table
+func NewPostgresLocker(db *sql.DB, prefix string) *PostgresLocker {
+	return &PostgresLocker{
+		db:        db,
+		prefix:    prefix,
+		lastSweep: time.Now(),
+	}
+}
+
+// Acquire implements the Locker interface
+func (l *PostgresLocker) Acquire(ctx context.Context, key, owner string, lease time.Duration) (bool, error) {
+	if lease < time.Millisecond {
+		return false, errors.NewValidationError("lease", "lease must be at least a millisecond")
+	}
+	l.sweep(ctx)
+
+	result, err := l.db.ExecContext(ctx, acquireQuery, l.prefix+key, owner, lease.Milliseconds())
+	if err != nil {
+		return false, errors.NewInternalError("failed to take lock "+key, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.NewInternalError("failed to take lock "+key, err)
+	}
+	return affected > 0, nil
+}
+
+// Release implements the Locker interface
+func (l *PostgresLocker) Release(ctx context.Context, key, owner string) error {
+	if _, err := l.db.ExecContext(ctx, releaseQuery, l.prefix+key, owner); err != nil {
+		return errors.NewInternalError("failed to release lock "+key, err)
+	}
+	return nil
+}
+
+// sweep deletes expired leases at most once per sweep interval. Failures
+// are ignored; the next sweep retries.
+func (l *PostgresLocker) sweep(ctx context.Context) {
+	l.mu.Lock()
+	due := time.Since(l.lastSweep) >= sweepInterval
+	if due {
+		l.lastSweep = time.Now()
+	}
+	l.mu.Unlock()
+
+	if due {
+		_, _ = l.db.ExecContext(ctx, sweepQuery)
+	}
+}
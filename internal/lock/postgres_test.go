@@ -0,0 +1,221 @@
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresLocker_AcquireAndRelease(t *testing.T) {
+	db, server := openTestPostgres(t)
+	ctx := context.Background()
+
+	// Two instances, each with its own locker on the shared database
+	first := NewPostgresLocker(db, "test:")
+	second := NewPostgresLocker(db, "test:")
+
+	acquired, err := first.Acquire(ctx, "retention", "instance-1", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, "instance-1", server.owner("test:retention"))
+
+	acquired, err = second.Acquire(ctx, "retention", "instance-2", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	// The holder renews its lease, from any instance
+	acquired, err = second.Acquire(ctx, "retention", "instance-1", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	acquired, _ = first.Acquire(ctx, "retention", "instance-3", time.Minute)
+	assert.False(t, acquired)
+
+	acquired, _ = second.Acquire(ctx, "reconcile", "instance-2", time.Minute)
+	assert.True(t, acquired)
+	assert.Equal(t, 2, server.leaseCount())
+
+	// Only the holder releases a lock
+	require.NoError(t, first.Release(ctx, "retention", "instance-3"))
+	assert.Equal(t, 2, server.leaseCount())
+	require.NoError(t, first.Release(ctx, "retention", "instance-1"))
+	assert.Equal(t, 1, server.leaseCount())
+
+	acquired, err = second.Acquire(ctx, "retention", "instance-2", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	_, err = first.Acquire(ctx, "retention", "instance-1", 0)
+	assert.Error(t, err)
+}
+
+func TestPostgresLocker_LeaseExpires(t *testing.T) {
+	db, server := openTestPostgres(t)
+	ctx := context.Background()
+	first := NewPostgresLocker(db, "")
+	second := NewPostgresLocker(db, "")
+
+	acquired, err := first.Acquire(ctx, "retention@1", "instance-1", time.Minute)
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	// Unreleased, the lock is free once its lease runs out, and the first
+	// instance finds it lost the lock when renewing
+	server.advance(2 * time.Minute)
+	acquired, err = second.Acquire(ctx, "retention@1", "instance-2", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	acquired, err = first.Acquire(ctx, "retention@1", "instance-1", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+}
+
+func TestPostgresLocker_HeldLocksPinNoConnections(t *testing.T) {
+	db, server := openTestPostgres(t)
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+	locker := NewPostgresLocker(db, "")
+
+	// Claims that are never released, like a scheduler's ticks
+	for i := 0; i < 20; i++ {
+		acquired, err := locker.Acquire(ctx, "jobs:tick@"+strconv.Itoa(i), "instance-1", time.Minute)
+		require.NoError(t, err)
+		require.True(t, acquired)
+	}
+	assert.Equal(t, 0, db.Stats().InUse)
+	assert.Equal(t, 20, server.leaseCount())
+
+	// Expired leases are swept
+	server.advance(2 * time.Minute)
+	locker.lastSweep = time.Now().Add(-sweepInterval)
+	acquired, err := locker.Acquire(ctx, "retention", "instance-1", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, 1, server.leaseCount())
+}
+
+// Helper functions
+
+// testPostgres emulates the lease table of a PostgreSQL server
+type testPostgres struct {
+	mu     sync.Mutex
+	leases map[string]*testLease
+	offset time.Duration // added to the server clock
+}
+
+type testLease struct {
+	owner     string
+	expiresAt time.Time
+}
+
+var (
+	testPostgresMu      sync.Mutex
+	testPostgresServers = make(map[string]*testPostgres)
+)
+
+func init() {
+	sql.Register("testpostgres", testPostgresDriver{})
+}
+
+func openTestPostgres(t *testing.T) (*sql.DB, *testPostgres) {
+	server := &testPostgres{leases: make(map[string]*testLease)}
+	testPostgresMu.Lock()
+	testPostgresServers[t.Name()] = server
+	testPostgresMu.Unlock()
+
+	db, err := sql.Open("testpostgres", t.Name())
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db, server
+}
+
+func (s *testPostgres) leaseCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.leases)
+}
+
+func (s *testPostgres) owner(key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if lease, exists := s.leases[key]; exists {
+		return lease.owner
+	}
+	return ""
+}
+
+// advance moves the server clock forward
+func (s *testPostgres) advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.offset += d
+}
+
+type testPostgresDriver struct{}
+
+func (testPostgresDriver) Open(name string) (driver.Conn, error) {
+	testPostgresMu.Lock()
+	defer testPostgresMu.Unlock()
+
+	return &testPostgresConn{server: testPostgresServers[name]}, nil
+}
+
+type testPostgresConn struct {
+	server *testPostgres
+}
+
+func (c *testPostgresConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("prepared statements are not supported")
+}
+
+func (c *testPostgresConn) Begin() (driver.Tx, error) {
+	return nil, fmt.Errorf("transactions are not supported")
+}
+
+func (c *testPostgresConn) Close() error { return nil }
+
+func (c *testPostgresConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+
+	now := time.Now().Add(c.server.offset)
+	switch query {
+	case acquireQuery:
+		key, owner := args[0].Value.(string), args[1].Value.(string)
+		lease, exists := c.server.leases[key]
+		if exists && lease.owner != owner && lease.expiresAt.After(now) {
+			return driver.RowsAffected(0), nil
+		}
+		expiresAt := now.Add(time.Duration(args[2].Value.(int64)) * time.Millisecond)
+		c.server.leases[key] = &testLease{owner: owner, expiresAt: expiresAt}
+		return driver.RowsAffected(1), nil
+	case releaseQuery:
+		key, owner := args[0].Value.(string), args[1].Value.(string)
+		if lease, exists := c.server.leases[key]; exists && lease.owner == owner {
+			delete(c.server.leases, key)
+			return driver.RowsAffected(1), nil
+		}
+		return driver.RowsAffected(0), nil
+	case sweepQuery:
+		var swept int64
+		for key, lease := range c.server.leases {
+			if !lease.expiresAt.After(now) {
+				delete(c.server.leases, key)
+				swept++
+			}
+		}
+		return driver.RowsAffected(swept), nil
+	}
+	return nil, fmt.Errorf("unexpected query: %s", query)
+}
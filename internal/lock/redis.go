@@ -0,0 +1,58 @@
+package lock
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/redis"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// Lua scripts run atomically by Redis. Acquiring renews a lease the owner
+// holds or sets the key if it is free; releasing deletes the key only if
+// the owner holds it, so an expired lease taken over by another owner is
+// never released by the first.
+const (
+	redisAcquireScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("PEXPIRE", KEYS[1], ARGV[2]) end
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then return 1 end
+return 0`
+	redisReleaseScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) end
+return 0`
+)
+
+// RedisLocker implements the Locker interface with Redis keys that expire
+// with their lease
+type RedisLocker struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisLocker creates a Redis locker from the configuration
+func NewRedisLocker(cfg config.LockConfig) (*RedisLocker, error) {
+	client, err := redis.NewClient(cfg.RedisURL, cfg.RedisPassword, cfg.RedisDB, cfg.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisLocker{client: client, prefix: cfg.KeyPrefix}, nil
+}
+
+// Acquire implements the Locker interface
+func (l *RedisLocker) Acquire(ctx context.Context, key, owner string, lease time.Duration) (bool, error) {
+	if lease < time.Millisecond {
+		return false, errors.NewValidationError("lease", "lease must be at least a millisecond")
+	}
+
+	reply, err := l.client.Eval(ctx, redisAcquireScript, []string{l.prefix + key}, owner, strconv.FormatInt(lease.Milliseconds(), 10))
+	if err != nil {
+		return false, err
+	}
+	return reply == int64(1), nil
+}
+
+// Release implements the Locker interface
+func (l *RedisLocker) Release(ctx context.Context, key, owner string) error {
+	_, err := l.client.Eval(ctx, redisReleaseScript, []string{l.prefix + key}, owner)
+	return err
+}
@@ -0,0 +1,131 @@
+package lock
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/redis/redistest"
+)
+
+func TestRedisLocker_AcquireAndRelease(t *testing.T) {
+	server, keys := startTestRedis(t, "secret")
+	locker, err := NewRedisLocker(config.LockConfig{
+		RedisURL:      server.Addr,
+		RedisPassword: "secret",
+		RedisDB:       3,
+		KeyPrefix:     "test:",
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	acquired, err := locker.Acquire(ctx, "retention", "instance-1", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, "instance-1", keys.get("test:retention"))
+
+	acquired, err = locker.Acquire(ctx, "retention", "instance-2", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	// The holder renews its lease
+	acquired, err = locker.Acquire(ctx, "retention", "instance-1", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	// Only the holder releases a lock
+	require.NoError(t, locker.Release(ctx, "retention", "instance-2"))
+	assert.Equal(t, "instance-1", keys.get("test:retention"))
+	require.NoError(t, locker.Release(ctx, "retention", "instance-1"))
+	assert.Empty(t, keys.get("test:retention"))
+
+	acquired, err = locker.Acquire(ctx, "retention", "instance-2", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	_, err = locker.Acquire(ctx, "retention", "instance-2", time.Microsecond)
+	assert.Error(t, err)
+}
+
+func TestRedisLocker_LeaseExpires(t *testing.T) {
+	server, _ := startTestRedis(t, "")
+	locker, err := NewRedisLocker(config.LockConfig{RedisURL: server.Addr})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	acquired, err := locker.Acquire(ctx, "retention", "instance-1", 20*time.Millisecond)
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	time.Sleep(30 * time.Millisecond)
+	acquired, err = locker.Acquire(ctx, "retention", "instance-2", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+}
+
+func TestRedisLocker_Errors(t *testing.T) {
+	server, _ := startTestRedis(t, "secret")
+	ctx := context.Background()
+
+	locker, err := NewRedisLocker(config.LockConfig{RedisURL: server.Addr, RedisPassword: "wrong"})
+	require.NoError(t, err)
+	_, err = locker.Acquire(ctx, "retention", "instance-1", time.Minute)
+	assert.ErrorContains(t, err, "AUTH")
+
+	server.Close()
+	locker, err = NewRedisLocker(config.LockConfig{RedisURL: server.Addr, Timeout: 100 * time.Millisecond})
+	require.NoError(t, err)
+	_, err = locker.Acquire(ctx, "retention", "instance-1", time.Minute)
+	assert.Error(t, err)
+
+	_, err = NewRedisLocker(config.LockConfig{RedisURL: "http://cache:6379"})
+	assert.Error(t, err)
+}
+
+// Helper functions
+
+// testKeys are the keys of a test Redis server, with their expiry
+type testKeys struct {
+	values  map[string]string
+	expires map[string]time.Time
+}
+
+// get returns the unexpired value of a key, or an empty string. The server
+// calls it holding its lock; tests call it between commands.
+func (k *testKeys) get(key string) string {
+	if expires, exists := k.expires[key]; exists && !time.Now().Before(expires) {
+		delete(k.values, key)
+		delete(k.expires, key)
+	}
+	return k.values[key]
+}
+
+func startTestRedis(t *testing.T, password string) (*redistest.Server, *testKeys) {
+	server := redistest.NewServer(t, password)
+	keys := &testKeys{values: make(map[string]string), expires: make(map[string]time.Time)}
+
+	server.HandleScript(redisAcquireScript, func(k, args []string) interface{} {
+		current := keys.get(k[0])
+		if current != "" && current != args[0] {
+			return int64(0)
+		}
+		ms, _ := strconv.Atoi(args[1])
+		keys.values[k[0]] = args[0]
+		keys.expires[k[0]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		return int64(1)
+	})
+	server.HandleScript(redisReleaseScript, func(k, args []string) interface{} {
+		if keys.get(k[0]) != args[0] {
+			return int64(0)
+		}
+		delete(keys.values, k[0])
+		delete(keys.expires, k[0])
+		return int64(1)
+	})
+	return server, keys
+}
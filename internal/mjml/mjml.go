@@ -0,0 +1,394 @@
+// Package mjml compiles a subset of MJML to responsive, table-based HTML so
+// email templates can be authored without hand-writing table layouts.
+//
+// Supported elements are mjml, mj-head (mj-title, mj-preview, mj-style),
+// mj-body, mj-section, mj-column, mj-text, mj-button, mj-image, mj-divider,
+// mj-spacer and mj-raw. Columns stack on screens narrower than 480px.
+// Text is passed through unchanged, so {{variable}} placeholders and
+// {% include %} tags survive compilation.
+package mjml
+
+import (
+	"crypto/sha256"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// Default attribute values, matching MJML's where practical
+const (
+	defaultWidth      = "600px"
+	defaultFontFamily = "Arial, Helvetica, sans-serif"
+	defaultPadding    = "10px 25px"
+)
+
+// endingTags hold HTML content rather than MJML elements
+var endingTags = map[string]bool{
+	"mj-text":    true,
+	"mj-button":  true,
+	"mj-raw":     true,
+	"mj-title":   true,
+	"mj-preview": true,
+	"mj-style":   true,
+}
+
+// voidTags are HTML elements without closing tags
+var voidTags = map[string]bool{
+	"br":    true,
+	"hr":    true,
+	"img":   true,
+	"input": true,
+	"link":  true,
+	"meta":  true,
+	"wbr":   true,
+}
+
+// responsiveStyle stacks columns on small screens
+const responsiveStyle = `@media only screen and (max-width: 480px) { .mj-column { width: 100% !important; max-width: 100% !important; } }`
+
+// IsMJML reports whether source is an MJML document
+func IsMJML(source string) bool {
+	return strings.HasPrefix(strings.TrimSpace(source), "<mjml")
+}
+
+// Compile compiles an MJML document to HTML
+func Compile(source string) (string, error) {
+	root, err := parse(source)
+	if err != nil {
+		return "", err
+	}
+
+	var head headContent
+	var body *element
+	for _, child := range root.children {
+		switch child.name {
+		case "mj-head":
+			if err := head.collect(child); err != nil {
+				return "", err
+			}
+		case "mj-body":
+			body = child
+		default:
+			return "", compileError(fmt.Sprintf("mjml cannot contain %s", child.name))
+		}
+	}
+	if body == nil {
+		return "", compileError("mjml requires an mj-body")
+	}
+
+	var out strings.Builder
+	out.WriteString("<!doctype html>\n<html>\n<head>\n")
+	out.WriteString(`<meta charset="utf-8">` + "\n")
+	out.WriteString(`<meta name="viewport" content="width=device-width, initial-scale=1">` + "\n")
+	fmt.Fprintf(&out, "<title>%s</title>\n", head.title)
+	fmt.Fprintf(&out, "<style>\n%s\n%s</style>\n", responsiveStyle, head.style)
+	out.WriteString("</head>\n")
+
+	background := body.attr("background-color", "#ffffff")
+	fmt.Fprintf(&out, `<body style="margin: 0; padding: 0; background-color: %s;">`+"\n", background)
+	if head.preview != "" {
+		fmt.Fprintf(&out, `<div style="display: none; max-height: 0; overflow: hidden;">%s</div>`+"\n", head.preview)
+	}
+	fmt.Fprintf(&out, `<div style="margin: 0 auto; max-width: %s;">`+"\n", body.attr("width", defaultWidth))
+	for _, child := range body.children {
+		switch child.name {
+		case "mj-section":
+			if err := writeSection(&out, child); err != nil {
+				return "", err
+			}
+		case "mj-raw":
+			out.WriteString(child.content)
+		default:
+			return "", compileError(fmt.Sprintf("mj-body cannot contain %s", child.name))
+		}
+	}
+	out.WriteString("</div>\n</body>\n</html>\n")
+
+	return out.String(), nil
+}
+
+// Compiler compiles MJML and caches the output by source, so saving an
+// unchanged template does not compile it again
+type Compiler struct {
+	mu    sync.Mutex
+	cache map[[sha256.Size]byte]string
+}
+
+// NewCompiler creates a compiler with an empty cache
+func NewCompiler() *Compiler {
+	return &Compiler{cache: make(map[[sha256.Size]byte]string)}
+}
+
+// Compile compiles an MJML document, returning the cached HTML if the same
+// source was compiled before
+func (c *Compiler) Compile(source string) (string, error) {
+	key := sha256.Sum256([]byte(source))
+
+	c.mu.Lock()
+	compiled, exists := c.cache[key]
+	c.mu.Unlock()
+	if exists {
+		return compiled, nil
+	}
+
+	compiled, err := Compile(source)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cache[key] = compiled
+	return compiled, nil
+}
+
+// Cached returns the number of compiled documents in the cache
+func (c *Compiler) Cached() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.cache)
+}
+
+// headContent is what mj-head contributes to the document
+type headContent struct {
+	title   string
+	preview string
+	style   string
+}
+
+// collect reads the children of mj-head
+func (h *headContent) collect(head *element) error {
+	for _, child := range head.children {
+		switch child.name {
+		case "mj-title":
+			h.title = strings.TrimSpace(child.content)
+		case "mj-preview":
+			h.preview = strings.TrimSpace(child.content)
+		case "mj-style":
+			h.style += child.content + "\n"
+		default:
+			return compileError(fmt.Sprintf("mj-head cannot contain %s", child.name))
+		}
+	}
+	return nil
+}
+
+// writeSection writes a row of columns
+func writeSection(out *strings.Builder, section *element) error {
+	fmt.Fprintf(out, `<table role="presentation" width="100%%" cellpadding="0" cellspacing="0" border="0" style="background-color: %s;">`,
+		section.attr("background-color", "transparent"))
+	fmt.Fprintf(out, `<tr><td style="padding: %s; text-align: %s; font-size: 0;">`+"\n",
+		section.attr("padding", "20px 0"), section.attr("text-align", "center"))
+
+	for _, column := range section.children {
+		if column.name != "mj-column" {
+			return compileError(fmt.Sprintf("mj-section cannot contain %s", column.name))
+		}
+		width := column.attr("width", fmt.Sprintf("%.4g%%", 100/float64(len(section.children))))
+		fmt.Fprintf(out, `<div class="mj-column" style="display: inline-block; vertical-align: %s; width: 100%%; max-width: %s;">`,
+			column.attr("vertical-align", "top"), width)
+		out.WriteString(`<table role="presentation" width="100%" cellpadding="0" cellspacing="0" border="0">` + "\n")
+
+		for _, content := range column.children {
+			fmt.Fprintf(out, `<tr><td align="%s" style="padding: %s;">`,
+				content.attr("align", defaultAlign(content.name)), content.attr("padding", defaultPadding))
+			if err := writeContent(out, content); err != nil {
+				return err
+			}
+			out.WriteString("</td></tr>\n")
+		}
+		out.WriteString("</table></div>\n")
+	}
+
+	out.WriteString("</td></tr></table>\n")
+	return nil
+}
+
+// writeContent writes an element inside a column
+func writeContent(out *strings.Builder, content *element) error {
+	fontFamily := content.attr("font-family", defaultFontFamily)
+
+	switch content.name {
+	case "mj-text":
+		fmt.Fprintf(out, `<div style="font-family: %s; font-size: %s; line-height: %s; color: %s; text-align: %s;">%s</div>`,
+			fontFamily, content.attr("font-size", "13px"), content.attr("line-height", "1.5"),
+			content.attr("color", "#000000"), content.attr("align", "left"), content.content)
+	case "mj-button":
+		fmt.Fprintf(out, `<table role="presentation" cellpadding="0" cellspacing="0" border="0" align="%s"><tr>`, content.attr("align", "center"))
+		fmt.Fprintf(out, `<td style="background-color: %s; border-radius: %s; padding: %s;">`,
+			content.attr("background-color", "#414141"), content.attr("border-radius", "3px"), content.attr("inner-padding", "10px 25px"))
+		fmt.Fprintf(out, `<a href="%s" style="display: inline-block; color: %s; font-family: %s; font-size: %s; text-decoration: none;">%s</a>`,
+			content.attr("href", "#"), content.attr("color", "#ffffff"), fontFamily, content.attr("font-size", "13px"), content.content)
+		out.WriteString("</td></tr></table>")
+	case "mj-image":
+		src, exists := content.attrs["src"]
+		if !exists {
+			return compileError("mj-image requires a src")
+		}
+		image := fmt.Sprintf(`<img src="%s" alt="%s" style="display: block; width: 100%%; max-width: %s; height: auto; border: 0;">`,
+			src, content.attr("alt", ""), content.attr("width", "100%"))
+		if href, exists := content.attrs["href"]; exists {
+			image = fmt.Sprintf(`<a href="%s">%s</a>`, href, image)
+		}
+		out.WriteString(image)
+	case "mj-divider":
+		fmt.Fprintf(out, `<p style="border-top: %s %s %s; margin: 0 auto; width: 100%%; font-size: 1px;"></p>`,
+			content.attr("border-style", "solid"), content.attr("border-width", "4px"), content.attr("border-color", "#000000"))
+	case "mj-spacer":
+		height := content.attr("height", "20px")
+		fmt.Fprintf(out, `<div style="height: %s; line-height: %s;">&#8202;</div>`, height, height)
+	case "mj-raw":
+		out.WriteString(content.content)
+	default:
+		return compileError(fmt.Sprintf("mj-column cannot contain %s", content.name))
+	}
+	return nil
+}
+
+// defaultAlign returns the alignment of an element without an align attribute
+func defaultAlign(name string) string {
+	if name == "mj-button" || name == "mj-image" || name == "mj-divider" {
+		return "center"
+	}
+	return "left"
+}
+
+// element is a parsed MJML element
+type element struct {
+	name     string
+	attrs    map[string]string // HTML-escaped
+	children []*element
+	content  string // HTML inside ending tags
+}
+
+// attr returns an attribute, or fallback when it is not set
+func (e *element) attr(name, fallback string) string {
+	if value, exists := e.attrs[name]; exists {
+		return value
+	}
+	return fallback
+}
+
+// parse parses an MJML document into its root element
+func parse(source string) (*element, error) {
+	decoder := newDecoder(source)
+
+	var root *element
+	var stack []*element
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, compileError(err.Error())
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			el := &element{name: t.Name.Local, attrs: make(map[string]string, len(t.Attr))}
+			for _, attr := range t.Attr {
+				el.attrs[attr.Name.Local] = html.EscapeString(attr.Value)
+			}
+
+			if len(stack) == 0 {
+				if root != nil {
+					return nil, compileError("document has more than one root element")
+				}
+				root = el
+			} else {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, el)
+			}
+
+			if endingTags[el.name] {
+				if el.content, err = readContent(decoder); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			stack = append(stack, el)
+		case xml.EndElement:
+			if len(stack) == 0 || stack[len(stack)-1].name != t.Name.Local {
+				return nil, compileError(fmt.Sprintf("unexpected closing tag %s", t.Name.Local))
+			}
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if strings.TrimSpace(string(t)) != "" {
+				return nil, compileError(fmt.Sprintf("text must be inside mj-text or mj-raw: %q", strings.TrimSpace(string(t))))
+			}
+		}
+	}
+
+	if root == nil || root.name != "mjml" {
+		return nil, compileError("document must start with <mjml>")
+	}
+	if len(stack) > 0 {
+		return nil, compileError(fmt.Sprintf("%s is not closed", stack[len(stack)-1].name))
+	}
+	return root, nil
+}
+
+// readContent serializes the HTML up to the end of the current element
+func readContent(decoder *xml.Decoder) (string, error) {
+	var content strings.Builder
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", compileError(fmt.Sprintf("unterminated element: %v", err))
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			content.WriteString("<" + t.Name.Local)
+			for _, attr := range t.Attr {
+				fmt.Fprintf(&content, ` %s="%s"`, attr.Name.Local, html.EscapeString(attr.Value))
+			}
+			content.WriteString(">")
+			if !voidTags[t.Name.Local] {
+				depth++
+			}
+		case xml.EndElement:
+			if voidTags[t.Name.Local] {
+				continue
+			}
+			if depth == 0 {
+				return content.String(), nil
+			}
+			depth--
+			content.WriteString("</" + t.Name.Local + ">")
+		case xml.CharData:
+			content.WriteString(escapeText(string(t)))
+		case xml.Comment:
+			content.WriteString("<!--" + string(t) + "-->")
+		}
+	}
+}
+
+// newDecoder creates a decoder lenient enough for the HTML inside MJML
+func newDecoder(source string) *xml.Decoder {
+	decoder := xml.NewDecoder(strings.NewReader(source))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+	return decoder
+}
+
+// escapeText escapes text content, leaving quotes alone so template tags such
+// as {% include "footer" %} keep working
+func escapeText(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// compileError reports MJML that cannot be compiled
+func compileError(message string) error {
+	return errors.NewValidationError("mjml", message)
+}
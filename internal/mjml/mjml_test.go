@@ -0,0 +1,103 @@
+package mjml
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompile(t *testing.T) {
+	compiled, err := Compile(createTestDocument())
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(compiled, "<!doctype html>"))
+	assert.Contains(t, compiled, "<title>Welcome</title>")
+	assert.Contains(t, compiled, "Your account is ready")
+	assert.Contains(t, compiled, `max-width: 480px`)
+	assert.Contains(t, compiled, "@media only screen and (max-width: 480px)")
+	assert.Contains(t, compiled, `class="mj-column"`)
+	assert.Contains(t, compiled, "max-width: 50%;")
+	assert.Contains(t, compiled, `<a href="{{login_url}}"`)
+	assert.Contains(t, compiled, `<img src="https://example.com/logo.png" alt="Logo"`)
+	assert.Contains(t, compiled, "background-color: #f4f4f4;")
+	assert.NotContains(t, compiled, "<mj-")
+}
+
+func TestCompile_PreservesTemplateSyntax(t *testing.T) {
+	compiled, err := Compile(`<mjml><mj-body><mj-section><mj-column>
+		<mj-text>Hi {{user_name}} &amp; friends<br>{% include "signature" %}</mj-text>
+	</mj-column></mj-section></mj-body></mjml>`)
+	require.NoError(t, err)
+
+	assert.Contains(t, compiled, `Hi {{user_name}} &amp; friends<br>{% include "signature" %}`)
+	assert.NotContains(t, compiled, "</br>")
+}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"not mjml", "<html><body></body></html>"},
+		{"missing body", "<mjml><mj-head></mj-head></mjml>"},
+		{"content outside column", "<mjml><mj-body><mj-section><mj-text>Hi</mj-text></mj-section></mj-body></mjml>"},
+		{"unknown element", "<mjml><mj-body><mj-section><mj-column><mj-carousel></mj-carousel></mj-column></mj-section></mj-body></mjml>"},
+		{"image without src", "<mjml><mj-body><mj-section><mj-column><mj-image /></mj-column></mj-section></mj-body></mjml>"},
+		{"stray text", "<mjml><mj-body>Hello</mj-body></mjml>"},
+		{"unclosed", "<mjml><mj-body>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(tt.source)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCompiler_Caches(t *testing.T) {
+	compiler := NewCompiler()
+
+	first, err := compiler.Compile(createTestDocument())
+	require.NoError(t, err)
+	second, err := compiler.Compile(createTestDocument())
+	require.NoError(t, err)
+
+	assert.Equal(t, first, second)
+	assert.Equal(t, 1, compiler.Cached())
+
+	_, err = compiler.Compile("<mjml></mjml>")
+	assert.Error(t, err)
+	assert.Equal(t, 1, compiler.Cached())
+}
+
+func TestIsMJML(t *testing.T) {
+	assert.True(t, IsMJML("\n  <mjml><mj-body></mj-body></mjml>"))
+	assert.False(t, IsMJML("<html></html>"))
+}
+
+// Helper functions
+
+func createTestDocument() string {
+	return `<mjml>
+  <mj-head>
+    <mj-title>Welcome</mj-title>
+    <mj-preview>Your account is ready</mj-preview>
+  </mj-head>
+  <mj-body width="480px" background-color="#f4f4f4">
+    <mj-section>
+      <mj-column>
+        <mj-image src="https://example.com/logo.png" alt="Logo" width="120px" />
+      </mj-column>
+      <mj-column>
+        <mj-text font-size="18px">Welcome {{user_name}}!</mj-text>
+        <mj-divider border-width="1px" border-color="#dddddd" />
+        <mj-spacer height="10px" />
+        <mj-button href="{{login_url}}">Sign in</mj-button>
+      </mj-column>
+    </mj-section>
+  </mj-body>
+</mjml>`
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditOutcome represents the decision recorded by an audit entry
+type AuditOutcome string
+
+const (
+	AuditOutcomeAccepted     AuditOutcome = "accepted"
+	AuditOutcomeSent         AuditOutcome = "sent"
+	AuditOutcomeDelivered    AuditOutcome = "delivered"
+	AuditOutcomeFailed       AuditOutcome = "failed"
+	AuditOutcomeRetried      AuditOutcome = "retried"
+	AuditOutcomeSuppressed   AuditOutcome = "suppressed"
+	AuditOutcomeRejected     AuditOutcome = "rejected"
+	AuditOutcomeExpired      AuditOutcome = "expired"
+	AuditOutcomeAcknowledged AuditOutcome = "acknowledged"
+
+	// Data subject requests
+	AuditOutcomeExported AuditOutcome = "exported"
+	AuditOutcomeErased   AuditOutcome = "erased"
+
+	// Attachment virus scans
+	AuditOutcomeQuarantined AuditOutcome = "quarantined"
+)
+
+// AuditEntry represents an immutable record of a send operation. Entries hold
+// a hash of the request payload rather than its content.
+type AuditEntry struct {
+	ID               uuid.UUID         `json:"id"`
+	Sequence         int64             `json:"sequence"` // assigned on append, strictly increasing
+	Timestamp        time.Time         `json:"timestamp"`
+	Actor            string            `json:"actor,omitempty"` // API key or identity that made the request
+	Action           string            `json:"action"`
+	Outcome          AuditOutcome      `json:"outcome"`
+	NotificationID   string            `json:"notification_id,omitempty"`
+	NotificationType NotificationType  `json:"notification_type,omitempty"`
+	PayloadHash      string            `json:"payload_hash,omitempty"`
+	Reason           string            `json:"reason,omitempty"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+}
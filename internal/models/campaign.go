@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CampaignStatus represents the lifecycle state of a campaign
+type CampaignStatus string
+
+const (
+	CampaignStatusDraft     CampaignStatus = "draft"
+	CampaignStatusScheduled CampaignStatus = "scheduled"
+	CampaignStatusRunning   CampaignStatus = "running"
+	CampaignStatusCompleted CampaignStatus = "completed"
+	CampaignStatusCancelled CampaignStatus = "cancelled"
+	CampaignStatusFailed    CampaignStatus = "failed"
+)
+
+// Campaign represents a managed bulk notification send to an audience
+type Campaign struct {
+	ID           uuid.UUID         `json:"id"`
+	Name         string            `json:"name"`
+	Channel      NotificationType  `json:"channel"`
+	Priority     Priority          `json:"priority"`
+	Audience     CampaignAudience  `json:"audience"`
+	Template     CampaignTemplate  `json:"template"`
+	TemplateData map[string]string `json:"template_data,omitempty"`
+	ScheduledAt  *time.Time        `json:"scheduled_at,omitempty"`
+	RatePerMin   int               `json:"rate_per_minute"` // throttle; zero sends as fast as workers allow
+	// SendAtLocalTime, e.g. "09:00", sends to each recipient at that time in
+	// their time zone, staggering the campaign across time zones
+	SendAtLocalTime string            `json:"send_at_local_time,omitempty"`
+	Status          CampaignStatus    `json:"status"`
+	Stats           CampaignStats     `json:"stats"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+	StartedAt       *time.Time        `json:"started_at,omitempty"`
+	CompletedAt     *time.Time        `json:"completed_at,omitempty"`
+	ErrorMsg        string            `json:"error_message,omitempty"`
+}
+
+// CampaignAudience defines who a campaign is sent to: an explicit list of
+// recipients, a segment query, or both
+type CampaignAudience struct {
+	Recipients []CampaignRecipient `json:"recipients,omitempty"`
+	Segment    string              `json:"segment,omitempty"`
+}
+
+// CampaignRecipient represents a single campaign recipient with personalization data.
+// Channel details such as "platform" (push) or "country_code" (SMS, voice) are read from Data.
+type CampaignRecipient struct {
+	Recipient string            `json:"recipient"`
+	Data      map[string]string `json:"data,omitempty"`
+}
+
+// CampaignTemplate holds the message content with {{variable}} placeholders
+type CampaignTemplate struct {
+	Subject string `json:"subject,omitempty"`
+	Body    string `json:"body"`
+}
+
+// CampaignStats represents campaign-level delivery statistics
+type CampaignStats struct {
+	Total     int `json:"total"`
+	Queued    int `json:"queued"`
+	Sent      int `json:"sent"`
+	Delivered int `json:"delivered"`
+	Opened    int `json:"opened"`
+	Failed    int `json:"failed"`
+}
+
+// IsFinished reports whether a campaign can no longer send
+func (c *Campaign) IsFinished() bool {
+	switch c.Status {
+	case CampaignStatusCompleted, CampaignStatusCancelled, CampaignStatusFailed:
+		return true
+	default:
+		return false
+	}
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,6 +14,8 @@ const (
 	NotificationTypeEmail NotificationType = "email"
 	NotificationTypeSMS   NotificationType = "sms"
 	NotificationTypePush  NotificationType = "push"
+	NotificationTypeChat  NotificationType = "chat"
+	NotificationTypeVoice NotificationType = "voice"
 )
 
 // NotificationStatus represents the status of a notification
@@ -24,6 +27,7 @@ const (
 	StatusDelivered NotificationStatus = "delivered"
 	StatusFailed    NotificationStatus = "failed"
 	StatusRetrying  NotificationStatus = "retrying"
+	StatusExpired   NotificationStatus = "expired" // not sent because it expired first
 )
 
 // Priority represents the priority level of a notification
@@ -36,25 +40,79 @@ const (
 	PriorityUrgent Priority = "urgent"
 )
 
+// Category classifies a notification for policy enforcement, e.g. whether
+// it respects opt-outs and quiet hours
+type Category string
+
+const (
+	CategoryTransactional Category = "transactional"
+	CategoryMarketing     Category = "marketing"
+	CategorySecurity      Category = "security"
+)
+
+// Categories returns the notification categories
+func Categories() []Category {
+	return []Category{CategoryTransactional, CategoryMarketing, CategorySecurity}
+}
+
+// CategoryForTemplate maps a template's free-form category, such as
+// "onboarding" or "security", to a notification category. Unknown template
+// categories are transactional.
+func CategoryForTemplate(templateCategory string) Category {
+	switch strings.ToLower(strings.TrimSpace(templateCategory)) {
+	case "security":
+		return CategorySecurity
+	case "marketing", "promotional", "promotion", "newsletter":
+		return CategoryMarketing
+	default:
+		return CategoryTransactional
+	}
+}
+
 // Notification represents a generic notification
 type Notification struct {
-	ID          uuid.UUID          `json:"id"`
-	Type        NotificationType   `json:"type"`
-	Status      NotificationStatus `json:"status"`
-	Priority    Priority           `json:"priority"`
-	Recipient   string             `json:"recipient"`
-	Subject     string             `json:"subject,omitempty"`
-	Body        string             `json:"body"`
-	Metadata    map[string]string  `json:"metadata,omitempty"`
-	CreatedAt   time.Time          `json:"created_at"`
-	UpdatedAt   time.Time          `json:"updated_at"`
-	ScheduledAt *time.Time         `json:"scheduled_at,omitempty"`
-	SentAt      *time.Time         `json:"sent_at,omitempty"`
-	DeliveredAt *time.Time         `json:"delivered_at,omitempty"`
-	FailedAt    *time.Time         `json:"failed_at,omitempty"`
-	ErrorMsg    string             `json:"error_message,omitempty"`
-	RetryCount  int                `json:"retry_count"`
-	MaxRetries  int                `json:"max_retries"`
+	ID        uuid.UUID          `json:"id"`
+	Type      NotificationType   `json:"type"`
+	Status    NotificationStatus `json:"status"`
+	Priority  Priority           `json:"priority"`
+	Recipient string             `json:"recipient"`
+	Subject   string             `json:"subject,omitempty"`
+	Body      string             `json:"body"`
+	HTMLBody  string             `json:"html_body,omitempty"`
+	Category  Category           `json:"category,omitempty"`
+	// TemplateData holds the variables the body was rendered with
+	TemplateData map[string]string `json:"template_data,omitempty"`
+	// SubjectTemplate and BodyTemplate hold the unrendered subject and body, so
+	// a resend can render them with new template data
+	SubjectTemplate string            `json:"subject_template,omitempty"`
+	BodyTemplate    string            `json:"body_template,omitempty"`
+	Metadata        map[string]string `json:"metadata,omitempty"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+	ScheduledAt     *time.Time        `json:"scheduled_at,omitempty"`
+	ExpiresAt       *time.Time        `json:"expires_at,omitempty"` // not sent after this time, e.g. a one-time password's expiry
+	SentAt          *time.Time        `json:"sent_at,omitempty"`
+	// ProviderMessageID is the ID the provider assigned to the sent message
+	ProviderMessageID string     `json:"provider_message_id,omitempty"`
+	DeliveredAt       *time.Time `json:"delivered_at,omitempty"`
+	FailedAt          *time.Time `json:"failed_at,omitempty"`
+	// AcknowledgedAt is when a person saw the notification, as opposed to the
+	// provider delivering it, and AcknowledgedVia how it was reported
+	AcknowledgedAt  *time.Time        `json:"acknowledged_at,omitempty"`
+	AcknowledgedVia AcknowledgeSource `json:"acknowledged_via,omitempty"`
+	ErrorMsg        string            `json:"error_message,omitempty"`
+	RetryCount      int               `json:"retry_count"`
+	MaxRetries      int               `json:"max_retries"`
+	// CorrelationID joins the notification with the traces of the system
+	// that requested it; providers receive it where their API allows
+	CorrelationID string `json:"correlation_id,omitempty"`
+	// ProviderOptions are passed through to the provider, which validates them
+	ProviderOptions map[string]any `json:"provider_options,omitempty"`
+}
+
+// Expired reports whether the notification expired at or before now
+func (n *Notification) Expired(now time.Time) bool {
+	return n.ExpiresAt != nil && !now.Before(*n.ExpiresAt)
 }
 
 // EmailNotification represents an email notification with specific fields
@@ -69,6 +127,9 @@ type EmailNotification struct {
 	TextBody    string            `json:"text_body,omitempty"`
 	Attachments []EmailAttachment `json:"attachments,omitempty"`
 	Headers     map[string]string `json:"headers,omitempty"`
+	MessageID   string            `json:"message_id,omitempty"`  // RFC 5322 Message-ID
+	InReplyTo   string            `json:"in_reply_to,omitempty"` // Message-ID of the email this follows up
+	References  []string          `json:"references,omitempty"`  // Message-IDs of the thread, oldest first
 }
 
 // EmailAttachment represents an email attachment
@@ -86,6 +147,7 @@ type SMSNotification struct {
 	CountryCode string `json:"country_code,omitempty"`
 	Message     string `json:"message"`
 	Unicode     bool   `json:"unicode"`
+	SenderID    string `json:"sender_id,omitempty"` // alphanumeric ID or number; empty uses the provider's default
 }
 
 // PushNotification represents a push notification with specific fields
@@ -101,23 +163,107 @@ type PushNotification struct {
 	Data        map[string]string `json:"data,omitempty"`
 	ImageURL    string            `json:"image_url,omitempty"`
 	ClickAction string            `json:"click_action,omitempty"`
+
+	// Rich payload options mapped to native APNs/FCM/Web Push fields
+	CollapseKey       string `json:"collapse_key,omitempty"`
+	ThreadID          string `json:"thread_id,omitempty"`
+	Category          string `json:"category,omitempty"`
+	TTL               int    `json:"ttl,omitempty"` // seconds
+	ContentAvailable  bool   `json:"content_available,omitempty"`
+	Silent            bool   `json:"silent,omitempty"` // background push without alert, sound or badge
+	DataOnly          bool   `json:"data_only,omitempty"`
+	MutableContent    bool   `json:"mutable_content,omitempty"`
+	ChannelID         string `json:"channel_id,omitempty"`         // Android
+	InterruptionLevel string `json:"interruption_level,omitempty"` // iOS
+
+	// Web Push display options
+	Actions            []PushAction `json:"actions,omitempty"`
+	Tag                string       `json:"tag,omitempty"`
+	Renotify           bool         `json:"renotify,omitempty"`
+	RequireInteraction bool         `json:"require_interaction,omitempty"`
+	Vibrate            []int        `json:"vibrate,omitempty"` // vibration pattern in milliseconds
+
+	// Tray grouping: alerts of the same group stack under a summary or
+	// replace each other rather than each taking a slot in the tray
+	Group        string        `json:"group,omitempty"`
+	GroupMode    PushGroupMode `json:"group_mode,omitempty"`    // stack by default
+	GroupSummary string        `json:"group_summary,omitempty"` // may use {{count}}
+	GroupCount   int           `json:"group_count,omitempty"`   // alerts in the group, set by the service
+}
+
+// ChatNotification represents a chat webhook notification (Slack, Microsoft Teams)
+type ChatNotification struct {
+	Notification
+	Platform   string                   `json:"platform"` // "slack", "teams"
+	WebhookURL string                   `json:"webhook_url"`
+	Title      string                   `json:"title,omitempty"`
+	Text       string                   `json:"text"`
+	Blocks     []map[string]interface{} `json:"blocks,omitempty"` // Slack Block Kit blocks
+	Card       map[string]interface{}   `json:"card,omitempty"`   // Teams Adaptive Card
+	Channel    string                   `json:"channel,omitempty"`
+	Username   string                   `json:"username,omitempty"`
+	IconEmoji  string                   `json:"icon_emoji,omitempty"`
+}
+
+// VoiceNotification represents a text-to-speech voice call notification
+type VoiceNotification struct {
+	Notification
+	PhoneNumber string `json:"phone_number"`
+	CountryCode string `json:"country_code"`
+	Message     string `json:"message"`
+	Voice       string `json:"voice,omitempty"`    // TTS voice, e.g. "alice" or "Polly.Joanna"
+	Language    string `json:"language,omitempty"` // e.g. "en-US"
+	Loop        int    `json:"loop,omitempty"`     // times the message is repeated
+}
+
+// PushGroupMode is how alerts of the same group are shown in the tray
+type PushGroupMode string
+
+const (
+	PushGroupStack   PushGroupMode = "stack"   // alerts are kept under a group summary
+	PushGroupReplace PushGroupMode = "replace" // each alert replaces the previous one
+)
+
+// PushAction represents an interactive action button on a push notification
+type PushAction struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Icon  string `json:"icon,omitempty"`
 }
 
 // NotificationRequest represents a request to send a notification
 type NotificationRequest struct {
-	Type        NotificationType  `json:"type" validate:"required,oneof=email sms push"`
-	Priority    Priority          `json:"priority" validate:"required,oneof=low normal high urgent"`
-	Recipient   string            `json:"recipient" validate:"required"`
-	Subject     string            `json:"subject,omitempty"`
-	Body        string            `json:"body" validate:"required"`
-	Metadata    map[string]string `json:"metadata,omitempty"`
-	ScheduledAt *time.Time        `json:"scheduled_at,omitempty"`
-	MaxRetries  int               `json:"max_retries,omitempty"`
+	Type      NotificationType  `json:"type" validate:"required,oneof=email sms push chat voice"`
+	Priority  Priority          `json:"priority" validate:"required,oneof=low normal high urgent"`
+	Recipient string            `json:"recipient" validate:"required"`
+	Subject   string            `json:"subject,omitempty"`
+	Body      string            `json:"body" validate:"required"`
+	Category  Category          `json:"category,omitempty" validate:"omitempty,oneof=transactional marketing security"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	// CorrelationID is the caller's trace or request ID. API requests
+	// without one get the ID of the HTTP request.
+	CorrelationID string `json:"correlation_id,omitempty" validate:"omitempty,max=128"`
+	// TemplateData holds the variables the body was rendered with, kept with the stored notification
+	TemplateData map[string]string `json:"template_data,omitempty"`
+	ScheduledAt  *time.Time        `json:"scheduled_at,omitempty"`
+	ExpiresAt    *time.Time        `json:"expires_at,omitempty"` // not sent after this time
+	MaxRetries   int               `json:"max_retries,omitempty"`
+	// ProviderOptions carries provider-specific features without typed
+	// fields, e.g. a Twilio messaging service SID or an FCM analytics label.
+	// The provider that sends the request validates them.
+	ProviderOptions map[string]any `json:"provider_options,omitempty"`
 
 	// Type-specific fields
 	EmailData *EmailData `json:"email_data,omitempty"`
 	SMSData   *SMSData   `json:"sms_data,omitempty"`
 	PushData  *PushData  `json:"push_data,omitempty"`
+	ChatData  *ChatData  `json:"chat_data,omitempty"`
+	VoiceData *VoiceData `json:"voice_data,omitempty"`
+}
+
+// Expired reports whether the request expired at or before now
+func (r *NotificationRequest) Expired(now time.Time) bool {
+	return r.ExpiresAt != nil && !now.Before(*r.ExpiresAt)
 }
 
 // EmailData contains email-specific request data
@@ -131,6 +277,8 @@ type EmailData struct {
 	TextBody    string            `json:"text_body,omitempty"`
 	Attachments []EmailAttachment `json:"attachments,omitempty"`
 	Headers     map[string]string `json:"headers,omitempty"`
+	InReplyTo   string            `json:"in_reply_to,omitempty"`
+	References  []string          `json:"references,omitempty"`
 }
 
 // SMSData contains SMS-specific request data
@@ -138,6 +286,7 @@ type SMSData struct {
 	PhoneNumber string `json:"phone_number"`
 	CountryCode string `json:"country_code,omitempty"`
 	Unicode     bool   `json:"unicode"`
+	SenderID    string `json:"sender_id,omitempty"` // alphanumeric ID or number; empty uses the provider's default
 }
 
 // PushData contains push notification-specific request data
@@ -151,6 +300,52 @@ type PushData struct {
 	Data        map[string]string `json:"data,omitempty"`
 	ImageURL    string            `json:"image_url,omitempty"`
 	ClickAction string            `json:"click_action,omitempty"`
+
+	// Rich payload options mapped to native APNs/FCM/Web Push fields
+	CollapseKey       string `json:"collapse_key,omitempty"`
+	ThreadID          string `json:"thread_id,omitempty"`
+	Category          string `json:"category,omitempty"`
+	TTL               int    `json:"ttl,omitempty"` // seconds
+	ContentAvailable  bool   `json:"content_available,omitempty"`
+	Silent            bool   `json:"silent,omitempty"` // background push without alert, sound or badge
+	DataOnly          bool   `json:"data_only,omitempty"`
+	MutableContent    bool   `json:"mutable_content,omitempty"`
+	ChannelID         string `json:"channel_id,omitempty"`         // Android
+	InterruptionLevel string `json:"interruption_level,omitempty"` // iOS
+
+	// Web Push display options
+	Actions            []PushAction `json:"actions,omitempty"`
+	Tag                string       `json:"tag,omitempty"`
+	Renotify           bool         `json:"renotify,omitempty"`
+	RequireInteraction bool         `json:"require_interaction,omitempty"`
+	Vibrate            []int        `json:"vibrate,omitempty"` // vibration pattern in milliseconds
+
+	// Tray grouping: alerts of the same group stack under a summary or
+	// replace each other rather than each taking a slot in the tray
+	Group        string        `json:"group,omitempty"`
+	GroupMode    PushGroupMode `json:"group_mode,omitempty"`    // stack by default
+	GroupSummary string        `json:"group_summary,omitempty"` // may use {{count}}
+	GroupCount   int           `json:"group_count,omitempty"`   // alerts in the group, set by the service
+}
+
+// ChatData contains chat webhook-specific request data
+type ChatData struct {
+	WebhookURL string                   `json:"webhook_url,omitempty"`
+	Title      string                   `json:"title,omitempty"`
+	Blocks     []map[string]interface{} `json:"blocks,omitempty"`
+	Card       map[string]interface{}   `json:"card,omitempty"`
+	Channel    string                   `json:"channel,omitempty"`
+	Username   string                   `json:"username,omitempty"`
+	IconEmoji  string                   `json:"icon_emoji,omitempty"`
+}
+
+// VoiceData contains voice call-specific request data
+type VoiceData struct {
+	PhoneNumber string `json:"phone_number,omitempty"`
+	CountryCode string `json:"country_code" validate:"required"`
+	Voice       string `json:"voice,omitempty"`
+	Language    string `json:"language,omitempty"`
+	Loop        int    `json:"loop,omitempty"`
 }
 
 // NotificationResponse represents the response after sending a notification
@@ -161,6 +356,30 @@ type NotificationResponse struct {
 	ProviderID string             `json:"provider_id,omitempty"`
 	SentAt     *time.Time         `json:"sent_at,omitempty"`
 	Error      string             `json:"error,omitempty"`
+	// Segments, Cost and Currency are set by providers that bill per message
+	Segments int     `json:"segments,omitempty"`
+	Cost     float64 `json:"cost,omitempty"`
+	Currency string  `json:"currency,omitempty"`
+	// ProviderMetadata holds other details the provider reported, such as
+	// its queue time or the delivery delay
+	ProviderMetadata map[string]string `json:"provider_metadata,omitempty"`
+}
+
+// AcknowledgeSource identifies how an acknowledgement was reported
+type AcknowledgeSource string
+
+const (
+	AcknowledgeSourceAPI   AcknowledgeSource = "api"    // reported through the acknowledgement API
+	AcknowledgeSourcePush  AcknowledgeSource = "push"   // the push was opened
+	AcknowledgeSourceInApp AcknowledgeSource = "in_app" // the message was viewed in the app
+	AcknowledgeSourceReply AcknowledgeSource = "reply"  // the recipient replied
+)
+
+// Acknowledgement reports that a person saw a notification
+type Acknowledgement struct {
+	Source AcknowledgeSource `json:"source,omitempty" validate:"omitempty,oneof=api push in_app reply"` // api when empty
+	// At is when the notification was seen, e.g. by an app reporting offline; now when empty
+	At *time.Time `json:"at,omitempty"`
 }
 
 // DeliveryStatus represents the delivery status of a notification
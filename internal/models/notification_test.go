@@ -18,6 +18,8 @@ func TestNotificationTypes(t *testing.T) {
 		{"Email type", NotificationTypeEmail, "email"},
 		{"SMS type", NotificationTypeSMS, "sms"},
 		{"Push type", NotificationTypePush, "push"},
+		{"Chat type", NotificationTypeChat, "chat"},
+		{"Voice type", NotificationTypeVoice, "voice"},
 	}
 
 	for _, tt := range tests {
@@ -66,6 +68,14 @@ func TestPriority(t *testing.T) {
 	}
 }
 
+func TestCategoryForTemplate(t *testing.T) {
+	assert.Equal(t, CategorySecurity, CategoryForTemplate("security"))
+	assert.Equal(t, CategoryMarketing, CategoryForTemplate(" Newsletter "))
+	assert.Equal(t, CategoryTransactional, CategoryForTemplate("onboarding"))
+	assert.Equal(t, CategoryTransactional, CategoryForTemplate(""))
+	assert.Len(t, Categories(), 3)
+}
+
 func TestNotificationCreation(t *testing.T) {
 	id := uuid.New()
 	now := time.Now()
@@ -186,6 +196,30 @@ func TestPushNotification(t *testing.T) {
 	assert.Equal(t, "default", pushNotification.Sound)
 }
 
+func TestChatNotification(t *testing.T) {
+	chatNotification := &ChatNotification{
+		Notification: Notification{
+			ID:        uuid.New(),
+			Type:      NotificationTypeChat,
+			Status:    StatusPending,
+			Priority:  PriorityHigh,
+			Recipient: "https://hooks.slack.com/services/T000/B000/XXXX",
+			Body:      "Deploy finished",
+		},
+		Platform:   "slack",
+		WebhookURL: "https://hooks.slack.com/services/T000/B000/XXXX",
+		Text:       "Deploy finished",
+		Blocks: []map[string]interface{}{
+			{"type": "section", "text": map[string]interface{}{"type": "mrkdwn", "text": "*Deploy finished*"}},
+		},
+	}
+
+	assert.Equal(t, NotificationTypeChat, chatNotification.Type)
+	assert.Equal(t, "slack", chatNotification.Platform)
+	assert.Equal(t, "Deploy finished", chatNotification.Text)
+	assert.Len(t, chatNotification.Blocks, 1)
+}
+
 func TestNotificationRequest(t *testing.T) {
 	request := &NotificationRequest{
 		Type:      NotificationTypeEmail,
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OutboxEntry is a notification intent written by an application in the same
+// transaction as its business data. The outbox poller moves it to the queue.
+type OutboxEntry struct {
+	ID           uuid.UUID           `json:"id"`
+	Request      NotificationRequest `json:"request"`
+	Metadata     map[string]string   `json:"metadata,omitempty"`
+	CreatedAt    time.Time           `json:"created_at"`
+	ClaimedUntil *time.Time          `json:"claimed_until,omitempty"` // lease held by a poller
+	ProcessedAt  *time.Time          `json:"processed_at,omitempty"`
+	Attempts     int                 `json:"attempts"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RecipientList represents a named list of recipients that campaigns and
+// bulk sends can target
+type RecipientList struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	MemberCount int       `json:"member_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ListMember represents a recipient in a list. Attributes are matched by
+// segment expressions and used as template data.
+type ListMember struct {
+	Recipient  string            `json:"recipient"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	AddedAt    time.Time         `json:"added_at"`
+}
@@ -0,0 +1,262 @@
+// Package numberlookup looks up the carrier and line type of phone numbers,
+// and whether they were ported, with a pluggable lookup provider such as
+// Twilio Lookup. SMS to landlines never arrives but is still billed, so the
+// send middleware can reject it.
+package numberlookup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pipeline"
+	"github.com/nareshkumar-microsoft/notificationService/internal/privacy"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// MiddlewareName is the name the service's middleware is registered under
+const MiddlewareName = "number-lookup"
+
+// Metadata keys of the lookup attached to checked notifications
+const (
+	MetadataLineType = "line_type"
+	MetadataCarrier  = "carrier"
+)
+
+// Defaults applied to zero configuration fields
+const (
+	defaultCacheTTL = 7 * 24 * time.Hour
+	defaultTimeout  = 5 * time.Second
+)
+
+// LineType is the kind of line a number belongs to
+type LineType string
+
+// Line types
+const (
+	LineTypeMobile   LineType = "mobile"
+	LineTypeLandline LineType = "landline"
+	LineTypeVoIP     LineType = "voip"
+	LineTypeTollFree LineType = "toll_free"
+	LineTypeUnknown  LineType = "unknown"
+)
+
+// Provider looks up phone numbers in E.164 format
+type Provider interface {
+	LookupPhoneNumber(ctx context.Context, number string) (*Result, error)
+}
+
+// Result describes a phone number
+type Result struct {
+	PhoneNumber       string       `json:"phone_number"` // E.164
+	CountryCode       string       `json:"country_code,omitempty"`
+	Carrier           string       `json:"carrier,omitempty"`
+	LineType          LineType     `json:"line_type"`
+	MobileCountryCode string       `json:"mobile_country_code,omitempty"`
+	MobileNetworkCode string       `json:"mobile_network_code,omitempty"`
+	Portability       *Portability `json:"portability,omitempty"` // nil when the provider does not report it
+	LookedUpAt        time.Time    `json:"looked_up_at"`
+}
+
+// Portability describes whether a number moved to another carrier
+type Portability struct {
+	Ported          bool       `json:"ported"`
+	OriginalCarrier string     `json:"original_carrier,omitempty"`
+	PortedAt        *time.Time `json:"ported_at,omitempty"`
+}
+
+// CanReceiveSMS reports whether the line takes SMS. Numbers of unknown type
+// are given the benefit of the doubt.
+func (r *Result) CanReceiveSMS() bool {
+	return r.LineType != LineTypeLandline
+}
+
+// cacheEntry is a cached lookup
+type cacheEntry struct {
+	result  *Result
+	expires time.Time
+}
+
+// Service looks up phone numbers, caching the results. It is safe for
+// concurrent use.
+type Service struct {
+	provider Provider
+	config   config.NumberLookupConfig
+	logger   interfaces.Logger
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+	now   func() time.Time
+}
+
+// NewService creates a lookup service
+func NewService(provider Provider, cfg config.NumberLookupConfig, logger interfaces.Logger) *Service {
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = defaultCacheTTL
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+
+	return &Service{
+		provider: provider,
+		config:   cfg,
+		logger:   logger,
+		cache:    make(map[string]cacheEntry),
+		now:      time.Now,
+	}
+}
+
+// NewProvider creates the lookup provider named by the configuration
+func NewProvider(cfg config.NumberLookupConfig) (Provider, error) {
+	switch strings.ToLower(cfg.Provider) {
+	case "", "mock":
+		return NewMockProvider(), nil
+	case "twilio":
+		return NewTwilioProvider(cfg)
+	default:
+		return nil, errors.NewNotificationError(errors.ErrorCodeProviderConfiguration,
+			fmt.Sprintf("unknown number lookup provider: %s", cfg.Provider))
+	}
+}
+
+// LookupPhoneNumber returns the carrier, line type and portability of a
+// number in international format, from the cache when possible
+func (s *Service) LookupPhoneNumber(ctx context.Context, number string) (*Result, error) {
+	return s.lookup(ctx, number, "")
+}
+
+// CheckSMS looks up an SMS recipient and, when landlines are blocked,
+// rejects landline numbers with INVALID_RECIPIENT. Numbers that cannot be
+// looked up are allowed. The result is nil when there was no lookup.
+func (s *Service) CheckSMS(ctx context.Context, phoneNumber, countryCode string) (*Result, error) {
+	result, err := s.lookup(ctx, phoneNumber, countryCode)
+	if err != nil {
+		s.logger.Warnf("Number lookup of %s failed, sending unchecked: %v", privacy.MaskPhone(phoneNumber), err)
+		return nil, nil
+	}
+
+	if s.config.BlockLandlines && !result.CanReceiveSMS() {
+		s.logger.Warnf("Blocked SMS to landline %s", privacy.MaskPhone(phoneNumber))
+		return result, errors.NewNotificationError(errors.ErrorCodeInvalidRecipient,
+			fmt.Sprintf("%s is a %s number and cannot receive SMS", privacy.MaskPhone(phoneNumber), result.LineType)).
+			WithMetadata(MetadataLineType, string(result.LineType)).
+			WithMetadata(MetadataCarrier, result.Carrier)
+	}
+	return result, nil
+}
+
+// Middleware returns the send middleware that looks up SMS recipients,
+// keeping their line type and carrier in the notification's metadata, and
+// rejects landlines when they are blocked. Register it at
+// pipeline.StagePreferences under MiddlewareName.
+func (s *Service) Middleware() pipeline.Middleware {
+	return func(next pipeline.Handler) pipeline.Handler {
+		return func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+			if request.Type != models.NotificationTypeSMS {
+				return next(ctx, request)
+			}
+
+			phoneNumber, countryCode := request.Recipient, ""
+			if request.SMSData != nil {
+				if request.SMSData.PhoneNumber != "" {
+					phoneNumber = request.SMSData.PhoneNumber
+				}
+				countryCode = request.SMSData.CountryCode
+			}
+
+			result, err := s.CheckSMS(ctx, phoneNumber, countryCode)
+			if err != nil {
+				return nil, err
+			}
+			if result == nil {
+				return next(ctx, request)
+			}
+
+			checked := *request
+			checked.Metadata = make(map[string]string, len(request.Metadata)+2)
+			for key, value := range request.Metadata {
+				checked.Metadata[key] = value
+			}
+			checked.Metadata[MetadataLineType] = string(result.LineType)
+			if result.Carrier != "" {
+				checked.Metadata[MetadataCarrier] = result.Carrier
+			}
+			return next(ctx, &checked)
+		}
+	}
+}
+
+// lookup looks up a number, from the cache when possible
+func (s *Service) lookup(ctx context.Context, phoneNumber, countryCode string) (*Result, error) {
+	number := utils.E164(phoneNumber, countryCode)
+	if len(number) < 8 || len(number) > 16 {
+		return nil, errors.NewValidationError("phone_number", "phone number must be in international format, e.g. +14155550123")
+	}
+
+	s.mu.Lock()
+	entry, cached := s.cache[number]
+	s.mu.Unlock()
+	if cached && s.now().Before(entry.expires) {
+		return entry.result, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+
+	result, err := s.provider.LookupPhoneNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	if result.LineType == "" {
+		result.LineType = LineTypeUnknown
+	}
+	if result.LookedUpAt.IsZero() {
+		result.LookedUpAt = s.now()
+	}
+
+	s.mu.Lock()
+	s.cache[number] = cacheEntry{result: result, expires: s.now().Add(s.config.CacheTTL)}
+	s.mu.Unlock()
+	return result, nil
+}
+
+// MockProvider answers lookups from numbers set on it, and reports any
+// other number as a mobile, for development and tests
+type MockProvider struct {
+	mu      sync.RWMutex
+	numbers map[string]Result
+}
+
+// NewMockProvider creates a mock lookup provider
+func NewMockProvider() *MockProvider {
+	return &MockProvider{numbers: make(map[string]Result)}
+}
+
+// Set sets the result of a number in E.164 format
+func (p *MockProvider) Set(number string, result Result) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.numbers[number] = result
+}
+
+// LookupPhoneNumber implements the Provider interface
+func (p *MockProvider) LookupPhoneNumber(ctx context.Context, number string) (*Result, error) {
+	p.mu.RLock()
+	result, exists := p.numbers[number]
+	p.mu.RUnlock()
+	if !exists {
+		result = Result{Carrier: "Mock Mobile", LineType: LineTypeMobile, Portability: &Portability{}}
+	}
+	result.PhoneNumber = number
+	if result.CountryCode == "" {
+		result.CountryCode = utils.CountryFromPhoneNumber(number)
+	}
+	return &result, nil
+}
@@ -0,0 +1,173 @@
+package numberlookup
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestService_LookupPhoneNumber(t *testing.T) {
+	provider := &countingProvider{MockProvider: NewMockProvider()}
+	provider.Set("+14155550123", Result{Carrier: "AT&T", LineType: LineTypeLandline})
+	service := createTestService(provider, config.NumberLookupConfig{})
+	ctx := context.Background()
+
+	result, err := service.LookupPhoneNumber(ctx, "+1 (415) 555-0123")
+	require.NoError(t, err)
+	assert.Equal(t, "+14155550123", result.PhoneNumber)
+	assert.Equal(t, "US", result.CountryCode)
+	assert.Equal(t, LineTypeLandline, result.LineType)
+	assert.False(t, result.CanReceiveSMS())
+
+	// Lookups are cached
+	_, err = service.LookupPhoneNumber(ctx, "+14155550123")
+	require.NoError(t, err)
+	assert.Equal(t, 1, provider.lookups)
+
+	_, err = service.LookupPhoneNumber(ctx, "4155550123")
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeValidationFailed, notifErr.Code)
+}
+
+func TestService_CheckSMS(t *testing.T) {
+	provider := NewMockProvider()
+	provider.Set("+447911123456", Result{Carrier: "BT", LineType: LineTypeLandline})
+	ctx := context.Background()
+
+	// National numbers are looked up with the country's calling code
+	blocking := createTestService(provider, config.NumberLookupConfig{BlockLandlines: true})
+	_, err := blocking.CheckSMS(ctx, "07911 123456", "UK")
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeInvalidRecipient, notifErr.Code)
+	assert.Equal(t, "landline", notifErr.Metadata[MetadataLineType])
+
+	result, err := blocking.CheckSMS(ctx, "+447700900123", "")
+	require.NoError(t, err)
+	assert.Equal(t, LineTypeMobile, result.LineType)
+
+	// Landlines are only reported when they are not blocked
+	result, err = createTestService(provider, config.NumberLookupConfig{}).CheckSMS(ctx, "+447911123456", "")
+	require.NoError(t, err)
+	assert.Equal(t, LineTypeLandline, result.LineType)
+
+	// Numbers that cannot be looked up are allowed
+	failing := createTestService(failingProvider{}, config.NumberLookupConfig{BlockLandlines: true})
+	result, err = failing.CheckSMS(ctx, "+447911123456", "")
+	require.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestService_Middleware(t *testing.T) {
+	provider := NewMockProvider()
+	provider.Set("+14155550123", Result{Carrier: "AT&T", LineType: LineTypeLandline})
+	service := createTestService(provider, config.NumberLookupConfig{BlockLandlines: true})
+
+	var sent *models.NotificationRequest
+	handler := service.Middleware()(func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+		sent = request
+		return &models.NotificationResponse{Status: models.StatusSent}, nil
+	})
+	ctx := context.Background()
+
+	_, err := handler(ctx, &models.NotificationRequest{Type: models.NotificationTypeSMS, Recipient: "+14155550123", Body: "Hi"})
+	assert.Error(t, err)
+	assert.Nil(t, sent)
+
+	request := &models.NotificationRequest{
+		Type:      models.NotificationTypeSMS,
+		Recipient: "4155550199",
+		Body:      "Hi",
+		SMSData:   &models.SMSData{PhoneNumber: "4155550199", CountryCode: "US"},
+	}
+	_, err = handler(ctx, request)
+	require.NoError(t, err)
+	assert.Equal(t, "mobile", sent.Metadata[MetadataLineType])
+	assert.Equal(t, "Mock Mobile", sent.Metadata[MetadataCarrier])
+	assert.Nil(t, request.Metadata)
+
+	// Other channels are not looked up
+	_, err = handler(ctx, &models.NotificationRequest{Type: models.NotificationTypeEmail, Recipient: "a@example.com"})
+	require.NoError(t, err)
+	assert.NotContains(t, sent.Metadata, MetadataLineType)
+}
+
+func TestTwilioProvider_LookupPhoneNumber(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		if user != "AC123" || password != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "line_type_intelligence", r.URL.Query().Get("Fields"))
+		switch r.URL.Path {
+		case "/v2/PhoneNumbers/+14155550123":
+			fmt.Fprint(w, `{"phone_number": "+14155550123", "country_code": "US", "valid": true,
+				"line_type_intelligence": {"carrier_name": "Bandwidth/13 - Bandwidth.com - SVR", "type": "nonFixedVoip", "mobile_country_code": "313", "mobile_network_code": "981"}}`)
+		default:
+			fmt.Fprint(w, `{"phone_number": "+1415", "valid": false, "line_type_intelligence": null}`)
+		}
+	}))
+	defer server.Close()
+
+	provider, err := NewTwilioProvider(config.NumberLookupConfig{TwilioAccountSID: "AC123", TwilioAuthToken: "token", TwilioBaseURL: server.URL})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	result, err := provider.LookupPhoneNumber(ctx, "+14155550123")
+	require.NoError(t, err)
+	assert.Equal(t, LineTypeVoIP, result.LineType)
+	assert.Equal(t, "Bandwidth/13 - Bandwidth.com - SVR", result.Carrier)
+	assert.Equal(t, "313", result.MobileCountryCode)
+	assert.Nil(t, result.Portability)
+
+	_, err = provider.LookupPhoneNumber(ctx, "+1415")
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeInvalidPhone, notifErr.Code)
+
+	unauthorized, err := NewTwilioProvider(config.NumberLookupConfig{TwilioAccountSID: "AC123", TwilioAuthToken: "wrong", TwilioBaseURL: server.URL})
+	require.NoError(t, err)
+	_, err = unauthorized.LookupPhoneNumber(ctx, "+14155550123")
+	notifErr, ok = errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeProviderAuthentication, notifErr.Code)
+
+	_, err = NewProvider(config.NumberLookupConfig{Provider: "twilio"})
+	assert.Error(t, err)
+}
+
+// Helper functions
+
+func createTestService(provider Provider, cfg config.NumberLookupConfig) *Service {
+	return NewService(provider, cfg, utils.NewSimpleLogger("error"))
+}
+
+// countingProvider counts the lookups reaching it
+type countingProvider struct {
+	*MockProvider
+	lookups int
+}
+
+func (p *countingProvider) LookupPhoneNumber(ctx context.Context, number string) (*Result, error) {
+	p.lookups++
+	return p.MockProvider.LookupPhoneNumber(ctx, number)
+}
+
+// failingProvider cannot be reached
+type failingProvider struct{}
+
+func (failingProvider) LookupPhoneNumber(ctx context.Context, number string) (*Result, error) {
+	return nil, errors.NewNotificationError(errors.ErrorCodeProviderUnavailable, "lookup service is down")
+}
@@ -0,0 +1,111 @@
+package numberlookup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/httpclient"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// defaultTwilioBaseURL is the Twilio Lookup API
+const defaultTwilioBaseURL = "https://lookups.twilio.com"
+
+// twilioLineTypes maps Twilio's line types to ours
+var twilioLineTypes = map[string]LineType{
+	"mobile":       LineTypeMobile,
+	"landline":     LineTypeLandline,
+	"fixedVoip":    LineTypeVoIP,
+	"nonFixedVoip": LineTypeVoIP,
+	"tollFree":     LineTypeTollFree,
+}
+
+// TwilioProvider looks up numbers with the line type intelligence of the
+// Twilio Lookup v2 API. Twilio does not report portability.
+type TwilioProvider struct {
+	accountSID string
+	authToken  string
+	baseURL    string
+	client     *http.Client
+}
+
+// twilioLookupResponse is the part of a Lookup response the provider reads
+type twilioLookupResponse struct {
+	PhoneNumber          string `json:"phone_number"`
+	CountryCode          string `json:"country_code"`
+	Valid                bool   `json:"valid"`
+	LineTypeIntelligence *struct {
+		CarrierName       string `json:"carrier_name"`
+		Type              string `json:"type"`
+		MobileCountryCode string `json:"mobile_country_code"`
+		MobileNetworkCode string `json:"mobile_network_code"`
+	} `json:"line_type_intelligence"`
+	Message string `json:"message"` // set on errors
+}
+
+// NewTwilioProvider creates a Twilio Lookup provider
+func NewTwilioProvider(cfg config.NumberLookupConfig) (*TwilioProvider, error) {
+	if cfg.TwilioAccountSID == "" || cfg.TwilioAuthToken == "" {
+		return nil, errors.NewNotificationError(errors.ErrorCodeProviderConfiguration, "Twilio number lookup requires an account SID and auth token")
+	}
+
+	baseURL := cfg.TwilioBaseURL
+	if baseURL == "" {
+		baseURL = defaultTwilioBaseURL
+	}
+	return &TwilioProvider{
+		accountSID: cfg.TwilioAccountSID,
+		authToken:  cfg.TwilioAuthToken,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		client:     httpclient.Shared.Client(config.HTTPClientConfig{}, defaultTimeout),
+	}, nil
+}
+
+// LookupPhoneNumber implements the Provider interface
+func (p *TwilioProvider) LookupPhoneNumber(ctx context.Context, number string) (*Result, error) {
+	endpoint := fmt.Sprintf("%s/v2/PhoneNumbers/%s?Fields=line_type_intelligence", p.baseURL, url.PathEscape(number))
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to create lookup request", err)
+	}
+	request.SetBasicAuth(p.accountSID, p.authToken)
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return nil, errors.NewProviderError("twilio-lookup", errors.ErrorCodeProviderUnavailable, "lookup request failed").WithCause(err)
+	}
+	defer response.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 64*1024))
+	var lookup twilioLookupResponse
+	_ = json.Unmarshal(body, &lookup)
+
+	switch {
+	case response.StatusCode == http.StatusUnauthorized || response.StatusCode == http.StatusForbidden:
+		return nil, errors.NewProviderError("twilio-lookup", errors.ErrorCodeProviderAuthentication, "Twilio rejected the lookup credentials")
+	case response.StatusCode == http.StatusTooManyRequests:
+		return nil, errors.NewProviderError("twilio-lookup", errors.ErrorCodeRateLimited, "Twilio lookup rate limit exceeded")
+	case response.StatusCode < 200 || response.StatusCode >= 300:
+		return nil, errors.NewProviderError("twilio-lookup", errors.ErrorCodeProviderUnavailable,
+			fmt.Sprintf("lookup failed with status %d: %s", response.StatusCode, lookup.Message))
+	case !lookup.Valid:
+		return nil, errors.NewNotificationError(errors.ErrorCodeInvalidPhone, fmt.Sprintf("%s is not a valid phone number", number))
+	}
+
+	result := &Result{PhoneNumber: lookup.PhoneNumber, CountryCode: lookup.CountryCode, LineType: LineTypeUnknown}
+	if intelligence := lookup.LineTypeIntelligence; intelligence != nil {
+		result.Carrier = intelligence.CarrierName
+		result.MobileCountryCode = intelligence.MobileCountryCode
+		result.MobileNetworkCode = intelligence.MobileNetworkCode
+		if lineType, known := twilioLineTypes[intelligence.Type]; known {
+			result.LineType = lineType
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,347 @@
+// Package outbox moves notification intents from a transactional outbox to
+// the send queue. Applications insert entries in the same database
+// transaction as their business data, so a notification is recorded if and
+// only if the business change commits. The poller claims entries and
+// enqueues them, and the worker marks each entry processed once its send
+// has been handed to the dispatcher, so an entry queued in a process that
+// dies is claimed and sent again. Sends are deduplicated on the entry ID.
+package outbox
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/queue"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// MetadataEntryID is the job metadata key holding the outbox entry ID
+const MetadataEntryID = "outbox_id"
+
+// Schema is the PostgreSQL definition of the outbox table PostgresOutbox
+// uses, for applications that share the service's database. Claims use
+// SELECT ... FOR UPDATE SKIP LOCKED so concurrent pollers never claim the
+// same entry.
+const Schema = `CREATE TABLE IF NOT EXISTS notification_outbox (
+    id            UUID PRIMARY KEY,
+    request       JSONB NOT NULL,
+    metadata      JSONB,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    claimed_until TIMESTAMPTZ,
+    processed_at  TIMESTAMPTZ,
+    attempts      INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS notification_outbox_pending
+    ON notification_outbox (created_at) WHERE processed_at IS NULL;`
+
+// LockKeyPrefix prefixes the locker key that claims an entry's send
+const LockKeyPrefix = "outbox:"
+
+// Stats holds cumulative poller metrics
+type Stats struct {
+	Polls      int64      `json:"polls"`
+	Enqueued   int64      `json:"enqueued"`
+	Processed  int64      `json:"processed"`
+	Duplicates int64      `json:"duplicates"` // sends skipped because the entry was sent already
+	Failures   int64      `json:"failures"`
+	LastPollAt *time.Time `json:"last_poll_at,omitempty"`
+	LastError  string     `json:"last_error,omitempty"`
+}
+
+// entryState is where an entry claimed by this process is
+type entryState int
+
+const (
+	entryQueued entryState = iota // enqueued, not yet sent
+	entrySent                     // sent, but marking it processed failed
+)
+
+// Poller claims outbox entries and enqueues them; its Handler sends them
+// and marks them processed. An entry is claimed again once its lease
+// expires while it is unprocessed. Entries still queued in this process are
+// not enqueued again, and entries sent but not yet marked are only marked.
+// With a locker, an entry's send is also claimed across instances, so an
+// entry one instance is sending or has sent is skipped by the others.
+type Poller struct {
+	outbox interfaces.Outbox
+	queue  *queue.MemoryQueue
+	config config.OutboxConfig
+	logger interfaces.Logger
+
+	mu      sync.Mutex
+	stats   Stats
+	entries map[uuid.UUID]entryState
+	locker  interfaces.Locker
+	owner   string
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	running bool
+}
+
+// NewPoller creates a poller moving entries from an outbox to a queue. The
+// queue's workers must send jobs through the poller's Handler.
+func NewPoller(outbox interfaces.Outbox, q *queue.MemoryQueue, cfg config.OutboxConfig, logger interfaces.Logger) *Poller {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = 30 * time.Second
+	}
+
+	return &Poller{
+		outbox:  outbox,
+		queue:   q,
+		config:  cfg,
+		logger:  logger,
+		entries: make(map[uuid.UUID]entryState),
+	}
+}
+
+// SetLocker makes the Handler take a lock on each entry, as owner, before
+// sending it, and skip the entry when another instance holds it. The lock
+// is kept for the lease, so pollers sharing the locker send an entry once
+// even when its claim expires while it is being sent.
+func (p *Poller) SetLocker(locker interfaces.Locker, owner string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.locker = locker
+	p.owner = owner
+}
+
+// Start polls immediately and then every poll interval until Stop is
+// called. Calling Start on a running poller has no effect.
+func (p *Poller) Start(ctx context.Context) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.running {
+		return
+	}
+
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.running = true
+	p.wg.Add(1)
+	go p.run(ctx)
+
+	p.logger.Infof("Started outbox poller (every %s, %d entries per poll)", p.config.PollInterval, p.config.BatchSize)
+}
+
+// Stop stops the poller and waits for a poll in progress to finish
+func (p *Poller) Stop() {
+	p.mu.Lock()
+	if !p.running {
+		p.mu.Unlock()
+		return
+	}
+	p.cancel()
+	p.running = false
+	p.mu.Unlock()
+
+	p.wg.Wait()
+	p.logger.Infof("Stopped outbox poller")
+}
+
+// PollOnce claims a batch of entries and enqueues them, returning how many
+// were enqueued. When the queue is full, the rest of the batch is left
+// claimed and retried after the lease expires.
+func (p *Poller) PollOnce(ctx context.Context) (int, error) {
+	now := time.Now()
+	entries, err := p.outbox.Claim(ctx, p.config.BatchSize, p.config.Lease)
+	if err != nil {
+		p.record(now, 0, err)
+		p.logger.Errorf("Outbox claim failed: %v", err)
+		return 0, err
+	}
+
+	enqueued := 0
+	var firstErr error
+	for _, entry := range entries {
+		state, known := p.state(entry.ID)
+		switch {
+		case known && state == entryQueued:
+			// Still waiting for a worker; its claim lapsed in the queue
+			continue
+		case known && state == entrySent:
+			if err := p.markProcessed(ctx, entry.ID); err != nil && firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		request := entry.Request
+		metadata := make(map[string]string, len(entry.Metadata)+1)
+		for key, value := range entry.Metadata {
+			metadata[key] = value
+		}
+		metadata[MetadataEntryID] = entry.ID.String()
+
+		p.setState(entry.ID, entryQueued)
+		job := &queue.Job{ID: entry.ID.String(), Request: &request, Metadata: metadata}
+		if err := p.queue.Enqueue(job); err != nil {
+			p.forget(entry.ID)
+			firstErr = err
+			p.logger.Warnf("Outbox entry %s not enqueued: %v", entry.ID, err)
+			break
+		}
+		enqueued++
+	}
+
+	p.record(now, enqueued, firstErr)
+	return enqueued, firstErr
+}
+
+// Handler wraps the send of a queue's jobs. Jobs of outbox entries are
+// skipped when the entry was sent already, and marked processed once send
+// returns, whatever its outcome: a failed send is the dispatcher's to
+// retry. Only a send cut short by shutdown leaves its entry to be claimed
+// again. Other jobs are sent unchanged.
+func (p *Poller) Handler(send queue.Handler) queue.Handler {
+	return func(ctx context.Context, job *queue.Job) error {
+		id, err := uuid.Parse(job.Metadata[MetadataEntryID])
+		if err != nil {
+			return send(ctx, job)
+		}
+
+		claimed, err := p.claimSend(ctx, id)
+		if err != nil {
+			p.forget(id)
+			return err
+		}
+		if !claimed {
+			p.forget(id)
+			p.mu.Lock()
+			p.stats.Duplicates++
+			p.mu.Unlock()
+			p.logger.Infof("Outbox entry %s was sent by another instance, skipped", id)
+			return nil
+		}
+
+		sendErr := send(ctx, job)
+		if ctx.Err() != nil {
+			p.forget(id)
+			return sendErr
+		}
+
+		p.setState(id, entrySent)
+		if err := p.markProcessed(ctx, id); err != nil && sendErr == nil {
+			return err
+		}
+		return sendErr
+	}
+}
+
+// Stats returns the cumulative poller metrics
+func (p *Poller) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := p.stats
+	if p.stats.LastPollAt != nil {
+		lastPollAt := *p.stats.LastPollAt
+		stats.LastPollAt = &lastPollAt
+	}
+	return stats
+}
+
+// run polls on every tick until the context is cancelled
+func (p *Poller) run(ctx context.Context) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Errors are logged and counted by PollOnce; the next tick retries
+		_, _ = p.PollOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// claimSend takes the lock on an entry's send, when there is a locker
+func (p *Poller) claimSend(ctx context.Context, id uuid.UUID) (bool, error) {
+	p.mu.Lock()
+	locker, owner := p.locker, p.owner
+	p.mu.Unlock()
+
+	if locker == nil {
+		return true, nil
+	}
+	acquired, err := locker.Acquire(ctx, LockKeyPrefix+id.String(), owner, p.config.Lease)
+	if err != nil {
+		p.logger.Errorf("Outbox entry %s not sent, failed to take its lock: %v", id, err)
+		return false, err
+	}
+	return acquired, nil
+}
+
+// markProcessed marks a sent entry processed, keeping it as sent when that
+// fails so the next claim only marks it
+func (p *Poller) markProcessed(ctx context.Context, id uuid.UUID) error {
+	if err := p.outbox.MarkProcessed(ctx, id); err != nil {
+		p.mu.Lock()
+		p.stats.Failures++
+		p.stats.LastError = err.Error()
+		p.mu.Unlock()
+		p.logger.Errorf("Outbox entry %s sent but not marked processed: %v", id, err)
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.entries, id)
+	p.stats.Processed++
+	return nil
+}
+
+// state returns where an entry claimed by this process is
+func (p *Poller) state(id uuid.UUID) (entryState, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state, known := p.entries[id]
+	return state, known
+}
+
+// setState records where an entry claimed by this process is
+func (p *Poller) setState(id uuid.UUID, state entryState) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.entries[id] = state
+}
+
+// forget drops an entry, so its next claim enqueues it again
+func (p *Poller) forget(id uuid.UUID) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.entries, id)
+}
+
+// record updates the stats after a poll
+func (p *Poller) record(at time.Time, enqueued int, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stats.Polls++
+	p.stats.Enqueued += int64(enqueued)
+	p.stats.LastPollAt = &at
+	p.stats.LastError = ""
+	if err != nil {
+		p.stats.Failures++
+		p.stats.LastError = err.Error()
+	}
+}
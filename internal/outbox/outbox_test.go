@@ -0,0 +1,255 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/lock"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/queue"
+	"github.com/nareshkumar-microsoft/notificationService/internal/repository"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestPoller_PollOnce(t *testing.T) {
+	outbox := repository.NewMemoryOutbox()
+	q := queue.NewMemoryQueue(0)
+	poller := NewPoller(outbox, q, config.OutboxConfig{BatchSize: 10, Lease: time.Minute}, utils.NewSimpleLogger("info"))
+	sender := &testSender{}
+	handle := poller.Handler(sender.send)
+	ctx := context.Background()
+
+	entry := createTestEntry()
+	require.NoError(t, outbox.Add(ctx, entry))
+	require.NoError(t, outbox.Add(ctx, createTestEntry()))
+
+	enqueued, err := poller.PollOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, enqueued)
+	// Entries stay pending until they are sent
+	assert.Equal(t, 2, outbox.Pending())
+
+	job, err := q.TryDequeue()
+	require.NoError(t, err)
+	assert.Equal(t, entry.ID.String(), job.ID)
+	assert.Equal(t, entry.ID.String(), job.Metadata[MetadataEntryID])
+	assert.Equal(t, "42", job.Metadata["order_id"])
+	assert.Equal(t, "user@example.com", job.Request.Recipient)
+	require.NoError(t, handle(ctx, job))
+	assert.Equal(t, 1, outbox.Pending())
+
+	job, err = q.TryDequeue()
+	require.NoError(t, err)
+	require.NoError(t, handle(ctx, job))
+	assert.Equal(t, 0, outbox.Pending())
+	assert.Equal(t, 2, sender.count())
+
+	// Processed entries are not enqueued again
+	enqueued, err = poller.PollOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, enqueued)
+	assert.Equal(t, 0, q.Len())
+
+	stats := poller.Stats()
+	assert.Equal(t, int64(2), stats.Polls)
+	assert.Equal(t, int64(2), stats.Enqueued)
+	assert.Equal(t, int64(2), stats.Processed)
+
+	// Jobs that are not outbox entries are sent unchanged
+	require.NoError(t, handle(ctx, &queue.Job{ID: "other"}))
+	assert.Equal(t, 3, sender.count())
+}
+
+func TestPoller_UnsentEntriesAreNotLost(t *testing.T) {
+	outbox := repository.NewMemoryOutbox()
+	cfg := config.OutboxConfig{BatchSize: 10, Lease: 10 * time.Millisecond}
+	q := queue.NewMemoryQueue(0)
+	poller := NewPoller(outbox, q, cfg, utils.NewSimpleLogger("info"))
+	require.NoError(t, outbox.Add(context.Background(), createTestEntry()))
+
+	enqueued, err := poller.PollOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, enqueued)
+
+	// Still queued when its claim lapses, the entry is not enqueued again
+	time.Sleep(20 * time.Millisecond)
+	enqueued, err = poller.PollOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, enqueued)
+	assert.Equal(t, 1, q.Len())
+
+	// The process dies with the job queued; after a restart it is sent
+	restarted := NewPoller(outbox, queue.NewMemoryQueue(0), cfg, utils.NewSimpleLogger("info"))
+	time.Sleep(20 * time.Millisecond)
+	enqueued, err = restarted.PollOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, enqueued)
+	assert.Equal(t, 1, outbox.Pending())
+}
+
+func TestPoller_MarkFailureDoesNotSendTwice(t *testing.T) {
+	outbox := &flakyOutbox{MemoryOutbox: repository.NewMemoryOutbox(), failMarks: 1}
+	q := queue.NewMemoryQueue(0)
+	poller := NewPoller(outbox, q, config.OutboxConfig{BatchSize: 10, Lease: 10 * time.Millisecond}, utils.NewSimpleLogger("info"))
+	sender := &testSender{}
+	require.NoError(t, outbox.Add(context.Background(), createTestEntry()))
+
+	enqueued, err := poller.PollOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, enqueued)
+	job, err := q.TryDequeue()
+	require.NoError(t, err)
+	assert.Error(t, poller.Handler(sender.send)(context.Background(), job))
+	assert.Equal(t, 1, outbox.Pending())
+	assert.Equal(t, int64(1), poller.Stats().Failures)
+
+	// Once the lease expires the entry is claimed again and only marked
+	time.Sleep(20 * time.Millisecond)
+	enqueued, err = poller.PollOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, enqueued)
+	assert.Equal(t, 0, outbox.Pending())
+	assert.Equal(t, 0, q.Len())
+	assert.Equal(t, 1, sender.count())
+}
+
+func TestPoller_SharedLocker(t *testing.T) {
+	outbox := repository.NewMemoryOutbox()
+	locker := lock.NewMemoryLocker()
+	cfg := config.OutboxConfig{BatchSize: 10, Lease: 10 * time.Millisecond}
+	first := NewPoller(outbox, queue.NewMemoryQueue(0), cfg, utils.NewSimpleLogger("info"))
+	second := NewPoller(outbox, queue.NewMemoryQueue(0), cfg, utils.NewSimpleLogger("info"))
+	first.SetLocker(locker, "instance-1")
+	second.SetLocker(locker, "instance-2")
+	sender := &testSender{}
+	require.NoError(t, outbox.Add(context.Background(), createTestEntry()))
+
+	// The first instance's claim lapses before it sends, so the second
+	// claims the entry too
+	_, err := first.PollOnce(context.Background())
+	require.NoError(t, err)
+	time.Sleep(20 * time.Millisecond)
+	enqueued, err := second.PollOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, enqueued)
+
+	job, err := first.queue.TryDequeue()
+	require.NoError(t, err)
+	require.NoError(t, first.Handler(sender.send)(context.Background(), job))
+	job, err = second.queue.TryDequeue()
+	require.NoError(t, err)
+	require.NoError(t, second.Handler(sender.send)(context.Background(), job))
+
+	assert.Equal(t, 1, sender.count())
+	assert.Equal(t, int64(1), second.Stats().Duplicates)
+	assert.Equal(t, 0, outbox.Pending())
+}
+
+func TestPoller_QueueFull(t *testing.T) {
+	outbox := repository.NewMemoryOutbox()
+	q := queue.NewMemoryQueue(1)
+	poller := NewPoller(outbox, q, config.OutboxConfig{BatchSize: 10, Lease: 10 * time.Millisecond}, utils.NewSimpleLogger("info"))
+	handle := poller.Handler((&testSender{}).send)
+	for i := 0; i < 2; i++ {
+		require.NoError(t, outbox.Add(context.Background(), createTestEntry()))
+	}
+
+	enqueued, err := poller.PollOnce(context.Background())
+	require.Error(t, err)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeQueueFull, notifErr.Code)
+	assert.Equal(t, 1, enqueued)
+
+	job, err := q.TryDequeue()
+	require.NoError(t, err)
+	require.NoError(t, handle(context.Background(), job))
+	assert.Equal(t, 1, outbox.Pending())
+	time.Sleep(20 * time.Millisecond)
+
+	enqueued, err = poller.PollOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, enqueued)
+	job, err = q.TryDequeue()
+	require.NoError(t, err)
+	require.NoError(t, handle(context.Background(), job))
+	assert.Equal(t, 0, outbox.Pending())
+}
+
+func TestPoller_StartStop(t *testing.T) {
+	outbox := repository.NewMemoryOutbox()
+	q := queue.NewMemoryQueue(0)
+	poller := NewPoller(outbox, q, config.OutboxConfig{PollInterval: 10 * time.Millisecond}, utils.NewSimpleLogger("info"))
+
+	poller.Start(context.Background())
+	poller.Start(context.Background()) // no effect
+	defer poller.Stop()
+
+	require.NoError(t, outbox.Add(context.Background(), createTestEntry()))
+	require.Eventually(t, func() bool { return q.Len() == 1 }, time.Second, 10*time.Millisecond)
+
+	poller.Stop()
+	poller.Stop() // no effect
+}
+
+// Helper functions
+
+// testSender counts the jobs sent
+type testSender struct {
+	mu   sync.Mutex
+	sent int
+}
+
+func (s *testSender) send(ctx context.Context, job *queue.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sent++
+	return nil
+}
+
+func (s *testSender) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sent
+}
+
+// flakyOutbox fails the first MarkProcessed calls
+type flakyOutbox struct {
+	*repository.MemoryOutbox
+	mu        sync.Mutex
+	failMarks int
+}
+
+func (o *flakyOutbox) MarkProcessed(ctx context.Context, id uuid.UUID) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.failMarks > 0 {
+		o.failMarks--
+		return errors.NewNotificationError(errors.ErrorCodeInternal, "database unavailable")
+	}
+	return o.MemoryOutbox.MarkProcessed(ctx, id)
+}
+
+func createTestEntry() *models.OutboxEntry {
+	return &models.OutboxEntry{
+		Request: models.NotificationRequest{
+			Type:      models.NotificationTypeEmail,
+			Priority:  models.PriorityNormal,
+			Recipient: "user@example.com",
+			Subject:   "Order shipped",
+			Body:      "Your order has shipped",
+		},
+		Metadata: map[string]string{"order_id": "42"},
+	}
+}
@@ -0,0 +1,150 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+const (
+	insertQuery = `INSERT INTO notification_outbox (id, request, metadata, created_at) VALUES ($1, $2, $3, $4)`
+
+	// claimQuery leases the oldest pending entries. Rows another poller is
+	// claiming are skipped rather than waited for, and leases use the
+	// database clock so pollers on different hosts agree on expiry.
+	claimQuery = `UPDATE notification_outbox
+SET claimed_until = now() + $2 * interval '1 millisecond', attempts = attempts + 1
+WHERE id IN (
+    SELECT id FROM notification_outbox
+    WHERE processed_at IS NULL AND (claimed_until IS NULL OR claimed_until <= now())
+    ORDER BY created_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, request, metadata, created_at, claimed_until, attempts`
+
+	markProcessedQuery = `UPDATE notification_outbox
+SET processed_at = COALESCE(processed_at, now()), claimed_until = NULL
+WHERE id = $1`
+)
+
+// txKey is the context key of the transaction Add joins
+type txKey struct{}
+
+// WithTx returns a context whose outbox entries are added in a transaction,
+// so they commit or roll back with the caller's business data
+func WithTx(ctx context.Context, tx *sql.Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// PostgresOutbox implements the Outbox interface on the PostgreSQL table
+// defined by Schema, shared by every poller
+type PostgresOutbox struct {
+	db *sql.DB
+}
+
+// NewPostgresOutbox creates an outbox on a database holding the Schema table
+func NewPostgresOutbox(db *sql.DB) *PostgresOutbox {
+	return &PostgresOutbox{db: db}
+}
+
+// Add implements the Outbox interface. The entry is inserted in the
+// transaction of a context made with WithTx, or on its own otherwise.
+func (o *PostgresOutbox) Add(ctx context.Context, entry *models.OutboxEntry) error {
+	if entry == nil {
+		return errors.NewValidationError("entry", "outbox entry is required")
+	}
+	if entry.ID == uuid.Nil {
+		entry.ID = uuid.New()
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+
+	request, err := json.Marshal(entry.Request)
+	if err != nil {
+		return errors.NewInternalError("failed to encode outbox request", err)
+	}
+	var metadata []byte
+	if entry.Metadata != nil {
+		if metadata, err = json.Marshal(entry.Metadata); err != nil {
+			return errors.NewInternalError("failed to encode outbox metadata", err)
+		}
+	}
+
+	args := []interface{}{entry.ID, request, metadata, entry.CreatedAt}
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok && tx != nil {
+		_, err = tx.ExecContext(ctx, insertQuery, args...)
+	} else {
+		_, err = o.db.ExecContext(ctx, insertQuery, args...)
+	}
+	if err != nil {
+		return errors.NewInternalError(fmt.Sprintf("failed to add outbox entry %s", entry.ID), err)
+	}
+	return nil
+}
+
+// Claim implements the Outbox interface
+func (o *PostgresOutbox) Claim(ctx context.Context, limit int, lease time.Duration) ([]*models.OutboxEntry, error) {
+	// A NULL limit claims every pending entry
+	var rowLimit sql.NullInt64
+	if limit > 0 {
+		rowLimit = sql.NullInt64{Int64: int64(limit), Valid: true}
+	}
+
+	rows, err := o.db.QueryContext(ctx, claimQuery, rowLimit, lease.Milliseconds())
+	if err != nil {
+		return nil, errors.NewInternalError("failed to claim outbox entries", err)
+	}
+	defer rows.Close()
+
+	claimed := make([]*models.OutboxEntry, 0)
+	for rows.Next() {
+		var (
+			entry             models.OutboxEntry
+			request, metadata []byte
+			claimedUntil      time.Time
+		)
+		if err := rows.Scan(&entry.ID, &request, &metadata, &entry.CreatedAt, &claimedUntil, &entry.Attempts); err != nil {
+			return nil, errors.NewInternalError("failed to read outbox entry", err)
+		}
+		if err := json.Unmarshal(request, &entry.Request); err != nil {
+			return nil, errors.NewInternalError(fmt.Sprintf("failed to decode outbox entry %s", entry.ID), err)
+		}
+		if metadata != nil {
+			if err := json.Unmarshal(metadata, &entry.Metadata); err != nil {
+				return nil, errors.NewInternalError(fmt.Sprintf("failed to decode outbox entry %s", entry.ID), err)
+			}
+		}
+		entry.ClaimedUntil = &claimedUntil
+		claimed = append(claimed, &entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.NewInternalError("failed to claim outbox entries", err)
+	}
+
+	// RETURNING does not keep the subquery's order
+	sort.SliceStable(claimed, func(i, j int) bool {
+		return claimed[i].CreatedAt.Before(claimed[j].CreatedAt)
+	})
+	return claimed, nil
+}
+
+// MarkProcessed implements the Outbox interface
+func (o *PostgresOutbox) MarkProcessed(ctx context.Context, id uuid.UUID) error {
+	result, err := o.db.ExecContext(ctx, markProcessedQuery, id)
+	if err != nil {
+		return errors.NewInternalError(fmt.Sprintf("failed to mark outbox entry %s processed", id), err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return errors.NewNotificationError(errors.ErrorCodeNotFound, fmt.Sprintf("outbox entry %s not found", id))
+	}
+	return nil
+}
@@ -0,0 +1,295 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/queue"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+)
+
+func TestPostgresOutbox_AddInTransaction(t *testing.T) {
+	db, server := openTestPostgres(t)
+	outbox := NewPostgresOutbox(db)
+	ctx := context.Background()
+
+	// An entry added in a rolled back transaction is never recorded
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, outbox.Add(WithTx(ctx, tx), createTestEntry()))
+	assert.Equal(t, 0, server.rowCount())
+	require.NoError(t, tx.Rollback())
+	assert.Equal(t, 0, server.rowCount())
+
+	tx, err = db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	entry := createTestEntry()
+	require.NoError(t, outbox.Add(WithTx(ctx, tx), entry))
+	require.NoError(t, tx.Commit())
+	assert.Equal(t, 1, server.rowCount())
+
+	require.NoError(t, outbox.Add(ctx, createTestEntry()))
+	assert.Equal(t, 2, server.rowCount())
+	assert.Error(t, outbox.Add(ctx, entry))
+
+	claimed, err := outbox.Claim(ctx, 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+	assert.Equal(t, entry.ID, claimed[0].ID)
+	assert.Equal(t, "user@example.com", claimed[0].Request.Recipient)
+	assert.Equal(t, "42", claimed[0].Metadata["order_id"])
+	assert.Equal(t, 1, claimed[0].Attempts)
+}
+
+func TestPostgresOutbox_ClaimAndMarkProcessed(t *testing.T) {
+	db, server := openTestPostgres(t)
+	ctx := context.Background()
+
+	// Two pollers, each with its own outbox on the shared table
+	first := NewPostgresOutbox(db)
+	second := NewPostgresOutbox(db)
+	start := time.Now()
+	var added []uuid.UUID
+	for i := 0; i < 3; i++ {
+		entry := createTestEntry()
+		entry.CreatedAt = start.Add(time.Duration(i) * time.Second)
+		require.NoError(t, first.Add(ctx, entry))
+		added = append(added, entry.ID)
+	}
+
+	claimed, err := first.Claim(ctx, 2, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+	assert.Equal(t, added[0], claimed[0].ID)
+	assert.Equal(t, added[1], claimed[1].ID)
+
+	rest, err := second.Claim(ctx, 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, rest, 1)
+	assert.Equal(t, added[2], rest[0].ID)
+
+	require.NoError(t, first.MarkProcessed(ctx, claimed[0].ID))
+	require.NoError(t, first.MarkProcessed(ctx, claimed[0].ID)) // idempotent
+	assert.Error(t, first.MarkProcessed(ctx, uuid.New()))
+
+	// Unprocessed entries are claimed again once their lease expires
+	server.advance(2 * time.Minute)
+	again, err := second.Claim(ctx, 0, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, again, 2)
+	assert.Equal(t, added[1], again[0].ID)
+	assert.Equal(t, 2, again[0].Attempts)
+}
+
+func TestPoller_PostgresOutbox(t *testing.T) {
+	db, _ := openTestPostgres(t)
+	outbox := NewPostgresOutbox(db)
+	q := queue.NewMemoryQueue(0)
+	poller := NewPoller(outbox, q, config.OutboxConfig{BatchSize: 10, Lease: time.Minute}, utils.NewSimpleLogger("info"))
+	entry := createTestEntry()
+	require.NoError(t, outbox.Add(context.Background(), entry))
+
+	enqueued, err := poller.PollOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, enqueued)
+	job, err := q.TryDequeue()
+	require.NoError(t, err)
+	assert.Equal(t, entry.ID.String(), job.Metadata[MetadataEntryID])
+	require.NoError(t, poller.Handler(func(context.Context, *queue.Job) error { return nil })(context.Background(), job))
+	assert.Equal(t, int64(1), poller.Stats().Processed)
+
+	enqueued, err = poller.PollOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, enqueued)
+}
+
+// Helper functions
+
+// testPostgres emulates the outbox queries on a PostgreSQL server
+type testPostgres struct {
+	mu     sync.Mutex
+	rows   []*testOutboxRow
+	offset time.Duration // added to the server clock
+}
+
+type testOutboxRow struct {
+	id           string
+	request      []byte
+	metadata     []byte
+	createdAt    time.Time
+	claimedUntil *time.Time
+	processedAt  *time.Time
+	attempts     int64
+}
+
+var (
+	testPostgresMu      sync.Mutex
+	testPostgresServers = make(map[string]*testPostgres)
+)
+
+func init() {
+	sql.Register("testoutbox", testPostgresDriver{})
+}
+
+func openTestPostgres(t *testing.T) (*sql.DB, *testPostgres) {
+	server := &testPostgres{}
+	testPostgresMu.Lock()
+	testPostgresServers[t.Name()] = server
+	testPostgresMu.Unlock()
+
+	db, err := sql.Open("testoutbox", t.Name())
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db, server
+}
+
+func (s *testPostgres) rowCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.rows)
+}
+
+// advance moves the server clock forward
+func (s *testPostgres) advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.offset += d
+}
+
+type testPostgresDriver struct{}
+
+func (testPostgresDriver) Open(name string) (driver.Conn, error) {
+	testPostgresMu.Lock()
+	defer testPostgresMu.Unlock()
+
+	return &testPostgresConn{server: testPostgresServers[name]}, nil
+}
+
+// testPostgresConn is a session. Rows inserted in a transaction are only
+// visible once it commits.
+type testPostgresConn struct {
+	server  *testPostgres
+	inTx    bool
+	pending []*testOutboxRow
+}
+
+func (c *testPostgresConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("prepared statements are not supported")
+}
+
+func (c *testPostgresConn) Begin() (driver.Tx, error) {
+	c.inTx = true
+	return c, nil
+}
+
+func (c *testPostgresConn) Commit() error {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+
+	c.server.rows = append(c.server.rows, c.pending...)
+	c.inTx, c.pending = false, nil
+	return nil
+}
+
+func (c *testPostgresConn) Rollback() error {
+	c.inTx, c.pending = false, nil
+	return nil
+}
+
+func (c *testPostgresConn) Close() error { return nil }
+
+func (c *testPostgresConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if query != claimQuery {
+		return nil, fmt.Errorf("unexpected query: %s", query)
+	}
+
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+
+	now := time.Now().Add(c.server.offset)
+	until := now.Add(time.Duration(args[1].Value.(int64)) * time.Millisecond)
+	limit, limited := args[0].Value.(int64)
+
+	rows := &testPostgresRows{columns: []string{"id", "request", "metadata", "created_at", "claimed_until", "attempts"}}
+	for _, row := range c.server.rows {
+		if limited && int64(len(rows.values)) >= limit {
+			break
+		}
+		if row.processedAt != nil || (row.claimedUntil != nil && row.claimedUntil.After(now)) {
+			continue
+		}
+		claimedUntil := until
+		row.claimedUntil = &claimedUntil
+		row.attempts++
+		// RETURNING has no order, so rows come back newest first
+		rows.values = append([][]driver.Value{{row.id, row.request, row.metadata, row.createdAt, until, row.attempts}}, rows.values...)
+	}
+	return rows, nil
+}
+
+func (c *testPostgresConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+
+	switch query {
+	case insertQuery:
+		id := args[0].Value.(string)
+		for _, row := range append(c.server.rows, c.pending...) {
+			if row.id == id {
+				return nil, fmt.Errorf("duplicate key value violates unique constraint")
+			}
+		}
+		metadata, _ := args[2].Value.([]byte)
+		row := &testOutboxRow{id: id, request: args[1].Value.([]byte), metadata: metadata, createdAt: args[3].Value.(time.Time)}
+		if c.inTx {
+			c.pending = append(c.pending, row)
+		} else {
+			c.server.rows = append(c.server.rows, row)
+		}
+		return driver.RowsAffected(1), nil
+	case markProcessedQuery:
+		for _, row := range c.server.rows {
+			if row.id == args[0].Value.(string) {
+				if row.processedAt == nil {
+					now := time.Now().Add(c.server.offset)
+					row.processedAt = &now
+				}
+				row.claimedUntil = nil
+				return driver.RowsAffected(1), nil
+			}
+		}
+		return driver.RowsAffected(0), nil
+	}
+	return nil, fmt.Errorf("unexpected query: %s", query)
+}
+
+type testPostgresRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *testPostgresRows) Columns() []string { return r.columns }
+
+func (r *testPostgresRows) Close() error { return nil }
+
+func (r *testPostgresRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
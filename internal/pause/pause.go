@@ -0,0 +1,320 @@
+// Package pause provides runtime switches that stop sending on a channel or
+// provider, for example all SMS during a provider incident. Queued jobs for a
+// paused channel or provider are held rather than failed, and released at a
+// controlled rate once sending resumes.
+package pause
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/queue"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// Scope identifies what a pause applies to
+type Scope string
+
+const (
+	ScopeChannel  Scope = "channel"
+	ScopeProvider Scope = "provider"
+)
+
+// metadataPaused marks errors returned for paused sends
+const metadataPaused = "paused"
+
+// Pause describes an active pause
+type Pause struct {
+	Scope    Scope     `json:"scope"`
+	Target   string    `json:"target"`
+	Reason   string    `json:"reason,omitempty"`
+	PausedAt time.Time `json:"paused_at"`
+}
+
+// ProviderResolver returns the name of the provider sending a channel, or ""
+type ProviderResolver func(channel models.NotificationType) string
+
+// drain releases a resumed backlog at a fixed rate
+type drain struct {
+	remaining int
+	interval  time.Duration
+	next      time.Time
+}
+
+// Controller holds the pause switches. Attached queues consult it before
+// handing out a job; direct sends are checked with Check.
+type Controller struct {
+	mu        sync.Mutex
+	pauses    map[string]*Pause
+	drains    map[string]*drain
+	queues    []*queue.MemoryQueue
+	resolver  ProviderResolver
+	drainRate int
+	logger    interfaces.Logger
+}
+
+// NewController creates a controller, pausing the channels and providers
+// listed in the configuration
+func NewController(cfg config.PauseConfig, logger interfaces.Logger) (*Controller, error) {
+	c := &Controller{
+		pauses:    make(map[string]*Pause),
+		drains:    make(map[string]*drain),
+		drainRate: cfg.DrainRate,
+		logger:    logger,
+	}
+
+	for _, channel := range cfg.Channels {
+		if _, err := c.PauseChannel(models.NotificationType(channel), "paused by configuration"); err != nil {
+			return nil, err
+		}
+	}
+	for _, provider := range cfg.Providers {
+		if _, err := c.PauseProvider(provider, "paused by configuration"); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// SetProviderResolver sets how channels map to provider names, so provider
+// pauses apply to the channels the provider sends
+func (c *Controller) SetProviderResolver(resolver ProviderResolver) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.resolver = resolver
+}
+
+// Attach makes a queue hold the jobs of paused channels and providers
+func (c *Controller) Attach(q *queue.MemoryQueue) {
+	c.mu.Lock()
+	c.queues = append(c.queues, q)
+	c.mu.Unlock()
+
+	q.SetHold(c.Hold)
+}
+
+// PauseChannel pauses sending on a channel
+func (c *Controller) PauseChannel(channel models.NotificationType, reason string) (Pause, error) {
+	if !utils.IsValidNotificationType(channel) {
+		return Pause{}, errors.NewValidationError("channel", fmt.Sprintf("unknown channel: %s", channel))
+	}
+	return c.pause(ScopeChannel, string(channel), reason), nil
+}
+
+// ResumeChannel resumes sending on a channel, reporting whether it was paused
+func (c *Controller) ResumeChannel(channel models.NotificationType) bool {
+	return c.resume(ScopeChannel, string(channel))
+}
+
+// PauseProvider pauses sending through a provider, on every channel it serves
+func (c *Controller) PauseProvider(name, reason string) (Pause, error) {
+	if name == "" {
+		return Pause{}, errors.NewValidationError("provider", "provider name is required")
+	}
+	return c.pause(ScopeProvider, name, reason), nil
+}
+
+// ResumeProvider resumes sending through a provider, reporting whether it was paused
+func (c *Controller) ResumeProvider(name string) bool {
+	return c.resume(ScopeProvider, name)
+}
+
+// Paused returns the active pauses, channels first
+func (c *Controller) Paused() []Pause {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pauses := make([]Pause, 0, len(c.pauses))
+	for _, p := range c.pauses {
+		pauses = append(pauses, *p)
+	}
+	sort.Slice(pauses, func(i, j int) bool {
+		if pauses[i].Scope != pauses[j].Scope {
+			return pauses[i].Scope == ScopeChannel
+		}
+		return pauses[i].Target < pauses[j].Target
+	})
+	return pauses
+}
+
+// Check returns an error when sending on a channel is paused, for callers
+// that send directly rather than through a queue
+func (c *Controller) Check(channel models.NotificationType) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p := c.activePause(channel)
+	if p == nil {
+		return nil
+	}
+
+	err := errors.NewNotificationErrorWithDetails(errors.ErrorCodeProviderUnavailable,
+		fmt.Sprintf("sending is paused for %s %s", p.Scope, p.Target), p.Reason)
+	return err.WithMetadata(metadataPaused, string(p.Scope))
+}
+
+// Hold implements queue.HoldFunc. Jobs are held while their channel or
+// provider is paused, and released one per drain interval after a resume.
+func (c *Controller) Hold(job *queue.Job) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	channel := job.Request.Type
+	if c.activePause(channel) != nil {
+		return true
+	}
+
+	now := time.Now()
+	active := make([]string, 0, 2)
+	for _, key := range c.keysFor(channel) {
+		d, exists := c.drains[key]
+		if !exists {
+			continue
+		}
+		if now.Before(d.next) {
+			return true
+		}
+		active = append(active, key)
+	}
+
+	for _, key := range active {
+		d := c.drains[key]
+		d.next = now.Add(d.interval)
+		d.remaining--
+		if d.remaining <= 0 {
+			delete(c.drains, key)
+		}
+	}
+	return false
+}
+
+// IsPausedError reports whether an error was returned because sending is paused
+func IsPausedError(err error) bool {
+	notifErr, ok := errors.AsNotificationError(err)
+	return ok && notifErr.Metadata[metadataPaused] != ""
+}
+
+// pause records a pause, replacing the reason of an existing one
+func (c *Controller) pause(scope Scope, target, reason string) Pause {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := pauseKey(scope, target)
+	if existing, exists := c.pauses[key]; exists {
+		existing.Reason = reason
+		return *existing
+	}
+
+	p := &Pause{Scope: scope, Target: target, Reason: reason, PausedAt: time.Now()}
+	c.pauses[key] = p
+	delete(c.drains, key)
+	c.logger.Warnf("Paused sending for %s %s: %s", scope, target, reason)
+	return *p
+}
+
+// resume lifts a pause and starts draining the jobs it held
+func (c *Controller) resume(scope Scope, target string) bool {
+	key := pauseKey(scope, target)
+
+	c.mu.Lock()
+	if _, exists := c.pauses[key]; !exists {
+		c.mu.Unlock()
+		return false
+	}
+	delete(c.pauses, key)
+	queues := append([]*queue.MemoryQueue(nil), c.queues...)
+	c.mu.Unlock()
+
+	c.logger.Infof("Resumed sending for %s %s", scope, target)
+
+	if c.drainRate > 0 {
+		// Queues call Hold under their own lock, so count without holding ours
+		backlog := 0
+		for _, q := range queues {
+			backlog += q.Count(func(job *queue.Job) bool {
+				return c.matches(scope, target, job)
+			})
+		}
+
+		if backlog > 0 {
+			d := &drain{remaining: backlog, interval: time.Second / time.Duration(c.drainRate)}
+			c.mu.Lock()
+			c.drains[key] = d
+			c.mu.Unlock()
+
+			go c.wakeWhileDraining(key, d, queues)
+			c.logger.Infof("Draining %d held jobs for %s %s at %d per second", backlog, scope, target, c.drainRate)
+		}
+	}
+
+	for _, q := range queues {
+		q.Wake()
+	}
+	return true
+}
+
+// wakeWhileDraining wakes the queues once per drain interval until the drain
+// finishes or is replaced by a new pause
+func (c *Controller) wakeWhileDraining(key string, d *drain, queues []*queue.MemoryQueue) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		c.mu.Lock()
+		current := c.drains[key]
+		c.mu.Unlock()
+
+		if current != d {
+			return
+		}
+		for _, q := range queues {
+			q.Wake()
+		}
+	}
+}
+
+// matches reports whether a job is sent on a channel or through a provider
+func (c *Controller) matches(scope Scope, target string, job *queue.Job) bool {
+	if scope == ScopeChannel {
+		return string(job.Request.Type) == target
+	}
+
+	c.mu.Lock()
+	resolver := c.resolver
+	c.mu.Unlock()
+	return resolver != nil && resolver(job.Request.Type) == target
+}
+
+// activePause returns the pause stopping a channel, if any. Callers must hold the lock.
+func (c *Controller) activePause(channel models.NotificationType) *Pause {
+	for _, key := range c.keysFor(channel) {
+		if p, exists := c.pauses[key]; exists {
+			return p
+		}
+	}
+	return nil
+}
+
+// keysFor returns the pause keys that apply to a channel. Callers must hold the lock.
+func (c *Controller) keysFor(channel models.NotificationType) []string {
+	keys := []string{pauseKey(ScopeChannel, string(channel))}
+	if c.resolver != nil {
+		if provider := c.resolver(channel); provider != "" {
+			keys = append(keys, pauseKey(ScopeProvider, provider))
+		}
+	}
+	return keys
+}
+
+// pauseKey returns the map key of a pause
+func pauseKey(scope Scope, target string) string {
+	return string(scope) + ":" + target
+}
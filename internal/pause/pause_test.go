@@ -0,0 +1,152 @@
+package pause
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/queue"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestNewController_FromConfig(t *testing.T) {
+	controller, err := NewController(config.PauseConfig{Channels: []string{"sms"}, Providers: []string{"twilio"}}, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+
+	paused := controller.Paused()
+	require.Len(t, paused, 2)
+	assert.Equal(t, ScopeChannel, paused[0].Scope)
+	assert.Equal(t, "sms", paused[0].Target)
+	assert.Equal(t, ScopeProvider, paused[1].Scope)
+	assert.Equal(t, "twilio", paused[1].Target)
+	assert.Equal(t, "paused by configuration", paused[1].Reason)
+
+	_, err = NewController(config.PauseConfig{Channels: []string{"fax"}}, utils.NewSimpleLogger("info"))
+	assert.Error(t, err)
+}
+
+func TestController_Check(t *testing.T) {
+	controller := createTestController(t, 0)
+
+	assert.NoError(t, controller.Check(models.NotificationTypeSMS))
+
+	paused, err := controller.PauseChannel(models.NotificationTypeSMS, "provider incident")
+	require.NoError(t, err)
+	assert.Equal(t, "provider incident", paused.Reason)
+
+	err = controller.Check(models.NotificationTypeSMS)
+	require.Error(t, err)
+	assert.True(t, IsPausedError(err))
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeProviderUnavailable, notifErr.Code)
+	assert.NoError(t, controller.Check(models.NotificationTypeEmail))
+
+	assert.True(t, controller.ResumeChannel(models.NotificationTypeSMS))
+	assert.False(t, controller.ResumeChannel(models.NotificationTypeSMS))
+	assert.NoError(t, controller.Check(models.NotificationTypeSMS))
+	assert.False(t, IsPausedError(errors.ErrProviderNotFound))
+}
+
+func TestController_PauseProvider(t *testing.T) {
+	controller := createTestController(t, 0)
+	controller.SetProviderResolver(func(channel models.NotificationType) string {
+		if channel == models.NotificationTypeSMS || channel == models.NotificationTypeVoice {
+			return "twilio"
+		}
+		return "mock"
+	})
+
+	_, err := controller.PauseProvider("twilio", "")
+	require.NoError(t, err)
+
+	assert.Error(t, controller.Check(models.NotificationTypeSMS))
+	assert.Error(t, controller.Check(models.NotificationTypeVoice))
+	assert.NoError(t, controller.Check(models.NotificationTypeEmail))
+
+	_, err = controller.PauseProvider("", "")
+	assert.Error(t, err)
+}
+
+func TestController_HoldsQueuedJobs(t *testing.T) {
+	controller := createTestController(t, 0)
+	q := queue.NewMemoryQueue(0)
+	controller.Attach(q)
+
+	_, err := controller.PauseChannel(models.NotificationTypeSMS, "")
+	require.NoError(t, err)
+
+	require.NoError(t, q.Enqueue(createTestJob("sms-1", models.NotificationTypeSMS)))
+	require.NoError(t, q.Enqueue(createTestJob("email-1", models.NotificationTypeEmail)))
+
+	// The SMS job is held, not failed or dropped
+	job, err := q.TryDequeue()
+	require.NoError(t, err)
+	assert.Equal(t, "email-1", job.ID)
+
+	_, err = q.TryDequeue()
+	assert.Error(t, err)
+	assert.Equal(t, 1, q.Len())
+
+	controller.ResumeChannel(models.NotificationTypeSMS)
+	job, err = q.TryDequeue()
+	require.NoError(t, err)
+	assert.Equal(t, "sms-1", job.ID)
+}
+
+func TestController_DrainsBacklogAtRate(t *testing.T) {
+	controller := createTestController(t, 20) // one job per 50ms
+	q := queue.NewMemoryQueue(0)
+	controller.Attach(q)
+
+	_, err := controller.PauseChannel(models.NotificationTypeSMS, "")
+	require.NoError(t, err)
+	for _, id := range []string{"sms-1", "sms-2", "sms-3"} {
+		require.NoError(t, q.Enqueue(createTestJob(id, models.NotificationTypeSMS)))
+	}
+
+	controller.ResumeChannel(models.NotificationTypeSMS)
+
+	// The first job is released at once, the rest one interval apart
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	for i := 0; i < 3; i++ {
+		_, err := q.Dequeue(ctx)
+		require.NoError(t, err)
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+
+	// Once drained, new jobs are not throttled
+	require.NoError(t, q.Enqueue(createTestJob("sms-4", models.NotificationTypeSMS)))
+	require.NoError(t, q.Enqueue(createTestJob("sms-5", models.NotificationTypeSMS)))
+	_, err = q.TryDequeue()
+	require.NoError(t, err)
+	_, err = q.TryDequeue()
+	require.NoError(t, err)
+}
+
+// Helper functions
+
+func createTestController(t *testing.T, drainRate int) *Controller {
+	controller, err := NewController(config.PauseConfig{DrainRate: drainRate}, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	return controller
+}
+
+func createTestJob(id string, channel models.NotificationType) *queue.Job {
+	return &queue.Job{
+		ID: id,
+		Request: &models.NotificationRequest{
+			Type:      channel,
+			Recipient: "+15550100",
+			Body:      "Queued notification",
+		},
+	}
+}
@@ -0,0 +1,128 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/audit"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/templatevars"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// Validate rejects requests that fail request validation
+func Validate() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+			if err := utils.ValidateNotificationRequest(request); err != nil {
+				return nil, err
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// RenderTemplate replaces {{variable}} placeholders in the subject and body
+// with the request's template data and computed variables such as {{date}}.
+// The caller's request is not modified.
+func RenderTemplate() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+			if !strings.Contains(request.Subject, "{{") && !strings.Contains(request.Body, "{{") {
+				return next(ctx, request)
+			}
+
+			data := templatevars.WithRecipient(request.TemplateData, request.Recipient)
+			now := time.Now()
+			rendered := *request
+			rendered.Subject = templatevars.Render(request.Subject, data, now)
+			rendered.Body = templatevars.Render(request.Body, data, now)
+			return next(ctx, &rendered)
+		}
+	}
+}
+
+// Deduplicate rejects a request identical to one accepted within the window:
+// same type, recipient, subject and body. A request whose send fails is
+// forgotten so it can be retried.
+func Deduplicate(window time.Duration) Middleware {
+	var mu sync.Mutex
+	seen := make(map[string]time.Time)
+	lastPrune := time.Now()
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+			key := audit.HashPayload([]string{string(request.Type), request.Recipient, request.Subject, request.Body})
+			now := time.Now()
+
+			mu.Lock()
+			if now.Sub(lastPrune) >= window {
+				for existing, expiresAt := range seen {
+					if now.After(expiresAt) {
+						delete(seen, existing)
+					}
+				}
+				lastPrune = now
+			}
+			if expiresAt, duplicate := seen[key]; duplicate && now.Before(expiresAt) {
+				mu.Unlock()
+				return nil, errors.NewNotificationError(errors.ErrorCodeInvalidRequest, fmt.Sprintf("duplicate notification within %s", window))
+			}
+			seen[key] = now.Add(window)
+			mu.Unlock()
+
+			response, err := next(ctx, request)
+			if err != nil {
+				mu.Lock()
+				delete(seen, key)
+				mu.Unlock()
+			}
+			return response, err
+		}
+	}
+}
+
+// RateLimit allows at most limit requests per recipient in each interval
+func RateLimit(limit int, interval time.Duration) Middleware {
+	type window struct {
+		start time.Time
+		count int
+	}
+
+	var mu sync.Mutex
+	windows := make(map[string]*window)
+	lastPrune := time.Now()
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+			now := time.Now()
+
+			mu.Lock()
+			if now.Sub(lastPrune) >= interval {
+				for recipient, expired := range windows {
+					if now.Sub(expired.start) >= interval {
+						delete(windows, recipient)
+					}
+				}
+				lastPrune = now
+			}
+			current, exists := windows[request.Recipient]
+			if !exists || now.Sub(current.start) >= interval {
+				current = &window{start: now}
+				windows[request.Recipient] = current
+			}
+			if current.count >= limit {
+				mu.Unlock()
+				return nil, errors.NewNotificationError(errors.ErrorCodeRateLimited, fmt.Sprintf("recipient rate limit of %d per %s exceeded", limit, interval))
+			}
+			current.count++
+			mu.Unlock()
+
+			return next(ctx, request)
+		}
+	}
+}
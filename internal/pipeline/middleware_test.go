@@ -0,0 +1,132 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestValidate(t *testing.T) {
+	handler := Validate()(createTestHandler(nil))
+
+	_, err := handler(context.Background(), createTestRequest())
+	assert.NoError(t, err)
+
+	request := createTestRequest()
+	request.Recipient = ""
+	_, err = handler(context.Background(), request)
+	assert.Error(t, err)
+}
+
+func TestRenderTemplate(t *testing.T) {
+	var received *models.NotificationRequest
+	handler := RenderTemplate()(func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+		received = request
+		return nil, nil
+	})
+
+	request := createTestRequest()
+	request.TemplateData = map[string]string{"name": "Asha", "code": "123456"}
+
+	_, err := handler(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello Asha", received.Subject)
+	assert.Equal(t, "Your code is 123456", received.Body)
+
+	// The caller's request is unchanged
+	assert.Equal(t, "Hello {{name}}", request.Subject)
+
+	// Computed variables render without template data
+	request = createTestRequest()
+	request.Recipient = "asha@example.com"
+	request.TemplateData = nil
+	request.Subject = "News for {{recipient_domain}}"
+	_, err = handler(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, "News for example.com", received.Subject)
+}
+
+func TestDeduplicate(t *testing.T) {
+	sendErr := error(nil)
+	handler := Deduplicate(time.Minute)(createTestHandler(&sendErr))
+	ctx := context.Background()
+
+	_, err := handler(ctx, createTestRequest())
+	require.NoError(t, err)
+
+	_, err = handler(ctx, createTestRequest())
+	require.Error(t, err)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeInvalidRequest, notifErr.Code)
+
+	// A different body is not a duplicate
+	other := createTestRequest()
+	other.Body = "Something else"
+	_, err = handler(ctx, other)
+	assert.NoError(t, err)
+
+	// A failed send can be retried
+	sendErr = assert.AnError
+	failing := createTestRequest()
+	failing.Recipient = "other@example.com"
+	_, err = handler(ctx, failing)
+	assert.ErrorIs(t, err, assert.AnError)
+
+	sendErr = nil
+	_, err = handler(ctx, failing)
+	assert.NoError(t, err)
+}
+
+func TestDeduplicate_WindowExpires(t *testing.T) {
+	handler := Deduplicate(20 * time.Millisecond)(createTestHandler(nil))
+	ctx := context.Background()
+
+	_, err := handler(ctx, createTestRequest())
+	require.NoError(t, err)
+
+	time.Sleep(30 * time.Millisecond)
+	_, err = handler(ctx, createTestRequest())
+	assert.NoError(t, err)
+}
+
+func TestRateLimit(t *testing.T) {
+	handler := RateLimit(2, time.Hour)(createTestHandler(nil))
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := handler(ctx, createTestRequest())
+		require.NoError(t, err)
+	}
+
+	_, err := handler(ctx, createTestRequest())
+	require.Error(t, err)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeRateLimited, notifErr.Code)
+
+	// Limits are per recipient
+	other := createTestRequest()
+	other.Recipient = "other@example.com"
+	_, err = handler(ctx, other)
+	assert.NoError(t, err)
+}
+
+// Helper functions
+
+// createTestHandler returns a final handler that fails with *sendErr when it is set
+func createTestHandler(sendErr *error) Handler {
+	return func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+		if sendErr != nil && *sendErr != nil {
+			return nil, *sendErr
+		}
+		return &models.NotificationResponse{ID: uuid.New(), Status: models.StatusSent}, nil
+	}
+}
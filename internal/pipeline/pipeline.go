@@ -0,0 +1,142 @@
+// Package pipeline runs notification requests through an ordered chain of
+// middleware before they reach a provider. Each middleware belongs to a
+// stage; stages run in order (validation, preferences, deduplication, rate
+// limiting, templating) and the provider send is always last.
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// Handler handles a notification request
+type Handler func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error)
+
+// Middleware wraps a handler. It may inspect or modify the request, stop it
+// by returning an error, or inspect the response of the rest of the chain.
+type Middleware func(next Handler) Handler
+
+// Stage orders middleware in the chain
+type Stage int
+
+const (
+	StageValidation  Stage = 100
+	StagePreferences Stage = 200
+	StageDedup       Stage = 300
+	StageRateLimit   Stage = 400
+	StageTemplate    Stage = 500
+)
+
+// Names of the built-in middleware
+const (
+	ValidationMiddleware = "validation"
+	TemplateMiddleware   = "template"
+)
+
+// entry is a registered middleware
+type entry struct {
+	name       string
+	stage      Stage
+	sequence   int
+	middleware Middleware
+}
+
+// Chain is an ordered, concurrency-safe set of middleware. Middleware run by
+// stage, and in registration order within a stage.
+type Chain struct {
+	mu       sync.RWMutex
+	entries  []entry
+	sequence int
+}
+
+// NewChain creates an empty chain
+func NewChain() *Chain {
+	return &Chain{}
+}
+
+// NewDefaultChain creates a chain with the built-in validation and template middleware
+func NewDefaultChain() *Chain {
+	chain := NewChain()
+	chain.mustRegister(ValidationMiddleware, StageValidation, Validate())
+	chain.mustRegister(TemplateMiddleware, StageTemplate, RenderTemplate())
+	return chain
+}
+
+// Register adds a middleware at a stage. Names must be unique.
+func (c *Chain) Register(name string, stage Stage, middleware Middleware) error {
+	if name == "" {
+		return errors.NewValidationError("name", "middleware name is required")
+	}
+	if middleware == nil {
+		return errors.NewValidationError("middleware", "middleware is required")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, existing := range c.entries {
+		if existing.name == name {
+			return errors.NewValidationError("name", fmt.Sprintf("middleware already registered: %s", name))
+		}
+	}
+
+	c.sequence++
+	c.entries = append(c.entries, entry{name: name, stage: stage, sequence: c.sequence, middleware: middleware})
+	sort.SliceStable(c.entries, func(i, j int) bool {
+		if c.entries[i].stage != c.entries[j].stage {
+			return c.entries[i].stage < c.entries[j].stage
+		}
+		return c.entries[i].sequence < c.entries[j].sequence
+	})
+	return nil
+}
+
+// Remove removes a middleware by name and reports whether it was registered
+func (c *Chain) Remove(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, existing := range c.entries {
+		if existing.name == name {
+			c.entries = append(c.entries[:i:i], c.entries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Names returns the registered middleware names in the order they run
+func (c *Chain) Names() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, len(c.entries))
+	for i, existing := range c.entries {
+		names[i] = existing.name
+	}
+	return names
+}
+
+// Then returns a handler that runs the chain and then the final handler
+func (c *Chain) Then(final Handler) Handler {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	handler := final
+	for i := len(c.entries) - 1; i >= 0; i-- {
+		handler = c.entries[i].middleware(handler)
+	}
+	return handler
+}
+
+// mustRegister registers a built-in middleware
+func (c *Chain) mustRegister(name string, stage Stage, middleware Middleware) {
+	if err := c.Register(name, stage, middleware); err != nil {
+		panic(err)
+	}
+}
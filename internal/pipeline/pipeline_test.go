@@ -0,0 +1,89 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+)
+
+func TestChain_RunsByStage(t *testing.T) {
+	chain := NewChain()
+	var calls []string
+
+	require.NoError(t, chain.Register("template", StageTemplate, createTestMiddleware("template", &calls)))
+	require.NoError(t, chain.Register("compliance", StagePreferences, createTestMiddleware("compliance", &calls)))
+	require.NoError(t, chain.Register("validation", StageValidation, createTestMiddleware("validation", &calls)))
+	require.NoError(t, chain.Register("opt-out", StagePreferences, createTestMiddleware("opt-out", &calls)))
+
+	assert.Equal(t, []string{"validation", "compliance", "opt-out", "template"}, chain.Names())
+
+	handler := chain.Then(func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+		calls = append(calls, "provider")
+		return &models.NotificationResponse{ID: uuid.New(), Status: models.StatusSent}, nil
+	})
+
+	response, err := handler(context.Background(), createTestRequest())
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusSent, response.Status)
+	assert.Equal(t, []string{"validation", "compliance", "opt-out", "template", "provider"}, calls)
+}
+
+func TestChain_MiddlewareCanStopRequest(t *testing.T) {
+	chain := NewDefaultChain()
+	blocked := assert.AnError
+
+	require.NoError(t, chain.Register("block", StagePreferences, func(next Handler) Handler {
+		return func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+			return nil, blocked
+		}
+	}))
+
+	reached := false
+	handler := chain.Then(func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+		reached = true
+		return nil, nil
+	})
+
+	_, err := handler(context.Background(), createTestRequest())
+	assert.ErrorIs(t, err, blocked)
+	assert.False(t, reached)
+}
+
+func TestChain_RegisterAndRemove(t *testing.T) {
+	chain := NewDefaultChain()
+	assert.Equal(t, []string{ValidationMiddleware, TemplateMiddleware}, chain.Names())
+
+	assert.Error(t, chain.Register(ValidationMiddleware, StageValidation, Validate()))
+	assert.Error(t, chain.Register("", StageValidation, Validate()))
+	assert.Error(t, chain.Register("nil", StageValidation, nil))
+
+	assert.True(t, chain.Remove(TemplateMiddleware))
+	assert.False(t, chain.Remove(TemplateMiddleware))
+	assert.Equal(t, []string{ValidationMiddleware}, chain.Names())
+}
+
+// Helper functions
+
+func createTestMiddleware(name string, calls *[]string) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+			*calls = append(*calls, name)
+			return next(ctx, request)
+		}
+	}
+}
+
+func createTestRequest() *models.NotificationRequest {
+	return &models.NotificationRequest{
+		Type:      models.NotificationTypeEmail,
+		Priority:  models.PriorityNormal,
+		Recipient: "user@example.com",
+		Subject:   "Hello {{name}}",
+		Body:      "Your code is {{code}}",
+	}
+}
@@ -0,0 +1,176 @@
+package preferences
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pipeline"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// MiddlewareName is the name the enforcer's middleware is registered under
+const MiddlewareName = "preferences"
+
+// Policy is how a category treats recipient preferences
+type Policy struct {
+	RespectOptOuts    bool `json:"respect_opt_outs"`
+	RespectQuietHours bool `json:"respect_quiet_hours"` // held until quiet hours end
+}
+
+// DefaultPolicies returns the policy of each category. Marketing respects
+// opt-outs and quiet hours, transactional notifications respect opt-outs,
+// and security notifications, such as login codes, bypass both.
+func DefaultPolicies() map[models.Category]Policy {
+	return map[models.Category]Policy{
+		models.CategoryMarketing:     {RespectOptOuts: true, RespectQuietHours: true},
+		models.CategoryTransactional: {RespectOptOuts: true},
+		models.CategorySecurity:      {},
+	}
+}
+
+// Enforcer applies category policies to sends. Requests without a category
+// are treated as transactional.
+type Enforcer struct {
+	mu       sync.Mutex
+	store    *Store
+	policies map[models.Category]Policy
+	timers   map[*time.Timer]bool
+	sender   pipeline.Handler
+	logger   interfaces.Logger
+	now      func() time.Time
+	stopped  bool
+}
+
+// NewEnforcer creates an enforcer with the default policies
+func NewEnforcer(store *Store, logger interfaces.Logger) *Enforcer {
+	return &Enforcer{
+		store:    store,
+		policies: DefaultPolicies(),
+		timers:   make(map[*time.Timer]bool),
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// SetPolicy replaces the policy of a category
+func (e *Enforcer) SetPolicy(category models.Category, policy Policy) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.policies[category] = policy
+}
+
+// Policy returns the policy applied to a category
+func (e *Enforcer) Policy(category models.Category) Policy {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.policy(category)
+}
+
+// SetSender sets the handler that sends notifications held for quiet hours,
+// typically the dispatcher's SendNotification. Without a sender, sends during
+// quiet hours are rejected instead.
+func (e *Enforcer) SetSender(sender pipeline.Handler) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.sender = sender
+}
+
+// Middleware returns the send middleware enforcing the policies. Register it
+// at pipeline.StagePreferences under MiddlewareName.
+func (e *Enforcer) Middleware() pipeline.Middleware {
+	return func(next pipeline.Handler) pipeline.Handler {
+		return func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+			category := request.Category
+			if category == "" {
+				category = models.CategoryTransactional
+			}
+			policy := e.Policy(category)
+
+			if policy.RespectOptOuts && e.store.IsOptedOut(request.Recipient, request.Type, category) {
+				return nil, errors.NewNotificationError(errors.ErrorCodeRecipientOptedOut,
+					fmt.Sprintf("recipient opted out of %s %s notifications", category, request.Type))
+			}
+
+			if policy.RespectQuietHours {
+				if hours, exists := e.store.QuietHours(request.Recipient); exists {
+					if until, quiet := hours.Until(e.now()); quiet {
+						return e.hold(request, until)
+					}
+				}
+			}
+
+			return next(ctx, request)
+		}
+	}
+}
+
+// Pending returns the number of sends held for quiet hours
+func (e *Enforcer) Pending() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return len(e.timers)
+}
+
+// Stop cancels sends held for quiet hours
+func (e *Enforcer) Stop() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for timer := range e.timers {
+		timer.Stop()
+		delete(e.timers, timer)
+	}
+	e.stopped = true
+}
+
+// hold schedules a send for the end of the recipient's quiet hours
+func (e *Enforcer) hold(request *models.NotificationRequest, until time.Time) (*models.NotificationResponse, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	message := fmt.Sprintf("recipient is in quiet hours until %s", until.Format(time.RFC3339))
+	if e.sender == nil || e.stopped {
+		retryAfter := int(until.Sub(e.now()).Seconds()) + 1
+		return nil, errors.NewNotificationError(errors.ErrorCodeRateLimited, message).
+			WithMetadata("retry_after", strconv.Itoa(retryAfter))
+	}
+
+	held := *request
+	held.ScheduledAt = &until
+	sender := e.sender
+
+	var timer *time.Timer
+	timer = time.AfterFunc(until.Sub(e.now()), func() {
+		e.mu.Lock()
+		delete(e.timers, timer)
+		e.mu.Unlock()
+
+		if _, err := sender(context.Background(), &held); err != nil {
+			e.logger.Errorf("Held %s notification failed after quiet hours: %v", held.Type, err)
+		}
+	})
+	e.timers[timer] = true
+
+	e.logger.Infof("Holding %s notification: %s", request.Type, message)
+	return &models.NotificationResponse{
+		Status:  models.StatusPending,
+		Message: fmt.Sprintf("%s; held until then", message),
+	}, nil
+}
+
+// policy returns the policy of a category. Callers must hold the lock.
+func (e *Enforcer) policy(category models.Category) Policy {
+	if policy, exists := e.policies[category]; exists {
+		return policy
+	}
+	return e.policies[models.CategoryTransactional]
+}
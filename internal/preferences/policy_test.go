@@ -0,0 +1,158 @@
+package preferences
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pipeline"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestEnforcer_OptOutsByCategory(t *testing.T) {
+	store := NewStore()
+	require.NoError(t, store.OptOut("user@example.com", models.NotificationTypeEmail, ""))
+	send := NewEnforcer(store, utils.NewSimpleLogger("info")).Middleware()(createTestHandler())
+
+	tests := []struct {
+		category models.Category
+		allowed  bool
+	}{
+		{models.CategoryMarketing, false},
+		{models.CategoryTransactional, false},
+		{"", false}, // uncategorized is transactional
+		{models.CategorySecurity, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.category), func(t *testing.T) {
+			_, err := send(context.Background(), createTestRequest(tt.category))
+			if tt.allowed {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			notifErr, ok := errors.AsNotificationError(err)
+			require.True(t, ok)
+			assert.Equal(t, errors.ErrorCodeRecipientOptedOut, notifErr.Code)
+		})
+	}
+}
+
+func TestEnforcer_MarketingOptOutOnly(t *testing.T) {
+	store := NewStore()
+	require.NoError(t, store.OptOut("user@example.com", "", models.CategoryMarketing))
+	send := NewEnforcer(store, utils.NewSimpleLogger("info")).Middleware()(createTestHandler())
+
+	_, err := send(context.Background(), createTestRequest(models.CategoryMarketing))
+	assert.Error(t, err)
+	_, err = send(context.Background(), createTestRequest(models.CategoryTransactional))
+	assert.NoError(t, err)
+}
+
+func TestEnforcer_QuietHours(t *testing.T) {
+	store := NewStore()
+	require.NoError(t, store.SetQuietHours("user@example.com", QuietHours{Start: "22:00", End: "07:00"}))
+
+	enforcer := NewEnforcer(store, utils.NewSimpleLogger("info"))
+	enforcer.now = func() time.Time { return time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC) }
+	send := enforcer.Middleware()(createTestHandler())
+
+	// Without a sender, marketing is rejected until quiet hours end
+	_, err := send(context.Background(), createTestRequest(models.CategoryMarketing))
+	require.Error(t, err)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeRateLimited, notifErr.Code)
+	assert.Equal(t, "28801", notifErr.Metadata["retry_after"])
+
+	// Transactional and security notifications ignore quiet hours
+	_, err = send(context.Background(), createTestRequest(models.CategoryTransactional))
+	assert.NoError(t, err)
+	_, err = send(context.Background(), createTestRequest(models.CategorySecurity))
+	assert.NoError(t, err)
+
+	// With a sender, marketing is held
+	enforcer.SetSender(createTestHandler())
+	defer enforcer.Stop()
+
+	response, err := send(context.Background(), createTestRequest(models.CategoryMarketing))
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusPending, response.Status)
+	assert.Equal(t, 1, enforcer.Pending())
+
+	enforcer.Stop()
+	assert.Equal(t, 0, enforcer.Pending())
+}
+
+func TestEnforcer_SendsHeldNotificationsAfterQuietHours(t *testing.T) {
+	store := NewStore()
+	require.NoError(t, store.SetQuietHours("user@example.com", QuietHours{Start: "22:00", End: "07:00"}))
+
+	enforcer := NewEnforcer(store, utils.NewSimpleLogger("info"))
+	defer enforcer.Stop()
+
+	// Quiet hours end 50ms from the enforcer's clock
+	end := time.Date(2024, 3, 2, 7, 0, 0, 0, time.UTC)
+	enforcer.now = func() time.Time { return end.Add(-50 * time.Millisecond) }
+
+	var mu sync.Mutex
+	var sent []*models.NotificationRequest
+	enforcer.SetSender(func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, request)
+		return &models.NotificationResponse{ID: uuid.New(), Status: models.StatusSent}, nil
+	})
+
+	_, err := enforcer.Middleware()(createTestHandler())(context.Background(), createTestRequest(models.CategoryMarketing))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(sent) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	assert.True(t, end.Equal(*sent[0].ScheduledAt))
+	mu.Unlock()
+}
+
+func TestEnforcer_SetPolicy(t *testing.T) {
+	store := NewStore()
+	require.NoError(t, store.OptOut("user@example.com", "", ""))
+
+	enforcer := NewEnforcer(store, utils.NewSimpleLogger("info"))
+	enforcer.SetPolicy(models.CategoryTransactional, Policy{})
+	assert.Equal(t, Policy{RespectOptOuts: true, RespectQuietHours: true}, enforcer.Policy(models.CategoryMarketing))
+
+	_, err := enforcer.Middleware()(createTestHandler())(context.Background(), createTestRequest(models.CategoryTransactional))
+	assert.NoError(t, err)
+}
+
+// Helper functions
+
+func createTestHandler() pipeline.Handler {
+	return func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+		return &models.NotificationResponse{ID: uuid.New(), Status: models.StatusSent}, nil
+	}
+}
+
+func createTestRequest(category models.Category) *models.NotificationRequest {
+	return &models.NotificationRequest{
+		Type:      models.NotificationTypeEmail,
+		Priority:  models.PriorityNormal,
+		Recipient: "user@example.com",
+		Subject:   "Hello",
+		Body:      "Hello there",
+		Category:  category,
+	}
+}
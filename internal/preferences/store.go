@@ -0,0 +1,243 @@
+// Package preferences holds recipients' opt-outs and quiet hours and enforces
+// them per notification category: marketing respects both, transactional
+// notifications respect opt-outs, and security notifications bypass them.
+package preferences
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// OptOut records that a recipient does not want notifications. An empty
+// channel or category opts out of every channel or category.
+type OptOut struct {
+	Recipient  string                  `json:"recipient"`
+	Channel    models.NotificationType `json:"channel,omitempty"`
+	Category   models.Category         `json:"category,omitempty"`
+	OptedOutAt time.Time               `json:"opted_out_at"`
+}
+
+// matches reports whether the opt-out covers a channel and category
+func (o OptOut) matches(channel models.NotificationType, category models.Category) bool {
+	return (o.Channel == "" || o.Channel == channel) && (o.Category == "" || o.Category == category)
+}
+
+// QuietHours is a daily window in the recipient's time zone during which
+// notifications that respect quiet hours are held. Start and End are "HH:MM";
+// a window may span midnight, e.g. 22:00 to 07:00.
+type QuietHours struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Timezone string `json:"timezone,omitempty"` // IANA name; empty means UTC
+}
+
+// Until returns the end of the quiet hours window containing t, and false
+// when t is outside quiet hours
+func (q QuietHours) Until(t time.Time) (time.Time, bool) {
+	start, end, location, err := q.parse()
+	if err != nil || start == end {
+		return time.Time{}, false
+	}
+
+	local := t.In(location)
+	now := local.Hour()*60 + local.Minute()
+	endOn := func(days int) time.Time {
+		return time.Date(local.Year(), local.Month(), local.Day()+days, end/60, end%60, 0, 0, location)
+	}
+
+	switch {
+	case start < end && now >= start && now < end:
+		return endOn(0), true
+	case start > end && now >= start:
+		return endOn(1), true
+	case start > end && now < end:
+		return endOn(0), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// validate checks the window and time zone
+func (q QuietHours) validate() error {
+	_, _, _, err := q.parse()
+	return err
+}
+
+// parse returns the window as minutes after midnight and its location
+func (q QuietHours) parse() (int, int, *time.Location, error) {
+	start, err := parseClock(q.Start)
+	if err != nil {
+		return 0, 0, nil, errors.NewValidationError("start", err.Error())
+	}
+	end, err := parseClock(q.End)
+	if err != nil {
+		return 0, 0, nil, errors.NewValidationError("end", err.Error())
+	}
+
+	location := time.UTC
+	if q.Timezone != "" {
+		if location, err = time.LoadLocation(q.Timezone); err != nil {
+			return 0, 0, nil, errors.NewValidationError("timezone", fmt.Sprintf("unknown time zone: %s", q.Timezone))
+		}
+	}
+	return start, end, location, nil
+}
+
+// parseClock parses "HH:MM" as minutes after midnight
+func parseClock(value string) (int, error) {
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("time must be HH:MM: %q", value)
+	}
+	return clock.Hour()*60 + clock.Minute(), nil
+}
+
+// Store is an in-memory store of recipients' preferences. Recipients are
+// matched case-insensitively.
+type Store struct {
+	mu         sync.RWMutex
+	optOuts    map[string][]OptOut
+	quietHours map[string]QuietHours
+}
+
+// NewStore creates an empty preference store
+func NewStore() *Store {
+	return &Store{
+		optOuts:    make(map[string][]OptOut),
+		quietHours: make(map[string]QuietHours),
+	}
+}
+
+// OptOut opts a recipient out of a channel and category. Leave channel or
+// category empty to opt out of all of them.
+func (s *Store) OptOut(recipient string, channel models.NotificationType, category models.Category) error {
+	if err := validateScope(recipient, channel, category); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := recipientKey(recipient)
+	for _, existing := range s.optOuts[key] {
+		if existing.Channel == channel && existing.Category == category {
+			return nil
+		}
+	}
+	s.optOuts[key] = append(s.optOuts[key], OptOut{
+		Recipient:  recipient,
+		Channel:    channel,
+		Category:   category,
+		OptedOutAt: time.Now(),
+	})
+	return nil
+}
+
+// OptIn removes an opt-out, reporting whether it existed. It must name the
+// same channel and category as the opt-out.
+func (s *Store) OptIn(recipient string, channel models.NotificationType, category models.Category) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := recipientKey(recipient)
+	optOuts := s.optOuts[key]
+	for i, existing := range optOuts {
+		if existing.Channel == channel && existing.Category == category {
+			optOuts = append(optOuts[:i:i], optOuts[i+1:]...)
+			if len(optOuts) == 0 {
+				delete(s.optOuts, key)
+			} else {
+				s.optOuts[key] = optOuts
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// OptOuts returns a recipient's opt-outs, oldest first
+func (s *Store) OptOuts(recipient string) []OptOut {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	optOuts := append([]OptOut(nil), s.optOuts[recipientKey(recipient)]...)
+	sort.SliceStable(optOuts, func(i, j int) bool {
+		return optOuts[i].OptedOutAt.Before(optOuts[j].OptedOutAt)
+	})
+	return optOuts
+}
+
+// IsOptedOut reports whether a recipient opted out of a channel and category
+func (s *Store) IsOptedOut(recipient string, channel models.NotificationType, category models.Category) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, optOut := range s.optOuts[recipientKey(recipient)] {
+		if optOut.matches(channel, category) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetQuietHours sets a recipient's quiet hours
+func (s *Store) SetQuietHours(recipient string, hours QuietHours) error {
+	if strings.TrimSpace(recipient) == "" {
+		return errors.NewValidationError("recipient", "recipient is required")
+	}
+	if err := hours.validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.quietHours[recipientKey(recipient)] = hours
+	return nil
+}
+
+// QuietHours returns a recipient's quiet hours
+func (s *Store) QuietHours(recipient string) (QuietHours, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hours, exists := s.quietHours[recipientKey(recipient)]
+	return hours, exists
+}
+
+// ClearQuietHours removes a recipient's quiet hours, reporting whether they were set
+func (s *Store) ClearQuietHours(recipient string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := recipientKey(recipient)
+	_, exists := s.quietHours[key]
+	delete(s.quietHours, key)
+	return exists
+}
+
+// validateScope checks the recipient, channel and category of an opt-out
+func validateScope(recipient string, channel models.NotificationType, category models.Category) error {
+	if strings.TrimSpace(recipient) == "" {
+		return errors.NewValidationError("recipient", "recipient is required")
+	}
+	if channel != "" && !utils.IsValidNotificationType(channel) {
+		return errors.NewValidationError("channel", fmt.Sprintf("unknown channel: %s", channel))
+	}
+	if category != "" && !utils.IsValidCategory(category) {
+		return errors.NewValidationError("category", fmt.Sprintf("unknown category: %s", category))
+	}
+	return nil
+}
+
+// recipientKey normalizes a recipient for lookups
+func recipientKey(recipient string) string {
+	return strings.ToLower(strings.TrimSpace(recipient))
+}
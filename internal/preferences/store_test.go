@@ -0,0 +1,107 @@
+package preferences
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+)
+
+func TestStore_OptOut(t *testing.T) {
+	store := NewStore()
+
+	require.NoError(t, store.OptOut("User@Example.com", models.NotificationTypeEmail, models.CategoryMarketing))
+	require.NoError(t, store.OptOut("user@example.com", models.NotificationTypeEmail, models.CategoryMarketing)) // already opted out
+
+	assert.True(t, store.IsOptedOut("user@example.com", models.NotificationTypeEmail, models.CategoryMarketing))
+	assert.False(t, store.IsOptedOut("user@example.com", models.NotificationTypeEmail, models.CategoryTransactional))
+	assert.False(t, store.IsOptedOut("user@example.com", models.NotificationTypeSMS, models.CategoryMarketing))
+	assert.Len(t, store.OptOuts("user@example.com"), 1)
+
+	assert.False(t, store.OptIn("user@example.com", models.NotificationTypeEmail, ""))
+	assert.True(t, store.OptIn("user@example.com", models.NotificationTypeEmail, models.CategoryMarketing))
+	assert.False(t, store.IsOptedOut("user@example.com", models.NotificationTypeEmail, models.CategoryMarketing))
+	assert.Empty(t, store.OptOuts("user@example.com"))
+}
+
+func TestStore_OptOutOfEverything(t *testing.T) {
+	store := NewStore()
+
+	// An SMS STOP opts out of every category on the channel
+	require.NoError(t, store.OptOut("+15550100", models.NotificationTypeSMS, ""))
+	assert.True(t, store.IsOptedOut("+15550100", models.NotificationTypeSMS, models.CategoryMarketing))
+	assert.True(t, store.IsOptedOut("+15550100", models.NotificationTypeSMS, models.CategorySecurity))
+	assert.False(t, store.IsOptedOut("+15550100", models.NotificationTypeVoice, models.CategoryMarketing))
+}
+
+func TestStore_OptOutValidation(t *testing.T) {
+	store := NewStore()
+
+	assert.Error(t, store.OptOut(" ", "", ""))
+	assert.Error(t, store.OptOut("user@example.com", "fax", ""))
+	assert.Error(t, store.OptOut("user@example.com", "", "newsletter"))
+}
+
+func TestStore_QuietHours(t *testing.T) {
+	store := NewStore()
+
+	require.NoError(t, store.SetQuietHours("user@example.com", QuietHours{Start: "22:00", End: "07:00"}))
+	hours, exists := store.QuietHours("USER@example.com")
+	require.True(t, exists)
+	assert.Equal(t, "22:00", hours.Start)
+
+	assert.Error(t, store.SetQuietHours("user@example.com", QuietHours{Start: "25:00", End: "07:00"}))
+	assert.Error(t, store.SetQuietHours("user@example.com", QuietHours{Start: "22:00", End: "07:00", Timezone: "Mars/Olympus"}))
+
+	assert.True(t, store.ClearQuietHours("user@example.com"))
+	assert.False(t, store.ClearQuietHours("user@example.com"))
+}
+
+func TestQuietHours_Until(t *testing.T) {
+	overnight := QuietHours{Start: "22:00", End: "07:00"}
+	daytime := QuietHours{Start: "12:00", End: "13:30"}
+	day := func(hour, minute int) time.Time {
+		return time.Date(2024, 3, 1, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name  string
+		hours QuietHours
+		at    time.Time
+		until time.Time
+		quiet bool
+	}{
+		{"before overnight window", overnight, day(21, 59), time.Time{}, false},
+		{"evening", overnight, day(23, 0), time.Date(2024, 3, 2, 7, 0, 0, 0, time.UTC), true},
+		{"early morning", overnight, day(6, 30), day(7, 0), true},
+		{"at window end", overnight, day(7, 0), time.Time{}, false},
+		{"inside daytime window", daytime, day(12, 15), day(13, 30), true},
+		{"after daytime window", daytime, day(14, 0), time.Time{}, false},
+		{"empty window", QuietHours{Start: "09:00", End: "09:00"}, day(9, 0), time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			until, quiet := tt.hours.Until(tt.at)
+			assert.Equal(t, tt.quiet, quiet)
+			assert.True(t, tt.until.Equal(until), "until %s, want %s", until, tt.until)
+		})
+	}
+}
+
+func TestQuietHours_UntilInTimezone(t *testing.T) {
+	location, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+
+	hours := QuietHours{Start: "22:00", End: "07:00", Timezone: "America/New_York"}
+
+	// 03:00 UTC is 22:00 in New York during standard time
+	until, quiet := hours.Until(time.Date(2024, 1, 10, 3, 0, 0, 0, time.UTC))
+	require.True(t, quiet)
+	assert.True(t, time.Date(2024, 1, 10, 7, 0, 0, 0, location).Equal(until))
+}
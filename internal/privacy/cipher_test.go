@@ -0,0 +1,63 @@
+package privacy
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecipientCipher_RoundTrip(t *testing.T) {
+	cipher := createTestCipher(t, 1)
+
+	encrypted := cipher.Encrypt("jane@example.com")
+	assert.True(t, IsEncrypted(encrypted))
+	assert.NotContains(t, encrypted, "jane")
+
+	decrypted, err := cipher.Decrypt(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "jane@example.com", decrypted)
+
+	// Encryption is deterministic so stored recipients can be looked up
+	assert.Equal(t, encrypted, cipher.Encrypt("jane@example.com"))
+	assert.NotEqual(t, encrypted, cipher.Encrypt("john@example.com"))
+
+	// Already encrypted and empty values are left alone
+	assert.Equal(t, encrypted, cipher.Encrypt(encrypted))
+	assert.Equal(t, "", cipher.Encrypt(""))
+}
+
+func TestRecipientCipher_Decrypt(t *testing.T) {
+	cipher := createTestCipher(t, 1)
+
+	// Values stored before encryption was enabled pass through
+	plain, err := cipher.Decrypt("+15551234567")
+	require.NoError(t, err)
+	assert.Equal(t, "+15551234567", plain)
+
+	// A different key cannot decrypt
+	_, err = createTestCipher(t, 2).Decrypt(cipher.Encrypt("jane@example.com"))
+	assert.Error(t, err)
+
+	_, err = cipher.Decrypt("enc:v1:not-base64!")
+	assert.Error(t, err)
+}
+
+func TestNewRecipientCipher_InvalidKey(t *testing.T) {
+	_, err := NewRecipientCipher("not base64")
+	assert.Error(t, err)
+
+	_, err = NewRecipientCipher(base64.StdEncoding.EncodeToString([]byte("too short")))
+	assert.Error(t, err)
+}
+
+// Helper functions
+
+func createTestCipher(t *testing.T, seed byte) *RecipientCipher {
+	key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(rune('a'+seed)), 32)))
+	cipher, err := NewRecipientCipher(key)
+	require.NoError(t, err)
+	return cipher
+}
@@ -0,0 +1,326 @@
+package privacy
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// envelopePrefix marks values produced by EnvelopeCipher
+const envelopePrefix = "env:v1:"
+
+// dataKeySize is the size of the per-value data encryption key
+const dataKeySize = 32
+
+// Keyring holds key encryption keys (KEKs) by ID. New values are encrypted
+// under the primary key; any key in the ring can decrypt. Rotating adds a
+// new primary while older keys stay available until data is rewrapped.
+type Keyring struct {
+	mu      sync.RWMutex
+	keys    map[string]cipher.AEAD
+	primary string
+}
+
+// NewKeyring creates a keyring with a single primary key
+func NewKeyring(keyID string, key []byte) (*Keyring, error) {
+	keyring := &Keyring{keys: make(map[string]cipher.AEAD)}
+	if err := keyring.Rotate(keyID, key); err != nil {
+		return nil, err
+	}
+	return keyring, nil
+}
+
+// ParseKeyring builds a keyring from "id:base64key" entries. The primary
+// key must be one of the entries.
+func ParseKeyring(primaryID string, entries []string) (*Keyring, error) {
+	keyring := &Keyring{keys: make(map[string]cipher.AEAD)}
+
+	for _, entry := range entries {
+		keyID, encoded, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if !found {
+			return nil, errors.NewNotificationError(errors.ErrorCodeProviderConfiguration, "content keys must be formatted as id:base64key")
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, errors.NewNotificationError(errors.ErrorCodeProviderConfiguration, fmt.Sprintf("content key %s is not valid base64", keyID))
+		}
+
+		if err := keyring.Add(keyID, key); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, exists := keyring.keys[primaryID]; !exists {
+		return nil, errors.NewNotificationError(errors.ErrorCodeProviderConfiguration, fmt.Sprintf("primary content key not found: %s", primaryID))
+	}
+	keyring.primary = primaryID
+
+	return keyring, nil
+}
+
+// Add adds a key that can decrypt existing values without making it the primary
+func (k *Keyring) Add(keyID string, key []byte) error {
+	if keyID == "" || strings.Contains(keyID, ":") {
+		return errors.NewValidationError("key_id", "key ID is required and cannot contain ':'")
+	}
+	if len(key) != 32 {
+		return errors.NewValidationError("key", "key encryption keys must be 32 bytes")
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.keys[keyID] = aead
+	return nil
+}
+
+// Rotate adds a key and makes it the primary key for new values
+func (k *Keyring) Rotate(keyID string, key []byte) error {
+	if err := k.Add(keyID, key); err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.primary = keyID
+	return nil
+}
+
+// Remove removes a retired key. The primary key cannot be removed.
+func (k *Keyring) Remove(keyID string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if keyID == k.primary {
+		return errors.NewValidationError("key_id", "the primary key cannot be removed")
+	}
+	delete(k.keys, keyID)
+	return nil
+}
+
+// PrimaryKeyID returns the ID of the key used for new values
+func (k *Keyring) PrimaryKeyID() string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.primary
+}
+
+// primaryKey returns the primary key and its ID
+func (k *Keyring) primaryKey() (string, cipher.AEAD) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.primary, k.keys[k.primary]
+}
+
+// key returns a key by ID
+func (k *Keyring) key(keyID string) (cipher.AEAD, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	aead, exists := k.keys[keyID]
+	if !exists {
+		return nil, errors.NewNotificationError(errors.ErrorCodeInternal, fmt.Sprintf("content encryption key not found: %s", keyID))
+	}
+	return aead, nil
+}
+
+// EnvelopeCipher encrypts notification content with envelope encryption.
+// Every value gets a random data key (DEK), which is itself encrypted with
+// the keyring's primary KEK and stored alongside the ciphertext:
+//
+//	env:v1:<kek id>:<wrapped dek>:<ciphertext>
+//
+// Rotating the KEK only requires rewrapping the small DEK, not re-encrypting content.
+type EnvelopeCipher struct {
+	keyring *Keyring
+}
+
+// NewEnvelopeCipher creates an envelope cipher backed by a keyring
+func NewEnvelopeCipher(keyring *Keyring) *EnvelopeCipher {
+	return &EnvelopeCipher{keyring: keyring}
+}
+
+// Keyring returns the cipher's keyring
+func (c *EnvelopeCipher) Keyring() *Keyring {
+	return c.keyring
+}
+
+// Encrypt encrypts a value. Empty values are returned unchanged; any other
+// value is encrypted, even one that looks like an envelope, so content
+// cannot opt out of encryption by its prefix.
+func (c *EnvelopeCipher) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return plaintext, nil
+	}
+
+	dataKey := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", errors.NewInternalError("failed to generate data key", err)
+	}
+
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(dataAEAD, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+
+	keyID, kek := c.keyring.primaryKey()
+	wrappedKey, err := seal(kek, dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	return formatEnvelope(keyID, wrappedKey, ciphertext), nil
+}
+
+// Decrypt decrypts a value. Values stored before encryption was enabled are returned unchanged.
+func (c *EnvelopeCipher) Decrypt(value string) (string, error) {
+	if !IsEnvelope(value) {
+		return value, nil
+	}
+
+	keyID, wrappedKey, ciphertext, err := parseEnvelope(value)
+	if err != nil {
+		return "", err
+	}
+
+	dataKey, err := c.unwrap(keyID, wrappedKey)
+	if err != nil {
+		return "", err
+	}
+
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(dataAEAD, ciphertext)
+	if err != nil {
+		return "", errors.NewInternalError("failed to decrypt content", err)
+	}
+	return string(plaintext), nil
+}
+
+// Rewrap re-encrypts a value's data key under the primary KEK. It reports
+// whether the value changed; plaintext values and values already under the
+// primary key are returned unchanged.
+func (c *EnvelopeCipher) Rewrap(value string) (string, bool, error) {
+	if !IsEnvelope(value) {
+		return value, false, nil
+	}
+
+	keyID, wrappedKey, ciphertext, err := parseEnvelope(value)
+	if err != nil {
+		return "", false, err
+	}
+
+	primaryID, kek := c.keyring.primaryKey()
+	if keyID == primaryID {
+		return value, false, nil
+	}
+
+	dataKey, err := c.unwrap(keyID, wrappedKey)
+	if err != nil {
+		return "", false, err
+	}
+
+	rewrapped, err := seal(kek, dataKey)
+	if err != nil {
+		return "", false, err
+	}
+	return formatEnvelope(primaryID, rewrapped, ciphertext), true, nil
+}
+
+// IsEnvelope reports whether a value was produced by EnvelopeCipher
+func IsEnvelope(value string) bool {
+	return strings.HasPrefix(value, envelopePrefix)
+}
+
+// unwrap decrypts a data key with the KEK it was wrapped under
+func (c *EnvelopeCipher) unwrap(keyID string, wrappedKey []byte) ([]byte, error) {
+	kek, err := c.keyring.key(keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := open(kek, wrappedKey)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to unwrap data key", err)
+	}
+	return dataKey, nil
+}
+
+// newAEAD creates an AES-GCM cipher
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to create content cipher", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to create content cipher", err)
+	}
+	return aead, nil
+}
+
+// seal encrypts with a random nonce, returning nonce and ciphertext together
+func seal(aead cipher.AEAD, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errors.NewInternalError("failed to generate nonce", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// open decrypts the output of seal
+func open(aead cipher.AEAD, sealed []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonceSize := aead.NonceSize()
+	return aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+}
+
+// formatEnvelope encodes an envelope as a string
+func formatEnvelope(keyID string, wrappedKey, ciphertext []byte) string {
+	return envelopePrefix + keyID + ":" +
+		base64.RawURLEncoding.EncodeToString(wrappedKey) + ":" +
+		base64.RawURLEncoding.EncodeToString(ciphertext)
+}
+
+// parseEnvelope decodes an envelope string
+func parseEnvelope(value string) (string, []byte, []byte, error) {
+	parts := strings.Split(strings.TrimPrefix(value, envelopePrefix), ":")
+	if len(parts) != 3 {
+		return "", nil, nil, errors.NewNotificationError(errors.ErrorCodeInternal, "malformed encrypted content")
+	}
+
+	wrappedKey, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", nil, nil, errors.NewInternalError("malformed encrypted content", err)
+	}
+	ciphertext, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, nil, errors.NewInternalError("malformed encrypted content", err)
+	}
+
+	return parts[0], wrappedKey, ciphertext, nil
+}
@@ -0,0 +1,142 @@
+package privacy
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvelopeCipher_RoundTrip(t *testing.T) {
+	cipher := NewEnvelopeCipher(createTestKeyring(t, "k1"))
+
+	encrypted, err := cipher.Encrypt("Your code is 123456")
+	require.NoError(t, err)
+	assert.True(t, IsEnvelope(encrypted))
+	assert.True(t, strings.HasPrefix(encrypted, "env:v1:k1:"))
+	assert.NotContains(t, encrypted, "123456")
+
+	decrypted, err := cipher.Decrypt(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "Your code is 123456", decrypted)
+
+	// Every value gets its own data key
+	again, err := cipher.Encrypt("Your code is 123456")
+	require.NoError(t, err)
+	assert.NotEqual(t, encrypted, again)
+
+	// Plaintext passes through decryption; empty values are not encrypted
+	plain, err := cipher.Decrypt("stored before encryption")
+	require.NoError(t, err)
+	assert.Equal(t, "stored before encryption", plain)
+
+	empty, err := cipher.Encrypt("")
+	require.NoError(t, err)
+	assert.Equal(t, "", empty)
+
+	// Content that looks like an envelope is encrypted all the same
+	lookalike, err := cipher.Encrypt("env:v1:k1:not:sealed")
+	require.NoError(t, err)
+	assert.NotContains(t, lookalike, "not:sealed")
+	decrypted, err = cipher.Decrypt(lookalike)
+	require.NoError(t, err)
+	assert.Equal(t, "env:v1:k1:not:sealed", decrypted)
+}
+
+func TestEnvelopeCipher_Rotation(t *testing.T) {
+	keyring := createTestKeyring(t, "k1")
+	cipher := NewEnvelopeCipher(keyring)
+
+	old, err := cipher.Encrypt("hello")
+	require.NoError(t, err)
+
+	require.NoError(t, keyring.Rotate("k2", testKey('2')))
+	assert.Equal(t, "k2", keyring.PrimaryKeyID())
+
+	// Old values still decrypt; new values use the new key
+	decrypted, err := cipher.Decrypt(old)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", decrypted)
+
+	fresh, err := cipher.Encrypt("hello")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(fresh, "env:v1:k2:"))
+
+	// Rewrapping moves the data key to the primary KEK without touching the content
+	rewrapped, changed, err := cipher.Rewrap(old)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.True(t, strings.HasPrefix(rewrapped, "env:v1:k2:"))
+	assert.Equal(t, old[strings.LastIndex(old, ":"):], rewrapped[strings.LastIndex(rewrapped, ":"):])
+
+	_, changed, err = cipher.Rewrap(rewrapped)
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	// Once retired, the old key can no longer decrypt
+	require.NoError(t, keyring.Remove("k1"))
+	_, err = cipher.Decrypt(old)
+	assert.Error(t, err)
+
+	decrypted, err = cipher.Decrypt(rewrapped)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", decrypted)
+
+	assert.Error(t, keyring.Remove("k2"))
+}
+
+func TestEnvelopeCipher_Tampering(t *testing.T) {
+	cipher := NewEnvelopeCipher(createTestKeyring(t, "k1"))
+
+	encrypted, err := cipher.Encrypt("hello")
+	require.NoError(t, err)
+
+	// Flip a character inside the ciphertext's authentication tag
+	tampered := []byte(encrypted)
+	if tampered[len(tampered)-2] == 'A' {
+		tampered[len(tampered)-2] = 'B'
+	} else {
+		tampered[len(tampered)-2] = 'A'
+	}
+	_, err = cipher.Decrypt(string(tampered))
+	assert.Error(t, err)
+
+	_, err = cipher.Decrypt("env:v1:k1:only-two-parts")
+	assert.Error(t, err)
+
+	_, err = cipher.Decrypt(strings.Replace(encrypted, ":k1:", ":missing:", 1))
+	assert.Error(t, err)
+}
+
+func TestParseKeyring(t *testing.T) {
+	k1 := base64.StdEncoding.EncodeToString(testKey('1'))
+	k2 := base64.StdEncoding.EncodeToString(testKey('2'))
+
+	keyring, err := ParseKeyring("k2", []string{"k1:" + k1, " k2:" + k2})
+	require.NoError(t, err)
+	assert.Equal(t, "k2", keyring.PrimaryKeyID())
+
+	_, err = ParseKeyring("k3", []string{"k1:" + k1})
+	assert.Error(t, err)
+
+	_, err = ParseKeyring("k1", []string{k1})
+	assert.Error(t, err)
+
+	_, err = ParseKeyring("k1", []string{"k1:" + base64.StdEncoding.EncodeToString([]byte("short"))})
+	assert.Error(t, err)
+}
+
+// Helper functions
+
+func createTestKeyring(t *testing.T, keyID string) *Keyring {
+	keyring, err := NewKeyring(keyID, testKey('1'))
+	require.NoError(t, err)
+	return keyring
+}
+
+func testKey(fill byte) []byte {
+	return bytes.Repeat([]byte{fill}, 32)
+}
@@ -0,0 +1,82 @@
+package privacy
+
+import (
+	"fmt"
+
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// RedactingLogger wraps a logger and redacts personal data from every message
+// and field before it is written
+type RedactingLogger struct {
+	next     interfaces.Logger
+	redactor *Redactor
+}
+
+// NewRedactingLogger wraps a logger with redaction
+func NewRedactingLogger(next interfaces.Logger, redactor *Redactor) *RedactingLogger {
+	return &RedactingLogger{next: next, redactor: redactor}
+}
+
+func (l *RedactingLogger) Debug(args ...interface{}) {
+	l.next.Debug(l.redactor.Redact(fmt.Sprint(args...)))
+}
+
+func (l *RedactingLogger) Info(args ...interface{}) {
+	l.next.Info(l.redactor.Redact(fmt.Sprint(args...)))
+}
+
+func (l *RedactingLogger) Warn(args ...interface{}) {
+	l.next.Warn(l.redactor.Redact(fmt.Sprint(args...)))
+}
+
+func (l *RedactingLogger) Error(args ...interface{}) {
+	l.next.Error(l.redactor.Redact(fmt.Sprint(args...)))
+}
+
+func (l *RedactingLogger) Debugf(format string, args ...interface{}) {
+	l.next.Debug(l.redactor.Redact(fmt.Sprintf(format, args...)))
+}
+
+func (l *RedactingLogger) Infof(format string, args ...interface{}) {
+	l.next.Info(l.redactor.Redact(fmt.Sprintf(format, args...)))
+}
+
+func (l *RedactingLogger) Warnf(format string, args ...interface{}) {
+	l.next.Warn(l.redactor.Redact(fmt.Sprintf(format, args...)))
+}
+
+func (l *RedactingLogger) Errorf(format string, args ...interface{}) {
+	l.next.Error(l.redactor.Redact(fmt.Sprintf(format, args...)))
+}
+
+func (l *RedactingLogger) WithField(key string, value interface{}) interfaces.Logger {
+	return &RedactingLogger{
+		next:     l.next.WithField(key, l.redactValue(value)),
+		redactor: l.redactor,
+	}
+}
+
+func (l *RedactingLogger) WithFields(fields map[string]interface{}) interfaces.Logger {
+	redacted := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		redacted[key] = l.redactValue(value)
+	}
+
+	return &RedactingLogger{
+		next:     l.next.WithFields(redacted),
+		redactor: l.redactor,
+	}
+}
+
+// redactValue redacts string field values
+func (l *RedactingLogger) redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return l.redactor.Redact(v)
+	case fmt.Stringer:
+		return l.redactor.Redact(v.String())
+	default:
+		return value
+	}
+}
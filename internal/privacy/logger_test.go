@@ -0,0 +1,83 @@
+package privacy
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+func TestRedactingLogger_RedactsMessages(t *testing.T) {
+	captured := &captureLogger{}
+	logger := NewRedactingLogger(captured, NewRedactor(LevelPartial))
+
+	logger.Infof("Sending email to %s", "jane@example.com")
+	logger.Error("call to ", "+15551234567", " failed")
+	logger.Debugf("100%% done for %d recipients", 3)
+
+	assert.Equal(t, []string{
+		"Sending email to j***@example.com",
+		"call to +*******4567 failed",
+		"100% done for 3 recipients",
+	}, captured.lines)
+}
+
+func TestRedactingLogger_RedactsFields(t *testing.T) {
+	captured := &captureLogger{}
+	logger := NewRedactingLogger(captured, NewRedactor(LevelStrict))
+
+	logger.WithField("recipient", "jane@example.com").
+		WithFields(map[string]interface{}{"attempt": 2, "phone": "+15551234567"}).
+		Warn("retrying")
+
+	assert.Equal(t, "[redacted-email]", captured.fields["recipient"])
+	assert.Equal(t, "[redacted-phone]", captured.fields["phone"])
+	assert.Equal(t, 2, captured.fields["attempt"])
+	assert.Equal(t, []string{"retrying"}, captured.lines)
+}
+
+// Helper functions
+
+// captureLogger records log lines and fields. Derived loggers share their parent's storage.
+type captureLogger struct {
+	lines  []string
+	fields map[string]interface{}
+}
+
+func (l *captureLogger) Debug(args ...interface{}) { l.log(args...) }
+func (l *captureLogger) Info(args ...interface{})  { l.log(args...) }
+func (l *captureLogger) Warn(args ...interface{})  { l.log(args...) }
+func (l *captureLogger) Error(args ...interface{}) { l.log(args...) }
+
+func (l *captureLogger) Debugf(format string, args ...interface{}) {
+	l.log(fmt.Sprintf(format, args...))
+}
+func (l *captureLogger) Infof(format string, args ...interface{}) {
+	l.log(fmt.Sprintf(format, args...))
+}
+func (l *captureLogger) Warnf(format string, args ...interface{}) {
+	l.log(fmt.Sprintf(format, args...))
+}
+func (l *captureLogger) Errorf(format string, args ...interface{}) {
+	l.log(fmt.Sprintf(format, args...))
+}
+
+func (l *captureLogger) WithField(key string, value interface{}) interfaces.Logger {
+	return l.WithFields(map[string]interface{}{key: value})
+}
+
+func (l *captureLogger) WithFields(fields map[string]interface{}) interfaces.Logger {
+	if l.fields == nil {
+		l.fields = make(map[string]interface{})
+	}
+	for key, value := range fields {
+		l.fields[key] = value
+	}
+	return l
+}
+
+func (l *captureLogger) log(args ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprint(args...))
+}
@@ -0,0 +1,176 @@
+// Package privacy masks personal data (email addresses, phone numbers and
+// device tokens) in logs and encrypts recipients stored at rest.
+package privacy
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+)
+
+// Level controls how aggressively personal data is redacted
+type Level string
+
+const (
+	// LevelOff leaves values unchanged
+	LevelOff Level = "off"
+	// LevelPartial keeps enough of a value to correlate log lines, such as
+	// the email domain or the last four digits of a phone number
+	LevelPartial Level = "partial"
+	// LevelStrict replaces values entirely
+	LevelStrict Level = "strict"
+)
+
+// Placeholders used by strict redaction
+const (
+	redactedEmail = "[redacted-email]"
+	redactedPhone = "[redacted-phone]"
+	redactedToken = "[redacted-token]"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	// International numbers with a leading +, or long runs of digits
+	phonePattern = regexp.MustCompile(`\+\d[\d ().-]{5,18}\d|\b\d{10,15}\b`)
+	// Device tokens: long unbroken alphanumeric strings (UUIDs are broken by hyphens)
+	tokenPattern = regexp.MustCompile(`\b[A-Za-z0-9_:]{32,}\b`)
+)
+
+// ParseLevel parses a redaction level, defaulting to partial for unknown values
+func ParseLevel(level string) Level {
+	switch Level(strings.ToLower(strings.TrimSpace(level))) {
+	case LevelOff:
+		return LevelOff
+	case LevelStrict:
+		return LevelStrict
+	default:
+		return LevelPartial
+	}
+}
+
+// Redactor masks personal data at a configured level
+type Redactor struct {
+	level Level
+}
+
+// NewRedactor creates a new redactor
+func NewRedactor(level Level) *Redactor {
+	return &Redactor{level: level}
+}
+
+// Level returns the redaction level
+func (r *Redactor) Level() Level {
+	return r.level
+}
+
+// MaskEmail masks an email address, keeping its first character and domain
+func (r *Redactor) MaskEmail(email string) string {
+	switch r.level {
+	case LevelOff:
+		return email
+	case LevelStrict:
+		return redactedEmail
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return maskAll(email)
+	}
+	return email[:1] + "***" + email[at:]
+}
+
+// MaskPhone masks a phone number, keeping its last four digits
+func (r *Redactor) MaskPhone(phone string) string {
+	switch r.level {
+	case LevelOff:
+		return phone
+	case LevelStrict:
+		return redactedPhone
+	}
+
+	digits := 0
+	for _, c := range phone {
+		if c >= '0' && c <= '9' {
+			digits++
+		}
+	}
+
+	var masked strings.Builder
+	seen := 0
+	for _, c := range phone {
+		if c >= '0' && c <= '9' {
+			seen++
+			if seen <= digits-4 {
+				masked.WriteByte('*')
+				continue
+			}
+		}
+		masked.WriteRune(c)
+	}
+	return masked.String()
+}
+
+// MaskToken masks a device token, keeping its first eight characters
+func (r *Redactor) MaskToken(token string) string {
+	switch r.level {
+	case LevelOff:
+		return token
+	case LevelStrict:
+		return redactedToken
+	}
+
+	if len(token) <= 8 {
+		return token
+	}
+	return token[:8] + "..."
+}
+
+// MaskRecipient masks a recipient according to the channel it belongs to
+func (r *Redactor) MaskRecipient(notificationType models.NotificationType, recipient string) string {
+	switch notificationType {
+	case models.NotificationTypeEmail:
+		return r.MaskEmail(recipient)
+	case models.NotificationTypeSMS, models.NotificationTypeVoice:
+		return r.MaskPhone(recipient)
+	case models.NotificationTypePush:
+		return r.MaskToken(recipient)
+	default:
+		return recipient
+	}
+}
+
+// Redact masks every email address, phone number and device token found in free text
+func (r *Redactor) Redact(text string) string {
+	if r.level == LevelOff {
+		return text
+	}
+
+	text = emailPattern.ReplaceAllStringFunc(text, r.MaskEmail)
+	text = tokenPattern.ReplaceAllStringFunc(text, r.MaskToken)
+	text = phonePattern.ReplaceAllStringFunc(text, r.MaskPhone)
+	return text
+}
+
+// defaultRedactor is used by the package-level helpers
+var defaultRedactor = NewRedactor(LevelPartial)
+
+// MaskEmail masks an email address at the partial level
+func MaskEmail(email string) string {
+	return defaultRedactor.MaskEmail(email)
+}
+
+// MaskPhone masks a phone number at the partial level
+func MaskPhone(phone string) string {
+	return defaultRedactor.MaskPhone(phone)
+}
+
+// MaskToken masks a device token at the partial level
+func MaskToken(token string) string {
+	return defaultRedactor.MaskToken(token)
+}
+
+// maskAll replaces every character of a value
+func maskAll(value string) string {
+	return strings.Repeat("*", len(value))
+}
@@ -0,0 +1,67 @@
+package privacy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+)
+
+func TestParseLevel(t *testing.T) {
+	assert.Equal(t, LevelOff, ParseLevel("off"))
+	assert.Equal(t, LevelStrict, ParseLevel(" STRICT "))
+	assert.Equal(t, LevelPartial, ParseLevel("partial"))
+	assert.Equal(t, LevelPartial, ParseLevel("unknown"))
+	assert.Equal(t, LevelPartial, ParseLevel(""))
+}
+
+func TestRedactor_MaskValues(t *testing.T) {
+	token := "abcdefgh1234567890abcdefgh1234567890"
+
+	tests := []struct {
+		level Level
+		email string
+		phone string
+		token string
+	}{
+		{LevelOff, "jane.doe@example.com", "+1 555-123-4567", token},
+		{LevelPartial, "j***@example.com", "+* ***-***-4567", "abcdefgh..."},
+		{LevelStrict, "[redacted-email]", "[redacted-phone]", "[redacted-token]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.level), func(t *testing.T) {
+			redactor := NewRedactor(tt.level)
+			assert.Equal(t, tt.email, redactor.MaskEmail("jane.doe@example.com"))
+			assert.Equal(t, tt.phone, redactor.MaskPhone("+1 555-123-4567"))
+			assert.Equal(t, tt.token, redactor.MaskToken(token))
+		})
+	}
+}
+
+func TestRedactor_MaskRecipient(t *testing.T) {
+	redactor := NewRedactor(LevelPartial)
+
+	assert.Equal(t, "j***@example.com", redactor.MaskRecipient(models.NotificationTypeEmail, "jane@example.com"))
+	assert.Equal(t, "+*******4567", redactor.MaskRecipient(models.NotificationTypeSMS, "+15551234567"))
+	assert.Equal(t, "abcdefgh...", redactor.MaskRecipient(models.NotificationTypePush, "abcdefghijklmnop"))
+	assert.Equal(t, "https://hooks.example.com/x", redactor.MaskRecipient(models.NotificationTypeChat, "https://hooks.example.com/x"))
+	assert.Equal(t, "***", redactor.MaskEmail("bad"))
+}
+
+func TestRedactor_Redact(t *testing.T) {
+	redactor := NewRedactor(LevelPartial)
+
+	text := "Sending to jane@example.com and +15551234567 via token abcdefgh1234567890abcdefgh1234567890"
+	redacted := redactor.Redact(text)
+
+	assert.Equal(t, "Sending to j***@example.com and +*******4567 via token abcdefgh...", redacted)
+
+	// Notification IDs and short numbers are left alone
+	id := "Email sent successfully with ID: 1b4e28ba-2fa1-11d2-883f-0016d3cca427 after 3 attempts"
+	assert.Equal(t, id, redactor.Redact(id))
+
+	assert.Equal(t, text, NewRedactor(LevelOff).Redact(text))
+	assert.Equal(t, "Sending to [redacted-email] and [redacted-phone] via token [redacted-token]", NewRedactor(LevelStrict).Redact(text))
+}
@@ -0,0 +1,571 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/httpclient"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/requestlog"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// Supported chat platforms
+const (
+	ChatPlatformSlack = "slack"
+	ChatPlatformTeams = "teams"
+)
+
+// maxChatResponseBody limits how much of a webhook error response is
+// drained so the connection can be reused
+const maxChatResponseBody = 1024
+
+// ChatWebhookProvider implements the ChatProvider interface for Slack incoming
+// webhooks and Microsoft Teams connectors
+type ChatWebhookProvider struct {
+	name      string
+	platform  string
+	config    config.ChatProviderConfig
+	client    *http.Client
+	mu        sync.RWMutex
+	templates map[string]*ChatTemplate
+}
+
+// ChatTemplate represents a chat message template. Payload holds a JSON
+// document with {{variable}} placeholders: an array of Block Kit blocks for
+// Slack or an Adaptive Card for Teams.
+type ChatTemplate struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	Title     string            `json:"title,omitempty"`
+	Text      string            `json:"text"`
+	Payload   string            `json:"payload,omitempty"`
+	Variables []string          `json:"variables"`
+	Category  string            `json:"category"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+// NewSlackWebhookProvider creates a provider that posts to Slack incoming webhooks
+func NewSlackWebhookProvider(cfg config.ChatProviderConfig) *ChatWebhookProvider {
+	return newChatWebhookProvider("slack-webhook", ChatPlatformSlack, cfg)
+}
+
+// NewTeamsWebhookProvider creates a provider that posts to Microsoft Teams connectors
+func NewTeamsWebhookProvider(cfg config.ChatProviderConfig) *ChatWebhookProvider {
+	return newChatWebhookProvider("teams-webhook", ChatPlatformTeams, cfg)
+}
+
+// newChatWebhookProvider creates a chat webhook provider for a platform
+func newChatWebhookProvider(name, platform string, cfg config.ChatProviderConfig) *ChatWebhookProvider {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	// Webhook URLs come from requests, so the client never connects to
+	// internal addresses
+	httpConfig := cfg.HTTP
+	httpConfig.PublicOnly = !cfg.AllowPrivateWebhooks
+
+	provider := &ChatWebhookProvider{
+		name:      name,
+		platform:  platform,
+		config:    cfg,
+		client:    httpclient.Shared.Client(httpConfig, timeout),
+		templates: make(map[string]*ChatTemplate),
+	}
+
+	provider.loadDefaultTemplates()
+
+	return provider
+}
+
+// Send implements the NotificationProvider interface
+func (p *ChatWebhookProvider) Send(ctx context.Context, notification *models.Notification) (*models.NotificationResponse, error) {
+	chatNotification, err := p.convertToChatNotification(notification)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.SendChat(ctx, chatNotification)
+}
+
+// SendChat implements the ChatProvider interface
+func (p *ChatWebhookProvider) SendChat(ctx context.Context, chat *models.ChatNotification) (*models.NotificationResponse, error) {
+	if chat.WebhookURL == "" {
+		chat.WebhookURL = p.config.WebhookURL
+	}
+	if chat.Platform == "" {
+		chat.Platform = p.platform
+	}
+
+	if err := p.validateChatNotification(chat); err != nil {
+		return nil, err
+	}
+
+	payload, err := p.BuildPayload(chat)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to encode chat payload", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, chat.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.NewInternalError("failed to create webhook request", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if chat.CorrelationID != "" {
+		req.Header.Set(requestlog.HeaderCorrelationID, chat.CorrelationID)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, errors.NewNotificationError(errors.ErrorCodeTimeout, "chat message sending timed out")
+		}
+		return nil, errors.NewProviderError(p.name, errors.ErrorCodeProviderUnavailable, "webhook request failed").WithCause(err)
+	}
+	defer resp.Body.Close()
+
+	if err := p.checkResponse(resp); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	response := &models.NotificationResponse{
+		ID:         chat.ID,
+		Status:     models.StatusSent,
+		Message:    fmt.Sprintf("Chat message posted to %s", p.platform),
+		ProviderID: fmt.Sprintf("%s-%s", p.platform, chat.ID.String()),
+		SentAt:     &now,
+
+		ProviderMetadata: map[string]string{
+			"provider": p.name,
+			"platform": p.platform,
+		},
+	}
+
+	return response, nil
+}
+
+// ValidateWebhookURL implements the ChatProvider interface. The host must
+// be in the configured allowlist, or Slack's or Teams' webhook host.
+func (p *ChatWebhookProvider) ValidateWebhookURL(webhookURL string) error {
+	return utils.ValidateWebhookTarget(webhookURL, p.config.AllowedHosts, p.config.AllowPrivateWebhooks)
+}
+
+// GetType implements the NotificationProvider interface
+func (p *ChatWebhookProvider) GetType() models.NotificationType {
+	return models.NotificationTypeChat
+}
+
+// IsHealthy implements the NotificationProvider interface. Webhooks have no
+// health endpoint, so only the configured default webhook is checked.
+func (p *ChatWebhookProvider) IsHealthy(ctx context.Context) error {
+	if p.config.WebhookURL == "" {
+		return nil
+	}
+
+	if err := p.ValidateWebhookURL(p.config.WebhookURL); err != nil {
+		return errors.NewProviderError(p.name, errors.ErrorCodeProviderConfiguration, "default webhook URL is invalid")
+	}
+
+	return nil
+}
+
+// GetConfig implements the NotificationProvider interface
+func (p *ChatWebhookProvider) GetConfig() interfaces.ProviderConfig {
+	name := "Slack Webhook Provider"
+	if p.platform == ChatPlatformTeams {
+		name = "Teams Webhook Provider"
+	}
+
+	return interfaces.ProviderConfig{
+		Name:       name,
+		Type:       models.NotificationTypeChat,
+		Enabled:    p.config.Enabled,
+		Priority:   4,
+		MaxRetries: 3,
+		Timeout:    int(p.client.Timeout.Seconds()),
+		RateLimit: interfaces.RateLimitConfig{
+			Enabled:        true,
+			RequestsPerMin: 60,
+			BurstSize:      5,
+		},
+		Settings: map[string]string{
+			"provider_type": p.name,
+			"version":       "1.0.0",
+			"features":      "templates,text,blocks,cards",
+			"platform":      p.platform,
+		},
+	}
+}
+
+// GetPlatform returns the chat platform the provider posts to
+func (p *ChatWebhookProvider) GetPlatform() string {
+	return p.platform
+}
+
+// GetTemplate retrieves a chat template by ID
+func (p *ChatWebhookProvider) GetTemplate(templateID string) (*ChatTemplate, error) {
+	p.mu.RLock()
+	template, exists := p.templates[templateID]
+	p.mu.RUnlock()
+
+	if !exists {
+		return nil, errors.NewNotificationError(errors.ErrorCodeTemplateNotFound, fmt.Sprintf("template not found: %s", templateID))
+	}
+	return template, nil
+}
+
+// Templates returns the chat templates, sorted by ID
+func (p *ChatWebhookProvider) Templates() []*ChatTemplate {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return sortedTemplates(p.templates)
+}
+
+// AddTemplate adds a new chat template
+func (p *ChatWebhookProvider) AddTemplate(template *ChatTemplate) error {
+	if err := template.prepare(time.Now()); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.templates[template.ID] = template
+	p.mu.Unlock()
+	return nil
+}
+
+// ReplaceTemplates replaces every chat template at once. Nothing is
+// replaced unless all the templates are valid.
+func (p *ChatWebhookProvider) ReplaceTemplates(templates []*ChatTemplate) error {
+	replaced, err := keyTemplates(templates, func(t *ChatTemplate) string { return t.ID }, (*ChatTemplate).prepare)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.templates = replaced
+	p.mu.Unlock()
+	return nil
+}
+
+// prepare checks a template before it is stored
+func (t *ChatTemplate) prepare(now time.Time) error {
+	if t.Payload != "" && !json.Valid([]byte(t.Payload)) {
+		return errors.NewValidationError("payload", "template payload must be valid JSON")
+	}
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+
+	t.CreatedAt = now
+	t.UpdatedAt = now
+	return nil
+}
+
+// RenderTemplate renders a chat template with provided data
+func (p *ChatWebhookProvider) RenderTemplate(templateID string, data map[string]string) (*ChatTemplate, error) {
+	template, err := p.GetTemplate(templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := &ChatTemplate{
+		ID:        template.ID,
+		Name:      template.Name,
+		Title:     p.replaceVariables(template.Title, data, false),
+		Text:      p.replaceVariables(template.Text, data, false),
+		Payload:   p.replaceVariables(template.Payload, data, true),
+		Variables: template.Variables,
+		Category:  template.Category,
+		CreatedAt: template.CreatedAt,
+		UpdatedAt: template.UpdatedAt,
+		Metadata:  template.Metadata,
+	}
+
+	return rendered, nil
+}
+
+// ApplyTemplatePayload decodes a rendered template payload into the chat
+// notification's blocks (Slack) or card (Teams)
+func (p *ChatWebhookProvider) ApplyTemplatePayload(chat *models.ChatNotification, payload string) error {
+	if payload == "" {
+		return nil
+	}
+
+	var err error
+	switch p.platform {
+	case ChatPlatformSlack:
+		err = json.Unmarshal([]byte(payload), &chat.Blocks)
+	case ChatPlatformTeams:
+		err = json.Unmarshal([]byte(payload), &chat.Card)
+	}
+	if err != nil {
+		return errors.NewValidationError("payload", "rendered template payload is not valid JSON")
+	}
+
+	return nil
+}
+
+// BuildPayload builds the webhook request body for the provider's platform
+func (p *ChatWebhookProvider) BuildPayload(chat *models.ChatNotification) (map[string]interface{}, error) {
+	switch p.platform {
+	case ChatPlatformSlack:
+		return p.buildSlackPayload(chat), nil
+	case ChatPlatformTeams:
+		return p.buildTeamsPayload(chat), nil
+	default:
+		return nil, errors.NewValidationError("platform", fmt.Sprintf("unsupported chat platform: %s", p.platform))
+	}
+}
+
+// buildSlackPayload maps a chat notification to a Slack incoming webhook message
+func (p *ChatWebhookProvider) buildSlackPayload(chat *models.ChatNotification) map[string]interface{} {
+	text := chat.Text
+	if chat.Title != "" {
+		text = fmt.Sprintf("*%s*\n%s", chat.Title, chat.Text)
+	}
+
+	// Text doubles as the notification fallback when blocks are present
+	payload := map[string]interface{}{"text": text}
+	if len(chat.Blocks) > 0 {
+		payload["blocks"] = chat.Blocks
+	}
+
+	if chat.Channel != "" {
+		payload["channel"] = chat.Channel
+	}
+
+	username := chat.Username
+	if username == "" {
+		username = p.config.SlackUsername
+	}
+	if username != "" {
+		payload["username"] = username
+	}
+
+	iconEmoji := chat.IconEmoji
+	if iconEmoji == "" {
+		iconEmoji = p.config.SlackIconEmoji
+	}
+	if iconEmoji != "" {
+		payload["icon_emoji"] = iconEmoji
+	}
+
+	for name, value := range chat.ProviderOptions {
+		if _, ok := slackOptions[name]; ok {
+			payload[name] = value
+		}
+	}
+
+	return payload
+}
+
+// buildTeamsPayload maps a chat notification to a Teams connector message.
+// Adaptive Cards are sent as attachments; plain messages use the MessageCard format.
+func (p *ChatWebhookProvider) buildTeamsPayload(chat *models.ChatNotification) map[string]interface{} {
+	if len(chat.Card) > 0 {
+		return map[string]interface{}{
+			"type": "message",
+			"attachments": []map[string]interface{}{
+				{
+					"contentType": "application/vnd.microsoft.card.adaptive",
+					"content":     chat.Card,
+				},
+			},
+		}
+	}
+
+	summary := chat.Title
+	if summary == "" {
+		summary = utils.TruncateString(chat.Text, 80)
+	}
+
+	payload := map[string]interface{}{
+		"@type":    "MessageCard",
+		"@context": "https://schema.org/extensions",
+		"summary":  summary,
+		"text":     chat.Text,
+	}
+	if chat.Title != "" {
+		payload["title"] = chat.Title
+	}
+
+	return payload
+}
+
+// checkResponse maps a webhook HTTP response to an error
+func (p *ChatWebhookProvider) checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	// The body is not returned: it would hand callers the response of
+	// whatever the webhook URL points at
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxChatResponseBody))
+
+	var err *errors.NotificationError
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return errors.NewRateLimitError(resp.Header.Get("Retry-After")).WithMetadata("provider", p.name)
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		err = errors.NewProviderError(p.name, errors.ErrorCodeInvalidRecipient, "webhook no longer exists")
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		err = errors.NewProviderError(p.name, errors.ErrorCodeProviderAuthentication, "webhook rejected the request")
+	case resp.StatusCode >= 500:
+		err = errors.NewProviderError(p.name, errors.ErrorCodeProviderUnavailable, "chat service unavailable")
+	default:
+		err = errors.NewProviderError(p.name, errors.ErrorCodeDeliveryFailed, "webhook rejected the message")
+	}
+
+	return err.WithMetadata("status_code", fmt.Sprintf("%d", resp.StatusCode))
+}
+
+// convertToChatNotification converts a generic notification to a chat notification
+func (p *ChatWebhookProvider) convertToChatNotification(notification *models.Notification) (*models.ChatNotification, error) {
+	if notification.Type != models.NotificationTypeChat {
+		return nil, errors.NewValidationError("type", "notification type must be chat")
+	}
+
+	chatNotification := &models.ChatNotification{
+		Notification: *notification,
+		Platform:     p.platform,
+		WebhookURL:   utils.RecipientWebhookURL(notification.Recipient),
+		Title:        notification.Subject,
+		Text:         notification.Body,
+	}
+
+	if notification.Metadata != nil {
+		if channel, exists := notification.Metadata["channel"]; exists {
+			chatNotification.Channel = channel
+		}
+	}
+
+	return chatNotification, nil
+}
+
+// validateChatNotification validates a chat notification
+func (p *ChatWebhookProvider) validateChatNotification(chat *models.ChatNotification) error {
+	if err := p.ValidateWebhookURL(chat.WebhookURL); err != nil {
+		return err
+	}
+
+	if chat.Text == "" && len(chat.Blocks) == 0 && len(chat.Card) == 0 {
+		return errors.NewValidationError("text", "chat message must have text, blocks or a card")
+	}
+
+	if p.platform == ChatPlatformTeams && len(chat.Blocks) > 0 {
+		return errors.NewValidationError("blocks", "Teams messages use cards, not Slack blocks")
+	}
+
+	if p.platform == ChatPlatformSlack && len(chat.Card) > 0 {
+		return errors.NewValidationError("card", "Slack messages use blocks, not Teams cards")
+	}
+
+	return p.ValidateProviderOptions(chat.ProviderOptions)
+}
+
+// slackOptions are the provider options of Slack webhooks, sent as the
+// message field of the same name. Teams webhooks take none.
+var slackOptions = OptionSpecs{
+	"unfurl_links": {Kind: OptionBoolean, Description: "Show previews of linked pages"},
+	"unfurl_media": {Kind: OptionBoolean, Description: "Show previews of linked media"},
+	"thread_ts":    {Kind: OptionString, Pattern: regexp.MustCompile(`^\d+\.\d+$`), Description: "Timestamp of the message to reply in the thread of"},
+}
+
+// ValidateProviderOptions implements the OptionsProvider interface
+func (p *ChatWebhookProvider) ValidateProviderOptions(options map[string]any) error {
+	if p.platform != ChatPlatformSlack {
+		if len(options) > 0 {
+			return errors.NewValidationError("provider_options", fmt.Sprintf("provider %s accepts no provider options", p.name))
+		}
+		return nil
+	}
+	return slackOptions.Validate(p.name, options)
+}
+
+// replaceVariables replaces template variables with provided data. Values are
+// JSON-escaped when substituted into a JSON payload.
+func (p *ChatWebhookProvider) replaceVariables(template string, data map[string]string, escapeJSON bool) string {
+	result := template
+	for key, value := range data {
+		if escapeJSON {
+			encoded, _ := json.Marshal(value)
+			value = string(encoded[1 : len(encoded)-1])
+		}
+		placeholder := fmt.Sprintf("{{%s}}", key)
+		result = strings.ReplaceAll(result, placeholder, value)
+	}
+	return result
+}
+
+// loadDefaultTemplates loads default chat templates for the provider's platform
+func (p *ChatWebhookProvider) loadDefaultTemplates() {
+	// Simple text alert template
+	alertTemplate := &ChatTemplate{
+		ID:        "alert",
+		Name:      "Alert",
+		Title:     "{{alert_name}}",
+		Text:      "{{alert_message}}",
+		Variables: []string{"alert_name", "alert_message"},
+		Category:  "monitoring",
+	}
+
+	// Incident template with a rich payload
+	incidentTemplate := &ChatTemplate{
+		ID:        "incident",
+		Name:      "Incident",
+		Title:     "Incident {{incident_id}}",
+		Text:      "Incident {{incident_id}} ({{severity}}): {{summary}}",
+		Variables: []string{"incident_id", "severity", "summary"},
+		Category:  "incident",
+	}
+
+	switch p.platform {
+	case ChatPlatformSlack:
+		incidentTemplate.Payload = `[
+			{"type": "header", "text": {"type": "plain_text", "text": "Incident {{incident_id}}"}},
+			{"type": "section", "fields": [
+				{"type": "mrkdwn", "text": "*Severity:*\n{{severity}}"},
+				{"type": "mrkdwn", "text": "*Summary:*\n{{summary}}"}
+			]}
+		]`
+	case ChatPlatformTeams:
+		incidentTemplate.Payload = `{
+			"type": "AdaptiveCard",
+			"version": "1.4",
+			"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+			"body": [
+				{"type": "TextBlock", "size": "Large", "weight": "Bolder", "text": "Incident {{incident_id}}"},
+				{"type": "FactSet", "facts": [
+					{"title": "Severity", "value": "{{severity}}"},
+					{"title": "Summary", "value": "{{summary}}"}
+				]}
+			]
+		}`
+	}
+
+	p.AddTemplate(alertTemplate)
+	p.AddTemplate(incidentTemplate)
+}
@@ -0,0 +1,275 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestNewChatWebhookProviders(t *testing.T) {
+	slack := NewSlackWebhookProvider(config.ChatProviderConfig{Enabled: true})
+	teams := NewTeamsWebhookProvider(config.ChatProviderConfig{Enabled: true})
+
+	assert.Equal(t, ChatPlatformSlack, slack.GetPlatform())
+	assert.Equal(t, ChatPlatformTeams, teams.GetPlatform())
+	assert.Equal(t, models.NotificationTypeChat, slack.GetType())
+	assert.Equal(t, "Teams Webhook Provider", teams.GetConfig().Name)
+	assert.Len(t, slack.templates, 2)
+}
+
+func TestChatWebhookProvider_SendChat_Slack(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "req-42", r.Header.Get("X-Correlation-ID"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	cfg := createTestChatConfig()
+	cfg.SlackUsername = "notifier"
+	provider := NewSlackWebhookProvider(cfg)
+
+	chat := createTestChatNotification(server.URL)
+	chat.Blocks = []map[string]interface{}{{"type": "divider"}}
+	chat.CorrelationID = "req-42"
+
+	response, err := provider.SendChat(context.Background(), chat)
+
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusSent, response.Status)
+	assert.Equal(t, "*Deploy*\nDeploy finished", received["text"])
+	assert.Equal(t, "notifier", received["username"])
+	assert.Len(t, received["blocks"], 1)
+}
+
+func TestChatWebhookProvider_SendChat_ProviderOptions(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	slack := NewSlackWebhookProvider(createTestChatConfig())
+	chat := createTestChatNotification(server.URL)
+	chat.ProviderOptions = map[string]any{"unfurl_links": false, "thread_ts": "1700000000.000100"}
+
+	_, err := slack.SendChat(context.Background(), chat)
+	require.NoError(t, err)
+	assert.Equal(t, false, received["unfurl_links"])
+	assert.Equal(t, "1700000000.000100", received["thread_ts"])
+
+	// Teams webhooks take no options
+	teams := NewTeamsWebhookProvider(createTestChatConfig())
+	_, err = teams.SendChat(context.Background(), chat)
+	assert.Error(t, err)
+}
+
+func TestChatWebhookProvider_SendChat_Teams(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	provider := NewTeamsWebhookProvider(createTestChatConfig())
+
+	// Plain messages use the MessageCard format
+	_, err := provider.SendChat(context.Background(), createTestChatNotification(server.URL))
+	require.NoError(t, err)
+	assert.Equal(t, "MessageCard", received["@type"])
+	assert.Equal(t, "Deploy", received["title"])
+
+	// Adaptive Cards are wrapped in an attachment
+	chat := createTestChatNotification(server.URL)
+	chat.Card = map[string]interface{}{"type": "AdaptiveCard", "version": "1.4"}
+	_, err = provider.SendChat(context.Background(), chat)
+	require.NoError(t, err)
+
+	attachments := received["attachments"].([]interface{})
+	require.Len(t, attachments, 1)
+	attachment := attachments[0].(map[string]interface{})
+	assert.Equal(t, "application/vnd.microsoft.card.adaptive", attachment["contentType"])
+}
+
+func TestChatWebhookProvider_SendChat_ErrorResponses(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		expectedCode errors.ErrorCode
+	}{
+		{"rate limited", http.StatusTooManyRequests, errors.ErrorCodeRateLimited},
+		{"webhook removed", http.StatusNotFound, errors.ErrorCodeInvalidRecipient},
+		{"forbidden", http.StatusForbidden, errors.ErrorCodeProviderAuthentication},
+		{"bad payload", http.StatusBadRequest, errors.ErrorCodeDeliveryFailed},
+		{"server error", http.StatusInternalServerError, errors.ErrorCodeProviderUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Retry-After", "30")
+				w.WriteHeader(tt.status)
+				w.Write([]byte("invalid_payload"))
+			}))
+			defer server.Close()
+
+			provider := NewSlackWebhookProvider(createTestChatConfig())
+			_, err := provider.SendChat(context.Background(), createTestChatNotification(server.URL))
+			require.Error(t, err)
+
+			notifErr, ok := errors.AsNotificationError(err)
+			require.True(t, ok)
+			assert.Equal(t, tt.expectedCode, notifErr.Code)
+			// The webhook's response never reaches the caller
+			assert.NotContains(t, notifErr.Metadata, "response")
+		})
+	}
+}
+
+func TestChatWebhookProvider_ValidationErrors(t *testing.T) {
+	provider := NewSlackWebhookProvider(config.ChatProviderConfig{})
+	ctx := context.Background()
+
+	noURL := createTestChatNotification("")
+	_, err := provider.SendChat(ctx, noURL)
+	require.Error(t, err)
+
+	badScheme := createTestChatNotification("ftp://hooks.example.com/x")
+	_, err = provider.SendChat(ctx, badScheme)
+	require.Error(t, err)
+
+	empty := createTestChatNotification("https://hooks.slack.com/services/x")
+	empty.Text = ""
+	_, err = provider.SendChat(ctx, empty)
+	require.Error(t, err)
+
+	withCard := createTestChatNotification("https://hooks.slack.com/services/x")
+	withCard.Card = map[string]interface{}{"type": "AdaptiveCard"}
+	_, err = provider.SendChat(ctx, withCard)
+	require.Error(t, err)
+}
+
+func TestChatWebhookProvider_WebhookTargets(t *testing.T) {
+	provider := NewSlackWebhookProvider(config.ChatProviderConfig{})
+
+	assert.NoError(t, provider.ValidateWebhookURL("https://hooks.slack.com/services/T0/B0/X"))
+	assert.NoError(t, provider.ValidateWebhookURL("https://contoso.webhook.office.com/webhookb2/x"))
+	for _, webhookURL := range []string{
+		"http://hooks.slack.com/services/T0/B0/X",
+		"https://webhook.office.com/x",
+		"https://example.com/hook",
+		"https://169.254.169.254/latest/meta-data",
+	} {
+		assert.Error(t, provider.ValidateWebhookURL(webhookURL), webhookURL)
+	}
+
+	// A configured allowlist replaces the defaults, but not the address checks
+	provider = NewSlackWebhookProvider(config.ChatProviderConfig{AllowedHosts: []string{"chat.example.com", "10.0.0.5"}})
+	assert.NoError(t, provider.ValidateWebhookURL("https://chat.example.com/hooks/x"))
+	assert.Error(t, provider.ValidateWebhookURL("https://hooks.slack.com/services/T0/B0/X"))
+	assert.Error(t, provider.ValidateWebhookURL("https://10.0.0.5/hooks/x"))
+}
+
+func TestChatWebhookProvider_RefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached a loopback webhook")
+	}))
+	defer server.Close()
+
+	// The host is allowed, but resolves to a loopback address
+	provider := NewSlackWebhookProvider(config.ChatProviderConfig{AllowedHosts: []string{"localhost"}})
+	chat := createTestChatNotification(strings.Replace(server.URL, "127.0.0.1", "localhost", 1))
+	_, err := provider.SendChat(context.Background(), chat)
+	require.Error(t, err)
+	assert.ErrorContains(t, stderrors.Unwrap(err), "non-public address")
+}
+
+func TestChatWebhookProvider_RenderTemplate(t *testing.T) {
+	provider := NewTeamsWebhookProvider(config.ChatProviderConfig{})
+
+	rendered, err := provider.RenderTemplate("incident", map[string]string{
+		"incident_id": "INC-7",
+		"severity":    "sev1",
+		"summary":     `API "down"`,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Incident INC-7", rendered.Title)
+	assert.Equal(t, `Incident INC-7 (sev1): API "down"`, rendered.Text)
+
+	// Values substituted into the payload are JSON-escaped
+	chat := createTestChatNotification("https://hooks.slack.com/services/x")
+	require.NoError(t, provider.ApplyTemplatePayload(chat, rendered.Payload))
+	assert.Equal(t, "AdaptiveCard", chat.Card["type"])
+
+	_, err = provider.RenderTemplate("missing", nil)
+	require.Error(t, err)
+}
+
+func TestChatWebhookProvider_AddTemplate_InvalidPayload(t *testing.T) {
+	provider := NewSlackWebhookProvider(config.ChatProviderConfig{})
+
+	err := provider.AddTemplate(&ChatTemplate{ID: "broken", Text: "x", Payload: "[{"})
+	require.Error(t, err)
+}
+
+func TestChatWebhookProvider_Send_GenericNotification(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	provider := NewSlackWebhookProvider(createTestChatConfig())
+
+	notification := &models.Notification{
+		ID:        uuid.New(),
+		Type:      models.NotificationTypeChat,
+		Recipient: server.URL,
+		Body:      "Generic chat message",
+	}
+
+	response, err := provider.Send(context.Background(), notification)
+	require.NoError(t, err)
+	assert.Equal(t, notification.ID, response.ID)
+
+	notification.Type = models.NotificationTypeSMS
+	_, err = provider.Send(context.Background(), notification)
+	require.Error(t, err)
+}
+
+// Helper functions
+
+// createTestChatConfig allows the local servers tests send webhooks to
+func createTestChatConfig() config.ChatProviderConfig {
+	return config.ChatProviderConfig{AllowedHosts: []string{"127.0.0.1"}, AllowPrivateWebhooks: true}
+}
+
+func createTestChatNotification(webhookURL string) *models.ChatNotification {
+	return &models.ChatNotification{
+		Notification: models.Notification{
+			ID:       uuid.New(),
+			Type:     models.NotificationTypeChat,
+			Status:   models.StatusPending,
+			Priority: models.PriorityNormal,
+		},
+		WebhookURL: webhookURL,
+		Title:      "Deploy",
+		Text:       "Deploy finished",
+	}
+}
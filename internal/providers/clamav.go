@@ -0,0 +1,106 @@
+package providers
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// clamdChunkSize is the size of the chunks an attachment is streamed in; it
+// must stay below clamd's StreamMaxLength
+const clamdChunkSize = 64 * 1024
+
+// ClamAVScanner scans attachments with a ClamAV clamd server, using the
+// INSTREAM command
+type ClamAVScanner struct {
+	address string
+	dialer  net.Dialer
+}
+
+// NewClamAVScanner creates a scanner for the clamd server at an address, host:port
+func NewClamAVScanner(address string) *ClamAVScanner {
+	return &ClamAVScanner{address: address}
+}
+
+// ScanAttachment implements the AttachmentScanner interface
+func (s *ClamAVScanner) ScanAttachment(ctx context.Context, attachment *models.EmailAttachment) (string, error) {
+	conn, err := s.dialer.DialContext(ctx, "tcp", s.address)
+	if err != nil {
+		return "", errors.NewNotificationErrorWithDetails(errors.ErrorCodeProviderUnavailable, "clamd is unreachable", err.Error())
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if err := writeClamdStream(conn, attachment.Content); err != nil {
+		return "", errors.NewNotificationErrorWithDetails(errors.ErrorCodeProviderUnavailable, "failed to send the attachment to clamd", err.Error())
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil {
+		return "", errors.NewNotificationErrorWithDetails(errors.ErrorCodeProviderUnavailable, "failed to read the clamd response", err.Error())
+	}
+	threat, err := parseClamdReply(strings.TrimRight(reply, "\x00\n"))
+	if err != nil {
+		return "", errors.NewNotificationErrorWithDetails(errors.ErrorCodeProviderUnavailable,
+			fmt.Sprintf("clamd could not scan attachment %s", attachment.Filename), err.Error())
+	}
+	return threat, nil
+}
+
+// writeClamdStream sends the INSTREAM command and the content in
+// length-prefixed chunks, ended by a zero-length chunk
+func writeClamdStream(conn net.Conn, content []byte) error {
+	writer := bufio.NewWriter(conn)
+	if _, err := writer.WriteString("zINSTREAM\x00"); err != nil {
+		return err
+	}
+
+	var size [4]byte
+	for len(content) > 0 {
+		chunk := content
+		if len(chunk) > clamdChunkSize {
+			chunk = chunk[:clamdChunkSize]
+		}
+		binary.BigEndian.PutUint32(size[:], uint32(len(chunk)))
+		if _, err := writer.Write(size[:]); err != nil {
+			return err
+		}
+		if _, err := writer.Write(chunk); err != nil {
+			return err
+		}
+		content = content[len(chunk):]
+	}
+
+	binary.BigEndian.PutUint32(size[:], 0)
+	if _, err := writer.Write(size[:]); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// parseClamdReply reads a scan reply: "stream: OK", "stream: <threat> FOUND"
+// or a message ending in "ERROR"
+func parseClamdReply(reply string) (string, error) {
+	result := reply
+	if _, after, found := strings.Cut(reply, ": "); found {
+		result = after
+	}
+
+	switch {
+	case result == "OK":
+		return "", nil
+	case strings.HasSuffix(result, " FOUND"):
+		return strings.TrimSuffix(result, " FOUND"), nil
+	default:
+		return "", fmt.Errorf("unexpected reply %q", reply)
+	}
+}
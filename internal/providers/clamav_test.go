@@ -0,0 +1,119 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// eicar is the EICAR anti-virus test file
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+func TestClamAVScanner_ScanAttachment(t *testing.T) {
+	address := startTestClamd(t, func(command string, content []byte) string {
+		if bytes.Contains(content, []byte("EICAR-STANDARD-ANTIVIRUS-TEST-FILE")) {
+			return "stream: Eicar-Test-Signature FOUND"
+		}
+		return "stream: OK"
+	})
+	scanner := NewClamAVScanner(address)
+	ctx := context.Background()
+
+	threat, err := scanner.ScanAttachment(ctx, &models.EmailAttachment{Filename: "eicar.com", Content: []byte(eicar)})
+	require.NoError(t, err)
+	assert.Equal(t, "Eicar-Test-Signature", threat)
+
+	// Content larger than a chunk is streamed in several
+	large := bytes.Repeat([]byte("clean "), clamdChunkSize)
+	threat, err = scanner.ScanAttachment(ctx, &models.EmailAttachment{Filename: "report.txt", Content: large})
+	require.NoError(t, err)
+	assert.Empty(t, threat)
+}
+
+func TestClamAVScanner_Errors(t *testing.T) {
+	address := startTestClamd(t, func(string, []byte) string { return "INSTREAM size limit exceeded. ERROR" })
+	_, err := NewClamAVScanner(address).ScanAttachment(context.Background(), &models.EmailAttachment{Filename: "big.zip", Content: []byte("data")})
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeProviderUnavailable, notifErr.Code)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address = listener.Addr().String()
+	listener.Close()
+	_, err = NewClamAVScanner(address).ScanAttachment(context.Background(), &models.EmailAttachment{Content: []byte("data")})
+	assert.Error(t, err)
+}
+
+func TestParseClamdReply(t *testing.T) {
+	threat, err := parseClamdReply("stream: OK")
+	require.NoError(t, err)
+	assert.Empty(t, threat)
+
+	threat, err = parseClamdReply("stream: Win.Test.EICAR_HDB-1 FOUND")
+	require.NoError(t, err)
+	assert.Equal(t, "Win.Test.EICAR_HDB-1", threat)
+
+	_, err = parseClamdReply("stream: Can't allocate memory ERROR")
+	assert.Error(t, err)
+}
+
+// Helper functions
+
+// startTestClamd serves the clamd INSTREAM command on a loopback port,
+// answering each stream with the respond function's reply
+func startTestClamd(t *testing.T, respond func(command string, content []byte) string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+				reader := bufio.NewReader(conn)
+				command, err := reader.ReadString(0)
+				if err != nil {
+					return
+				}
+				var content []byte
+				for {
+					var size [4]byte
+					if _, err := io.ReadFull(reader, size[:]); err != nil {
+						return
+					}
+					length := binary.BigEndian.Uint32(size[:])
+					if length == 0 {
+						break
+					}
+					chunk := make([]byte, length)
+					if _, err := io.ReadFull(reader, chunk); err != nil {
+						return
+					}
+					content = append(content, chunk...)
+				}
+
+				io.WriteString(conn, respond(command, content)+"\x00")
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
@@ -0,0 +1,90 @@
+package providers_test
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers/providertest"
+	"github.com/nareshkumar-microsoft/notificationService/internal/smtpsink"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+func TestMockEmailProvider_Conformance(t *testing.T) {
+	providertest.TestEmailProvider(t, providertest.EmailSuite{
+		NewProvider: func(t *testing.T) interfaces.EmailProvider {
+			provider := providers.NewMockEmailProvider(config.EmailProviderConfig{Provider: "mock", Enabled: true})
+			provider.SetSimulation(providers.Simulation{})
+			return provider
+		},
+		SetHealthy: func(provider interfaces.EmailProvider, healthy bool) {
+			provider.(*providers.MockEmailProvider).SetHealthy(healthy)
+		},
+	})
+}
+
+func TestSMTPEmailProvider_Conformance(t *testing.T) {
+	providertest.TestEmailProvider(t, providertest.EmailSuite{
+		NewProvider: func(t *testing.T) interfaces.EmailProvider {
+			sink := smtpsink.NewServer(config.SMTPSinkConfig{Addr: "127.0.0.1:0"}, utils.NewSimpleLogger("error"))
+			if err := sink.Start(context.Background()); err != nil {
+				t.Fatalf("failed to start the SMTP sink: %v", err)
+			}
+			t.Cleanup(sink.Stop)
+
+			host, port, _ := net.SplitHostPort(sink.Addr())
+			portNumber, _ := strconv.Atoi(port)
+			return providers.NewSMTPEmailProvider(config.EmailProviderConfig{Provider: "smtp", Enabled: true, SMTPHost: host, SMTPPort: portNumber})
+		},
+	})
+}
+
+func TestMockSMSProvider_Conformance(t *testing.T) {
+	providertest.TestSMSProvider(t, providertest.SMSSuite{
+		NewProvider: func(t *testing.T) interfaces.SMSProvider {
+			provider := providers.NewMockSMSProvider(config.SMSProviderConfig{Provider: "mock", Enabled: true})
+			provider.SetSimulation(providers.Simulation{})
+			return provider
+		},
+		SetHealthy: func(provider interfaces.SMSProvider, healthy bool) {
+			provider.(*providers.MockSMSProvider).SetHealthy(healthy)
+		},
+	})
+}
+
+func TestCountryRoutedSMSProvider_Conformance(t *testing.T) {
+	providertest.TestSMSProvider(t, providertest.SMSSuite{
+		NewProvider: func(t *testing.T) interfaces.SMSProvider {
+			provider, err := providers.NewSMSProvider(config.SMSProviderConfig{
+				Provider:      "mock",
+				Enabled:       true,
+				CountryRoutes: map[string][]string{"*": {"mock"}},
+			})
+			if err != nil {
+				t.Fatalf("failed to create the routed provider: %v", err)
+			}
+			provider.(*providers.CountryRoutedSMSProvider).Unwrap().(*providers.MockSMSProvider).SetSimulation(providers.Simulation{})
+			return provider
+		},
+		SetHealthy: func(provider interfaces.SMSProvider, healthy bool) {
+			provider.(*providers.CountryRoutedSMSProvider).Unwrap().(*providers.MockSMSProvider).SetHealthy(healthy)
+		},
+	})
+}
+
+func TestMockPushProvider_Conformance(t *testing.T) {
+	providertest.TestPushProvider(t, providertest.PushSuite{
+		NewProvider: func(t *testing.T) interfaces.PushProvider {
+			provider := providers.NewMockPushProvider(config.PushProviderConfig{Provider: "mock", Enabled: true})
+			provider.SetSimulation(providers.Simulation{})
+			return provider
+		},
+		SetHealthy: func(provider interfaces.PushProvider, healthy bool) {
+			provider.(*providers.MockPushProvider).SetHealthy(healthy)
+		},
+	})
+}
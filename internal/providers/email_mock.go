@@ -3,53 +3,88 @@ package providers
 import (
 	"context"
 	"fmt"
-	"strings"
+	"maps"
+	"math"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/layout"
+	"github.com/nareshkumar-microsoft/notificationService/internal/mjml"
 	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/templateschema"
+	"github.com/nareshkumar-microsoft/notificationService/internal/templatevars"
 	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
 	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
 	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
 )
 
-// MockEmailProvider implements the EmailProvider interface for testing and development
+// MockEmailProvider implements the EmailProvider interface for testing and
+// development. It is safe for concurrent use.
 type MockEmailProvider struct {
 	config     config.EmailProviderConfig
+	layouts    *layout.Library
+	compiler   *mjml.Compiler
+	sentEmails *SentLog[SentEmail]
+	sim        *simulator
+
+	mu         sync.RWMutex
 	templates  map[string]*EmailTemplate
-	sentEmails []SentEmail
+	compiled   *templatevars.Cache // compiled template fields
+	batchCalls int
 	healthy    bool
 }
 
+// maxEmailBatchSize is the number of personalizations one SendGrid request may carry
+const maxEmailBatchSize = 1000
+
 // EmailTemplate represents an email template
 type EmailTemplate struct {
-	ID        string            `json:"id"`
-	Name      string            `json:"name"`
-	Subject   string            `json:"subject"`
-	HTMLBody  string            `json:"html_body"`
-	TextBody  string            `json:"text_body"`
-	Variables []string          `json:"variables"`
-	Category  string            `json:"category"`
-	CreatedAt time.Time         `json:"created_at"`
-	UpdatedAt time.Time         `json:"updated_at"`
-	Metadata  map[string]string `json:"metadata,omitempty"`
+	ID        string                `json:"id"`
+	Name      string                `json:"name"`
+	Subject   string                `json:"subject"`
+	HTMLBody  string                `json:"html_body"`
+	TextBody  string                `json:"text_body"`
+	Variables []string              `json:"variables"`
+	Category  string                `json:"category"`
+	Format    TemplateFormat        `json:"format,omitempty"`
+	Source    string                `json:"source,omitempty"` // MJML the HTML body was compiled from
+	Schema    templateschema.Schema `json:"schema,omitempty"` // checked against template data before rendering
+	CreatedAt time.Time             `json:"created_at"`
+	UpdatedAt time.Time             `json:"updated_at"`
+	Metadata  map[string]string     `json:"metadata,omitempty"`
 }
 
+// TemplateFormat is the markup an email template's HTML body is authored in
+type TemplateFormat string
+
+const (
+	TemplateFormatHTML TemplateFormat = "html"
+	TemplateFormatMJML TemplateFormat = "mjml"
+)
+
 // SentEmail represents an email that was sent (for mock tracking)
 type SentEmail struct {
-	ID           uuid.UUID         `json:"id"`
-	To           []string          `json:"to"`
-	CC           []string          `json:"cc,omitempty"`
-	BCC          []string          `json:"bcc,omitempty"`
-	From         string            `json:"from"`
-	Subject      string            `json:"subject"`
-	HTMLBody     string            `json:"html_body,omitempty"`
-	TextBody     string            `json:"text_body,omitempty"`
-	Headers      map[string]string `json:"headers,omitempty"`
-	SentAt       time.Time         `json:"sent_at"`
-	Status       string            `json:"status"`
-	ProviderData map[string]string `json:"provider_data,omitempty"`
+	ID           uuid.UUID                `json:"id"`
+	To           []string                 `json:"to"`
+	CC           []string                 `json:"cc,omitempty"`
+	BCC          []string                 `json:"bcc,omitempty"`
+	From         string                   `json:"from"`
+	ReplyTo      string                   `json:"reply_to,omitempty"`
+	Subject      string                   `json:"subject"`
+	HTMLBody     string                   `json:"html_body,omitempty"`
+	TextBody     string                   `json:"text_body,omitempty"`
+	Headers      map[string]string        `json:"headers,omitempty"`
+	MessageID    string                   `json:"message_id,omitempty"`
+	InReplyTo    string                   `json:"in_reply_to,omitempty"`
+	References   []string                 `json:"references,omitempty"`
+	Attachments  []models.EmailAttachment `json:"attachments,omitempty"`
+	SentAt       time.Time                `json:"sent_at"`
+	Status       string                   `json:"status"`
+	ProviderData map[string]string        `json:"provider_data,omitempty"`
+	// ProviderOptions are the request's provider options, as the provider received them
+	ProviderOptions map[string]any `json:"provider_options,omitempty"`
 }
 
 // NewMockEmailProvider creates a new mock email provider
@@ -57,7 +92,11 @@ func NewMockEmailProvider(cfg config.EmailProviderConfig) *MockEmailProvider {
 	provider := &MockEmailProvider{
 		config:     cfg,
 		templates:  make(map[string]*EmailTemplate),
-		sentEmails: make([]SentEmail, 0),
+		compiled:   templatevars.NewCache(0),
+		layouts:    layout.NewLibrary(),
+		compiler:   mjml.NewCompiler(),
+		sentEmails: newSentLog[SentEmail](cfg.SentHistory),
+		sim:        newSimulator("mock-email", defaultEmailSimulation),
 		healthy:    true,
 	}
 
@@ -69,7 +108,7 @@ func NewMockEmailProvider(cfg config.EmailProviderConfig) *MockEmailProvider {
 
 // Send implements the NotificationProvider interface
 func (p *MockEmailProvider) Send(ctx context.Context, notification *models.Notification) (*models.NotificationResponse, error) {
-	if !p.healthy {
+	if !p.isHealthy() {
 		return nil, errors.NewProviderError("mock-email", errors.ErrorCodeProviderUnavailable, "provider is unhealthy")
 	}
 
@@ -84,36 +123,126 @@ func (p *MockEmailProvider) Send(ctx context.Context, notification *models.Notif
 
 // SendEmail implements the EmailProvider interface
 func (p *MockEmailProvider) SendEmail(ctx context.Context, email *models.EmailNotification) (*models.NotificationResponse, error) {
-	if !p.healthy {
+	if !p.isHealthy() {
 		return nil, errors.NewProviderError("mock-email", errors.ErrorCodeProviderUnavailable, "provider is unhealthy")
 	}
 
 	// Validate email
-	if err := p.validateEmailNotification(email); err != nil {
+	if err := validateEmailNotification(email); err != nil {
+		return nil, err
+	}
+	if err := p.ValidateProviderOptions(email.ProviderOptions); err != nil {
 		return nil, err
 	}
 
-	// Simulate processing delay
+	// Simulate processing delay. A cancelled send never reaches the
+	// provider, even when the simulation has no latency.
+	if ctx.Err() != nil {
+		return nil, errors.NewNotificationError(errors.ErrorCodeTimeout, "email sending timed out")
+	}
 	select {
 	case <-ctx.Done():
 		return nil, errors.NewNotificationError(errors.ErrorCodeTimeout, "email sending timed out")
-	case <-time.After(100 * time.Millisecond):
+	case <-time.After(p.sim.latency()):
+		// Continue processing
+	}
+
+	if err := p.sim.fail(emailRecipients(email)...); err != nil {
+		return nil, err
+	}
+
+	return p.recordEmail(email), nil
+}
+
+// SendEmailBatch implements the BatchEmailProvider interface. Like SendGrid
+// personalizations, the batch is one API call; invalid emails fail on their
+// own without failing the others.
+func (p *MockEmailProvider) SendEmailBatch(ctx context.Context, emails []*models.EmailNotification) ([]interfaces.BatchResult, error) {
+	if !p.isHealthy() {
+		return nil, errors.NewProviderError("mock-email", errors.ErrorCodeProviderUnavailable, "provider is unhealthy")
+	}
+	if len(emails) > p.MaxBatchSize() {
+		return nil, errors.NewValidationError("emails", fmt.Sprintf("batch of %d emails exceeds the maximum of %d", len(emails), p.MaxBatchSize()))
+	}
+
+	// Simulate one processing delay for the whole batch
+	select {
+	case <-ctx.Done():
+		return nil, errors.NewNotificationError(errors.ErrorCodeTimeout, "email batch sending timed out")
+	case <-time.After(p.sim.latency()):
 		// Continue processing
 	}
 
+	p.mu.Lock()
+	p.batchCalls++
+	p.mu.Unlock()
+	results := make([]interfaces.BatchResult, len(emails))
+	for i, email := range emails {
+		if err := validateEmailNotification(email); err != nil {
+			results[i].Err = err
+			continue
+		}
+		if err := p.ValidateProviderOptions(email.ProviderOptions); err != nil {
+			results[i].Err = err
+			continue
+		}
+		if err := p.sim.fail(emailRecipients(email)...); err != nil {
+			results[i].Err = err
+			continue
+		}
+		results[i].Response = p.recordEmail(email)
+	}
+	return results, nil
+}
+
+// mockEmailOptions are the provider options of the mock email provider,
+// modelled on SendGrid's
+var mockEmailOptions = OptionSpecs{
+	"categories":   {Kind: OptionStrings, Max: 10, Description: "Categories to group the email's statistics by"},
+	"ip_pool":      {Kind: OptionString, Max: 64, Description: "IP pool to send from"},
+	"asm_group_id": {Kind: OptionInteger, Min: 1, Max: math.MaxInt32, Description: "Unsubscribe group the email belongs to"},
+}
+
+// ValidateProviderOptions implements the OptionsProvider interface
+func (p *MockEmailProvider) ValidateProviderOptions(options map[string]any) error {
+	return mockEmailOptions.Validate("mock-email", options)
+}
+
+// MaxBatchSize implements the BatchEmailProvider interface
+func (p *MockEmailProvider) MaxBatchSize() int {
+	return maxEmailBatchSize
+}
+
+// BatchCalls returns how many batch API calls were made (for testing)
+func (p *MockEmailProvider) BatchCalls() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.batchCalls
+}
+
+// recordEmail stores a sent email and returns the provider's response
+func (p *MockEmailProvider) recordEmail(email *models.EmailNotification) *models.NotificationResponse {
 	// Create sent email record
 	sentEmail := SentEmail{
-		ID:       email.ID,
-		To:       email.To,
-		CC:       email.CC,
-		BCC:      email.BCC,
-		From:     email.From,
-		Subject:  email.Subject,
-		HTMLBody: email.HTMLBody,
-		TextBody: email.TextBody,
-		Headers:  email.Headers,
-		SentAt:   time.Now(),
-		Status:   "sent",
+		ID:          email.ID,
+		To:          email.To,
+		CC:          email.CC,
+		BCC:         email.BCC,
+		From:        email.From,
+		ReplyTo:     email.ReplyTo,
+		Subject:     email.Subject,
+		HTMLBody:    email.HTMLBody,
+		TextBody:    email.TextBody,
+		Headers:     email.Headers,
+		MessageID:   email.MessageID,
+		InReplyTo:   email.InReplyTo,
+		References:  email.References,
+		Attachments: email.Attachments,
+		SentAt:      time.Now(),
+		Status:      "sent",
+
+		ProviderOptions: email.ProviderOptions,
 		ProviderData: map[string]string{
 			"provider":    "mock-email",
 			"message_id":  fmt.Sprintf("mock-%s", email.ID.String()),
@@ -122,8 +251,12 @@ func (p *MockEmailProvider) SendEmail(ctx context.Context, email *models.EmailNo
 		},
 	}
 
+	if email.MessageID != "" {
+		sentEmail.ProviderData["internet_message_id"] = email.MessageID
+	}
+
 	// Store sent email for tracking
-	p.sentEmails = append(p.sentEmails, sentEmail)
+	p.sentEmails.add(sentEmail)
 
 	// Create response
 	now := time.Now()
@@ -133,9 +266,11 @@ func (p *MockEmailProvider) SendEmail(ctx context.Context, email *models.EmailNo
 		Message:    fmt.Sprintf("Email successfully sent to %d recipients", len(email.To)),
 		ProviderID: sentEmail.ProviderData["message_id"],
 		SentAt:     &now,
+
+		ProviderMetadata: maps.Clone(sentEmail.ProviderData),
 	}
 
-	return response, nil
+	return response
 }
 
 // ValidateEmailAddress implements the EmailProvider interface
@@ -145,6 +280,9 @@ func (p *MockEmailProvider) ValidateEmailAddress(email string) error {
 
 // GetEmailTemplates implements the EmailProvider interface
 func (p *MockEmailProvider) GetEmailTemplates() []interfaces.EmailTemplate {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
 	templates := make([]interfaces.EmailTemplate, 0, len(p.templates))
 	for _, template := range p.templates {
 		templates = append(templates, interfaces.EmailTemplate{
@@ -169,7 +307,7 @@ func (p *MockEmailProvider) GetType() models.NotificationType {
 
 // IsHealthy implements the NotificationProvider interface
 func (p *MockEmailProvider) IsHealthy(ctx context.Context) error {
-	if !p.healthy {
+	if !p.isHealthy() {
 		return errors.NewProviderError("mock-email", errors.ErrorCodeProviderUnavailable, "provider is marked as unhealthy")
 	}
 
@@ -206,43 +344,140 @@ func (p *MockEmailProvider) GetConfig() interfaces.ProviderConfig {
 
 // GetTemplate retrieves an email template by ID
 func (p *MockEmailProvider) GetTemplate(templateID string) (*EmailTemplate, error) {
+	p.mu.RLock()
 	template, exists := p.templates[templateID]
+	p.mu.RUnlock()
+
 	if !exists {
 		return nil, errors.NewNotificationError(errors.ErrorCodeTemplateNotFound, fmt.Sprintf("template not found: %s", templateID))
 	}
 	return template, nil
 }
 
-// AddTemplate adds a new email template
+// Templates returns the email templates, sorted by ID
+func (p *MockEmailProvider) Templates() []*EmailTemplate {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return sortedTemplates(p.templates)
+}
+
+// AddTemplate adds a new email template. MJML bodies, marked by the MJML
+// format or detected from the body, are compiled to HTML once here and the
+// MJML is kept in Source.
 func (p *MockEmailProvider) AddTemplate(template *EmailTemplate) error {
+	if err := p.prepareTemplate(template, time.Now()); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.templates[template.ID] = template
+	p.mu.Unlock()
+	return nil
+}
+
+// ReplaceTemplates replaces every email template at once. Nothing is
+// replaced unless all the templates are valid.
+func (p *MockEmailProvider) ReplaceTemplates(templates []*EmailTemplate) error {
+	replaced, err := keyTemplates(templates, func(t *EmailTemplate) string { return t.ID }, p.prepareTemplate)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.templates = replaced
+	p.mu.Unlock()
+	return nil
+}
+
+// prepareTemplate checks a template and compiles its MJML before it is stored
+func (p *MockEmailProvider) prepareTemplate(template *EmailTemplate, now time.Time) error {
+	if err := template.Schema.Check(); err != nil {
+		return err
+	}
+	if template.Format == "" && mjml.IsMJML(template.HTMLBody) {
+		template.Format = TemplateFormatMJML
+	}
+	switch template.Format {
+	case "", TemplateFormatHTML:
+	case TemplateFormatMJML:
+		if template.Source == "" {
+			template.Source = template.HTMLBody
+		}
+		compiled, err := p.compiler.Compile(template.Source)
+		if err != nil {
+			return err
+		}
+		template.HTMLBody = compiled
+	default:
+		return errors.NewValidationError("format", fmt.Sprintf("unknown template format: %s", template.Format))
+	}
+
 	if template.ID == "" {
 		template.ID = uuid.New().String()
 	}
 
-	now := time.Now()
 	template.CreatedAt = now
 	template.UpdatedAt = now
-
-	p.templates[template.ID] = template
 	return nil
 }
 
-// RenderTemplate renders an email template with provided data
+// Layouts returns the layouts, partials and branding templates are composed with
+func (p *MockEmailProvider) Layouts() *layout.Library {
+	return p.layouts
+}
+
+// RenderTemplate renders an email template with provided data and the default branding
 func (p *MockEmailProvider) RenderTemplate(templateID string, data map[string]string) (*EmailTemplate, error) {
+	return p.RenderTenantTemplate("", templateID, data)
+}
+
+// RenderTenantTemplate renders an email template with provided data and a
+// tenant's branding. Layouts and partials are composed before variables are
+// replaced, so they can use the template's variables too.
+func (p *MockEmailProvider) RenderTenantTemplate(tenantID, templateID string, data map[string]string) (*EmailTemplate, error) {
 	template, err := p.GetTemplate(templateID)
 	if err != nil {
 		return nil, err
 	}
+	data = template.Schema.Apply(data)
+	if err := template.Schema.Validate(data); err != nil {
+		return nil, err
+	}
+
+	data = p.layouts.Data(tenantID, data)
+	now := time.Now()
+	render := func(field, source string) (string, error) {
+		composed, err := p.layouts.Compose(source)
+		if err != nil {
+			return "", err
+		}
+		return p.compiled.Get(templateCacheKey(template.ID, template.UpdatedAt, field), composed).Render(data, now), nil
+	}
+
+	subject, err := render("subject", template.Subject)
+	if err != nil {
+		return nil, err
+	}
+	htmlBody, err := render("html_body", template.HTMLBody)
+	if err != nil {
+		return nil, err
+	}
+	textBody, err := render("text_body", template.TextBody)
+	if err != nil {
+		return nil, err
+	}
 
 	// Clone template for rendering
 	rendered := &EmailTemplate{
 		ID:        template.ID,
 		Name:      template.Name,
-		Subject:   p.replaceVariables(template.Subject, data),
-		HTMLBody:  p.replaceVariables(template.HTMLBody, data),
-		TextBody:  p.replaceVariables(template.TextBody, data),
+		Subject:   subject,
+		HTMLBody:  htmlBody,
+		TextBody:  textBody,
 		Variables: template.Variables,
 		Category:  template.Category,
+		Schema:    template.Schema,
 		CreatedAt: template.CreatedAt,
 		UpdatedAt: template.UpdatedAt,
 		Metadata:  template.Metadata,
@@ -251,21 +486,75 @@ func (p *MockEmailProvider) RenderTemplate(templateID string, data map[string]st
 	return rendered, nil
 }
 
-// GetSentEmails returns all sent emails (for testing)
+// GetSentEmails returns the kept sent emails, oldest first (for testing)
 func (p *MockEmailProvider) GetSentEmails() []SentEmail {
+	return p.sentEmails.All()
+}
+
+// SentEmails returns the log of sent emails (for testing)
+func (p *MockEmailProvider) SentEmails() *SentLog[SentEmail] {
 	return p.sentEmails
 }
 
+// LookupAuthoritative implements the AuthoritativeLookupProvider interface:
+// the sent log holds every message as soon as it is sent
+func (p *MockEmailProvider) LookupAuthoritative() bool {
+	return true
+}
+
+// LookupMessage implements the MessageLookupProvider interface
+func (p *MockEmailProvider) LookupMessage(ctx context.Context, notificationID uuid.UUID, providerMessageID string) (*models.NotificationResponse, error) {
+	sent, found := p.sentEmails.last(func(sent SentEmail) bool {
+		return sent.ID == notificationID || (providerMessageID != "" && sent.ProviderData["message_id"] == providerMessageID)
+	})
+	if !found {
+		return nil, errors.NewNotificationError(errors.ErrorCodeNotFound, fmt.Sprintf("no email was sent for notification %s", notificationID))
+	}
+
+	sentAt := sent.SentAt
+	return &models.NotificationResponse{
+		ID:         sent.ID,
+		Status:     models.StatusSent,
+		Message:    "Email was accepted",
+		ProviderID: sent.ProviderData["message_id"],
+		SentAt:     &sentAt,
+
+		ProviderMetadata: maps.Clone(sent.ProviderData),
+	}, nil
+}
+
 // ClearSentEmails clears the sent emails history (for testing)
 func (p *MockEmailProvider) ClearSentEmails() {
-	p.sentEmails = make([]SentEmail, 0)
+	p.sentEmails.Clear()
+}
+
+// SetSimulation replaces the simulated latency, delivery and failures,
+// restarting its random sequence (for testing)
+func (p *MockEmailProvider) SetSimulation(sim Simulation) {
+	p.sim.set(sim)
+}
+
+// Simulation returns the simulated behavior (for testing)
+func (p *MockEmailProvider) Simulation() Simulation {
+	return p.sim.get()
 }
 
 // SetHealthy sets the provider health status (for testing)
 func (p *MockEmailProvider) SetHealthy(healthy bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	p.healthy = healthy
 }
 
+// isHealthy reports whether the provider is set healthy
+func (p *MockEmailProvider) isHealthy() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.healthy
+}
+
 // convertToEmailNotification converts a generic notification to an email notification
 func (p *MockEmailProvider) convertToEmailNotification(notification *models.Notification) (*models.EmailNotification, error) {
 	if notification.Type != models.NotificationTypeEmail {
@@ -283,43 +572,51 @@ func (p *MockEmailProvider) convertToEmailNotification(notification *models.Noti
 	return emailNotification, nil
 }
 
+// emailRecipients returns every recipient of an email
+func emailRecipients(email *models.EmailNotification) []string {
+	recipients := make([]string, 0, len(email.To)+len(email.CC)+len(email.BCC))
+	recipients = append(recipients, email.To...)
+	recipients = append(recipients, email.CC...)
+	return append(recipients, email.BCC...)
+}
+
 // validateEmailNotification validates an email notification
-func (p *MockEmailProvider) validateEmailNotification(email *models.EmailNotification) error {
+func validateEmailNotification(email *models.EmailNotification) error {
 	// Validate To addresses
 	if len(email.To) == 0 {
 		return errors.NewValidationError("to", "at least one recipient is required")
 	}
 
 	for _, addr := range email.To {
-		if err := p.ValidateEmailAddress(addr); err != nil {
+		if err := utils.ValidateEmailAddress(addr); err != nil {
 			return errors.NewValidationError("to", fmt.Sprintf("invalid email address: %s", addr))
 		}
 	}
 
 	// Validate CC addresses
 	for _, addr := range email.CC {
-		if err := p.ValidateEmailAddress(addr); err != nil {
+		if err := utils.ValidateEmailAddress(addr); err != nil {
 			return errors.NewValidationError("cc", fmt.Sprintf("invalid email address: %s", addr))
 		}
 	}
 
 	// Validate BCC addresses
 	for _, addr := range email.BCC {
-		if err := p.ValidateEmailAddress(addr); err != nil {
+		if err := utils.ValidateEmailAddress(addr); err != nil {
 			return errors.NewValidationError("bcc", fmt.Sprintf("invalid email address: %s", addr))
 		}
 	}
 
 	// Validate From address
 	if email.From != "" {
-		if err := p.ValidateEmailAddress(email.From); err != nil {
+		if err := utils.ValidateEmailAddress(email.From); err != nil {
 			return errors.NewValidationError("from", "invalid sender email address")
 		}
 	}
 
 	// Validate ReplyTo address
 	if email.ReplyTo != "" {
-		if err := p.ValidateEmailAddress(email.ReplyTo); err != nil {
+		if err := utils.ValidateEmailAddress(email.ReplyTo); err != nil {
 			return errors.NewValidationError("reply_to", "invalid reply-to email address")
 		}
 	}
@@ -344,16 +641,6 @@ func (p *MockEmailProvider) getDefaultSender() string {
 	return "noreply@notification-service.local"
 }
 
-// replaceVariables replaces template variables with provided data
-func (p *MockEmailProvider) replaceVariables(template string, data map[string]string) string {
-	result := template
-	for key, value := range data {
-		placeholder := fmt.Sprintf("{{%s}}", key)
-		result = strings.ReplaceAll(result, placeholder, value)
-	}
-	return result
-}
-
 // loadDefaultTemplates loads default email templates
 func (p *MockEmailProvider) loadDefaultTemplates() {
 	// Welcome email template
@@ -2,6 +2,8 @@ package providers
 
 import (
 	"context"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -29,7 +31,7 @@ func TestNewMockEmailProvider(t *testing.T) {
 	assert.Equal(t, cfg, provider.config)
 	assert.True(t, provider.healthy)
 	assert.Len(t, provider.templates, 3) // Default templates loaded
-	assert.Empty(t, provider.sentEmails)
+	assert.Empty(t, provider.sentEmails.All())
 }
 
 func TestMockEmailProvider_GetType(t *testing.T) {
@@ -120,6 +122,51 @@ func TestMockEmailProvider_SendEmail_Success(t *testing.T) {
 	assert.Equal(t, email.Subject, sentEmails[0].Subject)
 }
 
+func TestMockEmailProvider_SendEmailBatch(t *testing.T) {
+	provider := createTestEmailProvider()
+
+	valid := createTestEmailNotification()
+	invalid := createTestEmailNotification()
+	invalid.To = []string{"not-an-email"}
+
+	results, err := provider.SendEmailBatch(context.Background(), []*models.EmailNotification{valid, invalid})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.NoError(t, results[0].Err)
+	assert.Equal(t, valid.ID, results[0].Response.ID)
+	assert.Error(t, results[1].Err)
+	assert.Len(t, provider.GetSentEmails(), 1)
+	assert.Equal(t, 1, provider.BatchCalls())
+
+	provider.SetHealthy(false)
+	_, err = provider.SendEmailBatch(context.Background(), []*models.EmailNotification{valid})
+	assert.Error(t, err)
+}
+
+func TestMockEmailProvider_LookupMessage(t *testing.T) {
+	provider := createTestEmailProvider()
+	ctx := context.Background()
+
+	email := createTestEmailNotification()
+	response, err := provider.SendEmail(ctx, email)
+	require.NoError(t, err)
+
+	found, err := provider.LookupMessage(ctx, email.ID, "")
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusSent, found.Status)
+	assert.Equal(t, response.ProviderID, found.ProviderID)
+
+	found, err = provider.LookupMessage(ctx, uuid.New(), response.ProviderID)
+	require.NoError(t, err)
+	assert.Equal(t, email.ID, found.ID)
+
+	_, err = provider.LookupMessage(ctx, uuid.New(), "")
+	require.Error(t, err)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeNotFound, notifErr.Code)
+}
+
 func TestMockEmailProvider_SendEmail_ValidationErrors(t *testing.T) {
 	provider := createTestEmailProvider()
 	ctx := context.Background()
@@ -347,6 +394,69 @@ func TestMockEmailProvider_RenderTemplate(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestMockEmailProvider_RenderTenantTemplate(t *testing.T) {
+	provider := createTestEmailProvider()
+	provider.Layouts().SetBranding("acme", map[string]string{"name": "Acme"})
+	require.NoError(t, provider.Layouts().AddPartial("cta", `<a href="{{cta_url}}">Get started</a>`))
+	require.NoError(t, provider.AddTemplate(&EmailTemplate{
+		ID:       "branded",
+		Name:     "Branded Email",
+		Subject:  "Hello from {{brand.name}}",
+		HTMLBody: `{% extends "base" %}{% block content %}<p>Hi {{user_name}}</p>{% include "cta" %}{% endblock %}`,
+		TextBody: `{% extends "base_text" %}{% block content %}Hi {{user_name}}{% endblock %}`,
+	}))
+
+	data := map[string]string{"user_name": "Jane", "cta_url": "https://acme.example/start"}
+	rendered, err := provider.RenderTenantTemplate("acme", "branded", data)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Hello from Acme", rendered.Subject)
+	assert.Contains(t, rendered.HTMLBody, "<p>Hi Jane</p>")
+	assert.Contains(t, rendered.HTMLBody, `<a href="https://acme.example/start">`)
+	assert.Contains(t, rendered.HTMLBody, "<strong>Acme</strong>")
+	assert.NotContains(t, rendered.HTMLBody, "{{")
+	assert.True(t, strings.HasPrefix(rendered.TextBody, "Hi Jane"))
+	assert.Contains(t, rendered.TextBody, "Acme")
+
+	// Without a tenant the default branding is used
+	rendered, err = provider.RenderTemplate("branded", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello from Notification Service", rendered.Subject)
+
+	// Missing layouts fail the render
+	require.NoError(t, provider.AddTemplate(&EmailTemplate{ID: "orphan", HTMLBody: `{% extends "missing" %}`}))
+	_, err = provider.RenderTemplate("orphan", data)
+	assert.Error(t, err)
+}
+
+func TestMockEmailProvider_AddMJMLTemplate(t *testing.T) {
+	provider := createTestEmailProvider()
+
+	source := `<mjml><mj-body><mj-section><mj-column>
+		<mj-text>Hi {{user_name}}</mj-text>
+		<mj-button href="{{cta_url}}">Open</mj-button>
+	</mj-column></mj-section></mj-body></mjml>`
+	template := &EmailTemplate{ID: "mjml", Subject: "Hi", HTMLBody: source}
+	require.NoError(t, provider.AddTemplate(template))
+
+	assert.Equal(t, TemplateFormatMJML, template.Format)
+	assert.Equal(t, source, template.Source)
+	assert.Contains(t, template.HTMLBody, `class="mj-column"`)
+
+	rendered, err := provider.RenderTemplate("mjml", map[string]string{"user_name": "Jane", "cta_url": "https://example.com"})
+	require.NoError(t, err)
+	assert.Contains(t, rendered.HTMLBody, "Hi Jane")
+	assert.Contains(t, rendered.HTMLBody, `href="https://example.com"`)
+
+	// Invalid MJML is rejected at save time
+	err = provider.AddTemplate(&EmailTemplate{ID: "broken", Format: TemplateFormatMJML, HTMLBody: "<mjml></mjml>"})
+	assert.Error(t, err)
+	_, err = provider.GetTemplate("broken")
+	assert.Error(t, err)
+
+	assert.Error(t, provider.AddTemplate(&EmailTemplate{ID: "markdown", Format: "markdown"}))
+}
+
 func TestMockEmailProvider_ComplexEmail(t *testing.T) {
 	provider := createTestEmailProvider()
 	ctx := context.Background()
@@ -420,6 +530,32 @@ func TestMockEmailProvider_ClearSentEmails(t *testing.T) {
 	assert.Empty(t, provider.GetSentEmails())
 }
 
+func TestMockEmailProvider_ConcurrentUse(t *testing.T) {
+	provider := createTestEmailProvider()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			provider.SendEmail(ctx, createTestEmailNotification())
+			provider.SendEmailBatch(ctx, []*models.EmailNotification{createTestEmailNotification()})
+			provider.AddTemplate(&EmailTemplate{Name: "concurrent", Subject: "Hi", TextBody: "Hi"})
+			provider.GetEmailTemplates()
+			provider.RenderTemplate("welcome", map[string]string{"name": "Ana"})
+			provider.SetHealthy(i%2 == 0)
+			provider.IsHealthy(ctx)
+			provider.BatchCalls()
+			provider.GetSentEmails()
+		}(i)
+	}
+	wg.Wait()
+
+	provider.SetHealthy(true)
+	assert.NoError(t, provider.IsHealthy(ctx))
+}
+
 // Helper functions
 
 func createTestEmailProvider() *MockEmailProvider {
@@ -0,0 +1,62 @@
+package providers
+
+import (
+	"context"
+	"strings"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/attachlink"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// OffloadingEmailProvider replaces an email's large attachments with signed
+// download links before the wrapped provider sends it, keeping the email
+// under the provider's size limit. See attachlink.Offloader.
+type OffloadingEmailProvider struct {
+	interfaces.EmailProvider
+
+	offloader *attachlink.Offloader
+}
+
+// NewOffloadingEmailProvider wraps a provider with an offloader. Batch
+// sends are not offloaded, so the wrapped provider sends one email at a
+// time.
+func NewOffloadingEmailProvider(provider interfaces.EmailProvider, offloader *attachlink.Offloader) *OffloadingEmailProvider {
+	return &OffloadingEmailProvider{EmailProvider: provider, offloader: offloader}
+}
+
+// SendEmail implements the EmailProvider interface. The names of offloaded
+// attachments are in the response's offloaded_attachments provider metadata.
+func (p *OffloadingEmailProvider) SendEmail(ctx context.Context, email *models.EmailNotification) (*models.NotificationResponse, error) {
+	offloaded, links, err := p.offloader.Offload(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	response, err := p.EmailProvider.SendEmail(ctx, offloaded)
+	if err != nil || len(links) == 0 {
+		return response, err
+	}
+
+	names := make([]string, len(links))
+	for i, link := range links {
+		names[i] = link.Filename
+	}
+	withMetadata := *response
+	withMetadata.ProviderMetadata = make(map[string]string, len(response.ProviderMetadata)+1)
+	for key, value := range response.ProviderMetadata {
+		withMetadata.ProviderMetadata[key] = value
+	}
+	withMetadata.ProviderMetadata["offloaded_attachments"] = strings.Join(names, ",")
+	return &withMetadata, nil
+}
+
+// ValidateProviderOptions implements the OptionsProvider interface for the
+// wrapped provider
+func (p *OffloadingEmailProvider) ValidateProviderOptions(options map[string]any) error {
+	return ValidateProviderOptions(p.EmailProvider, options)
+}
+
+// Unwrap returns the wrapped provider
+func (p *OffloadingEmailProvider) Unwrap() interfaces.EmailProvider {
+	return p.EmailProvider
+}
@@ -0,0 +1,38 @@
+package providers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/attachlink"
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+)
+
+func TestOffloadingEmailProvider_SendEmail(t *testing.T) {
+	offloader, err := attachlink.NewOffloader(attachlink.NewMemoryStore(time.Hour), config.AttachLinkConfig{
+		Threshold:  1000,
+		BaseURL:    "https://notify.example.com",
+		SigningKey: "0123456789abcdef0123456789abcdef",
+	})
+	require.NoError(t, err)
+	inner := createTestEmailProvider()
+	provider := NewOffloadingEmailProvider(inner, offloader)
+
+	email := createTestEmailWithAttachments("invoice.pdf")
+	email.Attachments[0].Content = []byte(strings.Repeat("x", 2000))
+	response, err := provider.SendEmail(context.Background(), email)
+	require.NoError(t, err)
+	assert.Equal(t, "invoice.pdf", response.ProviderMetadata["offloaded_attachments"])
+
+	sent := inner.GetSentEmails()
+	require.Len(t, sent, 1)
+	assert.Empty(t, sent[0].Attachments)
+	assert.Contains(t, sent[0].HTMLBody, "https://notify.example.com/attachments/")
+	assert.Contains(t, sent[0].TextBody, "invoice.pdf (2.0 KB)")
+	assert.Same(t, inner, provider.Unwrap())
+}
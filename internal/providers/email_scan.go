@@ -0,0 +1,267 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nareshkumar-microsoft/notificationService/internal/audit"
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// What happens to infected attachments
+const (
+	VirusScanReject     = "reject"     // the email is not sent
+	VirusScanQuarantine = "quarantine" // the email is sent without them
+)
+
+// Defaults and limits of attachment scanning
+const (
+	defaultVirusScanTimeout = 30 * time.Second
+	maxQuarantined          = 1000 // older quarantined attachments are dropped
+)
+
+// auditActionVirusScan is the audit action of an infected attachment
+const auditActionVirusScan = "attachment.infected"
+
+// QuarantinedAttachment is an infected attachment held back from an email
+type QuarantinedAttachment struct {
+	ID             uuid.UUID `json:"id"`
+	NotificationID uuid.UUID `json:"notification_id"`
+	Filename       string    `json:"filename"`
+	ContentType    string    `json:"content_type"`
+	Size           int64     `json:"size"`
+	SHA256         string    `json:"sha256"`
+	Threat         string    `json:"threat"`
+	Content        []byte    `json:"-"`
+	QuarantinedAt  time.Time `json:"quarantined_at"`
+}
+
+// ScanningEmailProvider scans every attachment of an email for viruses
+// before the wrapped provider sends it. Infected attachments reject the
+// email with ATTACHMENT_INFECTED, or are quarantined and left out of it,
+// and each is recorded in the audit trail. An email whose attachments
+// cannot be scanned is not sent.
+type ScanningEmailProvider struct {
+	interfaces.EmailProvider
+
+	scanner interfaces.AttachmentScanner
+	action  string
+	timeout time.Duration
+
+	mu          sync.RWMutex
+	audit       *audit.Recorder
+	quarantined []*QuarantinedAttachment // oldest first
+	now         func() time.Time
+}
+
+// NewScanningEmailProvider wraps a provider with an attachment scanner.
+// Batch sends are not scanned, so the wrapped provider sends one email at a
+// time.
+func NewScanningEmailProvider(provider interfaces.EmailProvider, scanner interfaces.AttachmentScanner, cfg config.VirusScanConfig) (*ScanningEmailProvider, error) {
+	action := strings.ToLower(cfg.Action)
+	switch action {
+	case "":
+		action = VirusScanReject
+	case VirusScanReject, VirusScanQuarantine:
+	default:
+		return nil, errors.NewValidationError("action", fmt.Sprintf("must be %s or %s, got %q", VirusScanReject, VirusScanQuarantine, cfg.Action))
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultVirusScanTimeout
+	}
+
+	return &ScanningEmailProvider{
+		EmailProvider: provider,
+		scanner:       scanner,
+		action:        action,
+		timeout:       timeout,
+		now:           time.Now,
+	}, nil
+}
+
+// SetAuditRecorder records infected attachments in the audit trail
+func (p *ScanningEmailProvider) SetAuditRecorder(recorder *audit.Recorder) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.audit = recorder
+}
+
+// SendEmail implements the EmailProvider interface
+func (p *ScanningEmailProvider) SendEmail(ctx context.Context, email *models.EmailNotification) (*models.NotificationResponse, error) {
+	if len(email.Attachments) == 0 {
+		return p.EmailProvider.SendEmail(ctx, email)
+	}
+
+	clean := make([]models.EmailAttachment, 0, len(email.Attachments))
+	var infected []*QuarantinedAttachment
+	for i := range email.Attachments {
+		attachment := &email.Attachments[i]
+		threat, err := p.scan(ctx, attachment)
+		if err != nil {
+			return nil, err
+		}
+		if threat == "" {
+			clean = append(clean, *attachment)
+			continue
+		}
+		infected = append(infected, p.infection(email, attachment, threat))
+	}
+	if len(infected) == 0 {
+		return p.EmailProvider.SendEmail(ctx, email)
+	}
+
+	p.record(ctx, email, infected)
+	if p.action == VirusScanReject {
+		first := infected[0]
+		return nil, errors.NewNotificationErrorWithDetails(errors.ErrorCodeAttachmentInfected,
+			fmt.Sprintf("attachment %s is infected", first.Filename), "threat: "+first.Threat).
+			WithMetadata("filename", first.Filename).
+			WithMetadata("threat", first.Threat).
+			WithMetadata("infected_attachments", fmt.Sprint(len(infected)))
+	}
+
+	p.quarantine(infected)
+	scanned := *email
+	scanned.Attachments = clean
+	response, err := p.EmailProvider.SendEmail(ctx, &scanned)
+	if err != nil {
+		return response, err
+	}
+
+	names := make([]string, len(infected))
+	for i, attachment := range infected {
+		names[i] = attachment.Filename
+	}
+	withMetadata := *response
+	withMetadata.ProviderMetadata = make(map[string]string, len(response.ProviderMetadata)+1)
+	for key, value := range response.ProviderMetadata {
+		withMetadata.ProviderMetadata[key] = value
+	}
+	withMetadata.ProviderMetadata["quarantined_attachments"] = strings.Join(names, ",")
+	return &withMetadata, nil
+}
+
+// ValidateProviderOptions implements the OptionsProvider interface for the
+// wrapped provider
+func (p *ScanningEmailProvider) ValidateProviderOptions(options map[string]any) error {
+	return ValidateProviderOptions(p.EmailProvider, options)
+}
+
+// Unwrap returns the wrapped provider
+func (p *ScanningEmailProvider) Unwrap() interfaces.EmailProvider {
+	return p.EmailProvider
+}
+
+// Quarantined returns the quarantined attachments, newest first
+func (p *ScanningEmailProvider) Quarantined() []*QuarantinedAttachment {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	attachments := make([]*QuarantinedAttachment, len(p.quarantined))
+	for i, attachment := range p.quarantined {
+		attachments[len(attachments)-1-i] = attachment
+	}
+	return attachments
+}
+
+// QuarantinedAttachment returns a quarantined attachment with its content
+func (p *ScanningEmailProvider) QuarantinedAttachment(id uuid.UUID) (*QuarantinedAttachment, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, attachment := range p.quarantined {
+		if attachment.ID == id {
+			return attachment, nil
+		}
+	}
+	return nil, errors.NewNotificationError(errors.ErrorCodeNotFound, fmt.Sprintf("quarantined attachment %s not found", id))
+}
+
+// scan scans one attachment within the timeout
+func (p *ScanningEmailProvider) scan(ctx context.Context, attachment *models.EmailAttachment) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	threat, err := p.scanner.ScanAttachment(ctx, attachment)
+	if err != nil {
+		if _, ok := errors.AsNotificationError(err); ok {
+			return "", err
+		}
+		return "", errors.NewNotificationErrorWithDetails(errors.ErrorCodeProviderUnavailable,
+			fmt.Sprintf("failed to scan attachment %s for viruses", attachment.Filename), err.Error()).WithCause(err)
+	}
+	return threat, nil
+}
+
+// infection describes an infected attachment of an email
+func (p *ScanningEmailProvider) infection(email *models.EmailNotification, attachment *models.EmailAttachment, threat string) *QuarantinedAttachment {
+	sum := sha256.Sum256(attachment.Content)
+	return &QuarantinedAttachment{
+		ID:             uuid.New(),
+		NotificationID: email.ID,
+		Filename:       attachment.Filename,
+		ContentType:    attachment.ContentType,
+		Size:           int64(len(attachment.Content)),
+		SHA256:         hex.EncodeToString(sum[:]),
+		Threat:         threat,
+		Content:        attachment.Content,
+		QuarantinedAt:  p.now(),
+	}
+}
+
+// quarantine keeps infected attachments, dropping the oldest beyond the limit
+func (p *ScanningEmailProvider) quarantine(attachments []*QuarantinedAttachment) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.quarantined = append(p.quarantined, attachments...)
+	if len(p.quarantined) > maxQuarantined {
+		p.quarantined = append([]*QuarantinedAttachment(nil), p.quarantined[len(p.quarantined)-maxQuarantined:]...)
+	}
+}
+
+// record adds an audit entry for each infected attachment. Entries hold the
+// attachment's hash, not its content.
+func (p *ScanningEmailProvider) record(ctx context.Context, email *models.EmailNotification, infected []*QuarantinedAttachment) {
+	p.mu.RLock()
+	recorder := p.audit
+	p.mu.RUnlock()
+	if recorder == nil {
+		return
+	}
+
+	outcome := models.AuditOutcomeRejected
+	if p.action == VirusScanQuarantine {
+		outcome = models.AuditOutcomeQuarantined
+	}
+	for _, attachment := range infected {
+		entry := &models.AuditEntry{
+			Action:           auditActionVirusScan,
+			Outcome:          outcome,
+			NotificationID:   email.ID.String(),
+			NotificationType: models.NotificationTypeEmail,
+			PayloadHash:      attachment.SHA256,
+			Reason:           fmt.Sprintf("attachment %s carries %s", attachment.Filename, attachment.Threat),
+			Metadata: map[string]string{
+				"filename": attachment.Filename,
+				"threat":   attachment.Threat,
+			},
+		}
+		if p.action == VirusScanQuarantine {
+			entry.Metadata["quarantine_id"] = attachment.ID.String()
+		}
+		// A failed append is logged by the recorder and does not stop the decision
+		_ = recorder.Record(ctx, entry)
+	}
+}
@@ -0,0 +1,140 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/audit"
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/repository"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+func TestScanningEmailProvider_RejectsInfectedAttachments(t *testing.T) {
+	inner := createTestEmailProvider()
+	provider, err := NewScanningEmailProvider(inner, testScanner{}, config.VirusScanConfig{})
+	require.NoError(t, err)
+	auditLog := repository.NewMemoryAuditRepository()
+	provider.SetAuditRecorder(audit.NewRecorder(auditLog, utils.NewSimpleLogger("error")))
+	ctx := context.Background()
+
+	email := createTestEmailWithAttachments("invoice.pdf", "eicar.com")
+	_, err = provider.SendEmail(ctx, email)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeAttachmentInfected, notifErr.Code)
+	assert.Equal(t, "eicar.com", notifErr.Metadata["filename"])
+	assert.Equal(t, "Eicar-Test-Signature", notifErr.Metadata["threat"])
+	assert.Empty(t, inner.GetSentEmails())
+	assert.Empty(t, provider.Quarantined())
+
+	entries, err := auditLog.Query(ctx, interfaces.AuditFilters{NotificationID: email.ID.String()})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "attachment.infected", entries[0].Action)
+	assert.Equal(t, models.AuditOutcomeRejected, entries[0].Outcome)
+	assert.Equal(t, "eicar.com", entries[0].Metadata["filename"])
+	assert.Len(t, entries[0].PayloadHash, 64)
+
+	// Clean attachments go through
+	_, err = provider.SendEmail(ctx, createTestEmailWithAttachments("invoice.pdf"))
+	require.NoError(t, err)
+	require.Len(t, inner.GetSentEmails(), 1)
+}
+
+func TestScanningEmailProvider_QuarantinesInfectedAttachments(t *testing.T) {
+	inner := createTestEmailProvider()
+	provider, err := NewScanningEmailProvider(inner, testScanner{}, config.VirusScanConfig{Action: "quarantine"})
+	require.NoError(t, err)
+	auditLog := repository.NewMemoryAuditRepository()
+	provider.SetAuditRecorder(audit.NewRecorder(auditLog, utils.NewSimpleLogger("error")))
+	ctx := context.Background()
+
+	email := createTestEmailWithAttachments("invoice.pdf", "eicar.com")
+	response, err := provider.SendEmail(ctx, email)
+	require.NoError(t, err)
+	assert.Equal(t, "eicar.com", response.ProviderMetadata["quarantined_attachments"])
+	assert.Len(t, email.Attachments, 2) // the caller's email is not modified
+
+	sent := inner.GetSentEmails()
+	require.Len(t, sent, 1)
+	require.Len(t, sent[0].Attachments, 1)
+	assert.Equal(t, "invoice.pdf", sent[0].Attachments[0].Filename)
+
+	quarantined := provider.Quarantined()
+	require.Len(t, quarantined, 1)
+	assert.Equal(t, email.ID, quarantined[0].NotificationID)
+	assert.Equal(t, "Eicar-Test-Signature", quarantined[0].Threat)
+	held, err := provider.QuarantinedAttachment(quarantined[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, []byte(eicar), held.Content)
+
+	entries, err := auditLog.Query(ctx, interfaces.AuditFilters{NotificationID: email.ID.String()})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, models.AuditOutcomeQuarantined, entries[0].Outcome)
+	assert.Equal(t, quarantined[0].ID.String(), entries[0].Metadata["quarantine_id"])
+}
+
+func TestScanningEmailProvider_ScanFailure(t *testing.T) {
+	inner := createTestEmailProvider()
+	provider, err := NewScanningEmailProvider(inner, testScanner{err: fmt.Errorf("connection refused")}, config.VirusScanConfig{})
+	require.NoError(t, err)
+
+	_, err = provider.SendEmail(context.Background(), createTestEmailWithAttachments("invoice.pdf"))
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeProviderUnavailable, notifErr.Code)
+	assert.Empty(t, inner.GetSentEmails())
+
+	// Emails without attachments are not scanned
+	_, err = provider.SendEmail(context.Background(), createTestEmailNotification())
+	require.NoError(t, err)
+	assert.Len(t, inner.GetSentEmails(), 1)
+}
+
+func TestNewScanningEmailProvider_InvalidAction(t *testing.T) {
+	_, err := NewScanningEmailProvider(createTestEmailProvider(), testScanner{}, config.VirusScanConfig{Action: "delete"})
+	assert.Error(t, err)
+}
+
+// Helper functions
+
+// testScanner finds the EICAR test file
+type testScanner struct {
+	err error
+}
+
+func (s testScanner) ScanAttachment(ctx context.Context, attachment *models.EmailAttachment) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	if string(attachment.Content) == eicar {
+		return "Eicar-Test-Signature", nil
+	}
+	return "", nil
+}
+
+func createTestEmailWithAttachments(filenames ...string) *models.EmailNotification {
+	email := createTestEmailNotification()
+	for _, filename := range filenames {
+		content := []byte("%PDF-1.4 test")
+		if filename == "eicar.com" {
+			content = []byte(eicar)
+		}
+		email.Attachments = append(email.Attachments, models.EmailAttachment{
+			Filename:    filename,
+			Content:     content,
+			ContentType: "application/octet-stream",
+			Size:        int64(len(content)),
+		})
+	}
+	return email
+}
@@ -0,0 +1,394 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	stderrors "errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// SMTP session defaults
+const (
+	defaultSMTPPort    = 587
+	defaultSMTPTimeout = 30 * time.Second // of a session whose context has no deadline
+)
+
+// SMTPEmailProvider implements the EmailProvider interface by delivering mail
+// to an SMTP server. With SMTPUseTLS it upgrades the connection with
+// STARTTLS and refuses servers that do not offer it; with a username it
+// authenticates with PLAIN.
+type SMTPEmailProvider struct {
+	config config.EmailProviderConfig
+	host   string
+	addr   string
+}
+
+// NewSMTPEmailProvider creates a new SMTP email provider
+func NewSMTPEmailProvider(cfg config.EmailProviderConfig) *SMTPEmailProvider {
+	host := cfg.SMTPHost
+	if host == "" {
+		host = "localhost"
+	}
+	port := cfg.SMTPPort
+	if port <= 0 {
+		port = defaultSMTPPort
+	}
+
+	return &SMTPEmailProvider{
+		config: cfg,
+		host:   host,
+		addr:   net.JoinHostPort(host, strconv.Itoa(port)),
+	}
+}
+
+// Send implements the NotificationProvider interface
+func (p *SMTPEmailProvider) Send(ctx context.Context, notification *models.Notification) (*models.NotificationResponse, error) {
+	if notification.Type != models.NotificationTypeEmail {
+		return nil, errors.NewValidationError("type", "notification type must be email")
+	}
+
+	return p.SendEmail(ctx, &models.EmailNotification{
+		Notification: *notification,
+		To:           []string{notification.Recipient},
+		HTMLBody:     notification.Body,
+		TextBody:     notification.Body,
+	})
+}
+
+// SendEmail implements the EmailProvider interface
+func (p *SMTPEmailProvider) SendEmail(ctx context.Context, email *models.EmailNotification) (*models.NotificationResponse, error) {
+	if err := validateEmailNotification(email); err != nil {
+		return nil, err
+	}
+	if err := utils.ValidateEmailHeaders(email.Headers); err != nil {
+		return nil, err
+	}
+	if err := utils.ValidateEmailThreading(email.InReplyTo, email.References); err != nil {
+		return nil, err
+	}
+	// SMTP has no provider options
+	if err := ValidateProviderOptions(p, email.ProviderOptions); err != nil {
+		return nil, err
+	}
+	if ctx.Err() != nil {
+		return nil, errors.NewNotificationError(errors.ErrorCodeTimeout, "email sending timed out")
+	}
+
+	from := email.From
+	if from == "" {
+		from = p.defaultSender()
+	}
+	messageID := email.MessageID
+	if messageID == "" {
+		messageID = utils.GenerateMessageID(email.ID, from)
+	}
+	message := buildSMTPMessage(email, from, messageID, time.Now())
+
+	recipients := emailRecipients(email)
+	err := p.session(ctx, func(client *smtp.Client) error {
+		if err := client.Mail(from); err != nil {
+			return p.replyError(ctx, err, "MAIL", from)
+		}
+		for _, recipient := range recipients {
+			if err := client.Rcpt(recipient); err != nil {
+				return p.replyError(ctx, err, "RCPT", recipient)
+			}
+		}
+		w, err := client.Data()
+		if err != nil {
+			return p.replyError(ctx, err, "DATA", "")
+		}
+		if _, err := w.Write(message); err != nil {
+			return p.replyError(ctx, err, "DATA", "")
+		}
+		if err := w.Close(); err != nil {
+			return p.replyError(ctx, err, "DATA", "")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &models.NotificationResponse{
+		ID:         email.ID,
+		Status:     models.StatusSent,
+		Message:    fmt.Sprintf("Email accepted by %s for %d recipients", p.addr, len(recipients)),
+		ProviderID: messageID,
+		SentAt:     &now,
+		ProviderMetadata: map[string]string{
+			"provider":            "smtp",
+			"internet_message_id": messageID,
+		},
+	}, nil
+}
+
+// ValidateEmailAddress implements the EmailProvider interface
+func (p *SMTPEmailProvider) ValidateEmailAddress(email string) error {
+	return utils.ValidateEmailAddress(email)
+}
+
+// GetEmailTemplates implements the EmailProvider interface. SMTP servers
+// hold no templates.
+func (p *SMTPEmailProvider) GetEmailTemplates() []interfaces.EmailTemplate {
+	return []interfaces.EmailTemplate{}
+}
+
+// GetType implements the NotificationProvider interface
+func (p *SMTPEmailProvider) GetType() models.NotificationType {
+	return models.NotificationTypeEmail
+}
+
+// IsHealthy implements the NotificationProvider interface by opening a
+// session with the server
+func (p *SMTPEmailProvider) IsHealthy(ctx context.Context) error {
+	return p.session(ctx, func(client *smtp.Client) error {
+		if err := client.Noop(); err != nil {
+			return p.replyError(ctx, err, "NOOP", "")
+		}
+		return nil
+	})
+}
+
+// GetConfig implements the NotificationProvider interface
+func (p *SMTPEmailProvider) GetConfig() interfaces.ProviderConfig {
+	return interfaces.ProviderConfig{
+		Name:       "SMTP Email Provider",
+		Type:       models.NotificationTypeEmail,
+		Enabled:    p.config.Enabled,
+		Priority:   1,
+		MaxRetries: 3,
+		Timeout:    int(defaultSMTPTimeout / time.Second),
+		Settings: map[string]string{
+			"provider_type": "smtp",
+			"server":        p.addr,
+			"starttls":      strconv.FormatBool(p.config.SMTPUseTLS),
+		},
+	}
+}
+
+// session connects to the server, upgrades and authenticates the
+// connection as configured, runs a transaction and quits. The connection
+// is closed as soon as the context ends.
+func (p *SMTPEmailProvider) session(ctx context.Context, transaction func(client *smtp.Client) error) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return p.replyError(ctx, err, "connect", "")
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultSMTPTimeout)
+	}
+	conn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, p.host)
+	if err != nil {
+		conn.Close()
+		return p.replyError(ctx, err, "greeting", "")
+	}
+	defer client.Close()
+
+	if p.config.SMTPUseTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.NewProviderError("smtp", errors.ErrorCodeProviderConfiguration,
+				fmt.Sprintf("%s does not offer STARTTLS; disable TLS for servers without it", p.addr))
+		}
+		if err := client.StartTLS(&tls.Config{ServerName: p.host}); err != nil {
+			return p.replyError(ctx, err, "STARTTLS", "")
+		}
+	}
+	if p.config.SMTPUsername != "" {
+		if err := client.Auth(smtp.PlainAuth("", p.config.SMTPUsername, p.config.SMTPPassword, p.host)); err != nil {
+			if ctx.Err() != nil {
+				return p.replyError(ctx, err, "AUTH", "")
+			}
+			return errors.NewProviderError("smtp", errors.ErrorCodeProviderAuthentication,
+				fmt.Sprintf("authentication with %s failed", p.addr)).WithCause(err)
+		}
+	}
+
+	if err := transaction(client); err != nil {
+		return err
+	}
+	if err := client.Quit(); err != nil {
+		return p.replyError(ctx, err, "QUIT", "")
+	}
+	return nil
+}
+
+// replyError converts an error of an SMTP command. A permanent (5xx)
+// rejection of a recipient blames the message; other replies blame the
+// server and carry the reply code, so 421 and 450 deferrals reach the
+// per-domain throttling.
+func (p *SMTPEmailProvider) replyError(ctx context.Context, err error, command, recipient string) error {
+	if ctx.Err() != nil {
+		return errors.NewNotificationError(errors.ErrorCodeTimeout,
+			fmt.Sprintf("SMTP %s with %s timed out", command, p.addr))
+	}
+
+	var reply *textproto.Error
+	if !stderrors.As(err, &reply) {
+		return errors.NewProviderError("smtp", errors.ErrorCodeProviderUnavailable,
+			fmt.Sprintf("SMTP %s with %s failed: %v", command, p.addr, err)).WithCause(err)
+	}
+
+	var notifErr *errors.NotificationError
+	switch {
+	case command == "RCPT" && reply.Code >= 500:
+		notifErr = errors.NewNotificationError(errors.ErrorCodeInvalidRecipient,
+			fmt.Sprintf("%s rejected recipient %s: %d %s", p.addr, recipient, reply.Code, reply.Msg))
+	case reply.Code >= 500:
+		notifErr = errors.NewProviderError("smtp", errors.ErrorCodeDeliveryFailed,
+			fmt.Sprintf("%s rejected %s: %d %s", p.addr, command, reply.Code, reply.Msg))
+	default:
+		notifErr = errors.NewProviderError("smtp", errors.ErrorCodeProviderUnavailable,
+			fmt.Sprintf("%s deferred %s: %d %s", p.addr, command, reply.Code, reply.Msg))
+	}
+	return notifErr.WithMetadata("smtp_reply", strconv.Itoa(reply.Code)).WithCause(err)
+}
+
+// defaultSender returns the default sender email address
+func (p *SMTPEmailProvider) defaultSender() string {
+	if sender, exists := p.config.Settings["default_sender"]; exists {
+		return sender
+	}
+	return "noreply@notification-service.local"
+}
+
+// buildSMTPMessage formats an email as an RFC 5322 message: its text and
+// HTML bodies as alternatives, mixed with its attachments. BCC recipients
+// are left out of the headers.
+func buildSMTPMessage(email *models.EmailNotification, from, messageID string, date time.Time) []byte {
+	var b bytes.Buffer
+	header := func(name, value string) {
+		fmt.Fprintf(&b, "%s: %s\r\n", name, value)
+	}
+
+	header("From", from)
+	header("To", strings.Join(email.To, ", "))
+	if len(email.CC) > 0 {
+		header("Cc", strings.Join(email.CC, ", "))
+	}
+	if email.ReplyTo != "" {
+		header("Reply-To", email.ReplyTo)
+	}
+	header("Subject", mime.QEncoding.Encode("utf-8", email.Subject))
+	header("Date", date.Format(time.RFC1123Z))
+	header("Message-ID", messageID)
+	if email.InReplyTo != "" {
+		header("In-Reply-To", email.InReplyTo)
+	}
+	if len(email.References) > 0 {
+		header("References", strings.Join(email.References, " "))
+	}
+	names := make([]string, 0, len(email.Headers))
+	for name := range email.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		header(textproto.CanonicalMIMEHeaderKey(name), email.Headers[name])
+	}
+	header("MIME-Version", "1.0")
+
+	contentHeader, content := smtpContent(email)
+	if len(email.Attachments) > 0 {
+		var mixed bytes.Buffer
+		mw := multipart.NewWriter(&mixed)
+		part, _ := mw.CreatePart(contentHeader)
+		part.Write(content)
+		for _, attachment := range email.Attachments {
+			contentType := attachment.ContentType
+			if contentType == "" {
+				contentType = "application/octet-stream"
+			}
+			part, _ := mw.CreatePart(textproto.MIMEHeader{
+				"Content-Type":              {mime.FormatMediaType(contentType, map[string]string{"name": attachment.Filename})},
+				"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})},
+				"Content-Transfer-Encoding": {"base64"},
+			})
+			part.Write(wrapBase64(attachment.Content))
+		}
+		mw.Close()
+		contentHeader = textproto.MIMEHeader{"Content-Type": {"multipart/mixed; boundary=" + mw.Boundary()}}
+		content = mixed.Bytes()
+	}
+
+	for _, name := range []string{"Content-Type", "Content-Transfer-Encoding"} {
+		if value := contentHeader.Get(name); value != "" {
+			header(name, value)
+		}
+	}
+	b.WriteString("\r\n")
+	b.Write(content)
+	return b.Bytes()
+}
+
+// smtpContent returns the header and encoded body of an email's text and
+// HTML, as alternatives when it has both
+func smtpContent(email *models.EmailNotification) (textproto.MIMEHeader, []byte) {
+	textPart := func(mediaType, body string) (textproto.MIMEHeader, []byte) {
+		var encoded bytes.Buffer
+		w := quotedprintable.NewWriter(&encoded)
+		w.Write([]byte(body))
+		w.Close()
+		return textproto.MIMEHeader{
+			"Content-Type":              {mediaType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		}, encoded.Bytes()
+	}
+
+	switch {
+	case email.HTMLBody == "":
+		return textPart("text/plain", email.TextBody)
+	case email.TextBody == "":
+		return textPart("text/html", email.HTMLBody)
+	}
+
+	var alternative bytes.Buffer
+	mw := multipart.NewWriter(&alternative)
+	for _, body := range []struct{ mediaType, content string }{
+		{"text/plain", email.TextBody},
+		{"text/html", email.HTMLBody},
+	} {
+		header, encoded := textPart(body.mediaType, body.content)
+		part, _ := mw.CreatePart(header)
+		part.Write(encoded)
+	}
+	mw.Close()
+	return textproto.MIMEHeader{"Content-Type": {"multipart/alternative; boundary=" + mw.Boundary()}}, alternative.Bytes()
+}
+
+// wrapBase64 encodes content as base64 in lines of 76 characters
+func wrapBase64(content []byte) []byte {
+	encoded := base64.StdEncoding.EncodeToString(content)
+	var b bytes.Buffer
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded)
+	return b.Bytes()
+}
@@ -0,0 +1,129 @@
+package providers
+
+import (
+	"context"
+	"net"
+	"net/textproto"
+	"strconv"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/smtpsink"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestSMTPEmailProvider_SendEmail(t *testing.T) {
+	provider, sink := createTestSMTPProvider(t, false)
+	email := &models.EmailNotification{
+		Notification: models.Notification{ID: uuid.New(), Type: models.NotificationTypeEmail, Subject: "Your invoice €12"},
+		To:           []string{"ana@example.com"},
+		CC:           []string{"bo@example.com"},
+		BCC:          []string{"audit@example.org"},
+		From:         "billing@example.com",
+		TextBody:     "Your invoice is attached.",
+		HTMLBody:     "<p>Your invoice is attached.</p>",
+		Headers:      map[string]string{"x-campaign": "march"},
+		Attachments: []models.EmailAttachment{
+			{Filename: "invoice.pdf", ContentType: "application/pdf", Content: []byte("%PDF-1.4")},
+		},
+	}
+
+	response, err := provider.SendEmail(context.Background(), email)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusSent, response.Status)
+	assert.Equal(t, utils.GenerateMessageID(email.ID, email.From), response.ProviderID)
+
+	messages := sink.Messages()
+	require.Len(t, messages, 1)
+	captured := messages[0]
+	assert.Equal(t, "billing@example.com", captured.From)
+	assert.Equal(t, []string{"ana@example.com", "bo@example.com", "audit@example.org"}, captured.Recipients)
+	assert.Equal(t, "Your invoice €12", captured.Subject)
+	assert.Equal(t, "bo@example.com", captured.Headers["Cc"])
+	assert.NotContains(t, captured.Headers, "Bcc")
+	assert.Equal(t, "march", captured.Headers["X-Campaign"])
+	assert.Equal(t, response.ProviderID, captured.Headers["Message-Id"])
+	assert.Equal(t, "Your invoice is attached.", captured.TextBody)
+	assert.Equal(t, "<p>Your invoice is attached.</p>", captured.HTMLBody)
+	assert.Equal(t, []smtpsink.Attachment{{Filename: "invoice.pdf", ContentType: "application/pdf", Size: 8}}, captured.Attachments)
+}
+
+func TestSMTPEmailProvider_RequiresSTARTTLS(t *testing.T) {
+	provider, sink := createTestSMTPProvider(t, true)
+
+	_, err := provider.SendEmail(context.Background(), createTestEmailNotification())
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeProviderConfiguration, notifErr.Code)
+	assert.Empty(t, sink.Messages())
+}
+
+func TestSMTPEmailProvider_Unreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().(*net.TCPAddr)
+	listener.Close()
+
+	provider := NewSMTPEmailProvider(config.EmailProviderConfig{SMTPHost: "127.0.0.1", SMTPPort: addr.Port})
+	err = provider.IsHealthy(context.Background())
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeProviderUnavailable, notifErr.Code)
+}
+
+func TestSMTPEmailProvider_ReplyError(t *testing.T) {
+	provider := NewSMTPEmailProvider(config.EmailProviderConfig{})
+	tests := []struct {
+		name     string
+		command  string
+		reply    int
+		code     errors.ErrorCode
+		failure  bool
+		deferral bool
+	}{
+		{"unknown mailbox", "RCPT", 550, errors.ErrorCodeInvalidRecipient, false, false},
+		{"greylisted", "RCPT", 450, errors.ErrorCodeProviderUnavailable, true, true},
+		{"rejected content", "DATA", 554, errors.ErrorCodeDeliveryFailed, true, false},
+		{"server shutting down", "MAIL", 421, errors.ErrorCodeProviderUnavailable, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := provider.replyError(context.Background(), &textproto.Error{Code: tt.reply, Msg: "no"}, tt.command, "ana@example.com")
+			notifErr, ok := errors.AsNotificationError(err)
+			require.True(t, ok)
+			assert.Equal(t, tt.code, notifErr.Code)
+			assert.Equal(t, strconv.Itoa(tt.reply), notifErr.Metadata["smtp_reply"])
+			assert.Equal(t, tt.failure, IsProviderFailure(err))
+			deferral, _ := isDeferral(err)
+			assert.Equal(t, tt.deferral, deferral)
+		})
+	}
+}
+
+// Helper functions
+
+func createTestSMTPProvider(t *testing.T, useTLS bool) (*SMTPEmailProvider, *smtpsink.Server) {
+	sink := smtpsink.NewServer(config.SMTPSinkConfig{Addr: "127.0.0.1:0"}, utils.NewSimpleLogger("error"))
+	require.NoError(t, sink.Start(context.Background()))
+	t.Cleanup(sink.Stop)
+
+	host, port, err := net.SplitHostPort(sink.Addr())
+	require.NoError(t, err)
+	portNumber, err := strconv.Atoi(port)
+	require.NoError(t, err)
+	provider := NewSMTPEmailProvider(config.EmailProviderConfig{
+		Provider:   "smtp",
+		Enabled:    true,
+		SMTPHost:   host,
+		SMTPPort:   portNumber,
+		SMTPUseTLS: useTLS,
+	})
+	return provider, sink
+}
@@ -0,0 +1,311 @@
+package providers
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// Default backoff after a recipient domain defers mail
+const (
+	defaultDeferralBackoff    = 30 * time.Second
+	defaultMaxDeferralBackoff = 15 * time.Minute
+)
+
+// anyDomain keys the limit of domains without a limit of their own
+const anyDomain = "*"
+
+// ThrottledEmailProvider limits the rate and concurrency of sends to each
+// recipient domain, so a large send does not get the sending IP graylisted
+// by a mailbox provider. When a domain defers mail, with an SMTP 421 or 450
+// reply or a RATE_LIMITED error, sends to it back off exponentially.
+type ThrottledEmailProvider struct {
+	interfaces.EmailProvider
+
+	limits     map[string]config.EmailDomainLimit
+	backoff    time.Duration
+	maxBackoff time.Duration
+
+	mu      sync.Mutex
+	domains map[string]*domainThrottle
+	now     func() time.Time
+}
+
+// domainThrottle is the state of one recipient domain
+type domainThrottle struct {
+	slots        chan struct{} // nil without a concurrency limit
+	interval     time.Duration // between sends; 0 without a rate limit
+	next         time.Time     // earliest time of the next send
+	backoff      time.Duration // current backoff, 0 when not deferred
+	backoffUntil time.Time
+}
+
+// NewThrottledEmailProvider wraps a provider with the domain limits of the
+// configuration. Batch sends are not throttled, so the wrapped provider
+// sends one email at a time.
+func NewThrottledEmailProvider(provider interfaces.EmailProvider, cfg config.EmailProviderConfig) *ThrottledEmailProvider {
+	limits := make(map[string]config.EmailDomainLimit, len(cfg.DomainLimits))
+	for domain, limit := range cfg.DomainLimits {
+		limits[strings.ToLower(domain)] = limit
+	}
+
+	backoff, maxBackoff := cfg.DeferralBackoff, cfg.MaxDeferralBackoff
+	if backoff <= 0 {
+		backoff = defaultDeferralBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxDeferralBackoff
+	}
+	if maxBackoff < backoff {
+		maxBackoff = backoff
+	}
+
+	return &ThrottledEmailProvider{
+		EmailProvider: provider,
+		limits:        limits,
+		backoff:       backoff,
+		maxBackoff:    maxBackoff,
+		domains:       make(map[string]*domainThrottle),
+		now:           time.Now,
+	}
+}
+
+// Send implements the NotificationProvider interface
+func (p *ThrottledEmailProvider) Send(ctx context.Context, notification *models.Notification) (*models.NotificationResponse, error) {
+	return p.throttle(ctx, []string{notification.Recipient}, func() (*models.NotificationResponse, error) {
+		return p.EmailProvider.Send(ctx, notification)
+	})
+}
+
+// SendEmail implements the EmailProvider interface
+func (p *ThrottledEmailProvider) SendEmail(ctx context.Context, email *models.EmailNotification) (*models.NotificationResponse, error) {
+	recipients := append(append(append([]string(nil), email.To...), email.CC...), email.BCC...)
+	return p.throttle(ctx, recipients, func() (*models.NotificationResponse, error) {
+		return p.EmailProvider.SendEmail(ctx, email)
+	})
+}
+
+// ValidateProviderOptions implements the OptionsProvider interface for the
+// wrapped provider
+func (p *ThrottledEmailProvider) ValidateProviderOptions(options map[string]any) error {
+	return ValidateProviderOptions(p.EmailProvider, options)
+}
+
+// Unwrap returns the wrapped provider
+func (p *ThrottledEmailProvider) Unwrap() interfaces.EmailProvider {
+	return p.EmailProvider
+}
+
+// throttle waits until each recipient domain may take a send, sends, and
+// backs the domains off when they defer it
+func (p *ThrottledEmailProvider) throttle(ctx context.Context, recipients []string, send func() (*models.NotificationResponse, error)) (*models.NotificationResponse, error) {
+	domains := p.throttledDomains(recipients)
+
+	// Domains are acquired in order, so concurrent sends cannot deadlock
+	for i, domain := range domains {
+		if err := p.acquire(ctx, domain); err != nil {
+			for _, acquired := range domains[:i] {
+				p.release(acquired)
+			}
+			return nil, err
+		}
+	}
+	defer func() {
+		for _, domain := range domains {
+			p.release(domain)
+		}
+	}()
+
+	response, err := send()
+	if err != nil {
+		if deferred, reply := isDeferral(err); deferred {
+			return nil, p.deferred(domains, reply, err)
+		}
+		return nil, err
+	}
+
+	p.mu.Lock()
+	for _, domain := range domains {
+		p.domains[domain].backoff = 0
+	}
+	p.mu.Unlock()
+	return response, nil
+}
+
+// throttledDomains returns the distinct recipient domains that have a limit, sorted
+func (p *ThrottledEmailProvider) throttledDomains(recipients []string) []string {
+	seen := make(map[string]bool)
+	var domains []string
+	for _, recipient := range recipients {
+		at := strings.LastIndex(recipient, "@")
+		if at < 0 {
+			continue
+		}
+		domain := strings.ToLower(strings.TrimSuffix(recipient[at+1:], ">"))
+		if seen[domain] {
+			continue
+		}
+		seen[domain] = true
+
+		if _, limited := p.limits[domain]; limited {
+			domains = append(domains, domain)
+		} else if _, limited := p.limits[anyDomain]; limited {
+			domains = append(domains, domain)
+		}
+	}
+	sort.Strings(domains)
+	return domains
+}
+
+// domain returns the state of a domain, creating it from its limit. The
+// lock must be held.
+func (p *ThrottledEmailProvider) domain(name string) *domainThrottle {
+	if throttle, exists := p.domains[name]; exists {
+		return throttle
+	}
+
+	limit, exists := p.limits[name]
+	if !exists {
+		limit = p.limits[anyDomain]
+	}
+	throttle := &domainThrottle{}
+	if limit.Concurrency > 0 {
+		throttle.slots = make(chan struct{}, limit.Concurrency)
+	}
+	if limit.PerSecond > 0 {
+		throttle.interval = time.Duration(float64(time.Second) / limit.PerSecond)
+	}
+	p.domains[name] = throttle
+	return throttle
+}
+
+// acquire waits for a send slot of a domain and its turn under the rate
+// limit and any backoff
+func (p *ThrottledEmailProvider) acquire(ctx context.Context, name string) error {
+	p.mu.Lock()
+	throttle := p.domain(name)
+	p.mu.Unlock()
+
+	if throttle.slots != nil {
+		select {
+		case throttle.slots <- struct{}{}:
+		case <-ctx.Done():
+			return p.waitError(name)
+		}
+	}
+
+	p.mu.Lock()
+	now := p.now()
+	start := now
+	if throttle.next.After(start) {
+		start = throttle.next
+	}
+	if throttle.backoffUntil.After(start) {
+		start = throttle.backoffUntil
+	}
+	throttle.next = start.Add(throttle.interval)
+	p.mu.Unlock()
+
+	wait := start.Sub(now)
+	if wait <= 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(start) {
+		p.releaseSlot(throttle)
+		return p.waitError(name)
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		p.releaseSlot(throttle)
+		return p.waitError(name)
+	}
+}
+
+// release frees a domain's send slot
+func (p *ThrottledEmailProvider) release(name string) {
+	p.mu.Lock()
+	throttle := p.domains[name]
+	p.mu.Unlock()
+
+	p.releaseSlot(throttle)
+}
+
+// releaseSlot frees a send slot, if the domain limits concurrency
+func (p *ThrottledEmailProvider) releaseSlot(throttle *domainThrottle) {
+	if throttle.slots != nil {
+		<-throttle.slots
+	}
+}
+
+// deferred backs off the domains of a deferred send and returns its error
+func (p *ThrottledEmailProvider) deferred(domains []string, reply int, cause error) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	var backoff time.Duration
+	for _, name := range domains {
+		throttle := p.domain(name)
+		throttle.backoff *= 2
+		if throttle.backoff == 0 {
+			throttle.backoff = p.backoff
+		}
+		if throttle.backoff > p.maxBackoff {
+			throttle.backoff = p.maxBackoff
+		}
+		throttle.backoffUntil = now.Add(throttle.backoff)
+		if throttle.backoff > backoff {
+			backoff = throttle.backoff
+		}
+	}
+
+	err := errors.NewProviderError(p.GetConfig().Name, errors.ErrorCodeRateLimited,
+		fmt.Sprintf("recipient domain deferred the email; backing off for %s", backoff)).
+		WithMetadata("recipient_domains", strings.Join(domains, ",")).
+		WithMetadata("retry_after", strconv.Itoa(int(backoff.Seconds())))
+	if reply != 0 {
+		err.WithMetadata("smtp_reply", strconv.Itoa(reply))
+	}
+	return err.WithCause(cause)
+}
+
+// waitError is returned when a send cannot wait for its turn
+func (p *ThrottledEmailProvider) waitError(domain string) error {
+	return errors.NewNotificationError(errors.ErrorCodeRateLimited,
+		fmt.Sprintf("send to %s is throttled beyond the request deadline", domain)).
+		WithMetadata("recipient_domains", domain)
+}
+
+// isDeferral reports whether an error is a recipient domain deferring mail:
+// an SMTP 421 (service unavailable) or 450 (mailbox busy, often graylisting)
+// reply, or a RATE_LIMITED error. It returns the SMTP reply code, if any.
+func isDeferral(err error) (bool, int) {
+	var reply *textproto.Error
+	if stderrors.As(err, &reply) {
+		return reply.Code == 421 || reply.Code == 450, reply.Code
+	}
+
+	if notifErr, ok := errors.AsNotificationError(err); ok {
+		if code, convErr := strconv.Atoi(notifErr.Metadata["smtp_reply"]); convErr == nil {
+			return code == 421 || code == 450, code
+		}
+		return notifErr.Code == errors.ErrorCodeRateLimited, 0
+	}
+	return false, 0
+}
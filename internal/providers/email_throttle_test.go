@@ -0,0 +1,190 @@
+package providers
+
+import (
+	"context"
+	"net/textproto"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestThrottledEmailProvider_RateLimitsPerDomain(t *testing.T) {
+	inner := createTestRecordingProvider(0)
+	provider := NewThrottledEmailProvider(inner, config.EmailProviderConfig{
+		DomainLimits: map[string]config.EmailDomainLimit{"Gmail.com": {PerSecond: 20}},
+	})
+	ctx := context.Background()
+
+	started := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := provider.SendEmail(ctx, createTestEmailTo("user@gmail.com"))
+		require.NoError(t, err)
+	}
+	assert.GreaterOrEqual(t, time.Since(started), 90*time.Millisecond)
+
+	// Domains without a limit are not throttled
+	started = time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := provider.SendEmail(ctx, createTestEmailTo("user@example.com"))
+		require.NoError(t, err)
+	}
+	assert.Less(t, time.Since(started), 40*time.Millisecond)
+}
+
+func TestThrottledEmailProvider_LimitsConcurrency(t *testing.T) {
+	inner := createTestRecordingProvider(20 * time.Millisecond)
+	provider := NewThrottledEmailProvider(inner, config.EmailProviderConfig{
+		DomainLimits: map[string]config.EmailDomainLimit{"*": {Concurrency: 2}},
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := provider.SendEmail(context.Background(), createTestEmailTo("user@yahoo.com", "other@YAHOO.com"))
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 6, inner.sends)
+	assert.Equal(t, 2, inner.maxInFlight)
+}
+
+func TestThrottledEmailProvider_BacksOffOnDeferral(t *testing.T) {
+	inner := createTestRecordingProvider(0)
+	inner.err = &textproto.Error{Code: 421, Msg: "4.7.0 Try again later"}
+	provider := NewThrottledEmailProvider(inner, config.EmailProviderConfig{
+		DomainLimits:       map[string]config.EmailDomainLimit{"gmail.com": {PerSecond: 100}},
+		DeferralBackoff:    time.Minute,
+		MaxDeferralBackoff: 3 * time.Minute,
+	})
+	clock := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	provider.now = func() time.Time { return clock }
+
+	_, err := provider.SendEmail(context.Background(), createTestEmailTo("user@gmail.com"))
+	require.Error(t, err)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeRateLimited, notifErr.Code)
+	assert.Equal(t, "421", notifErr.Metadata["smtp_reply"])
+	assert.Equal(t, "60", notifErr.Metadata["retry_after"])
+	assert.Equal(t, "gmail.com", notifErr.Metadata["recipient_domains"])
+
+	// Sends that cannot wait out the backoff fail without reaching the provider
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = provider.SendEmail(ctx, createTestEmailTo("user@gmail.com"))
+	require.Error(t, err)
+	assert.Equal(t, 1, inner.sends)
+
+	// Each deferral doubles the backoff, up to the maximum
+	clock = clock.Add(time.Minute)
+	_, err = provider.SendEmail(context.Background(), createTestEmailTo("user@gmail.com"))
+	require.Error(t, err)
+	notifErr, _ = errors.AsNotificationError(err)
+	assert.Equal(t, "120", notifErr.Metadata["retry_after"])
+
+	clock = clock.Add(2 * time.Minute)
+	_, err = provider.SendEmail(context.Background(), createTestEmailTo("user@gmail.com"))
+	notifErr, _ = errors.AsNotificationError(err)
+	assert.Equal(t, "180", notifErr.Metadata["retry_after"])
+
+	// A delivered send resets the backoff
+	clock = clock.Add(3 * time.Minute)
+	inner.err = nil
+	_, err = provider.SendEmail(context.Background(), createTestEmailTo("user@gmail.com"))
+	require.NoError(t, err)
+	assert.Zero(t, provider.domains["gmail.com"].backoff)
+}
+
+func TestIsDeferral(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		deferred bool
+		reply    int
+	}{
+		{"SMTP 421", &textproto.Error{Code: 421}, true, 421},
+		{"SMTP 450", &textproto.Error{Code: 450}, true, 450},
+		{"SMTP 550", &textproto.Error{Code: 550}, false, 550},
+		{"rate limited", errors.NewNotificationError(errors.ErrorCodeRateLimited, "slow down"), true, 0},
+		{"reply in metadata", errors.NewNotificationError(errors.ErrorCodeProviderUnavailable, "busy").WithMetadata("smtp_reply", "450"), true, 450},
+		{"other error", errors.NewNotificationError(errors.ErrorCodeProviderUnavailable, "down"), false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deferred, reply := isDeferral(tt.err)
+			assert.Equal(t, tt.deferred, deferred)
+			assert.Equal(t, tt.reply, reply)
+		})
+	}
+}
+
+func TestNewEmailProvider_Throttled(t *testing.T) {
+	provider, err := NewEmailProvider(config.EmailProviderConfig{Provider: "mock"})
+	require.NoError(t, err)
+	assert.IsType(t, &MockEmailProvider{}, provider)
+
+	provider, err = NewEmailProvider(config.EmailProviderConfig{
+		Provider:     "mock",
+		DomainLimits: map[string]config.EmailDomainLimit{"gmail.com": {PerSecond: 10}},
+	})
+	require.NoError(t, err)
+	throttled, ok := provider.(*ThrottledEmailProvider)
+	require.True(t, ok)
+	assert.IsType(t, &MockEmailProvider{}, throttled.Unwrap())
+}
+
+// Helper functions
+
+// recordingEmailProvider counts the sends reaching it and how many were in flight at once
+type recordingEmailProvider struct {
+	*MockEmailProvider
+	delay time.Duration
+	err   error
+
+	mu          sync.Mutex
+	sends       int
+	inFlight    int
+	maxInFlight int
+}
+
+func createTestRecordingProvider(delay time.Duration) *recordingEmailProvider {
+	return &recordingEmailProvider{MockEmailProvider: createTestEmailProvider(), delay: delay}
+}
+
+func (p *recordingEmailProvider) SendEmail(ctx context.Context, email *models.EmailNotification) (*models.NotificationResponse, error) {
+	p.mu.Lock()
+	p.sends++
+	p.inFlight++
+	if p.inFlight > p.maxInFlight {
+		p.maxInFlight = p.inFlight
+	}
+	p.mu.Unlock()
+
+	time.Sleep(p.delay)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.inFlight--
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &models.NotificationResponse{ID: email.ID, Status: models.StatusSent}, nil
+}
+
+func createTestEmailTo(to ...string) *models.EmailNotification {
+	email := createTestEmailNotification()
+	email.To = to
+	return email
+}
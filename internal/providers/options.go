@@ -0,0 +1,178 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// OptionKind is the type of a provider option's value
+type OptionKind string
+
+const (
+	OptionString  OptionKind = "string"
+	OptionInteger OptionKind = "integer"
+	OptionBoolean OptionKind = "boolean"
+	OptionStrings OptionKind = "strings" // a list of strings
+)
+
+// OptionSpec declares a provider option
+type OptionSpec struct {
+	Kind        OptionKind
+	Enum        []string       // allowed string values
+	Pattern     *regexp.Regexp // strings, or each string of a list, must match
+	Min         int            // smallest integer
+	Max         int            // largest integer, longest string or list; 0 means no limit
+	Description string
+}
+
+// OptionSpecs declares the options a provider accepts, by name
+type OptionSpecs map[string]OptionSpec
+
+// Validate checks options against the specs. It returns a validation error
+// listing every failed option, as provider_options.<name>, or nil.
+func (s OptionSpecs) Validate(provider string, options map[string]any) error {
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var failures []errors.FieldError
+	for _, name := range names {
+		field := "provider_options." + name
+		spec, known := s[name]
+		if !known {
+			failures = append(failures, errors.FieldError{Field: field, Rule: "unknown", Message: fmt.Sprintf("is not an option of provider %s", provider)})
+			continue
+		}
+		if message := spec.check(options[name]); message != "" {
+			failures = append(failures, errors.FieldError{Field: field, Rule: "type", Message: message})
+		}
+	}
+
+	if len(failures) > 0 {
+		return errors.NewFieldValidationError(failures...)
+	}
+	return nil
+}
+
+// check returns why a value does not satisfy the spec, or "" when it does
+func (o OptionSpec) check(value any) string {
+	switch o.Kind {
+	case OptionString:
+		text, ok := value.(string)
+		if !ok {
+			return "must be a string"
+		}
+		return o.checkString(text)
+	case OptionInteger:
+		number, ok := optionInteger(value)
+		if !ok {
+			return "must be an integer"
+		}
+		if number < int64(o.Min) || (o.Max > 0 && number > int64(o.Max)) {
+			return fmt.Sprintf("must be between %d and %d", o.Min, o.Max)
+		}
+	case OptionBoolean:
+		if _, ok := value.(bool); !ok {
+			return "must be true or false"
+		}
+	case OptionStrings:
+		list, ok := optionStrings(value)
+		if !ok {
+			return "must be a list of strings"
+		}
+		if o.Max > 0 && len(list) > o.Max {
+			return fmt.Sprintf("must have at most %d values", o.Max)
+		}
+		for _, text := range list {
+			if message := o.checkPattern(text); message != "" {
+				return message
+			}
+		}
+	}
+	return ""
+}
+
+// checkString checks a string option's length, allowed values and pattern
+func (o OptionSpec) checkString(text string) string {
+	if o.Max > 0 && len([]rune(text)) > o.Max {
+		return fmt.Sprintf("must be at most %d characters", o.Max)
+	}
+	if len(o.Enum) > 0 {
+		for _, allowed := range o.Enum {
+			if text == allowed {
+				return ""
+			}
+		}
+		return fmt.Sprintf("must be one of %s, got %q", strings.Join(o.Enum, ", "), text)
+	}
+	return o.checkPattern(text)
+}
+
+// checkPattern checks a string against the spec's pattern
+func (o OptionSpec) checkPattern(text string) string {
+	if o.Pattern != nil && !o.Pattern.MatchString(text) {
+		return fmt.Sprintf("must match %s, got %q", o.Pattern, text)
+	}
+	return ""
+}
+
+// optionInteger reads an integer option. Values decoded from JSON are
+// float64, so whole floats count.
+func optionInteger(value any) (int64, bool) {
+	switch number := value.(type) {
+	case int:
+		return int64(number), true
+	case int64:
+		return number, true
+	case float64:
+		if number != math.Trunc(number) || math.Abs(number) > 1<<53 {
+			return 0, false
+		}
+		return int64(number), true
+	case json.Number:
+		parsed, err := number.Int64()
+		return parsed, err == nil
+	}
+	return 0, false
+}
+
+// optionStrings reads a list of strings option. Values decoded from JSON are
+// []any.
+func optionStrings(value any) ([]string, bool) {
+	switch list := value.(type) {
+	case []string:
+		return list, true
+	case []any:
+		texts := make([]string, len(list))
+		for i, item := range list {
+			text, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			texts[i] = text
+		}
+		return texts, true
+	}
+	return nil, false
+}
+
+// ValidateProviderOptions checks a request's provider options against the
+// provider that will send it. Providers that declare no options reject any.
+func ValidateProviderOptions(provider interfaces.NotificationProvider, options map[string]any) error {
+	if len(options) == 0 {
+		return nil
+	}
+	if validator, ok := provider.(interfaces.OptionsProvider); ok {
+		return validator.ValidateProviderOptions(options)
+	}
+	return errors.NewValidationError("provider_options", fmt.Sprintf("provider %s accepts no provider options", provider.GetConfig().Name))
+}
@@ -0,0 +1,92 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestOptionSpecs_Validate(t *testing.T) {
+	specs := OptionSpecs{
+		"label":   {Kind: OptionString, Max: 5, Pattern: regexp.MustCompile(`^[a-z]+$`)},
+		"mode":    {Kind: OptionString, Enum: []string{"fast", "slow"}},
+		"timeout": {Kind: OptionInteger, Min: 5, Max: 60},
+		"record":  {Kind: OptionBoolean},
+		"tags":    {Kind: OptionStrings, Max: 2},
+	}
+
+	// Options decoded from JSON, as in an API request
+	var options map[string]any
+	require.NoError(t, json.Unmarshal([]byte(`{"label": "abc", "mode": "fast", "timeout": 30, "record": true, "tags": ["a", "b"]}`), &options))
+	assert.NoError(t, specs.Validate("test", options))
+	assert.NoError(t, specs.Validate("test", map[string]any{"timeout": 30, "tags": []string{"a"}}))
+	assert.NoError(t, specs.Validate("test", nil))
+
+	for name, value := range map[string]any{
+		"label":   "ABC",
+		"mode":    "medium",
+		"timeout": 2.5,
+		"record":  "yes",
+		"tags":    []any{"a", 1},
+		"unknown": true,
+	} {
+		err := specs.Validate("test", map[string]any{name: value})
+		require.Error(t, err, name)
+		notifErr, ok := errors.AsNotificationError(err)
+		require.True(t, ok)
+		assert.Equal(t, "provider_options."+name, notifErr.Fields[0].Field)
+	}
+
+	assert.Error(t, specs.Validate("test", map[string]any{"label": "abcdef"}))
+	assert.Error(t, specs.Validate("test", map[string]any{"timeout": 61}))
+	assert.Error(t, specs.Validate("test", map[string]any{"tags": []string{"a", "b", "c"}}))
+}
+
+func TestValidateProviderOptions(t *testing.T) {
+	mock := NewMockSMSProvider(config.SMSProviderConfig{Enabled: true})
+	assert.NoError(t, ValidateProviderOptions(mock, map[string]any{"shorten_urls": true}))
+	assert.Error(t, ValidateProviderOptions(mock, map[string]any{"messaging_service_sid": "not-a-sid"}))
+
+	// SMTP declares no options
+	smtp := NewSMTPEmailProvider(config.EmailProviderConfig{Enabled: true})
+	assert.NoError(t, ValidateProviderOptions(smtp, nil))
+	assert.Error(t, ValidateProviderOptions(smtp, map[string]any{"categories": []string{"orders"}}))
+}
+
+func TestMockProviders_RecordProviderOptions(t *testing.T) {
+	ctx := context.Background()
+
+	sms := createTestSMSNotification()
+	sms.ProviderOptions = map[string]any{"messaging_service_sid": "MG" + "0123456789abcdef0123456789abcdef"}
+	smsProvider := NewMockSMSProvider(config.SMSProviderConfig{Enabled: true})
+	_, err := smsProvider.SendSMS(ctx, sms)
+	require.NoError(t, err)
+	sent := smsProvider.GetSentSMS()
+	require.Len(t, sent, 1)
+	assert.Equal(t, sms.ProviderOptions, sent[0].ProviderOptions)
+	assert.Equal(t, "MG0123456789abcdef0123456789abcdef", sent[0].ProviderData["messaging_service_sid"])
+
+	push := createTestPushNotification()
+	push.Platform = "android"
+	push.DeviceToken = testAndroidToken
+	push.ProviderOptions = map[string]any{"analytics_label": "spring_sale", "direct_boot_ok": true}
+	pushProvider := NewMockPushProvider(config.PushProviderConfig{Enabled: true})
+	_, err = pushProvider.SendPush(ctx, push)
+	require.NoError(t, err)
+	sentPush := pushProvider.GetSentPush()
+	require.Len(t, sentPush, 1)
+	message := sentPush[0].Payload.Body["message"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"analytics_label": "spring_sale"}, message["fcm_options"])
+	assert.Equal(t, true, message["android"].(map[string]interface{})["direct_boot_ok"])
+
+	push.ProviderOptions = map[string]any{"analytics_label": "has spaces"}
+	_, err = pushProvider.SendPush(ctx, push)
+	assert.Error(t, err)
+}
@@ -0,0 +1,439 @@
+// Package providertest checks that email, SMS and push providers meet the
+// contract of their interfaces, so every implementation behaves the same
+// way to the dispatcher, retries and failover. A provider's tests run the
+// suite for its channel against fresh instances of it:
+//
+//	func TestConformance(t *testing.T) {
+//		providertest.TestSMSProvider(t, providertest.SMSSuite{
+//			NewProvider: func(t *testing.T) interfaces.SMSProvider { return newTestProvider(t) },
+//		})
+//	}
+//
+// The contract:
+//   - GetType and GetConfig report the provider's channel.
+//   - A valid message sends, through the typed API and the generic Send,
+//     and the response carries the notification's ID.
+//   - Invalid recipients and content fail validation with a notification
+//     error blaming the message, not the provider, so it is not retried
+//     elsewhere; see providers.IsProviderFailure.
+//   - A send with a cancelled context fails with ErrorCodeTimeout without
+//     sending, and health checks return promptly once their context ends.
+//   - An unhealthy provider fails health checks and sends with
+//     ErrorCodeProviderUnavailable.
+package providertest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// cancelBound is how long a call may run after its context has ended
+const cancelBound = time.Second
+
+// Defaults of the suites' recipients
+const (
+	DefaultEmailAddress = "jane@example.com"
+	DefaultPhoneNumber  = "+14155550100"
+	DefaultPushPlatform = "ios"
+)
+
+// DefaultDeviceToken is a well-formed iOS device token
+var DefaultDeviceToken = strings.Repeat("a1", 32)
+
+// EmailSuite configures the email provider checks
+type EmailSuite struct {
+	NewProvider func(t *testing.T) interfaces.EmailProvider // a fresh provider for each check
+	Address     string                                      // a recipient the provider accepts; DefaultEmailAddress when empty
+	From        string                                      // sender; the provider's default when empty
+
+	// SetHealthy marks a provider healthy or not. The health checks are
+	// skipped without it.
+	SetHealthy func(provider interfaces.EmailProvider, healthy bool)
+}
+
+// SMSSuite configures the SMS provider checks
+type SMSSuite struct {
+	NewProvider func(t *testing.T) interfaces.SMSProvider // a fresh provider for each check
+	PhoneNumber string                                    // a number the provider accepts; DefaultPhoneNumber when empty
+
+	// SetHealthy marks a provider healthy or not. The health checks are
+	// skipped without it.
+	SetHealthy func(provider interfaces.SMSProvider, healthy bool)
+}
+
+// PushSuite configures the push provider checks
+type PushSuite struct {
+	NewProvider func(t *testing.T) interfaces.PushProvider // a fresh provider for each check
+	DeviceToken string                                     // a token the provider accepts; DefaultDeviceToken when empty
+	Platform    string                                     // the token's platform; DefaultPushPlatform when empty
+
+	// SetHealthy marks a provider healthy or not. The health checks are
+	// skipped without it.
+	SetHealthy func(provider interfaces.PushProvider, healthy bool)
+}
+
+// TestEmailProvider runs the email provider checks
+func TestEmailProvider(t *testing.T, suite EmailSuite) {
+	if suite.Address == "" {
+		suite.Address = DefaultEmailAddress
+	}
+	email := func() *models.EmailNotification {
+		notification := newNotification(models.NotificationTypeEmail, suite.Address)
+		return &models.EmailNotification{
+			Notification: notification,
+			To:           []string{suite.Address},
+			From:         suite.From,
+			TextBody:     notification.Body,
+		}
+	}
+
+	t.Run("Type", func(t *testing.T) {
+		checkType(t, suite.NewProvider(t), models.NotificationTypeEmail)
+	})
+
+	t.Run("Send", func(t *testing.T) {
+		provider := suite.NewProvider(t)
+		valid := email()
+		response, err := provider.SendEmail(context.Background(), valid)
+		checkSent(t, "SendEmail", valid.ID, response, err)
+
+		notification := newNotification(models.NotificationTypeEmail, suite.Address)
+		response, err = provider.Send(context.Background(), &notification)
+		checkSent(t, "Send", notification.ID, response, err)
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		provider := suite.NewProvider(t)
+		if err := provider.ValidateEmailAddress(suite.Address); err != nil {
+			t.Errorf("ValidateEmailAddress(%q) = %v, want nil", suite.Address, err)
+		}
+		for _, address := range []string{"", "not-an-address", "jane@"} {
+			checkMessageFault(t, "ValidateEmailAddress("+address+")", provider.ValidateEmailAddress(address))
+		}
+
+		noRecipient := email()
+		noRecipient.To = nil
+		_, err := provider.SendEmail(context.Background(), noRecipient)
+		checkMessageFault(t, "SendEmail without a recipient", err)
+
+		badRecipient := email()
+		badRecipient.To = []string{"not-an-address"}
+		_, err = provider.SendEmail(context.Background(), badRecipient)
+		checkMessageFault(t, "SendEmail to an invalid address", err)
+
+		noSubject := email()
+		noSubject.Subject = ""
+		_, err = provider.SendEmail(context.Background(), noSubject)
+		checkMessageFault(t, "SendEmail without a subject", err)
+
+		noBody := email()
+		noBody.TextBody, noBody.HTMLBody = "", ""
+		_, err = provider.SendEmail(context.Background(), noBody)
+		checkMessageFault(t, "SendEmail without a body", err)
+	})
+
+	t.Run("ContextCancellation", func(t *testing.T) {
+		provider := suite.NewProvider(t)
+		checkCancelled(t, "SendEmail", func(ctx context.Context) error {
+			_, err := provider.SendEmail(ctx, email())
+			return err
+		})
+		checkHealthCancelled(t, provider)
+	})
+
+	t.Run("Health", func(t *testing.T) {
+		if suite.SetHealthy == nil {
+			t.Skip("the suite cannot mark the provider unhealthy")
+		}
+		provider := suite.NewProvider(t)
+		checkHealth(t, provider, func(healthy bool) { suite.SetHealthy(provider, healthy) }, func() error {
+			_, err := provider.SendEmail(context.Background(), email())
+			return err
+		})
+	})
+}
+
+// TestSMSProvider runs the SMS provider checks
+func TestSMSProvider(t *testing.T, suite SMSSuite) {
+	if suite.PhoneNumber == "" {
+		suite.PhoneNumber = DefaultPhoneNumber
+	}
+	sms := func() *models.SMSNotification {
+		notification := newNotification(models.NotificationTypeSMS, suite.PhoneNumber)
+		return &models.SMSNotification{
+			Notification: notification,
+			PhoneNumber:  suite.PhoneNumber,
+			Message:      notification.Body,
+		}
+	}
+
+	t.Run("Type", func(t *testing.T) {
+		checkType(t, suite.NewProvider(t), models.NotificationTypeSMS)
+	})
+
+	t.Run("Send", func(t *testing.T) {
+		provider := suite.NewProvider(t)
+		valid := sms()
+		response, err := provider.SendSMS(context.Background(), valid)
+		checkSent(t, "SendSMS", valid.ID, response, err)
+
+		notification := newNotification(models.NotificationTypeSMS, suite.PhoneNumber)
+		response, err = provider.Send(context.Background(), &notification)
+		checkSent(t, "Send", notification.ID, response, err)
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		provider := suite.NewProvider(t)
+		if err := provider.ValidatePhoneNumber(suite.PhoneNumber, ""); err != nil {
+			t.Errorf("ValidatePhoneNumber(%q) = %v, want nil", suite.PhoneNumber, err)
+		}
+		for _, number := range []string{"", "not-a-number", "12"} {
+			checkMessageFault(t, "ValidatePhoneNumber("+number+")", provider.ValidatePhoneNumber(number, ""))
+		}
+
+		badNumber := sms()
+		badNumber.PhoneNumber = "not-a-number"
+		_, err := provider.SendSMS(context.Background(), badNumber)
+		checkMessageFault(t, "SendSMS to an invalid number", err)
+
+		noMessage := sms()
+		noMessage.Message = ""
+		_, err = provider.SendSMS(context.Background(), noMessage)
+		checkMessageFault(t, "SendSMS without a message", err)
+	})
+
+	t.Run("ContextCancellation", func(t *testing.T) {
+		provider := suite.NewProvider(t)
+		checkCancelled(t, "SendSMS", func(ctx context.Context) error {
+			_, err := provider.SendSMS(ctx, sms())
+			return err
+		})
+		checkHealthCancelled(t, provider)
+	})
+
+	t.Run("Health", func(t *testing.T) {
+		if suite.SetHealthy == nil {
+			t.Skip("the suite cannot mark the provider unhealthy")
+		}
+		provider := suite.NewProvider(t)
+		checkHealth(t, provider, func(healthy bool) { suite.SetHealthy(provider, healthy) }, func() error {
+			_, err := provider.SendSMS(context.Background(), sms())
+			return err
+		})
+	})
+}
+
+// TestPushProvider runs the push provider checks
+func TestPushProvider(t *testing.T, suite PushSuite) {
+	if suite.DeviceToken == "" {
+		suite.DeviceToken = DefaultDeviceToken
+	}
+	if suite.Platform == "" {
+		suite.Platform = DefaultPushPlatform
+	}
+	push := func() *models.PushNotification {
+		notification := newNotification(models.NotificationTypePush, suite.DeviceToken)
+		return &models.PushNotification{
+			Notification: notification,
+			DeviceToken:  suite.DeviceToken,
+			Platform:     suite.Platform,
+			Title:        notification.Subject,
+			Message:      notification.Body,
+		}
+	}
+
+	t.Run("Type", func(t *testing.T) {
+		checkType(t, suite.NewProvider(t), models.NotificationTypePush)
+	})
+
+	t.Run("Send", func(t *testing.T) {
+		provider := suite.NewProvider(t)
+		valid := push()
+		response, err := provider.SendPush(context.Background(), valid)
+		checkSent(t, "SendPush", valid.ID, response, err)
+
+		notification := newNotification(models.NotificationTypePush, suite.DeviceToken)
+		notification.Metadata = map[string]string{"platform": suite.Platform}
+		response, err = provider.Send(context.Background(), &notification)
+		checkSent(t, "Send", notification.ID, response, err)
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		provider := suite.NewProvider(t)
+		if err := provider.ValidateDeviceToken(suite.DeviceToken, suite.Platform); err != nil {
+			t.Errorf("ValidateDeviceToken(%q, %q) = %v, want nil", suite.DeviceToken, suite.Platform, err)
+		}
+		checkMessageFault(t, "ValidateDeviceToken without a token", provider.ValidateDeviceToken("", suite.Platform))
+		checkMessageFault(t, "ValidateDeviceToken on an unknown platform", provider.ValidateDeviceToken(suite.DeviceToken, "fax"))
+
+		noToken := push()
+		noToken.DeviceToken = ""
+		_, err := provider.SendPush(context.Background(), noToken)
+		checkMessageFault(t, "SendPush without a token", err)
+
+		noContent := push()
+		noContent.Title, noContent.Message = "", ""
+		_, err = provider.SendPush(context.Background(), noContent)
+		checkMessageFault(t, "SendPush without a title or message", err)
+	})
+
+	t.Run("ContextCancellation", func(t *testing.T) {
+		provider := suite.NewProvider(t)
+		checkCancelled(t, "SendPush", func(ctx context.Context) error {
+			_, err := provider.SendPush(ctx, push())
+			return err
+		})
+		checkHealthCancelled(t, provider)
+	})
+
+	t.Run("Health", func(t *testing.T) {
+		if suite.SetHealthy == nil {
+			t.Skip("the suite cannot mark the provider unhealthy")
+		}
+		provider := suite.NewProvider(t)
+		checkHealth(t, provider, func(healthy bool) { suite.SetHealthy(provider, healthy) }, func() error {
+			_, err := provider.SendPush(context.Background(), push())
+			return err
+		})
+	})
+}
+
+// newNotification returns a valid notification of a channel
+func newNotification(notificationType models.NotificationType, recipient string) models.Notification {
+	return models.Notification{
+		ID:        uuid.New(),
+		Type:      notificationType,
+		Priority:  models.PriorityNormal,
+		Status:    models.StatusPending,
+		Recipient: recipient,
+		Subject:   "Conformance check",
+		Body:      "Your code is 123456",
+		CreatedAt: time.Now(),
+	}
+}
+
+// checkType checks a provider reports its channel
+func checkType(t *testing.T, provider interfaces.NotificationProvider, notificationType models.NotificationType) {
+	t.Helper()
+
+	if got := provider.GetType(); got != notificationType {
+		t.Errorf("GetType() = %s, want %s", got, notificationType)
+	}
+	cfg := provider.GetConfig()
+	if cfg.Type != notificationType {
+		t.Errorf("GetConfig().Type = %s, want %s", cfg.Type, notificationType)
+	}
+	if cfg.Name == "" {
+		t.Error("GetConfig().Name is empty")
+	}
+}
+
+// checkSent checks a valid send succeeded with a response for its notification
+func checkSent(t *testing.T, call string, id uuid.UUID, response *models.NotificationResponse, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatalf("%s of a valid message failed: %v", call, err)
+	}
+	if response == nil {
+		t.Fatalf("%s returned neither a response nor an error", call)
+	}
+	if response.ID != id {
+		t.Errorf("%s response ID = %s, want the notification's %s", call, response.ID, id)
+	}
+	switch response.Status {
+	case models.StatusPending, models.StatusSent, models.StatusDelivered:
+	default:
+		t.Errorf("%s response status = %s, want pending, sent or delivered", call, response.Status)
+	}
+}
+
+// checkMessageFault checks an error blames the message rather than the
+// provider
+func checkMessageFault(t *testing.T, call string, err error) {
+	t.Helper()
+
+	if err == nil {
+		t.Errorf("%s succeeded, want a validation error", call)
+		return
+	}
+	if _, ok := errors.AsNotificationError(err); !ok {
+		t.Errorf("%s returned %T (%v), want a notification error", call, err, err)
+		return
+	}
+	if providers.IsProviderFailure(err) {
+		t.Errorf("%s returned %v, which blames the provider rather than the message", call, err)
+	}
+}
+
+// checkCancelled checks a send with a cancelled context fails promptly
+// with ErrorCodeTimeout
+func checkCancelled(t *testing.T, call string, send func(ctx context.Context) error) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	err := send(ctx)
+	if elapsed := time.Since(start); elapsed > cancelBound {
+		t.Errorf("%s ran for %v after its context was cancelled", call, elapsed)
+	}
+	if err == nil {
+		t.Fatalf("%s with a cancelled context succeeded", call)
+	}
+	notifErr, ok := errors.AsNotificationError(err)
+	if !ok || notifErr.Code != errors.ErrorCodeTimeout {
+		t.Errorf("%s with a cancelled context returned %v, want %s", call, err, errors.ErrorCodeTimeout)
+	}
+}
+
+// checkHealthCancelled checks a health check returns promptly once its
+// context has ended
+func checkHealthCancelled(t *testing.T, provider interfaces.NotificationProvider) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	provider.IsHealthy(ctx)
+	if elapsed := time.Since(start); elapsed > cancelBound {
+		t.Errorf("IsHealthy ran for %v after its context was cancelled", elapsed)
+	}
+}
+
+// checkHealth checks a provider's health follows its marks, and that an
+// unhealthy provider's sends fail as provider failures
+func checkHealth(t *testing.T, provider interfaces.NotificationProvider, setHealthy func(bool), send func() error) {
+	t.Helper()
+
+	if err := provider.IsHealthy(context.Background()); err != nil {
+		t.Fatalf("IsHealthy of a new provider = %v, want nil", err)
+	}
+
+	setHealthy(false)
+	for call, err := range map[string]error{"IsHealthy": provider.IsHealthy(context.Background()), "send": send()} {
+		notifErr, ok := errors.AsNotificationError(err)
+		if !ok || notifErr.Code != errors.ErrorCodeProviderUnavailable {
+			t.Errorf("%s of an unhealthy provider = %v, want %s", call, err, errors.ErrorCodeProviderUnavailable)
+		}
+	}
+
+	setHealthy(true)
+	if err := provider.IsHealthy(context.Background()); err != nil {
+		t.Errorf("IsHealthy after recovering = %v, want nil", err)
+	}
+	if err := send(); err != nil {
+		t.Errorf("send after recovering = %v, want nil", err)
+	}
+}
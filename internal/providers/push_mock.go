@@ -0,0 +1,744 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/templateschema"
+	"github.com/nareshkumar-microsoft/notificationService/internal/templatevars"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// Platform payload limits
+const (
+	maxIOSTitleLength = 178
+	maxIOSBodyLength  = 1000
+	maxAndroidTitle   = 200
+	maxAndroidBody    = 1000
+	maxWebTitleLength = 120
+	maxWebBodyLength  = 500
+)
+
+// MockPushProvider implements the PushProvider interface for testing and
+// development. It is safe for concurrent use.
+type MockPushProvider struct {
+	config   config.PushProviderConfig
+	sentPush *SentLog[SentPush]
+	sim      *simulator
+
+	mu           sync.RWMutex
+	templates    map[string]*PushTemplate
+	compiled     *templatevars.Cache // compiled template fields
+	deviceTokens map[string]string   // Token to status mapping ("active", "unregistered")
+	batchCalls   int
+	healthy      bool
+}
+
+// maxPushBatchSize is the number of messages one FCM batch request may carry
+const maxPushBatchSize = 500
+
+// PushTemplate represents a push notification template
+type PushTemplate struct {
+	ID        string                `json:"id"`
+	Name      string                `json:"name"`
+	Title     string                `json:"title"`
+	Message   string                `json:"message"`
+	Variables []string              `json:"variables"`
+	Category  string                `json:"category"`
+	Sound     string                `json:"sound,omitempty"`
+	Badge     int                   `json:"badge,omitempty"`
+	Actions   []models.PushAction   `json:"actions,omitempty"`
+	Data      map[string]string     `json:"data,omitempty"`
+	Schema    templateschema.Schema `json:"schema,omitempty"` // checked against template data before rendering
+	CreatedAt time.Time             `json:"created_at"`
+	UpdatedAt time.Time             `json:"updated_at"`
+	Metadata  map[string]string     `json:"metadata,omitempty"`
+}
+
+// SentPush represents a push notification that was sent (for mock tracking)
+type SentPush struct {
+	ID           uuid.UUID         `json:"id"`
+	DeviceToken  string            `json:"device_token"`
+	Platform     string            `json:"platform"`
+	Title        string            `json:"title"`
+	Message      string            `json:"message"`
+	Badge        int               `json:"badge,omitempty"`
+	Sound        string            `json:"sound,omitempty"`
+	Data         map[string]string `json:"data,omitempty"`
+	PayloadSize  int               `json:"payload_size"`
+	Payload      *PlatformPayload  `json:"payload,omitempty"`
+	SentAt       time.Time         `json:"sent_at"`
+	Status       string            `json:"status"`
+	DeliveredAt  *time.Time        `json:"delivered_at,omitempty"`
+	ProviderData map[string]string `json:"provider_data,omitempty"`
+	// ProviderOptions are the request's provider options, as the provider received them
+	ProviderOptions map[string]any `json:"provider_options,omitempty"`
+}
+
+// NewMockPushProvider creates a new mock push provider
+func NewMockPushProvider(cfg config.PushProviderConfig) *MockPushProvider {
+	provider := &MockPushProvider{
+		config:       cfg,
+		templates:    make(map[string]*PushTemplate),
+		compiled:     templatevars.NewCache(0),
+		sentPush:     newSentLog[SentPush](cfg.SentHistory),
+		sim:          newSimulator("mock-push", defaultPushSimulation),
+		deviceTokens: make(map[string]string),
+		healthy:      true,
+	}
+
+	// Load default templates
+	provider.loadDefaultTemplates()
+
+	return provider
+}
+
+// Send implements the NotificationProvider interface
+func (p *MockPushProvider) Send(ctx context.Context, notification *models.Notification) (*models.NotificationResponse, error) {
+	if !p.isHealthy() {
+		return nil, errors.NewProviderError("mock-push", errors.ErrorCodeProviderUnavailable, "provider is unhealthy")
+	}
+
+	// Convert generic notification to push notification
+	pushNotification, err := p.convertToPushNotification(notification)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.SendPush(ctx, pushNotification)
+}
+
+// SendPush implements the PushProvider interface
+func (p *MockPushProvider) SendPush(ctx context.Context, push *models.PushNotification) (*models.NotificationResponse, error) {
+	if !p.isHealthy() {
+		return nil, errors.NewProviderError("mock-push", errors.ErrorCodeProviderUnavailable, "provider is unhealthy")
+	}
+
+	payloadSize, payload, err := p.preparePush(push)
+	if err != nil {
+		return nil, err
+	}
+
+	// Simulate processing delay. A cancelled send never reaches the
+	// provider, even when the simulation has no latency.
+	if ctx.Err() != nil {
+		return nil, errors.NewNotificationError(errors.ErrorCodeTimeout, "push sending timed out")
+	}
+	select {
+	case <-ctx.Done():
+		return nil, errors.NewNotificationError(errors.ErrorCodeTimeout, "push sending timed out")
+	case <-time.After(p.sim.latency()):
+		// Continue processing
+	}
+
+	if err := p.sim.fail(push.DeviceToken); err != nil {
+		return nil, err
+	}
+
+	return p.recordPush(push, payloadSize, payload), nil
+}
+
+// SendPushBatch implements the BatchPushProvider interface. Like FCM's
+// batch send, the batch is one API call; invalid notifications and
+// unregistered tokens fail on their own without failing the others.
+func (p *MockPushProvider) SendPushBatch(ctx context.Context, pushes []*models.PushNotification) ([]interfaces.BatchResult, error) {
+	if !p.isHealthy() {
+		return nil, errors.NewProviderError("mock-push", errors.ErrorCodeProviderUnavailable, "provider is unhealthy")
+	}
+	if len(pushes) > p.MaxBatchSize() {
+		return nil, errors.NewValidationError("pushes", fmt.Sprintf("batch of %d notifications exceeds the maximum of %d", len(pushes), p.MaxBatchSize()))
+	}
+
+	// Simulate one processing delay for the whole batch
+	select {
+	case <-ctx.Done():
+		return nil, errors.NewNotificationError(errors.ErrorCodeTimeout, "push batch sending timed out")
+	case <-time.After(p.sim.latency()):
+		// Continue processing
+	}
+
+	p.mu.Lock()
+	p.batchCalls++
+	p.mu.Unlock()
+	results := make([]interfaces.BatchResult, len(pushes))
+	for i, push := range pushes {
+		payloadSize, payload, err := p.preparePush(push)
+		if err == nil {
+			err = p.sim.fail(push.DeviceToken)
+		}
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		results[i].Response = p.recordPush(push, payloadSize, payload)
+	}
+	return results, nil
+}
+
+// MaxBatchSize implements the BatchPushProvider interface
+func (p *MockPushProvider) MaxBatchSize() int {
+	return maxPushBatchSize
+}
+
+// BatchCalls returns how many batch API calls were made (for testing)
+func (p *MockPushProvider) BatchCalls() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.batchCalls
+}
+
+// preparePush validates a push notification, formats it for its platform
+// and builds the native payload
+func (p *MockPushProvider) preparePush(push *models.PushNotification) (int, *PlatformPayload, error) {
+	// Validate push notification
+	if err := p.validatePushNotification(push); err != nil {
+		return 0, nil, err
+	}
+
+	// Reject tokens the platform has reported as unregistered
+	p.mu.RLock()
+	status := p.deviceTokens[push.DeviceToken]
+	p.mu.RUnlock()
+	if status == "unregistered" {
+		return 0, nil, errors.NewProviderError("mock-push", errors.ErrorCodeInvalidToken, "device token is no longer registered")
+	}
+
+	// Apply platform-specific formatting
+	p.preprocessForPlatform(push)
+
+	// Map to the native platform payload
+	payload, err := BuildPlatformPayload(push)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	payloadSize, err := checkPayloadSize(push.Platform, payload)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return payloadSize, payload, nil
+}
+
+// recordPush stores a sent push notification and returns the provider's response
+func (p *MockPushProvider) recordPush(push *models.PushNotification, payloadSize int, payload *PlatformPayload) *models.NotificationResponse {
+	// Create sent push record
+	sentPush := SentPush{
+		ID:          push.ID,
+		DeviceToken: push.DeviceToken,
+		Platform:    strings.ToLower(push.Platform),
+		Title:       push.Title,
+		Message:     push.Message,
+		Badge:       push.Badge,
+		Sound:       push.Sound,
+		Data:        push.Data,
+		PayloadSize: payloadSize,
+		Payload:     payload,
+		SentAt:      time.Now(),
+		Status:      "sent",
+
+		ProviderOptions: push.ProviderOptions,
+		ProviderData: map[string]string{
+			"provider":    "mock-push",
+			"message_id":  fmt.Sprintf("push-%s", push.ID.String()),
+			"platform":    strings.ToLower(push.Platform),
+			"queue_time":  "120ms",
+			"retry_count": "0",
+		},
+	}
+
+	// Simulate delivery
+	if deliveredAt, delivered := p.sim.delivery(sentPush.SentAt); delivered {
+		sentPush.DeliveredAt = &deliveredAt
+		sentPush.Status = "delivered"
+		sentPush.ProviderData["delivery_time"] = deliveredAt.Format(time.RFC3339)
+		sentPush.ProviderData["delivery_delay"] = deliveredAt.Sub(sentPush.SentAt).String()
+	}
+
+	// Track the token as seen and store the sent push
+	p.mu.Lock()
+	p.deviceTokens[push.DeviceToken] = "active"
+	p.mu.Unlock()
+	p.sentPush.add(sentPush)
+
+	// Create response
+	now := time.Now()
+	response := &models.NotificationResponse{
+		ID:         push.ID,
+		Status:     models.StatusSent,
+		Message:    fmt.Sprintf("Push notification sent to %s device", sentPush.Platform),
+		ProviderID: sentPush.ProviderData["message_id"],
+		SentAt:     &now,
+
+		ProviderMetadata: maps.Clone(sentPush.ProviderData),
+	}
+
+	return response
+}
+
+// SendPushToTopic implements the TopicPushProvider interface
+func (p *MockPushProvider) SendPushToTopic(ctx context.Context, topic string, push *models.PushNotification) (*models.NotificationResponse, error) {
+	if !p.isHealthy() {
+		return nil, errors.NewProviderError("mock-push", errors.ErrorCodeProviderUnavailable, "provider is unhealthy")
+	}
+
+	if topic == "" {
+		return nil, errors.NewValidationError("topic", "topic is required")
+	}
+
+	if push.Title == "" && push.Message == "" && !isBackgroundPush(push) {
+		return nil, errors.NewValidationError("message", "push notification must have a title or message")
+	}
+
+	if err := validateRichPayload(push); err != nil {
+		return nil, err
+	}
+	if err := p.ValidateProviderOptions(push.ProviderOptions); err != nil {
+		return nil, err
+	}
+
+	// Topics are an FCM feature, so the payload is FCM's
+	fcm := *push
+	fcm.Platform = "android"
+	payload, err := BuildPlatformPayload(&fcm)
+	if err != nil {
+		return nil, err
+	}
+	payloadSize, err := checkPayloadSize(fcm.Platform, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// Simulate processing delay
+	select {
+	case <-ctx.Done():
+		return nil, errors.NewNotificationError(errors.ErrorCodeTimeout, "push sending timed out")
+	case <-time.After(p.sim.latency()):
+		// Continue processing
+	}
+
+	destination := fmt.Sprintf("/topics/%s", topic)
+	if err := p.sim.fail(destination); err != nil {
+		return nil, err
+	}
+
+	sentPush := SentPush{
+		ID:          push.ID,
+		DeviceToken: destination,
+		Platform:    "topic",
+		Title:       push.Title,
+		Message:     push.Message,
+		Data:        push.Data,
+		PayloadSize: payloadSize,
+		SentAt:      time.Now(),
+		Status:      "sent",
+		ProviderData: map[string]string{
+			"provider":   "mock-push",
+			"message_id": fmt.Sprintf("topic-%s", push.ID.String()),
+			"topic":      topic,
+		},
+	}
+
+	p.sentPush.add(sentPush)
+
+	now := time.Now()
+	response := &models.NotificationResponse{
+		ID:         push.ID,
+		Status:     models.StatusSent,
+		Message:    fmt.Sprintf("Push notification sent to topic %s", topic),
+		ProviderID: sentPush.ProviderData["message_id"],
+		SentAt:     &now,
+
+		ProviderMetadata: maps.Clone(sentPush.ProviderData),
+	}
+
+	return response, nil
+}
+
+// ValidateDeviceToken implements the PushProvider interface
+func (p *MockPushProvider) ValidateDeviceToken(token, platform string) error {
+	return utils.ValidateDeviceToken(token, platform)
+}
+
+// GetPlatformConfig implements the PushProvider interface
+func (p *MockPushProvider) GetPlatformConfig(platform string) interfaces.PlatformConfig {
+	platform = strings.ToLower(platform)
+
+	platformConfig := interfaces.PlatformConfig{
+		Platform:   platform,
+		MaxPayload: MaxPayloadSize(platform),
+		Settings:   make(map[string]string),
+	}
+
+	switch platform {
+	case "ios":
+		platformConfig.BundleID = p.config.APNSBundleID
+		platformConfig.TeamID = p.config.APNSTeamID
+		platformConfig.Settings["service"] = "apns"
+		platformConfig.Settings["production"] = fmt.Sprintf("%t", p.config.APNSProduction)
+	case "android":
+		platformConfig.APIKey = p.config.FCMServerKey
+		platformConfig.ProjectID = p.config.FCMProjectID
+		platformConfig.Settings["service"] = "fcm"
+	case "web":
+		platformConfig.ProjectID = p.config.FCMProjectID
+		platformConfig.Settings["service"] = "webpush"
+	}
+
+	return platformConfig
+}
+
+// GetType implements the NotificationProvider interface
+func (p *MockPushProvider) GetType() models.NotificationType {
+	return models.NotificationTypePush
+}
+
+// IsHealthy implements the NotificationProvider interface
+func (p *MockPushProvider) IsHealthy(ctx context.Context) error {
+	if !p.isHealthy() {
+		return errors.NewProviderError("mock-push", errors.ErrorCodeProviderUnavailable, "provider is marked as unhealthy")
+	}
+
+	// Simulate health check delay
+	select {
+	case <-ctx.Done():
+		return errors.NewNotificationError(errors.ErrorCodeTimeout, "health check timed out")
+	case <-time.After(60 * time.Millisecond):
+		return nil
+	}
+}
+
+// GetConfig implements the NotificationProvider interface
+func (p *MockPushProvider) GetConfig() interfaces.ProviderConfig {
+	return interfaces.ProviderConfig{
+		Name:       "Mock Push Provider",
+		Type:       models.NotificationTypePush,
+		Enabled:    p.config.Enabled,
+		Priority:   3,
+		MaxRetries: 3,
+		Timeout:    30,
+		RateLimit: interfaces.RateLimitConfig{
+			Enabled:        true,
+			RequestsPerMin: 300,
+			BurstSize:      20,
+		},
+		Settings: map[string]string{
+			"provider_type":       "mock",
+			"version":             "1.0.0",
+			"features":            "templates,validation,platform_formatting,delivery_tracking,topics,rich_payloads",
+			"supported_platforms": "ios,android,web",
+		},
+	}
+}
+
+// GetTemplate retrieves a push template by ID
+func (p *MockPushProvider) GetTemplate(templateID string) (*PushTemplate, error) {
+	p.mu.RLock()
+	template, exists := p.templates[templateID]
+	p.mu.RUnlock()
+
+	if !exists {
+		return nil, errors.NewNotificationError(errors.ErrorCodeTemplateNotFound, fmt.Sprintf("template not found: %s", templateID))
+	}
+	return template, nil
+}
+
+// Templates returns the push templates, sorted by ID
+func (p *MockPushProvider) Templates() []*PushTemplate {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return sortedTemplates(p.templates)
+}
+
+// AddTemplate adds a new push template
+func (p *MockPushProvider) AddTemplate(template *PushTemplate) error {
+	if err := template.prepare(time.Now()); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.templates[template.ID] = template
+	p.mu.Unlock()
+	return nil
+}
+
+// ReplaceTemplates replaces every push template at once. Nothing is
+// replaced unless all the templates are valid.
+func (p *MockPushProvider) ReplaceTemplates(templates []*PushTemplate) error {
+	replaced, err := keyTemplates(templates, func(t *PushTemplate) string { return t.ID }, (*PushTemplate).prepare)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.templates = replaced
+	p.mu.Unlock()
+	return nil
+}
+
+// prepare checks a template before it is stored
+func (t *PushTemplate) prepare(now time.Time) error {
+	if err := t.Schema.Check(); err != nil {
+		return err
+	}
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+
+	t.CreatedAt = now
+	t.UpdatedAt = now
+	return nil
+}
+
+// RenderTemplate renders a push template with provided data
+func (p *MockPushProvider) RenderTemplate(templateID string, data map[string]string) (*PushTemplate, error) {
+	template, err := p.GetTemplate(templateID)
+	if err != nil {
+		return nil, err
+	}
+	data = template.Schema.Apply(data)
+	if err := template.Schema.Validate(data); err != nil {
+		return nil, err
+	}
+
+	// Clone template for rendering
+	now := time.Now()
+	rendered := &PushTemplate{
+		ID:        template.ID,
+		Name:      template.Name,
+		Title:     p.renderField(template, "title", template.Title, data, now),
+		Message:   p.renderField(template, "message", template.Message, data, now),
+		Variables: template.Variables,
+		Category:  template.Category,
+		Sound:     template.Sound,
+		Badge:     template.Badge,
+		Actions:   template.Actions,
+		Data:      template.Data,
+		Schema:    template.Schema,
+		CreatedAt: template.CreatedAt,
+		UpdatedAt: template.UpdatedAt,
+		Metadata:  template.Metadata,
+	}
+
+	return rendered, nil
+}
+
+// GetSentPush returns the kept sent push notifications, oldest first (for testing)
+func (p *MockPushProvider) GetSentPush() []SentPush {
+	return p.sentPush.All()
+}
+
+// SentPushes returns the log of sent push notifications (for testing)
+func (p *MockPushProvider) SentPushes() *SentLog[SentPush] {
+	return p.sentPush
+}
+
+// ClearSentPush clears the sent push history (for testing)
+func (p *MockPushProvider) ClearSentPush() {
+	p.sentPush.Clear()
+}
+
+// SetSimulation replaces the simulated latency, delivery and failures,
+// restarting its random sequence (for testing)
+func (p *MockPushProvider) SetSimulation(sim Simulation) {
+	p.sim.set(sim)
+}
+
+// Simulation returns the simulated behavior (for testing)
+func (p *MockPushProvider) Simulation() Simulation {
+	return p.sim.get()
+}
+
+// SetHealthy sets the provider health status (for testing)
+func (p *MockPushProvider) SetHealthy(healthy bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.healthy = healthy
+}
+
+// isHealthy reports whether the provider is set healthy
+func (p *MockPushProvider) isHealthy() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.healthy
+}
+
+// UnregisterToken marks a device token as unregistered, simulating an app
+// uninstall reported by APNs/FCM (for testing)
+func (p *MockPushProvider) UnregisterToken(token string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.deviceTokens[token] = "unregistered"
+}
+
+// Helper methods
+
+// convertToPushNotification converts a generic notification to a push notification
+func (p *MockPushProvider) convertToPushNotification(notification *models.Notification) (*models.PushNotification, error) {
+	if notification.Type != models.NotificationTypePush {
+		return nil, errors.NewValidationError("type", "notification type must be push")
+	}
+
+	pushNotification := &models.PushNotification{
+		Notification: *notification,
+		DeviceToken:  notification.Recipient,
+		Title:        notification.Subject,
+		Message:      notification.Body,
+	}
+
+	// Extract platform-specific fields from metadata if available
+	if notification.Metadata != nil {
+		if platform, exists := notification.Metadata["platform"]; exists {
+			pushNotification.Platform = platform
+		}
+		if sound, exists := notification.Metadata["sound"]; exists {
+			pushNotification.Sound = sound
+		}
+	}
+
+	return pushNotification, nil
+}
+
+// validatePushNotification validates a push notification
+func (p *MockPushProvider) validatePushNotification(push *models.PushNotification) error {
+	if push.Platform == "" {
+		return errors.NewValidationError("platform", "platform is required for push notifications")
+	}
+
+	// Validate device token
+	if err := p.ValidateDeviceToken(push.DeviceToken, push.Platform); err != nil {
+		return err
+	}
+
+	// Validate content
+	if push.Title == "" && push.Message == "" && !isBackgroundPush(push) {
+		return errors.NewValidationError("message", "push notification must have a title or message")
+	}
+
+	if push.Badge < 0 {
+		return errors.NewValidationError("badge", "badge count cannot be negative")
+	}
+
+	if err := p.ValidateProviderOptions(push.ProviderOptions); err != nil {
+		return err
+	}
+	return validateRichPayload(push)
+}
+
+// mockPushOptions are the provider options of the mock push provider. They
+// map to FCM fields, so only Android messages carry them.
+var mockPushOptions = OptionSpecs{
+	"analytics_label": {Kind: OptionString, Pattern: regexp.MustCompile(`^[a-zA-Z0-9\-_.~%]{1,50}$`), Description: "Label for the message in FCM analytics"},
+	"direct_boot_ok":  {Kind: OptionBoolean, Description: "Deliver while an Android device is in direct boot mode"},
+}
+
+// ValidateProviderOptions implements the OptionsProvider interface
+func (p *MockPushProvider) ValidateProviderOptions(options map[string]any) error {
+	return mockPushOptions.Validate("mock-push", options)
+}
+
+// preprocessForPlatform applies platform-specific formatting rules
+func (p *MockPushProvider) preprocessForPlatform(push *models.PushNotification) {
+	switch strings.ToLower(push.Platform) {
+	case "ios":
+		p.preprocessIOS(push)
+	case "android":
+		p.preprocessAndroid(push)
+	case "web":
+		p.preprocessWeb(push)
+	}
+}
+
+// preprocessIOS applies APNs formatting rules
+func (p *MockPushProvider) preprocessIOS(push *models.PushNotification) {
+	push.Title = utils.TruncateBytes(push.Title, maxIOSTitleLength)
+	push.Message = utils.TruncateBytes(push.Message, maxIOSBodyLength)
+	if push.Sound == "" && !isBackgroundPush(push) {
+		push.Sound = "default"
+	}
+}
+
+// preprocessAndroid applies FCM formatting rules
+func (p *MockPushProvider) preprocessAndroid(push *models.PushNotification) {
+	push.Title = utils.TruncateBytes(push.Title, maxAndroidTitle)
+	push.Message = utils.TruncateBytes(push.Message, maxAndroidBody)
+	// Android has no app icon badge count in the notification payload
+	push.Badge = 0
+}
+
+// preprocessWeb applies Web Push formatting rules
+func (p *MockPushProvider) preprocessWeb(push *models.PushNotification) {
+	push.Title = utils.TruncateBytes(push.Title, maxWebTitleLength)
+	push.Message = utils.TruncateBytes(push.Message, maxWebBodyLength)
+	// Browsers have no notification sound or badge count support
+	push.Sound = ""
+	push.Badge = 0
+}
+
+// renderField replaces a template field's variables with provided data and
+// computed variables such as {{date}}, at the time now. The field is
+// compiled once per template version.
+func (p *MockPushProvider) renderField(template *PushTemplate, field, text string, data map[string]string, now time.Time) string {
+	return p.compiled.Get(templateCacheKey(template.ID, template.UpdatedAt, field), text).Render(data, now)
+}
+
+// loadDefaultTemplates loads default push templates
+func (p *MockPushProvider) loadDefaultTemplates() {
+	// New message template
+	messageTemplate := &PushTemplate{
+		ID:        "new_message",
+		Name:      "New Message",
+		Title:     "New message from {{sender_name}}",
+		Message:   "{{message_preview}}",
+		Variables: []string{"sender_name", "message_preview"},
+		Category:  "messaging",
+		Sound:     "default",
+		Actions: []models.PushAction{
+			{ID: "reply", Title: "Reply"},
+			{ID: "mark_read", Title: "Mark as Read"},
+		},
+	}
+
+	// Order update template
+	orderTemplate := &PushTemplate{
+		ID:        "order_update",
+		Name:      "Order Update",
+		Title:     "Order {{order_id}} {{order_status}}",
+		Message:   "Your order {{order_id}} is now {{order_status}}.",
+		Variables: []string{"order_id", "order_status"},
+		Category:  "transactional",
+		Actions: []models.PushAction{
+			{ID: "view_order", Title: "View Order"},
+		},
+	}
+
+	// Reminder template
+	reminderTemplate := &PushTemplate{
+		ID:        "reminder_push",
+		Name:      "Reminder",
+		Title:     "Reminder",
+		Message:   "{{reminder_text}}",
+		Variables: []string{"reminder_text"},
+		Category:  "general",
+		Sound:     "default",
+	}
+
+	p.AddTemplate(messageTemplate)
+	p.AddTemplate(orderTemplate)
+	p.AddTemplate(reminderTemplate)
+}
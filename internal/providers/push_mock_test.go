@@ -0,0 +1,732 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+const (
+	testIOSToken     = "a1b2c3d4e5f6a1b2c3d4e5f6a1b2c3d4e5f6a1b2c3d4e5f6a1b2c3d4e5f6a1b2"
+	testWebPushToken = "web-push-subscription-token-0001"
+)
+
+var testAndroidToken = strings.Repeat("f", 152)
+
+func TestNewMockPushProvider(t *testing.T) {
+	cfg := config.PushProviderConfig{
+		Provider: "mock",
+		Enabled:  true,
+	}
+
+	provider := NewMockPushProvider(cfg)
+
+	assert.NotNil(t, provider)
+	assert.Equal(t, cfg, provider.config)
+	assert.True(t, provider.healthy)
+	assert.Len(t, provider.templates, 3) // Default templates loaded
+	assert.Empty(t, provider.sentPush.All())
+}
+
+func TestMockPushProvider_GetType(t *testing.T) {
+	provider := createTestPushProvider()
+	assert.Equal(t, models.NotificationTypePush, provider.GetType())
+}
+
+func TestMockPushProvider_IsHealthy(t *testing.T) {
+	provider := createTestPushProvider()
+	ctx := context.Background()
+
+	assert.NoError(t, provider.IsHealthy(ctx))
+
+	provider.SetHealthy(false)
+	err := provider.IsHealthy(ctx)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unhealthy")
+}
+
+func TestMockPushProvider_GetPlatformConfig(t *testing.T) {
+	provider := NewMockPushProvider(config.PushProviderConfig{
+		Provider:     "mock",
+		Enabled:      true,
+		FCMProjectID: "test-project",
+		APNSBundleID: "com.example.app",
+	})
+
+	iosConfig := provider.GetPlatformConfig("iOS")
+	assert.Equal(t, "ios", iosConfig.Platform)
+	assert.Equal(t, "com.example.app", iosConfig.BundleID)
+	assert.Equal(t, 4096, iosConfig.MaxPayload)
+
+	androidConfig := provider.GetPlatformConfig("android")
+	assert.Equal(t, "test-project", androidConfig.ProjectID)
+	assert.Equal(t, "fcm", androidConfig.Settings["service"])
+	assert.Equal(t, 3993, provider.GetPlatformConfig("web").MaxPayload)
+}
+
+func TestMockPushProvider_SendPushBatch(t *testing.T) {
+	provider := createTestPushProvider()
+
+	valid := createTestPushNotification()
+	invalid := createTestPushNotification()
+	invalid.DeviceToken = ""
+
+	results, err := provider.SendPushBatch(context.Background(), []*models.PushNotification{valid, invalid})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.NoError(t, results[0].Err)
+	assert.Equal(t, valid.ID, results[0].Response.ID)
+	assert.Error(t, results[1].Err)
+	assert.Nil(t, results[1].Response)
+	assert.Len(t, provider.GetSentPush(), 1)
+	assert.Equal(t, 1, provider.BatchCalls())
+
+	_, err = provider.SendPushBatch(context.Background(), make([]*models.PushNotification, provider.MaxBatchSize()+1))
+	assert.Error(t, err)
+}
+
+func TestMockPushProvider_SendPush_Success(t *testing.T) {
+	provider := createTestPushProvider()
+	ctx := context.Background()
+
+	push := createTestPushNotification()
+
+	response, err := provider.SendPush(ctx, push)
+
+	require.NoError(t, err)
+	assert.Equal(t, push.ID, response.ID)
+	assert.Equal(t, models.StatusSent, response.Status)
+	assert.NotEmpty(t, response.ProviderID)
+
+	sentPush := provider.GetSentPush()
+	require.Len(t, sentPush, 1)
+	assert.Equal(t, push.DeviceToken, sentPush[0].DeviceToken)
+	assert.Equal(t, "ios", sentPush[0].Platform)
+	assert.Equal(t, "default", sentPush[0].Sound) // iOS default sound applied
+	assert.Greater(t, sentPush[0].PayloadSize, 0)
+}
+
+func TestMockPushProvider_SendPush_ValidationErrors(t *testing.T) {
+	provider := createTestPushProvider()
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		modify func(*models.PushNotification)
+	}{
+		{"missing platform", func(p *models.PushNotification) { p.Platform = "" }},
+		{"invalid iOS token", func(p *models.PushNotification) { p.DeviceToken = "short" }},
+		{"unsupported platform", func(p *models.PushNotification) { p.Platform = "blackberry" }},
+		{"no content", func(p *models.PushNotification) { p.Title = ""; p.Message = "" }},
+		{"negative badge", func(p *models.PushNotification) { p.Badge = -1 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			push := createTestPushNotification()
+			tt.modify(push)
+
+			response, err := provider.SendPush(ctx, push)
+			assert.Error(t, err)
+			assert.Nil(t, response)
+
+			notifErr, ok := errors.AsNotificationError(err)
+			require.True(t, ok)
+			assert.Equal(t, errors.ErrorCodeValidationFailed, notifErr.Code)
+		})
+	}
+}
+
+func TestMockPushProvider_SendPush_PayloadTooLarge(t *testing.T) {
+	provider := createTestPushProvider()
+	ctx := context.Background()
+
+	for _, platform := range []struct {
+		name, token string
+		limit       int
+	}{{"ios", testIOSToken, 4096}, {"android", testAndroidToken, 4096}, {"web", testWebPushToken, 3993}} {
+		t.Run(platform.name, func(t *testing.T) {
+			createPush := func(blob string) *models.PushNotification {
+				push := createTestPushNotification()
+				push.Platform = platform.name
+				push.DeviceToken = platform.token
+				push.Data = map[string]string{"blob": blob}
+				return push
+			}
+
+			base := createPush("")
+			provider.preprocessForPlatform(base)
+			payload, err := BuildPlatformPayload(base)
+			require.NoError(t, err)
+			size, err := PayloadSize(platform.name, payload)
+			require.NoError(t, err)
+
+			// Multi-byte characters count their UTF-8 bytes, and HTML
+			// characters are not escaped
+			free := platform.limit - size
+			blob := strings.Repeat("é<", free/3) + strings.Repeat("x", free%3)
+
+			_, err = provider.SendPush(ctx, createPush(blob))
+			require.NoError(t, err, "a payload at the limit is sent")
+
+			_, err = provider.SendPush(ctx, createPush(blob+"x"))
+			notifErr, ok := errors.AsNotificationError(err)
+			require.True(t, ok)
+			assert.Equal(t, errors.ErrorCodePayloadTooLarge, notifErr.Code)
+			assert.Equal(t, strconv.Itoa(platform.limit), notifErr.Metadata["limit"])
+			assert.Equal(t, strconv.Itoa(platform.limit+1), notifErr.Metadata["size"])
+		})
+	}
+}
+
+func TestPayloadSize_FCMTokenNotCounted(t *testing.T) {
+	push := createTestPushNotification()
+	push.Platform = "android"
+	push.DeviceToken = testAndroidToken
+	payload, err := BuildPlatformPayload(push)
+	require.NoError(t, err)
+
+	size, err := PayloadSize("android", payload)
+	require.NoError(t, err)
+	body, err := json.Marshal(payload.Body)
+	require.NoError(t, err)
+	assert.Less(t, size, len(body)-len(testAndroidToken))
+}
+
+func TestMockPushProvider_SendPush_UnregisteredToken(t *testing.T) {
+	provider := createTestPushProvider()
+	ctx := context.Background()
+
+	push := createTestPushNotification()
+	provider.UnregisterToken(push.DeviceToken)
+
+	response, err := provider.SendPush(ctx, push)
+
+	assert.Error(t, err)
+	assert.Nil(t, response)
+
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeInvalidToken, notifErr.Code)
+}
+
+func TestMockPushProvider_SendPush_UnhealthyProvider(t *testing.T) {
+	provider := createTestPushProvider()
+	provider.SetHealthy(false)
+
+	response, err := provider.SendPush(context.Background(), createTestPushNotification())
+
+	assert.Error(t, err)
+	assert.Nil(t, response)
+
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeProviderUnavailable, notifErr.Code)
+}
+
+func TestMockPushProvider_PlatformPreprocessing(t *testing.T) {
+	provider := createTestPushProvider()
+	ctx := context.Background()
+
+	android := createTestPushNotification()
+	android.Platform = "android"
+	android.DeviceToken = testAndroidToken
+	android.Badge = 5
+	android.Title = strings.Repeat("t", 300)
+
+	_, err := provider.SendPush(ctx, android)
+	require.NoError(t, err)
+
+	web := createTestPushNotification()
+	web.Platform = "web"
+	web.DeviceToken = testWebPushToken
+	web.Sound = "chime"
+	// The limit falls inside the first emoji, which is dropped whole
+	web.Message = strings.Repeat("a", maxWebBodyLength-2) + "😀😀"
+
+	_, err = provider.SendPush(ctx, web)
+	require.NoError(t, err)
+
+	sentPush := provider.GetSentPush()
+	require.Len(t, sentPush, 2)
+	assert.Equal(t, 0, sentPush[0].Badge)
+	assert.Len(t, sentPush[0].Title, maxAndroidTitle)
+	assert.Empty(t, sentPush[1].Sound)
+	assert.Equal(t, strings.Repeat("a", maxWebBodyLength-2), sentPush[1].Message)
+}
+
+func TestMockPushProvider_RichPayloadMapping(t *testing.T) {
+	provider := createTestPushProvider()
+	ctx := context.Background()
+
+	ios := createTestPushNotification()
+	ios.CollapseKey = "order-42"
+	ios.ThreadID = "orders"
+	ios.Category = "ORDER_ACTIONS"
+	ios.TTL = 3600
+	ios.MutableContent = true
+	ios.InterruptionLevel = "time-sensitive"
+	ios.Data = map[string]string{"order_id": "42"}
+
+	_, err := provider.SendPush(ctx, ios)
+	require.NoError(t, err)
+
+	android := createTestPushNotification()
+	android.Platform = "android"
+	android.DeviceToken = testAndroidToken
+	android.DataOnly = true
+	android.CollapseKey = "sync"
+	android.TTL = 60
+	android.ChannelID = "updates"
+	android.Data = map[string]string{"sync": "inbox"}
+
+	_, err = provider.SendPush(ctx, android)
+	require.NoError(t, err)
+
+	web := createTestPushNotification()
+	web.Platform = "web"
+	web.DeviceToken = testWebPushToken
+	web.CollapseKey = "news"
+
+	_, err = provider.SendPush(ctx, web)
+	require.NoError(t, err)
+
+	sentPush := provider.GetSentPush()
+	require.Len(t, sentPush, 3)
+
+	apns := sentPush[0].Payload
+	require.NotNil(t, apns)
+	aps := apns.Body["aps"].(map[string]interface{})
+	assert.Equal(t, "orders", aps["thread-id"])
+	assert.Equal(t, "ORDER_ACTIONS", aps["category"])
+	assert.Equal(t, 1, aps["mutable-content"])
+	assert.Equal(t, "time-sensitive", aps["interruption-level"])
+	assert.Equal(t, "42", apns.Body["order_id"])
+	assert.Equal(t, "order-42", apns.Headers["apns-collapse-id"])
+	assert.NotEmpty(t, apns.Headers["apns-expiration"])
+
+	fcm := sentPush[1].Payload.Body["message"].(map[string]interface{})
+	assert.NotContains(t, fcm, "notification") // Data-only message
+	assert.Equal(t, map[string]string{"sync": "inbox"}, fcm["data"])
+	fcmAndroid := fcm["android"].(map[string]interface{})
+	assert.Equal(t, "sync", fcmAndroid["collapse_key"])
+	assert.Equal(t, "60s", fcmAndroid["ttl"])
+
+	assert.Equal(t, "news", sentPush[2].Payload.Headers["Topic"])
+	assert.Equal(t, "2419200", sentPush[2].Payload.Headers["TTL"], "pushes without a TTL wait for offline browsers")
+	assert.Equal(t, "Test Push", sentPush[2].Payload.Body["title"])
+}
+
+func TestMockPushProvider_RichPayloadValidation(t *testing.T) {
+	provider := createTestPushProvider()
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		modify func(*models.PushNotification)
+	}{
+		{"collapse key too long", func(p *models.PushNotification) { p.CollapseKey = strings.Repeat("k", 65) }},
+		{"negative ttl", func(p *models.PushNotification) { p.TTL = -1 }},
+		{"invalid interruption level", func(p *models.PushNotification) { p.InterruptionLevel = "loud" }},
+		{"data-only without data", func(p *models.PushNotification) { p.DataOnly = true }},
+		{"group too long", func(p *models.PushNotification) { p.Group = strings.Repeat("g", 65) }},
+		{"invalid group mode", func(p *models.PushNotification) { p.Group = "orders"; p.GroupMode = "merge" }},
+		{"group summary without group", func(p *models.PushNotification) { p.GroupSummary = "{{count}} updates" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			push := createTestPushNotification()
+			tt.modify(push)
+
+			_, err := provider.SendPush(ctx, push)
+			require.Error(t, err)
+
+			notifErr, ok := errors.AsNotificationError(err)
+			require.True(t, ok)
+			assert.Equal(t, errors.ErrorCodeValidationFailed, notifErr.Code)
+		})
+	}
+}
+
+func TestMockPushProvider_GroupStack(t *testing.T) {
+	provider := createTestPushProvider()
+	ctx := context.Background()
+
+	for _, platform := range []struct{ name, token string }{{"ios", testIOSToken}, {"android", testAndroidToken}, {"web", testWebPushToken}} {
+		push := createTestPushNotification()
+		push.Platform = platform.name
+		push.DeviceToken = platform.token
+		push.Group = "orders"
+		push.GroupSummary = "{{count}} order updates"
+		push.GroupCount = 3
+		push.Data = map[string]string{"order_id": "42"}
+
+		_, err := provider.SendPush(ctx, push)
+		require.NoError(t, err, platform.name)
+		assert.Equal(t, map[string]string{"order_id": "42"}, push.Data) // not changed
+	}
+
+	sentPush := provider.GetSentPush()
+	require.Len(t, sentPush, 3)
+
+	apns := sentPush[0].Payload
+	aps := apns.Body["aps"].(map[string]interface{})
+	assert.Equal(t, "orders", aps["thread-id"])
+	assert.Equal(t, "3 order updates", aps["alert"].(map[string]interface{})["summary-arg"])
+	assert.NotContains(t, apns.Headers, "apns-collapse-id")
+
+	fcm := sentPush[1].Payload.Body["message"].(map[string]interface{})
+	assert.Equal(t, map[string]string{"order_id": "42", "group_key": "orders", "group_summary": "3 order updates", "group_count": "3"}, fcm["data"])
+	assert.NotContains(t, fcm["android"], "collapse_key")
+
+	web := sentPush[2].Payload.Body
+	assert.Equal(t, "orders", web["tag"])
+	assert.Equal(t, true, web["renotify"])
+	assert.Equal(t, "orders", web["data"].(map[string]string)["group_key"])
+}
+
+func TestMockPushProvider_GroupReplace(t *testing.T) {
+	provider := createTestPushProvider()
+	ctx := context.Background()
+
+	for _, platform := range []struct{ name, token string }{{"ios", testIOSToken}, {"android", testAndroidToken}, {"web", testWebPushToken}} {
+		push := createTestPushNotification()
+		push.Platform = platform.name
+		push.DeviceToken = platform.token
+		push.Group = "delivery-eta"
+		push.GroupMode = models.PushGroupReplace
+
+		_, err := provider.SendPush(ctx, push)
+		require.NoError(t, err, platform.name)
+	}
+
+	sentPush := provider.GetSentPush()
+	require.Len(t, sentPush, 3)
+
+	assert.Equal(t, "delivery-eta", sentPush[0].Payload.Headers["apns-collapse-id"])
+
+	fcm := sentPush[1].Payload.Body["message"].(map[string]interface{})
+	fcmAndroid := fcm["android"].(map[string]interface{})
+	assert.Equal(t, "delivery-eta", fcmAndroid["collapse_key"])
+	assert.Equal(t, "delivery-eta", fcmAndroid["notification"].(map[string]interface{})["tag"])
+	assert.NotContains(t, fcm, "data")
+
+	assert.Equal(t, "delivery-eta", sentPush[2].Payload.Body["tag"])
+
+	// Explicit native options win over the group
+	push := createTestPushNotification()
+	push.Group = "delivery-eta"
+	push.GroupMode = models.PushGroupReplace
+	push.CollapseKey = "order-42"
+	push.ThreadID = "orders"
+	_, err := provider.SendPush(ctx, push)
+	require.NoError(t, err)
+	apns := provider.GetSentPush()[3].Payload
+	assert.Equal(t, "order-42", apns.Headers["apns-collapse-id"])
+	assert.Equal(t, "orders", apns.Body["aps"].(map[string]interface{})["thread-id"])
+}
+
+func TestMockPushProvider_ExpiresAt(t *testing.T) {
+	provider := createTestPushProvider()
+	ctx := context.Background()
+	expiresAt := time.Now().Add(10 * time.Minute)
+
+	// The expiry wins over a longer TTL
+	for _, platform := range []struct{ name, token string }{{"ios", testIOSToken}, {"android", testAndroidToken}, {"web", testWebPushToken}} {
+		push := createTestPushNotification()
+		push.Platform = platform.name
+		push.DeviceToken = platform.token
+		push.TTL = 3600
+		push.ExpiresAt = &expiresAt
+		_, err := provider.SendPush(ctx, push)
+		require.NoError(t, err)
+	}
+
+	// A shorter TTL wins over the expiry
+	web := createTestPushNotification()
+	web.Platform = "web"
+	web.DeviceToken = testWebPushToken
+	web.TTL = 60
+	web.ExpiresAt = &expiresAt
+	_, err := provider.SendPush(ctx, web)
+	require.NoError(t, err)
+
+	sentPush := provider.GetSentPush()
+	require.Len(t, sentPush, 4)
+	assert.Equal(t, strconv.FormatInt(expiresAt.Unix(), 10), sentPush[0].Payload.Headers["apns-expiration"])
+
+	android := sentPush[1].Payload.Body["message"].(map[string]interface{})["android"].(map[string]interface{})
+	ttl, err := strconv.Atoi(strings.TrimSuffix(android["ttl"].(string), "s"))
+	require.NoError(t, err)
+	assert.InDelta(t, 600, ttl, 2)
+
+	ttl, err = strconv.Atoi(sentPush[2].Payload.Headers["TTL"])
+	require.NoError(t, err)
+	assert.InDelta(t, 600, ttl, 2)
+	assert.Equal(t, "60", sentPush[3].Payload.Headers["TTL"])
+}
+
+func TestMockPushProvider_SilentPush(t *testing.T) {
+	provider := createTestPushProvider()
+	ctx := context.Background()
+
+	silent := createTestPushNotification()
+	silent.Title = ""
+	silent.Message = ""
+	silent.Silent = true
+	silent.Priority = models.PriorityUrgent
+	silent.Data = map[string]string{"refresh": "feed"}
+
+	_, err := provider.SendPush(ctx, silent)
+	require.NoError(t, err)
+
+	sentPush := provider.GetSentPush()
+	require.Len(t, sentPush, 1)
+	assert.Empty(t, sentPush[0].Sound) // No default sound for background pushes
+
+	payload := sentPush[0].Payload
+	aps := payload.Body["aps"].(map[string]interface{})
+	assert.NotContains(t, aps, "alert")
+	assert.Equal(t, 1, aps["content-available"])
+	assert.Equal(t, "background", payload.Headers["apns-push-type"])
+	assert.Equal(t, "5", payload.Headers["apns-priority"]) // Background pushes must use priority 5
+
+	// Silent pushes may not carry visible content
+	withTitle := createTestPushNotification()
+	withTitle.Silent = true
+	_, err = provider.SendPush(ctx, withTitle)
+	require.Error(t, err)
+
+	withBadge := createTestPushNotification()
+	withBadge.Title = ""
+	withBadge.Message = ""
+	withBadge.Silent = true
+	withBadge.Badge = 3
+	_, err = provider.SendPush(ctx, withBadge)
+	require.Error(t, err)
+}
+
+func TestMockPushProvider_WebPushActions(t *testing.T) {
+	provider := createTestPushProvider()
+
+	web := createTestPushNotification()
+	web.Platform = "web"
+	web.DeviceToken = testWebPushToken
+	web.Actions = []models.PushAction{
+		{ID: "reply", Title: "Reply", Icon: "/icons/reply.png"},
+		{ID: "dismiss", Title: "Dismiss"},
+	}
+	web.Tag = "chat-42"
+	web.Renotify = true
+	web.RequireInteraction = true
+	web.Vibrate = []int{200, 100, 200}
+
+	_, err := provider.SendPush(context.Background(), web)
+	require.NoError(t, err)
+
+	sentPush := provider.GetSentPush()
+	require.Len(t, sentPush, 1)
+
+	body := sentPush[0].Payload.Body
+	actions := body["actions"].([]map[string]string)
+	require.Len(t, actions, 2)
+	assert.Equal(t, "reply", actions[0]["action"])
+	assert.Equal(t, "/icons/reply.png", actions[0]["icon"])
+	assert.NotContains(t, actions[1], "icon")
+	assert.Equal(t, "chat-42", body["tag"])
+	assert.Equal(t, true, body["renotify"])
+	assert.Equal(t, true, body["requireInteraction"])
+	assert.Equal(t, []int{200, 100, 200}, body["vibrate"])
+}
+
+func TestMockPushProvider_WebPushOptionValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*models.PushNotification)
+	}{
+		{"too many actions", func(p *models.PushNotification) {
+			p.Actions = []models.PushAction{{ID: "a", Title: "A"}, {ID: "b", Title: "B"}, {ID: "c", Title: "C"}}
+		}},
+		{"action without title", func(p *models.PushNotification) { p.Actions = []models.PushAction{{ID: "a"}} }},
+		{"renotify without tag", func(p *models.PushNotification) { p.Renotify = true }},
+		{"negative vibration", func(p *models.PushNotification) { p.Vibrate = []int{100, -1} }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := createTestPushProvider()
+			push := createTestPushNotification()
+			push.Platform = "web"
+			push.DeviceToken = testWebPushToken
+			tt.modify(push)
+
+			_, err := provider.SendPush(context.Background(), push)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestPushPriorityMapping(t *testing.T) {
+	tests := []struct {
+		priority models.Priority
+		apns     string
+		fcm      string
+		urgency  string
+	}{
+		{models.PriorityLow, "5", "normal", "low"},
+		{models.PriorityNormal, "5", "normal", "normal"},
+		{models.PriorityHigh, "10", "high", "high"},
+		{models.PriorityUrgent, "10", "high", "high"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.priority), func(t *testing.T) {
+			push := createTestPushNotification()
+			push.Priority = tt.priority
+
+			assert.Equal(t, tt.apns, apnsPriority(push))
+			assert.Equal(t, tt.fcm, fcmPriority(push))
+			assert.Equal(t, tt.urgency, webPushUrgency(push))
+		})
+	}
+}
+
+func TestMockPushProvider_Send_GenericNotification(t *testing.T) {
+	provider := createTestPushProvider()
+	ctx := context.Background()
+
+	notification := &models.Notification{
+		ID:        uuid.New(),
+		Type:      models.NotificationTypePush,
+		Status:    models.StatusPending,
+		Priority:  models.PriorityNormal,
+		Recipient: testIOSToken,
+		Subject:   "Hello",
+		Body:      "Push message content",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Metadata: map[string]string{
+			"platform": "ios",
+		},
+	}
+
+	response, err := provider.Send(ctx, notification)
+
+	require.NoError(t, err)
+	assert.Equal(t, notification.ID, response.ID)
+
+	sentPush := provider.GetSentPush()
+	require.Len(t, sentPush, 1)
+	assert.Equal(t, "Hello", sentPush[0].Title)
+}
+
+func TestMockPushProvider_Send_WrongType(t *testing.T) {
+	provider := createTestPushProvider()
+
+	notification := &models.Notification{
+		ID:   uuid.New(),
+		Type: models.NotificationTypeSMS, // Wrong type
+	}
+
+	response, err := provider.Send(context.Background(), notification)
+
+	assert.Error(t, err)
+	assert.Nil(t, response)
+}
+
+func TestMockPushProvider_RenderTemplate(t *testing.T) {
+	provider := createTestPushProvider()
+
+	rendered, err := provider.RenderTemplate("new_message", map[string]string{
+		"sender_name":     "Alice",
+		"message_preview": "Are we still on for lunch?",
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "New message from Alice", rendered.Title)
+	assert.Equal(t, "Are we still on for lunch?", rendered.Message)
+	assert.Len(t, rendered.Actions, 2)
+
+	_, err = provider.RenderTemplate("non-existent", nil)
+	assert.Error(t, err)
+}
+
+func TestMockPushProvider_ClearSentPush(t *testing.T) {
+	provider := createTestPushProvider()
+
+	_, err := provider.SendPush(context.Background(), createTestPushNotification())
+	require.NoError(t, err)
+	assert.Len(t, provider.GetSentPush(), 1)
+
+	provider.ClearSentPush()
+	assert.Empty(t, provider.GetSentPush())
+}
+
+func TestMockPushProvider_ConcurrentUse(t *testing.T) {
+	provider := createTestPushProvider()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			provider.SendPush(ctx, createTestPushNotification())
+			provider.SendPushBatch(ctx, []*models.PushNotification{createTestPushNotification()})
+			provider.UnregisterToken(strings.Repeat("f", 64))
+			provider.AddTemplate(&PushTemplate{Name: "concurrent", Title: "Hi", Message: "Hi"})
+			provider.GetTemplate("message")
+			provider.SetHealthy(i%2 == 0)
+			provider.IsHealthy(ctx)
+			provider.BatchCalls()
+			provider.GetSentPush()
+		}(i)
+	}
+	wg.Wait()
+
+	provider.SetHealthy(true)
+	assert.NoError(t, provider.IsHealthy(ctx))
+}
+
+// Helper functions
+
+func createTestPushProvider() *MockPushProvider {
+	cfg := config.PushProviderConfig{
+		Provider: "mock",
+		Enabled:  true,
+	}
+	return NewMockPushProvider(cfg)
+}
+
+func createTestPushNotification() *models.PushNotification {
+	return &models.PushNotification{
+		Notification: models.Notification{
+			ID:        uuid.New(),
+			Type:      models.NotificationTypePush,
+			Status:    models.StatusPending,
+			Priority:  models.PriorityNormal,
+			Recipient: testIOSToken,
+			Subject:   "Test Push",
+			Body:      "Test push message",
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		},
+		DeviceToken: testIOSToken,
+		Platform:    "ios",
+		Title:       "Test Push",
+		Message:     "Test push message",
+	}
+}
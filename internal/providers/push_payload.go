@@ -0,0 +1,545 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// Native payload limits
+const (
+	maxCollapseKeyLength = 64      // apns-collapse-id is limited to 64 bytes
+	maxGroupLength       = 64      // groups are sent as collapse ids and tags
+	maxPushTTLSeconds    = 2419200 // FCM caps time-to-live at 28 days
+	maxWebPushActions    = 2       // Browsers display at most two action buttons
+	maxVibratePattern    = 32      // Longer vibration patterns are truncated by browsers
+
+	// APNs and FCM accept 4KB. Web Push encrypts the payload into a 4KB
+	// record that also holds the aes128gcm header (86 bytes), the
+	// authentication tag (16) and a padding delimiter (1).
+	maxPushPayloadSize    = 4096
+	maxWebPushPayloadSize = maxPushPayloadSize - 86 - 16 - 1
+)
+
+// validInterruptionLevels lists the iOS 15+ interruption levels
+var validInterruptionLevels = map[string]bool{
+	"passive":        true,
+	"active":         true,
+	"time-sensitive": true,
+	"critical":       true,
+}
+
+// APNs priorities
+const (
+	apnsPriorityImmediate = "10"
+	apnsPriorityConserve  = "5"
+)
+
+// PlatformPayload represents the native request a push service would receive
+type PlatformPayload struct {
+	Headers map[string]string      `json:"headers,omitempty"`
+	Body    map[string]interface{} `json:"body"`
+}
+
+// BuildPlatformPayload builds the native APNs, FCM or Web Push payload for a push notification
+func BuildPlatformPayload(push *models.PushNotification) (*PlatformPayload, error) {
+	switch strings.ToLower(push.Platform) {
+	case "ios":
+		return buildAPNsPayload(push), nil
+	case "android":
+		return buildFCMPayload(push), nil
+	case "web":
+		return buildWebPushPayload(push), nil
+	default:
+		return nil, errors.NewValidationError("platform", fmt.Sprintf("unsupported platform: %s", push.Platform))
+	}
+}
+
+// PayloadSize returns the size in bytes of the part of a native payload a
+// platform limits, encoded as UTF-8 JSON the way the platform receives it:
+// the whole APNs and Web Push body, and the FCM message without its token.
+func PayloadSize(platform string, payload *PlatformPayload) (int, error) {
+	body := payload.Body
+	if strings.ToLower(platform) == "android" {
+		if message, ok := body["message"].(map[string]interface{}); ok {
+			measured := maps.Clone(message)
+			delete(measured, "token")
+			body = measured
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false) // platforms count "<" as one byte, not as \u003c
+	if err := encoder.Encode(body); err != nil {
+		return 0, errors.NewInternalError("failed to encode push payload", err)
+	}
+	return buf.Len() - 1, nil // without the encoder's trailing newline
+}
+
+// MaxPayloadSize returns the payload limit of a platform in bytes
+func MaxPayloadSize(platform string) int {
+	if strings.ToLower(platform) == "web" {
+		return maxWebPushPayloadSize
+	}
+	return maxPushPayloadSize
+}
+
+// checkPayloadSize returns the size of a native payload, failing when it is
+// over its platform's limit
+func checkPayloadSize(platform string, payload *PlatformPayload) (int, error) {
+	size, err := PayloadSize(platform, payload)
+	if err != nil {
+		return 0, err
+	}
+	if limit := MaxPayloadSize(platform); size > limit {
+		return 0, errors.NewPayloadTooLargeError("payload", int64(size), int64(limit), "bytes")
+	}
+	return size, nil
+}
+
+// validateRichPayload validates the rich payload options of a push notification
+func validateRichPayload(push *models.PushNotification) error {
+	if len(push.CollapseKey) > maxCollapseKeyLength {
+		return errors.NewValidationError("collapse_key", fmt.Sprintf("collapse key cannot exceed %d bytes", maxCollapseKeyLength))
+	}
+
+	if err := validatePushGroup(push); err != nil {
+		return err
+	}
+
+	if push.TTL < 0 || push.TTL > maxPushTTLSeconds {
+		return errors.NewValidationError("ttl", fmt.Sprintf("ttl must be between 0 and %d seconds", maxPushTTLSeconds))
+	}
+
+	if push.InterruptionLevel != "" && !validInterruptionLevels[push.InterruptionLevel] {
+		return errors.NewValidationError("interruption_level", "interruption level must be passive, active, time-sensitive or critical")
+	}
+
+	if push.DataOnly && len(push.Data) == 0 {
+		return errors.NewValidationError("data", "data-only push notifications require a data payload")
+	}
+
+	if err := validateWebPushOptions(push); err != nil {
+		return err
+	}
+
+	if push.Silent {
+		return validateSilentPush(push)
+	}
+
+	return nil
+}
+
+// validateWebPushOptions validates actions and display options of a Web Push notification
+func validateWebPushOptions(push *models.PushNotification) error {
+	if len(push.Actions) > maxWebPushActions {
+		return errors.NewValidationError("actions", fmt.Sprintf("push notifications support at most %d actions", maxWebPushActions))
+	}
+
+	for i, action := range push.Actions {
+		if action.ID == "" || action.Title == "" {
+			return errors.NewValidationError("actions", fmt.Sprintf("action %d must have an id and title", i))
+		}
+	}
+
+	// Browsers reject renotify without a tag to replace; a group is sent as the tag
+	if push.Renotify && push.Tag == "" && push.Group == "" {
+		return errors.NewValidationError("tag", "renotify requires a tag")
+	}
+
+	if len(push.Vibrate) > maxVibratePattern {
+		return errors.NewValidationError("vibrate", fmt.Sprintf("vibration pattern cannot exceed %d entries", maxVibratePattern))
+	}
+
+	for _, duration := range push.Vibrate {
+		if duration < 0 {
+			return errors.NewValidationError("vibrate", "vibration durations cannot be negative")
+		}
+	}
+
+	return nil
+}
+
+// validatePushGroup validates the tray grouping of a push notification
+func validatePushGroup(push *models.PushNotification) error {
+	if push.Group == "" {
+		if push.GroupMode != "" || push.GroupSummary != "" {
+			return errors.NewValidationError("group", "group mode and summary require a group")
+		}
+		return nil
+	}
+
+	if len(push.Group) > maxGroupLength {
+		return errors.NewValidationError("group", fmt.Sprintf("group cannot exceed %d bytes", maxGroupLength))
+	}
+
+	switch push.GroupMode {
+	case "", models.PushGroupStack, models.PushGroupReplace:
+		return nil
+	default:
+		return errors.NewValidationError("group_mode", "group mode must be stack or replace")
+	}
+}
+
+// groupMode returns how a grouped push is shown, stacking by default
+func groupMode(push *models.PushNotification) models.PushGroupMode {
+	if push.GroupMode == "" {
+		return models.PushGroupStack
+	}
+	return push.GroupMode
+}
+
+// groupSummary renders the group summary with the number of alerts in the group
+func groupSummary(push *models.PushNotification) string {
+	count := push.GroupCount
+	if count < 1 {
+		count = 1
+	}
+	return strings.ReplaceAll(push.GroupSummary, "{{count}}", strconv.Itoa(count))
+}
+
+// groupData returns the custom data of a push with the group added, for
+// platforms where the app builds the group itself
+func groupData(push *models.PushNotification) map[string]string {
+	if push.Group == "" || groupMode(push) != models.PushGroupStack {
+		return push.Data
+	}
+
+	data := make(map[string]string, len(push.Data)+3)
+	for key, value := range push.Data {
+		data[key] = value
+	}
+	data["group_key"] = push.Group
+	if push.GroupSummary != "" {
+		data["group_summary"] = groupSummary(push)
+	}
+	if push.GroupCount > 0 {
+		data["group_count"] = strconv.Itoa(push.GroupCount)
+	}
+	return data
+}
+
+// validateSilentPush ensures a silent push carries nothing the platforms
+// would display or play, since APNs rejects background pushes with alerts
+func validateSilentPush(push *models.PushNotification) error {
+	if push.Title != "" || push.Message != "" {
+		return errors.NewValidationError("silent", "silent push notifications cannot include a title or message")
+	}
+
+	if push.Sound != "" || push.Badge != 0 {
+		return errors.NewValidationError("silent", "silent push notifications cannot include a sound or badge")
+	}
+
+	if push.ImageURL != "" || push.InterruptionLevel != "" || push.MutableContent {
+		return errors.NewValidationError("silent", "silent push notifications cannot include an image, interruption level or mutable content")
+	}
+
+	return nil
+}
+
+// isBackgroundPush reports whether a push is delivered without user-visible content
+func isBackgroundPush(push *models.PushNotification) bool {
+	return push.Silent || push.DataOnly
+}
+
+// apnsPriority maps a notification priority to the apns-priority header.
+// Background pushes must use priority 5 or APNs rejects them.
+func apnsPriority(push *models.PushNotification) string {
+	if isBackgroundPush(push) {
+		return apnsPriorityConserve
+	}
+
+	switch push.Priority {
+	case models.PriorityHigh, models.PriorityUrgent:
+		return apnsPriorityImmediate
+	default:
+		return apnsPriorityConserve
+	}
+}
+
+// fcmPriority maps a notification priority to the FCM Android message priority
+func fcmPriority(push *models.PushNotification) string {
+	switch push.Priority {
+	case models.PriorityHigh, models.PriorityUrgent:
+		return "high"
+	default:
+		return "normal"
+	}
+}
+
+// webPushUrgency maps a notification priority to the Web Push Urgency header
+func webPushUrgency(push *models.PushNotification) string {
+	switch push.Priority {
+	case models.PriorityUrgent, models.PriorityHigh:
+		return "high"
+	case models.PriorityLow:
+		return "low"
+	default:
+		return "normal"
+	}
+}
+
+// buildAPNsPayload maps a push notification to an APNs request
+func buildAPNsPayload(push *models.PushNotification) *PlatformPayload {
+	aps := make(map[string]interface{})
+
+	if !isBackgroundPush(push) {
+		alert := make(map[string]interface{})
+		if push.Title != "" {
+			alert["title"] = push.Title
+		}
+		if push.Message != "" {
+			alert["body"] = push.Message
+		}
+		// iOS names the stack in its summary, e.g. "3 more from Orders"
+		if push.GroupSummary != "" && groupMode(push) == models.PushGroupStack {
+			alert["summary-arg"] = groupSummary(push)
+		}
+		aps["alert"] = alert
+
+		if push.Badge > 0 {
+			aps["badge"] = push.Badge
+		}
+		if push.Sound != "" {
+			aps["sound"] = push.Sound
+		}
+	}
+
+	if push.ThreadID != "" {
+		aps["thread-id"] = push.ThreadID
+	} else if push.Group != "" {
+		aps["thread-id"] = push.Group
+	}
+	if push.Category != "" {
+		aps["category"] = push.Category
+	}
+	if push.ContentAvailable || isBackgroundPush(push) {
+		aps["content-available"] = 1
+	}
+	if push.MutableContent {
+		aps["mutable-content"] = 1
+	}
+	if push.InterruptionLevel != "" {
+		aps["interruption-level"] = push.InterruptionLevel
+	}
+
+	body := map[string]interface{}{"aps": aps}
+	// Custom data travels alongside the aps dictionary
+	for key, value := range push.Data {
+		body[key] = value
+	}
+	if push.ImageURL != "" {
+		body["image_url"] = push.ImageURL
+	}
+
+	headers := map[string]string{
+		"apns-push-type": "alert",
+		"apns-priority":  apnsPriority(push),
+	}
+	if isBackgroundPush(push) {
+		headers["apns-push-type"] = "background"
+	}
+	if push.CollapseKey != "" {
+		headers["apns-collapse-id"] = push.CollapseKey
+	} else if push.Group != "" && groupMode(push) == models.PushGroupReplace {
+		headers["apns-collapse-id"] = push.Group
+	}
+	if expiration := apnsExpiration(push); expiration > 0 {
+		headers["apns-expiration"] = fmt.Sprintf("%d", expiration)
+	}
+
+	return &PlatformPayload{Headers: headers, Body: body}
+}
+
+// buildFCMPayload maps a push notification to an FCM HTTP v1 message
+func buildFCMPayload(push *models.PushNotification) *PlatformPayload {
+	message := map[string]interface{}{
+		"token": push.DeviceToken,
+	}
+
+	// FCM has no group field, so stacked alerts are grouped by the app
+	if data := groupData(push); len(data) > 0 {
+		message["data"] = data
+	}
+
+	android := map[string]interface{}{
+		"priority": fcmPriority(push),
+	}
+	replace := push.Group != "" && groupMode(push) == models.PushGroupReplace
+	if push.CollapseKey != "" {
+		android["collapse_key"] = push.CollapseKey
+	} else if replace {
+		android["collapse_key"] = push.Group
+	}
+	if push.TTL > 0 || push.ExpiresAt != nil {
+		android["ttl"] = fmt.Sprintf("%ds", pushTTL(push))
+	}
+
+	// Data-only and silent messages are handed to the app without a system notification
+	if !isBackgroundPush(push) {
+		notification := make(map[string]interface{})
+		if push.Title != "" {
+			notification["title"] = push.Title
+		}
+		if push.Message != "" {
+			notification["body"] = push.Message
+		}
+		if push.ImageURL != "" {
+			notification["image"] = push.ImageURL
+		}
+		message["notification"] = notification
+
+		androidNotification := make(map[string]interface{})
+		if push.ChannelID != "" {
+			androidNotification["channel_id"] = push.ChannelID
+		}
+		if push.Icon != "" {
+			androidNotification["icon"] = push.Icon
+		}
+		if push.Sound != "" {
+			androidNotification["sound"] = push.Sound
+		}
+		if push.ClickAction != "" {
+			androidNotification["click_action"] = push.ClickAction
+		}
+		// A notification with the tag of one in the tray replaces it
+		if replace {
+			androidNotification["tag"] = push.Group
+		}
+		if len(androidNotification) > 0 {
+			android["notification"] = androidNotification
+		}
+	}
+
+	// Provider options that map to FCM fields
+	if label, ok := push.ProviderOptions["analytics_label"].(string); ok {
+		message["fcm_options"] = map[string]interface{}{"analytics_label": label}
+	}
+	if directBoot, ok := push.ProviderOptions["direct_boot_ok"].(bool); ok && directBoot {
+		android["direct_boot_ok"] = true
+	}
+
+	message["android"] = android
+
+	return &PlatformPayload{
+		Body: map[string]interface{}{"message": message},
+	}
+}
+
+// buildWebPushPayload maps a push notification to a Web Push request
+func buildWebPushPayload(push *models.PushNotification) *PlatformPayload {
+	body := make(map[string]interface{})
+
+	if !isBackgroundPush(push) {
+		body["title"] = push.Title
+		if push.Message != "" {
+			body["body"] = push.Message
+		}
+		if push.Icon != "" {
+			body["icon"] = push.Icon
+		}
+		if push.ImageURL != "" {
+			body["image"] = push.ImageURL
+		}
+		if len(push.Actions) > 0 {
+			body["actions"] = buildWebPushActions(push.Actions)
+		}
+		if push.Tag != "" {
+			body["tag"] = push.Tag
+		} else if push.Group != "" {
+			body["tag"] = push.Group
+		}
+		// Replacing a tagged notification is silent unless renotify is set
+		if push.Renotify || push.Group != "" {
+			body["renotify"] = true
+		}
+		if push.RequireInteraction {
+			body["requireInteraction"] = true
+		}
+		if len(push.Vibrate) > 0 {
+			body["vibrate"] = push.Vibrate
+		}
+	}
+	if data := groupData(push); len(data) > 0 {
+		body["data"] = data
+	}
+	if push.ClickAction != "" {
+		body["click_action"] = push.ClickAction
+	}
+
+	// A TTL of 0 drops the message unless the browser is online, so pushes
+	// without a TTL or expiry are kept as long as push services allow
+	ttl := pushTTL(push)
+	if push.TTL == 0 && push.ExpiresAt == nil {
+		ttl = maxPushTTLSeconds
+	}
+	headers := map[string]string{
+		"TTL":     fmt.Sprintf("%d", ttl),
+		"Urgency": webPushUrgency(push),
+	}
+	// The Web Push Topic header replaces pending messages with the same topic
+	if push.CollapseKey != "" {
+		headers["Topic"] = push.CollapseKey
+	}
+
+	return &PlatformPayload{Headers: headers, Body: body}
+}
+
+// buildWebPushActions maps push actions to Notification API action objects
+func buildWebPushActions(actions []models.PushAction) []map[string]string {
+	result := make([]map[string]string, 0, len(actions))
+	for _, action := range actions {
+		entry := map[string]string{
+			"action": action.ID,
+			"title":  action.Title,
+		}
+		if action.Icon != "" {
+			entry["icon"] = action.Icon
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+// pushTTL returns how many seconds the platform keeps trying to deliver a
+// push: until it expires, or for its TTL when that is sooner. An expired
+// push gets 0, which means deliver now or never.
+func pushTTL(push *models.PushNotification) int {
+	if push.ExpiresAt == nil {
+		return push.TTL
+	}
+
+	remaining := int(math.Ceil(time.Until(*push.ExpiresAt).Seconds()))
+	if remaining < 0 {
+		remaining = 0
+	}
+	if remaining > maxPushTTLSeconds {
+		remaining = maxPushTTLSeconds
+	}
+	if push.TTL > 0 && push.TTL < remaining {
+		return push.TTL
+	}
+	return remaining
+}
+
+// apnsExpiration returns the UNIX time after which APNs stops trying to
+// deliver a push, or 0 when it only tries once
+func apnsExpiration(push *models.PushNotification) int64 {
+	var expiration int64
+	if push.TTL > 0 {
+		expiration = push.CreatedAt.Unix() + int64(push.TTL)
+	}
+	if push.ExpiresAt != nil && (expiration == 0 || push.ExpiresAt.Unix() < expiration) {
+		expiration = push.ExpiresAt.Unix()
+	}
+	return expiration
+}
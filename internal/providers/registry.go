@@ -0,0 +1,198 @@
+package providers
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// Provider factories create a provider from its channel configuration. They
+// are registered under a name and selected by the Provider field of the
+// configuration, so new providers can be added without changing the services.
+type (
+	EmailProviderFactory func(cfg config.EmailProviderConfig) (interfaces.EmailProvider, error)
+	SMSProviderFactory   func(cfg config.SMSProviderConfig) (interfaces.SMSProvider, error)
+	PushProviderFactory  func(cfg config.PushProviderConfig) (interfaces.PushProvider, error)
+	ChatProviderFactory  func(cfg config.ChatProviderConfig) (interfaces.ChatProvider, error)
+	VoiceProviderFactory func(cfg config.VoiceProviderConfig) (interfaces.VoiceProvider, error)
+)
+
+var (
+	emailProviders = newRegistry[config.EmailProviderConfig, interfaces.EmailProvider]("email")
+	smsProviders   = newRegistry[config.SMSProviderConfig, interfaces.SMSProvider]("SMS")
+	pushProviders  = newRegistry[config.PushProviderConfig, interfaces.PushProvider]("push")
+	chatProviders  = newRegistry[config.ChatProviderConfig, interfaces.ChatProvider]("chat")
+	voiceProviders = newRegistry[config.VoiceProviderConfig, interfaces.VoiceProvider]("voice")
+)
+
+// Built-in providers
+func init() {
+	RegisterEmailProvider("mock", func(cfg config.EmailProviderConfig) (interfaces.EmailProvider, error) {
+		return NewMockEmailProvider(cfg), nil
+	})
+	RegisterEmailProvider("smtp", func(cfg config.EmailProviderConfig) (interfaces.EmailProvider, error) {
+		return NewSMTPEmailProvider(cfg), nil
+	})
+	RegisterSMSProvider("mock", func(cfg config.SMSProviderConfig) (interfaces.SMSProvider, error) {
+		return NewMockSMSProvider(cfg), nil
+	})
+	RegisterPushProvider("mock", func(cfg config.PushProviderConfig) (interfaces.PushProvider, error) {
+		return NewMockPushProvider(cfg), nil
+	})
+	RegisterChatProvider(ChatPlatformSlack, func(cfg config.ChatProviderConfig) (interfaces.ChatProvider, error) {
+		return NewSlackWebhookProvider(cfg), nil
+	})
+	RegisterChatProvider(ChatPlatformTeams, func(cfg config.ChatProviderConfig) (interfaces.ChatProvider, error) {
+		return NewTeamsWebhookProvider(cfg), nil
+	})
+	RegisterVoiceProvider("twilio", func(cfg config.VoiceProviderConfig) (interfaces.VoiceProvider, error) {
+		return NewTwilioVoiceProvider(cfg), nil
+	})
+}
+
+// RegisterEmailProvider registers an email provider factory, typically from an
+// init function. It panics if the name is empty or already registered, or
+// the factory is nil.
+func RegisterEmailProvider(name string, factory EmailProviderFactory) {
+	emailProviders.register(name, factory)
+}
+
+// RegisterSMSProvider registers an SMS provider factory. It panics like RegisterEmailProvider.
+func RegisterSMSProvider(name string, factory SMSProviderFactory) {
+	smsProviders.register(name, factory)
+}
+
+// RegisterPushProvider registers a push provider factory. It panics like RegisterEmailProvider.
+func RegisterPushProvider(name string, factory PushProviderFactory) {
+	pushProviders.register(name, factory)
+}
+
+// RegisterChatProvider registers a chat provider factory. It panics like RegisterEmailProvider.
+func RegisterChatProvider(name string, factory ChatProviderFactory) {
+	chatProviders.register(name, factory)
+}
+
+// RegisterVoiceProvider registers a voice provider factory. It panics like RegisterEmailProvider.
+func RegisterVoiceProvider(name string, factory VoiceProviderFactory) {
+	voiceProviders.register(name, factory)
+}
+
+// NewEmailProvider creates the email provider named by the configuration,
+// throttled per recipient domain when the configuration sets domain limits
+func NewEmailProvider(cfg config.EmailProviderConfig) (interfaces.EmailProvider, error) {
+	provider, err := emailProviders.create(cfg.Provider, cfg)
+	if err != nil || len(cfg.DomainLimits) == 0 {
+		return provider, err
+	}
+	return NewThrottledEmailProvider(provider, cfg), nil
+}
+
+// NewSMSProvider creates the SMS provider named by the configuration,
+// routed per destination country when the configuration sets country routes
+func NewSMSProvider(cfg config.SMSProviderConfig) (interfaces.SMSProvider, error) {
+	provider, err := smsProviders.create(cfg.Provider, cfg)
+	if err != nil || len(cfg.CountryRoutes) == 0 {
+		return provider, err
+	}
+	return NewCountryRoutedSMSProvider(provider, cfg)
+}
+
+// NewPushProvider creates the push provider named by the configuration
+func NewPushProvider(cfg config.PushProviderConfig) (interfaces.PushProvider, error) {
+	return pushProviders.create(cfg.Provider, cfg)
+}
+
+// NewChatProvider creates the chat provider named by the configuration
+func NewChatProvider(cfg config.ChatProviderConfig) (interfaces.ChatProvider, error) {
+	return chatProviders.create(cfg.Provider, cfg)
+}
+
+// NewVoiceProvider creates the voice provider named by the configuration
+func NewVoiceProvider(cfg config.VoiceProviderConfig) (interfaces.VoiceProvider, error) {
+	return voiceProviders.create(cfg.Provider, cfg)
+}
+
+// EmailProviders returns the names of the registered email providers
+func EmailProviders() []string { return emailProviders.names() }
+
+// SMSProviders returns the names of the registered SMS providers
+func SMSProviders() []string { return smsProviders.names() }
+
+// PushProviders returns the names of the registered push providers
+func PushProviders() []string { return pushProviders.names() }
+
+// ChatProviders returns the names of the registered chat providers
+func ChatProviders() []string { return chatProviders.names() }
+
+// VoiceProviders returns the names of the registered voice providers
+func VoiceProviders() []string { return voiceProviders.names() }
+
+// registry holds the provider factories of one channel
+type registry[C any, P any] struct {
+	mu        sync.RWMutex
+	channel   string
+	factories map[string]func(C) (P, error)
+}
+
+// newRegistry creates an empty registry for a channel
+func newRegistry[C any, P any](channel string) *registry[C, P] {
+	return &registry[C, P]{channel: channel, factories: make(map[string]func(C) (P, error))}
+}
+
+// register adds a factory, panicking on programming errors as registration happens at init time
+func (r *registry[C, P]) register(name string, factory func(C) (P, error)) {
+	if name == "" {
+		panic(fmt.Sprintf("providers: %s provider name is required", r.channel))
+	}
+	if factory == nil {
+		panic(fmt.Sprintf("providers: %s provider factory for %s is nil", r.channel, name))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.factories[name]; exists {
+		panic(fmt.Sprintf("providers: %s provider %s is already registered", r.channel, name))
+	}
+	r.factories[name] = factory
+}
+
+// unregister removes a factory, so tests can register theirs again
+func (r *registry[C, P]) unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.factories, name)
+}
+
+// create creates a provider with the named factory
+func (r *registry[C, P]) create(name string, cfg C) (P, error) {
+	r.mu.RLock()
+	factory, exists := r.factories[name]
+	r.mu.RUnlock()
+
+	if !exists {
+		var none P
+		return none, errors.NewNotificationError(
+			errors.ErrorCodeProviderNotFound,
+			fmt.Sprintf("unsupported %s provider: %s", r.channel, name),
+		)
+	}
+	return factory(cfg)
+}
+
+// names returns the registered factory names, sorted
+func (r *registry[C, P]) names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
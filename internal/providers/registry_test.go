@@ -0,0 +1,66 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+func TestRegistry_BuiltInProviders(t *testing.T) {
+	assert.Subset(t, EmailProviders(), []string{"mock", "smtp"})
+	assert.Contains(t, SMSProviders(), "mock")
+	assert.Contains(t, PushProviders(), "mock")
+	assert.Subset(t, ChatProviders(), []string{ChatPlatformSlack, ChatPlatformTeams})
+	assert.Contains(t, VoiceProviders(), "twilio")
+
+	provider, err := NewChatProvider(config.ChatProviderConfig{Provider: ChatPlatformTeams})
+	require.NoError(t, err)
+	assert.Equal(t, ChatPlatformTeams, provider.(*ChatWebhookProvider).platform)
+}
+
+func TestRegistry_RegisterEmailProvider(t *testing.T) {
+	// A third-party provider registered under its own name
+	t.Cleanup(func() { emailProviders.unregister("test-postmark") })
+	RegisterEmailProvider("test-postmark", func(cfg config.EmailProviderConfig) (interfaces.EmailProvider, error) {
+		cfg.Settings = map[string]string{"default_sender": "postmark@example.com"}
+		return NewMockEmailProvider(cfg), nil
+	})
+
+	provider, err := NewEmailProvider(config.EmailProviderConfig{Provider: "test-postmark"})
+	require.NoError(t, err)
+	assert.Equal(t, "postmark@example.com", provider.(*MockEmailProvider).config.Settings["default_sender"])
+	assert.Contains(t, EmailProviders(), "test-postmark")
+
+	assert.Panics(t, func() {
+		RegisterEmailProvider("test-postmark", func(cfg config.EmailProviderConfig) (interfaces.EmailProvider, error) {
+			return nil, nil
+		})
+	})
+	assert.Panics(t, func() { RegisterEmailProvider("", nil) })
+	assert.Panics(t, func() { RegisterSMSProvider("test-nil", nil) })
+}
+
+func TestRegistry_UnknownProvider(t *testing.T) {
+	_, err := NewSMSProvider(config.SMSProviderConfig{Provider: "messagebird"})
+	require.Error(t, err)
+
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeProviderNotFound, notifErr.Code)
+	assert.Contains(t, notifErr.Message, "unsupported SMS provider: messagebird")
+}
+
+func TestRegistry_FactoryErrors(t *testing.T) {
+	t.Cleanup(func() { voiceProviders.unregister("test-misconfigured") })
+	RegisterVoiceProvider("test-misconfigured", func(cfg config.VoiceProviderConfig) (interfaces.VoiceProvider, error) {
+		return nil, errors.NewNotificationError(errors.ErrorCodeProviderConfiguration, "missing API key")
+	})
+
+	_, err := NewVoiceProvider(config.VoiceProviderConfig{Provider: "test-misconfigured"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,151 @@
+package providers
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// SenderIDRule is how a country regulates alphanumeric sender IDs
+type SenderIDRule struct {
+	Allowed              bool `json:"allowed"` // false means senders must be numeric
+	MinLength            int  `json:"min_length"`
+	MaxLength            int  `json:"max_length"`
+	RegistrationRequired bool `json:"registration_required"` // only registered IDs may be used
+}
+
+// DefaultSenderIDRules returns the alphanumeric sender ID rules of the
+// supported countries. Carriers change them from time to time, so callers
+// can override them with SenderIDPolicy.SetRule.
+func DefaultSenderIDRules() map[string]SenderIDRule {
+	return map[string]SenderIDRule{
+		"US": {Allowed: false},
+		"CA": {Allowed: false},
+		"BR": {Allowed: false},
+		"UK": {Allowed: true, MinLength: 1, MaxLength: 11},
+		"DE": {Allowed: true, MinLength: 1, MaxLength: 11},
+		"FR": {Allowed: true, MinLength: 3, MaxLength: 11},
+		"AU": {Allowed: true, MinLength: 1, MaxLength: 11},
+		"IN": {Allowed: true, MinLength: 6, MaxLength: 6, RegistrationRequired: true}, // DLT headers
+	}
+}
+
+// SenderIDPolicy checks alphanumeric sender IDs against per-country rules
+// and falls back to a numeric sender where they are not allowed. It is safe
+// for concurrent use.
+type SenderIDPolicy struct {
+	fallbackNumber string
+
+	mu         sync.RWMutex
+	rules      map[string]SenderIDRule
+	registered map[string]map[string]bool // country to registered IDs
+}
+
+// NewSenderIDPolicy creates a policy with the default rules. Sends to
+// countries that prohibit alphanumeric senders use fallbackNumber, or the
+// provider's default sender when it is empty.
+func NewSenderIDPolicy(fallbackNumber string) *SenderIDPolicy {
+	return &SenderIDPolicy{
+		fallbackNumber: fallbackNumber,
+		rules:          DefaultSenderIDRules(),
+		registered:     make(map[string]map[string]bool),
+	}
+}
+
+// SetRule sets the rule of a country
+func (p *SenderIDPolicy) SetRule(countryCode string, rule SenderIDRule) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.rules[strings.ToUpper(countryCode)] = rule
+}
+
+// Rule returns the rule of a country
+func (p *SenderIDPolicy) Rule(countryCode string) (SenderIDRule, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	rule, exists := p.rules[strings.ToUpper(countryCode)]
+	return rule, exists
+}
+
+// Register records a sender ID as registered in a country
+func (p *SenderIDPolicy) Register(countryCode, senderID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	countryCode = strings.ToUpper(countryCode)
+	if p.registered[countryCode] == nil {
+		p.registered[countryCode] = make(map[string]bool)
+	}
+	p.registered[countryCode][strings.ToUpper(senderID)] = true
+}
+
+// IsRegistered reports whether a sender ID is registered in a country
+func (p *SenderIDPolicy) IsRegistered(countryCode, senderID string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.registered[strings.ToUpper(countryCode)][strings.ToUpper(senderID)]
+}
+
+// Resolve returns the sender to use for a send to a country. Numeric
+// senders are used as they are. Alphanumeric sender IDs must follow the
+// country's rule; in countries that prohibit them, or whose rule is unknown,
+// the fallback number is used instead.
+func (p *SenderIDPolicy) Resolve(senderID, countryCode string) (string, error) {
+	if senderID == "" || isNumericSender(senderID) {
+		return senderID, nil
+	}
+
+	if err := validateSenderIDFormat(senderID); err != nil {
+		return "", err
+	}
+
+	rule, exists := p.Rule(countryCode)
+	if !exists || !rule.Allowed {
+		return p.fallbackNumber, nil
+	}
+
+	if len(senderID) < rule.MinLength || len(senderID) > rule.MaxLength {
+		if rule.MinLength == rule.MaxLength {
+			return "", errors.NewValidationError("sender_id", fmt.Sprintf("sender IDs in %s must be %d characters", strings.ToUpper(countryCode), rule.MaxLength))
+		}
+		return "", errors.NewValidationError("sender_id", fmt.Sprintf("sender IDs in %s must be %d-%d characters", strings.ToUpper(countryCode), rule.MinLength, rule.MaxLength))
+	}
+
+	if rule.RegistrationRequired && !p.IsRegistered(countryCode, senderID) {
+		return "", errors.NewValidationError("sender_id", fmt.Sprintf("sender ID %q is not registered in %s", senderID, strings.ToUpper(countryCode)))
+	}
+
+	return senderID, nil
+}
+
+// isNumericSender reports whether a sender is a phone number or short code
+func isNumericSender(senderID string) bool {
+	for i, r := range senderID {
+		if r == '+' && i == 0 {
+			continue
+		}
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// validateSenderIDFormat checks the characters every carrier accepts in
+// alphanumeric sender IDs: letters, digits and spaces, at most 11 of them
+func validateSenderIDFormat(senderID string) error {
+	if len(senderID) > 11 {
+		return errors.NewValidationError("sender_id", "sender IDs must be at most 11 characters")
+	}
+	for _, r := range senderID {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == ' ') {
+			return errors.NewValidationError("sender_id", "sender IDs may only contain letters, digits and spaces")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSenderIDPolicy_Resolve(t *testing.T) {
+	policy := NewSenderIDPolicy("+15550001111")
+	policy.Register("in", "acmeco")
+
+	tests := []struct {
+		name     string
+		senderID string
+		country  string
+		want     string
+		wantErr  bool
+	}{
+		{"no sender ID", "", "UK", "", false},
+		{"numeric sender", "+447700900123", "UK", "+447700900123", false},
+		{"short code", "12345", "US", "12345", false},
+		{"allowed country", "ACME", "UK", "ACME", false},
+		{"prohibited country falls back", "ACME", "US", "+15550001111", false},
+		{"unknown country falls back", "ACME", "JP", "+15550001111", false},
+		{"country not known", "ACME", "", "+15550001111", false},
+		{"too long", "ACMECORPORATION", "UK", "", true},
+		{"invalid characters", "ACME-CO", "UK", "", true},
+		{"below country minimum", "AC", "FR", "", true},
+		{"registered header", "ACMECO", "IN", "ACMECO", false},
+		{"wrong header length", "ACME", "IN", "", true},
+		{"unregistered header", "OTHERS", "IN", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := policy.Resolve(tt.senderID, tt.country)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSenderIDPolicy_SetRule(t *testing.T) {
+	policy := NewSenderIDPolicy("")
+
+	// Without a fallback number the provider's default sender is used
+	sender, err := policy.Resolve("ACME", "US")
+	require.NoError(t, err)
+	assert.Empty(t, sender)
+
+	policy.SetRule("us", SenderIDRule{Allowed: true, MinLength: 1, MaxLength: 11})
+	sender, err = policy.Resolve("ACME", "US")
+	require.NoError(t, err)
+	assert.Equal(t, "ACME", sender)
+
+	rule, exists := policy.Rule("US")
+	require.True(t, exists)
+	assert.True(t, rule.Allowed)
+}
@@ -0,0 +1,197 @@
+package providers
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSentHistory is how many sends a mock provider keeps when its
+// configuration does not set a limit
+const DefaultSentHistory = 10000
+
+// sentRecord is a send kept by a mock provider
+type sentRecord interface {
+	SentEmail | SentSMS | SentPush
+}
+
+// SentLog is a capped ring buffer of the sends of a mock provider. Once full,
+// each new send replaces the oldest, so long-running load tests use bounded
+// memory; the counters still cover every send.
+type SentLog[T sentRecord] struct {
+	mu      sync.Mutex
+	records []T
+	start   int // index of the oldest record once the buffer is full
+	limit   int
+	total   int64
+}
+
+// newSentLog creates a log keeping the most recent limit sends. A limit of
+// zero or less uses DefaultSentHistory.
+func newSentLog[T sentRecord](limit int) *SentLog[T] {
+	if limit <= 0 {
+		limit = DefaultSentHistory
+	}
+	return &SentLog[T]{limit: limit}
+}
+
+// add records a send, evicting the oldest when the log is full
+func (l *SentLog[T]) add(record T) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.total++
+	if len(l.records) < l.limit {
+		l.records = append(l.records, record)
+		return
+	}
+	l.records[l.start] = record
+	l.start = (l.start + 1) % l.limit
+}
+
+// All returns the kept sends, oldest first
+func (l *SentLog[T]) All() []T {
+	return l.Filter(func(T) bool { return true })
+}
+
+// Filter returns the kept sends matching a predicate, oldest first
+func (l *SentLog[T]) Filter(match func(record T) bool) []T {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	result := make([]T, 0)
+	for i := range l.records {
+		record := l.records[(l.start+i)%len(l.records)]
+		if match(record) {
+			result = append(result, record)
+		}
+	}
+	return result
+}
+
+// ByRecipient returns the kept sends to a recipient: an email address, phone
+// number or device token
+func (l *SentLog[T]) ByRecipient(recipient string) []T {
+	return l.Filter(func(record T) bool {
+		for _, r := range recipientsOf(record) {
+			if r == recipient {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Search returns the kept sends with a recipient containing a query,
+// ignoring case, so "example.com" finds every address at the domain
+func (l *SentLog[T]) Search(query string) []T {
+	query = strings.ToLower(query)
+	return l.Filter(func(record T) bool {
+		for _, r := range recipientsOf(record) {
+			if strings.Contains(strings.ToLower(r), query) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// ByStatus returns the kept sends with a status, e.g. "sent" or "delivered"
+func (l *SentLog[T]) ByStatus(status string) []T {
+	return l.Filter(func(record T) bool {
+		recordStatus, _ := statusOf(record)
+		return recordStatus == status
+	})
+}
+
+// Since returns the kept sends sent at or after a time
+func (l *SentLog[T]) Since(since time.Time) []T {
+	return l.Filter(func(record T) bool {
+		_, sentAt := statusOf(record)
+		return !sentAt.Before(since)
+	})
+}
+
+// Len returns the number of kept sends
+func (l *SentLog[T]) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.records)
+}
+
+// Total returns the number of sends recorded, including evicted ones
+func (l *SentLog[T]) Total() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.total
+}
+
+// Dropped returns the number of sends evicted to stay within the limit
+func (l *SentLog[T]) Dropped() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.total - int64(len(l.records))
+}
+
+// Limit returns the maximum number of kept sends
+func (l *SentLog[T]) Limit() int {
+	return l.limit
+}
+
+// Clear removes the kept sends and resets the counters
+func (l *SentLog[T]) Clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.records = nil
+	l.start = 0
+	l.total = 0
+}
+
+// last returns the most recent kept send matching a predicate
+func (l *SentLog[T]) last(match func(record T) bool) (T, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i := len(l.records) - 1; i >= 0; i-- {
+		record := l.records[(l.start+i)%len(l.records)]
+		if match(record) {
+			return record, true
+		}
+	}
+
+	var zero T
+	return zero, false
+}
+
+// recipientsOf returns the recipients of a send
+func recipientsOf[T sentRecord](record T) []string {
+	switch r := any(record).(type) {
+	case SentEmail:
+		recipients := make([]string, 0, len(r.To)+len(r.CC)+len(r.BCC))
+		recipients = append(recipients, r.To...)
+		recipients = append(recipients, r.CC...)
+		return append(recipients, r.BCC...)
+	case SentSMS:
+		return []string{r.PhoneNumber}
+	case SentPush:
+		return []string{r.DeviceToken}
+	}
+	return nil
+}
+
+// statusOf returns the status and send time of a send
+func statusOf[T sentRecord](record T) (string, time.Time) {
+	switch r := any(record).(type) {
+	case SentEmail:
+		return r.Status, r.SentAt
+	case SentSMS:
+		return r.Status, r.SentAt
+	case SentPush:
+		return r.Status, r.SentAt
+	}
+	return "", time.Time{}
+}
@@ -0,0 +1,101 @@
+package providers
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+)
+
+func TestSentLog_EvictsOldest(t *testing.T) {
+	log := newSentLog[SentSMS](3)
+	for i := 0; i < 5; i++ {
+		log.add(SentSMS{ID: uuid.New(), Segments: i})
+	}
+
+	kept := log.All()
+	require.Len(t, kept, 3)
+	assert.Equal(t, []int{2, 3, 4}, []int{kept[0].Segments, kept[1].Segments, kept[2].Segments})
+	assert.Equal(t, 3, log.Len())
+	assert.Equal(t, int64(5), log.Total())
+	assert.Equal(t, int64(2), log.Dropped())
+
+	latest, found := log.last(func(sent SentSMS) bool { return sent.Segments < 4 })
+	require.True(t, found)
+	assert.Equal(t, 3, latest.Segments)
+
+	log.Clear()
+	assert.Empty(t, log.All())
+	assert.Equal(t, int64(0), log.Total())
+}
+
+func TestSentLog_DefaultLimit(t *testing.T) {
+	assert.Equal(t, DefaultSentHistory, newSentLog[SentEmail](0).Limit())
+	assert.Equal(t, 5, newSentLog[SentEmail](5).Limit())
+}
+
+func TestSentLog_Queries(t *testing.T) {
+	log := newSentLog[SentEmail](10)
+	start := time.Now()
+	log.add(SentEmail{To: []string{"ana@example.com"}, Status: "sent", SentAt: start.Add(-time.Hour)})
+	log.add(SentEmail{To: []string{"bo@example.com"}, CC: []string{"ana@example.com"}, Status: "failed", SentAt: start})
+	log.add(SentEmail{To: []string{"cy@example.com"}, Status: "sent", SentAt: start.Add(time.Minute)})
+
+	assert.Len(t, log.ByRecipient("ana@example.com"), 2)
+	assert.Empty(t, log.ByRecipient("dee@example.com"))
+	assert.Len(t, log.Search("ANA@"), 2)
+	assert.Len(t, log.Search("example.com"), 3)
+	assert.Empty(t, log.Search("example.org"))
+	assert.Len(t, log.ByStatus("sent"), 2)
+
+	since := log.Since(start)
+	require.Len(t, since, 2)
+	assert.Equal(t, "bo@example.com", since[0].To[0])
+}
+
+func TestSentLog_ConcurrentSends(t *testing.T) {
+	provider := NewMockPushProvider(config.PushProviderConfig{Provider: "mock", Enabled: true, SentHistory: 10})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				provider.sentPush.add(SentPush{DeviceToken: testIOSToken, Status: "sent"})
+				provider.SentPushes().ByRecipient(testIOSToken)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 10, provider.SentPushes().Len())
+	assert.Equal(t, int64(40), provider.SentPushes().Total())
+	assert.Equal(t, int64(30), provider.SentPushes().Dropped())
+}
+
+func TestMockSMSProvider_SentHistory(t *testing.T) {
+	provider := NewMockSMSProvider(config.SMSProviderConfig{Provider: "mock", Enabled: true, SentHistory: 2})
+	ctx := context.Background()
+
+	var ids []uuid.UUID
+	for i := 0; i < 3; i++ {
+		sms := createTestSMSNotification()
+		_, err := provider.SendSMS(ctx, sms)
+		require.NoError(t, err)
+		ids = append(ids, sms.ID)
+	}
+
+	assert.Len(t, provider.GetSentSMS(), 2)
+	assert.Equal(t, int64(3), provider.SentMessages().Total())
+
+	// Evicted sends can no longer be looked up
+	_, err := provider.LookupMessage(ctx, ids[0], "")
+	assert.Error(t, err)
+}
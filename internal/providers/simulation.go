@@ -0,0 +1,170 @@
+package providers
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// FailureRule makes a mock provider fail sends to matching recipients
+type FailureRule struct {
+	// Recipient is an email address, phone number or device token. A trailing
+	// "*" matches by prefix, and an empty recipient matches every send.
+	Recipient string           `json:"recipient,omitempty"`
+	Code      errors.ErrorCode `json:"code"`              // e.g. RATE_LIMITED, PROVIDER_UNAVAILABLE, INVALID_TOKEN
+	Message   string           `json:"message,omitempty"` // defaults to a message naming the rule
+	Rate      float64          `json:"rate,omitempty"`    // probability a matching send fails; 0 means every one
+	Times     int              `json:"times,omitempty"`   // fail only the first N matching sends; 0 means no limit
+}
+
+// Simulation describes how a mock provider behaves: how long sends take,
+// how many are delivered and which fail. With the same seed, the same
+// sequence of sends behaves the same way.
+type Simulation struct {
+	Seed             int64         `json:"seed"`        // 0 seeds from the clock
+	LatencyMin       time.Duration `json:"latency_min"` // send latency is uniform in [LatencyMin, LatencyMax]
+	LatencyMax       time.Duration `json:"latency_max"`
+	DeliveryRate     float64       `json:"delivery_rate"`      // fraction of accepted sends marked delivered
+	DeliveryDelayMin time.Duration `json:"delivery_delay_min"` // delivery follows the send by a delay uniform in [min, max]
+	DeliveryDelayMax time.Duration `json:"delivery_delay_max"`
+	Rules            []FailureRule `json:"rules,omitempty"`
+}
+
+// Default simulations, matching the mock providers' historical behavior
+var (
+	defaultEmailSimulation = Simulation{LatencyMin: 100 * time.Millisecond, LatencyMax: 100 * time.Millisecond}
+	defaultSMSSimulation   = Simulation{
+		LatencyMin:       150 * time.Millisecond,
+		LatencyMax:       150 * time.Millisecond,
+		DeliveryRate:     0.9,
+		DeliveryDelayMin: 100 * time.Millisecond,
+		DeliveryDelayMax: 600 * time.Millisecond,
+	}
+	defaultPushSimulation = Simulation{
+		LatencyMin:       120 * time.Millisecond,
+		LatencyMax:       120 * time.Millisecond,
+		DeliveryRate:     0.85,
+		DeliveryDelayMin: 50 * time.Millisecond,
+		DeliveryDelayMax: 350 * time.Millisecond,
+	}
+)
+
+// simulator runs a simulation
+type simulator struct {
+	provider string
+
+	mu     sync.Mutex
+	config Simulation
+	random *rand.Rand
+	hits   []int // matching sends failed by each rule
+}
+
+// newSimulator creates a simulator for a provider
+func newSimulator(provider string, sim Simulation) *simulator {
+	s := &simulator{provider: provider}
+	s.set(sim)
+	return s
+}
+
+// set replaces the simulation, resetting the random sequence and rule counts
+func (s *simulator) set(sim Simulation) {
+	seed := sim.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	sim.Rules = append([]FailureRule(nil), sim.Rules...)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.config = sim
+	s.random = rand.New(rand.NewSource(seed))
+	s.hits = make([]int, len(sim.Rules))
+}
+
+// get returns the simulation
+func (s *simulator) get() Simulation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sim := s.config
+	sim.Rules = append([]FailureRule(nil), s.config.Rules...)
+	return sim
+}
+
+// latency returns how long a send takes
+func (s *simulator) latency() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.uniform(s.config.LatencyMin, s.config.LatencyMax)
+}
+
+// fail returns the error of the first rule failing a send to any of the
+// recipients, or nil when the send goes through
+func (s *simulator) fail(recipients ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, rule := range s.config.Rules {
+		if !matchesAny(rule.Recipient, recipients) {
+			continue
+		}
+		if rule.Times > 0 && s.hits[i] >= rule.Times {
+			continue
+		}
+		if rule.Rate > 0 && s.random.Float64() >= rule.Rate {
+			continue
+		}
+
+		s.hits[i]++
+		message := rule.Message
+		if message == "" {
+			message = fmt.Sprintf("simulated failure: %s", rule.Code)
+		}
+		return errors.NewProviderError(s.provider, rule.Code, message)
+	}
+	return nil
+}
+
+// delivery reports whether an accepted send is delivered, and when
+func (s *simulator) delivery(sentAt time.Time) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.config.DeliveryRate <= 0 || s.random.Float64() >= s.config.DeliveryRate {
+		return time.Time{}, false
+	}
+
+	return sentAt.Add(s.uniform(s.config.DeliveryDelayMin, s.config.DeliveryDelayMax)), true
+}
+
+// uniform returns a duration uniform in [min, max]. Callers must hold the lock.
+func (s *simulator) uniform(min, max time.Duration) time.Duration {
+	if max <= min {
+		return min
+	}
+	return min + time.Duration(s.random.Int63n(int64(max-min)+1))
+}
+
+// matchesAny reports whether a rule's recipient pattern matches any recipient
+func matchesAny(pattern string, recipients []string) bool {
+	if pattern == "" {
+		return true
+	}
+
+	for _, recipient := range recipients {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(recipient, prefix) {
+				return true
+			}
+		} else if recipient == pattern {
+			return true
+		}
+	}
+	return false
+}
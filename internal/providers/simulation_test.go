@@ -0,0 +1,137 @@
+package providers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestSimulation_SameSeedSameOutcome(t *testing.T) {
+	sim := Simulation{Seed: 42, DeliveryRate: 0.5, DeliveryDelayMax: time.Second}
+
+	outcomes := func() []string {
+		provider := createTestSMSProvider()
+		provider.SetSimulation(sim)
+		for i := 0; i < 20; i++ {
+			_, err := provider.SendSMS(context.Background(), createTestSMSNotification())
+			require.NoError(t, err)
+		}
+
+		statuses := make([]string, 0, 20)
+		for _, sent := range provider.GetSentSMS() {
+			statuses = append(statuses, sent.Status)
+		}
+		return statuses
+	}
+
+	first := outcomes()
+	assert.Equal(t, first, outcomes())
+	assert.Contains(t, first, "sent")
+	assert.Contains(t, first, "delivered")
+}
+
+func TestSimulation_FailureRules(t *testing.T) {
+	provider := createTestEmailProvider()
+	provider.SetSimulation(Simulation{
+		Seed: 1,
+		Rules: []FailureRule{
+			{Recipient: "flaky@example.com", Code: errors.ErrorCodeRateLimited, Times: 2},
+			{Recipient: "bounce*", Code: errors.ErrorCodeInvalidRecipient, Message: "mailbox does not exist"},
+		},
+	})
+	ctx := context.Background()
+
+	flaky := func() error {
+		email := createTestEmailNotification()
+		email.To = []string{"flaky@example.com"}
+		_, err := provider.SendEmail(ctx, email)
+		return err
+	}
+
+	// The first two sends are rate limited, so a retry succeeds on the third
+	for i := 0; i < 2; i++ {
+		err := flaky()
+		require.Error(t, err)
+		notifErr, ok := errors.AsNotificationError(err)
+		require.True(t, ok)
+		assert.Equal(t, errors.ErrorCodeRateLimited, notifErr.Code)
+	}
+	assert.NoError(t, flaky())
+
+	email := createTestEmailNotification()
+	email.CC = []string{"bounce-1@example.com"}
+	_, err := provider.SendEmail(ctx, email)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "mailbox does not exist")
+
+	_, err = provider.SendEmail(ctx, createTestEmailNotification())
+	assert.NoError(t, err)
+	assert.Len(t, provider.GetSentEmails(), 2)
+}
+
+func TestSimulation_FailureRate(t *testing.T) {
+	failures := func(seed int64) int {
+		provider := createTestPushProvider()
+		provider.SetSimulation(Simulation{Seed: seed, Rules: []FailureRule{{Code: errors.ErrorCodeProviderUnavailable, Rate: 0.3}}})
+
+		count := 0
+		for i := 0; i < 50; i++ {
+			if _, err := provider.SendPush(context.Background(), createTestPushNotification()); err != nil {
+				count++
+			}
+		}
+		return count
+	}
+
+	count := failures(7)
+	assert.Equal(t, count, failures(7))
+	assert.Greater(t, count, 0)
+	assert.Less(t, count, 50)
+}
+
+func TestSimulation_BatchFailsMatchingItems(t *testing.T) {
+	provider := createTestPushProvider()
+	failing := createTestPushNotification()
+	provider.SetSimulation(Simulation{Rules: []FailureRule{{Recipient: failing.DeviceToken, Code: errors.ErrorCodeInvalidToken}}})
+
+	other := createTestPushNotification()
+	other.DeviceToken = testAndroidToken
+	other.Platform = "android"
+
+	results, err := provider.SendPushBatch(context.Background(), []*models.PushNotification{failing, other})
+	require.NoError(t, err)
+	assert.Error(t, results[0].Err)
+	assert.NoError(t, results[1].Err)
+}
+
+func TestSimulation_Latency(t *testing.T) {
+	sim := newSimulator("mock", Simulation{Seed: 3, LatencyMin: 10 * time.Millisecond, LatencyMax: 20 * time.Millisecond})
+	for i := 0; i < 100; i++ {
+		latency := sim.latency()
+		assert.GreaterOrEqual(t, latency, 10*time.Millisecond)
+		assert.LessOrEqual(t, latency, 20*time.Millisecond)
+	}
+
+	provider := createTestEmailProvider()
+	assert.Equal(t, 100*time.Millisecond, provider.Simulation().LatencyMin)
+}
+
+func TestSimulation_DeliveryDelayInResponse(t *testing.T) {
+	provider := createTestPushProvider()
+	provider.SetSimulation(Simulation{Seed: 1, DeliveryRate: 1, DeliveryDelayMin: 200 * time.Millisecond, DeliveryDelayMax: 200 * time.Millisecond})
+
+	response, err := provider.SendPush(context.Background(), createTestPushNotification())
+	require.NoError(t, err)
+	assert.Equal(t, "200ms", response.ProviderMetadata["delivery_delay"])
+	assert.Equal(t, "ios", response.ProviderMetadata["platform"])
+
+	// The response carries a copy of the provider data
+	response.ProviderMetadata["platform"] = "changed"
+	assert.Equal(t, "ios", provider.GetSentPush()[0].ProviderData["platform"])
+}
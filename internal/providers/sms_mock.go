@@ -3,38 +3,60 @@ package providers
 import (
 	"context"
 	"fmt"
+	"maps"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/nareshkumar-microsoft/notificationService/internal/config"
 	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/templateschema"
+	"github.com/nareshkumar-microsoft/notificationService/internal/templatevars"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
 	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
 	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
 )
 
-// MockSMSProvider implements the SMSProvider interface for testing and development
+// MockSMSProvider implements the SMSProvider interface for testing and
+// development. It is safe for concurrent use.
 type MockSMSProvider struct {
-	config    config.SMSProviderConfig
+	config  config.SMSProviderConfig
+	sentSMS *SentLog[SentSMS]
+	sim     *simulator
+	costs   map[string]float64 // Country code to cost mapping; read-only after creation
+
+	mu        sync.RWMutex
 	templates map[string]*SMSTemplate
-	sentSMS   []SentSMS
+	compiled  *templatevars.Cache // compiled template fields
 	healthy   bool
-	costs     map[string]float64 // Country code to cost mapping
 }
 
 // SMSTemplate represents an SMS template
 type SMSTemplate struct {
-	ID        string            `json:"id"`
-	Name      string            `json:"name"`
-	Message   string            `json:"message"`
-	Variables []string          `json:"variables"`
-	Category  string            `json:"category"`
-	MaxLength int               `json:"max_length"`
-	Unicode   bool              `json:"unicode"`
-	CreatedAt time.Time         `json:"created_at"`
-	UpdatedAt time.Time         `json:"updated_at"`
-	Metadata  map[string]string `json:"metadata,omitempty"`
+	ID        string                `json:"id"`
+	Name      string                `json:"name"`
+	Message   string                `json:"message"`
+	Variables []string              `json:"variables"`
+	Category  string                `json:"category"`
+	MaxLength int                   `json:"max_length"`
+	Unicode   bool                  `json:"unicode"`
+	Schema    templateschema.Schema `json:"schema,omitempty"` // checked against template data before rendering
+	CreatedAt time.Time             `json:"created_at"`
+	UpdatedAt time.Time             `json:"updated_at"`
+	Metadata  map[string]string     `json:"metadata,omitempty"`
+}
+
+// SetDefaults sets the default max length if not specified
+func (t *SMSTemplate) SetDefaults() {
+	if t.MaxLength == 0 {
+		if t.Unicode {
+			t.MaxLength = 70
+		} else {
+			t.MaxLength = 160
+		}
+	}
 }
 
 // SentSMS represents an SMS that was sent (for mock tracking)
@@ -42,6 +64,7 @@ type SentSMS struct {
 	ID           uuid.UUID         `json:"id"`
 	PhoneNumber  string            `json:"phone_number"`
 	CountryCode  string            `json:"country_code,omitempty"`
+	SenderID     string            `json:"sender_id,omitempty"`
 	Message      string            `json:"message"`
 	Unicode      bool              `json:"unicode"`
 	SentAt       time.Time         `json:"sent_at"`
@@ -50,8 +73,13 @@ type SentSMS struct {
 	Cost         float64           `json:"cost"`
 	Segments     int               `json:"segments"`
 	ProviderData map[string]string `json:"provider_data,omitempty"`
+	// ProviderOptions are the request's provider options, as the provider received them
+	ProviderOptions map[string]any `json:"provider_options,omitempty"`
 }
 
+// SMSCostCurrency is the currency of the costs GetSMSCost returns
+const SMSCostCurrency = "USD"
+
 // CountryInfo represents information about SMS costs for a country
 type CountryInfo struct {
 	Code      string  `json:"code"`
@@ -66,7 +94,9 @@ func NewMockSMSProvider(cfg config.SMSProviderConfig) *MockSMSProvider {
 	provider := &MockSMSProvider{
 		config:    cfg,
 		templates: make(map[string]*SMSTemplate),
-		sentSMS:   make([]SentSMS, 0),
+		compiled:  templatevars.NewCache(0),
+		sentSMS:   newSentLog[SentSMS](cfg.SentHistory),
+		sim:       newSimulator("mock-sms", defaultSMSSimulation),
 		healthy:   true,
 		costs:     make(map[string]float64),
 	}
@@ -80,7 +110,7 @@ func NewMockSMSProvider(cfg config.SMSProviderConfig) *MockSMSProvider {
 
 // Send implements the NotificationProvider interface
 func (p *MockSMSProvider) Send(ctx context.Context, notification *models.Notification) (*models.NotificationResponse, error) {
-	if !p.healthy {
+	if !p.isHealthy() {
 		return nil, errors.NewProviderError("mock-sms", errors.ErrorCodeProviderUnavailable, "provider is unhealthy")
 	}
 
@@ -95,7 +125,7 @@ func (p *MockSMSProvider) Send(ctx context.Context, notification *models.Notific
 
 // SendSMS implements the SMSProvider interface
 func (p *MockSMSProvider) SendSMS(ctx context.Context, sms *models.SMSNotification) (*models.NotificationResponse, error) {
-	if !p.healthy {
+	if !p.isHealthy() {
 		return nil, errors.NewProviderError("mock-sms", errors.ErrorCodeProviderUnavailable, "provider is unhealthy")
 	}
 
@@ -104,48 +134,71 @@ func (p *MockSMSProvider) SendSMS(ctx context.Context, sms *models.SMSNotificati
 		return nil, err
 	}
 
-	// Simulate processing delay
+	// Simulate processing delay. A cancelled send never reaches the
+	// provider, even when the simulation has no latency.
+	if ctx.Err() != nil {
+		return nil, errors.NewNotificationError(errors.ErrorCodeTimeout, "SMS sending timed out")
+	}
 	select {
 	case <-ctx.Done():
 		return nil, errors.NewNotificationError(errors.ErrorCodeTimeout, "SMS sending timed out")
-	case <-time.After(150 * time.Millisecond):
+	case <-time.After(p.sim.latency()):
 		// Continue processing
 	}
 
+	if err := p.sim.fail(sms.PhoneNumber); err != nil {
+		return nil, err
+	}
+
 	// Calculate segments and cost
 	segments := p.calculateSegments(sms.Message, sms.Unicode)
-	cost := p.calculateCost(sms.CountryCode, segments)
+	countryCode := utils.ResolveCountry(sms.PhoneNumber, sms.CountryCode)
+	cost := p.calculateCost(countryCode, segments)
 
 	// Create sent SMS record
 	sentSMS := SentSMS{
 		ID:          sms.ID,
 		PhoneNumber: sms.PhoneNumber,
-		CountryCode: sms.CountryCode,
+		CountryCode: countryCode,
+		SenderID:    sms.SenderID,
 		Message:     sms.Message,
 		Unicode:     sms.Unicode,
 		SentAt:      time.Now(),
 		Status:      "sent",
 		Cost:        cost,
 		Segments:    segments,
+
+		ProviderOptions: sms.ProviderOptions,
 		ProviderData: map[string]string{
 			"provider":     "mock-sms",
 			"message_id":   fmt.Sprintf("sms-%s", sms.ID.String()),
 			"queue_time":   "150ms",
 			"retry_count":  "0",
-			"country_code": sms.CountryCode,
+			"country_code": countryCode,
 		},
 	}
 
-	// Simulate delivery (90% success rate)
-	if time.Now().UnixNano()%10 < 9 {
-		deliveredAt := time.Now().Add(time.Duration(100+time.Now().UnixNano()%500) * time.Millisecond)
+	if sms.SenderID != "" {
+		sentSMS.ProviderData["sender_id"] = sms.SenderID
+	}
+	if sid, ok := sms.ProviderOptions["messaging_service_sid"].(string); ok {
+		sentSMS.ProviderData["messaging_service_sid"] = sid
+	}
+	if sms.CorrelationID != "" {
+		// SMS APIs take this as the message's client reference
+		sentSMS.ProviderData["client_reference"] = sms.CorrelationID
+	}
+
+	// Simulate delivery
+	if deliveredAt, delivered := p.sim.delivery(sentSMS.SentAt); delivered {
 		sentSMS.DeliveredAt = &deliveredAt
 		sentSMS.Status = "delivered"
 		sentSMS.ProviderData["delivery_time"] = deliveredAt.Format(time.RFC3339)
+		sentSMS.ProviderData["delivery_delay"] = deliveredAt.Sub(sentSMS.SentAt).String()
 	}
 
 	// Store sent SMS for tracking
-	p.sentSMS = append(p.sentSMS, sentSMS)
+	p.sentSMS.add(sentSMS)
 
 	// Create response
 	now := time.Now()
@@ -155,6 +208,11 @@ func (p *MockSMSProvider) SendSMS(ctx context.Context, sms *models.SMSNotificati
 		Message:    fmt.Sprintf("SMS sent to %s (%d segments, $%.4f)", sms.PhoneNumber, segments, cost),
 		ProviderID: sentSMS.ProviderData["message_id"],
 		SentAt:     &now,
+		Segments:   segments,
+		Cost:       cost,
+		Currency:   SMSCostCurrency,
+
+		ProviderMetadata: maps.Clone(sentSMS.ProviderData),
 	}
 
 	return response, nil
@@ -175,14 +233,19 @@ func (p *MockSMSProvider) ValidatePhoneNumber(phoneNumber, countryCode string) e
 		return errors.NewValidationError("phone_number", "phone number must contain 7-15 digits")
 	}
 
-	// Country-specific validation
+	// Country-specific validation. Without a country code, the country is
+	// inferred from an international number.
+	explicit := countryCode != ""
+	countryCode = utils.ResolveCountry(phoneNumber, countryCode)
 	if countryCode != "" {
-		if err := p.validateCountryCode(countryCode); err != nil {
-			return err
+		if explicit {
+			if err := p.validateCountryCode(countryCode); err != nil {
+				return err
+			}
 		}
 
-		// Validate number format for specific countries
-		if err := p.validatePhoneForCountry(cleanNumber, countryCode); err != nil {
+		// Validate number format for specific countries, without the calling code
+		if err := p.validatePhoneForCountry(utils.NationalNumber(phoneNumber, countryCode), countryCode); err != nil {
 			return err
 		}
 	}
@@ -211,7 +274,7 @@ func (p *MockSMSProvider) GetType() models.NotificationType {
 
 // IsHealthy implements the NotificationProvider interface
 func (p *MockSMSProvider) IsHealthy(ctx context.Context) error {
-	if !p.healthy {
+	if !p.isHealthy() {
 		return errors.NewProviderError("mock-sms", errors.ErrorCodeProviderUnavailable, "provider is marked as unhealthy")
 	}
 
@@ -249,33 +312,62 @@ func (p *MockSMSProvider) GetConfig() interfaces.ProviderConfig {
 
 // GetTemplate retrieves an SMS template by ID
 func (p *MockSMSProvider) GetTemplate(templateID string) (*SMSTemplate, error) {
+	p.mu.RLock()
 	template, exists := p.templates[templateID]
+	p.mu.RUnlock()
+
 	if !exists {
 		return nil, errors.NewNotificationError(errors.ErrorCodeTemplateNotFound, fmt.Sprintf("template not found: %s", templateID))
 	}
 	return template, nil
 }
 
+// Templates returns the SMS templates, sorted by ID
+func (p *MockSMSProvider) Templates() []*SMSTemplate {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return sortedTemplates(p.templates)
+}
+
 // AddTemplate adds a new SMS template
 func (p *MockSMSProvider) AddTemplate(template *SMSTemplate) error {
-	if template.ID == "" {
-		template.ID = uuid.New().String()
+	if err := template.prepare(time.Now()); err != nil {
+		return err
 	}
 
-	now := time.Now()
-	template.CreatedAt = now
-	template.UpdatedAt = now
+	p.mu.Lock()
+	p.templates[template.ID] = template
+	p.mu.Unlock()
+	return nil
+}
 
-	// Set default max length if not specified
-	if template.MaxLength == 0 {
-		if template.Unicode {
-			template.MaxLength = 70
-		} else {
-			template.MaxLength = 160
-		}
+// ReplaceTemplates replaces every SMS template at once. Nothing is
+// replaced unless all the templates are valid.
+func (p *MockSMSProvider) ReplaceTemplates(templates []*SMSTemplate) error {
+	replaced, err := keyTemplates(templates, func(t *SMSTemplate) string { return t.ID }, (*SMSTemplate).prepare)
+	if err != nil {
+		return err
 	}
 
-	p.templates[template.ID] = template
+	p.mu.Lock()
+	p.templates = replaced
+	p.mu.Unlock()
+	return nil
+}
+
+// prepare checks a template and sets its defaults before it is stored
+func (t *SMSTemplate) prepare(now time.Time) error {
+	if err := t.Schema.Check(); err != nil {
+		return err
+	}
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+
+	t.CreatedAt = now
+	t.UpdatedAt = now
+	t.SetDefaults()
 	return nil
 }
 
@@ -285,16 +377,21 @@ func (p *MockSMSProvider) RenderTemplate(templateID string, data map[string]stri
 	if err != nil {
 		return nil, err
 	}
+	data = template.Schema.Apply(data)
+	if err := template.Schema.Validate(data); err != nil {
+		return nil, err
+	}
 
 	// Clone template for rendering
 	rendered := &SMSTemplate{
 		ID:        template.ID,
 		Name:      template.Name,
-		Message:   p.replaceVariables(template.Message, data),
+		Message:   p.renderField(template, "message", template.Message, data, time.Now()),
 		Variables: template.Variables,
 		Category:  template.Category,
 		MaxLength: template.MaxLength,
 		Unicode:   template.Unicode,
+		Schema:    template.Schema,
 		CreatedAt: template.CreatedAt,
 		UpdatedAt: template.UpdatedAt,
 		Metadata:  template.Metadata,
@@ -303,21 +400,82 @@ func (p *MockSMSProvider) RenderTemplate(templateID string, data map[string]stri
 	return rendered, nil
 }
 
-// GetSentSMS returns all sent SMS messages (for testing)
+// GetSentSMS returns the kept sent SMS messages, oldest first (for testing)
 func (p *MockSMSProvider) GetSentSMS() []SentSMS {
+	return p.sentSMS.All()
+}
+
+// SentMessages returns the log of sent SMS messages (for testing)
+func (p *MockSMSProvider) SentMessages() *SentLog[SentSMS] {
 	return p.sentSMS
 }
 
+// LookupAuthoritative implements the AuthoritativeLookupProvider interface:
+// the sent log holds every message as soon as it is sent
+func (p *MockSMSProvider) LookupAuthoritative() bool {
+	return true
+}
+
+// LookupMessage implements the MessageLookupProvider interface
+func (p *MockSMSProvider) LookupMessage(ctx context.Context, notificationID uuid.UUID, providerMessageID string) (*models.NotificationResponse, error) {
+	sent, found := p.sentSMS.last(func(sent SentSMS) bool {
+		return sent.ID == notificationID || (providerMessageID != "" && sent.ProviderData["message_id"] == providerMessageID)
+	})
+	if !found {
+		return nil, errors.NewNotificationError(errors.ErrorCodeNotFound, fmt.Sprintf("no SMS was sent for notification %s", notificationID))
+	}
+
+	sentAt := sent.SentAt
+	status := models.StatusSent
+	if sent.DeliveredAt != nil {
+		status = models.StatusDelivered
+	}
+	return &models.NotificationResponse{
+		ID:         sent.ID,
+		Status:     status,
+		Message:    fmt.Sprintf("SMS is %s", sent.Status),
+		ProviderID: sent.ProviderData["message_id"],
+		SentAt:     &sentAt,
+		Segments:   sent.Segments,
+		Cost:       sent.Cost,
+		Currency:   SMSCostCurrency,
+
+		ProviderMetadata: maps.Clone(sent.ProviderData),
+	}, nil
+}
+
 // ClearSentSMS clears the sent SMS history (for testing)
 func (p *MockSMSProvider) ClearSentSMS() {
-	p.sentSMS = make([]SentSMS, 0)
+	p.sentSMS.Clear()
+}
+
+// SetSimulation replaces the simulated latency, delivery and failures,
+// restarting its random sequence (for testing)
+func (p *MockSMSProvider) SetSimulation(sim Simulation) {
+	p.sim.set(sim)
+}
+
+// Simulation returns the simulated behavior (for testing)
+func (p *MockSMSProvider) Simulation() Simulation {
+	return p.sim.get()
 }
 
 // SetHealthy sets the provider health status (for testing)
 func (p *MockSMSProvider) SetHealthy(healthy bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	p.healthy = healthy
 }
 
+// isHealthy reports whether the provider is set healthy
+func (p *MockSMSProvider) isHealthy() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.healthy
+}
+
 // GetSupportedCountries returns list of supported countries
 func (p *MockSMSProvider) GetSupportedCountries() []CountryInfo {
 	countries := []CountryInfo{
@@ -380,7 +538,20 @@ func (p *MockSMSProvider) validateSMSNotification(sms *models.SMSNotification) e
 		return errors.NewValidationError("message", fmt.Sprintf("message too long (max %d characters for 10 segments)", maxLength*10))
 	}
 
-	return nil
+	return p.ValidateProviderOptions(sms.ProviderOptions)
+}
+
+// mockSMSOptions are the provider options of the mock SMS provider,
+// modelled on Twilio's
+var mockSMSOptions = OptionSpecs{
+	"messaging_service_sid": {Kind: OptionString, Pattern: regexp.MustCompile(`^MG[0-9a-fA-F]{32}$`), Description: "Messaging service to send through instead of the sender"},
+	"validity_period":       {Kind: OptionInteger, Min: 1, Max: 36000, Description: "Seconds the message may wait in the queue before it is dropped"},
+	"shorten_urls":          {Kind: OptionBoolean, Description: "Shorten links in the message"},
+}
+
+// ValidateProviderOptions implements the OptionsProvider interface
+func (p *MockSMSProvider) ValidateProviderOptions(options map[string]any) error {
+	return mockSMSOptions.Validate("mock-sms", options)
 }
 
 // cleanPhoneNumber removes formatting characters from phone number
@@ -489,14 +660,11 @@ func (p *MockSMSProvider) calculateCost(countryCode string, segments int) float6
 	return baseCost * float64(segments)
 }
 
-// replaceVariables replaces template variables with provided data
-func (p *MockSMSProvider) replaceVariables(template string, data map[string]string) string {
-	result := template
-	for key, value := range data {
-		placeholder := fmt.Sprintf("{{%s}}", key)
-		result = strings.ReplaceAll(result, placeholder, value)
-	}
-	return result
+// renderField replaces a template field's variables with provided data and
+// computed variables such as {{date}}, at the time now. The field is
+// compiled once per template version.
+func (p *MockSMSProvider) renderField(template *SMSTemplate, field, text string, data map[string]string, now time.Time) string {
+	return p.compiled.Get(templateCacheKey(template.ID, template.UpdatedAt, field), text).Render(data, now)
 }
 
 // loadDefaultTemplates loads default SMS templates
@@ -3,6 +3,7 @@ package providers
 import (
 	"context"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -12,6 +13,8 @@ import (
 
 	"github.com/nareshkumar-microsoft/notificationService/internal/config"
 	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/templateschema"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
 	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
 )
 
@@ -30,7 +33,7 @@ func TestNewMockSMSProvider(t *testing.T) {
 	assert.Equal(t, cfg, provider.config)
 	assert.True(t, provider.healthy)
 	assert.Len(t, provider.templates, 4) // Default templates loaded
-	assert.Empty(t, provider.sentSMS)
+	assert.Empty(t, provider.sentSMS.All())
 	assert.NotEmpty(t, provider.costs) // Default costs loaded
 }
 
@@ -87,6 +90,11 @@ func TestMockSMSProvider_ValidatePhoneNumber(t *testing.T) {
 		{"contains letters", "123abc7890", "", true},
 		{"invalid US number", "123456789", "US", true},
 		{"unsupported country", "1234567890", "XX", true},
+		{"UK number with calling code", "+44 20 7946 0000", "UK", false},
+		{"inferred UK number", "+447911123456", "", false},
+		{"inferred UK number too short", "+44791112", "", true},
+		{"inferred Indian number too long", "+91 98765 432101", "", true},
+		{"00 international prefix", "0044 7911 123456", "", false},
 	}
 
 	for _, tt := range tests {
@@ -144,6 +152,11 @@ func TestMockSMSProvider_SendSMS_Success(t *testing.T) {
 	assert.Contains(t, response.Message, "SMS sent")
 	assert.NotEmpty(t, response.ProviderID)
 	assert.NotNil(t, response.SentAt)
+	assert.Equal(t, 1, response.Segments)
+	assert.Greater(t, response.Cost, 0.0)
+	assert.Equal(t, "USD", response.Currency)
+	assert.Equal(t, response.ProviderID, response.ProviderMetadata["message_id"])
+	assert.NotContains(t, response.ProviderMetadata, "client_reference")
 
 	// Check that SMS was recorded
 	sentSMS := provider.GetSentSMS()
@@ -155,6 +168,18 @@ func TestMockSMSProvider_SendSMS_Success(t *testing.T) {
 	assert.Greater(t, sentSMS[0].Cost, 0.0)
 }
 
+func TestMockSMSProvider_SendSMS_ClientReference(t *testing.T) {
+	provider := createTestSMSProvider()
+
+	sms := createTestSMSNotification()
+	sms.CorrelationID = "req-42"
+
+	response, err := provider.SendSMS(context.Background(), sms)
+
+	require.NoError(t, err)
+	assert.Equal(t, "req-42", response.ProviderMetadata["client_reference"])
+}
+
 func TestMockSMSProvider_SendSMS_ValidationErrors(t *testing.T) {
 	provider := createTestSMSProvider()
 	ctx := context.Background()
@@ -329,6 +354,62 @@ func TestMockSMSProvider_AddTemplate(t *testing.T) {
 	assert.Equal(t, 160, retrieved.MaxLength)
 }
 
+func TestMockSMSProvider_TemplateSchema(t *testing.T) {
+	provider := createTestSMSProvider()
+
+	template := &SMSTemplate{
+		ID:      "payment_due",
+		Message: "{{amount}} is due on {{due_date}}",
+		Schema: templateschema.Schema{
+			"amount":   {Type: templateschema.TypeNumber, Required: true},
+			"due_date": {Type: templateschema.TypeDate, Required: true},
+		},
+	}
+	require.NoError(t, provider.AddTemplate(template))
+
+	rendered, err := provider.RenderTemplate("payment_due", map[string]string{"amount": "42.50", "due_date": "2024-07-01"})
+	require.NoError(t, err)
+	assert.Equal(t, "42.50 is due on 2024-07-01", rendered.Message)
+
+	_, err = provider.RenderTemplate("payment_due", map[string]string{"amount": "forty-two"})
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	require.Len(t, notifErr.Fields, 2)
+	assert.Equal(t, "template_data.amount", notifErr.Fields[0].Field)
+	assert.Equal(t, templateschema.RuleType, notifErr.Fields[0].Rule)
+	assert.Equal(t, "template_data.due_date", notifErr.Fields[1].Field)
+	assert.Equal(t, templateschema.RuleRequired, notifErr.Fields[1].Rule)
+
+	// Schemas are checked when templates are added
+	err = provider.AddTemplate(&SMSTemplate{Message: "{{x}}", Schema: templateschema.Schema{"x": {Type: "money"}}})
+	assert.Error(t, err)
+}
+
+func TestMockSMSProvider_TemplateDefaultsAndComputedVariables(t *testing.T) {
+	provider := createTestSMSProvider()
+
+	require.NoError(t, provider.AddTemplate(&SMSTemplate{
+		ID:      "reminder",
+		Message: "{{greeting}} {{name}}, see you in {{date format=2006}}",
+		Schema: templateschema.Schema{
+			"greeting": {Default: "Hi"},
+			"name":     {Required: true, Default: "there"},
+		},
+	}))
+
+	rendered, err := provider.RenderTemplate("reminder", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Hi there, see you in "+time.Now().UTC().Format("2006"), rendered.Message)
+
+	rendered, err = provider.RenderTemplate("reminder", map[string]string{"greeting": "Hello", "name": "Ada"})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(rendered.Message, "Hello Ada, see you in "))
+
+	// Defaults must match their variable's declaration
+	err = provider.AddTemplate(&SMSTemplate{Message: "{{n}}", Schema: templateschema.Schema{"n": {Type: templateschema.TypeInteger, Default: "many"}}})
+	assert.Error(t, err)
+}
+
 func TestMockSMSProvider_RenderTemplate(t *testing.T) {
 	provider := createTestSMSProvider()
 
@@ -468,6 +549,47 @@ func TestMockSMSProvider_GetSupportedCountries(t *testing.T) {
 	assert.True(t, ukFound, "UK should be in supported countries")
 }
 
+func TestMockSMSProvider_SendSMS_InfersCountry(t *testing.T) {
+	provider := createTestSMSProvider()
+
+	sms := createTestSMSNotification()
+	sms.PhoneNumber = "+447911123456"
+	sms.CountryCode = ""
+
+	response, err := provider.SendSMS(context.Background(), sms)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0080, response.Cost) // UK rate
+	assert.Equal(t, "UK", response.ProviderMetadata["country_code"])
+	assert.Equal(t, "UK", provider.GetSentSMS()[0].CountryCode)
+}
+
+func TestCountryFromPhoneNumber(t *testing.T) {
+	tests := []struct {
+		phoneNumber string
+		country     string
+	}{
+		{"+447911123456", "UK"},
+		{"+1 (415) 555-0123", "US"},
+		{"+1 416 555 0123", "CA"},
+		{"+85221234567", "HK"},
+		{"0049 30 1234567", "DE"},
+		{"+1234567890", ""}, // NANP numbers have ten digits after the code
+		{"4155550123", ""},  // national format
+		{"+99912345678", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.phoneNumber, func(t *testing.T) {
+			assert.Equal(t, tt.country, utils.CountryFromPhoneNumber(tt.phoneNumber))
+		})
+	}
+
+	assert.Equal(t, "7911123456", utils.NationalNumber("+44 7911 123456", "UK"))
+	assert.Equal(t, "4165550123", utils.NationalNumber("+14165550123", "CA"))
+	assert.Equal(t, "07911123456", utils.NationalNumber("07911123456", "UK"))
+	assert.Equal(t, "DE", utils.ResolveCountry("+447911123456", "de"))
+}
+
 func TestMockSMSProvider_ClearSentSMS(t *testing.T) {
 	provider := createTestSMSProvider()
 	ctx := context.Background()
@@ -516,8 +638,9 @@ func TestMockSMSProvider_CostCalculation(t *testing.T) {
 				Unicode:     false,
 			}
 
-			_, err := provider.SendSMS(ctx, sms)
+			response, err := provider.SendSMS(ctx, sms)
 			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCost, response.Cost)
 
 			sentSMS := provider.GetSentSMS()
 			require.Len(t, sentSMS, 1)
@@ -529,6 +652,41 @@ func TestMockSMSProvider_CostCalculation(t *testing.T) {
 	}
 }
 
+func TestMockSMSProvider_ConcurrentUse(t *testing.T) {
+	provider := createTestSMSProvider()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			provider.SendSMS(ctx, createTestSMSNotification())
+			provider.AddTemplate(&SMSTemplate{Name: "concurrent", Message: "Hi"})
+			provider.GetTemplate("verification")
+			provider.SetHealthy(i%2 == 0)
+			provider.IsHealthy(ctx)
+			provider.GetSentSMS()
+		}(i)
+	}
+	wg.Wait()
+
+	provider.SetHealthy(true)
+	assert.NoError(t, provider.IsHealthy(ctx))
+}
+
+func BenchmarkMockSMSProvider_RenderTemplate(b *testing.B) {
+	provider := createTestSMSProvider()
+	data := map[string]string{"service_name": "TestApp", "code": "123456", "expiry_minutes": "10"}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := provider.RenderTemplate("verification", data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 // Helper functions
 
 func createTestSMSProvider() *MockSMSProvider {
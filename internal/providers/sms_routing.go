@@ -0,0 +1,409 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// anyCountry keys the route of countries without a route of their own
+const anyCountry = "*"
+
+// SMSRouteStats holds the metrics of one provider on one country's route
+type SMSRouteStats struct {
+	Country        string        `json:"country"`
+	Provider       string        `json:"provider"`
+	Attempts       int64         `json:"attempts"`
+	Sent           int64         `json:"sent"`
+	Failed         int64         `json:"failed"`
+	Fallbacks      int64         `json:"fallbacks"` // failed sends handed to the next provider
+	Cost           float64       `json:"cost"`      // of sent messages, in the providers' currency
+	AverageLatency time.Duration `json:"average_latency"`
+	MaxLatency     time.Duration `json:"max_latency"`
+}
+
+// SendMonitor records the outcome of each provider's sends and reports
+// the providers breaching their service level objectives; see sla.Monitor
+type SendMonitor interface {
+	RecordSend(channel models.NotificationType, provider string, err error, latency time.Duration)
+	Degraded(channel models.NotificationType, provider string) bool
+}
+
+// FaultInjector injects faults into provider calls in resilience tests. A
+// non-nil error fails the call without making it; see chaos.Injector.
+type FaultInjector interface {
+	Inject(ctx context.Context, channel models.NotificationType, provider string) error
+}
+
+// routeKey identifies a provider on a country's route
+type routeKey struct {
+	country  string
+	provider string
+}
+
+// routeStats accumulates the metrics of a route key
+type routeStats struct {
+	attempts     int64
+	sent         int64
+	failed       int64
+	fallbacks    int64
+	cost         float64
+	totalLatency time.Duration
+	maxLatency   time.Duration
+}
+
+// CountryRoutedSMSProvider sends each SMS through the providers routed for
+// its destination country, for example a local DLT-registered provider for
+// India and Twilio for the United States. When a provider fails a send for
+// reasons other than the message itself, the next provider on the route
+// takes it. With a monitor set, degraded providers are tried after the
+// healthy ones on their route. It is safe for concurrent use.
+type CountryRoutedSMSProvider struct {
+	interfaces.SMSProvider // the configured provider, used by countries without a route
+
+	providers   map[string]interfaces.SMSProvider
+	routes      map[string][]string
+	defaultName string
+
+	mu      sync.Mutex
+	stats   map[routeKey]*routeStats
+	monitor SendMonitor
+	faults  FaultInjector
+	now     func() time.Time
+}
+
+// NewCountryRoutedSMSProvider wraps the configured provider with the
+// configuration's country routes, creating each provider they name
+func NewCountryRoutedSMSProvider(provider interfaces.SMSProvider, cfg config.SMSProviderConfig) (*CountryRoutedSMSProvider, error) {
+	p := &CountryRoutedSMSProvider{
+		SMSProvider: provider,
+		providers:   map[string]interfaces.SMSProvider{cfg.Provider: provider},
+		routes:      make(map[string][]string, len(cfg.CountryRoutes)),
+		defaultName: cfg.Provider,
+		stats:       make(map[routeKey]*routeStats),
+		now:         time.Now,
+	}
+
+	for country, names := range cfg.CountryRoutes {
+		country = strings.ToUpper(strings.TrimSpace(country))
+		var route []string
+		for _, name := range names {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if _, exists := p.providers[name]; !exists {
+				routed, err := newRouteProvider(name, cfg)
+				if err != nil {
+					return nil, err
+				}
+				p.providers[name] = routed
+			}
+			route = append(route, name)
+		}
+		if len(route) == 0 {
+			return nil, errors.NewValidationError("country_routes", fmt.Sprintf("route of %s names no providers", country))
+		}
+		p.routes[country] = route
+	}
+
+	return p, nil
+}
+
+// newRouteProvider creates a provider named by a route from its own
+// configuration, or from the routed configuration under its name
+func newRouteProvider(name string, cfg config.SMSProviderConfig) (interfaces.SMSProvider, error) {
+	routeCfg, exists := cfg.RouteProviders[name]
+	if !exists {
+		routeCfg = cfg
+		routeCfg.Provider = name
+	}
+	if routeCfg.Provider == "" {
+		routeCfg.Provider = name
+	}
+	routeCfg.CountryRoutes, routeCfg.RouteProviders = nil, nil
+
+	return smsProviders.create(routeCfg.Provider, routeCfg)
+}
+
+// Send implements the NotificationProvider interface
+func (p *CountryRoutedSMSProvider) Send(ctx context.Context, notification *models.Notification) (*models.NotificationResponse, error) {
+	return p.route(ctx, utils.CountryFromPhoneNumber(notification.Recipient), func(provider interfaces.SMSProvider) (*models.NotificationResponse, error) {
+		return provider.Send(ctx, notification)
+	})
+}
+
+// SendSMS implements the SMSProvider interface
+func (p *CountryRoutedSMSProvider) SendSMS(ctx context.Context, sms *models.SMSNotification) (*models.NotificationResponse, error) {
+	return p.route(ctx, utils.ResolveCountry(sms.PhoneNumber, sms.CountryCode), func(provider interfaces.SMSProvider) (*models.NotificationResponse, error) {
+		return provider.SendSMS(ctx, sms)
+	})
+}
+
+// ValidatePhoneNumber implements the SMSProvider interface with the first
+// provider routed for the country
+func (p *CountryRoutedSMSProvider) ValidatePhoneNumber(phoneNumber, countryCode string) error {
+	country := utils.ResolveCountry(phoneNumber, countryCode)
+	return p.providers[p.Route(country)[0]].ValidatePhoneNumber(phoneNumber, countryCode)
+}
+
+// GetSMSCost implements the SMSProvider interface with the first provider
+// routed for the country
+func (p *CountryRoutedSMSProvider) GetSMSCost(countryCode string) (float64, error) {
+	return p.providers[p.Route(strings.ToUpper(countryCode))[0]].GetSMSCost(countryCode)
+}
+
+// IsHealthy implements the NotificationProvider interface. The provider is
+// healthy while every route has a healthy provider that is not degraded.
+func (p *CountryRoutedSMSProvider) IsHealthy(ctx context.Context) error {
+	p.mu.Lock()
+	monitor := p.monitor
+	p.mu.Unlock()
+
+	healthy := make(map[string]error, len(p.providers))
+	for name, provider := range p.providers {
+		healthy[name] = provider.IsHealthy(ctx)
+		if healthy[name] == nil && monitor != nil && monitor.Degraded(models.NotificationTypeSMS, name) {
+			healthy[name] = errors.NewNotificationError(errors.ErrorCodeProviderUnavailable, "degraded")
+		}
+	}
+
+	for _, country := range p.countries() {
+		var failures []string
+		for _, name := range p.Route(country) {
+			if healthy[name] == nil {
+				failures = nil
+				break
+			}
+			failures = append(failures, fmt.Sprintf("%s: %v", name, healthy[name]))
+		}
+		if len(failures) > 0 {
+			return errors.NewNotificationError(errors.ErrorCodeProviderUnavailable,
+				fmt.Sprintf("no healthy SMS provider for %s (%s)", country, strings.Join(failures, "; ")))
+		}
+	}
+	return nil
+}
+
+// Route returns the names of the providers a country's SMS is sent
+// through, in the order they are tried
+func (p *CountryRoutedSMSProvider) Route(country string) []string {
+	if route, exists := p.routes[country]; exists {
+		return route
+	}
+	if route, exists := p.routes[anyCountry]; exists {
+		return route
+	}
+	return []string{p.defaultName}
+}
+
+// Provider returns a routed provider by name
+func (p *CountryRoutedSMSProvider) Provider(name string) (interfaces.SMSProvider, bool) {
+	provider, exists := p.providers[name]
+	return provider, exists
+}
+
+// SetMonitor sets the monitor that records every send attempt and orders
+// routes by provider degradation
+func (p *CountryRoutedSMSProvider) SetMonitor(monitor SendMonitor) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.monitor = monitor
+}
+
+// SetFaultInjector sets the injector whose faults apply to each routed
+// provider's calls, so tests can exercise fallback
+func (p *CountryRoutedSMSProvider) SetFaultInjector(injector FaultInjector) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.faults = injector
+}
+
+// ValidateProviderOptions implements the OptionsProvider interface for the
+// configured provider. Routes to other providers pass the options on as is.
+func (p *CountryRoutedSMSProvider) ValidateProviderOptions(options map[string]any) error {
+	return ValidateProviderOptions(p.SMSProvider, options)
+}
+
+// Unwrap returns the configured provider
+func (p *CountryRoutedSMSProvider) Unwrap() interfaces.SMSProvider {
+	return p.SMSProvider
+}
+
+// RouteStats returns the metrics of every provider that took part in a
+// route, sorted by country and provider
+func (p *CountryRoutedSMSProvider) RouteStats() []SMSRouteStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := make([]SMSRouteStats, 0, len(p.stats))
+	for key, s := range p.stats {
+		entry := SMSRouteStats{
+			Country:    key.country,
+			Provider:   key.provider,
+			Attempts:   s.attempts,
+			Sent:       s.sent,
+			Failed:     s.failed,
+			Fallbacks:  s.fallbacks,
+			Cost:       s.cost,
+			MaxLatency: s.maxLatency,
+		}
+		if s.attempts > 0 {
+			entry.AverageLatency = s.totalLatency / time.Duration(s.attempts)
+		}
+		stats = append(stats, entry)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Country != stats[j].Country {
+			return stats[i].Country < stats[j].Country
+		}
+		return stats[i].Provider < stats[j].Provider
+	})
+	return stats
+}
+
+// route sends through a country's providers in order until one succeeds or
+// fails because of the message itself
+func (p *CountryRoutedSMSProvider) route(ctx context.Context, country string, send func(interfaces.SMSProvider) (*models.NotificationResponse, error)) (*models.NotificationResponse, error) {
+	p.mu.Lock()
+	monitor, faults := p.monitor, p.faults
+	p.mu.Unlock()
+
+	route := p.Route(country)
+	if monitor != nil {
+		route = healthyFirst(route, monitor)
+	}
+	statsCountry := country
+	if statsCountry == "" {
+		statsCountry = anyCountry
+	}
+
+	var failedOver []string
+	for i, name := range route {
+		provider := p.providers[name]
+		start := p.now()
+		var response *models.NotificationResponse
+		var err error
+		if faults != nil {
+			err = faults.Inject(ctx, models.NotificationTypeSMS, name)
+		}
+		if err == nil {
+			response, err = send(provider)
+		}
+		latency := p.now().Sub(start)
+
+		last := i == len(route)-1
+		fallBack := err != nil && !last && ctx.Err() == nil && IsProviderFailure(err)
+		p.record(routeKey{statsCountry, name}, latency, response, err, fallBack)
+		if monitor != nil {
+			monitor.RecordSend(models.NotificationTypeSMS, name, err, latency)
+		}
+
+		if err == nil {
+			if response != nil {
+				metadata := make(map[string]string, len(response.ProviderMetadata)+3)
+				for key, value := range response.ProviderMetadata {
+					metadata[key] = value
+				}
+				metadata["route_provider"] = name
+				metadata["route_country"] = statsCountry
+				if len(failedOver) > 0 {
+					metadata["failed_over_from"] = strings.Join(failedOver, ",")
+				}
+				routed := *response
+				routed.ProviderMetadata = metadata
+				response = &routed
+			}
+			return response, nil
+		}
+		if !fallBack {
+			return nil, err
+		}
+		failedOver = append(failedOver, name)
+	}
+	return nil, errors.NewNotificationError(errors.ErrorCodeProviderUnavailable, fmt.Sprintf("no SMS provider routed for %s", country))
+}
+
+// record adds a send attempt to a route key's metrics
+func (p *CountryRoutedSMSProvider) record(key routeKey, latency time.Duration, response *models.NotificationResponse, err error, fallBack bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s, exists := p.stats[key]
+	if !exists {
+		s = &routeStats{}
+		p.stats[key] = s
+	}
+	s.attempts++
+	s.totalLatency += latency
+	if latency > s.maxLatency {
+		s.maxLatency = latency
+	}
+	switch {
+	case err == nil:
+		s.sent++
+		if response != nil {
+			s.cost += response.Cost
+		}
+	case fallBack:
+		s.failed++
+		s.fallbacks++
+	default:
+		s.failed++
+	}
+}
+
+// healthyFirst returns a route with its degraded providers moved to the end
+func healthyFirst(route []string, monitor SendMonitor) []string {
+	ordered := make([]string, 0, len(route))
+	var degraded []string
+	for _, name := range route {
+		if monitor.Degraded(models.NotificationTypeSMS, name) {
+			degraded = append(degraded, name)
+			continue
+		}
+		ordered = append(ordered, name)
+	}
+	return append(ordered, degraded...)
+}
+
+// countries returns the routed countries
+func (p *CountryRoutedSMSProvider) countries() []string {
+	countries := make([]string, 0, len(p.routes)+1)
+	for country := range p.routes {
+		countries = append(countries, country)
+	}
+	if _, exists := p.routes[anyCountry]; !exists {
+		countries = append(countries, anyCountry)
+	}
+	sort.Strings(countries)
+	return countries
+}
+
+// IsProviderFailure reports whether a send failed because of the provider,
+// so another provider may succeed, rather than because of the message or
+// its recipient
+func IsProviderFailure(err error) bool {
+	notifErr, ok := errors.AsNotificationError(err)
+	if !ok {
+		return true
+	}
+
+	switch notifErr.Code {
+	case errors.ErrorCodeInvalidRecipient, errors.ErrorCodeInvalidPhone, errors.ErrorCodeInvalidNotification,
+		errors.ErrorCodeValidationFailed, errors.ErrorCodeInvalidRequest, errors.ErrorCodeRecipientOptedOut:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,202 @@
+package providers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+func init() {
+	RegisterSMSProvider("test-dlt", func(cfg config.SMSProviderConfig) (interfaces.SMSProvider, error) {
+		return NewMockSMSProvider(cfg), nil
+	})
+}
+
+func TestNewSMSProvider_CountryRoutes(t *testing.T) {
+	provider, err := NewSMSProvider(config.SMSProviderConfig{Provider: "mock"})
+	require.NoError(t, err)
+	assert.IsType(t, &MockSMSProvider{}, provider)
+
+	provider, err = NewSMSProvider(config.SMSProviderConfig{Provider: "mock", CountryRoutes: map[string][]string{"in": {"test-dlt", "mock"}}})
+	require.NoError(t, err)
+	routed, ok := provider.(*CountryRoutedSMSProvider)
+	require.True(t, ok)
+	assert.Equal(t, []string{"test-dlt", "mock"}, routed.Route("IN"))
+	assert.Equal(t, []string{"mock"}, routed.Route("US"))
+	assert.IsType(t, &MockSMSProvider{}, routed.Unwrap())
+
+	_, err = NewSMSProvider(config.SMSProviderConfig{Provider: "mock", CountryRoutes: map[string][]string{"IN": {"missing"}}})
+	assert.Error(t, err)
+
+	_, err = NewSMSProvider(config.SMSProviderConfig{Provider: "mock", CountryRoutes: map[string][]string{"IN": {" "}}})
+	assert.Error(t, err)
+}
+
+func TestCountryRoutedSMSProvider_Routes(t *testing.T) {
+	routed, dlt, fallback := createTestRoutedSMSProvider(t)
+
+	response, err := routed.SendSMS(context.Background(), createTestRoutedSMS("+919876543210", ""))
+	require.NoError(t, err)
+	assert.Equal(t, "test-dlt", response.ProviderMetadata["route_provider"])
+	assert.Equal(t, "IN", response.ProviderMetadata["route_country"])
+	assert.Len(t, dlt.GetSentSMS(), 1)
+
+	// The country code overrides the number, and other countries use "*"
+	_, err = routed.SendSMS(context.Background(), createTestRoutedSMS("9876543210", "IN"))
+	require.NoError(t, err)
+	assert.Len(t, dlt.GetSentSMS(), 2)
+
+	response, err = routed.SendSMS(context.Background(), createTestRoutedSMS("+14155550100", ""))
+	require.NoError(t, err)
+	assert.Equal(t, "mock", response.ProviderMetadata["route_provider"])
+	assert.Len(t, fallback.GetSentSMS(), 1)
+}
+
+func TestCountryRoutedSMSProvider_Fallback(t *testing.T) {
+	routed, dlt, fallback := createTestRoutedSMSProvider(t)
+	dlt.SetSimulation(Simulation{Rules: []FailureRule{{Code: errors.ErrorCodeProviderUnavailable, Times: 1}}})
+
+	response, err := routed.SendSMS(context.Background(), createTestRoutedSMS("+919876543210", ""))
+	require.NoError(t, err)
+	assert.Equal(t, "mock", response.ProviderMetadata["route_provider"])
+	assert.Equal(t, "test-dlt", response.ProviderMetadata["failed_over_from"])
+	assert.Len(t, fallback.GetSentSMS(), 1)
+
+	stats := routed.RouteStats()
+	require.Len(t, stats, 2)
+	assert.Equal(t, SMSRouteStats{Country: "IN", Provider: "mock", Attempts: 1, Sent: 1, Cost: stats[0].Cost,
+		AverageLatency: stats[0].AverageLatency, MaxLatency: stats[0].MaxLatency}, stats[0])
+	assert.Greater(t, stats[0].Cost, 0.0)
+	assert.Equal(t, "test-dlt", stats[1].Provider)
+	assert.Equal(t, int64(1), stats[1].Failed)
+	assert.Equal(t, int64(1), stats[1].Fallbacks)
+
+	// Messages at fault are not handed on
+	dlt.SetSimulation(Simulation{Rules: []FailureRule{{Code: errors.ErrorCodeInvalidPhone}}})
+	_, err = routed.SendSMS(context.Background(), createTestRoutedSMS("+919876543210", ""))
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeInvalidPhone, notifErr.Code)
+	assert.Len(t, fallback.GetSentSMS(), 1)
+
+	// The last provider's error is returned
+	dlt.SetSimulation(Simulation{Rules: []FailureRule{{Code: errors.ErrorCodeProviderUnavailable}}})
+	fallback.SetSimulation(Simulation{Rules: []FailureRule{{Code: errors.ErrorCodeRateLimited}}})
+	_, err = routed.SendSMS(context.Background(), createTestRoutedSMS("+919876543210", ""))
+	notifErr, ok = errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeRateLimited, notifErr.Code)
+}
+
+func TestCountryRoutedSMSProvider_IsHealthy(t *testing.T) {
+	routed, dlt, fallback := createTestRoutedSMSProvider(t)
+	require.NoError(t, routed.IsHealthy(context.Background()))
+
+	dlt.SetHealthy(false)
+	require.NoError(t, routed.IsHealthy(context.Background()))
+
+	fallback.SetHealthy(false)
+	err := routed.IsHealthy(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no healthy SMS provider")
+}
+
+func TestCountryRoutedSMSProvider_SetMonitor(t *testing.T) {
+	routed, dlt, fallback := createTestRoutedSMSProvider(t)
+	monitor := &testSendMonitor{degraded: map[string]bool{"test-dlt": true}}
+	routed.SetMonitor(monitor)
+
+	// A degraded provider is tried after the healthy ones
+	response, err := routed.SendSMS(context.Background(), createTestRoutedSMS("+919876543210", ""))
+	require.NoError(t, err)
+	assert.Equal(t, "mock", response.ProviderMetadata["route_provider"])
+	assert.Empty(t, dlt.GetSentSMS())
+	assert.Len(t, fallback.GetSentSMS(), 1)
+	assert.Equal(t, []string{"mock"}, monitor.sends)
+
+	fallback.SetSimulation(Simulation{Rules: []FailureRule{{Code: errors.ErrorCodeProviderUnavailable, Times: 1}}})
+	_, err = routed.SendSMS(context.Background(), createTestRoutedSMS("+919876543210", ""))
+	require.NoError(t, err)
+	assert.Len(t, dlt.GetSentSMS(), 1)
+	assert.Equal(t, []string{"mock", "mock", "test-dlt"}, monitor.sends)
+
+	// Every route must have a provider that is not degraded
+	monitor.degraded["mock"] = true
+	err = routed.IsHealthy(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "degraded")
+}
+
+func TestCountryRoutedSMSProvider_SetFaultInjector(t *testing.T) {
+	routed, dlt, fallback := createTestRoutedSMSProvider(t)
+	routed.SetFaultInjector(testFaultInjector{"test-dlt": errors.ErrorCodeTimeout})
+
+	response, err := routed.SendSMS(context.Background(), createTestRoutedSMS("+919876543210", ""))
+	require.NoError(t, err)
+	assert.Equal(t, "test-dlt", response.ProviderMetadata["failed_over_from"])
+	assert.Empty(t, dlt.GetSentSMS())
+	assert.Len(t, fallback.GetSentSMS(), 1)
+}
+
+// Helper functions
+
+// testFaultInjector fails the calls of providers with an error code
+type testFaultInjector map[string]errors.ErrorCode
+
+func (i testFaultInjector) Inject(_ context.Context, _ models.NotificationType, provider string) error {
+	if code, exists := i[provider]; exists {
+		return errors.NewNotificationError(code, "injected")
+	}
+	return nil
+}
+
+// testSendMonitor records the providers sent through and reports fixed degradations
+type testSendMonitor struct {
+	degraded map[string]bool
+	sends    []string
+}
+
+func (m *testSendMonitor) RecordSend(_ models.NotificationType, provider string, _ error, _ time.Duration) {
+	m.sends = append(m.sends, provider)
+}
+
+func (m *testSendMonitor) Degraded(_ models.NotificationType, provider string) bool {
+	return m.degraded[provider]
+}
+
+// createTestRoutedSMSProvider routes India through test-dlt then mock, and
+// other countries through mock, without simulated latency
+func createTestRoutedSMSProvider(t *testing.T) (*CountryRoutedSMSProvider, *MockSMSProvider, *MockSMSProvider) {
+	provider, err := NewSMSProvider(config.SMSProviderConfig{
+		Provider:      "mock",
+		CountryRoutes: map[string][]string{"IN": {"test-dlt", "mock"}, "*": {"mock"}},
+	})
+	require.NoError(t, err)
+	routed := provider.(*CountryRoutedSMSProvider)
+
+	dlt, exists := routed.Provider("test-dlt")
+	require.True(t, exists)
+	fallback, exists := routed.Provider("mock")
+	require.True(t, exists)
+	dlt.(*MockSMSProvider).SetSimulation(Simulation{})
+	fallback.(*MockSMSProvider).SetSimulation(Simulation{})
+	return routed, dlt.(*MockSMSProvider), fallback.(*MockSMSProvider)
+}
+
+func createTestRoutedSMS(phoneNumber, countryCode string) *models.SMSNotification {
+	return &models.SMSNotification{
+		Notification: models.Notification{ID: uuid.New(), Type: models.NotificationTypeSMS, Recipient: phoneNumber, Body: "Your code is 123456"},
+		PhoneNumber:  phoneNumber,
+		CountryCode:  countryCode,
+		Message:      "Your code is 123456",
+	}
+}
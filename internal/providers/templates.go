@@ -0,0 +1,48 @@
+package providers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// sortedTemplates returns a provider's templates sorted by ID
+func sortedTemplates[T any](templates map[string]*T) []*T {
+	ids := make([]string, 0, len(templates))
+	for id := range templates {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	sorted := make([]*T, len(ids))
+	for i, id := range ids {
+		sorted[i] = templates[id]
+	}
+	return sorted
+}
+
+// keyTemplates prepares templates for storing and returns them keyed by ID.
+// It fails on the first invalid template or repeated ID.
+func keyTemplates[T any](templates []*T, id func(*T) string, prepare func(*T, time.Time) error) (map[string]*T, error) {
+	now := time.Now()
+	keyed := make(map[string]*T, len(templates))
+	for _, template := range templates {
+		if err := prepare(template, now); err != nil {
+			return nil, err
+		}
+		if _, exists := keyed[id(template)]; exists {
+			return nil, errors.NewValidationError("id", fmt.Sprintf("template %s is defined more than once", id(template)))
+		}
+		keyed[id(template)] = template
+	}
+	return keyed, nil
+}
+
+// templateCacheKey returns the key a template field is compiled under. The
+// update time versions the template, since replacing a template sets it.
+func templateCacheKey(id string, updatedAt time.Time, field string) string {
+	return id + "@" + strconv.FormatInt(updatedAt.UnixNano(), 10) + "/" + field
+}
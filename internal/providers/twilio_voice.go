@@ -0,0 +1,653 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/httpclient"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/requestlog"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// Voice call limits
+const (
+	maxVoiceMessageLength = 4096 // Twilio <Say> limit in characters
+	maxVoiceLoop          = 5
+	ttsWordsPerSecond     = 2.5 // Typical text-to-speech speaking rate
+	callSetupSeconds      = 3   // Time to answer and start speaking
+)
+
+// TwilioVoiceProvider implements the VoiceProvider interface using the Twilio Programmable Voice API
+type TwilioVoiceProvider struct {
+	config    config.VoiceProviderConfig
+	client    *http.Client
+	baseURL   string
+	mu        sync.RWMutex
+	templates map[string]*VoiceTemplate
+	countries map[string]VoiceCountry
+	allowed   map[string]bool
+}
+
+// VoiceTemplate represents a voice message template
+type VoiceTemplate struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	Message   string            `json:"message"`
+	Variables []string          `json:"variables"`
+	Category  string            `json:"category"`
+	Voice     string            `json:"voice,omitempty"`
+	Language  string            `json:"language,omitempty"`
+	Loop      int               `json:"loop,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+// VoiceCountry represents call pricing and dialing information for a country
+type VoiceCountry struct {
+	Code          string  `json:"code"`
+	Name          string  `json:"name"`
+	DialCode      string  `json:"dial_code"`
+	CostPerMinute float64 `json:"cost_per_minute"`
+}
+
+// twilioCallResponse represents the fields used from a Twilio call resource
+type twilioCallResponse struct {
+	SID     string `json:"sid"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+	Code    int    `json:"code,omitempty"`
+}
+
+// NewTwilioVoiceProvider creates a new Twilio voice provider
+func NewTwilioVoiceProvider(cfg config.VoiceProviderConfig) *TwilioVoiceProvider {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 15 * time.Second
+	}
+
+	baseURL := cfg.TwilioBaseURL
+	if baseURL == "" {
+		baseURL = "https://api.twilio.com"
+	}
+
+	provider := &TwilioVoiceProvider{
+		config:    cfg,
+		client:    httpclient.Shared.Client(cfg.HTTP, timeout),
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		templates: make(map[string]*VoiceTemplate),
+		allowed:   make(map[string]bool),
+	}
+
+	for _, code := range cfg.AllowedCountries {
+		provider.allowed[strings.ToUpper(code)] = true
+	}
+
+	provider.loadDefaultTemplates()
+	provider.loadDefaultCountries()
+
+	return provider
+}
+
+// Send implements the NotificationProvider interface
+func (p *TwilioVoiceProvider) Send(ctx context.Context, notification *models.Notification) (*models.NotificationResponse, error) {
+	voiceNotification, err := p.convertToVoiceNotification(notification)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.SendVoice(ctx, voiceNotification)
+}
+
+// SendVoice implements the VoiceProvider interface
+func (p *TwilioVoiceProvider) SendVoice(ctx context.Context, voice *models.VoiceNotification) (*models.NotificationResponse, error) {
+	if err := p.validateVoiceNotification(voice); err != nil {
+		return nil, err
+	}
+
+	twiml, err := p.BuildTwiML(voice)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("To", p.formatE164(voice.PhoneNumber, voice.CountryCode))
+	form.Set("From", p.config.TwilioFromNumber)
+	form.Set("Twiml", twiml)
+	for name, value := range voice.ProviderOptions {
+		if param, ok := twilioVoiceParams[name]; ok {
+			form.Set(param, fmt.Sprint(value))
+		}
+	}
+
+	accountSID, authToken := p.credentials()
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Calls.json", p.baseURL, accountSID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, errors.NewInternalError("failed to create call request", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(accountSID, authToken)
+	if voice.CorrelationID != "" {
+		req.Header.Set(requestlog.HeaderCorrelationID, voice.CorrelationID)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, errors.NewNotificationError(errors.ErrorCodeTimeout, "voice call request timed out")
+		}
+		return nil, errors.NewProviderError("twilio-voice", errors.ErrorCodeProviderUnavailable, "call request failed").WithCause(err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	var call twilioCallResponse
+	_ = json.Unmarshal(body, &call)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, p.mapErrorResponse(resp, call)
+	}
+
+	now := time.Now()
+	response := &models.NotificationResponse{
+		ID:         voice.ID,
+		Status:     models.StatusSent,
+		Message:    fmt.Sprintf("Voice call %s to %s", call.Status, voice.CountryCode),
+		ProviderID: call.SID,
+		SentAt:     &now,
+
+		ProviderMetadata: map[string]string{
+			"provider":    "twilio-voice",
+			"call_status": call.Status,
+		},
+	}
+
+	return response, nil
+}
+
+// ValidatePhoneNumber implements the VoiceProvider interface
+func (p *TwilioVoiceProvider) ValidatePhoneNumber(phoneNumber, countryCode string) error {
+	if phoneNumber == "" {
+		return errors.NewValidationError("phone_number", "phone number is required")
+	}
+
+	phoneRegex := regexp.MustCompile(`^\d{7,15}$`)
+	if !phoneRegex.MatchString(p.cleanPhoneNumber(phoneNumber)) {
+		return errors.NewValidationError("phone_number", "phone number must contain 7-15 digits")
+	}
+
+	if countryCode == "" {
+		return errors.NewValidationError("country_code", "country code is required for voice calls")
+	}
+
+	countryCode = strings.ToUpper(countryCode)
+	if _, exists := p.countries[countryCode]; !exists {
+		return errors.NewValidationError("country_code", fmt.Sprintf("voice calls are not supported to country: %s", countryCode))
+	}
+
+	if !p.IsCountryAllowed(countryCode) {
+		return errors.NewNotificationError(
+			errors.ErrorCodeInvalidRecipient,
+			fmt.Sprintf("voice calls to %s are not allowed", countryCode),
+		).WithMetadata("country_code", countryCode)
+	}
+
+	return nil
+}
+
+// GetVoiceCost implements the VoiceProvider interface
+func (p *TwilioVoiceProvider) GetVoiceCost(countryCode string) (float64, error) {
+	country, exists := p.countries[strings.ToUpper(countryCode)]
+	if !exists {
+		return 0.0, errors.NewNotificationError(errors.ErrorCodeNotFound, fmt.Sprintf("country code not supported: %s", countryCode))
+	}
+	return country.CostPerMinute, nil
+}
+
+// IsCountryAllowed checks the country against the configured allowlist.
+// An empty allowlist allows every supported country.
+func (p *TwilioVoiceProvider) IsCountryAllowed(countryCode string) bool {
+	if len(p.allowed) == 0 {
+		return true
+	}
+	return p.allowed[strings.ToUpper(countryCode)]
+}
+
+// EstimateDuration estimates how long reading a message takes, including call setup
+func (p *TwilioVoiceProvider) EstimateDuration(message string, loop int) time.Duration {
+	if loop < 1 {
+		loop = 1
+	}
+
+	words := len(strings.Fields(message))
+	seconds := math.Ceil(float64(words)/ttsWordsPerSecond)*float64(loop) + callSetupSeconds
+
+	return time.Duration(seconds) * time.Second
+}
+
+// GetType implements the NotificationProvider interface
+func (p *TwilioVoiceProvider) GetType() models.NotificationType {
+	return models.NotificationTypeVoice
+}
+
+// IsHealthy implements the NotificationProvider interface
+func (p *TwilioVoiceProvider) IsHealthy(ctx context.Context) error {
+	accountSID, authToken := p.credentials()
+	if accountSID == "" || authToken == "" || p.config.TwilioFromNumber == "" {
+		return errors.NewProviderError("twilio-voice", errors.ErrorCodeProviderConfiguration, "Twilio credentials and from number are required")
+	}
+	return nil
+}
+
+// CheckCredentials implements the CredentialChecker interface by reading the
+// account balance, which any valid credentials may do
+func (p *TwilioVoiceProvider) CheckCredentials(ctx context.Context) (*interfaces.CredentialStatus, error) {
+	accountSID, authToken := p.credentials()
+	return p.checkBalance(ctx, accountSID, authToken)
+}
+
+// RotateCredentials implements the CredentialRotator interface. It takes an
+// "auth_token" and optionally an "account_sid", and switches to them once a
+// balance check accepts them.
+func (p *TwilioVoiceProvider) RotateCredentials(ctx context.Context, credentials map[string]string) error {
+	accountSID, _ := p.credentials()
+	if sid := credentials["account_sid"]; sid != "" {
+		accountSID = sid
+	}
+	authToken := credentials["auth_token"]
+	if authToken == "" {
+		return errors.NewValidationError("auth_token", "auth token is required")
+	}
+
+	status, err := p.checkBalance(ctx, accountSID, authToken)
+	if err != nil {
+		return err
+	}
+	if !status.Valid {
+		return errors.NewProviderError("twilio-voice", errors.ErrorCodeProviderAuthentication, "new credentials were rejected: "+status.Detail)
+	}
+
+	p.mu.Lock()
+	p.config.TwilioAccountSID = accountSID
+	p.config.TwilioAuthToken = authToken
+	p.mu.Unlock()
+	return nil
+}
+
+// GetConfig implements the NotificationProvider interface
+func (p *TwilioVoiceProvider) GetConfig() interfaces.ProviderConfig {
+	return interfaces.ProviderConfig{
+		Name:       "Twilio Voice Provider",
+		Type:       models.NotificationTypeVoice,
+		Enabled:    p.config.Enabled,
+		Priority:   5,
+		MaxRetries: 2,
+		Timeout:    int(p.client.Timeout.Seconds()),
+		RateLimit: interfaces.RateLimitConfig{
+			Enabled:        true,
+			RequestsPerMin: 60,
+			BurstSize:      1,
+		},
+		Settings: map[string]string{
+			"provider_type":     "twilio",
+			"version":           "2010-04-01",
+			"features":          "templates,tts,cost_estimation,country_allowlist",
+			"allowed_countries": strings.Join(p.config.AllowedCountries, ","),
+		},
+	}
+}
+
+// GetSupportedCountries returns the countries calls can be placed to
+func (p *TwilioVoiceProvider) GetSupportedCountries() []VoiceCountry {
+	countries := make([]VoiceCountry, 0, len(p.countries))
+	for _, country := range p.countries {
+		countries = append(countries, country)
+	}
+	return countries
+}
+
+// GetTemplate retrieves a voice template by ID
+func (p *TwilioVoiceProvider) GetTemplate(templateID string) (*VoiceTemplate, error) {
+	p.mu.RLock()
+	template, exists := p.templates[templateID]
+	p.mu.RUnlock()
+
+	if !exists {
+		return nil, errors.NewNotificationError(errors.ErrorCodeTemplateNotFound, fmt.Sprintf("template not found: %s", templateID))
+	}
+	return template, nil
+}
+
+// Templates returns the voice templates, sorted by ID
+func (p *TwilioVoiceProvider) Templates() []*VoiceTemplate {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return sortedTemplates(p.templates)
+}
+
+// AddTemplate adds a new voice template
+func (p *TwilioVoiceProvider) AddTemplate(template *VoiceTemplate) error {
+	if err := template.prepare(time.Now()); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.templates[template.ID] = template
+	p.mu.Unlock()
+	return nil
+}
+
+// ReplaceTemplates replaces every voice template at once
+func (p *TwilioVoiceProvider) ReplaceTemplates(templates []*VoiceTemplate) error {
+	replaced, err := keyTemplates(templates, func(t *VoiceTemplate) string { return t.ID }, (*VoiceTemplate).prepare)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.templates = replaced
+	p.mu.Unlock()
+	return nil
+}
+
+// prepare sets a template's ID and timestamps before it is stored
+func (t *VoiceTemplate) prepare(now time.Time) error {
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+
+	t.CreatedAt = now
+	t.UpdatedAt = now
+	return nil
+}
+
+// RenderTemplate renders a voice template with provided data
+func (p *TwilioVoiceProvider) RenderTemplate(templateID string, data map[string]string) (*VoiceTemplate, error) {
+	template, err := p.GetTemplate(templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := &VoiceTemplate{
+		ID:        template.ID,
+		Name:      template.Name,
+		Message:   p.replaceVariables(template.Message, data),
+		Variables: template.Variables,
+		Category:  template.Category,
+		Voice:     template.Voice,
+		Language:  template.Language,
+		Loop:      template.Loop,
+		CreatedAt: template.CreatedAt,
+		UpdatedAt: template.UpdatedAt,
+		Metadata:  template.Metadata,
+	}
+
+	return rendered, nil
+}
+
+// BuildTwiML builds the TwiML document that reads the message on the call
+func (p *TwilioVoiceProvider) BuildTwiML(voice *models.VoiceNotification) (string, error) {
+	voiceName := voice.Voice
+	if voiceName == "" {
+		voiceName = p.config.DefaultVoice
+	}
+
+	language := voice.Language
+	if language == "" {
+		language = p.config.DefaultLanguage
+	}
+
+	loop := voice.Loop
+	if loop < 1 {
+		loop = 1
+	}
+
+	var message strings.Builder
+	if err := xml.EscapeText(&message, []byte(voice.Message)); err != nil {
+		return "", errors.NewInternalError("failed to encode voice message", err)
+	}
+
+	var twiml strings.Builder
+	twiml.WriteString("<Response><Say")
+	if voiceName != "" {
+		fmt.Fprintf(&twiml, ` voice="%s"`, voiceName)
+	}
+	if language != "" {
+		fmt.Fprintf(&twiml, ` language="%s"`, language)
+	}
+	fmt.Fprintf(&twiml, ` loop="%d">%s</Say></Response>`, loop, message.String())
+
+	return twiml.String(), nil
+}
+
+// credentials returns the account SID and auth token in use
+func (p *TwilioVoiceProvider) credentials() (string, string) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.config.TwilioAccountSID, p.config.TwilioAuthToken
+}
+
+// checkBalance validates credentials by reading the account balance
+func (p *TwilioVoiceProvider) checkBalance(ctx context.Context, accountSID, authToken string) (*interfaces.CredentialStatus, error) {
+	if accountSID == "" || authToken == "" {
+		return &interfaces.CredentialStatus{Detail: "account SID and auth token are required"}, nil
+	}
+
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Balance.json", p.baseURL, url.PathEscape(accountSID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to create balance request", err)
+	}
+	req.SetBasicAuth(accountSID, authToken)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, errors.NewProviderError("twilio-voice", errors.ErrorCodeProviderUnavailable, "balance request failed").WithCause(err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return &interfaces.CredentialStatus{Detail: fmt.Sprintf("Twilio rejected the credentials with status %d", resp.StatusCode)}, nil
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return nil, p.mapErrorResponse(resp, twilioCallResponse{})
+	}
+
+	var balance struct {
+		Balance  string `json:"balance"`
+		Currency string `json:"currency"`
+	}
+	_ = json.Unmarshal(body, &balance)
+
+	status := &interfaces.CredentialStatus{Valid: true}
+	if balance.Balance != "" {
+		status.Detail = strings.TrimSpace(fmt.Sprintf("balance %s %s", balance.Balance, balance.Currency))
+	}
+	return status, nil
+}
+
+// convertToVoiceNotification converts a generic notification to a voice notification
+func (p *TwilioVoiceProvider) convertToVoiceNotification(notification *models.Notification) (*models.VoiceNotification, error) {
+	if notification.Type != models.NotificationTypeVoice {
+		return nil, errors.NewValidationError("type", "notification type must be voice")
+	}
+
+	voiceNotification := &models.VoiceNotification{
+		Notification: *notification,
+		PhoneNumber:  notification.Recipient,
+		Message:      notification.Body,
+	}
+
+	// Extract voice-specific fields from metadata if available
+	if notification.Metadata != nil {
+		if countryCode, exists := notification.Metadata["country_code"]; exists {
+			voiceNotification.CountryCode = countryCode
+		}
+		if language, exists := notification.Metadata["language"]; exists {
+			voiceNotification.Language = language
+		}
+	}
+
+	return voiceNotification, nil
+}
+
+// validateVoiceNotification validates a voice notification
+func (p *TwilioVoiceProvider) validateVoiceNotification(voice *models.VoiceNotification) error {
+	if err := p.ValidatePhoneNumber(voice.PhoneNumber, voice.CountryCode); err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(voice.Message) == "" {
+		return errors.NewValidationError("message", "voice message is required")
+	}
+
+	if len(voice.Message) > maxVoiceMessageLength {
+		return errors.NewValidationError("message", fmt.Sprintf("voice message too long (max %d characters)", maxVoiceMessageLength))
+	}
+
+	if voice.Loop < 0 || voice.Loop > maxVoiceLoop {
+		return errors.NewValidationError("loop", fmt.Sprintf("loop must be between 0 and %d", maxVoiceLoop))
+	}
+
+	return p.ValidateProviderOptions(voice.ProviderOptions)
+}
+
+// twilioVoiceOptions are the provider options of the Twilio voice provider,
+// sent as the Calls API parameter of the same meaning
+var twilioVoiceOptions = OptionSpecs{
+	"machine_detection": {Kind: OptionString, Enum: []string{"Enable", "DetectMessageEnd"}, Description: "Detect answering machines (MachineDetection)"},
+	"timeout":           {Kind: OptionInteger, Min: 5, Max: 600, Description: "Seconds to let the phone ring (Timeout)"},
+	"record":            {Kind: OptionBoolean, Description: "Record the call (Record)"},
+}
+
+// twilioVoiceParams maps provider options to Calls API parameters
+var twilioVoiceParams = map[string]string{
+	"machine_detection": "MachineDetection",
+	"timeout":           "Timeout",
+	"record":            "Record",
+}
+
+// ValidateProviderOptions implements the OptionsProvider interface
+func (p *TwilioVoiceProvider) ValidateProviderOptions(options map[string]any) error {
+	return twilioVoiceOptions.Validate("twilio-voice", options)
+}
+
+// mapErrorResponse maps a Twilio error response to a notification error
+func (p *TwilioVoiceProvider) mapErrorResponse(resp *http.Response, call twilioCallResponse) error {
+	message := call.Message
+	if message == "" {
+		message = fmt.Sprintf("Twilio returned status %d", resp.StatusCode)
+	}
+
+	var err *errors.NotificationError
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return errors.NewRateLimitError(resp.Header.Get("Retry-After")).WithMetadata("provider", "twilio-voice")
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		err = errors.NewProviderError("twilio-voice", errors.ErrorCodeProviderAuthentication, message)
+	case resp.StatusCode >= 500:
+		err = errors.NewProviderError("twilio-voice", errors.ErrorCodeProviderUnavailable, message)
+	default:
+		err = errors.NewProviderError("twilio-voice", errors.ErrorCodeDeliveryFailed, message)
+	}
+
+	if call.Code != 0 {
+		err.WithMetadata("twilio_code", fmt.Sprintf("%d", call.Code))
+	}
+
+	return err
+}
+
+// formatE164 formats a phone number in E.164 using the country's dial code
+func (p *TwilioVoiceProvider) formatE164(phoneNumber, countryCode string) string {
+	cleanNumber := p.cleanPhoneNumber(phoneNumber)
+
+	// Numbers entered with a leading + already include their dial code
+	if strings.HasPrefix(strings.TrimSpace(phoneNumber), "+") {
+		return "+" + cleanNumber
+	}
+
+	if country, exists := p.countries[strings.ToUpper(countryCode)]; exists {
+		return "+" + country.DialCode + strings.TrimPrefix(cleanNumber, "0")
+	}
+
+	return "+" + cleanNumber
+}
+
+// cleanPhoneNumber removes formatting characters from a phone number
+func (p *TwilioVoiceProvider) cleanPhoneNumber(phoneNumber string) string {
+	replacer := strings.NewReplacer(" ", "", "-", "", "(", "", ")", "", "+", "", ".", "")
+	return replacer.Replace(phoneNumber)
+}
+
+// replaceVariables replaces template variables with provided data
+func (p *TwilioVoiceProvider) replaceVariables(template string, data map[string]string) string {
+	result := template
+	for key, value := range data {
+		placeholder := fmt.Sprintf("{{%s}}", key)
+		result = strings.ReplaceAll(result, placeholder, value)
+	}
+	return result
+}
+
+// loadDefaultTemplates loads default voice templates
+func (p *TwilioVoiceProvider) loadDefaultTemplates() {
+	// Urgent alert template
+	alertTemplate := &VoiceTemplate{
+		ID:        "urgent_alert",
+		Name:      "Urgent Alert",
+		Message:   "This is an urgent alert from {{service_name}}. {{alert_message}}. Please respond immediately.",
+		Variables: []string{"service_name", "alert_message"},
+		Category:  "alert",
+		Loop:      2,
+	}
+
+	// Verification code template, read slowly digit by digit
+	verificationTemplate := &VoiceTemplate{
+		ID:        "verification_code",
+		Name:      "Verification Code",
+		Message:   "Your verification code is {{spoken_code}}. Again, your code is {{spoken_code}}.",
+		Variables: []string{"spoken_code"},
+		Category:  "security",
+	}
+
+	p.AddTemplate(alertTemplate)
+	p.AddTemplate(verificationTemplate)
+}
+
+// loadDefaultCountries loads per-minute call rates by country
+func (p *TwilioVoiceProvider) loadDefaultCountries() {
+	p.countries = map[string]VoiceCountry{
+		"US": {Code: "US", Name: "United States", DialCode: "1", CostPerMinute: 0.0140},
+		"CA": {Code: "CA", Name: "Canada", DialCode: "1", CostPerMinute: 0.0140},
+		"UK": {Code: "UK", Name: "United Kingdom", DialCode: "44", CostPerMinute: 0.0200},
+		"AU": {Code: "AU", Name: "Australia", DialCode: "61", CostPerMinute: 0.0350},
+		"DE": {Code: "DE", Name: "Germany", DialCode: "49", CostPerMinute: 0.0320},
+		"FR": {Code: "FR", Name: "France", DialCode: "33", CostPerMinute: 0.0300},
+		"IN": {Code: "IN", Name: "India", DialCode: "91", CostPerMinute: 0.0450},
+		"BR": {Code: "BR", Name: "Brazil", DialCode: "55", CostPerMinute: 0.0600},
+		"MX": {Code: "MX", Name: "Mexico", DialCode: "52", CostPerMinute: 0.0400},
+		"JP": {Code: "JP", Name: "Japan", DialCode: "81", CostPerMinute: 0.0900},
+		"SG": {Code: "SG", Name: "Singapore", DialCode: "65", CostPerMinute: 0.0250},
+	}
+}
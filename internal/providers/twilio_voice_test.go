@@ -0,0 +1,309 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestNewTwilioVoiceProvider(t *testing.T) {
+	provider := createTestVoiceProvider("")
+
+	assert.Equal(t, models.NotificationTypeVoice, provider.GetType())
+	assert.Equal(t, "Twilio Voice Provider", provider.GetConfig().Name)
+	assert.Len(t, provider.templates, 2)
+	assert.NotEmpty(t, provider.GetSupportedCountries())
+	assert.NoError(t, provider.IsHealthy(context.Background()))
+}
+
+func TestTwilioVoiceProvider_IsHealthy_MissingCredentials(t *testing.T) {
+	provider := NewTwilioVoiceProvider(config.VoiceProviderConfig{Provider: "twilio"})
+
+	err := provider.IsHealthy(context.Background())
+	require.Error(t, err)
+
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeProviderConfiguration, notifErr.Code)
+}
+
+func TestTwilioVoiceProvider_SendVoice_Success(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2010-04-01/Accounts/AC123/Calls.json", r.URL.Path)
+
+		user, pass, ok := r.BasicAuth()
+		require.True(t, ok)
+		assert.Equal(t, "AC123", user)
+		assert.Equal(t, "secret", pass)
+		assert.Equal(t, "req-42", r.Header.Get("X-Correlation-ID"))
+
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "+15551234567", r.PostForm.Get("To"))
+		assert.Equal(t, "+15550000000", r.PostForm.Get("From"))
+		assert.Equal(t, `<Response><Say voice="alice" language="en-US" loop="2">Disk &lt;90%&gt; full</Say></Response>`, r.PostForm.Get("Twiml"))
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"sid": "CA42", "status": "queued"})
+	}))
+	defer server.Close()
+
+	provider := createTestVoiceProvider(server.URL)
+
+	voice := createTestVoiceNotification()
+	voice.Message = "Disk <90%> full"
+	voice.Loop = 2
+	voice.CorrelationID = "req-42"
+
+	response, err := provider.SendVoice(context.Background(), voice)
+
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusSent, response.Status)
+	assert.Equal(t, "CA42", response.ProviderID)
+}
+
+func TestTwilioVoiceProvider_SendVoice_ProviderOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "DetectMessageEnd", r.PostForm.Get("MachineDetection"))
+		assert.Equal(t, "30", r.PostForm.Get("Timeout"))
+		assert.Equal(t, "true", r.PostForm.Get("Record"))
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"sid": "CA42", "status": "queued"})
+	}))
+	defer server.Close()
+
+	provider := createTestVoiceProvider(server.URL)
+
+	voice := createTestVoiceNotification()
+	voice.ProviderOptions = map[string]any{"machine_detection": "DetectMessageEnd", "timeout": float64(30), "record": true}
+	_, err := provider.SendVoice(context.Background(), voice)
+	require.NoError(t, err)
+
+	voice.ProviderOptions = map[string]any{"machine_detection": "Always"}
+	_, err = provider.SendVoice(context.Background(), voice)
+	assert.Error(t, err)
+}
+
+func TestTwilioVoiceProvider_SendVoice_ErrorResponses(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		expectedCode errors.ErrorCode
+	}{
+		{"invalid number", http.StatusBadRequest, errors.ErrorCodeDeliveryFailed},
+		{"bad credentials", http.StatusUnauthorized, errors.ErrorCodeProviderAuthentication},
+		{"rate limited", http.StatusTooManyRequests, errors.ErrorCodeRateLimited},
+		{"outage", http.StatusServiceUnavailable, errors.ErrorCodeProviderUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				json.NewEncoder(w).Encode(map[string]interface{}{"code": 21211, "message": "Invalid 'To' Phone Number"})
+			}))
+			defer server.Close()
+
+			provider := createTestVoiceProvider(server.URL)
+			_, err := provider.SendVoice(context.Background(), createTestVoiceNotification())
+			require.Error(t, err)
+
+			notifErr, ok := errors.AsNotificationError(err)
+			require.True(t, ok)
+			assert.Equal(t, tt.expectedCode, notifErr.Code)
+		})
+	}
+}
+
+func TestTwilioVoiceProvider_ValidatePhoneNumber(t *testing.T) {
+	provider := createTestVoiceProvider("")
+
+	assert.NoError(t, provider.ValidatePhoneNumber("+1 (555) 123-4567", "us"))
+	assert.Error(t, provider.ValidatePhoneNumber("", "US"))
+	assert.Error(t, provider.ValidatePhoneNumber("12ab", "US"))
+	assert.Error(t, provider.ValidatePhoneNumber("5551234567", ""))
+	assert.Error(t, provider.ValidatePhoneNumber("5551234567", "ZZ"))
+
+	// Supported but not on the allowlist
+	err := provider.ValidatePhoneNumber("0301234567", "DE")
+	require.Error(t, err)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeInvalidRecipient, notifErr.Code)
+	assert.Equal(t, "DE", notifErr.Metadata["country_code"])
+}
+
+func TestTwilioVoiceProvider_EmptyAllowlistAllowsSupportedCountries(t *testing.T) {
+	provider := NewTwilioVoiceProvider(config.VoiceProviderConfig{Provider: "twilio"})
+
+	assert.True(t, provider.IsCountryAllowed("DE"))
+	assert.NoError(t, provider.ValidatePhoneNumber("0301234567", "DE"))
+}
+
+func TestTwilioVoiceProvider_ValidationErrors(t *testing.T) {
+	provider := createTestVoiceProvider("")
+	ctx := context.Background()
+
+	empty := createTestVoiceNotification()
+	empty.Message = "  "
+	_, err := provider.SendVoice(ctx, empty)
+	require.Error(t, err)
+
+	loop := createTestVoiceNotification()
+	loop.Loop = 10
+	_, err = provider.SendVoice(ctx, loop)
+	require.Error(t, err)
+}
+
+func TestTwilioVoiceProvider_CostAndDuration(t *testing.T) {
+	provider := createTestVoiceProvider("")
+
+	cost, err := provider.GetVoiceCost("uk")
+	require.NoError(t, err)
+	assert.Equal(t, 0.0200, cost)
+
+	_, err = provider.GetVoiceCost("ZZ")
+	require.Error(t, err)
+
+	// 10 words at 2.5 words/second is 4 seconds, read twice, plus call setup
+	assert.Equal(t, 11*time.Second, provider.EstimateDuration("one two three four five six seven eight nine ten", 2))
+}
+
+func TestTwilioVoiceProvider_FormatE164(t *testing.T) {
+	provider := createTestVoiceProvider("")
+
+	assert.Equal(t, "+15551234567", provider.formatE164("555-123-4567", "US"))
+	assert.Equal(t, "+442079460000", provider.formatE164("020 7946 0000", "UK"))
+	assert.Equal(t, "+442079460000", provider.formatE164("+44 20 7946 0000", "UK"))
+}
+
+func TestTwilioVoiceProvider_RenderTemplate(t *testing.T) {
+	provider := createTestVoiceProvider("")
+
+	rendered, err := provider.RenderTemplate("urgent_alert", map[string]string{
+		"service_name":  "Payments",
+		"alert_message": "Error rate above 5 percent",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "This is an urgent alert from Payments. Error rate above 5 percent. Please respond immediately.", rendered.Message)
+	assert.Equal(t, 2, rendered.Loop)
+
+	_, err = provider.RenderTemplate("missing", nil)
+	require.Error(t, err)
+}
+
+func TestTwilioVoiceProvider_Send_WrongType(t *testing.T) {
+	provider := createTestVoiceProvider("")
+
+	_, err := provider.Send(context.Background(), &models.Notification{ID: uuid.New(), Type: models.NotificationTypeSMS})
+	require.Error(t, err)
+}
+
+func TestTwilioVoiceProvider_CheckCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "/2010-04-01/Accounts/AC123/Balance.json", r.URL.Path)
+
+		if _, pass, _ := r.BasicAuth(); pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"balance": "12.50", "currency": "USD"})
+	}))
+	defer server.Close()
+
+	provider := createTestVoiceProvider(server.URL)
+
+	status, err := provider.CheckCredentials(context.Background())
+	require.NoError(t, err)
+	assert.True(t, status.Valid)
+	assert.Equal(t, "balance 12.50 USD", status.Detail)
+
+	provider.config.TwilioAuthToken = "expired"
+	status, err = provider.CheckCredentials(context.Background())
+	require.NoError(t, err)
+	assert.False(t, status.Valid)
+
+	server.Close()
+	_, err = provider.CheckCredentials(context.Background())
+	assert.Error(t, err)
+}
+
+func TestTwilioVoiceProvider_RotateCredentials(t *testing.T) {
+	var callToken string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pass, _ := r.BasicAuth()
+		if r.Method == http.MethodPost {
+			callToken = pass
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]string{"sid": "CA42", "status": "queued"})
+			return
+		}
+		if pass != "rotated" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"balance": "1.00", "currency": "USD"})
+	}))
+	defer server.Close()
+
+	provider := createTestVoiceProvider(server.URL)
+	ctx := context.Background()
+
+	err := provider.RotateCredentials(ctx, map[string]string{"auth_token": "wrong"})
+	assert.Error(t, err)
+	err = provider.RotateCredentials(ctx, map[string]string{})
+	assert.Error(t, err)
+
+	// Rejected credentials leave the old ones in use
+	_, err = provider.SendVoice(ctx, createTestVoiceNotification())
+	require.NoError(t, err)
+	assert.Equal(t, "secret", callToken)
+
+	require.NoError(t, provider.RotateCredentials(ctx, map[string]string{"auth_token": "rotated"}))
+	_, err = provider.SendVoice(ctx, createTestVoiceNotification())
+	require.NoError(t, err)
+	assert.Equal(t, "rotated", callToken)
+}
+
+// Helper functions
+
+func createTestVoiceProvider(baseURL string) *TwilioVoiceProvider {
+	return NewTwilioVoiceProvider(config.VoiceProviderConfig{
+		Provider:         "twilio",
+		Enabled:          true,
+		AllowedCountries: []string{"US", "CA", "UK"},
+		DefaultVoice:     "alice",
+		DefaultLanguage:  "en-US",
+		TwilioAccountSID: "AC123",
+		TwilioAuthToken:  "secret",
+		TwilioFromNumber: "+15550000000",
+		TwilioBaseURL:    baseURL,
+	})
+}
+
+func createTestVoiceNotification() *models.VoiceNotification {
+	return &models.VoiceNotification{
+		Notification: models.Notification{
+			ID:       uuid.New(),
+			Type:     models.NotificationTypeVoice,
+			Status:   models.StatusPending,
+			Priority: models.PriorityUrgent,
+		},
+		PhoneNumber: "5551234567",
+		CountryCode: "US",
+		Message:     "Database primary is down",
+	}
+}
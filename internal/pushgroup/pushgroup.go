@@ -0,0 +1,156 @@
+// Package pushgroup counts the push alerts of each group on each device, so
+// repeated alerts of the same kind stack under a summary such as "3 new
+// orders" or replace each other instead of filling the notification tray.
+// A group's count starts over when the app reports it was opened or cleared,
+// or after a window without alerts.
+package pushgroup
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pipeline"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// MiddlewareName is the name the grouper's middleware is registered under
+const MiddlewareName = "push-group"
+
+// defaultWindow is the inactivity after which a group's count starts over
+const defaultWindow = 24 * time.Hour
+
+// group is the alerts of a group on a device
+type group struct {
+	count  int
+	lastAt time.Time
+}
+
+// Grouper counts the alerts of each group on each device. It is safe for
+// concurrent use.
+type Grouper struct {
+	window time.Duration
+
+	mu     sync.Mutex
+	groups map[string]*group
+	now    func() time.Time
+}
+
+// NewGrouper creates a grouper with no alerts counted
+func NewGrouper(cfg config.PushGroupConfig) *Grouper {
+	window := cfg.Window
+	if window <= 0 {
+		window = defaultWindow
+	}
+
+	return &Grouper{
+		window: window,
+		groups: make(map[string]*group),
+		now:    time.Now,
+	}
+}
+
+// Add counts an alert of a group sent to a device and returns the number of
+// alerts in the group, including it
+func (g *Grouper) Add(deviceToken, name string) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	key := groupKey(deviceToken, name)
+	entry, exists := g.groups[key]
+	if !exists || now.Sub(entry.lastAt) > g.window {
+		entry = &group{}
+		g.groups[key] = entry
+	}
+	entry.count++
+	entry.lastAt = now
+	return entry.count
+}
+
+// Count returns the number of alerts in a group on a device
+func (g *Grouper) Count(deviceToken, name string) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	entry, exists := g.groups[groupKey(deviceToken, name)]
+	if !exists || g.now().Sub(entry.lastAt) > g.window {
+		return 0
+	}
+	return entry.count
+}
+
+// Reset starts a group's count over, for when the user opened or cleared it
+func (g *Grouper) Reset(deviceToken, name string) error {
+	if deviceToken == "" || name == "" {
+		return errors.NewValidationError("group", "device token and group are required")
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.groups, groupKey(deviceToken, name))
+	return nil
+}
+
+// Apply counts a grouped push notification and sets its group count
+func (g *Grouper) Apply(push *models.PushNotification) {
+	if push.Group == "" {
+		return
+	}
+	push.GroupCount = g.Add(push.DeviceToken, push.Group)
+}
+
+// Middleware returns the send middleware counting grouped push requests.
+// Register it at pipeline.StageTemplate under MiddlewareName. Failed sends
+// are not counted.
+func (g *Grouper) Middleware(logger interfaces.Logger) pipeline.Middleware {
+	return func(next pipeline.Handler) pipeline.Handler {
+		return func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+			if request.Type != models.NotificationTypePush || request.PushData == nil || request.PushData.Group == "" {
+				return next(ctx, request)
+			}
+
+			deviceToken := request.PushData.DeviceToken
+			if deviceToken == "" {
+				deviceToken = request.Recipient
+			}
+
+			data := *request.PushData
+			data.GroupCount = g.Add(deviceToken, data.Group)
+			grouped := *request
+			grouped.PushData = &data
+
+			response, err := next(ctx, &grouped)
+			if err != nil {
+				g.Remove(deviceToken, data.Group)
+				return nil, err
+			}
+
+			logger.Debugf("Push %s is alert %d of group %s", response.ID, data.GroupCount, data.Group)
+			return response, nil
+		}
+	}
+}
+
+// Remove uncounts an alert that was not sent
+func (g *Grouper) Remove(deviceToken, name string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	key := groupKey(deviceToken, name)
+	if entry, exists := g.groups[key]; exists {
+		entry.count--
+		if entry.count <= 0 {
+			delete(g.groups, key)
+		}
+	}
+}
+
+// groupKey identifies a group on a device
+func groupKey(deviceToken, name string) string {
+	return deviceToken + "|" + name
+}
@@ -0,0 +1,110 @@
+package pushgroup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestGrouper_Add(t *testing.T) {
+	grouper, clock := createTestGrouper()
+
+	assert.Equal(t, 1, grouper.Add("device-1", "orders"))
+	assert.Equal(t, 2, grouper.Add("device-1", "orders"))
+	assert.Equal(t, 2, grouper.Count("device-1", "orders"))
+
+	// Groups are counted per device and group
+	assert.Equal(t, 1, grouper.Add("device-2", "orders"))
+	assert.Equal(t, 1, grouper.Add("device-1", "messages"))
+
+	// The count starts over after the window
+	*clock = clock.Add(2 * time.Hour)
+	assert.Equal(t, 0, grouper.Count("device-1", "orders"))
+	assert.Equal(t, 1, grouper.Add("device-1", "orders"))
+}
+
+func TestGrouper_Reset(t *testing.T) {
+	grouper, _ := createTestGrouper()
+
+	grouper.Add("device-1", "orders")
+	grouper.Add("device-1", "orders")
+	require.NoError(t, grouper.Reset("device-1", "orders"))
+	assert.Equal(t, 0, grouper.Count("device-1", "orders"))
+	assert.Equal(t, 1, grouper.Add("device-1", "orders"))
+
+	err := grouper.Reset("", "orders")
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeValidationFailed, notifErr.Code)
+}
+
+func TestGrouper_Apply(t *testing.T) {
+	grouper, _ := createTestGrouper()
+
+	push := &models.PushNotification{DeviceToken: "device-1", Group: "orders"}
+	grouper.Apply(push)
+	grouper.Apply(push)
+	assert.Equal(t, 2, push.GroupCount)
+
+	ungrouped := &models.PushNotification{DeviceToken: "device-1"}
+	grouper.Apply(ungrouped)
+	assert.Zero(t, ungrouped.GroupCount)
+}
+
+func TestGrouper_Middleware(t *testing.T) {
+	grouper, _ := createTestGrouper()
+
+	var counts []int
+	fail := false
+	handler := grouper.Middleware(utils.NewSimpleLogger("info"))(func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+		if fail {
+			return nil, errors.NewNotificationError(errors.ErrorCodeProviderUnavailable, "provider down")
+		}
+		if request.PushData != nil {
+			counts = append(counts, request.PushData.GroupCount)
+		}
+		return &models.NotificationResponse{ID: uuid.New()}, nil
+	})
+
+	data := &models.PushData{Platform: "ios", Group: "orders"}
+	request := &models.NotificationRequest{Type: models.NotificationTypePush, Recipient: "device-1", PushData: data}
+
+	for i := 0; i < 2; i++ {
+		_, err := handler(context.Background(), request)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []int{1, 2}, counts)
+	assert.Zero(t, data.GroupCount) // the caller's request is not changed
+
+	// Failed sends are not counted
+	fail = true
+	_, err := handler(context.Background(), request)
+	require.Error(t, err)
+	assert.Equal(t, 2, grouper.Count("device-1", "orders"))
+
+	// Ungrouped pushes and other channels pass through
+	fail = false
+	_, err = handler(context.Background(), &models.NotificationRequest{Type: models.NotificationTypePush, Recipient: "device-1", PushData: &models.PushData{Platform: "ios"}})
+	require.NoError(t, err)
+	_, err = handler(context.Background(), &models.NotificationRequest{Type: models.NotificationTypeSMS, Recipient: "+14155550123"})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 0}, counts)
+}
+
+// Helper functions
+
+func createTestGrouper() (*Grouper, *time.Time) {
+	grouper := NewGrouper(config.PushGroupConfig{Window: time.Hour})
+	clock := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	grouper.now = func() time.Time { return clock }
+	return grouper, &clock
+}
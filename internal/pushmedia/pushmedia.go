@@ -0,0 +1,341 @@
+// Package pushmedia checks push notification images before they are sent:
+// FCM and APNs fetch images themselves, so an image that cannot be
+// fetched, is of a type the platform cannot show or is over its size limit
+// is silently dropped from the notification. Images can also be uploaded
+// to an object store, since the platforms require public URLs.
+package pushmedia
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/httpclient"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pipeline"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// MiddlewareName is the name the checker's middleware is registered under
+const MiddlewareName = "push-media"
+
+// Defaults applied to zero configuration fields
+const (
+	defaultCheckTimeout = 5 * time.Second
+	defaultCacheTTL     = 10 * time.Minute
+	defaultCacheSize    = 10000
+)
+
+// Limits are the images a platform shows
+type Limits struct {
+	MaxSize      int64    `json:"max_size"` // bytes
+	ContentTypes []string `json:"content_types"`
+}
+
+// allows reports whether a content type is one the platform shows
+func (l Limits) allows(contentType string) bool {
+	for _, allowed := range l.ContentTypes {
+		if contentType == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// platformLimits are the documented image limits of each platform. iOS
+// images are notification attachments; web limits are what browsers
+// reliably show.
+var platformLimits = map[string]Limits{
+	"ios":     {MaxSize: 10 << 20, ContentTypes: []string{"image/jpeg", "image/png", "image/gif"}},
+	"android": {MaxSize: 1 << 20, ContentTypes: []string{"image/jpeg", "image/png", "image/bmp"}},
+	"web":     {MaxSize: 2 << 20, ContentTypes: []string{"image/jpeg", "image/png", "image/gif", "image/webp"}},
+}
+
+// PlatformLimits returns the image limits of a platform
+func PlatformLimits(platform string) (Limits, bool) {
+	limits, exists := platformLimits[strings.ToLower(platform)]
+	return limits, exists
+}
+
+// Media is a checked or uploaded image
+type Media struct {
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
+
+// Store stores uploaded images and returns their public URLs
+type Store interface {
+	Put(ctx context.Context, key, contentType string, data []byte) (string, error)
+}
+
+// cacheEntry is a checked image
+type cacheEntry struct {
+	media   Media
+	expires time.Time
+}
+
+// Checker checks and uploads push images. It is safe for concurrent use.
+type Checker struct {
+	client    *http.Client
+	cacheTTL  time.Duration
+	cacheSize int
+	store     Store
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+	now   func() time.Time
+}
+
+// NewChecker creates a checker. Uploads are disabled when store is nil.
+// Images are only fetched from public addresses, so image URLs cannot
+// reach internal services.
+func NewChecker(cfg config.PushMediaConfig, store Store) *Checker {
+	timeout := cfg.CheckTimeout
+	if timeout <= 0 {
+		timeout = defaultCheckTimeout
+	}
+	cacheTTL := cfg.CacheTTL
+	if cacheTTL <= 0 {
+		cacheTTL = defaultCacheTTL
+	}
+	cacheSize := cfg.CacheSize
+	if cacheSize <= 0 {
+		cacheSize = defaultCacheSize
+	}
+
+	return &Checker{
+		client:    httpclient.Shared.Client(config.HTTPClientConfig{PublicOnly: true}, timeout),
+		cacheTTL:  cacheTTL,
+		cacheSize: cacheSize,
+		store:     store,
+		cache:     make(map[string]cacheEntry),
+		now:       time.Now,
+	}
+}
+
+// Check fetches an image and checks it against a platform's limits, or
+// against every platform's when platform is empty, as for topic pushes.
+// Images that pass are trusted for the cache TTL, so bulk sends fetch them
+// once.
+func (c *Checker) Check(ctx context.Context, imageURL, platform string) (Media, error) {
+	checks := platformLimits
+	if platform != "" {
+		limits, exists := PlatformLimits(platform)
+		if !exists {
+			return Media{}, errors.NewValidationError("platform", fmt.Sprintf("unsupported platform: %s", platform))
+		}
+		checks = map[string]Limits{platform: limits}
+	}
+	var maxSize int64
+	for _, limits := range checks {
+		if limits.MaxSize > maxSize {
+			maxSize = limits.MaxSize
+		}
+	}
+
+	parsed, err := url.Parse(imageURL)
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+		return Media{}, errors.NewValidationError("image_url", "image URL must be a public https URL")
+	}
+
+	media, cached := c.cached(imageURL)
+	if !cached {
+		media, err = c.fetch(ctx, imageURL, maxSize)
+		if err != nil {
+			return Media{}, err
+		}
+	}
+
+	for name, limits := range checks {
+		if err := checkLimits(media, limits, name); err != nil {
+			return Media{}, err
+		}
+	}
+
+	if !cached {
+		c.remember(imageURL, media)
+	}
+	return media, nil
+}
+
+// Upload stores an image and returns its public URL. The image is checked
+// against the platform's limits, or against every platform's when platform
+// is empty. Images are stored under a hash of their content, so uploading
+// the same image again returns the same URL.
+func (c *Checker) Upload(ctx context.Context, data []byte, platform string) (Media, error) {
+	if c.store == nil {
+		return Media{}, errors.NewNotificationError(errors.ErrorCodeProviderConfiguration, "push media uploads are not configured")
+	}
+	if len(data) == 0 {
+		return Media{}, errors.NewValidationError("image", "image is required")
+	}
+
+	media := Media{ContentType: detectContentType("", data), Size: int64(len(data))}
+	if platform != "" {
+		limits, exists := PlatformLimits(platform)
+		if !exists {
+			return Media{}, errors.NewValidationError("platform", fmt.Sprintf("unsupported platform: %s", platform))
+		}
+		if err := checkLimits(media, limits, platform); err != nil {
+			return Media{}, err
+		}
+	} else {
+		for name, limits := range platformLimits {
+			if err := checkLimits(media, limits, name); err != nil {
+				return Media{}, err
+			}
+		}
+	}
+
+	sum := sha256.Sum256(data)
+	extensions, _ := mime.ExtensionsByType(media.ContentType)
+	key := hex.EncodeToString(sum[:])
+	if len(extensions) > 0 {
+		key += extensions[0]
+	}
+
+	hosted, err := c.store.Put(ctx, key, media.ContentType, data)
+	if err != nil {
+		return Media{}, errors.NewNotificationError(errors.ErrorCodeProviderUnavailable, "push image upload failed").WithCause(err)
+	}
+	media.URL = hosted
+
+	c.remember(hosted, media)
+	return media, nil
+}
+
+// Middleware returns the send middleware checking push images. Register it
+// at pipeline.StageTemplate under MiddlewareName, so only sends that reach
+// the provider fetch their image.
+func (c *Checker) Middleware(logger interfaces.Logger) pipeline.Middleware {
+	return func(next pipeline.Handler) pipeline.Handler {
+		return func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+			if request.Type != models.NotificationTypePush || request.PushData == nil || request.PushData.ImageURL == "" {
+				return next(ctx, request)
+			}
+
+			if _, err := c.Check(ctx, request.PushData.ImageURL, request.PushData.Platform); err != nil {
+				logger.Warnf("Push image %s rejected: %v", request.PushData.ImageURL, err)
+				return nil, err
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// cached returns a checked image, if its cache entry has not expired
+func (c *Checker) cached(imageURL string) (Media, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, exists := c.cache[imageURL]
+	if !exists || c.now().After(entry.expires) {
+		delete(c.cache, imageURL)
+		return Media{}, false
+	}
+	return entry.media, true
+}
+
+// remember caches a checked image. When the cache is full, expired entries
+// are swept, and if none expired the entry closest to expiry is evicted.
+func (c *Checker) remember(imageURL string, media Media) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if _, exists := c.cache[imageURL]; !exists && len(c.cache) >= c.cacheSize {
+		oldest := ""
+		for key, entry := range c.cache {
+			if now.After(entry.expires) {
+				delete(c.cache, key)
+			} else if oldest == "" || entry.expires.Before(c.cache[oldest].expires) {
+				oldest = key
+			}
+		}
+		if len(c.cache) >= c.cacheSize {
+			delete(c.cache, oldest)
+		}
+	}
+	c.cache[imageURL] = cacheEntry{media: media, expires: now.Add(c.cacheTTL)}
+}
+
+// fetch learns an image's content type and size. A HEAD request is enough
+// for most servers; otherwise the image is downloaded, up to just over
+// maxSize.
+func (c *Checker) fetch(ctx context.Context, imageURL string, maxSize int64) (Media, error) {
+	response, err := c.do(ctx, http.MethodHead, imageURL)
+	if err == nil {
+		response.Body.Close()
+		contentType := detectContentType(response.Header.Get("Content-Type"), nil)
+		if response.StatusCode == http.StatusOK && response.ContentLength >= 0 && contentType != "" {
+			return Media{URL: imageURL, ContentType: contentType, Size: response.ContentLength}, nil
+		}
+	}
+
+	response, err = c.do(ctx, http.MethodGet, imageURL)
+	if err != nil {
+		return Media{}, errors.NewValidationError("image_url", "image URL is not reachable").WithCause(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return Media{}, errors.NewValidationError("image_url", fmt.Sprintf("image URL is not reachable (status %d)", response.StatusCode))
+	}
+
+	data, err := io.ReadAll(io.LimitReader(response.Body, maxSize+1))
+	if err != nil {
+		return Media{}, errors.NewValidationError("image_url", "image could not be downloaded").WithCause(err)
+	}
+	return Media{
+		URL:         imageURL,
+		ContentType: detectContentType(response.Header.Get("Content-Type"), data),
+		Size:        int64(len(data)),
+	}, nil
+}
+
+// do sends a request for an image
+func (c *Checker) do(ctx context.Context, method, imageURL string) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, method, imageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "image/*")
+	return c.client.Do(request)
+}
+
+// checkLimits checks an image against a platform's limits
+func checkLimits(media Media, limits Limits, platform string) error {
+	if !limits.allows(media.ContentType) {
+		return errors.NewValidationError("image_url", fmt.Sprintf("%s images must be one of %s, not %s",
+			platform, strings.Join(limits.ContentTypes, ", "), media.ContentType))
+	}
+	if media.Size > limits.MaxSize {
+		return errors.NewValidationError("image_url", fmt.Sprintf("%s images cannot exceed %d bytes", platform, limits.MaxSize))
+	}
+	return nil
+}
+
+// detectContentType returns the media type of a Content-Type header,
+// sniffing it from the data when the header is missing or generic
+func detectContentType(header string, data []byte) string {
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err == nil && mediaType != "application/octet-stream" {
+		return strings.ToLower(mediaType)
+	}
+	if len(data) == 0 {
+		return ""
+	}
+	mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	return mediaType
+}
@@ -0,0 +1,281 @@
+package pushmedia
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestPlatformLimits(t *testing.T) {
+	limits, ok := PlatformLimits("Android")
+	require.True(t, ok)
+	assert.Equal(t, int64(1<<20), limits.MaxSize)
+
+	_, ok = PlatformLimits("blackberry")
+	assert.False(t, ok)
+}
+
+func TestChecker_Check(t *testing.T) {
+	checker, server, requests := createTestImageServer(t)
+	ctx := context.Background()
+
+	media, err := checker.Check(ctx, server.URL+"/photo.png", "ios")
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", media.ContentType)
+	assert.Equal(t, int64(len(createTestPNG(t))), media.Size)
+
+	// Checked images are cached
+	_, err = checker.Check(ctx, server.URL+"/photo.png", "android")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), requests.Load())
+
+	// Servers without HEAD support or a Content-Length are downloaded
+	media, err = checker.Check(ctx, server.URL+"/no-head", "web")
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", media.ContentType)
+}
+
+func TestChecker_Check_Rejects(t *testing.T) {
+	checker, server, _ := createTestImageServer(t)
+
+	tests := []struct {
+		name     string
+		url      string
+		platform string
+		field    string
+	}{
+		{"http URL", "http://example.com/photo.png", "ios", "image_url"},
+		{"not found", server.URL + "/missing.png", "ios", "image_url"},
+		{"not an image", server.URL + "/page.html", "ios", "image_url"},
+		{"type the platform cannot show", server.URL + "/photo.webp", "ios", "image_url"},
+		{"over the platform's size limit", server.URL + "/large.jpg", "android", "image_url"},
+		{"over a platform's limit, for every platform", server.URL + "/large.jpg", "", "image_url"},
+		{"unsupported platform", server.URL + "/photo.png", "blackberry", "platform"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := checker.Check(context.Background(), tt.url, tt.platform)
+			notifErr, ok := errors.AsNotificationError(err)
+			require.True(t, ok, "%v", err)
+			assert.Equal(t, errors.ErrorCodeValidationFailed, notifErr.Code)
+			assert.Equal(t, tt.field, notifErr.Metadata["field"])
+		})
+	}
+
+	// The large image is fine on iOS
+	_, err := checker.Check(context.Background(), server.URL+"/large.jpg", "ios")
+	assert.NoError(t, err)
+}
+
+func TestChecker_Check_CacheExpires(t *testing.T) {
+	checker, server, requests := createTestImageServer(t)
+	clock := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	checker.now = func() time.Time { return clock }
+
+	_, err := checker.Check(context.Background(), server.URL+"/photo.png", "ios")
+	require.NoError(t, err)
+	clock = clock.Add(11 * time.Minute)
+	_, err = checker.Check(context.Background(), server.URL+"/photo.png", "ios")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), requests.Load())
+}
+
+func TestChecker_Check_RefusesPrivateHosts(t *testing.T) {
+	_, server, requests := createTestImageServer(t)
+	// A checker with its own client, which only dials public addresses
+	checker := NewChecker(config.PushMediaConfig{}, nil)
+
+	for _, imageURL := range []string{server.URL + "/photo.png", "https://169.254.169.254/latest/meta-data"} {
+		_, err := checker.Check(context.Background(), imageURL, "ios")
+		notifErr, ok := errors.AsNotificationError(err)
+		require.True(t, ok, "%v", err)
+		assert.Equal(t, errors.ErrorCodeValidationFailed, notifErr.Code)
+	}
+	assert.Equal(t, int64(0), requests.Load())
+}
+
+func TestChecker_CacheBounded(t *testing.T) {
+	checker, server, requests := createTestImageServer(t)
+	checker.cacheSize = 2
+	clock := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	checker.now = func() time.Time { return clock }
+
+	for _, name := range []string{"/photo.png", "/no-head", "/large.jpg"} {
+		_, err := checker.Check(context.Background(), server.URL+name, "ios")
+		require.NoError(t, err)
+		clock = clock.Add(time.Second)
+	}
+	assert.Len(t, checker.cache, 2)
+
+	// The entry closest to expiry was evicted
+	requests.Store(0)
+	_, err := checker.Check(context.Background(), server.URL+"/large.jpg", "ios")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), requests.Load())
+	_, err = checker.Check(context.Background(), server.URL+"/photo.png", "ios")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), requests.Load())
+
+	// Expired entries are swept before anything else is evicted
+	clock = clock.Add(time.Hour)
+	_, err = checker.Check(context.Background(), server.URL+"/no-head", "ios")
+	require.NoError(t, err)
+	assert.Len(t, checker.cache, 1)
+}
+
+func TestChecker_Upload(t *testing.T) {
+	var uploads []*http.Request
+	var bodies [][]byte
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		uploads = append(uploads, r)
+		bodies = append(bodies, body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer origin.Close()
+
+	store, err := NewHTTPStore(config.PushMediaConfig{UploadURL: origin.URL + "/images/", PublicURL: "https://cdn.example.com/push", UploadToken: "secret"})
+	require.NoError(t, err)
+	checker := NewChecker(config.PushMediaConfig{}, store)
+	data := createTestPNG(t)
+
+	media, err := checker.Upload(context.Background(), data, "")
+	require.NoError(t, err)
+	assert.Regexp(t, `^https://cdn\.example\.com/push/[0-9a-f]{64}\.png$`, media.URL)
+	assert.Equal(t, "image/png", media.ContentType)
+
+	require.Len(t, uploads, 1)
+	assert.Equal(t, http.MethodPut, uploads[0].Method)
+	assert.Equal(t, "/images/"+media.URL[len("https://cdn.example.com/push/"):], uploads[0].URL.Path)
+	assert.Equal(t, "Bearer secret", uploads[0].Header.Get("Authorization"))
+	assert.Equal(t, "image/png", uploads[0].Header.Get("Content-Type"))
+	assert.Equal(t, data, bodies[0])
+
+	// Uploaded images pass checks without being fetched
+	_, err = checker.Check(context.Background(), media.URL, "android")
+	assert.NoError(t, err)
+
+	// The same image gets the same URL
+	again, err := checker.Upload(context.Background(), data, "ios")
+	require.NoError(t, err)
+	assert.Equal(t, media.URL, again.URL)
+
+	_, err = checker.Upload(context.Background(), []byte("<html></html>"), "ios")
+	assert.Error(t, err)
+}
+
+func TestChecker_Upload_Errors(t *testing.T) {
+	_, err := NewChecker(config.PushMediaConfig{}, nil).Upload(context.Background(), createTestPNG(t), "")
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeProviderConfiguration, notifErr.Code)
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer origin.Close()
+	store, err := NewHTTPStore(config.PushMediaConfig{UploadURL: origin.URL, PublicURL: "https://cdn.example.com"})
+	require.NoError(t, err)
+
+	_, err = NewChecker(config.PushMediaConfig{}, store).Upload(context.Background(), createTestPNG(t), "")
+	notifErr, ok = errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeProviderUnavailable, notifErr.Code)
+}
+
+func TestNewHTTPStore_Validates(t *testing.T) {
+	_, err := NewHTTPStore(config.PushMediaConfig{PublicURL: "https://cdn.example.com"})
+	assert.Error(t, err)
+
+	// Platforms only fetch public https images
+	_, err = NewHTTPStore(config.PushMediaConfig{UploadURL: "https://storage.example.com", PublicURL: "http://cdn.example.com"})
+	assert.Error(t, err)
+}
+
+func TestChecker_Middleware(t *testing.T) {
+	checker, server, _ := createTestImageServer(t)
+	sent := 0
+	handler := checker.Middleware(utils.NewSimpleLogger("info"))(func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+		sent++
+		return &models.NotificationResponse{ID: uuid.New()}, nil
+	})
+
+	request := &models.NotificationRequest{Type: models.NotificationTypePush, PushData: &models.PushData{Platform: "android", ImageURL: server.URL + "/photo.png"}}
+	_, err := handler(context.Background(), request)
+	require.NoError(t, err)
+
+	request.PushData.ImageURL = server.URL + "/large.jpg"
+	_, err = handler(context.Background(), request)
+	require.Error(t, err)
+
+	// Pushes without images are not checked
+	request.PushData.ImageURL = ""
+	_, err = handler(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, 2, sent)
+}
+
+// Helper functions
+
+func createTestImageServer(t *testing.T) (*Checker, *httptest.Server, *atomic.Int64) {
+	pngImage := createTestPNG(t)
+	requests := &atomic.Int64{}
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/photo.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Header().Set("Content-Length", strconv.Itoa(len(pngImage)))
+			if r.Method == http.MethodGet {
+				_, _ = w.Write(pngImage)
+			}
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			_, _ = w.Write(pngImage) // the type is sniffed
+		case "/photo.webp":
+			w.Header().Set("Content-Type", "image/webp")
+			w.Header().Set("Content-Length", "2048")
+		case "/large.jpg":
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Header().Set("Content-Length", strconv.Itoa(3<<20))
+		case "/page.html":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Header().Set("Content-Length", "13")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	checker := NewChecker(config.PushMediaConfig{}, nil)
+	checker.client = server.Client()
+	return checker, server, requests
+}
+
+func createTestPNG(t *testing.T) []byte {
+	var buffer bytes.Buffer
+	require.NoError(t, png.Encode(&buffer, image.NewRGBA(image.Rect(0, 0, 4, 4))))
+	return buffer.Bytes()
+}
@@ -0,0 +1,72 @@
+package pushmedia
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/httpclient"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// defaultUploadTimeout bounds an upload
+const defaultUploadTimeout = 30 * time.Second
+
+// HTTPStore uploads images with HTTP PUT requests, as S3, GCS and most CDN
+// origins accept, and serves them from a public base URL
+type HTTPStore struct {
+	uploadURL string
+	publicURL string
+	token     string
+	client    *http.Client
+}
+
+// NewHTTPStore creates a store for the configured upload and public URLs
+func NewHTTPStore(cfg config.PushMediaConfig) (*HTTPStore, error) {
+	upload, err := url.Parse(cfg.UploadURL)
+	if err != nil || upload.Host == "" || (upload.Scheme != "https" && upload.Scheme != "http") {
+		return nil, errors.NewValidationError("upload_url", "push media upload URL must be an http or https URL")
+	}
+
+	public, err := url.Parse(cfg.PublicURL)
+	if err != nil || public.Host == "" || public.Scheme != "https" {
+		return nil, errors.NewValidationError("public_url", "push media public URL must be an https URL")
+	}
+
+	return &HTTPStore{
+		uploadURL: strings.TrimRight(cfg.UploadURL, "/"),
+		publicURL: strings.TrimRight(cfg.PublicURL, "/"),
+		token:     cfg.UploadToken,
+		client:    httpclient.Shared.Client(config.HTTPClientConfig{}, defaultUploadTimeout),
+	}, nil
+}
+
+// Put uploads an image and returns its public URL
+func (s *HTTPStore) Put(ctx context.Context, key, contentType string, data []byte) (string, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPut, s.uploadURL+"/"+key, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", contentType)
+	// Keys are content hashes, so an uploaded image never changes
+	request.Header.Set("Cache-Control", "public, max-age=31536000, immutable")
+	if s.token != "" {
+		request.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return "", fmt.Errorf("object store returned status %d", response.StatusCode)
+	}
+	return s.publicURL + "/" + key, nil
+}
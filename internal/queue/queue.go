@@ -0,0 +1,236 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// Job represents a notification request waiting to be dispatched
+type Job struct {
+	ID         string                      `json:"id"`
+	Request    *models.NotificationRequest `json:"request"`
+	Metadata   map[string]string           `json:"metadata,omitempty"`
+	Attempts   int                         `json:"attempts"`
+	EnqueuedAt time.Time                   `json:"enqueued_at"`
+}
+
+// priorityOrder lists priorities from first to last dequeued
+var priorityOrder = []models.Priority{
+	models.PriorityUrgent,
+	models.PriorityHigh,
+	models.PriorityNormal,
+	models.PriorityLow,
+}
+
+// HoldFunc reports whether a job must stay queued for now, for example
+// while its channel is paused
+type HoldFunc func(job *Job) bool
+
+// ExpiredFunc is called with a job dropped because its request expired
+// while it was queued
+type ExpiredFunc func(job *Job)
+
+// MemoryQueue is a bounded in-memory job queue. Jobs are dequeued by
+// priority, and in FIFO order within a priority. Held jobs are skipped
+// without losing their place; expired jobs are dropped.
+type MemoryQueue struct {
+	mu      sync.Mutex
+	buckets map[models.Priority][]*Job
+	size    int
+	maxSize int
+	hold    HoldFunc
+	expired ExpiredFunc
+	signal  chan struct{}
+	now     func() time.Time
+}
+
+// NewMemoryQueue creates a new in-memory queue holding at most maxSize jobs.
+// A maxSize of zero or less means the queue is unbounded.
+func NewMemoryQueue(maxSize int) *MemoryQueue {
+	return &MemoryQueue{
+		buckets: make(map[models.Priority][]*Job),
+		maxSize: maxSize,
+		signal:  make(chan struct{}, 1),
+		now:     time.Now,
+	}
+}
+
+// Enqueue adds a job to the queue
+func (q *MemoryQueue) Enqueue(job *Job) error {
+	if job == nil || job.Request == nil {
+		return errors.NewValidationError("job", "job with a notification request is required")
+	}
+
+	q.mu.Lock()
+	if q.maxSize > 0 && q.size >= q.maxSize {
+		q.mu.Unlock()
+		return errors.ErrQueueFull
+	}
+
+	if job.ID == "" {
+		job.ID = uuid.New().String()
+	}
+	if job.EnqueuedAt.IsZero() {
+		job.EnqueuedAt = time.Now()
+	}
+
+	priority := bucketFor(job.Request.Priority)
+	q.buckets[priority] = append(q.buckets[priority], job)
+	q.size++
+	q.mu.Unlock()
+
+	q.notify()
+	return nil
+}
+
+// TryDequeue removes and returns the next job without waiting. Expired
+// jobs found on the way are dropped.
+func (q *MemoryQueue) TryDequeue() (*Job, error) {
+	q.mu.Lock()
+	job, dropped := q.pop(q.now())
+	remaining := q.size
+	expired := q.expired
+	q.mu.Unlock()
+
+	if expired != nil {
+		for _, dropped := range dropped {
+			expired(dropped)
+		}
+	}
+
+	if job == nil {
+		return nil, errors.ErrQueueEmpty
+	}
+
+	// Wake another waiting consumer if work remains
+	if remaining > 0 {
+		q.notify()
+	}
+
+	return job, nil
+}
+
+// Dequeue removes and returns the next job, waiting until one is available
+// or the context is done
+func (q *MemoryQueue) Dequeue(ctx context.Context) (*Job, error) {
+	for {
+		if job, err := q.TryDequeue(); err == nil {
+			return job, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.NewNotificationError(errors.ErrorCodeQueueTimeout, "timed out waiting for a job")
+		case <-q.signal:
+		}
+	}
+}
+
+// SetHold sets the function deciding which jobs are held. Call Wake when
+// held jobs may have become available.
+func (q *MemoryQueue) SetHold(hold HoldFunc) {
+	q.mu.Lock()
+	q.hold = hold
+	q.mu.Unlock()
+
+	q.notify()
+}
+
+// SetExpired sets the function called with jobs dropped because their
+// request expired, for example to record them as expired
+func (q *MemoryQueue) SetExpired(expired ExpiredFunc) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.expired = expired
+}
+
+// Wake wakes a waiting consumer so it looks for available jobs again
+func (q *MemoryQueue) Wake() {
+	q.notify()
+}
+
+// Count returns the number of queued jobs matching a predicate
+func (q *MemoryQueue) Count(match func(job *Job) bool) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	count := 0
+	for _, bucket := range q.buckets {
+		for _, job := range bucket {
+			if match(job) {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// Len returns the number of queued jobs
+func (q *MemoryQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.size
+}
+
+// pop removes the highest priority job that is not held, and drops the
+// expired jobs ahead of it. Callers must hold the lock.
+func (q *MemoryQueue) pop(now time.Time) (*Job, []*Job) {
+	var expired []*Job
+	for _, priority := range priorityOrder {
+		for i := 0; i < len(q.buckets[priority]); {
+			job := q.buckets[priority][i]
+			if job.Request.Expired(now) {
+				q.remove(priority, i)
+				expired = append(expired, job)
+				continue
+			}
+			if q.hold != nil && q.hold(job) {
+				i++
+				continue
+			}
+
+			q.remove(priority, i)
+			return job, expired
+		}
+	}
+	return nil, expired
+}
+
+// remove removes the job at an index of a bucket. Callers must hold the lock.
+func (q *MemoryQueue) remove(priority models.Priority, i int) {
+	bucket := q.buckets[priority]
+	if i == 0 {
+		bucket[0] = nil
+		q.buckets[priority] = bucket[1:]
+	} else {
+		copy(bucket[i:], bucket[i+1:])
+		bucket[len(bucket)-1] = nil
+		q.buckets[priority] = bucket[:len(bucket)-1]
+	}
+	q.size--
+}
+
+// notify wakes a waiting consumer without blocking
+func (q *MemoryQueue) notify() {
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+}
+
+// bucketFor maps a priority to its bucket, treating unknown priorities as normal
+func bucketFor(priority models.Priority) models.Priority {
+	switch priority {
+	case models.PriorityUrgent, models.PriorityHigh, models.PriorityLow:
+		return priority
+	default:
+		return models.PriorityNormal
+	}
+}
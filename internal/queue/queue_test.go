@@ -0,0 +1,190 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestMemoryQueue_PriorityOrder(t *testing.T) {
+	queue := NewMemoryQueue(0)
+
+	require.NoError(t, queue.Enqueue(createTestJob("low-1", models.PriorityLow)))
+	require.NoError(t, queue.Enqueue(createTestJob("normal-1", models.PriorityNormal)))
+	require.NoError(t, queue.Enqueue(createTestJob("urgent-1", models.PriorityUrgent)))
+	require.NoError(t, queue.Enqueue(createTestJob("normal-2", models.PriorityNormal)))
+	require.NoError(t, queue.Enqueue(createTestJob("high-1", models.PriorityHigh)))
+	assert.Equal(t, 5, queue.Len())
+
+	expected := []string{"urgent-1", "high-1", "normal-1", "normal-2", "low-1"}
+	for _, id := range expected {
+		job, err := queue.TryDequeue()
+		require.NoError(t, err)
+		assert.Equal(t, id, job.ID)
+	}
+
+	_, err := queue.TryDequeue()
+	assert.Equal(t, errors.ErrQueueEmpty, err)
+}
+
+func TestMemoryQueue_Enqueue(t *testing.T) {
+	queue := NewMemoryQueue(1)
+
+	job := createTestJob("", models.PriorityNormal)
+	require.NoError(t, queue.Enqueue(job))
+	assert.NotEmpty(t, job.ID)
+	assert.False(t, job.EnqueuedAt.IsZero())
+
+	// Bounded queue
+	err := queue.Enqueue(createTestJob("overflow", models.PriorityNormal))
+	assert.Equal(t, errors.ErrQueueFull, err)
+
+	// Missing request
+	err = queue.Enqueue(&Job{ID: "empty"})
+	require.Error(t, err)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeValidationFailed, notifErr.Code)
+}
+
+func TestMemoryQueue_Dequeue(t *testing.T) {
+	queue := NewMemoryQueue(0)
+
+	// Blocks until a job arrives
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_ = queue.Enqueue(createTestJob("late", models.PriorityNormal))
+	}()
+
+	job, err := queue.Dequeue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "late", job.ID)
+
+	// Times out with the context
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err = queue.Dequeue(ctx)
+	require.Error(t, err)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeQueueTimeout, notifErr.Code)
+}
+
+func TestMemoryQueue_Hold(t *testing.T) {
+	queue := NewMemoryQueue(0)
+	require.NoError(t, queue.Enqueue(createTestJob("held", models.PriorityHigh)))
+	require.NoError(t, queue.Enqueue(createTestJob("first", models.PriorityNormal)))
+	require.NoError(t, queue.Enqueue(createTestJob("second", models.PriorityNormal)))
+
+	holding := true
+	queue.SetHold(func(job *Job) bool {
+		return holding && (job.ID == "held" || job.ID == "first")
+	})
+
+	// Held jobs are skipped, including from the middle of a priority
+	job, err := queue.TryDequeue()
+	require.NoError(t, err)
+	assert.Equal(t, "second", job.ID)
+
+	_, err = queue.TryDequeue()
+	assert.Error(t, err)
+	assert.Equal(t, 2, queue.Len())
+	assert.Equal(t, 1, queue.Count(func(job *Job) bool { return job.Request.Priority == models.PriorityHigh }))
+
+	// Released jobs keep their place
+	holding = false
+	job, err = queue.TryDequeue()
+	require.NoError(t, err)
+	assert.Equal(t, "held", job.ID)
+	job, err = queue.TryDequeue()
+	require.NoError(t, err)
+	assert.Equal(t, "first", job.ID)
+}
+
+func TestMemoryQueue_DropsExpired(t *testing.T) {
+	queue := NewMemoryQueue(0)
+	clock := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	queue.now = func() time.Time { return clock }
+
+	var dropped []string
+	queue.SetExpired(func(job *Job) { dropped = append(dropped, job.ID) })
+
+	expiring := func(id string, expiresAt time.Time) *Job {
+		job := createTestJob(id, models.PriorityNormal)
+		job.Request.ExpiresAt = &expiresAt
+		return job
+	}
+	require.NoError(t, queue.Enqueue(expiring("stale-otp", clock.Add(-time.Second))))
+	require.NoError(t, queue.Enqueue(createTestJob("no-expiry", models.PriorityNormal)))
+	require.NoError(t, queue.Enqueue(expiring("fresh-otp", clock.Add(time.Minute))))
+
+	job, err := queue.TryDequeue()
+	require.NoError(t, err)
+	assert.Equal(t, "no-expiry", job.ID)
+	assert.Equal(t, []string{"stale-otp"}, dropped)
+
+	// A job expiring while queued is dropped when it is reached
+	clock = clock.Add(time.Minute)
+	_, err = queue.TryDequeue()
+	assert.Equal(t, errors.ErrQueueEmpty, err)
+	assert.Equal(t, []string{"stale-otp", "fresh-otp"}, dropped)
+	assert.Equal(t, 0, queue.Len())
+}
+
+func BenchmarkMemoryQueue_EnqueueDequeue(b *testing.B) {
+	queue := NewMemoryQueue(0)
+	priorities := []models.Priority{models.PriorityLow, models.PriorityNormal, models.PriorityHigh, models.PriorityUrgent}
+	jobs := make([]*Job, len(priorities))
+	for i, priority := range priorities {
+		jobs[i] = createTestJob("bench", priority)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := queue.Enqueue(jobs[i%len(jobs)]); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := queue.TryDequeue(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMemoryQueue_Parallel(b *testing.B) {
+	queue := NewMemoryQueue(0)
+	job := createTestJob("bench", models.PriorityNormal)
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := queue.Enqueue(job); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := queue.Dequeue(context.Background()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// Helper functions
+
+func createTestJob(id string, priority models.Priority) *Job {
+	return &Job{
+		ID: id,
+		Request: &models.NotificationRequest{
+			Type:      models.NotificationTypeEmail,
+			Priority:  priority,
+			Recipient: "test@example.com",
+			Body:      "Queued notification",
+		},
+	}
+}
@@ -0,0 +1,95 @@
+package queue
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// Handler processes a dequeued job
+type Handler func(ctx context.Context, job *Job) error
+
+// WorkerPool runs a fixed number of workers that consume jobs from a queue
+type WorkerPool struct {
+	queue   *MemoryQueue
+	handler Handler
+	workers int
+	logger  interfaces.Logger
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	running bool
+}
+
+// NewWorkerPool creates a worker pool. At least one worker is always started.
+func NewWorkerPool(queue *MemoryQueue, workers int, handler Handler, logger interfaces.Logger) *WorkerPool {
+	if workers < 1 {
+		workers = 1
+	}
+
+	return &WorkerPool{
+		queue:   queue,
+		handler: handler,
+		workers: workers,
+		logger:  logger,
+	}
+}
+
+// Start starts the workers. Calling Start on a running pool has no effect.
+func (p *WorkerPool) Start(ctx context.Context) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.running {
+		return
+	}
+
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.running = true
+
+	for i := 0; i < p.workers; i++ {
+		p.wg.Add(1)
+		go p.run(ctx, i)
+	}
+
+	p.logger.Infof("Started %d queue workers", p.workers)
+}
+
+// Stop stops the workers and waits for in-flight jobs to finish
+func (p *WorkerPool) Stop() {
+	p.mu.Lock()
+	if !p.running {
+		p.mu.Unlock()
+		return
+	}
+	p.cancel()
+	p.running = false
+	p.mu.Unlock()
+
+	p.wg.Wait()
+	p.logger.Infof("Stopped queue workers")
+}
+
+// run consumes jobs until the context is cancelled
+func (p *WorkerPool) run(ctx context.Context, worker int) {
+	defer p.wg.Done()
+
+	for {
+		job, err := p.queue.Dequeue(ctx)
+		if err != nil {
+			// Dequeue only fails once the pool is stopping
+			return
+		}
+
+		job.Attempts++
+		if err := p.handler(ctx, job); err != nil {
+			if job.Request != nil && job.Request.CorrelationID != "" {
+				p.logger.Errorf("Worker %d failed to process job %s (correlation_id=%s): %v", worker, job.ID, job.Request.CorrelationID, err)
+				continue
+			}
+			p.logger.Errorf("Worker %d failed to process job %s: %v", worker, job.ID, err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+)
+
+func TestWorkerPool_ProcessesJobs(t *testing.T) {
+	queue := NewMemoryQueue(0)
+
+	var mu sync.Mutex
+	processed := make(map[string]int)
+	done := make(chan struct{})
+
+	pool := NewWorkerPool(queue, 3, func(ctx context.Context, job *Job) error {
+		mu.Lock()
+		defer mu.Unlock()
+		processed[job.ID] = job.Attempts
+		if len(processed) == 10 {
+			close(done)
+		}
+		if job.ID == "job-0" {
+			return fmt.Errorf("handler failure")
+		}
+		return nil
+	}, utils.NewSimpleLogger("info"))
+
+	pool.Start(context.Background())
+	pool.Start(context.Background()) // no effect while running
+	defer pool.Stop()
+
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, queue.Enqueue(createTestJob(fmt.Sprintf("job-%d", i), models.PriorityNormal)))
+	}
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("workers did not process all jobs")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, attempts := range processed {
+		assert.Equal(t, 1, attempts)
+	}
+	assert.Equal(t, 0, queue.Len())
+}
+
+func TestWorkerPool_Stop(t *testing.T) {
+	queue := NewMemoryQueue(0)
+	pool := NewWorkerPool(queue, 0, func(ctx context.Context, job *Job) error {
+		return nil
+	}, utils.NewSimpleLogger("info"))
+
+	assert.Equal(t, 1, pool.workers)
+
+	pool.Start(context.Background())
+	pool.Stop()
+	pool.Stop() // no effect once stopped
+
+	// Jobs stay queued once the pool is stopped
+	assert.NoError(t, queue.Enqueue(createTestJob("after-stop", models.PriorityNormal)))
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, 1, queue.Len())
+}
+
+func BenchmarkWorkerPool(b *testing.B) {
+	queue := NewMemoryQueue(0)
+
+	var wg sync.WaitGroup
+	pool := NewWorkerPool(queue, 8, func(ctx context.Context, job *Job) error {
+		wg.Done()
+		return nil
+	}, utils.NewSimpleLogger("error"))
+	pool.Start(context.Background())
+	defer pool.Stop()
+
+	job := createTestJob("bench", models.PriorityNormal)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		wg.Add(1)
+		if err := queue.Enqueue(job); err != nil {
+			b.Fatal(err)
+		}
+	}
+	wg.Wait()
+}
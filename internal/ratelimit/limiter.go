@@ -0,0 +1,184 @@
+// Package ratelimit limits sends per provider and per tenant with token
+// buckets. With a shared store such as Redis the limits hold across every
+// instance of the service rather than per process.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pipeline"
+	"github.com/nareshkumar-microsoft/notificationService/internal/rbac"
+	"github.com/nareshkumar-microsoft/notificationService/internal/redis"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// MiddlewareName is the name the limiter's middleware is registered under
+const MiddlewareName = "ratelimit"
+
+// MetadataTenantID is the request metadata key naming the tenant a send is
+// for. The API sets it from the caller's identity; the limiter itself reads
+// the tenant from the identity, since metadata can be set by any caller.
+const MetadataTenantID = "tenant_id"
+
+// Store types accepted in config.RateLimitConfig
+const (
+	StoreMemory = "memory"
+	StoreRedis  = "redis"
+)
+
+// anyTenant keys the limit of tenants without a limit of their own, and of
+// sends made without a tenant
+const anyTenant = "*"
+
+// NewStore creates the store the configuration selects. The Redis store
+// falls back to local buckets while Redis is unavailable.
+func NewStore(cfg config.RateLimitConfig, logger interfaces.Logger) (interfaces.RateLimitStore, error) {
+	switch cfg.Store {
+	case "", StoreMemory:
+		return NewLocalStore(), nil
+	case StoreRedis:
+		client, err := redis.NewClient(cfg.RedisURL, cfg.RedisPassword, cfg.RedisDB, cfg.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		return NewFallbackStore(NewRedisStore(client, cfg.KeyPrefix), NewLocalStore(), cfg.FallbackCoolOff, logger), nil
+	default:
+		return nil, errors.NewValidationError("store", fmt.Sprintf("unknown rate limit store: %q", cfg.Store))
+	}
+}
+
+// Limiter enforces the configured provider and tenant limits on sends. It
+// is safe for concurrent use.
+type Limiter struct {
+	providers map[string]config.RateLimit
+	tenants   map[string]config.RateLimit
+	store     interfaces.RateLimitStore
+	logger    interfaces.Logger
+}
+
+// NewLimiter creates a limiter taking tokens from the store
+func NewLimiter(cfg config.RateLimitConfig, store interfaces.RateLimitStore, logger interfaces.Logger) (*Limiter, error) {
+	l := &Limiter{
+		providers: make(map[string]config.RateLimit, len(cfg.Providers)),
+		tenants:   make(map[string]config.RateLimit, len(cfg.Tenants)),
+		store:     store,
+		logger:    logger,
+	}
+
+	for name, limit := range cfg.Providers {
+		normalized, err := NormalizeLimit("providers", name, limit)
+		if err != nil {
+			return nil, err
+		}
+		l.providers[name] = normalized
+	}
+	for tenant, limit := range cfg.Tenants {
+		normalized, err := NormalizeLimit("tenants", tenant, limit)
+		if err != nil {
+			return nil, err
+		}
+		l.tenants[tenant] = normalized
+	}
+
+	return l, nil
+}
+
+// Middleware returns the send middleware enforcing the tenant limits.
+// Register it at pipeline.StageRateLimit under MiddlewareName. The tenant
+// is the one of the authenticated identity in the context; sends without
+// one share the "*" limit's bucket. Provider limits are taken with
+// TakeProvider once the provider is resolved, after the tenant's, so a
+// tenant over its limit does not use up the provider's tokens.
+func (l *Limiter) Middleware() pipeline.Middleware {
+	return func(next pipeline.Handler) pipeline.Handler {
+		return func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+			if err := l.takeTenant(ctx); err != nil {
+				return nil, err
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// TakeProvider takes a token from the bucket of the named provider, the one
+// routing or a rollout chose to send through. Providers without a limit
+// are not limited.
+func (l *Limiter) TakeProvider(ctx context.Context, provider string) error {
+	limit, exists := l.providers[provider]
+	if provider == "" || !exists {
+		return nil
+	}
+	return l.take(ctx, "provider:"+provider, "provider "+provider, limit)
+}
+
+// takeTenant takes a token for the tenant of the context's identity
+func (l *Limiter) takeTenant(ctx context.Context) error {
+	tenant := ""
+	if identity, ok := rbac.IdentityFrom(ctx); ok {
+		tenant = identity.TenantID
+	}
+
+	if tenant == "" {
+		limit, exists := l.tenants[anyTenant]
+		if !exists {
+			return nil
+		}
+		return l.take(ctx, "tenant:"+anyTenant, "sends without a tenant", limit)
+	}
+
+	limit, exists := l.tenants[tenant]
+	if !exists {
+		limit, exists = l.tenants[anyTenant]
+	}
+	if !exists {
+		return nil
+	}
+	return l.take(ctx, "tenant:"+tenant, "tenant "+tenant, limit)
+}
+
+// take takes a token for a send
+func (l *Limiter) take(ctx context.Context, key, subject string, limit config.RateLimit) error {
+	return Take(ctx, l.store, key, subject, limit, l.logger)
+}
+
+// Take takes a token for a send from a store's bucket, returning
+// ErrorCodeRateLimited with a retry_after when the bucket is empty. When the
+// store fails the send is allowed, since a broken store must not stop all
+// sending.
+func Take(ctx context.Context, store interfaces.RateLimitStore, key, subject string, limit config.RateLimit, logger interfaces.Logger) error {
+	allowed, wait, err := store.Take(ctx, key, limit.PerSecond, limit.Burst)
+	if err != nil {
+		logger.Errorf("Rate limit of %s not checked: %v", subject, err)
+		return nil
+	}
+	if allowed {
+		return nil
+	}
+
+	retryAfter := int(math.Ceil(wait.Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	return errors.NewNotificationError(errors.ErrorCodeRateLimited, fmt.Sprintf("rate limit of %s exceeded (%g per second)", subject, limit.PerSecond)).
+		WithMetadata("retry_after", strconv.Itoa(retryAfter))
+}
+
+// NormalizeLimit validates a limit and defaults its burst
+func NormalizeLimit(field, name string, limit config.RateLimit) (config.RateLimit, error) {
+	if limit.PerSecond <= 0 {
+		return limit, errors.NewValidationError(field, fmt.Sprintf("rate of %s must be positive", name))
+	}
+	if limit.Burst < 0 {
+		return limit, errors.NewValidationError(field, fmt.Sprintf("burst of %s cannot be negative", name))
+	}
+	if limit.Burst == 0 {
+		limit.Burst = int(math.Ceil(limit.PerSecond))
+	}
+	return limit, nil
+}
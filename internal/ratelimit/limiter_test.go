@@ -0,0 +1,160 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pipeline"
+	"github.com/nareshkumar-microsoft/notificationService/internal/rbac"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestNewLimiter_Validation(t *testing.T) {
+	logger := utils.NewSimpleLogger("error")
+
+	_, err := NewLimiter(config.RateLimitConfig{Providers: map[string]config.RateLimit{"twilio": {PerSecond: 0}}}, NewLocalStore(), logger)
+	assert.Error(t, err)
+
+	_, err = NewLimiter(config.RateLimitConfig{Tenants: map[string]config.RateLimit{"acme": {PerSecond: 1, Burst: -1}}}, NewLocalStore(), logger)
+	assert.Error(t, err)
+
+	limiter, err := NewLimiter(config.RateLimitConfig{Providers: map[string]config.RateLimit{"twilio": {PerSecond: 2.5}}}, NewLocalStore(), logger)
+	require.NoError(t, err)
+	assert.Equal(t, 3, limiter.providers["twilio"].Burst)
+}
+
+func TestNewStore(t *testing.T) {
+	logger := utils.NewSimpleLogger("error")
+
+	store, err := NewStore(config.RateLimitConfig{}, logger)
+	require.NoError(t, err)
+	assert.IsType(t, &LocalStore{}, store)
+
+	store, err = NewStore(config.RateLimitConfig{Store: StoreRedis, RedisURL: "cache:6379"}, logger)
+	require.NoError(t, err)
+	assert.IsType(t, &FallbackStore{}, store)
+
+	_, err = NewStore(config.RateLimitConfig{Store: "etcd"}, logger)
+	assert.Error(t, err)
+}
+
+func TestLimiter_ProviderLimit(t *testing.T) {
+	limiter := createTestLimiter(t, config.RateLimitConfig{
+		Providers: map[string]config.RateLimit{"twilio": {PerSecond: 0.001, Burst: 2}},
+	})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, limiter.TakeProvider(ctx, "twilio"))
+	}
+	err := limiter.TakeProvider(ctx, "twilio")
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeRateLimited, notifErr.Code)
+	assert.Contains(t, notifErr.Message, "provider twilio")
+	assert.NotEmpty(t, notifErr.Metadata["retry_after"])
+
+	// Providers without a limit are not limited
+	assert.NoError(t, limiter.TakeProvider(ctx, "vonage"))
+	assert.NoError(t, limiter.TakeProvider(ctx, ""))
+}
+
+func TestLimiter_TenantLimit(t *testing.T) {
+	limiter := createTestLimiter(t, config.RateLimitConfig{
+		Tenants: map[string]config.RateLimit{
+			"acme": {PerSecond: 0.001, Burst: 2},
+			"*":    {PerSecond: 0.001, Burst: 1},
+		},
+	})
+	handler := limiter.Middleware()(createTestHandler())
+
+	for i := 0; i < 2; i++ {
+		_, err := handler(createTestContext("acme"), createTestRequest(models.NotificationTypeSMS, ""))
+		require.NoError(t, err)
+	}
+	_, err := handler(createTestContext("acme"), createTestRequest(models.NotificationTypeSMS, ""))
+	assert.ErrorContains(t, err, "tenant acme")
+
+	// Other tenants get the default limit each
+	_, err = handler(createTestContext("globex"), createTestRequest(models.NotificationTypeSMS, ""))
+	require.NoError(t, err)
+	_, err = handler(createTestContext("globex"), createTestRequest(models.NotificationTypeSMS, ""))
+	assert.ErrorContains(t, err, "tenant globex")
+	_, err = handler(createTestContext("initech"), createTestRequest(models.NotificationTypeSMS, ""))
+	assert.NoError(t, err)
+}
+
+func TestLimiter_TenantFromIdentity(t *testing.T) {
+	limiter := createTestLimiter(t, config.RateLimitConfig{
+		Tenants: map[string]config.RateLimit{
+			"acme": {PerSecond: 0.001, Burst: 1},
+			"vip":  {PerSecond: 1000, Burst: 1000},
+			"*":    {PerSecond: 0.001, Burst: 1},
+		},
+	})
+	handler := limiter.Middleware()(createTestHandler())
+
+	// Metadata naming another tenant does not move a send to its bucket
+	_, err := handler(createTestContext("acme"), createTestRequest(models.NotificationTypeSMS, "vip"))
+	require.NoError(t, err)
+	_, err = handler(createTestContext("acme"), createTestRequest(models.NotificationTypeSMS, "vip"))
+	assert.ErrorContains(t, err, "tenant acme")
+
+	// Sends without a tenant share the default limit, whatever their metadata
+	_, err = handler(context.Background(), createTestRequest(models.NotificationTypeSMS, "vip"))
+	require.NoError(t, err)
+	_, err = handler(context.Background(), createTestRequest(models.NotificationTypeSMS, "initech"))
+	assert.ErrorContains(t, err, "without a tenant")
+	_, err = handler(createTestContext(""), createTestRequest(models.NotificationTypeSMS, ""))
+	assert.ErrorContains(t, err, "without a tenant")
+}
+
+func TestLimiter_FailsOpen(t *testing.T) {
+	limiter, err := NewLimiter(config.RateLimitConfig{
+		Tenants: map[string]config.RateLimit{"*": {PerSecond: 0.001, Burst: 1}},
+	}, &failingStore{err: errors.NewInternalError("failed to connect to Redis", nil)}, utils.NewSimpleLogger("error"))
+	require.NoError(t, err)
+	handler := limiter.Middleware()(createTestHandler())
+
+	for i := 0; i < 3; i++ {
+		_, err := handler(createTestContext("acme"), createTestRequest(models.NotificationTypeSMS, ""))
+		assert.NoError(t, err)
+	}
+}
+
+// Helper functions
+
+func createTestLimiter(t *testing.T, cfg config.RateLimitConfig) *Limiter {
+	limiter, err := NewLimiter(cfg, NewLocalStore(), utils.NewSimpleLogger("error"))
+	require.NoError(t, err)
+	return limiter
+}
+
+func createTestHandler() pipeline.Handler {
+	return func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+		return &models.NotificationResponse{Status: models.StatusSent}, nil
+	}
+}
+
+func createTestContext(tenant string) context.Context {
+	return rbac.WithIdentity(context.Background(), &rbac.Identity{Subject: "svc", TenantID: tenant})
+}
+
+func createTestRequest(notificationType models.NotificationType, tenant string) *models.NotificationRequest {
+	request := &models.NotificationRequest{
+		Type:      notificationType,
+		Priority:  models.PriorityNormal,
+		Recipient: "+14155552671",
+		Body:      "Your code is 1234",
+	}
+	if tenant != "" {
+		request.Metadata = map[string]string{MetadataTenantID: tenant}
+	}
+	return request
+}
@@ -0,0 +1,180 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/redis"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// pruneInterval is how often local buckets that have refilled are dropped
+const pruneInterval = time.Minute
+
+// bucket is the state of a local token bucket
+type bucket struct {
+	tokens float64
+	at     time.Time
+	full   time.Time // when the bucket will have refilled
+}
+
+// LocalStore implements the RateLimitStore interface with token buckets in
+// memory, so its limits hold per process
+type LocalStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// NewLocalStore creates an empty local store
+func NewLocalStore() *LocalStore {
+	return &LocalStore{
+		buckets:   make(map[string]*bucket),
+		lastPrune: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Take implements the RateLimitStore interface
+func (s *LocalStore) Take(_ context.Context, key string, perSecond float64, burst int) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastPrune) >= pruneInterval {
+		for existing, b := range s.buckets {
+			if !now.Before(b.full) {
+				delete(s.buckets, existing)
+			}
+		}
+		s.lastPrune = now
+	}
+
+	b, exists := s.buckets[key]
+	if !exists {
+		b = &bucket{tokens: float64(burst), at: now}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.at).Seconds()*perSecond)
+	b.at = now
+
+	if b.tokens < 1 {
+		return false, secondsToDuration((1 - b.tokens) / perSecond), nil
+	}
+	b.tokens--
+	b.full = now.Add(secondsToDuration((float64(burst) - b.tokens) / perSecond))
+	return true, 0, nil
+}
+
+// redisTakeScript takes a token from a bucket kept in a hash, refilled from
+// the server's clock so instances with skewed clocks agree. It returns
+// whether a token was taken and, when not, the milliseconds until one is.
+const redisTakeScript = `local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) + tonumber(time[2]) / 1000000
+local state = redis.call("HMGET", KEYS[1], "tokens", "at")
+local tokens = tonumber(state[1]) or burst
+local at = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - at) * rate)
+local allowed, wait = 0, 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  wait = math.ceil((1 - tokens) / rate * 1000)
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "at", tostring(now))
+redis.call("PEXPIRE", KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1000)
+return {allowed, wait}`
+
+// RedisStore implements the RateLimitStore interface with token buckets in
+// Redis, so its limits hold across every instance using the server. Idle
+// buckets expire once they have refilled.
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore creates a Redis store whose keys start with the prefix
+func NewRedisStore(client *redis.Client, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// Take implements the RateLimitStore interface
+func (s *RedisStore) Take(ctx context.Context, key string, perSecond float64, burst int) (bool, time.Duration, error) {
+	reply, err := s.client.Eval(ctx, redisTakeScript, []string{s.prefix + key},
+		strconv.FormatFloat(perSecond, 'f', -1, 64), strconv.Itoa(burst))
+	if err != nil {
+		return false, 0, err
+	}
+
+	result, ok := reply.([]interface{})
+	if !ok || len(result) != 2 {
+		return false, 0, errors.NewInternalError(fmt.Sprintf("unexpected rate limit reply: %v", reply), nil)
+	}
+	allowed, _ := result[0].(int64)
+	wait, _ := result[1].(int64)
+	return allowed == 1, time.Duration(wait) * time.Millisecond, nil
+}
+
+// FallbackStore takes tokens from a shared store and falls back to local
+// buckets when it fails, so sends are still limited, per process, while
+// the shared store is unavailable. After a failure the shared store is
+// tried again once the cool-off has passed.
+type FallbackStore struct {
+	primary interfaces.RateLimitStore
+	local   interfaces.RateLimitStore
+	coolOff time.Duration
+	logger  interfaces.Logger
+
+	mu        sync.Mutex
+	downUntil time.Time
+	now       func() time.Time
+}
+
+// NewFallbackStore creates a store using primary and falling back to local
+func NewFallbackStore(primary, local interfaces.RateLimitStore, coolOff time.Duration, logger interfaces.Logger) *FallbackStore {
+	return &FallbackStore{
+		primary: primary,
+		local:   local,
+		coolOff: coolOff,
+		logger:  logger,
+		now:     time.Now,
+	}
+}
+
+// Take implements the RateLimitStore interface
+func (s *FallbackStore) Take(ctx context.Context, key string, perSecond float64, burst int) (bool, time.Duration, error) {
+	if !s.Degraded() {
+		allowed, wait, err := s.primary.Take(ctx, key, perSecond, burst)
+		if err == nil {
+			return allowed, wait, nil
+		}
+
+		s.mu.Lock()
+		s.downUntil = s.now().Add(s.coolOff)
+		s.mu.Unlock()
+		s.logger.Warnf("Rate limit store unavailable, using local limits for %s: %v", s.coolOff, err)
+	}
+	return s.local.Take(ctx, key, perSecond, burst)
+}
+
+// Degraded reports whether limits currently fall back to local buckets
+func (s *FallbackStore) Degraded() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.now().Before(s.downUntil)
+}
+
+// secondsToDuration converts seconds to a duration, rounding up to a millisecond
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(seconds*1000)) * time.Millisecond
+}
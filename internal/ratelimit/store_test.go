@@ -0,0 +1,188 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/redis"
+	"github.com/nareshkumar-microsoft/notificationService/internal/redis/redistest"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestLocalStore_Take(t *testing.T) {
+	store := NewLocalStore()
+	clock := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return clock }
+	ctx := context.Background()
+
+	// A full bucket allows a burst
+	for i := 0; i < 3; i++ {
+		allowed, _, err := store.Take(ctx, "provider:twilio", 2, 3)
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	}
+	allowed, wait, err := store.Take(ctx, "provider:twilio", 2, 3)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	// Other keys have buckets of their own
+	allowed, _, _ = store.Take(ctx, "provider:sendgrid", 2, 3)
+	assert.True(t, allowed)
+
+	// Tokens refill at the rate, up to the burst
+	clock = clock.Add(500 * time.Millisecond)
+	allowed, _, _ = store.Take(ctx, "provider:twilio", 2, 3)
+	assert.True(t, allowed)
+	allowed, _, _ = store.Take(ctx, "provider:twilio", 2, 3)
+	assert.False(t, allowed)
+
+	clock = clock.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		allowed, _, _ = store.Take(ctx, "provider:twilio", 2, 3)
+		assert.True(t, allowed)
+	}
+	allowed, _, _ = store.Take(ctx, "provider:twilio", 2, 3)
+	assert.False(t, allowed)
+}
+
+func TestLocalStore_PrunesRefilledBuckets(t *testing.T) {
+	store := NewLocalStore()
+	clock := time.Now()
+	store.now = func() time.Time { return clock }
+	ctx := context.Background()
+
+	store.Take(ctx, "tenant:acme", 1, 1)
+	store.Take(ctx, "tenant:globex", 0.001, 1)
+	clock = clock.Add(2 * pruneInterval)
+	store.Take(ctx, "tenant:initech", 1, 1)
+
+	assert.Len(t, store.buckets, 2)
+	assert.Contains(t, store.buckets, "tenant:globex")
+}
+
+func TestRedisStore_Take(t *testing.T) {
+	server := startTestRedis(t)
+	client, err := redis.NewClient(server.Addr, "", 0, time.Second)
+	require.NoError(t, err)
+	store := NewRedisStore(client, "test:")
+	ctx := context.Background()
+
+	// Two instances share the bucket
+	other := NewRedisStore(client, "test:")
+	allowed, _, err := store.Take(ctx, "provider:twilio", 1, 2)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	allowed, _, err = other.Take(ctx, "provider:twilio", 1, 2)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	allowed, wait, err := store.Take(ctx, "provider:twilio", 1, 2)
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Greater(t, wait, 900*time.Millisecond)
+	assert.LessOrEqual(t, wait, time.Second)
+}
+
+func TestRedisStore_ReusesConnections(t *testing.T) {
+	server := startTestRedis(t)
+	client, err := redis.NewClient(server.Addr, "", 0, time.Second)
+	require.NoError(t, err)
+	store := NewRedisStore(client, "test:")
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		_, _, err := store.Take(ctx, "provider:twilio", 100, 100)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, server.Connections())
+	assert.Equal(t, 5, server.Evals())
+
+	// After Redis drops its script cache the script is sent in full again
+	server.FlushScripts()
+	allowed, _, err := store.Take(ctx, "provider:twilio", 100, 100)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Equal(t, 6, server.Evals())
+}
+
+func TestFallbackStore(t *testing.T) {
+	primary := &failingStore{}
+	store := NewFallbackStore(primary, NewLocalStore(), time.Minute, utils.NewSimpleLogger("error"))
+	clock := time.Now()
+	store.now = func() time.Time { return clock }
+	ctx := context.Background()
+
+	allowed, _, err := store.Take(ctx, "provider:twilio", 1, 1)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.False(t, store.Degraded())
+
+	// While the primary fails, local buckets limit sends
+	primary.err = errors.NewInternalError("failed to connect to Redis", nil)
+	allowed, _, err = store.Take(ctx, "provider:twilio", 1, 1)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.True(t, store.Degraded())
+	allowed, _, _ = store.Take(ctx, "provider:twilio", 1, 1)
+	assert.False(t, allowed)
+	assert.Equal(t, 2, primary.calls) // not tried again during the cool-off
+
+	// After the cool-off the primary is tried again
+	primary.err = nil
+	clock = clock.Add(time.Minute)
+	assert.False(t, store.Degraded())
+	allowed, _, _ = store.Take(ctx, "provider:twilio", 1, 1)
+	assert.True(t, allowed)
+	assert.Equal(t, 3, primary.calls)
+}
+
+// Helper functions
+
+// failingStore is a store that allows every send until err is set
+type failingStore struct {
+	err   error
+	calls int
+}
+
+func (s *failingStore) Take(ctx context.Context, key string, perSecond float64, burst int) (bool, time.Duration, error) {
+	s.calls++
+	return s.err == nil, 0, s.err
+}
+
+// startTestRedis starts a Redis server emulating the token bucket script
+func startTestRedis(t *testing.T) *redistest.Server {
+	type testBucket struct {
+		tokens float64
+		at     time.Time
+	}
+	buckets := make(map[string]*testBucket)
+
+	server := redistest.NewServer(t, "")
+	server.HandleScript(redisTakeScript, func(keys, args []string) interface{} {
+		rate, _ := strconv.ParseFloat(args[0], 64)
+		burst, _ := strconv.ParseFloat(args[1], 64)
+		now := time.Now()
+
+		b, exists := buckets[keys[0]]
+		if !exists {
+			b = &testBucket{tokens: burst, at: now}
+			buckets[keys[0]] = b
+		}
+		b.tokens = math.Min(burst, b.tokens+now.Sub(b.at).Seconds()*rate)
+		b.at = now
+		if b.tokens >= 1 {
+			b.tokens--
+			return []interface{}{int64(1), int64(0)}
+		}
+		return []interface{}{int64(0), int64(math.Ceil((1 - b.tokens) / rate * 1000))}
+	})
+	return server
+}
@@ -0,0 +1,85 @@
+package rbac
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"strings"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// certificateIdentity maps client certificates whose SAN matches a pattern
+type certificateIdentity struct {
+	pattern string
+	prefix  bool // the pattern ended in *, so it matches any SAN it starts
+	tenant  string
+	roles   []Role
+}
+
+// matches reports whether a SAN matches the pattern
+func (c certificateIdentity) matches(san string) bool {
+	if c.prefix {
+		return strings.HasPrefix(san, c.pattern)
+	}
+	return san == c.pattern
+}
+
+// CertificateAuthenticator identifies callers by the client certificate of a
+// mutual TLS connection. The TLS handshake verifies the certificate against
+// the client CA; the authenticator maps its subject alternative names (SANs),
+// such as SPIFFE IDs, to a tenant and roles.
+type CertificateAuthenticator struct {
+	identities []certificateIdentity
+}
+
+// NewCertificateAuthenticator creates an authenticator for the configured
+// identities, which are tried in order
+func NewCertificateAuthenticator(identities []config.ClientIdentityConfig) (*CertificateAuthenticator, error) {
+	authenticator := &CertificateAuthenticator{identities: make([]certificateIdentity, 0, len(identities))}
+	for _, identity := range identities {
+		pattern, prefix := strings.CutSuffix(identity.SAN, "*")
+		if pattern == "" {
+			return nil, errors.NewValidationError("client_identities", fmt.Sprintf("client identity SAN %q matches every certificate", identity.SAN))
+		}
+		roles, err := ParseRoles(identity.Roles)
+		if err != nil {
+			return nil, err
+		}
+		authenticator.identities = append(authenticator.identities, certificateIdentity{
+			pattern: pattern,
+			prefix:  prefix,
+			tenant:  identity.Tenant,
+			roles:   roles,
+		})
+	}
+	return authenticator, nil
+}
+
+// Authenticate implements Authenticator
+func (a *CertificateAuthenticator) Authenticate(_ context.Context, credentials Credentials) (*Identity, error) {
+	if credentials.Certificate == nil {
+		return nil, nil
+	}
+
+	sans := certificateSANs(credentials.Certificate)
+	for _, identity := range a.identities {
+		for _, san := range sans {
+			if identity.matches(san) {
+				return &Identity{Subject: san, Method: "mtls", Roles: identity.roles, TenantID: identity.tenant}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no client identity matches certificate %s with SANs %s", credentials.Certificate.Subject, strings.Join(sans, ", "))
+}
+
+// certificateSANs returns a certificate's URI, DNS and email SANs
+func certificateSANs(certificate *x509.Certificate) []string {
+	sans := make([]string, 0, len(certificate.URIs)+len(certificate.DNSNames)+len(certificate.EmailAddresses))
+	for _, uri := range certificate.URIs {
+		sans = append(sans, uri.String())
+	}
+	sans = append(sans, certificate.DNSNames...)
+	return append(sans, certificate.EmailAddresses...)
+}
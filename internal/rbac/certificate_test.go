@@ -0,0 +1,76 @@
+package rbac
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+)
+
+func TestCertificateAuthenticator(t *testing.T) {
+	authenticator, err := NewCertificateAuthenticator([]config.ClientIdentityConfig{
+		{SAN: "spiffe://mesh.local/ns/acme/sa/billing", Tenant: "acme", Roles: []string{"sender", "viewer"}},
+		{SAN: "spiffe://mesh.local/ns/acme/*", Tenant: "acme", Roles: []string{"viewer"}},
+		{SAN: "ops.internal", Roles: []string{"admin"}},
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		cert     *x509.Certificate
+		subject  string
+		tenantID string
+		roles    []Role
+	}{
+		{"exact URI", createTestCertificate("spiffe://mesh.local/ns/acme/sa/billing"), "spiffe://mesh.local/ns/acme/sa/billing", "acme", []Role{RoleSender, RoleViewer}},
+		{"URI prefix", createTestCertificate("spiffe://mesh.local/ns/acme/sa/reports"), "spiffe://mesh.local/ns/acme/sa/reports", "acme", []Role{RoleViewer}},
+		{"DNS name", createTestCertificate("", "ops.internal"), "ops.internal", "", []Role{RoleAdmin}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := authenticator.Authenticate(ctx, Credentials{Certificate: tt.cert})
+			require.NoError(t, err)
+			require.NotNil(t, identity)
+			assert.Equal(t, tt.subject, identity.Subject)
+			assert.Equal(t, "mtls", identity.Method)
+			assert.Equal(t, tt.tenantID, identity.TenantID)
+			assert.Equal(t, tt.roles, identity.Roles)
+		})
+	}
+
+	// Other tenants' certificates match nothing
+	_, err = authenticator.Authenticate(ctx, Credentials{Certificate: createTestCertificate("spiffe://mesh.local/ns/globex/sa/billing")})
+	assert.Error(t, err)
+
+	// Requests without a certificate are left to other authenticators
+	identity, err := authenticator.Authenticate(ctx, Credentials{APIKey: "key"})
+	assert.NoError(t, err)
+	assert.Nil(t, identity)
+}
+
+func TestNewCertificateAuthenticator_Invalid(t *testing.T) {
+	_, err := NewCertificateAuthenticator([]config.ClientIdentityConfig{{SAN: "*", Roles: []string{"admin"}}})
+	assert.Error(t, err)
+
+	_, err = NewCertificateAuthenticator([]config.ClientIdentityConfig{{SAN: "ops.internal", Roles: []string{"root"}}})
+	assert.Error(t, err)
+}
+
+// Helper functions
+
+func createTestCertificate(uri string, dnsNames ...string) *x509.Certificate {
+	certificate := &x509.Certificate{Subject: pkix.Name{CommonName: "test"}, DNSNames: dnsNames}
+	if uri != "" {
+		parsed, _ := url.Parse(uri)
+		certificate.URIs = []*url.URL{parsed}
+	}
+	return certificate
+}
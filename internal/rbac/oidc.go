@@ -0,0 +1,384 @@
+package rbac
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/httpclient"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// Limits of token verification
+const (
+	clockSkew          = time.Minute   // allowed between the issuer's clock and ours
+	keysRefreshBackoff = time.Minute   // between fetches, failed or not, for an unknown key ID
+	keysTTL            = 6 * time.Hour // signing keys are refetched after this
+	keysFetchTimeout   = 10 * time.Second
+	maxKeysResponse    = 1 << 20
+)
+
+// OIDCAuthenticator identifies callers by OIDC ID or access tokens: JWTs
+// signed with RS256 by the configured issuer, such as a corporate SSO.
+// Roles come from a token claim, either by name or through the role
+// mapping; values that match neither are ignored. The tenant, when the
+// token names one, comes from another claim.
+type OIDCAuthenticator struct {
+	issuer      string
+	audience    string
+	rolesClaim  string
+	tenantClaim string
+	roleMapping map[string][]Role
+	client      *http.Client
+
+	mu          sync.Mutex
+	jwksURL     string                    // discovered from the issuer when not configured
+	keys        map[string]*rsa.PublicKey // by key ID
+	fetchedAt   time.Time                 // of the last successful fetch
+	attemptedAt time.Time                 // of the last fetch
+	fetchErr    error                     // of the last fetch
+	fetching    chan struct{}             // closed when the fetch in progress ends
+	now         func() time.Time
+}
+
+// NewOIDCAuthenticator creates an authenticator for the configured issuer.
+// The signing keys are fetched from the JWKS URL or, when none is
+// configured, from the jwks_uri of the issuer's discovery document.
+func NewOIDCAuthenticator(cfg config.AuthConfig) (*OIDCAuthenticator, error) {
+	issuer, err := url.Parse(cfg.OIDCIssuer)
+	if err != nil || issuer.Host == "" || (issuer.Scheme != "https" && issuer.Scheme != "http") {
+		return nil, errors.NewValidationError("oidc_issuer", "OIDC issuer must be an http or https URL")
+	}
+	if cfg.OIDCJWKSURL != "" {
+		parsed, err := url.Parse(cfg.OIDCJWKSURL)
+		if err != nil || parsed.Host == "" || (parsed.Scheme != "https" && parsed.Scheme != "http") {
+			return nil, errors.NewValidationError("oidc_jwks_url", "OIDC JWKS URL must be an http or https URL")
+		}
+	}
+	// Without an audience, tokens the issuer minted for any other
+	// application would be accepted, with their roles
+	if cfg.OIDCAudience == "" {
+		return nil, errors.NewValidationError("oidc_audience", "OIDC audience is required")
+	}
+
+	roleMapping := make(map[string][]Role, len(cfg.OIDCRoleMapping))
+	for value, names := range cfg.OIDCRoleMapping {
+		roles, err := ParseRoles(names)
+		if err != nil {
+			return nil, err
+		}
+		roleMapping[value] = roles
+	}
+
+	rolesClaim := cfg.OIDCRolesClaim
+	if rolesClaim == "" {
+		rolesClaim = "roles"
+	}
+
+	return &OIDCAuthenticator{
+		issuer:      cfg.OIDCIssuer,
+		audience:    cfg.OIDCAudience,
+		rolesClaim:  rolesClaim,
+		tenantClaim: cfg.OIDCTenantClaim,
+		roleMapping: roleMapping,
+		client:      httpclient.Shared.Client(config.HTTPClientConfig{}, keysFetchTimeout),
+		jwksURL:     cfg.OIDCJWKSURL,
+		keys:        make(map[string]*rsa.PublicKey),
+		now:         time.Now,
+	}, nil
+}
+
+// tokenHeader is the header of a JWT
+type tokenHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// Authenticate implements Authenticator
+func (a *OIDCAuthenticator) Authenticate(ctx context.Context, credentials Credentials) (*Identity, error) {
+	if credentials.BearerToken == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(credentials.BearerToken, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("bearer token is not a JWT")
+	}
+	var header tokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid token header: %w", err)
+	}
+	if header.Algorithm != "RS256" {
+		return nil, fmt.Errorf("unsupported token algorithm %q", header.Algorithm)
+	}
+
+	key, err := a.key(ctx, header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid token signature encoding: %w", err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, fmt.Errorf("invalid token signature")
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("invalid token claims: %w", err)
+	}
+	return a.identity(claims)
+}
+
+// identity checks a verified token's claims and returns its identity
+func (a *OIDCAuthenticator) identity(claims map[string]any) (*Identity, error) {
+	if issuer, _ := claims["iss"].(string); issuer != a.issuer {
+		return nil, fmt.Errorf("token issued by %q, not %q", issuer, a.issuer)
+	}
+	if !contains(claimStrings(claims["aud"]), a.audience) {
+		return nil, fmt.Errorf("token is not issued for %q", a.audience)
+	}
+
+	now := a.now()
+	expires, ok := claims["exp"].(float64)
+	if !ok || now.After(time.Unix(int64(expires), 0).Add(clockSkew)) {
+		return nil, fmt.Errorf("token has expired")
+	}
+	if notBefore, ok := claims["nbf"].(float64); ok && now.Add(clockSkew).Before(time.Unix(int64(notBefore), 0)) {
+		return nil, fmt.Errorf("token is not valid yet")
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, fmt.Errorf("token has no subject")
+	}
+
+	identity := &Identity{Subject: subject, Method: "oidc"}
+	if a.tenantClaim != "" {
+		identity.TenantID, _ = claim(claims, a.tenantClaim).(string)
+	}
+
+	granted := make(map[Role]bool)
+	for _, value := range claimStrings(claim(claims, a.rolesClaim)) {
+		roles, mapped := a.roleMapping[value]
+		if !mapped {
+			roles, _ = ParseRoles([]string{value})
+		}
+		for _, role := range roles {
+			if !granted[role] {
+				granted[role] = true
+				identity.Roles = append(identity.Roles, role)
+			}
+		}
+	}
+	return identity, nil
+}
+
+// key returns the signing key with an ID, fetching the issuer's keys when
+// they are stale or the ID is unknown. Fetches are made at most once per
+// backoff, one at a time, and without the lock held, so verifying tokens
+// signed with known keys never waits on the issuer.
+func (a *OIDCAuthenticator) key(ctx context.Context, keyID string) (*rsa.PublicKey, error) {
+	for {
+		a.mu.Lock()
+		now := a.now()
+		key, exists := a.keys[keyID]
+		if exists && now.Sub(a.fetchedAt) <= keysTTL {
+			a.mu.Unlock()
+			return key, nil
+		}
+
+		if fetching := a.fetching; fetching != nil {
+			a.mu.Unlock()
+			select {
+			case <-fetching:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		if now.Sub(a.attemptedAt) < keysRefreshBackoff {
+			fetchErr := a.fetchErr
+			a.mu.Unlock()
+			switch {
+			case exists:
+				// Keep using known keys while the issuer is unreachable
+				return key, nil
+			case fetchErr != nil:
+				return nil, fetchErr
+			}
+			return nil, fmt.Errorf("unknown token signing key %q", keyID)
+		}
+
+		fetching := make(chan struct{})
+		a.fetching = fetching
+		attemptedAt := a.attemptedAt
+		a.attemptedAt = now
+		jwksURL := a.jwksURL
+		a.mu.Unlock()
+
+		keys, jwksURL, err := a.fetchKeys(ctx, jwksURL)
+
+		a.mu.Lock()
+		a.fetching = nil
+		switch {
+		case err == nil:
+			a.keys = keys
+			a.fetchedAt = now
+			a.jwksURL = jwksURL
+			a.fetchErr = nil
+		case ctx.Err() != nil:
+			// The caller gave up, which says nothing about the issuer
+			a.attemptedAt = attemptedAt
+		default:
+			a.fetchErr = err
+		}
+		a.mu.Unlock()
+		close(fetching)
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// jsonWebKey is an RSA key of a JWKS document
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+}
+
+// fetchKeys fetches the issuer's RSA signing keys from a JWKS URL,
+// discovering the URL first when it is empty. It returns the URL used.
+func (a *OIDCAuthenticator) fetchKeys(ctx context.Context, jwksURL string) (map[string]*rsa.PublicKey, string, error) {
+	if jwksURL == "" {
+		var discovery struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := a.getJSON(ctx, strings.TrimRight(a.issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+			return nil, "", fmt.Errorf("OIDC discovery failed: %w", err)
+		}
+		if discovery.Issuer != a.issuer || discovery.JWKSURI == "" {
+			return nil, "", fmt.Errorf("OIDC discovery document of %s is for issuer %q with JWKS URI %q", a.issuer, discovery.Issuer, discovery.JWKSURI)
+		}
+		jwksURL = discovery.JWKSURI
+	}
+
+	var document struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := a.getJSON(ctx, jwksURL, &document); err != nil {
+		return nil, "", fmt.Errorf("failed to fetch token signing keys: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(document.Keys))
+	for _, jwk := range document.Keys {
+		if jwk.KeyType != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		modulus, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			continue
+		}
+		exponent, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil || len(exponent) == 0 || len(exponent) > 4 {
+			continue
+		}
+		keys[jwk.KeyID] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(modulus),
+			E: int(new(big.Int).SetBytes(exponent).Int64()),
+		}
+	}
+	return keys, jwksURL, nil
+}
+
+// getJSON fetches and decodes a JSON document
+func (a *OIDCAuthenticator) getJSON(ctx context.Context, documentURL string, value any) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, documentURL, nil)
+	if err != nil {
+		return err
+	}
+	response, err := a.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", documentURL, response.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(response.Body, maxKeysResponse)).Decode(value)
+}
+
+// claim looks up a claim by name. A dotted name reaches into nested
+// objects, e.g. "realm_access.roles"; a claim whose own name has a dot is
+// found first.
+func claim(claims map[string]any, name string) any {
+	if value, exists := claims[name]; exists {
+		return value
+	}
+	head, rest, nested := strings.Cut(name, ".")
+	if !nested {
+		return nil
+	}
+	object, ok := claims[head].(map[string]any)
+	if !ok {
+		return nil
+	}
+	return claim(object, rest)
+}
+
+// decodeSegment decodes a base64url JSON segment of a JWT
+func decodeSegment(segment string, value any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, value)
+}
+
+// claimStrings reads a claim holding a string or a list of strings. A
+// string of space-separated values, as in the scope claim, is split.
+func claimStrings(claim any) []string {
+	switch value := claim.(type) {
+	case string:
+		return strings.Fields(value)
+	case []any:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if text, ok := item.(string); ok {
+				values = append(values, text)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// contains reports whether values include value
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,247 @@
+package rbac
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+)
+
+func TestOIDCAuthenticator(t *testing.T) {
+	issuer := startTestIssuer(t)
+	authenticator, err := NewOIDCAuthenticator(config.AuthConfig{
+		OIDCIssuer:   issuer.server.URL,
+		OIDCAudience: "notifications",
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	claims := issuer.claims()
+	claims["roles"] = []string{"template-editor", "unknown"}
+	identity, err := authenticator.Authenticate(ctx, Credentials{BearerToken: issuer.sign(t, "key-1", claims)})
+	require.NoError(t, err)
+	assert.Equal(t, &Identity{Subject: "user-1", Method: "oidc", Roles: []Role{RoleTemplateEditor}}, identity)
+
+	// Credentials without a bearer token are left to other authenticators
+	identity, err = authenticator.Authenticate(ctx, Credentials{APIKey: "key"})
+	assert.NoError(t, err)
+	assert.Nil(t, identity)
+	assert.Equal(t, int32(1), issuer.fetches.Load())
+
+	tests := map[string]func(claims map[string]any){
+		"expired":     func(claims map[string]any) { claims["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"not yet":     func(claims map[string]any) { claims["nbf"] = time.Now().Add(time.Hour).Unix() },
+		"issuer":      func(claims map[string]any) { claims["iss"] = "https://other.example.com" },
+		"audience":    func(claims map[string]any) { claims["aud"] = []string{"billing"} },
+		"no audience": func(claims map[string]any) { delete(claims, "aud") },
+		"subject":     func(claims map[string]any) { delete(claims, "sub") },
+		"missing exp": func(claims map[string]any) { delete(claims, "exp") },
+	}
+	for name, modify := range tests {
+		t.Run(name, func(t *testing.T) {
+			claims := issuer.claims()
+			modify(claims)
+			_, err := authenticator.Authenticate(ctx, Credentials{BearerToken: issuer.sign(t, "key-1", claims)})
+			assert.Error(t, err)
+		})
+	}
+
+	token := issuer.sign(t, "key-1", issuer.claims())
+	_, err = authenticator.Authenticate(ctx, Credentials{BearerToken: token[:len(token)-4] + "AAAA"})
+	assert.Error(t, err, "tampered signature")
+	_, err = authenticator.Authenticate(ctx, Credentials{BearerToken: "not-a-jwt"})
+	assert.Error(t, err)
+}
+
+func TestOIDCAuthenticator_ClaimMapping(t *testing.T) {
+	issuer := startTestIssuer(t)
+	authenticator, err := NewOIDCAuthenticator(config.AuthConfig{
+		OIDCIssuer:      issuer.server.URL,
+		OIDCAudience:    "notifications",
+		OIDCJWKSURL:     issuer.server.URL + "/keys",
+		OIDCRolesClaim:  "realm_access.roles",
+		OIDCTenantClaim: "org",
+		OIDCRoleMapping: map[string][]string{
+			"notify-admins": {"admin"},
+			"marketing":     {"sender", "viewer"},
+		},
+	})
+	require.NoError(t, err)
+
+	claims := issuer.claims()
+	claims["org"] = "acme"
+	claims["realm_access"] = map[string]any{"roles": []string{"marketing", "viewer", "offline_access"}}
+	identity, err := authenticator.Authenticate(context.Background(), Credentials{BearerToken: issuer.sign(t, "key-1", claims)})
+	require.NoError(t, err)
+	assert.Equal(t, "acme", identity.TenantID)
+	assert.Equal(t, []Role{RoleSender, RoleViewer}, identity.Roles)
+	assert.True(t, identity.CanAccessTenant("acme"))
+	assert.False(t, identity.CanAccessTenant("globex"))
+
+	_, err = NewOIDCAuthenticator(config.AuthConfig{
+		OIDCIssuer:      issuer.server.URL,
+		OIDCAudience:    "notifications",
+		OIDCRoleMapping: map[string][]string{"notify-admins": {"root"}},
+	})
+	assert.Error(t, err)
+
+	// An issuer's tokens for other applications are never accepted
+	_, err = NewOIDCAuthenticator(config.AuthConfig{OIDCIssuer: issuer.server.URL})
+	assert.Error(t, err)
+}
+
+func TestOIDCAuthenticator_Discovery(t *testing.T) {
+	other := startTestIssuer(t)
+	// The discovery document must be for the configured issuer
+	authenticator, err := NewOIDCAuthenticator(config.AuthConfig{OIDCIssuer: other.server.URL + "/tenant", OIDCAudience: "notifications"})
+	require.NoError(t, err)
+	_, err = authenticator.Authenticate(context.Background(), Credentials{BearerToken: other.sign(t, "key-1", other.claims())})
+	assert.Error(t, err)
+	assert.Zero(t, other.fetches.Load())
+}
+
+func TestOIDCAuthenticator_KeyRotation(t *testing.T) {
+	issuer := startTestIssuer(t)
+	authenticator, err := NewOIDCAuthenticator(config.AuthConfig{OIDCIssuer: issuer.server.URL, OIDCAudience: "notifications"})
+	require.NoError(t, err)
+	now := time.Now()
+	authenticator.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, err = authenticator.Authenticate(ctx, Credentials{BearerToken: issuer.sign(t, "key-1", issuer.claims())})
+	require.NoError(t, err)
+
+	// An unknown key ID is refetched at most once a minute
+	_, err = authenticator.Authenticate(ctx, Credentials{BearerToken: issuer.sign(t, "key-2", issuer.claims())})
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), issuer.fetches.Load())
+
+	now = now.Add(2 * time.Minute)
+	issuer.kid.Store("key-2")
+	_, err = authenticator.Authenticate(ctx, Credentials{BearerToken: issuer.sign(t, "key-2", issuer.claims())})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), issuer.fetches.Load())
+}
+
+func TestOIDCAuthenticator_RefetchBackoff(t *testing.T) {
+	issuer := startTestIssuer(t)
+	authenticator, err := NewOIDCAuthenticator(config.AuthConfig{OIDCIssuer: issuer.server.URL, OIDCAudience: "notifications"})
+	require.NoError(t, err)
+	now := time.Now()
+	authenticator.now = func() time.Time { return now }
+	ctx := context.Background()
+	known := issuer.sign(t, "key-1", issuer.claims())
+	_, err = authenticator.Authenticate(ctx, Credentials{BearerToken: known})
+	require.NoError(t, err)
+
+	// While the issuer is slow, unknown keys share one fetch and tokens
+	// signed with known keys do not wait for it
+	now = now.Add(2 * time.Minute)
+	release := make(chan struct{})
+	issuer.gate.Store(&release)
+	unknown := issuer.sign(t, "key-2", issuer.claims())
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			authenticator.Authenticate(ctx, Credentials{BearerToken: unknown})
+		}()
+	}
+	require.Eventually(t, func() bool { return issuer.fetches.Load() == 2 }, time.Second, 5*time.Millisecond)
+	_, err = authenticator.Authenticate(ctx, Credentials{BearerToken: known})
+	require.NoError(t, err)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(2), issuer.fetches.Load())
+
+	// Failed fetches are rate limited too
+	issuer.failing.Store(true)
+	now = now.Add(2 * time.Minute)
+	for i := 0; i < 3; i++ {
+		_, err = authenticator.Authenticate(ctx, Credentials{BearerToken: issuer.sign(t, "key-3", issuer.claims())})
+		assert.ErrorContains(t, err, "failed to fetch token signing keys")
+	}
+	assert.Equal(t, int32(3), issuer.fetches.Load())
+}
+
+// Helper functions
+
+type testIssuer struct {
+	server  *httptest.Server
+	key     *rsa.PrivateKey
+	kid     atomic.Value
+	fetches atomic.Int32
+	gate    atomic.Pointer[chan struct{}] // holds key fetches until closed
+	failing atomic.Bool
+}
+
+func startTestIssuer(t *testing.T) *testIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	issuer := &testIssuer{key: key}
+	issuer.kid.Store("key-1")
+
+	issuer.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/.well-known/openid-configuration") {
+			json.NewEncoder(w).Encode(map[string]string{"issuer": issuer.server.URL, "jwks_uri": issuer.server.URL + "/keys"})
+			return
+		}
+		assert.Equal(t, "/keys", r.URL.Path)
+		issuer.fetches.Add(1)
+		if gate := issuer.gate.Load(); gate != nil {
+			<-*gate
+		}
+		if issuer.failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": issuer.kid.Load().(string),
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}))
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+func (i *testIssuer) claims() map[string]any {
+	return map[string]any{
+		"iss": i.server.URL,
+		"sub": "user-1",
+		"aud": "notifications",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func (i *testIssuer) sign(t *testing.T, kid string, claims map[string]any) string {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": kid})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, i.key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
@@ -0,0 +1,254 @@
+// Package rbac controls access to the management APIs with roles. Callers
+// are identified by an API key, an OIDC token or a client certificate, each
+// identity has roles, and each role grants permissions to read or change a
+// kind of resource.
+// The HTTP server checks a route's permission before calling its handler; a
+// gRPC server would do the same in an interceptor with Authenticate and
+// Authorize.
+package rbac
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// APIKeyHeader is the request header carrying an API key
+const APIKeyHeader = "X-API-Key"
+
+// Role is a named set of permissions
+type Role string
+
+// Roles, from most to least privileged
+const (
+	RoleAdmin          Role = "admin"           // everything
+	RoleSender         Role = "sender"          // sends notifications and launches campaigns
+	RoleTemplateEditor Role = "template-editor" // edits templates and branding
+	RoleViewer         Role = "viewer"          // reads everything, changes nothing
+)
+
+// Permission allows reading or changing a kind of resource
+type Permission string
+
+// Permissions, as <resource>:<action>
+const (
+	PermissionNotificationsRead  Permission = "notifications:read"
+	PermissionNotificationsWrite Permission = "notifications:write" // send, resend, acknowledge
+	PermissionTemplatesRead      Permission = "templates:read"
+	PermissionTemplatesWrite     Permission = "templates:write" // templates, snapshots and branding
+	PermissionCampaignsRead      Permission = "campaigns:read"
+	PermissionCampaignsLaunch    Permission = "campaigns:launch"
+	PermissionSuppressionsRead   Permission = "suppressions:read"
+	PermissionSuppressionsWrite  Permission = "suppressions:write"
+	PermissionStatsRead          Permission = "stats:read"
+	PermissionSettingsRead       Permission = "settings:read"
+	PermissionSettingsWrite      Permission = "settings:write" // pauses, routing, rollouts
+)
+
+// rolePermissions are the permissions of each role
+var rolePermissions = map[Role][]Permission{
+	RoleAdmin: {
+		PermissionNotificationsRead, PermissionNotificationsWrite,
+		PermissionTemplatesRead, PermissionTemplatesWrite,
+		PermissionCampaignsRead, PermissionCampaignsLaunch,
+		PermissionSuppressionsRead, PermissionSuppressionsWrite,
+		PermissionStatsRead,
+		PermissionSettingsRead, PermissionSettingsWrite,
+	},
+	RoleSender: {
+		PermissionNotificationsRead, PermissionNotificationsWrite,
+		PermissionTemplatesRead,
+		PermissionCampaignsRead, PermissionCampaignsLaunch,
+		PermissionStatsRead,
+	},
+	RoleTemplateEditor: {
+		PermissionTemplatesRead, PermissionTemplatesWrite,
+		PermissionStatsRead,
+	},
+	RoleViewer: {
+		PermissionNotificationsRead,
+		PermissionTemplatesRead,
+		PermissionCampaignsRead,
+		PermissionSuppressionsRead,
+		PermissionStatsRead,
+		PermissionSettingsRead,
+	},
+}
+
+// Roles returns every role, sorted
+func Roles() []Role {
+	roles := make([]Role, 0, len(rolePermissions))
+	for role := range rolePermissions {
+		roles = append(roles, role)
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i] < roles[j] })
+	return roles
+}
+
+// Permissions returns the permissions of a role, or nil for an unknown role
+func Permissions(role Role) []Permission {
+	return append([]Permission(nil), rolePermissions[role]...)
+}
+
+// ParseRoles converts role names to roles, failing on unknown names
+func ParseRoles(names []string) ([]Role, error) {
+	roles := make([]Role, 0, len(names))
+	for _, name := range names {
+		role := Role(strings.ToLower(strings.TrimSpace(name)))
+		if _, known := rolePermissions[role]; !known {
+			return nil, errors.NewValidationError("roles", fmt.Sprintf("unknown role %q", name))
+		}
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+// Identity is an authenticated caller
+type Identity struct {
+	Subject  string `json:"subject"` // the API key's holder or the token's subject
+	Method   string `json:"method"`  // "api_key", "oidc" or "mtls"
+	Roles    []Role `json:"roles"`
+	TenantID string `json:"tenant_id,omitempty"` // limits the caller to one tenant; empty for every tenant
+}
+
+// Can reports whether one of the identity's roles grants a permission
+func (i *Identity) Can(permission Permission) bool {
+	for _, role := range i.Roles {
+		for _, granted := range rolePermissions[role] {
+			if granted == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CanAccessTenant reports whether the identity may act on a tenant's resources
+func (i *Identity) CanAccessTenant(tenantID string) bool {
+	return i.TenantID == "" || i.TenantID == tenantID
+}
+
+// identityKey is the context key of the authenticated identity
+type identityKey struct{}
+
+// WithIdentity returns a context carrying an identity
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFrom returns the identity a context carries, if any
+func IdentityFrom(ctx context.Context) (*Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(*Identity)
+	return identity, ok
+}
+
+// Credentials are what a caller presents to be authenticated
+type Credentials struct {
+	APIKey      string
+	BearerToken string
+	Certificate *x509.Certificate // the verified client certificate of a mutual TLS connection
+}
+
+// CredentialsFromRequest reads the API key header, the bearer token of the
+// Authorization header and the client certificate. A certificate is only
+// read when the TLS handshake verified it against the client CA.
+func CredentialsFromRequest(r *http.Request) Credentials {
+	credentials := Credentials{APIKey: r.Header.Get(APIKeyHeader)}
+	if scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " "); found && strings.EqualFold(scheme, "Bearer") {
+		credentials.BearerToken = strings.TrimSpace(token)
+	}
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		credentials.Certificate = r.TLS.VerifiedChains[0][0]
+	}
+	return credentials
+}
+
+// Authenticator identifies callers by one kind of credential
+type Authenticator interface {
+	// Authenticate returns the caller's identity, nil when the credentials
+	// do not include its kind, or an error when they are invalid
+	Authenticate(ctx context.Context, credentials Credentials) (*Identity, error)
+}
+
+// Authorizer authenticates callers and checks their permissions. It is safe
+// for concurrent use.
+type Authorizer struct {
+	authenticators []Authenticator
+	logger         interfaces.Logger
+}
+
+// NewAuthorizer creates an authorizer that tries the authenticators in order
+func NewAuthorizer(logger interfaces.Logger, authenticators ...Authenticator) *Authorizer {
+	return &Authorizer{authenticators: authenticators, logger: logger}
+}
+
+// NewAuthorizerFromConfig creates an authorizer accepting the configured API
+// keys and, when an issuer is configured, OIDC tokens. Extra authenticators,
+// such as a CertificateAuthenticator, are tried after them.
+func NewAuthorizerFromConfig(cfg config.AuthConfig, logger interfaces.Logger, extra ...Authenticator) (*Authorizer, error) {
+	var authenticators []Authenticator
+	if len(cfg.APIKeys) > 0 {
+		keys, err := NewAPIKeyAuthenticator(cfg.APIKeys)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, keys)
+	}
+	if cfg.OIDCIssuer != "" {
+		oidc, err := NewOIDCAuthenticator(cfg)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, oidc)
+	}
+	authenticators = append(authenticators, extra...)
+	if len(authenticators) == 0 {
+		return nil, errors.NewValidationError("auth", "access control needs API keys, an OIDC issuer or client certificates")
+	}
+	return NewAuthorizer(logger, authenticators...), nil
+}
+
+// Authenticate identifies the caller. Missing or invalid credentials are
+// UNAUTHORIZED errors.
+func (a *Authorizer) Authenticate(ctx context.Context, credentials Credentials) (*Identity, error) {
+	for _, authenticator := range a.authenticators {
+		identity, err := authenticator.Authenticate(ctx, credentials)
+		if err != nil {
+			a.logger.Warnf("Authentication failed: %v", err)
+			return nil, errors.NewNotificationError(errors.ErrorCodeUnauthorized, "invalid credentials")
+		}
+		if identity != nil {
+			return identity, nil
+		}
+	}
+	return nil, errors.NewNotificationError(errors.ErrorCodeUnauthorized, "authentication required")
+}
+
+// Authorize checks an identity has a permission. A missing permission is a
+// FORBIDDEN error.
+func (a *Authorizer) Authorize(identity *Identity, permission Permission) error {
+	if identity.Can(permission) {
+		return nil
+	}
+	a.logger.Warnf("Denied %s to %s %s", permission, identity.Method, identity.Subject)
+	return errors.NewNotificationError(errors.ErrorCodeForbidden, fmt.Sprintf("permission %s is required", permission)).
+		WithMetadata("permission", string(permission))
+}
+
+// AuthorizeTenant checks an identity may act on a tenant's resources. An
+// identity of another tenant gets a FORBIDDEN error.
+func (a *Authorizer) AuthorizeTenant(identity *Identity, tenantID string) error {
+	if identity.CanAccessTenant(tenantID) {
+		return nil
+	}
+	a.logger.Warnf("Denied tenant %s to %s %s of tenant %s", tenantID, identity.Method, identity.Subject, identity.TenantID)
+	return errors.NewNotificationError(errors.ErrorCodeForbidden, fmt.Sprintf("no access to tenant %s", tenantID)).
+		WithMetadata("tenant_id", tenantID)
+}
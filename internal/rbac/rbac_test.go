@@ -0,0 +1,116 @@
+package rbac
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestIdentity_Can(t *testing.T) {
+	tests := []struct {
+		role    Role
+		allowed []Permission
+		denied  []Permission
+	}{
+		{RoleAdmin, []Permission{PermissionSuppressionsWrite, PermissionSettingsWrite, PermissionCampaignsLaunch}, nil},
+		{RoleSender, []Permission{PermissionNotificationsWrite, PermissionCampaignsLaunch, PermissionTemplatesRead}, []Permission{PermissionTemplatesWrite, PermissionSuppressionsWrite, PermissionSettingsWrite}},
+		{RoleTemplateEditor, []Permission{PermissionTemplatesWrite, PermissionStatsRead}, []Permission{PermissionNotificationsWrite, PermissionCampaignsLaunch}},
+		{RoleViewer, []Permission{PermissionNotificationsRead, PermissionStatsRead, PermissionSettingsRead}, []Permission{PermissionNotificationsWrite, PermissionTemplatesWrite}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.role), func(t *testing.T) {
+			identity := &Identity{Subject: "test", Roles: []Role{tt.role}}
+			for _, permission := range tt.allowed {
+				assert.True(t, identity.Can(permission), permission)
+			}
+			for _, permission := range tt.denied {
+				assert.False(t, identity.Can(permission), permission)
+			}
+		})
+	}
+
+	// Roles add up
+	both := &Identity{Roles: []Role{RoleSender, RoleTemplateEditor}}
+	assert.True(t, both.Can(PermissionTemplatesWrite))
+	assert.True(t, both.Can(PermissionCampaignsLaunch))
+	assert.False(t, (&Identity{}).Can(PermissionStatsRead))
+}
+
+func TestParseRoles(t *testing.T) {
+	roles, err := ParseRoles([]string{"Admin", " viewer "})
+	require.NoError(t, err)
+	assert.Equal(t, []Role{RoleAdmin, RoleViewer}, roles)
+
+	_, err = ParseRoles([]string{"root"})
+	assert.Error(t, err)
+	assert.Equal(t, []Role{RoleAdmin, RoleSender, RoleTemplateEditor, RoleViewer}, Roles())
+}
+
+func TestCredentialsFromRequest(t *testing.T) {
+	request := httptest.NewRequest("GET", "/", nil)
+	request.Header.Set(APIKeyHeader, "key")
+	request.Header.Set("Authorization", "bearer token.value.sig")
+	assert.Equal(t, Credentials{APIKey: "key", BearerToken: "token.value.sig"}, CredentialsFromRequest(request))
+
+	request.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	assert.Empty(t, CredentialsFromRequest(request).BearerToken)
+}
+
+func TestAuthorizer_APIKeys(t *testing.T) {
+	authorizer, err := NewAuthorizerFromConfig(config.AuthConfig{APIKeys: []config.APIKeyConfig{
+		{Subject: "billing", Roles: []string{"sender"}, Key: "billing-0123456789"},
+		{Subject: "dashboard", Roles: []string{"viewer"}, Key: "dashboard-0123456789"},
+	}}, utils.NewSimpleLogger("error"))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	identity, err := authorizer.Authenticate(ctx, Credentials{APIKey: "billing-0123456789"})
+	require.NoError(t, err)
+	assert.Equal(t, &Identity{Subject: "billing", Method: "api_key", Roles: []Role{RoleSender}}, identity)
+	assert.NoError(t, authorizer.Authorize(identity, PermissionNotificationsWrite))
+
+	err = authorizer.Authorize(identity, PermissionTemplatesWrite)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeForbidden, notifErr.Code)
+	assert.Equal(t, "templates:write", notifErr.Metadata["permission"])
+
+	for _, credentials := range []Credentials{{}, {APIKey: "wrong-0123456789"}} {
+		_, err = authorizer.Authenticate(ctx, credentials)
+		notifErr, ok = errors.AsNotificationError(err)
+		require.True(t, ok)
+		assert.Equal(t, errors.ErrorCodeUnauthorized, notifErr.Code)
+	}
+}
+
+func TestNewAuthorizerFromConfig_Invalid(t *testing.T) {
+	logger := utils.NewSimpleLogger("error")
+	for name, cfg := range map[string]config.AuthConfig{
+		"no credentials": {},
+		"short key":      {APIKeys: []config.APIKeyConfig{{Subject: "a", Roles: []string{"admin"}, Key: "short"}}},
+		"unknown role":   {APIKeys: []config.APIKeyConfig{{Subject: "a", Roles: []string{"root"}, Key: "long-enough-0123456789"}}},
+		"bad JWKS URL":   {OIDCIssuer: "https://issuer.example.com", OIDCAudience: "notifications", OIDCJWKSURL: "ftp://issuer.example.com/keys"},
+		"no audience":    {OIDCIssuer: "https://issuer.example.com"},
+	} {
+		_, err := NewAuthorizerFromConfig(cfg, logger)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestIdentityFrom(t *testing.T) {
+	_, ok := IdentityFrom(context.Background())
+	assert.False(t, ok)
+
+	identity := &Identity{Subject: "ops"}
+	found, ok := IdentityFrom(WithIdentity(context.Background(), identity))
+	require.True(t, ok)
+	assert.Same(t, identity, found)
+}
@@ -0,0 +1,312 @@
+// Package reconcile resolves notifications left pending by a crash or error
+// between a provider accepting a message and its status being stored. Instead
+// of sending such notifications again, the reconciler asks the provider
+// whether it accepted them.
+package reconcile
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/events"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/routing"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// MetadataReconciled is the metadata key set on notifications resolved by the reconciler
+const MetadataReconciled = "reconciled"
+
+// notAcceptedMessage is the error stored on notifications the provider never accepted
+const notAcceptedMessage = "not accepted by the provider; safe to retry"
+
+// ProviderResolver returns the provider registered for a channel under a
+// name, or the channel's default provider when the name is empty, e.g.
+// Dispatcher.NamedProvider
+type ProviderResolver func(notificationType models.NotificationType, name string) (interfaces.NotificationProvider, error)
+
+// RunResult describes one reconciliation run
+type RunResult struct {
+	StartedAt  time.Time     `json:"started_at"`
+	Duration   time.Duration `json:"duration"`
+	Checked    int           `json:"checked"`
+	Accepted   int           `json:"accepted"`   // the provider had the message; marked sent or delivered
+	NotSent    int           `json:"not_sent"`   // the provider never had the message; marked failed
+	Unresolved int           `json:"unresolved"` // lookup unsupported, failed or not authoritative; left pending
+}
+
+// Stats holds cumulative reconciliation metrics
+type Stats struct {
+	Runs       int64      `json:"runs"`
+	Failures   int64      `json:"failures"`
+	Accepted   int64      `json:"accepted"`
+	NotSent    int64      `json:"not_sent"`
+	Unresolved int64      `json:"unresolved"`
+	LastRunAt  *time.Time `json:"last_run_at,omitempty"`
+	LastError  string     `json:"last_error,omitempty"`
+}
+
+// Reconciler periodically resolves notifications that have been pending for
+// longer than the grace period. The provider that sent a notification, the
+// one its routing.MetadataProvider names or else its channel's default, is
+// asked for the message when it implements interfaces.MessageLookupProvider:
+// if it has it, the notification is marked with the provider's status; if
+// not, and the provider's lookups are authoritative, it is marked failed so
+// RetryNotification can send it without risking a duplicate. Other
+// notifications stay pending and are counted as unresolved.
+type Reconciler struct {
+	repository interfaces.NotificationRepository
+	providers  ProviderResolver
+	config     config.ReconcileConfig
+	logger     interfaces.Logger
+
+	mu      sync.Mutex
+	events  events.Publisher
+	stats   Stats
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	running bool
+}
+
+// NewReconciler creates a new reconciler
+func NewReconciler(repository interfaces.NotificationRepository, providers ProviderResolver, cfg config.ReconcileConfig, logger interfaces.Logger) *Reconciler {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.GracePeriod <= 0 {
+		cfg.GracePeriod = 5 * time.Minute
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+
+	return &Reconciler{
+		repository: repository,
+		providers:  providers,
+		config:     cfg,
+		logger:     logger,
+	}
+}
+
+// SetEventPublisher sets the publisher that receives the sent, delivered and
+// failed events of reconciled notifications
+func (r *Reconciler) SetEventPublisher(publisher events.Publisher) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = publisher
+}
+
+// Start runs a reconciliation immediately and then every interval until Stop
+// is called. Calling Start on a running reconciler has no effect.
+func (r *Reconciler) Start(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.running {
+		return
+	}
+
+	ctx, r.cancel = context.WithCancel(ctx)
+	r.running = true
+	r.wg.Add(1)
+	go r.run(ctx)
+
+	r.logger.Infof("Started reconciler (grace period %s, every %s)", r.config.GracePeriod, r.config.Interval)
+}
+
+// Stop stops the reconciler and waits for a run in progress to finish
+func (r *Reconciler) Stop() {
+	r.mu.Lock()
+	if !r.running {
+		r.mu.Unlock()
+		return
+	}
+	r.cancel()
+	r.running = false
+	r.mu.Unlock()
+
+	r.wg.Wait()
+	r.logger.Infof("Stopped reconciler")
+}
+
+// ReconcileOnce resolves up to a batch of notifications pending for longer
+// than the grace period, oldest first
+func (r *Reconciler) ReconcileOnce(ctx context.Context) (*RunResult, error) {
+	now := time.Now()
+	result := &RunResult{StartedAt: now}
+
+	// Notifications of providers without lookup support stay pending, so the
+	// whole pending set is read to keep them from starving the batch
+	pending, err := r.repository.GetPendingNotifications(ctx, 0)
+	cutoff := now.Add(-r.config.GracePeriod)
+	for _, notification := range pending {
+		if err != nil || result.Checked >= r.config.BatchSize {
+			break
+		}
+		if notification.UpdatedAt.After(cutoff) {
+			continue
+		}
+		if err = ctx.Err(); err != nil {
+			break
+		}
+
+		result.Checked++
+		err = r.reconcile(ctx, notification, result)
+	}
+	result.Duration = time.Since(now)
+
+	r.mu.Lock()
+	r.stats.Runs++
+	r.stats.Accepted += int64(result.Accepted)
+	r.stats.NotSent += int64(result.NotSent)
+	r.stats.Unresolved += int64(result.Unresolved)
+	r.stats.LastRunAt = &now
+	r.stats.LastError = ""
+	if err != nil {
+		r.stats.Failures++
+		r.stats.LastError = err.Error()
+	}
+	r.mu.Unlock()
+
+	if err != nil {
+		r.logger.Errorf("Reconciliation failed after checking %d notifications: %v", result.Checked, err)
+		return result, err
+	}
+
+	if result.Checked > 0 {
+		r.logger.Infof("Reconciled %d notifications: %d accepted, %d not sent, %d unresolved",
+			result.Checked, result.Accepted, result.NotSent, result.Unresolved)
+	}
+	return result, nil
+}
+
+// Stats returns the cumulative reconciliation metrics
+func (r *Reconciler) Stats() Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := r.stats
+	if r.stats.LastRunAt != nil {
+		lastRunAt := *r.stats.LastRunAt
+		stats.LastRunAt = &lastRunAt
+	}
+	return stats
+}
+
+// run reconciles on every tick until the context is cancelled
+func (r *Reconciler) run(ctx context.Context) {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		// Errors are logged and counted by ReconcileOnce; the next tick retries
+		_, _ = r.ReconcileOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reconcile resolves one pending notification. Only repository errors are
+// returned; provider errors leave the notification unresolved.
+func (r *Reconciler) reconcile(ctx context.Context, notification *models.Notification, result *RunResult) error {
+	name := notification.Metadata[routing.MetadataProvider]
+	provider, err := r.providers(notification.Type, name)
+	if err != nil {
+		result.Unresolved++
+		r.logger.Warnf("Cannot reconcile notification %s: %v", notification.ID, err)
+		return nil
+	}
+	lookup, ok := provider.(interfaces.MessageLookupProvider)
+	if !ok {
+		result.Unresolved++
+		r.logger.Warnf("Cannot reconcile notification %s: its provider does not support message lookup", notification.ID)
+		return nil
+	}
+
+	response, lookupErr := lookup.LookupMessage(ctx, notification.ID, notification.ProviderMessageID)
+	notFound := false
+	if lookupErr != nil {
+		notifErr, ok := errors.AsNotificationError(lookupErr)
+		notFound = ok && notifErr.Code == errors.ErrorCodeNotFound
+		if !notFound {
+			result.Unresolved++
+			r.logger.Warnf("Message lookup for notification %s failed: %v", notification.ID, lookupErr)
+			return nil
+		}
+		if authoritative, ok := provider.(interfaces.AuthoritativeLookupProvider); !ok || !authoritative.LookupAuthoritative() {
+			result.Unresolved++
+			r.logger.Warnf("Cannot reconcile notification %s: its provider has no record of it, but its lookups are not authoritative", notification.ID)
+			return nil
+		}
+	}
+
+	// The send may have finished while the provider was queried
+	current, err := r.repository.GetByID(ctx, notification.ID.String())
+	if err != nil {
+		return err
+	}
+	if current.Status != models.StatusPending {
+		return nil
+	}
+
+	now := time.Now()
+	eventType := events.EventNotificationFailed
+	if notFound {
+		current.Status = models.StatusFailed
+		current.FailedAt = &now
+		current.ErrorMsg = notAcceptedMessage
+	} else {
+		current.Status = response.Status
+		current.SentAt = response.SentAt
+		if current.SentAt == nil {
+			current.SentAt = &now
+		}
+		current.ProviderMessageID = response.ProviderID
+		eventType = events.EventNotificationSent
+		if response.Status == models.StatusDelivered {
+			current.DeliveredAt = &now
+			eventType = events.EventNotificationDelivered
+		}
+	}
+	metadata := make(map[string]string, len(current.Metadata)+1)
+	for key, value := range current.Metadata {
+		metadata[key] = value
+	}
+	metadata[MetadataReconciled] = "true"
+	current.Metadata = metadata
+
+	if err := r.repository.Update(ctx, current); err != nil {
+		return err
+	}
+
+	if notFound {
+		result.NotSent++
+		r.logger.Infof("Notification %s was never accepted by the provider; marked failed", current.ID)
+	} else {
+		result.Accepted++
+		r.logger.Infof("Notification %s was accepted by the provider; marked %s", current.ID, current.Status)
+	}
+
+	if publisher := r.publisher(); publisher != nil {
+		publisher.Publish(ctx, events.NewNotificationEvent(eventType, current))
+	}
+	return nil
+}
+
+// publisher returns the event publisher, if one is set
+func (r *Reconciler) publisher() events.Publisher {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.events
+}
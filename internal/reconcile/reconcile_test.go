@@ -0,0 +1,261 @@
+package reconcile
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/events"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/internal/repository"
+	"github.com/nareshkumar-microsoft/notificationService/internal/routing"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+func TestReconciler_ReconcileOnce(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	email := providers.NewMockEmailProvider(config.EmailProviderConfig{Provider: "mock", Enabled: true})
+	push := providers.NewMockPushProvider(config.PushProviderConfig{Provider: "mock", Enabled: true})
+	ctx := context.Background()
+	old := time.Now().Add(-time.Hour)
+
+	// Accepted by the provider, but the status was never stored
+	accepted := createTestNotification(t, repo, models.NotificationTypeEmail, old)
+	_, err := email.Send(ctx, accepted)
+	require.NoError(t, err)
+
+	notSent := createTestNotification(t, repo, models.NotificationTypeEmail, old)
+	inFlight := createTestNotification(t, repo, models.NotificationTypeEmail, time.Now())
+	unsupported := createTestNotification(t, repo, models.NotificationTypePush, old)
+
+	var mu sync.Mutex
+	var published []events.EventType
+	reconciler := createTestReconciler(repo, email, push)
+	reconciler.SetEventPublisher(publisherFunc(func(ctx context.Context, event events.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		published = append(published, event.Type)
+	}))
+
+	result, err := reconciler.ReconcileOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Checked)
+	assert.Equal(t, 1, result.Accepted)
+	assert.Equal(t, 1, result.NotSent)
+	assert.Equal(t, 1, result.Unresolved)
+
+	found, err := repo.GetByID(ctx, accepted.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusSent, found.Status)
+	assert.Equal(t, "mock-"+accepted.ID.String(), found.ProviderMessageID)
+	assert.NotNil(t, found.SentAt)
+	assert.Equal(t, "true", found.Metadata[MetadataReconciled])
+
+	found, err = repo.GetByID(ctx, notSent.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusFailed, found.Status)
+	assert.True(t, utils.ShouldRetryNotification(found))
+
+	for _, id := range []uuid.UUID{inFlight.ID, unsupported.ID} {
+		found, err = repo.GetByID(ctx, id.String())
+		require.NoError(t, err)
+		assert.Equal(t, models.StatusPending, found.Status)
+	}
+
+	// Nothing was sent a second time
+	assert.Len(t, email.GetSentEmails(), 1)
+	assert.ElementsMatch(t, []events.EventType{events.EventNotificationSent, events.EventNotificationFailed}, published)
+
+	stats := reconciler.Stats()
+	assert.Equal(t, int64(1), stats.Runs)
+	assert.Equal(t, int64(1), stats.Accepted)
+	assert.Equal(t, int64(1), stats.NotSent)
+	assert.Equal(t, int64(1), stats.Unresolved)
+}
+
+func TestReconciler_BatchSize(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	email := providers.NewMockEmailProvider(config.EmailProviderConfig{Provider: "mock", Enabled: true})
+	push := providers.NewMockPushProvider(config.PushProviderConfig{Provider: "mock", Enabled: true})
+	old := time.Now().Add(-time.Hour)
+
+	// Unresolvable notifications count against the batch but do not block later runs
+	createTestNotification(t, repo, models.NotificationTypePush, old.Add(-time.Minute))
+	for i := 0; i < 3; i++ {
+		createTestNotification(t, repo, models.NotificationTypeEmail, old)
+	}
+
+	reconciler := NewReconciler(repo, createTestResolver(email, push), config.ReconcileConfig{BatchSize: 2}, utils.NewSimpleLogger("info"))
+	result, err := reconciler.ReconcileOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Checked)
+	assert.Equal(t, 1, result.Unresolved)
+	assert.Equal(t, 1, result.NotSent)
+}
+
+func TestReconciler_LookupErrorLeavesPending(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	notification := createTestNotification(t, repo, models.NotificationTypeEmail, time.Now().Add(-time.Hour))
+
+	resolver := func(models.NotificationType, string) (interfaces.NotificationProvider, error) {
+		return &failingLookupProvider{MockEmailProvider: providers.NewMockEmailProvider(config.EmailProviderConfig{})}, nil
+	}
+	reconciler := NewReconciler(repo, resolver, config.ReconcileConfig{}, utils.NewSimpleLogger("info"))
+
+	result, err := reconciler.ReconcileOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Unresolved)
+
+	found, err := repo.GetByID(context.Background(), notification.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusPending, found.Status)
+}
+
+func TestReconciler_RecordedProvider(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	email := providers.NewMockEmailProvider(config.EmailProviderConfig{Provider: "mock", Enabled: true})
+	backup := providers.NewMockEmailProvider(config.EmailProviderConfig{Provider: "mock", Enabled: true})
+	ctx := context.Background()
+	old := time.Now().Add(-time.Hour)
+
+	// Sent through a routed provider, which the default has no record of
+	routed := createTestNotification(t, repo, models.NotificationTypeEmail, old)
+	routed.Metadata = map[string]string{routing.MetadataProvider: "backup"}
+	require.NoError(t, repo.Update(ctx, routed))
+	_, err := backup.Send(ctx, routed)
+	require.NoError(t, err)
+	unknown := createTestNotification(t, repo, models.NotificationTypeEmail, old)
+	unknown.Metadata = map[string]string{routing.MetadataProvider: "retired"}
+	require.NoError(t, repo.Update(ctx, unknown))
+
+	var asked []string
+	reconciler := NewReconciler(repo, func(notificationType models.NotificationType, name string) (interfaces.NotificationProvider, error) {
+		asked = append(asked, name)
+		switch name {
+		case "":
+			return email, nil
+		case "backup":
+			return backup, nil
+		}
+		return nil, errors.NewNotificationError(errors.ErrorCodeProviderNotFound, "no email provider registered as "+name)
+	}, config.ReconcileConfig{GracePeriod: time.Nanosecond}, utils.NewSimpleLogger("info")) // the updates made them recent
+
+	result, err := reconciler.ReconcileOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Accepted)
+	assert.Equal(t, 1, result.Unresolved)
+	assert.ElementsMatch(t, []string{"backup", "retired"}, asked)
+
+	found, err := repo.GetByID(ctx, routed.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusSent, found.Status)
+	found, err = repo.GetByID(ctx, unknown.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusPending, found.Status)
+}
+
+func TestReconciler_NotFoundWithoutAuthoritativeLookup(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	notification := createTestNotification(t, repo, models.NotificationTypeEmail, time.Now().Add(-time.Hour))
+
+	// The provider has no record of the message, but may only not have indexed it yet
+	resolver := func(models.NotificationType, string) (interfaces.NotificationProvider, error) {
+		return &eventualLookupProvider{MockEmailProvider: providers.NewMockEmailProvider(config.EmailProviderConfig{})}, nil
+	}
+	reconciler := NewReconciler(repo, resolver, config.ReconcileConfig{}, utils.NewSimpleLogger("info"))
+
+	result, err := reconciler.ReconcileOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Unresolved)
+	assert.Equal(t, 0, result.NotSent)
+
+	found, err := repo.GetByID(context.Background(), notification.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusPending, found.Status)
+}
+
+func TestReconciler_StartStop(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	email := providers.NewMockEmailProvider(config.EmailProviderConfig{Provider: "mock", Enabled: true})
+	push := providers.NewMockPushProvider(config.PushProviderConfig{Provider: "mock", Enabled: true})
+	createTestNotification(t, repo, models.NotificationTypeEmail, time.Now().Add(-time.Hour))
+
+	reconciler := createTestReconciler(repo, email, push)
+	reconciler.Start(context.Background())
+	reconciler.Start(context.Background()) // no effect
+	require.Eventually(t, func() bool { return reconciler.Stats().NotSent == 1 }, time.Second, 10*time.Millisecond)
+
+	reconciler.Stop()
+	reconciler.Stop() // no effect
+}
+
+// Helper functions
+
+// publisherFunc adapts a function to the events.Publisher interface
+type publisherFunc func(ctx context.Context, event events.Event)
+
+func (f publisherFunc) Publish(ctx context.Context, event events.Event) {
+	f(ctx, event)
+}
+
+// failingLookupProvider is an email provider whose lookups fail
+type failingLookupProvider struct {
+	*providers.MockEmailProvider
+}
+
+func (p *failingLookupProvider) LookupMessage(ctx context.Context, notificationID uuid.UUID, providerMessageID string) (*models.NotificationResponse, error) {
+	return nil, errors.NewNotificationError(errors.ErrorCodeProviderUnavailable, "lookup API unavailable")
+}
+
+// eventualLookupProvider is an email provider whose lookups may miss
+// messages accepted recently
+type eventualLookupProvider struct {
+	*providers.MockEmailProvider
+}
+
+func (p *eventualLookupProvider) LookupAuthoritative() bool {
+	return false
+}
+
+func createTestResolver(email *providers.MockEmailProvider, push *providers.MockPushProvider) ProviderResolver {
+	return func(notificationType models.NotificationType, name string) (interfaces.NotificationProvider, error) {
+		if notificationType == models.NotificationTypePush {
+			return push, nil
+		}
+		return email, nil
+	}
+}
+
+func createTestReconciler(repo interfaces.NotificationRepository, email *providers.MockEmailProvider, push *providers.MockPushProvider) *Reconciler {
+	return NewReconciler(repo, createTestResolver(email, push), config.ReconcileConfig{
+		Interval:    10 * time.Millisecond,
+		GracePeriod: 5 * time.Minute,
+		BatchSize:   10,
+	}, utils.NewSimpleLogger("info"))
+}
+
+func createTestNotification(t *testing.T, repo *repository.MemoryRepository, notificationType models.NotificationType, updatedAt time.Time) *models.Notification {
+	notification := &models.Notification{
+		ID:         uuid.New(),
+		Type:       notificationType,
+		Status:     models.StatusPending,
+		Priority:   models.PriorityNormal,
+		Recipient:  "user@example.com",
+		Subject:    "Your order",
+		Body:       "Your order has shipped",
+		CreatedAt:  updatedAt,
+		UpdatedAt:  updatedAt,
+		MaxRetries: 3,
+	}
+	require.NoError(t, repo.Save(context.Background(), notification))
+	return notification
+}
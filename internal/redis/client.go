@@ -0,0 +1,287 @@
+// Package redis is a minimal client for the Redis commands the service's
+// shared stores run, such as the scripts of locks and rate limits. It speaks
+// the Redis protocol itself and keeps a small pool of connections, so
+// commands run on every send do not each pay for a dial and handshake.
+package redis
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// Defaults used when the configuration sets none
+const (
+	DefaultAddr    = "localhost:6379"
+	DefaultTimeout = 5 * time.Second
+)
+
+// maxIdleConns is the number of idle connections a client keeps for reuse
+const maxIdleConns = 8
+
+// Error is an error reply of the server. The connection it came on is
+// still usable.
+type Error string
+
+// Error implements the error interface
+func (e Error) Error() string {
+	return string(e)
+}
+
+// conn is a connection authenticated and on the client's database
+type conn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// Client runs commands on a Redis server. It is safe for concurrent use.
+type Client struct {
+	addr     string
+	password string
+	db       int
+	timeout  time.Duration
+	dialer   net.Dialer
+	idle     chan *conn
+}
+
+// NewClient creates a client for a server given as
+// redis://[:password@]host:port[/db] or host:port. A password or database in
+// the URL overrides the ones passed.
+func NewClient(redisURL, password string, db int, timeout time.Duration) (*Client, error) {
+	c := &Client{
+		addr:     DefaultAddr,
+		password: password,
+		db:       db,
+		timeout:  timeout,
+		idle:     make(chan *conn, maxIdleConns),
+	}
+	if c.timeout <= 0 {
+		c.timeout = DefaultTimeout
+	}
+
+	switch {
+	case strings.Contains(redisURL, "://"):
+		parsed, err := url.Parse(redisURL)
+		if err != nil || (parsed.Scheme != "redis" && parsed.Scheme != "tcp") || parsed.Host == "" {
+			return nil, errors.NewValidationError("redis_url", fmt.Sprintf("invalid Redis URL: %q", redisURL))
+		}
+		c.addr = parsed.Host
+		if password, set := parsed.User.Password(); set {
+			c.password = password
+		}
+		if db := strings.TrimPrefix(parsed.Path, "/"); db != "" {
+			if c.db, err = strconv.Atoi(db); err != nil {
+				return nil, errors.NewValidationError("redis_url", fmt.Sprintf("invalid Redis database: %q", db))
+			}
+		}
+	case redisURL != "":
+		c.addr = redisURL
+	}
+
+	return c, nil
+}
+
+// Addr returns the address of the server
+func (c *Client) Addr() string {
+	return c.addr
+}
+
+// Do runs a command on a pooled connection, or on a new one that is
+// authenticated and on the configured database. Replies are strings,
+// int64s, nil for a nil bulk string, or []interface{} for arrays; error
+// replies are returned as errors wrapping an Error.
+func (c *Client) Do(ctx context.Context, args ...string) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	// A pooled connection the server has since closed fails before the
+	// command runs; the command is then sent again on a new one
+	select {
+	case idle := <-c.idle:
+		reply, err := c.run(ctx, idle, args...)
+		if !isClosed(err) {
+			return reply, err
+		}
+	default:
+	}
+
+	fresh, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.run(ctx, fresh, args...)
+}
+
+// Eval runs a Lua script. It is sent by its SHA1 digest with EVALSHA, and
+// in full with EVAL only when the server does not have it cached yet.
+func (c *Client) Eval(ctx context.Context, script string, keys []string, args ...string) (interface{}, error) {
+	digest := sha1.Sum([]byte(script))
+	command := append([]string{"EVALSHA", hex.EncodeToString(digest[:]), strconv.Itoa(len(keys))}, keys...)
+	command = append(command, args...)
+
+	reply, err := c.Do(ctx, command...)
+	var replyErr Error
+	if stderrors.As(err, &replyErr) && strings.HasPrefix(string(replyErr), "NOSCRIPT") {
+		command[0], command[1] = "EVAL", script
+		return c.Do(ctx, command...)
+	}
+	return reply, err
+}
+
+// dial opens a connection, authenticating and selecting the database
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	netConn, err := c.dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to connect to Redis", err)
+	}
+	cn := &conn{Conn: netConn, reader: bufio.NewReader(netConn)}
+	if deadline, ok := ctx.Deadline(); ok {
+		cn.SetDeadline(deadline)
+	}
+
+	if c.password != "" {
+		if _, err := command(cn, cn.reader, "AUTH", c.password); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	if c.db != 0 {
+		if _, err := command(cn, cn.reader, "SELECT", strconv.Itoa(c.db)); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	return cn, nil
+}
+
+// run runs a command on a connection, then returns the connection to the
+// pool, or closes it when the pool is full or the connection failed
+func (c *Client) run(ctx context.Context, cn *conn, args ...string) (interface{}, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		cn.SetDeadline(deadline)
+	}
+
+	reply, err := command(cn, cn.reader, args...)
+	if err != nil && !isReply(err) {
+		cn.Close()
+		return nil, err
+	}
+
+	select {
+	case c.idle <- cn:
+	default:
+		cn.Close()
+	}
+	return reply, err
+}
+
+// isClosed reports whether an error shows the server had closed the
+// connection
+func isClosed(err error) bool {
+	return stderrors.Is(err, io.EOF) || stderrors.Is(err, syscall.EPIPE) || stderrors.Is(err, syscall.ECONNRESET)
+}
+
+// isReply reports whether an error is an error reply of the server, after
+// which the connection can still be used
+func isReply(err error) bool {
+	var replyErr Error
+	return stderrors.As(err, &replyErr)
+}
+
+// command writes a command and reads its reply
+func command(w io.Writer, r *bufio.Reader, args ...string) (interface{}, error) {
+	if err := WriteCommand(w, args...); err != nil {
+		return nil, errors.NewInternalError("failed to send Redis command", err)
+	}
+
+	reply, err := ReadReply(r)
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Sprintf("Redis %s failed", args[0]), err)
+	}
+	return reply, nil
+}
+
+// WriteCommand writes a command as an array of bulk strings
+func WriteCommand(w io.Writer, args ...string) error {
+	var command strings.Builder
+	fmt.Fprintf(&command, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&command, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	_, err := io.WriteString(w, command.String())
+	return err
+}
+
+// ReadReply reads one reply. See Do for the types replies are returned as.
+func ReadReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, Error(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		length, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("malformed bulk length %q", line)
+		}
+		if length < 0 {
+			return nil, nil
+		}
+		data := make([]byte, length+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return string(data[:length]), nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("malformed array length %q", line)
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		// An error reply among the elements is returned once the rest are
+		// read, so the connection stays usable
+		var replyErr error
+		elements := make([]interface{}, count)
+		for i := range elements {
+			if elements[i], err = ReadReply(r); err != nil {
+				if !isReply(err) {
+					return nil, err
+				}
+				if replyErr == nil {
+					replyErr = err
+				}
+			}
+		}
+		if replyErr != nil {
+			return nil, replyErr
+		}
+		return elements, nil
+	default:
+		return nil, fmt.Errorf("unexpected reply %q", line)
+	}
+}
@@ -0,0 +1,118 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClient(t *testing.T) {
+	client, err := NewClient("redis://:secret@cache:6380/2", "", 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "cache:6380", client.Addr())
+	assert.Equal(t, "secret", client.password)
+	assert.Equal(t, 2, client.db)
+	assert.Equal(t, DefaultTimeout, client.timeout)
+
+	client, err = NewClient("cache:6379", "secret", 1, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "cache:6379", client.Addr())
+	assert.Equal(t, "secret", client.password)
+	assert.Equal(t, 1, client.db)
+
+	client, err = NewClient("", "", 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultAddr, client.Addr())
+
+	_, err = NewClient("http://cache:6379", "", 0, 0)
+	assert.Error(t, err)
+	_, err = NewClient("redis://cache:6379/one", "", 0, 0)
+	assert.Error(t, err)
+}
+
+func TestWriteCommand(t *testing.T) {
+	var written strings.Builder
+	require.NoError(t, WriteCommand(&written, "GET", "key"))
+	assert.Equal(t, "*2\r\n$3\r\nGET\r\n$3\r\nkey\r\n", written.String())
+}
+
+func TestReadReply(t *testing.T) {
+	tests := []struct {
+		input string
+		reply interface{}
+	}{
+		{"+OK\r\n", "OK"},
+		{":42\r\n", int64(42)},
+		{"$5\r\nhello\r\n", "hello"},
+		{"$0\r\n\r\n", ""},
+		{"$-1\r\n", nil},
+		{"*2\r\n:1\r\n$2\r\nok\r\n", []interface{}{int64(1), "ok"}},
+	}
+	for _, tt := range tests {
+		reply, err := ReadReply(bufio.NewReader(strings.NewReader(tt.input)))
+		require.NoError(t, err, tt.input)
+		assert.Equal(t, tt.reply, reply, tt.input)
+	}
+
+	for _, input := range []string{"-ERR boom\r\n", "?\r\n", "\r\n", "$x\r\n", "*1\r\n"} {
+		_, err := ReadReply(bufio.NewReader(strings.NewReader(input)))
+		assert.Error(t, err, input)
+	}
+}
+
+func TestClient_Do(t *testing.T) {
+	client, err := NewClient("127.0.0.1:1", "", 0, 100*time.Millisecond)
+	require.NoError(t, err)
+
+	_, err = client.Do(context.Background(), "PING")
+	assert.Error(t, err)
+}
+
+func TestClient_DoRetriesClosedConnection(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	// The server answers one command per connection, then closes it
+	connections := make(chan struct{}, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			connections <- struct{}{}
+			reader := bufio.NewReader(conn)
+			if _, err := ReadReply(reader); err == nil {
+				conn.Write([]byte("+PONG\r\n"))
+			}
+			conn.Close()
+		}
+	}()
+
+	client, err := NewClient(listener.Addr().String(), "", 0, time.Second)
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		reply, err := client.Do(context.Background(), "PING")
+		require.NoError(t, err)
+		assert.Equal(t, "PONG", reply)
+	}
+	assert.Len(t, connections, 3)
+}
+
+func TestReadReply_ErrorInArray(t *testing.T) {
+	reader := bufio.NewReader(strings.NewReader("*2\r\n-ERR boom\r\n:1\r\n+OK\r\n"))
+	_, err := ReadReply(reader)
+	assert.Equal(t, Error("ERR boom"), err)
+
+	// The rest of the array was read, so the next reply is intact
+	reply, err := ReadReply(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "OK", reply)
+}
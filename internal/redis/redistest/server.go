@@ -0,0 +1,215 @@
+// Package redistest runs an in-process Redis server for tests of the stores
+// built on the redis package. It answers AUTH, SELECT and PING, and EVAL and
+// EVALSHA of the scripts registered with HandleScript, which the test
+// emulates in Go.
+package redistest
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/redis"
+)
+
+// Script emulates a Lua script. It returns the reply: a string, int64, nil,
+// []interface{} or error.
+type Script func(keys, args []string) interface{}
+
+// Server is a Redis server listening on a loopback port. Scripts run one
+// at a time, as they do in Redis.
+type Server struct {
+	Addr string
+
+	password string
+	listener net.Listener
+
+	mu          sync.Mutex
+	scripts     map[string]Script
+	cached      map[string]string
+	evals       int
+	connections int
+}
+
+// NewServer starts a server, requiring the password when it is not empty.
+// The server closes when the test ends.
+func NewServer(t testing.TB, password string) *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	s := &Server{
+		Addr:     listener.Addr().String(),
+		password: password,
+		listener: listener,
+		scripts:  make(map[string]Script),
+		cached:   make(map[string]string),
+	}
+	t.Cleanup(s.Close)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.connections++
+			s.mu.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// HandleScript registers the emulation of a script
+func (s *Server) HandleScript(script string, fn Script) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.scripts[script] = fn
+}
+
+// Evals returns the number of scripts run
+func (s *Server) Evals() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.evals
+}
+
+// Connections returns the number of connections accepted
+func (s *Server) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.connections
+}
+
+// FlushScripts empties the script cache, as a restart of Redis does
+func (s *Server) FlushScripts() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cached = make(map[string]string)
+}
+
+// Close stops the server; connecting to its address fails afterwards
+func (s *Server) Close() {
+	s.listener.Close()
+}
+
+// serve answers the commands of a connection
+func (s *Server) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authenticated := s.password == ""
+
+	for {
+		reply, err := redis.ReadReply(reader)
+		if err != nil {
+			return
+		}
+		elements, _ := reply.([]interface{})
+		args := make([]string, len(elements))
+		for i, element := range elements {
+			args[i], _ = element.(string)
+		}
+		if len(args) == 0 {
+			return
+		}
+
+		var response interface{}
+		switch command := strings.ToUpper(args[0]); {
+		case command == "AUTH":
+			authenticated = len(args) == 2 && args[1] == s.password
+			response = "OK"
+			if !authenticated {
+				response = fmt.Errorf("WRONGPASS invalid password")
+			}
+		case !authenticated:
+			response = fmt.Errorf("NOAUTH Authentication required.")
+		case command == "SELECT" || command == "PING":
+			response = "OK"
+		case command == "EVAL" && len(args) >= 3:
+			response = s.eval(args[1], args[2:])
+		case command == "EVALSHA" && len(args) >= 3:
+			response = s.evalSHA(args[1], args[2:])
+		default:
+			response = fmt.Errorf("ERR unknown command '%s'", args[0])
+		}
+
+		if _, err := io.WriteString(conn, encode(response)); err != nil {
+			return
+		}
+	}
+}
+
+// eval runs a registered script with EVAL's numkeys, keys and args, and
+// caches it for EVALSHA
+func (s *Server) eval(source string, args []string) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	digest := sha1.Sum([]byte(source))
+	s.cached[hex.EncodeToString(digest[:])] = source
+	return s.run(source, args)
+}
+
+// evalSHA runs a cached script with EVALSHA's numkeys, keys and args
+func (s *Server) evalSHA(sha string, args []string) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	source, exists := s.cached[strings.ToLower(sha)]
+	if !exists {
+		return fmt.Errorf("NOSCRIPT No matching script. Please use EVAL.")
+	}
+	return s.run(source, args)
+}
+
+// run runs the emulation of a script. The caller holds s.mu.
+func (s *Server) run(source string, args []string) interface{} {
+	script, exists := s.scripts[source]
+	if !exists {
+		return fmt.Errorf("ERR no emulation of the script")
+	}
+	numKeys, err := strconv.Atoi(args[0])
+	if err != nil || numKeys < 0 || numKeys > len(args)-1 {
+		return fmt.Errorf("ERR invalid number of keys")
+	}
+
+	s.evals++
+	return script(args[1:1+numKeys], args[1+numKeys:])
+}
+
+// encode encodes a reply in the Redis protocol
+func encode(reply interface{}) string {
+	switch value := reply.(type) {
+	case nil:
+		return "$-1\r\n"
+	case error:
+		return "-" + value.Error() + "\r\n"
+	case int64:
+		return ":" + strconv.FormatInt(value, 10) + "\r\n"
+	case string:
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+	case []interface{}:
+		var encoded strings.Builder
+		fmt.Fprintf(&encoded, "*%d\r\n", len(value))
+		for _, element := range value {
+			encoded.WriteString(encode(element))
+		}
+		return encoded.String()
+	default:
+		return fmt.Sprintf("-ERR cannot encode %T\r\n", reply)
+	}
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// MemoryAuditRepository implements the AuditRepository interface with
+// in-memory, append-only storage
+type MemoryAuditRepository struct {
+	mu      sync.RWMutex
+	entries []*models.AuditEntry
+	ids     map[string]bool
+}
+
+// NewMemoryAuditRepository creates a new in-memory audit repository
+func NewMemoryAuditRepository() *MemoryAuditRepository {
+	return &MemoryAuditRepository{
+		ids: make(map[string]bool),
+	}
+}
+
+// Append implements the AuditRepository interface
+func (r *MemoryAuditRepository) Append(ctx context.Context, entry *models.AuditEntry) error {
+	if entry == nil {
+		return errors.NewValidationError("entry", "audit entry is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := entry.ID.String()
+	if r.ids[id] {
+		return errors.NewNotificationError(errors.ErrorCodeInvalidRequest, "audit entries cannot be overwritten")
+	}
+
+	stored := cloneAuditEntry(entry)
+	stored.Sequence = int64(len(r.entries) + 1)
+	entry.Sequence = stored.Sequence
+
+	r.entries = append(r.entries, stored)
+	r.ids[id] = true
+	return nil
+}
+
+// Query implements the AuditRepository interface
+func (r *MemoryAuditRepository) Query(ctx context.Context, filters interfaces.AuditFilters) ([]*models.AuditEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	results := make([]*models.AuditEntry, 0)
+	skipped := 0
+
+	for _, entry := range r.entries {
+		if !matchesAuditFilters(entry, filters) {
+			continue
+		}
+		if skipped < filters.Offset {
+			skipped++
+			continue
+		}
+
+		results = append(results, cloneAuditEntry(entry))
+		if filters.Limit > 0 && len(results) >= filters.Limit {
+			break
+		}
+	}
+
+	return results, nil
+}
+
+// Len returns the number of audit entries
+func (r *MemoryAuditRepository) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.entries)
+}
+
+// matchesAuditFilters checks if an audit entry matches the query filters
+func matchesAuditFilters(entry *models.AuditEntry, filters interfaces.AuditFilters) bool {
+	if filters.NotificationID != "" && entry.NotificationID != filters.NotificationID {
+		return false
+	}
+	if filters.Actor != "" && entry.Actor != filters.Actor {
+		return false
+	}
+	if filters.Outcome != nil && entry.Outcome != *filters.Outcome {
+		return false
+	}
+	if filters.From != nil && entry.Timestamp.Before(*filters.From) {
+		return false
+	}
+	if filters.To != nil && entry.Timestamp.After(*filters.To) {
+		return false
+	}
+	return true
+}
+
+// cloneAuditEntry copies an audit entry so stored entries cannot be mutated
+func cloneAuditEntry(entry *models.AuditEntry) *models.AuditEntry {
+	clone := *entry
+	if entry.Metadata != nil {
+		clone.Metadata = make(map[string]string, len(entry.Metadata))
+		for key, value := range entry.Metadata {
+			clone.Metadata[key] = value
+		}
+	}
+	return &clone
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+func TestMemoryAuditRepository_AppendOnly(t *testing.T) {
+	repo := NewMemoryAuditRepository()
+	ctx := context.Background()
+
+	entry := createTestAuditEntry("n-1", "key-a", models.AuditOutcomeAccepted, time.Now())
+	require.NoError(t, repo.Append(ctx, entry))
+	assert.Equal(t, int64(1), entry.Sequence)
+
+	// The same entry cannot be written twice
+	assert.Error(t, repo.Append(ctx, entry))
+	assert.Error(t, repo.Append(ctx, nil))
+
+	// Mutating the caller's entry or query results does not change the trail
+	entry.Outcome = models.AuditOutcomeRejected
+	results, err := repo.Query(ctx, interfaces.AuditFilters{})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, models.AuditOutcomeAccepted, results[0].Outcome)
+
+	results[0].Metadata["tampered"] = "yes"
+	results, _ = repo.Query(ctx, interfaces.AuditFilters{})
+	assert.NotContains(t, results[0].Metadata, "tampered")
+	assert.Equal(t, 1, repo.Len())
+}
+
+func TestMemoryAuditRepository_Query(t *testing.T) {
+	repo := NewMemoryAuditRepository()
+	ctx := context.Background()
+
+	base := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.Append(ctx, createTestAuditEntry("n-1", "key-a", models.AuditOutcomeAccepted, base)))
+	require.NoError(t, repo.Append(ctx, createTestAuditEntry("n-1", "key-a", models.AuditOutcomeSent, base.Add(time.Minute))))
+	require.NoError(t, repo.Append(ctx, createTestAuditEntry("n-2", "key-b", models.AuditOutcomeAccepted, base.Add(2*time.Minute))))
+	require.NoError(t, repo.Append(ctx, createTestAuditEntry("", "key-b", models.AuditOutcomeRejected, base.Add(3*time.Minute))))
+
+	byNotification, err := repo.Query(ctx, interfaces.AuditFilters{NotificationID: "n-1"})
+	require.NoError(t, err)
+	require.Len(t, byNotification, 2)
+	assert.Equal(t, models.AuditOutcomeAccepted, byNotification[0].Outcome)
+	assert.Equal(t, models.AuditOutcomeSent, byNotification[1].Outcome)
+
+	from, to := base.Add(time.Minute), base.Add(2*time.Minute)
+	inRange, err := repo.Query(ctx, interfaces.AuditFilters{From: &from, To: &to})
+	require.NoError(t, err)
+	assert.Len(t, inRange, 2)
+
+	rejected := models.AuditOutcomeRejected
+	byOutcome, err := repo.Query(ctx, interfaces.AuditFilters{Actor: "key-b", Outcome: &rejected})
+	require.NoError(t, err)
+	require.Len(t, byOutcome, 1)
+	assert.Equal(t, int64(4), byOutcome[0].Sequence)
+
+	page, err := repo.Query(ctx, interfaces.AuditFilters{Offset: 1, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, int64(2), page[0].Sequence)
+}
+
+// Helper functions
+
+func createTestAuditEntry(notificationID, actor string, outcome models.AuditOutcome, timestamp time.Time) *models.AuditEntry {
+	return &models.AuditEntry{
+		ID:             uuid.New(),
+		Timestamp:      timestamp,
+		Actor:          actor,
+		Action:         "notification.test",
+		Outcome:        outcome,
+		NotificationID: notificationID,
+		Metadata:       map[string]string{"source": "test"},
+	}
+}
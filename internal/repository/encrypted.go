@@ -0,0 +1,276 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/privacy"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// encryptedPageSize is the number of notifications rewrapped or purged per page
+const encryptedPageSize = 500
+
+// EncryptedRepository wraps a NotificationRepository and encrypts sensitive
+// notification fields before they are stored, decrypting them again on read.
+// Recipients are encrypted when a recipient cipher is given; Body, HTMLBody,
+// BodyTemplate and TemplateData values are encrypted when a content cipher is set.
+type EncryptedRepository struct {
+	next       interfaces.NotificationRepository
+	recipients *privacy.RecipientCipher
+	content    *privacy.EnvelopeCipher
+}
+
+// NewEncryptedRepository wraps a repository with recipient encryption. The
+// cipher may be nil when only content encryption is wanted.
+func NewEncryptedRepository(next interfaces.NotificationRepository, cipher *privacy.RecipientCipher) *EncryptedRepository {
+	return &EncryptedRepository{next: next, recipients: cipher}
+}
+
+// SetContentCipher enables envelope encryption of notification content
+func (r *EncryptedRepository) SetContentCipher(cipher *privacy.EnvelopeCipher) {
+	r.content = cipher
+}
+
+// Save implements the NotificationRepository interface
+func (r *EncryptedRepository) Save(ctx context.Context, notification *models.Notification) error {
+	if notification == nil {
+		return r.next.Save(ctx, notification)
+	}
+
+	encrypted, err := r.encrypt(notification)
+	if err != nil {
+		return err
+	}
+	return r.next.Save(ctx, encrypted)
+}
+
+// GetByID implements the NotificationRepository interface
+func (r *EncryptedRepository) GetByID(ctx context.Context, id string) (*models.Notification, error) {
+	notification, err := r.next.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return r.decrypt(notification)
+}
+
+// Update implements the NotificationRepository interface
+func (r *EncryptedRepository) Update(ctx context.Context, notification *models.Notification) error {
+	if notification == nil {
+		return r.next.Update(ctx, notification)
+	}
+
+	encrypted, err := r.encrypt(notification)
+	if err != nil {
+		return err
+	}
+	return r.next.Update(ctx, encrypted)
+}
+
+// List implements the NotificationRepository interface. Recipient filters
+// work because recipients encrypt deterministically.
+func (r *EncryptedRepository) List(ctx context.Context, filters interfaces.NotificationFilters) ([]*models.Notification, error) {
+	if filters.Recipient != "" && r.recipients != nil {
+		filters.Recipient = r.recipients.Encrypt(filters.Recipient)
+	}
+
+	notifications, err := r.next.List(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+	return r.decryptAll(notifications)
+}
+
+// Delete implements the NotificationRepository interface
+func (r *EncryptedRepository) Delete(ctx context.Context, id string) error {
+	return r.next.Delete(ctx, id)
+}
+
+// PurgeBefore implements the NotificationPurger interface. Purging needs no
+// decryption, so it is passed to the wrapped repository; one that cannot
+// purge has the notifications deleted one by one instead.
+func (r *EncryptedRepository) PurgeBefore(ctx context.Context, cutoff time.Time) (int, error) {
+	if purger, ok := r.next.(interfaces.NotificationPurger); ok {
+		return purger.PurgeBefore(ctx, cutoff)
+	}
+
+	// Deleted notifications drop out of List, so the first page is always read
+	before := cutoff.Format(time.RFC3339)
+	filters := interfaces.NotificationFilters{DateTo: &before, Limit: encryptedPageSize, SortBy: "created_at", SortOrder: "asc"}
+	purged := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return purged, err
+		}
+
+		notifications, err := r.next.List(ctx, filters)
+		if err != nil {
+			return purged, err
+		}
+		for _, notification := range notifications {
+			if err := r.next.Delete(ctx, notification.ID.String()); err != nil {
+				return purged, err
+			}
+			purged++
+		}
+		if len(notifications) < filters.Limit {
+			return purged, nil
+		}
+	}
+}
+
+// GetPendingNotifications implements the NotificationRepository interface
+func (r *EncryptedRepository) GetPendingNotifications(ctx context.Context, limit int) ([]*models.Notification, error) {
+	notifications, err := r.next.GetPendingNotifications(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	return r.decryptAll(notifications)
+}
+
+// RewrapContent re-encrypts the data keys of stored content under the
+// content keyring's primary key, so retired keys can be removed after a
+// rotation. Content itself is not re-encrypted. It returns the number of
+// notifications updated and is safe to run again if interrupted.
+func (r *EncryptedRepository) RewrapContent(ctx context.Context) (int, error) {
+	if r.content == nil {
+		return 0, nil
+	}
+
+	filters := interfaces.NotificationFilters{Limit: encryptedPageSize, SortBy: "created_at", SortOrder: "asc"}
+	rewrapped := 0
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return rewrapped, err
+		}
+
+		notifications, err := r.next.List(ctx, filters)
+		if err != nil {
+			return rewrapped, err
+		}
+
+		for _, notification := range notifications {
+			changed, err := r.rewrap(notification)
+			if err != nil {
+				return rewrapped, err
+			}
+			if !changed {
+				continue
+			}
+			if err := r.next.Update(ctx, notification); err != nil {
+				return rewrapped, err
+			}
+			rewrapped++
+		}
+
+		if len(notifications) < filters.Limit {
+			return rewrapped, nil
+		}
+		filters.Offset += len(notifications)
+	}
+}
+
+// encrypt returns a copy of the notification with its sensitive fields encrypted
+func (r *EncryptedRepository) encrypt(notification *models.Notification) (*models.Notification, error) {
+	encrypted := *notification
+
+	if r.recipients != nil {
+		encrypted.Recipient = r.recipients.Encrypt(notification.Recipient)
+	}
+
+	if r.content != nil {
+		var err error
+		if encrypted.Body, err = r.content.Encrypt(notification.Body); err != nil {
+			return nil, err
+		}
+		if encrypted.HTMLBody, err = r.content.Encrypt(notification.HTMLBody); err != nil {
+			return nil, err
+		}
+		if encrypted.BodyTemplate, err = r.content.Encrypt(notification.BodyTemplate); err != nil {
+			return nil, err
+		}
+		if notification.TemplateData != nil {
+			encrypted.TemplateData = make(map[string]string, len(notification.TemplateData))
+			for key, value := range notification.TemplateData {
+				if encrypted.TemplateData[key], err = r.content.Encrypt(value); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return &encrypted, nil
+}
+
+// decrypt decrypts a notification's sensitive fields in place
+func (r *EncryptedRepository) decrypt(notification *models.Notification) (*models.Notification, error) {
+	var err error
+
+	if r.recipients != nil {
+		if notification.Recipient, err = r.recipients.Decrypt(notification.Recipient); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.content != nil {
+		if notification.Body, err = r.content.Decrypt(notification.Body); err != nil {
+			return nil, err
+		}
+		if notification.HTMLBody, err = r.content.Decrypt(notification.HTMLBody); err != nil {
+			return nil, err
+		}
+		if notification.BodyTemplate, err = r.content.Decrypt(notification.BodyTemplate); err != nil {
+			return nil, err
+		}
+		for key, value := range notification.TemplateData {
+			if notification.TemplateData[key], err = r.content.Decrypt(value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return notification, nil
+}
+
+// decryptAll decrypts the sensitive fields of a result set
+func (r *EncryptedRepository) decryptAll(notifications []*models.Notification) ([]*models.Notification, error) {
+	for _, notification := range notifications {
+		if _, err := r.decrypt(notification); err != nil {
+			return nil, err
+		}
+	}
+	return notifications, nil
+}
+
+// rewrap rewraps a stored notification's content in place and reports whether it changed
+func (r *EncryptedRepository) rewrap(notification *models.Notification) (bool, error) {
+	changed := false
+
+	rewrapField := func(value string) (string, error) {
+		rewrapped, fieldChanged, err := r.content.Rewrap(value)
+		if fieldChanged {
+			changed = true
+		}
+		return rewrapped, err
+	}
+
+	var err error
+	if notification.Body, err = rewrapField(notification.Body); err != nil {
+		return false, err
+	}
+	if notification.HTMLBody, err = rewrapField(notification.HTMLBody); err != nil {
+		return false, err
+	}
+	if notification.BodyTemplate, err = rewrapField(notification.BodyTemplate); err != nil {
+		return false, err
+	}
+	for key, value := range notification.TemplateData {
+		if notification.TemplateData[key], err = rewrapField(value); err != nil {
+			return false, err
+		}
+	}
+
+	return changed, nil
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/privacy"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+func TestEncryptedRepository_EncryptsRecipients(t *testing.T) {
+	inner := NewMemoryRepository()
+	repo := NewEncryptedRepository(inner, createTestRecipientCipher(t))
+	ctx := context.Background()
+
+	notification := createTestNotification(models.NotificationTypeEmail, time.Now())
+	require.NoError(t, repo.Save(ctx, notification))
+
+	// The caller's notification is not modified
+	assert.Equal(t, "user@example.com", notification.Recipient)
+
+	stored, err := inner.GetByID(ctx, notification.ID.String())
+	require.NoError(t, err)
+	assert.True(t, privacy.IsEncrypted(stored.Recipient))
+
+	found, err := repo.GetByID(ctx, notification.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, "user@example.com", found.Recipient)
+
+	notification.Status = models.StatusSent
+	require.NoError(t, repo.Update(ctx, notification))
+	stored, _ = inner.GetByID(ctx, notification.ID.String())
+	assert.True(t, privacy.IsEncrypted(stored.Recipient))
+	assert.Equal(t, models.StatusSent, stored.Status)
+}
+
+func TestEncryptedRepository_ListByRecipient(t *testing.T) {
+	repo := NewEncryptedRepository(NewMemoryRepository(), createTestRecipientCipher(t))
+	ctx := context.Background()
+
+	mine := createTestNotification(models.NotificationTypeEmail, time.Now())
+	other := createTestNotification(models.NotificationTypeEmail, time.Now())
+	other.Recipient = "other@example.com"
+	require.NoError(t, repo.Save(ctx, mine))
+	require.NoError(t, repo.Save(ctx, other))
+
+	results, err := repo.List(ctx, interfaces.NotificationFilters{Recipient: "user@example.com"})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, mine.ID, results[0].ID)
+	assert.Equal(t, "user@example.com", results[0].Recipient)
+
+	pending, err := repo.GetPendingNotifications(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	for _, notification := range pending {
+		assert.False(t, privacy.IsEncrypted(notification.Recipient))
+	}
+}
+
+func TestEncryptedRepository_EncryptsContent(t *testing.T) {
+	inner := NewMemoryRepository()
+	repo := NewEncryptedRepository(inner, nil)
+	keyring := createTestContentKeyring(t)
+	repo.SetContentCipher(privacy.NewEnvelopeCipher(keyring))
+	ctx := context.Background()
+
+	notification := createTestNotification(models.NotificationTypeEmail, time.Now())
+	notification.HTMLBody = "<p>Test notification</p>"
+	notification.BodyTemplate = "Your code is {{code}}"
+	notification.TemplateData = map[string]string{"code": "123456"}
+	require.NoError(t, repo.Save(ctx, notification))
+
+	stored, err := inner.GetByID(ctx, notification.ID.String())
+	require.NoError(t, err)
+	assert.True(t, privacy.IsEnvelope(stored.Body))
+	assert.True(t, privacy.IsEnvelope(stored.HTMLBody))
+	assert.True(t, privacy.IsEnvelope(stored.BodyTemplate))
+	assert.True(t, privacy.IsEnvelope(stored.TemplateData["code"]))
+	assert.Equal(t, "user@example.com", stored.Recipient)
+
+	found, err := repo.GetByID(ctx, notification.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, "Test notification", found.Body)
+	assert.Equal(t, "<p>Test notification</p>", found.HTMLBody)
+	assert.Equal(t, "Your code is {{code}}", found.BodyTemplate)
+	assert.Equal(t, "123456", found.TemplateData["code"])
+	assert.Equal(t, "123456", notification.TemplateData["code"])
+
+	// A body that looks like an envelope is stored encrypted and reads back
+	lookalike := createTestNotification(models.NotificationTypeSMS, time.Now())
+	lookalike.Body = "env:v1:k1:see:attached"
+	require.NoError(t, repo.Save(ctx, lookalike))
+	found, err = repo.GetByID(ctx, lookalike.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, "env:v1:k1:see:attached", found.Body)
+	listed, err := repo.List(ctx, interfaces.NotificationFilters{})
+	require.NoError(t, err)
+	assert.Len(t, listed, 2)
+}
+
+func TestEncryptedRepository_RewrapContent(t *testing.T) {
+	inner := NewMemoryRepository()
+	repo := NewEncryptedRepository(inner, nil)
+	keyring := createTestContentKeyring(t)
+	repo.SetContentCipher(privacy.NewEnvelopeCipher(keyring))
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, repo.Save(ctx, createTestNotification(models.NotificationTypeSMS, time.Now())))
+	}
+
+	require.NoError(t, keyring.Rotate("k2", []byte(strings.Repeat("2", 32))))
+
+	rewrapped, err := repo.RewrapContent(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, rewrapped)
+
+	// The old key can be retired once everything is rewrapped
+	require.NoError(t, keyring.Remove("k1"))
+
+	stored, err := inner.List(ctx, interfaces.NotificationFilters{})
+	require.NoError(t, err)
+	for _, notification := range stored {
+		assert.True(t, strings.HasPrefix(notification.Body, "env:v1:k2:"))
+	}
+
+	results, err := repo.List(ctx, interfaces.NotificationFilters{})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.Equal(t, "Test notification", results[0].Body)
+
+	rewrapped, err = repo.RewrapContent(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, rewrapped)
+}
+
+func TestEncryptedRepository_PurgeBefore(t *testing.T) {
+	inner := NewMemoryRepository()
+	repo := NewEncryptedRepository(inner, createTestRecipientCipher(t))
+	ctx := context.Background()
+
+	for _, createdAt := range []time.Time{time.Now().Add(-48 * time.Hour), time.Now().Add(-time.Hour), time.Now()} {
+		require.NoError(t, repo.Save(ctx, createTestNotification(models.NotificationTypeEmail, createdAt)))
+	}
+
+	// Passed through, so notifications are removed rather than soft deleted
+	purged, err := repo.PurgeBefore(ctx, time.Now().Add(-30*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 2, purged)
+	assert.Equal(t, 1, inner.Count())
+	assert.Len(t, inner.notifications, 1) // none left soft deleted
+}
+
+// Helper functions
+
+func createTestRecipientCipher(t *testing.T) *privacy.RecipientCipher {
+	cipher, err := privacy.NewRecipientCipher(base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32))))
+	require.NoError(t, err)
+	return cipher
+}
+
+func createTestContentKeyring(t *testing.T) *privacy.Keyring {
+	keyring, err := privacy.NewKeyring("k1", []byte(strings.Repeat("1", 32)))
+	require.NoError(t, err)
+	return keyring
+}
@@ -0,0 +1,264 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// MemoryRepository implements the NotificationRepository interface with in-memory storage
+type MemoryRepository struct {
+	mu            sync.RWMutex
+	notifications map[string]*models.Notification
+	deleted       map[string]bool
+}
+
+// NewMemoryRepository creates a new in-memory notification repository
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		notifications: make(map[string]*models.Notification),
+		deleted:       make(map[string]bool),
+	}
+}
+
+// Save implements the NotificationRepository interface
+func (r *MemoryRepository) Save(ctx context.Context, notification *models.Notification) error {
+	if notification == nil {
+		return errors.NewValidationError("notification", "notification is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := notification.ID.String()
+	if _, exists := r.notifications[id]; exists {
+		return errors.NewNotificationError(errors.ErrorCodeInvalidNotification, "notification already exists")
+	}
+
+	r.notifications[id] = cloneNotification(notification)
+	return nil
+}
+
+// GetByID implements the NotificationRepository interface
+func (r *MemoryRepository) GetByID(ctx context.Context, id string) (*models.Notification, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	notification, exists := r.notifications[id]
+	if !exists || r.deleted[id] {
+		return nil, errors.ErrNotificationNotFound
+	}
+
+	return cloneNotification(notification), nil
+}
+
+// Update implements the NotificationRepository interface
+func (r *MemoryRepository) Update(ctx context.Context, notification *models.Notification) error {
+	if notification == nil {
+		return errors.NewValidationError("notification", "notification is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := notification.ID.String()
+	if _, exists := r.notifications[id]; !exists || r.deleted[id] {
+		return errors.ErrNotificationNotFound
+	}
+
+	updated := cloneNotification(notification)
+	updated.UpdatedAt = time.Now()
+	r.notifications[id] = updated
+	return nil
+}
+
+// List implements the NotificationRepository interface
+func (r *MemoryRepository) List(ctx context.Context, filters interfaces.NotificationFilters) ([]*models.Notification, error) {
+	dateFrom, dateTo, err := parseDateRange(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	results := make([]*models.Notification, 0)
+	for id, notification := range r.notifications {
+		if r.deleted[id] || !matchesFilters(notification, filters, dateFrom, dateTo) {
+			continue
+		}
+		results = append(results, cloneNotification(notification))
+	}
+	r.mu.RUnlock()
+
+	sortNotifications(results, filters.SortBy, filters.SortOrder)
+
+	return paginate(results, filters.Offset, filters.Limit), nil
+}
+
+// Delete implements the NotificationRepository interface
+func (r *MemoryRepository) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.notifications[id]; !exists || r.deleted[id] {
+		return errors.ErrNotificationNotFound
+	}
+
+	r.deleted[id] = true
+	return nil
+}
+
+// GetPendingNotifications implements the NotificationRepository interface
+func (r *MemoryRepository) GetPendingNotifications(ctx context.Context, limit int) ([]*models.Notification, error) {
+	now := time.Now()
+
+	r.mu.RLock()
+	results := make([]*models.Notification, 0)
+	for id, notification := range r.notifications {
+		if r.deleted[id] || notification.Status != models.StatusPending {
+			continue
+		}
+		// Scheduled notifications are not pending until they are due
+		if notification.ScheduledAt != nil && notification.ScheduledAt.After(now) {
+			continue
+		}
+		results = append(results, cloneNotification(notification))
+	}
+	r.mu.RUnlock()
+
+	sortNotifications(results, "created_at", "asc")
+
+	return paginate(results, 0, limit), nil
+}
+
+// PurgeBefore implements the NotificationPurger interface. Soft deleted
+// notifications are removed too.
+func (r *MemoryRepository) PurgeBefore(ctx context.Context, cutoff time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purged := 0
+	for id, notification := range r.notifications {
+		if !notification.CreatedAt.Before(cutoff) {
+			continue
+		}
+
+		delete(r.notifications, id)
+		delete(r.deleted, id)
+		purged++
+	}
+
+	return purged, nil
+}
+
+// Count returns the number of stored notifications, excluding deleted ones
+func (r *MemoryRepository) Count() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.notifications) - len(r.deleted)
+}
+
+// Helper functions
+
+// cloneNotification copies a notification so callers cannot mutate stored state
+func cloneNotification(notification *models.Notification) *models.Notification {
+	clone := *notification
+	if notification.Metadata != nil {
+		clone.Metadata = make(map[string]string, len(notification.Metadata))
+		for key, value := range notification.Metadata {
+			clone.Metadata[key] = value
+		}
+	}
+	if notification.TemplateData != nil {
+		clone.TemplateData = make(map[string]string, len(notification.TemplateData))
+		for key, value := range notification.TemplateData {
+			clone.TemplateData[key] = value
+		}
+	}
+	return &clone
+}
+
+// parseDateRange parses the RFC 3339 date filters
+func parseDateRange(filters interfaces.NotificationFilters) (*time.Time, *time.Time, error) {
+	var dateFrom, dateTo *time.Time
+
+	if filters.DateFrom != nil && *filters.DateFrom != "" {
+		parsed, err := time.Parse(time.RFC3339, *filters.DateFrom)
+		if err != nil {
+			return nil, nil, errors.NewValidationError("date_from", "date must be in RFC 3339 format")
+		}
+		dateFrom = &parsed
+	}
+
+	if filters.DateTo != nil && *filters.DateTo != "" {
+		parsed, err := time.Parse(time.RFC3339, *filters.DateTo)
+		if err != nil {
+			return nil, nil, errors.NewValidationError("date_to", "date must be in RFC 3339 format")
+		}
+		dateTo = &parsed
+	}
+
+	return dateFrom, dateTo, nil
+}
+
+// matchesFilters checks if a notification matches the query filters
+func matchesFilters(notification *models.Notification, filters interfaces.NotificationFilters, dateFrom, dateTo *time.Time) bool {
+	if filters.Type != nil && notification.Type != *filters.Type {
+		return false
+	}
+	if filters.Status != nil && notification.Status != *filters.Status {
+		return false
+	}
+	if filters.Priority != nil && notification.Priority != *filters.Priority {
+		return false
+	}
+	if filters.Recipient != "" && notification.Recipient != filters.Recipient {
+		return false
+	}
+	if dateFrom != nil && notification.CreatedAt.Before(*dateFrom) {
+		return false
+	}
+	if dateTo != nil && notification.CreatedAt.After(*dateTo) {
+		return false
+	}
+	return true
+}
+
+// sortNotifications sorts notifications by a field, newest first by default
+func sortNotifications(notifications []*models.Notification, sortBy, sortOrder string) {
+	ascending := sortOrder == "asc"
+
+	sort.SliceStable(notifications, func(i, j int) bool {
+		var a, b time.Time
+		switch sortBy {
+		case "updated_at":
+			a, b = notifications[i].UpdatedAt, notifications[j].UpdatedAt
+		default:
+			a, b = notifications[i].CreatedAt, notifications[j].CreatedAt
+		}
+
+		if ascending {
+			return a.Before(b)
+		}
+		return a.After(b)
+	})
+}
+
+// paginate applies offset and limit to a result set. A limit of zero returns all results.
+func paginate(notifications []*models.Notification, offset, limit int) []*models.Notification {
+	if offset >= len(notifications) {
+		return []*models.Notification{}
+	}
+	if offset > 0 {
+		notifications = notifications[offset:]
+	}
+	if limit > 0 && limit < len(notifications) {
+		notifications = notifications[:limit]
+	}
+	return notifications
+}
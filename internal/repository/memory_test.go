@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+func TestMemoryRepository_SaveAndGet(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	notification := createTestNotification(models.NotificationTypeEmail, time.Now())
+	require.NoError(t, repo.Save(ctx, notification))
+
+	stored, err := repo.GetByID(ctx, notification.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, notification.ID, stored.ID)
+
+	// Stored notifications are copies
+	stored.Metadata["source"] = "changed"
+	again, err := repo.GetByID(ctx, notification.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, "test", again.Metadata["source"])
+
+	// Duplicate saves are rejected
+	require.Error(t, repo.Save(ctx, notification))
+}
+
+func TestMemoryRepository_GetByID_NotFound(t *testing.T) {
+	repo := NewMemoryRepository()
+
+	_, err := repo.GetByID(context.Background(), uuid.New().String())
+	require.Error(t, err)
+
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeNotFound, notifErr.Code)
+}
+
+func TestMemoryRepository_UpdateAndDelete(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	notification := createTestNotification(models.NotificationTypeSMS, time.Now())
+	require.NoError(t, repo.Save(ctx, notification))
+
+	notification.Status = models.StatusSent
+	require.NoError(t, repo.Update(ctx, notification))
+
+	stored, err := repo.GetByID(ctx, notification.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusSent, stored.Status)
+
+	require.NoError(t, repo.Delete(ctx, notification.ID.String()))
+	_, err = repo.GetByID(ctx, notification.ID.String())
+	require.Error(t, err)
+	require.Error(t, repo.Update(ctx, notification))
+	require.Error(t, repo.Delete(ctx, notification.ID.String()))
+	assert.Equal(t, 0, repo.Count())
+}
+
+func TestMemoryRepository_List(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+	base := time.Now().Add(-time.Hour)
+
+	for i := 0; i < 5; i++ {
+		notificationType := models.NotificationTypeEmail
+		if i%2 == 1 {
+			notificationType = models.NotificationTypePush
+		}
+		require.NoError(t, repo.Save(ctx, createTestNotification(notificationType, base.Add(time.Duration(i)*time.Minute))))
+	}
+
+	all, err := repo.List(ctx, interfaces.NotificationFilters{})
+	require.NoError(t, err)
+	require.Len(t, all, 5)
+	assert.True(t, all[0].CreatedAt.After(all[4].CreatedAt)) // Newest first by default
+
+	pushType := models.NotificationTypePush
+	push, err := repo.List(ctx, interfaces.NotificationFilters{Type: &pushType})
+	require.NoError(t, err)
+	assert.Len(t, push, 2)
+
+	page, err := repo.List(ctx, interfaces.NotificationFilters{Limit: 2, Offset: 1, SortOrder: "asc"})
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.True(t, page[0].CreatedAt.Before(page[1].CreatedAt))
+
+	from := base.Add(150 * time.Second).Format(time.RFC3339)
+	recent, err := repo.List(ctx, interfaces.NotificationFilters{DateFrom: &from})
+	require.NoError(t, err)
+	assert.Len(t, recent, 2)
+
+	invalid := "yesterday"
+	_, err = repo.List(ctx, interfaces.NotificationFilters{DateTo: &invalid})
+	require.Error(t, err)
+}
+
+func TestMemoryRepository_GetPendingNotifications(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+
+	due := createTestNotification(models.NotificationTypeEmail, time.Now())
+	sent := createTestNotification(models.NotificationTypeEmail, time.Now())
+	sent.Status = models.StatusSent
+	future := time.Now().Add(time.Hour)
+	scheduled := createTestNotification(models.NotificationTypeEmail, time.Now())
+	scheduled.ScheduledAt = &future
+
+	for _, notification := range []*models.Notification{due, sent, scheduled} {
+		require.NoError(t, repo.Save(ctx, notification))
+	}
+
+	pending, err := repo.GetPendingNotifications(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, due.ID, pending[0].ID)
+}
+
+func TestMemoryRepository_PurgeBefore(t *testing.T) {
+	repo := NewMemoryRepository()
+	ctx := context.Background()
+	now := time.Now()
+
+	old := createTestNotification(models.NotificationTypeEmail, now.Add(-48*time.Hour))
+	oldDeleted := createTestNotification(models.NotificationTypeEmail, now.Add(-72*time.Hour))
+	recent := createTestNotification(models.NotificationTypeEmail, now)
+	for _, notification := range []*models.Notification{old, oldDeleted, recent} {
+		require.NoError(t, repo.Save(ctx, notification))
+	}
+	require.NoError(t, repo.Delete(ctx, oldDeleted.ID.String()))
+
+	purged, err := repo.PurgeBefore(ctx, now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 2, purged)
+	assert.Equal(t, 1, repo.Count())
+
+	// Purged IDs are free to be saved again
+	require.NoError(t, repo.Save(ctx, oldDeleted))
+}
+
+// Helper functions
+
+func createTestNotification(notificationType models.NotificationType, createdAt time.Time) *models.Notification {
+	return &models.Notification{
+		ID:        uuid.New(),
+		Type:      notificationType,
+		Status:    models.StatusPending,
+		Priority:  models.PriorityNormal,
+		Recipient: "user@example.com",
+		Body:      "Test notification",
+		Metadata:  map[string]string{"source": "test"},
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}
+}
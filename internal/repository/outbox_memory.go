@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// MemoryOutbox implements the Outbox interface in memory, for tests and
+// single-process deployments
+type MemoryOutbox struct {
+	mu      sync.Mutex
+	entries []*models.OutboxEntry
+	byID    map[uuid.UUID]*models.OutboxEntry
+	now     func() time.Time
+}
+
+// NewMemoryOutbox creates an empty in-memory outbox
+func NewMemoryOutbox() *MemoryOutbox {
+	return &MemoryOutbox{
+		byID: make(map[uuid.UUID]*models.OutboxEntry),
+		now:  time.Now,
+	}
+}
+
+// Add implements the Outbox interface
+func (o *MemoryOutbox) Add(ctx context.Context, entry *models.OutboxEntry) error {
+	if entry == nil {
+		return errors.NewValidationError("entry", "outbox entry is required")
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if entry.ID == uuid.Nil {
+		entry.ID = uuid.New()
+	}
+	if _, exists := o.byID[entry.ID]; exists {
+		return errors.NewNotificationError(errors.ErrorCodeInvalidRequest, fmt.Sprintf("outbox entry %s already exists", entry.ID))
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = o.now()
+	}
+
+	stored := cloneOutboxEntry(entry)
+	o.entries = append(o.entries, stored)
+	o.byID[stored.ID] = stored
+	return nil
+}
+
+// Claim implements the Outbox interface
+func (o *MemoryOutbox) Claim(ctx context.Context, limit int, lease time.Duration) ([]*models.OutboxEntry, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := o.now()
+	until := now.Add(lease)
+	claimed := make([]*models.OutboxEntry, 0)
+	for _, entry := range o.entries {
+		if limit > 0 && len(claimed) >= limit {
+			break
+		}
+		if entry.ProcessedAt != nil || (entry.ClaimedUntil != nil && entry.ClaimedUntil.After(now)) {
+			continue
+		}
+
+		entry.ClaimedUntil = &until
+		entry.Attempts++
+		claimed = append(claimed, cloneOutboxEntry(entry))
+	}
+	return claimed, nil
+}
+
+// MarkProcessed implements the Outbox interface
+func (o *MemoryOutbox) MarkProcessed(ctx context.Context, id uuid.UUID) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	entry, exists := o.byID[id]
+	if !exists {
+		return errors.NewNotificationError(errors.ErrorCodeNotFound, fmt.Sprintf("outbox entry %s not found", id))
+	}
+	if entry.ProcessedAt == nil {
+		now := o.now()
+		entry.ProcessedAt = &now
+		entry.ClaimedUntil = nil
+	}
+	return nil
+}
+
+// Pending returns the number of entries not yet processed
+func (o *MemoryOutbox) Pending() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	pending := 0
+	for _, entry := range o.entries {
+		if entry.ProcessedAt == nil {
+			pending++
+		}
+	}
+	return pending
+}
+
+// cloneOutboxEntry returns a copy of an entry so callers cannot change stored state
+func cloneOutboxEntry(entry *models.OutboxEntry) *models.OutboxEntry {
+	clone := *entry
+	if entry.Metadata != nil {
+		clone.Metadata = make(map[string]string, len(entry.Metadata))
+		for key, value := range entry.Metadata {
+			clone.Metadata[key] = value
+		}
+	}
+	if entry.ClaimedUntil != nil {
+		claimedUntil := *entry.ClaimedUntil
+		clone.ClaimedUntil = &claimedUntil
+	}
+	if entry.ProcessedAt != nil {
+		processedAt := *entry.ProcessedAt
+		clone.ProcessedAt = &processedAt
+	}
+	return &clone
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+)
+
+func TestMemoryOutbox_ClaimAndMarkProcessed(t *testing.T) {
+	outbox := NewMemoryOutbox()
+	clock := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	outbox.now = func() time.Time { return clock }
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, outbox.Add(ctx, createTestOutboxEntry()))
+	}
+	assert.Equal(t, 3, outbox.Pending())
+
+	claimed, err := outbox.Claim(ctx, 2, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 2)
+	assert.Equal(t, 1, claimed[0].Attempts)
+
+	// Leased entries are skipped
+	rest, err := outbox.Claim(ctx, 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, rest, 1)
+
+	require.NoError(t, outbox.MarkProcessed(ctx, claimed[0].ID))
+	require.NoError(t, outbox.MarkProcessed(ctx, claimed[0].ID)) // idempotent
+	assert.Equal(t, 2, outbox.Pending())
+
+	// Expired leases are claimed again; processed entries never are
+	clock = clock.Add(2 * time.Minute)
+	again, err := outbox.Claim(ctx, 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, again, 2)
+	assert.Equal(t, claimed[1].ID, again[0].ID)
+	assert.Equal(t, 2, again[0].Attempts)
+
+	assert.Error(t, outbox.MarkProcessed(ctx, uuid.New()))
+}
+
+func TestMemoryOutbox_Add(t *testing.T) {
+	outbox := NewMemoryOutbox()
+	ctx := context.Background()
+
+	entry := createTestOutboxEntry()
+	require.NoError(t, outbox.Add(ctx, entry))
+	assert.NotEqual(t, uuid.Nil, entry.ID)
+	assert.False(t, entry.CreatedAt.IsZero())
+
+	assert.Error(t, outbox.Add(ctx, entry))
+	assert.Error(t, outbox.Add(ctx, nil))
+
+	// Stored entries are copies
+	entry.Metadata["order_id"] = "changed"
+	claimed, err := outbox.Claim(ctx, 1, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "42", claimed[0].Metadata["order_id"])
+}
+
+// Helper functions
+
+func createTestOutboxEntry() *models.OutboxEntry {
+	return &models.OutboxEntry{
+		Request: models.NotificationRequest{
+			Type:      models.NotificationTypeEmail,
+			Priority:  models.PriorityNormal,
+			Recipient: "user@example.com",
+			Subject:   "Order shipped",
+			Body:      "Your order has shipped",
+		},
+		Metadata: map[string]string{"order_id": "42"},
+	}
+}
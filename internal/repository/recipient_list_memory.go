@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// MemoryRecipientListRepository implements the RecipientListRepository interface with in-memory storage
+type MemoryRecipientListRepository struct {
+	mu      sync.RWMutex
+	lists   map[string]*models.RecipientList
+	members map[string][]models.ListMember // list ID to members, in insertion order
+}
+
+// NewMemoryRecipientListRepository creates a new in-memory recipient list repository
+func NewMemoryRecipientListRepository() *MemoryRecipientListRepository {
+	return &MemoryRecipientListRepository{
+		lists:   make(map[string]*models.RecipientList),
+		members: make(map[string][]models.ListMember),
+	}
+}
+
+// CreateList implements the RecipientListRepository interface
+func (r *MemoryRecipientListRepository) CreateList(ctx context.Context, list *models.RecipientList) error {
+	if list == nil {
+		return errors.NewValidationError("list", "recipient list is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := list.ID.String()
+	if _, exists := r.lists[id]; exists {
+		return errors.NewNotificationError(errors.ErrorCodeInvalidRequest, "recipient list already exists")
+	}
+	for _, existing := range r.lists {
+		if existing.Name == list.Name {
+			return errors.NewValidationError("name", fmt.Sprintf("recipient list name already in use: %s", list.Name))
+		}
+	}
+
+	stored := *list
+	stored.MemberCount = 0
+	r.lists[id] = &stored
+	r.members[id] = nil
+	return nil
+}
+
+// GetList implements the RecipientListRepository interface
+func (r *MemoryRecipientListRepository) GetList(ctx context.Context, id string) (*models.RecipientList, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list, exists := r.lists[id]
+	if !exists {
+		return nil, listNotFound(id)
+	}
+
+	clone := *list
+	return &clone, nil
+}
+
+// GetListByName implements the RecipientListRepository interface
+func (r *MemoryRecipientListRepository) GetListByName(ctx context.Context, name string) (*models.RecipientList, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, list := range r.lists {
+		if list.Name == name {
+			clone := *list
+			return &clone, nil
+		}
+	}
+
+	return nil, listNotFound(name)
+}
+
+// ListLists implements the RecipientListRepository interface
+func (r *MemoryRecipientListRepository) ListLists(ctx context.Context) ([]*models.RecipientList, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	lists := make([]*models.RecipientList, 0, len(r.lists))
+	for _, list := range r.lists {
+		clone := *list
+		lists = append(lists, &clone)
+	}
+
+	sort.Slice(lists, func(i, j int) bool {
+		return lists[i].Name < lists[j].Name
+	})
+	return lists, nil
+}
+
+// DeleteList implements the RecipientListRepository interface
+func (r *MemoryRecipientListRepository) DeleteList(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.lists[id]; !exists {
+		return listNotFound(id)
+	}
+
+	delete(r.lists, id)
+	delete(r.members, id)
+	return nil
+}
+
+// AddMembers implements the RecipientListRepository interface
+func (r *MemoryRecipientListRepository) AddMembers(ctx context.Context, listID string, members []models.ListMember) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list, exists := r.lists[listID]
+	if !exists {
+		return listNotFound(listID)
+	}
+
+	now := time.Now()
+	current := r.members[listID]
+	index := make(map[string]int, len(current))
+	for i, member := range current {
+		index[member.Recipient] = i
+	}
+
+	for _, member := range members {
+		stored := cloneMember(member)
+		if i, exists := index[member.Recipient]; exists {
+			stored.AddedAt = current[i].AddedAt
+			current[i] = stored
+			continue
+		}
+
+		if stored.AddedAt.IsZero() {
+			stored.AddedAt = now
+		}
+		index[member.Recipient] = len(current)
+		current = append(current, stored)
+	}
+
+	r.members[listID] = current
+	list.MemberCount = len(current)
+	list.UpdatedAt = now
+	return nil
+}
+
+// RemoveMembers implements the RecipientListRepository interface
+func (r *MemoryRecipientListRepository) RemoveMembers(ctx context.Context, listID string, recipients []string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list, exists := r.lists[listID]
+	if !exists {
+		return 0, listNotFound(listID)
+	}
+
+	remove := make(map[string]bool, len(recipients))
+	for _, recipient := range recipients {
+		remove[recipient] = true
+	}
+
+	current := r.members[listID]
+	kept := current[:0]
+	for _, member := range current {
+		if !remove[member.Recipient] {
+			kept = append(kept, member)
+		}
+	}
+
+	removed := len(current) - len(kept)
+	r.members[listID] = kept
+	list.MemberCount = len(kept)
+	if removed > 0 {
+		list.UpdatedAt = time.Now()
+	}
+	return removed, nil
+}
+
+// GetMembers implements the RecipientListRepository interface
+func (r *MemoryRecipientListRepository) GetMembers(ctx context.Context, listID string) ([]models.ListMember, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, exists := r.lists[listID]; !exists {
+		return nil, listNotFound(listID)
+	}
+
+	members := make([]models.ListMember, 0, len(r.members[listID]))
+	for _, member := range r.members[listID] {
+		members = append(members, cloneMember(member))
+	}
+	return members, nil
+}
+
+// listNotFound returns the error for a missing recipient list
+func listNotFound(id string) error {
+	return errors.NewNotificationError(errors.ErrorCodeNotFound, fmt.Sprintf("recipient list not found: %s", id))
+}
+
+// cloneMember copies a list member so callers cannot mutate stored state
+func cloneMember(member models.ListMember) models.ListMember {
+	clone := member
+	if member.Attributes != nil {
+		clone.Attributes = make(map[string]string, len(member.Attributes))
+		for key, value := range member.Attributes {
+			clone.Attributes[key] = value
+		}
+	}
+	return clone
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestMemoryRecipientListRepository_Lists(t *testing.T) {
+	repo := NewMemoryRecipientListRepository()
+	ctx := context.Background()
+
+	list := createTestRecipientList("newsletter")
+	require.NoError(t, repo.CreateList(ctx, list))
+	require.NoError(t, repo.CreateList(ctx, createTestRecipientList("beta")))
+
+	// Names are unique
+	err := repo.CreateList(ctx, createTestRecipientList("newsletter"))
+	require.Error(t, err)
+
+	stored, err := repo.GetList(ctx, list.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, "newsletter", stored.Name)
+
+	byName, err := repo.GetListByName(ctx, "newsletter")
+	require.NoError(t, err)
+	assert.Equal(t, list.ID, byName.ID)
+
+	lists, err := repo.ListLists(ctx)
+	require.NoError(t, err)
+	require.Len(t, lists, 2)
+	assert.Equal(t, "beta", lists[0].Name)
+
+	require.NoError(t, repo.DeleteList(ctx, list.ID.String()))
+	_, err = repo.GetList(ctx, list.ID.String())
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeNotFound, notifErr.Code)
+	assert.Error(t, repo.DeleteList(ctx, list.ID.String()))
+}
+
+func TestMemoryRecipientListRepository_Members(t *testing.T) {
+	repo := NewMemoryRecipientListRepository()
+	ctx := context.Background()
+
+	list := createTestRecipientList("newsletter")
+	require.NoError(t, repo.CreateList(ctx, list))
+	id := list.ID.String()
+
+	require.NoError(t, repo.AddMembers(ctx, id, []models.ListMember{
+		{Recipient: "a@example.com", Attributes: map[string]string{"plan": "free"}},
+		{Recipient: "b@example.com"},
+	}))
+
+	// Re-adding a member replaces its attributes and keeps its position
+	require.NoError(t, repo.AddMembers(ctx, id, []models.ListMember{
+		{Recipient: "c@example.com"},
+		{Recipient: "a@example.com", Attributes: map[string]string{"plan": "pro"}},
+	}))
+
+	members, err := repo.GetMembers(ctx, id)
+	require.NoError(t, err)
+	require.Len(t, members, 3)
+	assert.Equal(t, "a@example.com", members[0].Recipient)
+	assert.Equal(t, "pro", members[0].Attributes["plan"])
+	assert.False(t, members[0].AddedAt.IsZero())
+
+	// Returned members are copies
+	members[0].Attributes["plan"] = "mutated"
+	members, _ = repo.GetMembers(ctx, id)
+	assert.Equal(t, "pro", members[0].Attributes["plan"])
+
+	removed, err := repo.RemoveMembers(ctx, id, []string{"b@example.com", "missing@example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	stored, err := repo.GetList(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stored.MemberCount)
+
+	_, err = repo.GetMembers(ctx, uuid.New().String())
+	assert.Error(t, err)
+	assert.Error(t, repo.AddMembers(ctx, uuid.New().String(), nil))
+}
+
+// Helper functions
+
+func createTestRecipientList(name string) *models.RecipientList {
+	now := time.Now()
+	return &models.RecipientList{
+		ID:        uuid.New(),
+		Name:      name,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
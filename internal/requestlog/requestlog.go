@@ -0,0 +1,246 @@
+// Package requestlog logs API requests with a correlation ID that follows
+// each request into the notifications it sends, so one ID finds every log
+// line of a send from the HTTP call to the provider. Bodies are logged for a
+// sample of requests, with personal data and secrets redacted.
+package requestlog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pipeline"
+	"github.com/nareshkumar-microsoft/notificationService/internal/privacy"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// MiddlewareName is the name the correlation middleware is registered under
+const MiddlewareName = "correlation"
+
+// HeaderCorrelationID carries the correlation ID on requests and responses.
+// Requests may send X-Request-ID instead.
+const (
+	HeaderCorrelationID = "X-Correlation-ID"
+	HeaderRequestID     = "X-Request-ID"
+)
+
+// maxCorrelationIDLength bounds a correlation ID taken from a request
+const maxCorrelationIDLength = 128
+
+var (
+	// Correlation IDs from clients are limited to characters safe in logs
+	correlationIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]+$`)
+	// JSON string fields holding secrets, whose values are always removed
+	secretFieldPattern = regexp.MustCompile(`(?i)("(?:[a-z_]*password|[a-z_]*secret|[a-z_]*token|api_?key|authorization)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+)
+
+// correlationKey is the context key for the correlation ID
+type correlationKey struct{}
+
+// WithCorrelationID returns a context carrying a correlation ID
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, id)
+}
+
+// CorrelationIDFromContext returns the correlation ID carried by a context, if any
+func CorrelationIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(correlationKey{}).(string)
+	return id
+}
+
+// Middleware returns the send middleware that gives a request without a
+// correlation ID the one carried by the context
+func Middleware() pipeline.Middleware {
+	return func(next pipeline.Handler) pipeline.Handler {
+		return func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+			if request.CorrelationID == "" {
+				request.CorrelationID = CorrelationIDFromContext(ctx)
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// Logger is HTTP middleware logging each request's method, path, status,
+// latency and correlation ID. Server errors are logged as errors and client
+// errors as warnings.
+type Logger struct {
+	config   config.RequestLogConfig
+	redactor *privacy.Redactor
+	logger   interfaces.Logger
+	sample   func() bool
+}
+
+// NewLogger creates a request logger. Sampled bodies are redacted with the
+// redactor; nil redacts at the partial level.
+func NewLogger(cfg config.RequestLogConfig, redactor *privacy.Redactor, logger interfaces.Logger) *Logger {
+	if redactor == nil {
+		redactor = privacy.NewRedactor(privacy.LevelPartial)
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = 4096
+	}
+
+	return &Logger{
+		config:   cfg,
+		redactor: redactor,
+		logger:   logger,
+		sample:   func() bool { return rand.Float64() < cfg.SampleRate },
+	}
+}
+
+// Handler wraps an HTTP handler. Every request gets a correlation ID, the
+// client's when it sends a valid one, which is echoed in the response and
+// carried in the request context. Requests are logged only when the logger
+// is enabled.
+func (l *Logger) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := correlationID(r)
+		w.Header().Set(HeaderCorrelationID, id)
+		r = r.WithContext(WithCorrelationID(r.Context(), id))
+
+		if !l.config.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		var requestBody *limitedBuffer
+		if l.sample() {
+			requestBody = &limitedBuffer{max: l.config.MaxBodyBytes}
+			recorder.body = &limitedBuffer{max: l.config.MaxBodyBytes}
+			if r.Body != nil {
+				r.Body = &teeBody{ReadCloser: r.Body, copy: requestBody}
+			}
+		}
+
+		started := time.Now()
+		next.ServeHTTP(recorder, r)
+		latency := time.Since(started)
+
+		logger := l.logger.WithFields(map[string]interface{}{
+			"correlation_id": id,
+			"method":         r.Method,
+			"path":           r.URL.Path,
+			"status":         recorder.status,
+			"latency_ms":     latency.Milliseconds(),
+		})
+		message := fmt.Sprintf("%s %s %d in %s (correlation_id=%s, %d bytes)", r.Method, r.URL.Path, recorder.status, latency.Round(time.Microsecond), id, recorder.written)
+		if requestBody != nil {
+			message += fmt.Sprintf("; request: %s; response: %s", l.redact(requestBody), l.redact(recorder.body))
+		}
+
+		switch {
+		case recorder.status >= 500:
+			logger.Error(message)
+		case recorder.status >= 400:
+			logger.Warn(message)
+		default:
+			logger.Info(message)
+		}
+	})
+}
+
+// redact redacts a captured body for the log
+func (l *Logger) redact(body *limitedBuffer) string {
+	if body.Len() == 0 {
+		return "(empty)"
+	}
+
+	text := secretFieldPattern.ReplaceAllString(body.String(), `$1"[redacted]"`)
+	text = l.redactor.Redact(text)
+	if body.truncated {
+		text += "...(truncated)"
+	}
+	return text
+}
+
+// correlationID returns the request's correlation ID, or a new one when it
+// has none or an unusable one
+func correlationID(r *http.Request) string {
+	for _, header := range []string{HeaderCorrelationID, HeaderRequestID} {
+		id := r.Header.Get(header)
+		if id != "" && len(id) <= maxCorrelationIDLength && correlationIDPattern.MatchString(id) {
+			return id
+		}
+	}
+	return uuid.New().String()
+}
+
+// responseRecorder records the status and size of a response, and its body
+// when it is sampled
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	written     int
+	wroteHeader bool
+	body        *limitedBuffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(data)
+	r.written += n
+	if r.body != nil {
+		r.body.Write(data[:n])
+	}
+	return n, err
+}
+
+// Flush supports streaming handlers when the wrapped writer does
+func (r *responseRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the wrapped writer
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// teeBody copies what a handler reads of a request body
+type teeBody struct {
+	io.ReadCloser
+	copy *limitedBuffer
+}
+
+func (b *teeBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.copy.Write(p[:n])
+	return n, err
+}
+
+// limitedBuffer keeps the first max bytes written to it
+type limitedBuffer struct {
+	bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.Len(); len(p) > room {
+		b.truncated = true
+		if room > 0 {
+			b.Buffer.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
@@ -0,0 +1,177 @@
+package requestlog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/privacy"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+func TestLogger_CorrelationID(t *testing.T) {
+	logger := &captureLogger{}
+	requestLogger := NewLogger(config.RequestLogConfig{Enabled: true}, nil, logger)
+
+	var seen string
+	handler := requestLogger.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = CorrelationIDFromContext(r.Context())
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	tests := []struct {
+		name     string
+		header   string
+		value    string
+		expected string
+	}{
+		{"correlation header", HeaderCorrelationID, "abc-123", "abc-123"},
+		{"request ID header", HeaderRequestID, "req.7", "req.7"},
+		{"unsafe ID replaced", HeaderCorrelationID, "bad id\n", ""},
+		{"no ID", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/notifications", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(HeaderCorrelationID)
+			assert.Equal(t, id, seen)
+			if tt.expected != "" {
+				assert.Equal(t, tt.expected, id)
+			} else {
+				assert.Len(t, id, 36)
+			}
+			assert.Equal(t, id, logger.fields["correlation_id"])
+			assert.Equal(t, http.StatusAccepted, logger.fields["status"])
+		})
+	}
+}
+
+func TestLogger_LevelsAndSampling(t *testing.T) {
+	logger := &captureLogger{}
+	requestLogger := NewLogger(config.RequestLogConfig{Enabled: true, MaxBodyBytes: 80}, privacy.NewRedactor(privacy.LevelStrict), logger)
+
+	status := http.StatusOK
+	handler := requestLogger.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `{"echo":%s}`, body)
+	}))
+	body := `{"recipient":"jane@example.com","api_key":"sk_live_1","password":"hunter2"}`
+
+	// Unsampled requests log no bodies
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/notifications", strings.NewReader(body)))
+	require.Len(t, logger.lines, 1)
+	assert.True(t, strings.HasPrefix(logger.lines[0], "[INFO] POST /v1/notifications 200"))
+	assert.NotContains(t, logger.lines[0], "request:")
+
+	requestLogger.sample = func() bool { return true }
+	status = http.StatusBadGateway
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/notifications", strings.NewReader(body)))
+	require.Len(t, logger.lines, 2)
+	line := logger.lines[1]
+	assert.True(t, strings.HasPrefix(line, "[ERROR] POST /v1/notifications 502"))
+	assert.Contains(t, line, `request: {"recipient":"[redacted-email]","api_key":"[redacted]","password":"[redacted]"}`)
+	assert.NotContains(t, line, "jane@example.com")
+	assert.NotContains(t, line, "hunter2")
+	assert.Contains(t, line, "...(truncated)")
+
+	status = http.StatusNotFound
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/notifications/x", nil))
+	assert.True(t, strings.HasPrefix(logger.lines[2], "[WARN] GET /v1/notifications/x 404"))
+	assert.Contains(t, logger.lines[2], "request: (empty)")
+}
+
+func TestLogger_Disabled(t *testing.T) {
+	logger := &captureLogger{}
+	handler := NewLogger(config.RequestLogConfig{}, nil, logger).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NotEmpty(t, CorrelationIDFromContext(r.Context()))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/health", nil))
+
+	assert.NotEmpty(t, rec.Header().Get(HeaderCorrelationID))
+	assert.Empty(t, logger.lines)
+}
+
+func TestMiddleware(t *testing.T) {
+	var correlationID string
+	handler := Middleware()(func(_ context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+		correlationID = request.CorrelationID
+		return &models.NotificationResponse{}, nil
+	})
+
+	_, err := handler(WithCorrelationID(context.Background(), "abc"), &models.NotificationRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "abc", correlationID)
+
+	// An ID the caller set is kept
+	_, err = handler(WithCorrelationID(context.Background(), "abc"), &models.NotificationRequest{CorrelationID: "upstream"})
+	require.NoError(t, err)
+	assert.Equal(t, "upstream", correlationID)
+
+	_, err = handler(context.Background(), &models.NotificationRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, correlationID)
+}
+
+// Helper functions
+
+// captureLogger records log lines with their level, and fields. Derived
+// loggers share their parent's storage.
+type captureLogger struct {
+	lines  []string
+	fields map[string]interface{}
+}
+
+func (l *captureLogger) Debug(args ...interface{}) { l.log("DEBUG", fmt.Sprint(args...)) }
+func (l *captureLogger) Info(args ...interface{})  { l.log("INFO", fmt.Sprint(args...)) }
+func (l *captureLogger) Warn(args ...interface{})  { l.log("WARN", fmt.Sprint(args...)) }
+func (l *captureLogger) Error(args ...interface{}) { l.log("ERROR", fmt.Sprint(args...)) }
+
+func (l *captureLogger) Debugf(format string, args ...interface{}) {
+	l.log("DEBUG", fmt.Sprintf(format, args...))
+}
+func (l *captureLogger) Infof(format string, args ...interface{}) {
+	l.log("INFO", fmt.Sprintf(format, args...))
+}
+func (l *captureLogger) Warnf(format string, args ...interface{}) {
+	l.log("WARN", fmt.Sprintf(format, args...))
+}
+func (l *captureLogger) Errorf(format string, args ...interface{}) {
+	l.log("ERROR", fmt.Sprintf(format, args...))
+}
+
+func (l *captureLogger) WithField(key string, value interface{}) interfaces.Logger {
+	return l.WithFields(map[string]interface{}{key: value})
+}
+
+func (l *captureLogger) WithFields(fields map[string]interface{}) interfaces.Logger {
+	if l.fields == nil {
+		l.fields = make(map[string]interface{})
+	}
+	for key, value := range fields {
+		l.fields[key] = value
+	}
+	return l
+}
+
+func (l *captureLogger) log(level, message string) {
+	l.lines = append(l.lines, "["+level+"] "+message)
+}
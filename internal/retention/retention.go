@@ -0,0 +1,303 @@
+// Package retention enforces how long notification data is kept. A purger
+// runs in the background, clearing the content of old notifications and
+// later deleting them, so the repository does not grow without bound.
+package retention
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// pageSize is the number of notifications read from the repository per page
+const pageSize = 500
+
+// LockKey is the lock a scheduled purge takes when the purger has a locker
+const LockKey = "retention"
+
+// RunResult describes one purge run
+type RunResult struct {
+	StartedAt     time.Time     `json:"started_at"`
+	Duration      time.Duration `json:"duration"`
+	BodiesPurged  int           `json:"bodies_purged"`
+	RecordsPurged int           `json:"records_purged"`
+}
+
+// Stats holds cumulative purge metrics
+type Stats struct {
+	Runs          int64      `json:"runs"`
+	Failures      int64      `json:"failures"`
+	Skipped       int64      `json:"skipped"` // scheduled purges another instance was running
+	BodiesPurged  int64      `json:"bodies_purged"`
+	RecordsPurged int64      `json:"records_purged"`
+	LastRunAt     *time.Time `json:"last_run_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+}
+
+// Purger applies a retention policy to a notification repository. Bodies,
+// HTML bodies and template data of finished notifications are cleared once
+// they are older than the body retention; whole notifications are deleted
+// once older than the record retention. Repositories implementing
+// interfaces.NotificationPurger are purged with it, so soft deleted rows are
+// removed too; others fall back to Delete.
+type Purger struct {
+	repository interfaces.NotificationRepository
+	config     config.RetentionConfig
+	logger     interfaces.Logger
+	locker     interfaces.Locker
+	owner      string
+
+	mu      sync.Mutex
+	stats   Stats
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	running bool
+}
+
+// NewPurger creates a new retention purger
+func NewPurger(repository interfaces.NotificationRepository, cfg config.RetentionConfig, logger interfaces.Logger) *Purger {
+	return &Purger{
+		repository: repository,
+		config:     cfg,
+		logger:     logger,
+	}
+}
+
+// SetLocker makes each scheduled purge take LockKey as owner first and skip
+// the run when another instance holds it, so replicas sharing the locker
+// do not purge at the same time. Call it before Start.
+func (p *Purger) SetLocker(locker interfaces.Locker, owner string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.locker = locker
+	p.owner = owner
+}
+
+// Start runs a purge immediately and then every purge interval until Stop is
+// called. Calling Start on a running purger has no effect.
+func (p *Purger) Start(ctx context.Context) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.running {
+		return
+	}
+
+	interval := p.config.PurgeInterval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.running = true
+
+	p.wg.Add(1)
+	go p.run(ctx, interval)
+
+	p.logger.Infof("Started retention purger (bodies: %s, records: %s, every %s)", p.config.BodyRetention, p.config.RecordRetention, interval)
+}
+
+// Stop stops the purger and waits for a run in progress to finish
+func (p *Purger) Stop() {
+	p.mu.Lock()
+	if !p.running {
+		p.mu.Unlock()
+		return
+	}
+	p.cancel()
+	p.running = false
+	p.mu.Unlock()
+
+	p.wg.Wait()
+	p.logger.Infof("Stopped retention purger")
+}
+
+// PurgeOnce applies the retention policy once
+func (p *Purger) PurgeOnce(ctx context.Context) (*RunResult, error) {
+	now := time.Now()
+	result := &RunResult{StartedAt: now}
+
+	// Expired records go first so their bodies are not cleared needlessly
+	var err error
+	if p.config.RecordRetention > 0 {
+		result.RecordsPurged, err = p.purgeRecords(ctx, now.Add(-p.config.RecordRetention))
+	}
+	if err == nil && p.config.BodyRetention > 0 {
+		result.BodiesPurged, err = p.purgeBodies(ctx, now.Add(-p.config.BodyRetention))
+	}
+	result.Duration = time.Since(now)
+
+	p.mu.Lock()
+	p.stats.Runs++
+	p.stats.BodiesPurged += int64(result.BodiesPurged)
+	p.stats.RecordsPurged += int64(result.RecordsPurged)
+	p.stats.LastRunAt = &now
+	p.stats.LastError = ""
+	if err != nil {
+		p.stats.Failures++
+		p.stats.LastError = err.Error()
+	}
+	p.mu.Unlock()
+
+	if err != nil {
+		p.logger.Errorf("Retention purge failed after clearing %d bodies and deleting %d notifications: %v", result.BodiesPurged, result.RecordsPurged, err)
+		return result, err
+	}
+
+	p.logger.Infof("Retention purge cleared %d bodies and deleted %d notifications in %s", result.BodiesPurged, result.RecordsPurged, result.Duration)
+	return result, nil
+}
+
+// Stats returns the cumulative purge metrics
+func (p *Purger) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := p.stats
+	if p.stats.LastRunAt != nil {
+		lastRunAt := *p.stats.LastRunAt
+		stats.LastRunAt = &lastRunAt
+	}
+	return stats
+}
+
+// run purges on every tick until the context is cancelled
+func (p *Purger) run(ctx context.Context, interval time.Duration) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		p.purgeLocked(ctx, interval)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeLocked purges under the lock when the purger has a locker. The lease
+// lasts an interval, so a run that outlives it may overlap the next one.
+func (p *Purger) purgeLocked(ctx context.Context, interval time.Duration) {
+	p.mu.Lock()
+	locker, owner := p.locker, p.owner
+	p.mu.Unlock()
+
+	if locker != nil {
+		acquired, err := locker.Acquire(ctx, LockKey, owner, interval)
+		if err != nil {
+			p.logger.Errorf("Retention purge skipped, failed to take its lock: %v", err)
+			return
+		}
+		if !acquired {
+			p.mu.Lock()
+			p.stats.Skipped++
+			p.mu.Unlock()
+			p.logger.Debugf("Retention purge is running on another instance, skipped")
+			return
+		}
+		defer func() {
+			if err := locker.Release(context.Background(), LockKey, owner); err != nil {
+				p.logger.Warnf("Failed to release the retention lock: %v", err)
+			}
+		}()
+	}
+
+	// Errors are logged and counted by PurgeOnce; the next tick retries
+	_, _ = p.PurgeOnce(ctx)
+}
+
+// purgeBodies clears the content of finished notifications created before the cutoff
+func (p *Purger) purgeBodies(ctx context.Context, cutoff time.Time) (int, error) {
+	before := cutoff.Format(time.RFC3339)
+	filters := interfaces.NotificationFilters{DateTo: &before, Limit: pageSize, SortBy: "created_at", SortOrder: "asc"}
+	purged := 0
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return purged, err
+		}
+
+		notifications, err := p.repository.List(ctx, filters)
+		if err != nil {
+			return purged, err
+		}
+
+		for _, notification := range notifications {
+			if !isFinished(notification) || !hasContent(notification) {
+				continue
+			}
+
+			notification.Body = ""
+			notification.HTMLBody = ""
+			notification.SubjectTemplate = ""
+			notification.BodyTemplate = ""
+			notification.TemplateData = nil
+			if err := p.repository.Update(ctx, notification); err != nil {
+				return purged, err
+			}
+			purged++
+		}
+
+		if len(notifications) < filters.Limit {
+			return purged, nil
+		}
+		filters.Offset += len(notifications)
+	}
+}
+
+// purgeRecords deletes notifications created before the cutoff
+func (p *Purger) purgeRecords(ctx context.Context, cutoff time.Time) (int, error) {
+	if purger, ok := p.repository.(interfaces.NotificationPurger); ok {
+		return purger.PurgeBefore(ctx, cutoff)
+	}
+
+	// Deleted notifications drop out of List, so the first page is always read
+	before := cutoff.Format(time.RFC3339)
+	filters := interfaces.NotificationFilters{DateTo: &before, Limit: pageSize, SortBy: "created_at", SortOrder: "asc"}
+	purged := 0
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return purged, err
+		}
+
+		notifications, err := p.repository.List(ctx, filters)
+		if err != nil {
+			return purged, err
+		}
+
+		for _, notification := range notifications {
+			if err := p.repository.Delete(ctx, notification.ID.String()); err != nil {
+				return purged, err
+			}
+			purged++
+		}
+
+		if len(notifications) < filters.Limit {
+			return purged, nil
+		}
+	}
+}
+
+// isFinished reports whether a notification will not be sent again
+func isFinished(notification *models.Notification) bool {
+	switch notification.Status {
+	case models.StatusSent, models.StatusDelivered, models.StatusFailed, models.StatusExpired:
+		return true
+	}
+	return false
+}
+
+// hasContent reports whether a notification still holds content to clear
+func hasContent(notification *models.Notification) bool {
+	return notification.Body != "" || notification.HTMLBody != "" || notification.BodyTemplate != "" || len(notification.TemplateData) > 0
+}
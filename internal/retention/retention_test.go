@@ -0,0 +1,215 @@
+package retention
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/lock"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/repository"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+func TestPurger_PurgeOnce(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	ctx := context.Background()
+	now := time.Now()
+
+	recent := createTestNotification(t, repo, models.StatusDelivered, now.Add(-24*time.Hour))
+	old := createTestNotification(t, repo, models.StatusDelivered, now.Add(-40*24*time.Hour))
+	oldPending := createTestNotification(t, repo, models.StatusPending, now.Add(-40*24*time.Hour))
+	expired := createTestNotification(t, repo, models.StatusFailed, now.Add(-200*24*time.Hour))
+
+	// Soft deleted notifications are purged too
+	deleted := createTestNotification(t, repo, models.StatusSent, now.Add(-365*24*time.Hour))
+	require.NoError(t, repo.Delete(ctx, deleted.ID.String()))
+
+	purger := createTestPurger(repo)
+	result, err := purger.PurgeOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.BodiesPurged)
+	assert.Equal(t, 2, result.RecordsPurged)
+
+	found, err := repo.GetByID(ctx, recent.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, "Your code is 123456", found.Body)
+
+	found, err = repo.GetByID(ctx, old.ID.String())
+	require.NoError(t, err)
+	assert.Empty(t, found.Body)
+	assert.Empty(t, found.HTMLBody)
+	assert.Empty(t, found.BodyTemplate)
+	assert.Nil(t, found.TemplateData)
+	assert.Equal(t, "user@example.com", found.Recipient)
+
+	// Notifications that may still be sent keep their content
+	found, err = repo.GetByID(ctx, oldPending.ID.String())
+	require.NoError(t, err)
+	assert.NotEmpty(t, found.Body)
+
+	_, err = repo.GetByID(ctx, expired.ID.String())
+	assert.Error(t, err)
+	assert.Equal(t, 3, repo.Count())
+
+	// A second run has nothing left to do
+	result, err = purger.PurgeOnce(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, result.BodiesPurged)
+	assert.Zero(t, result.RecordsPurged)
+
+	stats := purger.Stats()
+	assert.Equal(t, int64(2), stats.Runs)
+	assert.Equal(t, int64(1), stats.BodiesPurged)
+	assert.Equal(t, int64(2), stats.RecordsPurged)
+	assert.NotNil(t, stats.LastRunAt)
+	assert.Empty(t, stats.LastError)
+}
+
+func TestPurger_EncryptedRepository(t *testing.T) {
+	inner := repository.NewMemoryRepository()
+	repo := repository.NewEncryptedRepository(inner, nil)
+	ctx := context.Background()
+
+	createTestNotification(t, inner, models.StatusSent, time.Now().Add(-200*24*time.Hour))
+	createTestNotification(t, inner, models.StatusSent, time.Now())
+
+	// Purges pass through the wrapper, so soft deleted notifications go too
+	deleted := createTestNotification(t, inner, models.StatusSent, time.Now().Add(-365*24*time.Hour))
+	require.NoError(t, inner.Delete(ctx, deleted.ID.String()))
+
+	result, err := createTestPurger(repo).PurgeOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.RecordsPurged)
+	assert.Equal(t, 1, inner.Count())
+}
+
+func TestPurger_FallsBackToDelete(t *testing.T) {
+	inner := repository.NewMemoryRepository()
+	repo := &unpurgeableRepository{NotificationRepository: inner}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		createTestNotification(t, inner, models.StatusSent, time.Now().Add(-200*24*time.Hour))
+	}
+	createTestNotification(t, inner, models.StatusSent, time.Now())
+
+	result, err := createTestPurger(repo).PurgeOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.RecordsPurged)
+	assert.Equal(t, 1, inner.Count())
+}
+
+func TestPurger_DisabledRetention(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	createTestNotification(t, repo, models.StatusSent, time.Now().Add(-1000*24*time.Hour))
+
+	purger := NewPurger(repo, config.RetentionConfig{}, utils.NewSimpleLogger("info"))
+	result, err := purger.PurgeOnce(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, result.BodiesPurged)
+	assert.Zero(t, result.RecordsPurged)
+	assert.Equal(t, 1, repo.Count())
+}
+
+func TestPurger_StartStop(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	createTestNotification(t, repo, models.StatusSent, time.Now().Add(-200*24*time.Hour))
+
+	purger := createTestPurger(repo)
+	purger.Start(context.Background())
+	purger.Start(context.Background()) // no effect while running
+
+	// Start purges immediately
+	assert.Eventually(t, func() bool {
+		return purger.Stats().Runs >= 1
+	}, time.Second, 10*time.Millisecond)
+
+	purger.Stop()
+	purger.Stop()
+	assert.Zero(t, repo.Count())
+}
+
+func TestPurger_Locked(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	createTestNotification(t, repo, models.StatusSent, time.Now().Add(-200*24*time.Hour))
+	ctx := context.Background()
+
+	locker := lock.NewMemoryLocker()
+	acquired, err := locker.Acquire(ctx, LockKey, "instance-2", time.Hour)
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	// Another instance holds the lock, so scheduled purges are skipped
+	purger := createTestPurger(repo)
+	purger.SetLocker(locker, "instance-1")
+	purger.purgeLocked(ctx, time.Hour)
+	assert.Equal(t, int64(1), purger.Stats().Skipped)
+	assert.Zero(t, purger.Stats().Runs)
+	assert.Equal(t, 1, repo.Count())
+
+	require.NoError(t, locker.Release(ctx, LockKey, "instance-2"))
+	purger.purgeLocked(ctx, time.Hour)
+	assert.Equal(t, int64(1), purger.Stats().Runs)
+	assert.Zero(t, repo.Count())
+
+	// The lock is released after the run
+	acquired, err = locker.Acquire(ctx, LockKey, "instance-2", time.Hour)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+}
+
+func TestPurger_RecordsFailures(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	purger := createTestPurger(repo)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := purger.PurgeOnce(ctx)
+	assert.Error(t, err)
+
+	stats := purger.Stats()
+	assert.Equal(t, int64(1), stats.Failures)
+	assert.NotEmpty(t, stats.LastError)
+}
+
+// Helper functions
+
+// unpurgeableRepository is a repository that does not implement NotificationPurger
+type unpurgeableRepository struct {
+	interfaces.NotificationRepository
+}
+
+func createTestPurger(repo interfaces.NotificationRepository) *Purger {
+	return NewPurger(repo, config.RetentionConfig{
+		Enabled:         true,
+		BodyRetention:   30 * 24 * time.Hour,
+		RecordRetention: 180 * 24 * time.Hour,
+		PurgeInterval:   time.Hour,
+	}, utils.NewSimpleLogger("info"))
+}
+
+func createTestNotification(t *testing.T, repo *repository.MemoryRepository, status models.NotificationStatus, createdAt time.Time) *models.Notification {
+	notification := &models.Notification{
+		ID:           uuid.New(),
+		Type:         models.NotificationTypeEmail,
+		Status:       status,
+		Priority:     models.PriorityNormal,
+		Recipient:    "user@example.com",
+		Body:         "Your code is 123456",
+		HTMLBody:     "<p>Your code is 123456</p>",
+		BodyTemplate: "Your code is {{code}}",
+		TemplateData: map[string]string{"code": "123456"},
+		CreatedAt:    createdAt,
+		UpdatedAt:    createdAt,
+	}
+	require.NoError(t, repo.Save(context.Background(), notification))
+	return notification
+}
@@ -0,0 +1,297 @@
+// Package rollout moves a channel to a new provider gradually. A percentage
+// of the channel's sends goes to the new, canary, provider and the rest to
+// the incumbent; their error rates and latencies are compared, and the
+// canary is rolled back automatically when its error rate exceeds the
+// incumbent's by more than the configured margin.
+package rollout
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// MetadataArm is the metadata key naming the side of a rollout a send went to
+const MetadataArm = "rollout_arm"
+
+// Arms of a rollout
+const (
+	ArmCanary    = "canary"
+	ArmIncumbent = "incumbent"
+)
+
+// Defaults applied to zero configuration fields
+const (
+	defaultMinSends             = 50
+	defaultMaxErrorRateIncrease = 0.05
+)
+
+// State is the state of a rollout
+type State string
+
+// Rollout states
+const (
+	StateActive     State = "active"
+	StateRolledBack State = "rolled_back"
+)
+
+// Metrics are the send results of one side of a rollout
+type Metrics struct {
+	Sends          int64         `json:"sends"`
+	Failures       int64         `json:"failures"` // failures of the provider, not of the message
+	ErrorRate      float64       `json:"error_rate"`
+	AverageLatency time.Duration `json:"average_latency"`
+}
+
+// Status is a rollout's state and the metrics of both sides
+type Status struct {
+	Channel        models.NotificationType `json:"channel"`
+	Provider       string                  `json:"provider"`
+	Percent        float64                 `json:"percent"`
+	State          State                   `json:"state"`
+	Canary         Metrics                 `json:"canary"`
+	Incumbent      Metrics                 `json:"incumbent"`
+	RolledBackAt   *time.Time              `json:"rolled_back_at,omitempty"`
+	RollbackReason string                  `json:"rollback_reason,omitempty"`
+}
+
+// counts accumulates the results of one side of a rollout
+type counts struct {
+	sends        int64
+	failures     int64
+	totalLatency time.Duration
+}
+
+// rollout is the state of one channel's rollout
+type rollout struct {
+	provider       string
+	percent        float64
+	state          State
+	canary         counts
+	incumbent      counts
+	rolledBackAt   *time.Time
+	rollbackReason string
+}
+
+// Controller splits sends between incumbent and canary providers and rolls
+// canaries back. It is safe for concurrent use.
+type Controller struct {
+	minSends             int64
+	maxErrorRateIncrease float64
+	logger               interfaces.Logger
+
+	mu       sync.Mutex
+	rollouts map[models.NotificationType]*rollout
+	now      func() time.Time
+}
+
+// NewController creates a controller for the configured rollouts
+func NewController(cfg config.RolloutConfig, logger interfaces.Logger) (*Controller, error) {
+	if cfg.MinSends <= 0 {
+		cfg.MinSends = defaultMinSends
+	}
+	if cfg.MaxErrorRateIncrease <= 0 {
+		cfg.MaxErrorRateIncrease = defaultMaxErrorRateIncrease
+	}
+
+	c := &Controller{
+		minSends:             int64(cfg.MinSends),
+		maxErrorRateIncrease: cfg.MaxErrorRateIncrease,
+		logger:               logger,
+		rollouts:             make(map[models.NotificationType]*rollout, len(cfg.Rollouts)),
+		now:                  time.Now,
+	}
+	for i, configured := range cfg.Rollouts {
+		field := fmt.Sprintf("rollouts[%d]", i)
+		channel := models.NotificationType(strings.ToLower(configured.Channel))
+		if !validChannel(channel) {
+			return nil, errors.NewValidationError(field, fmt.Sprintf("unknown channel: %s", configured.Channel))
+		}
+		if _, exists := c.rollouts[channel]; exists {
+			return nil, errors.NewValidationError(field, fmt.Sprintf("channel %s has more than one rollout", channel))
+		}
+		if configured.Provider == "" {
+			return nil, errors.NewValidationError(field, "provider is required")
+		}
+		if err := validatePercent(field, configured.Percent); err != nil {
+			return nil, err
+		}
+		c.rollouts[channel] = &rollout{provider: configured.Provider, percent: configured.Percent, state: StateActive}
+	}
+	return c, nil
+}
+
+// Choose returns the provider a send goes to under its channel's rollout and
+// the arm it belongs to. A recipient always lands on the same side for a
+// given percentage. It returns an empty arm when the channel has no rollout,
+// and an empty provider for sends staying with the incumbent.
+func (c *Controller) Choose(request *models.NotificationRequest) (provider, arm string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r, exists := c.rollouts[request.Type]
+	if !exists || r.state != StateActive {
+		return "", ""
+	}
+	if bucket(request.Recipient) < r.percent {
+		return r.provider, ArmCanary
+	}
+	return "", ArmIncumbent
+}
+
+// Record counts the result of a send on one arm of a channel's rollout,
+// rolling the canary back when its error rate has grown too high
+func (c *Controller) Record(channel models.NotificationType, arm string, err error, latency time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r, exists := c.rollouts[channel]
+	if !exists || r.state != StateActive {
+		return
+	}
+
+	side := &r.incumbent
+	if arm == ArmCanary {
+		side = &r.canary
+	}
+	side.sends++
+	side.totalLatency += latency
+	if err != nil && providers.IsProviderFailure(err) {
+		side.failures++
+	}
+
+	if arm != ArmCanary || r.canary.sends < c.minSends {
+		return
+	}
+	canaryRate, incumbentRate := r.canary.metrics().ErrorRate, r.incumbent.metrics().ErrorRate
+	if canaryRate <= incumbentRate+c.maxErrorRateIncrease {
+		return
+	}
+
+	now := c.now()
+	r.state = StateRolledBack
+	r.rolledBackAt = &now
+	r.rollbackReason = fmt.Sprintf("canary error rate %.1f%% exceeds the incumbent's %.1f%% by more than %.1f points",
+		canaryRate*100, incumbentRate*100, c.maxErrorRateIncrease*100)
+	c.logger.Errorf("Rolled back %s provider %s: %s", channel, r.provider, r.rollbackReason)
+}
+
+// SetPercent changes the share of a channel's sends going to its canary.
+// Setting it on a rolled back rollout resumes it with fresh metrics.
+func (c *Controller) SetPercent(channel models.NotificationType, percent float64) (Status, error) {
+	if err := validatePercent("percent", percent); err != nil {
+		return Status{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r, exists := c.rollouts[channel]
+	if !exists {
+		return Status{}, errors.NewNotificationError(errors.ErrorCodeNotFound, fmt.Sprintf("no rollout for %s", channel))
+	}
+	if r.state == StateRolledBack {
+		*r = rollout{provider: r.provider, state: StateActive}
+		c.logger.Infof("Resumed rollout of %s provider %s", channel, r.provider)
+	}
+	r.percent = percent
+	return r.status(channel), nil
+}
+
+// Status returns the status of a channel's rollout
+func (c *Controller) Status(channel models.NotificationType) (Status, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r, exists := c.rollouts[channel]
+	if !exists {
+		return Status{}, false
+	}
+	return r.status(channel), true
+}
+
+// Statuses returns the status of every rollout, ordered by channel
+func (c *Controller) Statuses() []Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var statuses []Status
+	for _, channel := range channels() {
+		if r, exists := c.rollouts[channel]; exists {
+			statuses = append(statuses, r.status(channel))
+		}
+	}
+	return statuses
+}
+
+// status returns a rollout's status. Callers hold the lock.
+func (r *rollout) status(channel models.NotificationType) Status {
+	status := Status{
+		Channel:        channel,
+		Provider:       r.provider,
+		Percent:        r.percent,
+		State:          r.state,
+		Canary:         r.canary.metrics(),
+		Incumbent:      r.incumbent.metrics(),
+		RollbackReason: r.rollbackReason,
+	}
+	if r.rolledBackAt != nil {
+		rolledBackAt := *r.rolledBackAt
+		status.RolledBackAt = &rolledBackAt
+	}
+	return status
+}
+
+// metrics derives the rates of one side's counts
+func (c counts) metrics() Metrics {
+	metrics := Metrics{Sends: c.sends, Failures: c.failures}
+	if c.sends > 0 {
+		metrics.ErrorRate = float64(c.failures) / float64(c.sends)
+		metrics.AverageLatency = c.totalLatency / time.Duration(c.sends)
+	}
+	return metrics
+}
+
+// bucket places a recipient in [0, 100) by a hash of its address
+func bucket(recipient string) float64 {
+	hash := fnv.New32a()
+	hash.Write([]byte(strings.ToLower(recipient)))
+	return float64(hash.Sum32()%10000) / 100
+}
+
+// validatePercent checks a percentage is within 0 to 100
+func validatePercent(field string, percent float64) error {
+	if percent < 0 || percent > 100 {
+		return errors.NewValidationError(field, fmt.Sprintf("percent must be between 0 and 100, got %g", percent))
+	}
+	return nil
+}
+
+// channels returns the channels in the order statuses are listed
+func channels() []models.NotificationType {
+	return []models.NotificationType{
+		models.NotificationTypeEmail,
+		models.NotificationTypeSMS,
+		models.NotificationTypePush,
+		models.NotificationTypeChat,
+		models.NotificationTypeVoice,
+	}
+}
+
+// validChannel reports whether a channel can be rolled out
+func validChannel(channel models.NotificationType) bool {
+	for _, known := range channels() {
+		if channel == known {
+			return true
+		}
+	}
+	return false
+}
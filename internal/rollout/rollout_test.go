@@ -0,0 +1,153 @@
+package rollout
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestNewController_Validation(t *testing.T) {
+	tests := []struct {
+		name     string
+		rollouts []config.ProviderRollout
+	}{
+		{"unknown channel", []config.ProviderRollout{{Channel: "fax", Provider: "new", Percent: 10}}},
+		{"missing provider", []config.ProviderRollout{{Channel: "sms", Percent: 10}}},
+		{"percent over 100", []config.ProviderRollout{{Channel: "sms", Provider: "new", Percent: 110}}},
+		{"negative percent", []config.ProviderRollout{{Channel: "sms", Provider: "new", Percent: -1}}},
+		{"two rollouts of a channel", []config.ProviderRollout{
+			{Channel: "sms", Provider: "new", Percent: 10},
+			{Channel: "SMS", Provider: "newer", Percent: 10},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewController(config.RolloutConfig{Rollouts: tt.rollouts}, utils.NewSimpleLogger("info"))
+			notifErr, ok := errors.AsNotificationError(err)
+			require.True(t, ok)
+			assert.Equal(t, errors.ErrorCodeValidationFailed, notifErr.Code)
+		})
+	}
+}
+
+func TestController_Choose(t *testing.T) {
+	controller := createTestController(t, 20)
+
+	canary := 0
+	for i := 0; i < 1000; i++ {
+		request := &models.NotificationRequest{Type: models.NotificationTypeSMS, Recipient: fmt.Sprintf("+1415555%04d", i)}
+		provider, arm := controller.Choose(request)
+		if arm == ArmCanary {
+			assert.Equal(t, "twilio-v2", provider)
+			canary++
+		} else {
+			assert.Equal(t, ArmIncumbent, arm)
+			assert.Empty(t, provider)
+		}
+
+		// A recipient stays on its side
+		_, again := controller.Choose(request)
+		assert.Equal(t, arm, again)
+	}
+	assert.InDelta(t, 200, canary, 50)
+
+	provider, arm := controller.Choose(&models.NotificationRequest{Type: models.NotificationTypeEmail, Recipient: "jane@example.com"})
+	assert.Empty(t, provider)
+	assert.Empty(t, arm)
+}
+
+func TestController_Rollback(t *testing.T) {
+	controller := createTestController(t, 50)
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	controller.now = func() time.Time { return now }
+	unavailable := errors.NewNotificationError(errors.ErrorCodeProviderUnavailable, "down")
+
+	for i := 0; i < 20; i++ {
+		controller.Record(models.NotificationTypeSMS, ArmIncumbent, nil, 100*time.Millisecond)
+	}
+	// Failures of the message count against neither provider
+	for i := 0; i < 9; i++ {
+		controller.Record(models.NotificationTypeSMS, ArmCanary, errors.NewNotificationError(errors.ErrorCodeInvalidPhone, "bad number"), 0)
+	}
+	controller.Record(models.NotificationTypeSMS, ArmCanary, unavailable, 0)
+
+	status, exists := controller.Status(models.NotificationTypeSMS)
+	require.True(t, exists)
+	assert.Equal(t, StateRolledBack, status.State)
+	assert.Equal(t, int64(10), status.Canary.Sends)
+	assert.Equal(t, int64(1), status.Canary.Failures)
+	assert.InDelta(t, 0.1, status.Canary.ErrorRate, 0.001)
+	assert.Equal(t, 100*time.Millisecond, status.Incumbent.AverageLatency)
+	assert.Equal(t, now, *status.RolledBackAt)
+	assert.Contains(t, status.RollbackReason, "10.0%")
+
+	// Rolled back rollouts send everything to the incumbent
+	_, arm := controller.Choose(&models.NotificationRequest{Type: models.NotificationTypeSMS, Recipient: "+14155550100"})
+	assert.Empty(t, arm)
+
+	status, err := controller.SetPercent(models.NotificationTypeSMS, 5)
+	require.NoError(t, err)
+	assert.Equal(t, StateActive, status.State)
+	assert.Equal(t, 5.0, status.Percent)
+	assert.Zero(t, status.Canary.Sends)
+	assert.Nil(t, status.RolledBackAt)
+}
+
+func TestController_NoRollbackWithinMargin(t *testing.T) {
+	controller := createTestController(t, 50)
+	unavailable := errors.NewNotificationError(errors.ErrorCodeProviderUnavailable, "down")
+
+	for i := 0; i < 20; i++ {
+		var err error
+		if i%10 == 0 {
+			err = unavailable
+		}
+		controller.Record(models.NotificationTypeSMS, ArmIncumbent, err, 0)
+		controller.Record(models.NotificationTypeSMS, ArmCanary, err, 0)
+	}
+
+	status, _ := controller.Status(models.NotificationTypeSMS)
+	assert.Equal(t, StateActive, status.State)
+	assert.InDelta(t, status.Incumbent.ErrorRate, status.Canary.ErrorRate, 0.001)
+}
+
+func TestController_SetPercent(t *testing.T) {
+	controller := createTestController(t, 10)
+
+	_, err := controller.SetPercent(models.NotificationTypeEmail, 10)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeNotFound, notifErr.Code)
+
+	_, err = controller.SetPercent(models.NotificationTypeSMS, 101)
+	require.Error(t, err)
+
+	status, err := controller.SetPercent(models.NotificationTypeSMS, 100)
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, status.Percent)
+	_, arm := controller.Choose(&models.NotificationRequest{Type: models.NotificationTypeSMS, Recipient: "+14155550100"})
+	assert.Equal(t, ArmCanary, arm)
+
+	require.Len(t, controller.Statuses(), 1)
+}
+
+// Helper functions
+
+func createTestController(t *testing.T, percent float64) *Controller {
+	controller, err := NewController(config.RolloutConfig{
+		Rollouts:             []config.ProviderRollout{{Channel: "sms", Provider: "twilio-v2", Percent: percent}},
+		MinSends:             10,
+		MaxErrorRateIncrease: 0.05,
+	}, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	return controller
+}
@@ -0,0 +1,351 @@
+// Package routing chooses the provider that sends each notification. An
+// Engine evaluates declarative rules matching sends by channel, tenant,
+// country, category and priority, and applies the first matching rule's
+// provider, sender and rate limit.
+//
+// A Router picks the provider and sending domain of each email by its
+// category and the reputation of the routes that may send it. Keeping
+// marketing and transactional email on separate routes means a marketing
+// blast that draws bounces and complaints does not hurt the delivery of
+// one-time passwords; a route whose bounce or complaint rate climbs too high
+// is avoided in favour of the next route for the category.
+package routing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pipeline"
+	"github.com/nareshkumar-microsoft/notificationService/internal/warmup"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// MiddlewareName is the name the router's middleware is registered under
+const MiddlewareName = "email-routing"
+
+// Metadata keys set on routed email
+const (
+	MetadataRoute    = "email_route"
+	MetadataProvider = "provider" // the named provider the dispatcher sends through
+)
+
+// Defaults applied to zero configuration fields
+const (
+	defaultWindow           = 24 * time.Hour
+	defaultMinSends         = 100
+	defaultMaxBounceRate    = 0.05
+	defaultMaxComplaintRate = 0.003
+)
+
+// buckets is the number of periods a route's window is counted in
+const buckets = 24
+
+// Outcome is what became of a sent email
+type Outcome string
+
+// Outcomes reported by bounce and complaint webhooks
+const (
+	OutcomeBounced    Outcome = "bounced"
+	OutcomeComplained Outcome = "complained"
+)
+
+// Reputation is a route's sending record over the window
+type Reputation struct {
+	Route         string   `json:"route"`
+	Categories    []string `json:"categories"`
+	Provider      string   `json:"provider,omitempty"`
+	FromDomain    string   `json:"from_domain,omitempty"`
+	Sent          int      `json:"sent"`
+	Bounced       int      `json:"bounced"`
+	Complained    int      `json:"complained"`
+	BounceRate    float64  `json:"bounce_rate"`
+	ComplaintRate float64  `json:"complaint_rate"`
+	Healthy       bool     `json:"healthy"`
+}
+
+// count is a route's record over one period
+type count struct {
+	start      time.Time
+	sent       int
+	bounced    int
+	complained int
+}
+
+// route is a configured route with its record
+type route struct {
+	config.EmailRoute
+	counts []count // oldest first
+}
+
+// Router routes email and tracks the reputation of its routes. It is safe
+// for concurrent use.
+type Router struct {
+	config config.EmailRoutingConfig
+	logger interfaces.Logger
+
+	mu         sync.Mutex
+	routes     []*route
+	byName     map[string]*route
+	byCategory map[models.Category][]*route
+	now        func() time.Time
+}
+
+// NewRouter creates a router for the configured routes
+func NewRouter(cfg config.EmailRoutingConfig, logger interfaces.Logger) (*Router, error) {
+	if cfg.Window <= 0 {
+		cfg.Window = defaultWindow
+	}
+	if cfg.MinSends <= 0 {
+		cfg.MinSends = defaultMinSends
+	}
+	if cfg.MaxBounceRate <= 0 {
+		cfg.MaxBounceRate = defaultMaxBounceRate
+	}
+	if cfg.MaxComplaintRate <= 0 {
+		cfg.MaxComplaintRate = defaultMaxComplaintRate
+	}
+
+	r := &Router{
+		config:     cfg,
+		logger:     logger,
+		byName:     make(map[string]*route),
+		byCategory: make(map[models.Category][]*route),
+		now:        time.Now,
+	}
+	for i, configured := range cfg.Routes {
+		field := fmt.Sprintf("routes[%d]", i)
+		if configured.Name == "" {
+			return nil, errors.NewValidationError(field, "route name is required")
+		}
+		if _, exists := r.byName[configured.Name]; exists {
+			return nil, errors.NewValidationError(field, fmt.Sprintf("duplicate route: %s", configured.Name))
+		}
+		if len(configured.Categories) == 0 {
+			return nil, errors.NewValidationError(field, "route categories are required")
+		}
+
+		rt := &route{EmailRoute: configured}
+		rt.FromDomain = strings.ToLower(strings.TrimPrefix(rt.FromDomain, "@"))
+		for _, name := range configured.Categories {
+			category := models.Category(strings.ToLower(strings.TrimSpace(name)))
+			if !validCategory(category) {
+				return nil, errors.NewValidationError(field, fmt.Sprintf("unknown category: %s", name))
+			}
+			r.byCategory[category] = append(r.byCategory[category], rt)
+		}
+		r.routes = append(r.routes, rt)
+		r.byName[rt.Name] = rt
+	}
+	return r, nil
+}
+
+// Middleware returns the send middleware that routes email. Email of a
+// category without routes is sent unchanged.
+func (r *Router) Middleware() pipeline.Middleware {
+	return func(next pipeline.Handler) pipeline.Handler {
+		return func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+			if request.Type != models.NotificationTypeEmail {
+				return next(ctx, request)
+			}
+
+			selected := r.Select(request.Category)
+			if selected == nil {
+				return next(ctx, request)
+			}
+
+			response, err := next(ctx, applyRoute(selected, request))
+			if err == nil {
+				r.record(selected.Name, func(c *count) { c.sent++ })
+			}
+			return response, err
+		}
+	}
+}
+
+// Select returns the route for a category: the first healthy route listing
+// it, or the one with the lowest bounce rate when none is healthy. Email
+// without a category is transactional. It returns nil when no route lists
+// the category.
+func (r *Router) Select(category models.Category) *config.EmailRoute {
+	if category == "" {
+		category = models.CategoryTransactional
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	candidates := r.byCategory[category]
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	var best *route
+	var bestRate float64
+	for _, candidate := range candidates {
+		reputation := r.reputation(candidate)
+		if reputation.Healthy {
+			selected := candidate.EmailRoute
+			return &selected
+		}
+		if best == nil || reputation.BounceRate < bestRate {
+			best, bestRate = candidate, reputation.BounceRate
+		}
+	}
+
+	r.logger.Warnf("Every %s email route has a poor reputation; using %s with a bounce rate of %.1f%%", category, best.Name, bestRate*100)
+	selected := best.EmailRoute
+	return &selected
+}
+
+// Record counts a bounce or complaint of email sent through a route
+func (r *Router) Record(routeName string, outcome Outcome) error {
+	var apply func(*count)
+	switch outcome {
+	case OutcomeBounced:
+		apply = func(c *count) { c.bounced++ }
+	case OutcomeComplained:
+		apply = func(c *count) { c.complained++ }
+	default:
+		return errors.NewValidationError("outcome", fmt.Sprintf("unknown outcome %q, expected %s or %s", outcome, OutcomeBounced, OutcomeComplained))
+	}
+
+	if !r.record(routeName, apply) {
+		return errors.NewNotificationError(errors.ErrorCodeNotFound, fmt.Sprintf("unknown email route: %s", routeName))
+	}
+	return nil
+}
+
+// RecordNotification counts a bounce or complaint of a routed notification
+func (r *Router) RecordNotification(notification *models.Notification, outcome Outcome) error {
+	routeName := notification.Metadata[MetadataRoute]
+	if routeName == "" {
+		return errors.NewValidationError("notification", fmt.Sprintf("notification %s was not routed", notification.ID))
+	}
+	return r.Record(routeName, outcome)
+}
+
+// Reputations returns the reputation of every route, in configuration order
+func (r *Router) Reputations() []Reputation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reputations := make([]Reputation, len(r.routes))
+	for i, rt := range r.routes {
+		reputations[i] = r.reputation(rt)
+	}
+	return reputations
+}
+
+// record applies a change to the current period of a route. It reports
+// whether the route exists.
+func (r *Router) record(routeName string, apply func(*count)) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rt, exists := r.byName[routeName]
+	if !exists {
+		return false
+	}
+
+	now := r.now()
+	r.prune(rt, now)
+	period := r.config.Window / buckets
+	start := now.Truncate(period)
+	if n := len(rt.counts); n == 0 || rt.counts[n-1].start.Before(start) {
+		rt.counts = append(rt.counts, count{start: start})
+	}
+	apply(&rt.counts[len(rt.counts)-1])
+	return true
+}
+
+// reputation sums a route's record over the window. Callers hold the lock.
+func (r *Router) reputation(rt *route) Reputation {
+	r.prune(rt, r.now())
+
+	reputation := Reputation{
+		Route:      rt.Name,
+		Categories: rt.Categories,
+		Provider:   rt.Provider,
+		FromDomain: rt.FromDomain,
+	}
+	for _, c := range rt.counts {
+		reputation.Sent += c.sent
+		reputation.Bounced += c.bounced
+		reputation.Complained += c.complained
+	}
+	if reputation.Sent > 0 {
+		reputation.BounceRate = float64(reputation.Bounced) / float64(reputation.Sent)
+		reputation.ComplaintRate = float64(reputation.Complained) / float64(reputation.Sent)
+	}
+
+	// Rates over a few sends say little, so a new route starts healthy
+	reputation.Healthy = reputation.Sent < r.config.MinSends ||
+		(reputation.BounceRate <= r.config.MaxBounceRate && reputation.ComplaintRate <= r.config.MaxComplaintRate)
+	return reputation
+}
+
+// prune drops a route's periods that have left the window. Callers hold the lock.
+func (r *Router) prune(rt *route, now time.Time) {
+	cutoff := now.Add(-r.config.Window)
+	drop := 0
+	for drop < len(rt.counts) && !rt.counts[drop].start.After(cutoff) {
+		drop++
+	}
+	rt.counts = rt.counts[drop:]
+}
+
+// applyRoute returns a copy of an email request sent through a route: its
+// From domain replaced, and the route, provider and sending IP in its
+// metadata. A request without a From address gets noreply at the route's
+// domain.
+func applyRoute(selected *config.EmailRoute, request *models.NotificationRequest) *models.NotificationRequest {
+	routed := *request
+	routed.Metadata = make(map[string]string, len(request.Metadata)+3)
+	for key, value := range request.Metadata {
+		routed.Metadata[key] = value
+	}
+	routed.Metadata[MetadataRoute] = selected.Name
+	if selected.Provider != "" {
+		routed.Metadata[MetadataProvider] = selected.Provider
+	}
+	if selected.SendingIP != "" {
+		routed.Metadata[warmup.MetadataSendingIP] = selected.SendingIP
+	}
+
+	if selected.FromDomain != "" {
+		data := models.EmailData{}
+		if request.EmailData != nil {
+			data = *request.EmailData
+		}
+		if at := strings.LastIndex(data.From, "@"); at >= 0 {
+			// Keep the closing bracket of a "Name <local@domain>" address
+			rest := data.From[at+1:]
+			if end := strings.IndexByte(rest, '>'); end >= 0 {
+				rest = rest[end:]
+			} else {
+				rest = ""
+			}
+			data.From = data.From[:at+1] + selected.FromDomain + rest
+		} else {
+			data.From = "noreply@" + selected.FromDomain
+		}
+		routed.EmailData = &data
+	}
+	return &routed
+}
+
+// validCategory reports whether a category is one routes can list
+func validCategory(category models.Category) bool {
+	for _, known := range models.Categories() {
+		if category == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,184 @@
+package routing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/internal/warmup"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestNewRouter_Validation(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes []config.EmailRoute
+	}{
+		{"missing name", []config.EmailRoute{{Categories: []string{"marketing"}}}},
+		{"missing categories", []config.EmailRoute{{Name: "bulk"}}},
+		{"unknown category", []config.EmailRoute{{Name: "bulk", Categories: []string{"newsletters"}}}},
+		{"duplicate name", []config.EmailRoute{
+			{Name: "bulk", Categories: []string{"marketing"}},
+			{Name: "bulk", Categories: []string{"transactional"}},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRouter(config.EmailRoutingConfig{Routes: tt.routes}, utils.NewSimpleLogger("info"))
+			notifErr, ok := errors.AsNotificationError(err)
+			require.True(t, ok)
+			assert.Equal(t, errors.ErrorCodeValidationFailed, notifErr.Code)
+		})
+	}
+}
+
+func TestRouter_Select(t *testing.T) {
+	router := createTestRouter(t)
+
+	assert.Equal(t, "otp", router.Select(models.CategorySecurity).Name)
+	assert.Equal(t, "otp", router.Select("").Name)
+	assert.Equal(t, "campaigns", router.Select(models.CategoryMarketing).Name)
+}
+
+func TestRouter_SelectAvoidsPoorReputation(t *testing.T) {
+	router := createTestRouter(t)
+
+	// A few bounces on a new route say little
+	sendAndBounce(t, router, "campaigns", 5, 5)
+	assert.Equal(t, "campaigns", router.Select(models.CategoryMarketing).Name)
+
+	sendAndBounce(t, router, "campaigns", 5, 0)
+	assert.Equal(t, "campaigns-backup", router.Select(models.CategoryMarketing).Name)
+
+	// With every route poor, the lowest bounce rate wins
+	sendAndBounce(t, router, "campaigns-backup", 10, 2)
+	assert.Equal(t, "campaigns-backup", router.Select(models.CategoryMarketing).Name)
+
+	// Marketing never falls back to the transactional route
+	for _, reputation := range router.Reputations() {
+		if reputation.Route == "otp" {
+			assert.Zero(t, reputation.Sent)
+			assert.True(t, reputation.Healthy)
+		}
+	}
+}
+
+func TestRouter_Complaints(t *testing.T) {
+	router := createTestRouter(t)
+	sendAndBounce(t, router, "campaigns", 10, 0)
+	require.NoError(t, router.Record("campaigns", OutcomeComplained))
+
+	reputations := router.Reputations()
+	assert.Equal(t, 1, reputations[1].Complained)
+	assert.InDelta(t, 0.1, reputations[1].ComplaintRate, 0.001)
+	assert.False(t, reputations[1].Healthy)
+}
+
+func TestRouter_WindowExpires(t *testing.T) {
+	router := createTestRouter(t)
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	router.now = func() time.Time { return now }
+
+	sendAndBounce(t, router, "campaigns", 10, 10)
+	assert.Equal(t, "campaigns-backup", router.Select(models.CategoryMarketing).Name)
+
+	now = now.Add(25 * time.Hour)
+	assert.Equal(t, "campaigns", router.Select(models.CategoryMarketing).Name)
+	assert.Zero(t, router.Reputations()[1].Sent)
+}
+
+func TestRouter_Record(t *testing.T) {
+	router := createTestRouter(t)
+
+	err := router.Record("missing", OutcomeBounced)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeNotFound, notifErr.Code)
+
+	err = router.Record("campaigns", "delivered")
+	notifErr, ok = errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeValidationFailed, notifErr.Code)
+
+	require.Error(t, router.RecordNotification(&models.Notification{}, OutcomeBounced))
+	require.NoError(t, router.RecordNotification(&models.Notification{
+		Metadata: map[string]string{MetadataRoute: "otp"},
+	}, OutcomeBounced))
+	assert.Equal(t, 1, router.Reputations()[0].Bounced)
+}
+
+func TestRouter_Middleware(t *testing.T) {
+	router := createTestRouter(t)
+
+	var routed *models.NotificationRequest
+	handler := router.Middleware()(func(_ context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+		routed = request
+		return &models.NotificationResponse{}, nil
+	})
+
+	request := &models.NotificationRequest{
+		Type:      models.NotificationTypeEmail,
+		Category:  models.CategoryMarketing,
+		Recipient: "jane@example.com",
+		Metadata:  map[string]string{"campaign": "spring"},
+		EmailData: &models.EmailData{From: "Deals <deals@example.com>"},
+	}
+	_, err := handler(context.Background(), request)
+	require.NoError(t, err)
+
+	assert.Equal(t, "campaigns", routed.Metadata[MetadataRoute])
+	assert.Equal(t, "bulk", routed.Metadata[MetadataProvider])
+	assert.Equal(t, "203.0.113.7", routed.Metadata[warmup.MetadataSendingIP])
+	assert.Equal(t, "spring", routed.Metadata["campaign"])
+	assert.Equal(t, "Deals <deals@news.example.com>", routed.EmailData.From)
+	// The caller's request is left alone
+	assert.Equal(t, "Deals <deals@example.com>", request.EmailData.From)
+	assert.NotContains(t, request.Metadata, MetadataRoute)
+	assert.Equal(t, 1, router.Reputations()[1].Sent)
+
+	request.Category = models.CategoryTransactional
+	request.EmailData = nil
+	_, err = handler(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, "noreply@mail.example.com", routed.EmailData.From)
+	assert.NotContains(t, routed.Metadata, MetadataProvider)
+
+	sms := &models.NotificationRequest{Type: models.NotificationTypeSMS, Recipient: "+14155550100"}
+	_, err = handler(context.Background(), sms)
+	require.NoError(t, err)
+	assert.Same(t, sms, routed)
+}
+
+// Helper functions
+
+func createTestRouter(t *testing.T) *Router {
+	router, err := NewRouter(config.EmailRoutingConfig{
+		Routes: []config.EmailRoute{
+			{Name: "otp", Categories: []string{"transactional", "security"}, FromDomain: "mail.example.com"},
+			{Name: "campaigns", Categories: []string{"marketing"}, Provider: "bulk", FromDomain: "news.example.com", SendingIP: "203.0.113.7"},
+			{Name: "campaigns-backup", Categories: []string{"marketing"}, FromDomain: "offers.example.com"},
+		},
+		MinSends:         10,
+		MaxBounceRate:    0.05,
+		MaxComplaintRate: 0.003,
+	}, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	return router
+}
+
+// sendAndBounce counts sends through a route, the first of them bouncing
+func sendAndBounce(t *testing.T, router *Router, route string, sends, bounces int) {
+	for i := 0; i < sends; i++ {
+		require.True(t, router.record(route, func(c *count) { c.sent++ }))
+	}
+	for i := 0; i < bounces; i++ {
+		require.NoError(t, router.Record(route, OutcomeBounced))
+	}
+}
@@ -0,0 +1,309 @@
+package routing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pipeline"
+	"github.com/nareshkumar-microsoft/notificationService/internal/ratelimit"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// Names the rules engine's middleware are registered under
+const (
+	RulesMiddlewareName      = "routing-rules"
+	RuleLimitsMiddlewareName = "routing-rule-limits"
+)
+
+// Metadata keys read and set by the rules engine
+const (
+	MetadataRule    = "routing_rule"
+	MetadataCountry = "country" // country of sends without a phone number
+)
+
+// Decision is what the rule matching a send decides
+type Decision struct {
+	Rule      string            `json:"rule"`
+	Provider  string            `json:"provider,omitempty"`
+	Sender    string            `json:"sender,omitempty"`
+	RateLimit *config.RateLimit `json:"rate_limit,omitempty"`
+}
+
+// rule is a configured rule with its match lists as sets
+type rule struct {
+	config.RoutingRule
+	types      map[string]bool
+	tenants    map[string]bool
+	countries  map[string]bool
+	categories map[string]bool
+	priorities map[string]bool
+}
+
+// Engine evaluates routing rules against sends. Rules are evaluated in
+// order and the first matching rule decides.
+type Engine struct {
+	rules  []*rule
+	byName map[string]*rule
+	store  interfaces.RateLimitStore
+	logger interfaces.Logger
+}
+
+// LoadRules returns the configured rules followed by those in the rules file
+func LoadRules(cfg config.RoutingConfig) ([]config.RoutingRule, error) {
+	rules := append([]config.RoutingRule(nil), cfg.Rules...)
+	if cfg.RulesFile == "" {
+		return rules, nil
+	}
+
+	data, err := os.ReadFile(cfg.RulesFile)
+	if err != nil {
+		return nil, errors.WrapError(err, "failed to read routing rules file")
+	}
+	var fileRules []config.RoutingRule
+	if err := json.Unmarshal(data, &fileRules); err != nil {
+		return nil, errors.NewNotificationErrorWithDetails(errors.ErrorCodeValidationFailed, "routing rules file is not a JSON array of rules", err.Error())
+	}
+	return append(rules, fileRules...), nil
+}
+
+// NewEngine creates a rules engine. Rule rate limits take tokens from the
+// store, so with a shared store they hold across instances.
+func NewEngine(rules []config.RoutingRule, store interfaces.RateLimitStore, logger interfaces.Logger) (*Engine, error) {
+	e := &Engine{
+		byName: make(map[string]*rule, len(rules)),
+		store:  store,
+		logger: logger,
+	}
+
+	for i, configured := range rules {
+		field := fmt.Sprintf("rules[%d]", i)
+		if configured.Name == "" {
+			return nil, errors.NewValidationError(field, "rule name is required")
+		}
+		if _, exists := e.byName[configured.Name]; exists {
+			return nil, errors.NewValidationError(field, fmt.Sprintf("duplicate rule: %s", configured.Name))
+		}
+		if configured.Provider == "" && configured.Sender == "" && configured.RateLimit == nil {
+			return nil, errors.NewValidationError(field, fmt.Sprintf("rule %s sets no provider, sender or rate limit", configured.Name))
+		}
+
+		r := &rule{RoutingRule: configured}
+		if configured.RateLimit != nil {
+			limit, err := ratelimit.NormalizeLimit(field, configured.Name, *configured.RateLimit)
+			if err != nil {
+				return nil, err
+			}
+			r.RateLimit = &limit
+		}
+
+		var err error
+		match := configured.Match
+		if r.types, err = matchSet(field+".types", match.Types, strings.ToLower, notificationTypes()); err != nil {
+			return nil, err
+		}
+		if r.categories, err = matchSet(field+".categories", match.Categories, strings.ToLower, categories()); err != nil {
+			return nil, err
+		}
+		if r.priorities, err = matchSet(field+".priorities", match.Priorities, strings.ToLower, priorities()); err != nil {
+			return nil, err
+		}
+		r.tenants, _ = matchSet(field+".tenants", match.Tenants, strings.TrimSpace, nil)
+		r.countries, _ = matchSet(field+".countries", match.Countries, strings.ToUpper, nil)
+
+		e.rules = append(e.rules, r)
+		e.byName[r.Name] = r
+	}
+	return e, nil
+}
+
+// Rules returns the engine's rules in evaluation order
+func (e *Engine) Rules() []config.RoutingRule {
+	rules := make([]config.RoutingRule, len(e.rules))
+	for i, r := range e.rules {
+		rules[i] = r.RoutingRule
+	}
+	return rules
+}
+
+// Evaluate returns the decision of the first rule matching a send, or nil
+// when no rule matches
+func (e *Engine) Evaluate(request *models.NotificationRequest) *Decision {
+	category := request.Category
+	if category == "" {
+		category = models.CategoryTransactional
+	}
+	country := requestCountry(request)
+
+	for _, r := range e.rules {
+		if matches(r.types, string(request.Type)) &&
+			matches(r.tenants, request.Metadata[ratelimit.MetadataTenantID]) &&
+			matches(r.countries, country) &&
+			matches(r.categories, string(category)) &&
+			matches(r.priorities, string(request.Priority)) {
+			return &Decision{Rule: r.Name, Provider: r.Provider, Sender: r.Sender, RateLimit: r.RateLimit}
+		}
+	}
+	return nil
+}
+
+// Middleware returns the send middleware applying the matching rule's
+// provider and sender. Register it at pipeline.StagePreferences under
+// RulesMiddlewareName, so later stages see the chosen provider and sender.
+func (e *Engine) Middleware() pipeline.Middleware {
+	return func(next pipeline.Handler) pipeline.Handler {
+		return func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+			decision := e.Evaluate(request)
+			if decision == nil {
+				if _, exists := request.Metadata[MetadataRule]; !exists {
+					return next(ctx, request)
+				}
+				// A rule is only ever named by the engine
+				unmatched := *request
+				unmatched.Metadata = make(map[string]string, len(request.Metadata))
+				for key, value := range request.Metadata {
+					if key != MetadataRule {
+						unmatched.Metadata[key] = value
+					}
+				}
+				return next(ctx, &unmatched)
+			}
+
+			return next(ctx, applyDecision(decision, request))
+		}
+	}
+}
+
+// LimitMiddleware returns the send middleware enforcing the rate limit of
+// the rule a send matched. Register it at pipeline.StageRateLimit under
+// RuleLimitsMiddlewareName.
+func (e *Engine) LimitMiddleware() pipeline.Middleware {
+	return func(next pipeline.Handler) pipeline.Handler {
+		return func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+			if r, exists := e.byName[request.Metadata[MetadataRule]]; exists && r.RateLimit != nil {
+				if err := ratelimit.Take(ctx, e.store, "rule:"+r.Name, "routing rule "+r.Name, *r.RateLimit, e.logger); err != nil {
+					return nil, err
+				}
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// applyDecision returns a copy of a request with a rule's decision applied.
+// The sender replaces the From address of email and the sender ID of SMS.
+func applyDecision(decision *Decision, request *models.NotificationRequest) *models.NotificationRequest {
+	routed := *request
+	routed.Metadata = make(map[string]string, len(request.Metadata)+2)
+	for key, value := range request.Metadata {
+		routed.Metadata[key] = value
+	}
+	routed.Metadata[MetadataRule] = decision.Rule
+	if decision.Provider != "" {
+		routed.Metadata[MetadataProvider] = decision.Provider
+	}
+
+	if decision.Sender != "" {
+		switch request.Type {
+		case models.NotificationTypeEmail:
+			data := models.EmailData{}
+			if request.EmailData != nil {
+				data = *request.EmailData
+			}
+			data.From = decision.Sender
+			routed.EmailData = &data
+		case models.NotificationTypeSMS:
+			data := models.SMSData{}
+			if request.SMSData != nil {
+				data = *request.SMSData
+			}
+			data.SenderID = decision.Sender
+			routed.SMSData = &data
+		}
+	}
+	return &routed
+}
+
+// requestCountry returns the country a send goes to: that of its phone
+// number for SMS and voice, or the country in its metadata
+func requestCountry(request *models.NotificationRequest) string {
+	switch {
+	case request.Type == models.NotificationTypeSMS && request.SMSData != nil:
+		return utils.ResolveCountry(firstNonEmpty(request.SMSData.PhoneNumber, request.Recipient), request.SMSData.CountryCode)
+	case request.Type == models.NotificationTypeSMS:
+		return utils.CountryFromPhoneNumber(request.Recipient)
+	case request.Type == models.NotificationTypeVoice && request.VoiceData != nil:
+		return utils.ResolveCountry(firstNonEmpty(request.VoiceData.PhoneNumber, request.Recipient), request.VoiceData.CountryCode)
+	}
+	return strings.ToUpper(request.Metadata[MetadataCountry])
+}
+
+// matches reports whether a value is in a match set; an empty set matches
+// any value
+func matches(set map[string]bool, value string) bool {
+	return len(set) == 0 || set[value]
+}
+
+// matchSet normalizes a match list into a set, checking its values against
+// the known ones when there are any
+func matchSet(field string, values []string, normalize func(string) string, known map[string]bool) (map[string]bool, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		value = normalize(strings.TrimSpace(value))
+		if known != nil && !known[value] {
+			return nil, errors.NewValidationError(field, fmt.Sprintf("unknown value: %s", value))
+		}
+		set[value] = true
+	}
+	return set, nil
+}
+
+// notificationTypes returns the channels rules can match
+func notificationTypes() map[string]bool {
+	return map[string]bool{
+		string(models.NotificationTypeEmail): true,
+		string(models.NotificationTypeSMS):   true,
+		string(models.NotificationTypePush):  true,
+		string(models.NotificationTypeChat):  true,
+		string(models.NotificationTypeVoice): true,
+	}
+}
+
+// categories returns the categories rules can match
+func categories() map[string]bool {
+	set := make(map[string]bool)
+	for _, category := range models.Categories() {
+		set[string(category)] = true
+	}
+	return set
+}
+
+// priorities returns the priorities rules can match
+func priorities() map[string]bool {
+	return map[string]bool{
+		string(models.PriorityLow):    true,
+		string(models.PriorityNormal): true,
+		string(models.PriorityHigh):   true,
+		string(models.PriorityUrgent): true,
+	}
+}
+
+// firstNonEmpty returns the first of its arguments that is not empty
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,210 @@
+package routing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/ratelimit"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestNewEngine_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		rule config.RoutingRule
+	}{
+		{"missing name", config.RoutingRule{Provider: "bulk"}},
+		{"no decision", config.RoutingRule{Name: "empty"}},
+		{"unknown type", config.RoutingRule{Name: "fax", Provider: "bulk", Match: config.RuleMatch{Types: []string{"fax"}}}},
+		{"unknown category", config.RoutingRule{Name: "news", Provider: "bulk", Match: config.RuleMatch{Categories: []string{"newsletters"}}}},
+		{"unknown priority", config.RoutingRule{Name: "asap", Provider: "bulk", Match: config.RuleMatch{Priorities: []string{"asap"}}}},
+		{"invalid rate limit", config.RoutingRule{Name: "slow", RateLimit: &config.RateLimit{PerSecond: 0}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewEngine([]config.RoutingRule{tt.rule}, ratelimit.NewLocalStore(), utils.NewSimpleLogger("info"))
+			notifErr, ok := errors.AsNotificationError(err)
+			require.True(t, ok)
+			assert.Equal(t, errors.ErrorCodeValidationFailed, notifErr.Code)
+		})
+	}
+
+	_, err := NewEngine([]config.RoutingRule{
+		{Name: "bulk", Provider: "bulk"},
+		{Name: "bulk", Provider: "other"},
+	}, ratelimit.NewLocalStore(), utils.NewSimpleLogger("info"))
+	require.Error(t, err)
+}
+
+func TestEngine_Evaluate(t *testing.T) {
+	engine := createTestEngine(t)
+
+	tests := []struct {
+		name     string
+		request  *models.NotificationRequest
+		expected string
+	}{
+		{
+			name:     "tenant",
+			request:  &models.NotificationRequest{Type: models.NotificationTypeEmail, Priority: models.PriorityNormal, Metadata: map[string]string{ratelimit.MetadataTenantID: "acme"}},
+			expected: "acme",
+		},
+		{
+			name:     "country from phone number",
+			request:  &models.NotificationRequest{Type: models.NotificationTypeSMS, Priority: models.PriorityNormal, Recipient: "+919876543210"},
+			expected: "india-sms",
+		},
+		{
+			name: "country code overrides the number",
+			request: &models.NotificationRequest{Type: models.NotificationTypeSMS, Priority: models.PriorityNormal, Recipient: "9876543210",
+				SMSData: &models.SMSData{CountryCode: "in"}},
+			expected: "india-sms",
+		},
+		{
+			name:     "rules match their channels only",
+			request:  &models.NotificationRequest{Type: models.NotificationTypePush, Priority: models.PriorityNormal, Metadata: map[string]string{MetadataCountry: "in"}},
+			expected: "",
+		},
+		{
+			name:     "category and priority",
+			request:  &models.NotificationRequest{Type: models.NotificationTypeEmail, Priority: models.PriorityLow, Category: models.CategoryMarketing},
+			expected: "bulk-email",
+		},
+		{
+			name:     "missing category is transactional",
+			request:  &models.NotificationRequest{Type: models.NotificationTypeEmail, Priority: models.PriorityHigh},
+			expected: "transactional-email",
+		},
+		{
+			name:     "no match",
+			request:  &models.NotificationRequest{Type: models.NotificationTypeEmail, Priority: models.PriorityHigh, Category: models.CategoryMarketing},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := engine.Evaluate(tt.request)
+			if tt.expected == "" {
+				assert.Nil(t, decision)
+				return
+			}
+			require.NotNil(t, decision)
+			assert.Equal(t, tt.expected, decision.Rule)
+		})
+	}
+}
+
+func TestEngine_Middleware(t *testing.T) {
+	engine := createTestEngine(t)
+
+	var routed *models.NotificationRequest
+	handler := engine.Middleware()(func(_ context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+		routed = request
+		return &models.NotificationResponse{}, nil
+	})
+
+	request := &models.NotificationRequest{
+		Type:      models.NotificationTypeEmail,
+		Priority:  models.PriorityLow,
+		Category:  models.CategoryMarketing,
+		EmailData: &models.EmailData{From: "me@example.com", To: []string{"jane@example.com"}},
+	}
+	_, err := handler(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, "bulk-email", routed.Metadata[MetadataRule])
+	assert.Equal(t, "sendgrid-bulk", routed.Metadata[MetadataProvider])
+	assert.Equal(t, "news@example.com", routed.EmailData.From)
+	assert.Equal(t, []string{"jane@example.com"}, routed.EmailData.To)
+	assert.Equal(t, "me@example.com", request.EmailData.From)
+
+	sms := &models.NotificationRequest{Type: models.NotificationTypeSMS, Priority: models.PriorityNormal, Recipient: "+919876543210"}
+	_, err = handler(context.Background(), sms)
+	require.NoError(t, err)
+	assert.Equal(t, "NOTIFY", routed.SMSData.SenderID)
+	assert.NotContains(t, routed.Metadata, MetadataProvider)
+
+	// Callers cannot name a rule themselves
+	forged := &models.NotificationRequest{Type: models.NotificationTypePush, Priority: models.PriorityHigh, Metadata: map[string]string{MetadataRule: "india-sms"}}
+	_, err = handler(context.Background(), forged)
+	require.NoError(t, err)
+	assert.NotContains(t, routed.Metadata, MetadataRule)
+}
+
+func TestEngine_LimitMiddleware(t *testing.T) {
+	engine := createTestEngine(t)
+	handler := engine.LimitMiddleware()(func(_ context.Context, _ *models.NotificationRequest) (*models.NotificationResponse, error) {
+		return &models.NotificationResponse{}, nil
+	})
+
+	limited := &models.NotificationRequest{Metadata: map[string]string{MetadataRule: "india-sms"}}
+	_, err := handler(context.Background(), limited)
+	require.NoError(t, err)
+	_, err = handler(context.Background(), limited)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeRateLimited, notifErr.Code)
+	assert.NotEmpty(t, notifErr.Metadata["retry_after"])
+
+	// Rules without a limit, and unrouted sends, are not limited
+	for i := 0; i < 3; i++ {
+		_, err = handler(context.Background(), &models.NotificationRequest{Metadata: map[string]string{MetadataRule: "acme"}})
+		require.NoError(t, err)
+		_, err = handler(context.Background(), &models.NotificationRequest{})
+		require.NoError(t, err)
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"name":"from-file","match":{"types":["push"]},"provider":"fcm-eu"}]`), 0o600))
+
+	rules, err := LoadRules(config.RoutingConfig{
+		Rules:     []config.RoutingRule{{Name: "inline", Provider: "bulk"}},
+		RulesFile: path,
+	})
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	assert.Equal(t, "inline", rules[0].Name)
+	assert.Equal(t, "from-file", rules[1].Name)
+	assert.Equal(t, []string{"push"}, rules[1].Match.Types)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"name":"not-a-list"}`), 0o600))
+	_, err = LoadRules(config.RoutingConfig{RulesFile: path})
+	require.Error(t, err)
+
+	_, err = LoadRules(config.RoutingConfig{RulesFile: filepath.Join(t.TempDir(), "missing.json")})
+	require.Error(t, err)
+}
+
+// Helper functions
+
+func createTestEngine(t *testing.T) *Engine {
+	engine, err := NewEngine([]config.RoutingRule{
+		{Name: "acme", Match: config.RuleMatch{Tenants: []string{"acme"}}, Provider: "acme-ses"},
+		{
+			Name:      "india-sms",
+			Match:     config.RuleMatch{Types: []string{"SMS"}, Countries: []string{"in"}},
+			Sender:    "NOTIFY",
+			RateLimit: &config.RateLimit{PerSecond: 0.001, Burst: 1},
+		},
+		{
+			Name:     "bulk-email",
+			Match:    config.RuleMatch{Types: []string{"email"}, Categories: []string{"marketing"}, Priorities: []string{"low", "normal"}},
+			Provider: "sendgrid-bulk",
+			Sender:   "news@example.com",
+		},
+		{Name: "transactional-email", Match: config.RuleMatch{Types: []string{"email"}, Categories: []string{"transactional"}}, Provider: "ses"},
+	}, ratelimit.NewLocalStore(), utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	return engine
+}
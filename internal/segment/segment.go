@@ -0,0 +1,304 @@
+// Package segment parses and evaluates audience segment expressions such as
+//
+//	country == "IN" AND plan == "pro"
+//	(plan == "pro" OR plan == "team") AND NOT country == "US"
+//
+// Comparisons test a recipient attribute with == or != against a quoted
+// string or bare word. AND binds tighter than OR, and keywords are case-insensitive.
+// A missing attribute compares as the empty string.
+package segment
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// Expression is a parsed segment expression
+type Expression struct {
+	source string
+	root   node
+}
+
+// Parse parses a segment expression
+func Parse(source string) (*Expression, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, errors.NewValidationError("segment", "segment expression is empty")
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, syntaxError(tok, "unexpected %q", tok.text)
+	}
+
+	return &Expression{source: source, root: root}, nil
+}
+
+// Matches reports whether the attributes satisfy the expression
+func (e *Expression) Matches(attributes map[string]string) bool {
+	return e.root.eval(attributes)
+}
+
+// String returns the source of the expression
+func (e *Expression) String() string {
+	return e.source
+}
+
+// node is an evaluable expression tree node
+type node interface {
+	eval(attributes map[string]string) bool
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(attributes map[string]string) bool {
+	return n.left.eval(attributes) && n.right.eval(attributes)
+}
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(attributes map[string]string) bool {
+	return n.left.eval(attributes) || n.right.eval(attributes)
+}
+
+type notNode struct{ operand node }
+
+func (n notNode) eval(attributes map[string]string) bool {
+	return !n.operand.eval(attributes)
+}
+
+type compareNode struct {
+	attribute string
+	value     string
+	negate    bool
+}
+
+func (n compareNode) eval(attributes map[string]string) bool {
+	return (attributes[n.attribute] == n.value) != n.negate
+}
+
+// parser is a recursive descent parser over the token stream
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// parseOr parses: and (OR and)*
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().isKeyword("OR") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+// parseAnd parses: unary (AND unary)*
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().isKeyword("AND") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+// parseUnary parses: NOT unary | "(" or ")" | comparison
+func (p *parser) parseUnary() (node, error) {
+	tok := p.peek()
+
+	switch {
+	case tok.isKeyword("NOT"):
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+
+	case tok.kind == tokenLParen:
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, syntaxError(closing, "expected \")\"")
+		}
+		return inner, nil
+	}
+
+	return p.parseComparison()
+}
+
+// parseComparison parses: attribute ("==" | "!=") value
+func (p *parser) parseComparison() (node, error) {
+	attribute := p.next()
+	if attribute.kind != tokenWord || attribute.isKeyword("AND", "OR", "NOT") {
+		return nil, syntaxError(attribute, "expected attribute name")
+	}
+
+	operator := p.next()
+	if operator.kind != tokenOperator {
+		return nil, syntaxError(operator, "expected == or != after %q", attribute.text)
+	}
+
+	value := p.next()
+	if value.kind != tokenWord && value.kind != tokenString {
+		return nil, syntaxError(value, "expected value after %s", operator.text)
+	}
+
+	return compareNode{
+		attribute: attribute.text,
+		value:     value.text,
+		negate:    operator.text == "!=",
+	}, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOperator
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// isKeyword reports whether the token is an unquoted word matching one of the keywords
+func (t token) isKeyword(keywords ...string) bool {
+	if t.kind != tokenWord {
+		return false
+	}
+	for _, keyword := range keywords {
+		if strings.EqualFold(t.text, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenize splits a segment expression into tokens
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	runes := []rune(source)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+
+		case r == '=' || r == '!':
+			if i+1 >= len(runes) || runes[i+1] != '=' {
+				return nil, errors.NewValidationError("segment", fmt.Sprintf("invalid operator at position %d", i))
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: string(r) + "=", pos: i})
+			i += 2
+
+		case r == '"' || r == '\'':
+			start := i
+			var value strings.Builder
+			i++
+			for i < len(runes) && runes[i] != r {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				value.WriteRune(runes[i])
+				i++
+			}
+			if i >= len(runes) {
+				return nil, errors.NewValidationError("segment", fmt.Sprintf("unterminated string at position %d", start))
+			}
+			i++
+			tokens = append(tokens, token{kind: tokenString, text: value.String(), pos: start})
+
+		case isWordRune(r):
+			start := i
+			for i < len(runes) && isWordRune(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: string(runes[start:i]), pos: start})
+
+		default:
+			return nil, errors.NewValidationError("segment", fmt.Sprintf("unexpected character %q at position %d", r, i))
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokenEOF, pos: len(runes)})
+	return tokens, nil
+}
+
+// isWordRune reports whether a rune can appear in an attribute name or bare value
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.' || r == '@' || r == '+'
+}
+
+// syntaxError builds a validation error pointing at a token
+func syntaxError(tok token, format string, args ...interface{}) error {
+	message := fmt.Sprintf(format, args...)
+	if tok.kind == tokenEOF {
+		message += " at end of expression"
+	} else {
+		message += fmt.Sprintf(" at position %d", tok.pos)
+	}
+	return errors.NewValidationError("segment", message)
+}
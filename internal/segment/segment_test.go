@@ -0,0 +1,77 @@
+package segment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestParse_Matches(t *testing.T) {
+	attributes := map[string]string{"country": "IN", "plan": "pro", "email": "a@example.com"}
+
+	tests := []struct {
+		expression string
+		expected   bool
+	}{
+		{`country == "IN"`, true},
+		{`country == "US"`, false},
+		{`country != "US"`, true},
+		{`country == "IN" AND plan == "pro"`, true},
+		{`country == "IN" and plan == "free"`, false},
+		{`country == "US" OR plan == pro`, true},
+		{`plan == "free" OR country == "US" AND plan == "pro"`, false},
+		{`(plan == "free" OR country == "IN") AND plan == "pro"`, true},
+		{`NOT country == "US"`, true},
+		{`not (country == "IN" AND plan == "pro")`, false},
+		{`email == a@example.com`, true},
+		{`missing == ""`, true},
+		{`missing != ''`, false},
+		{`country == 'IN'`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			expression, err := Parse(tt.expression)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, expression.Matches(attributes))
+			assert.Equal(t, tt.expression, expression.String())
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []string{
+		``,
+		`   `,
+		`country`,
+		`country =`,
+		`country = "IN"`,
+		`country == "IN`,
+		`country == "IN" AND`,
+		`(country == "IN"`,
+		`country == "IN")`,
+		`AND == "x"`,
+		`country == "IN" plan == "pro"`,
+		`country > 5`,
+	}
+
+	for _, source := range tests {
+		t.Run(source, func(t *testing.T) {
+			_, err := Parse(source)
+			require.Error(t, err)
+
+			notifErr, ok := errors.AsNotificationError(err)
+			require.True(t, ok)
+			assert.Equal(t, "segment", notifErr.Metadata["field"])
+		})
+	}
+}
+
+func TestParse_EscapedQuotes(t *testing.T) {
+	expression, err := Parse(`company == "Acme \"West\""`)
+	require.NoError(t, err)
+	assert.True(t, expression.Matches(map[string]string{"company": `Acme "West"`}))
+}
@@ -0,0 +1,288 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nareshkumar-microsoft/notificationService/internal/csvio"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/segment"
+	"github.com/nareshkumar-microsoft/notificationService/internal/validation"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// Pseudo-attributes available to segment expressions alongside member attributes
+const (
+	segmentAttributeList      = "list"
+	segmentAttributeRecipient = "recipient"
+)
+
+// AudienceService manages recipient lists and resolves segment expressions
+// to recipients for campaigns and bulk sends. It implements AudienceResolver.
+//
+// Segments are evaluated against every list member's attributes, plus the
+// "list" (list name) and "recipient" pseudo-attributes, so a segment can be
+// scoped to one list with an expression like: list == "newsletter" AND plan == "pro"
+type AudienceService struct {
+	lists  interfaces.RecipientListRepository
+	logger interfaces.Logger
+}
+
+// NewAudienceService creates a new audience service
+func NewAudienceService(lists interfaces.RecipientListRepository, logger interfaces.Logger) *AudienceService {
+	return &AudienceService{
+		lists:  lists,
+		logger: logger,
+	}
+}
+
+// CreateList creates an empty recipient list
+func (s *AudienceService) CreateList(ctx context.Context, request *RecipientListRequest) (*models.RecipientList, error) {
+	if request == nil || strings.TrimSpace(request.Name) == "" {
+		return nil, errors.NewValidationError("name", "recipient list name is required")
+	}
+
+	if err := validation.Struct(request); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	list := &models.RecipientList{
+		ID:          uuid.New(),
+		Name:        strings.TrimSpace(request.Name),
+		Description: request.Description,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := s.lists.CreateList(ctx, list); err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("Created recipient list %s (%s)", list.ID, list.Name)
+	return list, nil
+}
+
+// GetList returns a recipient list by ID
+func (s *AudienceService) GetList(ctx context.Context, listID string) (*models.RecipientList, error) {
+	return s.lists.GetList(ctx, listID)
+}
+
+// ListLists returns all recipient lists
+func (s *AudienceService) ListLists(ctx context.Context) ([]*models.RecipientList, error) {
+	return s.lists.ListLists(ctx)
+}
+
+// DeleteList deletes a recipient list and its members
+func (s *AudienceService) DeleteList(ctx context.Context, listID string) error {
+	if err := s.lists.DeleteList(ctx, listID); err != nil {
+		return err
+	}
+
+	s.logger.Infof("Deleted recipient list %s", listID)
+	return nil
+}
+
+// AddMembers adds members to a list. Adding an existing recipient replaces its attributes.
+func (s *AudienceService) AddMembers(ctx context.Context, listID string, members []models.ListMember) error {
+	if len(members) == 0 {
+		return errors.NewValidationError("members", "at least one member is required")
+	}
+
+	for i, member := range members {
+		if err := validateListMember(member); err != nil {
+			s.logger.Errorf("Invalid member %d for list %s: %v", i, listID, err)
+			return err
+		}
+	}
+
+	if err := s.lists.AddMembers(ctx, listID, members); err != nil {
+		return err
+	}
+
+	s.logger.Infof("Added %d members to recipient list %s", len(members), listID)
+	return nil
+}
+
+// RemoveMembers removes recipients from a list and returns how many were removed
+func (s *AudienceService) RemoveMembers(ctx context.Context, listID string, recipients []string) (int, error) {
+	if len(recipients) == 0 {
+		return 0, errors.NewValidationError("recipients", "at least one recipient is required")
+	}
+
+	removed, err := s.lists.RemoveMembers(ctx, listID, recipients)
+	if err != nil {
+		return 0, err
+	}
+
+	s.logger.Infof("Removed %d members from recipient list %s", removed, listID)
+	return removed, nil
+}
+
+// ImportMembersCSV streams members from a CSV file into a list. The recipient
+// column is the member's recipient and the other columns become its attributes.
+// Rows that fail validation are reported in the result and skipped.
+func (s *AudienceService) ImportMembersCSV(ctx context.Context, listID string, r io.Reader, opts csvio.ImportOptions) (*csvio.ImportResult, error) {
+	if _, err := s.lists.GetList(ctx, listID); err != nil {
+		return nil, err
+	}
+
+	sink := csvio.SinkFunc(func(ctx context.Context, batch []models.CampaignRecipient) error {
+		members := make([]models.ListMember, 0, len(batch))
+		for _, recipient := range batch {
+			member := models.ListMember{Recipient: recipient.Recipient, Attributes: recipient.Data}
+			if err := validateListMember(member); err != nil {
+				return err
+			}
+			members = append(members, member)
+		}
+		return s.lists.AddMembers(ctx, listID, members)
+	})
+
+	result, err := csvio.ImportRecipients(ctx, r, sink, opts)
+	if err != nil {
+		s.logger.Errorf("CSV import into recipient list %s failed: %v", listID, err)
+		return result, err
+	}
+
+	s.logger.Infof("Imported %d of %d CSV rows into recipient list %s", result.Imported, result.Rows, listID)
+	return result, nil
+}
+
+// GetMembers returns the members of a list
+func (s *AudienceService) GetMembers(ctx context.Context, listID string) ([]models.ListMember, error) {
+	return s.lists.GetMembers(ctx, listID)
+}
+
+// ResolveSegment implements AudienceResolver. Each recipient appears once, with
+// the attributes from the first list (by name) it matched in.
+func (s *AudienceService) ResolveSegment(ctx context.Context, expression string) ([]models.CampaignRecipient, error) {
+	parsed, err := segment.Parse(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	lists, err := s.lists.ListLists(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := make([]models.CampaignRecipient, 0)
+	seen := make(map[string]bool)
+
+	for _, list := range lists {
+		members, err := s.lists.GetMembers(ctx, list.ID.String())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, member := range members {
+			if seen[member.Recipient] || !parsed.Matches(segmentAttributes(list, member)) {
+				continue
+			}
+
+			seen[member.Recipient] = true
+			recipients = append(recipients, models.CampaignRecipient{
+				Recipient: member.Recipient,
+				Data:      member.Attributes,
+			})
+		}
+	}
+
+	s.logger.Infof("Segment %q resolved to %d recipients", expression, len(recipients))
+	return recipients, nil
+}
+
+// BulkEmailRecipients resolves a segment to recipients for SendBulkEmail
+func (s *AudienceService) BulkEmailRecipients(ctx context.Context, expression string) ([]BulkEmailRecipient, error) {
+	resolved, err := s.ResolveSegment(ctx, expression)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := make([]BulkEmailRecipient, 0, len(resolved))
+	for _, recipient := range resolved {
+		recipients = append(recipients, BulkEmailRecipient{Email: recipient.Recipient, Data: recipient.Data})
+	}
+	return recipients, nil
+}
+
+// BulkSMSRecipients resolves a segment to recipients for SendBulkSMS. The
+// country code is read from the "country_code" attribute.
+func (s *AudienceService) BulkSMSRecipients(ctx context.Context, expression string) ([]BulkSMSRecipient, error) {
+	resolved, err := s.ResolveSegment(ctx, expression)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := make([]BulkSMSRecipient, 0, len(resolved))
+	for _, recipient := range resolved {
+		recipients = append(recipients, BulkSMSRecipient{
+			PhoneNumber: recipient.Recipient,
+			CountryCode: recipient.Data["country_code"],
+			Data:        recipient.Data,
+		})
+	}
+	return recipients, nil
+}
+
+// BulkPushRecipients resolves a segment to recipients for SendBulkPush. The
+// platform is read from the "platform" attribute.
+func (s *AudienceService) BulkPushRecipients(ctx context.Context, expression string) ([]BulkPushRecipient, error) {
+	resolved, err := s.ResolveSegment(ctx, expression)
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := make([]BulkPushRecipient, 0, len(resolved))
+	for _, recipient := range resolved {
+		recipients = append(recipients, BulkPushRecipient{
+			DeviceToken: recipient.Recipient,
+			Platform:    recipient.Data["platform"],
+			Data:        recipient.Data,
+		})
+	}
+	return recipients, nil
+}
+
+// Helper functions
+
+// validateListMember validates a list member
+func validateListMember(member models.ListMember) error {
+	if strings.TrimSpace(member.Recipient) == "" {
+		return errors.NewValidationError("recipient", "member recipient is required")
+	}
+
+	for name := range member.Attributes {
+		if name == "" {
+			return errors.NewValidationError("attributes", "attribute names cannot be empty")
+		}
+		if name == segmentAttributeList || name == segmentAttributeRecipient {
+			return errors.NewValidationError("attributes", fmt.Sprintf("attribute name is reserved: %s", name))
+		}
+	}
+
+	return nil
+}
+
+// segmentAttributes returns the attributes a segment expression is evaluated against
+func segmentAttributes(list *models.RecipientList, member models.ListMember) map[string]string {
+	attributes := make(map[string]string, len(member.Attributes)+2)
+	for name, value := range member.Attributes {
+		attributes[name] = value
+	}
+	attributes[segmentAttributeList] = list.Name
+	attributes[segmentAttributeRecipient] = member.Recipient
+	return attributes
+}
+
+// RecipientListRequest represents a request to create a recipient list
+type RecipientListRequest struct {
+	Name        string `json:"name" validate:"required"`
+	Description string `json:"description,omitempty"`
+}
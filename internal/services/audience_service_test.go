@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/csvio"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/repository"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestAudienceService_Lists(t *testing.T) {
+	service := createTestAudienceService()
+	ctx := context.Background()
+
+	list, err := service.CreateList(ctx, &RecipientListRequest{Name: " newsletter ", Description: "Monthly"})
+	require.NoError(t, err)
+	assert.Equal(t, "newsletter", list.Name)
+
+	_, err = service.CreateList(ctx, &RecipientListRequest{})
+	require.Error(t, err)
+
+	id := list.ID.String()
+	require.NoError(t, service.AddMembers(ctx, id, []models.ListMember{
+		{Recipient: "a@example.com", Attributes: map[string]string{"country": "IN"}},
+		{Recipient: "b@example.com"},
+	}))
+
+	removed, err := service.RemoveMembers(ctx, id, []string{"b@example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	members, err := service.GetMembers(ctx, id)
+	require.NoError(t, err)
+	assert.Len(t, members, 1)
+
+	require.NoError(t, service.DeleteList(ctx, id))
+	lists, err := service.ListLists(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, lists)
+}
+
+func TestAudienceService_AddMembers_Validation(t *testing.T) {
+	service := createTestAudienceService()
+	ctx := context.Background()
+
+	list, err := service.CreateList(ctx, &RecipientListRequest{Name: "newsletter"})
+	require.NoError(t, err)
+	id := list.ID.String()
+
+	tests := []struct {
+		name    string
+		members []models.ListMember
+	}{
+		{"no members", nil},
+		{"missing recipient", []models.ListMember{{Recipient: " "}}},
+		{"empty attribute name", []models.ListMember{{Recipient: "a@example.com", Attributes: map[string]string{"": "x"}}}},
+		{"reserved attribute", []models.ListMember{{Recipient: "a@example.com", Attributes: map[string]string{"list": "x"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.AddMembers(ctx, id, tt.members)
+			require.Error(t, err)
+			notifErr, ok := errors.AsNotificationError(err)
+			require.True(t, ok)
+			assert.Equal(t, errors.ErrorCodeValidationFailed, notifErr.Code)
+		})
+	}
+}
+
+func TestAudienceService_ResolveSegment(t *testing.T) {
+	service := createTestAudienceService()
+	ctx := context.Background()
+	seedTestAudience(t, service)
+
+	recipients, err := service.ResolveSegment(ctx, `country == "IN" AND plan == "pro"`)
+	require.NoError(t, err)
+	require.Len(t, recipients, 2)
+	assert.Equal(t, "ravi@example.com", recipients[0].Recipient) // lists are scanned by name
+	assert.Equal(t, "asha@example.com", recipients[1].Recipient)
+
+	// Scoped to one list with the list pseudo-attribute
+	recipients, err = service.ResolveSegment(ctx, `list == "beta" AND plan == "pro"`)
+	require.NoError(t, err)
+	require.Len(t, recipients, 1)
+	assert.Equal(t, "ravi@example.com", recipients[0].Recipient)
+
+	// Recipients in several lists are returned once
+	recipients, err = service.ResolveSegment(ctx, `country != "XX"`)
+	require.NoError(t, err)
+	assert.Len(t, recipients, 3)
+
+	_, err = service.ResolveSegment(ctx, `country ==`)
+	require.Error(t, err)
+}
+
+func TestAudienceService_BulkRecipients(t *testing.T) {
+	service := createTestAudienceService()
+	ctx := context.Background()
+	seedTestAudience(t, service)
+
+	emails, err := service.BulkEmailRecipients(ctx, `country == "US"`)
+	require.NoError(t, err)
+	require.Len(t, emails, 1)
+	assert.Equal(t, "sam@example.com", emails[0].Email)
+	assert.Equal(t, "free", emails[0].Data["plan"])
+
+	list, err := service.CreateList(ctx, &RecipientListRequest{Name: "sms"})
+	require.NoError(t, err)
+	require.NoError(t, service.AddMembers(ctx, list.ID.String(), []models.ListMember{
+		{Recipient: "+919876543210", Attributes: map[string]string{"country_code": "IN"}},
+	}))
+
+	sms, err := service.BulkSMSRecipients(ctx, `list == sms`)
+	require.NoError(t, err)
+	require.Len(t, sms, 1)
+	assert.Equal(t, "IN", sms[0].CountryCode)
+
+	list, err = service.CreateList(ctx, &RecipientListRequest{Name: "devices"})
+	require.NoError(t, err)
+	require.NoError(t, service.AddMembers(ctx, list.ID.String(), []models.ListMember{
+		{Recipient: testIOSToken, Attributes: map[string]string{"platform": "ios"}},
+	}))
+
+	push, err := service.BulkPushRecipients(ctx, `list == devices`)
+	require.NoError(t, err)
+	require.Len(t, push, 1)
+	assert.Equal(t, "ios", push[0].Platform)
+}
+
+func TestAudienceService_CampaignSegment(t *testing.T) {
+	server, texts := createTestChatServer(t)
+	defer server.Close()
+
+	audience := createTestAudienceService()
+	ctx := context.Background()
+
+	list, err := audience.CreateList(ctx, &RecipientListRequest{Name: "ops"})
+	require.NoError(t, err)
+	require.NoError(t, audience.AddMembers(ctx, list.ID.String(), []models.ListMember{
+		{Recipient: server.URL + "/oncall", Attributes: map[string]string{"name": "On-call", "team": "sre"}},
+		{Recipient: server.URL + "/sales", Attributes: map[string]string{"name": "Sales", "team": "sales"}},
+	}))
+
+	campaigns := createTestCampaignService(t)
+	campaigns.SetAudienceResolver(audience)
+	campaigns.Start(ctx)
+	defer campaigns.Stop()
+
+	request := createTestCampaignRequest()
+	request.Audience = models.CampaignAudience{Segment: `team == "sre"`}
+
+	campaign, err := campaigns.CreateCampaign(request)
+	require.NoError(t, err)
+	require.NoError(t, campaigns.LaunchCampaign(campaign.ID))
+
+	finished := waitForCampaign(t, campaigns, campaign.ID)
+	assert.Equal(t, models.CampaignStatusCompleted, finished.Status)
+	assert.Equal(t, []string{"*Sale*\nHi On-call, the sale is on"}, texts())
+}
+
+func TestAudienceService_ImportMembersCSV(t *testing.T) {
+	service := createTestAudienceService()
+	ctx := context.Background()
+
+	list, err := service.CreateList(ctx, &RecipientListRequest{Name: "imported"})
+	require.NoError(t, err)
+
+	input := "email,country,plan\nasha@example.com,IN,pro\ninvalid,IN,pro\nsam@example.com,US,free\n"
+	result, err := service.ImportMembersCSV(ctx, list.ID.String(), strings.NewReader(input), csvio.ImportOptions{Channel: models.NotificationTypeEmail})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Imported)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, 3, result.Errors[0].Row)
+
+	recipients, err := service.ResolveSegment(ctx, `list == imported AND country == IN`)
+	require.NoError(t, err)
+	require.Len(t, recipients, 1)
+	assert.Equal(t, "pro", recipients[0].Data["plan"])
+
+	// Reserved attribute columns are rejected
+	_, err = service.ImportMembersCSV(ctx, list.ID.String(), strings.NewReader("email,list\na@example.com,x\n"), csvio.ImportOptions{})
+	assert.Error(t, err)
+
+	_, err = service.ImportMembersCSV(ctx, "missing", strings.NewReader(input), csvio.ImportOptions{})
+	assert.Error(t, err)
+}
+
+// Helper functions
+
+func createTestAudienceService() *AudienceService {
+	return NewAudienceService(repository.NewMemoryRecipientListRepository(), utils.NewSimpleLogger("info"))
+}
+
+func seedTestAudience(t *testing.T, service *AudienceService) {
+	ctx := context.Background()
+
+	newsletter, err := service.CreateList(ctx, &RecipientListRequest{Name: "newsletter"})
+	require.NoError(t, err)
+	require.NoError(t, service.AddMembers(ctx, newsletter.ID.String(), []models.ListMember{
+		{Recipient: "asha@example.com", Attributes: map[string]string{"country": "IN", "plan": "pro"}},
+		{Recipient: "sam@example.com", Attributes: map[string]string{"country": "US", "plan": "free"}},
+	}))
+
+	beta, err := service.CreateList(ctx, &RecipientListRequest{Name: "beta"})
+	require.NoError(t, err)
+	require.NoError(t, service.AddMembers(ctx, beta.ID.String(), []models.ListMember{
+		{Recipient: "ravi@example.com", Attributes: map[string]string{"country": "IN", "plan": "pro"}},
+		{Recipient: "asha@example.com", Attributes: map[string]string{"country": "IN", "plan": "free"}},
+	}))
+}
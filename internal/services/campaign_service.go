@@ -0,0 +1,701 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/events"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pause"
+	"github.com/nareshkumar-microsoft/notificationService/internal/queue"
+	"github.com/nareshkumar-microsoft/notificationService/internal/templatevars"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/internal/validation"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// enqueueRetryDelay is how long a campaign waits for room when the queue is full
+const enqueueRetryDelay = 50 * time.Millisecond
+
+// campaignClaimLease is how long a scheduled launch keeps its claim, long
+// enough to cover the timers of every replica it was scheduled on
+const campaignClaimLease = time.Hour
+
+// AudienceResolver resolves a segment query to campaign recipients
+type AudienceResolver interface {
+	ResolveSegment(ctx context.Context, segment string) ([]models.CampaignRecipient, error)
+}
+
+// CampaignService manages notification campaigns: it resolves each campaign's
+// audience, renders its template per recipient, feeds the queue at the
+// campaign's throttle rate and tracks campaign-level stats
+type CampaignService struct {
+	mu            sync.Mutex
+	campaigns     map[uuid.UUID]*models.Campaign
+	notifications map[uuid.UUID]uuid.UUID // notification ID to campaign ID
+	cancels       map[uuid.UUID]context.CancelFunc
+	timers        map[uuid.UUID]*time.Timer
+
+	dispatcher interfaces.NotificationService
+	queue      *queue.MemoryQueue
+	workers    *queue.WorkerPool
+	resolver   AudienceResolver
+	devices    *DeviceRegistry
+	locker     interfaces.Locker
+	owner      string
+	logger     interfaces.Logger
+	baseCtx    context.Context
+	now        func() time.Time
+}
+
+// NewCampaignService creates a new campaign service that sends through the dispatcher
+func NewCampaignService(dispatcher interfaces.NotificationService, cfg config.QueueConfig, logger interfaces.Logger) *CampaignService {
+	service := &CampaignService{
+		campaigns:     make(map[uuid.UUID]*models.Campaign),
+		notifications: make(map[uuid.UUID]uuid.UUID),
+		cancels:       make(map[uuid.UUID]context.CancelFunc),
+		timers:        make(map[uuid.UUID]*time.Timer),
+		dispatcher:    dispatcher,
+		queue:         queue.NewMemoryQueue(cfg.MaxSize),
+		logger:        logger,
+		now:           time.Now,
+	}
+
+	service.workers = queue.NewWorkerPool(service.queue, cfg.Workers, service.processJob, logger)
+
+	return service
+}
+
+// SetAudienceResolver sets the resolver used for segment audiences
+func (s *CampaignService) SetAudienceResolver(resolver AudienceResolver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.resolver = resolver
+}
+
+// SetDeviceRegistry sets the registry recipients' time zones are read from
+// for campaigns sent at local time. A recipient's "timezone" data takes
+// precedence over their device's.
+func (s *CampaignService) SetDeviceRegistry(registry *DeviceRegistry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.devices = registry
+}
+
+// SetLocker makes a scheduled campaign claim its name and scheduled time as
+// owner when its time comes, so a campaign scheduled on several replicas
+// sharing the locker, e.g. by a retried request, is sent by one of them.
+// The others cancel their copy.
+func (s *CampaignService) SetLocker(locker interfaces.Locker, owner string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.locker = locker
+	s.owner = owner
+}
+
+// SetPauseController makes the campaign queue hold jobs for paused channels
+// and providers until sending resumes
+func (s *CampaignService) SetPauseController(controller *pause.Controller) {
+	controller.Attach(s.queue)
+}
+
+// Start starts the queue workers. Campaigns can only be launched once started.
+func (s *CampaignService) Start(ctx context.Context) {
+	s.mu.Lock()
+	s.baseCtx = ctx
+	s.mu.Unlock()
+
+	s.workers.Start(ctx)
+}
+
+// Stop cancels scheduled and running campaigns and stops the queue workers
+func (s *CampaignService) Stop() {
+	s.mu.Lock()
+	for id, timer := range s.timers {
+		timer.Stop()
+		delete(s.timers, id)
+	}
+	for id, cancel := range s.cancels {
+		cancel()
+		delete(s.cancels, id)
+	}
+	s.baseCtx = nil
+	s.mu.Unlock()
+
+	s.workers.Stop()
+}
+
+// CreateCampaign creates a draft campaign
+func (s *CampaignService) CreateCampaign(request *CampaignRequest) (*models.Campaign, error) {
+	if err := s.validateCampaignRequest(request); err != nil {
+		s.logger.Errorf("Campaign validation failed: %v", err)
+		return nil, err
+	}
+
+	priority := request.Priority
+	if priority == "" {
+		priority = models.PriorityNormal
+	}
+
+	now := time.Now()
+	campaign := &models.Campaign{
+		ID:              uuid.New(),
+		Name:            request.Name,
+		Channel:         request.Channel,
+		Priority:        priority,
+		Audience:        request.Audience,
+		Template:        request.Template,
+		TemplateData:    request.TemplateData,
+		ScheduledAt:     request.ScheduledAt,
+		RatePerMin:      request.RatePerMin,
+		SendAtLocalTime: request.SendAtLocalTime,
+		Status:          models.CampaignStatusDraft,
+		Metadata:        request.Metadata,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	s.mu.Lock()
+	s.campaigns[campaign.ID] = campaign
+	s.mu.Unlock()
+
+	s.logger.Infof("Created campaign %s (%s)", campaign.ID, campaign.Name)
+	return cloneCampaign(campaign), nil
+}
+
+// GetCampaign returns a campaign by ID
+func (s *CampaignService) GetCampaign(id uuid.UUID) (*models.Campaign, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	campaign, exists := s.campaigns[id]
+	if !exists {
+		return nil, errors.NewNotificationError(errors.ErrorCodeNotFound, fmt.Sprintf("campaign not found: %s", id))
+	}
+
+	return cloneCampaign(campaign), nil
+}
+
+// ListCampaigns returns all campaigns
+func (s *CampaignService) ListCampaigns() []*models.Campaign {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	campaigns := make([]*models.Campaign, 0, len(s.campaigns))
+	for _, campaign := range s.campaigns {
+		campaigns = append(campaigns, cloneCampaign(campaign))
+	}
+	return campaigns
+}
+
+// GetCampaignStats returns campaign-level delivery statistics
+func (s *CampaignService) GetCampaignStats(id uuid.UUID) (*models.CampaignStats, error) {
+	campaign, err := s.GetCampaign(id)
+	if err != nil {
+		return nil, err
+	}
+	return &campaign.Stats, nil
+}
+
+// LaunchCampaign starts a draft campaign immediately, or schedules it when
+// its scheduled time is in the future
+func (s *CampaignService) LaunchCampaign(id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	campaign, exists := s.campaigns[id]
+	if !exists {
+		return errors.NewNotificationError(errors.ErrorCodeNotFound, fmt.Sprintf("campaign not found: %s", id))
+	}
+
+	if campaign.Status != models.CampaignStatusDraft {
+		return errors.NewValidationError("status", fmt.Sprintf("campaign is %s and cannot be launched", campaign.Status))
+	}
+
+	if s.baseCtx == nil {
+		return errors.NewNotificationError(errors.ErrorCodeProviderUnavailable, "campaign service is not started")
+	}
+
+	if campaign.ScheduledAt != nil && campaign.ScheduledAt.After(time.Now()) {
+		campaign.Status = models.CampaignStatusScheduled
+		campaign.UpdatedAt = time.Now()
+		s.timers[id] = time.AfterFunc(time.Until(*campaign.ScheduledAt), func() {
+			s.mu.Lock()
+			delete(s.timers, id)
+			s.mu.Unlock()
+			if s.claimLaunch(id) {
+				s.runCampaign(id)
+			}
+		})
+		s.logger.Infof("Scheduled campaign %s for %s", id, campaign.ScheduledAt.Format(time.RFC3339))
+		return nil
+	}
+
+	campaign.Status = models.CampaignStatusRunning
+	go s.runCampaign(id)
+	return nil
+}
+
+// CancelCampaign cancels a scheduled or running campaign. Notifications
+// already handed to the dispatcher are not recalled.
+func (s *CampaignService) CancelCampaign(id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	campaign, exists := s.campaigns[id]
+	if !exists {
+		return errors.NewNotificationError(errors.ErrorCodeNotFound, fmt.Sprintf("campaign not found: %s", id))
+	}
+
+	if campaign.IsFinished() {
+		return errors.NewValidationError("status", fmt.Sprintf("campaign is already %s", campaign.Status))
+	}
+
+	if timer, exists := s.timers[id]; exists {
+		timer.Stop()
+		delete(s.timers, id)
+	}
+	if cancel, exists := s.cancels[id]; exists {
+		cancel()
+		delete(s.cancels, id)
+	}
+
+	s.finishCampaign(campaign, models.CampaignStatusCancelled, "")
+	s.logger.Infof("Cancelled campaign %s", id)
+	return nil
+}
+
+// RecordDelivered records a delivery receipt for a campaign notification
+func (s *CampaignService) RecordDelivered(notificationID uuid.UUID) error {
+	return s.recordEvent(notificationID, func(stats *models.CampaignStats) {
+		stats.Delivered++
+	})
+}
+
+// RecordOpened records an open for a campaign notification
+func (s *CampaignService) RecordOpened(notificationID uuid.UUID) error {
+	return s.recordEvent(notificationID, func(stats *models.CampaignStats) {
+		stats.Opened++
+	})
+}
+
+// HandleEvent implements events.Subscriber, updating campaign stats from
+// delivery and acknowledgement events. Subscribe it for
+// EventNotificationDelivered and EventNotificationAcknowledged.
+func (s *CampaignService) HandleEvent(ctx context.Context, event events.Event) error {
+	if event.Metadata["campaign_id"] == "" {
+		return nil
+	}
+	switch event.Type {
+	case events.EventNotificationDelivered:
+		return s.RecordDelivered(event.NotificationID)
+	case events.EventNotificationAcknowledged:
+		return s.RecordOpened(event.NotificationID)
+	}
+	return nil
+}
+
+// recordEvent applies a stats update to the campaign a notification belongs to
+func (s *CampaignService) recordEvent(notificationID uuid.UUID, update func(stats *models.CampaignStats)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	campaignID, exists := s.notifications[notificationID]
+	if !exists {
+		return errors.NewNotificationError(errors.ErrorCodeNotFound, fmt.Sprintf("notification is not part of a campaign: %s", notificationID))
+	}
+
+	campaign := s.campaigns[campaignID]
+	update(&campaign.Stats)
+	campaign.UpdatedAt = time.Now()
+	return nil
+}
+
+// claimLaunch claims the launch of a scheduled campaign when the service has
+// a locker. A campaign another instance claimed is cancelled. When the
+// locker fails the campaign runs rather than risk not sending it.
+func (s *CampaignService) claimLaunch(id uuid.UUID) bool {
+	s.mu.Lock()
+	campaign, exists := s.campaigns[id]
+	if !exists || campaign.ScheduledAt == nil || s.locker == nil {
+		s.mu.Unlock()
+		return true
+	}
+	key := fmt.Sprintf("campaign:%s@%d", campaign.Name, campaign.ScheduledAt.Unix())
+	locker, owner := s.locker, s.owner
+	s.mu.Unlock()
+
+	claimed, err := locker.Acquire(context.Background(), key, owner, campaignClaimLease)
+	if err != nil {
+		s.logger.Warnf("Failed to claim the launch of campaign %s, running it: %v", id, err)
+		return true
+	}
+	if claimed {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !campaign.IsFinished() {
+		s.finishCampaign(campaign, models.CampaignStatusCancelled, "launched on another instance")
+	}
+	s.logger.Infof("Campaign %s was launched on another instance, cancelled", id)
+	return false
+}
+
+// runCampaign resolves the audience and feeds the queue at the campaign's throttle rate
+func (s *CampaignService) runCampaign(id uuid.UUID) {
+	s.mu.Lock()
+	campaign, exists := s.campaigns[id]
+	if !exists || campaign.IsFinished() || s.baseCtx == nil {
+		s.mu.Unlock()
+		return
+	}
+
+	ctx, cancel := context.WithCancel(s.baseCtx)
+	s.cancels[id] = cancel
+
+	now := time.Now()
+	campaign.Status = models.CampaignStatusRunning
+	campaign.StartedAt = &now
+	campaign.UpdatedAt = now
+	snapshot := cloneCampaign(campaign)
+	s.mu.Unlock()
+
+	s.logger.Infof("Running campaign %s", id)
+
+	recipients, err := s.resolveAudience(ctx, snapshot)
+	if err != nil {
+		s.mu.Lock()
+		s.finishCampaign(campaign, models.CampaignStatusFailed, err.Error())
+		s.mu.Unlock()
+		return
+	}
+
+	s.mu.Lock()
+	campaign.Stats.Total = len(recipients)
+	if len(recipients) == 0 {
+		s.finishCampaign(campaign, models.CampaignStatusCompleted, "")
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+
+	var interval time.Duration
+	if snapshot.RatePerMin > 0 {
+		interval = time.Minute / time.Duration(snapshot.RatePerMin)
+	}
+
+	template := compileCampaignTemplate(snapshot.Template)
+	var sendAt []time.Time
+	if snapshot.SendAtLocalTime != "" {
+		recipients, sendAt = s.staggerRecipients(snapshot, recipients)
+	}
+
+	for i, recipient := range recipients {
+		if sendAt != nil {
+			if wait := sendAt[i].Sub(s.now()); wait > 0 {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(wait):
+				}
+			}
+		}
+
+		if i > 0 && interval > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+
+		job := &queue.Job{
+			Request:  buildCampaignRequest(snapshot, template, recipient),
+			Metadata: map[string]string{"campaign_id": id.String()},
+		}
+
+		if err := s.enqueue(ctx, job); err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		campaign.Stats.Queued++
+		s.mu.Unlock()
+	}
+}
+
+// staggerRecipients orders recipients by when the campaign's local time
+// comes in their time zone, and returns those times
+func (s *CampaignService) staggerRecipients(campaign *models.Campaign, recipients []models.CampaignRecipient) ([]models.CampaignRecipient, []time.Time) {
+	s.mu.Lock()
+	devices := s.devices
+	s.mu.Unlock()
+
+	now := s.now()
+	sendAt := make(map[string]time.Time, len(recipients))
+	for _, recipient := range recipients {
+		timezone := recipient.Data["timezone"]
+		if timezone == "" && devices != nil {
+			timezone = devices.Timezone(recipient.Recipient)
+		}
+		sendAt[recipient.Recipient] = nextLocalTime(now, campaign.SendAtLocalTime, timezone)
+	}
+
+	ordered := append([]models.CampaignRecipient(nil), recipients...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return sendAt[ordered[i].Recipient].Before(sendAt[ordered[j].Recipient])
+	})
+
+	times := make([]time.Time, len(ordered))
+	for i, recipient := range ordered {
+		times[i] = sendAt[recipient.Recipient]
+	}
+	s.logger.Infof("Campaign %s sends at %s local time, from %s to %s", campaign.ID, campaign.SendAtLocalTime,
+		times[0].Format(time.RFC3339), times[len(times)-1].Format(time.RFC3339))
+	return ordered, times
+}
+
+// enqueue adds a job to the queue, waiting for room while the queue is full
+func (s *CampaignService) enqueue(ctx context.Context, job *queue.Job) error {
+	for {
+		err := s.queue.Enqueue(job)
+		if err == nil {
+			return nil
+		}
+
+		if notifErr, ok := errors.AsNotificationError(err); !ok || notifErr.Code != errors.ErrorCodeQueueFull {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(enqueueRetryDelay):
+		}
+	}
+}
+
+// processJob dispatches a queued campaign notification and updates campaign stats
+func (s *CampaignService) processJob(ctx context.Context, job *queue.Job) error {
+	campaignID, err := uuid.Parse(job.Metadata["campaign_id"])
+	if err != nil {
+		return errors.NewValidationError("campaign_id", "job is not part of a campaign")
+	}
+
+	s.mu.Lock()
+	campaign, exists := s.campaigns[campaignID]
+	cancelled := exists && campaign.Status == models.CampaignStatusCancelled
+	s.mu.Unlock()
+
+	if !exists || cancelled {
+		return nil
+	}
+
+	response, sendErr := s.dispatcher.SendNotification(ctx, job.Request)
+	if pause.IsPausedError(sendErr) {
+		// Paused after the job was dequeued: hold it with the rest of the backlog
+		return s.enqueue(ctx, job)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	campaign.Stats.Queued--
+	if sendErr != nil {
+		campaign.Stats.Failed++
+	} else {
+		campaign.Stats.Sent++
+		if response.ID != uuid.Nil {
+			// Sends held by a middleware, e.g. for quiet hours, have no notification yet
+			s.notifications[response.ID] = campaignID
+		}
+	}
+	campaign.UpdatedAt = time.Now()
+
+	if campaign.Status == models.CampaignStatusRunning && campaign.Stats.Sent+campaign.Stats.Failed >= campaign.Stats.Total {
+		s.finishCampaign(campaign, models.CampaignStatusCompleted, "")
+		s.logger.Infof("Campaign %s completed: %d sent, %d failed", campaign.ID, campaign.Stats.Sent, campaign.Stats.Failed)
+	}
+
+	return sendErr
+}
+
+// resolveAudience combines explicit recipients with the segment's recipients,
+// skipping duplicates
+func (s *CampaignService) resolveAudience(ctx context.Context, campaign *models.Campaign) ([]models.CampaignRecipient, error) {
+	recipients := make([]models.CampaignRecipient, 0, len(campaign.Audience.Recipients))
+	seen := make(map[string]bool)
+
+	add := func(list []models.CampaignRecipient) {
+		for _, recipient := range list {
+			if recipient.Recipient == "" || seen[recipient.Recipient] {
+				continue
+			}
+			seen[recipient.Recipient] = true
+			recipients = append(recipients, recipient)
+		}
+	}
+
+	add(campaign.Audience.Recipients)
+
+	if campaign.Audience.Segment != "" {
+		s.mu.Lock()
+		resolver := s.resolver
+		s.mu.Unlock()
+
+		if resolver == nil {
+			return nil, errors.NewValidationError("segment", "no audience resolver is configured for segment queries")
+		}
+
+		segmentRecipients, err := resolver.ResolveSegment(ctx, campaign.Audience.Segment)
+		if err != nil {
+			return nil, err
+		}
+		add(segmentRecipients)
+	}
+
+	return recipients, nil
+}
+
+// finishCampaign moves a campaign to a final state. Callers must hold the lock.
+func (s *CampaignService) finishCampaign(campaign *models.Campaign, status models.CampaignStatus, errorMsg string) {
+	now := time.Now()
+	campaign.Status = status
+	campaign.CompletedAt = &now
+	campaign.UpdatedAt = now
+	campaign.ErrorMsg = errorMsg
+
+	if cancel, exists := s.cancels[campaign.ID]; exists {
+		cancel()
+		delete(s.cancels, campaign.ID)
+	}
+}
+
+// validateCampaignRequest validates a campaign request
+func (s *CampaignService) validateCampaignRequest(request *CampaignRequest) error {
+	if request == nil {
+		return errors.NewValidationError("request", "campaign request is required")
+	}
+
+	if err := validation.Struct(request); err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(request.Name) == "" {
+		return errors.NewValidationError("name", "campaign name is required")
+	}
+
+	if !utils.IsValidNotificationType(request.Channel) {
+		return errors.NewValidationError("channel", "unsupported notification channel")
+	}
+
+	if request.Priority != "" && !utils.IsValidPriority(request.Priority) {
+		return errors.NewValidationError("priority", "invalid priority level")
+	}
+
+	if request.Template.Body == "" {
+		return errors.NewValidationError("template", "campaign template body is required")
+	}
+
+	if len(request.Audience.Recipients) == 0 && request.Audience.Segment == "" {
+		return errors.NewValidationError("audience", "campaign audience requires recipients or a segment")
+	}
+
+	if request.RatePerMin < 0 {
+		return errors.NewValidationError("rate_per_minute", "throttle rate cannot be negative")
+	}
+
+	if request.SendAtLocalTime != "" {
+		if err := validateLocalTime(request.SendAtLocalTime); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Helper functions
+
+// campaignTemplate is a campaign template compiled once for all its recipients
+type campaignTemplate struct {
+	subject *templatevars.Template
+	body    *templatevars.Template
+}
+
+// compileCampaignTemplate compiles a campaign's template
+func compileCampaignTemplate(template models.CampaignTemplate) campaignTemplate {
+	return campaignTemplate{
+		subject: templatevars.Compile(template.Subject),
+		body:    templatevars.Compile(template.Body),
+	}
+}
+
+// buildCampaignRequest renders the campaign template for one recipient
+func buildCampaignRequest(campaign *models.Campaign, template campaignTemplate, recipient models.CampaignRecipient) *models.NotificationRequest {
+	data := mergeTemplateData(campaign.TemplateData, recipient.Data)
+	renderData := templatevars.WithRecipient(data, recipient.Recipient)
+	now := time.Now()
+
+	request := &models.NotificationRequest{
+		Type:         campaign.Channel,
+		Priority:     campaign.Priority,
+		Recipient:    recipient.Recipient,
+		Subject:      template.subject.Render(renderData, now),
+		Body:         template.body.Render(renderData, now),
+		Category:     models.CategoryMarketing,
+		TemplateData: data,
+		Metadata: map[string]string{
+			"campaign_id":   campaign.ID.String(),
+			"campaign_name": campaign.Name,
+		},
+	}
+
+	// Channel details travel in the recipient's data
+	switch campaign.Channel {
+	case models.NotificationTypePush:
+		request.PushData = &models.PushData{Platform: data["platform"], Title: request.Subject}
+	case models.NotificationTypeSMS:
+		request.SMSData = &models.SMSData{CountryCode: data["country_code"]}
+	case models.NotificationTypeVoice:
+		request.VoiceData = &models.VoiceData{CountryCode: data["country_code"]}
+	}
+
+	return request
+}
+
+// renderCampaignText replaces {{variable}} placeholders with recipient data
+// cloneCampaign copies a campaign so callers cannot mutate service state
+func cloneCampaign(campaign *models.Campaign) *models.Campaign {
+	clone := *campaign
+	return &clone
+}
+
+// CampaignRequest represents a request to create a campaign
+type CampaignRequest struct {
+	Name         string                  `json:"name" validate:"required"`
+	Channel      models.NotificationType `json:"channel" validate:"required"`
+	Priority     models.Priority         `json:"priority,omitempty"`
+	Audience     models.CampaignAudience `json:"audience"`
+	Template     models.CampaignTemplate `json:"template"`
+	TemplateData map[string]string       `json:"template_data,omitempty"`
+	ScheduledAt  *time.Time              `json:"scheduled_at,omitempty"`
+	RatePerMin   int                     `json:"rate_per_minute,omitempty"`
+	Metadata     map[string]string       `json:"metadata,omitempty"`
+
+	// SendAtLocalTime, e.g. "09:00", sends to each recipient at that time in
+	// their time zone
+	SendAtLocalTime string `json:"send_at_local_time,omitempty"`
+}
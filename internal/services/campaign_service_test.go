@@ -0,0 +1,446 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/events"
+	"github.com/nareshkumar-microsoft/notificationService/internal/lock"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pause"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestCampaignService_CreateCampaign(t *testing.T) {
+	service := createTestCampaignService(t)
+
+	campaign, err := service.CreateCampaign(createTestCampaignRequest("https://hooks.example.com/a"))
+	require.NoError(t, err)
+	assert.Equal(t, models.CampaignStatusDraft, campaign.Status)
+	assert.Equal(t, models.PriorityNormal, campaign.Priority)
+	assert.NotEqual(t, uuid.Nil, campaign.ID)
+
+	stored, err := service.GetCampaign(campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, campaign.Name, stored.Name)
+	assert.Len(t, service.ListCampaigns(), 1)
+}
+
+func TestCampaignService_CreateCampaign_Validation(t *testing.T) {
+	service := createTestCampaignService(t)
+
+	tests := []struct {
+		name   string
+		modify func(r *CampaignRequest)
+		field  string
+	}{
+		{"missing name", func(r *CampaignRequest) { r.Name = " " }, "name"},
+		{"invalid channel", func(r *CampaignRequest) { r.Channel = "fax" }, "channel"},
+		{"invalid priority", func(r *CampaignRequest) { r.Priority = "asap" }, "priority"},
+		{"missing body", func(r *CampaignRequest) { r.Template.Body = "" }, "template"},
+		{"empty audience", func(r *CampaignRequest) { r.Audience = models.CampaignAudience{} }, "audience"},
+		{"negative rate", func(r *CampaignRequest) { r.RatePerMin = -1 }, "rate_per_minute"},
+		{"invalid local time", func(r *CampaignRequest) { r.SendAtLocalTime = "9am" }, "send_at_local_time"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := createTestCampaignRequest("https://hooks.example.com/a")
+			tt.modify(request)
+
+			_, err := service.CreateCampaign(request)
+			require.Error(t, err)
+			notifErr, ok := errors.AsNotificationError(err)
+			require.True(t, ok)
+			assert.Equal(t, tt.field, notifErr.Metadata["field"])
+		})
+	}
+}
+
+func TestCampaignService_RunCampaign(t *testing.T) {
+	server, texts := createTestChatServer(t)
+	defer server.Close()
+
+	service := createTestCampaignService(t)
+	service.Start(context.Background())
+	defer service.Stop()
+
+	request := createTestCampaignRequest(server.URL+"/alice", server.URL+"/bob", server.URL+"/alice")
+	request.Audience.Recipients[0].Data = map[string]string{"name": "Alice"}
+	request.Audience.Recipients[1].Data = map[string]string{"name": "Bob"}
+	request.Audience.Recipients = append(request.Audience.Recipients, models.CampaignRecipient{Recipient: server.URL + "/down"})
+
+	campaign, err := service.CreateCampaign(request)
+	require.NoError(t, err)
+	require.NoError(t, service.LaunchCampaign(campaign.ID))
+
+	finished := waitForCampaign(t, service, campaign.ID)
+	assert.Equal(t, models.CampaignStatusCompleted, finished.Status)
+	assert.Equal(t, 3, finished.Stats.Total) // duplicate recipient skipped
+	assert.Equal(t, 2, finished.Stats.Sent)
+	assert.Equal(t, 1, finished.Stats.Failed)
+	assert.Equal(t, 0, finished.Stats.Queued)
+	assert.NotNil(t, finished.StartedAt)
+	assert.NotNil(t, finished.CompletedAt)
+
+	assert.ElementsMatch(t, []string{"*Sale*\nHi Alice, the sale is on", "*Sale*\nHi Bob, the sale is on"}, texts())
+
+	// Relaunching a finished campaign is rejected
+	assert.Error(t, service.LaunchCampaign(campaign.ID))
+}
+
+func TestCampaignService_DeliveryAndOpenStats(t *testing.T) {
+	server, _ := createTestChatServer(t)
+	defer server.Close()
+
+	service := createTestCampaignService(t)
+	service.Start(context.Background())
+	defer service.Stop()
+
+	campaign, err := service.CreateCampaign(createTestCampaignRequest(server.URL + "/alice"))
+	require.NoError(t, err)
+	require.NoError(t, service.LaunchCampaign(campaign.ID))
+	waitForCampaign(t, service, campaign.ID)
+
+	service.mu.Lock()
+	var notificationID uuid.UUID
+	for id := range service.notifications {
+		notificationID = id
+	}
+	service.mu.Unlock()
+
+	require.NoError(t, service.RecordDelivered(notificationID))
+	require.NoError(t, service.RecordOpened(notificationID))
+
+	// Delivery and acknowledgement events from the event bus are counted too
+	require.NoError(t, service.HandleEvent(context.Background(), events.Event{
+		Type:           events.EventNotificationDelivered,
+		NotificationID: notificationID,
+		Metadata:       map[string]string{"campaign_id": campaign.ID.String()},
+	}))
+	require.NoError(t, service.HandleEvent(context.Background(), events.Event{
+		Type:           events.EventNotificationAcknowledged,
+		NotificationID: notificationID,
+		Metadata:       map[string]string{"campaign_id": campaign.ID.String()},
+	}))
+	require.NoError(t, service.HandleEvent(context.Background(), events.Event{Type: events.EventNotificationSent}))
+
+	stats, err := service.GetCampaignStats(campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Delivered)
+	assert.Equal(t, 2, stats.Opened)
+
+	assert.Error(t, service.RecordOpened(uuid.New()))
+}
+
+func TestCampaignService_ScheduleAndCancel(t *testing.T) {
+	service := createTestCampaignService(t)
+
+	request := createTestCampaignRequest("https://hooks.example.com/a")
+	scheduledAt := time.Now().Add(time.Hour)
+	request.ScheduledAt = &scheduledAt
+
+	campaign, err := service.CreateCampaign(request)
+	require.NoError(t, err)
+
+	// Launching requires a started service
+	assert.Error(t, service.LaunchCampaign(campaign.ID))
+
+	service.Start(context.Background())
+	defer service.Stop()
+
+	require.NoError(t, service.LaunchCampaign(campaign.ID))
+	scheduled, err := service.GetCampaign(campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.CampaignStatusScheduled, scheduled.Status)
+
+	require.NoError(t, service.CancelCampaign(campaign.ID))
+	cancelled, err := service.GetCampaign(campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.CampaignStatusCancelled, cancelled.Status)
+
+	assert.Error(t, service.CancelCampaign(campaign.ID))
+	assert.Error(t, service.CancelCampaign(uuid.New()))
+}
+
+func TestCampaignService_ScheduledOnceAcrossInstances(t *testing.T) {
+	server, texts := createTestChatServer(t)
+	defer server.Close()
+
+	locker := lock.NewMemoryLocker()
+	scheduledAt := time.Now().Add(50 * time.Millisecond)
+	var campaigns []*models.Campaign
+	var instances []*CampaignService
+	for _, owner := range []string{"instance-1", "instance-2"} {
+		service := createTestCampaignService(t)
+		service.SetLocker(locker, owner)
+		service.Start(context.Background())
+		defer service.Stop()
+
+		// The same campaign scheduled on both replicas, as a retried request would
+		request := createTestCampaignRequest(server.URL + "/alice")
+		request.ScheduledAt = &scheduledAt
+		campaign, err := service.CreateCampaign(request)
+		require.NoError(t, err)
+		require.NoError(t, service.LaunchCampaign(campaign.ID))
+		campaigns = append(campaigns, campaign)
+		instances = append(instances, service)
+	}
+
+	statuses := []models.CampaignStatus{
+		waitForCampaign(t, instances[0], campaigns[0].ID).Status,
+		waitForCampaign(t, instances[1], campaigns[1].ID).Status,
+	}
+	assert.ElementsMatch(t, []models.CampaignStatus{models.CampaignStatusCompleted, models.CampaignStatusCancelled}, statuses)
+	assert.Len(t, texts(), 1)
+}
+
+func TestCampaignService_Segments(t *testing.T) {
+	server, texts := createTestChatServer(t)
+	defer server.Close()
+
+	service := createTestCampaignService(t)
+	service.Start(context.Background())
+	defer service.Stop()
+
+	request := createTestCampaignRequest()
+	request.Audience = models.CampaignAudience{Segment: "plan = pro"}
+
+	// No resolver configured
+	campaign, err := service.CreateCampaign(request)
+	require.NoError(t, err)
+	require.NoError(t, service.LaunchCampaign(campaign.ID))
+	failed := waitForCampaign(t, service, campaign.ID)
+	assert.Equal(t, models.CampaignStatusFailed, failed.Status)
+	assert.NotEmpty(t, failed.ErrorMsg)
+
+	service.SetAudienceResolver(&staticResolver{recipients: []models.CampaignRecipient{
+		{Recipient: server.URL + "/pro", Data: map[string]string{"name": "Pro"}},
+	}})
+
+	campaign, err = service.CreateCampaign(request)
+	require.NoError(t, err)
+	require.NoError(t, service.LaunchCampaign(campaign.ID))
+	completed := waitForCampaign(t, service, campaign.ID)
+	assert.Equal(t, models.CampaignStatusCompleted, completed.Status)
+	assert.Equal(t, []string{"*Sale*\nHi Pro, the sale is on"}, texts())
+}
+
+func TestCampaignService_Throttle(t *testing.T) {
+	server, _ := createTestChatServer(t)
+	defer server.Close()
+
+	service := createTestCampaignService(t)
+	service.Start(context.Background())
+	defer service.Stop()
+
+	request := createTestCampaignRequest(server.URL+"/a", server.URL+"/b", server.URL+"/c")
+	request.RatePerMin = 1200 // one every 50ms
+
+	campaign, err := service.CreateCampaign(request)
+	require.NoError(t, err)
+
+	start := time.Now()
+	require.NoError(t, service.LaunchCampaign(campaign.ID))
+	finished := waitForCampaign(t, service, campaign.ID)
+
+	assert.Equal(t, 3, finished.Stats.Sent)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestCampaignService_SendAtLocalTime(t *testing.T) {
+	server, texts := createTestChatServer(t)
+	defer server.Close()
+
+	service := createTestCampaignService(t)
+	// 09:00 in New York, when it is still night in Tokyo
+	service.now = func() time.Time { return time.Date(2024, 6, 3, 13, 0, 0, 0, time.UTC) }
+	service.Start(context.Background())
+	defer service.Stop()
+
+	request := createTestCampaignRequest(server.URL+"/tokyo", server.URL+"/new-york")
+	request.Audience.Recipients[0].Data = map[string]string{"name": "Aiko", "timezone": "Asia/Tokyo"}
+	request.Audience.Recipients[1].Data = map[string]string{"name": "Nora", "timezone": "America/New_York"}
+	request.SendAtLocalTime = "09:00"
+
+	campaign, err := service.CreateCampaign(request)
+	require.NoError(t, err)
+	assert.Equal(t, "09:00", campaign.SendAtLocalTime)
+	require.NoError(t, service.LaunchCampaign(campaign.ID))
+
+	require.Eventually(t, func() bool {
+		stats, err := service.GetCampaignStats(campaign.ID)
+		return err == nil && stats.Sent == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"*Sale*\nHi Nora, the sale is on"}, texts())
+
+	// Tokyo's send waits for its morning
+	running, err := service.GetCampaign(campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.CampaignStatusRunning, running.Status)
+	require.NoError(t, service.CancelCampaign(campaign.ID))
+}
+
+func TestCampaignService_StaggerRecipients(t *testing.T) {
+	service := createTestCampaignService(t)
+	now := time.Date(2024, 6, 3, 6, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	registry := NewDeviceRegistry()
+	_, err := registry.Register(&Device{UserID: "user-1", Token: testIOSToken, Platform: "ios", Timezone: "Europe/Berlin"})
+	require.NoError(t, err)
+	service.SetDeviceRegistry(registry)
+
+	campaign := &models.Campaign{ID: uuid.New(), SendAtLocalTime: "09:00"}
+	recipients, sendAt := service.staggerRecipients(campaign, []models.CampaignRecipient{
+		{Recipient: "no-timezone"},
+		{Recipient: testIOSToken},
+		{Recipient: "los-angeles", Data: map[string]string{"timezone": "America/Los_Angeles"}},
+	})
+
+	require.Len(t, recipients, 3)
+	assert.Equal(t, testIOSToken, recipients[0].Recipient) // 09:00 CEST is 07:00 UTC
+	assert.Equal(t, time.Date(2024, 6, 3, 7, 0, 0, 0, time.UTC), sendAt[0].UTC())
+	assert.Equal(t, "no-timezone", recipients[1].Recipient) // UTC
+	assert.Equal(t, time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC), sendAt[1].UTC())
+	assert.Equal(t, "los-angeles", recipients[2].Recipient)
+	assert.Equal(t, time.Date(2024, 6, 3, 16, 0, 0, 0, time.UTC), sendAt[2].UTC())
+}
+
+func TestCampaignService_HoldsPausedChannel(t *testing.T) {
+	server, texts := createTestChatServer(t)
+	defer server.Close()
+
+	controller, err := pause.NewController(config.PauseConfig{Channels: []string{"chat"}, DrainRate: 100}, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+
+	service := createTestCampaignService(t)
+	service.SetPauseController(controller)
+	service.Start(context.Background())
+	defer service.Stop()
+
+	campaign, err := service.CreateCampaign(createTestCampaignRequest(server.URL+"/alice", server.URL+"/bob"))
+	require.NoError(t, err)
+	require.NoError(t, service.LaunchCampaign(campaign.ID))
+
+	// Held in the queue: nothing sent and nothing failed
+	time.Sleep(100 * time.Millisecond)
+	held, err := service.GetCampaign(campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.CampaignStatusRunning, held.Status)
+	assert.Equal(t, 2, held.Stats.Queued)
+	assert.Equal(t, 0, held.Stats.Failed)
+	assert.Empty(t, texts())
+
+	controller.ResumeChannel(models.NotificationTypeChat)
+
+	finished := waitForCampaign(t, service, campaign.ID)
+	assert.Equal(t, models.CampaignStatusCompleted, finished.Status)
+	assert.Equal(t, 2, finished.Stats.Sent)
+	assert.Len(t, texts(), 2)
+}
+
+func TestBuildCampaignRequest(t *testing.T) {
+	campaign := &models.Campaign{
+		ID:           uuid.New(),
+		Name:         "Launch",
+		Channel:      models.NotificationTypePush,
+		Priority:     models.PriorityHigh,
+		Template:     models.CampaignTemplate{Subject: "{{product}} is here", Body: "Hi {{name}}"},
+		TemplateData: map[string]string{"product": "Widget", "name": "there"},
+	}
+
+	request := buildCampaignRequest(campaign, compileCampaignTemplate(campaign.Template), models.CampaignRecipient{
+		Recipient: testIOSToken,
+		Data:      map[string]string{"name": "Sam", "platform": "ios"},
+	})
+
+	assert.Equal(t, "Widget is here", request.Subject)
+	assert.Equal(t, "Hi Sam", request.Body)
+	assert.Equal(t, models.PriorityHigh, request.Priority)
+	require.NotNil(t, request.PushData)
+	assert.Equal(t, "ios", request.PushData.Platform)
+	assert.Equal(t, campaign.ID.String(), request.Metadata["campaign_id"])
+}
+
+// Helper functions
+
+type staticResolver struct {
+	recipients []models.CampaignRecipient
+}
+
+func (r *staticResolver) ResolveSegment(ctx context.Context, segment string) ([]models.CampaignRecipient, error) {
+	return r.recipients, nil
+}
+
+func createTestCampaignService(t *testing.T) *CampaignService {
+	return NewCampaignService(createTestDispatcher(t), config.QueueConfig{MaxSize: 2, Workers: 2}, utils.NewSimpleLogger("info"))
+}
+
+func createTestCampaignRequest(recipients ...string) *CampaignRequest {
+	audience := models.CampaignAudience{}
+	for _, recipient := range recipients {
+		audience.Recipients = append(audience.Recipients, models.CampaignRecipient{Recipient: recipient})
+	}
+
+	return &CampaignRequest{
+		Name:         "Spring sale",
+		Channel:      models.NotificationTypeChat,
+		Audience:     audience,
+		Template:     models.CampaignTemplate{Subject: "Sale", Body: "Hi {{name}}, the sale is on"},
+		TemplateData: map[string]string{"name": "friend"},
+	}
+}
+
+// createTestChatServer accepts Slack webhook posts, except on /down, and
+// returns the texts it received
+func createTestChatServer(t *testing.T) (*httptest.Server, func() []string) {
+	var mu sync.Mutex
+	var texts []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+
+		var payload map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+
+		mu.Lock()
+		texts = append(texts, payload["text"].(string))
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), texts...)
+	}
+}
+
+func waitForCampaign(t *testing.T, service *CampaignService, id uuid.UUID) *models.Campaign {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		campaign, err := service.GetCampaign(id)
+		require.NoError(t, err)
+		if campaign.IsFinished() {
+			return campaign
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("campaign %s did not finish", id)
+	return nil
+}
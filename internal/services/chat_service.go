@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/internal/validation"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// ChatService provides chat webhook notification functionality (Slack, Microsoft Teams)
+type ChatService struct {
+	provider interfaces.ChatProvider
+	config   config.ChatProviderConfig
+	logger   interfaces.Logger
+}
+
+// NewChatService creates a new chat service
+func NewChatService(cfg config.ChatProviderConfig, logger interfaces.Logger) (*ChatService, error) {
+	provider, err := providers.NewChatProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	service := &ChatService{
+		provider: provider,
+		config:   cfg,
+		logger:   logger,
+	}
+
+	return service, nil
+}
+
+// SendChat posts a chat message to a webhook
+func (s *ChatService) SendChat(ctx context.Context, request *ChatRequest) (*models.NotificationResponse, error) {
+	// Validate request first
+	if err := s.validateChatRequest(request); err != nil {
+		s.logger.Errorf("Chat message validation failed: %v", err)
+		return nil, err
+	}
+
+	s.logger.Infof("Sending chat message via %s", s.config.Provider)
+
+	// Create chat notification
+	chatNotification := s.createChatNotification(request)
+
+	// Apply template if specified
+	if request.TemplateID != "" {
+		if err := s.applyTemplate(chatNotification, request.TemplateID, request.TemplateData); err != nil {
+			s.logger.Errorf("Template application failed: %v", err)
+			return nil, err
+		}
+	}
+
+	// Send chat message
+	response, err := s.provider.SendChat(ctx, chatNotification)
+	if err != nil {
+		s.logger.Errorf("Chat message sending failed: %v", err)
+		return nil, err
+	}
+
+	s.logger.Infof("Chat message sent successfully with ID: %s", response.ID)
+	return response, nil
+}
+
+// RenderTemplate renders a chat template with data
+func (s *ChatService) RenderTemplate(templateID string, data map[string]string) (*providers.ChatTemplate, error) {
+	webhookProvider, ok := s.provider.(*providers.ChatWebhookProvider)
+	if !ok {
+		return nil, errors.NewNotificationError(
+			errors.ErrorCodeProviderNotFound,
+			"template rendering not supported by this provider",
+		)
+	}
+
+	return webhookProvider.RenderTemplate(templateID, data)
+}
+
+// ValidateWebhookURL validates a webhook URL
+func (s *ChatService) ValidateWebhookURL(webhookURL string) error {
+	return s.provider.ValidateWebhookURL(webhookURL)
+}
+
+// GetProviderStatus returns the current provider status
+func (s *ChatService) GetProviderStatus(ctx context.Context) *ProviderStatus {
+	status := &ProviderStatus{
+		Name:    s.provider.GetConfig().Name,
+		Type:    string(s.provider.GetType()),
+		Healthy: true,
+	}
+
+	if err := s.provider.IsHealthy(ctx); err != nil {
+		status.Healthy = false
+		status.Error = err.Error()
+	}
+
+	return status
+}
+
+// validateChatRequest validates a chat request
+func (s *ChatService) validateChatRequest(request *ChatRequest) error {
+	if request == nil {
+		return errors.NewValidationError("request", "chat request is required")
+	}
+
+	if err := validation.Struct(request); err != nil {
+		return err
+	}
+
+	webhookURL := request.WebhookURL
+	if webhookURL == "" {
+		webhookURL = s.config.WebhookURL
+	}
+
+	if err := s.provider.ValidateWebhookURL(webhookURL); err != nil {
+		return err
+	}
+
+	if request.Text == "" && len(request.Blocks) == 0 && len(request.Card) == 0 && request.TemplateID == "" {
+		return errors.NewValidationError("text", "chat message must have text, blocks, a card or a template")
+	}
+
+	return nil
+}
+
+// createChatNotification creates a chat notification from a request
+func (s *ChatService) createChatNotification(request *ChatRequest) *models.ChatNotification {
+	now := time.Now()
+
+	webhookURL := request.WebhookURL
+	if webhookURL == "" {
+		webhookURL = s.config.WebhookURL
+	}
+
+	return &models.ChatNotification{
+		Notification: models.Notification{
+			ID:         uuid.New(),
+			Type:       models.NotificationTypeChat,
+			Status:     models.StatusPending,
+			Priority:   request.Priority,
+			Recipient:  webhookURL,
+			Subject:    request.Title,
+			Body:       request.Text,
+			Metadata:   request.Metadata,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+			RetryCount: 0,
+			MaxRetries: 3,
+		},
+		Platform:   s.config.Provider,
+		WebhookURL: webhookURL,
+		Title:      request.Title,
+		Text:       request.Text,
+		Blocks:     request.Blocks,
+		Card:       request.Card,
+		Channel:    request.Channel,
+		Username:   request.Username,
+		IconEmoji:  request.IconEmoji,
+	}
+}
+
+// applyTemplate applies a template to a chat notification
+func (s *ChatService) applyTemplate(chat *models.ChatNotification, templateID string, data map[string]string) error {
+	webhookProvider, ok := s.provider.(*providers.ChatWebhookProvider)
+	if !ok {
+		return errors.NewNotificationError(
+			errors.ErrorCodeProviderNotFound,
+			"template rendering not supported by this provider",
+		)
+	}
+
+	template, err := webhookProvider.RenderTemplate(templateID, data)
+	if err != nil {
+		return err
+	}
+
+	// Apply template content
+	chat.Title = template.Title
+	chat.Text = template.Text
+	chat.Subject = template.Title
+	chat.Body = template.Text
+
+	return webhookProvider.ApplyTemplatePayload(chat, template.Payload)
+}
+
+// ChatRequest represents a request to post a chat message
+type ChatRequest struct {
+	WebhookURL   string                   `json:"webhook_url,omitempty"`
+	Title        string                   `json:"title,omitempty"`
+	Text         string                   `json:"text,omitempty"`
+	Blocks       []map[string]interface{} `json:"blocks,omitempty"`
+	Card         map[string]interface{}   `json:"card,omitempty"`
+	Channel      string                   `json:"channel,omitempty"`
+	Username     string                   `json:"username,omitempty"`
+	IconEmoji    string                   `json:"icon_emoji,omitempty"`
+	TemplateID   string                   `json:"template_id,omitempty"`
+	TemplateData map[string]string        `json:"template_data,omitempty"`
+	Priority     models.Priority          `json:"priority"`
+	Metadata     map[string]string        `json:"metadata,omitempty"`
+}
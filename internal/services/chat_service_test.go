@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestNewChatService(t *testing.T) {
+	for _, provider := range []string{"slack", "teams"} {
+		service, err := NewChatService(config.ChatProviderConfig{Provider: provider, Enabled: true}, utils.NewSimpleLogger("info"))
+		require.NoError(t, err)
+		assert.NotNil(t, service)
+	}
+}
+
+func TestNewChatService_UnsupportedProvider(t *testing.T) {
+	service, err := NewChatService(config.ChatProviderConfig{Provider: "discord"}, utils.NewSimpleLogger("info"))
+	assert.Error(t, err)
+	assert.Nil(t, service)
+
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeProviderNotFound, notifErr.Code)
+}
+
+func TestChatService_SendChat_DefaultWebhook(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	service := createTestChatService("slack", server.URL)
+
+	response, err := service.SendChat(context.Background(), &ChatRequest{
+		Text:     "Build green",
+		Priority: models.PriorityNormal,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusSent, response.Status)
+	assert.Equal(t, "Build green", received["text"])
+}
+
+func TestChatService_SendChat_WithTemplate(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	service := createTestChatService("slack", "")
+
+	_, err := service.SendChat(context.Background(), &ChatRequest{
+		WebhookURL: server.URL,
+		TemplateID: "incident",
+		TemplateData: map[string]string{
+			"incident_id": "INC-9",
+			"severity":    "sev2",
+			"summary":     "Queue backlog",
+		},
+	})
+
+	require.NoError(t, err)
+	assert.Contains(t, received["text"], "Incident INC-9 (sev2): Queue backlog")
+
+	blocks := received["blocks"].([]interface{})
+	require.Len(t, blocks, 2)
+	assert.Equal(t, "header", blocks[0].(map[string]interface{})["type"])
+}
+
+func TestChatService_SendChat_ValidationErrors(t *testing.T) {
+	service := createTestChatService("teams", "")
+
+	tests := []struct {
+		name    string
+		request *ChatRequest
+	}{
+		{"nil request", nil},
+		{"missing webhook", &ChatRequest{Text: "hello"}},
+		{"missing content", &ChatRequest{WebhookURL: "https://example.webhook.office.com/x"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.SendChat(context.Background(), tt.request)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestChatService_GetProviderStatus(t *testing.T) {
+	service := createTestChatService("teams", "https://example.webhook.office.com/x")
+
+	status := service.GetProviderStatus(context.Background())
+	assert.True(t, status.Healthy)
+	assert.Equal(t, "chat", status.Type)
+}
+
+// Helper functions
+
+func createTestChatService(provider, webhookURL string) *ChatService {
+	cfg := config.ChatProviderConfig{
+		Provider:             provider,
+		Enabled:              true,
+		WebhookURL:           webhookURL,
+		AllowedHosts:         []string{"127.0.0.1", "*.webhook.office.com"},
+		AllowPrivateWebhooks: true,
+	}
+
+	service, err := NewChatService(cfg, utils.NewSimpleLogger("info"))
+	if err != nil {
+		panic(err)
+	}
+
+	return service
+}
@@ -0,0 +1,482 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nareshkumar-microsoft/notificationService/internal/audit"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/preferences"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// dataSubjectPageSize is the number of notifications read per page when collecting a subject's data
+const dataSubjectPageSize = 500
+
+// erasedValue replaces personal data in notifications that are erased
+const erasedValue = "[erased]"
+
+// DataSubjectJobType identifies what a data subject job does
+type DataSubjectJobType string
+
+const (
+	DataSubjectJobExport   DataSubjectJobType = "export"
+	DataSubjectJobDeletion DataSubjectJobType = "deletion"
+)
+
+// DataSubjectJobStatus represents the state of a data subject job
+type DataSubjectJobStatus string
+
+const (
+	DataSubjectJobPending   DataSubjectJobStatus = "pending"
+	DataSubjectJobRunning   DataSubjectJobStatus = "running"
+	DataSubjectJobCompleted DataSubjectJobStatus = "completed"
+	DataSubjectJobFailed    DataSubjectJobStatus = "failed"
+)
+
+// DataSubjectService exports and erases everything stored about a recipient
+// to serve data-subject access and right-to-erasure requests. Requests run as
+// asynchronous jobs that finish with a completion report.
+//
+// A recipient is matched by notification recipient, device token and device
+// user ID; the tokens of a user's devices are then matched as recipients too.
+// Audit entries hold no personal data (only payload hashes), so erasure keeps
+// them and records the erasure itself in the audit trail.
+type DataSubjectService struct {
+	notifications interfaces.NotificationRepository
+	devices       *DeviceRegistry
+	lists         interfaces.RecipientListRepository
+	preferences   *preferences.Store
+	audit         *audit.Recorder
+	logger        interfaces.Logger
+
+	mu   sync.RWMutex
+	jobs map[string]*dataSubjectJob
+}
+
+// dataSubjectJob is a job and the channel closed when it finishes
+type dataSubjectJob struct {
+	job  *DataSubjectJob
+	done chan struct{}
+}
+
+// NewDataSubjectService creates a new data subject service
+func NewDataSubjectService(notifications interfaces.NotificationRepository, logger interfaces.Logger) *DataSubjectService {
+	return &DataSubjectService{
+		notifications: notifications,
+		logger:        logger,
+		jobs:          make(map[string]*dataSubjectJob),
+	}
+}
+
+// SetDeviceRegistry includes push device registrations in exports and erasure
+func (s *DataSubjectService) SetDeviceRegistry(devices *DeviceRegistry) {
+	s.devices = devices
+}
+
+// SetRecipientLists includes recipient list memberships in exports and erasure
+func (s *DataSubjectService) SetRecipientLists(lists interfaces.RecipientListRepository) {
+	s.lists = lists
+}
+
+// SetPreferenceStore includes opt-outs and quiet hours in exports and erasure
+func (s *DataSubjectService) SetPreferenceStore(store *preferences.Store) {
+	s.preferences = store
+}
+
+// SetAuditRecorder includes audit entries in exports and records completed requests
+func (s *DataSubjectService) SetAuditRecorder(recorder *audit.Recorder) {
+	s.audit = recorder
+}
+
+// ExportRecipientData starts a job that collects all data stored about a recipient
+func (s *DataSubjectService) ExportRecipientData(ctx context.Context, recipient string) (*DataSubjectJob, error) {
+	return s.startJob(ctx, DataSubjectJobExport, recipient)
+}
+
+// DeleteRecipientData starts a job that erases all data stored about a recipient
+func (s *DataSubjectService) DeleteRecipientData(ctx context.Context, recipient string) (*DataSubjectJob, error) {
+	return s.startJob(ctx, DataSubjectJobDeletion, recipient)
+}
+
+// GetJob returns a data subject job by ID
+func (s *DataSubjectService) GetJob(jobID string) (*DataSubjectJob, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tracked, exists := s.jobs[jobID]
+	if !exists {
+		return nil, errors.NewNotificationError(errors.ErrorCodeNotFound, fmt.Sprintf("data subject job not found: %s", jobID))
+	}
+	return cloneDataSubjectJob(tracked.job), nil
+}
+
+// WaitForJob blocks until a job finishes or the context is done
+func (s *DataSubjectService) WaitForJob(ctx context.Context, jobID string) (*DataSubjectJob, error) {
+	s.mu.RLock()
+	tracked, exists := s.jobs[jobID]
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, errors.NewNotificationError(errors.ErrorCodeNotFound, fmt.Sprintf("data subject job not found: %s", jobID))
+	}
+
+	select {
+	case <-tracked.done:
+		return s.GetJob(jobID)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// startJob registers a job and runs it in the background. The job keeps the
+// caller's context values, such as the audit actor, but not its cancellation.
+func (s *DataSubjectService) startJob(ctx context.Context, jobType DataSubjectJobType, recipient string) (*DataSubjectJob, error) {
+	if recipient == "" {
+		return nil, errors.NewValidationError("recipient", "recipient is required")
+	}
+
+	job := &DataSubjectJob{
+		ID:        uuid.New(),
+		Type:      jobType,
+		Status:    DataSubjectJobPending,
+		CreatedAt: time.Now(),
+	}
+	tracked := &dataSubjectJob{job: job, done: make(chan struct{})}
+
+	s.mu.Lock()
+	s.jobs[job.ID.String()] = tracked
+	s.mu.Unlock()
+
+	s.logger.Infof("Started data subject %s job %s", jobType, job.ID)
+
+	go s.runJob(context.WithoutCancel(ctx), tracked, recipient)
+
+	return cloneDataSubjectJob(job), nil
+}
+
+// runJob runs a job and records its outcome
+func (s *DataSubjectService) runJob(ctx context.Context, tracked *dataSubjectJob, recipient string) {
+	defer close(tracked.done)
+
+	s.updateJob(tracked, func(job *DataSubjectJob) {
+		job.Status = DataSubjectJobRunning
+	})
+
+	data, err := s.collect(ctx, recipient)
+	if err == nil && tracked.job.Type == DataSubjectJobDeletion {
+		err = s.erase(ctx, data)
+	}
+
+	if err == nil {
+		err = s.recordCompletion(ctx, tracked.job, recipient, data)
+	}
+
+	s.updateJob(tracked, func(job *DataSubjectJob) {
+		now := time.Now()
+		job.CompletedAt = &now
+
+		if err != nil {
+			job.Status = DataSubjectJobFailed
+			job.Error = err.Error()
+			return
+		}
+
+		job.Status = DataSubjectJobCompleted
+		job.Report = data.report()
+		if job.Type == DataSubjectJobExport {
+			job.Export = data
+		}
+	})
+
+	if err != nil {
+		s.logger.Errorf("Data subject %s job %s failed: %v", tracked.job.Type, tracked.job.ID, err)
+		return
+	}
+	s.logger.Infof("Completed data subject %s job %s", tracked.job.Type, tracked.job.ID)
+}
+
+// updateJob applies a change to a job under the service lock
+func (s *DataSubjectService) updateJob(tracked *dataSubjectJob, update func(job *DataSubjectJob)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	update(tracked.job)
+}
+
+// collect gathers everything stored about a recipient
+func (s *DataSubjectService) collect(ctx context.Context, recipient string) (*DataSubjectExport, error) {
+	data := &DataSubjectExport{
+		GeneratedAt:     time.Now(),
+		Notifications:   make([]*models.Notification, 0),
+		Devices:         make([]Device, 0),
+		ListMemberships: make([]ListMembership, 0),
+		OptOuts:         make([]preferences.OptOut, 0),
+		QuietHours:      make(map[string]preferences.QuietHours),
+		AuditEntries:    make([]*models.AuditEntry, 0),
+	}
+
+	identifiers := []string{recipient}
+	if s.devices != nil {
+		data.Devices = s.subjectDevices(recipient)
+		for _, device := range data.Devices {
+			if device.Token != recipient {
+				identifiers = append(identifiers, device.Token)
+			}
+		}
+	}
+
+	for _, identifier := range identifiers {
+		notifications, err := s.recipientNotifications(ctx, identifier)
+		if err != nil {
+			return nil, err
+		}
+		data.Notifications = append(data.Notifications, notifications...)
+	}
+
+	if s.lists != nil {
+		memberships, err := s.listMemberships(ctx, identifiers)
+		if err != nil {
+			return nil, err
+		}
+		data.ListMemberships = memberships
+	}
+
+	if s.preferences != nil {
+		for _, identifier := range identifiers {
+			data.OptOuts = append(data.OptOuts, s.preferences.OptOuts(identifier)...)
+			if hours, exists := s.preferences.QuietHours(identifier); exists {
+				data.QuietHours[identifier] = hours
+			}
+		}
+	}
+
+	if s.audit != nil {
+		for _, notification := range data.Notifications {
+			entries, err := s.audit.ForNotification(ctx, notification.ID.String())
+			if err != nil {
+				return nil, err
+			}
+			data.AuditEntries = append(data.AuditEntries, entries...)
+		}
+	}
+
+	return data, nil
+}
+
+// erase removes the collected data. Notifications are scrubbed before they
+// are deleted, so repositories that soft delete keep no personal data.
+func (s *DataSubjectService) erase(ctx context.Context, data *DataSubjectExport) error {
+	for _, notification := range data.Notifications {
+		scrubbed := *notification
+		scrubbed.Recipient = erasedValue
+		scrubbed.Subject = ""
+		scrubbed.Body = ""
+		scrubbed.HTMLBody = ""
+		scrubbed.SubjectTemplate = ""
+		scrubbed.BodyTemplate = ""
+		scrubbed.TemplateData = nil
+		scrubbed.Metadata = nil
+		scrubbed.ErrorMsg = ""
+
+		if err := s.notifications.Update(ctx, &scrubbed); err != nil {
+			return err
+		}
+		if err := s.notifications.Delete(ctx, notification.ID.String()); err != nil {
+			return err
+		}
+	}
+
+	for _, device := range data.Devices {
+		if err := s.devices.Unregister(device.Token); err != nil {
+			return err
+		}
+	}
+
+	for _, membership := range data.ListMemberships {
+		if _, err := s.lists.RemoveMembers(ctx, membership.ListID, []string{membership.Recipient}); err != nil {
+			return err
+		}
+	}
+
+	for _, optOut := range data.OptOuts {
+		s.preferences.OptIn(optOut.Recipient, optOut.Channel, optOut.Category)
+	}
+	for recipient := range data.QuietHours {
+		s.preferences.ClearQuietHours(recipient)
+	}
+
+	return nil
+}
+
+// recordCompletion records a finished request in the audit trail. The
+// recipient is stored as a hash so the request can be proven without keeping it.
+func (s *DataSubjectService) recordCompletion(ctx context.Context, job *DataSubjectJob, recipient string, data *DataSubjectExport) error {
+	if s.audit == nil {
+		return nil
+	}
+
+	outcome := models.AuditOutcomeExported
+	if job.Type == DataSubjectJobDeletion {
+		outcome = models.AuditOutcomeErased
+	}
+
+	report := data.report()
+	return s.audit.Record(ctx, &models.AuditEntry{
+		Action:      fmt.Sprintf("data_subject.%s", job.Type),
+		Outcome:     outcome,
+		PayloadHash: audit.HashPayload(recipient),
+		Metadata: map[string]string{
+			"job_id":           job.ID.String(),
+			"notifications":    fmt.Sprint(report.Notifications),
+			"devices":          fmt.Sprint(report.Devices),
+			"list_memberships": fmt.Sprint(report.ListMemberships),
+			"preferences":      fmt.Sprint(report.Preferences),
+		},
+	})
+}
+
+// subjectDevices returns copies of the devices registered with the recipient as token or user ID
+func (s *DataSubjectService) subjectDevices(recipient string) []Device {
+	devices := make([]Device, 0)
+	seen := make(map[string]bool)
+
+	if device, err := s.devices.GetDevice(recipient); err == nil {
+		devices = append(devices, *device)
+		seen[device.Token] = true
+	}
+
+	for _, device := range s.devices.GetUserDevices(recipient) {
+		if !seen[device.Token] {
+			devices = append(devices, *device)
+			seen[device.Token] = true
+		}
+	}
+
+	return devices
+}
+
+// recipientNotifications returns every stored notification sent to a recipient
+func (s *DataSubjectService) recipientNotifications(ctx context.Context, recipient string) ([]*models.Notification, error) {
+	filters := interfaces.NotificationFilters{
+		Recipient: recipient,
+		Limit:     dataSubjectPageSize,
+		SortBy:    "created_at",
+		SortOrder: "asc",
+	}
+
+	notifications := make([]*models.Notification, 0)
+	for {
+		page, err := s.notifications.List(ctx, filters)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, page...)
+
+		if len(page) < filters.Limit {
+			return notifications, nil
+		}
+		filters.Offset += len(page)
+	}
+}
+
+// listMemberships returns the recipient list memberships of any of the identifiers
+func (s *DataSubjectService) listMemberships(ctx context.Context, identifiers []string) ([]ListMembership, error) {
+	match := make(map[string]bool, len(identifiers))
+	for _, identifier := range identifiers {
+		match[identifier] = true
+	}
+
+	lists, err := s.lists.ListLists(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	memberships := make([]ListMembership, 0)
+	for _, list := range lists {
+		members, err := s.lists.GetMembers(ctx, list.ID.String())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, member := range members {
+			if match[member.Recipient] {
+				memberships = append(memberships, ListMembership{
+					ListID:     list.ID.String(),
+					ListName:   list.Name,
+					Recipient:  member.Recipient,
+					Attributes: member.Attributes,
+					AddedAt:    member.AddedAt,
+				})
+			}
+		}
+	}
+
+	return memberships, nil
+}
+
+// cloneDataSubjectJob copies a job so callers cannot mutate service state
+func cloneDataSubjectJob(job *DataSubjectJob) *DataSubjectJob {
+	clone := *job
+	if job.Report != nil {
+		report := *job.Report
+		clone.Report = &report
+	}
+	return &clone
+}
+
+// DataSubjectJob represents an asynchronous export or erasure request
+type DataSubjectJob struct {
+	ID          uuid.UUID            `json:"id"`
+	Type        DataSubjectJobType   `json:"type"`
+	Status      DataSubjectJobStatus `json:"status"`
+	CreatedAt   time.Time            `json:"created_at"`
+	CompletedAt *time.Time           `json:"completed_at,omitempty"`
+	Error       string               `json:"error,omitempty"`
+	Report      *DataSubjectReport   `json:"report,omitempty"`
+	Export      *DataSubjectExport   `json:"export,omitempty"` // set on completed export jobs
+}
+
+// DataSubjectReport summarises the records a job exported or erased
+type DataSubjectReport struct {
+	Notifications   int `json:"notifications"`
+	Devices         int `json:"devices"`
+	ListMemberships int `json:"list_memberships"`
+	Preferences     int `json:"preferences"`   // opt-outs and quiet hours
+	AuditEntries    int `json:"audit_entries"` // kept on erasure, as they hold no personal data
+}
+
+// DataSubjectExport holds everything stored about a recipient
+type DataSubjectExport struct {
+	GeneratedAt     time.Time                         `json:"generated_at"`
+	Notifications   []*models.Notification            `json:"notifications"`
+	Devices         []Device                          `json:"devices"`
+	ListMemberships []ListMembership                  `json:"list_memberships"`
+	OptOuts         []preferences.OptOut              `json:"opt_outs"`
+	QuietHours      map[string]preferences.QuietHours `json:"quiet_hours"` // by recipient
+	AuditEntries    []*models.AuditEntry              `json:"audit_entries"`
+}
+
+// report counts the records in an export
+func (e *DataSubjectExport) report() *DataSubjectReport {
+	return &DataSubjectReport{
+		Notifications:   len(e.Notifications),
+		Devices:         len(e.Devices),
+		ListMemberships: len(e.ListMemberships),
+		Preferences:     len(e.OptOuts) + len(e.QuietHours),
+		AuditEntries:    len(e.AuditEntries),
+	}
+}
+
+// ListMembership represents a recipient's membership of a recipient list
+type ListMembership struct {
+	ListID     string            `json:"list_id"`
+	ListName   string            `json:"list_name"`
+	Recipient  string            `json:"recipient"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	AddedAt    time.Time         `json:"added_at"`
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/audit"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/preferences"
+	"github.com/nareshkumar-microsoft/notificationService/internal/repository"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+func TestDataSubjectService_ExportRecipientData(t *testing.T) {
+	service := createTestDataSubjectService(t)
+	ctx := context.Background()
+
+	job, err := service.ExportRecipientData(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, DataSubjectJobExport, job.Type)
+
+	job = waitForDataSubjectJob(t, service, job.ID.String())
+	require.Equal(t, DataSubjectJobCompleted, job.Status, job.Error)
+	require.NotNil(t, job.CompletedAt)
+
+	// The user's device token is matched as a recipient too
+	assert.Equal(t, &DataSubjectReport{Notifications: 1, Devices: 1, ListMemberships: 0, AuditEntries: 1}, job.Report)
+	require.NotNil(t, job.Export)
+	assert.Equal(t, testIOSToken, job.Export.Devices[0].Token)
+	assert.Equal(t, "Your order shipped", job.Export.Notifications[0].Body)
+
+	job, err = service.ExportRecipientData(ctx, "asha@example.com")
+	require.NoError(t, err)
+	job = waitForDataSubjectJob(t, service, job.ID.String())
+	assert.Equal(t, &DataSubjectReport{Notifications: 2, Devices: 0, ListMemberships: 2, AuditEntries: 2}, job.Report)
+	assert.Equal(t, "beta", job.Export.ListMemberships[0].ListName)
+	assert.Equal(t, "free", job.Export.ListMemberships[0].Attributes["plan"])
+}
+
+func TestDataSubjectService_DeleteRecipientData(t *testing.T) {
+	service := createTestDataSubjectService(t)
+	ctx := audit.WithActor(context.Background(), "privacy-team")
+
+	job, err := service.DeleteRecipientData(ctx, "asha@example.com")
+	require.NoError(t, err)
+
+	job = waitForDataSubjectJob(t, service, job.ID.String())
+	require.Equal(t, DataSubjectJobCompleted, job.Status, job.Error)
+	assert.Equal(t, &DataSubjectReport{Notifications: 2, Devices: 0, ListMemberships: 2, AuditEntries: 2}, job.Report)
+	assert.Nil(t, job.Export)
+
+	// Notifications, list memberships and nothing else are gone
+	remaining, err := service.notifications.List(ctx, interfaces.NotificationFilters{Recipient: "asha@example.com"})
+	require.NoError(t, err)
+	assert.Empty(t, remaining)
+
+	all, err := service.notifications.List(ctx, interfaces.NotificationFilters{})
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	lists, err := service.lists.ListLists(ctx)
+	require.NoError(t, err)
+	for _, list := range lists {
+		members, err := service.lists.GetMembers(ctx, list.ID.String())
+		require.NoError(t, err)
+		for _, member := range members {
+			assert.NotEqual(t, "asha@example.com", member.Recipient)
+		}
+	}
+
+	// The erasure is audited without storing the recipient
+	entries, err := service.audit.Query(ctx, interfaces.AuditFilters{})
+	require.NoError(t, err)
+	last := entries[len(entries)-1]
+	assert.Equal(t, "data_subject.deletion", last.Action)
+	assert.Equal(t, models.AuditOutcomeErased, last.Outcome)
+	assert.Equal(t, "privacy-team", last.Actor)
+	assert.Equal(t, audit.HashPayload("asha@example.com"), last.PayloadHash)
+	assert.Equal(t, "2", last.Metadata["notifications"])
+
+	// A second request finds nothing left
+	job, err = service.DeleteRecipientData(ctx, "asha@example.com")
+	require.NoError(t, err)
+	job = waitForDataSubjectJob(t, service, job.ID.String())
+	assert.Equal(t, &DataSubjectReport{}, job.Report)
+}
+
+func TestDataSubjectService_DeleteScrubsNotifications(t *testing.T) {
+	service := createTestDataSubjectService(t)
+	spy := &updateSpyRepository{MemoryRepository: service.notifications.(*repository.MemoryRepository)}
+	service.notifications = spy
+	ctx := context.Background()
+
+	job, err := service.DeleteRecipientData(ctx, "user-1")
+	require.NoError(t, err)
+	job = waitForDataSubjectJob(t, service, job.ID.String())
+	require.Equal(t, DataSubjectJobCompleted, job.Status, job.Error)
+
+	_, err = service.devices.GetDevice(testIOSToken)
+	assert.Error(t, err)
+
+	// Personal data is overwritten before the delete, in case the store soft deletes
+	require.Len(t, spy.updates, 1)
+	assert.Equal(t, erasedValue, spy.updates[0].Recipient)
+	assert.Empty(t, spy.updates[0].Body)
+	assert.Equal(t, 3, spy.Count())
+}
+
+func TestDataSubjectService_Preferences(t *testing.T) {
+	service := createTestDataSubjectService(t)
+	store := preferences.NewStore()
+	service.SetPreferenceStore(store)
+	ctx := context.Background()
+
+	require.NoError(t, store.OptOut("asha@example.com", models.NotificationTypeEmail, models.CategoryMarketing))
+	require.NoError(t, store.SetQuietHours("asha@example.com", preferences.QuietHours{Start: "22:00", End: "07:00"}))
+	require.NoError(t, store.OptOut("sam@example.com", "", ""))
+
+	job, err := service.ExportRecipientData(ctx, "asha@example.com")
+	require.NoError(t, err)
+	job = waitForDataSubjectJob(t, service, job.ID.String())
+	assert.Equal(t, 2, job.Report.Preferences)
+	require.Len(t, job.Export.OptOuts, 1)
+	assert.Equal(t, models.CategoryMarketing, job.Export.OptOuts[0].Category)
+	assert.Equal(t, "22:00", job.Export.QuietHours["asha@example.com"].Start)
+
+	job, err = service.DeleteRecipientData(ctx, "asha@example.com")
+	require.NoError(t, err)
+	job = waitForDataSubjectJob(t, service, job.ID.String())
+	require.Equal(t, DataSubjectJobCompleted, job.Status, job.Error)
+	assert.Equal(t, 2, job.Report.Preferences)
+
+	assert.Empty(t, store.OptOuts("asha@example.com"))
+	_, exists := store.QuietHours("asha@example.com")
+	assert.False(t, exists)
+	assert.Len(t, store.OptOuts("sam@example.com"), 1)
+}
+
+func TestDataSubjectService_Jobs(t *testing.T) {
+	service := createTestDataSubjectService(t)
+
+	_, err := service.ExportRecipientData(context.Background(), "")
+	assert.Error(t, err)
+
+	_, err = service.GetJob("missing")
+	assert.Error(t, err)
+
+	_, err = service.WaitForJob(context.Background(), "missing")
+	assert.Error(t, err)
+
+	// Cancelling the request context does not abort an erasure in progress
+	ctx, cancel := context.WithCancel(context.Background())
+	job, err := service.DeleteRecipientData(ctx, "sam@example.com")
+	require.NoError(t, err)
+	cancel()
+
+	job = waitForDataSubjectJob(t, service, job.ID.String())
+	assert.Equal(t, DataSubjectJobCompleted, job.Status)
+}
+
+// Helper functions
+
+func createTestDataSubjectService(t *testing.T) *DataSubjectService {
+	ctx := context.Background()
+	logger := utils.NewSimpleLogger("info")
+
+	notifications := repository.NewMemoryRepository()
+	recorder := audit.NewRecorder(repository.NewMemoryAuditRepository(), logger)
+	devices := NewDeviceRegistry()
+	audience := createTestAudienceService()
+	seedTestAudience(t, audience)
+
+	_, err := devices.Register(&Device{UserID: "user-1", Token: testIOSToken, Platform: "ios"})
+	require.NoError(t, err)
+
+	requests := []*models.NotificationRequest{
+		{Type: models.NotificationTypeEmail, Priority: models.PriorityNormal, Recipient: "asha@example.com", Body: "Welcome"},
+		{Type: models.NotificationTypeEmail, Priority: models.PriorityNormal, Recipient: "asha@example.com", Body: "Your invoice"},
+		{Type: models.NotificationTypeEmail, Priority: models.PriorityNormal, Recipient: "sam@example.com", Body: "Welcome"},
+		{Type: models.NotificationTypePush, Priority: models.PriorityHigh, Recipient: testIOSToken, Body: "Your order shipped"},
+	}
+	for _, request := range requests {
+		notification := utils.CreateNotificationFromRequest(request)
+		require.NoError(t, notifications.Save(ctx, notification))
+		require.NoError(t, recorder.Record(ctx, &models.AuditEntry{
+			Action:         "notification.queued",
+			Outcome:        models.AuditOutcomeAccepted,
+			NotificationID: notification.ID.String(),
+		}))
+	}
+
+	service := NewDataSubjectService(notifications, logger)
+	service.SetDeviceRegistry(devices)
+	service.SetRecipientLists(audience.lists)
+	service.SetAuditRecorder(recorder)
+	return service
+}
+
+func waitForDataSubjectJob(t *testing.T, service *DataSubjectService, jobID string) *DataSubjectJob {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	job, err := service.WaitForJob(ctx, jobID)
+	require.NoError(t, err)
+	return job
+}
+
+// updateSpyRepository records the notifications passed to Update
+type updateSpyRepository struct {
+	*repository.MemoryRepository
+	updates []*models.Notification
+}
+
+func (r *updateSpyRepository) Update(ctx context.Context, notification *models.Notification) error {
+	r.updates = append(r.updates, notification)
+	return r.MemoryRepository.Update(ctx, notification)
+}
@@ -0,0 +1,321 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// Device represents a registered push notification device
+type Device struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	Token      string    `json:"token"`
+	Platform   string    `json:"platform"`
+	Active     bool      `json:"active"`
+	AppVersion string    `json:"app_version,omitempty"`
+	Locale     string    `json:"locale,omitempty"`
+	Timezone   string    `json:"timezone,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// topicNameRegex matches the topic names accepted by FCM
+var topicNameRegex = regexp.MustCompile(`^[a-zA-Z0-9\-_.~%]{1,900}$`)
+
+// DeviceRegistry keeps track of the devices registered for each user. It is
+// safe for concurrent use; the devices it returns are copies.
+type DeviceRegistry struct {
+	mu      sync.RWMutex
+	devices map[string]*Device         // Token to device mapping
+	users   map[string][]string        // User ID to device tokens mapping
+	topics  map[string]map[string]bool // Topic to subscribed tokens mapping
+}
+
+// NewDeviceRegistry creates a new device registry
+func NewDeviceRegistry() *DeviceRegistry {
+	return &DeviceRegistry{
+		devices: make(map[string]*Device),
+		users:   make(map[string][]string),
+		topics:  make(map[string]map[string]bool),
+	}
+}
+
+// Register registers a device for a user. Registering a token that is already
+// known updates it, moving it to the new user if the app was re-logged in.
+func (r *DeviceRegistry) Register(device *Device) (*Device, error) {
+	if device == nil {
+		return nil, errors.NewValidationError("device", "device is required")
+	}
+
+	if device.UserID == "" {
+		return nil, errors.NewValidationError("user_id", "user ID is required")
+	}
+
+	device.Platform = strings.ToLower(device.Platform)
+	if err := utils.ValidateDeviceToken(device.Token, device.Platform); err != nil {
+		return nil, err
+	}
+
+	if device.Timezone != "" {
+		if err := validateTimezone(device.Timezone); err != nil {
+			return nil, err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if existing, exists := r.devices[device.Token]; exists {
+		if existing.UserID != device.UserID {
+			r.removeUserToken(existing.UserID, existing.Token)
+			r.users[device.UserID] = append(r.users[device.UserID], device.Token)
+		}
+
+		existing.UserID = device.UserID
+		existing.Platform = device.Platform
+		existing.Active = true
+		existing.AppVersion = device.AppVersion
+		existing.Locale = device.Locale
+		existing.Timezone = device.Timezone
+		existing.UpdatedAt = now
+		existing.LastSeenAt = now
+		return copyDevice(existing), nil
+	}
+
+	if device.ID == "" {
+		device.ID = uuid.New().String()
+	}
+	device.Active = true
+	device.CreatedAt = now
+	device.UpdatedAt = now
+	device.LastSeenAt = now
+
+	r.devices[device.Token] = copyDevice(device)
+	r.users[device.UserID] = append(r.users[device.UserID], device.Token)
+
+	return copyDevice(device), nil
+}
+
+// Unregister removes a device from the registry
+func (r *DeviceRegistry) Unregister(token string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	device, exists := r.devices[token]
+	if !exists {
+		return errors.NewNotificationError(errors.ErrorCodeNotFound, "device not found")
+	}
+
+	r.removeUserToken(device.UserID, token)
+	for topic := range r.topics {
+		r.removeTopicToken(topic, token)
+	}
+	delete(r.devices, token)
+	return nil
+}
+
+// Deactivate marks a device as inactive so it no longer receives pushes
+func (r *DeviceRegistry) Deactivate(token string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	device, exists := r.devices[token]
+	if !exists {
+		return errors.NewNotificationError(errors.ErrorCodeNotFound, "device not found")
+	}
+
+	device.Active = false
+	device.UpdatedAt = time.Now()
+	return nil
+}
+
+// PruneStale removes deactivated devices, whose tokens providers rejected,
+// and devices last seen before the cutoff. It returns how many were removed.
+func (r *DeviceRegistry) PruneStale(cutoff time.Time) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pruned := 0
+	for token, device := range r.devices {
+		if device.Active && !device.LastSeenAt.Before(cutoff) {
+			continue
+		}
+
+		r.removeUserToken(device.UserID, token)
+		for topic := range r.topics {
+			r.removeTopicToken(topic, token)
+		}
+		delete(r.devices, token)
+		pruned++
+	}
+	return pruned
+}
+
+// GetDevice returns the device registered with a token
+func (r *DeviceRegistry) GetDevice(token string) (*Device, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	device, exists := r.devices[token]
+	if !exists {
+		return nil, errors.NewNotificationError(errors.ErrorCodeNotFound, fmt.Sprintf("device not found: %s", maskDeviceToken(token)))
+	}
+	return copyDevice(device), nil
+}
+
+// Timezone returns the time zone of the device registered with a token, and
+// an empty string when the device or its time zone is not known
+func (r *DeviceRegistry) Timezone(token string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if device, exists := r.devices[token]; exists {
+		return device.Timezone
+	}
+	return ""
+}
+
+// GetUserDevices returns all devices registered for a user
+func (r *DeviceRegistry) GetUserDevices(userID string) []*Device {
+	return r.userDevices(userID, false)
+}
+
+// GetActiveDevices returns the active devices registered for a user
+func (r *DeviceRegistry) GetActiveDevices(userID string) []*Device {
+	return r.userDevices(userID, true)
+}
+
+// Subscribe subscribes a registered device to a topic
+func (r *DeviceRegistry) Subscribe(token, topic string) error {
+	if err := ValidateTopicName(topic); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.devices[token]; !exists {
+		return errors.NewNotificationError(errors.ErrorCodeNotFound, fmt.Sprintf("device not found: %s", maskDeviceToken(token)))
+	}
+
+	if r.topics[topic] == nil {
+		r.topics[topic] = make(map[string]bool)
+	}
+	r.topics[topic][token] = true
+	return nil
+}
+
+// Unsubscribe removes a device from a topic
+func (r *DeviceRegistry) Unsubscribe(token, topic string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.topics[topic][token] {
+		return errors.NewNotificationError(errors.ErrorCodeNotFound, fmt.Sprintf("device is not subscribed to topic: %s", topic))
+	}
+
+	r.removeTopicToken(topic, token)
+	return nil
+}
+
+// GetTopicSubscribers returns the active devices subscribed to a topic
+func (r *DeviceRegistry) GetTopicSubscribers(topic string) []*Device {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	devices := make([]*Device, 0, len(r.topics[topic]))
+	for token := range r.topics[topic] {
+		if device, exists := r.devices[token]; exists && device.Active {
+			devices = append(devices, copyDevice(device))
+		}
+	}
+	return devices
+}
+
+// GetDeviceTopics returns the topics a device is subscribed to
+func (r *DeviceRegistry) GetDeviceTopics(token string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	topics := make([]string, 0)
+	for topic, tokens := range r.topics {
+		if tokens[token] {
+			topics = append(topics, topic)
+		}
+	}
+	return topics
+}
+
+// Count returns the number of registered devices
+func (r *DeviceRegistry) Count() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.devices)
+}
+
+// userDevices returns the devices registered for a user, optionally only the active ones
+func (r *DeviceRegistry) userDevices(userID string, activeOnly bool) []*Device {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tokens := r.users[userID]
+	devices := make([]*Device, 0, len(tokens))
+	for _, token := range tokens {
+		if device, exists := r.devices[token]; exists && (device.Active || !activeOnly) {
+			devices = append(devices, copyDevice(device))
+		}
+	}
+	return devices
+}
+
+// copyDevice returns a copy of a device, so callers never share the registry's state
+func copyDevice(device *Device) *Device {
+	copied := *device
+	return &copied
+}
+
+// removeUserToken removes a token from a user's device list. Callers must hold the lock.
+func (r *DeviceRegistry) removeUserToken(userID, token string) {
+	tokens := r.users[userID]
+	for i, t := range tokens {
+		if t == token {
+			r.users[userID] = append(tokens[:i], tokens[i+1:]...)
+			break
+		}
+	}
+
+	if len(r.users[userID]) == 0 {
+		delete(r.users, userID)
+	}
+}
+
+// removeTopicToken removes a token from a topic's subscriber set. Callers must hold the lock.
+func (r *DeviceRegistry) removeTopicToken(topic, token string) {
+	delete(r.topics[topic], token)
+	if len(r.topics[topic]) == 0 {
+		delete(r.topics, topic)
+	}
+}
+
+// ValidateTopicName validates a push topic name
+func ValidateTopicName(topic string) error {
+	if topic == "" {
+		return errors.NewValidationError("topic", "topic is required")
+	}
+
+	if !topicNameRegex.MatchString(topic) {
+		return errors.NewValidationError("topic", "topic may only contain letters, digits and -_.~%")
+	}
+
+	return nil
+}
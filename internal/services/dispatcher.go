@@ -0,0 +1,1164 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/audit"
+	"github.com/nareshkumar-microsoft/notificationService/internal/chaos"
+	"github.com/nareshkumar-microsoft/notificationService/internal/coalesce"
+	"github.com/nareshkumar-microsoft/notificationService/internal/compliance"
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/conversation"
+	"github.com/nareshkumar-microsoft/notificationService/internal/events"
+	"github.com/nareshkumar-microsoft/notificationService/internal/frequency"
+	"github.com/nareshkumar-microsoft/notificationService/internal/inbound"
+	"github.com/nareshkumar-microsoft/notificationService/internal/limits"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/numberlookup"
+	"github.com/nareshkumar-microsoft/notificationService/internal/outbox"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pause"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pipeline"
+	"github.com/nareshkumar-microsoft/notificationService/internal/preferences"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pushgroup"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pushmedia"
+	"github.com/nareshkumar-microsoft/notificationService/internal/ratelimit"
+	"github.com/nareshkumar-microsoft/notificationService/internal/reconcile"
+	"github.com/nareshkumar-microsoft/notificationService/internal/requestlog"
+	"github.com/nareshkumar-microsoft/notificationService/internal/rollout"
+	"github.com/nareshkumar-microsoft/notificationService/internal/routing"
+	"github.com/nareshkumar-microsoft/notificationService/internal/shortlink"
+	"github.com/nareshkumar-microsoft/notificationService/internal/sla"
+	"github.com/nareshkumar-microsoft/notificationService/internal/spamcheck"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// Dispatcher implements the NotificationService interface by routing
+// notification requests to the provider registered for their type, or to a
+// named provider chosen by routing rules. Requests pass through a middleware
+// chain before they are stored and sent. It publishes lifecycle events when
+// an event publisher is set.
+type Dispatcher struct {
+	mu            sync.RWMutex
+	providers     map[models.NotificationType]interfaces.NotificationProvider
+	providerNames map[models.NotificationType]string
+	named         map[models.NotificationType]map[string]interfaces.NotificationProvider
+	repository    interfaces.NotificationRepository
+	chain         *pipeline.Chain
+	events        events.Publisher
+	replies       *inbound.Router
+	pauses        *pause.Controller
+	rollouts      *rollout.Controller
+	sla           *sla.Monitor
+	chaos         *chaos.Injector
+	limiter       *ratelimit.Limiter
+	logger        interfaces.Logger
+}
+
+// NewDispatcher creates a new dispatcher with no registered providers
+func NewDispatcher(repository interfaces.NotificationRepository, logger interfaces.Logger) *Dispatcher {
+	return &Dispatcher{
+		providers:     make(map[models.NotificationType]interfaces.NotificationProvider),
+		providerNames: make(map[models.NotificationType]string),
+		named:         make(map[models.NotificationType]map[string]interfaces.NotificationProvider),
+		repository:    repository,
+		chain:         pipeline.NewDefaultChain(),
+		logger:        logger,
+	}
+}
+
+// NewDispatcherFromConfig creates a dispatcher and registers a provider for
+// every channel enabled in the configuration
+func NewDispatcherFromConfig(cfg config.ProvidersConfig, repository interfaces.NotificationRepository, logger interfaces.Logger) (*Dispatcher, error) {
+	dispatcher := NewDispatcher(repository, logger)
+
+	if cfg.Email.Enabled {
+		provider, err := providers.NewEmailProvider(cfg.Email)
+		if err != nil {
+			return nil, err
+		}
+		dispatcher.RegisterProvider(provider)
+	}
+
+	if cfg.SMS.Enabled {
+		provider, err := providers.NewSMSProvider(cfg.SMS)
+		if err != nil {
+			return nil, err
+		}
+		dispatcher.RegisterProvider(provider)
+	}
+
+	if cfg.Push.Enabled {
+		provider, err := providers.NewPushProvider(cfg.Push)
+		if err != nil {
+			return nil, err
+		}
+		dispatcher.RegisterProvider(provider)
+	}
+
+	// Providers calling HTTP APIs share pooled connections; their own HTTP
+	// settings override the shared ones
+	cfg.Chat.HTTP = cfg.HTTP.Merge(cfg.Chat.HTTP)
+	cfg.Voice.HTTP = cfg.HTTP.Merge(cfg.Voice.HTTP)
+
+	if cfg.Chat.Enabled {
+		provider, err := providers.NewChatProvider(cfg.Chat)
+		if err != nil {
+			return nil, err
+		}
+		dispatcher.RegisterProvider(provider)
+	}
+
+	if cfg.Voice.Enabled {
+		provider, err := providers.NewVoiceProvider(cfg.Voice)
+		if err != nil {
+			return nil, err
+		}
+		dispatcher.RegisterProvider(provider)
+	}
+
+	dispatcher.providerNames[models.NotificationTypeEmail] = cfg.Email.Provider
+	dispatcher.providerNames[models.NotificationTypeSMS] = cfg.SMS.Provider
+	dispatcher.providerNames[models.NotificationTypePush] = cfg.Push.Provider
+	dispatcher.providerNames[models.NotificationTypeChat] = cfg.Chat.Provider
+	dispatcher.providerNames[models.NotificationTypeVoice] = cfg.Voice.Provider
+
+	return dispatcher, nil
+}
+
+// ProviderName returns the configured name of the provider sending a channel,
+// e.g. "twilio", falling back to the provider's display name
+func (d *Dispatcher) ProviderName(notificationType models.NotificationType) string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if name := d.providerNames[notificationType]; name != "" {
+		return name
+	}
+	if provider, exists := d.providers[notificationType]; exists {
+		return provider.GetConfig().Name
+	}
+	return ""
+}
+
+// SetPauseController sets the controller whose pauses stop sending. Paused
+// sends are rejected with ErrorCodeProviderUnavailable.
+func (d *Dispatcher) SetPauseController(controller *pause.Controller) {
+	controller.SetProviderResolver(d.ProviderName)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.pauses = controller
+}
+
+// SetRolloutController splits each channel's sends between its provider
+// and the provider being rolled out, as the controller decides. Sends whose
+// provider was already chosen, by routing rules or email routes, are left
+// out of the rollout.
+func (d *Dispatcher) SetRolloutController(controller *rollout.Controller) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.rollouts = controller
+}
+
+// SetSLAMonitor records the success and latency of every provider send in
+// the monitor, whose degraded providers fail health checks. Providers with
+// failover of their own, such as country routed SMS, record each attempt
+// themselves and try healthy providers first; set the monitor after
+// registering them.
+func (d *Dispatcher) SetSLAMonitor(monitor *sla.Monitor) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.sla = monitor
+	for _, provider := range d.providers {
+		if monitored, ok := provider.(monitoredProvider); ok {
+			monitored.SetMonitor(monitor)
+		}
+	}
+	for _, named := range d.named {
+		for _, provider := range named {
+			if monitored, ok := provider.(monitoredProvider); ok {
+				monitored.SetMonitor(monitor)
+			}
+		}
+	}
+}
+
+// SetChaosInjector applies the injector's faults to every provider call,
+// for resilience tests. Providers with failover of their own, such as
+// country routed SMS, apply them to each attempt; set the injector after
+// registering them.
+func (d *Dispatcher) SetChaosInjector(injector *chaos.Injector) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.chaos = injector
+	for _, provider := range d.providers {
+		if injectable, ok := provider.(faultInjectableProvider); ok {
+			injectable.SetFaultInjector(injector)
+		}
+	}
+	for _, named := range d.named {
+		for _, provider := range named {
+			if injectable, ok := provider.(faultInjectableProvider); ok {
+				injectable.SetFaultInjector(injector)
+			}
+		}
+	}
+}
+
+// faultInjectableProvider is a provider applying injected faults itself
+type faultInjectableProvider interface {
+	SetFaultInjector(injector providers.FaultInjector)
+}
+
+// injectFault applies the chaos injector's faults to a provider call, if
+// an injector is set
+func (d *Dispatcher) injectFault(ctx context.Context, provider interfaces.NotificationProvider, request *models.NotificationRequest) error {
+	d.mu.RLock()
+	injector := d.chaos
+	d.mu.RUnlock()
+
+	if injector == nil {
+		return nil
+	}
+	if _, injectable := provider.(faultInjectableProvider); injectable {
+		return nil
+	}
+	return injector.Inject(ctx, request.Type, d.sendingProviderName(request))
+}
+
+// sendingProviderName returns the name of the provider sending a request:
+// the named provider its metadata asks for, or its channel's configured one
+func (d *Dispatcher) sendingProviderName(request *models.NotificationRequest) string {
+	if name := request.Metadata[routing.MetadataProvider]; name != "" {
+		return name
+	}
+	return d.ProviderName(request.Type)
+}
+
+// monitoredProvider is a provider recording its own sends in a monitor
+type monitoredProvider interface {
+	SetMonitor(monitor providers.SendMonitor)
+}
+
+// slaMonitor returns the SLA monitor, if one is set
+func (d *Dispatcher) slaMonitor() *sla.Monitor {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.sla
+}
+
+// recordSLA records a provider send in the SLA monitor, if one is set
+func (d *Dispatcher) recordSLA(provider interfaces.NotificationProvider, request *models.NotificationRequest, err error, latency time.Duration) {
+	monitor := d.slaMonitor()
+	if monitor == nil {
+		return
+	}
+	if _, monitored := provider.(monitoredProvider); monitored {
+		return
+	}
+
+	monitor.RecordSend(request.Type, d.sendingProviderName(request), err, latency)
+}
+
+// rolloutController returns the rollout controller, if one is set
+func (d *Dispatcher) rolloutController() *rollout.Controller {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.rollouts
+}
+
+// applyRollout returns a request sent under its channel's rollout, with the
+// canary provider and the rollout arm in its metadata, and the arm. The
+// request is returned unchanged, with no arm, when no rollout applies.
+func (d *Dispatcher) applyRollout(request *models.NotificationRequest) (*models.NotificationRequest, string) {
+	controller := d.rolloutController()
+	if controller == nil || request.Metadata[routing.MetadataProvider] != "" {
+		return request, ""
+	}
+
+	provider, arm := controller.Choose(request)
+	if arm == "" {
+		return request, ""
+	}
+
+	routed := *request
+	routed.Metadata = make(map[string]string, len(request.Metadata)+2)
+	for key, value := range request.Metadata {
+		routed.Metadata[key] = value
+	}
+	routed.Metadata[rollout.MetadataArm] = arm
+	if provider != "" {
+		routed.Metadata[routing.MetadataProvider] = provider
+	}
+	return &routed, arm
+}
+
+// checkPaused returns an error when sending on a channel is paused
+func (d *Dispatcher) checkPaused(notificationType models.NotificationType) error {
+	d.mu.RLock()
+	controller := d.pauses
+	d.mu.RUnlock()
+
+	if controller == nil {
+		return nil
+	}
+	return controller.Check(notificationType)
+}
+
+// takeProviderToken takes a rate limit token for the provider a routed
+// request is sent through, if a rate limiter is set
+func (d *Dispatcher) takeProviderToken(ctx context.Context, request *models.NotificationRequest) error {
+	d.mu.RLock()
+	limiter := d.limiter
+	d.mu.RUnlock()
+
+	if limiter == nil {
+		return nil
+	}
+	return limiter.TakeProvider(ctx, d.sendingProviderName(request))
+}
+
+// SendNotification implements the NotificationService interface. The request
+// runs through the middleware chain; a request stopped by a middleware is
+// published as rejected.
+func (d *Dispatcher) SendNotification(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+	if request == nil {
+		return nil, errors.NewValidationError("request", "notification request is required")
+	}
+
+	payloadHash := audit.HashPayload(request)
+	source := request
+
+	reachedProvider := false
+	send := d.chain.Then(func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+		request, arm := d.applyRollout(request)
+		provider, err := d.providerFor(request)
+		if err != nil {
+			return nil, err
+		}
+		if err := d.checkPaused(request.Type); err != nil {
+			return nil, err
+		}
+		if err := providers.ValidateProviderOptions(provider, request.ProviderOptions); err != nil {
+			return nil, err
+		}
+		if err := d.takeProviderToken(ctx, request); err != nil {
+			return nil, err
+		}
+
+		reachedProvider = true
+		if arm == "" {
+			return d.send(ctx, provider, request, source, payloadHash)
+		}
+
+		start := time.Now()
+		response, err := d.send(ctx, provider, request, source, payloadHash)
+		d.rolloutController().Record(request.Type, arm, err, time.Since(start))
+		return response, err
+	})
+
+	response, err := send(ctx, source)
+	if err != nil && !reachedProvider {
+		d.logger.Errorf("Notification rejected: %v", err)
+		d.publishRejection(ctx, request, payloadHash, err)
+	}
+	return response, err
+}
+
+// RegisterMiddleware adds a middleware to the send pipeline at a stage, for
+// example a compliance check at pipeline.StagePreferences
+func (d *Dispatcher) RegisterMiddleware(name string, stage pipeline.Stage, middleware pipeline.Middleware) error {
+	if err := d.chain.Register(name, stage, middleware); err != nil {
+		return err
+	}
+
+	d.logger.Infof("Registered send middleware %s (stage %d)", name, stage)
+	return nil
+}
+
+// SetPreferences enforces recipients' opt-outs and quiet hours according to
+// each category's policy. Sends held for quiet hours go through the
+// dispatcher again when the quiet hours end.
+func (d *Dispatcher) SetPreferences(enforcer *preferences.Enforcer) error {
+	if err := d.RegisterMiddleware(preferences.MiddlewareName, pipeline.StagePreferences, enforcer.Middleware()); err != nil {
+		return err
+	}
+
+	enforcer.SetSender(d.SendNotification)
+	return nil
+}
+
+// SetCompliance checks SMS sends against the guard's do-not-disturb
+// registries and consent records before they reach the provider
+func (d *Dispatcher) SetCompliance(guard *compliance.Guard) error {
+	return d.RegisterMiddleware(compliance.MiddlewareName, pipeline.StagePreferences, guard.Middleware())
+}
+
+// SetNumberLookup looks up the line type of SMS recipients before they
+// reach the provider, rejecting landlines when the service blocks them
+func (d *Dispatcher) SetNumberLookup(lookup *numberlookup.Service) error {
+	return d.RegisterMiddleware(numberlookup.MiddlewareName, pipeline.StagePreferences, lookup.Middleware())
+}
+
+// SetShortLinks rewrites long URLs in SMS bodies to short tracked links,
+// after templates are rendered, and ties them to the sent notification
+func (d *Dispatcher) SetShortLinks(shortener shortlink.Shortener) error {
+	return d.RegisterMiddleware(shortlink.MiddlewareName, pipeline.StageTemplate, shortlink.Middleware(shortener, d.logger))
+}
+
+// SetConversations records sent SMS in their two-way conversations
+func (d *Dispatcher) SetConversations(store *conversation.Store) error {
+	return d.RegisterMiddleware(conversation.MiddlewareName, pipeline.StageTemplate, store.Middleware(d.logger))
+}
+
+// SetPushGroups counts grouped push notifications per device, so their
+// group summaries can say how many alerts are in the group
+func (d *Dispatcher) SetPushGroups(grouper *pushgroup.Grouper) error {
+	return d.RegisterMiddleware(pushgroup.MiddlewareName, pipeline.StageTemplate, grouper.Middleware(d.logger))
+}
+
+// SetPushMedia checks push images are reachable and within the platform's
+// type and size limits before they reach the provider
+func (d *Dispatcher) SetPushMedia(checker *pushmedia.Checker) error {
+	return d.RegisterMiddleware(pushmedia.MiddlewareName, pipeline.StageTemplate, checker.Middleware(d.logger))
+}
+
+// SetSpamCheck scores marketing email after templates are rendered, and
+// rejects spam when the checker blocks it
+func (d *Dispatcher) SetSpamCheck(checker *spamcheck.Checker) error {
+	return d.RegisterMiddleware(spamcheck.MiddlewareName, pipeline.StageTemplate, checker.Middleware())
+}
+
+// SetLimits rejects notifications over the size and content limits after
+// templates are rendered. Call it after the other template-stage setters,
+// such as SetShortLinks, so their changes are measured.
+func (d *Dispatcher) SetLimits(payloadLimits *limits.Limits) error {
+	return d.RegisterMiddleware(limits.MiddlewareName, pipeline.StageTemplate, payloadLimits.Middleware())
+}
+
+// SetEmailRouting routes email to the provider and sending domain of its
+// category's route. Routing runs with the preference checks, so rate limits
+// and warmup see the routed sender.
+func (d *Dispatcher) SetEmailRouting(router *routing.Router) error {
+	return d.RegisterMiddleware(routing.MiddlewareName, pipeline.StagePreferences, router.Middleware())
+}
+
+// SetRoutingRules applies the engine's rules to every send: the matching
+// rule's provider and sender are set with the preference checks, and its
+// rate limit is enforced with the other rate limits
+func (d *Dispatcher) SetRoutingRules(engine *routing.Engine) error {
+	if err := d.RegisterMiddleware(routing.RulesMiddlewareName, pipeline.StagePreferences, engine.Middleware()); err != nil {
+		return err
+	}
+	return d.RegisterMiddleware(routing.RuleLimitsMiddlewareName, pipeline.StageRateLimit, engine.LimitMiddleware())
+}
+
+// SetRateLimiter enforces the limiter's provider and tenant limits before
+// sends reach their provider. Tenant limits run in the pipeline; provider
+// limits apply to the provider a send is routed to, after any rollout.
+func (d *Dispatcher) SetRateLimiter(limiter *ratelimit.Limiter) error {
+	if err := d.RegisterMiddleware(ratelimit.MiddlewareName, pipeline.StageRateLimit, limiter.Middleware()); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.limiter = limiter
+	return nil
+}
+
+// SetCorrelationIDs gives notifications sent without a correlation ID the ID
+// of the API request sending them, so their logs and events can be traced
+// back to the request
+func (d *Dispatcher) SetCorrelationIDs() error {
+	return d.RegisterMiddleware(requestlog.MiddlewareName, pipeline.StageValidation, requestlog.Middleware())
+}
+
+// RemoveMiddleware removes a middleware, including a built-in one, from the
+// send pipeline. Removing the rate limiter's also lifts its provider limits.
+func (d *Dispatcher) RemoveMiddleware(name string) bool {
+	if name == ratelimit.MiddlewareName {
+		d.mu.Lock()
+		d.limiter = nil
+		d.mu.Unlock()
+	}
+	return d.chain.Remove(name)
+}
+
+// Middleware returns the names of the send pipeline's middleware in the order they run
+func (d *Dispatcher) Middleware() []string {
+	return d.chain.Names()
+}
+
+// send stores a notification for the request and delivers it through the
+// provider. The source request, as it was before the middleware chain, keeps
+// the unrendered subject and body of a templated notification.
+func (d *Dispatcher) send(ctx context.Context, provider interfaces.NotificationProvider, request, source *models.NotificationRequest, payloadHash string) (*models.NotificationResponse, error) {
+	notification := utils.CreateNotificationFromRequest(request)
+	if len(source.TemplateData) > 0 {
+		if strings.Contains(source.Subject, "{{") {
+			notification.SubjectTemplate = source.Subject
+		}
+		if strings.Contains(source.Body, "{{") {
+			notification.BodyTemplate = source.Body
+		}
+	}
+	if notification.Expired(time.Now()) {
+		return d.expire(ctx, notification, payloadHash)
+	}
+	if err := d.repository.Save(ctx, notification); err != nil {
+		return nil, err
+	}
+
+	d.publish(ctx, events.EventNotificationQueued, notification, payloadHash)
+	d.logger.Infof("Dispatching %s notification %s%s", notification.Type, notification.ID, correlation(notification))
+
+	start := time.Now()
+	response, sendErr := d.deliver(ctx, provider, notification, request)
+	d.recordSLA(provider, request, sendErr, time.Since(start))
+	d.recordResult(ctx, notification, response, sendErr, payloadHash)
+
+	if sendErr != nil {
+		d.logger.Errorf("Notification %s%s failed: %v", notification.ID, correlation(notification), sendErr)
+		return nil, sendErr
+	}
+
+	return response, nil
+}
+
+// RetryNotification resends a failed notification through the provider that
+// sent it, the one routing or a rollout recorded, while it has retries left. Channel-specific request data is not stored, so the
+// retry goes through the provider's generic Send.
+func (d *Dispatcher) RetryNotification(ctx context.Context, notificationID string) (*models.NotificationResponse, error) {
+	notification, err := d.repository.GetByID(ctx, notificationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !utils.ShouldRetryNotification(notification) {
+		return nil, errors.NewValidationError("notification", fmt.Sprintf("notification %s cannot be retried (status %s, %d of %d retries used)",
+			notification.ID, notification.Status, notification.RetryCount, notification.MaxRetries))
+	}
+
+	provider, err := d.NamedProvider(notification.Type, notification.Metadata[routing.MetadataProvider])
+	if err != nil {
+		return nil, err
+	}
+	if err := d.checkPaused(notification.Type); err != nil {
+		return nil, err
+	}
+
+	notification.RetryCount++
+	notification.Status = models.StatusRetrying
+	notification.ErrorMsg = ""
+	if err := d.repository.Update(ctx, notification); err != nil {
+		return nil, err
+	}
+
+	d.publish(ctx, events.EventNotificationRetried, notification, "")
+	d.logger.Infof("Retrying %s notification %s%s (attempt %d)", notification.Type, notification.ID, correlation(notification), notification.RetryCount+1)
+
+	response, sendErr := provider.Send(ctx, notification)
+	d.recordResult(ctx, notification, response, sendErr, "")
+
+	if sendErr != nil {
+		d.logger.Errorf("Retry of notification %s%s failed: %v", notification.ID, correlation(notification), sendErr)
+		return nil, sendErr
+	}
+
+	return response, nil
+}
+
+// MetadataResentFrom is the metadata key linking a resent notification to the original
+const MetadataResentFrom = "resent_from"
+
+// reservedMetadata lists the metadata keys the service sets on requests
+// and notifications as it routes and sends them, along with the compliance
+// guard's, which share compliance.MetadataPrefix
+var reservedMetadata = []string{
+	routing.MetadataProvider,
+	routing.MetadataRule,
+	rollout.MetadataArm,
+	coalesce.MetadataCoalescedCount,
+	outbox.MetadataEntryID,
+	frequency.MetadataCapped,
+	reconcile.MetadataReconciled,
+	MetadataResentFrom,
+}
+
+// IsReservedMetadata reports whether a metadata key is set only by the
+// service. The API removes such keys from callers' requests.
+func IsReservedMetadata(key string) bool {
+	if strings.HasPrefix(key, compliance.MetadataPrefix) {
+		return true
+	}
+	for _, reserved := range reservedMetadata {
+		if key == reserved {
+			return true
+		}
+	}
+	return false
+}
+
+// ResendOptions overrides fields of a notification being resent
+type ResendOptions struct {
+	Recipient    string            `json:"recipient,omitempty"`
+	TemplateData map[string]string `json:"template_data,omitempty"` // merged over the original's data
+}
+
+// ResendNotification dispatches a copy of a finished notification as a new
+// notification linked to the original through its metadata, for support
+// workflows such as resending to a corrected address. Templated subjects and
+// bodies are rendered again, so template data overrides take effect.
+// Channel-specific request data other than the email HTML body is not stored
+// and is not resent.
+func (d *Dispatcher) ResendNotification(ctx context.Context, notificationID string, options *ResendOptions) (*models.NotificationResponse, error) {
+	original, err := d.repository.GetByID(ctx, notificationID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch original.Status {
+	case models.StatusSent, models.StatusDelivered, models.StatusFailed:
+	default:
+		return nil, errors.NewValidationError("notification", fmt.Sprintf("notification %s is %s and cannot be resent", original.ID, original.Status))
+	}
+
+	// Only the caller's metadata is copied; the original's routing and
+	// decision records are made again for the resend
+	metadata := make(map[string]string, len(original.Metadata)+1)
+	for key, value := range original.Metadata {
+		if !IsReservedMetadata(key) {
+			metadata[key] = value
+		}
+	}
+	metadata[MetadataResentFrom] = original.ID.String()
+
+	request := &models.NotificationRequest{
+		Type:         original.Type,
+		Priority:     original.Priority,
+		Recipient:    original.Recipient,
+		Subject:      original.Subject,
+		Body:         original.Body,
+		Category:     original.Category,
+		TemplateData: original.TemplateData,
+		Metadata:     metadata,
+		MaxRetries:   original.MaxRetries,
+	}
+	if original.SubjectTemplate != "" {
+		request.Subject = original.SubjectTemplate
+	}
+	if original.BodyTemplate != "" {
+		request.Body = original.BodyTemplate
+	}
+	if original.Type == models.NotificationTypeEmail && original.HTMLBody != "" {
+		request.EmailData = &models.EmailData{HTMLBody: original.HTMLBody}
+	}
+
+	if options != nil {
+		if options.Recipient != "" {
+			request.Recipient = options.Recipient
+		}
+		request.TemplateData = mergeTemplateData(request.TemplateData, options.TemplateData)
+	}
+
+	d.logger.Infof("Resending %s notification %s", original.Type, original.ID)
+	return d.SendNotification(ctx, request)
+}
+
+// MarkDelivered records a delivery confirmation for a sent notification
+func (d *Dispatcher) MarkDelivered(ctx context.Context, notificationID string) error {
+	notification, err := d.repository.GetByID(ctx, notificationID)
+	if err != nil {
+		return err
+	}
+
+	if notification.Status == models.StatusDelivered {
+		return nil
+	}
+	if notification.Status != models.StatusSent {
+		return errors.NewValidationError("status", fmt.Sprintf("notification is %s and cannot be marked delivered", notification.Status))
+	}
+
+	now := time.Now()
+	notification.Status = models.StatusDelivered
+	notification.DeliveredAt = &now
+	if err := d.repository.Update(ctx, notification); err != nil {
+		return err
+	}
+
+	d.publish(ctx, events.EventNotificationDelivered, notification, "")
+	return nil
+}
+
+// AcknowledgeNotification records that a person saw a sent notification. A
+// sent notification is also marked delivered. Acknowledging a notification
+// again has no effect. Subscribers such as the user notifier's escalations
+// learn of it from EventNotificationAcknowledged.
+func (d *Dispatcher) AcknowledgeNotification(ctx context.Context, notificationID string, ack *models.Acknowledgement) (*models.Notification, error) {
+	notification, err := d.repository.GetByID(ctx, notificationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if notification.AcknowledgedAt != nil {
+		return notification, nil
+	}
+	if notification.Status != models.StatusSent && notification.Status != models.StatusDelivered {
+		return nil, errors.NewValidationError("status", fmt.Sprintf("notification is %s and cannot be acknowledged", notification.Status))
+	}
+
+	if ack == nil {
+		ack = &models.Acknowledgement{}
+	}
+	now := time.Now()
+	acknowledgedAt := now
+	if ack.At != nil {
+		if ack.At.After(now) {
+			return nil, errors.NewValidationError("at", "acknowledgement time cannot be in the future")
+		}
+		acknowledgedAt = *ack.At
+	}
+	source := ack.Source
+	if source == "" {
+		source = models.AcknowledgeSourceAPI
+	}
+
+	delivered := notification.Status == models.StatusSent
+	if delivered {
+		notification.Status = models.StatusDelivered
+		notification.DeliveredAt = &now
+	}
+	notification.AcknowledgedAt = &acknowledgedAt
+	notification.AcknowledgedVia = source
+	if err := d.repository.Update(ctx, notification); err != nil {
+		return nil, err
+	}
+
+	if delivered {
+		d.publish(ctx, events.EventNotificationDelivered, notification, "")
+	}
+	d.publish(ctx, events.EventNotificationAcknowledged, notification, "")
+	d.logger.Infof("Notification %s acknowledged via %s", notification.ID, source)
+	return notification, nil
+}
+
+// SetEventPublisher sets the publisher that receives lifecycle events
+func (d *Dispatcher) SetEventPublisher(publisher events.Publisher) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.events = publisher
+}
+
+// SetReplyRouter sets the router whose reply addresses become the Reply-To
+// of emails without one, so replies reach the inbound webhook
+func (d *Dispatcher) SetReplyRouter(router *inbound.Router) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.replies = router
+}
+
+// GetNotificationStatus implements the NotificationService interface
+func (d *Dispatcher) GetNotificationStatus(ctx context.Context, notificationID string) (*models.Notification, error) {
+	return d.repository.GetByID(ctx, notificationID)
+}
+
+// RegisterProvider implements the NotificationService interface. A provider
+// replaces any provider previously registered for the same type.
+func (d *Dispatcher) RegisterProvider(provider interfaces.NotificationProvider) error {
+	if provider == nil {
+		return errors.NewValidationError("provider", "provider is required")
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.providers[provider.GetType()] = provider
+	return nil
+}
+
+// GetProvider implements the NotificationService interface
+func (d *Dispatcher) GetProvider(notificationType models.NotificationType) (interfaces.NotificationProvider, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	provider, exists := d.providers[notificationType]
+	if !exists {
+		return nil, errors.NewNotificationError(
+			errors.ErrorCodeProviderNotFound,
+			fmt.Sprintf("no provider registered for %s notifications", notificationType),
+		)
+	}
+
+	return provider, nil
+}
+
+// RegisterNamedProvider registers a provider under a name, such as a second
+// email provider that routes can send through. Requests name it in their
+// metadata; see routing.MetadataProvider. Names are per channel, and the
+// configured name of a channel's default provider, e.g. "twilio", names it.
+func (d *Dispatcher) RegisterNamedProvider(name string, provider interfaces.NotificationProvider) error {
+	if name == "" {
+		return errors.NewValidationError("name", "provider name is required")
+	}
+	if provider == nil {
+		return errors.NewValidationError("provider", "provider is required")
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	notificationType := provider.GetType()
+	if d.named[notificationType] == nil {
+		d.named[notificationType] = make(map[string]interfaces.NotificationProvider)
+	}
+	d.named[notificationType][name] = provider
+	return nil
+}
+
+// providerFor returns the provider sending a request: the named provider
+// its metadata asks for, or the provider registered for its type
+func (d *Dispatcher) providerFor(request *models.NotificationRequest) (interfaces.NotificationProvider, error) {
+	return d.NamedProvider(request.Type, request.Metadata[routing.MetadataProvider])
+}
+
+// NamedProvider returns the provider registered for a channel under a
+// name, such as the one a notification's routing.MetadataProvider records,
+// or the channel's default provider when the name is empty
+func (d *Dispatcher) NamedProvider(notificationType models.NotificationType, name string) (interfaces.NotificationProvider, error) {
+	if name == "" {
+		return d.GetProvider(notificationType)
+	}
+
+	d.mu.RLock()
+	provider, exists := d.named[notificationType][name]
+	if !exists && name == d.providerNames[notificationType] {
+		provider, exists = d.providers[notificationType]
+	}
+	d.mu.RUnlock()
+
+	if !exists {
+		return nil, errors.NewNotificationError(
+			errors.ErrorCodeProviderNotFound,
+			fmt.Sprintf("no %s provider registered as %s", notificationType, name),
+		)
+	}
+	return provider, nil
+}
+
+// ListProviders implements the NotificationService interface
+func (d *Dispatcher) ListProviders() map[models.NotificationType]interfaces.NotificationProvider {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	result := make(map[models.NotificationType]interfaces.NotificationProvider, len(d.providers))
+	for notificationType, provider := range d.providers {
+		result[notificationType] = provider
+	}
+	return result
+}
+
+// HealthCheck implements the NotificationService interface. A provider the
+// SLA monitor marks degraded is unhealthy.
+func (d *Dispatcher) HealthCheck(ctx context.Context) map[models.NotificationType]error {
+	monitor := d.slaMonitor()
+	results := make(map[models.NotificationType]error)
+	for notificationType, provider := range d.ListProviders() {
+		results[notificationType] = provider.IsHealthy(ctx)
+		if results[notificationType] != nil || monitor == nil {
+			continue
+		}
+		if _, monitored := provider.(monitoredProvider); monitored {
+			continue
+		}
+		name := d.ProviderName(notificationType)
+		if stats, _ := monitor.Provider(notificationType, name); stats.Degraded {
+			results[notificationType] = errors.NewNotificationError(errors.ErrorCodeProviderUnavailable,
+				fmt.Sprintf("%s provider %s is degraded: %s", notificationType, name, stats.Reason))
+		}
+	}
+	return results
+}
+
+// recordResult stores the outcome of a send and publishes the matching event
+func (d *Dispatcher) recordResult(ctx context.Context, notification *models.Notification, response *models.NotificationResponse, sendErr error, payloadHash string) {
+	now := time.Now()
+	if sendErr != nil {
+		notification.Status = models.StatusFailed
+		notification.FailedAt = &now
+		notification.ErrorMsg = sendErr.Error()
+	} else {
+		notification.Status = response.Status
+		notification.SentAt = &now
+		notification.ProviderMessageID = response.ProviderID
+	}
+
+	if err := d.repository.Update(ctx, notification); err != nil {
+		d.logger.Errorf("Failed to update notification %s: %v", notification.ID, err)
+	}
+
+	if sendErr != nil {
+		d.publish(ctx, events.EventNotificationFailed, notification, payloadHash)
+	} else {
+		d.publish(ctx, events.EventNotificationSent, notification, payloadHash)
+	}
+}
+
+// expire stores a notification that expired before it could be sent, such
+// as a one-time password that sat in a queue, without sending it
+func (d *Dispatcher) expire(ctx context.Context, notification *models.Notification, payloadHash string) (*models.NotificationResponse, error) {
+	notification.Status = models.StatusExpired
+	if err := d.repository.Save(ctx, notification); err != nil {
+		return nil, err
+	}
+
+	d.logger.Warnf("Notification %s expired at %s and was not sent", notification.ID, notification.ExpiresAt.Format(time.RFC3339))
+	d.publish(ctx, events.EventNotificationExpired, notification, payloadHash)
+	return expiredResponse(notification), nil
+}
+
+// publish publishes a lifecycle event if a publisher is set
+func (d *Dispatcher) publish(ctx context.Context, eventType events.EventType, notification *models.Notification, payloadHash string) {
+	publisher := d.publisher()
+	if publisher == nil {
+		return
+	}
+
+	event := events.NewNotificationEvent(eventType, notification)
+	event.PayloadHash = payloadHash
+	publisher.Publish(ctx, event)
+}
+
+// publishRejection publishes an event for a request that was refused before a notification was created
+func (d *Dispatcher) publishRejection(ctx context.Context, request *models.NotificationRequest, payloadHash string, reason error) {
+	publisher := d.publisher()
+	if publisher == nil || request == nil {
+		return
+	}
+
+	publisher.Publish(ctx, events.Event{
+		Type:             events.EventNotificationRejected,
+		NotificationType: request.Type,
+		Recipient:        request.Recipient,
+		Status:           models.StatusFailed,
+		Reason:           reason.Error(),
+		PayloadHash:      payloadHash,
+		Metadata:         request.Metadata,
+	})
+}
+
+// publisher returns the event publisher, if one is set
+func (d *Dispatcher) publisher() events.Publisher {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.events
+}
+
+// replyRouter returns the reply router, if one is set
+func (d *Dispatcher) replyRouter() *inbound.Router {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.replies
+}
+
+// deliver sends a notification through its provider, using the typed
+// channel API when the request carries channel-specific data. Injected
+// chaos faults apply first.
+func (d *Dispatcher) deliver(ctx context.Context, provider interfaces.NotificationProvider, notification *models.Notification, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+	if err := d.injectFault(ctx, provider, request); err != nil {
+		return nil, err
+	}
+
+	switch typed := provider.(type) {
+	case interfaces.EmailProvider:
+		if request.EmailData != nil {
+			email := buildEmailNotification(notification, request.EmailData)
+			if replies := d.replyRouter(); email.ReplyTo == "" && replies != nil {
+				email.ReplyTo = replies.ReplyAddress(notification.ID)
+			}
+			return typed.SendEmail(ctx, email)
+		}
+	case interfaces.SMSProvider:
+		if request.SMSData != nil {
+			return typed.SendSMS(ctx, buildSMSNotification(notification, request.SMSData))
+		}
+	case interfaces.PushProvider:
+		if request.PushData != nil {
+			return typed.SendPush(ctx, buildPushNotification(notification, request.PushData))
+		}
+	case interfaces.ChatProvider:
+		if request.ChatData != nil {
+			return typed.SendChat(ctx, buildChatNotification(notification, request.ChatData))
+		}
+	case interfaces.VoiceProvider:
+		if request.VoiceData != nil {
+			return typed.SendVoice(ctx, buildVoiceNotification(notification, request.VoiceData))
+		}
+	}
+
+	return provider.Send(ctx, notification)
+}
+
+// correlation formats a notification's correlation ID for its log lines
+func correlation(notification *models.Notification) string {
+	if notification.CorrelationID == "" {
+		return ""
+	}
+	return " (correlation_id=" + notification.CorrelationID + ")"
+}
+
+// buildEmailNotification combines a notification with email request data
+func buildEmailNotification(notification *models.Notification, data *models.EmailData) *models.EmailNotification {
+	email := &models.EmailNotification{
+		Notification: *notification,
+		To:           data.To,
+		CC:           data.CC,
+		BCC:          data.BCC,
+		From:         data.From,
+		ReplyTo:      data.ReplyTo,
+		HTMLBody:     data.HTMLBody,
+		TextBody:     data.TextBody,
+		Attachments:  data.Attachments,
+		Headers:      data.Headers,
+		MessageID:    utils.GenerateMessageID(notification.ID, data.From),
+		InReplyTo:    data.InReplyTo,
+		References:   utils.ThreadReferences(data.InReplyTo, data.References),
+	}
+
+	if len(email.To) == 0 {
+		email.To = []string{notification.Recipient}
+	}
+	if email.TextBody == "" && email.HTMLBody == "" {
+		email.TextBody = notification.Body
+	}
+
+	return email
+}
+
+// buildSMSNotification combines a notification with SMS request data
+func buildSMSNotification(notification *models.Notification, data *models.SMSData) *models.SMSNotification {
+	phoneNumber := data.PhoneNumber
+	if phoneNumber == "" {
+		phoneNumber = notification.Recipient
+	}
+
+	return &models.SMSNotification{
+		Notification: *notification,
+		PhoneNumber:  phoneNumber,
+		CountryCode:  data.CountryCode,
+		Message:      notification.Body,
+		Unicode:      data.Unicode,
+		SenderID:     data.SenderID,
+	}
+}
+
+// buildPushNotification combines a notification with push request data
+func buildPushNotification(notification *models.Notification, data *models.PushData) *models.PushNotification {
+	deviceToken := data.DeviceToken
+	if deviceToken == "" {
+		deviceToken = notification.Recipient
+	}
+
+	title := data.Title
+	if title == "" {
+		title = notification.Subject
+	}
+
+	return &models.PushNotification{
+		Notification: *notification,
+		DeviceToken:  deviceToken,
+		Platform:     data.Platform,
+		Title:        title,
+		Message:      notification.Body,
+		Icon:         data.Icon,
+		Badge:        data.Badge,
+		Sound:        data.Sound,
+		Data:         data.Data,
+		ImageURL:     data.ImageURL,
+		ClickAction:  data.ClickAction,
+
+		CollapseKey:       data.CollapseKey,
+		ThreadID:          data.ThreadID,
+		Category:          data.Category,
+		TTL:               data.TTL,
+		ContentAvailable:  data.ContentAvailable,
+		Silent:            data.Silent,
+		DataOnly:          data.DataOnly,
+		MutableContent:    data.MutableContent,
+		ChannelID:         data.ChannelID,
+		InterruptionLevel: data.InterruptionLevel,
+
+		Actions:            data.Actions,
+		Tag:                data.Tag,
+		Renotify:           data.Renotify,
+		RequireInteraction: data.RequireInteraction,
+		Vibrate:            data.Vibrate,
+
+		Group:        data.Group,
+		GroupMode:    data.GroupMode,
+		GroupSummary: data.GroupSummary,
+		GroupCount:   data.GroupCount,
+	}
+}
+
+// buildChatNotification combines a notification with chat request data
+func buildChatNotification(notification *models.Notification, data *models.ChatData) *models.ChatNotification {
+	// An empty URL sends to the provider's default webhook
+	webhookURL := data.WebhookURL
+	if webhookURL == "" {
+		webhookURL = utils.RecipientWebhookURL(notification.Recipient)
+	}
+
+	title := data.Title
+	if title == "" {
+		title = notification.Subject
+	}
+
+	return &models.ChatNotification{
+		Notification: *notification,
+		WebhookURL:   webhookURL,
+		Title:        title,
+		Text:         notification.Body,
+		Blocks:       data.Blocks,
+		Card:         data.Card,
+		Channel:      data.Channel,
+		Username:     data.Username,
+		IconEmoji:    data.IconEmoji,
+	}
+}
+
+// buildVoiceNotification combines a notification with voice request data
+func buildVoiceNotification(notification *models.Notification, data *models.VoiceData) *models.VoiceNotification {
+	phoneNumber := data.PhoneNumber
+	if phoneNumber == "" {
+		phoneNumber = notification.Recipient
+	}
+
+	return &models.VoiceNotification{
+		Notification: *notification,
+		PhoneNumber:  phoneNumber,
+		CountryCode:  data.CountryCode,
+		Message:      notification.Body,
+		Voice:        data.Voice,
+		Language:     data.Language,
+		Loop:         data.Loop,
+	}
+}
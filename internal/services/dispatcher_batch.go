@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+const (
+	// MaxBatchSize is the number of requests one batch may carry
+	MaxBatchSize = 1000
+
+	// batchChannelConcurrency is the number of requests a batch sends at once
+	// on each channel, so a slow provider holds up only its own channel
+	batchChannelConcurrency = 4
+)
+
+// BatchResult represents the outcome of one request of a batch
+type BatchResult struct {
+	Index    int                          `json:"index"` // of the request in the batch
+	Type     models.NotificationType      `json:"type,omitempty"`
+	Response *models.NotificationResponse `json:"response,omitempty"`
+	Error    string                       `json:"error,omitempty"`
+	Code     errors.ErrorCode             `json:"code,omitempty"`
+}
+
+// BatchResponse aggregates the results of a batch, in the order of its requests
+type BatchResponse struct {
+	Total        int           `json:"total"`
+	SuccessCount int           `json:"success_count"`
+	FailureCount int           `json:"failure_count"`
+	Results      []BatchResult `json:"results"`
+}
+
+// SendBatch sends a batch of requests that may mix channels. Requests are
+// grouped by channel and the groups are sent concurrently, each request
+// through SendNotification. A failed request does not stop the others; its
+// error is reported in its result.
+func (d *Dispatcher) SendBatch(ctx context.Context, requests []*models.NotificationRequest) (*BatchResponse, error) {
+	if len(requests) == 0 {
+		return nil, errors.NewValidationError("requests", "batch must contain at least one request")
+	}
+	if len(requests) > MaxBatchSize {
+		return nil, errors.NewValidationError("requests", fmt.Sprintf("batch of %d requests exceeds the maximum of %d", len(requests), MaxBatchSize))
+	}
+
+	results := make([]BatchResult, len(requests))
+	groups := make(map[models.NotificationType][]int)
+	for i, request := range requests {
+		results[i].Index = i
+		if request == nil {
+			failBatchResult(&results[i], errors.NewValidationError("request", "notification request is required"))
+			continue
+		}
+		results[i].Type = request.Type
+		groups[request.Type] = append(groups[request.Type], i)
+	}
+
+	var wg sync.WaitGroup
+	for _, indexes := range groups {
+		wg.Add(1)
+		go func(indexes []int) {
+			defer wg.Done()
+			d.sendBatchGroup(ctx, requests, indexes, results)
+		}(indexes)
+	}
+	wg.Wait()
+
+	response := &BatchResponse{Total: len(requests), Results: results}
+	for _, result := range results {
+		if result.Response != nil {
+			response.SuccessCount++
+		} else {
+			response.FailureCount++
+		}
+	}
+
+	d.logger.Infof("Sent batch of %d notifications on %d channels: %d succeeded, %d failed",
+		response.Total, len(groups), response.SuccessCount, response.FailureCount)
+	return response, nil
+}
+
+// sendBatchGroup sends the requests of one channel at the indexes, at most
+// batchChannelConcurrency at a time. Each result is written by one goroutine.
+func (d *Dispatcher) sendBatchGroup(ctx context.Context, requests []*models.NotificationRequest, indexes []int, results []BatchResult) {
+	slots := make(chan struct{}, batchChannelConcurrency)
+	var wg sync.WaitGroup
+	for _, i := range indexes {
+		if err := ctx.Err(); err != nil {
+			failBatchResult(&results[i], errors.NewInternalError("batch cancelled", err))
+			continue
+		}
+
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			response, err := d.SendNotification(ctx, requests[i])
+			if err != nil {
+				failBatchResult(&results[i], err)
+				return
+			}
+			results[i].Response = response
+		}(i)
+	}
+	wg.Wait()
+}
+
+// failBatchResult records a request's error in its result
+func failBatchResult(result *BatchResult, err error) {
+	result.Error = err.Error()
+	if notifErr, ok := errors.AsNotificationError(err); ok {
+		result.Code = notifErr.Code
+	}
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestDispatcher_SendBatch(t *testing.T) {
+	dispatcher := createTestDispatcher(t)
+
+	requests := []*models.NotificationRequest{
+		{Type: models.NotificationTypeEmail, Priority: models.PriorityNormal, Recipient: "user@example.com", Subject: "Hello", Body: "Email in a batch"},
+		{Type: models.NotificationTypeSMS, Priority: models.PriorityNormal, Recipient: "+1234567890", Body: "SMS in a batch"},
+		nil,
+		{Type: models.NotificationTypePush, Priority: models.PriorityNormal, Recipient: testIOSToken, Subject: "Hello", Body: "Push in a batch", PushData: &models.PushData{Platform: "ios"}},
+		{Type: models.NotificationTypeVoice, Priority: models.PriorityNormal, Recipient: "+1234567890", Body: "no voice provider", VoiceData: &models.VoiceData{CountryCode: "US"}},
+		{Type: models.NotificationTypeSMS, Priority: models.PriorityNormal, Recipient: "+1234567890", Body: "Another SMS"},
+	}
+
+	response, err := dispatcher.SendBatch(context.Background(), requests)
+	require.NoError(t, err)
+	assert.Equal(t, 6, response.Total)
+	assert.Equal(t, 4, response.SuccessCount)
+	assert.Equal(t, 2, response.FailureCount)
+
+	// Results follow the order of the requests
+	require.Len(t, response.Results, 6)
+	for i, result := range response.Results {
+		assert.Equal(t, i, result.Index)
+	}
+	assert.Equal(t, models.NotificationTypePush, response.Results[3].Type)
+	assert.Equal(t, models.StatusSent, response.Results[3].Response.Status)
+	assert.Equal(t, errors.ErrorCodeValidationFailed, response.Results[2].Code)
+	assert.Equal(t, errors.ErrorCodeProviderNotFound, response.Results[4].Code)
+	assert.Nil(t, response.Results[4].Response)
+
+	notification, err := dispatcher.GetNotificationStatus(context.Background(), response.Results[5].Response.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, "Another SMS", notification.Body)
+}
+
+func TestDispatcher_SendBatch_Errors(t *testing.T) {
+	dispatcher := createTestDispatcher(t)
+
+	_, err := dispatcher.SendBatch(context.Background(), nil)
+	assert.Error(t, err)
+
+	_, err = dispatcher.SendBatch(context.Background(), make([]*models.NotificationRequest, MaxBatchSize+1))
+	assert.Error(t, err)
+
+	// A cancelled batch fails its requests rather than the call
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	response, err := dispatcher.SendBatch(ctx, []*models.NotificationRequest{
+		{Type: models.NotificationTypeSMS, Priority: models.PriorityNormal, Recipient: "+1234567890", Body: "too late"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, response.FailureCount)
+}
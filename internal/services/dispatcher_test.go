@@ -0,0 +1,1330 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/audit"
+	"github.com/nareshkumar-microsoft/notificationService/internal/chaos"
+	"github.com/nareshkumar-microsoft/notificationService/internal/compliance"
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/conversation"
+	"github.com/nareshkumar-microsoft/notificationService/internal/events"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pause"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pipeline"
+	"github.com/nareshkumar-microsoft/notificationService/internal/preferences"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pushgroup"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pushmedia"
+	"github.com/nareshkumar-microsoft/notificationService/internal/ratelimit"
+	"github.com/nareshkumar-microsoft/notificationService/internal/rbac"
+	"github.com/nareshkumar-microsoft/notificationService/internal/repository"
+	"github.com/nareshkumar-microsoft/notificationService/internal/requestlog"
+	"github.com/nareshkumar-microsoft/notificationService/internal/rollout"
+	"github.com/nareshkumar-microsoft/notificationService/internal/routing"
+	"github.com/nareshkumar-microsoft/notificationService/internal/shortlink"
+	"github.com/nareshkumar-microsoft/notificationService/internal/sla"
+	"github.com/nareshkumar-microsoft/notificationService/internal/spamcheck"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+func TestNewDispatcherFromConfig(t *testing.T) {
+	dispatcher := createTestDispatcher(t)
+
+	registered := dispatcher.ListProviders()
+	assert.Len(t, registered, 4)
+	assert.Contains(t, registered, models.NotificationTypeChat)
+}
+
+func TestNewDispatcherFromConfig_SkipsDisabledChannels(t *testing.T) {
+	cfg := createTestProvidersConfig()
+	cfg.Chat.Enabled = false
+	cfg.SMS.Enabled = false
+
+	dispatcher, err := NewDispatcherFromConfig(cfg, repository.NewMemoryRepository(), utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+
+	_, err = dispatcher.GetProvider(models.NotificationTypeChat)
+	require.Error(t, err)
+	assert.Len(t, dispatcher.ListProviders(), 2)
+}
+
+func TestDispatcher_SendNotification_Chat(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	dispatcher := createTestDispatcher(t)
+	ctx := context.Background()
+
+	response, err := dispatcher.SendNotification(ctx, &models.NotificationRequest{
+		Type:      models.NotificationTypeChat,
+		Priority:  models.PriorityHigh,
+		Recipient: server.URL,
+		Subject:   "Deploy",
+		Body:      "Deploy finished",
+		ChatData:  &models.ChatData{Channel: "#releases"},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusSent, response.Status)
+	assert.Equal(t, 1, requests)
+
+	stored, err := dispatcher.GetNotificationStatus(ctx, response.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusSent, stored.Status)
+	assert.NotNil(t, stored.SentAt)
+	assert.Equal(t, response.ProviderID, stored.ProviderMessageID)
+}
+
+func TestDispatcher_SendNotification_ChatDefaultWebhook(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	request := &models.NotificationRequest{
+		Type:      models.NotificationTypeChat,
+		Priority:  models.PriorityNormal,
+		Recipient: "#ops",
+		Body:      "Backup finished",
+	}
+
+	// The recipient names the channel; the configured webhook receives it
+	cfg := createTestProvidersConfig()
+	cfg.Chat.WebhookURL = server.URL
+	dispatcher, err := NewDispatcherFromConfig(cfg, repository.NewMemoryRepository(), utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	_, err = dispatcher.SendNotification(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, 1, requests)
+
+	// Without either webhook the send fails
+	_, err = createTestDispatcher(t).SendNotification(context.Background(), request)
+	require.Error(t, err)
+	assert.Equal(t, 1, requests)
+}
+
+func TestDispatcher_SendNotification_RecordsFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer server.Close()
+
+	repo := repository.NewMemoryRepository()
+	dispatcher, err := NewDispatcherFromConfig(createTestProvidersConfig(), repo, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+
+	_, err = dispatcher.SendNotification(context.Background(), &models.NotificationRequest{
+		Type:      models.NotificationTypeChat,
+		Priority:  models.PriorityNormal,
+		Recipient: server.URL,
+		Body:      "Unreachable",
+	})
+	require.Error(t, err)
+
+	status := models.StatusFailed
+	failed, err := repo.List(context.Background(), interfaces.NotificationFilters{Status: &status})
+	require.NoError(t, err)
+	require.Len(t, failed, 1)
+	assert.NotEmpty(t, failed[0].ErrorMsg)
+}
+
+func TestDispatcher_SendNotification_Push(t *testing.T) {
+	dispatcher := createTestDispatcher(t)
+
+	response, err := dispatcher.SendNotification(context.Background(), &models.NotificationRequest{
+		Type:      models.NotificationTypePush,
+		Priority:  models.PriorityNormal,
+		Recipient: testIOSToken,
+		Subject:   "Hello",
+		Body:      "Push via dispatcher",
+		PushData:  &models.PushData{Platform: "ios"},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusSent, response.Status)
+}
+
+func TestDispatcher_SendNotification_ProviderOptions(t *testing.T) {
+	dispatcher := createTestDispatcher(t)
+	request := &models.NotificationRequest{
+		Type:            models.NotificationTypeSMS,
+		Priority:        models.PriorityNormal,
+		Recipient:       "+15551234567",
+		Body:            "Your order shipped",
+		SMSData:         &models.SMSData{PhoneNumber: "5551234567", CountryCode: "US"},
+		ProviderOptions: map[string]any{"validity_period": float64(600)},
+	}
+
+	_, err := dispatcher.SendNotification(context.Background(), request)
+	require.NoError(t, err)
+	provider, err := dispatcher.GetProvider(models.NotificationTypeSMS)
+	require.NoError(t, err)
+	sent := provider.(*providers.MockSMSProvider).GetSentSMS()
+	require.Len(t, sent, 1)
+	assert.Equal(t, float64(600), sent[0].ProviderOptions["validity_period"])
+
+	// Options the provider does not declare are rejected before sending
+	request.ProviderOptions = map[string]any{"analytics_label": "spring"}
+	_, err = dispatcher.SendNotification(context.Background(), request)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "provider_options.analytics_label")
+	assert.Len(t, provider.(*providers.MockSMSProvider).GetSentSMS(), 1)
+}
+
+func TestDispatcher_SendNotification_Expired(t *testing.T) {
+	dispatcher := createTestDispatcher(t)
+	bus := events.NewBus(utils.NewSimpleLogger("info"))
+	dispatcher.SetEventPublisher(bus)
+
+	var received []events.EventType
+	bus.Subscribe(events.SubscriberFunc(func(ctx context.Context, event events.Event) error {
+		received = append(received, event.Type)
+		return nil
+	}))
+
+	expired := time.Now().Add(-time.Minute)
+	request := &models.NotificationRequest{
+		Type:      models.NotificationTypeSMS,
+		Priority:  models.PriorityHigh,
+		Recipient: "+14155550123",
+		Body:      "Your code is 123456",
+		ExpiresAt: &expired,
+	}
+	response, err := dispatcher.SendNotification(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusExpired, response.Status)
+	assert.Equal(t, []events.EventType{events.EventNotificationExpired}, received)
+
+	stored, err := dispatcher.GetNotificationStatus(context.Background(), response.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusExpired, stored.Status)
+
+	provider, err := dispatcher.GetProvider(models.NotificationTypeSMS)
+	require.NoError(t, err)
+	assert.Empty(t, provider.(*providers.MockSMSProvider).GetSentSMS())
+
+	// The expiry must come after the scheduled time
+	scheduled := expired.Add(time.Hour)
+	request.ScheduledAt = &scheduled
+	_, err = dispatcher.SendNotification(context.Background(), request)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, "expires_at", notifErr.Metadata["field"])
+}
+
+func TestDispatcher_SendNotification_Errors(t *testing.T) {
+	dispatcher := NewDispatcher(repository.NewMemoryRepository(), utils.NewSimpleLogger("info"))
+
+	// Invalid request
+	_, err := dispatcher.SendNotification(context.Background(), &models.NotificationRequest{
+		Type:     models.NotificationTypeChat,
+		Priority: models.PriorityNormal,
+		Body:     "missing recipient",
+	})
+	require.Error(t, err)
+
+	// No provider registered
+	_, err = dispatcher.SendNotification(context.Background(), &models.NotificationRequest{
+		Type:      models.NotificationTypeChat,
+		Priority:  models.PriorityNormal,
+		Recipient: "https://hooks.example.com/x",
+		Body:      "no provider",
+	})
+	require.Error(t, err)
+
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeProviderNotFound, notifErr.Code)
+}
+
+func TestDispatcher_PauseController(t *testing.T) {
+	dispatcher := createTestDispatcher(t)
+	assert.Equal(t, "slack", dispatcher.ProviderName(models.NotificationTypeChat))
+	assert.Equal(t, "mock", dispatcher.ProviderName(models.NotificationTypeEmail))
+	assert.Equal(t, "", dispatcher.ProviderName(models.NotificationTypeVoice))
+
+	controller, err := pause.NewController(config.PauseConfig{}, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	dispatcher.SetPauseController(controller)
+
+	bus := events.NewBus(utils.NewSimpleLogger("info"))
+	dispatcher.SetEventPublisher(bus)
+
+	var rejected []events.Event
+	bus.Subscribe(events.SubscriberFunc(func(ctx context.Context, event events.Event) error {
+		rejected = append(rejected, event)
+		return nil
+	}), events.EventNotificationRejected)
+
+	_, err = controller.PauseProvider("slack", "webhook outage")
+	require.NoError(t, err)
+
+	request := &models.NotificationRequest{
+		Type:      models.NotificationTypeChat,
+		Priority:  models.PriorityNormal,
+		Recipient: "https://hooks.example.com/x",
+		Body:      "paused",
+	}
+	_, err = dispatcher.SendNotification(context.Background(), request)
+	require.Error(t, err)
+	assert.True(t, pause.IsPausedError(err))
+	assert.Len(t, rejected, 1)
+
+	// Other channels keep sending
+	_, err = dispatcher.SendNotification(context.Background(), &models.NotificationRequest{
+		Type:      models.NotificationTypeEmail,
+		Priority:  models.PriorityNormal,
+		Recipient: "user@example.com",
+		Subject:   "Hello",
+		Body:      "not paused",
+	})
+	require.NoError(t, err)
+
+	controller.ResumeProvider("slack")
+	_, err = dispatcher.SendNotification(context.Background(), request)
+	assert.False(t, pause.IsPausedError(err))
+}
+
+func TestDispatcher_SendNotification_EmailHeaders(t *testing.T) {
+	dispatcher := createTestDispatcher(t)
+	request := &models.NotificationRequest{
+		Type:      models.NotificationTypeEmail,
+		Priority:  models.PriorityNormal,
+		Recipient: "user@example.com",
+		Subject:   "Re: Hello",
+		Body:      "follow-up",
+		EmailData: &models.EmailData{
+			From:      "alerts@example.com",
+			Headers:   map[string]string{"X-Campaign-ID": "spring"},
+			InReplyTo: "<first@example.com>",
+		},
+	}
+
+	response, err := dispatcher.SendNotification(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("<%s@example.com>", response.ID), response.ProviderMetadata["internet_message_id"])
+
+	request.EmailData.Headers = map[string]string{"Return-Path": "<bounce@attacker.example>"}
+	_, err = dispatcher.SendNotification(context.Background(), request)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, "headers", notifErr.Metadata["field"])
+}
+
+func TestDispatcher_SetPreferences(t *testing.T) {
+	dispatcher := createTestDispatcher(t)
+
+	store := preferences.NewStore()
+	require.NoError(t, store.OptOut("user@example.com", models.NotificationTypeEmail, models.CategoryMarketing))
+	enforcer := preferences.NewEnforcer(store, utils.NewSimpleLogger("info"))
+	defer enforcer.Stop()
+
+	require.NoError(t, dispatcher.SetPreferences(enforcer))
+	assert.Contains(t, dispatcher.Middleware(), preferences.MiddlewareName)
+	assert.Error(t, dispatcher.SetPreferences(enforcer)) // already registered
+
+	request := &models.NotificationRequest{
+		Type:      models.NotificationTypeEmail,
+		Priority:  models.PriorityNormal,
+		Recipient: "user@example.com",
+		Subject:   "Spring sale",
+		Body:      "The sale is on",
+		Category:  models.CategoryMarketing,
+	}
+	_, err := dispatcher.SendNotification(context.Background(), request)
+	require.Error(t, err)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeRecipientOptedOut, notifErr.Code)
+
+	// Security notifications bypass opt-outs
+	request.Category = models.CategorySecurity
+	response, err := dispatcher.SendNotification(context.Background(), request)
+	require.NoError(t, err)
+
+	stored, err := dispatcher.GetNotificationStatus(context.Background(), response.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.CategorySecurity, stored.Category)
+}
+
+func TestDispatcher_SetCompliance(t *testing.T) {
+	dispatcher := createTestDispatcher(t)
+
+	consent := compliance.NewConsentRecords("US")
+	consent.Grant("+14155550123", "signup-form")
+	require.NoError(t, dispatcher.SetCompliance(compliance.NewGuard(utils.NewSimpleLogger("info"), consent)))
+	assert.Contains(t, dispatcher.Middleware(), compliance.MiddlewareName)
+
+	request := &models.NotificationRequest{
+		Type:      models.NotificationTypeSMS,
+		Priority:  models.PriorityNormal,
+		Recipient: "+14155550199",
+		Body:      "The sale is on",
+		Category:  models.CategoryMarketing,
+	}
+	_, err := dispatcher.SendNotification(context.Background(), request)
+	require.Error(t, err)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeRecipientOptedOut, notifErr.Code)
+
+	// The stored notification records the decision
+	request.Recipient = "+14155550123"
+	response, err := dispatcher.SendNotification(context.Background(), request)
+	require.NoError(t, err)
+
+	stored, err := dispatcher.GetNotificationStatus(context.Background(), response.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, "allowed", stored.Metadata[compliance.MetadataDecision])
+	assert.Equal(t, "consent", stored.Metadata[compliance.MetadataCheckedBy])
+}
+
+func TestDispatcher_SetShortLinks(t *testing.T) {
+	dispatcher := createTestDispatcher(t)
+
+	links, err := shortlink.NewService("https://sms.example.com/s/")
+	require.NoError(t, err)
+	require.NoError(t, dispatcher.SetShortLinks(links))
+	assert.Contains(t, dispatcher.Middleware(), shortlink.MiddlewareName)
+
+	response, err := dispatcher.SendNotification(context.Background(), &models.NotificationRequest{
+		Type:      models.NotificationTypeSMS,
+		Priority:  models.PriorityNormal,
+		Recipient: "+14155550123",
+		Body:      "Track your order: https://shop.example.com/orders/12345/tracking?utm_source=sms",
+	})
+	require.NoError(t, err)
+
+	sent := links.Links(response.ID.String())
+	require.Len(t, sent, 1)
+	assert.Equal(t, "https://shop.example.com/orders/12345/tracking?utm_source=sms", sent[0].URL)
+
+	stored, err := dispatcher.GetNotificationStatus(context.Background(), response.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, "Track your order: "+sent[0].ShortURL, stored.Body)
+}
+
+func TestDispatcher_SetConversations(t *testing.T) {
+	dispatcher := createTestDispatcher(t)
+
+	store := conversation.NewStore(config.ConversationConfig{ServiceNumber: "+14155550100"})
+	require.NoError(t, dispatcher.SetConversations(store))
+	assert.Contains(t, dispatcher.Middleware(), conversation.MiddlewareName)
+
+	response, err := dispatcher.SendNotification(context.Background(), &models.NotificationRequest{
+		Type:         models.NotificationTypeSMS,
+		Priority:     models.PriorityNormal,
+		Recipient:    "+14155550123",
+		Body:         "Your code is {{code}}",
+		TemplateData: map[string]string{"code": "123456"},
+	})
+	require.NoError(t, err)
+
+	conversations := store.ForParticipant("+14155550123")
+	require.Len(t, conversations, 1)
+	require.Len(t, conversations[0].Messages, 1)
+	assert.Equal(t, "Your code is 123456", conversations[0].Messages[0].Body)
+	assert.Equal(t, response.ID.String(), conversations[0].Messages[0].NotificationID)
+
+	// Other channels are not recorded
+	_, err = dispatcher.SendNotification(context.Background(), &models.NotificationRequest{
+		Type:      models.NotificationTypeEmail,
+		Priority:  models.PriorityNormal,
+		Recipient: "user@example.com",
+		Subject:   "Hello",
+		Body:      "Hello",
+	})
+	require.NoError(t, err)
+	assert.Empty(t, store.ForParticipant("user@example.com"))
+}
+
+func TestDispatcher_SetPushGroups(t *testing.T) {
+	dispatcher := createTestDispatcher(t)
+
+	grouper := pushgroup.NewGrouper(config.PushGroupConfig{})
+	require.NoError(t, dispatcher.SetPushGroups(grouper))
+	assert.Contains(t, dispatcher.Middleware(), pushgroup.MiddlewareName)
+
+	request := &models.NotificationRequest{
+		Type:      models.NotificationTypePush,
+		Priority:  models.PriorityNormal,
+		Recipient: testIOSToken,
+		Subject:   "New message",
+		Body:      "Alex: are you coming?",
+		PushData:  &models.PushData{Platform: "ios", Group: "chat-alex", GroupSummary: "{{count}} messages from Alex"},
+	}
+	for i := 0; i < 3; i++ {
+		_, err := dispatcher.SendNotification(context.Background(), request)
+		require.NoError(t, err)
+	}
+
+	provider, err := dispatcher.GetProvider(models.NotificationTypePush)
+	require.NoError(t, err)
+	sent := provider.(*providers.MockPushProvider).GetSentPush()
+	require.Len(t, sent, 3)
+	aps := sent[2].Payload.Body["aps"].(map[string]interface{})
+	assert.Equal(t, "chat-alex", aps["thread-id"])
+	assert.Equal(t, "3 messages from Alex", aps["alert"].(map[string]interface{})["summary-arg"])
+}
+
+func TestDispatcher_SetPushMedia(t *testing.T) {
+	dispatcher := createTestDispatcher(t)
+	require.NoError(t, dispatcher.SetPushMedia(pushmedia.NewChecker(config.PushMediaConfig{}, nil)))
+	assert.Contains(t, dispatcher.Middleware(), pushmedia.MiddlewareName)
+
+	_, err := dispatcher.SendNotification(context.Background(), &models.NotificationRequest{
+		Type:      models.NotificationTypePush,
+		Priority:  models.PriorityNormal,
+		Recipient: testIOSToken,
+		Subject:   "Hello",
+		Body:      "Push with an image",
+		PushData:  &models.PushData{Platform: "ios", ImageURL: "http://example.com/photo.png"},
+	})
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, "image_url", notifErr.Metadata["field"])
+
+	provider, err := dispatcher.GetProvider(models.NotificationTypePush)
+	require.NoError(t, err)
+	assert.Empty(t, provider.(*providers.MockPushProvider).GetSentPush())
+}
+
+func TestDispatcher_SetSpamCheck(t *testing.T) {
+	dispatcher := createTestDispatcher(t)
+	scorer := &testSpamScorer{}
+	checker := spamcheck.NewChecker(scorer, config.SpamCheckConfig{Threshold: 5, Block: true}, utils.NewSimpleLogger("info"))
+	require.NoError(t, dispatcher.SetSpamCheck(checker))
+	assert.Contains(t, dispatcher.Middleware(), spamcheck.MiddlewareName)
+
+	request := &models.NotificationRequest{
+		Type:         models.NotificationTypeEmail,
+		Priority:     models.PriorityNormal,
+		Category:     models.CategoryMarketing,
+		Recipient:    "jane@example.com",
+		Subject:      "A deal for {{name}}",
+		Body:         "Save 10% today",
+		TemplateData: map[string]string{"name": "Jane"},
+	}
+	response, err := dispatcher.SendNotification(context.Background(), request)
+	require.NoError(t, err)
+	// Templates are rendered before scoring
+	assert.Contains(t, string(scorer.message), "Subject: A deal for Jane")
+
+	notification, err := dispatcher.GetNotificationStatus(context.Background(), response.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, "1.0", notification.Metadata[spamcheck.MetadataSpamScore])
+
+	request.Body = "FREE MONEY!!! Click now"
+	_, err = dispatcher.SendNotification(context.Background(), request)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeInvalidNotification, notifErr.Code)
+	assert.Equal(t, "9.0", notifErr.Metadata[spamcheck.MetadataSpamScore])
+}
+
+func TestDispatcher_SetEmailRouting(t *testing.T) {
+	dispatcher := createTestDispatcher(t)
+	marketing := providers.NewMockEmailProvider(config.EmailProviderConfig{Provider: "mock", Enabled: true})
+	require.NoError(t, dispatcher.RegisterNamedProvider("bulk", marketing))
+
+	router, err := routing.NewRouter(config.EmailRoutingConfig{Routes: []config.EmailRoute{
+		{Name: "otp", Categories: []string{"transactional", "security"}, FromDomain: "mail.example.com"},
+		{Name: "campaigns", Categories: []string{"marketing"}, Provider: "bulk", FromDomain: "news.example.com"},
+	}}, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	require.NoError(t, dispatcher.SetEmailRouting(router))
+	assert.Contains(t, dispatcher.Middleware(), routing.MiddlewareName)
+
+	request := &models.NotificationRequest{
+		Type:      models.NotificationTypeEmail,
+		Priority:  models.PriorityNormal,
+		Category:  models.CategoryMarketing,
+		Recipient: "jane@example.com",
+		Subject:   "Spring sale",
+		Body:      "Save 10% today",
+		EmailData: &models.EmailData{From: "deals@example.com"},
+	}
+	response, err := dispatcher.SendNotification(context.Background(), request)
+	require.NoError(t, err)
+
+	sent := marketing.GetSentEmails()
+	require.Len(t, sent, 1)
+	assert.Equal(t, "deals@news.example.com", sent[0].From)
+
+	notification, err := dispatcher.GetNotificationStatus(context.Background(), response.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, "campaigns", notification.Metadata[routing.MetadataRoute])
+
+	// Transactional email keeps the default provider
+	request.Category = models.CategoryTransactional
+	_, err = dispatcher.SendNotification(context.Background(), request)
+	require.NoError(t, err)
+	assert.Len(t, marketing.GetSentEmails(), 1)
+
+	reputations := router.Reputations()
+	assert.Equal(t, 1, reputations[0].Sent)
+	assert.Equal(t, 1, reputations[1].Sent)
+}
+
+func TestDispatcher_NamedProviderMismatch(t *testing.T) {
+	dispatcher := createTestDispatcher(t)
+	require.NoError(t, dispatcher.RegisterNamedProvider("bulk", providers.NewMockEmailProvider(config.EmailProviderConfig{Provider: "mock", Enabled: true})))
+
+	_, err := dispatcher.SendNotification(context.Background(), &models.NotificationRequest{
+		Type:      models.NotificationTypeSMS,
+		Priority:  models.PriorityNormal,
+		Recipient: "+14155550100",
+		Body:      "Your code is 123456",
+		Metadata:  map[string]string{routing.MetadataProvider: "bulk"},
+	})
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeProviderNotFound, notifErr.Code)
+}
+
+func TestDispatcher_SetRoutingRules(t *testing.T) {
+	dispatcher := createTestDispatcher(t)
+	bulk := providers.NewMockSMSProvider(config.SMSProviderConfig{Provider: "mock", Enabled: true})
+	require.NoError(t, dispatcher.RegisterNamedProvider("bulk-sms", bulk))
+
+	engine, err := routing.NewEngine([]config.RoutingRule{
+		{Name: "urgent", Match: config.RuleMatch{Priorities: []string{"urgent"}}, Provider: "mock"},
+		{
+			Name:      "uk-marketing",
+			Match:     config.RuleMatch{Types: []string{"sms"}, Countries: []string{"uk"}, Categories: []string{"marketing"}},
+			Provider:  "bulk-sms",
+			Sender:    "ACME",
+			RateLimit: &config.RateLimit{PerSecond: 0.001, Burst: 1},
+		},
+	}, ratelimit.NewLocalStore(), utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	require.NoError(t, dispatcher.SetRoutingRules(engine))
+	assert.Contains(t, dispatcher.Middleware(), routing.RulesMiddlewareName)
+	assert.Contains(t, dispatcher.Middleware(), routing.RuleLimitsMiddlewareName)
+
+	request := &models.NotificationRequest{
+		Type:      models.NotificationTypeSMS,
+		Priority:  models.PriorityNormal,
+		Category:  models.CategoryMarketing,
+		Recipient: "+447911123456",
+		Body:      "Spring sale: 10% off",
+		SMSData:   &models.SMSData{},
+	}
+	response, err := dispatcher.SendNotification(context.Background(), request)
+	require.NoError(t, err)
+
+	sent := bulk.GetSentSMS()
+	require.Len(t, sent, 1)
+	assert.Equal(t, "ACME", sent[0].SenderID)
+	notification, err := dispatcher.GetNotificationStatus(context.Background(), response.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, "uk-marketing", notification.Metadata[routing.MetadataRule])
+
+	// The rule's rate limit is shared by the sends it matches
+	_, err = dispatcher.SendNotification(context.Background(), request)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeRateLimited, notifErr.Code)
+
+	// Earlier rules win, and the default provider can be named
+	request.Priority = models.PriorityUrgent
+	_, err = dispatcher.SendNotification(context.Background(), request)
+	require.NoError(t, err)
+	assert.Len(t, bulk.GetSentSMS(), 1)
+}
+
+func TestDispatcher_SetRolloutController(t *testing.T) {
+	dispatcher := createTestDispatcher(t)
+	canary := providers.NewMockSMSProvider(config.SMSProviderConfig{Provider: "mock", Enabled: true})
+	canary.SetSimulation(providers.Simulation{})
+	require.NoError(t, dispatcher.RegisterNamedProvider("twilio-v2", canary))
+
+	controller, err := rollout.NewController(config.RolloutConfig{
+		Rollouts: []config.ProviderRollout{{Channel: "sms", Provider: "twilio-v2", Percent: 100}},
+		MinSends: 2,
+	}, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	dispatcher.SetRolloutController(controller)
+
+	request := &models.NotificationRequest{
+		Type:      models.NotificationTypeSMS,
+		Priority:  models.PriorityNormal,
+		Recipient: "+14155550100",
+		Body:      "Your code is 123456",
+		SMSData:   &models.SMSData{},
+	}
+	response, err := dispatcher.SendNotification(context.Background(), request)
+	require.NoError(t, err)
+	assert.Len(t, canary.GetSentSMS(), 1)
+	notification, err := dispatcher.GetNotificationStatus(context.Background(), response.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, rollout.ArmCanary, notification.Metadata[rollout.MetadataArm])
+
+	// A failing canary is rolled back, and later sends use the incumbent
+	canary.SetSimulation(providers.Simulation{Rules: []providers.FailureRule{{Code: errors.ErrorCodeProviderUnavailable}}})
+	_, err = dispatcher.SendNotification(context.Background(), request)
+	require.Error(t, err)
+	status, _ := controller.Status(models.NotificationTypeSMS)
+	assert.Equal(t, rollout.StateRolledBack, status.State)
+
+	_, err = dispatcher.SendNotification(context.Background(), request)
+	require.NoError(t, err)
+	assert.Len(t, canary.GetSentSMS(), 1)
+}
+
+func TestDispatcher_SetSLAMonitor(t *testing.T) {
+	dispatcher := createTestDispatcher(t)
+	provider := providers.NewMockSMSProvider(config.SMSProviderConfig{Provider: "mock", Enabled: true})
+	provider.SetSimulation(providers.Simulation{})
+	require.NoError(t, dispatcher.RegisterProvider(provider))
+
+	monitor := sla.NewMonitor(config.SLAConfig{
+		MinSamples: 3,
+		Objectives: config.SLO{MinSuccessRate: 0.9},
+	}, utils.NewSimpleLogger("info"))
+	dispatcher.SetSLAMonitor(monitor)
+
+	request := &models.NotificationRequest{
+		Type:      models.NotificationTypeSMS,
+		Priority:  models.PriorityNormal,
+		Recipient: "+14155550100",
+		Body:      "Your code is 123456",
+		SMSData:   &models.SMSData{},
+	}
+	_, err := dispatcher.SendNotification(context.Background(), request)
+	require.NoError(t, err)
+	require.NoError(t, dispatcher.HealthCheck(context.Background())[models.NotificationTypeSMS])
+
+	provider.SetSimulation(providers.Simulation{Rules: []providers.FailureRule{{Code: errors.ErrorCodeProviderUnavailable}}})
+	for i := 0; i < 2; i++ {
+		_, err = dispatcher.SendNotification(context.Background(), request)
+		require.Error(t, err)
+	}
+
+	stats, exists := monitor.Provider(models.NotificationTypeSMS, "mock")
+	require.True(t, exists)
+	assert.Equal(t, int64(3), stats.Sends)
+	assert.Equal(t, int64(2), stats.Failures)
+	assert.True(t, stats.Degraded)
+
+	err = dispatcher.HealthCheck(context.Background())[models.NotificationTypeSMS]
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "mock is degraded")
+}
+
+func TestDispatcher_SetChaosInjector(t *testing.T) {
+	dispatcher := createTestDispatcher(t)
+	provider := providers.NewMockSMSProvider(config.SMSProviderConfig{Provider: "mock", Enabled: true})
+	provider.SetSimulation(providers.Simulation{})
+	require.NoError(t, dispatcher.RegisterProvider(provider))
+
+	injector, err := chaos.NewInjector(&chaos.Scenario{Faults: []chaos.Fault{
+		{Name: "outage", Channels: []string{"sms"}, Providers: []string{"mock"}, Action: chaos.ActionError, Times: 1},
+	}}, 1, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	dispatcher.SetChaosInjector(injector)
+
+	request := &models.NotificationRequest{
+		Type:      models.NotificationTypeSMS,
+		Priority:  models.PriorityNormal,
+		Recipient: "+14155550100",
+		Body:      "Your code is 123456",
+		SMSData:   &models.SMSData{},
+	}
+	_, err = dispatcher.SendNotification(context.Background(), request)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeProviderUnavailable, notifErr.Code)
+	assert.Empty(t, provider.GetSentSMS())
+
+	// The failed notification is stored, so it can be retried
+	notifications, err := dispatcher.repository.List(context.Background(), interfaces.NotificationFilters{Recipient: "+14155550100", Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, models.StatusFailed, notifications[0].Status)
+
+	_, err = dispatcher.SendNotification(context.Background(), request)
+	require.NoError(t, err)
+	assert.Len(t, provider.GetSentSMS(), 1)
+}
+
+func TestDispatcher_SetRateLimiter(t *testing.T) {
+	dispatcher := createTestDispatcher(t)
+	limiter, err := ratelimit.NewLimiter(config.RateLimitConfig{
+		Providers: map[string]config.RateLimit{dispatcher.ProviderName(models.NotificationTypeSMS): {PerSecond: 0.001, Burst: 1}},
+	}, ratelimit.NewLocalStore(), utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	require.NoError(t, dispatcher.SetRateLimiter(limiter))
+	assert.Contains(t, dispatcher.Middleware(), ratelimit.MiddlewareName)
+
+	request := &models.NotificationRequest{
+		Type:      models.NotificationTypeSMS,
+		Priority:  models.PriorityNormal,
+		Recipient: "+14155552671",
+		Body:      "Your code is 1234",
+	}
+	_, err = dispatcher.SendNotification(context.Background(), request)
+	require.NoError(t, err)
+
+	_, err = dispatcher.SendNotification(context.Background(), request)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeRateLimited, notifErr.Code)
+	assert.NotEmpty(t, notifErr.Metadata["retry_after"])
+}
+
+func TestDispatcher_RateLimitsRoutedProvider(t *testing.T) {
+	dispatcher := createTestDispatcher(t)
+	canary := providers.NewMockSMSProvider(config.SMSProviderConfig{Provider: "mock", Enabled: true})
+	canary.SetSimulation(providers.Simulation{})
+	require.NoError(t, dispatcher.RegisterNamedProvider("twilio-v2", canary))
+	controller, err := rollout.NewController(config.RolloutConfig{
+		Rollouts: []config.ProviderRollout{{Channel: "sms", Provider: "twilio-v2", Percent: 100}},
+	}, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	dispatcher.SetRolloutController(controller)
+
+	limiter, err := ratelimit.NewLimiter(config.RateLimitConfig{
+		Providers: map[string]config.RateLimit{"twilio-v2": {PerSecond: 0.001, Burst: 1}},
+		Tenants:   map[string]config.RateLimit{"acme": {PerSecond: 0.001, Burst: 2}},
+	}, ratelimit.NewLocalStore(), utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	require.NoError(t, dispatcher.SetRateLimiter(limiter))
+
+	request := &models.NotificationRequest{
+		Type:      models.NotificationTypeSMS,
+		Priority:  models.PriorityNormal,
+		Recipient: "+14155552671",
+		Body:      "Your code is 1234",
+		SMSData:   &models.SMSData{},
+	}
+	ctx := rbac.WithIdentity(context.Background(), &rbac.Identity{Subject: "svc", TenantID: "acme"})
+	_, err = dispatcher.SendNotification(ctx, request)
+	require.NoError(t, err)
+
+	// The limit is the canary's, the provider the rollout sends through
+	_, err = dispatcher.SendNotification(ctx, request)
+	assert.ErrorContains(t, err, "provider twilio-v2")
+	assert.Len(t, canary.GetSentSMS(), 1)
+
+	// The tenant's tokens were taken before the provider's
+	_, err = dispatcher.SendNotification(ctx, request)
+	assert.ErrorContains(t, err, "tenant acme")
+
+	assert.True(t, dispatcher.RemoveMiddleware(ratelimit.MiddlewareName))
+	_, err = dispatcher.SendNotification(ctx, request)
+	assert.NoError(t, err)
+}
+
+func TestDispatcher_SetCorrelationIDs(t *testing.T) {
+	dispatcher := createTestDispatcher(t)
+	require.NoError(t, dispatcher.SetCorrelationIDs())
+	assert.Contains(t, dispatcher.Middleware(), requestlog.MiddlewareName)
+
+	ctx := requestlog.WithCorrelationID(context.Background(), "req-42")
+	response, err := dispatcher.SendNotification(ctx, &models.NotificationRequest{
+		Type:      models.NotificationTypeSMS,
+		Priority:  models.PriorityNormal,
+		Recipient: "+14155552671",
+		Body:      "Your code is 1234",
+	})
+	require.NoError(t, err)
+
+	notification, err := dispatcher.GetNotificationStatus(ctx, response.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, "req-42", notification.CorrelationID)
+}
+
+func TestDispatcher_HealthCheck(t *testing.T) {
+	dispatcher := createTestDispatcher(t)
+
+	results := dispatcher.HealthCheck(context.Background())
+	assert.Len(t, results, 4)
+	for notificationType, err := range results {
+		assert.NoError(t, err, notificationType)
+	}
+}
+
+func TestDispatcher_PublishesEvents(t *testing.T) {
+	failing := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	dispatcher := createTestDispatcher(t)
+	bus := events.NewBus(utils.NewSimpleLogger("info"))
+	dispatcher.SetEventPublisher(bus)
+
+	var received []events.Event
+	bus.Subscribe(events.SubscriberFunc(func(ctx context.Context, event events.Event) error {
+		received = append(received, event)
+		return nil
+	}))
+
+	ctx := context.Background()
+	_, err := dispatcher.SendNotification(ctx, &models.NotificationRequest{
+		Type:      models.NotificationTypeChat,
+		Priority:  models.PriorityHigh,
+		Recipient: server.URL,
+		Body:      "Deploy failed",
+	})
+	require.Error(t, err)
+	require.Len(t, received, 2)
+	assert.Equal(t, events.EventNotificationQueued, received[0].Type)
+	assert.Equal(t, events.EventNotificationFailed, received[1].Type)
+	assert.NotEmpty(t, received[1].Error)
+	notificationID := received[0].NotificationID.String()
+
+	// A failed notification can be retried
+	failing = false
+	response, err := dispatcher.RetryNotification(ctx, notificationID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusSent, response.Status)
+	require.Len(t, received, 4)
+	assert.Equal(t, events.EventNotificationRetried, received[2].Type)
+	assert.Equal(t, 2, received[2].Attempt)
+	assert.Equal(t, events.EventNotificationSent, received[3].Type)
+
+	// A sent notification cannot be retried, but can be marked delivered
+	_, err = dispatcher.RetryNotification(ctx, notificationID)
+	require.Error(t, err)
+
+	require.NoError(t, dispatcher.MarkDelivered(ctx, notificationID))
+	require.NoError(t, dispatcher.MarkDelivered(ctx, notificationID)) // already delivered
+	require.Len(t, received, 5)
+	assert.Equal(t, events.EventNotificationDelivered, received[4].Type)
+
+	stored, err := dispatcher.GetNotificationStatus(ctx, notificationID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusDelivered, stored.Status)
+	assert.Equal(t, 1, stored.RetryCount)
+	assert.NotNil(t, stored.DeliveredAt)
+}
+
+func TestDispatcher_RetryNotification_RecordedProvider(t *testing.T) {
+	dispatcher := createTestDispatcher(t)
+	canary := providers.NewMockSMSProvider(config.SMSProviderConfig{Provider: "mock", Enabled: true})
+	canary.SetSimulation(providers.Simulation{Rules: []providers.FailureRule{{Code: errors.ErrorCodeProviderUnavailable}}})
+	require.NoError(t, dispatcher.RegisterNamedProvider("twilio-v2", canary))
+	controller, err := rollout.NewController(config.RolloutConfig{
+		Rollouts: []config.ProviderRollout{{Channel: "sms", Provider: "twilio-v2", Percent: 100}},
+	}, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	dispatcher.SetRolloutController(controller)
+	ctx := context.Background()
+
+	_, err = dispatcher.SendNotification(ctx, &models.NotificationRequest{
+		Type:       models.NotificationTypeSMS,
+		Priority:   models.PriorityNormal,
+		Recipient:  "+14155550100",
+		Body:       "Your code is 123456",
+		MaxRetries: 2,
+	})
+	require.Error(t, err)
+	failed := models.StatusFailed
+	stored, err := dispatcher.repository.List(ctx, interfaces.NotificationFilters{Status: &failed})
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+
+	// The retry goes through the canary that sent it, not the channel's default
+	canary.SetSimulation(providers.Simulation{})
+	_, err = dispatcher.RetryNotification(ctx, stored[0].ID.String())
+	require.NoError(t, err)
+	assert.Len(t, canary.GetSentSMS(), 1)
+}
+
+func TestDispatcher_RetryNotification_Exhausted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	dispatcher := createTestDispatcher(t)
+	ctx := context.Background()
+
+	_, err := dispatcher.SendNotification(ctx, &models.NotificationRequest{
+		Type:       models.NotificationTypeChat,
+		Priority:   models.PriorityNormal,
+		Recipient:  server.URL,
+		Body:       "Flaky",
+		MaxRetries: 1,
+	})
+	require.Error(t, err)
+
+	failed := models.StatusFailed
+	stored, err := dispatcher.repository.List(ctx, interfaces.NotificationFilters{Status: &failed})
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	id := stored[0].ID.String()
+
+	_, err = dispatcher.RetryNotification(ctx, id)
+	require.Error(t, err)
+
+	// Retries used up
+	_, err = dispatcher.RetryNotification(ctx, id)
+	require.Error(t, err)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeValidationFailed, notifErr.Code)
+
+	// Failed notifications cannot be marked delivered
+	assert.Error(t, dispatcher.MarkDelivered(ctx, id))
+}
+
+func TestDispatcher_AcknowledgeNotification(t *testing.T) {
+	dispatcher := createTestDispatcher(t)
+	bus := events.NewBus(utils.NewSimpleLogger("info"))
+	dispatcher.SetEventPublisher(bus)
+
+	var received []events.EventType
+	bus.Subscribe(events.SubscriberFunc(func(ctx context.Context, event events.Event) error {
+		received = append(received, event.Type)
+		return nil
+	}), events.EventNotificationDelivered, events.EventNotificationAcknowledged)
+
+	ctx := context.Background()
+	response, err := dispatcher.SendNotification(ctx, &models.NotificationRequest{
+		Type:      models.NotificationTypeSMS,
+		Priority:  models.PriorityNormal,
+		Recipient: "+1234567890",
+		Body:      "Your table is ready",
+	})
+	require.NoError(t, err)
+
+	seen := time.Now().Add(-time.Minute)
+	notification, err := dispatcher.AcknowledgeNotification(ctx, response.ID.String(), &models.Acknowledgement{Source: models.AcknowledgeSourcePush, At: &seen})
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusDelivered, notification.Status)
+	assert.Equal(t, models.AcknowledgeSourcePush, notification.AcknowledgedVia)
+	assert.True(t, seen.Equal(*notification.AcknowledgedAt))
+	assert.Equal(t, []events.EventType{events.EventNotificationDelivered, events.EventNotificationAcknowledged}, received)
+
+	// Acknowledging again changes nothing
+	_, err = dispatcher.AcknowledgeNotification(ctx, response.ID.String(), nil)
+	require.NoError(t, err)
+	assert.Len(t, received, 2)
+	stored, err := dispatcher.GetNotificationStatus(ctx, response.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.AcknowledgeSourcePush, stored.AcknowledgedVia)
+
+	_, err = dispatcher.AcknowledgeNotification(ctx, uuid.New().String(), nil)
+	assert.Error(t, err)
+
+	response, err = dispatcher.SendNotification(ctx, &models.NotificationRequest{
+		Type:      models.NotificationTypeSMS,
+		Priority:  models.PriorityNormal,
+		Recipient: "+1234567890",
+		Body:      "Your order shipped",
+	})
+	require.NoError(t, err)
+	future := time.Now().Add(time.Hour)
+	_, err = dispatcher.AcknowledgeNotification(ctx, response.ID.String(), &models.Acknowledgement{At: &future})
+	assert.Error(t, err)
+}
+
+func TestDispatcher_ResendNotification(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer failing.Close()
+
+	var received string
+	working := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer working.Close()
+
+	dispatcher := createTestDispatcher(t)
+	ctx := context.Background()
+
+	_, err := dispatcher.SendNotification(ctx, &models.NotificationRequest{
+		Type:         models.NotificationTypeChat,
+		Priority:     models.PriorityHigh,
+		Recipient:    failing.URL,
+		Body:         "Build {{build}} finished",
+		TemplateData: map[string]string{"build": "41", "branch": "main"},
+		Metadata:     map[string]string{"team": "platform"},
+	})
+	require.Error(t, err)
+
+	failed := models.StatusFailed
+	stored, err := dispatcher.repository.List(ctx, interfaces.NotificationFilters{Status: &failed})
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	original := stored[0]
+	assert.Equal(t, "Build 41 finished", original.Body)
+	assert.Equal(t, "Build {{build}} finished", original.BodyTemplate)
+
+	response, err := dispatcher.ResendNotification(ctx, original.ID.String(), &ResendOptions{
+		Recipient:    working.URL,
+		TemplateData: map[string]string{"build": "42"},
+	})
+	require.NoError(t, err)
+	assert.NotEqual(t, original.ID, response.ID)
+	assert.Contains(t, received, "Build 42 finished")
+
+	resent, err := dispatcher.GetNotificationStatus(ctx, response.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusSent, resent.Status)
+	assert.Equal(t, models.PriorityHigh, resent.Priority)
+	assert.Equal(t, working.URL, resent.Recipient)
+	assert.Equal(t, "Build 42 finished", resent.Body)
+	assert.Equal(t, map[string]string{"build": "42", "branch": "main"}, resent.TemplateData)
+	assert.Equal(t, original.ID.String(), resent.Metadata[MetadataResentFrom])
+	assert.Equal(t, "platform", resent.Metadata["team"])
+
+	// The original is left untouched
+	unchanged, err := dispatcher.GetNotificationStatus(ctx, original.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusFailed, unchanged.Status)
+	assert.Equal(t, failing.URL, unchanged.Recipient)
+}
+
+func TestDispatcher_ResendNotification_InternalMetadata(t *testing.T) {
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer webhook.Close()
+
+	repo := repository.NewMemoryRepository()
+	dispatcher, err := NewDispatcherFromConfig(createTestProvidersConfig(), repo, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// Routed through a provider that has since been removed
+	original := &models.Notification{
+		ID:        uuid.New(),
+		Type:      models.NotificationTypeChat,
+		Status:    models.StatusFailed,
+		Priority:  models.PriorityNormal,
+		Recipient: webhook.URL,
+		Body:      "Deploy finished",
+		Metadata: map[string]string{
+			routing.MetadataProvider:    "retired",
+			rollout.MetadataArm:         rollout.ArmCanary,
+			compliance.MetadataDecision: "blocked",
+			"reconciled":                "true",
+			MetadataResentFrom:          uuid.NewString(),
+			"team":                      "platform",
+		},
+	}
+	require.NoError(t, repo.Save(ctx, original))
+
+	response, err := dispatcher.ResendNotification(ctx, original.ID.String(), nil)
+	require.NoError(t, err)
+	resent, err := dispatcher.GetNotificationStatus(ctx, response.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "platform", MetadataResentFrom: original.ID.String()}, resent.Metadata)
+}
+
+func TestDispatcher_ResendNotification_Errors(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	dispatcher, err := NewDispatcherFromConfig(createTestProvidersConfig(), repo, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = dispatcher.ResendNotification(ctx, "missing", nil)
+	require.Error(t, err)
+
+	pending := &models.Notification{
+		ID:        uuid.New(),
+		Type:      models.NotificationTypeChat,
+		Status:    models.StatusPending,
+		Priority:  models.PriorityNormal,
+		Recipient: "https://hooks.example.com/pending",
+		Body:      "In flight",
+	}
+	require.NoError(t, repo.Save(ctx, pending))
+
+	_, err = dispatcher.ResendNotification(ctx, pending.ID.String(), nil)
+	require.Error(t, err)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeValidationFailed, notifErr.Code)
+}
+
+func TestDispatcher_AuditTrail(t *testing.T) {
+	dispatcher := createTestDispatcher(t)
+	bus := events.NewBus(utils.NewSimpleLogger("info"))
+	dispatcher.SetEventPublisher(bus)
+
+	recorder := audit.NewRecorder(repository.NewMemoryAuditRepository(), utils.NewSimpleLogger("info"))
+	bus.Subscribe(recorder)
+
+	ctx := audit.WithActor(context.Background(), "key-live-1")
+	request := &models.NotificationRequest{
+		Type:      models.NotificationTypePush,
+		Priority:  models.PriorityNormal,
+		Recipient: testIOSToken,
+		Subject:   "Hello",
+		Body:      "Audited push",
+		PushData:  &models.PushData{Platform: "ios"},
+	}
+
+	response, err := dispatcher.SendNotification(ctx, request)
+	require.NoError(t, err)
+
+	trail, err := recorder.ForNotification(ctx, response.ID.String())
+	require.NoError(t, err)
+	require.Len(t, trail, 2)
+	assert.Equal(t, models.AuditOutcomeAccepted, trail[0].Outcome)
+	assert.Equal(t, models.AuditOutcomeSent, trail[1].Outcome)
+	assert.Equal(t, "key-live-1", trail[1].Actor)
+	assert.Equal(t, audit.HashPayload(request), trail[1].PayloadHash)
+
+	// Rejected requests are audited without a notification
+	_, err = dispatcher.SendNotification(ctx, &models.NotificationRequest{
+		Type:     models.NotificationTypeEmail,
+		Priority: models.PriorityNormal,
+		Body:     "missing recipient",
+	})
+	require.Error(t, err)
+
+	rejected := models.AuditOutcomeRejected
+	entries, err := recorder.Query(ctx, interfaces.AuditFilters{Outcome: &rejected})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.NotEmpty(t, entries[0].Reason)
+	assert.Empty(t, entries[0].NotificationID)
+}
+
+func TestDispatcher_Middleware(t *testing.T) {
+	dispatcher := createTestDispatcher(t)
+	bus := events.NewBus(utils.NewSimpleLogger("info"))
+	dispatcher.SetEventPublisher(bus)
+
+	var rejected []events.Event
+	bus.Subscribe(events.SubscriberFunc(func(ctx context.Context, event events.Event) error {
+		rejected = append(rejected, event)
+		return nil
+	}), events.EventNotificationRejected)
+
+	// A custom compliance check runs after validation and before templating
+	require.NoError(t, dispatcher.RegisterMiddleware("no-marketing-sms", pipeline.StagePreferences, func(next pipeline.Handler) pipeline.Handler {
+		return func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+			if request.Type == models.NotificationTypeSMS && request.Metadata["category"] == "marketing" {
+				return nil, errors.NewValidationError("category", "marketing SMS is not allowed")
+			}
+			return next(ctx, request)
+		}
+	}))
+	assert.Equal(t, []string{pipeline.ValidationMiddleware, "no-marketing-sms", pipeline.TemplateMiddleware}, dispatcher.Middleware())
+	assert.Error(t, dispatcher.RegisterMiddleware("no-marketing-sms", pipeline.StagePreferences, pipeline.Validate()))
+
+	_, err := dispatcher.SendNotification(context.Background(), &models.NotificationRequest{
+		Type:      models.NotificationTypeSMS,
+		Priority:  models.PriorityNormal,
+		Recipient: "+15551234567",
+		Body:      "50% off today",
+		Metadata:  map[string]string{"category": "marketing"},
+	})
+	require.Error(t, err)
+	require.Len(t, rejected, 1)
+	assert.Contains(t, rejected[0].Reason, "marketing SMS is not allowed")
+
+	// Template data is rendered into the stored notification
+	response, err := dispatcher.SendNotification(context.Background(), &models.NotificationRequest{
+		Type:         models.NotificationTypeSMS,
+		Priority:     models.PriorityNormal,
+		Recipient:    "+15551234567",
+		Body:         "Your code is {{code}}",
+		TemplateData: map[string]string{"code": "123456"},
+	})
+	require.NoError(t, err)
+
+	notification, err := dispatcher.GetNotificationStatus(context.Background(), response.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, "Your code is 123456", notification.Body)
+	assert.Equal(t, "123456", notification.TemplateData["code"])
+
+	assert.True(t, dispatcher.RemoveMiddleware("no-marketing-sms"))
+}
+
+func BenchmarkDispatcher_SendNotification(b *testing.B) {
+	requests := map[string]*models.NotificationRequest{
+		"email": {Type: models.NotificationTypeEmail, Priority: models.PriorityNormal, Recipient: "bench@example.com", Subject: "Hello", Body: "Benchmark"},
+		"sms":   {Type: models.NotificationTypeSMS, Priority: models.PriorityNormal, Recipient: "+15551234567", Body: "Benchmark", SMSData: &models.SMSData{CountryCode: "US"}},
+		"push":  {Type: models.NotificationTypePush, Priority: models.PriorityNormal, Recipient: testIOSToken, Subject: "Hello", Body: "Benchmark", PushData: &models.PushData{Platform: "ios"}},
+	}
+
+	for name, request := range requests {
+		b.Run(name, func(b *testing.B) {
+			dispatcher, err := NewDispatcherFromConfig(createTestProvidersConfig(), repository.NewMemoryRepository(), utils.NewSimpleLogger("error"))
+			require.NoError(b, err)
+
+			// Without simulated latency the benchmark measures the dispatcher itself
+			for _, provider := range dispatcher.ListProviders() {
+				if simulated, ok := provider.(interface{ SetSimulation(providers.Simulation) }); ok {
+					simulated.SetSimulation(providers.Simulation{Seed: 1})
+				}
+			}
+
+			ctx := context.Background()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := dispatcher.SendNotification(ctx, request); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// Helper functions
+
+func createTestProvidersConfig() config.ProvidersConfig {
+	return config.ProvidersConfig{
+		Email: config.EmailProviderConfig{Provider: "mock", Enabled: true},
+		SMS:   config.SMSProviderConfig{Provider: "mock", Enabled: true},
+		Push:  config.PushProviderConfig{Provider: "mock", Enabled: true},
+		// Chat webhooks go to local test servers
+		Chat: config.ChatProviderConfig{Provider: "slack", Enabled: true, AllowedHosts: []string{"127.0.0.1", "hooks.example.com"}, AllowPrivateWebhooks: true},
+	}
+}
+
+func createTestDispatcher(t *testing.T) *Dispatcher {
+	dispatcher, err := NewDispatcherFromConfig(createTestProvidersConfig(), repository.NewMemoryRepository(), utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	return dispatcher
+}
+
+// testSpamScorer scores messages shouting FREE MONEY as spam
+type testSpamScorer struct {
+	message []byte
+}
+
+func (s *testSpamScorer) Score(_ context.Context, message []byte) (*spamcheck.Result, error) {
+	s.message = message
+	if bytes.Contains(message, []byte("FREE MONEY")) {
+		return &spamcheck.Result{Score: 9, Rules: []spamcheck.RuleHit{{Name: "MONEY_FREE", Score: 9}}}, nil
+	}
+	return &spamcheck.Result{Score: 1}, nil
+}
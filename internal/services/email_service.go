@@ -3,41 +3,65 @@ package services
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/domainauth"
+	"github.com/nareshkumar-microsoft/notificationService/internal/emailverify"
+	"github.com/nareshkumar-microsoft/notificationService/internal/ics"
+	"github.com/nareshkumar-microsoft/notificationService/internal/inbound"
+	"github.com/nareshkumar-microsoft/notificationService/internal/limits"
 	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/privacy"
 	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/internal/templatelint"
+	"github.com/nareshkumar-microsoft/notificationService/internal/templatevars"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/internal/validation"
 	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
 	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
 )
 
 // EmailService provides email notification functionality
 type EmailService struct {
-	provider interfaces.EmailProvider
-	config   config.EmailProviderConfig
-	logger   interfaces.Logger
+	provider       interfaces.EmailProvider
+	config         config.EmailProviderConfig
+	logger         interfaces.Logger
+	linter         *templatelint.Linter
+	testRecipients testRecipients
+	renderersMu    sync.RWMutex
+	renderers      map[string]interfaces.AttachmentRenderer
+	verifier       *emailverify.Verifier
+	replies        *inbound.Router
+	diagnoser      *domainauth.Diagnoser
+	limits         *limits.Limits
+}
+
+// AttachmentRendererFunc adapts a function to interfaces.AttachmentRenderer
+type AttachmentRendererFunc func(ctx context.Context, data map[string]string) (*models.EmailAttachment, error)
+
+// RenderAttachment implements interfaces.AttachmentRenderer
+func (f AttachmentRendererFunc) RenderAttachment(ctx context.Context, data map[string]string) (*models.EmailAttachment, error) {
+	return f(ctx, data)
 }
 
 // NewEmailService creates a new email service
 func NewEmailService(cfg config.EmailProviderConfig, logger interfaces.Logger) (*EmailService, error) {
-	var provider interfaces.EmailProvider
-
-	switch cfg.Provider {
-	case "mock":
-		provider = providers.NewMockEmailProvider(cfg)
-	default:
-		return nil, errors.NewNotificationError(
-			errors.ErrorCodeProviderNotFound,
-			fmt.Sprintf("unsupported email provider: %s", cfg.Provider),
-		)
+	provider, err := providers.NewEmailProvider(cfg)
+	if err != nil {
+		return nil, err
 	}
 
 	service := &EmailService{
-		provider: provider,
-		config:   cfg,
-		logger:   logger,
+		provider:  provider,
+		config:    cfg,
+		logger:    logger,
+		linter:    templatelint.NewLinter(templatelint.DefaultRules()),
+		renderers: make(map[string]interfaces.AttachmentRenderer),
+		limits:    limits.New(config.LimitsConfig{}),
 	}
 
 	return service, nil
@@ -51,7 +75,7 @@ func (s *EmailService) SendEmail(ctx context.Context, request *EmailRequest) (*m
 		return nil, err
 	}
 
-	s.logger.Infof("Sending email to %v with subject: %s", request.To, request.Subject)
+	s.logger.Infof("Sending email to %v with subject: %s", maskEmails(request.To), request.Subject)
 
 	// Check provider health
 	if err := s.provider.IsHealthy(ctx); err != nil {
@@ -59,15 +83,13 @@ func (s *EmailService) SendEmail(ctx context.Context, request *EmailRequest) (*m
 		return nil, err
 	}
 
-	// Create email notification
-	emailNotification := s.createEmailNotification(request)
-
-	// Apply template if specified
-	if request.TemplateID != "" {
-		if err := s.applyTemplate(emailNotification, request.TemplateID, request.TemplateData); err != nil {
-			s.logger.Errorf("Template application failed: %v", err)
-			return nil, err
-		}
+	emailNotification, err := s.prepareEmail(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if emailNotification.Expired(time.Now()) {
+		s.logger.Warnf("Email %s expired before it was sent", emailNotification.ID)
+		return expiredResponse(&emailNotification.Notification), nil
 	}
 
 	// Send email
@@ -81,7 +103,9 @@ func (s *EmailService) SendEmail(ctx context.Context, request *EmailRequest) (*m
 	return response, nil
 }
 
-// SendBulkEmail sends emails to multiple recipients
+// SendBulkEmail sends emails to multiple recipients. Providers with a batch
+// API send them in batches of up to their maximum batch size; others are
+// sent one at a time. Responses are in the order of the recipients.
 func (s *EmailService) SendBulkEmail(ctx context.Context, request *BulkEmailRequest) ([]*models.NotificationResponse, error) {
 	s.logger.Infof("Sending bulk email to %d recipients", len(request.Recipients))
 
@@ -89,32 +113,18 @@ func (s *EmailService) SendBulkEmail(ctx context.Context, request *BulkEmailRequ
 		return nil, errors.NewValidationError("recipients", "at least one recipient is required")
 	}
 
+	if batcher, ok := s.provider.(interfaces.BatchEmailProvider); ok {
+		return s.sendEmailBatches(ctx, batcher, request), nil
+	}
+
 	responses := make([]*models.NotificationResponse, 0, len(request.Recipients))
 
 	for _, recipient := range request.Recipients {
-		emailRequest := &EmailRequest{
-			To:           []string{recipient.Email},
-			Subject:      request.Subject,
-			HTMLBody:     request.HTMLBody,
-			TextBody:     request.TextBody,
-			From:         request.From,
-			ReplyTo:      request.ReplyTo,
-			Headers:      request.Headers,
-			TemplateID:   request.TemplateID,
-			TemplateData: s.mergeTemplateData(request.TemplateData, recipient.Data),
-			Priority:     request.Priority,
-			Metadata:     request.Metadata,
-		}
-
-		response, err := s.SendEmail(ctx, emailRequest)
+		response, err := s.SendEmail(ctx, s.bulkEmailRequest(request, recipient))
 		if err != nil {
 			s.logger.Errorf("Failed to send email to %s: %v", recipient.Email, err)
 			// Continue with other recipients, but record the error
-			response = &models.NotificationResponse{
-				ID:     uuid.New(),
-				Status: models.StatusFailed,
-				Error:  err.Error(),
-			}
+			response = failedResponse(err)
 		}
 
 		responses = append(responses, response)
@@ -131,7 +141,12 @@ func (s *EmailService) GetEmailTemplates() []interfaces.EmailTemplate {
 
 // RenderTemplate renders an email template with data
 func (s *EmailService) RenderTemplate(templateID string, data map[string]string) (*RenderedTemplate, error) {
-	mockProvider, ok := s.provider.(*providers.MockEmailProvider)
+	return s.RenderTenantTemplate("", templateID, data)
+}
+
+// RenderTenantTemplate renders an email template with data and a tenant's branding
+func (s *EmailService) RenderTenantTemplate(tenantID, templateID string, data map[string]string) (*RenderedTemplate, error) {
+	mockProvider, ok := s.mockProvider()
 	if !ok {
 		return nil, errors.NewNotificationError(
 			errors.ErrorCodeProviderNotFound,
@@ -139,7 +154,7 @@ func (s *EmailService) RenderTemplate(templateID string, data map[string]string)
 		)
 	}
 
-	template, err := mockProvider.RenderTemplate(templateID, data)
+	template, err := mockProvider.RenderTenantTemplate(tenantID, templateID, data)
 	if err != nil {
 		return nil, err
 	}
@@ -152,11 +167,145 @@ func (s *EmailService) RenderTemplate(templateID string, data map[string]string)
 	}, nil
 }
 
+// SetLintRules replaces the rules templates are validated against
+func (s *EmailService) SetLintRules(rules templatelint.Rules) {
+	s.linter = templatelint.NewLinter(rules)
+}
+
+// SetLimits replaces the size and attachment limits emails are checked against
+func (s *EmailService) SetLimits(payloadLimits *limits.Limits) {
+	s.limits = payloadLimits
+}
+
+// SetTestRecipients sets the verified addresses TestSend may send to
+func (s *EmailService) SetTestRecipients(recipients ...string) {
+	s.testRecipients.set(recipients)
+}
+
+// ValidateTemplate checks an email template's variables, HTML, subject
+// length and wording
+func (s *EmailService) ValidateTemplate(template *providers.EmailTemplate) templatelint.Report {
+	return s.linter.ValidateTemplate(templatelint.Template{
+		Channel:   models.NotificationTypeEmail,
+		Subject:   template.Subject,
+		HTMLBody:  template.HTMLBody,
+		Body:      template.TextBody,
+		Variables: declaredVariables(template.Variables, template.Schema),
+	})
+}
+
+// TestSend sends a template rendered with sample data to a verified test
+// address. Templates that fail validation are not sent.
+func (s *EmailService) TestSend(ctx context.Context, templateID, recipient string, sampleData map[string]string) (*models.NotificationResponse, error) {
+	if err := s.testRecipients.check(recipient); err != nil {
+		return nil, err
+	}
+
+	mockProvider, ok := s.mockProvider()
+	if !ok {
+		return nil, errors.NewNotificationError(
+			errors.ErrorCodeProviderNotFound,
+			"template rendering not supported by this provider",
+		)
+	}
+	template, err := mockProvider.GetTemplate(templateID)
+	if err != nil {
+		return nil, err
+	}
+	if err := lintError(templateID, s.ValidateTemplate(template)); err != nil {
+		return nil, err
+	}
+
+	return s.SendEmail(ctx, &EmailRequest{
+		To:           []string{recipient},
+		TemplateID:   templateID,
+		TemplateData: sampleData,
+		Headers:      map[string]string{"X-Test-Send": "true"},
+		Priority:     models.PriorityNormal,
+		Metadata:     map[string]string{"test_send": "true"},
+	})
+}
+
+// RegisterAttachmentRenderer registers a renderer that requests can name in
+// RenderAttachments, replacing any renderer with the same name
+func (s *EmailService) RegisterAttachmentRenderer(name string, renderer interfaces.AttachmentRenderer) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.NewValidationError("name", "renderer name is required")
+	}
+	if renderer == nil {
+		return errors.NewValidationError("renderer", "renderer is required")
+	}
+
+	s.renderersMu.Lock()
+	defer s.renderersMu.Unlock()
+
+	s.renderers[name] = renderer
+	return nil
+}
+
+// AttachCalendarInvite generates an ICS invite for an event and attaches it to
+// an email request
+func AttachCalendarInvite(request *EmailRequest, event ics.Event) error {
+	attachment, err := ics.Attachment(event)
+	if err != nil {
+		return err
+	}
+
+	request.Attachments = append(request.Attachments, attachment)
+	return nil
+}
+
 // ValidateEmailAddress validates an email address
 func (s *EmailService) ValidateEmailAddress(email string) error {
 	return s.provider.ValidateEmailAddress(email)
 }
 
+// defaultEmailVerifier serves VerifyEmail when no verifier is set; it makes
+// no DNS lookups
+var defaultEmailVerifier = emailverify.NewVerifier(emailverify.Options{})
+
+// VerifyEmail checks an address beyond its format: disposable domains, role
+// accounts and, when the verifier set with SetVerifier looks them up, the
+// domain's MX records
+func (s *EmailService) VerifyEmail(ctx context.Context, email string) (emailverify.VerificationResult, error) {
+	verifier := s.verifier
+	if verifier == nil {
+		verifier = defaultEmailVerifier
+	}
+	return verifier.VerifyEmail(ctx, email)
+}
+
+// SetVerifier sets the verifier recipients are checked with before each
+// send; nil stops checking them
+func (s *EmailService) SetVerifier(verifier *emailverify.Verifier) {
+	s.verifier = verifier
+}
+
+// DiagnoseSendingDomain checks a sending domain's SPF record and DKIM keys,
+// and the DMARC policy of the default sender's domain, and reports what to
+// fix for mail from the default sender to pass DMARC
+func (s *EmailService) DiagnoseSendingDomain(ctx context.Context, domain string) (*domainauth.Report, error) {
+	diagnoser := s.diagnoser
+	if diagnoser == nil {
+		diagnoser = domainauth.NewDiagnoser(domainauth.Options{})
+	}
+
+	sender := s.getDefaultSender()
+	return diagnoser.DiagnoseSendingDomain(ctx, domain, sender[strings.LastIndex(sender, "@")+1:])
+}
+
+// SetDomainDiagnoser sets the diagnoser DiagnoseSendingDomain uses, for
+// example one with the DKIM selectors of the configured provider
+func (s *EmailService) SetDomainDiagnoser(diagnoser *domainauth.Diagnoser) {
+	s.diagnoser = diagnoser
+}
+
+// SetReplyRouter sets the router whose reply addresses become the Reply-To
+// of emails without one, so replies reach the inbound webhook
+func (s *EmailService) SetReplyRouter(router *inbound.Router) {
+	s.replies = router
+}
+
 // GetProviderStatus returns the current provider status
 func (s *EmailService) GetProviderStatus(ctx context.Context) *ProviderStatus {
 	status := &ProviderStatus{
@@ -179,8 +328,8 @@ func (s *EmailService) validateEmailRequest(request *EmailRequest) error {
 		return errors.NewValidationError("request", "email request is required")
 	}
 
-	if len(request.To) == 0 {
-		return errors.NewValidationError("to", "at least one recipient is required")
+	if err := validation.Struct(request); err != nil {
+		return err
 	}
 
 	// Validate all email addresses
@@ -214,6 +363,14 @@ func (s *EmailService) validateEmailRequest(request *EmailRequest) error {
 		}
 	}
 
+	if err := utils.ValidateEmailHeaders(request.Headers); err != nil {
+		return err
+	}
+
+	if err := utils.ValidateEmailThreading(request.InReplyTo, request.References); err != nil {
+		return err
+	}
+
 	// Validate content
 	if request.Subject == "" && request.TemplateID == "" {
 		return errors.NewValidationError("subject", "email subject is required when not using a template")
@@ -223,6 +380,195 @@ func (s *EmailService) validateEmailRequest(request *EmailRequest) error {
 		return errors.NewValidationError("body", "email must have either HTML body, text body, or template")
 	}
 
+	if err := s.limits.CheckEmail(request.Subject, request.HTMLBody, request.TextBody, request.Attachments); err != nil {
+		return err
+	}
+
+	for _, attachment := range request.RenderAttachments {
+		if _, exists := s.renderer(attachment.Renderer); !exists {
+			return errors.NewValidationError("render_attachments", fmt.Sprintf("unknown attachment renderer: %s", attachment.Renderer))
+		}
+	}
+
+	return nil
+}
+
+// renderer returns a registered attachment renderer
+func (s *EmailService) renderer(name string) (interfaces.AttachmentRenderer, bool) {
+	s.renderersMu.RLock()
+	defer s.renderersMu.RUnlock()
+
+	renderer, exists := s.renderers[name]
+	return renderer, exists
+}
+
+// renderAttachments invokes the renderers a request names and attaches their output
+func (s *EmailService) renderAttachments(ctx context.Context, email *models.EmailNotification, request *EmailRequest) error {
+	for _, spec := range request.RenderAttachments {
+		renderer, exists := s.renderer(spec.Renderer)
+		if !exists {
+			return errors.NewValidationError("render_attachments", fmt.Sprintf("unknown attachment renderer: %s", spec.Renderer))
+		}
+
+		attachment, err := renderer.RenderAttachment(ctx, s.mergeTemplateData(request.TemplateData, spec.Data))
+		if err != nil {
+			return errors.NewNotificationError(errors.ErrorCodeNotificationFailed,
+				fmt.Sprintf("attachment renderer %s failed", spec.Renderer)).WithCause(err)
+		}
+		if attachment == nil || attachment.ContentType == "" {
+			return errors.NewNotificationError(errors.ErrorCodeNotificationFailed,
+				fmt.Sprintf("attachment renderer %s returned no content type", spec.Renderer))
+		}
+
+		rendered := *attachment
+		if spec.Filename != "" {
+			rendered.Filename = spec.Filename
+		}
+		rendered.Size = int64(len(rendered.Content))
+		email.Attachments = append(email.Attachments, rendered)
+	}
+	return nil
+}
+
+// sendEmailBatches prepares an email for every recipient of a bulk request
+// and sends them through the provider's batch API. Recipients whose email
+// cannot be prepared, or whose batch fails, get failed responses.
+func (s *EmailService) sendEmailBatches(ctx context.Context, batcher interfaces.BatchEmailProvider, request *BulkEmailRequest) []*models.NotificationResponse {
+	responses := make([]*models.NotificationResponse, len(request.Recipients))
+
+	if err := s.provider.IsHealthy(ctx); err != nil {
+		s.logger.Errorf("Email provider health check failed: %v", err)
+		for i := range responses {
+			responses[i] = failedResponse(err)
+		}
+		return responses
+	}
+
+	emails := make([]*models.EmailNotification, 0, len(request.Recipients))
+	indexes := make([]int, 0, len(request.Recipients))
+	for i, recipient := range request.Recipients {
+		emailRequest := s.bulkEmailRequest(request, recipient)
+		err := s.validateEmailRequest(emailRequest)
+		var email *models.EmailNotification
+		if err == nil {
+			email, err = s.prepareEmail(ctx, emailRequest)
+		}
+		if err != nil {
+			s.logger.Errorf("Failed to prepare email to %s: %v", maskEmails([]string{recipient.Email}), err)
+			responses[i] = failedResponse(err)
+			continue
+		}
+		if email.Expired(time.Now()) {
+			responses[i] = expiredResponse(&email.Notification)
+			continue
+		}
+		emails = append(emails, email)
+		indexes = append(indexes, i)
+	}
+
+	size := batcher.MaxBatchSize()
+	if size < 1 {
+		size = 1
+	}
+	batches := 0
+	for start := 0; start < len(emails); start += size {
+		end := start + size
+		if end > len(emails) {
+			end = len(emails)
+		}
+		batches++
+
+		results, err := batcher.SendEmailBatch(ctx, emails[start:end])
+		for j := start; j < end; j++ {
+			switch {
+			case err != nil:
+				responses[indexes[j]] = failedResponse(err)
+			case results[j-start].Err != nil:
+				responses[indexes[j]] = failedResponse(results[j-start].Err)
+			default:
+				responses[indexes[j]] = results[j-start].Response
+			}
+		}
+		if err != nil {
+			s.logger.Errorf("Email batch of %d failed: %v", end-start, err)
+		}
+	}
+
+	s.logger.Infof("Bulk email completed: %d emails processed in %d batches", len(responses), batches)
+	return responses
+}
+
+// bulkEmailRequest builds the email request for one recipient of a bulk request
+func (s *EmailService) bulkEmailRequest(request *BulkEmailRequest, recipient BulkEmailRecipient) *EmailRequest {
+	return &EmailRequest{
+		To:                []string{recipient.Email},
+		Subject:           request.Subject,
+		HTMLBody:          request.HTMLBody,
+		TextBody:          request.TextBody,
+		From:              request.From,
+		ReplyTo:           request.ReplyTo,
+		Headers:           request.Headers,
+		InReplyTo:         request.InReplyTo,
+		References:        request.References,
+		TemplateID:        request.TemplateID,
+		TemplateData:      s.mergeTemplateData(request.TemplateData, recipient.Data),
+		TenantID:          request.TenantID,
+		RenderAttachments: request.RenderAttachments,
+		Priority:          request.Priority,
+		Metadata:          request.Metadata,
+		ExpiresAt:         request.ExpiresAt,
+	}
+}
+
+// prepareEmail creates the email notification for a validated request,
+// applying its template and rendering its attachments
+func (s *EmailService) prepareEmail(ctx context.Context, request *EmailRequest) (*models.EmailNotification, error) {
+	if s.verifier != nil {
+		if err := s.verifyRecipients(ctx, request); err != nil {
+			s.logger.Errorf("Email verification failed: %v", err)
+			return nil, err
+		}
+	}
+
+	emailNotification := s.createEmailNotification(request)
+
+	// Apply template if specified
+	if request.TemplateID != "" {
+		if err := s.applyTemplate(emailNotification, request.TenantID, request.TemplateID, request.TemplateData); err != nil {
+			s.logger.Errorf("Template application failed: %v", err)
+			return nil, err
+		}
+	}
+
+	// Render attachments from data
+	if err := s.renderAttachments(ctx, emailNotification, request); err != nil {
+		s.logger.Errorf("Attachment rendering failed: %v", err)
+		return nil, err
+	}
+
+	// Templates and rendered attachments add to the email
+	if err := s.limits.CheckEmail(emailNotification.Subject, emailNotification.HTMLBody, emailNotification.TextBody, emailNotification.Attachments); err != nil {
+		s.logger.Errorf("Email exceeds limits: %v", err)
+		return nil, err
+	}
+
+	return emailNotification, nil
+}
+
+// verifyRecipients checks the recipients of an email with the verifier
+func (s *EmailService) verifyRecipients(ctx context.Context, request *EmailRequest) error {
+	fields := []struct {
+		name   string
+		emails []string
+	}{{"to", request.To}, {"cc", request.CC}, {"bcc", request.BCC}}
+
+	for _, field := range fields {
+		for _, email := range field.emails {
+			if err := s.verifier.Check(ctx, field.name, email); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
@@ -242,6 +588,7 @@ func (s *EmailService) createEmailNotification(request *EmailRequest) *models.Em
 			Metadata:   request.Metadata,
 			CreatedAt:  now,
 			UpdatedAt:  now,
+			ExpiresAt:  request.ExpiresAt,
 			RetryCount: 0,
 			MaxRetries: 3,
 		},
@@ -254,19 +601,45 @@ func (s *EmailService) createEmailNotification(request *EmailRequest) *models.Em
 		TextBody:    request.TextBody,
 		Attachments: request.Attachments,
 		Headers:     request.Headers,
+		InReplyTo:   request.InReplyTo,
+		References:  utils.ThreadReferences(request.InReplyTo, request.References),
 	}
 
 	// Set default sender if not provided
 	if notification.From == "" {
 		notification.From = s.getDefaultSender()
 	}
+	if notification.ReplyTo == "" && s.replies != nil {
+		notification.ReplyTo = s.replies.ReplyAddress(notification.ID)
+	}
+	notification.MessageID = utils.GenerateMessageID(notification.ID, notification.From)
 
 	return notification
 }
 
+// mockProvider returns the mock provider that renders templates, unwrapping
+// throttling, scanning and offloading wrappers
+func (s *EmailService) mockProvider() (*providers.MockEmailProvider, bool) {
+	provider := s.provider
+	for unwrapped := false; !unwrapped; {
+		switch wrapper := provider.(type) {
+		case *providers.ThrottledEmailProvider:
+			provider = wrapper.Unwrap()
+		case *providers.ScanningEmailProvider:
+			provider = wrapper.Unwrap()
+		case *providers.OffloadingEmailProvider:
+			provider = wrapper.Unwrap()
+		default:
+			unwrapped = true
+		}
+	}
+	mockProvider, ok := provider.(*providers.MockEmailProvider)
+	return mockProvider, ok
+}
+
 // applyTemplate applies a template to an email notification
-func (s *EmailService) applyTemplate(email *models.EmailNotification, templateID string, data map[string]string) error {
-	mockProvider, ok := s.provider.(*providers.MockEmailProvider)
+func (s *EmailService) applyTemplate(email *models.EmailNotification, tenantID, templateID string, data map[string]string) error {
+	mockProvider, ok := s.mockProvider()
 	if !ok {
 		return errors.NewNotificationError(
 			errors.ErrorCodeProviderNotFound,
@@ -274,7 +647,8 @@ func (s *EmailService) applyTemplate(email *models.EmailNotification, templateID
 		)
 	}
 
-	template, err := mockProvider.RenderTemplate(templateID, data)
+	data = templatevars.WithRecipient(data, email.Recipient)
+	template, err := mockProvider.RenderTenantTemplate(tenantID, templateID, data)
 	if err != nil {
 		return err
 	}
@@ -313,37 +687,64 @@ func (s *EmailService) getDefaultSender() string {
 	return "noreply@notification-service.local"
 }
 
+// maskEmails masks a list of email addresses for logging
+func maskEmails(emails []string) []string {
+	masked := make([]string, len(emails))
+	for i, email := range emails {
+		masked[i] = privacy.MaskEmail(email)
+	}
+	return masked
+}
+
 // EmailRequest represents a request to send an email
 type EmailRequest struct {
-	To           []string                 `json:"to" validate:"required,min=1"`
-	CC           []string                 `json:"cc,omitempty"`
-	BCC          []string                 `json:"bcc,omitempty"`
-	From         string                   `json:"from,omitempty"`
-	ReplyTo      string                   `json:"reply_to,omitempty"`
-	Subject      string                   `json:"subject,omitempty"`
-	HTMLBody     string                   `json:"html_body,omitempty"`
-	TextBody     string                   `json:"text_body,omitempty"`
-	Attachments  []models.EmailAttachment `json:"attachments,omitempty"`
-	Headers      map[string]string        `json:"headers,omitempty"`
-	TemplateID   string                   `json:"template_id,omitempty"`
-	TemplateData map[string]string        `json:"template_data,omitempty"`
-	Priority     models.Priority          `json:"priority"`
-	Metadata     map[string]string        `json:"metadata,omitempty"`
+	To                []string                 `json:"to" validate:"required,min=1"`
+	CC                []string                 `json:"cc,omitempty"`
+	BCC               []string                 `json:"bcc,omitempty"`
+	From              string                   `json:"from,omitempty"`
+	ReplyTo           string                   `json:"reply_to,omitempty"`
+	Subject           string                   `json:"subject,omitempty"`
+	HTMLBody          string                   `json:"html_body,omitempty"`
+	TextBody          string                   `json:"text_body,omitempty"`
+	Attachments       []models.EmailAttachment `json:"attachments,omitempty"`
+	RenderAttachments []AttachmentRequest      `json:"render_attachments,omitempty"` // rendered at send time by registered renderers
+	Headers           map[string]string        `json:"headers,omitempty"`            // X- headers and a few list and priority headers
+	InReplyTo         string                   `json:"in_reply_to,omitempty"`        // Message-ID of the email this follows up
+	References        []string                 `json:"references,omitempty"`
+	TemplateID        string                   `json:"template_id,omitempty"`
+	TemplateData      map[string]string        `json:"template_data,omitempty"`
+	TenantID          string                   `json:"tenant_id,omitempty"` // selects the branding injected into templates
+	Priority          models.Priority          `json:"priority"`
+	Metadata          map[string]string        `json:"metadata,omitempty"`
+	ExpiresAt         *time.Time               `json:"expires_at,omitempty"` // not sent after this time
+}
+
+// AttachmentRequest asks a registered renderer for an attachment. The renderer
+// receives the request's template data overlaid with Data.
+type AttachmentRequest struct {
+	Renderer string            `json:"renderer" validate:"required"`
+	Filename string            `json:"filename,omitempty"` // overrides the renderer's filename
+	Data     map[string]string `json:"data,omitempty"`
 }
 
 // BulkEmailRequest represents a request to send emails to multiple recipients
 type BulkEmailRequest struct {
-	Recipients   []BulkEmailRecipient `json:"recipients" validate:"required,min=1"`
-	Subject      string               `json:"subject,omitempty"`
-	HTMLBody     string               `json:"html_body,omitempty"`
-	TextBody     string               `json:"text_body,omitempty"`
-	From         string               `json:"from,omitempty"`
-	ReplyTo      string               `json:"reply_to,omitempty"`
-	Headers      map[string]string    `json:"headers,omitempty"`
-	TemplateID   string               `json:"template_id,omitempty"`
-	TemplateData map[string]string    `json:"template_data,omitempty"`
-	Priority     models.Priority      `json:"priority"`
-	Metadata     map[string]string    `json:"metadata,omitempty"`
+	Recipients        []BulkEmailRecipient `json:"recipients" validate:"required,min=1"`
+	Subject           string               `json:"subject,omitempty"`
+	HTMLBody          string               `json:"html_body,omitempty"`
+	TextBody          string               `json:"text_body,omitempty"`
+	From              string               `json:"from,omitempty"`
+	ReplyTo           string               `json:"reply_to,omitempty"`
+	Headers           map[string]string    `json:"headers,omitempty"`
+	InReplyTo         string               `json:"in_reply_to,omitempty"`
+	References        []string             `json:"references,omitempty"`
+	TemplateID        string               `json:"template_id,omitempty"`
+	TemplateData      map[string]string    `json:"template_data,omitempty"`
+	TenantID          string               `json:"tenant_id,omitempty"`
+	RenderAttachments []AttachmentRequest  `json:"render_attachments,omitempty"` // rendered per recipient with their data
+	Priority          models.Priority      `json:"priority"`
+	Metadata          map[string]string    `json:"metadata,omitempty"`
+	ExpiresAt         *time.Time           `json:"expires_at,omitempty"`
 }
 
 // BulkEmailRecipient represents a recipient in a bulk email request
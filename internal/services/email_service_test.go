@@ -2,13 +2,25 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"net"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/domainauth"
+	"github.com/nareshkumar-microsoft/notificationService/internal/emailverify"
+	"github.com/nareshkumar-microsoft/notificationService/internal/ics"
+	"github.com/nareshkumar-microsoft/notificationService/internal/inbound"
 	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/internal/repository"
+	"github.com/nareshkumar-microsoft/notificationService/internal/templatelint"
+	"github.com/nareshkumar-microsoft/notificationService/internal/templateschema"
 	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
 	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
 )
@@ -131,6 +143,26 @@ func TestEmailService_SendEmail_WithTemplate(t *testing.T) {
 	assert.Equal(t, models.StatusSent, response.Status)
 }
 
+func TestEmailService_SendEmail_ThrottledProvider(t *testing.T) {
+	service, err := NewEmailService(config.EmailProviderConfig{
+		Provider:     "mock",
+		Enabled:      true,
+		DomainLimits: map[string]config.EmailDomainLimit{"example.com": {PerSecond: 100, Concurrency: 2}},
+	}, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	require.IsType(t, &providers.ThrottledEmailProvider{}, service.provider)
+
+	// Templates render through the throttled provider
+	response, err := service.SendEmail(context.Background(), &EmailRequest{
+		To:           []string{"test@example.com"},
+		TemplateID:   "welcome",
+		TemplateData: map[string]string{"user_name": "John Doe", "user_email": "john@example.com", "service_name": "Test Service"},
+		Priority:     models.PriorityNormal,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusSent, response.Status)
+}
+
 func TestEmailService_SendBulkEmail(t *testing.T) {
 	service := createTestEmailService()
 	ctx := context.Background()
@@ -155,6 +187,33 @@ func TestEmailService_SendBulkEmail(t *testing.T) {
 	}
 }
 
+func TestEmailService_SendBulkEmail_Batches(t *testing.T) {
+	service := createTestEmailService()
+	provider := service.provider.(*providers.MockEmailProvider)
+
+	recipients := make([]BulkEmailRecipient, 0, 1205)
+	for i := 0; i < 1204; i++ {
+		recipients = append(recipients, BulkEmailRecipient{Email: fmt.Sprintf("user%d@example.com", i), Data: map[string]string{"name": fmt.Sprint(i)}})
+	}
+	recipients = append(recipients[:2], append([]BulkEmailRecipient{{Email: "not-an-email"}}, recipients[2:]...)...)
+
+	responses, err := service.SendBulkEmail(context.Background(), &BulkEmailRequest{
+		Recipients: recipients,
+		Subject:    "Hello {{name}}",
+		TextBody:   "Hello {{name}}!",
+		Priority:   models.PriorityNormal,
+	})
+	require.NoError(t, err)
+	require.Len(t, responses, 1205)
+
+	// 1204 valid emails take two API calls instead of 1204
+	assert.Equal(t, 2, provider.BatchCalls())
+	assert.Len(t, provider.GetSentEmails(), 1204)
+	assert.Equal(t, models.StatusFailed, responses[2].Status)
+	assert.Equal(t, models.StatusSent, responses[3].Status)
+	assert.Equal(t, []string{"user2@example.com"}, provider.GetSentEmails()[2].To)
+}
+
 func TestEmailService_SendBulkEmail_NoRecipients(t *testing.T) {
 	service := createTestEmailService()
 	ctx := context.Background()
@@ -175,6 +234,105 @@ func TestEmailService_SendBulkEmail_NoRecipients(t *testing.T) {
 	assert.Equal(t, errors.ErrorCodeValidationFailed, notifErr.Code)
 }
 
+func TestEmailService_SendEmail_HeaderPolicy(t *testing.T) {
+	service := createTestEmailService()
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		wantErr bool
+	}{
+		{"x header", map[string]string{"X-Campaign-ID": "spring"}, false},
+		{"list unsubscribe", map[string]string{"list-unsubscribe": "<https://example.com/u>", "List-Unsubscribe-Post": "List-Unsubscribe=One-Click"}, false},
+		{"reserved header", map[string]string{"Bcc": "attacker@example.com"}, true},
+		{"content type", map[string]string{"Content-Type": "text/html"}, true},
+		{"unknown header", map[string]string{"Disposition-Notification-To": "a@example.com"}, true},
+		{"trusted x header", map[string]string{"X-Spam-Status": "No"}, true},
+		{"injected line", map[string]string{"X-Note": "hi\r\nBcc: attacker@example.com"}, true},
+		{"invalid name", map[string]string{"X Note": "hi"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.SendEmail(context.Background(), &EmailRequest{
+				To:       []string{"test@example.com"},
+				Subject:  "Test",
+				TextBody: "Test",
+				Headers:  tt.headers,
+			})
+			if !tt.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+
+			notifErr, ok := errors.AsNotificationError(err)
+			require.True(t, ok)
+			assert.Equal(t, errors.ErrorCodeValidationFailed, notifErr.Code)
+			assert.Equal(t, "headers", notifErr.Metadata["field"])
+		})
+	}
+}
+
+func TestEmailService_SendEmail_Threading(t *testing.T) {
+	service := createTestEmailService()
+	provider := service.provider.(*providers.MockEmailProvider)
+	ctx := context.Background()
+
+	first, err := service.SendEmail(ctx, &EmailRequest{
+		To:       []string{"test@example.com"},
+		From:     "orders@shop.example.com",
+		Subject:  "Order 42 confirmed",
+		TextBody: "Thanks for your order",
+	})
+	require.NoError(t, err)
+	messageID := first.ProviderMetadata["internet_message_id"]
+	assert.Equal(t, fmt.Sprintf("<%s@shop.example.com>", first.ID), messageID)
+
+	_, err = service.SendEmail(ctx, &EmailRequest{
+		To:        []string{"test@example.com"},
+		From:      "orders@shop.example.com",
+		Subject:   "Re: Order 42 confirmed",
+		TextBody:  "Your order has shipped",
+		InReplyTo: messageID,
+	})
+	require.NoError(t, err)
+
+	sent := provider.GetSentEmails()
+	require.Len(t, sent, 2)
+	assert.Equal(t, messageID, sent[1].InReplyTo)
+	assert.Equal(t, []string{messageID}, sent[1].References)
+	assert.NotEqual(t, messageID, sent[1].MessageID)
+
+	_, err = service.SendEmail(ctx, &EmailRequest{
+		To:        []string{"test@example.com"},
+		Subject:   "Re: Order 42 confirmed",
+		TextBody:  "Your order has shipped",
+		InReplyTo: "not-a-message-id",
+	})
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, "in_reply_to", notifErr.Metadata["field"])
+}
+
+func TestEmailService_SendEmail_ReplyRouter(t *testing.T) {
+	service := createTestEmailService()
+	provider := service.provider.(*providers.MockEmailProvider)
+	router, err := inbound.NewRouter(config.InboundConfig{ReplyDomain: "replies.example.com", SigningKey: "secret"}, repository.NewMemoryRepository(), nil, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	service.SetReplyRouter(router)
+
+	response, err := service.SendEmail(context.Background(), &EmailRequest{To: []string{"test@example.com"}, Subject: "Test", TextBody: "Test"})
+	require.NoError(t, err)
+	id, ok := router.NotificationID(provider.GetSentEmails()[0].ReplyTo)
+	require.True(t, ok)
+	assert.Equal(t, response.ID, id)
+
+	// An explicit Reply-To is kept
+	_, err = service.SendEmail(context.Background(), &EmailRequest{To: []string{"test@example.com"}, ReplyTo: "help@example.com", Subject: "Test", TextBody: "Test"})
+	require.NoError(t, err)
+	assert.Equal(t, "help@example.com", provider.GetSentEmails()[1].ReplyTo)
+}
+
 func TestEmailService_GetEmailTemplates(t *testing.T) {
 	service := createTestEmailService()
 
@@ -229,6 +387,243 @@ func TestEmailService_RenderTemplate_NotFound(t *testing.T) {
 	assert.Equal(t, errors.ErrorCodeTemplateNotFound, notifErr.Code)
 }
 
+func TestEmailService_SendEmail_TenantBranding(t *testing.T) {
+	service := createTestEmailService()
+	provider := service.provider.(*providers.MockEmailProvider)
+	provider.Layouts().SetBranding("acme", map[string]string{"name": "Acme", "primary_color": "#ff6600"})
+	require.NoError(t, provider.AddTemplate(&providers.EmailTemplate{
+		ID:       "receipt",
+		Subject:  "Your {{brand.name}} receipt",
+		HTMLBody: `{% extends "base" %}{% block content %}Order {{order_id}}{% endblock %}`,
+		TextBody: `{% extends "base_text" %}{% block content %}Order {{order_id}}{% endblock %}`,
+	}))
+
+	response, err := service.SendEmail(context.Background(), &EmailRequest{
+		To:           []string{"user@example.com"},
+		TemplateID:   "receipt",
+		TemplateData: map[string]string{"order_id": "A-100"},
+		TenantID:     "acme",
+		Priority:     models.PriorityNormal,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusSent, response.Status)
+
+	sent := provider.GetSentEmails()
+	require.Len(t, sent, 1)
+	assert.Equal(t, "Your Acme receipt", sent[0].Subject)
+	assert.Contains(t, sent[0].HTMLBody, "Order A-100")
+	assert.Contains(t, sent[0].HTMLBody, "#ff6600")
+
+	rendered, err := service.RenderTenantTemplate("acme", "receipt", map[string]string{"order_id": "A-101"})
+	require.NoError(t, err)
+	assert.Contains(t, rendered.TextBody, "Order A-101")
+	assert.Contains(t, rendered.TextBody, "Acme")
+}
+
+func TestEmailService_ValidateTemplate(t *testing.T) {
+	service := createTestEmailService()
+
+	for _, template := range service.GetEmailTemplates() {
+		report := service.ValidateTemplate(&providers.EmailTemplate{
+			Subject:   template.Subject,
+			HTMLBody:  template.HTMLBody,
+			TextBody:  template.TextBody,
+			Variables: template.Variables,
+		})
+		assert.True(t, report.Valid(), "template %s: %v", template.ID, report.Issues)
+	}
+
+	report := service.ValidateTemplate(&providers.EmailTemplate{Subject: "Hi {{ name }}", HTMLBody: "<p>Hi"})
+	assert.Len(t, report.Errors(), 2)
+}
+
+func TestEmailService_SendEmail_TemplateSchema(t *testing.T) {
+	service := createTestEmailService()
+	provider := service.provider.(*providers.MockEmailProvider)
+	require.NoError(t, provider.AddTemplate(&providers.EmailTemplate{
+		ID:       "invoice",
+		Subject:  "Invoice for {{amount}}",
+		TextBody: "Your {{plan}} plan invoice of {{amount}} is ready.",
+		Schema: templateschema.Schema{
+			"amount": {Type: templateschema.TypeNumber, Required: true},
+			"plan":   {Enum: []string{"basic", "pro"}},
+		},
+	}))
+
+	_, err := service.SendEmail(context.Background(), &EmailRequest{
+		To:           []string{"test@example.com"},
+		TemplateID:   "invoice",
+		TemplateData: map[string]string{"amount": "$10", "plan": "gold"},
+		Priority:     models.PriorityNormal,
+	})
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	require.Len(t, notifErr.Fields, 2)
+	assert.Equal(t, `must be a number, got "$10"`, notifErr.Fields[0].Message)
+	assert.Equal(t, "template_data.plan", notifErr.Fields[1].Field)
+	assert.Empty(t, provider.GetSentEmails())
+
+	// The schema declares the template's variables for linting
+	template, err := provider.GetTemplate("invoice")
+	require.NoError(t, err)
+	assert.Empty(t, service.ValidateTemplate(template).Issues)
+	report := service.ValidateTemplate(&providers.EmailTemplate{Subject: "{{total}}", TextBody: "Total", Schema: template.Schema})
+	require.Len(t, report.Issues, 1)
+	assert.Equal(t, templatelint.RuleUndeclared, report.Issues[0].Rule)
+}
+
+func TestEmailService_SendEmail_ComputedVariables(t *testing.T) {
+	service := createTestEmailService()
+	provider := service.provider.(*providers.MockEmailProvider)
+	require.NoError(t, provider.AddTemplate(&providers.EmailTemplate{
+		ID:       "team_invite",
+		Subject:  "Join the {{recipient_domain}} team",
+		TextBody: "{{inviter}} invited you on {{date}}.",
+		Schema:   templateschema.Schema{"inviter": {Default: "A teammate"}},
+	}))
+
+	_, err := service.SendEmail(context.Background(), &EmailRequest{
+		To:         []string{"ada@Example.com"},
+		TemplateID: "team_invite",
+		Priority:   models.PriorityNormal,
+	})
+	require.NoError(t, err)
+
+	sent := provider.GetSentEmails()
+	require.Len(t, sent, 1)
+	assert.Equal(t, "Join the example.com team", sent[0].Subject)
+	assert.True(t, strings.HasPrefix(sent[0].TextBody, "A teammate invited you on "))
+	assert.NotContains(t, sent[0].TextBody, "{{")
+}
+
+func TestEmailService_TestSend(t *testing.T) {
+	service := createTestEmailService()
+	service.SetTestRecipients("qa@example.com")
+	data := map[string]string{"user_name": "QA", "user_email": "qa@example.com", "service_name": "Test Service"}
+
+	response, err := service.TestSend(context.Background(), "welcome", "QA@example.com", data)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusSent, response.Status)
+
+	sent := service.provider.(*providers.MockEmailProvider).GetSentEmails()
+	require.Len(t, sent, 1)
+	assert.Equal(t, "true", sent[0].Headers["X-Test-Send"])
+	assert.Contains(t, sent[0].Subject, "Test Service")
+
+	// Unverified recipients are refused
+	_, err = service.TestSend(context.Background(), "welcome", "customer@example.com", data)
+	require.Error(t, err)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeValidationFailed, notifErr.Code)
+
+	// Templates that fail validation are not sent
+	provider := service.provider.(*providers.MockEmailProvider)
+	require.NoError(t, provider.AddTemplate(&providers.EmailTemplate{ID: "broken", Subject: "Hi", HTMLBody: "<div>{{name"}))
+	_, err = service.TestSend(context.Background(), "broken", "qa@example.com", data)
+	assert.Error(t, err)
+	assert.Len(t, provider.GetSentEmails(), 1)
+}
+
+func TestEmailService_SendEmail_CalendarInvite(t *testing.T) {
+	service := createTestEmailService()
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	request := &EmailRequest{
+		To:       []string{"jane@example.com"},
+		Subject:  "Your appointment",
+		TextBody: "See the attached invite",
+		Priority: models.PriorityNormal,
+	}
+	require.NoError(t, AttachCalendarInvite(request, ics.Event{
+		Title:     "Appointment",
+		Start:     start,
+		End:       start.Add(time.Hour),
+		Organizer: ics.Person{Email: "desk@clinic.example"},
+		Attendees: []ics.Person{{Email: "jane@example.com"}},
+	}))
+	assert.Error(t, AttachCalendarInvite(request, ics.Event{Title: "Missing times"}))
+
+	_, err := service.SendEmail(context.Background(), request)
+	require.NoError(t, err)
+
+	sent := service.provider.(*providers.MockEmailProvider).GetSentEmails()
+	require.Len(t, sent, 1)
+	require.Len(t, sent[0].Attachments, 1)
+	assert.Equal(t, "text/calendar; charset=UTF-8; method=REQUEST", sent[0].Attachments[0].ContentType)
+	assert.Contains(t, string(sent[0].Attachments[0].Content), "SUMMARY:Appointment")
+}
+
+func TestEmailService_SendEmail_RenderAttachments(t *testing.T) {
+	service := createTestEmailService()
+
+	var received map[string]string
+	require.NoError(t, service.RegisterAttachmentRenderer("invoice", AttachmentRendererFunc(
+		func(ctx context.Context, data map[string]string) (*models.EmailAttachment, error) {
+			received = data
+			return &models.EmailAttachment{
+				Filename:    "invoice.pdf",
+				Content:     []byte("%PDF invoice " + data["invoice_id"]),
+				ContentType: "application/pdf",
+			}, nil
+		})))
+
+	request := &EmailRequest{
+		To:           []string{"user@example.com"},
+		Subject:      "Your invoice",
+		TextBody:     "Attached",
+		TemplateData: map[string]string{"customer": "Jane", "invoice_id": "default"},
+		RenderAttachments: []AttachmentRequest{
+			{Renderer: "invoice", Filename: "INV-7.pdf", Data: map[string]string{"invoice_id": "INV-7"}},
+		},
+		Priority: models.PriorityNormal,
+	}
+	_, err := service.SendEmail(context.Background(), request)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"customer": "Jane", "invoice_id": "INV-7"}, received)
+	sent := service.provider.(*providers.MockEmailProvider).GetSentEmails()
+	require.Len(t, sent, 1)
+	require.Len(t, sent[0].Attachments, 1)
+	assert.Equal(t, "INV-7.pdf", sent[0].Attachments[0].Filename)
+	assert.Equal(t, "application/pdf", sent[0].Attachments[0].ContentType)
+	assert.Equal(t, int64(len("%PDF invoice INV-7")), sent[0].Attachments[0].Size)
+}
+
+func TestEmailService_SendEmail_RenderAttachmentErrors(t *testing.T) {
+	service := createTestEmailService()
+	require.NoError(t, service.RegisterAttachmentRenderer("broken", AttachmentRendererFunc(
+		func(ctx context.Context, data map[string]string) (*models.EmailAttachment, error) {
+			return nil, assert.AnError
+		})))
+	assert.Error(t, service.RegisterAttachmentRenderer("", AttachmentRendererFunc(nil)))
+	assert.Error(t, service.RegisterAttachmentRenderer("nil", nil))
+
+	send := func(renderer string) error {
+		_, err := service.SendEmail(context.Background(), &EmailRequest{
+			To:                []string{"user@example.com"},
+			Subject:           "Your invoice",
+			TextBody:          "Attached",
+			RenderAttachments: []AttachmentRequest{{Renderer: renderer}},
+			Priority:          models.PriorityNormal,
+		})
+		return err
+	}
+
+	err := send("unknown")
+	require.Error(t, err)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeValidationFailed, notifErr.Code)
+
+	err = send("broken")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, assert.AnError)
+
+	// Nothing is sent when rendering fails
+	assert.Empty(t, service.provider.(*providers.MockEmailProvider).GetSentEmails())
+}
+
 func TestEmailService_ValidateEmailAddress(t *testing.T) {
 	service := createTestEmailService()
 
@@ -253,6 +648,66 @@ func TestEmailService_ValidateEmailAddress(t *testing.T) {
 	}
 }
 
+func TestEmailService_DiagnoseSendingDomain(t *testing.T) {
+	service := createTestEmailService()
+	service.SetDomainDiagnoser(domainauth.NewDiagnoser(domainauth.Options{Resolver: staticTXTResolver{
+		"bounce.test.com":  {"v=spf1 include:_spf.mailer.example -all"},
+		"_dmarc.test.com":  {"v=DMARC1; p=quarantine; rua=mailto:dmarc@test.com"},
+		"_dmarc.other.com": {"v=DMARC1; p=reject"},
+	}}))
+
+	// The default sender's domain is the From domain
+	report, err := service.DiagnoseSendingDomain(context.Background(), "bounce.test.com")
+	require.NoError(t, err)
+	assert.Equal(t, "test.com", report.FromDomain)
+	assert.True(t, report.Aligned)
+	assert.False(t, report.OK(), "no DKIM key is published")
+
+	report, err = service.DiagnoseSendingDomain(context.Background(), "other.com")
+	require.NoError(t, err)
+	assert.False(t, report.Aligned)
+}
+
+func TestEmailService_VerifyEmail(t *testing.T) {
+	service := createTestEmailService()
+	ctx := context.Background()
+
+	result, err := service.VerifyEmail(ctx, "noreply@example.com")
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.True(t, result.RoleAccount)
+	assert.False(t, result.MXChecked)
+
+	// Without a verifier, sends do not check more than the format
+	_, err = service.SendEmail(ctx, &EmailRequest{To: []string{"jane@mailinator.com"}, Subject: "Hi", TextBody: "Hi", Priority: models.PriorityNormal})
+	require.NoError(t, err)
+
+	service.SetVerifier(emailverify.NewVerifier(emailverify.Options{RejectDisposable: true}))
+	_, err = service.SendEmail(ctx, &EmailRequest{
+		To:       []string{"jane@example.com"},
+		CC:       []string{"jane@mailinator.com"},
+		Subject:  "Hi",
+		TextBody: "Hi",
+		Priority: models.PriorityNormal,
+	})
+	require.Error(t, err)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, "cc", notifErr.Metadata["field"])
+
+	// Bulk sends fail only the rejected recipients
+	responses, err := service.SendBulkEmail(ctx, &BulkEmailRequest{
+		Recipients: []BulkEmailRecipient{{Email: "jane@yopmail.com"}, {Email: "john@example.com"}},
+		Subject:    "Hi",
+		TextBody:   "Hi",
+		Priority:   models.PriorityNormal,
+	})
+	require.NoError(t, err)
+	require.Len(t, responses, 2)
+	assert.Equal(t, models.StatusFailed, responses[0].Status)
+	assert.Equal(t, models.StatusSent, responses[1].Status)
+}
+
 func TestEmailService_GetProviderStatus(t *testing.T) {
 	service := createTestEmailService()
 	ctx := context.Background()
@@ -323,3 +778,14 @@ func createTestEmailService() *EmailService {
 
 	return service
 }
+
+// staticTXTResolver answers TXT lookups from a table; other names do not exist
+type staticTXTResolver map[string][]string
+
+func (r staticTXTResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	records, exists := r[name]
+	if !exists {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return records, nil
+}
@@ -0,0 +1,464 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nareshkumar-microsoft/notificationService/internal/events"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// MetadataChainExecutionID is the metadata key set to the chain execution a
+// notification was sent by
+const MetadataChainExecutionID = "chain_execution_id"
+
+// ChainStep is a step of an escalation chain: the users notified together
+// and how long they have to acknowledge before the alert moves on
+type ChainStep struct {
+	Targets []string `json:"targets"` // user IDs
+	// Channels are tried in order for each target; empty uses DefaultEscalationOrder
+	Channels []models.NotificationType `json:"channels,omitempty"`
+	// Delay is how long to wait for an acknowledgement after notifying the targets
+	Delay time.Duration `json:"delay"`
+	// Repeat is how many more times the targets are notified before escalating
+	Repeat int `json:"repeat,omitempty"`
+}
+
+// EscalationChain is an on-call escalation policy for operational alerts:
+// steps are notified in turn until someone acknowledges the alert
+type EscalationChain struct {
+	ID    string      `json:"id"`
+	Name  string      `json:"name"`
+	Steps []ChainStep `json:"steps"`
+	// Loops is how many more times the chain starts over from its first
+	// step when the last step goes unacknowledged
+	Loops     int       `json:"loops,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ChainExecutionStatus represents the state of an escalation chain run
+type ChainExecutionStatus string
+
+const (
+	ChainExecutionRunning      ChainExecutionStatus = "running"
+	ChainExecutionAcknowledged ChainExecutionStatus = "acknowledged"
+	ChainExecutionExhausted    ChainExecutionStatus = "exhausted" // every step ran unacknowledged
+	ChainExecutionCancelled    ChainExecutionStatus = "cancelled"
+)
+
+// ChainAction identifies an entry of an execution's log
+type ChainAction string
+
+const (
+	ChainActionNotified     ChainAction = "notified"
+	ChainActionNotifyFailed ChainAction = "notify_failed"
+	ChainActionTimedOut     ChainAction = "timed_out" // a step's delay passed without an acknowledgement
+	ChainActionAcknowledged ChainAction = "acknowledged"
+	ChainActionExhausted    ChainAction = "exhausted"
+	ChainActionCancelled    ChainAction = "cancelled"
+)
+
+// ChainLogEntry records one thing an escalation chain run did. Step, loop
+// and repeat count from zero.
+type ChainLogEntry struct {
+	At             time.Time               `json:"at"`
+	Action         ChainAction             `json:"action"`
+	Step           int                     `json:"step"`
+	Loop           int                     `json:"loop"`
+	Repeat         int                     `json:"repeat"`
+	UserID         string                  `json:"user_id,omitempty"`
+	Channel        models.NotificationType `json:"channel,omitempty"`
+	NotificationID string                  `json:"notification_id,omitempty"`
+	Detail         string                  `json:"detail,omitempty"`
+}
+
+// ChainExecution is a run of an escalation chain for an alert. Its log is
+// the audit trail of who was notified, how and when.
+type ChainExecution struct {
+	ID             string               `json:"id"`
+	ChainID        string               `json:"chain_id"`
+	Alert          UserMessage          `json:"alert"`
+	Status         ChainExecutionStatus `json:"status"`
+	AcknowledgedBy string               `json:"acknowledged_by,omitempty"`
+	Log            []ChainLogEntry      `json:"log"`
+	StartedAt      time.Time            `json:"started_at"`
+	FinishedAt     *time.Time           `json:"finished_at,omitempty"`
+}
+
+// EscalationChainService stores escalation chains and runs them for alerts.
+// A run stops when any notification it sent is acknowledged, when
+// Acknowledge is called for it, or when it is cancelled.
+type EscalationChainService struct {
+	mu            sync.Mutex
+	chains        map[string]*EscalationChain
+	executions    map[string]*ChainExecution
+	notifications map[string]string      // notification ID to execution ID
+	acks          map[string]chan string // execution ID to the user who acknowledged it
+	cancels       map[string]context.CancelFunc
+	wg            sync.WaitGroup
+
+	notifier *UserNotifier
+	logger   interfaces.Logger
+}
+
+// NewEscalationChainService creates an escalation chain service that
+// notifies users through the notifier
+func NewEscalationChainService(notifier *UserNotifier, logger interfaces.Logger) *EscalationChainService {
+	return &EscalationChainService{
+		chains:        make(map[string]*EscalationChain),
+		executions:    make(map[string]*ChainExecution),
+		notifications: make(map[string]string),
+		acks:          make(map[string]chan string),
+		cancels:       make(map[string]context.CancelFunc),
+		notifier:      notifier,
+		logger:        logger,
+	}
+}
+
+// CreateChain validates and stores an escalation chain
+func (s *EscalationChainService) CreateChain(chain *EscalationChain) (*EscalationChain, error) {
+	if err := validateEscalationChain(chain); err != nil {
+		return nil, err
+	}
+
+	stored := cloneEscalationChain(chain)
+	stored.ID = uuid.New().String()
+	stored.CreatedAt = time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.chains[stored.ID] = stored
+	s.logger.Infof("Created escalation chain %s (%s) with %d steps", stored.ID, stored.Name, len(stored.Steps))
+	return cloneEscalationChain(stored), nil
+}
+
+// GetChain returns an escalation chain
+func (s *EscalationChainService) GetChain(chainID string) (*EscalationChain, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chain, exists := s.chains[chainID]
+	if !exists {
+		return nil, errors.NewNotificationError(errors.ErrorCodeNotFound, fmt.Sprintf("escalation chain not found: %s", chainID))
+	}
+	return cloneEscalationChain(chain), nil
+}
+
+// ListChains returns the escalation chains sorted by name
+func (s *EscalationChainService) ListChains() []*EscalationChain {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chains := make([]*EscalationChain, 0, len(s.chains))
+	for _, chain := range s.chains {
+		chains = append(chains, cloneEscalationChain(chain))
+	}
+	sort.Slice(chains, func(i, j int) bool { return chains[i].Name < chains[j].Name })
+	return chains
+}
+
+// DeleteChain deletes an escalation chain. Runs already started finish.
+func (s *EscalationChainService) DeleteChain(chainID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, exists := s.chains[chainID]
+	delete(s.chains, chainID)
+	return exists
+}
+
+// Trigger starts running a chain for an alert and returns the execution
+// without waiting for it
+func (s *EscalationChainService) Trigger(chainID string, alert *UserMessage) (*ChainExecution, error) {
+	if alert == nil || alert.Body == "" {
+		return nil, errors.NewValidationError("alert", "alert body is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chain, exists := s.chains[chainID]
+	if !exists {
+		return nil, errors.NewNotificationError(errors.ErrorCodeNotFound, fmt.Sprintf("escalation chain not found: %s", chainID))
+	}
+
+	execution := &ChainExecution{
+		ID:        uuid.New().String(),
+		ChainID:   chainID,
+		Alert:     *alert,
+		Status:    ChainExecutionRunning,
+		StartedAt: time.Now(),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.executions[execution.ID] = execution
+	s.acks[execution.ID] = make(chan string, 1)
+	s.cancels[execution.ID] = cancel
+
+	s.wg.Add(1)
+	go s.run(ctx, cloneEscalationChain(chain), execution.ID)
+
+	s.logger.Infof("Triggered escalation chain %s for alert %q (execution %s)", chain.Name, alert.Subject, execution.ID)
+	return cloneChainExecution(execution), nil
+}
+
+// GetExecution returns a chain execution
+func (s *EscalationChainService) GetExecution(executionID string) (*ChainExecution, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	execution, exists := s.executions[executionID]
+	if !exists {
+		return nil, errors.NewNotificationError(errors.ErrorCodeNotFound, fmt.Sprintf("chain execution not found: %s", executionID))
+	}
+	return cloneChainExecution(execution), nil
+}
+
+// Acknowledge stops a running execution on behalf of a user, for example
+// an on-call engineer acknowledging the incident
+func (s *EscalationChainService) Acknowledge(executionID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	execution, exists := s.executions[executionID]
+	if !exists {
+		return errors.NewNotificationError(errors.ErrorCodeNotFound, fmt.Sprintf("chain execution not found: %s", executionID))
+	}
+	if execution.Status != ChainExecutionRunning {
+		return errors.NewValidationError("status", fmt.Sprintf("chain execution is already %s", execution.Status))
+	}
+
+	select {
+	case s.acks[executionID] <- userID:
+	default: // already acknowledged
+	}
+	return nil
+}
+
+// Cancel stops a running execution without an acknowledgement
+func (s *EscalationChainService) Cancel(executionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	execution, exists := s.executions[executionID]
+	if !exists {
+		return errors.NewNotificationError(errors.ErrorCodeNotFound, fmt.Sprintf("chain execution not found: %s", executionID))
+	}
+	if execution.Status != ChainExecutionRunning {
+		return errors.NewValidationError("status", fmt.Sprintf("chain execution is already %s", execution.Status))
+	}
+
+	s.cancels[executionID]()
+	return nil
+}
+
+// HandleEvent implements events.Subscriber, acknowledging the execution a
+// notification was sent by when it is acknowledged. Subscribe it for
+// EventNotificationAcknowledged.
+func (s *EscalationChainService) HandleEvent(ctx context.Context, event events.Event) error {
+	if event.Type != events.EventNotificationAcknowledged {
+		return nil
+	}
+
+	s.mu.Lock()
+	executionID, exists := s.notifications[event.NotificationID.String()]
+	s.mu.Unlock()
+	if !exists {
+		return nil
+	}
+
+	if err := s.Acknowledge(executionID, event.Metadata[MetadataUserID]); err != nil {
+		if notifErr, ok := errors.AsNotificationError(err); ok && notifErr.Code == errors.ErrorCodeValidationFailed {
+			return nil // the execution already finished
+		}
+		return err
+	}
+	return nil
+}
+
+// Stop cancels running executions and waits for them to finish
+func (s *EscalationChainService) Stop() {
+	s.mu.Lock()
+	for _, cancel := range s.cancels {
+		cancel()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+// run notifies the chain's steps in turn until the execution is
+// acknowledged, cancelled or every step ran
+func (s *EscalationChainService) run(ctx context.Context, chain *EscalationChain, executionID string) {
+	defer s.wg.Done()
+
+	s.mu.Lock()
+	acks := s.acks[executionID]
+	alert := s.executions[executionID].Alert
+	s.mu.Unlock()
+
+	for loop := 0; loop <= chain.Loops; loop++ {
+		for step, chainStep := range chain.Steps {
+			for repeat := 0; repeat <= chainStep.Repeat; repeat++ {
+				position := ChainLogEntry{Step: step, Loop: loop, Repeat: repeat}
+				for _, userID := range chainStep.Targets {
+					s.notify(ctx, executionID, position, userID, &alert, chainStep.Channels)
+				}
+
+				timer := time.NewTimer(chainStep.Delay)
+				select {
+				case userID := <-acks:
+					timer.Stop()
+					position.UserID = userID
+					s.finish(executionID, ChainExecutionAcknowledged, ChainActionAcknowledged, position)
+					return
+				case <-ctx.Done():
+					timer.Stop()
+					s.finish(executionID, ChainExecutionCancelled, ChainActionCancelled, position)
+					return
+				case <-timer.C:
+					position.Detail = fmt.Sprintf("no acknowledgement within %s", chainStep.Delay)
+					s.record(executionID, ChainActionTimedOut, position)
+				}
+			}
+		}
+	}
+
+	s.finish(executionID, ChainExecutionExhausted, ChainActionExhausted, ChainLogEntry{Loop: chain.Loops, Step: len(chain.Steps) - 1})
+	s.logger.Warnf("Escalation chain %s ran out of steps without an acknowledgement (execution %s)", chain.Name, executionID)
+}
+
+// notify notifies one target of a step and records the outcome. Its
+// notifications are tied to the execution so their acknowledgements stop it.
+func (s *EscalationChainService) notify(ctx context.Context, executionID string, position ChainLogEntry, userID string, alert *UserMessage, channels []models.NotificationType) {
+	message := *alert
+	message.Metadata = make(map[string]string, len(alert.Metadata)+1)
+	for key, value := range alert.Metadata {
+		message.Metadata[key] = value
+	}
+	message.Metadata[MetadataChainExecutionID] = executionID
+
+	position.UserID = userID
+	result, err := s.notifier.NotifyUser(ctx, userID, &message, EscalationPolicy{Channels: channels})
+	if err != nil {
+		position.Detail = err.Error()
+		s.record(executionID, ChainActionNotifyFailed, position)
+		return
+	}
+
+	for _, attempt := range result.Attempts {
+		if attempt.Response == nil {
+			continue
+		}
+		entry := position
+		entry.Channel = attempt.Channel
+		entry.NotificationID = attempt.Response.ID.String()
+
+		s.mu.Lock()
+		s.notifications[entry.NotificationID] = executionID
+		s.mu.Unlock()
+		s.record(executionID, ChainActionNotified, entry)
+	}
+}
+
+// record appends an entry to an execution's log
+func (s *EscalationChainService) record(executionID string, action ChainAction, entry ChainLogEntry) {
+	entry.At = time.Now()
+	entry.Action = action
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	execution := s.executions[executionID]
+	execution.Log = append(execution.Log, entry)
+}
+
+// finish records how an execution ended and releases its resources
+func (s *EscalationChainService) finish(executionID string, status ChainExecutionStatus, action ChainAction, entry ChainLogEntry) {
+	s.record(executionID, action, entry)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	execution := s.executions[executionID]
+	execution.Status = status
+	execution.FinishedAt = &now
+	if status == ChainExecutionAcknowledged {
+		execution.AcknowledgedBy = entry.UserID
+	}
+
+	s.cancels[executionID]()
+	delete(s.cancels, executionID)
+	delete(s.acks, executionID)
+	for notificationID, id := range s.notifications {
+		if id == executionID {
+			delete(s.notifications, notificationID)
+		}
+	}
+
+	s.logger.Infof("Escalation chain execution %s %s", executionID, status)
+}
+
+// validateEscalationChain checks a chain can be run
+func validateEscalationChain(chain *EscalationChain) error {
+	if chain == nil {
+		return errors.NewValidationError("chain", "escalation chain is required")
+	}
+	if chain.Name == "" {
+		return errors.NewValidationError("name", "chain name is required")
+	}
+	if len(chain.Steps) == 0 {
+		return errors.NewValidationError("steps", "chain must have at least one step")
+	}
+	if chain.Loops < 0 {
+		return errors.NewValidationError("loops", "loops cannot be negative")
+	}
+
+	for i, step := range chain.Steps {
+		field := fmt.Sprintf("steps[%d]", i)
+		if len(step.Targets) == 0 {
+			return errors.NewValidationError(field+".targets", "step must have at least one target")
+		}
+		for _, target := range step.Targets {
+			if target == "" {
+				return errors.NewValidationError(field+".targets", "target user ID is required")
+			}
+		}
+		if step.Delay <= 0 {
+			return errors.NewValidationError(field+".delay", "step delay must be positive")
+		}
+		if step.Repeat < 0 {
+			return errors.NewValidationError(field+".repeat", "repeat cannot be negative")
+		}
+		if err := validateUserChannels(step.Channels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cloneEscalationChain returns a copy of a chain that shares no slices with it
+func cloneEscalationChain(chain *EscalationChain) *EscalationChain {
+	clone := *chain
+	clone.Steps = make([]ChainStep, len(chain.Steps))
+	for i, step := range chain.Steps {
+		step.Targets = append([]string(nil), step.Targets...)
+		step.Channels = append([]models.NotificationType(nil), step.Channels...)
+		clone.Steps[i] = step
+	}
+	return &clone
+}
+
+// cloneChainExecution returns a copy of an execution that shares no log with it
+func cloneChainExecution(execution *ChainExecution) *ChainExecution {
+	clone := *execution
+	clone.Log = append([]ChainLogEntry(nil), execution.Log...)
+	return &clone
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/events"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+)
+
+func TestEscalationChainService_Exhausted(t *testing.T) {
+	service, _ := createTestEscalationChainService(t)
+	defer service.Stop()
+
+	chain, err := service.CreateChain(&EscalationChain{
+		Name: "database",
+		Steps: []ChainStep{
+			{Targets: []string{"user-1"}, Channels: []models.NotificationType{models.NotificationTypeSMS}, Delay: 10 * time.Millisecond, Repeat: 1},
+			{Targets: []string{"user-2", "unknown"}, Delay: 10 * time.Millisecond},
+		},
+	})
+	require.NoError(t, err)
+
+	execution, err := service.Trigger(chain.ID, &UserMessage{Subject: "db-1 down", Body: "Primary is unreachable"})
+	require.NoError(t, err)
+	assert.Equal(t, ChainExecutionRunning, execution.Status)
+
+	execution = waitForChainExecution(t, service, execution.ID)
+	assert.Equal(t, ChainExecutionExhausted, execution.Status)
+	assert.Empty(t, execution.AcknowledgedBy)
+
+	var actions []ChainAction
+	for _, entry := range execution.Log {
+		actions = append(actions, entry.Action)
+	}
+	assert.Equal(t, []ChainAction{
+		ChainActionNotified, ChainActionTimedOut, // user-1 by SMS
+		ChainActionNotified, ChainActionTimedOut, // user-1 again
+		ChainActionNotified, ChainActionNotifyFailed, ChainActionTimedOut, // user-2 by email, no contacts for unknown
+		ChainActionExhausted,
+	}, actions)
+	assert.Equal(t, models.NotificationTypeSMS, execution.Log[0].Channel)
+	assert.Equal(t, 1, execution.Log[2].Repeat)
+	assert.Equal(t, "user-2", execution.Log[4].UserID)
+	assert.Equal(t, 1, execution.Log[4].Step)
+	assert.Equal(t, models.NotificationTypeEmail, execution.Log[4].Channel)
+}
+
+func TestEscalationChainService_AcknowledgedByNotification(t *testing.T) {
+	service, dispatcher := createTestEscalationChainService(t)
+	defer service.Stop()
+	bus := events.NewBus(utils.NewSimpleLogger("info"))
+	dispatcher.SetEventPublisher(bus)
+	bus.Subscribe(service, events.EventNotificationAcknowledged)
+
+	chain, err := service.CreateChain(&EscalationChain{
+		Name: "payments",
+		Steps: []ChainStep{
+			{Targets: []string{"user-1"}, Delay: time.Minute},
+			{Targets: []string{"user-2"}, Delay: time.Minute},
+		},
+		Loops: 2,
+	})
+	require.NoError(t, err)
+
+	execution, err := service.Trigger(chain.ID, &UserMessage{Subject: "Checkout errors", Body: "5xx rate above 5%"})
+	require.NoError(t, err)
+
+	notificationID := waitForChainNotification(t, service, execution.ID)
+	notification, err := dispatcher.GetNotificationStatus(context.Background(), notificationID)
+	require.NoError(t, err)
+	assert.Equal(t, execution.ID, notification.Metadata[MetadataChainExecutionID])
+
+	_, err = dispatcher.AcknowledgeNotification(context.Background(), notificationID, &models.Acknowledgement{Source: models.AcknowledgeSourcePush})
+	require.NoError(t, err)
+
+	execution = waitForChainExecution(t, service, execution.ID)
+	assert.Equal(t, ChainExecutionAcknowledged, execution.Status)
+	assert.Equal(t, "user-1", execution.AcknowledgedBy)
+	assert.Equal(t, ChainActionAcknowledged, execution.Log[len(execution.Log)-1].Action)
+	assert.NotNil(t, execution.FinishedAt)
+
+	// Finished executions cannot be acknowledged or cancelled
+	assert.Error(t, service.Acknowledge(execution.ID, "user-2"))
+	assert.Error(t, service.Cancel(execution.ID))
+}
+
+func TestEscalationChainService_Cancel(t *testing.T) {
+	service, _ := createTestEscalationChainService(t)
+	defer service.Stop()
+
+	chain, err := service.CreateChain(&EscalationChain{Name: "web", Steps: []ChainStep{{Targets: []string{"user-1"}, Delay: time.Minute}}})
+	require.NoError(t, err)
+	execution, err := service.Trigger(chain.ID, &UserMessage{Subject: "Latency", Body: "p99 above 2s"})
+	require.NoError(t, err)
+
+	waitForChainNotification(t, service, execution.ID)
+	require.NoError(t, service.Cancel(execution.ID))
+	execution = waitForChainExecution(t, service, execution.ID)
+	assert.Equal(t, ChainExecutionCancelled, execution.Status)
+
+	// Acknowledging by execution also stops a run
+	execution, err = service.Trigger(chain.ID, &UserMessage{Subject: "Latency", Body: "p99 above 2s"})
+	require.NoError(t, err)
+	require.NoError(t, service.Acknowledge(execution.ID, "user-1"))
+	execution = waitForChainExecution(t, service, execution.ID)
+	assert.Equal(t, ChainExecutionAcknowledged, execution.Status)
+}
+
+func TestEscalationChainService_Chains(t *testing.T) {
+	service, _ := createTestEscalationChainService(t)
+	defer service.Stop()
+
+	tests := []struct {
+		name  string
+		chain *EscalationChain
+	}{
+		{"missing chain", nil},
+		{"missing name", &EscalationChain{Steps: []ChainStep{{Targets: []string{"user-1"}, Delay: time.Minute}}}},
+		{"no steps", &EscalationChain{Name: "web"}},
+		{"no targets", &EscalationChain{Name: "web", Steps: []ChainStep{{Delay: time.Minute}}}},
+		{"no delay", &EscalationChain{Name: "web", Steps: []ChainStep{{Targets: []string{"user-1"}}}}},
+		{"negative repeat", &EscalationChain{Name: "web", Steps: []ChainStep{{Targets: []string{"user-1"}, Delay: time.Minute, Repeat: -1}}}},
+		{"unreachable channel", &EscalationChain{Name: "web", Steps: []ChainStep{{Targets: []string{"user-1"}, Delay: time.Minute, Channels: []models.NotificationType{models.NotificationTypeChat}}}}},
+		{"negative loops", &EscalationChain{Name: "web", Steps: []ChainStep{{Targets: []string{"user-1"}, Delay: time.Minute}}, Loops: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.CreateChain(tt.chain)
+			assert.Error(t, err)
+		})
+	}
+
+	steps := []ChainStep{{Targets: []string{"user-1"}, Delay: time.Minute}}
+	web, err := service.CreateChain(&EscalationChain{Name: "web", Steps: steps})
+	require.NoError(t, err)
+	_, err = service.CreateChain(&EscalationChain{Name: "api", Steps: steps})
+	require.NoError(t, err)
+
+	chains := service.ListChains()
+	require.Len(t, chains, 2)
+	assert.Equal(t, "api", chains[0].Name)
+
+	// Stored chains are copies
+	steps[0].Targets[0] = "changed"
+	stored, err := service.GetChain(web.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", stored.Steps[0].Targets[0])
+
+	assert.True(t, service.DeleteChain(web.ID))
+	_, err = service.GetChain(web.ID)
+	assert.Error(t, err)
+	_, err = service.Trigger(web.ID, &UserMessage{Body: "Down"})
+	assert.Error(t, err)
+	_, err = service.GetExecution(uuid.New().String())
+	assert.Error(t, err)
+}
+
+// Helper functions
+
+func createTestEscalationChainService(t *testing.T) (*EscalationChainService, *Dispatcher) {
+	dispatcher := createTestDispatcher(t)
+	notifier, contacts, _ := createTestUserNotifier(t, dispatcher)
+	require.NoError(t, contacts.Set(UserContacts{UserID: "user-2", Email: "oncall@example.com"}))
+	return NewEscalationChainService(notifier, utils.NewSimpleLogger("info")), dispatcher
+}
+
+func waitForChainExecution(t *testing.T, service *EscalationChainService, id string) *ChainExecution {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		execution, err := service.GetExecution(id)
+		require.NoError(t, err)
+		if execution.Status != ChainExecutionRunning {
+			return execution
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("chain execution %s did not finish", id)
+	return nil
+}
+
+func waitForChainNotification(t *testing.T, service *EscalationChainService, id string) string {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		execution, err := service.GetExecution(id)
+		require.NoError(t, err)
+		for _, entry := range execution.Log {
+			if entry.Action == ChainActionNotified {
+				return entry.NotificationID
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("chain execution %s notified nobody", id)
+	return ""
+}
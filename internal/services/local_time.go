@@ -0,0 +1,50 @@
+package services
+
+import (
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// localTimeLayout is the format of send-at-local-time options, e.g. "09:00"
+const localTimeLayout = "15:04"
+
+// validateLocalTime validates a send-at-local-time option
+func validateLocalTime(value string) error {
+	if _, err := time.Parse(localTimeLayout, value); err != nil {
+		return errors.NewValidationError("send_at_local_time", "send at local time must be a time of day such as 09:00")
+	}
+	return nil
+}
+
+// validateTimezone validates an IANA time zone name
+func validateTimezone(timezone string) error {
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return errors.NewValidationError("timezone", "unknown time zone: "+timezone)
+	}
+	return nil
+}
+
+// nextLocalTime returns the first time at or after from that the clock
+// reads value in a time zone. Recipients without a known time zone are
+// treated as UTC.
+func nextLocalTime(from time.Time, value, timezone string) time.Time {
+	location := time.UTC
+	if timezone != "" {
+		if loaded, err := time.LoadLocation(timezone); err == nil {
+			location = loaded
+		}
+	}
+
+	clock, err := time.Parse(localTimeLayout, value)
+	if err != nil {
+		return from
+	}
+
+	local := from.In(location)
+	at := time.Date(local.Year(), local.Month(), local.Day(), clock.Hour(), clock.Minute(), 0, 0, location)
+	if at.Before(from) {
+		at = time.Date(local.Year(), local.Month(), local.Day()+1, clock.Hour(), clock.Minute(), 0, 0, location)
+	}
+	return at
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextLocalTime(t *testing.T) {
+	from := time.Date(2024, 3, 9, 15, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		value    string
+		timezone string
+		want     time.Time
+	}{
+		{"later today in UTC", "18:00", "", time.Date(2024, 3, 9, 18, 0, 0, 0, time.UTC)},
+		{"passed today in UTC", "09:00", "", time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)},
+		{"exactly now", "15:30", "UTC", from},
+		{"ahead of UTC", "09:00", "Asia/Kolkata", time.Date(2024, 3, 10, 3, 30, 0, 0, time.UTC)},
+		{"behind UTC", "09:00", "America/New_York", time.Date(2024, 3, 10, 13, 0, 0, 0, time.UTC)}, // after the DST change
+		{"unknown time zone", "18:00", "Mars/Olympus_Mons", time.Date(2024, 3, 9, 18, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, nextLocalTime(from, tt.value, tt.timezone).UTC())
+		})
+	}
+}
+
+func TestValidateLocalTime(t *testing.T) {
+	assert.NoError(t, validateLocalTime("09:00"))
+	assert.NoError(t, validateLocalTime("23:59"))
+	assert.Error(t, validateLocalTime("9am"))
+	assert.Error(t, validateLocalTime("24:00"))
+}
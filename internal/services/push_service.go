@@ -0,0 +1,962 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/layout"
+	"github.com/nareshkumar-microsoft/notificationService/internal/limits"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/privacy"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pushgroup"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pushmedia"
+	"github.com/nareshkumar-microsoft/notificationService/internal/templatevars"
+	"github.com/nareshkumar-microsoft/notificationService/internal/validation"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// PushService provides push notification functionality
+type PushService struct {
+	provider interfaces.PushProvider
+	config   config.PushProviderConfig
+	logger   interfaces.Logger
+	registry *DeviceRegistry
+	groups   *pushgroup.Grouper
+	media    *pushmedia.Checker
+	branding *layout.Library
+	limits   *limits.Limits
+
+	mu        sync.Mutex
+	scheduled map[*time.Timer]bool // pushes waiting for their local send time
+	now       func() time.Time
+}
+
+// NewPushService creates a new push notification service
+func NewPushService(cfg config.PushProviderConfig, logger interfaces.Logger) (*PushService, error) {
+	provider, err := providers.NewPushProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	service := &PushService{
+		provider:  provider,
+		config:    cfg,
+		logger:    logger,
+		registry:  NewDeviceRegistry(),
+		limits:    limits.New(config.LimitsConfig{}),
+		scheduled: make(map[*time.Timer]bool),
+		now:       time.Now,
+	}
+
+	return service, nil
+}
+
+// SendPush sends a push notification to a single device
+func (s *PushService) SendPush(ctx context.Context, request *PushRequest) (*models.NotificationResponse, error) {
+	// Validate request first
+	if err := s.validatePushRequest(request); err != nil {
+		s.logger.Errorf("Push validation failed: %v", err)
+		return nil, err
+	}
+
+	if err := s.checkImage(ctx, request); err != nil {
+		s.logger.Errorf("Push image check failed: %v", err)
+		return nil, err
+	}
+
+	if request.SendAtLocalTime != "" {
+		sendAt := nextLocalTime(s.now(), request.SendAtLocalTime, s.registry.Timezone(request.DeviceToken))
+		if sendAt.After(s.now()) {
+			if request.ExpiresAt != nil && !sendAt.Before(*request.ExpiresAt) {
+				s.logger.Warnf("Push to %s expires before %s device local time, not sending", maskDeviceToken(request.DeviceToken), request.SendAtLocalTime)
+				return expiredResponse(&s.createPushNotification(request).Notification), nil
+			}
+			return s.schedule(request, sendAt), nil
+		}
+	}
+
+	s.logger.Infof("Sending push to %s device %s", request.Platform, maskDeviceToken(request.DeviceToken))
+
+	// Check provider health
+	if err := s.provider.IsHealthy(ctx); err != nil {
+		s.logger.Errorf("Push provider health check failed: %v", err)
+		return nil, err
+	}
+
+	// Create push notification
+	pushNotification := s.createPushNotification(request)
+	if pushNotification.Expired(s.now()) {
+		s.logger.Warnf("Push %s expired before it was sent", pushNotification.ID)
+		return expiredResponse(&pushNotification.Notification), nil
+	}
+
+	// Apply template if specified
+	if request.TemplateID != "" {
+		if err := s.applyTemplate(pushNotification, request.TenantID, request.TemplateID, request.TemplateData); err != nil {
+			s.logger.Errorf("Template application failed: %v", err)
+			return nil, err
+		}
+	}
+
+	s.countGroup(pushNotification)
+
+	// Send push notification
+	response, err := s.provider.SendPush(ctx, pushNotification)
+	if err != nil {
+		s.logger.Errorf("Push sending failed: %v", err)
+		s.uncountGroup(pushNotification)
+		return nil, err
+	}
+
+	s.logger.Infof("Push sent successfully with ID: %s", response.ID)
+	return response, nil
+}
+
+// SendBulkPush sends push notifications to multiple devices. Providers with
+// a batch API send them in batches of up to their maximum batch size; others
+// are sent one at a time. Responses are in the order of the recipients.
+func (s *PushService) SendBulkPush(ctx context.Context, request *BulkPushRequest) ([]*models.NotificationResponse, error) {
+	s.logger.Infof("Sending bulk push to %d devices", len(request.Recipients))
+
+	if len(request.Recipients) == 0 {
+		return nil, errors.NewValidationError("recipients", "at least one recipient is required")
+	}
+
+	// Pushes at local time go out per device, so they are not batched
+	if batcher, ok := s.provider.(interfaces.BatchPushProvider); ok && request.SendAtLocalTime == "" {
+		return s.sendPushBatches(ctx, batcher, request), nil
+	}
+
+	responses := make([]*models.NotificationResponse, 0, len(request.Recipients))
+
+	for _, recipient := range request.Recipients {
+		response, err := s.SendPush(ctx, bulkPushRequest(request, recipient))
+		if err != nil {
+			s.logger.Errorf("Failed to send push to %s: %v", maskDeviceToken(recipient.DeviceToken), err)
+			// Continue with other recipients, but record the error
+			response = failedResponse(err)
+		}
+
+		responses = append(responses, response)
+	}
+
+	s.logger.Infof("Bulk push completed: %d notifications processed", len(responses))
+	return responses, nil
+}
+
+// ScheduledCount returns the number of pushes waiting for their local send time
+func (s *PushService) ScheduledCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.scheduled)
+}
+
+// Stop cancels pushes waiting for their local send time
+func (s *PushService) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for timer := range s.scheduled {
+		timer.Stop()
+		delete(s.scheduled, timer)
+	}
+}
+
+// schedule sends a validated push at a device's local send time
+func (s *PushService) schedule(request *PushRequest, sendAt time.Time) *models.NotificationResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	scheduled := *request
+	scheduled.SendAtLocalTime = ""
+
+	var timer *time.Timer
+	timer = time.AfterFunc(sendAt.Sub(s.now()), func() {
+		s.mu.Lock()
+		delete(s.scheduled, timer)
+		s.mu.Unlock()
+
+		if _, err := s.SendPush(context.Background(), &scheduled); err != nil {
+			s.logger.Errorf("Scheduled push to %s failed: %v", maskDeviceToken(scheduled.DeviceToken), err)
+		}
+	})
+	s.scheduled[timer] = true
+
+	s.logger.Infof("Scheduled push to %s for %s", maskDeviceToken(request.DeviceToken), sendAt.Format(time.RFC3339))
+	return &models.NotificationResponse{
+		Status:  models.StatusPending,
+		Message: fmt.Sprintf("scheduled for %s device local time", sendAt.Format(time.RFC3339)),
+	}
+}
+
+// SendPushToUser fans a push notification out to every active device
+// registered for a user, formatting it for each device's platform
+func (s *PushService) SendPushToUser(ctx context.Context, userID string, request *PushRequest) (*UserPushResponse, error) {
+	if userID == "" {
+		return nil, errors.NewValidationError("user_id", "user ID is required")
+	}
+
+	if request == nil {
+		return nil, errors.NewValidationError("request", "push request is required")
+	}
+
+	devices := s.registry.GetActiveDevices(userID)
+	if len(devices) == 0 {
+		return nil, errors.NewNotificationError(
+			errors.ErrorCodeInvalidRecipient,
+			fmt.Sprintf("no active devices registered for user: %s", userID),
+		)
+	}
+
+	s.logger.Infof("Sending push to user %s on %d devices", userID, len(devices))
+
+	result := &UserPushResponse{
+		UserID:       userID,
+		TotalDevices: len(devices),
+	}
+	result.Results, result.SuccessCount, result.FailureCount = s.sendToDevices(ctx, devices, request)
+
+	s.logger.Infof("User push completed for %s: %d succeeded, %d failed", userID, result.SuccessCount, result.FailureCount)
+	return result, nil
+}
+
+// SendPushToTopic broadcasts a push notification to every device subscribed
+// to a topic. Providers with native topic support receive a single topic
+// message when "native_topics" is enabled in the provider settings; otherwise
+// subscribers are resolved from the device registry and sent individually.
+func (s *PushService) SendPushToTopic(ctx context.Context, topic string, request *PushRequest) (*TopicPushResponse, error) {
+	if err := ValidateTopicName(topic); err != nil {
+		return nil, err
+	}
+
+	if request == nil {
+		return nil, errors.NewValidationError("request", "push request is required")
+	}
+
+	if topicProvider, ok := s.provider.(interfaces.TopicPushProvider); ok && s.config.Settings["native_topics"] == "true" {
+		return s.sendNativeTopicPush(ctx, topicProvider, topic, request)
+	}
+
+	devices := s.registry.GetTopicSubscribers(topic)
+	if len(devices) == 0 {
+		return nil, errors.NewNotificationError(
+			errors.ErrorCodeInvalidRecipient,
+			fmt.Sprintf("no active devices subscribed to topic: %s", topic),
+		)
+	}
+
+	s.logger.Infof("Broadcasting push to topic %s (%d subscribers)", topic, len(devices))
+
+	result := &TopicPushResponse{
+		Topic:        topic,
+		TotalDevices: len(devices),
+	}
+	result.Results, result.SuccessCount, result.FailureCount = s.sendToDevices(ctx, devices, request)
+
+	s.logger.Infof("Topic push completed for %s: %d succeeded, %d failed", topic, result.SuccessCount, result.FailureCount)
+	return result, nil
+}
+
+// sendToDevices sends a push to each device, formatting it for the device's
+// platform, and returns the per-device results with the success and failure
+// counts. Devices the platform no longer recognises are deactivated.
+func (s *PushService) sendToDevices(ctx context.Context, devices []*Device, request *PushRequest) ([]DevicePushResult, int, int) {
+	results := make([]DevicePushResult, 0, len(devices))
+	succeeded, failed := 0, 0
+
+	for _, device := range devices {
+		deviceRequest := *request
+		deviceRequest.DeviceToken = device.Token
+		deviceRequest.Platform = device.Platform
+
+		deviceResult := DevicePushResult{
+			DeviceID: device.ID,
+			Platform: device.Platform,
+		}
+
+		response, err := s.SendPush(ctx, &deviceRequest)
+		if err != nil {
+			deviceResult.Error = err.Error()
+			failed++
+
+			// Stop targeting devices the platform no longer recognises
+			if notifErr, ok := errors.AsNotificationError(err); ok && notifErr.Code == errors.ErrorCodeInvalidToken {
+				s.logger.Warnf("Deactivating unregistered device %s for user %s", device.ID, device.UserID)
+				s.registry.Deactivate(device.Token)
+			}
+		} else {
+			deviceResult.Response = response
+			succeeded++
+		}
+
+		results = append(results, deviceResult)
+	}
+
+	return results, succeeded, failed
+}
+
+// SubscribeToTopic subscribes a registered device to a topic
+func (s *PushService) SubscribeToTopic(token, topic string) error {
+	if err := s.registry.Subscribe(token, topic); err != nil {
+		return err
+	}
+
+	s.logger.Infof("Subscribed device %s to topic %s", maskDeviceToken(token), topic)
+	return nil
+}
+
+// UnsubscribeFromTopic removes a device from a topic
+func (s *PushService) UnsubscribeFromTopic(token, topic string) error {
+	if err := s.registry.Unsubscribe(token, topic); err != nil {
+		return err
+	}
+
+	s.logger.Infof("Unsubscribed device %s from topic %s", maskDeviceToken(token), topic)
+	return nil
+}
+
+// GetTopicSubscribers returns the active devices subscribed to a topic
+func (s *PushService) GetTopicSubscribers(topic string) []*Device {
+	return s.registry.GetTopicSubscribers(topic)
+}
+
+// RegisterDevice registers a device for a user
+func (s *PushService) RegisterDevice(device *Device) (*Device, error) {
+	if device != nil {
+		if err := s.provider.ValidateDeviceToken(device.Token, device.Platform); err != nil {
+			return nil, err
+		}
+	}
+
+	registered, err := s.registry.Register(device)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("Registered %s device %s for user %s", registered.Platform, registered.ID, registered.UserID)
+	return registered, nil
+}
+
+// UnregisterDevice removes a device from the registry
+func (s *PushService) UnregisterDevice(token string) error {
+	return s.registry.Unregister(token)
+}
+
+// GetUserDevices returns all devices registered for a user
+func (s *PushService) GetUserDevices(userID string) []*Device {
+	return s.registry.GetUserDevices(userID)
+}
+
+// DeviceRegistry returns the registry of devices push notifications are sent to
+func (s *PushService) DeviceRegistry() *DeviceRegistry {
+	return s.registry
+}
+
+// RenderTemplate renders a push template with data
+func (s *PushService) RenderTemplate(templateID string, data map[string]string) (*RenderedPushTemplate, error) {
+	mockProvider, ok := s.provider.(*providers.MockPushProvider)
+	if !ok {
+		return nil, errors.NewNotificationError(
+			errors.ErrorCodeProviderNotFound,
+			"template rendering not supported by this provider",
+		)
+	}
+
+	template, err := mockProvider.RenderTemplate(templateID, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RenderedPushTemplate{
+		ID:      template.ID,
+		Title:   template.Title,
+		Message: template.Message,
+		Sound:   template.Sound,
+		Badge:   template.Badge,
+		Actions: template.Actions,
+	}, nil
+}
+
+// ValidateDeviceToken validates a device token for a platform
+func (s *PushService) ValidateDeviceToken(token, platform string) error {
+	return s.provider.ValidateDeviceToken(token, platform)
+}
+
+// GetProviderStatus returns the current provider status
+func (s *PushService) GetProviderStatus(ctx context.Context) *ProviderStatus {
+	status := &ProviderStatus{
+		Name:    s.provider.GetConfig().Name,
+		Type:    string(s.provider.GetType()),
+		Healthy: true,
+	}
+
+	if err := s.provider.IsHealthy(ctx); err != nil {
+		status.Healthy = false
+		status.Error = err.Error()
+	}
+
+	return status
+}
+
+// sendNativeTopicPush delegates a topic broadcast to the provider. The
+// request is validated as SendPush validates it, except that it names no
+// device; its image must fit every platform unless a platform is given.
+func (s *PushService) sendNativeTopicPush(ctx context.Context, provider interfaces.TopicPushProvider, topic string, request *PushRequest) (*TopicPushResponse, error) {
+	if request.SendAtLocalTime != "" {
+		return nil, errors.NewValidationError("send_at_local_time", "topic pushes cannot be held until device local time")
+	}
+	if request.Platform != "" {
+		if _, exists := pushmedia.PlatformLimits(request.Platform); !exists {
+			return nil, errors.NewValidationError("platform", fmt.Sprintf("unsupported platform: %s", request.Platform))
+		}
+	}
+	if err := s.validatePushContent(request); err != nil {
+		s.logger.Errorf("Push validation failed: %v", err)
+		return nil, err
+	}
+
+	if err := s.checkImage(ctx, request); err != nil {
+		s.logger.Errorf("Push image check failed: %v", err)
+		return nil, err
+	}
+
+	s.logger.Infof("Delegating push broadcast to provider topic %s", topic)
+
+	if err := s.provider.IsHealthy(ctx); err != nil {
+		s.logger.Errorf("Push provider health check failed: %v", err)
+		return nil, err
+	}
+
+	pushNotification := s.createPushNotification(request)
+	pushNotification.Recipient = fmt.Sprintf("/topics/%s", topic)
+	pushNotification.Metadata["topic"] = topic
+	if pushNotification.Expired(s.now()) {
+		s.logger.Warnf("Push %s to topic %s expired before it was sent", pushNotification.ID, topic)
+		return &TopicPushResponse{
+			Topic:     topic,
+			Delegated: true,
+			Response:  expiredResponse(&pushNotification.Notification),
+		}, nil
+	}
+
+	if request.TemplateID != "" {
+		if err := s.applyTemplate(pushNotification, request.TenantID, request.TemplateID, request.TemplateData); err != nil {
+			s.logger.Errorf("Template application failed: %v", err)
+			return nil, err
+		}
+	}
+
+	response, err := provider.SendPushToTopic(ctx, topic, pushNotification)
+	if err != nil {
+		s.logger.Errorf("Topic push failed: %v", err)
+		return nil, err
+	}
+
+	return &TopicPushResponse{
+		Topic:     topic,
+		Delegated: true,
+		Response:  response,
+	}, nil
+}
+
+// sendPushBatches prepares a notification for every recipient of a bulk
+// request and sends them through the provider's batch API. Recipients whose
+// notification cannot be prepared, or whose batch fails, get failed responses.
+func (s *PushService) sendPushBatches(ctx context.Context, batcher interfaces.BatchPushProvider, request *BulkPushRequest) []*models.NotificationResponse {
+	responses := make([]*models.NotificationResponse, len(request.Recipients))
+
+	if err := s.provider.IsHealthy(ctx); err != nil {
+		s.logger.Errorf("Push provider health check failed: %v", err)
+		for i := range responses {
+			responses[i] = failedResponse(err)
+		}
+		return responses
+	}
+
+	pushes := make([]*models.PushNotification, 0, len(request.Recipients))
+	indexes := make([]int, 0, len(request.Recipients))
+	for i, recipient := range request.Recipients {
+		pushRequest := bulkPushRequest(request, recipient)
+		err := s.validatePushRequest(pushRequest)
+		if err == nil {
+			err = s.checkImage(ctx, pushRequest)
+		}
+		var push *models.PushNotification
+		if err == nil {
+			push = s.createPushNotification(pushRequest)
+			if push.Expired(s.now()) {
+				responses[i] = expiredResponse(&push.Notification)
+				continue
+			}
+			if pushRequest.TemplateID != "" {
+				err = s.applyTemplate(push, pushRequest.TenantID, pushRequest.TemplateID, pushRequest.TemplateData)
+			}
+		}
+		if err != nil {
+			s.logger.Errorf("Failed to prepare push to %s: %v", maskDeviceToken(recipient.DeviceToken), err)
+			responses[i] = failedResponse(err)
+			continue
+		}
+		s.countGroup(push)
+		pushes = append(pushes, push)
+		indexes = append(indexes, i)
+	}
+
+	size := batcher.MaxBatchSize()
+	if size < 1 {
+		size = 1
+	}
+	batches := 0
+	for start := 0; start < len(pushes); start += size {
+		end := start + size
+		if end > len(pushes) {
+			end = len(pushes)
+		}
+		batches++
+
+		results, err := batcher.SendPushBatch(ctx, pushes[start:end])
+		for j := start; j < end; j++ {
+			switch {
+			case err != nil:
+				responses[indexes[j]] = failedResponse(err)
+				s.uncountGroup(pushes[j])
+			case results[j-start].Err != nil:
+				responses[indexes[j]] = failedResponse(results[j-start].Err)
+				s.uncountGroup(pushes[j])
+			default:
+				responses[indexes[j]] = results[j-start].Response
+			}
+		}
+		if err != nil {
+			s.logger.Errorf("Push batch of %d failed: %v", end-start, err)
+		}
+	}
+
+	s.logger.Infof("Bulk push completed: %d notifications processed in %d batches", len(responses), batches)
+	return responses
+}
+
+// SetMedia checks push images against the platform's limits before sending
+func (s *PushService) SetMedia(checker *pushmedia.Checker) {
+	s.media = checker
+}
+
+// checkImage checks a request's image, when a checker is set
+func (s *PushService) checkImage(ctx context.Context, request *PushRequest) error {
+	if s.media == nil || request.ImageURL == "" {
+		return nil
+	}
+	_, err := s.media.Check(ctx, request.ImageURL, request.Platform)
+	return err
+}
+
+// SetBranding injects tenant branding into push templates as {{brand.<key>}},
+// typically from the email provider's layout library so both channels share it
+func (s *PushService) SetBranding(library *layout.Library) {
+	s.branding = library
+}
+
+// SetLimits replaces the data key limit push notifications are checked against
+func (s *PushService) SetLimits(payloadLimits *limits.Limits) {
+	s.limits = payloadLimits
+}
+
+// SetGroups counts grouped push notifications per device, so their group
+// summaries can say how many alerts are in the group
+func (s *PushService) SetGroups(grouper *pushgroup.Grouper) {
+	s.groups = grouper
+}
+
+// countGroup counts a grouped push notification, when a grouper is set
+func (s *PushService) countGroup(push *models.PushNotification) {
+	if s.groups != nil {
+		s.groups.Apply(push)
+	}
+}
+
+// uncountGroup uncounts a grouped push notification that was not sent
+func (s *PushService) uncountGroup(push *models.PushNotification) {
+	if s.groups != nil && push.Group != "" {
+		s.groups.Remove(push.DeviceToken, push.Group)
+	}
+}
+
+// bulkPushRequest builds the push request for one recipient of a bulk request
+func bulkPushRequest(request *BulkPushRequest, recipient BulkPushRecipient) *PushRequest {
+	return &PushRequest{
+		DeviceToken:  recipient.DeviceToken,
+		Platform:     recipient.Platform,
+		Title:        request.Title,
+		Message:      request.Message,
+		Icon:         request.Icon,
+		Sound:        request.Sound,
+		Data:         request.Data,
+		ImageURL:     request.ImageURL,
+		ClickAction:  request.ClickAction,
+		TemplateID:   request.TemplateID,
+		TemplateData: mergeTemplateData(request.TemplateData, recipient.Data),
+		TenantID:     request.TenantID,
+		Priority:     request.Priority,
+		Metadata:     request.Metadata,
+		ExpiresAt:    request.ExpiresAt,
+
+		CollapseKey:       request.CollapseKey,
+		ThreadID:          request.ThreadID,
+		Category:          request.Category,
+		TTL:               request.TTL,
+		ContentAvailable:  request.ContentAvailable,
+		Silent:            request.Silent,
+		DataOnly:          request.DataOnly,
+		MutableContent:    request.MutableContent,
+		ChannelID:         request.ChannelID,
+		InterruptionLevel: request.InterruptionLevel,
+
+		Actions:            request.Actions,
+		Tag:                request.Tag,
+		Renotify:           request.Renotify,
+		RequireInteraction: request.RequireInteraction,
+		Vibrate:            request.Vibrate,
+
+		Group:        request.Group,
+		GroupMode:    request.GroupMode,
+		GroupSummary: request.GroupSummary,
+
+		SendAtLocalTime: request.SendAtLocalTime,
+	}
+}
+
+// validatePushRequest validates a push request
+func (s *PushService) validatePushRequest(request *PushRequest) error {
+	if request == nil {
+		return errors.NewValidationError("request", "push request is required")
+	}
+
+	if err := validation.Struct(request); err != nil {
+		return err
+	}
+
+	if request.SendAtLocalTime != "" {
+		if err := validateLocalTime(request.SendAtLocalTime); err != nil {
+			return err
+		}
+	}
+
+	// Validate device token
+	if err := s.provider.ValidateDeviceToken(request.DeviceToken, request.Platform); err != nil {
+		return err
+	}
+
+	return s.validatePushContent(request)
+}
+
+// validatePushContent validates what a push shows or carries: silent and
+// data-only pushes have no visible content, others need some, and data must
+// fit the payload limits
+func (s *PushService) validatePushContent(request *PushRequest) error {
+	if request.Silent {
+		if request.Title != "" || request.Message != "" || request.Sound != "" || request.Badge != 0 || request.TemplateID != "" {
+			return errors.NewValidationError("silent", "silent push notifications cannot include a title, message, sound, badge or template")
+		}
+	} else if request.DataOnly {
+		if len(request.Data) == 0 {
+			return errors.NewValidationError("data", "data-only push notifications require a data payload")
+		}
+	} else if request.Title == "" && request.Message == "" && request.TemplateID == "" {
+		return errors.NewValidationError("message", "push title or message is required when not using a template")
+	}
+
+	return s.limits.CheckPushData(request.Data)
+}
+
+// createPushNotification creates a push notification from a request
+func (s *PushService) createPushNotification(request *PushRequest) *models.PushNotification {
+	now := time.Now()
+
+	notification := &models.PushNotification{
+		Notification: models.Notification{
+			ID:         uuid.New(),
+			Type:       models.NotificationTypePush,
+			Status:     models.StatusPending,
+			Priority:   request.Priority,
+			Recipient:  request.DeviceToken,
+			Subject:    request.Title,
+			Body:       request.Message,
+			Metadata:   request.Metadata,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+			ExpiresAt:  request.ExpiresAt,
+			RetryCount: 0,
+			MaxRetries: 3,
+		},
+		DeviceToken: request.DeviceToken,
+		Platform:    strings.ToLower(request.Platform),
+		Title:       request.Title,
+		Message:     request.Message,
+		Icon:        request.Icon,
+		Badge:       request.Badge,
+		Sound:       request.Sound,
+		Data:        request.Data,
+		ImageURL:    request.ImageURL,
+		ClickAction: request.ClickAction,
+
+		CollapseKey:       request.CollapseKey,
+		ThreadID:          request.ThreadID,
+		Category:          request.Category,
+		TTL:               request.TTL,
+		ContentAvailable:  request.ContentAvailable,
+		Silent:            request.Silent,
+		DataOnly:          request.DataOnly,
+		MutableContent:    request.MutableContent,
+		ChannelID:         request.ChannelID,
+		InterruptionLevel: request.InterruptionLevel,
+
+		Actions:            request.Actions,
+		Tag:                request.Tag,
+		Renotify:           request.Renotify,
+		RequireInteraction: request.RequireInteraction,
+		Vibrate:            request.Vibrate,
+
+		Group:        request.Group,
+		GroupMode:    request.GroupMode,
+		GroupSummary: request.GroupSummary,
+	}
+
+	// Add platform to metadata
+	if notification.Metadata == nil {
+		notification.Metadata = make(map[string]string)
+	}
+	notification.Metadata["platform"] = notification.Platform
+
+	return notification
+}
+
+// applyTemplate applies a template to a push notification, with the tenant's
+// branding when branding is set
+func (s *PushService) applyTemplate(push *models.PushNotification, tenantID, templateID string, data map[string]string) error {
+	mockProvider, ok := s.provider.(*providers.MockPushProvider)
+	if !ok {
+		return errors.NewNotificationError(
+			errors.ErrorCodeProviderNotFound,
+			"template rendering not supported by this provider",
+		)
+	}
+
+	data = templatevars.WithRecipient(data, push.Recipient)
+	if s.branding != nil {
+		data = s.branding.Data(tenantID, data)
+	}
+	template, err := mockProvider.RenderTemplate(templateID, data)
+	if err != nil {
+		return err
+	}
+
+	// Apply template content
+	push.Title = template.Title
+	push.Message = template.Message
+	push.Subject = template.Title
+	push.Body = template.Message
+
+	if push.Sound == "" {
+		push.Sound = template.Sound
+	}
+	if push.Badge == 0 {
+		push.Badge = template.Badge
+	}
+	if len(push.Actions) == 0 {
+		push.Actions = template.Actions
+	}
+
+	return nil
+}
+
+// Helper functions
+
+// maskDeviceToken shortens a device token for logging
+func maskDeviceToken(token string) string {
+	return privacy.MaskToken(token)
+}
+
+// mergeTemplateData merges global and recipient-specific template data
+func mergeTemplateData(global, recipient map[string]string) map[string]string {
+	merged := make(map[string]string)
+
+	// Add global data first
+	for key, value := range global {
+		merged[key] = value
+	}
+
+	// Override with recipient-specific data
+	for key, value := range recipient {
+		merged[key] = value
+	}
+
+	return merged
+}
+
+// failedResponse records a bulk send that failed for one recipient
+func failedResponse(err error) *models.NotificationResponse {
+	return &models.NotificationResponse{
+		ID:     uuid.New(),
+		Status: models.StatusFailed,
+		Error:  err.Error(),
+	}
+}
+
+// expiredResponse is the response for a notification not sent because it expired first
+func expiredResponse(notification *models.Notification) *models.NotificationResponse {
+	return &models.NotificationResponse{
+		ID:      notification.ID,
+		Status:  models.StatusExpired,
+		Message: "notification expired before it was sent",
+	}
+}
+
+// Request and response types
+
+// PushRequest represents a request to send a push notification
+type PushRequest struct {
+	DeviceToken  string            `json:"device_token" validate:"required"`
+	Platform     string            `json:"platform" validate:"required,oneof=ios android web"`
+	Title        string            `json:"title,omitempty"`
+	Message      string            `json:"message,omitempty"`
+	Icon         string            `json:"icon,omitempty"`
+	Badge        int               `json:"badge,omitempty"`
+	Sound        string            `json:"sound,omitempty"`
+	Data         map[string]string `json:"data,omitempty"`
+	ImageURL     string            `json:"image_url,omitempty"`
+	ClickAction  string            `json:"click_action,omitempty"`
+	TemplateID   string            `json:"template_id,omitempty"`
+	TemplateData map[string]string `json:"template_data,omitempty"`
+	TenantID     string            `json:"tenant_id,omitempty"` // selects the branding injected into templates
+	Priority     models.Priority   `json:"priority"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	ExpiresAt    *time.Time        `json:"expires_at,omitempty"` // not sent after this time; platforms stop delivery attempts then
+
+	// Rich payload options
+	CollapseKey       string `json:"collapse_key,omitempty"`
+	ThreadID          string `json:"thread_id,omitempty"`
+	Category          string `json:"category,omitempty"`
+	TTL               int    `json:"ttl,omitempty"`
+	ContentAvailable  bool   `json:"content_available,omitempty"`
+	Silent            bool   `json:"silent,omitempty"`
+	DataOnly          bool   `json:"data_only,omitempty"`
+	MutableContent    bool   `json:"mutable_content,omitempty"`
+	ChannelID         string `json:"channel_id,omitempty"`
+	InterruptionLevel string `json:"interruption_level,omitempty"`
+
+	// Web Push display options
+	Actions            []models.PushAction `json:"actions,omitempty"`
+	Tag                string              `json:"tag,omitempty"`
+	Renotify           bool                `json:"renotify,omitempty"`
+	RequireInteraction bool                `json:"require_interaction,omitempty"`
+	Vibrate            []int               `json:"vibrate,omitempty"`
+
+	// Tray grouping
+	Group        string               `json:"group,omitempty"`
+	GroupMode    models.PushGroupMode `json:"group_mode,omitempty"`
+	GroupSummary string               `json:"group_summary,omitempty"`
+
+	// SendAtLocalTime, e.g. "09:00", holds the push until that time in the
+	// device's registered time zone
+	SendAtLocalTime string `json:"send_at_local_time,omitempty"`
+}
+
+// BulkPushRequest represents a request to send push notifications to multiple devices
+type BulkPushRequest struct {
+	Recipients   []BulkPushRecipient `json:"recipients" validate:"required,min=1"`
+	Title        string              `json:"title,omitempty"`
+	Message      string              `json:"message,omitempty"`
+	Icon         string              `json:"icon,omitempty"`
+	Sound        string              `json:"sound,omitempty"`
+	Data         map[string]string   `json:"data,omitempty"`
+	ImageURL     string              `json:"image_url,omitempty"`
+	ClickAction  string              `json:"click_action,omitempty"`
+	TemplateID   string              `json:"template_id,omitempty"`
+	TemplateData map[string]string   `json:"template_data,omitempty"`
+	TenantID     string              `json:"tenant_id,omitempty"`
+	Priority     models.Priority     `json:"priority"`
+	Metadata     map[string]string   `json:"metadata,omitempty"`
+	ExpiresAt    *time.Time          `json:"expires_at,omitempty"`
+
+	// Rich payload options
+	CollapseKey       string `json:"collapse_key,omitempty"`
+	ThreadID          string `json:"thread_id,omitempty"`
+	Category          string `json:"category,omitempty"`
+	TTL               int    `json:"ttl,omitempty"`
+	ContentAvailable  bool   `json:"content_available,omitempty"`
+	Silent            bool   `json:"silent,omitempty"`
+	DataOnly          bool   `json:"data_only,omitempty"`
+	MutableContent    bool   `json:"mutable_content,omitempty"`
+	ChannelID         string `json:"channel_id,omitempty"`
+	InterruptionLevel string `json:"interruption_level,omitempty"`
+
+	// Web Push display options
+	Actions            []models.PushAction `json:"actions,omitempty"`
+	Tag                string              `json:"tag,omitempty"`
+	Renotify           bool                `json:"renotify,omitempty"`
+	RequireInteraction bool                `json:"require_interaction,omitempty"`
+	Vibrate            []int               `json:"vibrate,omitempty"`
+
+	// Tray grouping
+	Group        string               `json:"group,omitempty"`
+	GroupMode    models.PushGroupMode `json:"group_mode,omitempty"`
+	GroupSummary string               `json:"group_summary,omitempty"`
+
+	SendAtLocalTime string `json:"send_at_local_time,omitempty"`
+}
+
+// BulkPushRecipient represents a device in a bulk push request
+type BulkPushRecipient struct {
+	DeviceToken string            `json:"device_token" validate:"required"`
+	Platform    string            `json:"platform" validate:"required,oneof=ios android web"`
+	Data        map[string]string `json:"data,omitempty"`
+}
+
+// UserPushResponse aggregates the per-device results of a push sent to a user
+type UserPushResponse struct {
+	UserID       string             `json:"user_id"`
+	TotalDevices int                `json:"total_devices"`
+	SuccessCount int                `json:"success_count"`
+	FailureCount int                `json:"failure_count"`
+	Results      []DevicePushResult `json:"results"`
+}
+
+// DevicePushResult represents the outcome of a push to a single device
+type DevicePushResult struct {
+	DeviceID string                       `json:"device_id"`
+	Platform string                       `json:"platform"`
+	Response *models.NotificationResponse `json:"response,omitempty"`
+	Error    string                       `json:"error,omitempty"`
+}
+
+// TopicPushResponse represents the outcome of a push broadcast to a topic.
+// Delegated broadcasts carry the provider's single response instead of
+// per-device results.
+type TopicPushResponse struct {
+	Topic        string                       `json:"topic"`
+	Delegated    bool                         `json:"delegated"`
+	Response     *models.NotificationResponse `json:"response,omitempty"`
+	TotalDevices int                          `json:"total_devices"`
+	SuccessCount int                          `json:"success_count"`
+	FailureCount int                          `json:"failure_count"`
+	Results      []DevicePushResult           `json:"results,omitempty"`
+}
+
+// RenderedPushTemplate represents a rendered push template
+type RenderedPushTemplate struct {
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	Message string `json:"message"`
+	Sound   string `json:"sound,omitempty"`
+	Badge   int    `json:"badge,omitempty"`
+
+	Actions []models.PushAction `json:"actions,omitempty"`
+}
@@ -0,0 +1,760 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/layout"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pushgroup"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pushmedia"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+const (
+	testIOSToken     = "a1b2c3d4e5f6a1b2c3d4e5f6a1b2c3d4e5f6a1b2c3d4e5f6a1b2c3d4e5f6a1b2"
+	testWebPushToken = "web-push-subscription-token-0001"
+)
+
+var testAndroidToken = strings.Repeat("f", 152)
+
+func TestNewPushService(t *testing.T) {
+	cfg := config.PushProviderConfig{
+		Provider: "mock",
+		Enabled:  true,
+	}
+	logger := utils.NewSimpleLogger("info")
+
+	service, err := NewPushService(cfg, logger)
+	require.NoError(t, err)
+	assert.NotNil(t, service)
+	assert.Equal(t, cfg, service.config)
+}
+
+func TestNewPushService_UnsupportedProvider(t *testing.T) {
+	cfg := config.PushProviderConfig{
+		Provider: "unsupported",
+		Enabled:  true,
+	}
+
+	service, err := NewPushService(cfg, utils.NewSimpleLogger("info"))
+	assert.Error(t, err)
+	assert.Nil(t, service)
+
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeProviderNotFound, notifErr.Code)
+}
+
+func TestPushService_SendPush_Success(t *testing.T) {
+	service := createTestPushService()
+
+	request := &PushRequest{
+		DeviceToken: testIOSToken,
+		Platform:    "ios",
+		Title:       "Hello",
+		Message:     "Test push message",
+		Priority:    models.PriorityNormal,
+	}
+
+	response, err := service.SendPush(context.Background(), request)
+
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusSent, response.Status)
+}
+
+func TestPushService_SendPush_ValidationErrors(t *testing.T) {
+	service := createTestPushService()
+
+	tests := []struct {
+		name    string
+		request *PushRequest
+	}{
+		{"nil request", nil},
+		{"missing platform", &PushRequest{DeviceToken: testIOSToken, Title: "Hi"}},
+		{"invalid token", &PushRequest{DeviceToken: "bad", Platform: "ios", Title: "Hi"}},
+		{"no content", &PushRequest{DeviceToken: testIOSToken, Platform: "ios"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := service.SendPush(context.Background(), tt.request)
+			assert.Error(t, err)
+			assert.Nil(t, response)
+
+			notifErr, ok := errors.AsNotificationError(err)
+			require.True(t, ok)
+			assert.Equal(t, errors.ErrorCodeValidationFailed, notifErr.Code)
+		})
+	}
+}
+
+func TestPushService_SendPush_WithTemplate(t *testing.T) {
+	service := createTestPushService()
+
+	request := &PushRequest{
+		DeviceToken: testAndroidToken,
+		Platform:    "android",
+		TemplateID:  "order_update",
+		TemplateData: map[string]string{
+			"order_id":     "A-100",
+			"order_status": "shipped",
+		},
+	}
+
+	response, err := service.SendPush(context.Background(), request)
+
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusSent, response.Status)
+}
+
+func TestPushService_SendPush_TemplateBranding(t *testing.T) {
+	service := createTestPushService()
+	mock := service.provider.(*providers.MockPushProvider)
+	require.NoError(t, mock.AddTemplate(&providers.PushTemplate{
+		ID:      "branded",
+		Title:   "{{brand.name}}",
+		Message: "Questions? {{brand.support_email}}",
+	}))
+	library := layout.NewLibrary()
+	library.SetBranding("acme", map[string]string{"name": "Acme", "support_email": "help@acme.example"})
+	service.SetBranding(library)
+
+	_, err := service.SendPush(context.Background(), &PushRequest{
+		DeviceToken: testAndroidToken,
+		Platform:    "android",
+		TemplateID:  "branded",
+		TenantID:    "acme",
+	})
+	require.NoError(t, err)
+
+	sentPush := mock.GetSentPush()
+	require.Len(t, sentPush, 1)
+	assert.Equal(t, "Acme", sentPush[0].Title)
+	assert.Equal(t, "Questions? help@acme.example", sentPush[0].Message)
+}
+
+func TestPushService_SendPush_TemplateActions(t *testing.T) {
+	service := createTestPushService()
+
+	request := &PushRequest{
+		DeviceToken: testWebPushToken,
+		Platform:    "web",
+		TemplateID:  "new_message",
+		TemplateData: map[string]string{
+			"sender_name":     "Ana",
+			"message_preview": "Lunch?",
+		},
+	}
+
+	_, err := service.SendPush(context.Background(), request)
+	require.NoError(t, err)
+
+	sentPush := service.provider.(*providers.MockPushProvider).GetSentPush()
+	require.Len(t, sentPush, 1)
+
+	// Template actions are delivered to the browser as notification actions
+	actions := sentPush[0].Payload.Body["actions"].([]map[string]string)
+	require.Len(t, actions, 2)
+	assert.Equal(t, "reply", actions[0]["action"])
+	assert.Equal(t, "mark_read", actions[1]["action"])
+}
+
+func TestPushService_SendPush_DataOnly(t *testing.T) {
+	service := createTestPushService()
+
+	request := &PushRequest{
+		DeviceToken: testAndroidToken,
+		Platform:    "android",
+		DataOnly:    true,
+		CollapseKey: "sync",
+		Data:        map[string]string{"action": "refresh"},
+	}
+
+	response, err := service.SendPush(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusSent, response.Status)
+
+	sentPush := service.provider.(*providers.MockPushProvider).GetSentPush()
+	require.Len(t, sentPush, 1)
+	message := sentPush[0].Payload.Body["message"].(map[string]interface{})
+	assert.NotContains(t, message, "notification")
+
+	// Data-only pushes still need data
+	request.Data = nil
+	_, err = service.SendPush(context.Background(), request)
+	assert.Error(t, err)
+}
+
+func TestPushService_SendPush_Silent(t *testing.T) {
+	service := createTestPushService()
+
+	request := &PushRequest{
+		DeviceToken: testIOSToken,
+		Platform:    "ios",
+		Silent:      true,
+		Priority:    models.PriorityHigh,
+		Data:        map[string]string{"sync": "mail"},
+	}
+
+	_, err := service.SendPush(context.Background(), request)
+	require.NoError(t, err)
+
+	sentPush := service.provider.(*providers.MockPushProvider).GetSentPush()
+	require.Len(t, sentPush, 1)
+	assert.Equal(t, "5", sentPush[0].Payload.Headers["apns-priority"])
+
+	request.Title = "Visible"
+	_, err = service.SendPush(context.Background(), request)
+	require.Error(t, err)
+
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, "silent", notifErr.Metadata["field"])
+}
+
+func TestPushService_SendPush_Groups(t *testing.T) {
+	service := createTestPushService()
+	provider := service.provider.(*providers.MockPushProvider)
+	grouper := pushgroup.NewGrouper(config.PushGroupConfig{})
+	service.SetGroups(grouper)
+
+	request := &PushRequest{
+		DeviceToken:  testAndroidToken,
+		Platform:     "android",
+		Title:        "Order shipped",
+		Message:      "Order 42 is on its way",
+		Priority:     models.PriorityNormal,
+		Group:        "orders",
+		GroupSummary: "{{count}} order updates",
+	}
+	for i := 0; i < 2; i++ {
+		_, err := service.SendPush(context.Background(), request)
+		require.NoError(t, err)
+	}
+
+	sent := provider.GetSentPush()
+	require.Len(t, sent, 2)
+	fcm := sent[1].Payload.Body["message"].(map[string]interface{})
+	assert.Equal(t, "2 order updates", fcm["data"].(map[string]string)["group_summary"])
+
+	// Failed sends are not counted
+	provider.SetHealthy(false)
+	_, err := service.SendPush(context.Background(), request)
+	require.Error(t, err)
+	assert.Equal(t, 2, grouper.Count(testAndroidToken, "orders"))
+
+	// Bulk sends are counted per device
+	provider.SetHealthy(true)
+	responses, err := service.SendBulkPush(context.Background(), &BulkPushRequest{
+		Recipients: []BulkPushRecipient{{DeviceToken: testAndroidToken, Platform: "android"}, {DeviceToken: testIOSToken, Platform: "ios"}},
+		Message:    "Order 43 is on its way",
+		Group:      "orders",
+	})
+	require.NoError(t, err)
+	require.Len(t, responses, 2)
+	assert.Equal(t, 3, grouper.Count(testAndroidToken, "orders"))
+	assert.Equal(t, 1, grouper.Count(testIOSToken, "orders"))
+}
+
+func TestPushService_SendPush_ImageCheck(t *testing.T) {
+	service := createTestPushService()
+	service.SetMedia(pushmedia.NewChecker(config.PushMediaConfig{}, nil))
+
+	request := &PushRequest{
+		DeviceToken: testIOSToken,
+		Platform:    "ios",
+		Title:       "Hello",
+		Message:     "Test push message",
+		ImageURL:    "http://example.com/photo.png",
+		Priority:    models.PriorityNormal,
+	}
+	_, err := service.SendPush(context.Background(), request)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, "image_url", notifErr.Metadata["field"])
+	assert.Empty(t, service.provider.(*providers.MockPushProvider).GetSentPush())
+
+	// Pushes without images are not checked
+	request.ImageURL = ""
+	_, err = service.SendPush(context.Background(), request)
+	assert.NoError(t, err)
+}
+
+func TestPushService_SendPush_AtLocalTime(t *testing.T) {
+	service := createTestPushService()
+	defer service.Stop()
+	provider := service.provider.(*providers.MockPushProvider)
+
+	_, err := service.RegisterDevice(&Device{UserID: "user-1", Token: testIOSToken, Platform: "ios", Timezone: "America/New_York"})
+	require.NoError(t, err)
+
+	// 08:59:59.95 in New York
+	service.now = func() time.Time { return time.Date(2024, 6, 3, 12, 59, 59, 950_000_000, time.UTC) }
+
+	request := &PushRequest{
+		DeviceToken:     testIOSToken,
+		Platform:        "ios",
+		Title:           "Good morning",
+		Message:         "Today's deals are live",
+		Priority:        models.PriorityNormal,
+		SendAtLocalTime: "09:00",
+	}
+	response, err := service.SendPush(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusPending, response.Status)
+	assert.Contains(t, response.Message, "2024-06-03T09:00:00-04:00")
+	assert.Empty(t, provider.GetSentPush())
+
+	require.Eventually(t, func() bool { return len(provider.GetSentPush()) == 1 }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 0, service.ScheduledCount())
+
+	// Devices without a time zone are treated as UTC; Stop cancels waiting pushes
+	request.DeviceToken = testAndroidToken
+	request.Platform = "android"
+	response, err = service.SendPush(context.Background(), request)
+	require.NoError(t, err)
+	assert.Contains(t, response.Message, "2024-06-04T09:00:00Z")
+	assert.Equal(t, 1, service.ScheduledCount())
+	service.Stop()
+	assert.Equal(t, 0, service.ScheduledCount())
+
+	request.SendAtLocalTime = "9am"
+	_, err = service.SendPush(context.Background(), request)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, "send_at_local_time", notifErr.Metadata["field"])
+}
+
+func TestPushService_SendPush_Expired(t *testing.T) {
+	service := createTestPushService()
+	defer service.Stop()
+	provider := service.provider.(*providers.MockPushProvider)
+
+	expired := time.Now().Add(-time.Second)
+	request := &PushRequest{
+		DeviceToken: testIOSToken,
+		Platform:    "ios",
+		Title:       "Sign in",
+		Message:     "Your code is 123456",
+		Priority:    models.PriorityHigh,
+		ExpiresAt:   &expired,
+	}
+	response, err := service.SendPush(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusExpired, response.Status)
+	assert.Empty(t, provider.GetSentPush())
+
+	// A push expiring before its local send time is not held
+	expiresAt := time.Now().Add(time.Minute)
+	request.ExpiresAt = &expiresAt
+	request.SendAtLocalTime = service.now().UTC().Add(time.Hour).Format("15:04")
+	response, err = service.SendPush(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusExpired, response.Status)
+	assert.Equal(t, 0, service.ScheduledCount())
+}
+
+func TestPushService_SendBulkPush(t *testing.T) {
+	service := createTestPushService()
+
+	request := &BulkPushRequest{
+		Recipients: []BulkPushRecipient{
+			{DeviceToken: testIOSToken, Platform: "ios"},
+			{DeviceToken: "bad", Platform: "ios"},
+		},
+		Title:   "Announcement",
+		Message: "Hello everyone",
+	}
+
+	responses, err := service.SendBulkPush(context.Background(), request)
+
+	require.NoError(t, err)
+	require.Len(t, responses, 2)
+	assert.Equal(t, models.StatusSent, responses[0].Status)
+	assert.Equal(t, models.StatusFailed, responses[1].Status)
+}
+
+func TestPushService_SendBulkPush_RichFields(t *testing.T) {
+	service := createTestPushService()
+	provider := service.provider.(*providers.MockPushProvider)
+
+	_, err := service.SendBulkPush(context.Background(), &BulkPushRequest{
+		Recipients: []BulkPushRecipient{
+			{DeviceToken: testWebPushToken, Platform: "web"},
+			{DeviceToken: testWebPushToken, Platform: "web"},
+		},
+		Title:   "Flash sale",
+		Message: "Ends tonight",
+		TTL:     600,
+		Tag:     "sale",
+		Actions: []models.PushAction{{ID: "open", Title: "Shop now"}},
+	})
+	require.NoError(t, err)
+
+	sent := provider.GetSentPush()
+	require.Len(t, sent, 2)
+	for _, push := range sent {
+		assert.Equal(t, "600", push.Payload.Headers["TTL"])
+		assert.Equal(t, "sale", push.Payload.Body["tag"])
+		assert.Len(t, push.Payload.Body["actions"], 1)
+	}
+}
+
+func TestPushService_SendBulkPush_Batches(t *testing.T) {
+	service := createTestPushService()
+	provider := service.provider.(*providers.MockPushProvider)
+
+	recipients := make([]BulkPushRecipient, 0, 600)
+	for i := 0; i < 600; i++ {
+		recipients = append(recipients, BulkPushRecipient{DeviceToken: testAndroidToken, Platform: "android"})
+	}
+
+	responses, err := service.SendBulkPush(context.Background(), &BulkPushRequest{
+		Recipients: recipients,
+		Title:      "Announcement",
+		Message:    "Hello everyone",
+	})
+	require.NoError(t, err)
+	require.Len(t, responses, 600)
+	assert.Equal(t, 2, provider.BatchCalls())
+	for _, response := range responses {
+		assert.Equal(t, models.StatusSent, response.Status)
+	}
+
+	// A failed batch fails each of its recipients
+	provider.SetHealthy(false)
+	responses, err = service.SendBulkPush(context.Background(), &BulkPushRequest{Recipients: recipients[:2], Message: "Hello"})
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusFailed, responses[0].Status)
+	assert.Equal(t, 2, provider.BatchCalls())
+}
+
+func TestPushService_SendPushToUser(t *testing.T) {
+	service := createTestPushService()
+
+	for _, device := range []*Device{
+		{UserID: "user-1", Token: testIOSToken, Platform: "ios"},
+		{UserID: "user-1", Token: testAndroidToken, Platform: "android"},
+		{UserID: "user-1", Token: testWebPushToken, Platform: "web"},
+	} {
+		_, err := service.RegisterDevice(device)
+		require.NoError(t, err)
+	}
+
+	request := &PushRequest{
+		Title:   "Security alert",
+		Message: "New sign-in to your account",
+	}
+
+	result, err := service.SendPushToUser(context.Background(), "user-1", request)
+
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", result.UserID)
+	assert.Equal(t, 3, result.TotalDevices)
+	assert.Equal(t, 3, result.SuccessCount)
+	assert.Equal(t, 0, result.FailureCount)
+
+	platforms := make([]string, 0, len(result.Results))
+	for _, deviceResult := range result.Results {
+		assert.NotNil(t, deviceResult.Response)
+		platforms = append(platforms, deviceResult.Platform)
+	}
+	assert.ElementsMatch(t, []string{"ios", "android", "web"}, platforms)
+
+	// The caller's request should not be modified by the fan-out
+	assert.Empty(t, request.DeviceToken)
+}
+
+func TestPushService_SendPushToUser_DeactivatesUnregisteredDevices(t *testing.T) {
+	service := createTestPushService()
+
+	_, err := service.RegisterDevice(&Device{UserID: "user-1", Token: testIOSToken, Platform: "ios"})
+	require.NoError(t, err)
+	_, err = service.RegisterDevice(&Device{UserID: "user-1", Token: testWebPushToken, Platform: "web"})
+	require.NoError(t, err)
+
+	service.provider.(*providers.MockPushProvider).UnregisterToken(testIOSToken)
+
+	result, err := service.SendPushToUser(context.Background(), "user-1", &PushRequest{Title: "Hi"})
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.SuccessCount)
+	assert.Equal(t, 1, result.FailureCount)
+
+	device, err := service.registry.GetDevice(testIOSToken)
+	require.NoError(t, err)
+	assert.False(t, device.Active)
+	assert.Len(t, service.registry.GetActiveDevices("user-1"), 1)
+}
+
+func TestPushService_SendPushToUser_NoDevices(t *testing.T) {
+	service := createTestPushService()
+
+	result, err := service.SendPushToUser(context.Background(), "unknown-user", &PushRequest{Title: "Hi"})
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeInvalidRecipient, notifErr.Code)
+}
+
+func TestPushService_SendPushToTopic(t *testing.T) {
+	service := createTestPushService()
+
+	_, err := service.RegisterDevice(&Device{UserID: "user-1", Token: testIOSToken, Platform: "ios"})
+	require.NoError(t, err)
+	_, err = service.RegisterDevice(&Device{UserID: "user-2", Token: testAndroidToken, Platform: "android"})
+	require.NoError(t, err)
+	_, err = service.RegisterDevice(&Device{UserID: "user-3", Token: testWebPushToken, Platform: "web"})
+	require.NoError(t, err)
+
+	require.NoError(t, service.SubscribeToTopic(testIOSToken, "release-notes"))
+	require.NoError(t, service.SubscribeToTopic(testAndroidToken, "release-notes"))
+	assert.Len(t, service.GetTopicSubscribers("release-notes"), 2)
+
+	result, err := service.SendPushToTopic(context.Background(), "release-notes", &PushRequest{
+		Title:   "Version 2.0",
+		Message: "See what's new",
+	})
+
+	require.NoError(t, err)
+	assert.False(t, result.Delegated)
+	assert.Equal(t, 2, result.TotalDevices)
+	assert.Equal(t, 2, result.SuccessCount)
+
+	require.NoError(t, service.UnsubscribeFromTopic(testAndroidToken, "release-notes"))
+	assert.Len(t, service.GetTopicSubscribers("release-notes"), 1)
+	assert.Error(t, service.UnsubscribeFromTopic(testAndroidToken, "release-notes"))
+}
+
+func TestPushService_SendPushToTopic_NativeDelegation(t *testing.T) {
+	cfg := config.PushProviderConfig{
+		Provider: "mock",
+		Enabled:  true,
+		Settings: map[string]string{"native_topics": "true"},
+	}
+	service, err := NewPushService(cfg, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+
+	// No subscribers are registered locally; the provider resolves them
+	result, err := service.SendPushToTopic(context.Background(), "news", &PushRequest{Title: "Breaking"})
+
+	require.NoError(t, err)
+	assert.True(t, result.Delegated)
+	require.NotNil(t, result.Response)
+	assert.Equal(t, models.StatusSent, result.Response.Status)
+
+	sentPush := service.provider.(*providers.MockPushProvider).GetSentPush()
+	require.Len(t, sentPush, 1)
+	assert.Equal(t, "/topics/news", sentPush[0].DeviceToken)
+}
+
+func TestPushService_SendPushToTopic_DeactivatesUnregisteredDevices(t *testing.T) {
+	service := createTestPushService()
+
+	_, err := service.RegisterDevice(&Device{UserID: "user-1", Token: testIOSToken, Platform: "ios"})
+	require.NoError(t, err)
+	_, err = service.RegisterDevice(&Device{UserID: "user-2", Token: testWebPushToken, Platform: "web"})
+	require.NoError(t, err)
+	require.NoError(t, service.SubscribeToTopic(testIOSToken, "news"))
+	require.NoError(t, service.SubscribeToTopic(testWebPushToken, "news"))
+
+	service.provider.(*providers.MockPushProvider).UnregisterToken(testIOSToken)
+
+	result, err := service.SendPushToTopic(context.Background(), "news", &PushRequest{Title: "Hi"})
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.SuccessCount)
+	assert.Equal(t, 1, result.FailureCount)
+	assert.Len(t, service.GetTopicSubscribers("news"), 1)
+}
+
+func TestPushService_SendPushToTopic_NativeValidation(t *testing.T) {
+	cfg := config.PushProviderConfig{
+		Provider: "mock",
+		Enabled:  true,
+		Settings: map[string]string{"native_topics": "true"},
+	}
+	service, err := NewPushService(cfg, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	provider := service.provider.(*providers.MockPushProvider)
+
+	// Silent and data-only pushes have no visible content
+	result, err := service.SendPushToTopic(context.Background(), "news", &PushRequest{Silent: true, Data: map[string]string{"sync": "1"}})
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusSent, result.Response.Status)
+	result, err = service.SendPushToTopic(context.Background(), "news", &PushRequest{DataOnly: true, Data: map[string]string{"sync": "1"}})
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusSent, result.Response.Status)
+	require.Len(t, provider.GetSentPush(), 2)
+
+	tests := []struct {
+		name    string
+		request *PushRequest
+		field   string
+	}{
+		{"no content", &PushRequest{}, "message"},
+		{"silent with a title", &PushRequest{Silent: true, Title: "Hi"}, "silent"},
+		{"data-only without data", &PushRequest{DataOnly: true}, "data"},
+		{"unsupported platform", &PushRequest{Title: "Hi", Platform: "blackberry"}, "platform"},
+		{"local send time", &PushRequest{Title: "Hi", SendAtLocalTime: "09:00"}, "send_at_local_time"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.SendPushToTopic(context.Background(), "news", tt.request)
+			notifErr, ok := errors.AsNotificationError(err)
+			require.True(t, ok, "%v", err)
+			assert.Equal(t, errors.ErrorCodeValidationFailed, notifErr.Code)
+			assert.Equal(t, tt.field, notifErr.Metadata["field"])
+		})
+	}
+
+	// Expired pushes are not sent
+	expired := time.Now().Add(-time.Second)
+	result, err = service.SendPushToTopic(context.Background(), "news", &PushRequest{Title: "Hi", ExpiresAt: &expired})
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusExpired, result.Response.Status)
+	assert.Len(t, provider.GetSentPush(), 2)
+}
+
+func TestPushService_SendPushToTopic_Errors(t *testing.T) {
+	service := createTestPushService()
+
+	_, err := service.SendPushToTopic(context.Background(), "bad topic!", &PushRequest{Title: "Hi"})
+	assert.Error(t, err)
+
+	_, err = service.SendPushToTopic(context.Background(), "empty-topic", &PushRequest{Title: "Hi"})
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeInvalidRecipient, notifErr.Code)
+
+	assert.Error(t, service.SubscribeToTopic(testIOSToken, "news")) // Device not registered
+}
+
+func TestDeviceRegistry_Register(t *testing.T) {
+	registry := NewDeviceRegistry()
+
+	device, err := registry.Register(&Device{UserID: "user-1", Token: testIOSToken, Platform: "iOS"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, device.ID)
+	assert.Equal(t, "ios", device.Platform)
+	assert.True(t, device.Active)
+
+	// Re-registering the token for another user moves the device
+	moved, err := registry.Register(&Device{UserID: "user-2", Token: testIOSToken, Platform: "ios"})
+	require.NoError(t, err)
+	assert.Equal(t, device.ID, moved.ID)
+	assert.Empty(t, registry.GetUserDevices("user-1"))
+	assert.Len(t, registry.GetUserDevices("user-2"), 1)
+	assert.Equal(t, 1, registry.Count())
+
+	_, err = registry.Register(&Device{UserID: "user-1", Token: "bad", Platform: "ios"})
+	assert.Error(t, err)
+
+	require.NoError(t, registry.Unregister(testIOSToken))
+	assert.Equal(t, 0, registry.Count())
+	assert.Error(t, registry.Unregister(testIOSToken))
+}
+
+func TestDeviceRegistry_Timezone(t *testing.T) {
+	registry := NewDeviceRegistry()
+
+	_, err := registry.Register(&Device{UserID: "user-1", Token: testIOSToken, Platform: "ios", Timezone: "Europe/Paris"})
+	require.NoError(t, err)
+	assert.Equal(t, "Europe/Paris", registry.Timezone(testIOSToken))
+	assert.Empty(t, registry.Timezone("unknown"))
+
+	_, err = registry.Register(&Device{UserID: "user-1", Token: testWebPushToken, Platform: "web", Timezone: "Europe/Atlantis"})
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, "timezone", notifErr.Metadata["field"])
+}
+
+func TestDeviceRegistry_ConcurrentUse(t *testing.T) {
+	registry := NewDeviceRegistry()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			userID := fmt.Sprintf("user-%d", i%2)
+			registry.Register(&Device{UserID: userID, Token: testIOSToken, Platform: "ios"})
+			registry.Subscribe(testIOSToken, "news")
+			registry.GetActiveDevices(userID)
+			registry.GetTopicSubscribers("news")
+			registry.GetDeviceTopics(testIOSToken)
+			registry.Deactivate(testIOSToken)
+			registry.Unsubscribe(testIOSToken, "news")
+			registry.Count()
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, registry.Count())
+}
+
+func TestDeviceRegistry_ReturnsCopies(t *testing.T) {
+	registry := NewDeviceRegistry()
+	device, err := registry.Register(&Device{UserID: "user-1", Token: testIOSToken, Platform: "ios"})
+	require.NoError(t, err)
+
+	device.Active = false
+	found, err := registry.GetDevice(testIOSToken)
+	require.NoError(t, err)
+	assert.True(t, found.Active)
+}
+
+func TestDeviceRegistry_PruneStale(t *testing.T) {
+	registry := NewDeviceRegistry()
+	_, err := registry.Register(&Device{UserID: "user-1", Token: testIOSToken, Platform: "ios"})
+	require.NoError(t, err)
+	_, err = registry.Register(&Device{UserID: "user-1", Token: testWebPushToken, Platform: "web"})
+	require.NoError(t, err)
+	require.NoError(t, registry.Subscribe(testWebPushToken, "news"))
+
+	assert.Equal(t, 0, registry.PruneStale(time.Now().Add(-time.Hour)))
+
+	require.NoError(t, registry.Deactivate(testWebPushToken))
+	assert.Equal(t, 1, registry.PruneStale(time.Now().Add(-time.Hour)))
+	assert.Empty(t, registry.GetDeviceTopics(testWebPushToken))
+	assert.Len(t, registry.GetUserDevices("user-1"), 1)
+
+	assert.Equal(t, 1, registry.PruneStale(time.Now().Add(time.Minute)))
+	assert.Equal(t, 0, registry.Count())
+	assert.Empty(t, registry.GetUserDevices("user-1"))
+}
+
+func TestMaskDeviceToken(t *testing.T) {
+	assert.Equal(t, "a1b2c3d4...", maskDeviceToken(testIOSToken))
+	assert.Equal(t, "short", maskDeviceToken("short"))
+}
+
+func createTestPushService() *PushService {
+	cfg := config.PushProviderConfig{
+		Provider: "mock",
+		Enabled:  true,
+	}
+	logger := utils.NewSimpleLogger("info")
+
+	service, err := NewPushService(cfg, logger)
+	if err != nil {
+		panic(err)
+	}
+
+	return service
+}
@@ -7,37 +7,49 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/conversation"
+	"github.com/nareshkumar-microsoft/notificationService/internal/limits"
 	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/numberlookup"
+	"github.com/nareshkumar-microsoft/notificationService/internal/privacy"
 	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/internal/shortlink"
+	"github.com/nareshkumar-microsoft/notificationService/internal/templatelint"
+	"github.com/nareshkumar-microsoft/notificationService/internal/templatevars"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/internal/validation"
 	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
 	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
 )
 
 // SMSService provides SMS notification functionality
 type SMSService struct {
-	provider interfaces.SMSProvider
-	config   config.SMSProviderConfig
-	logger   interfaces.Logger
+	provider       interfaces.SMSProvider
+	config         config.SMSProviderConfig
+	logger         interfaces.Logger
+	linter         *templatelint.Linter
+	testRecipients testRecipients
+	shortener      shortlink.Shortener
+	senderIDs      *providers.SenderIDPolicy
+	conversations  *conversation.Store
+	lookup         *numberlookup.Service
+	limits         *limits.Limits
 }
 
 // NewSMSService creates a new SMS service
 func NewSMSService(cfg config.SMSProviderConfig, logger interfaces.Logger) (*SMSService, error) {
-	var provider interfaces.SMSProvider
-
-	switch cfg.Provider {
-	case "mock":
-		provider = providers.NewMockSMSProvider(cfg)
-	default:
-		return nil, errors.NewNotificationError(
-			errors.ErrorCodeProviderNotFound,
-			fmt.Sprintf("unsupported SMS provider: %s", cfg.Provider),
-		)
+	provider, err := providers.NewSMSProvider(cfg)
+	if err != nil {
+		return nil, err
 	}
 
 	service := &SMSService{
-		provider: provider,
-		config:   cfg,
-		logger:   logger,
+		provider:  provider,
+		config:    cfg,
+		logger:    logger,
+		linter:    templatelint.NewLinter(templatelint.DefaultRules()),
+		senderIDs: providers.NewSenderIDPolicy(cfg.FromNumber),
+		limits:    limits.New(config.LimitsConfig{}),
 	}
 
 	return service, nil
@@ -51,7 +63,7 @@ func (s *SMSService) SendSMS(ctx context.Context, request *SMSRequest) (*models.
 		return nil, err
 	}
 
-	s.logger.Infof("Sending SMS to %s with message: %s", request.PhoneNumber, truncateMessage(request.Message, 50))
+	s.logger.Infof("Sending SMS to %s with message: %s", privacy.MaskPhone(request.PhoneNumber), truncateMessage(request.Message, 50))
 
 	// Check provider health
 	if err := s.provider.IsHealthy(ctx); err != nil {
@@ -59,9 +71,28 @@ func (s *SMSService) SendSMS(ctx context.Context, request *SMSRequest) (*models.
 		return nil, err
 	}
 
+	// Check the recipient's line type, e.g. to reject landlines
+	if s.lookup != nil {
+		if _, err := s.lookup.CheckSMS(ctx, request.PhoneNumber, request.CountryCode); err != nil {
+			s.logger.Errorf("SMS recipient rejected: %v", err)
+			return nil, err
+		}
+	}
+
 	// Create SMS notification
 	smsNotification := s.createSMSNotification(request)
 
+	// Check the sender ID against the destination country's rules
+	senderID, err := s.senderIDs.Resolve(request.SenderID, smsNotification.CountryCode)
+	if err != nil {
+		s.logger.Errorf("SMS sender ID rejected: %v", err)
+		return nil, err
+	}
+	if senderID != request.SenderID {
+		s.logger.Infof("Alphanumeric sender IDs are not allowed in %s, sending from %q", smsNotification.CountryCode, senderID)
+	}
+	smsNotification.SenderID = senderID
+
 	// Apply template if specified
 	if request.TemplateID != "" {
 		if err := s.applyTemplate(smsNotification, request.TemplateID, request.TemplateData); err != nil {
@@ -70,6 +101,38 @@ func (s *SMSService) SendSMS(ctx context.Context, request *SMSRequest) (*models.
 		}
 	}
 
+	// Stale messages such as expired one-time passwords are not sent
+	if smsNotification.Expired(time.Now()) {
+		s.logger.Warnf("SMS %s expired before it was sent", smsNotification.ID)
+		return expiredResponse(&smsNotification.Notification), nil
+	}
+
+	// Shorten long URLs before the cost is estimated, as they cost segments
+	var linkCodes []string
+	if s.shortener != nil {
+		message, codes, err := shortlink.Rewrite(ctx, s.shortener, smsNotification.Message)
+		if err != nil {
+			s.logger.Errorf("SMS link shortening failed: %v", err)
+			return nil, err
+		}
+		smsNotification.Message = message
+		smsNotification.Body = message
+		linkCodes = codes
+	}
+
+	// Templates and shortened links change the segments the message takes
+	if err := s.limits.CheckSMS(smsNotification.Message, smsNotification.Unicode); err != nil {
+		s.logger.Errorf("SMS exceeds limits: %v", err)
+		return nil, err
+	}
+
+	// Check the cost of the final message against the ceiling
+	estimate, err := s.checkCost(smsNotification, request.MaxCost)
+	if err != nil {
+		s.logger.Errorf("SMS cost check failed: %v", err)
+		return nil, err
+	}
+
 	// Send SMS
 	response, err := s.provider.SendSMS(ctx, smsNotification)
 	if err != nil {
@@ -77,6 +140,23 @@ func (s *SMSService) SendSMS(ctx context.Context, request *SMSRequest) (*models.
 		return nil, err
 	}
 
+	// Billing systems rely on the cost, so fill it in when the provider does not report it
+	if estimate != nil && response.Segments == 0 && response.Cost == 0 {
+		response.Segments = estimate.Segments
+		response.Cost = estimate.TotalCost
+		response.Currency = providers.SMSCostCurrency
+	}
+
+	if len(linkCodes) > 0 {
+		if err := s.shortener.Attach(ctx, smsNotification.ID.String(), linkCodes...); err != nil {
+			s.logger.Warnf("Failed to attach short links to SMS %s: %v", smsNotification.ID, err)
+		}
+	}
+
+	if s.conversations != nil {
+		s.conversations.RecordOutbound(smsNotification.ID, smsNotification.SenderID, smsNotification.PhoneNumber, smsNotification.Message)
+	}
+
 	s.logger.Infof("SMS sent successfully with ID: %s", response.ID)
 	return response, nil
 }
@@ -101,17 +181,16 @@ func (s *SMSService) SendBulkSMS(ctx context.Context, request *BulkSMSRequest) (
 			TemplateData: s.mergeTemplateData(request.TemplateData, recipient.Data),
 			Priority:     request.Priority,
 			Metadata:     request.Metadata,
+			MaxCost:      request.MaxCost,
+			SenderID:     request.SenderID,
+			ExpiresAt:    request.ExpiresAt,
 		}
 
 		response, err := s.SendSMS(ctx, smsRequest)
 		if err != nil {
 			s.logger.Errorf("Failed to send SMS to %s: %v", recipient.PhoneNumber, err)
 			// Continue with other recipients, but record the error
-			response = &models.NotificationResponse{
-				ID:     uuid.New(),
-				Status: models.StatusFailed,
-				Error:  err.Error(),
-			}
+			response = failedResponse(err)
 		}
 
 		responses = append(responses, response)
@@ -128,7 +207,7 @@ func (s *SMSService) GetSMSCost(countryCode string) (float64, error) {
 
 // GetSupportedCountries returns list of supported countries
 func (s *SMSService) GetSupportedCountries() []CountryInfo {
-	mockProvider, ok := s.provider.(*providers.MockSMSProvider)
+	mockProvider, ok := s.mockProvider()
 	if !ok {
 		return []CountryInfo{}
 	}
@@ -149,7 +228,7 @@ func (s *SMSService) GetSupportedCountries() []CountryInfo {
 
 // RenderTemplate renders an SMS template with data
 func (s *SMSService) RenderTemplate(templateID string, data map[string]string) (*RenderedSMSTemplate, error) {
-	mockProvider, ok := s.provider.(*providers.MockSMSProvider)
+	mockProvider, ok := s.mockProvider()
 	if !ok {
 		return nil, errors.NewNotificationError(
 			errors.ErrorCodeProviderNotFound,
@@ -171,6 +250,93 @@ func (s *SMSService) RenderTemplate(templateID string, data map[string]string) (
 	}, nil
 }
 
+// SetLintRules replaces the rules templates are validated against
+func (s *SMSService) SetLintRules(rules templatelint.Rules) {
+	s.linter = templatelint.NewLinter(rules)
+}
+
+// SetLimits replaces the segment limit messages are checked against
+func (s *SMSService) SetLimits(payloadLimits *limits.Limits) {
+	s.limits = payloadLimits
+}
+
+// SetShortener rewrites long URLs in messages to short tracked links
+func (s *SMSService) SetShortener(shortener shortlink.Shortener) {
+	s.shortener = shortener
+}
+
+// SetSenderIDPolicy replaces the per-country rules sender IDs are checked against
+func (s *SMSService) SetSenderIDPolicy(policy *providers.SenderIDPolicy) {
+	s.senderIDs = policy
+}
+
+// SetConversations records sent messages in their two-way conversations
+func (s *SMSService) SetConversations(store *conversation.Store) {
+	s.conversations = store
+}
+
+// SetNumberLookup sets the service recipients are looked up with before
+// each send, which rejects landlines when configured to; nil stops looking
+// them up
+func (s *SMSService) SetNumberLookup(lookup *numberlookup.Service) {
+	s.lookup = lookup
+}
+
+// LookupPhoneNumber returns the carrier, line type and portability of a
+// number in international format, with the service set by SetNumberLookup
+func (s *SMSService) LookupPhoneNumber(ctx context.Context, number string) (*numberlookup.Result, error) {
+	if s.lookup == nil {
+		return nil, errors.NewNotificationError(errors.ErrorCodeProviderConfiguration, "number lookup is not configured")
+	}
+	return s.lookup.LookupPhoneNumber(ctx, number)
+}
+
+// SetTestRecipients sets the verified phone numbers TestSend may send to
+func (s *SMSService) SetTestRecipients(recipients ...string) {
+	s.testRecipients.set(recipients)
+}
+
+// ValidateTemplate checks an SMS template's variables, segment budget and wording
+func (s *SMSService) ValidateTemplate(template *providers.SMSTemplate) templatelint.Report {
+	return s.linter.ValidateTemplate(templatelint.Template{
+		Channel:   models.NotificationTypeSMS,
+		Body:      template.Message,
+		Variables: declaredVariables(template.Variables, template.Schema),
+		Unicode:   template.Unicode,
+	})
+}
+
+// TestSend sends a template rendered with sample data to a verified test
+// phone number. Templates that fail validation are not sent.
+func (s *SMSService) TestSend(ctx context.Context, templateID, recipient string, sampleData map[string]string) (*models.NotificationResponse, error) {
+	if err := s.testRecipients.check(recipient); err != nil {
+		return nil, err
+	}
+
+	mockProvider, ok := s.mockProvider()
+	if !ok {
+		return nil, errors.NewNotificationError(
+			errors.ErrorCodeProviderNotFound,
+			"template rendering not supported by this provider",
+		)
+	}
+	template, err := mockProvider.GetTemplate(templateID)
+	if err != nil {
+		return nil, err
+	}
+	if err := lintError(templateID, s.ValidateTemplate(template)); err != nil {
+		return nil, err
+	}
+
+	return s.SendSMS(ctx, &SMSRequest{
+		PhoneNumber:  recipient,
+		TemplateID:   templateID,
+		TemplateData: sampleData,
+		Priority:     models.PriorityNormal,
+		Metadata:     map[string]string{"test_send": "true"},
+	})
+}
+
 // ValidatePhoneNumber validates a phone number
 func (s *SMSService) ValidatePhoneNumber(phoneNumber, countryCode string) error {
 	return s.provider.ValidatePhoneNumber(phoneNumber, countryCode)
@@ -212,14 +378,38 @@ func (s *SMSService) EstimateCost(message string, countryCode string, unicode bo
 	}, nil
 }
 
+// checkCost estimates the cost of an SMS for its country, or the default
+// rate when it has none, and rejects it when the estimate exceeds maxCost.
+// A maxCost of zero means no ceiling; the estimate is then best effort and
+// nil when the cost is unknown.
+func (s *SMSService) checkCost(sms *models.SMSNotification, maxCost float64) (*SMSCostEstimate, error) {
+	estimate, err := s.EstimateCost(sms.Message, sms.CountryCode, sms.Unicode)
+	if err != nil {
+		if maxCost > 0 {
+			return nil, err
+		}
+		s.logger.Warnf("Could not estimate SMS cost: %v", err)
+		return nil, nil
+	}
+
+	if maxCost > 0 && estimate.TotalCost > maxCost {
+		return nil, errors.NewValidationError("max_cost", fmt.Sprintf(
+			"estimated cost %.4f %s (%d segments) exceeds the maximum of %.4f",
+			estimate.TotalCost, providers.SMSCostCurrency, estimate.Segments, maxCost,
+		))
+	}
+
+	return estimate, nil
+}
+
 // validateSMSRequest validates an SMS request
 func (s *SMSService) validateSMSRequest(request *SMSRequest) error {
 	if request == nil {
 		return errors.NewValidationError("request", "SMS request is required")
 	}
 
-	if request.PhoneNumber == "" {
-		return errors.NewValidationError("phone_number", "phone number is required")
+	if err := validation.Struct(request); err != nil {
+		return err
 	}
 
 	// Validate phone number
@@ -232,17 +422,7 @@ func (s *SMSService) validateSMSRequest(request *SMSRequest) error {
 		return errors.NewValidationError("message", "SMS message is required when not using a template")
 	}
 
-	// Check message length (allow up to 10 segments)
-	maxLength := 160 * 10
-	if request.Unicode {
-		maxLength = 70 * 10
-	}
-
-	if len(request.Message) > maxLength {
-		return errors.NewValidationError("message", fmt.Sprintf("message too long (max %d characters for 10 segments)", maxLength))
-	}
-
-	return nil
+	return s.limits.CheckSMS(request.Message, request.Unicode)
 }
 
 // createSMSNotification creates an SMS notification from a request
@@ -261,29 +441,41 @@ func (s *SMSService) createSMSNotification(request *SMSRequest) *models.SMSNotif
 			Metadata:   request.Metadata,
 			CreatedAt:  now,
 			UpdatedAt:  now,
+			ExpiresAt:  request.ExpiresAt,
 			RetryCount: 0,
 			MaxRetries: 3,
 		},
 		PhoneNumber: request.PhoneNumber,
-		CountryCode: request.CountryCode,
+		CountryCode: utils.ResolveCountry(request.PhoneNumber, request.CountryCode),
 		Message:     request.Message,
 		Unicode:     request.Unicode,
 	}
 
-	// Add country code to metadata if provided
+	// Add the given or inferred country code to metadata
 	if notification.Metadata == nil {
 		notification.Metadata = make(map[string]string)
 	}
-	if request.CountryCode != "" {
-		notification.Metadata["country_code"] = request.CountryCode
+	if notification.CountryCode != "" {
+		notification.Metadata["country_code"] = notification.CountryCode
 	}
 
 	return notification
 }
 
+// mockProvider returns the mock provider that renders templates, unwrapping
+// a country-routed provider
+func (s *SMSService) mockProvider() (*providers.MockSMSProvider, bool) {
+	provider := s.provider
+	if routed, ok := provider.(*providers.CountryRoutedSMSProvider); ok {
+		provider = routed.Unwrap()
+	}
+	mockProvider, ok := provider.(*providers.MockSMSProvider)
+	return mockProvider, ok
+}
+
 // applyTemplate applies a template to an SMS notification
 func (s *SMSService) applyTemplate(sms *models.SMSNotification, templateID string, data map[string]string) error {
-	mockProvider, ok := s.provider.(*providers.MockSMSProvider)
+	mockProvider, ok := s.mockProvider()
 	if !ok {
 		return errors.NewNotificationError(
 			errors.ErrorCodeProviderNotFound,
@@ -291,6 +483,7 @@ func (s *SMSService) applyTemplate(sms *models.SMSNotification, templateID strin
 		)
 	}
 
+	data = templatevars.WithRecipient(data, sms.Recipient)
 	template, err := mockProvider.RenderTemplate(templateID, data)
 	if err != nil {
 		return err
@@ -325,31 +518,12 @@ func (s *SMSService) mergeTemplateData(global, recipient map[string]string) map[
 
 // truncateMessage truncates a message to a maximum length for logging
 func truncateMessage(message string, maxLength int) string {
-	if len(message) <= maxLength {
-		return message
-	}
-	return message[:maxLength-3] + "..."
+	return utils.TruncateString(message, maxLength)
 }
 
 // calculateSMSSegments calculates the number of SMS segments needed
 func calculateSMSSegments(message string, unicode bool) int {
-	maxLength := 160
-	if unicode {
-		maxLength = 70
-	}
-
-	length := len(message)
-	if length <= maxLength {
-		return 1
-	}
-
-	// For multi-part messages, each segment is slightly shorter
-	segmentLength := maxLength - 7 // Account for UDH (User Data Header)
-	if unicode {
-		segmentLength = 67
-	}
-
-	return (length + segmentLength - 1) / segmentLength
+	return utils.CalculateSMSSegments(message, unicode)
 }
 
 // Request and response types
@@ -364,6 +538,13 @@ type SMSRequest struct {
 	TemplateData map[string]string `json:"template_data,omitempty"`
 	Priority     models.Priority   `json:"priority"`
 	Metadata     map[string]string `json:"metadata,omitempty"`
+	// MaxCost rejects the send when its estimated cost is higher; 0 means no limit
+	MaxCost float64 `json:"max_cost,omitempty" validate:"min=0"`
+	// SenderID is an alphanumeric sender ID such as "ACME" or a number; where
+	// alphanumeric IDs are prohibited, the configured from number is used
+	SenderID string `json:"sender_id,omitempty"`
+	// ExpiresAt skips the send after this time, e.g. a one-time password's expiry
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
 // BulkSMSRequest represents a request to send SMS to multiple recipients
@@ -375,6 +556,11 @@ type BulkSMSRequest struct {
 	TemplateData map[string]string  `json:"template_data,omitempty"`
 	Priority     models.Priority    `json:"priority"`
 	Metadata     map[string]string  `json:"metadata,omitempty"`
+	// MaxCost is the cost ceiling of each message; 0 means no limit
+	MaxCost float64 `json:"max_cost,omitempty" validate:"min=0"`
+	// SenderID is checked against each recipient's country
+	SenderID  string     `json:"sender_id,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
 // BulkSMSRecipient represents a recipient in a bulk SMS request
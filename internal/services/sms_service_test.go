@@ -4,12 +4,19 @@ import (
 	"context"
 	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/conversation"
+	"github.com/nareshkumar-microsoft/notificationService/internal/limits"
 	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/numberlookup"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/internal/shortlink"
 	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
 	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
 )
@@ -61,6 +68,63 @@ func TestSMSService_SendSMS_Success(t *testing.T) {
 	assert.NotNil(t, response)
 	assert.Equal(t, models.StatusSent, response.Status)
 	assert.Contains(t, response.Message, "SMS sent")
+	assert.Equal(t, 1, response.Segments)
+	assert.Equal(t, 0.0075, response.Cost)
+	assert.Equal(t, "USD", response.Currency)
+}
+
+func TestSMSService_SendSMS_MaxCost(t *testing.T) {
+	service := createTestSMSService()
+	ctx := context.Background()
+
+	// 300 characters take two US segments at $0.0075 each
+	request := &SMSRequest{
+		PhoneNumber: "1234567890",
+		CountryCode: "US",
+		Message:     strings.Repeat("a", 300),
+		Priority:    models.PriorityNormal,
+		MaxCost:     0.01,
+	}
+
+	_, err := service.SendSMS(ctx, request)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds the maximum")
+	assert.Empty(t, service.provider.(*providers.MockSMSProvider).GetSentSMS())
+
+	request.MaxCost = 0.015
+	response, err := service.SendSMS(ctx, request)
+	require.NoError(t, err)
+	assert.Equal(t, 2, response.Segments)
+	assert.Equal(t, 0.015, response.Cost)
+
+	// Without a country the default rate applies
+	request.CountryCode = ""
+	request.Message = "Short"
+	request.MaxCost = 0.005
+	_, err = service.SendSMS(ctx, request)
+	assert.Error(t, err)
+
+	request.MaxCost = -1
+	_, err = service.SendSMS(ctx, request)
+	assert.Error(t, err)
+}
+
+func TestSMSService_SendBulkSMS_MaxCost(t *testing.T) {
+	service := createTestSMSService()
+
+	responses, err := service.SendBulkSMS(context.Background(), &BulkSMSRequest{
+		Recipients: []BulkSMSRecipient{
+			{PhoneNumber: "1234567890", CountryCode: "US"},
+			{PhoneNumber: "1234567890", CountryCode: "BR"},
+		},
+		Message: "Your order has shipped",
+		MaxCost: 0.009,
+	})
+	require.NoError(t, err)
+	require.Len(t, responses, 2)
+	assert.Equal(t, models.StatusSent, responses[0].Status)
+	assert.Equal(t, models.StatusFailed, responses[1].Status)
+	assert.Contains(t, responses[1].Error, "max_cost")
 }
 
 func TestSMSService_SendSMS_ValidationErrors(t *testing.T) {
@@ -133,6 +197,35 @@ func TestSMSService_SendSMS_WithTemplate(t *testing.T) {
 	assert.Equal(t, models.StatusSent, response.Status)
 }
 
+func TestSMSService_SendSMS_SegmentLimit(t *testing.T) {
+	service := createTestSMSService()
+	service.SetLimits(limits.New(config.LimitsConfig{MaxSMSSegments: 1}))
+	ctx := context.Background()
+
+	_, err := service.SendSMS(ctx, &SMSRequest{
+		PhoneNumber: "1234567890",
+		CountryCode: "US",
+		Message:     strings.Repeat("a", 161),
+		Priority:    models.PriorityNormal,
+	})
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodePayloadTooLarge, notifErr.Code)
+	assert.Equal(t, "2", notifErr.Metadata["size"])
+
+	// Rendered templates are checked too
+	_, err = service.SendSMS(ctx, &SMSRequest{
+		PhoneNumber:  "1234567890",
+		CountryCode:  "US",
+		TemplateID:   "verification",
+		TemplateData: map[string]string{"service_name": strings.Repeat("a", 160), "code": "123456", "expiry_minutes": "10"},
+		Priority:     models.PriorityHigh,
+	})
+	notifErr, ok = errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodePayloadTooLarge, notifErr.Code)
+}
+
 func TestSMSService_SendBulkSMS(t *testing.T) {
 	service := createTestSMSService()
 	ctx := context.Background()
@@ -157,6 +250,33 @@ func TestSMSService_SendBulkSMS(t *testing.T) {
 	}
 }
 
+func TestSMSService_SendSMS_Expired(t *testing.T) {
+	service := createTestSMSService()
+	provider := service.provider.(*providers.MockSMSProvider)
+	expired := time.Now().Add(-time.Second)
+
+	response, err := service.SendSMS(context.Background(), &SMSRequest{
+		PhoneNumber: "1234567890",
+		CountryCode: "US",
+		Message:     "Your code is 123456",
+		Priority:    models.PriorityHigh,
+		ExpiresAt:   &expired,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusExpired, response.Status)
+	assert.Empty(t, provider.GetSentSMS())
+
+	valid := time.Now().Add(5 * time.Minute)
+	responses, err := service.SendBulkSMS(context.Background(), &BulkSMSRequest{
+		Recipients: []BulkSMSRecipient{{PhoneNumber: "1234567890", CountryCode: "US"}},
+		Message:    "Your code is 123456",
+		Priority:   models.PriorityHigh,
+		ExpiresAt:  &valid,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusSent, responses[0].Status)
+}
+
 func TestSMSService_SendBulkSMS_NoRecipients(t *testing.T) {
 	service := createTestSMSService()
 	ctx := context.Background()
@@ -354,6 +474,117 @@ func TestSMSService_EstimateCost(t *testing.T) {
 	}
 }
 
+func TestSMSService_SendSMS_InfersCountry(t *testing.T) {
+	service := createTestSMSService()
+	ctx := context.Background()
+
+	// A +44 number resolves UK pricing
+	response, err := service.SendSMS(ctx, &SMSRequest{PhoneNumber: "+447911123456", Message: "Hello", MaxCost: 0.008})
+	require.NoError(t, err)
+	assert.Equal(t, 0.0080, response.Cost)
+
+	// and UK validation rules
+	_, err = service.SendSMS(ctx, &SMSRequest{PhoneNumber: "+44791112", Message: "Hello"})
+	assert.Error(t, err)
+
+	// An explicit country code overrides the inferred one
+	response, err = service.SendSMS(ctx, &SMSRequest{PhoneNumber: "+447911123456", CountryCode: "DE", Message: "Hello"})
+	require.NoError(t, err)
+	assert.Equal(t, 0.0090, response.Cost)
+}
+
+func TestSMSService_SendSMS_ShortensLinks(t *testing.T) {
+	service := createTestSMSService()
+	links, err := shortlink.NewService("https://sms.example.com/s/")
+	require.NoError(t, err)
+	service.SetShortener(links)
+
+	longURL := "https://shop.example.com/account/orders/ORD-2024-000123/tracking?utm_source=sms&utm_campaign=shipping"
+	message := "Your order ORD-2024-000123 has shipped and should arrive by Friday. Track it here: " + longURL
+	require.Equal(t, 2, calculateSMSSegments(message, false))
+
+	response, err := service.SendSMS(context.Background(), &SMSRequest{PhoneNumber: "+14155550123", Message: message})
+	require.NoError(t, err)
+
+	// The short link brings the message down to one segment
+	assert.Equal(t, 1, response.Segments)
+
+	sent := links.Links(response.ID.String())
+	require.Len(t, sent, 1)
+	assert.Equal(t, longURL, sent[0].URL)
+}
+
+func TestSMSService_SendSMS_Conversation(t *testing.T) {
+	service := createTestSMSService()
+	store := conversation.NewStore(config.ConversationConfig{ServiceNumber: "+14155550100"})
+	service.SetConversations(store)
+
+	response, err := service.SendSMS(context.Background(), &SMSRequest{PhoneNumber: "+14155550123", Message: "Your code is 123456"})
+	require.NoError(t, err)
+	reply := store.RecordInbound("+14155550123", "+14155550100", "Thanks", "")
+
+	require.Len(t, reply.Messages, 2)
+	assert.Equal(t, response.ID.String(), reply.Messages[0].NotificationID)
+	assert.Equal(t, "Your code is 123456", reply.Messages[0].Body)
+}
+
+func TestSMSService_SendSMS_NumberLookup(t *testing.T) {
+	service := createTestSMSService()
+	ctx := context.Background()
+	_, err := service.LookupPhoneNumber(ctx, "+14155550123")
+	assert.Error(t, err) // not configured
+
+	provider := numberlookup.NewMockProvider()
+	provider.Set("+14155550123", numberlookup.Result{Carrier: "AT&T", LineType: numberlookup.LineTypeLandline})
+	service.SetNumberLookup(numberlookup.NewService(provider, config.NumberLookupConfig{BlockLandlines: true}, utils.NewSimpleLogger("error")))
+
+	result, err := service.LookupPhoneNumber(ctx, "+14155550123")
+	require.NoError(t, err)
+	assert.Equal(t, "AT&T", result.Carrier)
+
+	_, err = service.SendSMS(ctx, &SMSRequest{PhoneNumber: "+14155550123", Message: "Your code is 123456"})
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeInvalidRecipient, notifErr.Code)
+
+	_, err = service.SendSMS(ctx, &SMSRequest{PhoneNumber: "+14155550199", Message: "Your code is 123456"})
+	require.NoError(t, err)
+}
+
+func TestSMSService_SendSMS_SenderID(t *testing.T) {
+	service := createTestSMSService()
+	policy := providers.NewSenderIDPolicy("+15550001111")
+	policy.Register("IN", "ACMECO")
+	service.SetSenderIDPolicy(policy)
+	ctx := context.Background()
+
+	response, err := service.SendSMS(ctx, &SMSRequest{PhoneNumber: "+447911123456", Message: "Hello", SenderID: "ACME"})
+	require.NoError(t, err)
+	assert.Equal(t, "ACME", response.ProviderMetadata["sender_id"])
+
+	// Alphanumeric senders are prohibited in the US
+	response, err = service.SendSMS(ctx, &SMSRequest{PhoneNumber: "+14155550123", Message: "Hello", SenderID: "ACME"})
+	require.NoError(t, err)
+	assert.Equal(t, "+15550001111", response.ProviderMetadata["sender_id"])
+
+	// India only accepts registered headers
+	_, err = service.SendSMS(ctx, &SMSRequest{PhoneNumber: "+919876543210", Message: "Hello", SenderID: "OTHERS"})
+	require.Error(t, err)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, "sender_id", notifErr.Metadata["field"])
+
+	responses, err := service.SendBulkSMS(ctx, &BulkSMSRequest{
+		Recipients: []BulkSMSRecipient{{PhoneNumber: "+919876543210"}, {PhoneNumber: "+447911123456"}},
+		Message:    "Hello",
+		SenderID:   "ACMECO",
+	})
+	require.NoError(t, err)
+	require.Len(t, responses, 2)
+	assert.Equal(t, "ACMECO", responses[0].ProviderMetadata["sender_id"])
+	assert.Equal(t, "ACMECO", responses[1].ProviderMetadata["sender_id"])
+}
+
 func TestSMSService_UnicodeHandling(t *testing.T) {
 	service := createTestSMSService()
 	ctx := context.Background()
@@ -465,6 +696,13 @@ func TestTruncateMessage(t *testing.T) {
 		{"Short", 10, "Short"},
 		{"This is a long message", 10, "This is..."},
 		{"Exact length", 12, "Exact length"},
+		// Characters are not split
+		{"héllo wörld", 6, "hé..."},
+		{"éééé", 6, "é..."},
+		{"e\u0301e\u0301 accents", 8, "e\u0301..."},
+		{"👍🏽 thumbs up", 11, "👍🏽..."},
+		{"🇫🇷🇩🇪 flags", 14, "🇫🇷..."},
+		{"👨\u200d👩\u200d👧 family", 20, "..."},
 	}
 
 	for _, tt := range tests {
@@ -472,10 +710,28 @@ func TestTruncateMessage(t *testing.T) {
 			result := truncateMessage(tt.message, tt.maxLength)
 			assert.Equal(t, tt.expected, result)
 			assert.LessOrEqual(t, len(result), tt.maxLength)
+			assert.True(t, utf8.ValidString(result))
 		})
 	}
 }
 
+func TestSMSService_TestSend(t *testing.T) {
+	service := createTestSMSService()
+	service.SetTestRecipients("1234567890")
+	data := map[string]string{"service_name": "Test", "code": "123456", "expiry_minutes": "5"}
+
+	response, err := service.TestSend(context.Background(), "verification", "1234567890", data)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusSent, response.Status)
+
+	_, err = service.TestSend(context.Background(), "verification", "5555555555", data)
+	assert.Error(t, err)
+
+	// Messages over the segment budget fail validation
+	report := service.ValidateTemplate(&providers.SMSTemplate{Message: strings.Repeat("a", 500)})
+	assert.False(t, report.Valid())
+}
+
 // Helper function
 func createTestSMSService() *SMSService {
 	cfg := config.SMSProviderConfig{
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"strings"
+
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// standardTemplateFiles holds the standard template library, one bundle per
+// locale
+//
+//go:embed standard_templates/*.json
+var standardTemplateFiles embed.FS
+
+// StandardTemplateIDs are the templates of the standard library. Each has
+// email, SMS and push versions; two_factor_code, appointment_reminder and
+// payment_failed have voice versions as well.
+var StandardTemplateIDs = []string{
+	"order_confirmation",
+	"shipping_update",
+	"password_changed",
+	"two_factor_code",
+	"appointment_reminder",
+	"payment_failed",
+}
+
+// StandardTemplateLocales are the locales of the standard library. English
+// templates keep the plain ID; the others are stored as <id>.<locale>.
+var StandardTemplateLocales = []string{"en", "es", "fr", "de"}
+
+// StandardTemplates returns the standard library in the given locales, or in
+// every locale when none are given. Each call returns a fresh bundle.
+func StandardTemplates(locales ...string) (*TemplateBundle, error) {
+	if len(locales) == 0 {
+		locales = StandardTemplateLocales
+	}
+
+	standard := &TemplateBundle{Version: TemplateBundleVersion}
+	for _, locale := range locales {
+		if !isStandardLocale(locale) {
+			return nil, errors.NewValidationError("locale", fmt.Sprintf("no standard templates in locale %q; available: %s",
+				locale, strings.Join(StandardTemplateLocales, ", ")))
+		}
+		content, err := standardTemplateFiles.ReadFile("standard_templates/" + locale + ".json")
+		if err != nil {
+			return nil, errors.NewInternalError("failed to read standard templates", err)
+		}
+		bundle, err := ReadTemplateBundle(bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+
+		standard.Email = append(standard.Email, bundle.Email...)
+		standard.SMS = append(standard.SMS, bundle.SMS...)
+		standard.Push = append(standard.Push, bundle.Push...)
+		standard.Voice = append(standard.Voice, bundle.Voice...)
+	}
+	return standard, nil
+}
+
+// StandardTemplateID returns the ID of a standard template in a locale such
+// as "es" or "es-MX". Locales without standard templates get English.
+func StandardTemplateID(id, locale string) string {
+	language, _, _ := strings.Cut(strings.ToLower(locale), "-")
+	if language == "en" || !isStandardLocale(language) {
+		return id
+	}
+	return id + "." + language
+}
+
+// isStandardLocale reports whether the standard library has a locale
+func isStandardLocale(locale string) bool {
+	for _, candidate := range StandardTemplateLocales {
+		if candidate == locale {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadStandardTemplates imports the standard library in the given locales,
+// or in every locale when none are given. Channels without a store are left
+// out. Like any import it follows the conflict policy, so templates of the
+// same ID that were edited are kept unless options say otherwise.
+func (b *TemplateBundler) LoadStandardTemplates(options TemplateImportOptions, locales ...string) (*TemplateImportReport, error) {
+	standard, err := StandardTemplates(locales...)
+	if err != nil {
+		return nil, err
+	}
+	return b.Import(b.configuredOnly(standard), options)
+}
+
+// withStandardTemplates returns a bundle with the standard library added
+// under its templates: standard templates whose ID the bundle already uses
+// in a channel are left out
+func (b *TemplateBundler) withStandardTemplates(bundle *TemplateBundle) (*TemplateBundle, error) {
+	standard, err := StandardTemplates()
+	if err != nil {
+		return nil, err
+	}
+	standard = b.configuredOnly(standard)
+
+	merged := *bundle
+	merged.Email = layerTemplates(bundle.Email, standard.Email, b.emailChannel())
+	merged.SMS = layerTemplates(bundle.SMS, standard.SMS, b.smsChannel())
+	merged.Push = layerTemplates(bundle.Push, standard.Push, b.pushChannel())
+	merged.Voice = layerTemplates(bundle.Voice, standard.Voice, b.voiceChannel())
+	return &merged, nil
+}
+
+// configuredOnly drops a bundle's templates for channels without a store
+func (b *TemplateBundler) configuredOnly(bundle *TemplateBundle) *TemplateBundle {
+	if b.stores.Email == nil {
+		bundle.Email = nil
+	}
+	if b.stores.SMS == nil {
+		bundle.SMS = nil
+	}
+	if b.stores.Push == nil {
+		bundle.Push = nil
+	}
+	if b.stores.Chat == nil {
+		bundle.Chat = nil
+	}
+	if b.stores.Voice == nil {
+		bundle.Voice = nil
+	}
+	return bundle
+}
+
+// layerTemplates appends the lower templates whose IDs the upper ones do not use
+func layerTemplates[T any](upper, lower []*T, channel templateChannel[T]) []*T {
+	taken := make(map[string]bool, len(upper))
+	for _, template := range upper {
+		taken[*channel.id(template)] = true
+	}
+
+	layered := append([]*T(nil), upper...)
+	for _, template := range lower {
+		if !taken[*channel.id(template)] {
+			layered = append(layered, template)
+		}
+	}
+	return layered
+}
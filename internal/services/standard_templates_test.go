@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+)
+
+func TestStandardTemplates(t *testing.T) {
+	bundle, err := StandardTemplates()
+	require.NoError(t, err)
+
+	for _, locale := range StandardTemplateLocales {
+		for _, id := range StandardTemplateIDs {
+			localized := StandardTemplateID(id, locale)
+			assert.True(t, hasTemplate(bundle.Email, localized, func(t *providers.EmailTemplate) string { return t.ID }), "email %s", localized)
+			assert.True(t, hasTemplate(bundle.SMS, localized, func(t *providers.SMSTemplate) string { return t.ID }), "sms %s", localized)
+			assert.True(t, hasTemplate(bundle.Push, localized, func(t *providers.PushTemplate) string { return t.ID }), "push %s", localized)
+		}
+		assert.True(t, hasTemplate(bundle.Voice, StandardTemplateID("two_factor_code", locale), func(t *providers.VoiceTemplate) string { return t.ID }))
+	}
+
+	spanish, err := StandardTemplates("es")
+	require.NoError(t, err)
+	assert.Len(t, spanish.Email, len(StandardTemplateIDs))
+	assert.Equal(t, "es", spanish.Email[0].Metadata["locale"])
+
+	_, err = StandardTemplates("xx")
+	assert.Error(t, err)
+}
+
+func TestStandardTemplateID(t *testing.T) {
+	assert.Equal(t, "order_confirmation", StandardTemplateID("order_confirmation", "en-GB"))
+	assert.Equal(t, "order_confirmation.es", StandardTemplateID("order_confirmation", "es-MX"))
+	assert.Equal(t, "order_confirmation.de", StandardTemplateID("order_confirmation", "DE"))
+	assert.Equal(t, "order_confirmation", StandardTemplateID("order_confirmation", "ja"))
+	assert.Equal(t, "order_confirmation", StandardTemplateID("order_confirmation", ""))
+}
+
+func TestTemplateBundler_LoadStandardTemplates(t *testing.T) {
+	bundler, stores := createTestTemplateBundler()
+
+	report, err := bundler.LoadStandardTemplates(TemplateImportOptions{})
+	require.NoError(t, err)
+	assert.Zero(t, report.Count(ImportSkipped))
+	assert.Equal(t, report.Count(ImportCreated), len(report.Templates))
+
+	// Loading again changes nothing
+	report, err = bundler.LoadStandardTemplates(TemplateImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, report.Count(ImportUnchanged), len(report.Templates))
+
+	data := map[string]string{
+		"customer_name": "Ana",
+		"order_number":  "A-1001",
+		"order_total":   "49,99 €",
+		"order_url":     "https://shop.example.com/orders/A-1001",
+	}
+	email, err := stores.Email.RenderTemplate("order_confirmation.es", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Pedido A-1001 confirmado", email.Subject)
+	assert.Contains(t, email.HTMLBody, "¡Gracias por tu pedido, Ana!")
+	assert.Contains(t, email.HTMLBody, "Notification Service") // branded layout
+	assert.NotContains(t, email.HTMLBody, "{{")
+	assert.NotContains(t, email.TextBody, "{{")
+
+	_, err = stores.Email.RenderTemplate("order_confirmation", map[string]string{"customer_name": "Ana"})
+	assert.Error(t, err) // schema requires the order fields
+
+	sms, err := stores.SMS.RenderTemplate("two_factor_code", map[string]string{"code": "123456"})
+	require.NoError(t, err)
+	assert.Equal(t, "123456 is your verification code. It expires in 10 minutes. Don't share it with anyone.", sms.Message)
+}
+
+func TestStandardTemplates_Render(t *testing.T) {
+	bundler, stores := createTestTemplateBundler()
+	_, err := bundler.LoadStandardTemplates(TemplateImportOptions{})
+	require.NoError(t, err)
+
+	standard, err := StandardTemplates()
+	require.NoError(t, err)
+	for _, template := range standard.Email {
+		rendered, err := stores.Email.RenderTemplate(template.ID, sampleTemplateData(template.Variables))
+		require.NoError(t, err, template.ID)
+		assert.NotContains(t, rendered.Subject+rendered.HTMLBody+rendered.TextBody, "{{", template.ID)
+	}
+	for _, template := range standard.SMS {
+		rendered, err := stores.SMS.RenderTemplate(template.ID, sampleTemplateData(template.Variables))
+		require.NoError(t, err, template.ID)
+		assert.NotContains(t, rendered.Message, "{{", template.ID)
+	}
+	for _, template := range standard.Push {
+		rendered, err := stores.Push.RenderTemplate(template.ID, sampleTemplateData(template.Variables))
+		require.NoError(t, err, template.ID)
+		assert.NotContains(t, rendered.Title+rendered.Message, "{{", template.ID)
+	}
+	for _, template := range standard.Voice {
+		rendered, err := stores.Voice.RenderTemplate(template.ID, sampleTemplateData(template.Variables))
+		require.NoError(t, err, template.ID)
+		assert.NotContains(t, rendered.Message, "{{", template.ID)
+	}
+}
+
+func TestTemplateBundler_LoadStandardTemplatesSkipsMissingStores(t *testing.T) {
+	bundler := NewTemplateBundler(TemplateStores{
+		SMS: providers.NewMockSMSProvider(config.SMSProviderConfig{Enabled: true}),
+	}, utils.NewSimpleLogger("info"))
+
+	report, err := bundler.LoadStandardTemplates(TemplateImportOptions{}, "fr")
+	require.NoError(t, err)
+	assert.Len(t, report.Templates, len(StandardTemplateIDs))
+}
+
+func TestTemplateSyncer_StandardTemplates(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTemplateFile(t, dir, "sms/two_factor_code.json", `{"id": "two_factor_code", "message": "Code: {{code}}"}`)
+	bundler, stores := createTestTemplateBundler()
+	syncer := NewTemplateSyncer(NewDirectoryTemplateSource(dir), bundler, 0, utils.NewSimpleLogger("info"))
+	syncer.SetStandardTemplates(true)
+
+	_, err := syncer.SyncOnce(context.Background())
+	require.NoError(t, err)
+
+	template, err := stores.SMS.GetTemplate("two_factor_code")
+	require.NoError(t, err)
+	assert.Equal(t, "Code: {{code}}", template.Message) // the source overrides the library
+	_, err = stores.SMS.GetTemplate("two_factor_code.de")
+	assert.NoError(t, err)
+	_, err = stores.Email.GetTemplate("payment_failed")
+	assert.NoError(t, err)
+}
+
+// Helper functions
+
+func hasTemplate[T any](templates []*T, id string, templateID func(*T) string) bool {
+	for _, template := range templates {
+		if templateID(template) == id {
+			return true
+		}
+	}
+	return false
+}
+
+func sampleTemplateData(variables []string) map[string]string {
+	data := make(map[string]string, len(variables))
+	for _, name := range variables {
+		switch {
+		case strings.HasSuffix(name, "_url"):
+			data[name] = "https://example.com/" + name
+		case name == "expires_minutes":
+			data[name] = "5"
+		default:
+			data[name] = "sample"
+		}
+	}
+	return data
+}
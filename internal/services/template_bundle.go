@@ -0,0 +1,736 @@
+package services
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// TemplateBundleVersion is the format version of template bundles
+const TemplateBundleVersion = 1
+
+// bundleManifest is the name of the manifest in a bundle tarball
+const bundleManifest = "bundle.json"
+
+// TemplateBundle holds a tenant's templates for every channel, for promotion
+// between environments
+type TemplateBundle struct {
+	Version    int                        `json:"version"`
+	TenantID   string                     `json:"tenant_id,omitempty"`
+	ExportedAt time.Time                  `json:"exported_at"`
+	Branding   map[string]string          `json:"branding,omitempty"` // the tenant's own email branding
+	Email      []*providers.EmailTemplate `json:"email,omitempty"`
+	SMS        []*providers.SMSTemplate   `json:"sms,omitempty"`
+	Push       []*providers.PushTemplate  `json:"push,omitempty"`
+	Chat       []*providers.ChatTemplate  `json:"chat,omitempty"`
+	Voice      []*providers.VoiceTemplate `json:"voice,omitempty"`
+}
+
+// Len returns the number of templates in the bundle
+func (b *TemplateBundle) Len() int {
+	return len(b.Email) + len(b.SMS) + len(b.Push) + len(b.Chat) + len(b.Voice)
+}
+
+// WriteJSON writes the bundle as a single JSON document
+func (b *TemplateBundle) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(b); err != nil {
+		return errors.NewInternalError("failed to write template bundle", err)
+	}
+	return nil
+}
+
+// WriteTarball writes the bundle as a gzipped tarball with a bundle.json
+// manifest and one <channel>/<template id>.json file per template, which
+// reads well in version control and code review
+func (b *TemplateBundle) WriteTarball(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+
+	manifest := *b
+	manifest.Email, manifest.SMS, manifest.Push, manifest.Chat, manifest.Voice = nil, nil, nil, nil, nil
+	if err := writeTarEntry(archive, bundleManifest, &manifest, b.ExportedAt); err != nil {
+		return err
+	}
+	for _, entry := range b.entries() {
+		if err := writeTarEntry(archive, entry.name, entry.template, b.ExportedAt); err != nil {
+			return err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return errors.NewInternalError("failed to write template bundle", err)
+	}
+	if err := gz.Close(); err != nil {
+		return errors.NewInternalError("failed to write template bundle", err)
+	}
+	return nil
+}
+
+// bundleEntry is a template file in a bundle tarball
+type bundleEntry struct {
+	name     string
+	template interface{}
+}
+
+// entries returns the bundle's template files
+func (b *TemplateBundle) entries() []bundleEntry {
+	entries := make([]bundleEntry, 0, b.Len())
+	add := func(channel models.NotificationType, id string, template interface{}) {
+		entries = append(entries, bundleEntry{name: string(channel) + "/" + url.PathEscape(id) + ".json", template: template})
+	}
+	for _, template := range b.Email {
+		add(models.NotificationTypeEmail, template.ID, template)
+	}
+	for _, template := range b.SMS {
+		add(models.NotificationTypeSMS, template.ID, template)
+	}
+	for _, template := range b.Push {
+		add(models.NotificationTypePush, template.ID, template)
+	}
+	for _, template := range b.Chat {
+		add(models.NotificationTypeChat, template.ID, template)
+	}
+	for _, template := range b.Voice {
+		add(models.NotificationTypeVoice, template.ID, template)
+	}
+	return entries
+}
+
+// writeTarEntry writes a JSON file to a tarball
+func writeTarEntry(archive *tar.Writer, name string, value interface{}, modTime time.Time) error {
+	content, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return errors.NewInternalError("failed to encode "+name, err)
+	}
+	content = append(content, '\n')
+
+	header := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), ModTime: modTime, Typeflag: tar.TypeReg}
+	if err := archive.WriteHeader(header); err != nil {
+		return errors.NewInternalError("failed to write "+name, err)
+	}
+	if _, err := archive.Write(content); err != nil {
+		return errors.NewInternalError("failed to write "+name, err)
+	}
+	return nil
+}
+
+// ReadTemplateBundle reads a bundle written by WriteJSON or WriteTarball
+func ReadTemplateBundle(r io.Reader) (*TemplateBundle, error) {
+	reader := bufio.NewReader(r)
+	magic, _ := reader.Peek(2)
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return readTarball(reader)
+	}
+
+	var bundle TemplateBundle
+	if err := json.NewDecoder(reader).Decode(&bundle); err != nil {
+		return nil, errors.NewValidationError("bundle", fmt.Sprintf("invalid template bundle: %v", err))
+	}
+	return &bundle, nil
+}
+
+// readTarball reads a bundle tarball
+func readTarball(r io.Reader) (*TemplateBundle, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, errors.NewValidationError("bundle", fmt.Sprintf("invalid template bundle: %v", err))
+	}
+	defer gz.Close()
+
+	var files bundleFiles
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.NewValidationError("bundle", fmt.Sprintf("invalid template bundle: %v", err))
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := files.add(header.Name, archive); err != nil {
+			return nil, err
+		}
+	}
+
+	if files.manifest == nil {
+		return nil, errors.NewValidationError("bundle", "template bundle has no "+bundleManifest)
+	}
+	return files.bundle(), nil
+}
+
+// bundleFiles collects the manifest and template files of a bundle
+type bundleFiles struct {
+	manifest  *TemplateBundle
+	templates TemplateBundle
+}
+
+// add decodes a bundle file: the manifest or a template in a channel
+// directory
+func (f *bundleFiles) add(name string, r io.Reader) error {
+	decoder := json.NewDecoder(r)
+	var err error
+	if clean := path.Clean(name); clean == bundleManifest {
+		f.manifest = &TemplateBundle{}
+		err = decoder.Decode(f.manifest)
+	} else {
+		err = decodeTemplateEntry(decoder, clean, &f.templates)
+	}
+	if err != nil {
+		return errors.NewValidationError("bundle", fmt.Sprintf("invalid template bundle file %s: %v", name, err))
+	}
+	return nil
+}
+
+// bundle returns the bundle, with a current version manifest when the files
+// had none
+func (f *bundleFiles) bundle() *TemplateBundle {
+	bundle := f.manifest
+	if bundle == nil {
+		bundle = &TemplateBundle{Version: TemplateBundleVersion}
+	}
+	bundle.Email, bundle.SMS, bundle.Push, bundle.Chat, bundle.Voice = f.templates.Email, f.templates.SMS, f.templates.Push, f.templates.Chat, f.templates.Voice
+	return bundle
+}
+
+// decodeTemplateEntry decodes a template file into the bundle, by the
+// channel directory it is in
+func decodeTemplateEntry(decoder *json.Decoder, name string, bundle *TemplateBundle) error {
+	channel, _, found := strings.Cut(name, "/")
+	if !found {
+		return fmt.Errorf("not in a channel directory")
+	}
+
+	switch models.NotificationType(channel) {
+	case models.NotificationTypeEmail:
+		return decodeTemplate(decoder, &bundle.Email)
+	case models.NotificationTypeSMS:
+		return decodeTemplate(decoder, &bundle.SMS)
+	case models.NotificationTypePush:
+		return decodeTemplate(decoder, &bundle.Push)
+	case models.NotificationTypeChat:
+		return decodeTemplate(decoder, &bundle.Chat)
+	case models.NotificationTypeVoice:
+		return decodeTemplate(decoder, &bundle.Voice)
+	}
+	return fmt.Errorf("unknown channel %q", channel)
+}
+
+// decodeTemplate decodes a template and appends it to templates
+func decodeTemplate[T any](decoder *json.Decoder, templates *[]*T) error {
+	template := new(T)
+	if err := decoder.Decode(template); err != nil {
+		return err
+	}
+	*templates = append(*templates, template)
+	return nil
+}
+
+// ConflictPolicy decides what importing does with a template whose ID is
+// already taken by a different template
+type ConflictPolicy string
+
+const (
+	// ConflictSkip keeps the existing template
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictOverwrite replaces the existing template
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	// ConflictVersion keeps the existing template and stores the imported one
+	// as <id>_v2, <id>_v3 and so on
+	ConflictVersion ConflictPolicy = "version"
+)
+
+// ImportAction is what importing did with a template
+type ImportAction string
+
+const (
+	ImportCreated     ImportAction = "created"
+	ImportOverwritten ImportAction = "overwritten"
+	ImportVersioned   ImportAction = "versioned"
+	ImportSkipped     ImportAction = "skipped"
+	ImportUnchanged   ImportAction = "unchanged" // an identical template already exists
+)
+
+// TemplateImportOptions controls a bundle import
+type TemplateImportOptions struct {
+	Conflict ConflictPolicy // skip when empty
+	TenantID string         // tenant to import branding into; the bundle's tenant when empty
+	DryRun   bool           // report what would happen without changing anything
+}
+
+// ImportedTemplate reports what importing did with one template
+type ImportedTemplate struct {
+	Channel  models.NotificationType `json:"channel"`
+	ID       string                  `json:"id"`
+	StoredAs string                  `json:"stored_as"`
+	Action   ImportAction            `json:"action"`
+}
+
+// TemplateImportReport reports the outcome of a bundle import
+type TemplateImportReport struct {
+	DryRun    bool               `json:"dry_run,omitempty"`
+	Templates []ImportedTemplate `json:"templates"`
+	Branding  ImportAction       `json:"branding,omitempty"`
+}
+
+// Count returns the number of templates importing did action with
+func (r *TemplateImportReport) Count(action ImportAction) int {
+	count := 0
+	for _, template := range r.Templates {
+		if template.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// TemplateStores are the providers whose templates are bundled. Channels
+// without a store are left out of exports, and imports of bundles with
+// templates for them fail.
+type TemplateStores struct {
+	Email *providers.MockEmailProvider
+	SMS   *providers.MockSMSProvider
+	Push  *providers.MockPushProvider
+	Chat  *providers.ChatWebhookProvider
+	Voice *providers.TwilioVoiceProvider
+}
+
+// TemplateBundler exports templates to bundles and imports them
+type TemplateBundler struct {
+	stores TemplateStores
+	logger interfaces.Logger
+}
+
+// NewTemplateBundler creates a template bundler
+func NewTemplateBundler(stores TemplateStores, logger interfaces.Logger) *TemplateBundler {
+	return &TemplateBundler{stores: stores, logger: logger}
+}
+
+// Export bundles every template of every channel, with the tenant's email
+// branding when tenantID is set. Templates are copied, so the bundle can be
+// changed without changing the stored templates.
+func (b *TemplateBundler) Export(tenantID string) (*TemplateBundle, error) {
+	bundle := &TemplateBundle{
+		Version:    TemplateBundleVersion,
+		TenantID:   tenantID,
+		ExportedAt: time.Now().UTC(),
+	}
+
+	var err error
+	if b.stores.Email != nil {
+		if bundle.Email, err = cloneTemplates(b.stores.Email.Templates()); err != nil {
+			return nil, err
+		}
+		if tenantID != "" {
+			bundle.Branding, _ = b.stores.Email.Layouts().TenantBranding(tenantID)
+		}
+	}
+	if b.stores.SMS != nil {
+		if bundle.SMS, err = cloneTemplates(b.stores.SMS.Templates()); err != nil {
+			return nil, err
+		}
+	}
+	if b.stores.Push != nil {
+		if bundle.Push, err = cloneTemplates(b.stores.Push.Templates()); err != nil {
+			return nil, err
+		}
+	}
+	if b.stores.Chat != nil {
+		if bundle.Chat, err = cloneTemplates(b.stores.Chat.Templates()); err != nil {
+			return nil, err
+		}
+	}
+	if b.stores.Voice != nil {
+		if bundle.Voice, err = cloneTemplates(b.stores.Voice.Templates()); err != nil {
+			return nil, err
+		}
+	}
+	return bundle, nil
+}
+
+// Import stores a bundle's templates. Importing is idempotent: templates
+// identical to a stored one, apart from timestamps, are left alone, so a
+// bundle can be imported again safely. The whole bundle is checked before
+// anything is stored.
+func (b *TemplateBundler) Import(bundle *TemplateBundle, options TemplateImportOptions) (*TemplateImportReport, error) {
+	if options.Conflict == "" {
+		options.Conflict = ConflictSkip
+	}
+	if err := b.check(bundle, options); err != nil {
+		return nil, err
+	}
+
+	report := &TemplateImportReport{DryRun: options.DryRun, Templates: make([]ImportedTemplate, 0, bundle.Len())}
+	var err error
+	if len(bundle.Email) > 0 {
+		err = importTemplates(b.emailChannel(), bundle.Email, options, report)
+	}
+	if err == nil && len(bundle.SMS) > 0 {
+		err = importTemplates(b.smsChannel(), bundle.SMS, options, report)
+	}
+	if err == nil && len(bundle.Push) > 0 {
+		err = importTemplates(b.pushChannel(), bundle.Push, options, report)
+	}
+	if err == nil && len(bundle.Chat) > 0 {
+		err = importTemplates(b.chatChannel(), bundle.Chat, options, report)
+	}
+	if err == nil && len(bundle.Voice) > 0 {
+		err = importTemplates(b.voiceChannel(), bundle.Voice, options, report)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tenantID := options.TenantID
+	if tenantID == "" {
+		tenantID = bundle.TenantID
+	}
+	if tenantID != "" && len(bundle.Branding) > 0 {
+		report.Branding = b.importBranding(tenantID, bundle.Branding, options)
+	}
+
+	b.logger.Infof("Imported template bundle: %d created, %d overwritten, %d versioned, %d skipped, %d unchanged (dry run: %t)",
+		report.Count(ImportCreated), report.Count(ImportOverwritten), report.Count(ImportVersioned),
+		report.Count(ImportSkipped), report.Count(ImportUnchanged), options.DryRun)
+	return report, nil
+}
+
+// Replace makes a bundle's templates the only templates of every configured
+// channel, and sets its tenant's branding. Channels without templates in the
+// bundle are left with none. The whole bundle is checked first, so a bad
+// bundle leaves the current templates in place.
+func (b *TemplateBundler) Replace(bundle *TemplateBundle) error {
+	if err := b.check(bundle, TemplateImportOptions{Conflict: ConflictSkip}); err != nil {
+		return err
+	}
+
+	// Email goes first: its MJML is only compiled when it is stored
+	if b.stores.Email != nil {
+		if err := replaceTemplates(b.stores.Email.ReplaceTemplates, bundle.Email); err != nil {
+			return err
+		}
+	}
+	if b.stores.SMS != nil {
+		if err := replaceTemplates(b.stores.SMS.ReplaceTemplates, bundle.SMS); err != nil {
+			return err
+		}
+	}
+	if b.stores.Push != nil {
+		if err := replaceTemplates(b.stores.Push.ReplaceTemplates, bundle.Push); err != nil {
+			return err
+		}
+	}
+	if b.stores.Chat != nil {
+		if err := replaceTemplates(b.stores.Chat.ReplaceTemplates, bundle.Chat); err != nil {
+			return err
+		}
+	}
+	if b.stores.Voice != nil {
+		if err := replaceTemplates(b.stores.Voice.ReplaceTemplates, bundle.Voice); err != nil {
+			return err
+		}
+	}
+
+	if bundle.TenantID != "" && len(bundle.Branding) > 0 {
+		b.stores.Email.Layouts().SetBranding(bundle.TenantID, bundle.Branding)
+	}
+	return nil
+}
+
+// replaceTemplates replaces a store's templates with copies of templates
+func replaceTemplates[T any](replace func([]*T) error, templates []*T) error {
+	clones, err := cloneTemplates(templates)
+	if err != nil {
+		return err
+	}
+	return replace(clones)
+}
+
+// check checks a bundle and the import options before importing
+func (b *TemplateBundler) check(bundle *TemplateBundle, options TemplateImportOptions) error {
+	switch options.Conflict {
+	case ConflictSkip, ConflictOverwrite, ConflictVersion:
+	default:
+		return errors.NewValidationError("conflict", fmt.Sprintf("unknown conflict policy: %s", options.Conflict))
+	}
+	if bundle.Version != TemplateBundleVersion {
+		return errors.NewValidationError("bundle", fmt.Sprintf("unsupported template bundle version %d", bundle.Version))
+	}
+
+	if err := checkTemplates(b.emailChannel(), bundle.Email); err != nil {
+		return err
+	}
+	if err := checkTemplates(b.smsChannel(), bundle.SMS); err != nil {
+		return err
+	}
+	if err := checkTemplates(b.pushChannel(), bundle.Push); err != nil {
+		return err
+	}
+	if err := checkTemplates(b.chatChannel(), bundle.Chat); err != nil {
+		return err
+	}
+	if err := checkTemplates(b.voiceChannel(), bundle.Voice); err != nil {
+		return err
+	}
+	if len(bundle.Branding) > 0 && b.stores.Email == nil {
+		return errors.NewValidationError("bundle", "bundle has email branding but no email templates are configured")
+	}
+	return nil
+}
+
+// importBranding sets a tenant's branding, following the conflict policy
+// when the tenant already has different branding
+func (b *TemplateBundler) importBranding(tenantID string, branding map[string]string, options TemplateImportOptions) ImportAction {
+	layouts := b.stores.Email.Layouts()
+	existing, exists := layouts.TenantBranding(tenantID)
+
+	action := ImportCreated
+	switch {
+	case exists && reflect.DeepEqual(existing, branding):
+		return ImportUnchanged
+	case exists && options.Conflict == ConflictOverwrite:
+		action = ImportOverwritten
+	case exists:
+		// Branding has no versions, so versioning keeps it as well
+		return ImportSkipped
+	}
+
+	if !options.DryRun {
+		layouts.SetBranding(tenantID, branding)
+	}
+	return action
+}
+
+// templateChannel adapts a channel's template store for bundling
+type templateChannel[T any] struct {
+	channel    models.NotificationType
+	configured bool
+	id         func(*T) *string
+	get        func(id string) (*T, error)
+	add        func(*T) error
+	check      func(*T) error // checks a template before anything is stored
+	normalize  func(*T)       // sets the defaults the store sets, so templates compare as stored
+}
+
+// emailChannel adapts the email template store
+func (b *TemplateBundler) emailChannel() templateChannel[providers.EmailTemplate] {
+	store := b.stores.Email
+	return templateChannel[providers.EmailTemplate]{
+		channel:    models.NotificationTypeEmail,
+		configured: store != nil,
+		id:         func(t *providers.EmailTemplate) *string { return &t.ID },
+		get:        func(id string) (*providers.EmailTemplate, error) { return store.GetTemplate(id) },
+		add:        func(t *providers.EmailTemplate) error { return store.AddTemplate(t) },
+		check:      func(t *providers.EmailTemplate) error { return t.Schema.Check() },
+	}
+}
+
+// smsChannel adapts the SMS template store
+func (b *TemplateBundler) smsChannel() templateChannel[providers.SMSTemplate] {
+	store := b.stores.SMS
+	return templateChannel[providers.SMSTemplate]{
+		channel:    models.NotificationTypeSMS,
+		configured: store != nil,
+		id:         func(t *providers.SMSTemplate) *string { return &t.ID },
+		get:        func(id string) (*providers.SMSTemplate, error) { return store.GetTemplate(id) },
+		add:        func(t *providers.SMSTemplate) error { return store.AddTemplate(t) },
+		check:      func(t *providers.SMSTemplate) error { return t.Schema.Check() },
+		normalize:  func(t *providers.SMSTemplate) { t.SetDefaults() },
+	}
+}
+
+// pushChannel adapts the push template store
+func (b *TemplateBundler) pushChannel() templateChannel[providers.PushTemplate] {
+	store := b.stores.Push
+	return templateChannel[providers.PushTemplate]{
+		channel:    models.NotificationTypePush,
+		configured: store != nil,
+		id:         func(t *providers.PushTemplate) *string { return &t.ID },
+		get:        func(id string) (*providers.PushTemplate, error) { return store.GetTemplate(id) },
+		add:        func(t *providers.PushTemplate) error { return store.AddTemplate(t) },
+		check:      func(t *providers.PushTemplate) error { return t.Schema.Check() },
+	}
+}
+
+// chatChannel adapts the chat template store
+func (b *TemplateBundler) chatChannel() templateChannel[providers.ChatTemplate] {
+	store := b.stores.Chat
+	return templateChannel[providers.ChatTemplate]{
+		channel:    models.NotificationTypeChat,
+		configured: store != nil,
+		id:         func(t *providers.ChatTemplate) *string { return &t.ID },
+		get:        func(id string) (*providers.ChatTemplate, error) { return store.GetTemplate(id) },
+		add:        func(t *providers.ChatTemplate) error { return store.AddTemplate(t) },
+		check: func(t *providers.ChatTemplate) error {
+			if t.Payload != "" && !json.Valid([]byte(t.Payload)) {
+				return errors.NewValidationError("payload", "template payload must be valid JSON")
+			}
+			return nil
+		},
+	}
+}
+
+// voiceChannel adapts the voice template store
+func (b *TemplateBundler) voiceChannel() templateChannel[providers.VoiceTemplate] {
+	store := b.stores.Voice
+	return templateChannel[providers.VoiceTemplate]{
+		channel:    models.NotificationTypeVoice,
+		configured: store != nil,
+		id:         func(t *providers.VoiceTemplate) *string { return &t.ID },
+		get:        func(id string) (*providers.VoiceTemplate, error) { return store.GetTemplate(id) },
+		add:        func(t *providers.VoiceTemplate) error { return store.AddTemplate(t) },
+		check:      func(*providers.VoiceTemplate) error { return nil },
+	}
+}
+
+// checkTemplates checks a channel's bundled templates: the channel must be
+// configured, and IDs must be set and unique
+func checkTemplates[T any](channel templateChannel[T], templates []*T) error {
+	if len(templates) == 0 {
+		return nil
+	}
+	if !channel.configured {
+		return errors.NewValidationError("bundle", fmt.Sprintf("bundle has %s templates but no %s templates are configured", channel.channel, channel.channel))
+	}
+
+	seen := make(map[string]bool, len(templates))
+	for _, template := range templates {
+		id := *channel.id(template)
+		if id == "" {
+			return errors.NewValidationError("bundle", fmt.Sprintf("bundle has a %s template without an ID", channel.channel))
+		}
+		if seen[id] {
+			return errors.NewValidationError("bundle", fmt.Sprintf("bundle has %s template %s more than once", channel.channel, id))
+		}
+		seen[id] = true
+
+		if err := channel.check(template); err != nil {
+			return errors.NewValidationError("bundle", fmt.Sprintf("%s template %s is invalid: %v", channel.channel, id, err))
+		}
+	}
+	return nil
+}
+
+// importTemplates imports a channel's templates into its store
+func importTemplates[T any](channel templateChannel[T], templates []*T, options TemplateImportOptions, report *TemplateImportReport) error {
+	for _, template := range templates {
+		imported, err := cloneTemplate(template)
+		if err != nil {
+			return err
+		}
+
+		if channel.normalize != nil {
+			channel.normalize(imported)
+		}
+
+		id := *channel.id(imported)
+		result := ImportedTemplate{Channel: channel.channel, ID: id, StoredAs: id}
+		existing, err := channel.get(id)
+		switch {
+		case err != nil:
+			result.Action = ImportCreated
+		case sameTemplate(existing, imported):
+			result.Action = ImportUnchanged
+		case options.Conflict == ConflictOverwrite:
+			result.Action = ImportOverwritten
+		case options.Conflict == ConflictVersion:
+			result.StoredAs, result.Action = versionedID(channel, id, imported)
+		default:
+			result.Action = ImportSkipped
+		}
+
+		stored := result.Action == ImportCreated || result.Action == ImportOverwritten || result.Action == ImportVersioned
+		if stored && !options.DryRun {
+			*channel.id(imported) = result.StoredAs
+			if err := channel.add(imported); err != nil {
+				return err
+			}
+		}
+		report.Templates = append(report.Templates, result)
+	}
+	return nil
+}
+
+// versionedID returns the first free <id>_v<n> ID for an imported template,
+// or the ID of an earlier version identical to it
+func versionedID[T any](channel templateChannel[T], id string, imported *T) (string, ImportAction) {
+	for version := 2; ; version++ {
+		candidate := fmt.Sprintf("%s_v%d", id, version)
+		existing, err := channel.get(candidate)
+		if err != nil {
+			return candidate, ImportVersioned
+		}
+		if sameTemplate(existing, imported) {
+			return candidate, ImportUnchanged
+		}
+	}
+}
+
+// sameTemplate reports whether two templates have the same content, ignoring
+// their IDs and timestamps
+func sameTemplate(a, b interface{}) bool {
+	contentA, errA := templateContent(a)
+	contentB, errB := templateContent(b)
+	return errA == nil && errB == nil && reflect.DeepEqual(contentA, contentB)
+}
+
+// templateContent returns a template's fields, without its ID and timestamps
+func templateContent(template interface{}) (map[string]interface{}, error) {
+	encoded, err := json.Marshal(template)
+	if err != nil {
+		return nil, err
+	}
+
+	var content map[string]interface{}
+	if err := json.Unmarshal(encoded, &content); err != nil {
+		return nil, err
+	}
+	delete(content, "id")
+	delete(content, "created_at")
+	delete(content, "updated_at")
+	return content, nil
+}
+
+// cloneTemplates deep copies templates
+func cloneTemplates[T any](templates []*T) ([]*T, error) {
+	clones := make([]*T, len(templates))
+	for i, template := range templates {
+		clone, err := cloneTemplate(template)
+		if err != nil {
+			return nil, err
+		}
+		clones[i] = clone
+	}
+	return clones, nil
+}
+
+// cloneTemplate deep copies a template through its JSON form
+func cloneTemplate[T any](template *T) (*T, error) {
+	encoded, err := json.Marshal(template)
+	if err != nil {
+		return nil, errors.NewInternalError("failed to copy template", err)
+	}
+
+	clone := new(T)
+	if err := json.Unmarshal(encoded, clone); err != nil {
+		return nil, errors.NewInternalError("failed to copy template", err)
+	}
+	return clone, nil
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/internal/templateschema"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestTemplateBundler_ExportImport(t *testing.T) {
+	staging, stagingStores := createTestTemplateBundler()
+	require.NoError(t, stagingStores.SMS.AddTemplate(&providers.SMSTemplate{
+		ID:      "payment_due",
+		Message: "{{amount}} is due",
+		Schema:  templateschema.Schema{"amount": {Type: templateschema.TypeNumber, Required: true}},
+	}))
+	require.NoError(t, stagingStores.Email.AddTemplate(&providers.EmailTemplate{ID: "digest", Subject: "Your digest", TextBody: "{{items}}"}))
+	stagingStores.Email.Layouts().SetBranding("acme", map[string]string{"name": "Acme"})
+
+	bundle, err := staging.Export("acme")
+	require.NoError(t, err)
+	assert.Equal(t, TemplateBundleVersion, bundle.Version)
+	assert.Equal(t, map[string]string{"name": "Acme"}, bundle.Branding)
+	assert.Equal(t, len(stagingStores.SMS.Templates()), len(bundle.SMS))
+
+	var archive bytes.Buffer
+	require.NoError(t, bundle.WriteTarball(&archive))
+	read, err := ReadTemplateBundle(&archive)
+	require.NoError(t, err)
+	assert.Equal(t, bundle.Len(), read.Len())
+	assert.Equal(t, "acme", read.TenantID)
+
+	production, productionStores := createTestTemplateBundler()
+	report, err := production.Import(read, TemplateImportOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Count(ImportCreated))
+	assert.Equal(t, read.Len()-2, report.Count(ImportUnchanged)) // default templates match
+	assert.Equal(t, ImportCreated, report.Branding)
+
+	template, err := productionStores.SMS.GetTemplate("payment_due")
+	require.NoError(t, err)
+	assert.Equal(t, templateschema.TypeNumber, template.Schema["amount"].Type)
+	assert.Equal(t, "Acme", productionStores.Email.Layouts().Branding("acme")["name"])
+
+	// Importing again changes nothing
+	report, err = production.Import(read, TemplateImportOptions{Conflict: ConflictVersion})
+	require.NoError(t, err)
+	assert.Equal(t, read.Len(), report.Count(ImportUnchanged))
+	assert.Equal(t, ImportUnchanged, report.Branding)
+}
+
+func TestTemplateBundler_ImportConflicts(t *testing.T) {
+	bundler, stores := createTestTemplateBundler()
+	bundle := &TemplateBundle{
+		Version: TemplateBundleVersion,
+		SMS:     []*providers.SMSTemplate{{ID: "verification", Message: "Code: {{code}}"}},
+	}
+
+	report, err := bundler.Import(bundle, TemplateImportOptions{Conflict: ConflictSkip})
+	require.NoError(t, err)
+	assert.Equal(t, ImportSkipped, report.Templates[0].Action)
+
+	report, err = bundler.Import(bundle, TemplateImportOptions{Conflict: ConflictVersion, DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, ImportVersioned, report.Templates[0].Action)
+	_, err = stores.SMS.GetTemplate("verification_v2")
+	assert.Error(t, err) // dry runs store nothing
+
+	report, err = bundler.Import(bundle, TemplateImportOptions{Conflict: ConflictVersion})
+	require.NoError(t, err)
+	assert.Equal(t, ImportedTemplate{Channel: "sms", ID: "verification", StoredAs: "verification_v2", Action: ImportVersioned}, report.Templates[0])
+	versioned, err := stores.SMS.GetTemplate("verification_v2")
+	require.NoError(t, err)
+	assert.Equal(t, "Code: {{code}}", versioned.Message)
+
+	report, err = bundler.Import(bundle, TemplateImportOptions{Conflict: ConflictVersion})
+	require.NoError(t, err)
+	assert.Equal(t, ImportUnchanged, report.Templates[0].Action)
+	assert.Equal(t, "verification_v2", report.Templates[0].StoredAs)
+
+	report, err = bundler.Import(bundle, TemplateImportOptions{Conflict: ConflictOverwrite})
+	require.NoError(t, err)
+	assert.Equal(t, ImportOverwritten, report.Templates[0].Action)
+	overwritten, err := stores.SMS.GetTemplate("verification")
+	require.NoError(t, err)
+	assert.Equal(t, "Code: {{code}}", overwritten.Message)
+
+	// Imported templates are copies of the bundle's
+	bundle.SMS[0].Message = "changed"
+	assert.Equal(t, "Code: {{code}}", overwritten.Message)
+}
+
+func TestTemplateBundler_ImportInvalid(t *testing.T) {
+	bundler, _ := createTestTemplateBundler()
+	smsOnly := NewTemplateBundler(TemplateStores{SMS: providers.NewMockSMSProvider(config.SMSProviderConfig{Enabled: true})}, utils.NewSimpleLogger("info"))
+
+	tests := []struct {
+		name     string
+		bundler  *TemplateBundler
+		bundle   *TemplateBundle
+		conflict ConflictPolicy
+	}{
+		{"unsupported version", bundler, &TemplateBundle{Version: 99}, ""},
+		{"unknown conflict policy", bundler, &TemplateBundle{Version: TemplateBundleVersion}, "rename"},
+		{"missing ID", bundler, &TemplateBundle{Version: TemplateBundleVersion, Push: []*providers.PushTemplate{{Title: "Hi"}}}, ""},
+		{"duplicate ID", bundler, &TemplateBundle{Version: TemplateBundleVersion, SMS: []*providers.SMSTemplate{{ID: "a"}, {ID: "a"}}}, ""},
+		{"invalid schema", bundler, &TemplateBundle{Version: TemplateBundleVersion, SMS: []*providers.SMSTemplate{{ID: "a"}, {ID: "b", Schema: templateschema.Schema{"x": {Type: "money"}}}}}, ""},
+		{"channel not configured", smsOnly, &TemplateBundle{Version: TemplateBundleVersion, Email: []*providers.EmailTemplate{{ID: "welcome"}}}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.bundler.Import(tt.bundle, TemplateImportOptions{Conflict: tt.conflict})
+			notifErr, ok := errors.AsNotificationError(err)
+			require.True(t, ok)
+			assert.Equal(t, errors.ErrorCodeValidationFailed, notifErr.Code)
+		})
+	}
+
+	// Nothing is stored from a bundle that fails its checks
+	_, stores := createTestTemplateBundler()
+	_, err := NewTemplateBundler(stores, utils.NewSimpleLogger("info")).Import(tests[4].bundle, TemplateImportOptions{})
+	require.Error(t, err)
+	_, err = stores.SMS.GetTemplate("a")
+	assert.Error(t, err)
+}
+
+func TestReadTemplateBundle_JSON(t *testing.T) {
+	bundler, _ := createTestTemplateBundler()
+	bundle, err := bundler.Export("")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, bundle.WriteJSON(&buf))
+	read, err := ReadTemplateBundle(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, bundle.Len(), read.Len())
+	assert.Empty(t, read.Branding)
+
+	_, err = ReadTemplateBundle(bytes.NewBufferString("not a bundle"))
+	assert.Error(t, err)
+}
+
+// Helper functions
+
+func createTestTemplateBundler() (*TemplateBundler, TemplateStores) {
+	stores := TemplateStores{
+		Email: providers.NewMockEmailProvider(config.EmailProviderConfig{Enabled: true}),
+		SMS:   providers.NewMockSMSProvider(config.SMSProviderConfig{Enabled: true}),
+		Push:  providers.NewMockPushProvider(config.PushProviderConfig{Enabled: true}),
+		Chat:  providers.NewSlackWebhookProvider(config.ChatProviderConfig{Enabled: true}),
+		Voice: providers.NewTwilioVoiceProvider(config.VoiceProviderConfig{Enabled: true}),
+	}
+	return NewTemplateBundler(stores, utils.NewSimpleLogger("info")), stores
+}
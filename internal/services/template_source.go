@@ -0,0 +1,311 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// TemplateSource loads the templates to serve, laid out as in a bundle
+// tarball: <channel>/<template id>.json files and an optional bundle.json
+// manifest
+type TemplateSource interface {
+	// Load returns the templates and a revision that changes when they do
+	Load(ctx context.Context) (*TemplateBundle, string, error)
+}
+
+// NewTemplateSource creates the template source configured by cfg: a Git
+// checkout when a repository is set, or else a local directory
+func NewTemplateSource(cfg config.TemplateConfig) (TemplateSource, error) {
+	if cfg.Dir == "" {
+		return nil, errors.NewValidationError("templates.dir", "template directory is required")
+	}
+	if cfg.GitURL != "" {
+		return NewGitTemplateSource(cfg.GitURL, cfg.GitBranch, cfg.Dir, cfg.GitPath), nil
+	}
+	return NewDirectoryTemplateSource(cfg.Dir), nil
+}
+
+// DirectoryTemplateSource loads templates from a local directory. Its
+// revision is a hash of the files, so edits are picked up on the next sync.
+type DirectoryTemplateSource struct {
+	dir string
+}
+
+// NewDirectoryTemplateSource creates a directory template source
+func NewDirectoryTemplateSource(dir string) *DirectoryTemplateSource {
+	return &DirectoryTemplateSource{dir: dir}
+}
+
+// Load reads the directory's JSON files. Other files, such as a README, and
+// hidden directories, such as .git, are ignored.
+func (s *DirectoryTemplateSource) Load(ctx context.Context) (*TemplateBundle, string, error) {
+	var files bundleFiles
+	hash := sha256.New()
+	err := filepath.WalkDir(s.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if entry.IsDir() {
+			if path != s.dir && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.dir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(hash, "%s\x00%d\x00", name, len(content))
+		hash.Write(content)
+		return files.add(name, bytes.NewReader(content))
+	})
+	if err != nil {
+		if _, ok := errors.AsNotificationError(err); ok {
+			return nil, "", err
+		}
+		return nil, "", errors.NewInternalError("failed to read templates from "+s.dir, err)
+	}
+
+	return files.bundle(), hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// GitTemplateSource loads templates from a branch of a Git repository,
+// cloned into a local directory on the first load and fetched on later
+// ones. Its revision is the commit, so templates change only when the
+// branch does. It runs the git command, which must be installed.
+type GitTemplateSource struct {
+	url    string
+	branch string
+	dir    string
+	path   string
+}
+
+// NewGitTemplateSource creates a Git template source. Templates are read from
+// path within the repository, or its root when path is empty.
+func NewGitTemplateSource(url, branch, dir, path string) *GitTemplateSource {
+	if branch == "" {
+		branch = "main"
+	}
+	return &GitTemplateSource{url: url, branch: branch, dir: dir, path: path}
+}
+
+// Load brings the checkout up to date with the branch and reads it
+func (s *GitTemplateSource) Load(ctx context.Context) (*TemplateBundle, string, error) {
+	if err := s.checkout(ctx); err != nil {
+		return nil, "", err
+	}
+	revision, err := runGit(ctx, s.dir, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, "", err
+	}
+
+	bundle, _, err := NewDirectoryTemplateSource(filepath.Join(s.dir, s.path)).Load(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	return bundle, revision, nil
+}
+
+// checkout clones the branch, or fetches it and resets the checkout to it.
+// Local changes in the checkout are discarded.
+func (s *GitTemplateSource) checkout(ctx context.Context) error {
+	if _, err := os.Stat(filepath.Join(s.dir, ".git")); os.IsNotExist(err) {
+		_, err := runGit(ctx, "", "clone", "--quiet", "--depth", "1", "--branch", s.branch, s.url, s.dir)
+		return err
+	}
+
+	if _, err := runGit(ctx, s.dir, "fetch", "--quiet", "--depth", "1", "origin", s.branch); err != nil {
+		return err
+	}
+	_, err := runGit(ctx, s.dir, "reset", "--quiet", "--hard", "FETCH_HEAD")
+	return err
+}
+
+// runGit runs a git command in dir and returns its trimmed output
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", errors.NewInternalError(fmt.Sprintf("git %s failed: %s", args[0], strings.TrimSpace(string(output))), err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// TemplateSyncStats holds template sync metrics
+type TemplateSyncStats struct {
+	Syncs      int64      `json:"syncs"`
+	Failures   int64      `json:"failures"`
+	Revision   string     `json:"revision,omitempty"` // of the active templates
+	Templates  int        `json:"templates"`
+	LastSyncAt *time.Time `json:"last_sync_at,omitempty"`
+	LastError  string     `json:"last_error,omitempty"`
+}
+
+// TemplateSyncer keeps the active templates in step with a template source.
+// Each sync loads the source and, when its revision changed, checks the
+// templates and swaps them in, all channels at once. A source that fails to
+// load or holds invalid templates leaves the active templates in place.
+type TemplateSyncer struct {
+	source   TemplateSource
+	bundler  *TemplateBundler
+	interval time.Duration
+	logger   interfaces.Logger
+	standard bool // layer the standard library under the source's templates
+
+	syncMu  sync.Mutex // serializes syncs
+	mu      sync.Mutex
+	stats   TemplateSyncStats
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	running bool
+}
+
+// NewTemplateSyncer creates a template syncer
+func NewTemplateSyncer(source TemplateSource, bundler *TemplateBundler, interval time.Duration, logger interfaces.Logger) *TemplateSyncer {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	return &TemplateSyncer{
+		source:   source,
+		bundler:  bundler,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// SetStandardTemplates makes each sync add the standard template library
+// under the source's templates, so the source can override any of them by ID.
+// Call it before Start.
+func (s *TemplateSyncer) SetStandardTemplates(enabled bool) {
+	s.standard = enabled
+}
+
+// Start syncs immediately and then every interval until Stop is called.
+// Calling Start on a running syncer has no effect.
+func (s *TemplateSyncer) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return
+	}
+
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.running = true
+
+	s.wg.Add(1)
+	go s.run(ctx)
+
+	s.logger.Infof("Started template sync (every %s)", s.interval)
+}
+
+// Stop stops the syncer and waits for a sync in progress to finish
+func (s *TemplateSyncer) Stop() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.cancel()
+	s.running = false
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	s.logger.Infof("Stopped template sync")
+}
+
+// run syncs until the context is cancelled
+func (s *TemplateSyncer) run(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		// Failures are recorded in the stats and logged
+		_, _ = s.SyncOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SyncOnce loads the source and swaps in its templates when its revision
+// changed. It reports whether the active templates were replaced.
+func (s *TemplateSyncer) SyncOnce(ctx context.Context) (bool, error) {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	now := time.Now()
+	bundle, revision, err := s.source.Load(ctx)
+	if err == nil && s.standard {
+		bundle, err = s.bundler.withStandardTemplates(bundle)
+	}
+	replaced := false
+	if err == nil && revision != s.Stats().Revision {
+		err = s.bundler.Replace(bundle)
+		replaced = err == nil
+	}
+
+	s.mu.Lock()
+	s.stats.Syncs++
+	s.stats.LastSyncAt = &now
+	s.stats.LastError = ""
+	if err != nil {
+		s.stats.Failures++
+		s.stats.LastError = err.Error()
+	}
+	if replaced {
+		s.stats.Revision = revision
+		s.stats.Templates = bundle.Len()
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		s.logger.Errorf("Template sync failed, keeping the active templates: %v", err)
+		return false, err
+	}
+	if replaced {
+		s.logger.Infof("Synced %d templates at revision %s", bundle.Len(), revision)
+	}
+	return replaced, nil
+}
+
+// Stats returns the template sync metrics
+func (s *TemplateSyncer) Stats() TemplateSyncStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.stats
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+)
+
+func TestDirectoryTemplateSource_Load(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTemplateFile(t, dir, "sms/otp.json", `{"id": "otp", "message": "Your code is {{code}}"}`)
+	writeTestTemplateFile(t, dir, "push/welcome.json", `{"id": "welcome", "title": "Welcome", "message": "Hi {{name}}"}`)
+	writeTestTemplateFile(t, dir, "README.md", "Templates ship through code review")
+	writeTestTemplateFile(t, dir, ".git/config.json", `ignored`)
+
+	source := NewDirectoryTemplateSource(dir)
+	bundle, revision, err := source.Load(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, TemplateBundleVersion, bundle.Version)
+	require.Len(t, bundle.SMS, 1)
+	assert.Equal(t, "Your code is {{code}}", bundle.SMS[0].Message)
+	require.Len(t, bundle.Push, 1)
+
+	_, same, err := source.Load(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, revision, same)
+
+	writeTestTemplateFile(t, dir, "sms/otp.json", `{"id": "otp", "message": "Code: {{code}}"}`)
+	_, changed, err := source.Load(context.Background())
+	require.NoError(t, err)
+	assert.NotEqual(t, revision, changed)
+
+	writeTestTemplateFile(t, dir, "fax/cover.json", `{"id": "cover"}`)
+	_, _, err = source.Load(context.Background())
+	assert.Error(t, err)
+}
+
+func TestTemplateSyncer_SyncOnce(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTemplateFile(t, dir, "sms/otp.json", `{"id": "otp", "message": "Your code is {{code}}"}`)
+	bundler, stores := createTestTemplateBundler()
+	syncer := NewTemplateSyncer(NewDirectoryTemplateSource(dir), bundler, 0, utils.NewSimpleLogger("info"))
+
+	replaced, err := syncer.SyncOnce(context.Background())
+	require.NoError(t, err)
+	assert.True(t, replaced)
+	assert.Len(t, stores.SMS.Templates(), 1) // the source is the whole set
+	assert.Empty(t, stores.Email.Templates())
+	assert.Equal(t, 1, syncer.Stats().Templates)
+
+	replaced, err = syncer.SyncOnce(context.Background())
+	require.NoError(t, err)
+	assert.False(t, replaced) // unchanged revision
+
+	// Invalid templates leave the active set in place
+	writeTestTemplateFile(t, dir, "sms/bad.json", `{"id": "bad", "message": "{{x}}", "schema": {"x": {"type": "money"}}}`)
+	_, err = syncer.SyncOnce(context.Background())
+	require.Error(t, err)
+	template, err := stores.SMS.GetTemplate("otp")
+	require.NoError(t, err)
+	assert.Equal(t, "Your code is {{code}}", template.Message)
+
+	stats := syncer.Stats()
+	assert.Equal(t, int64(3), stats.Syncs)
+	assert.Equal(t, int64(1), stats.Failures)
+	assert.NotEmpty(t, stats.LastError)
+}
+
+func TestGitTemplateSource_Load(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	remote := t.TempDir()
+	runTestGit(t, remote, "init", "--quiet", "--initial-branch", "main")
+	writeTestTemplateFile(t, remote, "templates/sms/otp.json", `{"id": "otp", "message": "Your code is {{code}}"}`)
+	runTestGit(t, remote, "add", ".")
+	runTestGit(t, remote, "commit", "--quiet", "-m", "Add OTP template")
+
+	cfg := config.TemplateConfig{Dir: filepath.Join(t.TempDir(), "checkout"), GitURL: "file://" + remote, GitBranch: "main", GitPath: "templates"}
+	source, err := NewTemplateSource(cfg)
+	require.NoError(t, err)
+
+	bundle, revision, err := source.Load(context.Background())
+	require.NoError(t, err)
+	require.Len(t, bundle.SMS, 1)
+	assert.Equal(t, runTestGit(t, remote, "rev-parse", "HEAD"), revision)
+
+	writeTestTemplateFile(t, remote, "templates/sms/otp.json", `{"id": "otp", "message": "Code: {{code}}"}`)
+	runTestGit(t, remote, "commit", "--quiet", "-am", "Shorten OTP template")
+
+	bundle, updated, err := source.Load(context.Background())
+	require.NoError(t, err)
+	assert.NotEqual(t, revision, updated)
+	assert.Equal(t, "Code: {{code}}", bundle.SMS[0].Message)
+}
+
+// Helper functions
+
+func writeTestTemplateFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func runTestGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	args = append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com"}, args...)
+	output, err := runGit(context.Background(), dir, args...)
+	require.NoError(t, err)
+	return output
+}
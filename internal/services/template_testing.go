@@ -0,0 +1,68 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/templatelint"
+	"github.com/nareshkumar-microsoft/notificationService/internal/templateschema"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// testRecipients is the set of verified addresses test sends may go to
+type testRecipients struct {
+	mu       sync.RWMutex
+	verified map[string]bool
+}
+
+// set replaces the verified addresses
+func (t *testRecipients) set(recipients []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.verified = make(map[string]bool, len(recipients))
+	for _, recipient := range recipients {
+		t.verified[normalizeTestRecipient(recipient)] = true
+	}
+}
+
+// check returns an error unless recipient is verified
+func (t *testRecipients) check(recipient string) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if !t.verified[normalizeTestRecipient(recipient)] {
+		return errors.NewValidationError("recipient", "test sends may only go to verified test addresses")
+	}
+	return nil
+}
+
+// normalizeTestRecipient normalizes an address for matching
+func normalizeTestRecipient(recipient string) string {
+	return strings.ToLower(strings.TrimSpace(recipient))
+}
+
+// lintError returns a validation error describing a report's errors, or nil
+// when the template is valid
+func lintError(templateID string, report templatelint.Report) error {
+	errs := report.Errors()
+	if len(errs) == 0 {
+		return nil
+	}
+
+	messages := make([]string, len(errs))
+	for i, issue := range errs {
+		messages[i] = fmt.Sprintf("%s: %s", issue.Field, issue.Message)
+	}
+	return errors.NewValidationError("template", fmt.Sprintf("template %s failed validation: %s", templateID, strings.Join(messages, "; ")))
+}
+
+// declaredVariables returns the variables a template declares: its variable
+// list, or the variables of its schema
+func declaredVariables(variables []string, schema templateschema.Schema) []string {
+	if len(variables) > 0 || len(schema) == 0 {
+		return variables
+	}
+	return schema.Names()
+}
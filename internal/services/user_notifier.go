@@ -0,0 +1,363 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/events"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// MetadataUserID is the metadata key set to the user a NotifyUser send was for
+const MetadataUserID = "user_id"
+
+// DefaultEscalationOrder is the channel order used when a policy sets none
+var DefaultEscalationOrder = []models.NotificationType{
+	models.NotificationTypePush,
+	models.NotificationTypeEmail,
+	models.NotificationTypeSMS,
+}
+
+// UserContacts are the email address and phone number a user can be
+// reached at. Push devices come from the device registry.
+type UserContacts struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email,omitempty"`
+	Phone  string `json:"phone,omitempty"`
+}
+
+// ContactResolver resolves a user's contact points
+type ContactResolver interface {
+	ResolveContacts(ctx context.Context, userID string) (*UserContacts, error)
+}
+
+// ContactDirectory is an in-memory ContactResolver. It is safe for
+// concurrent use.
+type ContactDirectory struct {
+	mu       sync.RWMutex
+	contacts map[string]UserContacts
+}
+
+// NewContactDirectory creates an empty contact directory
+func NewContactDirectory() *ContactDirectory {
+	return &ContactDirectory{contacts: make(map[string]UserContacts)}
+}
+
+// Set sets a user's contact points, replacing any set before
+func (d *ContactDirectory) Set(contacts UserContacts) error {
+	if contacts.UserID == "" {
+		return errors.NewValidationError("user_id", "user ID is required")
+	}
+	if contacts.Email != "" {
+		if err := utils.ValidateEmailAddress(contacts.Email); err != nil {
+			return err
+		}
+	}
+	if contacts.Phone != "" {
+		if err := utils.ValidatePhoneNumber(contacts.Phone, ""); err != nil {
+			return err
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.contacts[contacts.UserID] = contacts
+	return nil
+}
+
+// Remove removes a user's contact points
+func (d *ContactDirectory) Remove(userID string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	_, exists := d.contacts[userID]
+	delete(d.contacts, userID)
+	return exists
+}
+
+// ResolveContacts implements ContactResolver
+func (d *ContactDirectory) ResolveContacts(_ context.Context, userID string) (*UserContacts, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	contacts, exists := d.contacts[userID]
+	if !exists {
+		return nil, errors.NewNotificationError(errors.ErrorCodeNotFound, fmt.Sprintf("no contact points for user %s", userID))
+	}
+	return &contacts, nil
+}
+
+// UserMessage is the message NotifyUser sends on whichever channel it tries.
+// Push uses the subject as its title.
+type UserMessage struct {
+	Subject      string            `json:"subject,omitempty"`
+	Body         string            `json:"body"`
+	Priority     models.Priority   `json:"priority,omitempty"` // normal when empty
+	Category     models.Category   `json:"category,omitempty"`
+	TemplateData map[string]string `json:"template_data,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+}
+
+// EscalationPolicy sets the order NotifyUser tries channels in and when it
+// moves on to the next one
+type EscalationPolicy struct {
+	// Channels to try in order: push, email or sms. Empty uses DefaultEscalationOrder.
+	Channels []models.NotificationType `json:"channels,omitempty"`
+	// AckTimeout is how long to wait for a sent notification to be
+	// acknowledged before escalating. Zero escalates only when sending fails.
+	AckTimeout time.Duration `json:"ack_timeout,omitempty"`
+}
+
+// EscalationAttempt is one send NotifyUser made, or a channel it could not
+// use. A push attempt is made per active device.
+type EscalationAttempt struct {
+	Channel   models.NotificationType      `json:"channel"`
+	Recipient string                       `json:"recipient,omitempty"`
+	Response  *models.NotificationResponse `json:"response,omitempty"`
+	Error     string                       `json:"error,omitempty"`
+}
+
+// UserNotifyResult reports how NotifyUser reached a user
+type UserNotifyResult struct {
+	UserID   string              `json:"user_id"`
+	Attempts []EscalationAttempt `json:"attempts"`
+	// Channel is the last channel a notification was sent on, empty when none was
+	Channel      models.NotificationType `json:"channel,omitempty"`
+	Acknowledged bool                    `json:"acknowledged"`
+}
+
+// UserNotifier sends a message to a user rather than to an address,
+// escalating through the user's channels until one succeeds or, with an
+// acknowledgement timeout, until the user acknowledges a notification
+type UserNotifier struct {
+	mu      sync.Mutex
+	waiters map[string]chan string // notification ID to the NotifyUser call awaiting its acknowledgement
+
+	dispatcher interfaces.NotificationService
+	contacts   ContactResolver
+	devices    *DeviceRegistry
+	logger     interfaces.Logger
+}
+
+// NewUserNotifier creates a user notifier. Devices may be nil, in which case
+// the user has no push contact points.
+func NewUserNotifier(dispatcher interfaces.NotificationService, contacts ContactResolver, devices *DeviceRegistry, logger interfaces.Logger) *UserNotifier {
+	return &UserNotifier{
+		waiters:    make(map[string]chan string),
+		dispatcher: dispatcher,
+		contacts:   contacts,
+		devices:    devices,
+		logger:     logger,
+	}
+}
+
+// NotifyUser sends a message to a user, trying the policy's channels in
+// order. Channels the user has no contact point for are skipped. It moves on
+// from a channel when every send on it fails or, with an acknowledgement
+// timeout, when no notification sent on it is acknowledged in time. An
+// acknowledgement of a notification sent on an earlier channel also stops
+// the escalation. It fails when no notification could be sent.
+func (n *UserNotifier) NotifyUser(ctx context.Context, userID string, message *UserMessage, policy EscalationPolicy) (*UserNotifyResult, error) {
+	if userID == "" {
+		return nil, errors.NewValidationError("user_id", "user ID is required")
+	}
+	if message == nil {
+		return nil, errors.NewValidationError("message", "message is required")
+	}
+	channels := policy.Channels
+	if len(channels) == 0 {
+		channels = DefaultEscalationOrder
+	}
+	if err := validateUserChannels(channels); err != nil {
+		return nil, err
+	}
+	if policy.AckTimeout < 0 {
+		return nil, errors.NewValidationError("ack_timeout", "acknowledgement timeout cannot be negative")
+	}
+
+	contacts, err := n.contacts.ResolveContacts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	acks := make(chan string, 1)
+	var sentIDs []string
+	defer func() { n.release(sentIDs) }()
+
+	result := &UserNotifyResult{UserID: userID}
+	for _, channel := range channels {
+		requests := n.requests(userID, contacts, channel, message)
+		if len(requests) == 0 {
+			result.Attempts = append(result.Attempts, EscalationAttempt{
+				Channel: channel,
+				Error:   fmt.Sprintf("user has no %s contact point", channel),
+			})
+			continue
+		}
+
+		sent := false
+		for _, request := range requests {
+			attempt := EscalationAttempt{Channel: channel, Recipient: request.Recipient}
+			response, err := n.dispatcher.SendNotification(ctx, request)
+			if err != nil {
+				attempt.Error = err.Error()
+			} else {
+				attempt.Response = response
+				sentIDs = append(sentIDs, response.ID.String())
+				n.await(response.ID.String(), acks)
+				sent = true
+			}
+			result.Attempts = append(result.Attempts, attempt)
+		}
+		if !sent {
+			n.logger.Warnf("Could not notify user %s by %s, escalating", userID, channel)
+			continue
+		}
+
+		result.Channel = channel
+		if policy.AckTimeout == 0 {
+			return result, nil
+		}
+		acknowledged, err := n.awaitAck(ctx, acks, policy.AckTimeout)
+		if err != nil {
+			return result, err
+		}
+		if acknowledged {
+			result.Acknowledged = true
+			return result, nil
+		}
+		n.logger.Infof("User %s did not acknowledge the %s notification within %s, escalating", userID, channel, policy.AckTimeout)
+	}
+
+	if result.Channel == "" {
+		return result, errors.NewNotificationError(errors.ErrorCodeDeliveryFailed, fmt.Sprintf("could not notify user %s on any channel", userID))
+	}
+	return result, nil
+}
+
+// Acknowledge records that a user acknowledged a notification sent by
+// NotifyUser, for example by opening a push or replying to an SMS, stopping
+// its escalation. It reports whether an escalation was waiting on it.
+func (n *UserNotifier) Acknowledge(notificationID string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	acks, exists := n.waiters[notificationID]
+	if !exists {
+		return false
+	}
+	select {
+	case acks <- notificationID:
+	default: // already acknowledged
+	}
+	return true
+}
+
+// validateUserChannels checks channels can reach a user
+func validateUserChannels(channels []models.NotificationType) error {
+	for _, channel := range channels {
+		if channel != models.NotificationTypePush && channel != models.NotificationTypeEmail && channel != models.NotificationTypeSMS {
+			return errors.NewValidationError("channels", fmt.Sprintf("channel %s cannot reach a user; use push, email or sms", channel))
+		}
+	}
+	return nil
+}
+
+// HandleEvent implements events.Subscriber, acknowledging notifications as
+// the dispatcher records their acknowledgement. Subscribe it for
+// EventNotificationAcknowledged.
+func (n *UserNotifier) HandleEvent(ctx context.Context, event events.Event) error {
+	if event.Type == events.EventNotificationAcknowledged {
+		n.Acknowledge(event.NotificationID.String())
+	}
+	return nil
+}
+
+// requests returns the requests sending a message to a user on a channel,
+// one per address
+func (n *UserNotifier) requests(userID string, contacts *UserContacts, channel models.NotificationType, message *UserMessage) []*models.NotificationRequest {
+	request := func(recipient string) *models.NotificationRequest {
+		priority := message.Priority
+		if priority == "" {
+			priority = models.PriorityNormal
+		}
+		metadata := make(map[string]string, len(message.Metadata)+1)
+		for key, value := range message.Metadata {
+			metadata[key] = value
+		}
+		metadata[MetadataUserID] = userID
+
+		return &models.NotificationRequest{
+			Type:         channel,
+			Priority:     priority,
+			Recipient:    recipient,
+			Subject:      message.Subject,
+			Body:         message.Body,
+			Category:     message.Category,
+			Metadata:     metadata,
+			TemplateData: message.TemplateData,
+		}
+	}
+
+	switch channel {
+	case models.NotificationTypeEmail:
+		if contacts.Email != "" {
+			return []*models.NotificationRequest{request(contacts.Email)}
+		}
+	case models.NotificationTypeSMS:
+		if contacts.Phone != "" {
+			return []*models.NotificationRequest{request(contacts.Phone)}
+		}
+	case models.NotificationTypePush:
+		if n.devices == nil {
+			return nil
+		}
+		var requests []*models.NotificationRequest
+		for _, device := range n.devices.GetActiveDevices(userID) {
+			push := request(device.Token)
+			push.PushData = &models.PushData{Platform: device.Platform}
+			requests = append(requests, push)
+		}
+		return requests
+	}
+	return nil
+}
+
+// await registers a sent notification to await its acknowledgement
+func (n *UserNotifier) await(notificationID string, acks chan string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.waiters[notificationID] = acks
+}
+
+// awaitAck waits for an acknowledgement until the timeout
+func (n *UserNotifier) awaitAck(ctx context.Context, acks chan string, timeout time.Duration) (bool, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-acks:
+		return true, nil
+	case <-timer.C:
+		return false, nil
+	case <-ctx.Done():
+		return false, errors.NewNotificationError(errors.ErrorCodeTimeout, "stopped waiting for the user to acknowledge: "+ctx.Err().Error())
+	}
+}
+
+// release stops awaiting acknowledgements of notifications
+func (n *UserNotifier) release(notificationIDs []string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, id := range notificationIDs {
+		delete(n.waiters, id)
+	}
+}
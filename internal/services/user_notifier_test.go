@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/events"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/repository"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+func TestContactDirectory(t *testing.T) {
+	directory := NewContactDirectory()
+	require.NoError(t, directory.Set(UserContacts{UserID: "user-1", Email: "user@example.com", Phone: "+14155550100"}))
+	assert.Error(t, directory.Set(UserContacts{Email: "user@example.com"}))
+	assert.Error(t, directory.Set(UserContacts{UserID: "user-2", Email: "not-an-email"}))
+
+	contacts, err := directory.ResolveContacts(context.Background(), "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, "+14155550100", contacts.Phone)
+
+	assert.True(t, directory.Remove("user-1"))
+	_, err = directory.ResolveContacts(context.Background(), "user-1")
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeNotFound, notifErr.Code)
+}
+
+func TestUserNotifier_NotifyUser(t *testing.T) {
+	notifier, _, devices := createTestUserNotifier(t, createTestDispatcher(t))
+	message := &UserMessage{Subject: "Build failed", Body: "main is red"}
+
+	// Without devices, push is skipped for email
+	result, err := notifier.NotifyUser(context.Background(), "user-1", message, EscalationPolicy{})
+	require.NoError(t, err)
+	assert.Equal(t, models.NotificationTypeEmail, result.Channel)
+	require.Len(t, result.Attempts, 2)
+	assert.NotEmpty(t, result.Attempts[0].Error)
+	assert.Equal(t, "user@example.com", result.Attempts[1].Recipient)
+
+	_, err = devices.Register(&Device{UserID: "user-1", Token: testIOSToken, Platform: "ios"})
+	require.NoError(t, err)
+
+	result, err = notifier.NotifyUser(context.Background(), "user-1", message, EscalationPolicy{})
+	require.NoError(t, err)
+	assert.Equal(t, models.NotificationTypePush, result.Channel)
+	require.Len(t, result.Attempts, 1)
+	assert.Equal(t, models.StatusSent, result.Attempts[0].Response.Status)
+}
+
+func TestUserNotifier_EscalatesOnFailure(t *testing.T) {
+	cfg := createTestProvidersConfig()
+	cfg.SMS.Enabled = false
+	dispatcher, err := NewDispatcherFromConfig(cfg, repository.NewMemoryRepository(), utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	notifier, _, _ := createTestUserNotifier(t, dispatcher)
+
+	policy := EscalationPolicy{Channels: []models.NotificationType{models.NotificationTypeSMS, models.NotificationTypeEmail}}
+	result, err := notifier.NotifyUser(context.Background(), "user-1", &UserMessage{Subject: "Shipped", Body: "Your order shipped"}, policy)
+	require.NoError(t, err)
+	assert.Equal(t, models.NotificationTypeEmail, result.Channel)
+	require.Len(t, result.Attempts, 2)
+	assert.Nil(t, result.Attempts[0].Response)
+
+	// Failing every channel fails the call
+	policy.Channels = policy.Channels[:1]
+	result, err = notifier.NotifyUser(context.Background(), "user-1", &UserMessage{Subject: "Shipped", Body: "Your order shipped"}, policy)
+	require.Error(t, err)
+	assert.Empty(t, result.Channel)
+}
+
+func TestUserNotifier_EscalatesWithoutAcknowledgement(t *testing.T) {
+	recorder := &sendRecorder{NotificationService: createTestDispatcher(t), sent: make(chan *models.NotificationResponse, 10)}
+	notifier, _, _ := createTestUserNotifier(t, recorder)
+	policy := EscalationPolicy{
+		Channels:   []models.NotificationType{models.NotificationTypeEmail, models.NotificationTypeSMS},
+		AckTimeout: 20 * time.Millisecond,
+	}
+
+	result, err := notifier.NotifyUser(context.Background(), "user-1", &UserMessage{Subject: "Pager", Body: "Disk full"}, policy)
+	require.NoError(t, err)
+	assert.Equal(t, models.NotificationTypeSMS, result.Channel)
+	assert.False(t, result.Acknowledged)
+	assert.Len(t, result.Attempts, 2)
+	assert.False(t, notifier.Acknowledge(result.Attempts[0].Response.ID.String())) // no longer awaited
+
+	// Acknowledging the email stops the escalation
+	for len(recorder.sent) > 0 {
+		<-recorder.sent
+	}
+	policy.AckTimeout = time.Minute
+	go func() {
+		email := <-recorder.sent
+		for !notifier.Acknowledge(email.ID.String()) {
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	result, err = notifier.NotifyUser(context.Background(), "user-1", &UserMessage{Subject: "Pager", Body: "Disk full"}, policy)
+	require.NoError(t, err)
+	assert.Equal(t, models.NotificationTypeEmail, result.Channel)
+	assert.True(t, result.Acknowledged)
+	assert.Len(t, result.Attempts, 1)
+}
+
+func TestUserNotifier_AcknowledgedThroughDispatcher(t *testing.T) {
+	dispatcher := createTestDispatcher(t)
+	bus := events.NewBus(utils.NewSimpleLogger("info"))
+	dispatcher.SetEventPublisher(bus)
+	recorder := &sendRecorder{NotificationService: dispatcher, sent: make(chan *models.NotificationResponse, 10)}
+	notifier, _, _ := createTestUserNotifier(t, recorder)
+	bus.Subscribe(notifier, events.EventNotificationAcknowledged)
+
+	go func() {
+		email := <-recorder.sent
+		for !awaiting(notifier, email.ID.String()) {
+			time.Sleep(time.Millisecond)
+		}
+		_, _ = dispatcher.AcknowledgeNotification(context.Background(), email.ID.String(), &models.Acknowledgement{Source: models.AcknowledgeSourceInApp})
+	}()
+
+	policy := EscalationPolicy{
+		Channels:   []models.NotificationType{models.NotificationTypeEmail, models.NotificationTypeSMS},
+		AckTimeout: time.Minute,
+	}
+	result, err := notifier.NotifyUser(context.Background(), "user-1", &UserMessage{Subject: "Pager", Body: "Disk full"}, policy)
+	require.NoError(t, err)
+	assert.True(t, result.Acknowledged)
+	assert.Equal(t, models.NotificationTypeEmail, result.Channel)
+}
+
+func TestUserNotifier_NotifyUser_Errors(t *testing.T) {
+	notifier, _, _ := createTestUserNotifier(t, createTestDispatcher(t))
+	message := &UserMessage{Body: "Hello"}
+
+	_, err := notifier.NotifyUser(context.Background(), "", message, EscalationPolicy{})
+	assert.Error(t, err)
+	_, err = notifier.NotifyUser(context.Background(), "user-1", nil, EscalationPolicy{})
+	assert.Error(t, err)
+	_, err = notifier.NotifyUser(context.Background(), "user-1", message, EscalationPolicy{Channels: []models.NotificationType{models.NotificationTypeChat}})
+	assert.Error(t, err)
+	_, err = notifier.NotifyUser(context.Background(), "user-1", message, EscalationPolicy{AckTimeout: -time.Second})
+	assert.Error(t, err)
+
+	_, err = notifier.NotifyUser(context.Background(), "unknown", message, EscalationPolicy{})
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeNotFound, notifErr.Code)
+}
+
+// Helper functions
+
+func createTestUserNotifier(t *testing.T, dispatcher interfaces.NotificationService) (*UserNotifier, *ContactDirectory, *DeviceRegistry) {
+	contacts := NewContactDirectory()
+	require.NoError(t, contacts.Set(UserContacts{UserID: "user-1", Email: "user@example.com", Phone: "+14155550100"}))
+	devices := NewDeviceRegistry()
+	return NewUserNotifier(dispatcher, contacts, devices, utils.NewSimpleLogger("info")), contacts, devices
+}
+
+// awaiting reports whether an escalation is waiting on a notification
+func awaiting(notifier *UserNotifier, notificationID string) bool {
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+
+	_, exists := notifier.waiters[notificationID]
+	return exists
+}
+
+// sendRecorder passes sends to a notification service and records their responses
+type sendRecorder struct {
+	interfaces.NotificationService
+	sent chan *models.NotificationResponse
+}
+
+func (r *sendRecorder) SendNotification(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+	response, err := r.NotificationService.SendNotification(ctx, request)
+	if err == nil {
+		r.sent <- response
+	}
+	return response, err
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/privacy"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/internal/validation"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// VoiceService provides text-to-speech voice call functionality for urgent
+// alerts where SMS is insufficient
+type VoiceService struct {
+	provider interfaces.VoiceProvider
+	config   config.VoiceProviderConfig
+	logger   interfaces.Logger
+}
+
+// NewVoiceService creates a new voice call service
+func NewVoiceService(cfg config.VoiceProviderConfig, logger interfaces.Logger) (*VoiceService, error) {
+	provider, err := providers.NewVoiceProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	service := &VoiceService{
+		provider: provider,
+		config:   cfg,
+		logger:   logger,
+	}
+
+	return service, nil
+}
+
+// SendVoice places a voice call that reads a message to the recipient
+func (s *VoiceService) SendVoice(ctx context.Context, request *VoiceRequest) (*models.NotificationResponse, error) {
+	// Validate request first
+	if err := s.validateVoiceRequest(request); err != nil {
+		s.logger.Errorf("Voice call validation failed: %v", err)
+		return nil, err
+	}
+
+	s.logger.Infof("Placing voice call to %s (%s)", privacy.MaskPhone(request.PhoneNumber), request.CountryCode)
+
+	// Create voice notification
+	voiceNotification := s.createVoiceNotification(request)
+
+	// Apply template if specified
+	if request.TemplateID != "" {
+		if err := s.applyTemplate(voiceNotification, request.TemplateID, request.TemplateData); err != nil {
+			s.logger.Errorf("Template application failed: %v", err)
+			return nil, err
+		}
+	}
+
+	// Place call
+	response, err := s.provider.SendVoice(ctx, voiceNotification)
+	if err != nil {
+		s.logger.Errorf("Voice call failed: %v", err)
+		return nil, err
+	}
+
+	s.logger.Infof("Voice call placed successfully with ID: %s", response.ID)
+	return response, nil
+}
+
+// EstimateCost estimates the cost of reading a message on a call to a country.
+// Calls are billed per started minute.
+func (s *VoiceService) EstimateCost(message string, countryCode string, loop int) (*VoiceCostEstimate, error) {
+	costPerMinute, err := s.provider.GetVoiceCost(countryCode)
+	if err != nil {
+		return nil, err
+	}
+
+	twilioProvider, ok := s.provider.(*providers.TwilioVoiceProvider)
+	if !ok {
+		return nil, errors.NewNotificationError(
+			errors.ErrorCodeProviderNotFound,
+			"cost estimation not supported by this provider",
+		)
+	}
+
+	duration := twilioProvider.EstimateDuration(message, loop)
+	billedMinutes := int(math.Ceil(duration.Minutes()))
+
+	return &VoiceCostEstimate{
+		EstimatedSeconds: int(duration.Seconds()),
+		BilledMinutes:    billedMinutes,
+		CostPerMinute:    costPerMinute,
+		TotalCost:        costPerMinute * float64(billedMinutes),
+		CountryCode:      countryCode,
+		Allowed:          twilioProvider.IsCountryAllowed(countryCode),
+	}, nil
+}
+
+// RenderTemplate renders a voice template with data
+func (s *VoiceService) RenderTemplate(templateID string, data map[string]string) (*providers.VoiceTemplate, error) {
+	twilioProvider, ok := s.provider.(*providers.TwilioVoiceProvider)
+	if !ok {
+		return nil, errors.NewNotificationError(
+			errors.ErrorCodeProviderNotFound,
+			"template rendering not supported by this provider",
+		)
+	}
+
+	return twilioProvider.RenderTemplate(templateID, data)
+}
+
+// ValidatePhoneNumber validates a phone number and its country
+func (s *VoiceService) ValidatePhoneNumber(phoneNumber, countryCode string) error {
+	return s.provider.ValidatePhoneNumber(phoneNumber, countryCode)
+}
+
+// GetProviderStatus returns the current provider status
+func (s *VoiceService) GetProviderStatus(ctx context.Context) *ProviderStatus {
+	status := &ProviderStatus{
+		Name:    s.provider.GetConfig().Name,
+		Type:    string(s.provider.GetType()),
+		Healthy: true,
+	}
+
+	if err := s.provider.IsHealthy(ctx); err != nil {
+		status.Healthy = false
+		status.Error = err.Error()
+	}
+
+	return status
+}
+
+// validateVoiceRequest validates a voice request
+func (s *VoiceService) validateVoiceRequest(request *VoiceRequest) error {
+	if request == nil {
+		return errors.NewValidationError("request", "voice request is required")
+	}
+
+	if err := validation.Struct(request); err != nil {
+		return err
+	}
+
+	if err := s.provider.ValidatePhoneNumber(request.PhoneNumber, request.CountryCode); err != nil {
+		return err
+	}
+
+	if request.Message == "" && request.TemplateID == "" {
+		return errors.NewValidationError("message", "voice message is required when not using a template")
+	}
+
+	// Calls interrupt the recipient, so they are reserved for urgent alerts
+	if s.config.Settings["allow_all_priorities"] != "true" &&
+		request.Priority != models.PriorityHigh && request.Priority != models.PriorityUrgent {
+		return errors.NewValidationError("priority", "voice calls are reserved for high and urgent priority notifications")
+	}
+
+	return nil
+}
+
+// createVoiceNotification creates a voice notification from a request
+func (s *VoiceService) createVoiceNotification(request *VoiceRequest) *models.VoiceNotification {
+	now := time.Now()
+
+	return &models.VoiceNotification{
+		Notification: models.Notification{
+			ID:         uuid.New(),
+			Type:       models.NotificationTypeVoice,
+			Status:     models.StatusPending,
+			Priority:   request.Priority,
+			Recipient:  request.PhoneNumber,
+			Body:       request.Message,
+			Metadata:   request.Metadata,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+			RetryCount: 0,
+			MaxRetries: 2,
+		},
+		PhoneNumber: request.PhoneNumber,
+		CountryCode: request.CountryCode,
+		Message:     request.Message,
+		Voice:       request.Voice,
+		Language:    request.Language,
+		Loop:        request.Loop,
+	}
+}
+
+// applyTemplate applies a template to a voice notification
+func (s *VoiceService) applyTemplate(voice *models.VoiceNotification, templateID string, data map[string]string) error {
+	template, err := s.RenderTemplate(templateID, data)
+	if err != nil {
+		return err
+	}
+
+	// Apply template content
+	voice.Message = template.Message
+	voice.Body = template.Message
+
+	if voice.Voice == "" {
+		voice.Voice = template.Voice
+	}
+	if voice.Language == "" {
+		voice.Language = template.Language
+	}
+	if voice.Loop == 0 {
+		voice.Loop = template.Loop
+	}
+
+	return nil
+}
+
+// VoiceRequest represents a request to place a text-to-speech voice call
+type VoiceRequest struct {
+	PhoneNumber  string            `json:"phone_number" validate:"required"`
+	CountryCode  string            `json:"country_code" validate:"required"`
+	Message      string            `json:"message,omitempty"`
+	Voice        string            `json:"voice,omitempty"`
+	Language     string            `json:"language,omitempty"`
+	Loop         int               `json:"loop,omitempty"`
+	TemplateID   string            `json:"template_id,omitempty"`
+	TemplateData map[string]string `json:"template_data,omitempty"`
+	Priority     models.Priority   `json:"priority"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+}
+
+// VoiceCostEstimate represents the estimated cost of a voice call
+type VoiceCostEstimate struct {
+	EstimatedSeconds int     `json:"estimated_seconds"`
+	BilledMinutes    int     `json:"billed_minutes"`
+	CostPerMinute    float64 `json:"cost_per_minute"`
+	TotalCost        float64 `json:"total_cost"`
+	CountryCode      string  `json:"country_code"`
+	Allowed          bool    `json:"allowed"`
+}
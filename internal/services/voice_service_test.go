@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestNewVoiceService_UnsupportedProvider(t *testing.T) {
+	service, err := NewVoiceService(config.VoiceProviderConfig{Provider: "vonage"}, utils.NewSimpleLogger("info"))
+	assert.Error(t, err)
+	assert.Nil(t, service)
+
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeProviderNotFound, notifErr.Code)
+}
+
+func TestVoiceService_SendVoice_WithTemplate(t *testing.T) {
+	var twiml string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		twiml = r.PostForm.Get("Twiml")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"sid": "CA1", "status": "queued"})
+	}))
+	defer server.Close()
+
+	service := createTestVoiceService(server.URL)
+
+	response, err := service.SendVoice(context.Background(), &VoiceRequest{
+		PhoneNumber: "5551234567",
+		CountryCode: "US",
+		TemplateID:  "urgent_alert",
+		TemplateData: map[string]string{
+			"service_name":  "Checkout",
+			"alert_message": "Payments are failing",
+		},
+		Priority: models.PriorityUrgent,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "CA1", response.ProviderID)
+	assert.Contains(t, twiml, `loop="2"`)
+	assert.Contains(t, twiml, "urgent alert from Checkout. Payments are failing.")
+}
+
+func TestVoiceService_SendVoice_ValidationErrors(t *testing.T) {
+	service := createTestVoiceService("")
+
+	tests := []struct {
+		name    string
+		request *VoiceRequest
+	}{
+		{"nil request", nil},
+		{"country not allowed", &VoiceRequest{PhoneNumber: "0301234567", CountryCode: "DE", Message: "x", Priority: models.PriorityUrgent}},
+		{"missing message", &VoiceRequest{PhoneNumber: "5551234567", CountryCode: "US", Priority: models.PriorityUrgent}},
+		{"low priority", &VoiceRequest{PhoneNumber: "5551234567", CountryCode: "US", Message: "x", Priority: models.PriorityNormal}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.SendVoice(context.Background(), tt.request)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestVoiceService_EstimateCost(t *testing.T) {
+	service := createTestVoiceService("")
+
+	estimate, err := service.EstimateCost("Your verification code is one two three four", "UK", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, estimate.BilledMinutes)
+	assert.Equal(t, 0.0200, estimate.TotalCost)
+	assert.True(t, estimate.Allowed)
+
+	estimate, err = service.EstimateCost("Short", "DE", 1)
+	require.NoError(t, err)
+	assert.False(t, estimate.Allowed)
+
+	_, err = service.EstimateCost("Short", "ZZ", 1)
+	require.Error(t, err)
+}
+
+// Helper functions
+
+func createTestVoiceService(baseURL string) *VoiceService {
+	cfg := config.VoiceProviderConfig{
+		Provider:         "twilio",
+		Enabled:          true,
+		AllowedCountries: []string{"US", "CA", "UK"},
+		TwilioAccountSID: "AC123",
+		TwilioAuthToken:  "secret",
+		TwilioFromNumber: "+15550000000",
+		TwilioBaseURL:    baseURL,
+	}
+
+	service, err := NewVoiceService(cfg, utils.NewSimpleLogger("info"))
+	if err != nil {
+		panic(err)
+	}
+
+	return service
+}
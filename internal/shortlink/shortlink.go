@@ -0,0 +1,252 @@
+// Package shortlink rewrites long URLs in SMS bodies to short tracked links,
+// which saves segments and counts clicks per notification. The built-in
+// Service keeps links in memory; other shorteners plug in through Shortener.
+package shortlink
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pipeline"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// MiddlewareName is the name the shortening middleware is registered under
+const MiddlewareName = "shortlink"
+
+// codeLength is the length of generated link codes; 62^7 codes make
+// collisions rare
+const codeLength = 7
+
+const codeAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// urlPattern matches http and https URLs in message text
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"']+`)
+
+// Link is a short link and its clicks
+type Link struct {
+	Code           string     `json:"code"`
+	URL            string     `json:"url"`       // the long URL
+	ShortURL       string     `json:"short_url"` // the URL sent in the message
+	NotificationID string     `json:"notification_id,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	Clicks         int64      `json:"clicks"`
+	LastClickedAt  *time.Time `json:"last_clicked_at,omitempty"`
+}
+
+// Shortener creates short links. Implement it to use an external service;
+// Attach may do nothing when the service cannot tie clicks to notifications.
+type Shortener interface {
+	// Shorten returns a link to a URL. A link without a code means the URL
+	// is kept as it is, for example because it is already short.
+	Shorten(ctx context.Context, longURL string) (Link, error)
+	// Attach ties links to the notification that sent them
+	Attach(ctx context.Context, notificationID string, codes ...string) error
+}
+
+// Service is the built-in shortener. Its links resolve under a base URL
+// such as "https://sms.example.com/s/", served by the API. It is safe for
+// concurrent use.
+type Service struct {
+	baseURL string
+
+	mu    sync.RWMutex
+	links map[string]*Link
+}
+
+// NewService creates a shortener whose links start with baseURL
+func NewService(baseURL string) (*Service, error) {
+	if !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
+		return nil, errors.NewValidationError("base_url", "base URL must be an http or https URL")
+	}
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+
+	return &Service{
+		baseURL: baseURL,
+		links:   make(map[string]*Link),
+	}, nil
+}
+
+// Shorten implements the Shortener interface. URLs no longer than a short
+// link are kept as they are.
+func (s *Service) Shorten(ctx context.Context, longURL string) (Link, error) {
+	if len(longURL) <= len(s.baseURL)+codeLength {
+		return Link{URL: longURL, ShortURL: longURL}, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for {
+		code, err := generateCode()
+		if err != nil {
+			return Link{}, errors.NewNotificationError(errors.ErrorCodeInternal, "failed to generate short link code").WithCause(err)
+		}
+		if _, exists := s.links[code]; exists {
+			continue
+		}
+
+		link := &Link{Code: code, URL: longURL, ShortURL: s.baseURL + code, CreatedAt: time.Now()}
+		s.links[code] = link
+		return *link, nil
+	}
+}
+
+// Attach implements the Shortener interface
+func (s *Service) Attach(ctx context.Context, notificationID string, codes ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, code := range codes {
+		if link, exists := s.links[code]; exists {
+			link.NotificationID = notificationID
+		}
+	}
+	return nil
+}
+
+// Resolve returns the link with a code
+func (s *Service) Resolve(code string) (Link, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	link, exists := s.links[code]
+	if !exists {
+		return Link{}, errors.NewNotificationError(errors.ErrorCodeNotFound, fmt.Sprintf("short link not found: %s", code))
+	}
+	return *link, nil
+}
+
+// Click records a click on the link with a code and returns the link
+func (s *Service) Click(code string) (Link, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link, exists := s.links[code]
+	if !exists {
+		return Link{}, errors.NewNotificationError(errors.ErrorCodeNotFound, fmt.Sprintf("short link not found: %s", code))
+	}
+
+	now := time.Now()
+	link.Clicks++
+	link.LastClickedAt = &now
+	return *link, nil
+}
+
+// Links returns the links sent in a notification, oldest first
+func (s *Service) Links(notificationID string) []Link {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	links := make([]Link, 0)
+	for _, link := range s.links {
+		if link.NotificationID == notificationID {
+			links = append(links, *link)
+		}
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].CreatedAt.Before(links[j].CreatedAt) })
+	return links
+}
+
+// Clicks returns the total clicks on the links sent in a notification
+func (s *Service) Clicks(notificationID string) int64 {
+	var clicks int64
+	for _, link := range s.Links(notificationID) {
+		clicks += link.Clicks
+	}
+	return clicks
+}
+
+// Rewrite replaces the URLs in a text with short links and returns the
+// codes of the links it created
+func Rewrite(ctx context.Context, shortener Shortener, text string) (string, []string, error) {
+	var codes []string
+	var rewriteErr error
+
+	rewritten := urlPattern.ReplaceAllStringFunc(text, func(match string) string {
+		if rewriteErr != nil {
+			return match
+		}
+
+		// Sentence punctuation after a URL is not part of it
+		longURL := strings.TrimRight(match, ".,;:!?)")
+		trailing := match[len(longURL):]
+
+		link, err := shortener.Shorten(ctx, longURL)
+		if err != nil {
+			rewriteErr = err
+			return match
+		}
+		if link.Code == "" {
+			return match
+		}
+
+		codes = append(codes, link.Code)
+		return link.ShortURL + trailing
+	})
+	if rewriteErr != nil {
+		return text, nil, rewriteErr
+	}
+
+	return rewritten, codes, nil
+}
+
+// Middleware returns send middleware shortening the URLs in SMS bodies and
+// tying the links to the sent notification. Register it at
+// pipeline.StageTemplate under MiddlewareName, after the template middleware,
+// so URLs from template data are shortened too.
+func Middleware(shortener Shortener, logger interfaces.Logger) pipeline.Middleware {
+	return func(next pipeline.Handler) pipeline.Handler {
+		return func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+			if request.Type != models.NotificationTypeSMS {
+				return next(ctx, request)
+			}
+
+			body, codes, err := Rewrite(ctx, shortener, request.Body)
+			if err != nil {
+				logger.Errorf("Failed to shorten SMS links: %v", err)
+				return nil, err
+			}
+			if len(codes) == 0 {
+				return next(ctx, request)
+			}
+
+			shortened := *request
+			shortened.Body = body
+			response, err := next(ctx, &shortened)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := shortener.Attach(ctx, response.ID.String(), codes...); err != nil {
+				logger.Warnf("Failed to attach short links to notification %s: %v", response.ID, err)
+			}
+			return response, nil
+		}
+	}
+}
+
+// generateCode returns a random link code
+func generateCode() (string, error) {
+	code := make([]byte, codeLength)
+	limit := big.NewInt(int64(len(codeAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", err
+		}
+		code[i] = codeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
@@ -0,0 +1,126 @@
+package shortlink
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+const testLongURL = "https://shop.example.com/orders/12345/tracking?utm_source=sms"
+
+func TestNewService(t *testing.T) {
+	service, err := NewService("https://sms.example.com/s")
+	require.NoError(t, err)
+	assert.Equal(t, "https://sms.example.com/s/", service.baseURL)
+
+	_, err = NewService("sms.example.com/s/")
+	assert.Error(t, err)
+}
+
+func TestService_ShortenAndClick(t *testing.T) {
+	service := createTestService(t)
+	ctx := context.Background()
+
+	link, err := service.Shorten(ctx, testLongURL)
+	require.NoError(t, err)
+	assert.Len(t, link.Code, codeLength)
+	assert.Equal(t, "https://sms.example.com/s/"+link.Code, link.ShortURL)
+	assert.Equal(t, testLongURL, link.URL)
+
+	// URLs no longer than a short link are kept
+	short, err := service.Shorten(ctx, "https://ex.co/a")
+	require.NoError(t, err)
+	assert.Empty(t, short.Code)
+	assert.Equal(t, "https://ex.co/a", short.ShortURL)
+
+	require.NoError(t, service.Attach(ctx, "notification-1", link.Code))
+	for i := 0; i < 2; i++ {
+		_, err = service.Click(link.Code)
+		require.NoError(t, err)
+	}
+
+	resolved, err := service.Resolve(link.Code)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), resolved.Clicks)
+	assert.NotNil(t, resolved.LastClickedAt)
+	assert.Equal(t, int64(2), service.Clicks("notification-1"))
+	assert.Len(t, service.Links("notification-1"), 1)
+	assert.Empty(t, service.Links("notification-2"))
+
+	_, err = service.Click("missing")
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeNotFound, notifErr.Code)
+}
+
+func TestRewrite(t *testing.T) {
+	service := createTestService(t)
+	ctx := context.Background()
+
+	text := fmt.Sprintf("Track it at %s. Help: https://ex.co/h or (%s)", testLongURL, testLongURL+"&x=1")
+	rewritten, codes, err := Rewrite(ctx, service, text)
+	require.NoError(t, err)
+	require.Len(t, codes, 2)
+	assert.Equal(t, fmt.Sprintf("Track it at https://sms.example.com/s/%s. Help: https://ex.co/h or (https://sms.example.com/s/%s)", codes[0], codes[1]), rewritten)
+
+	// Text without URLs is unchanged
+	rewritten, codes, err = Rewrite(ctx, service, "Your code is 1234")
+	require.NoError(t, err)
+	assert.Empty(t, codes)
+	assert.Equal(t, "Your code is 1234", rewritten)
+}
+
+func TestMiddleware(t *testing.T) {
+	service := createTestService(t)
+	ctx := context.Background()
+
+	notificationID := uuid.New()
+	var sent *models.NotificationRequest
+	fail := false
+	handler := Middleware(service, utils.NewSimpleLogger("error"))(func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+		sent = request
+		if fail {
+			return nil, errors.NewNotificationError(errors.ErrorCodeProviderUnavailable, "down")
+		}
+		return &models.NotificationResponse{ID: notificationID, Status: models.StatusSent}, nil
+	})
+
+	request := &models.NotificationRequest{Type: models.NotificationTypeSMS, Recipient: "+14155550123", Body: "Track: " + testLongURL}
+	_, err := handler(ctx, request)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(sent.Body, "Track: https://sms.example.com/s/"))
+	assert.Equal(t, "Track: "+testLongURL, request.Body)
+
+	links := service.Links(notificationID.String())
+	require.Len(t, links, 1)
+	assert.Equal(t, testLongURL, links[0].URL)
+
+	// Failed sends do not tie their links to a notification
+	fail = true
+	_, err = handler(ctx, request)
+	require.Error(t, err)
+	assert.Len(t, service.Links(notificationID.String()), 1)
+
+	// Other channels are not rewritten
+	fail = false
+	_, err = handler(ctx, &models.NotificationRequest{Type: models.NotificationTypeEmail, Recipient: "a@example.com", Body: testLongURL})
+	require.NoError(t, err)
+	assert.Equal(t, testLongURL, sent.Body)
+}
+
+// Helper functions
+
+func createTestService(t *testing.T) *Service {
+	service, err := NewService("https://sms.example.com/s/")
+	require.NoError(t, err)
+	return service
+}
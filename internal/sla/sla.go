@@ -0,0 +1,274 @@
+// Package sla tracks the success rate and latency of each provider over a
+// sliding window and marks providers breaching their service level
+// objectives as degraded. Failover consults the marks to try healthy
+// providers first, and health checks report them.
+package sla
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// Defaults applied to zero configuration fields
+const (
+	defaultWindow     = 5 * time.Minute
+	defaultMinSamples = 20
+)
+
+// buckets is the number of periods a provider's window is counted in
+const buckets = 10
+
+// maxRollups is the number of rollups a monitor keeps, a day of five-minute ones
+const maxRollups = 288
+
+// ProviderStats is a provider's record over the window
+type ProviderStats struct {
+	Channel        models.NotificationType `json:"channel"`
+	Provider       string                  `json:"provider"`
+	Sends          int64                   `json:"sends"`
+	Failures       int64                   `json:"failures"` // failures of the provider, not of the message
+	SuccessRate    float64                 `json:"success_rate"`
+	AverageLatency time.Duration           `json:"average_latency"`
+	MaxLatency     time.Duration           `json:"max_latency"`
+	Objectives     config.SLO              `json:"objectives"`
+	Degraded       bool                    `json:"degraded"`
+	DegradedSince  *time.Time              `json:"degraded_since,omitempty"`
+	Reason         string                  `json:"reason,omitempty"`
+}
+
+// Rollup is every provider's record over the window at a point in time,
+// kept after the window has moved on
+type Rollup struct {
+	At        time.Time       `json:"at"`
+	Providers []ProviderStats `json:"providers"`
+}
+
+// key identifies a provider of a channel
+type key struct {
+	channel  models.NotificationType
+	provider string
+}
+
+// count is a provider's record over one period
+type count struct {
+	start        time.Time
+	sends        int64
+	failures     int64
+	totalLatency time.Duration
+	maxLatency   time.Duration
+}
+
+// record is a provider's periods and degradation
+type record struct {
+	counts        []count // oldest first
+	degradedSince *time.Time
+	reason        string
+}
+
+// Monitor records provider sends and judges them against their objectives.
+// It is safe for concurrent use.
+type Monitor struct {
+	config config.SLAConfig
+	logger interfaces.Logger
+
+	mu      sync.Mutex
+	records map[key]*record
+	rollups []Rollup // oldest first
+	now     func() time.Time
+}
+
+// NewMonitor creates a monitor for the configured objectives
+func NewMonitor(cfg config.SLAConfig, logger interfaces.Logger) *Monitor {
+	if cfg.Window <= 0 {
+		cfg.Window = defaultWindow
+	}
+	if cfg.MinSamples <= 0 {
+		cfg.MinSamples = defaultMinSamples
+	}
+
+	return &Monitor{
+		config:  cfg,
+		logger:  logger,
+		records: make(map[key]*record),
+		now:     time.Now,
+	}
+}
+
+// RecordSend counts a send through a provider. Failures of the message
+// itself, such as an invalid phone number, count as successes of the
+// provider.
+func (m *Monitor) RecordSend(channel models.NotificationType, provider string, err error, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := key{channel, provider}
+	r, exists := m.records[k]
+	if !exists {
+		r = &record{}
+		m.records[k] = r
+	}
+
+	now := m.now()
+	m.prune(r, now)
+	period := m.config.Window / buckets
+	start := now.Truncate(period)
+	if n := len(r.counts); n == 0 || r.counts[n-1].start.Before(start) {
+		r.counts = append(r.counts, count{start: start})
+	}
+	c := &r.counts[len(r.counts)-1]
+	c.sends++
+	c.totalLatency += latency
+	if latency > c.maxLatency {
+		c.maxLatency = latency
+	}
+	if err != nil && providers.IsProviderFailure(err) {
+		c.failures++
+	}
+
+	m.judge(k, r, now)
+}
+
+// Degraded reports whether a provider currently breaches its objectives
+func (m *Monitor) Degraded(channel models.NotificationType, provider string) bool {
+	stats, _ := m.Provider(channel, provider)
+	return stats.Degraded
+}
+
+// Provider returns a provider's record over the window. It reports false
+// when the provider has never sent.
+func (m *Monitor) Provider(channel models.NotificationType, provider string) (ProviderStats, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := key{channel, provider}
+	r, exists := m.records[k]
+	if !exists {
+		return ProviderStats{}, false
+	}
+	return m.judge(k, r, m.now()), true
+}
+
+// Stats returns the record of every provider that has sent, sorted by
+// channel and provider
+func (m *Monitor) Stats() []ProviderStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	stats := make([]ProviderStats, 0, len(m.records))
+	for k, r := range m.records {
+		stats = append(stats, m.judge(k, r, now))
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Channel != stats[j].Channel {
+			return stats[i].Channel < stats[j].Channel
+		}
+		return stats[i].Provider < stats[j].Provider
+	})
+	return stats
+}
+
+// Rollup records every provider's current record as a rollup and returns
+// it. The monitor keeps the latest rollups, so scheduling Rollup once per
+// window keeps a history of the providers' service levels.
+func (m *Monitor) Rollup() Rollup {
+	rollup := Rollup{At: m.now(), Providers: m.Stats()}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.rollups = append(m.rollups, rollup)
+	if len(m.rollups) > maxRollups {
+		m.rollups = m.rollups[len(m.rollups)-maxRollups:]
+	}
+	return rollup
+}
+
+// Rollups returns the rollups kept, oldest first
+func (m *Monitor) Rollups() []Rollup {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Rollup(nil), m.rollups...)
+}
+
+// Objectives returns the objectives a provider is judged against
+func (m *Monitor) Objectives(provider string) config.SLO {
+	if objectives, exists := m.config.Providers[provider]; exists {
+		return objectives
+	}
+	return m.config.Objectives
+}
+
+// judge sums a provider's record over the window and updates its
+// degradation. A provider with fewer sends in the window than the minimum
+// is not judged, so a degraded provider that failover kept away from is
+// tried again once its failures leave the window. Callers hold the lock.
+func (m *Monitor) judge(k key, r *record, now time.Time) ProviderStats {
+	m.prune(r, now)
+
+	stats := ProviderStats{Channel: k.channel, Provider: k.provider, Objectives: m.Objectives(k.provider)}
+	var totalLatency time.Duration
+	for _, c := range r.counts {
+		stats.Sends += c.sends
+		stats.Failures += c.failures
+		totalLatency += c.totalLatency
+		if c.maxLatency > stats.MaxLatency {
+			stats.MaxLatency = c.maxLatency
+		}
+	}
+	if stats.Sends > 0 {
+		stats.SuccessRate = float64(stats.Sends-stats.Failures) / float64(stats.Sends)
+		stats.AverageLatency = totalLatency / time.Duration(stats.Sends)
+	}
+
+	var breaches []string
+	if stats.Sends >= int64(m.config.MinSamples) {
+		if stats.Objectives.MinSuccessRate > 0 && stats.SuccessRate < stats.Objectives.MinSuccessRate {
+			breaches = append(breaches, fmt.Sprintf("success rate %.1f%% is below %.1f%%",
+				stats.SuccessRate*100, stats.Objectives.MinSuccessRate*100))
+		}
+		if stats.Objectives.MaxAverageLatency > 0 && stats.AverageLatency > stats.Objectives.MaxAverageLatency {
+			breaches = append(breaches, fmt.Sprintf("average latency %v exceeds %v",
+				stats.AverageLatency.Round(time.Millisecond), stats.Objectives.MaxAverageLatency))
+		}
+	}
+
+	switch {
+	case len(breaches) > 0:
+		reason := strings.Join(breaches, "; ")
+		if r.degradedSince == nil {
+			since := now
+			r.degradedSince = &since
+			m.logger.Errorf("%s provider %s is degraded: %s", k.channel, k.provider, reason)
+		}
+		r.reason = reason
+	case r.degradedSince != nil:
+		m.logger.Infof("%s provider %s has recovered", k.channel, k.provider)
+		r.degradedSince, r.reason = nil, ""
+	}
+
+	if r.degradedSince != nil {
+		since := *r.degradedSince
+		stats.Degraded, stats.DegradedSince, stats.Reason = true, &since, r.reason
+	}
+	return stats
+}
+
+// prune drops a provider's periods that have left the window. Callers hold the lock.
+func (m *Monitor) prune(r *record, now time.Time) {
+	cutoff := now.Add(-m.config.Window)
+	drop := 0
+	for drop < len(r.counts) && !r.counts[drop].start.After(cutoff) {
+		drop++
+	}
+	r.counts = r.counts[drop:]
+}
@@ -0,0 +1,116 @@
+package sla
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestMonitor_SuccessRate(t *testing.T) {
+	monitor, _ := createTestMonitor()
+	unavailable := errors.NewNotificationError(errors.ErrorCodeProviderUnavailable, "down")
+
+	for i := 0; i < 8; i++ {
+		monitor.RecordSend(models.NotificationTypeSMS, "twilio", nil, 100*time.Millisecond)
+	}
+	// Failures of the message are not the provider's
+	monitor.RecordSend(models.NotificationTypeSMS, "twilio", errors.NewNotificationError(errors.ErrorCodeInvalidPhone, "bad number"), 0)
+	assert.False(t, monitor.Degraded(models.NotificationTypeSMS, "twilio"))
+
+	monitor.RecordSend(models.NotificationTypeSMS, "twilio", unavailable, 0)
+	stats, exists := monitor.Provider(models.NotificationTypeSMS, "twilio")
+	require.True(t, exists)
+	assert.Equal(t, int64(10), stats.Sends)
+	assert.Equal(t, int64(1), stats.Failures)
+	assert.InDelta(t, 0.9, stats.SuccessRate, 0.001)
+	assert.Equal(t, 80*time.Millisecond, stats.AverageLatency)
+	assert.Equal(t, 100*time.Millisecond, stats.MaxLatency)
+	assert.True(t, stats.Degraded)
+	assert.Contains(t, stats.Reason, "success rate 90.0% is below 95.0%")
+
+	// The same provider name on another channel is tracked apart
+	assert.False(t, monitor.Degraded(models.NotificationTypeEmail, "twilio"))
+}
+
+func TestMonitor_Latency(t *testing.T) {
+	monitor, _ := createTestMonitor()
+
+	for i := 0; i < 10; i++ {
+		monitor.RecordSend(models.NotificationTypeVoice, "twilio", nil, 3*time.Second)
+		monitor.RecordSend(models.NotificationTypeSMS, "twilio", nil, 3*time.Second)
+	}
+
+	voice, _ := monitor.Provider(models.NotificationTypeVoice, "twilio")
+	assert.True(t, voice.Degraded)
+	assert.Contains(t, voice.Reason, "average latency 3s exceeds 1s")
+
+	// An override by provider name replaces the default objectives
+	monitor.config.Providers = map[string]config.SLO{"twilio": {MaxAverageLatency: 5 * time.Second}}
+	assert.False(t, monitor.Degraded(models.NotificationTypeSMS, "twilio"))
+}
+
+func TestMonitor_Window(t *testing.T) {
+	monitor, now := createTestMonitor()
+	unavailable := errors.NewNotificationError(errors.ErrorCodeProviderUnavailable, "down")
+
+	for i := 0; i < 10; i++ {
+		monitor.RecordSend(models.NotificationTypeEmail, "ses", unavailable, 0)
+	}
+	stats, _ := monitor.Provider(models.NotificationTypeEmail, "ses")
+	require.True(t, stats.Degraded)
+	assert.Equal(t, *now, *stats.DegradedSince)
+
+	// Failures leave the window and the provider is tried again
+	*now = now.Add(6 * time.Minute)
+	assert.False(t, monitor.Degraded(models.NotificationTypeEmail, "ses"))
+	stats, _ = monitor.Provider(models.NotificationTypeEmail, "ses")
+	assert.Zero(t, stats.Sends)
+	assert.Nil(t, stats.DegradedSince)
+
+	_, exists := monitor.Provider(models.NotificationTypePush, "fcm")
+	assert.False(t, exists)
+	require.Len(t, monitor.Stats(), 1)
+}
+
+func TestMonitor_Rollup(t *testing.T) {
+	monitor, now := createTestMonitor()
+	assert.Empty(t, monitor.Rollup().Providers)
+
+	monitor.RecordSend(models.NotificationTypeSMS, "twilio", nil, 100*time.Millisecond)
+	rollup := monitor.Rollup()
+	assert.Equal(t, *now, rollup.At)
+	require.Len(t, rollup.Providers, 1)
+	assert.Equal(t, int64(1), rollup.Providers[0].Sends)
+
+	// The send has left the window, and only the latest rollups are kept
+	*now = now.Add(time.Hour)
+	for i := 0; i < maxRollups; i++ {
+		monitor.Rollup()
+	}
+	rollups := monitor.Rollups()
+	require.Len(t, rollups, maxRollups)
+	assert.Equal(t, *now, rollups[0].At)
+	assert.Zero(t, rollups[0].Providers[0].Sends)
+}
+
+// Helper functions
+
+// createTestMonitor returns a monitor judging after 10 sends in a five
+// minute window, and the time it reads
+func createTestMonitor() (*Monitor, *time.Time) {
+	monitor := NewMonitor(config.SLAConfig{
+		Window:     5 * time.Minute,
+		MinSamples: 10,
+		Objectives: config.SLO{MinSuccessRate: 0.95, MaxAverageLatency: time.Second},
+	}, utils.NewSimpleLogger("info"))
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	monitor.now = func() time.Time { return now }
+	return monitor, &now
+}
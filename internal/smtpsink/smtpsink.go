@@ -0,0 +1,413 @@
+// Package smtpsink runs a local SMTP server that accepts every message and
+// keeps it instead of delivering it. Pointing the SMTP email provider at it
+// exercises the whole SMTP path in development, from MIME formatting to the
+// SMTP conversation, without sending real mail; the development mailbox
+// shows what it captured.
+//
+// The sink offers neither TLS nor real authentication: AUTH PLAIN accepts
+// any credentials. Listen on a loopback address only.
+package smtpsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// Session limits
+const (
+	defaultMaxMessages = 1000
+	maxMessageSize     = 25 << 20 // bytes of DATA
+	maxRecipients      = 1000
+	idleTimeout        = 5 * time.Minute
+)
+
+// Message is a captured message
+type Message struct {
+	ID          string            `json:"id"`
+	From        string            `json:"from"`       // envelope sender
+	Recipients  []string          `json:"recipients"` // envelope recipients, BCC included
+	Subject     string            `json:"subject"`
+	Headers     map[string]string `json:"headers"` // first value of each header
+	TextBody    string            `json:"text_body,omitempty"`
+	HTMLBody    string            `json:"html_body,omitempty"`
+	Attachments []Attachment      `json:"attachments,omitempty"`
+	Size        int               `json:"size"` // bytes of the raw message
+	ReceivedAt  time.Time         `json:"received_at"`
+}
+
+// Attachment describes an attachment of a captured message
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int    `json:"size"` // decoded bytes
+}
+
+// Server is the SMTP sink. It is safe for concurrent use.
+type Server struct {
+	config config.SMTPSinkConfig
+	logger interfaces.Logger
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	messages []Message // oldest first
+	wg       sync.WaitGroup
+}
+
+// NewServer creates an SMTP sink
+func NewServer(cfg config.SMTPSinkConfig, logger interfaces.Logger) *Server {
+	if cfg.Addr == "" {
+		cfg.Addr = "127.0.0.1:1025"
+	}
+	if cfg.MaxMessages <= 0 {
+		cfg.MaxMessages = defaultMaxMessages
+	}
+
+	return &Server{
+		config: cfg,
+		logger: logger,
+		conns:  make(map[net.Conn]struct{}),
+	}
+}
+
+// Start listens on the configured address and accepts sessions until Stop
+// is called or the context ends. Calling Start on a running sink has no
+// effect.
+func (s *Server) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener != nil {
+		return nil
+	}
+	listener, err := net.Listen("tcp", s.config.Addr)
+	if err != nil {
+		return errors.WrapError(err, "failed to start the SMTP sink")
+	}
+	s.listener = listener
+
+	s.wg.Add(1)
+	go s.serve(listener)
+	context.AfterFunc(ctx, s.Stop)
+
+	s.logger.Infof("SMTP sink capturing mail on %s", listener.Addr())
+	return nil
+}
+
+// Stop closes the listener and open sessions and waits for them to end
+func (s *Server) Stop() {
+	s.mu.Lock()
+	if s.listener == nil {
+		s.mu.Unlock()
+		return
+	}
+	s.listener.Close()
+	s.listener = nil
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+// Addr returns the address the sink listens on, or "" when it is stopped
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// Messages returns the captured messages, oldest first
+func (s *Server) Messages() []Message {
+	return s.Search("")
+}
+
+// Search returns the captured messages with a recipient containing a
+// query, ignoring case, oldest first
+func (s *Server) Search(query string) []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query = strings.ToLower(query)
+	result := make([]Message, 0, len(s.messages))
+	for _, message := range s.messages {
+		for _, recipient := range message.Recipients {
+			if strings.Contains(strings.ToLower(recipient), query) {
+				result = append(result, message)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// Clear removes the captured messages
+func (s *Server) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.messages = nil
+}
+
+// serve accepts sessions until the listener closes
+func (s *Server) serve(listener net.Listener) {
+	defer s.wg.Done()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if s.listener != listener {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// handle runs an SMTP session
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	tp := textproto.NewConn(conn)
+	reply := func(code int, format string, args ...any) {
+		tp.PrintfLine("%d %s", code, fmt.Sprintf(format, args...))
+	}
+
+	var from string
+	var recipients []string
+	var transaction bool // MAIL was given; the sender may be empty for bounces
+	reset := func() {
+		from, recipients, transaction = "", nil, false
+	}
+
+	reply(220, "notification-service SMTP sink ready")
+	for {
+		conn.SetDeadline(time.Now().Add(idleTimeout))
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			reset()
+			tp.PrintfLine("250-notification-service greets %s", arg)
+			tp.PrintfLine("250-8BITMIME")
+			tp.PrintfLine("250-SIZE %d", maxMessageSize)
+			tp.PrintfLine("250 AUTH PLAIN")
+		case "HELO":
+			reset()
+			reply(250, "notification-service greets %s", arg)
+		case "AUTH":
+			mechanism, initial, _ := strings.Cut(arg, " ")
+			if !strings.EqualFold(mechanism, "PLAIN") {
+				reply(504, "only AUTH PLAIN is supported")
+				continue
+			}
+			if initial == "" {
+				reply(334, "")
+				if _, err := tp.ReadLine(); err != nil {
+					return
+				}
+			}
+			reply(235, "authenticated")
+		case "MAIL":
+			address, ok := pathArgument(arg, "FROM:")
+			if !ok {
+				reply(501, "syntax: MAIL FROM:<address>")
+				continue
+			}
+			reset()
+			from, transaction = address, true
+			reply(250, "OK")
+		case "RCPT":
+			address, ok := pathArgument(arg, "TO:")
+			switch {
+			case !ok || address == "":
+				reply(501, "syntax: RCPT TO:<address>")
+			case !transaction:
+				reply(503, "MAIL first")
+			case len(recipients) >= maxRecipients:
+				reply(452, "too many recipients")
+			default:
+				recipients = append(recipients, address)
+				reply(250, "OK")
+			}
+		case "DATA":
+			if len(recipients) == 0 {
+				reply(503, "RCPT first")
+				continue
+			}
+			reply(354, "end data with <CR><LF>.<CR><LF>")
+			data := tp.DotReader()
+			raw, err := io.ReadAll(io.LimitReader(data, maxMessageSize+1))
+			if err != nil {
+				return
+			}
+			if len(raw) > maxMessageSize {
+				io.Copy(io.Discard, data)
+				reply(552, "message exceeds %d bytes", maxMessageSize)
+				reset()
+				continue
+			}
+			message := s.capture(from, recipients, raw)
+			reply(250, "OK: captured as %s", message.ID)
+			reset()
+		case "RSET":
+			reset()
+			reply(250, "OK")
+		case "NOOP":
+			reply(250, "OK")
+		case "QUIT":
+			reply(221, "bye")
+			return
+		case "STARTTLS":
+			reply(454, "TLS not available")
+		default:
+			reply(502, "command not implemented")
+		}
+	}
+}
+
+// capture parses and keeps a message, evicting the oldest beyond the limit
+func (s *Server) capture(from string, recipients []string, raw []byte) Message {
+	message := parseMessage(raw)
+	message.ID = uuid.New().String()
+	message.From = from
+	message.Recipients = append([]string(nil), recipients...)
+	message.Size = len(raw)
+	message.ReceivedAt = time.Now()
+
+	s.mu.Lock()
+	s.messages = append(s.messages, message)
+	if excess := len(s.messages) - s.config.MaxMessages; excess > 0 {
+		s.messages = append([]Message(nil), s.messages[excess:]...)
+	}
+	s.mu.Unlock()
+
+	s.logger.Infof("SMTP sink captured %q from %s to %s", message.Subject, from, strings.Join(recipients, ", "))
+	return message
+}
+
+// pathArgument returns the address of a MAIL FROM or RCPT TO argument,
+// ignoring parameters such as SIZE
+func pathArgument(arg, prefix string) (string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", false
+	}
+	path := strings.TrimSpace(arg[len(prefix):])
+	if !strings.HasPrefix(path, "<") {
+		address, _, _ := strings.Cut(path, " ")
+		return address, address != ""
+	}
+	end := strings.Index(path, ">")
+	if end < 0 {
+		return "", false
+	}
+	return path[1:end], true
+}
+
+// parseMessage reads the subject, headers, bodies and attachments of a raw
+// message. A message that does not parse is kept as its text.
+func parseMessage(raw []byte) Message {
+	parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return Message{Headers: map[string]string{}, TextBody: string(raw)}
+	}
+
+	decoder := new(mime.WordDecoder)
+	message := Message{Headers: make(map[string]string, len(parsed.Header))}
+	for name, values := range parsed.Header {
+		if value, err := decoder.DecodeHeader(values[0]); err == nil {
+			message.Headers[name] = value
+		} else {
+			message.Headers[name] = values[0]
+		}
+	}
+	message.Subject = message.Headers["Subject"]
+
+	readPart(&message, textproto.MIMEHeader(parsed.Header), parsed.Body)
+	return message
+}
+
+// readPart adds a MIME part to a message: multipart parts part by part,
+// attachments by name and size, and the first text and HTML bodies
+func readPart(message *Message, header textproto.MIMEHeader, body io.Reader) {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", nil
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				return
+			}
+			readPart(message, part.Header, part)
+		}
+	}
+
+	switch strings.ToLower(header.Get("Content-Transfer-Encoding")) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+	content, _ := io.ReadAll(body)
+
+	disposition, dispositionParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := dispositionParams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+	switch {
+	case disposition == "attachment" || filename != "":
+		message.Attachments = append(message.Attachments, Attachment{Filename: filename, ContentType: mediaType, Size: len(content)})
+	case mediaType == "text/plain" && message.TextBody == "":
+		message.TextBody = string(content)
+	case mediaType == "text/html" && message.HTMLBody == "":
+		message.HTMLBody = string(content)
+	}
+}
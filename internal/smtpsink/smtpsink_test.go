@@ -0,0 +1,145 @@
+package smtpsink
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+)
+
+func TestServer_Capture(t *testing.T) {
+	sink := createTestSink(t, 0)
+	message := strings.Join([]string{
+		"From: alerts@example.com",
+		"To: ana@example.com",
+		"Subject: =?utf-8?q?R=C3=A9sum=C3=A9_ready?=",
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/mixed; boundary=outer",
+		"",
+		"--outer",
+		"Content-Type: multipart/alternative; boundary=inner",
+		"",
+		"--inner",
+		"Content-Type: text/plain; charset=utf-8",
+		"Content-Transfer-Encoding: quoted-printable",
+		"",
+		"Hello =3D there",
+		"--inner",
+		"Content-Type: text/html; charset=utf-8",
+		"",
+		"<p>Hello</p>",
+		"--inner--",
+		"--outer",
+		"Content-Type: text/csv; name=report.csv",
+		"Content-Disposition: attachment; filename=report.csv",
+		"Content-Transfer-Encoding: base64",
+		"",
+		"YSxiCjEsMgo=",
+		"--outer--",
+		"",
+	}, "\r\n")
+
+	err := smtp.SendMail(sink.Addr(), nil, "alerts@example.com", []string{"ana@example.com", "audit@example.org"}, []byte(message))
+	require.NoError(t, err)
+
+	messages := sink.Messages()
+	require.Len(t, messages, 1)
+	captured := messages[0]
+	assert.NotEmpty(t, captured.ID)
+	assert.Equal(t, "alerts@example.com", captured.From)
+	assert.Equal(t, []string{"ana@example.com", "audit@example.org"}, captured.Recipients)
+	assert.Equal(t, "Résumé ready", captured.Subject)
+	assert.Equal(t, "ana@example.com", captured.Headers["To"])
+	assert.Equal(t, "Hello = there", captured.TextBody)
+	assert.Equal(t, "<p>Hello</p>", captured.HTMLBody)
+	assert.Equal(t, []Attachment{{Filename: "report.csv", ContentType: "text/csv", Size: 8}}, captured.Attachments)
+
+	assert.Len(t, sink.Search("EXAMPLE.ORG"), 1)
+	assert.Empty(t, sink.Search("example.net"))
+
+	sink.Clear()
+	assert.Empty(t, sink.Messages())
+}
+
+func TestServer_MaxMessages(t *testing.T) {
+	sink := createTestSink(t, 2)
+	for i := 1; i <= 3; i++ {
+		message := fmt.Sprintf("Subject: Message %d\r\n\r\nBody\r\n", i)
+		require.NoError(t, smtp.SendMail(sink.Addr(), nil, "alerts@example.com", []string{"ana@example.com"}, []byte(message)))
+	}
+
+	messages := sink.Messages()
+	require.Len(t, messages, 2)
+	assert.Equal(t, "Message 2", messages[0].Subject)
+	assert.Equal(t, "Message 3", messages[1].Subject)
+}
+
+func TestServer_CommandOrder(t *testing.T) {
+	sink := createTestSink(t, 0)
+	conn, err := textproto.Dial("tcp", sink.Addr())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, _, err = conn.ReadResponse(220)
+	require.NoError(t, err)
+
+	for _, step := range []struct {
+		command string
+		code    int
+	}{
+		{"HELO test", 250},
+		{"RCPT TO:<ana@example.com>", 503},
+		{"DATA", 503},
+		{"MAIL FROM:<>", 250},
+		{"RCPT TO:<ana@example.com>", 250},
+		{"STARTTLS", 454},
+		{"VRFY ana", 502},
+		{"RSET", 250},
+		{"DATA", 503},
+		{"QUIT", 221},
+	} {
+		id, err := conn.Cmd("%s", step.command)
+		require.NoError(t, err)
+		conn.StartResponse(id)
+		code, _, _ := conn.ReadResponse(0)
+		conn.EndResponse(id)
+		assert.Equal(t, step.code, code, step.command)
+	}
+	assert.Empty(t, sink.Messages())
+}
+
+func TestServer_Stop(t *testing.T) {
+	sink := NewServer(config.SMTPSinkConfig{Addr: "127.0.0.1:0"}, utils.NewSimpleLogger("error"))
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, sink.Start(ctx))
+	addr := sink.Addr()
+	require.NotEmpty(t, addr)
+
+	// An idle session does not keep the sink from stopping
+	conn, err := textproto.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	cancel()
+	assert.Eventually(t, func() bool { return sink.Addr() == "" }, time.Second, 10*time.Millisecond)
+	sink.Stop()
+	assert.Error(t, smtp.SendMail(addr, nil, "alerts@example.com", []string{"ana@example.com"}, []byte("Subject: Late\r\n\r\n")))
+}
+
+// Helper functions
+
+func createTestSink(t *testing.T, maxMessages int) *Server {
+	sink := NewServer(config.SMTPSinkConfig{Addr: "127.0.0.1:0", MaxMessages: maxMessages}, utils.NewSimpleLogger("error"))
+	require.NoError(t, sink.Start(context.Background()))
+	t.Cleanup(sink.Stop)
+	return sink
+}
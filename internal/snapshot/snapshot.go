@@ -0,0 +1,252 @@
+// Package snapshot renders email templates to images for review. Each
+// snapshot is stored with the version of the template it shows, so a
+// template change can be reviewed by diffing the snapshots of its old and
+// new versions. Images come from a Renderer, typically a headless browser.
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/jpeg" // decode JPEG snapshots for diffs
+	_ "image/png"  // decode PNG snapshots for diffs
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// Snapshot widths, in CSS pixels
+const (
+	DefaultWidth = 600 // the usual width of an email layout
+	MaxWidth     = 2000
+)
+
+// maxSnapshots is the number of snapshots kept per template; older ones are dropped
+const maxSnapshots = 50
+
+// Renderer renders HTML to an image. Implement it with a headless browser
+// or a rendering service.
+type Renderer interface {
+	// Render renders a document at a viewport width and returns the image
+	// and its content type, e.g. "image/png"
+	Render(ctx context.Context, html string, width int) ([]byte, string, error)
+}
+
+// Templates gives access to email templates, such as the email provider's
+type Templates interface {
+	GetTemplate(templateID string) (*providers.EmailTemplate, error)
+	RenderTemplate(templateID string, data map[string]string) (*providers.EmailTemplate, error)
+}
+
+// Snapshot is an image of a template version rendered with some data
+type Snapshot struct {
+	TemplateID   string            `json:"template_id"`
+	Version      string            `json:"version"`
+	Width        int               `json:"width"`
+	ContentType  string            `json:"content_type"`
+	Size         int               `json:"size"`
+	ImageHash    string            `json:"image_hash"` // SHA-256 of the image
+	TemplateData map[string]string `json:"template_data,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
+	Image        []byte            `json:"-"`
+}
+
+// CaptureRequest is the body of a snapshot request
+type CaptureRequest struct {
+	TemplateData map[string]string `json:"template_data,omitempty"`
+	Width        int               `json:"width,omitempty"` // DefaultWidth when zero
+}
+
+// Diff compares the snapshots of two template versions. Pixels are compared
+// when both images decode; otherwise only their hashes are.
+type Diff struct {
+	TemplateID    string  `json:"template_id"`
+	From          string  `json:"from"`
+	To            string  `json:"to"`
+	Identical     bool    `json:"identical"`
+	SizeChanged   bool    `json:"size_changed"`
+	ChangedPixels int     `json:"changed_pixels"`
+	ChangedRatio  float64 `json:"changed_ratio"` // of the pixels of the larger image
+}
+
+// Version returns the version of a template: a hash of its content, so it
+// changes with every edit that can change how the template looks
+func Version(template *providers.EmailTemplate) string {
+	hash := sha256.New()
+	for _, field := range []string{template.Subject, template.HTMLBody, template.TextBody, template.Source} {
+		fmt.Fprintf(hash, "%d:%s", len(field), field)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:12]
+}
+
+// Service captures and keeps template snapshots. It is safe for concurrent use.
+type Service struct {
+	templates Templates
+	renderer  Renderer
+	logger    interfaces.Logger
+
+	mu        sync.RWMutex
+	snapshots map[string][]*Snapshot // by template ID, oldest first
+	now       func() time.Time
+}
+
+// NewService creates a snapshot service
+func NewService(templates Templates, renderer Renderer, logger interfaces.Logger) *Service {
+	return &Service{
+		templates: templates,
+		renderer:  renderer,
+		logger:    logger,
+		snapshots: make(map[string][]*Snapshot),
+		now:       time.Now,
+	}
+}
+
+// Capture renders the current version of a template with the data and
+// stores the image, replacing an earlier snapshot of the same version and
+// width
+func (s *Service) Capture(ctx context.Context, templateID string, request CaptureRequest) (*Snapshot, error) {
+	width := request.Width
+	if width == 0 {
+		width = DefaultWidth
+	}
+	if width < 0 || width > MaxWidth {
+		return nil, errors.NewValidationError("width", fmt.Sprintf("width must be between 1 and %d", MaxWidth))
+	}
+
+	template, err := s.templates.GetTemplate(templateID)
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := s.templates.RenderTemplate(templateID, request.TemplateData)
+	if err != nil {
+		return nil, err
+	}
+	if rendered.HTMLBody == "" {
+		return nil, errors.NewValidationError("template", fmt.Sprintf("template %s has no HTML body", templateID))
+	}
+
+	img, contentType, err := s.renderer.Render(ctx, rendered.HTMLBody, width)
+	if err != nil {
+		return nil, errors.NewInternalError(fmt.Sprintf("failed to render a snapshot of template %s", templateID), err)
+	}
+
+	sum := sha256.Sum256(img)
+	snapshot := &Snapshot{
+		TemplateID:   templateID,
+		Version:      Version(template),
+		Width:        width,
+		ContentType:  contentType,
+		Size:         len(img),
+		ImageHash:    hex.EncodeToString(sum[:]),
+		TemplateData: request.TemplateData,
+		CreatedAt:    s.now(),
+		Image:        img,
+	}
+
+	s.mu.Lock()
+	kept := s.snapshots[templateID][:0:0]
+	for _, existing := range s.snapshots[templateID] {
+		if existing.Version != snapshot.Version || existing.Width != snapshot.Width {
+			kept = append(kept, existing)
+		}
+	}
+	kept = append(kept, snapshot)
+	if len(kept) > maxSnapshots {
+		kept = kept[len(kept)-maxSnapshots:]
+	}
+	s.snapshots[templateID] = kept
+	s.mu.Unlock()
+
+	s.logger.Infof("Captured snapshot of template %s version %s at %dpx", templateID, snapshot.Version, width)
+	return snapshot, nil
+}
+
+// List returns the snapshots of a template, newest first
+func (s *Service) List(templateID string) []*Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshots := append([]*Snapshot(nil), s.snapshots[templateID]...)
+	sort.SliceStable(snapshots, func(i, j int) bool { return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt) })
+	return snapshots
+}
+
+// Get returns the newest snapshot of a template version
+func (s *Service) Get(templateID, version string) (*Snapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshots := s.snapshots[templateID]
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		if snapshots[i].Version == version {
+			return snapshots[i], nil
+		}
+	}
+	return nil, errors.NewNotificationError(errors.ErrorCodeNotFound, fmt.Sprintf("no snapshot of template %s version %s", templateID, version))
+}
+
+// Compare diffs the newest snapshots of two versions of a template
+func (s *Service) Compare(templateID, from, to string) (*Diff, error) {
+	before, err := s.Get(templateID, from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := s.Get(templateID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &Diff{TemplateID: templateID, From: from, To: to}
+	if before.ImageHash == after.ImageHash {
+		diff.Identical = true
+		return diff, nil
+	}
+
+	beforeImage, _, errBefore := image.Decode(bytes.NewReader(before.Image))
+	afterImage, _, errAfter := image.Decode(bytes.NewReader(after.Image))
+	if errBefore != nil || errAfter != nil {
+		// Images that cannot be decoded differ as a whole
+		diff.ChangedRatio = 1
+		return diff, nil
+	}
+
+	diff.ChangedPixels, diff.ChangedRatio, diff.SizeChanged = comparePixels(beforeImage, afterImage)
+	diff.Identical = diff.ChangedPixels == 0 && !diff.SizeChanged
+	return diff, nil
+}
+
+// comparePixels counts the pixels that differ between two images. Pixels
+// outside the smaller image count as changed.
+func comparePixels(a, b image.Image) (int, float64, bool) {
+	boundsA, boundsB := a.Bounds(), b.Bounds()
+	width := max(boundsA.Dx(), boundsB.Dx())
+	height := max(boundsA.Dy(), boundsB.Dy())
+	if width == 0 || height == 0 {
+		return 0, 0, boundsA.Size() != boundsB.Size()
+	}
+
+	changed := 0
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			pointA := image.Pt(boundsA.Min.X+x, boundsA.Min.Y+y)
+			pointB := image.Pt(boundsB.Min.X+x, boundsB.Min.Y+y)
+			if !pointA.In(boundsA) || !pointB.In(boundsB) {
+				changed++
+				continue
+			}
+			r1, g1, b1, a1 := a.At(pointA.X, pointA.Y).RGBA()
+			r2, g2, b2, a2 := b.At(pointB.X, pointB.Y).RGBA()
+			if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+				changed++
+			}
+		}
+	}
+	return changed, float64(changed) / float64(width*height), boundsA.Size() != boundsB.Size()
+}
@@ -0,0 +1,182 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/providers"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestVersion(t *testing.T) {
+	template := &providers.EmailTemplate{Subject: "Hi", HTMLBody: "<p>Hello</p>"}
+	version := Version(template)
+
+	assert.Len(t, version, 12)
+	assert.Equal(t, version, Version(&providers.EmailTemplate{Subject: "Hi", HTMLBody: "<p>Hello</p>", Name: "renamed"}))
+	assert.NotEqual(t, version, Version(&providers.EmailTemplate{Subject: "Hi", HTMLBody: "<p>Hello!</p>"}))
+	// Moving text between fields changes the version
+	assert.NotEqual(t, Version(&providers.EmailTemplate{Subject: "ab"}), Version(&providers.EmailTemplate{Subject: "a", HTMLBody: "b"}))
+}
+
+func TestService_Capture(t *testing.T) {
+	service, email, renderer := createTestService(t)
+	ctx := context.Background()
+
+	first, err := service.Capture(ctx, "snap", CaptureRequest{TemplateData: map[string]string{"name": "Jane"}})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultWidth, first.Width)
+	assert.Equal(t, "image/png", first.ContentType)
+	assert.Len(t, first.ImageHash, 64)
+	assert.Equal(t, len(first.Image), first.Size)
+	assert.Contains(t, renderer.lastHTML, "Hello Jane")
+
+	// Capturing the same version again replaces its snapshot
+	_, err = service.Capture(ctx, "snap", CaptureRequest{TemplateData: map[string]string{"name": "Jane"}})
+	require.NoError(t, err)
+	require.Len(t, service.List("snap"), 1)
+
+	require.NoError(t, email.AddTemplate(&providers.EmailTemplate{
+		ID: "snap", Name: "Snap", Subject: "Hi", HTMLBody: `<p style="color:red">Hello {{name}}</p>`,
+	}))
+	second, err := service.Capture(ctx, "snap", CaptureRequest{TemplateData: map[string]string{"name": "Jane"}})
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Version, second.Version)
+
+	listed := service.List("snap")
+	require.Len(t, listed, 2)
+	assert.Equal(t, second.Version, listed[0].Version)
+
+	got, err := service.Get("snap", first.Version)
+	require.NoError(t, err)
+	assert.Equal(t, first.ImageHash, got.ImageHash)
+}
+
+func TestService_CaptureErrors(t *testing.T) {
+	service, _, renderer := createTestService(t)
+	ctx := context.Background()
+
+	_, err := service.Capture(ctx, "snap", CaptureRequest{Width: MaxWidth + 1})
+	assertCode(t, err, errors.ErrorCodeValidationFailed)
+
+	_, err = service.Capture(ctx, "missing", CaptureRequest{})
+	assertCode(t, err, errors.ErrorCodeTemplateNotFound)
+
+	renderer.fail = true
+	_, err = service.Capture(ctx, "snap", CaptureRequest{})
+	assertCode(t, err, errors.ErrorCodeInternal)
+	assert.Empty(t, service.List("snap"))
+
+	_, err = service.Get("snap", "unknown")
+	assertCode(t, err, errors.ErrorCodeNotFound)
+}
+
+func TestService_Compare(t *testing.T) {
+	service, email, _ := createTestService(t)
+	ctx := context.Background()
+
+	first, err := service.Capture(ctx, "snap", CaptureRequest{})
+	require.NoError(t, err)
+
+	diff, err := service.Compare("snap", first.Version, first.Version)
+	require.NoError(t, err)
+	assert.True(t, diff.Identical)
+
+	require.NoError(t, email.AddTemplate(&providers.EmailTemplate{
+		ID: "snap", Name: "Snap", Subject: "Hi", HTMLBody: `<p style="color:red">Hello {{name}}</p>`,
+	}))
+	second, err := service.Capture(ctx, "snap", CaptureRequest{})
+	require.NoError(t, err)
+
+	diff, err = service.Compare("snap", first.Version, second.Version)
+	require.NoError(t, err)
+	assert.False(t, diff.Identical)
+	assert.False(t, diff.SizeChanged)
+	assert.Equal(t, 10, diff.ChangedPixels)
+	assert.InDelta(t, 0.1, diff.ChangedRatio, 0.0001)
+
+	_, err = service.Compare("snap", first.Version, "unknown")
+	assertCode(t, err, errors.ErrorCodeNotFound)
+}
+
+func TestComparePixels(t *testing.T) {
+	a := solidImage(4, 4, color.White)
+	b := solidImage(4, 2, color.White)
+
+	changed, ratio, sizeChanged := comparePixels(a, b)
+	assert.Equal(t, 8, changed)
+	assert.InDelta(t, 0.5, ratio, 0.0001)
+	assert.True(t, sizeChanged)
+}
+
+// Helper functions
+
+// testRenderer draws a 10x10 white image whose first row is red when the
+// HTML is red, so template changes show as changed pixels
+type testRenderer struct {
+	lastHTML string
+	fail     bool
+}
+
+func (r *testRenderer) Render(_ context.Context, html string, _ int) ([]byte, string, error) {
+	if r.fail {
+		return nil, "", errors.NewNotificationError(errors.ErrorCodeProviderUnavailable, "browser crashed")
+	}
+	r.lastHTML = html
+
+	img := solidImage(10, 10, color.White)
+	if strings.Contains(html, "color:red") {
+		for x := 0; x < 10; x++ {
+			img.Set(x, 0, color.RGBA{R: 255, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "image/png", nil
+}
+
+func solidImage(width, height int, c color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func assertCode(t *testing.T, err error, code errors.ErrorCode) {
+	t.Helper()
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok, "expected a notification error, got %v", err)
+	assert.Equal(t, code, notifErr.Code)
+}
+
+func createTestService(t *testing.T) (*Service, *providers.MockEmailProvider, *testRenderer) {
+	email := providers.NewMockEmailProvider(config.EmailProviderConfig{Provider: "mock"})
+	require.NoError(t, email.AddTemplate(&providers.EmailTemplate{
+		ID: "snap", Name: "Snap", Subject: "Hi", HTMLBody: "<p>Hello {{name}}</p>",
+	}))
+
+	renderer := &testRenderer{}
+	service := NewService(email, renderer, utils.NewSimpleLogger("error"))
+	now := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	service.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	return service, email, renderer
+}
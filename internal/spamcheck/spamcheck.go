@@ -0,0 +1,211 @@
+// Package spamcheck scores marketing email for spam before it is sent, so
+// a campaign that would land in spam folders, and hurt the sender's
+// reputation, is caught first. Scores come from a Scorer, such as a
+// SpamAssassin spamd server; sends scoring at or above the threshold can be
+// blocked.
+package spamcheck
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pipeline"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// MiddlewareName is the name the checker's middleware is registered under
+const MiddlewareName = "spam-check"
+
+// MetadataSpamScore is the metadata key a checked send's score is stored under
+const MetadataSpamScore = "spam_score"
+
+// Defaults applied to zero configuration fields
+const (
+	defaultThreshold = 5.0 // SpamAssassin's required_score
+	defaultTimeout   = 5 * time.Second
+)
+
+// defaultFrom is the sender of scored messages without one, since rules
+// penalise a missing From header
+const defaultFrom = "notifications@localhost"
+
+// Scorer scores a raw RFC 5322 message. Scores follow SpamAssassin: the sum
+// of the scores of the rules the message hits.
+type Scorer interface {
+	Score(ctx context.Context, message []byte) (*Result, error)
+}
+
+// RuleHit is a rule a message hit
+type RuleHit struct {
+	Name        string  `json:"name"`
+	Score       float64 `json:"score"`
+	Description string  `json:"description,omitempty"`
+}
+
+// Result is the spam score of a message
+type Result struct {
+	Score     float64   `json:"score"`
+	Threshold float64   `json:"threshold"`
+	Spam      bool      `json:"spam"`
+	Rules     []RuleHit `json:"rules,omitempty"` // highest scoring first
+}
+
+// Checker scores email with a Scorer
+type Checker struct {
+	scorer Scorer
+	config config.SpamCheckConfig
+	logger interfaces.Logger
+}
+
+// NewChecker creates a spam checker
+func NewChecker(scorer Scorer, cfg config.SpamCheckConfig, logger interfaces.Logger) *Checker {
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = defaultThreshold
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+
+	return &Checker{scorer: scorer, config: cfg, logger: logger}
+}
+
+// Check scores an email request as it would be sent
+func (c *Checker) Check(ctx context.Context, request *models.NotificationRequest) (*Result, error) {
+	if request.Type != models.NotificationTypeEmail {
+		return nil, errors.NewValidationError("type", "only email can be checked for spam")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancel()
+
+	result, err := c.scorer.Score(ctx, Message(request))
+	if err != nil {
+		return nil, err
+	}
+
+	checked := *result
+	checked.Threshold = c.config.Threshold
+	checked.Spam = checked.Score >= c.config.Threshold
+	checked.Rules = append([]RuleHit(nil), result.Rules...)
+	sort.SliceStable(checked.Rules, func(i, j int) bool { return checked.Rules[i].Score > checked.Rules[j].Score })
+	return &checked, nil
+}
+
+// Preview scores an email request as the send pipeline would, after
+// rendering its templates
+func (c *Checker) Preview(ctx context.Context, request *models.NotificationRequest) (*Result, error) {
+	var result *Result
+	check := pipeline.RenderTemplate()(func(ctx context.Context, rendered *models.NotificationRequest) (*models.NotificationResponse, error) {
+		var err error
+		result, err = c.Check(ctx, rendered)
+		return nil, err
+	})
+
+	if _, err := check(ctx, request); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Middleware returns the send middleware that scores marketing email and,
+// when blocking is enabled, rejects spam. The score is kept in the
+// notification's metadata. Sends the scorer cannot score go out unscored.
+func (c *Checker) Middleware() pipeline.Middleware {
+	return func(next pipeline.Handler) pipeline.Handler {
+		return func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+			if request.Type != models.NotificationTypeEmail || request.Category != models.CategoryMarketing {
+				return next(ctx, request)
+			}
+
+			result, err := c.Check(ctx, request)
+			if err != nil {
+				c.logger.Warnf("Spam check of email to %s failed, sending unscored: %v", request.Recipient, err)
+				return next(ctx, request)
+			}
+
+			score := strconv.FormatFloat(result.Score, 'f', 1, 64)
+			if result.Spam {
+				if c.config.Block {
+					c.logger.Warnf("Blocked marketing email to %s scoring %s (threshold %.1f): %s", request.Recipient, score, result.Threshold, result.summary())
+					return nil, errors.NewNotificationErrorWithDetails(errors.ErrorCodeInvalidNotification,
+						fmt.Sprintf("email scored %s for spam, the threshold is %.1f", score, result.Threshold), result.summary()).
+						WithMetadata(MetadataSpamScore, score)
+				}
+				c.logger.Warnf("Marketing email to %s scores %s for spam (threshold %.1f): %s", request.Recipient, score, result.Threshold, result.summary())
+			}
+
+			scored := *request
+			scored.Metadata = make(map[string]string, len(request.Metadata)+1)
+			for key, value := range request.Metadata {
+				scored.Metadata[key] = value
+			}
+			scored.Metadata[MetadataSpamScore] = score
+			return next(ctx, &scored)
+		}
+	}
+}
+
+// summary lists the rules a message hit
+func (r *Result) summary() string {
+	if len(r.Rules) == 0 {
+		return "no rules hit"
+	}
+
+	names := make([]string, len(r.Rules))
+	for i, rule := range r.Rules {
+		names[i] = fmt.Sprintf("%s=%.1f", rule.Name, rule.Score)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Message builds the message an email request sends, as a scorer reads it:
+// the text body, and the HTML body as an alternative when there is one
+func Message(request *models.NotificationRequest) []byte {
+	from, to := defaultFrom, request.Recipient
+	var htmlBody, textBody string
+	if data := request.EmailData; data != nil {
+		if data.From != "" {
+			from = data.From
+		}
+		if len(data.To) > 0 {
+			to = strings.Join(data.To, ", ")
+		}
+		htmlBody, textBody = data.HTMLBody, data.TextBody
+	}
+	if textBody == "" {
+		textBody = request.Body
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", to)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", request.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().UTC().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@spamcheck>\r\n", uuid.New())
+	buf.WriteString("MIME-Version: 1.0\r\n")
+
+	if htmlBody == "" {
+		buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+		buf.WriteString(textBody)
+		buf.WriteString("\r\n")
+		return buf.Bytes()
+	}
+
+	boundary := strings.ReplaceAll(uuid.New().String(), "-", "")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+	for _, part := range []struct{ contentType, body string }{{"text/plain", textBody}, {"text/html", htmlBody}} {
+		fmt.Fprintf(&buf, "--%s\r\nContent-Type: %s; charset=utf-8\r\n\r\n%s\r\n", boundary, part.contentType, part.body)
+	}
+	fmt.Fprintf(&buf, "--%s--\r\n", boundary)
+	return buf.Bytes()
+}
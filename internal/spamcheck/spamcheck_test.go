@@ -0,0 +1,133 @@
+package spamcheck
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestChecker_Check(t *testing.T) {
+	scorer := &testScorer{result: &Result{Score: 6.2, Threshold: 99, Rules: []RuleHit{
+		{Name: "HTML_MESSAGE", Score: 0.2},
+		{Name: "URIBL_BLACK", Score: 6},
+	}}}
+	checker := NewChecker(scorer, config.SpamCheckConfig{}, utils.NewSimpleLogger("error"))
+
+	result, err := checker.Check(context.Background(), createTestEmail())
+	require.NoError(t, err)
+	assert.True(t, result.Spam)
+	assert.Equal(t, defaultThreshold, result.Threshold)
+	require.Len(t, result.Rules, 2)
+	assert.Equal(t, "URIBL_BLACK", result.Rules[0].Name)
+	// The scorer's result is not modified
+	assert.Equal(t, "HTML_MESSAGE", scorer.result.Rules[0].Name)
+
+	_, err = checker.Check(context.Background(), &models.NotificationRequest{Type: models.NotificationTypeSMS})
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeValidationFailed, notifErr.Code)
+}
+
+func TestChecker_Middleware(t *testing.T) {
+	scorer := &testScorer{result: &Result{Score: 7.5, Rules: []RuleHit{{Name: "MONEY_FREE", Score: 7.5}}}}
+
+	tests := []struct {
+		name     string
+		block    bool
+		category models.Category
+		scorer   *testScorer
+		sent     bool
+		score    string
+	}{
+		{"spam blocked", true, models.CategoryMarketing, scorer, false, ""},
+		{"spam logged", false, models.CategoryMarketing, scorer, true, "7.5"},
+		{"transactional not scored", true, models.CategoryTransactional, scorer, true, ""},
+		{"scorer failure sends unscored", true, models.CategoryMarketing, &testScorer{}, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := NewChecker(tt.scorer, config.SpamCheckConfig{Threshold: 5, Block: tt.block}, utils.NewSimpleLogger("error"))
+			var sent *models.NotificationRequest
+			handler := checker.Middleware()(func(_ context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+				sent = request
+				return &models.NotificationResponse{}, nil
+			})
+
+			request := createTestEmail()
+			request.Category = tt.category
+			_, err := handler(context.Background(), request)
+
+			if !tt.sent {
+				notifErr, ok := errors.AsNotificationError(err)
+				require.True(t, ok)
+				assert.Equal(t, errors.ErrorCodeInvalidNotification, notifErr.Code)
+				assert.Contains(t, notifErr.Details, "MONEY_FREE=7.5")
+				assert.Equal(t, "7.5", notifErr.Metadata[MetadataSpamScore])
+				assert.Nil(t, sent)
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, sent)
+			assert.Equal(t, tt.score, sent.Metadata[MetadataSpamScore])
+			assert.Equal(t, "spring", sent.Metadata["campaign"])
+			// The caller's metadata is not modified
+			assert.NotContains(t, request.Metadata, MetadataSpamScore)
+		})
+	}
+}
+
+func TestMessage(t *testing.T) {
+	request := createTestEmail()
+	message := string(Message(request))
+
+	assert.Contains(t, message, "From: deals@shop.example.com\r\n")
+	assert.Contains(t, message, "To: jane@example.com\r\n")
+	assert.Contains(t, message, "Subject: =?utf-8?q?Spring_sale_=E2=80=93_50%_off?=\r\n")
+	assert.Contains(t, message, "Content-Type: multipart/alternative")
+	assert.Contains(t, message, "Content-Type: text/plain; charset=utf-8\r\n\r\nHalf price")
+	assert.Contains(t, message, "<p>Half price</p>")
+
+	plain := string(Message(&models.NotificationRequest{Type: models.NotificationTypeEmail, Recipient: "joe@example.com", Subject: "Hi", Body: "Hello"}))
+	assert.Contains(t, plain, "From: "+defaultFrom)
+	assert.Contains(t, plain, "Subject: Hi\r\n")
+	assert.True(t, strings.HasSuffix(plain, "text/plain; charset=utf-8\r\n\r\nHello\r\n"))
+}
+
+// Helper functions
+
+// testScorer returns a fixed result, or fails without one
+type testScorer struct {
+	result *Result
+}
+
+func (s *testScorer) Score(context.Context, []byte) (*Result, error) {
+	if s.result == nil {
+		return nil, errors.NewNotificationError(errors.ErrorCodeProviderUnavailable, "spamd is unreachable")
+	}
+	return s.result, nil
+}
+
+func createTestEmail() *models.NotificationRequest {
+	return &models.NotificationRequest{
+		Type:      models.NotificationTypeEmail,
+		Priority:  models.PriorityNormal,
+		Category:  models.CategoryMarketing,
+		Recipient: "jane@example.com",
+		Subject:   "Spring sale – 50% off",
+		Body:      "Half price",
+		Metadata:  map[string]string{"campaign": "spring"},
+		EmailData: &models.EmailData{
+			From:     "deals@shop.example.com",
+			HTMLBody: "<p>Half price</p>",
+		},
+	}
+}
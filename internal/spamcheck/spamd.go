@@ -0,0 +1,135 @@
+package spamcheck
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// maxReportSize bounds the report read from spamd
+const maxReportSize = 1 << 20
+
+var (
+	// spamHeaderPattern matches spamd's "Spam: True ; 15.3 / 5.0" header value
+	spamHeaderPattern = regexp.MustCompile(`^\s*(?:True|False|Yes|No)\s*;\s*(-?[0-9.]+)\s*/\s*(-?[0-9.]+)`)
+	// reportRulePattern matches a rule line of a report: " 2.5 URIBL_BLACK  Contains a listed URL"
+	reportRulePattern = regexp.MustCompile(`^\s*(-?[0-9]+(?:\.[0-9]+)?)\s+([A-Z0-9_]+)\s*(.*)$`)
+)
+
+// SpamdScorer scores messages with a SpamAssassin spamd server, using the
+// REPORT command of the spamc protocol
+type SpamdScorer struct {
+	address string
+	dialer  net.Dialer
+}
+
+// NewSpamdScorer creates a scorer for the spamd server at an address, host:port
+func NewSpamdScorer(address string) *SpamdScorer {
+	return &SpamdScorer{address: address}
+}
+
+// Score implements the Scorer interface
+func (s *SpamdScorer) Score(ctx context.Context, message []byte) (*Result, error) {
+	conn, err := s.dialer.DialContext(ctx, "tcp", s.address)
+	if err != nil {
+		return nil, errors.NewNotificationErrorWithDetails(errors.ErrorCodeProviderUnavailable, "spamd is unreachable", err.Error())
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	request := fmt.Sprintf("REPORT SPAMC/1.5\r\nContent-length: %d\r\n\r\n", len(message))
+	if _, err := io.WriteString(conn, request); err == nil {
+		_, err = conn.Write(message)
+	}
+	if err != nil {
+		return nil, errors.NewNotificationErrorWithDetails(errors.ErrorCodeProviderUnavailable, "failed to send the message to spamd", err.Error())
+	}
+
+	result, err := readSpamdResponse(bufio.NewReader(conn))
+	if err != nil {
+		return nil, errors.NewNotificationErrorWithDetails(errors.ErrorCodeProviderUnavailable, "failed to read the spamd response", err.Error())
+	}
+	return result, nil
+}
+
+// readSpamdResponse reads a REPORT response: a status line, headers
+// including the score, and the report listing the rules hit
+func readSpamdResponse(reader *bufio.Reader) (*Result, error) {
+	status, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(status)
+	if len(fields) < 3 || !strings.HasPrefix(fields[0], "SPAMD/") {
+		return nil, fmt.Errorf("unexpected status line %q", strings.TrimSpace(status))
+	}
+	if fields[1] != "0" {
+		return nil, fmt.Errorf("spamd returned %s", strings.Join(fields[1:], " "))
+	}
+
+	var result *Result
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+
+		name, value, found := strings.Cut(line, ":")
+		if !found || !strings.EqualFold(strings.TrimSpace(name), "Spam") {
+			continue
+		}
+		matches := spamHeaderPattern.FindStringSubmatch(value)
+		if matches == nil {
+			return nil, fmt.Errorf("unexpected Spam header %q", value)
+		}
+		score, _ := strconv.ParseFloat(matches[1], 64)
+		result = &Result{Score: score}
+	}
+	if result == nil {
+		return nil, fmt.Errorf("response has no Spam header")
+	}
+
+	report, err := io.ReadAll(io.LimitReader(reader, maxReportSize))
+	if err != nil {
+		return nil, err
+	}
+	result.Rules = parseReport(string(report))
+	return result, nil
+}
+
+// parseReport reads the rules from the table of a report, which follows a
+// line of dashes. Descriptions may continue on indented lines.
+func parseReport(report string) []RuleHit {
+	var rules []RuleHit
+	inTable := false
+	for _, line := range strings.Split(report, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if !inTable {
+			inTable = strings.HasPrefix(line, "----")
+			continue
+		}
+
+		if matches := reportRulePattern.FindStringSubmatch(line); matches != nil {
+			score, _ := strconv.ParseFloat(matches[1], 64)
+			rules = append(rules, RuleHit{Name: matches[2], Score: score, Description: strings.TrimSpace(matches[3])})
+		} else if text := strings.TrimSpace(line); text != "" && len(rules) > 0 {
+			last := &rules[len(rules)-1]
+			last.Description = strings.TrimSpace(last.Description + " " + text)
+		}
+	}
+	return rules
+}
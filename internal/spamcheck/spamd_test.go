@@ -0,0 +1,124 @@
+package spamcheck
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+const testReport = `Spam detection software, running on the system "mail.example.com",
+has identified this incoming email as possible spam.
+
+Content analysis details:   (7.7 points, 5.0 required)
+
+ pts rule name              description
+---- ---------------------- --------------------------------------------------
+ 2.5 URIBL_BLACK            Contains an URL listed in the URIBL blacklist
+                            [URIs: cheap.example]
+ 5.0 MONEY_FREE             Lots of money is available for free
+ 0.0 HTML_MESSAGE           BODY: HTML included in message
+-0.1 DKIM_VALID             Message has at least one valid DKIM signature
+`
+
+func TestSpamdScorer_Score(t *testing.T) {
+	received := make(chan string, 1)
+	address := startTestSpamd(t, func(command, message string) string {
+		received <- command + "\n" + message
+		return fmt.Sprintf("SPAMD/1.1 0 EX_OK\r\nContent-length: %d\r\nSpam: True ; 7.7 / 5.0\r\n\r\n%s", len(testReport), testReport)
+	})
+
+	result, err := NewSpamdScorer(address).Score(context.Background(), []byte("Subject: Free money\r\n\r\nClick here\r\n"))
+	require.NoError(t, err)
+
+	request := <-received
+	assert.Contains(t, request, "REPORT SPAMC/1.5")
+	assert.Contains(t, request, "Click here")
+	assert.Equal(t, 7.7, result.Score)
+	require.Len(t, result.Rules, 4)
+	assert.Equal(t, RuleHit{Name: "URIBL_BLACK", Score: 2.5, Description: "Contains an URL listed in the URIBL blacklist [URIs: cheap.example]"}, result.Rules[0])
+	assert.Equal(t, "MONEY_FREE", result.Rules[1].Name)
+	assert.Equal(t, -0.1, result.Rules[3].Score)
+}
+
+func TestSpamdScorer_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+	}{
+		{"error status", "SPAMD/1.1 76 EX_PROTOCOL\r\n\r\n"},
+		{"no score", "SPAMD/1.1 0 EX_OK\r\nContent-length: 0\r\n\r\n"},
+		{"not spamd", "HTTP/1.1 400 Bad Request\r\n\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address := startTestSpamd(t, func(string, string) string { return tt.response })
+
+			_, err := NewSpamdScorer(address).Score(context.Background(), []byte("Subject: Hi\r\n\r\nHello\r\n"))
+			notifErr, ok := errors.AsNotificationError(err)
+			require.True(t, ok)
+			assert.Equal(t, errors.ErrorCodeProviderUnavailable, notifErr.Code)
+		})
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+	_, err = NewSpamdScorer(address).Score(context.Background(), []byte("Subject: Hi\r\n\r\nHello\r\n"))
+	assert.Error(t, err)
+}
+
+// Helper functions
+
+// startTestSpamd serves the spamc protocol on a loopback port, answering
+// each request with the respond function's response
+func startTestSpamd(t *testing.T, respond func(command, message string) string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+				reader := bufio.NewReader(conn)
+				command, _ := reader.ReadString('\n')
+				length := 0
+				for {
+					line, err := reader.ReadString('\n')
+					line = strings.TrimSpace(line)
+					if err != nil || line == "" {
+						break
+					}
+					if name, value, found := strings.Cut(line, ":"); found && strings.EqualFold(name, "Content-length") {
+						length, _ = strconv.Atoi(strings.TrimSpace(value))
+					}
+				}
+				message := make([]byte, length)
+				io.ReadFull(reader, message)
+
+				io.WriteString(conn, respond(strings.TrimSpace(command), string(message)))
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
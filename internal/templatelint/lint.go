@@ -0,0 +1,282 @@
+// Package templatelint checks notification templates before they are used:
+// variable syntax, HTML well-formedness, subject length, the SMS segment
+// budget and spam-trigger words.
+package templatelint
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/layout"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+)
+
+// Severity is how serious an issue is. Templates with errors should not be
+// used; warnings are advice.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Rule names reported with each issue
+const (
+	RuleVariableSyntax = "variable-syntax"
+	RuleUndeclared     = "undeclared-variable"
+	RuleHTML           = "html"
+	RuleSubject        = "subject-length"
+	RuleSMSSegments    = "sms-segments"
+	RuleSpamWords      = "spam-words"
+)
+
+// variablePattern matches well-formed {{variable}} placeholders
+var variablePattern = regexp.MustCompile(`\{\{([A-Za-z_][\w.]*)\}\}`)
+
+// voidTags are HTML elements without closing tags
+var voidTags = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true,
+	"img": true, "input": true, "link": true, "meta": true, "source": true, "wbr": true,
+}
+
+// Template is the content of a template to check, whatever its channel
+type Template struct {
+	Channel   models.NotificationType
+	Subject   string
+	HTMLBody  string
+	Body      string   // text body, or the message of SMS templates
+	Variables []string // declared variables; when set, others are reported
+	Unicode   bool     // SMS encoding
+}
+
+// Issue is a problem found in a template
+type Issue struct {
+	Severity Severity `json:"severity"`
+	Rule     string   `json:"rule"`
+	Field    string   `json:"field"`
+	Message  string   `json:"message"`
+}
+
+// Report lists the issues found in a template
+type Report struct {
+	Issues []Issue `json:"issues"`
+}
+
+// Valid reports whether the template has no errors
+func (r Report) Valid() bool {
+	return len(r.Errors()) == 0
+}
+
+// Errors returns the issues with error severity
+func (r Report) Errors() []Issue {
+	var errs []Issue
+	for _, issue := range r.Issues {
+		if issue.Severity == SeverityError {
+			errs = append(errs, issue)
+		}
+	}
+	return errs
+}
+
+// Rules configure the checks
+type Rules struct {
+	MaxSubjectLength int      // subjects longer than this are truncated by many clients
+	MaxSMSSegments   int      // SMS messages longer than this are too costly
+	SpamWords        []string // phrases that trigger spam filters, matched case-insensitively
+}
+
+// DefaultRules returns the rules ValidateTemplate applies
+func DefaultRules() Rules {
+	return Rules{
+		MaxSubjectLength: 78,
+		MaxSMSSegments:   3,
+		SpamWords: []string{
+			"100% free", "act now", "cash bonus", "click here", "free money", "guaranteed",
+			"no credit check", "risk-free", "winner", "urgent response",
+		},
+	}
+}
+
+// Linter checks templates against rules
+type Linter struct {
+	rules Rules
+}
+
+// NewLinter creates a linter with the given rules
+func NewLinter(rules Rules) *Linter {
+	return &Linter{rules: rules}
+}
+
+// ValidateTemplate checks a template against the default rules
+func ValidateTemplate(template Template) Report {
+	return NewLinter(DefaultRules()).ValidateTemplate(template)
+}
+
+// ValidateTemplate checks a template
+func (l *Linter) ValidateTemplate(template Template) Report {
+	var report Report
+	add := func(severity Severity, rule, field, message string) {
+		report.Issues = append(report.Issues, Issue{Severity: severity, Rule: rule, Field: field, Message: message})
+	}
+
+	fields := []struct{ name, value string }{
+		{"subject", template.Subject},
+		{"html_body", template.HTMLBody},
+		{"body", template.Body},
+	}
+	for _, field := range fields {
+		for _, message := range checkVariables(field.value) {
+			add(SeverityError, RuleVariableSyntax, field.name, message)
+		}
+		for _, name := range undeclared(field.value, template.Variables) {
+			add(SeverityWarning, RuleUndeclared, field.name, fmt.Sprintf("variable %s is not declared", name))
+		}
+		for _, word := range l.spamWords(field.value) {
+			add(SeverityWarning, RuleSpamWords, field.name, fmt.Sprintf("contains spam-trigger phrase %q", word))
+		}
+	}
+
+	if template.HTMLBody != "" {
+		if err := checkHTML(template.HTMLBody); err != nil {
+			add(SeverityError, RuleHTML, "html_body", err.Error())
+		}
+	}
+
+	if template.Channel == models.NotificationTypeEmail {
+		switch {
+		case strings.TrimSpace(template.Subject) == "":
+			add(SeverityError, RuleSubject, "subject", "subject is required")
+		case l.rules.MaxSubjectLength > 0 && len([]rune(template.Subject)) > l.rules.MaxSubjectLength:
+			add(SeverityWarning, RuleSubject, "subject",
+				fmt.Sprintf("subject is %d characters; clients may truncate after %d", len([]rune(template.Subject)), l.rules.MaxSubjectLength))
+		}
+	}
+
+	if template.Channel == models.NotificationTypeSMS && l.rules.MaxSMSSegments > 0 {
+		unicode := template.Unicode || !isASCII(template.Body)
+		if segments := utils.CalculateSMSSegments(template.Body, unicode); segments > l.rules.MaxSMSSegments {
+			add(SeverityError, RuleSMSSegments, "body",
+				fmt.Sprintf("message needs %d segments before variables are filled in; the budget is %d", segments, l.rules.MaxSMSSegments))
+		}
+	}
+
+	return report
+}
+
+// spamWords returns the spam-trigger phrases in text
+func (l *Linter) spamWords(text string) []string {
+	lower := strings.ToLower(text)
+	var found []string
+	for _, word := range l.rules.SpamWords {
+		if strings.Contains(lower, strings.ToLower(word)) {
+			found = append(found, word)
+		}
+	}
+	return found
+}
+
+// checkVariables reports malformed placeholders, such as unbalanced braces
+// or {{ name }} with spaces, which would never be replaced
+func checkVariables(text string) []string {
+	var problems []string
+	rest := text
+	for {
+		open := strings.Index(rest, "{{")
+		closing := strings.Index(rest, "}}")
+		if open < 0 {
+			if closing >= 0 {
+				problems = append(problems, "}} without a matching {{")
+			}
+			return problems
+		}
+		if closing >= 0 && closing < open {
+			problems = append(problems, "}} without a matching {{")
+		}
+
+		end := strings.Index(rest[open:], "}}")
+		if end < 0 {
+			return append(problems, "{{ is not closed")
+		}
+		placeholder := rest[open : open+end+2]
+		if variablePattern.FindString(placeholder) != placeholder {
+			problems = append(problems, fmt.Sprintf("malformed variable %s; use {{name}}", placeholder))
+		}
+		rest = rest[open+end+2:]
+	}
+}
+
+// undeclared returns the variables in text that are not declared. Branding
+// variables are injected automatically and always allowed.
+func undeclared(text string, declared []string) []string {
+	if len(declared) == 0 {
+		return nil
+	}
+	allowed := make(map[string]bool, len(declared))
+	for _, name := range declared {
+		allowed[name] = true
+	}
+
+	var names []string
+	for _, match := range variablePattern.FindAllStringSubmatch(text, -1) {
+		name := match[1]
+		if !allowed[name] && !strings.HasPrefix(name, layout.BrandingPrefix) {
+			names = append(names, name)
+			allowed[name] = true // report once
+		}
+	}
+	return names
+}
+
+// checkHTML checks that every element is closed in order
+func checkHTML(body string) error {
+	decoder := xml.NewDecoder(strings.NewReader(body))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+
+	var open []string
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("malformed HTML: %v", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if !voidTags[strings.ToLower(t.Name.Local)] {
+				open = append(open, strings.ToLower(t.Name.Local))
+			}
+		case xml.EndElement:
+			name := strings.ToLower(t.Name.Local)
+			if voidTags[name] {
+				continue
+			}
+			if len(open) == 0 || open[len(open)-1] != name {
+				return fmt.Errorf("unexpected </%s>", name)
+			}
+			open = open[:len(open)-1]
+		}
+	}
+
+	if len(open) > 0 {
+		return fmt.Errorf("<%s> is not closed", open[len(open)-1])
+	}
+	return nil
+}
+
+// isASCII reports whether text fits the GSM-7 encoding closely enough to
+// count segments as such
+func isASCII(text string) bool {
+	for _, r := range text {
+		if r > 127 {
+			return false
+		}
+	}
+	return true
+}
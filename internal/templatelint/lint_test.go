@@ -0,0 +1,138 @@
+package templatelint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+)
+
+func TestValidateTemplate_Valid(t *testing.T) {
+	report := ValidateTemplate(createTestTemplate())
+
+	assert.True(t, report.Valid())
+	assert.Empty(t, report.Issues)
+}
+
+func TestValidateTemplate_VariableSyntax(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"spaces", "Hi {{ user_name }}"},
+		{"unclosed", "Hi {{user_name"},
+		{"unopened", "Hi user_name}}"},
+		{"empty", "Hi {{}}"},
+		{"invalid name", "Hi {{user-name}}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := createTestTemplate()
+			template.Body = tt.body
+
+			report := ValidateTemplate(template)
+			require.False(t, report.Valid())
+			assert.Equal(t, RuleVariableSyntax, report.Errors()[0].Rule)
+			assert.Equal(t, "body", report.Errors()[0].Field)
+		})
+	}
+}
+
+func TestValidateTemplate_UndeclaredVariables(t *testing.T) {
+	template := createTestTemplate()
+	template.Body = "Hi {{user_name}}, your order {{order_id}} from {{brand.name}} ships {{order_id}}"
+
+	report := ValidateTemplate(template)
+	assert.True(t, report.Valid())
+	require.Len(t, report.Issues, 1)
+	assert.Equal(t, RuleUndeclared, report.Issues[0].Rule)
+	assert.Contains(t, report.Issues[0].Message, "order_id")
+}
+
+func TestValidateTemplate_HTML(t *testing.T) {
+	tests := []struct {
+		name  string
+		html  string
+		valid bool
+	}{
+		{"well formed", `<!DOCTYPE html><html><body><p>Hi<br>there</p><img src="x.png"></body></html>`, true},
+		{"unclosed", "<div><p>Hi</p>", false},
+		{"misnested", "<b><i>Hi</b></i>", false},
+		{"stray close", "<p>Hi</p></div>", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := createTestTemplate()
+			template.HTMLBody = tt.html
+
+			report := ValidateTemplate(template)
+			assert.Equal(t, tt.valid, report.Valid())
+		})
+	}
+}
+
+func TestValidateTemplate_Subject(t *testing.T) {
+	template := createTestTemplate()
+	template.Subject = strings.Repeat("a", 79)
+
+	report := ValidateTemplate(template)
+	assert.True(t, report.Valid())
+	require.Len(t, report.Issues, 1)
+	assert.Equal(t, RuleSubject, report.Issues[0].Rule)
+
+	template.Subject = " "
+	assert.False(t, ValidateTemplate(template).Valid())
+}
+
+func TestValidateTemplate_SMSSegments(t *testing.T) {
+	template := Template{Channel: models.NotificationTypeSMS, Body: strings.Repeat("a", 459)}
+	assert.True(t, ValidateTemplate(template).Valid())
+
+	template.Body = strings.Repeat("a", 460)
+	report := ValidateTemplate(template)
+	require.False(t, report.Valid())
+	assert.Equal(t, RuleSMSSegments, report.Errors()[0].Rule)
+
+	// Non-GSM characters switch to the shorter unicode segments
+	template.Body = strings.Repeat("é", 202)
+	assert.False(t, ValidateTemplate(template).Valid())
+}
+
+func TestValidateTemplate_SpamWords(t *testing.T) {
+	template := createTestTemplate()
+	template.Subject = "You are a WINNER - act now"
+
+	report := ValidateTemplate(template)
+	assert.True(t, report.Valid())
+	require.Len(t, report.Issues, 2)
+	for _, issue := range report.Issues {
+		assert.Equal(t, RuleSpamWords, issue.Rule)
+		assert.Equal(t, SeverityWarning, issue.Severity)
+	}
+}
+
+func TestLinter_CustomRules(t *testing.T) {
+	linter := NewLinter(Rules{MaxSubjectLength: 10, SpamWords: []string{"sale"}})
+	template := createTestTemplate()
+	template.Subject = "Big summer sale"
+
+	report := linter.ValidateTemplate(template)
+	assert.Len(t, report.Issues, 2)
+}
+
+// Helper functions
+
+func createTestTemplate() Template {
+	return Template{
+		Channel:   models.NotificationTypeEmail,
+		Subject:   "Welcome {{user_name}}",
+		HTMLBody:  "<html><body><p>Hi {{user_name}}</p></body></html>",
+		Body:      "Hi {{user_name}}",
+		Variables: []string{"user_name"},
+	}
+}
@@ -0,0 +1,203 @@
+// Package templateschema declares the variables a template takes, with their
+// types, whether they are required and their allowed values, so template
+// data is checked before rendering instead of producing a message with
+// "{{amount}}" or "ten dollars" where a number belongs.
+package templateschema
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// Type is the type of a template variable. Template data is all strings, so
+// types describe what the string must hold.
+type Type string
+
+const (
+	TypeString  Type = "string"
+	TypeNumber  Type = "number"
+	TypeInteger Type = "integer"
+	TypeBoolean Type = "boolean" // "true" or "false"
+	TypeDate    Type = "date"    // YYYY-MM-DD or RFC 3339
+	TypeEmail   Type = "email"
+	TypeURL     Type = "url" // absolute http or https URL
+)
+
+// Rule names reported in field errors
+const (
+	RuleRequired  = "required"
+	RuleType      = "type"
+	RuleEnum      = "enum"
+	RuleMaxLength = "max_length"
+)
+
+// Variable declares a template variable
+type Variable struct {
+	Type        Type     `json:"type,omitempty"` // string when empty
+	Required    bool     `json:"required,omitempty"`
+	Enum        []string `json:"enum,omitempty"`       // allowed values
+	MaxLength   int      `json:"max_length,omitempty"` // in characters; 0 means no limit
+	Default     string   `json:"default,omitempty"`    // used when the data has no value
+	Description string   `json:"description,omitempty"`
+}
+
+// Schema declares a template's variables by name. Data for undeclared
+// variables is allowed, since callers often share data across templates.
+type Schema map[string]Variable
+
+// Names returns the declared variable names, sorted
+func (s Schema) Names() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Check checks the schema itself: types must be known and enum values must
+// be of the variable's type
+func (s Schema) Check() error {
+	for _, name := range s.Names() {
+		variable := s[name]
+		if !variable.Type.known() {
+			return errors.NewValidationError("schema."+name, fmt.Sprintf("unknown type %q", variable.Type))
+		}
+		if variable.MaxLength < 0 {
+			return errors.NewValidationError("schema."+name, "max_length cannot be negative")
+		}
+		for _, value := range variable.Enum {
+			if message := variable.Type.check(value); message != "" {
+				return errors.NewValidationError("schema."+name, fmt.Sprintf("enum value %q %s", value, message))
+			}
+		}
+		if variable.Default != "" {
+			if failure, failed := variable.validate(name, map[string]string{name: variable.Default}); failed {
+				return errors.NewValidationError("schema."+name, "default "+failure.Message)
+			}
+		}
+	}
+	return nil
+}
+
+// Apply returns template data with the schema's defaults filled in for
+// variables without a value. The data is not modified.
+func (s Schema) Apply(data map[string]string) map[string]string {
+	var applied map[string]string
+	for name, variable := range s {
+		if variable.Default == "" || data[name] != "" {
+			continue
+		}
+		if applied == nil {
+			applied = make(map[string]string, len(data)+len(s))
+			for key, value := range data {
+				applied[key] = value
+			}
+		}
+		applied[name] = variable.Default
+	}
+
+	if applied == nil {
+		return data
+	}
+	return applied
+}
+
+// Validate checks template data against the schema. It returns a
+// validation error listing every failed variable, as template_data.<name>,
+// or nil.
+func (s Schema) Validate(data map[string]string) error {
+	var failures []errors.FieldError
+	for _, name := range s.Names() {
+		if failure, failed := s[name].validate(name, data); failed {
+			failures = append(failures, failure)
+		}
+	}
+
+	if len(failures) > 0 {
+		return errors.NewFieldValidationError(failures...)
+	}
+	return nil
+}
+
+// validate checks a variable's value
+func (v Variable) validate(name string, data map[string]string) (errors.FieldError, bool) {
+	field := "template_data." + name
+	value, exists := data[name]
+	if !exists || value == "" {
+		if v.Required {
+			return errors.FieldError{Field: field, Rule: RuleRequired, Message: "is required"}, true
+		}
+		return errors.FieldError{}, false
+	}
+
+	if message := v.Type.check(value); message != "" {
+		return errors.FieldError{Field: field, Rule: RuleType, Message: fmt.Sprintf("%s, got %q", message, value)}, true
+	}
+	if len(v.Enum) > 0 && !contains(v.Enum, value) {
+		return errors.FieldError{Field: field, Rule: RuleEnum, Message: fmt.Sprintf("must be one of %s, got %q", strings.Join(v.Enum, ", "), value)}, true
+	}
+	if v.MaxLength > 0 && len([]rune(value)) > v.MaxLength {
+		return errors.FieldError{Field: field, Rule: RuleMaxLength, Message: fmt.Sprintf("must be at most %d characters", v.MaxLength)}, true
+	}
+	return errors.FieldError{}, false
+}
+
+// known reports whether the type is one of the declared types
+func (t Type) known() bool {
+	switch t {
+	case "", TypeString, TypeNumber, TypeInteger, TypeBoolean, TypeDate, TypeEmail, TypeURL:
+		return true
+	}
+	return false
+}
+
+// check returns why a value is not of the type, or "" when it is
+func (t Type) check(value string) string {
+	switch t {
+	case TypeNumber:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "must be a number"
+		}
+	case TypeInteger:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return "must be an integer"
+		}
+	case TypeBoolean:
+		if value != "true" && value != "false" {
+			return "must be true or false"
+		}
+	case TypeDate:
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			if _, err := time.Parse(time.RFC3339, value); err != nil {
+				return "must be a date (YYYY-MM-DD or RFC 3339)"
+			}
+		}
+	case TypeEmail:
+		if address, err := mail.ParseAddress(value); err != nil || address.Address != value {
+			return "must be an email address"
+		}
+	case TypeURL:
+		if parsed, err := url.Parse(value); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return "must be an http or https URL"
+		}
+	}
+	return ""
+}
+
+// contains reports whether a value is in a list
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package templateschema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestSchema_Validate(t *testing.T) {
+	schema := createTestSchema()
+
+	valid := map[string]string{
+		"name":       "Ada",
+		"amount":     "19.99",
+		"items":      "3",
+		"plan":       "pro",
+		"renews_on":  "2024-07-01",
+		"reply_to":   "billing@example.com",
+		"manage_url": "https://example.com/billing",
+		"trial":      "false",
+		"unused":     "undeclared variables are allowed",
+	}
+	assert.NoError(t, schema.Validate(valid))
+
+	// Optional variables may be missing
+	assert.NoError(t, schema.Validate(map[string]string{"name": "Ada", "amount": "5"}))
+}
+
+func TestSchema_Validate_Errors(t *testing.T) {
+	schema := createTestSchema()
+
+	err := schema.Validate(map[string]string{
+		"amount":    "ten dollars",
+		"items":     "2.5",
+		"plan":      "platinum",
+		"renews_on": "next week",
+		"reply_to":  "billing",
+		"trial":     "yes",
+	})
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeValidationFailed, notifErr.Code)
+
+	failures := make(map[string]errors.FieldError)
+	for _, failure := range notifErr.Fields {
+		failures[failure.Field] = failure
+	}
+	require.Len(t, failures, 7)
+	assert.Equal(t, RuleRequired, failures["template_data.name"].Rule)
+	assert.Equal(t, RuleType, failures["template_data.amount"].Rule)
+	assert.Equal(t, `must be a number, got "ten dollars"`, failures["template_data.amount"].Message)
+	assert.Equal(t, RuleType, failures["template_data.items"].Rule)
+	assert.Equal(t, RuleEnum, failures["template_data.plan"].Rule)
+	assert.Equal(t, `must be one of basic, pro, got "platinum"`, failures["template_data.plan"].Message)
+	assert.Equal(t, RuleType, failures["template_data.renews_on"].Rule)
+	assert.Equal(t, RuleType, failures["template_data.reply_to"].Rule)
+	assert.Equal(t, RuleType, failures["template_data.trial"].Rule)
+
+	err = schema.Validate(map[string]string{"name": "A very long name indeed", "amount": "1", "manage_url": "javascript:alert(1)"})
+	notifErr, ok = errors.AsNotificationError(err)
+	require.True(t, ok)
+	require.Len(t, notifErr.Fields, 2)
+	assert.Equal(t, "template_data.manage_url", notifErr.Fields[0].Field)
+	assert.Equal(t, RuleMaxLength, notifErr.Fields[1].Rule)
+}
+
+func TestSchema_Apply(t *testing.T) {
+	schema := Schema{
+		"plan":    {Enum: []string{"basic", "pro"}, Default: "basic"},
+		"support": {Type: TypeEmail, Required: true, Default: "help@example.com"},
+		"name":    {},
+	}
+	data := map[string]string{"plan": "pro", "name": "Ada"}
+
+	applied := schema.Apply(data)
+	assert.Equal(t, map[string]string{"plan": "pro", "name": "Ada", "support": "help@example.com"}, applied)
+	assert.NotContains(t, data, "support") // the caller's data is not modified
+	assert.NoError(t, schema.Validate(applied))
+
+	assert.Equal(t, "basic", schema.Apply(nil)["plan"])
+}
+
+func TestSchema_Check(t *testing.T) {
+	assert.NoError(t, createTestSchema().Check())
+
+	err := Schema{"amount": {Type: "money"}}.Check()
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, "schema.amount", notifErr.Metadata["field"])
+
+	assert.Error(t, Schema{"level": {Type: TypeInteger, Enum: []string{"1", "two"}}}.Check())
+	assert.Error(t, Schema{"name": {MaxLength: -1}}.Check())
+	assert.Error(t, Schema{"plan": {Enum: []string{"basic", "pro"}, Default: "gold"}}.Check())
+}
+
+// Helper functions
+
+func createTestSchema() Schema {
+	return Schema{
+		"name":       {Required: true, MaxLength: 10},
+		"amount":     {Type: TypeNumber, Required: true},
+		"items":      {Type: TypeInteger},
+		"plan":       {Enum: []string{"basic", "pro"}},
+		"renews_on":  {Type: TypeDate},
+		"reply_to":   {Type: TypeEmail},
+		"manage_url": {Type: TypeURL},
+		"trial":      {Type: TypeBoolean},
+	}
+}
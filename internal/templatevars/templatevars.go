@@ -0,0 +1,247 @@
+// Package templatevars renders {{variable}} placeholders in templates,
+// including the computed variables every email, SMS and push template can
+// use without the caller passing them:
+//
+//	{{now}}                    the send time, RFC 3339
+//	{{date}}                   the send date, YYYY-MM-DD
+//	{{date format="Jan 2"}}    the send time in a Go time layout
+//	{{recipient_domain}}       the domain of the recipient's email address
+//
+// Times are in the "timezone" template variable's zone, or UTC. Template
+// data with the same name takes precedence over a computed variable.
+package templatevars
+
+import (
+	"net/mail"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Computed variable names
+const (
+	VarNow             = "now"
+	VarDate            = "date"
+	VarRecipientDomain = "recipient_domain"
+)
+
+// dateLayout is the layout of {{date}}
+const dateLayout = "2006-01-02"
+
+// datePattern matches a {{date format=...}} expression, with the layout
+// quoted or, when it has no spaces, bare
+var datePattern = regexp.MustCompile(`^\{\{date\s+format=(?:"([^"]*)"|([^\s"}]+))\s*\}\}$`)
+
+// Render replaces {{variable}} placeholders in text with template data and
+// computed variables, at the time now. Templates rendered repeatedly, as in
+// bulk sends, are cheaper to Compile once.
+func Render(text string, data map[string]string, now time.Time) string {
+	if !strings.Contains(text, "{{") {
+		return text
+	}
+	return Compile(text).Render(data, now)
+}
+
+// segmentKind is the kind of a template segment
+type segmentKind int
+
+const (
+	segmentText segmentKind = iota
+	segmentVariable
+	segmentDate // {{date format=...}}
+)
+
+// segment is a run of literal text or a placeholder. For placeholders, value
+// is the variable name or date layout and text the placeholder as written.
+type segment struct {
+	kind  segmentKind
+	value string
+	text  string
+}
+
+// Template is text parsed into literal runs and placeholders, so rendering
+// it is a single pass with no searching
+type Template struct {
+	segments []segment
+	size     int // of the literal text, to size the output
+}
+
+// Compile parses text's {{variable}} placeholders
+func Compile(text string) *Template {
+	template := &Template{}
+	addText := func(literal string) {
+		if literal != "" {
+			template.segments = append(template.segments, segment{kind: segmentText, text: literal})
+			template.size += len(literal)
+		}
+	}
+
+	rest := text
+	for {
+		open := strings.Index(rest, "{{")
+		if open < 0 {
+			break
+		}
+		end := strings.Index(rest[open+2:], "}}")
+		if end < 0 {
+			break
+		}
+		end += open + 2
+		// The placeholder starts at the last {{ before its }}, as in "{{{name}}"
+		open += strings.LastIndex(rest[open:end], "{{")
+
+		addText(rest[:open])
+		placeholder := rest[open : end+2]
+		if match := datePattern.FindStringSubmatch(placeholder); match != nil {
+			layout := match[1]
+			if layout == "" {
+				layout = match[2]
+			}
+			template.segments = append(template.segments, segment{kind: segmentDate, value: layout, text: placeholder})
+		} else {
+			template.segments = append(template.segments, segment{kind: segmentVariable, value: placeholder[2 : len(placeholder)-2], text: placeholder})
+		}
+		rest = rest[end+2:]
+	}
+	addText(rest)
+	return template
+}
+
+// Render renders the template with template data and computed variables, at
+// the time now. Placeholders without a value are kept as written.
+func (t *Template) Render(data map[string]string, now time.Time) string {
+	var out strings.Builder
+	out.Grow(t.size + 16*len(t.segments))
+
+	zoned := false
+	for _, seg := range t.segments {
+		if seg.kind == segmentText {
+			out.WriteString(seg.text)
+			continue
+		}
+		if !zoned {
+			now, zoned = now.In(location(data)), true
+		}
+
+		switch {
+		case seg.kind == segmentDate:
+			out.WriteString(now.Format(seg.value))
+		case hasValue(data, seg.value):
+			out.WriteString(data[seg.value])
+		case seg.value == VarNow:
+			out.WriteString(now.Format(time.RFC3339))
+		case seg.value == VarDate:
+			out.WriteString(now.Format(dateLayout))
+		default:
+			out.WriteString(seg.text)
+		}
+	}
+	return out.String()
+}
+
+// hasValue reports whether data has the variable, even if empty
+func hasValue(data map[string]string, name string) bool {
+	_, exists := data[name]
+	return exists
+}
+
+// Cache holds compiled templates by key, such as a template's ID, version
+// and field. It is safe for concurrent use.
+type Cache struct {
+	mu        sync.RWMutex
+	templates map[string]cacheEntry
+	limit     int
+}
+
+// cacheEntry is a compiled template and the text it was compiled from
+type cacheEntry struct {
+	text     string
+	template *Template
+}
+
+// NewCache creates a cache holding up to limit templates. When it is full,
+// it is emptied, so templates of old versions do not accumulate.
+func NewCache(limit int) *Cache {
+	if limit <= 0 {
+		limit = 1000
+	}
+	return &Cache{templates: make(map[string]cacheEntry), limit: limit}
+}
+
+// Get returns text compiled, from the cache when it holds key compiled from
+// the same text
+func (c *Cache) Get(key, text string) *Template {
+	c.mu.RLock()
+	entry, exists := c.templates[key]
+	c.mu.RUnlock()
+	if exists && entry.text == text {
+		return entry.template
+	}
+
+	template := Compile(text)
+	c.mu.Lock()
+	if len(c.templates) >= c.limit {
+		c.templates = make(map[string]cacheEntry)
+	}
+	c.templates[key] = cacheEntry{text: text, template: template}
+	c.mu.Unlock()
+	return template
+}
+
+// Len returns the number of cached templates
+func (c *Cache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return len(c.templates)
+}
+
+// WithRecipient returns template data with {{recipient_domain}} set from
+// the recipient's email address: the recipient itself when it is an email
+// address, as for email, or else the "email" template variable, as for SMS
+// and push. The data is not modified.
+func WithRecipient(data map[string]string, recipient string) map[string]string {
+	if _, exists := data[VarRecipientDomain]; exists {
+		return data
+	}
+
+	domain := emailDomain(recipient)
+	if domain == "" {
+		domain = emailDomain(data["email"])
+	}
+	if domain == "" {
+		return data
+	}
+
+	withDomain := make(map[string]string, len(data)+1)
+	for key, value := range data {
+		withDomain[key] = value
+	}
+	withDomain[VarRecipientDomain] = domain
+	return withDomain
+}
+
+// emailDomain returns the lowercased domain of an email address, or "" when
+// value is not one
+func emailDomain(value string) string {
+	address, err := mail.ParseAddress(value)
+	if err != nil {
+		return ""
+	}
+	at := strings.LastIndex(address.Address, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(address.Address[at+1:])
+}
+
+// location returns the zone of the "timezone" template variable, or UTC
+func location(data map[string]string) *time.Location {
+	if name := data["timezone"]; name != "" {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
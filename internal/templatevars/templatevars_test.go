@@ -0,0 +1,153 @@
+package templatevars
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	now := time.Date(2024, 6, 3, 14, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		text string
+		data map[string]string
+		want string
+	}{
+		{"template data", "Hi {{name}}", map[string]string{"name": "Ada"}, "Hi Ada"},
+		{"now", "Sent {{now}}", nil, "Sent 2024-06-03T14:30:00Z"},
+		{"date", "On {{date}}", nil, "On 2024-06-03"},
+		{"quoted date format", `{{date format="Jan 2, 2006 at 3:04pm"}}`, nil, "Jun 3, 2024 at 2:30pm"},
+		{"bare date format", "{{date format=02/01/2006}}", nil, "03/06/2024"},
+		{"recipient time zone", "{{date format=15:04}}", map[string]string{"timezone": "Asia/Tokyo"}, "23:30"},
+		{"template data wins", "{{date}}", map[string]string{"date": "tomorrow"}, "tomorrow"},
+		{"unknown variables are kept", "{{unknown}} {{date format=}}", nil, "{{unknown}} {{date format=}}"},
+		{"no placeholders", "Plain text", nil, "Plain text"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Render(tt.text, tt.data, now))
+		})
+	}
+}
+
+func TestCompile(t *testing.T) {
+	now := time.Date(2024, 6, 3, 14, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		text string
+		data map[string]string
+		want string
+	}{
+		{"extra brace", "{{{name}}}", map[string]string{"name": "Ada"}, "{Ada}"},
+		{"unclosed placeholder", "{{a {{name}}", map[string]string{"name": "Ada"}, "{{a Ada"},
+		{"empty value", "[{{name}}]", map[string]string{"name": ""}, "[]"},
+		{"values are not rendered again", "{{a}}", map[string]string{"a": "{{b}}", "b": "x"}, "{{b}}"},
+		{"no closing braces", "Hi {{name", map[string]string{"name": "Ada"}, "Hi {{name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Compile(tt.text).Render(tt.data, now))
+		})
+	}
+}
+
+func TestCache(t *testing.T) {
+	cache := NewCache(2)
+
+	first := cache.Get("welcome@1/subject", "Hi {{name}}")
+	assert.Same(t, first, cache.Get("welcome@1/subject", "Hi {{name}}"))
+
+	// Changed text under the same key is compiled again
+	changed := cache.Get("welcome@1/subject", "Hello {{name}}")
+	assert.NotSame(t, first, changed)
+	assert.Equal(t, "Hello Ada", changed.Render(map[string]string{"name": "Ada"}, time.Now()))
+
+	cache.Get("welcome@1/body", "Body")
+	assert.Equal(t, 2, cache.Len())
+	cache.Get("welcome@2/subject", "Hi {{name}}") // full: emptied first
+	assert.Equal(t, 1, cache.Len())
+}
+
+func TestWithRecipient(t *testing.T) {
+	data := map[string]string{"name": "Ada"}
+
+	withDomain := WithRecipient(data, "Ada@Example.COM")
+	assert.Equal(t, "example.com", withDomain[VarRecipientDomain])
+	assert.NotContains(t, data, VarRecipientDomain) // the caller's data is not modified
+
+	// SMS and push recipients fall back to the email variable
+	assert.Equal(t, "example.org", WithRecipient(map[string]string{"email": "ada@example.org"}, "+14155550123")[VarRecipientDomain])
+	assert.NotContains(t, WithRecipient(data, "+14155550123"), VarRecipientDomain)
+
+	// Template data wins
+	assert.Equal(t, "custom", WithRecipient(map[string]string{VarRecipientDomain: "custom"}, "ada@example.com")[VarRecipientDomain])
+}
+
+// The render benchmarks compare rendering a bulk send's message per
+// recipient: replacing each variable in turn, compiling the template per
+// message, and compiling it once.
+
+func BenchmarkRender_ReplaceAll(b *testing.B) {
+	text, data := createBenchmarkTemplate()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		result := text
+		for key, value := range data {
+			result = strings.ReplaceAll(result, fmt.Sprintf("{{%s}}", key), value)
+		}
+	}
+}
+
+func BenchmarkRender_Uncached(b *testing.B) {
+	text, data := createBenchmarkTemplate()
+	now := time.Now()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Render(text, data, now)
+	}
+}
+
+func BenchmarkRender_Compiled(b *testing.B) {
+	text, data := createBenchmarkTemplate()
+	now := time.Now()
+	template := Compile(text)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		template.Render(data, now)
+	}
+}
+
+func BenchmarkRender_Cached(b *testing.B) {
+	text, data := createBenchmarkTemplate()
+	now := time.Now()
+	cache := NewCache(0)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		cache.Get("receipt@1/body", text).Render(data, now)
+	}
+}
+
+// Helper functions
+
+func createBenchmarkTemplate() (string, map[string]string) {
+	data := make(map[string]string)
+	var text strings.Builder
+	text.WriteString("Hi {{name}}, thanks for your order on {{date format=\"Jan 2\"}}.\n")
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("item_%d", i)
+		data[key] = fmt.Sprintf("Item number %d", i)
+		fmt.Fprintf(&text, "- {{%s}}: some description of the line item\n", key)
+	}
+	data["name"] = "Ada"
+	text.WriteString("Questions? Reply to this email or visit our help center.")
+	return text.String(), data
+}
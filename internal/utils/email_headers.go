@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"fmt"
+	"net/textproto"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// maxHeaderLineLength is the longest line RFC 5322 allows, which bounds a
+// header's name and value
+const maxHeaderLineLength = 998
+
+// allowedEmailHeaders are the custom headers callers may set besides X-
+// headers. Headers the service sets itself, such as From, Subject and
+// Message-ID, and headers that change how the message is parsed, such as
+// Content-Type, are not allowed.
+var allowedEmailHeaders = map[string]bool{
+	"Auto-Submitted":        true,
+	"Comments":              true,
+	"Importance":            true,
+	"Keywords":              true,
+	"List-Help":             true,
+	"List-Id":               true,
+	"List-Unsubscribe":      true,
+	"List-Unsubscribe-Post": true,
+	"Precedence":            true,
+	"Priority":              true,
+	"Sensitivity":           true,
+}
+
+// deniedXHeaders are X- headers that mail servers and clients trust, so
+// callers may not set them
+var deniedXHeaders = map[string]bool{
+	"X-Original-To":        true,
+	"X-Originating-Ip":     true,
+	"X-Forwarded-To":       true,
+	"X-Forwarded-For":      true,
+	"X-Spam-Status":        true,
+	"X-Spam-Flag":          true,
+	"X-Spam-Score":         true,
+	"X-Virus-Scanned":      true,
+	"X-Authenticated-User": true,
+}
+
+// headerNameRegex matches RFC 5322 field names: printable ASCII without colons
+var headerNameRegex = regexp.MustCompile(`^[!-9;-~]+$`)
+
+// messageIDRegex matches an RFC 5322 msg-id
+var messageIDRegex = regexp.MustCompile(`^<[^<>@\s]+@[^<>@\s]+>$`)
+
+// ValidateEmailHeaders checks custom email headers against the allowlist:
+// X- headers and the headers in allowedEmailHeaders, with values free of line
+// breaks so they cannot inject further headers
+func ValidateEmailHeaders(headers map[string]string) error {
+	for name, value := range headers {
+		if !headerNameRegex.MatchString(name) {
+			return errors.NewValidationError("headers", fmt.Sprintf("invalid header name: %q", name))
+		}
+		if len(name)+len(value)+2 > maxHeaderLineLength {
+			return errors.NewValidationError("headers", fmt.Sprintf("header %s is too long", name))
+		}
+		if strings.ContainsAny(value, "\r\n\x00") {
+			return errors.NewValidationError("headers", fmt.Sprintf("header %s must not contain line breaks", name))
+		}
+
+		canonical := textproto.CanonicalMIMEHeaderKey(name)
+		if strings.HasPrefix(canonical, "X-") && !deniedXHeaders[canonical] {
+			continue
+		}
+		if !allowedEmailHeaders[canonical] {
+			return errors.NewValidationError("headers", fmt.Sprintf("header %s is not allowed", name))
+		}
+	}
+	return nil
+}
+
+// GenerateMessageID generates an RFC 5322 Message-ID for a notification,
+// qualified with the domain of the sender address
+func GenerateMessageID(id uuid.UUID, from string) string {
+	domain := "localhost"
+	from = strings.TrimSuffix(strings.TrimSpace(from), ">")
+	if at := strings.LastIndex(from, "@"); at >= 0 && at < len(from)-1 {
+		domain = strings.ToLower(from[at+1:])
+	}
+	return fmt.Sprintf("<%s@%s>", id.String(), domain)
+}
+
+// ValidateMessageID validates the format of a Message-ID, such as the
+// In-Reply-To of a follow-up email
+func ValidateMessageID(field, messageID string) error {
+	if !messageIDRegex.MatchString(messageID) {
+		return errors.NewValidationError(field, fmt.Sprintf("invalid message ID: %q", messageID))
+	}
+	return nil
+}
+
+// ValidateEmailThreading validates the In-Reply-To and References of an email
+func ValidateEmailThreading(inReplyTo string, references []string) error {
+	if inReplyTo != "" {
+		if err := ValidateMessageID("in_reply_to", inReplyTo); err != nil {
+			return err
+		}
+	}
+	for _, reference := range references {
+		if err := ValidateMessageID("references", reference); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ThreadReferences returns the References of a reply: the references of the
+// message replied to followed by its Message-ID, as RFC 5322 recommends
+func ThreadReferences(inReplyTo string, references []string) []string {
+	if inReplyTo == "" {
+		return references
+	}
+	for _, reference := range references {
+		if reference == inReplyTo {
+			return references
+		}
+	}
+	return append(append([]string(nil), references...), inReplyTo)
+}
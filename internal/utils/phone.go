@@ -0,0 +1,141 @@
+package utils
+
+import "strings"
+
+// callingCodes maps international calling codes to the country codes used
+// for SMS pricing and validation
+var callingCodes = map[string]string{
+	"1":   "US", // North American Numbering Plan; Canadian area codes resolve to CA
+	"33":  "FR",
+	"44":  "UK",
+	"49":  "DE",
+	"52":  "MX",
+	"55":  "BR",
+	"60":  "MY",
+	"61":  "AU",
+	"63":  "PH",
+	"65":  "SG",
+	"66":  "TH",
+	"81":  "JP",
+	"82":  "KR",
+	"91":  "IN",
+	"852": "HK",
+}
+
+// canadianAreaCodes are the NANP area codes assigned to Canada
+var canadianAreaCodes = map[string]bool{
+	"204": true, "226": true, "236": true, "249": true, "250": true, "263": true,
+	"289": true, "306": true, "343": true, "354": true, "365": true, "367": true,
+	"368": true, "382": true, "403": true, "416": true, "418": true, "428": true,
+	"431": true, "437": true, "438": true, "450": true, "468": true, "474": true,
+	"506": true, "514": true, "519": true, "548": true, "579": true, "581": true,
+	"584": true, "587": true, "604": true, "613": true, "639": true, "647": true,
+	"672": true, "683": true, "705": true, "709": true, "742": true, "753": true,
+	"778": true, "780": true, "782": true, "807": true, "819": true, "825": true,
+	"867": true, "873": true, "879": true, "902": true, "905": true,
+}
+
+// CountryFromPhoneNumber infers the country of a phone number in
+// international format ("+44 7911 123456" or "0044 7911 123456") from its
+// calling code. It returns "" for numbers in national format and for calling
+// codes it does not know. A "+1" number must have ten digits after the code,
+// as every NANP number does.
+func CountryFromPhoneNumber(phoneNumber string) string {
+	digits, international := internationalDigits(phoneNumber)
+	if !international {
+		return ""
+	}
+
+	for length := 3; length >= 1; length-- {
+		if len(digits) <= length {
+			continue
+		}
+		country, ok := callingCodes[digits[:length]]
+		if !ok {
+			continue
+		}
+
+		national := digits[length:]
+		if country == "US" {
+			if len(national) != 10 {
+				return ""
+			}
+			if canadianAreaCodes[national[:3]] {
+				return "CA"
+			}
+		}
+		return country
+	}
+	return ""
+}
+
+// NationalNumber returns the digits of a phone number without the calling
+// code of its country, so per-country length rules apply to numbers in
+// either format. Numbers in national format, or whose calling code belongs
+// to another country, are returned as digits only.
+func NationalNumber(phoneNumber, countryCode string) string {
+	digits, international := internationalDigits(phoneNumber)
+	if !international {
+		return digits
+	}
+
+	countryCode = strings.ToUpper(countryCode)
+	for code, country := range callingCodes {
+		if (country == countryCode || (code == "1" && countryCode == "CA")) && strings.HasPrefix(digits, code) {
+			return digits[len(code):]
+		}
+	}
+	return digits
+}
+
+// E164 returns a phone number in E.164 format, "+" and digits. Numbers in
+// national format take the calling code of the country, without their
+// trunk prefix. It returns "" for national numbers of a country whose
+// calling code it does not know.
+func E164(phoneNumber, countryCode string) string {
+	digits, international := internationalDigits(phoneNumber)
+	if international {
+		return "+" + digits
+	}
+
+	countryCode = strings.ToUpper(countryCode)
+	for code, country := range callingCodes {
+		if country == countryCode || (code == "1" && countryCode == "CA") {
+			if code == "1" && len(digits) == 11 {
+				digits = strings.TrimPrefix(digits, "1")
+			} else if code != "1" {
+				digits = strings.TrimPrefix(digits, "0")
+			}
+			return "+" + code + digits
+		}
+	}
+	return ""
+}
+
+// internationalDigits returns the digits of a phone number without
+// formatting or the international prefix, and whether it had one
+func internationalDigits(phoneNumber string) (string, bool) {
+	number := strings.TrimSpace(phoneNumber)
+	international := strings.HasPrefix(number, "+") || strings.HasPrefix(number, "00")
+	if strings.HasPrefix(number, "00") {
+		number = number[2:]
+	}
+
+	var digits strings.Builder
+	for _, r := range number {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	return digits.String(), international
+}
+
+// ResolveCountry returns the country of a phone number: the country code
+// when one is given, which overrides inference, or the one inferred from the
+// number otherwise
+func ResolveCountry(phoneNumber, countryCode string) string {
+	if countryCode != "" {
+		return strings.ToUpper(countryCode)
+	}
+	return CountryFromPhoneNumber(phoneNumber)
+}
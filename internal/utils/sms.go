@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"strings"
+	"unicode/utf16"
+)
+
+// GSM 03.38 characters. Extension characters take an escape septet plus their own.
+const (
+	gsm7Basic     = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+	gsm7Extension = "\f^{}\\[~]|€"
+)
+
+// SMS segment sizes: a single message, and each part of a concatenated one
+// after its user data header
+const (
+	gsm7SingleSegment = 160
+	gsm7MultiSegment  = 153
+	ucs2SingleSegment = 70
+	ucs2MultiSegment  = 67
+)
+
+// CountSMSSegments returns the number of segments a carrier bills for a
+// message. Messages of GSM-7 characters count septets; any other character,
+// or unicode set, switches the whole message to UCS-2, counted in UTF-16
+// code units so emoji take two.
+func CountSMSSegments(message string, unicode bool) int {
+	length, single, multi := 0, gsm7SingleSegment, gsm7MultiSegment
+	if septets, ok := gsm7Septets(message); ok && !unicode {
+		length = septets
+	} else {
+		length = len(utf16.Encode([]rune(message)))
+		single, multi = ucs2SingleSegment, ucs2MultiSegment
+	}
+
+	if length <= single {
+		return 1
+	}
+	return (length + multi - 1) / multi
+}
+
+// gsm7Septets returns the septets a message takes in the GSM-7 alphabet, and
+// false when it has characters outside it
+func gsm7Septets(message string) (int, bool) {
+	septets := 0
+	for _, r := range message {
+		switch {
+		case strings.ContainsRune(gsm7Basic, r):
+			septets++
+		case strings.ContainsRune(gsm7Extension, r):
+			septets += 2
+		default:
+			return 0, false
+		}
+	}
+	return septets, true
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Characters of emoji sequences
+const (
+	zeroWidthJoiner       = '\u200d'
+	emojiModifierLow      = '\U0001F3FB' // skin tones
+	emojiModifierHigh     = '\U0001F3FF'
+	tagLow                = '\U000E0020' // subdivision flags such as England's
+	tagHigh               = '\U000E007F'
+	regionalIndicatorLow  = '\U0001F1E6' // pairs form country flags
+	regionalIndicatorHigh = '\U0001F1FF'
+)
+
+// TruncateBytes returns the longest prefix of s of at most maxBytes bytes
+// that does not split a character. Characters are user-perceived: a letter
+// with its combining accents, or an emoji with its skin tone, variation
+// selector and the emoji joined to it, such as a family or a flag.
+func TruncateBytes(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	end := 0
+	for end < len(s) {
+		next := end + graphemeLength(s[end:])
+		if next > maxBytes {
+			break
+		}
+		end = next
+	}
+	return s[:end]
+}
+
+// graphemeLength returns the length in bytes of the user-perceived character
+// s starts with. It covers combining marks, emoji sequences and flags, but
+// not every rule of Unicode's segmentation, e.g. Hangul syllables of jamo.
+func graphemeLength(s string) int {
+	r, size := utf8.DecodeRuneInString(s)
+	i := size
+	if r == '\r' && i < len(s) && s[i] == '\n' {
+		return i + 1
+	}
+	if isRegionalIndicator(r) && i < len(s) {
+		if next, n := utf8.DecodeRuneInString(s[i:]); isRegionalIndicator(next) {
+			i += n
+		}
+	}
+
+	previous := r
+	for i < len(s) {
+		next, n := utf8.DecodeRuneInString(s[i:])
+		if !extendsGrapheme(next) && previous != zeroWidthJoiner {
+			break
+		}
+		i += n
+		previous = next
+	}
+	return i
+}
+
+// extendsGrapheme reports whether r belongs to the character before it
+func extendsGrapheme(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc) || // accents, keycaps and variation selectors
+		r == zeroWidthJoiner ||
+		(r >= emojiModifierLow && r <= emojiModifierHigh) ||
+		(r >= tagLow && r <= tagHigh)
+}
+
+// isRegionalIndicator reports whether r is one of the letters flags are made of
+func isRegionalIndicator(r rune) bool {
+	return r >= regionalIndicatorLow && r <= regionalIndicatorHigh
+}
@@ -2,12 +2,16 @@ package utils
 
 import (
 	"fmt"
+	"maps"
+	"net"
+	"net/url"
 	"regexp"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/validation"
 	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
 )
 
@@ -89,12 +93,103 @@ func ValidateDeviceToken(token string, platform string) error {
 	return nil
 }
 
+// DefaultWebhookHosts are the hosts chat webhooks may be sent to when no
+// allowlist is configured
+var DefaultWebhookHosts = []string{"hooks.slack.com", "*.webhook.office.com"}
+
+// ValidateWebhookURL validates the format of a chat webhook URL. Which
+// hosts webhooks may be sent to is the chat provider's to decide; see
+// ValidateWebhookTarget.
+func ValidateWebhookURL(webhookURL string) error {
+	if webhookURL == "" {
+		return errors.NewValidationError("webhook_url", "webhook URL is required")
+	}
+
+	parsed, err := url.Parse(webhookURL)
+	if err != nil || parsed.Hostname() == "" {
+		return errors.NewValidationError("webhook_url", "invalid webhook URL format")
+	}
+
+	if parsed.Scheme != "https" && parsed.Scheme != "http" {
+		return errors.NewValidationError("webhook_url", "webhook URL must use http or https")
+	}
+
+	return nil
+}
+
+// ValidateWebhookTarget checks that a chat webhook URL may be sent to: it
+// must use https, its host must be in the allowlist, DefaultWebhookHosts
+// when empty, and a host given as an IP address must be public. With
+// allowPrivate, http and private addresses are accepted, e.g. for a
+// self-hosted chat server.
+func ValidateWebhookTarget(webhookURL string, allowedHosts []string, allowPrivate bool) error {
+	if err := ValidateWebhookURL(webhookURL); err != nil {
+		return err
+	}
+
+	parsed, _ := url.Parse(webhookURL)
+	if parsed.Scheme != "https" && !allowPrivate {
+		return errors.NewValidationError("webhook_url", "webhook URL must use https")
+	}
+
+	if len(allowedHosts) == 0 {
+		allowedHosts = DefaultWebhookHosts
+	}
+	host := parsed.Hostname()
+	if !WebhookHostAllowed(host, allowedHosts) {
+		return errors.NewValidationError("webhook_url", fmt.Sprintf("webhook host %s is not allowed", host))
+	}
+
+	if ip := net.ParseIP(host); ip != nil && !IsPublicIP(ip) && !allowPrivate {
+		return errors.NewValidationError("webhook_url", "webhook URL must not point to a private address")
+	}
+	return nil
+}
+
+// WebhookHostAllowed reports whether a host is in an allowlist, where
+// "*.example.com" matches any subdomain of example.com
+func WebhookHostAllowed(host string, allowed []string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(pattern)
+		if suffix, wildcard := strings.CutPrefix(pattern, "*"); wildcard {
+			if strings.HasSuffix(host, suffix) && len(host) > len(suffix) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPublicIP reports whether an address is routable on the internet rather
+// than private, loopback, link-local or otherwise reserved
+func IsPublicIP(ip net.IP) bool {
+	if ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	// Carrier-grade NAT, which cloud providers also use internally
+	if ip4 := ip.To4(); ip4 != nil && ip4[0] == 100 && ip4[1]&0xc0 == 64 {
+		return false
+	}
+	return true
+}
+
+// MaxProviderOptions is the most provider options a request may carry
+const MaxProviderOptions = 32
+
 // ValidateNotificationRequest validates a notification request
 func ValidateNotificationRequest(request *models.NotificationRequest) error {
 	if request == nil {
 		return errors.NewValidationError("request", "notification request is required")
 	}
 
+	if err := validation.Struct(request); err != nil {
+		return err
+	}
+
 	// Validate basic fields
 	if request.Type == "" {
 		return errors.NewValidationError("type", "notification type is required")
@@ -113,6 +208,15 @@ func ValidateNotificationRequest(request *models.NotificationRequest) error {
 		return errors.NewValidationError("priority", "invalid priority level")
 	}
 
+	if request.ExpiresAt != nil && request.ScheduledAt != nil && !request.ExpiresAt.After(*request.ScheduledAt) {
+		return errors.NewValidationError("expires_at", "expiry must be after the scheduled time")
+	}
+
+	// The provider checks the options themselves when it is chosen
+	if len(request.ProviderOptions) > MaxProviderOptions {
+		return errors.NewValidationError("provider_options", fmt.Sprintf("at most %d provider options are allowed", MaxProviderOptions))
+	}
+
 	// Type-specific validation
 	switch request.Type {
 	case models.NotificationTypeEmail:
@@ -121,6 +225,10 @@ func ValidateNotificationRequest(request *models.NotificationRequest) error {
 		return validateSMSRequest(request)
 	case models.NotificationTypePush:
 		return validatePushRequest(request)
+	case models.NotificationTypeChat:
+		return validateChatRequest(request)
+	case models.NotificationTypeVoice:
+		return validateVoiceRequest(request)
 	default:
 		return errors.NewValidationError("type", "unsupported notification type")
 	}
@@ -163,6 +271,14 @@ func validateEmailRequest(request *models.NotificationRequest) error {
 				return errors.NewValidationError("reply_to", "invalid reply-to email address")
 			}
 		}
+
+		if err := ValidateEmailHeaders(request.EmailData.Headers); err != nil {
+			return err
+		}
+
+		if err := ValidateEmailThreading(request.EmailData.InReplyTo, request.EmailData.References); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -202,6 +318,50 @@ func validatePushRequest(request *models.NotificationRequest) error {
 	return ValidateDeviceToken(deviceToken, platform)
 }
 
+// validateChatRequest validates chat webhook-specific fields. A request
+// without a webhook URL is sent to the provider's default webhook, which the
+// provider checks.
+func validateChatRequest(request *models.NotificationRequest) error {
+	if webhookURL := ChatWebhookURL(request); webhookURL != "" {
+		return ValidateWebhookURL(webhookURL)
+	}
+	return nil
+}
+
+// ChatWebhookURL returns the webhook a chat request is sent to: its chat
+// data's webhook URL, or its recipient when that is a URL. It is empty when
+// the recipient only names the destination, e.g. "#ops", so the provider's
+// default webhook is used.
+func ChatWebhookURL(request *models.NotificationRequest) string {
+	if request.ChatData != nil && request.ChatData.WebhookURL != "" {
+		return request.ChatData.WebhookURL
+	}
+	return RecipientWebhookURL(request.Recipient)
+}
+
+// RecipientWebhookURL returns a chat recipient when it is a URL, and
+// otherwise an empty string
+func RecipientWebhookURL(recipient string) string {
+	if strings.Contains(recipient, "://") {
+		return recipient
+	}
+	return ""
+}
+
+// validateVoiceRequest validates voice call-specific fields
+func validateVoiceRequest(request *models.NotificationRequest) error {
+	if request.VoiceData == nil || request.VoiceData.CountryCode == "" {
+		return errors.NewValidationError("country_code", "country code is required for voice calls")
+	}
+
+	phoneNumber := request.Recipient
+	if request.VoiceData.PhoneNumber != "" {
+		phoneNumber = request.VoiceData.PhoneNumber
+	}
+
+	return ValidatePhoneNumber(phoneNumber, request.VoiceData.CountryCode)
+}
+
 // IsValidPriority checks if a priority level is valid
 func IsValidPriority(priority models.Priority) bool {
 	switch priority {
@@ -215,7 +375,18 @@ func IsValidPriority(priority models.Priority) bool {
 // IsValidNotificationType checks if a notification type is valid
 func IsValidNotificationType(notificationType models.NotificationType) bool {
 	switch notificationType {
-	case models.NotificationTypeEmail, models.NotificationTypeSMS, models.NotificationTypePush:
+	case models.NotificationTypeEmail, models.NotificationTypeSMS, models.NotificationTypePush, models.NotificationTypeChat,
+		models.NotificationTypeVoice:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsValidCategory checks if a notification category is valid
+func IsValidCategory(category models.Category) bool {
+	switch category {
+	case models.CategoryTransactional, models.CategoryMarketing, models.CategorySecurity:
 		return true
 	default:
 		return false
@@ -225,7 +396,7 @@ func IsValidNotificationType(notificationType models.NotificationType) bool {
 // IsValidNotificationStatus checks if a notification status is valid
 func IsValidNotificationStatus(status models.NotificationStatus) bool {
 	switch status {
-	case models.StatusPending, models.StatusSent, models.StatusDelivered, models.StatusFailed, models.StatusRetrying:
+	case models.StatusPending, models.StatusSent, models.StatusDelivered, models.StatusFailed, models.StatusRetrying, models.StatusExpired:
 		return true
 	default:
 		return false
@@ -254,12 +425,34 @@ func FormatPhoneNumber(phoneNumber string, countryCode string) string {
 	return cleanNumber
 }
 
-// TruncateString truncates a string to a maximum length
+// TruncateString truncates a string to a maximum length in bytes, ending it
+// with "..." and without splitting a character
 func TruncateString(s string, maxLength int) string {
 	if len(s) <= maxLength {
 		return s
 	}
-	return s[:maxLength-3] + "..."
+	return TruncateBytes(s, maxLength-3) + "..."
+}
+
+// CalculateSMSSegments calculates the number of SMS segments a message needs
+func CalculateSMSSegments(message string, unicode bool) int {
+	maxLength := 160
+	if unicode {
+		maxLength = 70
+	}
+
+	length := len(message)
+	if length <= maxLength {
+		return 1
+	}
+
+	// For multi-part messages, each segment is slightly shorter
+	segmentLength := maxLength - 7 // Account for UDH (User Data Header)
+	if unicode {
+		segmentLength = 67
+	}
+
+	return (length + segmentLength - 1) / segmentLength
 }
 
 // IsScheduledNotification checks if a notification is scheduled for the future
@@ -267,10 +460,12 @@ func IsScheduledNotification(notification *models.Notification) bool {
 	return notification.ScheduledAt != nil && notification.ScheduledAt.After(time.Now())
 }
 
-// ShouldRetryNotification determines if a notification should be retried
+// ShouldRetryNotification determines if a notification should be retried.
+// Expired notifications are not retried.
 func ShouldRetryNotification(notification *models.Notification) bool {
 	return notification.Status == models.StatusFailed &&
-		notification.RetryCount < notification.MaxRetries
+		notification.RetryCount < notification.MaxRetries &&
+		!notification.Expired(time.Now())
 }
 
 // CalculateNextRetryTime calculates when to retry a failed notification
@@ -308,6 +503,7 @@ func CreateNotificationFromRequest(request *models.NotificationRequest) *models.
 		Recipient:  request.Recipient,
 		Subject:    request.Subject,
 		Body:       request.Body,
+		Category:   request.Category,
 		Metadata:   request.Metadata,
 		CreatedAt:  now,
 		UpdatedAt:  now,
@@ -318,10 +514,26 @@ func CreateNotificationFromRequest(request *models.NotificationRequest) *models.
 	if request.ScheduledAt != nil {
 		notification.ScheduledAt = request.ScheduledAt
 	}
+	notification.ExpiresAt = request.ExpiresAt
+	notification.CorrelationID = request.CorrelationID
+	if len(request.ProviderOptions) > 0 {
+		notification.ProviderOptions = maps.Clone(request.ProviderOptions)
+	}
 
 	if request.MaxRetries > 0 {
 		notification.MaxRetries = request.MaxRetries
 	}
 
+	if request.EmailData != nil {
+		notification.HTMLBody = request.EmailData.HTMLBody
+	}
+
+	if len(request.TemplateData) > 0 {
+		notification.TemplateData = make(map[string]string, len(request.TemplateData))
+		for key, value := range request.TemplateData {
+			notification.TemplateData[key] = value
+		}
+	}
+
 	return notification
 }
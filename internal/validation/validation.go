@@ -0,0 +1,266 @@
+// Package validation evaluates `validate` struct tags on request types, so
+// the rules declared next to each field are what services and the HTTP layer
+// actually enforce. Supported rules are required, omitempty, min, max, len,
+// oneof, email and url; nested structs, pointers to structs and slices of
+// structs are validated recursively.
+package validation
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// emailPattern matches the address format accepted by utils.ValidateEmailAddress
+var emailPattern = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+
+// rule is a single parsed validate rule, e.g. min=1
+type rule struct {
+	name  string
+	param string
+}
+
+// field is a struct field and its validate rules
+type field struct {
+	index    int
+	name     string // JSON name used in error paths
+	embedded bool   // embedded structs share their parent's path
+	rules    []rule
+}
+
+// fieldCache holds the parsed fields of each struct type
+var fieldCache sync.Map // reflect.Type -> []field
+
+// Struct validates a struct, or pointer to a struct, against its validate
+// tags. It returns a validation error listing every failed field, or nil.
+// A nil pointer is reported as a missing request.
+func Struct(value interface{}) error {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return errors.NewValidationError("request", "request is required")
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return errors.NewValidationError("request", "request must be an object")
+	}
+
+	var failures []errors.FieldError
+	validateStruct(v, "", &failures)
+	if len(failures) > 0 {
+		return errors.NewFieldValidationError(failures...)
+	}
+	return nil
+}
+
+// validateStruct validates the fields of a struct value, appending failures
+func validateStruct(v reflect.Value, prefix string, failures *[]errors.FieldError) {
+	for _, f := range fieldsOf(v.Type()) {
+		value := v.Field(f.index)
+		path := f.name
+		if f.embedded {
+			path = prefix
+		} else if prefix != "" {
+			path = prefix + "." + f.name
+		}
+
+		if failure, failed := checkRules(value, path, f.rules); failed {
+			*failures = append(*failures, failure)
+			continue
+		}
+
+		validateNested(value, path, failures)
+	}
+}
+
+// validateNested descends into struct, pointer and slice values
+func validateNested(value reflect.Value, path string, failures *[]errors.FieldError) {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if !value.IsNil() {
+			validateNested(value.Elem(), path, failures)
+		}
+	case reflect.Struct:
+		validateStruct(value, path, failures)
+	case reflect.Slice, reflect.Array:
+		if !hasStructElements(value.Type()) {
+			return
+		}
+		for i := 0; i < value.Len(); i++ {
+			validateNested(value.Index(i), fmt.Sprintf("%s[%d]", path, i), failures)
+		}
+	}
+}
+
+// hasStructElements reports whether a slice or array holds structs or pointers to structs
+func hasStructElements(t reflect.Type) bool {
+	elem := t.Elem()
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	return elem.Kind() == reflect.Struct
+}
+
+// checkRules applies a field's rules in order, stopping at the first failure
+func checkRules(value reflect.Value, path string, rules []rule) (errors.FieldError, bool) {
+	for _, r := range rules {
+		if r.name == "omitempty" {
+			if isEmpty(value) {
+				return errors.FieldError{}, false
+			}
+			continue
+		}
+
+		if message, ok := check(value, r); !ok {
+			return errors.FieldError{Field: path, Rule: r.name, Message: message}, true
+		}
+	}
+	return errors.FieldError{}, false
+}
+
+// check applies a single rule, returning a message describing a failure
+func check(value reflect.Value, r rule) (string, bool) {
+	switch r.name {
+	case "required":
+		return "is required", !isEmpty(value)
+
+	case "min", "max", "len":
+		limit, _ := strconv.ParseFloat(r.param, 64)
+		size, unit := measure(value)
+		switch r.name {
+		case "min":
+			return fmt.Sprintf("must be at least %s%s", r.param, unit), size >= limit
+		case "max":
+			return fmt.Sprintf("must be at most %s%s", r.param, unit), size <= limit
+		default:
+			return fmt.Sprintf("must be exactly %s%s", r.param, unit), size == limit
+		}
+
+	case "oneof":
+		allowed := strings.Fields(r.param)
+		actual := stringValue(value)
+		for _, option := range allowed {
+			if actual == option {
+				return "", true
+			}
+		}
+		return fmt.Sprintf("must be one of: %s", strings.Join(allowed, ", ")), false
+
+	case "email":
+		return "must be a valid email address", emailPattern.MatchString(value.String())
+
+	case "url":
+		parsed, err := url.Parse(value.String())
+		return "must be a valid URL", err == nil && parsed.Scheme != "" && parsed.Host != ""
+	}
+
+	return "", true
+}
+
+// measure returns the size a min, max or len rule compares, and its unit
+func measure(value reflect.Value) (float64, string) {
+	switch value.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(value.String())), " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(value.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), ""
+	}
+	return 0, ""
+}
+
+// stringValue formats a string or numeric value for comparison with oneof options
+func stringValue(value reflect.Value) string {
+	switch value.Kind() {
+	case reflect.String:
+		return value.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10)
+	}
+	return ""
+}
+
+// isEmpty reports whether a value is missing: zero, or an empty slice or map
+func isEmpty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	}
+	return value.IsZero()
+}
+
+// fieldsOf returns the validated fields of a struct type, parsing tags once per type
+func fieldsOf(t reflect.Type) []field {
+	if cached, exists := fieldCache.Load(t); exists {
+		return cached.([]field)
+	}
+
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		// Unexported embedded structs still promote their exported fields
+		if !sf.IsExported() && !sf.Anonymous {
+			continue
+		}
+
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		embedded := sf.Anonymous && name == ""
+		if name == "" || name == "-" {
+			name = sf.Name
+		}
+
+		fields = append(fields, field{
+			index:    i,
+			name:     name,
+			embedded: embedded,
+			rules:    parseRules(t, sf),
+		})
+	}
+
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+// parseRules parses a field's validate tag, panicking on an unknown rule
+// since that is a programming error in the struct definition
+func parseRules(t reflect.Type, sf reflect.StructField) []rule {
+	tag := sf.Tag.Get("validate")
+	if tag == "" {
+		return nil
+	}
+
+	var rules []rule
+	for _, part := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch name {
+		case "required", "omitempty", "email", "url":
+		case "oneof":
+			if strings.TrimSpace(param) == "" {
+				panic(fmt.Sprintf("validation: oneof on %s.%s needs values", t.Name(), sf.Name))
+			}
+		case "min", "max", "len":
+			if _, err := strconv.ParseFloat(param, 64); err != nil {
+				panic(fmt.Sprintf("validation: %s on %s.%s needs a number", name, t.Name(), sf.Name))
+			}
+		default:
+			panic(fmt.Sprintf("validation: unknown rule %q on %s.%s", name, t.Name(), sf.Name))
+		}
+		rules = append(rules, rule{name: name, param: param})
+	}
+	return rules
+}
@@ -0,0 +1,122 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+type testRecipient struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type testBase struct {
+	ID string `json:"id" validate:"required"`
+}
+
+type testRequest struct {
+	testBase
+	Name       string            `json:"name" validate:"required,min=2,max=5"`
+	Channel    string            `json:"channel" validate:"required,oneof=email sms"`
+	Code       string            `json:"code,omitempty" validate:"omitempty,len=4"`
+	Callback   string            `json:"callback,omitempty" validate:"omitempty,url"`
+	Retries    int               `json:"retries" validate:"max=3"`
+	Recipients []testRecipient   `json:"recipients" validate:"required,min=1"`
+	Primary    *testRecipient    `json:"primary,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+}
+
+func TestStruct_Valid(t *testing.T) {
+	assert.NoError(t, Struct(createTestRequest()))
+
+	request := createTestRequest()
+	assert.NoError(t, Struct(&request))
+}
+
+func TestStruct_ReportsEveryFailedField(t *testing.T) {
+	request := createTestRequest()
+	request.ID = ""
+	request.Name = "x"
+	request.Channel = "fax"
+	request.Code = "12345"
+	request.Callback = "not a url"
+	request.Retries = 4
+	request.Recipients = []testRecipient{{Email: "ok@example.com"}, {Email: "bad"}}
+	request.Primary = &testRecipient{}
+
+	err := Struct(request)
+	require.Error(t, err)
+
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeValidationFailed, notifErr.Code)
+	assert.Equal(t, 400, notifErr.StatusCode)
+	assert.Equal(t, "id", notifErr.Metadata["field"])
+
+	assert.Equal(t, []errors.FieldError{
+		{Field: "id", Rule: "required", Message: "is required"},
+		{Field: "name", Rule: "min", Message: "must be at least 2 characters"},
+		{Field: "channel", Rule: "oneof", Message: "must be one of: email, sms"},
+		{Field: "code", Rule: "len", Message: "must be exactly 4 characters"},
+		{Field: "callback", Rule: "url", Message: "must be a valid URL"},
+		{Field: "retries", Rule: "max", Message: "must be at most 3"},
+		{Field: "recipients[1].email", Rule: "email", Message: "must be a valid email address"},
+		{Field: "primary.email", Rule: "required", Message: "is required"},
+	}, notifErr.Fields)
+}
+
+func TestStruct_SingleFieldMessage(t *testing.T) {
+	request := createTestRequest()
+	request.Recipients = nil
+
+	err := Struct(request)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Validation failed for field 'recipients': is required")
+}
+
+func TestStruct_InvalidInput(t *testing.T) {
+	var request *testRequest
+	assert.Error(t, Struct(request))
+	assert.Error(t, Struct("not a struct"))
+}
+
+func TestStruct_UnknownRulePanics(t *testing.T) {
+	type badRequest struct {
+		Name string `validate:"required,uppercase"`
+	}
+
+	assert.Panics(t, func() {
+		Struct(badRequest{})
+	})
+}
+
+func TestStruct_NotificationRequest(t *testing.T) {
+	err := Struct(&models.NotificationRequest{
+		Type:      "fax",
+		Priority:  models.PriorityNormal,
+		Recipient: "user@example.com",
+		Body:      "Hello",
+		PushData:  &models.PushData{Platform: "windows"},
+	})
+	require.Error(t, err)
+
+	notifErr, _ := errors.AsNotificationError(err)
+	require.Len(t, notifErr.Fields, 2)
+	assert.Equal(t, "type", notifErr.Fields[0].Field)
+	assert.Equal(t, "push_data.platform", notifErr.Fields[1].Field)
+}
+
+// Helper functions
+
+func createTestRequest() testRequest {
+	return testRequest{
+		testBase:   testBase{ID: "req-1"},
+		Name:       "abc",
+		Channel:    "sms",
+		Recipients: []testRecipient{{Email: "user@example.com"}},
+	}
+}
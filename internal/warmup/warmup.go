@@ -0,0 +1,294 @@
+// Package warmup ramps up the daily email volume of new sending domains and
+// IPs over a schedule of weeks, which protects their reputation with mailbox
+// providers. Marketing email over a day's limit is deferred to a later day;
+// transactional and security email is always sent but counts toward the limit.
+package warmup
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pipeline"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// MiddlewareName is the name the scheduler's middleware is registered under
+const MiddlewareName = "warmup"
+
+// MetadataSendingIP is the request metadata key naming the IP a send goes
+// out from, for warm-ups of IPs rather than domains
+const MetadataSendingIP = "sending_ip"
+
+// MetadataLimited names the warming domain on errors for marketing email
+// over the day's limit that could not be deferred
+const MetadataLimited = "warmup_limited"
+
+const day = 24 * time.Hour
+
+// DefaultDailyLimits is the daily limit of each week of a warm-up without a
+// schedule of its own; from the ninth week, volume is not limited
+var DefaultDailyLimits = []int{50, 100, 500, 1000, 5000, 10000, 25000, 50000}
+
+// Status is how far a warm-up has progressed
+type Status struct {
+	Domain     string `json:"domain"`
+	Week       int    `json:"week"`        // 1-based week of the warm-up
+	DailyLimit int    `json:"daily_limit"` // 0 once the warm-up is complete
+	SentToday  int    `json:"sent_today"`
+	Complete   bool   `json:"complete"`
+}
+
+// domain is a validated warm-up with its volume today
+type domain struct {
+	name        string
+	start       time.Time // midnight UTC of the first day
+	dailyLimits []int
+	today       time.Time
+	sent        int
+}
+
+// Scheduler enforces the daily limits of warm-ups. Days are UTC.
+type Scheduler struct {
+	mu      sync.Mutex
+	domains map[string]*domain
+	timers  map[*time.Timer]bool
+	sender  pipeline.Handler
+	logger  interfaces.Logger
+	now     func() time.Time
+	stopped bool
+}
+
+// NewScheduler creates a scheduler for the configured warm-ups
+func NewScheduler(cfg config.WarmupConfig, logger interfaces.Logger) (*Scheduler, error) {
+	s := &Scheduler{
+		domains: make(map[string]*domain),
+		timers:  make(map[*time.Timer]bool),
+		logger:  logger,
+		now:     time.Now,
+	}
+
+	for i, warmup := range cfg.Domains {
+		field := fmt.Sprintf("domains[%d]", i)
+		name := strings.ToLower(strings.TrimSpace(warmup.Domain))
+		if name == "" {
+			return nil, errors.NewValidationError(field, "warm-up domain is required")
+		}
+		if _, exists := s.domains[name]; exists {
+			return nil, errors.NewValidationError(field, fmt.Sprintf("duplicate warm-up domain: %s", name))
+		}
+		if warmup.Start.IsZero() {
+			return nil, errors.NewValidationError(field, "warm-up start date is required")
+		}
+
+		dailyLimits := warmup.DailyLimits
+		if len(dailyLimits) == 0 {
+			dailyLimits = DefaultDailyLimits
+		}
+		for _, limit := range dailyLimits {
+			if limit <= 0 {
+				return nil, errors.NewValidationError(field, "daily limits must be positive")
+			}
+		}
+
+		s.domains[name] = &domain{
+			name:        name,
+			start:       warmup.Start.UTC().Truncate(day),
+			dailyLimits: append([]int(nil), dailyLimits...),
+		}
+	}
+
+	return s, nil
+}
+
+// SetSender sets the handler that sends deferred email, typically the
+// dispatcher's SendNotification. Deferred email passes the limits again.
+func (s *Scheduler) SetSender(sender pipeline.Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sender = sender
+}
+
+// Middleware returns the send middleware enforcing the limits. Register it
+// at pipeline.StageRateLimit under MiddlewareName.
+func (s *Scheduler) Middleware() pipeline.Middleware {
+	return func(next pipeline.Handler) pipeline.Handler {
+		return func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+			if request.Type != models.NotificationTypeEmail {
+				return next(ctx, request)
+			}
+
+			warming, reserved, retryAt := s.reserve(request)
+			if warming != nil && !reserved {
+				return s.postpone(request, warming, retryAt)
+			}
+
+			response, err := next(ctx, request)
+			if err != nil && reserved {
+				s.release(warming)
+			}
+			return response, err
+		}
+	}
+}
+
+// Status returns the progress of a domain's warm-up
+func (s *Scheduler) Status(name string) (Status, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, exists := s.domains[strings.ToLower(name)]
+	if !exists {
+		return Status{}, false
+	}
+
+	now := s.now()
+	s.rollover(d, now)
+	week, limit := d.limit(now)
+	return Status{
+		Domain:     d.name,
+		Week:       week,
+		DailyLimit: limit,
+		SentToday:  d.sent,
+		Complete:   limit == 0,
+	}, true
+}
+
+// Pending returns the number of deferred sends waiting to be sent
+func (s *Scheduler) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.timers)
+}
+
+// Stop cancels deferred sends that are still waiting
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for timer := range s.timers {
+		timer.Stop()
+		delete(s.timers, timer)
+	}
+	s.stopped = true
+}
+
+// reserve counts a send against its warm-up. It returns the warm-up, or nil
+// when the send is not warming up, whether the send was counted, and when
+// uncounted marketing email may go out.
+func (s *Scheduler) reserve(request *models.NotificationRequest) (*domain, bool, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.domainOf(request)
+	if d == nil {
+		return nil, false, time.Time{}
+	}
+
+	now := s.now()
+	s.rollover(d, now)
+	if _, limit := d.limit(now); limit > 0 && d.sent >= limit && request.Category == models.CategoryMarketing {
+		return d, false, now.UTC().Truncate(day).Add(day)
+	}
+
+	d.sent++
+	return d, true, time.Time{}
+}
+
+// release uncounts a reserved send that failed
+func (s *Scheduler) release(d *domain) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if d.sent > 0 {
+		d.sent--
+	}
+}
+
+// postpone defers marketing email over the day's limit to retryAt
+func (s *Scheduler) postpone(request *models.NotificationRequest, d *domain, retryAt time.Time) (*models.NotificationResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, limit := d.limit(s.now())
+	message := fmt.Sprintf("warm-up limit of %d emails a day reached for %s", limit, d.name)
+
+	if s.sender == nil || s.stopped {
+		retryAfter := int(retryAt.Sub(s.now()).Seconds()) + 1
+		return nil, errors.NewNotificationError(errors.ErrorCodeRateLimited, message).
+			WithMetadata(MetadataLimited, d.name).
+			WithMetadata("retry_after", strconv.Itoa(retryAfter))
+	}
+
+	deferred := *request
+	deferred.ScheduledAt = &retryAt
+	sender := s.sender
+
+	var timer *time.Timer
+	timer = time.AfterFunc(retryAt.Sub(s.now()), func() {
+		s.mu.Lock()
+		delete(s.timers, timer)
+		s.mu.Unlock()
+
+		if _, err := sender(context.Background(), &deferred); err != nil {
+			s.logger.Errorf("Deferred warm-up email from %s failed: %v", d.name, err)
+		}
+	})
+	s.timers[timer] = true
+
+	s.logger.Infof("%s; marketing email deferred to %s", message, retryAt.Format(time.RFC3339))
+	return &models.NotificationResponse{
+		Status:  models.StatusPending,
+		Message: fmt.Sprintf("%s; deferred to %s", message, retryAt.Format(time.RFC3339)),
+	}, nil
+}
+
+// domainOf returns the warm-up a send counts toward: its sending IP's when
+// that is warming up, otherwise its from address domain's
+func (s *Scheduler) domainOf(request *models.NotificationRequest) *domain {
+	if ip := request.Metadata[MetadataSendingIP]; ip != "" {
+		if d, exists := s.domains[ip]; exists {
+			return d
+		}
+	}
+	if request.EmailData == nil {
+		return nil
+	}
+
+	from := strings.TrimSuffix(strings.TrimSpace(request.EmailData.From), ">")
+	at := strings.LastIndex(from, "@")
+	if at < 0 {
+		return nil
+	}
+	return s.domains[strings.ToLower(from[at+1:])]
+}
+
+// rollover resets the volume of a warm-up when a new day starts
+func (s *Scheduler) rollover(d *domain, now time.Time) {
+	if today := now.UTC().Truncate(day); !today.Equal(d.today) {
+		d.today = today
+		d.sent = 0
+	}
+}
+
+// limit returns the 1-based week of a warm-up and its daily limit, 0 once
+// the warm-up is complete. Days before the start use the first week's limit.
+func (d *domain) limit(now time.Time) (int, int) {
+	week := 0
+	if elapsed := now.UTC().Truncate(day).Sub(d.start); elapsed > 0 {
+		week = int(elapsed / (7 * day))
+	}
+
+	if week >= len(d.dailyLimits) {
+		return week + 1, 0
+	}
+	return week + 1, d.dailyLimits[week]
+}
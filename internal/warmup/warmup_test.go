@@ -0,0 +1,176 @@
+package warmup
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/models"
+	"github.com/nareshkumar-microsoft/notificationService/internal/pipeline"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+var testStart = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+func TestNewScheduler_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		domains []config.WarmupDomain
+	}{
+		{"missing domain", []config.WarmupDomain{{Start: testStart}}},
+		{"missing start", []config.WarmupDomain{{Domain: "news.example.com"}}},
+		{"zero limit", []config.WarmupDomain{{Domain: "news.example.com", Start: testStart, DailyLimits: []int{10, 0}}}},
+		{"duplicate domain", []config.WarmupDomain{{Domain: "news.example.com", Start: testStart}, {Domain: "News.example.com", Start: testStart}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewScheduler(config.WarmupConfig{Domains: tt.domains}, utils.NewSimpleLogger("info"))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestScheduler_LimitsMarketingEmail(t *testing.T) {
+	scheduler, _ := createTestScheduler(t, config.WarmupDomain{Domain: "news.example.com", Start: testStart, DailyLimits: []int{2, 4}})
+	send := scheduler.Middleware()(createTestHandler(nil))
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := send(ctx, createTestRequest("news@news.example.com", models.CategoryMarketing))
+		require.NoError(t, err)
+	}
+
+	// Without a sender, marketing email over the limit is rejected
+	_, err := send(ctx, createTestRequest("news@news.example.com", models.CategoryMarketing))
+	require.Error(t, err)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeRateLimited, notifErr.Code)
+	assert.Equal(t, "news.example.com", notifErr.Metadata[MetadataLimited])
+	assert.Equal(t, fmt.Sprint(15*60*60+1), notifErr.Metadata["retry_after"])
+
+	// Transactional email is sent over the limit but still counted
+	_, err = send(ctx, createTestRequest("Billing <billing@News.example.com>", models.CategoryTransactional))
+	require.NoError(t, err)
+	status, ok := scheduler.Status("news.example.com")
+	require.True(t, ok)
+	assert.Equal(t, Status{Domain: "news.example.com", Week: 1, DailyLimit: 2, SentToday: 3}, status)
+
+	// Other domains and channels are not limited
+	_, err = send(ctx, createTestRequest("news@example.org", models.CategoryMarketing))
+	assert.NoError(t, err)
+	_, err = send(ctx, &models.NotificationRequest{Type: models.NotificationTypeSMS, Recipient: "+14155550123", Category: models.CategoryMarketing})
+	assert.NoError(t, err)
+}
+
+func TestScheduler_RampsOverWeeks(t *testing.T) {
+	scheduler, clock := createTestScheduler(t, config.WarmupDomain{Domain: "news.example.com", Start: testStart, DailyLimits: []int{2, 4}})
+
+	*clock = testStart.Add(8*24*time.Hour + time.Hour)
+	status, _ := scheduler.Status("news.example.com")
+	assert.Equal(t, 2, status.Week)
+	assert.Equal(t, 4, status.DailyLimit)
+
+	*clock = testStart.Add(14 * 24 * time.Hour)
+	status, _ = scheduler.Status("news.example.com")
+	assert.True(t, status.Complete)
+	assert.Equal(t, 0, status.DailyLimit)
+
+	// Volume is counted per day
+	*clock = testStart.Add(9 * time.Hour)
+	send := scheduler.Middleware()(createTestHandler(nil))
+	for i := 0; i < 2; i++ {
+		_, err := send(context.Background(), createTestRequest("news@news.example.com", models.CategoryMarketing))
+		require.NoError(t, err)
+	}
+	*clock = clock.Add(24 * time.Hour)
+	_, err := send(context.Background(), createTestRequest("news@news.example.com", models.CategoryMarketing))
+	assert.NoError(t, err)
+}
+
+func TestScheduler_SendingIP(t *testing.T) {
+	scheduler, _ := createTestScheduler(t, config.WarmupDomain{Domain: "203.0.113.7", Start: testStart, DailyLimits: []int{1}})
+	send := scheduler.Middleware()(createTestHandler(nil))
+
+	request := createTestRequest("news@example.org", models.CategoryMarketing)
+	request.Metadata = map[string]string{MetadataSendingIP: "203.0.113.7"}
+	_, err := send(context.Background(), request)
+	require.NoError(t, err)
+	_, err = send(context.Background(), request)
+	assert.Error(t, err)
+}
+
+func TestScheduler_FailedSendsAreNotCounted(t *testing.T) {
+	scheduler, _ := createTestScheduler(t, config.WarmupDomain{Domain: "news.example.com", Start: testStart, DailyLimits: []int{1}})
+
+	failing := scheduler.Middleware()(createTestHandler(errors.NewNotificationError(errors.ErrorCodeProviderUnavailable, "down")))
+	_, err := failing(context.Background(), createTestRequest("news@news.example.com", models.CategoryMarketing))
+	require.Error(t, err)
+
+	status, _ := scheduler.Status("news.example.com")
+	assert.Equal(t, 0, status.SentToday)
+}
+
+func TestScheduler_DefersToNextDay(t *testing.T) {
+	scheduler, _ := createTestScheduler(t, config.WarmupDomain{Domain: "news.example.com", Start: testStart, DailyLimits: []int{1}})
+	send := scheduler.Middleware()(createTestHandler(nil))
+	scheduler.SetSender(send)
+
+	_, err := send(context.Background(), createTestRequest("news@news.example.com", models.CategoryMarketing))
+	require.NoError(t, err)
+
+	response, err := send(context.Background(), createTestRequest("news@news.example.com", models.CategoryMarketing))
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusPending, response.Status)
+	assert.Contains(t, response.Message, "deferred to 2024-03-02T00:00:00Z")
+	assert.Equal(t, 1, scheduler.Pending())
+
+	scheduler.Stop()
+	assert.Equal(t, 0, scheduler.Pending())
+
+	// Once stopped, marketing email over the limit is rejected
+	_, err = send(context.Background(), createTestRequest("news@news.example.com", models.CategoryMarketing))
+	assert.Error(t, err)
+}
+
+// Helper functions
+
+// createTestScheduler creates a scheduler whose clock only moves when the test moves it
+func createTestScheduler(t *testing.T, domains ...config.WarmupDomain) (*Scheduler, *time.Time) {
+	scheduler, err := NewScheduler(config.WarmupConfig{Domains: domains}, utils.NewSimpleLogger("info"))
+	require.NoError(t, err)
+	t.Cleanup(scheduler.Stop)
+
+	clock := testStart.Add(9 * time.Hour)
+	scheduler.now = func() time.Time { return clock }
+	return scheduler, &clock
+}
+
+func createTestHandler(err error) pipeline.Handler {
+	return func(ctx context.Context, request *models.NotificationRequest) (*models.NotificationResponse, error) {
+		if err != nil {
+			return nil, err
+		}
+		return &models.NotificationResponse{ID: uuid.New(), Status: models.StatusSent}, nil
+	}
+}
+
+func createTestRequest(from string, category models.Category) *models.NotificationRequest {
+	return &models.NotificationRequest{
+		Type:      models.NotificationTypeEmail,
+		Priority:  models.PriorityNormal,
+		Recipient: "user@example.com",
+		Subject:   "Spring sale",
+		Body:      "The sale is on",
+		Category:  category,
+		EmailData: &models.EmailData{From: from},
+	}
+}
@@ -0,0 +1,345 @@
+// Package webhookauth protects provider webhooks, such as inbound email and
+// SMS posts, which are served without API credentials. Each provider's
+// webhooks can be limited to an IP allowlist and required to carry a valid
+// signature: X-Twilio-Signature for Twilio, and the signed event headers
+// for SendGrid. Misconfigured checks reject every post of their provider
+// and are reported by HealthCheck.
+package webhookauth
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+// Webhook sources
+const (
+	SourceSendGrid = "sendgrid"
+	SourceSES      = "ses"
+	SourceTwilio   = "twilio"
+)
+
+// Signature headers
+const (
+	TwilioSignatureHeader   = "X-Twilio-Signature"
+	SendGridSignatureHeader = "X-Twilio-Email-Event-Webhook-Signature"
+	SendGridTimestampHeader = "X-Twilio-Email-Event-Webhook-Timestamp"
+)
+
+// maxSignedBody bounds the body read to check a signature; inbound email
+// posts carry attachments
+const maxSignedBody = 16 << 20
+
+// source holds the checks of one provider's webhooks
+type source struct {
+	allowed []*net.IPNet
+	verify  func(r *http.Request, body []byte) error // nil when posts are unsigned
+	problem error                                    // misconfiguration; every post is rejected
+}
+
+// Guard checks webhook posts against their provider's allowlist and
+// signature. It is safe for concurrent use.
+type Guard struct {
+	sources   map[string]*source
+	trusted   []*net.IPNet
+	publicURL string
+	problems  map[string]error
+}
+
+// NewGuard creates a guard for the configured providers. Invalid settings do
+// not fail construction: the affected provider rejects its posts and
+// HealthCheck reports the problem.
+func NewGuard(cfg config.WebhookAuthConfig) *Guard {
+	g := &Guard{
+		sources:   make(map[string]*source),
+		publicURL: strings.TrimRight(cfg.PublicURL, "/"),
+		problems:  make(map[string]error),
+	}
+
+	trusted, err := parseNetworks(cfg.TrustedProxies)
+	if err != nil {
+		g.problems["trusted_proxies"] = err
+	}
+	g.trusted = trusted
+	if g.publicURL != "" {
+		if parsed, err := url.Parse(g.publicURL); err != nil || parsed.Host == "" {
+			g.problems["public_url"] = fmt.Errorf("public URL %q is not an absolute URL", cfg.PublicURL)
+		}
+	}
+
+	g.add(SourceSendGrid, cfg.SendGrid, g.sendGridVerifier)
+	g.add(SourceSES, cfg.SES, nil)
+	g.add(SourceTwilio, cfg.Twilio, g.twilioVerifier)
+	return g
+}
+
+// add sets up a provider's checks, recording any misconfiguration
+func (g *Guard) add(name string, cfg config.WebhookSourceConfig, verifier func(key string) (func(*http.Request, []byte) error, error)) {
+	s := &source{}
+	allowed, err := parseNetworks(cfg.AllowedIPs)
+	s.allowed = allowed
+	if err != nil {
+		s.problem = err
+	}
+	if cfg.SigningKey != "" {
+		if verifier == nil {
+			s.problem = fmt.Errorf("%s webhooks are not signed", name)
+		} else if s.verify, err = verifier(cfg.SigningKey); err != nil {
+			s.problem = err
+		}
+	}
+	if s.problem != nil {
+		g.problems[name] = s.problem
+	}
+	g.sources[name] = s
+}
+
+// Check checks a post to a provider's webhook. A caller outside the
+// allowlist or with a missing or invalid signature gets a FORBIDDEN error.
+// The request body is restored for the handler.
+func (g *Guard) Check(r *http.Request, name string) error {
+	s, exists := g.sources[name]
+	if !exists {
+		return nil
+	}
+	if s.problem != nil {
+		return errors.NewNotificationErrorWithDetails(errors.ErrorCodeProviderConfiguration,
+			fmt.Sprintf("%s webhook checks are misconfigured", name), s.problem.Error())
+	}
+
+	if len(s.allowed) > 0 {
+		ip := g.clientIP(r)
+		if ip == nil || !containsIP(s.allowed, ip) {
+			return errors.NewNotificationError(errors.ErrorCodeForbidden, fmt.Sprintf("%s webhook posted from an address that is not allowed", name)).
+				WithMetadata("ip", fmt.Sprint(ip))
+		}
+	}
+
+	if s.verify == nil {
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxSignedBody+1))
+	if err != nil {
+		return errors.NewNotificationErrorWithDetails(errors.ErrorCodeInvalidRequest, "request body could not be read", err.Error())
+	}
+	if len(body) > maxSignedBody {
+		return errors.NewNotificationError(errors.ErrorCodeInvalidRequest, "request body is too large")
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err := s.verify(r, body); err != nil {
+		return errors.NewNotificationErrorWithDetails(errors.ErrorCodeForbidden, fmt.Sprintf("%s webhook signature is invalid", name), err.Error())
+	}
+	return nil
+}
+
+// HealthCheck returns the misconfiguration of each provider and of the
+// shared settings, with nil for providers that are set up correctly
+func (g *Guard) HealthCheck() map[string]error {
+	health := make(map[string]error, len(g.sources)+len(g.problems))
+	for name := range g.sources {
+		health[name] = nil
+	}
+	for name, problem := range g.problems {
+		health[name] = problem
+	}
+	return health
+}
+
+// Protection describes the checks of a provider's webhooks, e.g.
+// "ip allowlist, signature", or "unprotected" when there are none
+func (g *Guard) Protection(name string) string {
+	s, exists := g.sources[name]
+	if !exists {
+		return "unprotected"
+	}
+	var checks []string
+	if len(s.allowed) > 0 {
+		checks = append(checks, "ip allowlist")
+	}
+	if s.verify != nil {
+		checks = append(checks, "signature")
+	}
+	if len(checks) == 0 {
+		return "unprotected"
+	}
+	return strings.Join(checks, ", ")
+}
+
+// clientIP returns the caller's address. X-Forwarded-For is only read
+// through trusted proxies: the client is its last address that is not one.
+func (g *Guard) clientIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !containsIP(g.trusted, ip) {
+		return ip
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if hop == nil {
+			return ip
+		}
+		ip = hop
+		if !containsIP(g.trusted, hop) {
+			break
+		}
+	}
+	return ip
+}
+
+// twilioVerifier checks X-Twilio-Signature: the base64 HMAC-SHA1, keyed by
+// the auth token, of the URL followed by the sorted form parameters. JSON
+// posts are signed with a bodySHA256 query parameter instead.
+func (g *Guard) twilioVerifier(authToken string) (func(*http.Request, []byte) error, error) {
+	return func(r *http.Request, body []byte) error {
+		signature := r.Header.Get(TwilioSignatureHeader)
+		if signature == "" {
+			return fmt.Errorf("missing %s header", TwilioSignatureHeader)
+		}
+
+		postURL := g.requestURL(r)
+		data := postURL
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType == "application/x-www-form-urlencoded" {
+			form, err := url.ParseQuery(string(body))
+			if err != nil {
+				return fmt.Errorf("invalid form body: %w", err)
+			}
+			keys := make([]string, 0, len(form))
+			for key := range form {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				values := form[key]
+				sort.Strings(values)
+				for _, value := range values {
+					data += key + value
+				}
+			}
+		} else {
+			digest := sha256.Sum256(body)
+			if !hmac.Equal([]byte(r.URL.Query().Get("bodySHA256")), []byte(hex.EncodeToString(digest[:]))) {
+				return fmt.Errorf("body does not match bodySHA256")
+			}
+		}
+
+		mac := hmac.New(sha1.New, []byte(authToken))
+		mac.Write([]byte(data))
+		if !hmac.Equal([]byte(signature), []byte(base64.StdEncoding.EncodeToString(mac.Sum(nil)))) {
+			return fmt.Errorf("signature does not match a post to %s", postURL)
+		}
+		return nil
+	}, nil
+}
+
+// requestURL returns the URL a provider posted to, as it signed it
+func (g *Guard) requestURL(r *http.Request) string {
+	if g.publicURL != "" {
+		return g.publicURL + r.URL.RequestURI()
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+// sendGridVerifier checks SendGrid's signed events: an ECDSA signature of
+// the timestamp header followed by the body. The verification key is the
+// base64 public key SendGrid shows, or the same key in PEM.
+func (g *Guard) sendGridVerifier(verificationKey string) (func(*http.Request, []byte) error, error) {
+	var der []byte
+	if block, _ := pem.Decode([]byte(verificationKey)); block != nil {
+		der = block.Bytes
+	} else {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(verificationKey))
+		if err != nil {
+			return nil, fmt.Errorf("SendGrid verification key is not base64: %w", err)
+		}
+		der = decoded
+	}
+	parsed, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("SendGrid verification key is invalid: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("SendGrid verification key is not an ECDSA key")
+	}
+
+	return func(r *http.Request, body []byte) error {
+		timestamp := r.Header.Get(SendGridTimestampHeader)
+		encoded := r.Header.Get(SendGridSignatureHeader)
+		if timestamp == "" || encoded == "" {
+			return fmt.Errorf("missing %s or %s header", SendGridSignatureHeader, SendGridTimestampHeader)
+		}
+		signature, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return fmt.Errorf("signature is not base64: %w", err)
+		}
+		digest := sha256.Sum256(append([]byte(timestamp), body...))
+		if !ecdsa.VerifyASN1(key, digest[:], signature) {
+			return fmt.Errorf("signature does not match")
+		}
+		return nil
+	}, nil
+}
+
+// parseNetworks parses addresses and CIDRs. Invalid entries are skipped and
+// reported together.
+func parseNetworks(entries []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(entries))
+	var invalid []string
+	for _, entry := range entries {
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil {
+				bits := 8 * net.IPv6len
+				if ip.To4() != nil {
+					ip, bits = ip.To4(), 8*net.IPv4len
+				}
+				networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+				continue
+			}
+		} else if _, network, err := net.ParseCIDR(entry); err == nil {
+			networks = append(networks, network)
+			continue
+		}
+		invalid = append(invalid, entry)
+	}
+	if len(invalid) > 0 {
+		return networks, fmt.Errorf("invalid addresses %s", strings.Join(invalid, ", "))
+	}
+	return networks, nil
+}
+
+// containsIP reports whether an address is in one of the networks
+func containsIP(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,202 @@
+package webhookauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+const testAuthToken = "twilio-auth-token"
+
+func TestGuard_AllowedIPs(t *testing.T) {
+	guard := NewGuard(config.WebhookAuthConfig{
+		TrustedProxies: []string{"10.0.0.0/8"},
+		SES:            config.WebhookSourceConfig{AllowedIPs: []string{"54.240.0.0/18", "2001:db8::1"}},
+	})
+
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded string
+		allowed   bool
+	}{
+		{"allowed network", "54.240.1.2:443", "", true},
+		{"allowed address", "[2001:db8::1]:443", "", true},
+		{"other address", "203.0.113.9:443", "", false},
+		{"forwarded by a trusted proxy", "10.1.2.3:80", "54.240.1.2", true},
+		{"forwarded through two proxies", "10.1.2.3:80", "54.240.1.2, 10.9.9.9", true},
+		{"spoofed before the client", "10.1.2.3:80", "54.240.1.2, 203.0.113.9", false},
+		{"forwarded header from an untrusted peer", "203.0.113.9:443", "54.240.1.2", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/v1/inbound/ses", nil)
+			request.RemoteAddr = tt.remote
+			if tt.forwarded != "" {
+				request.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			err := guard.Check(request, SourceSES)
+			if tt.allowed {
+				assert.NoError(t, err)
+				return
+			}
+			notifErr, ok := errors.AsNotificationError(err)
+			require.True(t, ok)
+			assert.Equal(t, errors.ErrorCodeForbidden, notifErr.Code)
+		})
+	}
+
+	// Providers without checks accept any post
+	assert.NoError(t, guard.Check(httptest.NewRequest(http.MethodPost, "/v1/inbound/sms", nil), SourceTwilio))
+}
+
+func TestGuard_TwilioSignature(t *testing.T) {
+	guard := NewGuard(config.WebhookAuthConfig{
+		PublicURL: "https://notify.example.com/",
+		Twilio:    config.WebhookSourceConfig{SigningKey: testAuthToken},
+	})
+	form := url.Values{"From": {"+14155550123"}, "Body": {"STOP"}, "MessageSid": {"SM123"}}
+
+	request := createTestFormPost(form.Encode())
+	request.Header.Set(TwilioSignatureHeader, signTwilio("https://notify.example.com/v1/inbound/sms", form))
+	require.NoError(t, guard.Check(request, SourceTwilio))
+	body, err := io.ReadAll(request.Body)
+	require.NoError(t, err)
+	assert.Equal(t, form.Encode(), string(body), "the body is restored for the handler")
+
+	// A changed parameter breaks the signature
+	tampered := url.Values{"From": {"+14155550123"}, "Body": {"START"}, "MessageSid": {"SM123"}}
+	request = createTestFormPost(tampered.Encode())
+	request.Header.Set(TwilioSignatureHeader, signTwilio("https://notify.example.com/v1/inbound/sms", form))
+	assert.Error(t, guard.Check(request, SourceTwilio))
+
+	// So does signing another URL
+	request = createTestFormPost(form.Encode())
+	request.Header.Set(TwilioSignatureHeader, signTwilio("https://other.example.com/v1/inbound/sms", form))
+	assert.Error(t, guard.Check(request, SourceTwilio))
+
+	request = createTestFormPost(form.Encode())
+	assert.Error(t, guard.Check(request, SourceTwilio))
+}
+
+func TestGuard_TwilioJSONSignature(t *testing.T) {
+	guard := NewGuard(config.WebhookAuthConfig{Twilio: config.WebhookSourceConfig{SigningKey: testAuthToken}})
+	body := `{"from":"+14155550123","body":"HELP"}`
+	digest := sha256.Sum256([]byte(body))
+	target := "/v1/inbound/sms?bodySHA256=" + hex.EncodeToString(digest[:])
+
+	request := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(TwilioSignatureHeader, signTwilio("http://example.com"+target, nil))
+	assert.NoError(t, guard.Check(request, SourceTwilio))
+
+	request = httptest.NewRequest(http.MethodPost, target, strings.NewReader(`{"from":"+14155550123","body":"STOP"}`))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(TwilioSignatureHeader, signTwilio("http://example.com"+target, nil))
+	assert.Error(t, guard.Check(request, SourceTwilio))
+}
+
+func TestGuard_SendGridSignature(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	guard := NewGuard(config.WebhookAuthConfig{SendGrid: config.WebhookSourceConfig{SigningKey: base64.StdEncoding.EncodeToString(der)}})
+
+	body := "--boundary\r\nContent-Disposition: form-data; name=\"subject\"\r\n\r\nRe: Invoice\r\n--boundary--\r\n"
+	digest := sha256.Sum256([]byte("1700000000" + body))
+	signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	require.NoError(t, err)
+
+	request := httptest.NewRequest(http.MethodPost, "/v1/inbound/sendgrid", strings.NewReader(body))
+	request.Header.Set(SendGridTimestampHeader, "1700000000")
+	request.Header.Set(SendGridSignatureHeader, base64.StdEncoding.EncodeToString(signature))
+	assert.NoError(t, guard.Check(request, SourceSendGrid))
+
+	// A replayed signature with another timestamp fails
+	request = httptest.NewRequest(http.MethodPost, "/v1/inbound/sendgrid", strings.NewReader(body))
+	request.Header.Set(SendGridTimestampHeader, "1700000001")
+	request.Header.Set(SendGridSignatureHeader, base64.StdEncoding.EncodeToString(signature))
+	assert.Error(t, guard.Check(request, SourceSendGrid))
+
+	request = httptest.NewRequest(http.MethodPost, "/v1/inbound/sendgrid", strings.NewReader(body))
+	assert.Error(t, guard.Check(request, SourceSendGrid))
+}
+
+func TestGuard_Misconfiguration(t *testing.T) {
+	guard := NewGuard(config.WebhookAuthConfig{
+		TrustedProxies: []string{"10.0.0.0/33"},
+		SendGrid:       config.WebhookSourceConfig{SigningKey: "not-a-key"},
+		SES:            config.WebhookSourceConfig{AllowedIPs: []string{"54.240.0.0/18"}, SigningKey: "secret"},
+		Twilio:         config.WebhookSourceConfig{AllowedIPs: []string{"not-an-ip"}},
+	})
+
+	health := guard.HealthCheck()
+	assert.Error(t, health["trusted_proxies"])
+	assert.Error(t, health[SourceSendGrid])
+	assert.Error(t, health[SourceSES])
+	assert.Error(t, health[SourceTwilio])
+
+	// Misconfigured providers reject every post
+	err := guard.Check(httptest.NewRequest(http.MethodPost, "/v1/inbound/sms", nil), SourceTwilio)
+	notifErr, ok := errors.AsNotificationError(err)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrorCodeProviderConfiguration, notifErr.Code)
+
+	guard = NewGuard(config.WebhookAuthConfig{
+		SES:    config.WebhookSourceConfig{AllowedIPs: []string{"54.240.0.0/18"}},
+		Twilio: config.WebhookSourceConfig{AllowedIPs: []string{"54.172.60.0/30"}, SigningKey: testAuthToken},
+	})
+	for name, err := range guard.HealthCheck() {
+		assert.NoError(t, err, name)
+	}
+	assert.Equal(t, "unprotected", guard.Protection(SourceSendGrid))
+	assert.Equal(t, "ip allowlist", guard.Protection(SourceSES))
+	assert.Equal(t, "ip allowlist, signature", guard.Protection(SourceTwilio))
+}
+
+// Helper functions
+
+func createTestFormPost(body string) *http.Request {
+	request := httptest.NewRequest(http.MethodPost, "/v1/inbound/sms", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return request
+}
+
+// signTwilio signs a post the way Twilio does
+func signTwilio(postURL string, form url.Values) string {
+	data := postURL
+	keys := make([]string, 0, len(form))
+	for key := range form {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range form[key] {
+			data += key + value
+		}
+	}
+	mac := hmac.New(sha1.New, []byte(testAuthToken))
+	mac.Write([]byte(data))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
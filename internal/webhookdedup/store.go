@@ -0,0 +1,125 @@
+package webhookdedup
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/redis"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// pruneInterval is how often expired events are dropped from a memory store
+const pruneInterval = time.Minute
+
+// event is the state of an event in a memory store
+type event struct {
+	state   string
+	expires time.Time
+}
+
+// MemoryStore implements the WebhookEventStore interface in memory, so
+// retries are only recognized by the instance that processed the event
+type MemoryStore struct {
+	mu        sync.Mutex
+	events    map[string]event
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// NewMemoryStore creates an empty memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		events:    make(map[string]event),
+		lastPrune: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Claim implements the WebhookEventStore interface
+func (s *MemoryStore) Claim(_ context.Context, key string, lease time.Duration) (bool, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastPrune) >= pruneInterval {
+		for existing, e := range s.events {
+			if !now.Before(e.expires) {
+				delete(s.events, existing)
+			}
+		}
+		s.lastPrune = now
+	}
+
+	if e, exists := s.events[key]; exists && now.Before(e.expires) {
+		return false, e.state, nil
+	}
+	s.events[key] = event{state: interfaces.WebhookEventProcessing, expires: now.Add(lease)}
+	return true, "", nil
+}
+
+// Complete implements the WebhookEventStore interface
+func (s *MemoryStore) Complete(_ context.Context, key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events[key] = event{state: interfaces.WebhookEventDone, expires: s.now().Add(ttl)}
+	return nil
+}
+
+// Release implements the WebhookEventStore interface
+func (s *MemoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.events[key].state == interfaces.WebhookEventProcessing {
+		delete(s.events, key)
+	}
+	return nil
+}
+
+// Lua scripts run atomically by Redis. Claiming sets the key if it is free
+// and otherwise returns its state; releasing deletes the key only while it
+// is being processed, so a completed event is never forgotten.
+const (
+	redisClaimScript = `if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then return 1 end
+return redis.call("GET", KEYS[1])`
+	redisCompleteScript = `return redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])`
+	redisReleaseScript  = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) end
+return 0`
+)
+
+// RedisStore implements the WebhookEventStore interface with Redis keys that
+// expire with the event, so retries are recognized by every instance
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore creates a Redis store whose keys start with the prefix
+func NewRedisStore(client *redis.Client, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// Claim implements the WebhookEventStore interface
+func (s *RedisStore) Claim(ctx context.Context, key string, lease time.Duration) (bool, string, error) {
+	reply, err := s.client.Eval(ctx, redisClaimScript, []string{s.prefix + key},
+		interfaces.WebhookEventProcessing, strconv.FormatInt(lease.Milliseconds(), 10))
+	if err != nil {
+		return false, "", err
+	}
+	state, claimed := reply.(string)
+	return !claimed, state, nil
+}
+
+// Complete implements the WebhookEventStore interface
+func (s *RedisStore) Complete(ctx context.Context, key string, ttl time.Duration) error {
+	_, err := s.client.Eval(ctx, redisCompleteScript, []string{s.prefix + key},
+		interfaces.WebhookEventDone, strconv.FormatInt(ttl.Milliseconds(), 10))
+	return err
+}
+
+// Release implements the WebhookEventStore interface
+func (s *RedisStore) Release(ctx context.Context, key string) error {
+	_, err := s.client.Eval(ctx, redisReleaseScript, []string{s.prefix + key}, interfaces.WebhookEventProcessing)
+	return err
+}
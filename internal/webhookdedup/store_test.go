@@ -0,0 +1,113 @@
+package webhookdedup
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/redis/redistest"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	claimed, _, err := store.Claim(ctx, "twilio:SM1", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	claimed, state, err := store.Claim(ctx, "twilio:SM1", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+	assert.Equal(t, interfaces.WebhookEventProcessing, state)
+
+	// An abandoned claim expires with its lease
+	now = now.Add(2 * time.Minute)
+	claimed, _, err = store.Claim(ctx, "twilio:SM1", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	require.NoError(t, store.Complete(ctx, "twilio:SM1", time.Hour))
+	require.NoError(t, store.Release(ctx, "twilio:SM1"), "completed events are not released")
+	claimed, state, err = store.Claim(ctx, "twilio:SM1", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+	assert.Equal(t, interfaces.WebhookEventDone, state)
+
+	// Processed events are forgotten after the TTL
+	now = now.Add(2 * time.Hour)
+	claimed, _, err = store.Claim(ctx, "twilio:SM1", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	assert.Len(t, store.events, 1, "expired events are pruned")
+}
+
+func TestRedisStore(t *testing.T) {
+	server, keys := startTestRedis(t)
+	store, err := NewStore(config.WebhookDedupConfig{Store: StoreRedis, RedisURL: server.Addr, KeyPrefix: "test:"})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	claimed, _, err := store.Claim(ctx, "ses:msg-1", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	assert.Equal(t, interfaces.WebhookEventProcessing, keys["test:ses:msg-1"])
+
+	claimed, state, err := store.Claim(ctx, "ses:msg-1", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+	assert.Equal(t, interfaces.WebhookEventProcessing, state)
+
+	require.NoError(t, store.Release(ctx, "ses:msg-1"))
+	assert.NotContains(t, keys, "test:ses:msg-1")
+
+	claimed, _, err = store.Claim(ctx, "ses:msg-1", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	require.NoError(t, store.Complete(ctx, "ses:msg-1", time.Hour))
+	require.NoError(t, store.Release(ctx, "ses:msg-1"))
+
+	claimed, state, err = store.Claim(ctx, "ses:msg-1", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+	assert.Equal(t, interfaces.WebhookEventDone, state)
+}
+
+// Helper functions
+
+// startTestRedis emulates the store's scripts; keys do not expire
+func startTestRedis(t *testing.T) (*redistest.Server, map[string]string) {
+	server := redistest.NewServer(t, "")
+	keys := make(map[string]string)
+
+	server.HandleScript(redisClaimScript, func(k, args []string) interface{} {
+		if state, exists := keys[k[0]]; exists {
+			return state
+		}
+		if _, err := strconv.Atoi(args[1]); err != nil {
+			return err
+		}
+		keys[k[0]] = args[0]
+		return int64(1)
+	})
+	server.HandleScript(redisCompleteScript, func(k, args []string) interface{} {
+		keys[k[0]] = args[0]
+		return "OK"
+	})
+	server.HandleScript(redisReleaseScript, func(k, args []string) interface{} {
+		if keys[k[0]] != args[0] {
+			return int64(0)
+		}
+		delete(keys, k[0])
+		return int64(1)
+	})
+	return server, keys
+}
@@ -0,0 +1,106 @@
+// Package webhookdedup processes provider webhook events once. Providers
+// retry webhooks they consider failed, so the same inbound SMS or email can
+// arrive several times; events are recognized by their provider's event ID,
+// such as Twilio's MessageSid, and retries of a processed event are
+// acknowledged without updating statuses or firing callbacks again.
+package webhookdedup
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/redis"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/interfaces"
+)
+
+// Store types accepted in config.WebhookDedupConfig
+const (
+	StoreMemory = "memory"
+	StoreRedis  = "redis"
+)
+
+// Defaults used when the configuration leaves them zero
+const (
+	defaultTTL   = 72 * time.Hour // SendGrid retries for up to three days
+	defaultLease = time.Minute
+)
+
+// NewStore creates the store the configuration selects
+func NewStore(cfg config.WebhookDedupConfig) (interfaces.WebhookEventStore, error) {
+	switch cfg.Store {
+	case "", StoreMemory:
+		return NewMemoryStore(), nil
+	case StoreRedis:
+		client, err := redis.NewClient(cfg.RedisURL, cfg.RedisPassword, cfg.RedisDB, cfg.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		return NewRedisStore(client, cfg.KeyPrefix), nil
+	default:
+		return nil, errors.NewValidationError("store", fmt.Sprintf("unknown webhook dedup store: %q", cfg.Store))
+	}
+}
+
+// Deduplicator processes each webhook event once. It is safe for concurrent
+// use.
+type Deduplicator struct {
+	store  interfaces.WebhookEventStore
+	ttl    time.Duration
+	lease  time.Duration
+	logger interfaces.Logger
+}
+
+// NewDeduplicator creates a deduplicator recording events in the store
+func NewDeduplicator(cfg config.WebhookDedupConfig, store interfaces.WebhookEventStore, logger interfaces.Logger) *Deduplicator {
+	d := &Deduplicator{store: store, ttl: cfg.TTL, lease: cfg.Lease, logger: logger}
+	if d.ttl <= 0 {
+		d.ttl = defaultTTL
+	}
+	if d.lease <= 0 {
+		d.lease = defaultLease
+	}
+	return d
+}
+
+// Process calls handle for an event unless it was processed before, in which
+// case it reports a duplicate. A retry that arrives while the event is still
+// being processed gets a RATE_LIMITED error, so the provider tries again
+// later. When handle fails the event is released and its retry processed.
+// Events without an ID, and all events while the store fails, are processed
+// every time.
+func (d *Deduplicator) Process(ctx context.Context, source, eventID string, handle func() error) (bool, error) {
+	if eventID == "" {
+		return false, handle()
+	}
+
+	key := source + ":" + eventID
+	claimed, state, err := d.store.Claim(ctx, key, d.lease)
+	if err != nil {
+		d.logger.Warnf("Webhook dedup store failed, processing %s event %s without it: %v", source, eventID, err)
+		return false, handle()
+	}
+	if !claimed {
+		if state == interfaces.WebhookEventProcessing {
+			return false, errors.NewNotificationError(errors.ErrorCodeRateLimited,
+				fmt.Sprintf("%s event %s is already being processed", source, eventID)).
+				WithMetadata("retry_after", strconv.Itoa(int(d.lease.Seconds())))
+		}
+		d.logger.Infof("Skipped duplicate %s event %s", source, eventID)
+		return true, nil
+	}
+
+	if err := handle(); err != nil {
+		if releaseErr := d.store.Release(ctx, key); releaseErr != nil {
+			d.logger.Errorf("Failed to release %s event %s: %v", source, eventID, releaseErr)
+		}
+		return false, err
+	}
+	if err := d.store.Complete(ctx, key, d.ttl); err != nil {
+		d.logger.Errorf("Failed to record %s event %s as processed: %v", source, eventID, err)
+	}
+	return false, nil
+}
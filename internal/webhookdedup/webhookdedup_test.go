@@ -0,0 +1,124 @@
+package webhookdedup
+
+import (
+	"context"
+	stderrors "errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nareshkumar-microsoft/notificationService/internal/config"
+	"github.com/nareshkumar-microsoft/notificationService/internal/utils"
+	"github.com/nareshkumar-microsoft/notificationService/pkg/errors"
+)
+
+func TestDeduplicator_Process(t *testing.T) {
+	dedup := NewDeduplicator(config.WebhookDedupConfig{}, NewMemoryStore(), utils.NewSimpleLogger("error"))
+	ctx := context.Background()
+	calls := 0
+	handle := func() error {
+		calls++
+		return nil
+	}
+
+	duplicate, err := dedup.Process(ctx, "twilio", "SM123", handle)
+	require.NoError(t, err)
+	assert.False(t, duplicate)
+
+	duplicate, err = dedup.Process(ctx, "twilio", "SM123", handle)
+	require.NoError(t, err)
+	assert.True(t, duplicate)
+	assert.Equal(t, 1, calls)
+
+	// Event IDs are per provider
+	duplicate, err = dedup.Process(ctx, "vonage", "SM123", handle)
+	require.NoError(t, err)
+	assert.False(t, duplicate)
+
+	// Events without an ID are always processed
+	for i := 0; i < 2; i++ {
+		duplicate, err = dedup.Process(ctx, "twilio", "", handle)
+		require.NoError(t, err)
+		assert.False(t, duplicate)
+	}
+	assert.Equal(t, 4, calls)
+}
+
+func TestDeduplicator_ProcessFailure(t *testing.T) {
+	dedup := NewDeduplicator(config.WebhookDedupConfig{}, NewMemoryStore(), utils.NewSimpleLogger("error"))
+	ctx := context.Background()
+
+	failure := stderrors.New("forward failed")
+	_, err := dedup.Process(ctx, "twilio", "SM123", func() error { return failure })
+	assert.ErrorIs(t, err, failure)
+
+	// The retry of a failed event is processed
+	calls := 0
+	duplicate, err := dedup.Process(ctx, "twilio", "SM123", func() error {
+		calls++
+		return nil
+	})
+	require.NoError(t, err)
+	assert.False(t, duplicate)
+	assert.Equal(t, 1, calls)
+}
+
+func TestDeduplicator_ProcessConcurrentRetry(t *testing.T) {
+	dedup := NewDeduplicator(config.WebhookDedupConfig{Lease: 30 * time.Second}, NewMemoryStore(), utils.NewSimpleLogger("error"))
+	ctx := context.Background()
+
+	_, err := dedup.Process(ctx, "ses", "msg-1", func() error {
+		// The provider retries while the first post is still being handled
+		_, err := dedup.Process(ctx, "ses", "msg-1", func() error {
+			t.Fatal("retry was processed concurrently")
+			return nil
+		})
+		notifErr, ok := errors.AsNotificationError(err)
+		require.True(t, ok)
+		assert.Equal(t, errors.ErrorCodeRateLimited, notifErr.Code)
+		assert.Equal(t, "30", notifErr.Metadata["retry_after"])
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestDeduplicator_StoreFailure(t *testing.T) {
+	dedup := NewDeduplicator(config.WebhookDedupConfig{}, failingStore{}, utils.NewSimpleLogger("error"))
+	calls := 0
+	for i := 0; i < 2; i++ {
+		duplicate, err := dedup.Process(context.Background(), "twilio", "SM123", func() error {
+			calls++
+			return nil
+		})
+		require.NoError(t, err)
+		assert.False(t, duplicate)
+	}
+	assert.Equal(t, 2, calls, "events are processed while the store is down")
+}
+
+func TestNewStore(t *testing.T) {
+	store, err := NewStore(config.WebhookDedupConfig{})
+	require.NoError(t, err)
+	assert.IsType(t, &MemoryStore{}, store)
+
+	_, err = NewStore(config.WebhookDedupConfig{Store: "dynamo"})
+	assert.Error(t, err)
+}
+
+// Helper functions
+
+type failingStore struct{}
+
+func (failingStore) Claim(context.Context, string, time.Duration) (bool, string, error) {
+	return false, "", stderrors.New("store down")
+}
+
+func (failingStore) Complete(context.Context, string, time.Duration) error {
+	return stderrors.New("store down")
+}
+
+func (failingStore) Release(context.Context, string) error {
+	return stderrors.New("store down")
+}
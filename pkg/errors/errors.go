@@ -3,6 +3,8 @@ package errors
 import (
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 // ErrorCode represents different types of errors that can occur
@@ -14,6 +16,7 @@ const (
 	ErrorCodeInvalidRequest ErrorCode = "INVALID_REQUEST"
 	ErrorCodeNotFound       ErrorCode = "NOT_FOUND"
 	ErrorCodeUnauthorized   ErrorCode = "UNAUTHORIZED"
+	ErrorCodeForbidden      ErrorCode = "FORBIDDEN"
 	ErrorCodeRateLimited    ErrorCode = "RATE_LIMITED"
 	ErrorCodeTimeout        ErrorCode = "TIMEOUT"
 
@@ -29,6 +32,9 @@ const (
 	ErrorCodeNotificationFailed  ErrorCode = "NOTIFICATION_FAILED"
 	ErrorCodeDeliveryFailed      ErrorCode = "DELIVERY_FAILED"
 	ErrorCodeTemplateNotFound    ErrorCode = "TEMPLATE_NOT_FOUND"
+	ErrorCodeRecipientOptedOut   ErrorCode = "RECIPIENT_OPTED_OUT"
+	ErrorCodeAttachmentInfected  ErrorCode = "ATTACHMENT_INFECTED"
+	ErrorCodePayloadTooLarge     ErrorCode = "PAYLOAD_TOO_LARGE"
 
 	// Validation errors
 	ErrorCodeValidationFailed ErrorCode = "VALIDATION_FAILED"
@@ -49,9 +55,17 @@ type NotificationError struct {
 	Details    string            `json:"details,omitempty"`
 	StatusCode int               `json:"status_code"`
 	Metadata   map[string]string `json:"metadata,omitempty"`
+	Fields     []FieldError      `json:"fields,omitempty"`
 	Cause      error             `json:"-"`
 }
 
+// FieldError describes a single field that failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
 // Error implements the error interface
 func (e *NotificationError) Error() string {
 	if e.Details != "" {
@@ -124,6 +138,32 @@ func NewValidationError(field, message string) *NotificationError {
 	return err
 }
 
+// NewFieldValidationError creates a validation error listing every failed field
+func NewFieldValidationError(fields ...FieldError) *NotificationError {
+	if len(fields) == 0 {
+		return NewNotificationError(ErrorCodeValidationFailed, "Validation failed")
+	}
+
+	message := fmt.Sprintf("Validation failed for field '%s': %s", fields[0].Field, fields[0].Message)
+	if len(fields) > 1 {
+		names := make([]string, len(fields))
+		for i, field := range fields {
+			names[i] = field.Field
+		}
+		message = fmt.Sprintf("Validation failed for fields: %s", strings.Join(names, ", "))
+	}
+
+	err := &NotificationError{
+		Code:       ErrorCodeValidationFailed,
+		Message:    message,
+		StatusCode: http.StatusBadRequest,
+		Metadata:   make(map[string]string),
+		Fields:     fields,
+	}
+	err.WithMetadata("field", fields[0].Field)
+	return err
+}
+
 // NewProviderError creates a new provider error
 func NewProviderError(providerName string, code ErrorCode, message string) *NotificationError {
 	err := &NotificationError{
@@ -151,6 +191,21 @@ func NewRateLimitError(retryAfter string) *NotificationError {
 	return err
 }
 
+// NewPayloadTooLargeError creates an error for a field over a size or count
+// limit, e.g. 12 attachments where 10 are allowed
+func NewPayloadTooLargeError(field string, size, limit int64, unit string) *NotificationError {
+	err := &NotificationError{
+		Code:       ErrorCodePayloadTooLarge,
+		Message:    fmt.Sprintf("Payload too large for field '%s': %d %s exceeds the limit of %d", field, size, unit, limit),
+		StatusCode: http.StatusRequestEntityTooLarge,
+		Metadata:   make(map[string]string),
+	}
+	err.WithMetadata("field", field).
+		WithMetadata("size", strconv.FormatInt(size, 10)).
+		WithMetadata("limit", strconv.FormatInt(limit, 10))
+	return err
+}
+
 // IsNotificationError checks if an error is a NotificationError
 func IsNotificationError(err error) bool {
 	_, ok := err.(*NotificationError)
@@ -189,9 +244,18 @@ func getHTTPStatusCode(code ErrorCode) int {
 	case ErrorCodeUnauthorized, ErrorCodeProviderAuthentication:
 		return http.StatusUnauthorized
 
+	case ErrorCodeForbidden:
+		return http.StatusForbidden
+
 	case ErrorCodeNotFound, ErrorCodeProviderNotFound, ErrorCodeTemplateNotFound:
 		return http.StatusNotFound
 
+	case ErrorCodeRecipientOptedOut, ErrorCodeAttachmentInfected:
+		return http.StatusUnprocessableEntity
+
+	case ErrorCodePayloadTooLarge:
+		return http.StatusRequestEntityTooLarge
+
 	case ErrorCodeRateLimited:
 		return http.StatusTooManyRequests
 
@@ -0,0 +1,108 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem details
+const ProblemContentType = "application/problem+json"
+
+// ProblemTypePrefix prefixes the error code in a problem's type URI
+const ProblemTypePrefix = "urn:notification-service:problem:"
+
+// Problem is an RFC 7807 problem details body. Code carries the stable error
+// code, so API consumers can branch on it rather than parsing the detail.
+type Problem struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title"`
+	Status   int               `json:"status"`
+	Detail   string            `json:"detail,omitempty"`
+	Instance string            `json:"instance,omitempty"`
+	Code     ErrorCode         `json:"code"`
+	Errors   []FieldError      `json:"errors,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Error implements the error interface
+func (p *Problem) Error() string {
+	return fmt.Sprintf("%s: %s", p.Code, p.Detail)
+}
+
+// NotificationError converts a problem received from the API back into a NotificationError
+func (p *Problem) NotificationError() *NotificationError {
+	metadata := make(map[string]string, len(p.Metadata))
+	for key, value := range p.Metadata {
+		metadata[key] = value
+	}
+
+	return &NotificationError{
+		Code:       p.Code,
+		Message:    p.Detail,
+		StatusCode: p.Status,
+		Metadata:   metadata,
+		Fields:     p.Errors,
+	}
+}
+
+// NewProblem builds the problem details of an error. Errors that are not
+// NotificationErrors are reported as internal errors without their message,
+// so internal details never reach API consumers. Instance identifies the
+// request, typically its path.
+func NewProblem(err error, instance string) *Problem {
+	notifErr := toNotificationError(err)
+
+	problem := &Problem{
+		Type:     ProblemType(notifErr.Code),
+		Title:    http.StatusText(notifErr.StatusCode),
+		Status:   notifErr.StatusCode,
+		Detail:   notifErr.Message,
+		Instance: instance,
+		Code:     notifErr.Code,
+		Errors:   notifErr.Fields,
+	}
+	if notifErr.Details != "" {
+		problem.Detail = notifErr.Message + ": " + notifErr.Details
+	}
+	if len(notifErr.Metadata) > 0 {
+		problem.Metadata = notifErr.Metadata
+	}
+
+	return problem
+}
+
+// ProblemType returns the type URI of an error code, e.g.
+// urn:notification-service:problem:validation-failed
+func ProblemType(code ErrorCode) string {
+	return ProblemTypePrefix + strings.ReplaceAll(strings.ToLower(string(code)), "_", "-")
+}
+
+// WriteProblem writes an error as an application/problem+json response
+func WriteProblem(w http.ResponseWriter, r *http.Request, err error) {
+	instance := ""
+	if r != nil && r.URL != nil {
+		instance = r.URL.Path
+	}
+	problem := NewProblem(err, instance)
+
+	if retryAfter := problem.Metadata["retry_after"]; retryAfter != "" {
+		w.Header().Set("Retry-After", retryAfter)
+	}
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// ParseProblem decodes a problem+json body into a NotificationError
+func ParseProblem(data []byte) (*NotificationError, error) {
+	var problem Problem
+	if err := json.Unmarshal(data, &problem); err != nil {
+		return nil, err
+	}
+	if problem.Code == "" {
+		return nil, fmt.Errorf("response is not a problem details body")
+	}
+	return problem.NotificationError(), nil
+}
@@ -0,0 +1,78 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewProblem(t *testing.T) {
+	err := NewFieldValidationError(
+		FieldError{Field: "recipient", Rule: "required", Message: "is required"},
+		FieldError{Field: "body", Rule: "required", Message: "is required"},
+	)
+
+	problem := NewProblem(err, "/v1/notifications")
+
+	assert.Equal(t, "urn:notification-service:problem:validation-failed", problem.Type)
+	assert.Equal(t, "Bad Request", problem.Title)
+	assert.Equal(t, http.StatusBadRequest, problem.Status)
+	assert.Equal(t, "Validation failed for fields: recipient, body", problem.Detail)
+	assert.Equal(t, "/v1/notifications", problem.Instance)
+	assert.Equal(t, ErrorCodeValidationFailed, problem.Code)
+	assert.Len(t, problem.Errors, 2)
+}
+
+func TestNewProblem_HidesInternalErrors(t *testing.T) {
+	problem := NewProblem(fmt.Errorf("dial tcp 10.0.0.5:5432: connection refused"), "")
+
+	assert.Equal(t, ErrorCodeInternal, problem.Code)
+	assert.Equal(t, http.StatusInternalServerError, problem.Status)
+	assert.Equal(t, "internal server error", problem.Detail)
+}
+
+func TestNewProblem_WrappedError(t *testing.T) {
+	err := fmt.Errorf("loading notification: %w", ErrNotificationNotFound)
+
+	problem := NewProblem(err, "")
+	assert.Equal(t, ErrorCodeNotFound, problem.Code)
+	assert.Equal(t, http.StatusNotFound, problem.Status)
+}
+
+func TestWriteProblem(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/v1/notifications", nil)
+
+	WriteProblem(recorder, request, NewRateLimitError("30"))
+
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, ProblemContentType, recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "30", recorder.Header().Get("Retry-After"))
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	assert.Equal(t, "RATE_LIMITED", body["code"])
+	assert.Equal(t, float64(http.StatusTooManyRequests), body["status"])
+	assert.Equal(t, "/v1/notifications", body["instance"])
+}
+
+func TestParseProblem(t *testing.T) {
+	original := NewValidationError("to", "invalid email address")
+	data, err := json.Marshal(NewProblem(original, ""))
+	require.NoError(t, err)
+
+	parsed, err := ParseProblem(data)
+	require.NoError(t, err)
+	assert.Equal(t, ErrorCodeValidationFailed, parsed.Code)
+	assert.Equal(t, http.StatusBadRequest, parsed.StatusCode)
+	assert.Equal(t, original.Message, parsed.Message)
+	assert.Equal(t, "to", parsed.Metadata["field"])
+
+	_, err = ParseProblem([]byte(`{"message":"not a problem"}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,142 @@
+package errors
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+)
+
+// GRPCCode is a gRPC status code. The values match google.golang.org/grpc/codes,
+// so a gRPC server can convert with codes.Code(errors.GRPCStatus(err)) without
+// this package depending on gRPC.
+type GRPCCode uint32
+
+const (
+	GRPCOK                 GRPCCode = 0
+	GRPCCanceled           GRPCCode = 1
+	GRPCUnknown            GRPCCode = 2
+	GRPCInvalidArgument    GRPCCode = 3
+	GRPCDeadlineExceeded   GRPCCode = 4
+	GRPCNotFound           GRPCCode = 5
+	GRPCAlreadyExists      GRPCCode = 6
+	GRPCPermissionDenied   GRPCCode = 7
+	GRPCResourceExhausted  GRPCCode = 8
+	GRPCFailedPrecondition GRPCCode = 9
+	GRPCAborted            GRPCCode = 10
+	GRPCOutOfRange         GRPCCode = 11
+	GRPCUnimplemented      GRPCCode = 12
+	GRPCInternal           GRPCCode = 13
+	GRPCUnavailable        GRPCCode = 14
+	GRPCDataLoss           GRPCCode = 15
+	GRPCUnauthenticated    GRPCCode = 16
+)
+
+// grpcCodeNames holds the canonical names of the gRPC status codes
+var grpcCodeNames = [...]string{
+	"OK", "Canceled", "Unknown", "InvalidArgument", "DeadlineExceeded", "NotFound",
+	"AlreadyExists", "PermissionDenied", "ResourceExhausted", "FailedPrecondition",
+	"Aborted", "OutOfRange", "Unimplemented", "Internal", "Unavailable", "DataLoss",
+	"Unauthenticated",
+}
+
+// String returns the canonical name of the code
+func (c GRPCCode) String() string {
+	if int(c) < len(grpcCodeNames) {
+		return grpcCodeNames[c]
+	}
+	return "Unknown"
+}
+
+// Codes returns every error code the service can return. Codes are part of
+// the API contract: new codes may be added, existing ones are never renamed.
+func Codes() []ErrorCode {
+	return []ErrorCode{
+		ErrorCodeInternal, ErrorCodeInvalidRequest, ErrorCodeNotFound, ErrorCodeUnauthorized,
+		ErrorCodeForbidden, ErrorCodeRateLimited, ErrorCodeTimeout,
+		ErrorCodeProviderNotFound, ErrorCodeProviderUnavailable, ErrorCodeProviderConfiguration,
+		ErrorCodeProviderAuthentication,
+		ErrorCodeInvalidRecipient, ErrorCodeInvalidNotification, ErrorCodeNotificationFailed,
+		ErrorCodeDeliveryFailed, ErrorCodeTemplateNotFound, ErrorCodeRecipientOptedOut,
+		ErrorCodeAttachmentInfected, ErrorCodePayloadTooLarge,
+		ErrorCodeValidationFailed, ErrorCodeInvalidEmail, ErrorCodeInvalidPhone, ErrorCodeInvalidToken,
+		ErrorCodeQueueFull, ErrorCodeQueueEmpty, ErrorCodeQueueTimeout,
+	}
+}
+
+// HTTPStatus returns the HTTP status code for an error. NotificationErrors use
+// their own status code; context deadlines map to 504 and other errors to 500.
+func HTTPStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	return toNotificationError(err).StatusCode
+}
+
+// GRPCStatus returns the gRPC status code for an error
+func GRPCStatus(err error) GRPCCode {
+	if err == nil {
+		return GRPCOK
+	}
+	if stderrors.Is(err, context.Canceled) {
+		return GRPCCanceled
+	}
+	return getGRPCCode(toNotificationError(err).Code)
+}
+
+// toNotificationError finds the NotificationError in an error chain, converting
+// context errors and wrapping anything else as an internal error
+func toNotificationError(err error) *NotificationError {
+	var notifErr *NotificationError
+	if stderrors.As(err, &notifErr) {
+		if notifErr.StatusCode == 0 {
+			// Copy so a zero-valued error from outside this package is not modified
+			withStatus := *notifErr
+			withStatus.StatusCode = getHTTPStatusCode(notifErr.Code)
+			return &withStatus
+		}
+		return notifErr
+	}
+
+	if stderrors.Is(err, context.DeadlineExceeded) {
+		timeout := NewNotificationError(ErrorCodeTimeout, "request timed out")
+		timeout.StatusCode = http.StatusGatewayTimeout
+		return timeout.WithCause(err)
+	}
+
+	return NewInternalError("internal server error", err)
+}
+
+// getGRPCCode maps error codes to gRPC status codes, in line with getHTTPStatusCode
+func getGRPCCode(code ErrorCode) GRPCCode {
+	switch code {
+	case ErrorCodeInvalidRequest, ErrorCodeValidationFailed,
+		ErrorCodeInvalidEmail, ErrorCodeInvalidPhone, ErrorCodeInvalidToken,
+		ErrorCodeInvalidRecipient, ErrorCodeInvalidNotification, ErrorCodeAttachmentInfected,
+		ErrorCodePayloadTooLarge:
+		return GRPCInvalidArgument
+
+	case ErrorCodeUnauthorized, ErrorCodeProviderAuthentication:
+		return GRPCUnauthenticated
+
+	case ErrorCodeForbidden:
+		return GRPCPermissionDenied
+
+	case ErrorCodeNotFound, ErrorCodeProviderNotFound, ErrorCodeTemplateNotFound:
+		return GRPCNotFound
+
+	case ErrorCodeRecipientOptedOut:
+		return GRPCFailedPrecondition
+
+	case ErrorCodeRateLimited, ErrorCodeQueueFull:
+		return GRPCResourceExhausted
+
+	case ErrorCodeTimeout, ErrorCodeQueueTimeout:
+		return GRPCDeadlineExceeded
+
+	case ErrorCodeProviderUnavailable, ErrorCodeNotificationFailed, ErrorCodeDeliveryFailed:
+		return GRPCUnavailable
+
+	default:
+		return GRPCInternal
+	}
+}
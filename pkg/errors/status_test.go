@@ -0,0 +1,86 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, http.StatusOK},
+		{"validation", NewValidationError("to", "required"), http.StatusBadRequest},
+		{"not found", ErrNotificationNotFound, http.StatusNotFound},
+		{"opted out", NewNotificationError(ErrorCodeRecipientOptedOut, "opted out"), http.StatusUnprocessableEntity},
+		{"infected", NewNotificationError(ErrorCodeAttachmentInfected, "infected"), http.StatusUnprocessableEntity},
+		{"too large", NewPayloadTooLargeError("attachments", 12, 10, "attachments"), http.StatusRequestEntityTooLarge},
+		{"forbidden", NewNotificationError(ErrorCodeForbidden, "forbidden"), http.StatusForbidden},
+		{"wrapped", fmt.Errorf("send: %w", NewNotificationError(ErrorCodeProviderUnavailable, "down")), http.StatusServiceUnavailable},
+		{"zero status", &NotificationError{Code: ErrorCodeRateLimited}, http.StatusTooManyRequests},
+		{"deadline", context.DeadlineExceeded, http.StatusGatewayTimeout},
+		{"plain", fmt.Errorf("boom"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, HTTPStatus(tt.err))
+		})
+	}
+}
+
+func TestGRPCStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want GRPCCode
+	}{
+		{"nil", nil, GRPCOK},
+		{"validation", NewValidationError("to", "required"), GRPCInvalidArgument},
+		{"unauthorized", NewNotificationError(ErrorCodeUnauthorized, "bad key"), GRPCUnauthenticated},
+		{"not found", ErrNotificationNotFound, GRPCNotFound},
+		{"opted out", NewNotificationError(ErrorCodeRecipientOptedOut, "opted out"), GRPCFailedPrecondition},
+		{"infected", NewNotificationError(ErrorCodeAttachmentInfected, "infected"), GRPCInvalidArgument},
+		{"too large", NewPayloadTooLargeError("payload", 5000, 4096, "bytes"), GRPCInvalidArgument},
+		{"forbidden", NewNotificationError(ErrorCodeForbidden, "forbidden"), GRPCPermissionDenied},
+		{"rate limited", NewRateLimitError(""), GRPCResourceExhausted},
+		{"queue full", ErrQueueFull, GRPCResourceExhausted},
+		{"timeout", NewNotificationError(ErrorCodeQueueTimeout, "timed out"), GRPCDeadlineExceeded},
+		{"unavailable", NewNotificationError(ErrorCodeDeliveryFailed, "failed"), GRPCUnavailable},
+		{"canceled", context.Canceled, GRPCCanceled},
+		{"deadline", context.DeadlineExceeded, GRPCDeadlineExceeded},
+		{"plain", fmt.Errorf("boom"), GRPCInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, GRPCStatus(tt.err))
+		})
+	}
+}
+
+func TestGRPCCode_String(t *testing.T) {
+	assert.Equal(t, "InvalidArgument", GRPCInvalidArgument.String())
+	assert.Equal(t, "Unauthenticated", GRPCUnauthenticated.String())
+	assert.Equal(t, "Unknown", GRPCCode(99).String())
+}
+
+func TestCodes_AreStable(t *testing.T) {
+	seen := make(map[ErrorCode]bool)
+	for _, code := range Codes() {
+		assert.False(t, seen[code], "duplicate code %s", code)
+		seen[code] = true
+	}
+
+	// Renaming a code breaks API consumers that branch on it
+	assert.Equal(t, ErrorCode("VALIDATION_FAILED"), ErrorCodeValidationFailed)
+	assert.Equal(t, ErrorCode("NOT_FOUND"), ErrorCodeNotFound)
+	assert.Equal(t, ErrorCode("RATE_LIMITED"), ErrorCodeRateLimited)
+	assert.Len(t, Codes(), 26)
+}
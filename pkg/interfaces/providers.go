@@ -2,7 +2,9 @@ package interfaces
 
 import (
 	"context"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/nareshkumar-microsoft/notificationService/internal/models"
 )
 
@@ -35,6 +37,98 @@ type EmailProvider interface {
 	GetEmailTemplates() []EmailTemplate
 }
 
+// BatchResult is the outcome of one notification in a batch send: a response
+// on success, an error otherwise
+type BatchResult struct {
+	Response *models.NotificationResponse
+	Err      error
+}
+
+// BatchEmailProvider is implemented by email providers with a batch API, such
+// as SendGrid personalizations or SES bulk templated email
+type BatchEmailProvider interface {
+	EmailProvider
+
+	// SendEmailBatch sends up to MaxBatchSize emails in one API call. Results
+	// are in the order of the emails; an error fails the whole batch.
+	SendEmailBatch(ctx context.Context, emails []*models.EmailNotification) ([]BatchResult, error)
+
+	// MaxBatchSize returns how many emails one call may carry
+	MaxBatchSize() int
+}
+
+// AttachmentRenderer renders an email attachment, such as a PDF invoice, from
+// template data at send time
+type AttachmentRenderer interface {
+	// RenderAttachment renders the attachment. The content type must be set.
+	RenderAttachment(ctx context.Context, data map[string]string) (*models.EmailAttachment, error)
+}
+
+// AttachmentScanner scans email attachments for viruses, e.g. with ClamAV
+type AttachmentScanner interface {
+	// ScanAttachment returns the name of the threat the attachment carries,
+	// or "" when it is clean
+	ScanAttachment(ctx context.Context, attachment *models.EmailAttachment) (string, error)
+}
+
+// MessageLookupProvider is implemented by providers that can look up the
+// messages they accepted, so a send whose outcome was never recorded can be
+// resolved without sending it again
+type MessageLookupProvider interface {
+	// LookupMessage finds the message sent for a notification, by provider
+	// message ID when known and otherwise by the notification ID, which
+	// providers receive as the idempotency key. It returns an
+	// ErrorCodeNotFound error when the provider never accepted the message.
+	LookupMessage(ctx context.Context, notificationID uuid.UUID, providerMessageID string) (*models.NotificationResponse, error)
+}
+
+// AuthoritativeLookupProvider is implemented by lookup providers whose
+// lookups see every message they accepted as soon as they accept it, so an
+// ErrorCodeNotFound proves a message was never sent. A NotFound from other
+// providers may only mean the message is not indexed yet.
+type AuthoritativeLookupProvider interface {
+	MessageLookupProvider
+
+	// LookupAuthoritative reports whether a failed lookup proves a message
+	// was never accepted
+	LookupAuthoritative() bool
+}
+
+// CredentialStatus is the outcome of validating a provider's credentials
+type CredentialStatus struct {
+	Valid     bool       `json:"valid"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // when the credentials stop working, if known
+	Detail    string     `json:"detail,omitempty"`     // e.g. the account balance
+}
+
+// CredentialChecker is implemented by providers that can validate their
+// credentials against the provider's API, e.g. with an auth probe or a
+// balance check
+type CredentialChecker interface {
+	// CheckCredentials validates the credentials. It returns an error only
+	// when the check could not be made; rejected credentials are reported
+	// as not valid.
+	CheckCredentials(ctx context.Context) (*CredentialStatus, error)
+}
+
+// CredentialRotator is implemented by providers whose credentials can be
+// replaced while they are sending
+type CredentialRotator interface {
+	// RotateCredentials validates the new credentials and switches to them.
+	// Sends in flight finish with the old ones; on error the old ones stay
+	// in use.
+	RotateCredentials(ctx context.Context, credentials map[string]string) error
+}
+
+// OptionsProvider is implemented by providers that accept provider-specific
+// options on requests, such as a Twilio messaging service SID or an FCM
+// analytics label. Providers that do not implement it accept none.
+type OptionsProvider interface {
+	// ValidateProviderOptions checks a request's provider options. Unknown
+	// options and values of the wrong type are rejected.
+	ValidateProviderOptions(options map[string]any) error
+}
+
 // SMSProvider defines the interface for SMS notification providers
 type SMSProvider interface {
 	NotificationProvider
@@ -63,6 +157,54 @@ type PushProvider interface {
 	GetPlatformConfig(platform string) PlatformConfig
 }
 
+// BatchPushProvider is implemented by push providers with a batch API, such
+// as FCM's batch send
+type BatchPushProvider interface {
+	PushProvider
+
+	// SendPushBatch sends up to MaxBatchSize notifications in one API call.
+	// Results are in the order of the notifications; an error fails the
+	// whole batch.
+	SendPushBatch(ctx context.Context, pushes []*models.PushNotification) ([]BatchResult, error)
+
+	// MaxBatchSize returns how many notifications one call may carry
+	MaxBatchSize() int
+}
+
+// TopicPushProvider is implemented by push providers that can broadcast to
+// topics natively (e.g. FCM topic messaging) instead of per-device sends
+type TopicPushProvider interface {
+	PushProvider
+
+	// SendPushToTopic sends a push notification to all subscribers of a topic
+	SendPushToTopic(ctx context.Context, topic string, push *models.PushNotification) (*models.NotificationResponse, error)
+}
+
+// ChatProvider defines the interface for chat webhook providers (Slack, Microsoft Teams)
+type ChatProvider interface {
+	NotificationProvider
+
+	// SendChat posts a message to a chat webhook
+	SendChat(ctx context.Context, chat *models.ChatNotification) (*models.NotificationResponse, error)
+
+	// ValidateWebhookURL validates a webhook URL for the provider's platform
+	ValidateWebhookURL(webhookURL string) error
+}
+
+// VoiceProvider defines the interface for text-to-speech voice call providers
+type VoiceProvider interface {
+	NotificationProvider
+
+	// SendVoice places a call that reads the message to the recipient
+	SendVoice(ctx context.Context, voice *models.VoiceNotification) (*models.NotificationResponse, error)
+
+	// ValidatePhoneNumber validates a phone number and checks the country is allowed
+	ValidatePhoneNumber(phoneNumber, countryCode string) error
+
+	// GetVoiceCost returns the per-minute cost of a call to a specific country
+	GetVoiceCost(countryCode string) (float64, error)
+}
+
 // NotificationService defines the main service interface
 type NotificationService interface {
 	// SendNotification sends a notification using the appropriate provider
@@ -105,6 +247,99 @@ type NotificationRepository interface {
 	GetPendingNotifications(ctx context.Context, limit int) ([]*models.Notification, error)
 }
 
+// RecipientListRepository defines the interface for recipient list storage
+type RecipientListRepository interface {
+	// CreateList saves a new recipient list. List names are unique.
+	CreateList(ctx context.Context, list *models.RecipientList) error
+
+	// GetList retrieves a recipient list by ID
+	GetList(ctx context.Context, id string) (*models.RecipientList, error)
+
+	// GetListByName retrieves a recipient list by name
+	GetListByName(ctx context.Context, name string) (*models.RecipientList, error)
+
+	// ListLists retrieves all recipient lists
+	ListLists(ctx context.Context) ([]*models.RecipientList, error)
+
+	// DeleteList deletes a recipient list and its members
+	DeleteList(ctx context.Context, id string) error
+
+	// AddMembers adds members to a list, replacing the attributes of existing members
+	AddMembers(ctx context.Context, listID string, members []models.ListMember) error
+
+	// RemoveMembers removes recipients from a list and returns how many were removed
+	RemoveMembers(ctx context.Context, listID string, recipients []string) (int, error)
+
+	// GetMembers retrieves the members of a list in the order they were added
+	GetMembers(ctx context.Context, listID string) ([]models.ListMember, error)
+}
+
+// Outbox defines the interface for a transactional outbox table. Applications
+// sharing the database insert entries in the same transaction as their
+// business data; implementations over such a database should let Add join
+// the caller's transaction.
+type Outbox interface {
+	// Add inserts an entry
+	Add(ctx context.Context, entry *models.OutboxEntry) error
+
+	// Claim leases up to limit unprocessed entries, oldest first. Entries
+	// leased by another poller are skipped until the lease expires.
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]*models.OutboxEntry, error)
+
+	// MarkProcessed marks a claimed entry as moved to the queue, so it is never claimed again
+	MarkProcessed(ctx context.Context, id uuid.UUID) error
+}
+
+// Locker defines the interface for the locks that keep work such as
+// scheduled jobs and purges from running on more than one instance at a
+// time. Locks are leases, so a lock held by an instance that died is freed
+// when its lease expires.
+type Locker interface {
+	// Acquire takes the lock of a key for an owner until the lease expires.
+	// An owner acquiring a lock it holds extends the lease. It reports false
+	// when another owner holds an unexpired lease.
+	Acquire(ctx context.Context, key, owner string, lease time.Duration) (bool, error)
+
+	// Release frees the lock of a key if the owner still holds it
+	Release(ctx context.Context, key, owner string) error
+}
+
+// RateLimitStore defines the interface for the token buckets that instances
+// enforcing a shared limit take tokens from
+type RateLimitStore interface {
+	// Take takes a token from the bucket of a key, which refills at
+	// perSecond tokens a second up to burst tokens. When the bucket is empty
+	// it reports false and how long until a token is available.
+	Take(ctx context.Context, key string, perSecond float64, burst int) (bool, time.Duration, error)
+}
+
+// Webhook event states recorded by a WebhookEventStore
+const (
+	WebhookEventProcessing = "processing"
+	WebhookEventDone       = "done"
+)
+
+// WebhookEventStore defines the interface for the record of provider
+// webhook events that lets retried events be recognized
+type WebhookEventStore interface {
+	// Claim marks an event as being processed for lease. When the event was
+	// already claimed it reports false and the event's state.
+	Claim(ctx context.Context, key string, lease time.Duration) (bool, string, error)
+	// Complete marks a claimed event as processed for ttl
+	Complete(ctx context.Context, key string, ttl time.Duration) error
+	// Release forgets an event whose processing failed, so its retry is processed
+	Release(ctx context.Context, key string) error
+}
+
+// AuditRepository defines the interface for append-only audit storage
+type AuditRepository interface {
+	// Append adds an entry to the audit trail. Entries cannot be changed or removed.
+	Append(ctx context.Context, entry *models.AuditEntry) error
+
+	// Query retrieves entries matching the filters, oldest first
+	Query(ctx context.Context, filters AuditFilters) ([]*models.AuditEntry, error)
+}
+
 // Logger defines the interface for logging
 type Logger interface {
 	Debug(args ...interface{})
@@ -164,6 +399,13 @@ type PlatformConfig struct {
 	Settings   map[string]string `json:"settings"`
 }
 
+// NotificationPurger is implemented by repositories that can permanently
+// remove old notifications, including ones that were soft deleted
+type NotificationPurger interface {
+	// PurgeBefore removes notifications created before the cutoff and returns how many were removed
+	PurgeBefore(ctx context.Context, cutoff time.Time) (int, error)
+}
+
 // NotificationFilters represents filters for querying notifications
 type NotificationFilters struct {
 	Type      *models.NotificationType   `json:"type,omitempty"`
@@ -177,3 +419,14 @@ type NotificationFilters struct {
 	SortBy    string                     `json:"sort_by"`
 	SortOrder string                     `json:"sort_order"`
 }
+
+// AuditFilters represents filters for querying the audit trail
+type AuditFilters struct {
+	NotificationID string               `json:"notification_id,omitempty"`
+	Actor          string               `json:"actor,omitempty"`
+	Outcome        *models.AuditOutcome `json:"outcome,omitempty"`
+	From           *time.Time           `json:"from,omitempty"`
+	To             *time.Time           `json:"to,omitempty"`
+	Limit          int                  `json:"limit"`
+	Offset         int                  `json:"offset"`
+}